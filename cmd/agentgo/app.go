@@ -2,19 +2,28 @@ package main
 
 import (
 	"context"
-	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 
 	"github.com/adrg/xdg"
+
+	"github.com/flexigpt/flexigpt-app/internal/fsutil"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 )
 
+var appLogger = logging.Module("app")
+
 const (
 	AppTitle = "FlexiGPT"
 
+	// instanceLockFileName is locked by the process that owns the stores, so
+	// CLI commands do not write them while the GUI runs.
+	instanceLockFileName = "instance.lock"
+
 	settingsDirectoryName           = "settings"
 	conversationsDirectoryName      = "conversationsv1"
 	modelPresetsDirectoryName       = "modelpresetsv1"
@@ -23,9 +32,25 @@ const (
 	mcpDirectoryName                = "mcpserversv1"
 	assistantPresetsDirectoryName   = "assistantpresetsv1"
 	workspaceArtifactsDirectoryName = "workspace-artifacts"
+	clipboardPastesDirectoryName    = "clipboard-pastes"
+	attachmentBlobsDirectoryName    = "attachmentblobsv1"
+	toolRunsDirectoryName           = "toolrunsv1"
+	usageDirectoryName              = "usagev1"
+	completionCacheDirectoryName    = "completioncachev1"
+	knowledgeBaseDirectoryName      = "knowledgebasev1"
+	schedulerDirectoryName          = "schedulerv1"
 	appDirectoryMode                = 0o770
 )
 
+// storeBackupPolicy bounds the copies user store files keep of their
+// previous content. At most one copy is taken per interval, so frequent
+// background writes do not push out the copies from before user edits.
+var storeBackupPolicy = fsutil.BackupPolicy{
+	MaxCopies:   20,
+	MaxAge:      30 * 24 * time.Hour,
+	MinInterval: 10 * time.Minute,
+}
+
 type App struct {
 	ctx context.Context
 
@@ -39,8 +64,17 @@ type App struct {
 	aggregateAPI            *AggregrateWrapper
 	assistantPresetStoreAPI *AssistantPresetStoreWrapper
 	workspaceAPI            *WorkspaceWrapper
+	usageStoreAPI           *UsageStoreWrapper
+	knowledgeBaseAPI        *KnowledgeBaseWrapper
+	schedulerAPI            *SchedulerWrapper
+	progressAPI             *ProgressWrapper
+	logAPI                  *LogWrapper
+	diagnosticsAPI          *DiagnosticsWrapper
+	healthAPI               *HealthWrapper
+	consistencyAPI          *ConsistencyWrapper
 
 	dataBasePath string
+	instanceLock *fsutil.FileLock
 
 	settingsDirPath           string
 	conversationsDirPath      string
@@ -50,19 +84,24 @@ type App struct {
 	mcpsDirPath               string
 	assistantPresetsDirPath   string
 	workspaceArtifactsDirPath string
+	clipboardPastesDirPath    string
 }
 
 func NewApp() *App {
 	if xdg.DataHome == "" {
-		slog.Error(
+		appLogger.Error(
 			"could not resolve xdg data paths",
 			"xdg data dir", xdg.DataHome,
 		)
 		panic("failed to initialize app: xdg paths not set")
 	}
+	return newApp(filepath.Join(xdg.DataHome, strings.ToLower(AppTitle)))
+}
 
+// newApp creates the app with all its data under dataBasePath.
+func newApp(dataBasePath string) *App {
 	app := &App{}
-	app.dataBasePath = filepath.Join(xdg.DataHome, strings.ToLower(AppTitle))
+	app.dataBasePath = dataBasePath
 
 	app.settingsDirPath = filepath.Join(app.dataBasePath, settingsDirectoryName)
 	app.conversationsDirPath = filepath.Join(app.dataBasePath, conversationsDirectoryName)
@@ -72,15 +111,17 @@ func NewApp() *App {
 	app.mcpsDirPath = filepath.Join(app.dataBasePath, mcpDirectoryName)
 	app.assistantPresetsDirPath = filepath.Join(app.dataBasePath, assistantPresetsDirectoryName)
 	app.workspaceArtifactsDirPath = filepath.Join(app.dataBasePath, workspaceArtifactsDirectoryName)
+	app.clipboardPastesDirPath = filepath.Join(app.dataBasePath, clipboardPastesDirectoryName)
 
 	if app.settingsDirPath == "" || app.conversationsDirPath == "" ||
 		app.modelPresetsDirPath == "" ||
 		app.assistantPresetsDirPath == "" || app.toolsDirPath == "" ||
 		app.skillsDirPath == "" || app.mcpsDirPath == "" ||
-		app.workspaceArtifactsDirPath == "" {
-		slog.Error(
+		app.workspaceArtifactsDirPath == "" || app.clipboardPastesDirPath == "" {
+		appLogger.Error(
 			"invalid app path configuration",
 			"workspaceArtifactsDirPath", app.workspaceArtifactsDirPath,
+			"clipboardPastesDirPath", app.clipboardPastesDirPath,
 			"settingsDirPath", app.settingsDirPath,
 			"conversationsDirPath", app.conversationsDirPath,
 			"modelPresetsDirPath", app.modelPresetsDirPath,
@@ -103,11 +144,19 @@ func NewApp() *App {
 	app.toolRuntimeAPI = &ToolRuntimeWrapper{}
 	app.aggregateAPI = &AggregrateWrapper{}
 	app.workspaceAPI = &WorkspaceWrapper{}
+	app.usageStoreAPI = &UsageStoreWrapper{}
+	app.knowledgeBaseAPI = &KnowledgeBaseWrapper{}
+	app.schedulerAPI = &SchedulerWrapper{}
+	app.progressAPI = &ProgressWrapper{}
+	app.logAPI = &LogWrapper{}
+	app.diagnosticsAPI = &DiagnosticsWrapper{}
+	app.healthAPI = &HealthWrapper{}
+	app.consistencyAPI = &ConsistencyWrapper{}
 
 	app.assistantPresetStoreAPI = &AssistantPresetStoreWrapper{}
 
 	if err := os.MkdirAll(app.settingsDirPath, os.FileMode(appDirectoryMode)); err != nil {
-		slog.Error(
+		appLogger.Error(
 			"failed to create settings directory",
 			"settings path", app.settingsDirPath,
 			"error", err,
@@ -115,7 +164,7 @@ func NewApp() *App {
 		panic("failed to initialize app: could not create settings directory")
 	}
 	if err := os.MkdirAll(app.conversationsDirPath, os.FileMode(appDirectoryMode)); err != nil {
-		slog.Error(
+		appLogger.Error(
 			"failed to create conversations directory",
 			"conversations path", app.conversationsDirPath,
 			"error", err,
@@ -123,7 +172,7 @@ func NewApp() *App {
 		panic("failed to initialize app: could not create conversations directory")
 	}
 	if err := os.MkdirAll(app.modelPresetsDirPath, os.FileMode(appDirectoryMode)); err != nil {
-		slog.Error(
+		appLogger.Error(
 			"failed to create model presets directory",
 			"model presets path", app.modelPresetsDirPath,
 			"error", err,
@@ -132,7 +181,7 @@ func NewApp() *App {
 	}
 
 	if err := os.MkdirAll(app.toolsDirPath, os.FileMode(appDirectoryMode)); err != nil {
-		slog.Error(
+		appLogger.Error(
 			"failed to create tools directory",
 			"tools path", app.toolsDirPath,
 			"error", err,
@@ -140,7 +189,7 @@ func NewApp() *App {
 		panic("failed to initialize app: could not create tools directory")
 	}
 	if err := os.MkdirAll(app.skillsDirPath, os.FileMode(appDirectoryMode)); err != nil {
-		slog.Error(
+		appLogger.Error(
 			"failed to create skills directory",
 			"skills path", app.skillsDirPath,
 			"error", err,
@@ -148,7 +197,7 @@ func NewApp() *App {
 		panic("failed to initialize app: could not create skills directory")
 	}
 	if err := os.MkdirAll(app.mcpsDirPath, os.FileMode(appDirectoryMode)); err != nil {
-		slog.Error(
+		appLogger.Error(
 			"failed to create mcp directory",
 			"mcps path", app.mcpsDirPath,
 			"error", err,
@@ -157,7 +206,7 @@ func NewApp() *App {
 
 	}
 	if err := os.MkdirAll(app.assistantPresetsDirPath, os.FileMode(appDirectoryMode)); err != nil {
-		slog.Error(
+		appLogger.Error(
 			"failed to create assistant presets directory",
 			"assistant presets path", app.assistantPresetsDirPath,
 			"error", err,
@@ -165,15 +214,23 @@ func NewApp() *App {
 		panic("failed to initialize app: could not create assistant presets directory")
 	}
 	if err := os.MkdirAll(app.workspaceArtifactsDirPath, os.FileMode(appDirectoryMode)); err != nil {
-		slog.Error(
+		appLogger.Error(
 			"failed to create Workspace artifact directory",
 			"workspaceArtifactsDirPath", app.workspaceArtifactsDirPath,
 			"error", err,
 		)
 		panic("failed to initialize app: could not create Workspace artifact directory")
 	}
+	if err := os.MkdirAll(app.clipboardPastesDirPath, os.FileMode(appDirectoryMode)); err != nil {
+		appLogger.Error(
+			"failed to create clipboard pastes directory",
+			"clipboardPastesDirPath", app.clipboardPastesDirPath,
+			"error", err,
+		)
+		panic("failed to initialize app: could not create clipboard pastes directory")
+	}
 
-	slog.Info(
+	appLogger.Info(
 		"flexiGPT paths initialized",
 		"app data", app.dataBasePath,
 		"settingsDirPath", app.settingsDirPath,
@@ -184,6 +241,7 @@ func NewApp() *App {
 		"mcpsDirPath", app.mcpsDirPath,
 		"assistantPresetsDirPath", app.assistantPresetsDirPath,
 		"workspaceArtifactsDirPath", app.workspaceArtifactsDirPath,
+		"clipboardPastesDirPath", app.clipboardPastesDirPath,
 	)
 	return app
 }
@@ -197,20 +255,24 @@ func (a *App) GetAppVersion() string {
 }
 
 func (a *App) initManagers() {
-	err := InitConversationCollectionWrapper(a.conversationStoreAPI, a.conversationsDirPath)
+	err := InitConversationCollectionWrapper(
+		a.conversationStoreAPI,
+		a.conversationsDirPath,
+		filepath.Join(a.dataBasePath, attachmentBlobsDirectoryName),
+	)
 	if err != nil {
-		slog.Error(
+		appLogger.Error(
 			"couldn't initialize conversation store",
 			"directory", a.conversationsDirPath,
 			"error", err,
 		)
 		panic("failed to initialize managers: conversation store initialization failed\n" + err.Error())
 	}
-	slog.Info("conversation store initialized", "directory", a.conversationsDirPath)
+	appLogger.Info("conversation store initialized", "directory", a.conversationsDirPath)
 
 	err = InitToolStoreWrapper(a.toolStoreAPI, a.toolsDirPath)
 	if err != nil {
-		slog.Error(
+		appLogger.Error(
 			"couldn't initialize tool store",
 			"directory", a.toolsDirPath,
 			"error", err,
@@ -218,9 +280,13 @@ func (a *App) initManagers() {
 		panic("failed to initialize managers: tool store initialization failed\n" + err.Error())
 	}
 
-	err = InitToolRuntimeWrapper(a.toolRuntimeAPI, a.toolStoreAPI.store)
+	err = InitToolRuntimeWrapper(
+		a.toolRuntimeAPI,
+		a.toolStoreAPI.store,
+		filepath.Join(a.dataBasePath, toolRunsDirectoryName),
+	)
 	if err != nil {
-		slog.Error(
+		appLogger.Error(
 			"couldn't initialize tool runtime",
 			"error", err,
 		)
@@ -232,36 +298,54 @@ func (a *App) initManagers() {
 		a.workspaceArtifactsDirPath,
 	)
 	if err != nil {
-		slog.Error(
+		appLogger.Error(
 			"couldn't initialize Workspace",
 			"directory", a.workspaceArtifactsDirPath,
 			"error", err,
 		)
 		panic("failed to initialize managers: Workspace initialization failed\n" + err.Error())
 	}
-	slog.Info("workspace initialized", "directory", a.workspaceArtifactsDirPath)
+	appLogger.Info("workspace initialized", "directory", a.workspaceArtifactsDirPath)
+
+	// Settings come before stores that consult feature flags.
+	err = InitSettingStoreWrapper(a.settingStoreAPI, a.settingsDirPath)
+	if err != nil {
+		appLogger.Error(
+			"couldn't initialize settings store",
+			"directory", a.settingsDirPath,
+			"error", err,
+		)
+		panic("failed to initialize managers: settings store initialization failed\n" + err.Error())
+	}
+	a.settingStoreAPI.store.SetImplicitTrustedRoots(a.skillsDirPath)
+	appLogger.Info("settings store initialized", "directory", a.settingsDirPath)
 
 	err = InitSkillStoreWrapper(
 		a.skillStoreAPI,
 		a.skillsDirPath,
 		a.workspaceAPI.api.SkillAdapter(),
+		a.settingStoreAPI.store,
+		a.settingStoreAPI.store,
+		a.settingStoreAPI.store,
+		configuredSkillRegistryURL(context.Background(), a.settingStoreAPI.store),
+		true,
 	)
 	if err != nil {
-		slog.Error(
+		appLogger.Error(
 			"couldn't initialize Skill services",
 			"directory", a.skillsDirPath,
 			"error", err,
 		)
 		panic("failed to initialize managers: Skill initialization failed\n" + err.Error())
 	}
-	slog.Info("skill services initialized", "directory", a.skillsDirPath)
+	appLogger.Info("skill services initialized", "directory", a.skillsDirPath)
 
 	err = BindWorkspaceSkillRuntime(
 		a.workspaceAPI,
 		a.skillStoreAPI.runtime,
 	)
 	if err != nil {
-		slog.Error(
+		appLogger.Error(
 			"couldn't bind Workspace Skill runtime",
 			"error", err,
 		)
@@ -270,24 +354,14 @@ func (a *App) initManagers() {
 
 	err = InitAggregateSkillProvider(a.skillStoreAPI)
 	if err != nil {
-		slog.Error(
+		appLogger.Error(
 			"couldn't initialize aggregate Skill provider",
 			"error", err,
 		)
 		panic("failed to initialize managers: aggregate Skill provider initialization failed\n" + err.Error())
 	}
 
-	slog.Info("aggregate Skill provider initialized")
-	err = InitSettingStoreWrapper(a.settingStoreAPI, a.settingsDirPath)
-	if err != nil {
-		slog.Error(
-			"couldn't initialize settings store",
-			"directory", a.settingsDirPath,
-			"error", err,
-		)
-		panic("failed to initialize managers: settings store initialization failed\n" + err.Error())
-	}
-	slog.Info("settings store initialized", "directory", a.settingsDirPath)
+	appLogger.Info("aggregate Skill provider initialized")
 
 	err = InitMCPWrapper(
 		context.Background(),
@@ -296,28 +370,29 @@ func (a *App) initManagers() {
 		newSettingSecretResolver(a.settingStoreAPI.store),
 	)
 	if err != nil {
-		slog.Error(
+		appLogger.Error(
 			"couldn't initialize mcp host",
 			"directory", a.mcpsDirPath,
 			"error", err,
 		)
 		panic("failed to initialize managers: mcp store initialization failed\n" + err.Error())
 	}
-	slog.Info("mcp host initialized", "directory", a.mcpsDirPath)
+	appLogger.Info("mcp host initialized", "directory", a.mcpsDirPath)
 
 	err = InitModelPresetStoreWrapper(
 		a.modelPresetStoreAPI,
 		a.modelPresetsDirPath,
+		true,
 	)
 	if err != nil {
-		slog.Error(
+		appLogger.Error(
 			"couldn't initialize model presets store",
 			"dir", a.modelPresetsDirPath,
 			"error", err,
 		)
 		panic("failed to initialize managers: model presets store initialization failed\n" + err.Error())
 	}
-	slog.Info("model presets store initialized", "dir", a.modelPresetsDirPath)
+	appLogger.Info("model presets store initialized", "dir", a.modelPresetsDirPath)
 
 	err = InitAssistantPresetStoreWrapper(
 		a.assistantPresetStoreAPI,
@@ -329,18 +404,30 @@ func (a *App) initManagers() {
 		a.mcpAPI.runtime,
 	)
 	if err != nil {
-		slog.Error(
+		appLogger.Error(
 			"couldn't initialize assistant preset store",
 			"dir", a.assistantPresetsDirPath,
 			"error", err,
 		)
 		panic("failed to initialize managers: assistant preset store initialization failed\n" + err.Error())
 	}
-	slog.Info(
+	appLogger.Info(
 		"assistant preset store initialized",
 		"dir", a.assistantPresetsDirPath,
 	)
 
+	usageDirPath := filepath.Join(a.dataBasePath, usageDirectoryName)
+	err = InitUsageStoreWrapper(a.usageStoreAPI, usageDirPath)
+	if err != nil {
+		appLogger.Error(
+			"couldn't initialize usage store",
+			"dir", usageDirPath,
+			"error", err,
+		)
+		panic("failed to initialize managers: usage store initialization failed\n" + err.Error())
+	}
+	appLogger.Info("usage store initialized", "dir", usageDirPath)
+
 	err = InitAggregrateWrapper(
 		a.aggregateAPI,
 		a.modelPresetStoreAPI.store,
@@ -349,16 +436,73 @@ func (a *App) initManagers() {
 		a.skillStoreAPI.store,
 		a.skillStoreAPI.runtime,
 		a.mcpAPI.runtime,
+		a.usageStoreAPI.store,
+		filepath.Join(a.dataBasePath, completionCacheDirectoryName),
+		true,
 	)
 	if err != nil {
-		slog.Error(
+		appLogger.Error(
 			"couldn't initialize aggregate",
 			"error", err,
 		)
 		panic("failed to initialize managers: aggregate initialization failed\n" + err.Error())
 	}
+	SetModelPresetProviderResync(a.modelPresetStoreAPI, a.aggregateAPI.resyncProvider)
+
+	appLogger.Info("aggregate initialized", "dir", a.modelPresetsDirPath)
+
+	knowledgeBaseDirPath := filepath.Join(a.dataBasePath, knowledgeBaseDirectoryName)
+	err = InitKnowledgeBaseWrapper(
+		a.knowledgeBaseAPI,
+		knowledgeBaseDirPath,
+		a.aggregateAPI,
+		a.settingStoreAPI.store.RequirePathTrust,
+	)
+	if err != nil {
+		appLogger.Error(
+			"couldn't initialize knowledge base store",
+			"dir", knowledgeBaseDirPath,
+			"error", err,
+		)
+		panic("failed to initialize managers: knowledge base store initialization failed\n" + err.Error())
+	}
+	appLogger.Info("knowledge base store initialized", "dir", knowledgeBaseDirPath)
 
-	slog.Info("aggregate initialized", "dir", a.modelPresetsDirPath)
+	schedulerDirPath := filepath.Join(a.dataBasePath, schedulerDirectoryName)
+	err = InitSchedulerWrapper(a.schedulerAPI, schedulerDirPath, a.aggregateAPI, a.conversationStoreAPI)
+	if err != nil {
+		appLogger.Error(
+			"couldn't initialize scheduler store",
+			"dir", schedulerDirPath,
+			"error", err,
+		)
+		panic("failed to initialize managers: scheduler store initialization failed\n" + err.Error())
+	}
+	appLogger.Info("scheduler store initialized", "dir", schedulerDirPath)
+
+	if err := InitDiagnosticsWrapper(a.diagnosticsAPI, a.dataBasePath, a.settingStoreAPI); err != nil {
+		appLogger.Error("couldn't initialize diagnostics", "error", err)
+		panic("failed to initialize managers: diagnostics initialization failed\n" + err.Error())
+	}
+	if err := InitHealthWrapper(a.healthAPI, a); err != nil {
+		appLogger.Error("couldn't initialize health checks", "error", err)
+		panic("failed to initialize managers: health check initialization failed\n" + err.Error())
+	}
+	if err := InitConsistencyWrapper(a.consistencyAPI, a); err != nil {
+		appLogger.Error("couldn't initialize consistency checks", "error", err)
+		panic("failed to initialize managers: consistency check initialization failed\n" + err.Error())
+	}
+}
+
+// lockInstance takes the instance lock of the data directory. It returns
+// fsutil.ErrLocked while another process holds it.
+func (a *App) lockInstance() error {
+	lock, err := fsutil.LockFile(filepath.Join(a.dataBasePath, instanceLockFileName))
+	if err != nil {
+		return err
+	}
+	a.instanceLock = lock
+	return nil
 }
 
 // startup is called at application startup.
@@ -387,6 +531,11 @@ func (a *App) shutdown(ctx context.Context) { //nolint:all
 
 	// Stop background goroutines + flushes for stores that need it.
 
+	// Scheduled runs use the other stores, so stop them first.
+	if a.schedulerAPI != nil {
+		a.schedulerAPI.close()
+	}
+
 	if a.assistantPresetStoreAPI != nil {
 		a.assistantPresetStoreAPI.close()
 	}
@@ -413,4 +562,16 @@ func (a *App) shutdown(ctx context.Context) { //nolint:all
 	if a.conversationStoreAPI != nil {
 		a.conversationStoreAPI.close()
 	}
+	if a.usageStoreAPI != nil {
+		a.usageStoreAPI.close()
+	}
+	if a.knowledgeBaseAPI != nil {
+		a.knowledgeBaseAPI.close()
+	}
+	if a.progressAPI != nil {
+		a.progressAPI.close()
+	}
+	if err := a.instanceLock.Unlock(); err != nil {
+		appLogger.Error("couldn't release instance lock", "error", err)
+	}
 }
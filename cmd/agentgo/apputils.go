@@ -3,7 +3,6 @@ package main
 import (
 	"embed"
 	"io/fs"
-	"log/slog"
 	"runtime"
 )
 
@@ -14,7 +13,7 @@ func EmbeddedFSWalker(assets embed.FS) {
 		if err != nil {
 			return err
 		}
-		slog.Info("embedded walk", "path", path)
+		appLogger.Info("embedded walk", "path", path)
 		return nil
 	})
 }
@@ -25,5 +24,5 @@ func LogStackTrace() {
 	// Capture the stack trace.
 	n := runtime.Stack(buf, false)
 	// Log the stack trace.
-	slog.Info("stack", "trace", string(buf[:n]))
+	appLogger.Info("stack", "trace", string(buf[:n]))
 }
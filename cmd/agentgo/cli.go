@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+
+	"github.com/flexigpt/flexigpt-app/internal/fsutil"
+	inferencewrapperSpec "github.com/flexigpt/flexigpt-app/internal/inferencewrapper/spec"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	settingSpec "github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// errCLIUsage is returned after a usage message has been printed.
+var errCLIUsage = errors.New("usage")
+
+// errGUIRunning is returned when the GUI holds the instance lock.
+var errGUIRunning = errors.New("FlexiGPT is running; quit it before using the command line")
+
+type cliCommand struct {
+	// open initializes the stores run uses, and nothing else: the scheduler,
+	// file watchers, presence checks and migrations belong to the GUI.
+	open func(a *App) error
+	run  func(ctx context.Context, a *App, args []string, out io.Writer) error
+}
+
+// cliCommands run against the same stores as the GUI and exit. Any other
+// first argument starts the GUI as usual.
+var cliCommands = map[string]cliCommand{
+	"presets": {open: (*App).openCLIModelPresets, run: runPresetsCommand},
+	"skills":  {open: (*App).openCLISkills, run: runSkillsCommand},
+	"chat":    {open: (*App).openCLIChat, run: runChatCommand},
+}
+
+func lookupCLICommand(args []string) (string, bool) {
+	if len(args) == 0 {
+		return "", false
+	}
+	_, ok := cliCommands[args[0]]
+	return args[0], ok
+}
+
+// runCLI runs a subcommand and returns the process exit code.
+func runCLI(a *App, name string, args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := runCLICommand(ctx, a, name, args, os.Stdout)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errCLIUsage):
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "agentgo %s: %v\n", name, err)
+	return 1
+}
+
+// runCLICommand takes the instance lock, opens the stores of the command and
+// runs it.
+func runCLICommand(ctx context.Context, a *App, name string, args []string, out io.Writer) error {
+	cmd := cliCommands[name]
+	if err := a.lockInstance(); err != nil {
+		if errors.Is(err, fsutil.ErrLocked) {
+			return errGUIRunning
+		}
+		return fmt.Errorf("lock data directory: %w", err)
+	}
+	defer a.shutdown(context.WithoutCancel(ctx))
+	if err := cmd.open(a); err != nil {
+		return err
+	}
+	return cmd.run(ctx, a, args, out)
+}
+
+func (a *App) openCLISettings() error {
+	if a.settingStoreAPI.store != nil {
+		return nil
+	}
+	if err := InitSettingStoreWrapper(a.settingStoreAPI, a.settingsDirPath); err != nil {
+		return fmt.Errorf("open settings: %w", err)
+	}
+	a.settingStoreAPI.store.SetImplicitTrustedRoots(a.skillsDirPath)
+	return nil
+}
+
+func (a *App) openCLIModelPresets() error {
+	if err := InitModelPresetStoreWrapper(a.modelPresetStoreAPI, a.modelPresetsDirPath, false); err != nil {
+		return fmt.Errorf("open model presets: %w", err)
+	}
+	return nil
+}
+
+func (a *App) openCLISkills() error {
+	if err := a.openCLISettings(); err != nil {
+		return err
+	}
+	err := InitSkillStoreWrapper(
+		a.skillStoreAPI,
+		a.skillsDirPath,
+		nil,
+		a.settingStoreAPI.store,
+		a.settingStoreAPI.store,
+		a.settingStoreAPI.store,
+		configuredSkillRegistryURL(context.Background(), a.settingStoreAPI.store),
+		false,
+	)
+	if err != nil {
+		return fmt.Errorf("open skills: %w", err)
+	}
+	return nil
+}
+
+func (a *App) openCLIChat() error {
+	if err := a.openCLISkills(); err != nil {
+		return err
+	}
+	if err := a.openCLIModelPresets(); err != nil {
+		return err
+	}
+	if err := InitToolStoreWrapper(a.toolStoreAPI, a.toolsDirPath); err != nil {
+		return fmt.Errorf("open tools: %w", err)
+	}
+	if err := InitUsageStoreWrapper(a.usageStoreAPI, filepath.Join(a.dataBasePath, usageDirectoryName)); err != nil {
+		return fmt.Errorf("open usage: %w", err)
+	}
+	return InitAggregrateWrapper(
+		a.aggregateAPI,
+		a.modelPresetStoreAPI.store,
+		a.settingStoreAPI.store,
+		a.toolStoreAPI.store,
+		a.skillStoreAPI.store,
+		a.skillStoreAPI.runtime,
+		nil,
+		a.usageStoreAPI.store,
+		filepath.Join(a.dataBasePath, completionCacheDirectoryName),
+		false,
+	)
+}
+
+func newCLIFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet("agentgo "+name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: agentgo %s\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func parseCLIFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errCLIUsage
+	}
+	return nil
+}
+
+func runPresetsCommand(ctx context.Context, a *App, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "list" {
+		fmt.Fprintln(os.Stderr, "usage: agentgo presets list [-all]")
+		return errCLIUsage
+	}
+	fs := newCLIFlagSet("presets list", "presets list [-all]")
+	all := fs.Bool("all", false, "include disabled providers and model presets")
+	if err := parseCLIFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	st := a.modelPresetStoreAPI.store
+	def, err := st.GetDefaultProvider(ctx, &modelpresetSpec.GetDefaultProviderRequest{})
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PRESET\tMODEL\tDISPLAY NAME\tDEFAULT")
+	req := &modelpresetSpec.ListProviderPresetsRequest{IncludeDisabled: *all}
+	for {
+		resp, err := st.ListProviderPresets(ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, p := range resp.Body.Providers {
+			for _, mp := range sortedModelPresets(p) {
+				mark := ""
+				if mp.ID == p.DefaultModelPresetID {
+					mark = "provider"
+					if p.Name == def.Body.DefaultProvider {
+						mark = "app"
+					}
+				}
+				fmt.Fprintf(tw, "%s/%s\t%s\t%s\t%s\n", p.Name, mp.ID, mp.Name, mp.DisplayName, mark)
+			}
+		}
+		if resp.Body.NextPageToken == nil || *resp.Body.NextPageToken == "" {
+			break
+		}
+		req = &modelpresetSpec.ListProviderPresetsRequest{PageToken: *resp.Body.NextPageToken}
+	}
+	return tw.Flush()
+}
+
+func sortedModelPresets(p modelpresetSpec.ProviderPreset) []modelpresetSpec.ModelPreset {
+	out := make([]modelpresetSpec.ModelPreset, 0, len(p.ModelPresets))
+	for _, mp := range p.ModelPresets {
+		out = append(out, mp)
+	}
+	slices.SortFunc(out, func(x, y modelpresetSpec.ModelPreset) int {
+		return strings.Compare(string(x.ID), string(y.ID))
+	})
+	return out
+}
+
+// runSkillsCommand installs a skill package directory into a user bundle, or
+// imports a bundle archive. A directory outside trusted roots needs -trust,
+// which records the user's confirmation as the app's trust dialog does.
+func runSkillsCommand(ctx context.Context, a *App, args []string, out io.Writer) error {
+	const usage = "skills install [-bundle ID] [-slug SLUG] [-disabled] [-trust] <skill dir | bundle archive>"
+	if len(args) == 0 || args[0] != "install" {
+		fmt.Fprintln(os.Stderr, "usage: agentgo "+usage)
+		return errCLIUsage
+	}
+	fs := newCLIFlagSet("skills install", usage)
+	bundleID := fs.String("bundle", "", "bundle to add the skill to; defaults to the only user bundle")
+	slug := fs.String("slug", "", "skill slug; defaults to the directory name")
+	disabled := fs.Bool("disabled", false, "install the skill disabled")
+	trust := fs.Bool("trust", false, "trust the skill directory, allowing its scripts to be used")
+	if err := parseCLIFlags(fs, args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errCLIUsage
+	}
+	path, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	w := a.skillStoreAPI
+
+	if !info.IsDir() {
+		archive, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		resp, err := w.ImportSkillBundle(&skillstoreSpec.ImportSkillBundleRequest{
+			Body: &skillstoreSpec.ImportSkillBundleRequestBody{Archive: archive},
+		})
+		if err != nil {
+			return err
+		}
+		b := resp.Body.SkillBundle
+		fmt.Fprintf(out, "imported bundle %s (%s)\n", b.Slug, b.ID)
+		return nil
+	}
+
+	bid := skillstoreSpec.SkillBundleID(*bundleID)
+	if bid == "" {
+		if bid, err = soleUserSkillBundle(ctx, a); err != nil {
+			return err
+		}
+	}
+	name := filepath.Base(path)
+	skillSlug := skillstoreSpec.SkillSlug(*slug)
+	if skillSlug == "" {
+		skillSlug = skillstoreSpec.SkillSlug(name)
+	}
+	if *trust {
+		if _, err := a.settingStoreAPI.store.ConfirmPathTrust(ctx, &settingSpec.ConfirmPathTrustRequest{
+			Body: &settingSpec.ConfirmPathTrustRequestBody{Path: path, Purpose: settingSpec.TrustPurposeSkill},
+		}); err != nil {
+			return err
+		}
+	} else if err := w.requireLocationTrust(ctx, path); err != nil {
+		return fmt.Errorf("%w; pass -trust to trust it", err)
+	}
+	if _, err := w.PutSkill(&skillstoreSpec.PutSkillRequest{
+		BundleID:  bid,
+		SkillSlug: skillSlug,
+		Body: &skillstoreSpec.PutSkillRequestBody{
+			SkillType: skillstoreSpec.SkillTypeFS,
+			Location:  path,
+			Name:      name,
+			IsEnabled: !*disabled,
+		},
+	}); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "installed skill %s in bundle %s\n", skillSlug, bid)
+	return nil
+}
+
+func soleUserSkillBundle(ctx context.Context, a *App) (skillstoreSpec.SkillBundleID, error) {
+	var ids []skillstoreSpec.SkillBundleID
+	req := &skillstoreSpec.ListSkillBundlesRequest{IncludeDisabled: true}
+	for {
+		resp, err := a.skillStoreAPI.store.ListSkillBundles(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		for _, b := range resp.Body.SkillBundles {
+			if !b.IsBuiltIn && b.SoftDeletedAt == nil {
+				ids = append(ids, b.ID)
+			}
+		}
+		if resp.Body.NextPageToken == nil || *resp.Body.NextPageToken == "" {
+			break
+		}
+		req = &skillstoreSpec.ListSkillBundlesRequest{PageToken: *resp.Body.NextPageToken}
+	}
+	switch len(ids) {
+	case 0:
+		return "", errors.New("no user skill bundle exists; create one in the app first")
+	case 1:
+		return ids[0], nil
+	}
+	return "", fmt.Errorf("%d user skill bundles exist; pick one with -bundle", len(ids))
+}
+
+// runChatCommand sends one question and prints the answer. The question is
+// read from stdin when no argument is given.
+func runChatCommand(ctx context.Context, a *App, args []string, out io.Writer) error {
+	fs := newCLIFlagSet("chat", `chat [-preset PROVIDER/PRESET] ["question" | < question]`)
+	preset := fs.String("preset", "", "model preset as provider/modelPresetID; defaults to the app default")
+	if err := parseCLIFlags(fs, args); err != nil {
+		return err
+	}
+	question := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if question == "" {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		question = strings.TrimSpace(string(b))
+	}
+	if question == "" {
+		fs.Usage()
+		return errCLIUsage
+	}
+
+	ref, err := resolveCLIModelPreset(ctx, a, *preset)
+	if err != nil {
+		return err
+	}
+	presetResp, err := a.modelPresetStoreAPI.store.GetModelPreset(ctx, &modelpresetSpec.GetModelPresetRequest{
+		ProviderName:  ref.ProviderName,
+		ModelPresetID: ref.ModelPresetID,
+	})
+	if err != nil {
+		return err
+	}
+	modelParam := modelParamFromPreset(presetResp.Body.Model)
+	user, err := newUserTextMessage(question, ref, modelParam)
+	if err != nil {
+		return err
+	}
+
+	streamed := false
+	resp, err := a.aggregateAPI.providersetAPI.FetchCompletion(ctx, &inferencewrapperSpec.CompletionRequest{
+		Provider:      ref.ProviderName,
+		ModelPresetID: ref.ModelPresetID,
+		Body:          &inferencewrapperSpec.CompletionRequestBody{ModelParam: &modelParam, Current: user},
+		OnStreamText: func(text string) error {
+			streamed = true
+			_, err := io.WriteString(out, text)
+			return err
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if resp == nil || resp.Body == nil || resp.Body.InferenceResponse == nil {
+		return errors.New("empty completion response")
+	}
+	inf := resp.Body.InferenceResponse
+	if !streamed {
+		for _, o := range inf.Outputs {
+			if o.Kind != inferenceSpec.OutputKindOutputMessage || o.OutputMessage == nil {
+				continue
+			}
+			for _, c := range o.OutputMessage.Contents {
+				if c.TextItem != nil {
+					if _, err := io.WriteString(out, c.TextItem.Text); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	fmt.Fprintln(out)
+	if inf.Error != nil {
+		return errors.New(inf.Error.Message)
+	}
+	return nil
+}
+
+// resolveCLIModelPreset parses "provider/modelPresetID". An empty value
+// picks the default model preset of the default provider.
+func resolveCLIModelPreset(ctx context.Context, a *App, value string) (modelpresetSpec.ModelPresetRef, error) {
+	if value != "" {
+		provider, id, ok := strings.Cut(value, "/")
+		if !ok || provider == "" || id == "" {
+			return modelpresetSpec.ModelPresetRef{}, fmt.Errorf("-preset %q is not provider/modelPresetID", value)
+		}
+		return modelpresetSpec.ModelPresetRef{
+			ProviderName:  inferenceSpec.ProviderName(provider),
+			ModelPresetID: modelpresetSpec.ModelPresetID(id),
+		}, nil
+	}
+	st := a.modelPresetStoreAPI.store
+	def, err := st.GetDefaultProvider(ctx, &modelpresetSpec.GetDefaultProviderRequest{})
+	if err != nil {
+		return modelpresetSpec.ModelPresetRef{}, err
+	}
+	resp, err := st.GetProviderPreset(ctx, &modelpresetSpec.GetProviderPresetRequest{
+		ProviderName: def.Body.DefaultProvider,
+	})
+	notFound := errors.Is(err, modelpresetSpec.ErrProviderNotFound) || errors.Is(err, modelpresetSpec.ErrInvalidDir)
+	if err != nil && !notFound {
+		return modelpresetSpec.ModelPresetRef{}, err
+	}
+	if notFound || resp.Body.DefaultModelPresetID == "" {
+		return modelpresetSpec.ModelPresetRef{}, errors.New("no default model preset; pass -preset")
+	}
+	return modelpresetSpec.ModelPresetRef{
+		ProviderName:  def.Body.DefaultProvider,
+		ModelPresetID: resp.Body.DefaultModelPresetID,
+	}, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+
+	settingSpec "github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+func TestLookupCLICommand(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want bool
+	}{
+		{args: nil},
+		{args: []string{"-debug"}},
+		{args: []string{"presets", "list"}, want: true},
+		{args: []string{"skills"}, want: true},
+	} {
+		if _, ok := lookupCLICommand(tt.args); ok != tt.want {
+			t.Errorf("lookupCLICommand(%q) = %v, want %v", tt.args, ok, tt.want)
+		}
+	}
+}
+
+func TestRunCLICommand_RefusesWhileGUIRuns(t *testing.T) {
+	dir := t.TempDir()
+	gui := newApp(dir)
+	if err := gui.lockInstance(); err != nil {
+		t.Fatalf("lockInstance: %v", err)
+	}
+	defer gui.shutdown(t.Context())
+
+	cli := newApp(dir)
+	var out bytes.Buffer
+	err := runCLICommand(t.Context(), cli, "presets", []string{"list"}, &out)
+	if !errors.Is(err, errGUIRunning) {
+		t.Fatalf("runCLICommand = %v, want errGUIRunning", err)
+	}
+	if cli.modelPresetStoreAPI.store != nil {
+		t.Fatal("stores were opened while the GUI holds the lock")
+	}
+}
+
+func TestRunCLICommand_PresetsList(t *testing.T) {
+	a := newApp(t.TempDir())
+	var out bytes.Buffer
+	if err := runCLICommand(t.Context(), a, "presets", []string{"list"}, &out); err != nil {
+		t.Fatalf("presets list: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) < 2 || !strings.HasPrefix(lines[0], "PRESET") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	// Only the stores of the command are opened, and all are closed again.
+	if a.schedulerAPI.store != nil || a.skillStoreAPI.store != nil || a.modelPresetStoreAPI.store == nil {
+		t.Fatal("presets list opened stores it does not use")
+	}
+	if err := a.lockInstance(); err != nil {
+		t.Fatalf("instance lock not released: %v", err)
+	}
+	_ = a.instanceLock.Unlock()
+}
+
+func TestRunCLICommand_SkillsInstallRequiresTrust(t *testing.T) {
+	skillDir := filepath.Join(t.TempDir(), "notes")
+	if err := os.MkdirAll(skillDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	skillMD := "---\nname: notes\ndescription: Take notes.\n---\n\nWrite notes down.\n"
+	if err := os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte(skillMD), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dataDir := t.TempDir()
+	// The settings store encrypts auth keys with a key from the OS keyring.
+	keyring.MockInit()
+
+	var out bytes.Buffer
+	err := runCLICommand(t.Context(), newApp(dataDir), "skills", []string{"install", skillDir}, &out)
+	if !errors.Is(err, settingSpec.ErrPathNotTrusted) || !strings.Contains(err.Error(), "-trust") {
+		t.Fatalf("install without -trust = %v, want ErrPathNotTrusted with a hint", err)
+	}
+
+	err = runCLICommand(t.Context(), newApp(dataDir), "skills", []string{"install", "-trust", skillDir}, &out)
+	if err != nil {
+		t.Fatalf("install -trust: %v", err)
+	}
+	if !strings.Contains(out.String(), "installed skill notes") {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+
+	a := newApp(dataDir)
+	if err := a.openCLISkills(); err != nil {
+		t.Fatalf("openCLISkills: %v", err)
+	}
+	defer a.shutdown(t.Context())
+	if _, err := a.skillStoreAPI.store.GetSkill(t.Context(), &skillstoreSpec.GetSkillRequest{
+		BundleID:  skillstoreSpec.BaseSkillBundleID,
+		SkillSlug: "notes",
+	}); err != nil {
+		t.Fatalf("installed skill: %v", err)
+	}
+}
+
+func TestOpenCLIChat(t *testing.T) {
+	keyring.MockInit()
+	a := newApp(t.TempDir())
+	if err := a.openCLIChat(); err != nil {
+		t.Fatalf("openCLIChat: %v", err)
+	}
+	defer a.shutdown(t.Context())
+	if a.aggregateAPI.providersetAPI == nil {
+		t.Fatal("provider set not initialized")
+	}
+}
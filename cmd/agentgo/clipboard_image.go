@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	clipboardImageTimeout = 5 * time.Second
+	// Pasted files are referenced by conversations; keep them for a while but
+	// do not let the directory grow without bound.
+	clipboardPasteMaxAge   = 30 * 24 * time.Hour
+	clipboardPasteMaxFiles = 500
+)
+
+// errNoClipboardImage is returned when the clipboard holds no image or the
+// platform tool to read one is missing.
+var errNoClipboardImage = errors.New("clipboard holds no image")
+
+// readClipboardImage returns the clipboard image as PNG, e.g. an OS
+// screenshot copied to the clipboard. Wails only exposes clipboard text.
+func readClipboardImage(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, clipboardImageTimeout)
+	defer cancel()
+	data, err := readPlatformClipboardImage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errNoClipboardImage
+	}
+	return data, nil
+}
+
+// pruneClipboardPastes removes pastes older than clipboardPasteMaxAge and
+// the oldest ones beyond clipboardPasteMaxFiles.
+func pruneClipboardPastes(dir string, now time.Time) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	type paste struct {
+		name    string
+		modTime time.Time
+	}
+	pastes := make([]paste, 0, len(entries))
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasPrefix(e.Name(), "paste-") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		pastes = append(pastes, paste{name: e.Name(), modTime: info.ModTime()})
+	}
+	slices.SortFunc(pastes, func(a, b paste) int { return b.modTime.Compare(a.modTime) })
+
+	var errs []error
+	for i, p := range pastes {
+		if i < clipboardPasteMaxFiles && now.Sub(p.modTime) <= clipboardPasteMaxAge {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, p.name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"os/exec"
+	"strings"
+)
+
+// readPlatformClipboardImage asks AppleScript for the clipboard as PNG; it
+// prints the bytes as «data PNGf<hex>».
+func readPlatformClipboardImage(ctx context.Context) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, "osascript", "-e", "the clipboard as «class PNGf»").Output()
+	if err != nil {
+		return nil, errNoClipboardImage
+	}
+	s := strings.TrimSpace(string(out))
+	s, ok := strings.CutPrefix(s, "«data PNGf")
+	if !ok {
+		return nil, errNoClipboardImage
+	}
+	return hex.DecodeString(strings.TrimSuffix(s, "»"))
+}
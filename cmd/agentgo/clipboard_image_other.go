@@ -0,0 +1,25 @@
+//go:build !darwin && !windows
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
+// readPlatformClipboardImage reads image/png from the clipboard with
+// wl-paste on Wayland or xclip on X11.
+func readPlatformClipboardImage(ctx context.Context) ([]byte, error) {
+	var cmd *exec.Cmd
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		cmd = exec.CommandContext(ctx, "wl-paste", "--no-newline", "--type", "image/png")
+	} else {
+		cmd = exec.CommandContext(ctx, "xclip", "-selection", "clipboard", "-target", "image/png", "-out")
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errNoClipboardImage
+	}
+	return out, nil
+}
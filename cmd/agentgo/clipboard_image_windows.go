@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+const clipboardImageScript = `Add-Type -AssemblyName System.Windows.Forms, System.Drawing
+$img = [System.Windows.Forms.Clipboard]::GetImage()
+if ($img -eq $null) { exit 3 }
+$ms = New-Object System.IO.MemoryStream
+$img.Save($ms, [System.Drawing.Imaging.ImageFormat]::Png)
+$out = [Console]::OpenStandardOutput()
+$out.Write($ms.ToArray(), 0, $ms.Length)
+$out.Flush()`
+
+// readPlatformClipboardImage reads the clipboard bitmap through PowerShell and
+// writes it out as PNG. The clipboard API needs a single-threaded apartment.
+func readPlatformClipboardImage(ctx context.Context) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "powershell.exe",
+		"-NoProfile", "-NonInteractive", "-STA", "-Command", clipboardImageScript)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errNoClipboardImage
+	}
+	return out, nil
+}
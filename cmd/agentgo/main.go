@@ -16,7 +16,9 @@ import (
 	"github.com/wailsapp/wails/v2/pkg/options/windows"
 
 	assets "github.com/flexigpt/flexigpt-app/frontend"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 	"github.com/flexigpt/flexigpt-app/internal/logrotate"
+	"github.com/flexigpt/flexigpt-app/internal/middleware"
 
 	// Run registry init.
 	_ "github.com/flexigpt/flexigpt-app/internal/llmtoolsutil"
@@ -44,6 +46,7 @@ func main() {
 		MaximumFileSize:      10 * 1024 * 1024, // 10 MB
 		MaximumLifetime:      24 * time.Hour,
 		FileNameFunc:         logrotate.DefaultFilenameFunc,
+		MaximumFiles:         20,
 		FlushAfterEveryWrite: true,
 	}
 	writer, err := logrotate.New(stdoutLogger, opts)
@@ -53,10 +56,20 @@ func main() {
 	}
 	defer writer.Close()
 
-	var handler slog.Handler = slog.NewTextHandler(writer, slogOpts)
-	slogger := slog.New(handler)
+	appLogHandler = logging.NewHandler(writer, logging.Config{Level: appSlogLevelVar.Level()})
+	slogger := slog.New(appLogHandler)
 	slog.SetDefault(slogger)
 
+	if name, ok := lookupCLICommand(os.Args[1:]); ok {
+		code := runCLI(app, name, os.Args[2:])
+		writer.Close()
+		os.Exit(code)
+	}
+	if err := app.lockInstance(); err != nil {
+		// Another window or a CLI command holds it. The GUI starts anyway;
+		// only CLI commands refuse to share the stores.
+		slog.Warn("couldn't take the instance lock", "error", err)
+	}
 	app.initManagers()
 	// EmbeddedFSWalker(assets).
 
@@ -76,6 +89,7 @@ func main() {
 			Assets: assets.Assets,
 		},
 		Menu:                     nil,
+		ErrorFormatter:           middleware.FormatError,
 		Logger:                   wailsLogger,
 		LogLevel:                 wailsLogLevel,
 		LogLevelProduction:       wailsProdLogLevel,
@@ -83,6 +97,9 @@ func main() {
 		OnStartup: func(ctx context.Context) {
 			app.startup(ctx)
 			SetWrappedProviderAppContext(app.aggregateAPI, ctx)
+			SetModelPresetEventsAppContext(app.modelPresetStoreAPI, ctx)
+			SetSettingEventsAppContext(app.settingStoreAPI, ctx)
+			SetProgressEventsAppContext(app.progressAPI, ctx)
 		},
 
 		OnDomReady:      app.domReady,
@@ -108,6 +125,14 @@ func main() {
 			app.mcpAPI,
 			app.aggregateAPI,
 			app.assistantPresetStoreAPI,
+			app.usageStoreAPI,
+			app.knowledgeBaseAPI,
+			app.schedulerAPI,
+			app.progressAPI,
+			app.logAPI,
+			app.diagnosticsAPI,
+			app.healthAPI,
+			app.consistencyAPI,
 		},
 
 		Windows: &windows.Options{
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"log/slog"
 	"runtime/debug"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -26,9 +28,17 @@ import (
 	settingStore "github.com/flexigpt/flexigpt-app/internal/setting/store"
 	"github.com/flexigpt/flexigpt-app/internal/skillruntime"
 	"github.com/flexigpt/flexigpt-app/internal/skillstore"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+	toolSpec "github.com/flexigpt/flexigpt-app/internal/tool/spec"
 	toolStore "github.com/flexigpt/flexigpt-app/internal/tool/store"
+	usageSpec "github.com/flexigpt/flexigpt-app/internal/usage/spec"
+	usageStore "github.com/flexigpt/flexigpt-app/internal/usage/store"
 )
 
+// completionStreamEvent is the Wails event carrying an
+// inferencewrapperSpec.CompletionStreamEvent.
+const completionStreamEvent = "completion:stream"
+
 var appSlogLevelVar slog.LevelVar
 
 func init() {
@@ -43,6 +53,10 @@ type AggregrateWrapper struct {
 	skillRuntime     *skillruntime.SkillRuntime
 	providersetAPI   *inferencewrapper.ProviderSetAPI
 
+	// providerRenames are the reserved provider names renamed at startup,
+	// old name to new name, reported by HealthCheck.
+	providerRenames map[string]string
+
 	appContext          context.Context
 	completionCancelMux sync.Mutex
 	completionCancels   map[string]context.CancelFunc
@@ -57,6 +71,9 @@ func InitAggregrateWrapper(
 	skillSt *skillstore.SkillStore,
 	skillRt *skillruntime.SkillRuntime,
 	mr *mcpRuntime.MCPRuntimeManager,
+	us *usageStore.UsageStore,
+	completionCacheDir string,
+	migrate bool,
 ) error {
 	if agg == nil || ts == nil || mps == nil || ss == nil || skillSt == nil || skillRt == nil {
 		panic("initializing aggregate store wrapper on nil receivers")
@@ -70,19 +87,50 @@ func InitAggregrateWrapper(
 
 	defaultDebugConfig := inferencewrapper.DefaultDebugConfig()
 
-	var bridge *inferencewrapper.MCPInferenceBridge
+	// The CLI runs without MCP servers; the bridge then adds no tools.
+	var mcp inferencewrapper.MCPRuntime
 	if mr != nil {
-		bridge = inferencewrapper.NewMCPInferenceBridge(mr)
+		mcp = mr
 	}
+	bridge := inferencewrapper.NewMCPInferenceBridge(mcp)
 
 	p, err := inferencewrapper.NewProviderSetAPI(
 		agg.toolStore,
 		agg.modelPresetStore,
 		agg.skillRuntime,
 		bridge,
-		inferencewrapper.WithLogger(slog.Default()),
 		inferencewrapper.WithDebugConfig(&defaultDebugConfig),
 		inferencewrapper.WithSkillsRunScriptEnabled(skillRt.RunScriptsEnabled()),
+		inferencewrapper.WithUsageStore(us),
+		inferencewrapper.WithBudgetGuard(usageStore.NewBudgetGuard(us,
+			func(ctx context.Context, provider inferenceSpec.ProviderName) (daily, monthly float64, err error) {
+				b, err := ss.GetProviderBudget(ctx, string(provider))
+				return b.DailyLimitUSD, b.MonthlyLimitUSD, err
+			},
+		)),
+		inferencewrapper.WithCompletionCache(completionCacheDir,
+			func(ctx context.Context) (inferencewrapper.CompletionCacheConfig, error) {
+				c, err := ss.GetCompletionCacheSettings(ctx)
+				return inferencewrapper.CompletionCacheConfig{
+					Enabled:  c.Enabled,
+					MaxBytes: int64(c.MaxSizeMB) * 1024 * 1024,
+					TTL:      time.Duration(c.TTLMinutes) * time.Minute,
+				}, err
+			},
+		),
+		inferencewrapper.WithAuthKeyResolver(func(ctx context.Context, keyType, keyName string) (string, error) {
+			resp, err := ss.GetAuthKey(
+				settingStore.WithAuthKeyCaller(ctx, settingSpec.AuthKeyCallerProviders),
+				&settingSpec.GetAuthKeyRequest{
+					Type:    settingSpec.AuthKeyType(keyType),
+					KeyName: settingSpec.AuthKeyName(keyName),
+				},
+			)
+			if err != nil || resp.Body == nil {
+				return "", err
+			}
+			return resp.Body.Secret, nil
+		}),
 	)
 	if err != nil {
 		return errors.Join(err, errors.New("invalid default provider"))
@@ -91,6 +139,14 @@ func InitAggregrateWrapper(
 	agg.completionCancels = map[string]context.CancelFunc{}
 	agg.preCanceled = map[string]time.Time{}
 
+	if migrate {
+		renames, err := migrateReservedProviderNames(context.Background(), agg.modelPresetStore, agg.settingStore)
+		if err != nil {
+			appLogger.Error("couldn't migrate reserved provider names", "error", err)
+		}
+		agg.providerRenames = renames
+	}
+
 	err = initProviderSetUsingSettingsAndPresets(
 		context.Background(),
 		agg.modelPresetStore,
@@ -101,16 +157,95 @@ func InitAggregrateWrapper(
 		return err
 	}
 
+	agg.modelPresetStore.SetProviderTransport(agg.providersetAPI.HTTPTransport)
+	// A presets file edited or synced outside the app changes providers
+	// without going through the wrappers.
+	agg.modelPresetStore.Subscribe(func(ev modelpresetSpec.PresetChangeEvent) {
+		if ev.Kind != modelpresetSpec.PresetChangeReloaded {
+			return
+		}
+		if err := agg.resyncProviders(context.Background()); err != nil {
+			appLogger.Error("couldn't resync providers after the presets file changed", "error", err)
+		}
+	})
+
+	agg.modelPresetStore.SetProviderPresetPurgeHandler(
+		func(ctx context.Context, name inferenceSpec.ProviderName) error {
+			_, err := agg.settingStore.DeleteAuthKey(ctx, &settingSpec.DeleteAuthKeyRequest{
+				Type:    settingSpec.AuthKeyTypeProvider,
+				KeyName: settingSpec.AuthKeyName(name),
+			})
+			return err
+		},
+	)
+
 	agg.settingStore.SetDebugSettingsApplier(func(_ context.Context, cfg settingSpec.DebugSettings) error {
 		return applyDebugSettings(agg.providersetAPI, cfg)
 	})
 	if err := agg.settingStore.ApplyCurrentDebugSettings(context.Background(), true); err != nil {
-		slog.Error("couldn't apply persisted debug settings", "error", err)
+		appLogger.Error("couldn't apply persisted debug settings", "error", err)
 		return err
 	}
+
+	agg.settingStore.SetNetworkSettingsApplier(func(_ context.Context, cfg settingSpec.NetworkSettings) error {
+		return applyNetworkSettings(agg.providersetAPI, cfg)
+	})
+	if err := agg.settingStore.ApplyCurrentNetworkSettings(context.Background(), false); err != nil {
+		// Providers keep the default transport; the settings can be fixed in the UI.
+		appLogger.Error("couldn't apply persisted network settings", "error", err)
+	}
 	return nil
 }
 
+// migrateReservedProviderNames renames user providers that collide with the
+// reserved namespace and moves their auth keys to the new names. It returns
+// the renames, old name to new name, even when moving a key failed.
+func migrateReservedProviderNames(
+	ctx context.Context,
+	mps *modelpresetStore.ModelPresetStore,
+	ss *settingStore.SettingStore,
+) (map[string]string, error) {
+	renames, err := mps.MigrateReservedProviderNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(renames))
+	ctx = settingStore.WithAuthKeyCaller(ctx, settingSpec.AuthKeyCallerMigration)
+	var errs []error
+	for from, to := range renames {
+		names[string(from)] = string(to)
+		secResp, err := ss.GetAuthKey(ctx, &settingSpec.GetAuthKeyRequest{
+			Type:    settingSpec.AuthKeyTypeProvider,
+			KeyName: settingSpec.AuthKeyName(from),
+		})
+		if errors.Is(err, settingSpec.ErrAuthKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if secResp.Body == nil || secResp.Body.Secret == "" {
+			continue
+		}
+		if _, err := ss.SetAuthKey(ctx, &settingSpec.SetAuthKeyRequest{
+			Type:    settingSpec.AuthKeyTypeProvider,
+			KeyName: settingSpec.AuthKeyName(to),
+			Body:    &settingSpec.SetAuthKeyRequestBody{Secret: secResp.Body.Secret},
+		}); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, err := ss.DeleteAuthKey(ctx, &settingSpec.DeleteAuthKeyRequest{
+			Type:    settingSpec.AuthKeyTypeProvider,
+			KeyName: settingSpec.AuthKeyName(from),
+		}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return names, errors.Join(errs...)
+}
+
 func SetWrappedProviderAppContext(w *AggregrateWrapper, ctx context.Context) {
 	w.appContext = ctx
 }
@@ -137,18 +272,24 @@ func (w *AggregrateWrapper) PostProviderPreset(
 				Provider: inferenceSpec.ProviderName(string(req.ProviderName)),
 			},
 		)
+		modelpresetStore.ApplyProviderSDKDefaults(req.Body)
+		body := &inferencewrapperSpec.AddProviderRequestBody{
+			SDKType:                  req.Body.SDKType,
+			Origin:                   req.Body.Origin,
+			ChatCompletionPathPrefix: req.Body.ChatCompletionPathPrefix,
+			APIKeyHeaderKey:          req.Body.APIKeyHeaderKey,
+			DefaultHeaders:           req.Body.DefaultHeaders,
+			Azure:                    req.Body.Azure,
+		}
+		if err := resolveProviderTLS(context.Background(), w.settingStore, body, req.Body.TLS); err != nil {
+			return nil, err
+		}
 		// Then try to add in provider apis, need to skip adding to store if it cannot be added.
 		if _, err := w.providersetAPI.AddProvider(
 			context.Background(),
 			&inferencewrapperSpec.AddProviderRequest{
 				Provider: inferenceSpec.ProviderName(string(req.ProviderName)),
-				Body: &inferencewrapperSpec.AddProviderRequestBody{
-					SDKType:                  req.Body.SDKType,
-					Origin:                   req.Body.Origin,
-					ChatCompletionPathPrefix: req.Body.ChatCompletionPathPrefix,
-					APIKeyHeaderKey:          req.Body.APIKeyHeaderKey,
-					DefaultHeaders:           req.Body.DefaultHeaders,
-				},
+				Body:     body,
 			}); err != nil {
 			return nil, err
 		}
@@ -164,12 +305,9 @@ func (w *AggregrateWrapper) DeleteProviderPreset(
 	req *modelpresetSpec.DeleteProviderPresetRequest,
 ) (*modelpresetSpec.DeleteProviderPresetResponse, error) {
 	return middleware.WithRecoveryResp(func() (*modelpresetSpec.DeleteProviderPresetResponse, error) {
-		_, err := w.DeleteAuthKey(
-			&settingSpec.DeleteAuthKeyRequest{
-				Type:    settingSpec.AuthKeyTypeProvider,
-				KeyName: settingSpec.AuthKeyName(req.ProviderName),
-			},
-		)
+		// The preset is only soft-deleted; its auth key is kept so that an undelete
+		// can restore the provider and is dropped once the preset is purged.
+		resp, err := w.modelPresetStore.DeleteProviderPreset(context.Background(), req)
 		if err != nil {
 			return nil, err
 		}
@@ -179,14 +317,308 @@ func (w *AggregrateWrapper) DeleteProviderPreset(
 				Provider: inferenceSpec.ProviderName(string(req.ProviderName)),
 			},
 		)
-		resp, err := w.modelPresetStore.DeleteProviderPreset(context.Background(), req)
+		return resp, nil
+	})
+}
+
+func (w *AggregrateWrapper) UndeleteProviderPreset(
+	req *modelpresetSpec.UndeleteProviderPresetRequest,
+) (*modelpresetSpec.UndeleteProviderPresetResponse, error) {
+	return middleware.WithRecoveryResp(func() (*modelpresetSpec.UndeleteProviderPresetResponse, error) {
+		ctx := context.Background()
+		resp, err := w.modelPresetStore.UndeleteProviderPreset(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		pp, err := w.modelPresetStore.GetProviderPreset(ctx, &modelpresetSpec.GetProviderPresetRequest{
+			ProviderName:    req.ProviderName,
+			IncludeDisabled: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		secrets, err := getAllProviderSecrets(ctx, w.settingStore)
+		if err != nil {
+			return nil, err
+		}
+		providers := []modelpresetSpec.ProviderPreset{*pp.Body}
+		if err := initProviders(ctx, w.providersetAPI, w.settingStore, providers, secrets); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	})
+}
+
+// ListSoftDeletedResponse is the trash: every soft-deleted item that can still
+// be restored, grouped by kind as each store reports it.
+type ListSoftDeletedResponse struct {
+	SkillBundles    []skillstoreSpec.SoftDeletedSkillBundle     `json:"skillBundles"`
+	Skills          []skillstoreSpec.SoftDeletedSkill           `json:"skills"`
+	ProviderPresets []modelpresetSpec.SoftDeletedProviderPreset `json:"providerPresets"`
+}
+
+// ListSoftDeleted lists the soft-deleted skill bundles, skills and provider
+// presets pending purge.
+func (w *AggregrateWrapper) ListSoftDeleted() (*ListSoftDeletedResponse, error) {
+	return middleware.WithRecoveryResp(func() (*ListSoftDeletedResponse, error) {
+		ctx := context.Background()
+		skills, err := w.skillStore.ListSoftDeleted(ctx, &skillstoreSpec.ListSoftDeletedRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("list deleted skills: %w", err)
+		}
+		presets, err := w.modelPresetStore.ListSoftDeleted(ctx, &modelpresetSpec.ListSoftDeletedRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("list deleted provider presets: %w", err)
+		}
+		return &ListSoftDeletedResponse{
+			SkillBundles:    skills.Body.SkillBundles,
+			Skills:          skills.Body.Skills,
+			ProviderPresets: presets.Body.ProviderPresets,
+		}, nil
+	})
+}
+
+// DiscoverProviderModels lists the provider's models using its stored auth key.
+func (w *AggregrateWrapper) DiscoverProviderModels(
+	req *modelpresetSpec.DiscoverProviderModelsRequest,
+) (*modelpresetSpec.DiscoverProviderModelsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*modelpresetSpec.DiscoverProviderModelsResponse, error) {
+		if req == nil {
+			return nil, errors.New("invalid request")
+		}
+		ctx := context.Background()
+		body := modelpresetSpec.DiscoverProviderModelsRequestBody{}
+		if req.Body != nil {
+			body = *req.Body
+		}
+		secResp, err := w.settingStore.GetAuthKey(
+			settingStore.WithAuthKeyCaller(ctx, settingSpec.AuthKeyCallerModelDiscovery),
+			&settingSpec.GetAuthKeyRequest{
+				Type:    settingSpec.AuthKeyTypeProvider,
+				KeyName: settingSpec.AuthKeyName(req.ProviderName),
+			},
+		)
+		if err != nil && !errors.Is(err, settingSpec.ErrAuthKeyNotFound) {
+			return nil, err
+		}
+		body.APIKey = ""
+		if err == nil && secResp.Body != nil {
+			body.APIKey = secResp.Body.Secret
+		}
+		return w.modelPresetStore.DiscoverProviderModels(ctx, &modelpresetSpec.DiscoverProviderModelsRequest{
+			ProviderName: req.ProviderName,
+			Body:         &body,
+		})
+	})
+}
+
+// GenerateEmbeddings embeds the inputs with an embedding preset using the
+// stored auth key of its provider. The request waits for the provider's rate
+// limits and its tokens are recorded as usage.
+func (w *AggregrateWrapper) GenerateEmbeddings(
+	req *modelpresetSpec.GenerateEmbeddingsRequest,
+) (*modelpresetSpec.GenerateEmbeddingsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*modelpresetSpec.GenerateEmbeddingsResponse, error) {
+		if req == nil || req.Body == nil {
+			return nil, errors.New("invalid request")
+		}
+		return w.generateEmbeddings(context.Background(), req)
+	})
+}
+
+// embed adapts generateEmbeddings to the knowledge base embedder.
+func (w *AggregrateWrapper) embed(
+	ctx context.Context,
+	presetID modelpresetSpec.EmbeddingPresetID,
+	inputs []string,
+) ([][]float32, error) {
+	resp, err := w.generateEmbeddings(ctx, &modelpresetSpec.GenerateEmbeddingsRequest{
+		EmbeddingPresetID: presetID,
+		Body:              &modelpresetSpec.GenerateEmbeddingsRequestBody{Inputs: inputs},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body.Embeddings, nil
+}
+
+func (w *AggregrateWrapper) generateEmbeddings(
+	ctx context.Context,
+	req *modelpresetSpec.GenerateEmbeddingsRequest,
+) (*modelpresetSpec.GenerateEmbeddingsResponse, error) {
+	epResp, err := w.modelPresetStore.GetEmbeddingPreset(ctx, &modelpresetSpec.GetEmbeddingPresetRequest{
+		EmbeddingPresetID: req.EmbeddingPresetID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	secResp, err := w.settingStore.GetAuthKey(
+		settingStore.WithAuthKeyCaller(ctx, settingSpec.AuthKeyCallerEmbeddings),
+		&settingSpec.GetAuthKeyRequest{
+			Type:    settingSpec.AuthKeyTypeProvider,
+			KeyName: settingSpec.AuthKeyName(epResp.Body.ProviderName),
+		},
+	)
+	if err != nil && !errors.Is(err, settingSpec.ErrAuthKeyNotFound) {
+		return nil, err
+	}
+	body := *req.Body
+	body.APIKey = ""
+	if err == nil && secResp.Body != nil {
+		body.APIKey = secResp.Body.Secret
+	}
+	embReq := &modelpresetSpec.GenerateEmbeddingsRequest{
+		EmbeddingPresetID: req.EmbeddingPresetID,
+		Body:              &body,
+	}
+	var resp *modelpresetSpec.GenerateEmbeddingsResponse
+	err = w.providersetAPI.GuardEmbeddings(ctx, epResp.Body.ProviderName,
+		inferenceSpec.ModelName(epResp.Body.ModelName), body.Inputs,
+		func(ctx context.Context) (int, error) {
+			var err error
+			resp, err = w.modelPresetStore.GenerateEmbeddings(ctx, embReq)
+			if err != nil {
+				return 0, err
+			}
+			return resp.Body.InputTokens, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SwitchProfile activates a settings profile and makes its model preset the
+// default one. Either all of it applies or nothing does: the model preset
+// defaults are restored when the settings part fails.
+func (w *AggregrateWrapper) SwitchProfile(
+	req *settingSpec.SwitchProfileRequest,
+) (*settingSpec.SwitchProfileResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.SwitchProfileResponse, error) {
+		if req == nil {
+			return nil, errors.New("invalid request")
+		}
+		ctx := context.Background()
+		if req.ProfileID == "" {
+			return w.settingStore.SwitchProfile(ctx, req)
+		}
+		pResp, err := w.settingStore.GetProfile(ctx, &settingSpec.GetProfileRequest{ProfileID: req.ProfileID})
+		if err != nil {
+			return nil, err
+		}
+		restore, err := w.applyProfileModelPreset(ctx, pResp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %w", req.ProfileID, err)
+		}
+		resp, err := w.settingStore.SwitchProfile(ctx, req)
 		if err != nil {
+			if rerr := restore(ctx); rerr != nil {
+				err = errors.Join(err, fmt.Errorf("restore model preset defaults: %w", rerr))
+			}
 			return nil, err
 		}
 		return resp, nil
 	})
 }
 
+// applyProfileModelPreset makes the profile's model preset the default of its
+// provider and its provider the default provider. The returned func puts the
+// previous defaults back.
+func (w *AggregrateWrapper) applyProfileModelPreset(
+	ctx context.Context,
+	p *settingSpec.Profile,
+) (restore func(context.Context) error, err error) {
+	noop := func(context.Context) error { return nil }
+	ref := p.ModelPresetRef
+	if ref == nil {
+		return noop, nil
+	}
+	if _, err := w.modelPresetStore.GetModelPreset(ctx, &modelpresetSpec.GetModelPresetRequest{
+		ProviderName:  ref.ProviderName,
+		ModelPresetID: ref.ModelPresetID,
+	}); err != nil {
+		return nil, err
+	}
+	prevProvider, err := w.modelPresetStore.GetDefaultProvider(ctx, &modelpresetSpec.GetDefaultProviderRequest{})
+	if err != nil {
+		return nil, err
+	}
+	prevPreset, err := w.modelPresetStore.GetProviderPreset(ctx, &modelpresetSpec.GetProviderPresetRequest{
+		ProviderName: ref.ProviderName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	setDefaultModel := func(ctx context.Context, id modelpresetSpec.ModelPresetID) error {
+		if id == "" {
+			return nil
+		}
+		_, err := w.modelPresetStore.PatchProviderPreset(ctx, &modelpresetSpec.PatchProviderPresetRequest{
+			ProviderName: ref.ProviderName,
+			Body:         &modelpresetSpec.PatchProviderPresetRequestBody{DefaultModelPresetID: &id},
+		})
+		return err
+	}
+	setDefaultProvider := func(ctx context.Context, name inferenceSpec.ProviderName) error {
+		if name == "" {
+			return nil
+		}
+		_, err := w.modelPresetStore.PatchDefaultProvider(ctx, &modelpresetSpec.PatchDefaultProviderRequest{
+			Body: &modelpresetSpec.PatchDefaultProviderRequestBody{DefaultProvider: name},
+		})
+		return err
+	}
+
+	if err := setDefaultModel(ctx, ref.ModelPresetID); err != nil {
+		return nil, err
+	}
+	restoreModel := func(ctx context.Context) error {
+		return setDefaultModel(ctx, prevPreset.Body.DefaultModelPresetID)
+	}
+	if err := setDefaultProvider(ctx, ref.ProviderName); err != nil {
+		if rerr := restoreModel(ctx); rerr != nil {
+			err = errors.Join(err, rerr)
+		}
+		return nil, err
+	}
+	return func(ctx context.Context) error {
+		return errors.Join(
+			setDefaultProvider(ctx, prevProvider.Body.DefaultProvider),
+			restoreModel(ctx),
+		)
+	}, nil
+}
+
+// restrictToProfileTools drops the tool choices the active profile does not
+// allow. A profile without a tool allowlist allows every tool.
+func (w *AggregrateWrapper) restrictToProfileTools(
+	ctx context.Context,
+	body *inferencewrapperSpec.CompletionRequestBody,
+) (*inferencewrapperSpec.CompletionRequestBody, error) {
+	if body == nil || len(body.ToolStoreChoices) == 0 {
+		return body, nil
+	}
+	p, err := w.settingStore.ActiveProfile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil || len(p.ToolAllowlist) == 0 {
+		return body, nil
+	}
+	allowed := func(c toolSpec.ToolStoreChoice) bool {
+		return slices.ContainsFunc(p.ToolAllowlist, func(ref toolSpec.ToolRef) bool {
+			return ref.BundleID == c.BundleID && ref.ToolSlug == c.ToolSlug &&
+				(ref.ToolVersion == "" || string(ref.ToolVersion) == c.ToolVersion)
+		})
+	}
+	out := *body
+	out.ToolStoreChoices = slices.DeleteFunc(
+		slices.Clone(body.ToolStoreChoices),
+		func(c toolSpec.ToolStoreChoice) bool { return !allowed(c) },
+	)
+	return &out, nil
+}
+
 func (w *AggregrateWrapper) SetAuthKey(
 	req *settingSpec.SetAuthKeyRequest,
 ) (*settingSpec.SetAuthKeyResponse, error) {
@@ -207,6 +639,12 @@ func (w *AggregrateWrapper) SetAuthKey(
 		if err != nil {
 			return nil, err
 		}
+		if req.Type == settingSpec.AuthKeyTypeProviderTLS {
+			// Client certificates are loaded when a provider is added.
+			if err := w.resyncProvidersUsingTLSKey(context.Background(), req.KeyName); err != nil {
+				return nil, err
+			}
+		}
 		return resp, nil
 	})
 }
@@ -242,48 +680,20 @@ func (w *AggregrateWrapper) FetchCompletion(
 	requestID string,
 ) (*inferencewrapperSpec.CompletionResponse, error) {
 	return middleware.WithRecoveryResp(func() (*inferencewrapperSpec.CompletionResponse, error) {
-		if requestID == "" {
-			return nil, errors.New("requestID is empty")
-		}
-		if w.appContext == nil {
-			return nil, errors.New("appContext is not set (call SetWrappedProviderAppContext during startup)")
-		}
-		if w.completionCancels == nil {
-			w.completionCancels = map[string]context.CancelFunc{}
-		}
-		if w.preCanceled == nil {
-			w.preCanceled = map[string]time.Time{}
+		ctx, done, err := w.beginCompletion(requestID)
+		if err != nil {
+			return nil, err
 		}
+		defer done()
 
-		ctx, cancel := context.WithCancel(w.appContext)
-		defer cancel()
-
-		w.completionCancelMux.Lock()
-		// If a cancel arrived before the fetch registered, honor it.
-		if _, ok := w.preCanceled[requestID]; ok {
-			delete(w.preCanceled, requestID)
-			w.completionCancelMux.Unlock()
-			return nil, context.Canceled
-		}
-		// Protect against requestID reuse while in-flight.
-		if _, exists := w.completionCancels[requestID]; exists {
-			w.completionCancelMux.Unlock()
-			return nil, errors.New("duplicate requestID: a completion with this id is already in flight")
+		body, err := w.restrictToProfileTools(ctx, completionData)
+		if err != nil {
+			return nil, err
 		}
-
-		w.completionCancels[requestID] = cancel
-		w.completionCancelMux.Unlock()
-
-		defer func() {
-			w.completionCancelMux.Lock()
-			delete(w.completionCancels, requestID)
-			w.completionCancelMux.Unlock()
-		}()
-
 		req := &inferencewrapperSpec.CompletionRequest{
 			Provider:      inferenceSpec.ProviderName(provider),
 			ModelPresetID: modelpresetSpec.ModelPresetID(modelPresetID),
-			Body:          completionData,
+			Body:          body,
 		}
 
 		if textCallbackID != "" {
@@ -310,30 +720,180 @@ func (w *AggregrateWrapper) FetchCompletion(
 			ctx,
 			req,
 		)
+		return finishCompletion(provider, resp, err)
+	})
+}
+
+// StreamCompletion starts a completion and returns once it is registered.
+// Output arrives as completionStreamEvent events tagged with requestID, ending
+// with a done or error event. CancelCompletion(requestID) aborts the stream.
+func (w *AggregrateWrapper) StreamCompletion(
+	provider string,
+	modelPresetID string,
+	completionData *inferencewrapperSpec.CompletionRequestBody,
+	requestID string,
+) error {
+	_, err := middleware.WithRecoveryResp(func() (struct{}, error) {
+		ctx, done, err := w.beginCompletion(requestID)
 		if err != nil {
-			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-				// Expected lifecycle event; return partial resp if present without noisy error logging.
-				if resp != nil {
-					return resp, nil
+			return struct{}{}, err
+		}
+
+		var seq atomic.Int64
+		emit := func(ev inferencewrapperSpec.CompletionStreamEvent) {
+			ev.RequestID = requestID
+			ev.Seq = seq.Add(1)
+			//nolint:contextcheck // Need to pass app context here and not new context.
+			runtime.EventsEmit(w.appContext, completionStreamEvent, ev)
+		}
+		emitDelta := func(kind inferencewrapperSpec.CompletionStreamEventKind) func(string) error {
+			return func(text string) error {
+				if err := ctx.Err(); err != nil {
+					return err
 				}
-				return nil, err
+				emit(inferencewrapperSpec.CompletionStreamEvent{Kind: kind, Text: text})
+				return nil
 			}
-			// If we have a partial response, attach error info there and return it.
-			if resp != nil && resp.Body != nil && resp.Body.InferenceResponse != nil {
-				if resp.Body.InferenceResponse.Error == nil {
-					resp.Body.InferenceResponse.Error = &inferenceSpec.Error{
-						Message: err.Error(),
-					}
+		}
+
+		body, err := w.restrictToProfileTools(ctx, completionData)
+		if err != nil {
+			done()
+			return struct{}{}, err
+		}
+		req := &inferencewrapperSpec.CompletionRequest{
+			Provider:         inferenceSpec.ProviderName(provider),
+			ModelPresetID:    modelpresetSpec.ModelPresetID(modelPresetID),
+			Body:             body,
+			OnStreamText:     emitDelta(inferencewrapperSpec.CompletionStreamEventText),
+			OnStreamThinking: emitDelta(inferencewrapperSpec.CompletionStreamEventThinking),
+		}
+
+		go func() {
+			defer done()
+			defer func() {
+				if r := recover(); r != nil {
+					appLogger.Error("panic recovered",
+						slog.Any("panic", r),
+						slog.String("stacktrace", string(debug.Stack())),
+					)
+					emit(inferencewrapperSpec.CompletionStreamEvent{
+						Kind:  inferencewrapperSpec.CompletionStreamEventError,
+						Error: fmt.Sprintf("panic recovered: %v", r),
+					})
 				}
-				// Log, but do not propagate Go error so Wails resolves the Promise.
-				slog.Error("fetchCompletion failed", "provider", provider, "err", err)
-				return resp, nil
+			}()
+
+			resp, err := w.providersetAPI.FetchCompletion(ctx, req)
+			resp, err = finishCompletion(provider, resp, err)
+			ev := inferencewrapperSpec.CompletionStreamEvent{
+				Kind:     inferencewrapperSpec.CompletionStreamEventDone,
+				Canceled: ctx.Err() != nil,
 			}
-			// No response at all => infrastructure error.
-			return nil, err
-		}
+			if resp != nil {
+				ev.Response = resp.Body
+			}
+			if err != nil {
+				ev.Kind = inferencewrapperSpec.CompletionStreamEventError
+				ev.Error = err.Error()
+			}
+			emit(ev)
+		}()
+		return struct{}{}, nil
+	})
+	return err
+}
+
+// beginCompletion registers a cancelable context for requestID. done must be
+// called once the completion has finished.
+func (w *AggregrateWrapper) beginCompletion(requestID string) (ctx context.Context, done func(), err error) {
+	if requestID == "" {
+		return nil, nil, errors.New("requestID is empty")
+	}
+	if w.appContext == nil {
+		return nil, nil, errors.New("appContext is not set (call SetWrappedProviderAppContext during startup)")
+	}
+
+	w.completionCancelMux.Lock()
+	defer w.completionCancelMux.Unlock()
+	if w.completionCancels == nil {
+		w.completionCancels = map[string]context.CancelFunc{}
+	}
+	if w.preCanceled == nil {
+		w.preCanceled = map[string]time.Time{}
+	}
+	// If a cancel arrived before the fetch registered, honor it.
+	if _, ok := w.preCanceled[requestID]; ok {
+		delete(w.preCanceled, requestID)
+		return nil, nil, context.Canceled
+	}
+	// Protect against requestID reuse while in-flight.
+	if _, exists := w.completionCancels[requestID]; exists {
+		return nil, nil, errors.New("duplicate requestID: a completion with this id is already in flight")
+	}
+
+	ctx, cancel := context.WithCancel(w.appContext)
+	w.completionCancels[requestID] = cancel
+	return ctx, func() {
+		cancel()
+		w.completionCancelMux.Lock()
+		delete(w.completionCancels, requestID)
+		w.completionCancelMux.Unlock()
+	}, nil
+}
 
+// finishCompletion turns provider errors that come with a partial response
+// into an error on the response, so the frontend still gets the output.
+func finishCompletion(
+	provider string,
+	resp *inferencewrapperSpec.CompletionResponse,
+	err error,
+) (*inferencewrapperSpec.CompletionResponse, error) {
+	if err == nil {
+		return resp, nil
+	}
+	// A refused budget check is a normal outcome the UI prompts on, so it is
+	// returned as a typed field rather than an error string.
+	var exceeded *usageSpec.BudgetExceededError
+	if errors.As(err, &exceeded) {
+		return &inferencewrapperSpec.CompletionResponse{
+			Body: &inferencewrapperSpec.CompletionResponseBody{BudgetExceeded: exceeded},
+		}, nil
+	}
+	var unpriced *usageSpec.BudgetUnpricedError
+	if errors.As(err, &unpriced) {
+		return &inferencewrapperSpec.CompletionResponse{
+			Body: &inferencewrapperSpec.CompletionResponseBody{BudgetUnpriced: unpriced},
+		}, nil
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// Expected lifecycle event; return partial resp if present without noisy error logging.
+		if resp != nil {
+			return resp, nil
+		}
+		return nil, err
+	}
+	// If we have a partial response, attach error info there and return it.
+	if resp != nil && resp.Body != nil && resp.Body.InferenceResponse != nil {
+		if resp.Body.InferenceResponse.Error == nil {
+			resp.Body.InferenceResponse.Error = &inferenceSpec.Error{
+				Message: err.Error(),
+			}
+		}
+		// Log, but do not propagate Go error so Wails resolves the Promise.
+		appLogger.Error("fetchCompletion failed", "provider", provider, "err", err)
 		return resp, nil
+	}
+	// No response at all => infrastructure error.
+	return nil, err
+}
+
+// GetProviderQueueStatus reports the rate limit queue of each provider.
+func (w *AggregrateWrapper) GetProviderQueueStatus(
+	req *inferencewrapperSpec.GetProviderQueueStatusRequest,
+) (*inferencewrapperSpec.GetProviderQueueStatusResponse, error) {
+	return middleware.WithRecoveryResp(func() (*inferencewrapperSpec.GetProviderQueueStatusResponse, error) {
+		return w.providersetAPI.GetProviderQueueStatus(context.Background(), req)
 	})
 }
 
@@ -342,7 +902,7 @@ func (w *AggregrateWrapper) CancelCompletion(id string) error {
 	defer func() {
 		if r := recover(); r != nil {
 			// Log the panic plus stack trace.
-			slog.Error("panic recovered",
+			appLogger.Error("panic recovered",
 				slog.Any("panic", r),
 				slog.String("stacktrace", string(debug.Stack())),
 			)
@@ -363,7 +923,7 @@ func (w *AggregrateWrapper) CancelCompletion(id string) error {
 		return err
 	}
 
-	// Cancel arrived before FetchCompletion registered the cancel func.
+	// Cancel arrived before the completion registered the cancel func.
 	w.preCanceled[id] = time.Now().UTC()
 
 	// Best-effort pruning to avoid unbounded growth.
@@ -391,11 +951,11 @@ func initProviderSetUsingSettingsAndPresets(
 		return err
 	}
 
-	if err := initProviders(ctx, p, allProviders, keySecrets); err != nil {
+	if err := initProviders(ctx, p, s, allProviders, keySecrets); err != nil {
 		return err
 	}
 
-	slog.Info("initProviderSetUsingSettingsAndPresets completed",
+	appLogger.Info("initProviderSetUsingSettingsAndPresets completed",
 		"authKeys", len(keySecrets))
 
 	return nil
@@ -452,6 +1012,7 @@ func getAllProviderSecrets(
 		return nil, errors.New("GetSettings: empty response body")
 	}
 
+	ctx = settingStore.WithAuthKeyCaller(ctx, settingSpec.AuthKeyCallerProviders)
 	secrets := make(map[string]string, len(resp.Body.AuthKeys))
 	for _, meta := range resp.Body.AuthKeys {
 		if meta.Type != settingSpec.AuthKeyTypeProvider {
@@ -471,12 +1032,158 @@ func getAllProviderSecrets(
 	return secrets, nil
 }
 
+// resolveProviderTLS reads the client certificate and key named by c from
+// the setting store into body.
+func resolveProviderTLS(
+	ctx context.Context,
+	s *settingStore.SettingStore,
+	body *inferencewrapperSpec.AddProviderRequestBody,
+	c *modelpresetSpec.ProviderTLSConfig,
+) error {
+	if c == nil {
+		return nil
+	}
+	out := &inferencewrapperSpec.ProviderTLS{CABundlePath: c.CABundlePath}
+	if c.ClientCertKeyName != "" {
+		ctx = settingStore.WithAuthKeyCaller(ctx, settingSpec.AuthKeyCallerProviders)
+		for _, key := range []struct {
+			name string
+			dst  *string
+		}{
+			{c.ClientCertKeyName, &out.ClientCertPEM},
+			{c.ClientKeyKeyName, &out.ClientKeyPEM},
+		} {
+			resp, err := s.GetAuthKey(ctx, &settingSpec.GetAuthKeyRequest{
+				Type:    settingSpec.AuthKeyTypeProviderTLS,
+				KeyName: settingSpec.AuthKeyName(key.name),
+			})
+			if err != nil {
+				return fmt.Errorf("auth key %s/%s: %w", settingSpec.AuthKeyTypeProviderTLS, key.name, err)
+			}
+			if resp.Body != nil {
+				*key.dst = resp.Body.Secret
+			}
+		}
+	}
+	body.TLS = out
+	return nil
+}
+
+func addProviderRequestBody(pp modelpresetSpec.ProviderPreset) *inferencewrapperSpec.AddProviderRequestBody {
+	return &inferencewrapperSpec.AddProviderRequestBody{
+		SDKType:                  pp.SDKType,
+		Origin:                   pp.Origin,
+		ChatCompletionPathPrefix: pp.ChatCompletionPathPrefix,
+		APIKeyHeaderKey:          pp.APIKeyHeaderKey,
+		DefaultHeaders:           pp.DefaultHeaders,
+		Azure:                    pp.Azure,
+	}
+}
+
+// resyncProvider re-adds a provider to the provider set from its stored
+// preset, so connection settings changed since it was added take effect. The
+// live provider is left alone when the new settings cannot be applied.
+func (w *AggregrateWrapper) resyncProvider(ctx context.Context, name inferenceSpec.ProviderName) error {
+	ppResp, err := w.modelPresetStore.GetProviderPreset(ctx, &modelpresetSpec.GetProviderPresetRequest{
+		ProviderName:    name,
+		IncludeDisabled: true,
+	})
+	if err != nil {
+		return err
+	}
+	pp := *ppResp.Body
+	body := addProviderRequestBody(pp)
+	if err := resolveProviderTLS(ctx, w.settingStore, body, pp.TLS); err != nil {
+		return err
+	}
+	secResp, err := w.settingStore.GetAuthKey(
+		settingStore.WithAuthKeyCaller(ctx, settingSpec.AuthKeyCallerProviders),
+		&settingSpec.GetAuthKeyRequest{
+			Type:    settingSpec.AuthKeyTypeProvider,
+			KeyName: settingSpec.AuthKeyName(name),
+		},
+	)
+	if err != nil && !errors.Is(err, settingSpec.ErrAuthKeyNotFound) {
+		return err
+	}
+	var apiKey string
+	if err == nil && secResp.Body != nil {
+		apiKey = secResp.Body.Secret
+	}
+	_, _ = w.providersetAPI.DeleteProvider(ctx, &inferencewrapperSpec.DeleteProviderRequest{Provider: name})
+	if _, err := w.providersetAPI.AddProvider(ctx, &inferencewrapperSpec.AddProviderRequest{
+		Provider: name,
+		Body:     body,
+	}); err != nil {
+		return err
+	}
+	if apiKey == "" {
+		return nil
+	}
+	_, err = w.providersetAPI.SetProviderAPIKey(ctx, &inferencewrapperSpec.SetProviderAPIKeyRequest{
+		Provider: name,
+		Body:     &inferencewrapperSpec.SetProviderAPIKeyRequestBody{APIKey: apiKey},
+	})
+	return err
+}
+
+// resyncProviders re-adds every provider preset and deletes live providers
+// whose preset is gone.
+func (w *AggregrateWrapper) resyncProviders(ctx context.Context) error {
+	providers, err := getAllProviderPresets(ctx, w.modelPresetStore)
+	if err != nil {
+		return err
+	}
+	want := make(map[inferenceSpec.ProviderName]struct{}, len(providers))
+	var errs []error
+	for _, pp := range providers {
+		want[pp.Name] = struct{}{}
+		if err := w.resyncProvider(ctx, pp.Name); err != nil {
+			errs = append(errs, fmt.Errorf("provider %s: %w", pp.Name, err))
+		}
+	}
+	for _, name := range w.providersetAPI.ProviderNames() {
+		if _, ok := want[name]; ok {
+			continue
+		}
+		if _, err := w.providersetAPI.DeleteProvider(
+			ctx, &inferencewrapperSpec.DeleteProviderRequest{Provider: name},
+		); err != nil {
+			errs = append(errs, fmt.Errorf("provider %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resyncProvidersUsingTLSKey re-adds the providers whose TLS settings name
+// the provider TLS auth key.
+func (w *AggregrateWrapper) resyncProvidersUsingTLSKey(ctx context.Context, keyName settingSpec.AuthKeyName) error {
+	providers, err := getAllProviderPresets(ctx, w.modelPresetStore)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, pp := range providers {
+		if pp.TLS == nil {
+			continue
+		}
+		if name := string(keyName); pp.TLS.ClientCertKeyName != name && pp.TLS.ClientKeyKeyName != name {
+			continue
+		}
+		if err := w.resyncProvider(ctx, pp.Name); err != nil {
+			errs = append(errs, fmt.Errorf("provider %s: %w", pp.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // BuildAddProviderRequests merges presets + secrets.
 // Only providers that have a (valid) preset are considered.  If a matching
 // secret exists its value is copied into the request.
 func initProviders(
 	ctx context.Context,
 	providerAPI *inferencewrapper.ProviderSetAPI,
+	ss *settingStore.SettingStore,
 	providers []modelpresetSpec.ProviderPreset,
 	secrets map[string]string,
 ) error {
@@ -484,22 +1191,26 @@ func initProviders(
 	providersWithAPIKey := 0
 	for _, pp := range providers {
 		if pp.Name == "" || pp.Origin == "" {
-			slog.Warn("skipping provider with invalid preset", "name", pp.Name)
+			appLogger.Warn("skipping provider with invalid preset", "name", pp.Name)
 			continue
 		}
 
-		body := &inferencewrapperSpec.AddProviderRequestBody{
-			SDKType:                  pp.SDKType,
-			Origin:                   pp.Origin,
-			ChatCompletionPathPrefix: pp.ChatCompletionPathPrefix,
-			APIKeyHeaderKey:          pp.APIKeyHeaderKey,
-			DefaultHeaders:           pp.DefaultHeaders,
+		body := addProviderRequestBody(pp)
+		// A provider whose certificates cannot be loaded is left out rather
+		// than failing startup or connecting without them.
+		if err := resolveProviderTLS(ctx, ss, body, pp.TLS); err != nil {
+			appLogger.Warn("skipping provider with unusable tls settings", "name", pp.Name, "err", err)
+			continue
 		}
 		r := &inferencewrapperSpec.AddProviderRequest{
 			Provider: inferenceSpec.ProviderName(string(pp.Name)),
 			Body:     body,
 		}
 		if _, err := providerAPI.AddProvider(ctx, r); err != nil {
+			if body.TLS != nil {
+				appLogger.Warn("skipping provider with unusable tls settings", "name", pp.Name, "err", err)
+				continue
+			}
 			return fmt.Errorf("add provider failed. name: %s, err: %w ", pp.Name, err)
 		}
 		providersAdded++
@@ -518,10 +1229,10 @@ func initProviders(
 	}
 
 	if providersAdded == 0 {
-		slog.Warn("no providers found - nothing to initialize")
+		appLogger.Warn("no providers found - nothing to initialize")
 	}
 	if providersWithAPIKey == 0 {
-		slog.Warn("no providers with APIKey")
+		appLogger.Warn("no providers with APIKey")
 	}
 
 	return nil
@@ -529,6 +1240,7 @@ func initProviders(
 
 func applyDebugSettings(providerSet *inferencewrapper.ProviderSetAPI, cfg settingSpec.DebugSettings) error {
 	appSlogLevelVar.Set(toSlogLevel(cfg.LogLevel))
+	appLogHandler.SetConfig(logConfigFromDebugSettings(cfg))
 	if providerSet != nil {
 		clone := providerSet.GetDebugConfig()
 		if clone != nil {
@@ -538,11 +1250,40 @@ func applyDebugSettings(providerSet *inferencewrapper.ProviderSetAPI, cfg settin
 		}
 	}
 
-	slog.Info(
+	appLogger.Info(
 		"applied debug settings",
 		"logLLMReqResp", cfg.LogLLMReqResp,
 		"disableContentStripping", cfg.DisableContentStripping,
 		"logLevel", cfg.LogLevel,
+		"logFormat", cfg.LogFormat,
+		"moduleLogLevels", cfg.ModuleLogLevels,
+	)
+	return nil
+}
+
+func applyNetworkSettings(providerSet *inferencewrapper.ProviderSetAPI, cfg settingSpec.NetworkSettings) error {
+	if providerSet == nil {
+		return nil
+	}
+	skipVerify := make([]inferenceSpec.ProviderName, 0, len(cfg.InsecureSkipVerifyProviders))
+	for _, name := range cfg.InsecureSkipVerifyProviders {
+		skipVerify = append(skipVerify, inferenceSpec.ProviderName(name))
+	}
+	if err := providerSet.SetNetworkConfig(&inferencewrapper.NetworkConfig{
+		HTTPProxy:                   cfg.HTTPProxy,
+		HTTPSProxy:                  cfg.HTTPSProxy,
+		SOCKSProxy:                  cfg.SOCKSProxy,
+		NoProxy:                     cfg.NoProxy,
+		CABundlePath:                cfg.CABundlePath,
+		InsecureSkipVerifyProviders: skipVerify,
+	}); err != nil {
+		return err
+	}
+
+	appLogger.Info(
+		"applied network settings",
+		"caBundle", cfg.CABundlePath,
+		"insecureSkipVerifyProviders", cfg.InsecureSkipVerifyProviders,
 	)
 	return nil
 }
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+
+	inferencewrapperSpec "github.com/flexigpt/flexigpt-app/internal/inferencewrapper/spec"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	settingSpec "github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	toolSpec "github.com/flexigpt/flexigpt-app/internal/tool/spec"
+	usageSpec "github.com/flexigpt/flexigpt-app/internal/usage/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// newProfileTestWrapper opens the settings and model preset stores of a fresh
+// data directory.
+func newProfileTestWrapper(t *testing.T) *AggregrateWrapper {
+	t.Helper()
+	keyring.MockInit()
+	a := newApp(t.TempDir())
+	if err := a.openCLISettings(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.openCLIModelPresets(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.shutdown(t.Context()) })
+	return &AggregrateWrapper{settingStore: a.settingStoreAPI.store, modelPresetStore: a.modelPresetStoreAPI.store}
+}
+
+func TestSwitchProfile_AllOrNothing(t *testing.T) {
+	w := newProfileTestWrapper(t)
+	ctx := t.Context()
+
+	def, err := w.modelPresetStore.GetDefaultProvider(ctx, &modelpresetSpec.GetDefaultProviderRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pick a model preset that is not the current default.
+	list, err := w.modelPresetStore.ListProviderPresets(ctx, &modelpresetSpec.ListProviderPresetsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ref *modelpresetSpec.ModelPresetRef
+	for _, p := range list.Body.Providers {
+		if p.Name == def.Body.DefaultProvider {
+			continue
+		}
+		for id := range p.ModelPresets {
+			if id != p.DefaultModelPresetID {
+				ref = &modelpresetSpec.ModelPresetRef{ProviderName: p.Name, ModelPresetID: id}
+				break
+			}
+		}
+		if ref != nil {
+			break
+		}
+	}
+	if ref == nil {
+		t.Skip("no built-in model preset to switch to")
+	}
+
+	if _, err := w.settingStore.PutProfile(ctx, &settingSpec.PutProfileRequest{
+		ProfileID: "acme",
+		Body: &settingSpec.PutProfileRequestBody{
+			DisplayName:    "Acme",
+			ModelPresetRef: ref,
+			Preferences:    map[string]map[string]any{"editor": {"wrap": "soft"}},
+		},
+	}); err != nil {
+		t.Fatalf("PutProfile: %v", err)
+	}
+	// The preference stops validating after the profile was saved.
+	w.settingStore.RegisterPreferenceValidator("editor", func(string, json.RawMessage) error {
+		return errors.New("rejected")
+	})
+	if _, err := w.SwitchProfile(&settingSpec.SwitchProfileRequest{ProfileID: "acme"}); err == nil {
+		t.Fatal("SwitchProfile succeeded with an invalid preference")
+	}
+	after, err := w.modelPresetStore.GetDefaultProvider(ctx, &modelpresetSpec.GetDefaultProviderRequest{})
+	if err != nil || after.Body.DefaultProvider != def.Body.DefaultProvider {
+		t.Fatalf("default provider = %v, %v; want %s restored", after, err, def.Body.DefaultProvider)
+	}
+	pp, err := w.modelPresetStore.GetProviderPreset(ctx, &modelpresetSpec.GetProviderPresetRequest{
+		ProviderName: ref.ProviderName,
+	})
+	if err != nil || pp.Body.DefaultModelPresetID == ref.ModelPresetID {
+		t.Fatalf("default model preset of %s not restored: %v", ref.ProviderName, err)
+	}
+
+	w.settingStore.RegisterPreferenceValidator("editor", nil)
+	if _, err := w.SwitchProfile(&settingSpec.SwitchProfileRequest{ProfileID: "acme"}); err != nil {
+		t.Fatalf("SwitchProfile: %v", err)
+	}
+	after, err = w.modelPresetStore.GetDefaultProvider(ctx, &modelpresetSpec.GetDefaultProviderRequest{})
+	if err != nil || after.Body.DefaultProvider != ref.ProviderName {
+		t.Fatalf("default provider = %v, %v; want %s", after, err, ref.ProviderName)
+	}
+}
+
+func TestRestrictToProfileTools(t *testing.T) {
+	w := newProfileTestWrapper(t)
+	ctx := t.Context()
+	body := &inferencewrapperSpec.CompletionRequestBody{ToolStoreChoices: []toolSpec.ToolStoreChoice{
+		{BundleID: "b", ToolSlug: "read", ToolVersion: "v1"},
+		{BundleID: "b", ToolSlug: "write", ToolVersion: "v1"},
+	}}
+
+	got, err := w.restrictToProfileTools(ctx, body)
+	if err != nil || len(got.ToolStoreChoices) != 2 {
+		t.Fatalf("without a profile = %+v, %v", got, err)
+	}
+
+	if _, err := w.settingStore.PutProfile(ctx, &settingSpec.PutProfileRequest{
+		ProfileID: "readonly",
+		Body: &settingSpec.PutProfileRequestBody{
+			DisplayName:   "Read only",
+			ToolAllowlist: []toolSpec.ToolRef{{BundleID: "b", ToolSlug: "read"}},
+		},
+	}); err != nil {
+		t.Fatalf("PutProfile: %v", err)
+	}
+	_, err = w.settingStore.SwitchProfile(ctx, &settingSpec.SwitchProfileRequest{ProfileID: "readonly"})
+	if err != nil {
+		t.Fatalf("SwitchProfile: %v", err)
+	}
+	got, err = w.restrictToProfileTools(ctx, body)
+	if err != nil || len(got.ToolStoreChoices) != 1 || got.ToolStoreChoices[0].ToolSlug != "read" {
+		t.Fatalf("with allowlist = %+v, %v", got, err)
+	}
+	if len(body.ToolStoreChoices) != 2 {
+		t.Fatal("caller's request body was modified")
+	}
+}
+
+func TestFinishCompletion_BudgetRefusal(t *testing.T) {
+	exceeded := &usageSpec.BudgetExceededError{
+		ProviderName: "openai", Period: usageSpec.BudgetPeriodDay, LimitUSD: 1, SpentUSD: 2,
+	}
+	resp, err := finishCompletion("openai", nil, fmt.Errorf("completion: %w", exceeded))
+	if err != nil || resp == nil || resp.Body.BudgetExceeded != exceeded {
+		t.Fatalf("exceeded = %+v, %v", resp, err)
+	}
+
+	unpriced := &usageSpec.BudgetUnpricedError{ProviderName: "openai"}
+	resp, err = finishCompletion("openai", nil, unpriced)
+	if err != nil || resp == nil || resp.Body.BudgetUnpriced != unpriced || resp.Body.BudgetExceeded != nil {
+		t.Fatalf("unpriced = %+v, %v", resp, err)
+	}
+
+	if _, err := finishCompletion("openai", nil, errors.New("boom")); err == nil {
+		t.Fatal("other errors must still fail the call")
+	}
+}
+
+func TestResyncProviders_FollowsPresetStore(t *testing.T) {
+	keyring.MockInit()
+	a := newApp(t.TempDir())
+	if err := a.openCLIChat(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.shutdown(t.Context()) })
+	w, st := a.aggregateAPI, a.modelPresetStoreAPI.store
+	live := func(name inferenceSpec.ProviderName) bool {
+		return slices.Contains(w.providersetAPI.ProviderNames(), name)
+	}
+
+	// Changes made behind the wrappers, as by an external edit of the file.
+	const name inferenceSpec.ProviderName = "user-synced"
+	if _, err := st.PostProviderPreset(t.Context(), &modelpresetSpec.PostProviderPresetRequest{
+		ProviderName: name,
+		Body: &modelpresetSpec.PostProviderPresetRequestBody{
+			DisplayName:              "Synced",
+			SDKType:                  inferenceSpec.ProviderSDKTypeOpenAIChatCompletions,
+			Origin:                   "https://synced.example.com",
+			ChatCompletionPathPrefix: modelpresetSpec.DefaultOpenAIChatCompletionsPrefix,
+		},
+	}); err != nil {
+		t.Fatalf("PostProviderPreset: %v", err)
+	}
+	if live(name) {
+		t.Fatal("provider live before the resync")
+	}
+	if err := w.resyncProviders(t.Context()); err != nil {
+		t.Fatalf("resyncProviders: %v", err)
+	}
+	if !live(name) {
+		t.Fatal("added preset not live after the resync")
+	}
+
+	if _, err := st.DeleteProviderPreset(t.Context(), &modelpresetSpec.DeleteProviderPresetRequest{
+		ProviderName: name,
+	}); err != nil {
+		t.Fatalf("DeleteProviderPreset: %v", err)
+	}
+	if err := w.resyncProviders(t.Context()); err != nil {
+		t.Fatalf("resyncProviders: %v", err)
+	}
+	if live(name) {
+		t.Fatal("deleted preset still live after the resync")
+	}
+}
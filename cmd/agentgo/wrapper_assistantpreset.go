@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"errors"
-	"log/slog"
 
 	"github.com/flexigpt/flexigpt-app/internal/assistantpreset/lookupimpl"
 	"github.com/flexigpt/flexigpt-app/internal/assistantpreset/spec"
@@ -145,7 +144,7 @@ func (w *AssistantPresetStoreWrapper) close() {
 		return
 	}
 	if err := w.store.Close(); err != nil {
-		slog.Error("failed to close assistant preset store", "error", err)
+		appLogger.Error("failed to close assistant preset store", "error", err)
 	}
 	w.store = nil
 }
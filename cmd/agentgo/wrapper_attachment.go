@@ -1,12 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"errors"
-	"log/slog"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 
@@ -15,6 +22,7 @@ import (
 	"github.com/flexigpt/flexigpt-app/internal/attachment"
 	"github.com/flexigpt/flexigpt-app/internal/llmtoolsutil"
 	"github.com/flexigpt/flexigpt-app/internal/middleware"
+	settingSpec "github.com/flexigpt/flexigpt-app/internal/setting/spec"
 )
 
 func (a *App) OpenURLAsAttachment(
@@ -72,13 +80,102 @@ func (a *App) OpenDirectoryAsAttachments(maxFiles int) (*attachment.DirectoryAtt
 	})
 }
 
-func (a *App) GetPathsAsAttachments(paths []string, maxFilesPerDir int) (*attachment.PathAttachmentsResult, error) {
+// GetPathsAsAttachments builds attachments for dropped or typed paths. The
+// optional filter applies to the files walked in directories.
+func (a *App) GetPathsAsAttachments(
+	paths []string,
+	maxFilesPerDir int,
+	filter *attachment.DirWalkFilter,
+) (*attachment.PathAttachmentsResult, error) {
 	return middleware.WithRecoveryResp(func() (*attachment.PathAttachmentsResult, error) {
-		return a.getPathsAsAttachments(paths, maxFilesPerDir)
+		return a.getPathsAsAttachments(paths, maxFilesPerDir, filter)
 	})
 }
 
-func (a *App) getPathsAsAttachments(paths []string, inMaxFilesPerDir int) (*attachment.PathAttachmentsResult, error) {
+// GetDirectoryDiffAsAttachment diffs two directories, e.g. two folders dropped
+// together for a "review these two versions" turn, and returns the diff along
+// with an attachment that sends it as a single text block.
+func (a *App) GetDirectoryDiffAsAttachment(
+	oldDirPath string,
+	newDirPath string,
+	maxFiles int,
+) (*attachment.DirDiffAttachmentResult, error) {
+	return middleware.WithRecoveryResp(func() (*attachment.DirDiffAttachmentResult, error) {
+		if a.ctx == nil {
+			return nil, errors.New("context is not initialized")
+		}
+		for _, p := range []string{oldDirPath, newDirPath} {
+			if err := a.requirePathTrust(p); err != nil {
+				return nil, err
+			}
+		}
+		return attachment.BuildAttachmentForDirDiff(a.ctx, oldDirPath, newDirPath, maxFiles)
+	})
+}
+
+// GetFileAsChunkedAttachment attaches a large text file split into parts of at
+// most chunkSize bytes overlapping by overlap bytes. Zero values use the
+// defaults.
+func (a *App) GetFileAsChunkedAttachment(
+	path string,
+	chunkSize int64,
+	overlap int64,
+) (*attachment.Attachment, error) {
+	return middleware.WithRecoveryResp(func() (*attachment.Attachment, error) {
+		info, err := llmtoolsutil.StatPath(context.Background(), fstool.StatPathArgs{Path: strings.TrimSpace(path)})
+		if err != nil {
+			return nil, err
+		}
+		if info == nil || !info.Exists {
+			return nil, errors.New("cannot access: " + path)
+		}
+		if err := a.requirePathTrust(info.Path); err != nil {
+			return nil, err
+		}
+		opts := []attachment.FileChunkOption{}
+		if chunkSize > 0 {
+			opts = append(opts, attachment.WithFileChunkSize(chunkSize))
+		}
+		if overlap > 0 {
+			opts = append(opts, attachment.WithFileChunkOverlap(overlap))
+		}
+		return attachment.BuildChunkedAttachmentForFile(context.Background(), &attachment.PathInfo{
+			Path:    info.Path,
+			Name:    info.Name,
+			Exists:  info.Exists,
+			IsDir:   info.IsDir,
+			Size:    info.SizeBytes,
+			ModTime: info.ModTime,
+		}, opts...)
+	})
+}
+
+// PasteClipboardAsAttachment attaches the clipboard content. A clipboard image
+// (e.g. an OS screenshot) is read through the platform clipboard tool and
+// stored as PNG. Image data URLs in the clipboard text are stored as WebP or
+// PNG; any other text is stored and attached as a text file. Old pastes are
+// pruned after each paste.
+func (a *App) PasteClipboardAsAttachment() (*attachment.Attachment, error) {
+	return middleware.WithRecoveryResp(func() (*attachment.Attachment, error) {
+		return a.pasteClipboardAsAttachment()
+	})
+}
+
+// requirePathTrust rejects paths outside the trusted directories unless the
+// user confirmed them. Paths picked in a native dialog are explicit user
+// choices and are not checked.
+func (a *App) requirePathTrust(path string) error {
+	if a.settingStoreAPI == nil || a.settingStoreAPI.store == nil {
+		return errors.New("settings store is not initialized")
+	}
+	return a.settingStoreAPI.store.RequirePathTrust(context.Background(), path)
+}
+
+func (a *App) getPathsAsAttachments(
+	paths []string,
+	inMaxFilesPerDir int,
+	filter *attachment.DirWalkFilter,
+) (*attachment.PathAttachmentsResult, error) {
 	if len(paths) == 0 {
 		return nil, errors.New("empty paths received")
 	}
@@ -115,9 +212,18 @@ func (a *App) getPathsAsAttachments(paths []string, inMaxFilesPerDir int) (*atta
 			out.Errors = append(out.Errors, "Cannot access: "+p)
 			continue
 		}
+		if err := a.requirePathTrust(info.Path); err != nil {
+			if !errors.Is(err, settingSpec.ErrPathNotTrusted) {
+				out.Errors = append(out.Errors, "Cannot check trust: "+info.Path)
+				continue
+			}
+			out.UntrustedPaths = append(out.UntrustedPaths, info.Path)
+			out.Errors = append(out.Errors, "Not trusted, confirm to attach: "+info.Path)
+			continue
+		}
 
 		if info.IsDir {
-			dirRes, derr := a.buildDirectoryAttachments(info.Path, maxFilesPerDir)
+			dirRes, derr := a.buildDirectoryAttachments(info.Path, maxFilesPerDir, filter)
 			if derr != nil || dirRes == nil {
 				out.Errors = append(out.Errors, "Failed to attach folder: "+info.Path)
 				continue
@@ -143,8 +249,16 @@ func (a *App) getPathsAsAttachments(paths []string, inMaxFilesPerDir int) (*atta
 	return &out, nil
 }
 
-func (a *App) buildDirectoryAttachments(dirPath string, maxFiles int) (*attachment.DirectoryAttachmentsResult, error) {
-	walkRes, err := attachment.WalkDirectoryWithFiles(a.ctx, dirPath, maxFiles)
+func (a *App) buildDirectoryAttachments(
+	dirPath string,
+	maxFiles int,
+	filter *attachment.DirWalkFilter,
+) (*attachment.DirectoryAttachmentsResult, error) {
+	var opts []attachment.DirWalkOption
+	if filter != nil {
+		opts = append(opts, attachment.WithDirWalkFilter(*filter))
+	}
+	walkRes, err := attachment.WalkDirectoryWithFiles(a.ctx, dirPath, maxFiles, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -236,7 +350,7 @@ func (a *App) openMultipleFilesAsAttachments(
 	for _, p := range paths {
 		path := strings.TrimSpace(p)
 		if path == "" {
-			slog.Debug("got empty path")
+			appLogger.Debug("got empty path")
 			continue
 		}
 		// Basic sanity + existence checks.
@@ -245,7 +359,7 @@ func (a *App) openMultipleFilesAsAttachments(
 		})
 
 		if err != nil || pathInfo == nil {
-			slog.Debug("failed to build attachment for file", "path", p, "error", "stat failed")
+			appLogger.Debug("failed to build attachment for file", "path", p, "error", "stat failed")
 			continue
 		}
 
@@ -258,7 +372,7 @@ func (a *App) openMultipleFilesAsAttachments(
 			ModTime: pathInfo.ModTime,
 		})
 		if attErr != nil || att == nil {
-			slog.Debug("failed to build attachment for file", "path", p, "error", attErr)
+			appLogger.Debug("failed to build attachment for file", "path", p, "error", attErr)
 			continue
 		}
 		attachments = append(attachments, *att)
@@ -348,7 +462,7 @@ func (a *App) openDirectoryAsAttachments(maxFiles int) (*attachment.DirectoryAtt
 	for _, pi := range walkRes.Files {
 		att, buildErr := attachment.BuildAttachmentForFile(context.Background(), &pi)
 		if buildErr != nil || att == nil {
-			slog.Debug("failed to build attachment for directory file",
+			appLogger.Debug("failed to build attachment for directory file",
 				"path", pi.Path,
 				"error", buildErr,
 			)
@@ -402,3 +516,86 @@ func getRuntimeFilters(additionalFilters []attachment.FileFilter, includeDefault
 	}
 	return runtimeFilters
 }
+
+func (a *App) pasteClipboardAsAttachment() (*attachment.Attachment, error) {
+	if a.ctx == nil {
+		return nil, errors.New("context is not initialized")
+	}
+	name := "paste-" + time.Now().UTC().Format("20060102-150405.000")
+	content, ext, err := a.readClipboardContent()
+	if err != nil {
+		return nil, err
+	}
+	name += ext
+
+	path := filepath.Join(a.clipboardPastesDirPath, name)
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		return nil, err
+	}
+	info, err := llmtoolsutil.StatPath(context.Background(), fstool.StatPathArgs{Path: path})
+	if err != nil {
+		return nil, err
+	}
+	if info == nil || !info.Exists {
+		return nil, errors.New("pasted file missing: " + path)
+	}
+	if err := pruneClipboardPastes(a.clipboardPastesDirPath, time.Now()); err != nil {
+		appLogger.Warn("prune clipboard pastes", "error", err)
+	}
+	return attachment.BuildAttachmentForFile(context.Background(), &attachment.PathInfo{
+		Path:    info.Path,
+		Name:    info.Name,
+		Exists:  info.Exists,
+		IsDir:   info.IsDir,
+		Size:    info.SizeBytes,
+		ModTime: info.ModTime,
+	})
+}
+
+// readClipboardContent returns the clipboard content and the file extension to
+// store it under. An image on the clipboard wins over its text.
+func (a *App) readClipboardContent() (content []byte, ext string, err error) {
+	content, err = readClipboardImage(a.ctx)
+	if err == nil {
+		return content, ".png", nil
+	}
+	if !errors.Is(err, errNoClipboardImage) {
+		return nil, "", err
+	}
+	text, err := runtime.ClipboardGetText(a.ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, "", errors.New("clipboard is empty")
+	}
+	if data, ok := strings.CutPrefix(strings.TrimSpace(text), "data:image/"); ok {
+		return clipboardDataURLImage(data)
+	}
+	return []byte(text), ".txt", nil
+}
+
+// clipboardDataURLImage decodes the part of an image data URL after
+// "data:image/". WebP is kept as is; other formats are re-encoded as PNG.
+func clipboardDataURLImage(data string) (content []byte, ext string, err error) {
+	_, payload, ok := strings.Cut(data, ";base64,")
+	if !ok {
+		return nil, "", errors.New("clipboard image is not base64 encoded")
+	}
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid clipboard image: %w", err)
+	}
+	if _, format, err := image.DecodeConfig(bytes.NewReader(raw)); err == nil && format == "webp" {
+		return raw, ".webp", nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid clipboard image: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), ".png", nil
+}
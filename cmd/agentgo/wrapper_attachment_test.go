@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestClipboardDataURLImage(t *testing.T) {
+	var pngBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	// A lossless 1x1 WebP; only its header is parsed.
+	webp := []byte("RIFF\x1a\x00\x00\x00WEBPVP8L\x0d\x00\x00\x00" +
+		"\x2f\x00\x00\x00\x10\x07\x10\x11\x11\x88\x88\xfe\x07\x00")
+
+	for _, tt := range []struct {
+		name    string
+		data    string
+		wantExt string
+		wantErr bool
+	}{
+		{name: "png", data: "png;base64," + base64.StdEncoding.EncodeToString(pngBuf.Bytes()), wantExt: ".png"},
+		{name: "webp", data: "webp;base64," + base64.StdEncoding.EncodeToString(webp), wantExt: ".webp"},
+		{name: "not base64", data: "png,abc", wantErr: true},
+		{name: "not an image", data: "png;base64," + base64.StdEncoding.EncodeToString([]byte("text")), wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			content, ext, err := clipboardDataURLImage(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("clipboardDataURLImage = %q, want error", ext)
+				}
+				return
+			}
+			if err != nil || ext != tt.wantExt || len(content) == 0 {
+				t.Fatalf("clipboardDataURLImage = %d bytes, %q, %v; want %q", len(content), ext, err, tt.wantExt)
+			}
+			if tt.wantExt == ".webp" && !bytes.Equal(content, webp) {
+				t.Fatal("webp was not kept as is")
+			}
+		})
+	}
+}
+
+func TestPruneClipboardPastes(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	write := func(name string, age time.Duration) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("paste-old.txt", clipboardPasteMaxAge+time.Hour)
+	write("other.txt", clipboardPasteMaxAge+time.Hour)
+	for i := range clipboardPasteMaxFiles + 2 {
+		write("paste-"+strconv.Itoa(i)+".png", time.Duration(i)*time.Minute)
+	}
+
+	if err := pruneClipboardPastes(dir, now); err != nil {
+		t.Fatalf("pruneClipboardPastes: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != clipboardPasteMaxFiles+1 {
+		t.Fatalf("kept %d files, want %d", len(entries), clipboardPasteMaxFiles+1)
+	}
+	for _, name := range []string{"paste-old.txt", "paste-" + strconv.Itoa(clipboardPasteMaxFiles) + ".png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was not pruned", name)
+		}
+	}
+	for _, name := range []string{"other.txt", "paste-0.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was pruned: %v", name, err)
+		}
+	}
+}
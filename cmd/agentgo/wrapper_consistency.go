@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+
+	"github.com/flexigpt/flexigpt-app/internal/consistency"
+	"github.com/flexigpt/flexigpt-app/internal/consistency/spec"
+	"github.com/flexigpt/flexigpt-app/internal/middleware"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	settingSpec "github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+type ConsistencyWrapper struct {
+	app *App
+}
+
+// InitConsistencyWrapper keeps the app to read its stores on each check.
+func InitConsistencyWrapper(w *ConsistencyWrapper, a *App) error {
+	if w == nil || a == nil {
+		panic("initialising ConsistencyWrapper on nil receivers")
+	}
+	w.app = a
+	return nil
+}
+
+// ConsistencyCheck validates references between the setting, model preset
+// and skill stores.
+func (w *ConsistencyWrapper) ConsistencyCheck(
+	_ *spec.ConsistencyCheckRequest,
+) (*spec.ConsistencyCheckResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ConsistencyCheckResponse, error) {
+		snap := w.snapshot(context.Background())
+		return &spec.ConsistencyCheckResponse{Body: consistency.Check(snap)}, nil
+	})
+}
+
+// snapshot reads the stores. A store that fails is recorded in StoreErrors
+// rather than failing the check.
+func (w *ConsistencyWrapper) snapshot(ctx context.Context) consistency.Snapshot {
+	snap := consistency.Snapshot{StoreErrors: map[string]error{}}
+	a := w.app
+
+	if resp, err := a.settingStoreAPI.store.GetSettings(ctx, &settingSpec.GetSettingsRequest{}); err != nil {
+		snap.StoreErrors[consistency.StoreSetting] = err
+	} else if resp.Body != nil {
+		snap.AuthKeys = resp.Body.AuthKeys
+	}
+
+	if err := w.readModelPresets(ctx, &snap); err != nil {
+		snap.StoreErrors[consistency.StoreModelPreset] = err
+	}
+
+	if err := w.readSkills(ctx, &snap); err != nil {
+		snap.StoreErrors[consistency.StoreSkill] = err
+	}
+	return snap
+}
+
+func (w *ConsistencyWrapper) readModelPresets(ctx context.Context, snap *consistency.Snapshot) error {
+	store := w.app.modelPresetStoreAPI.store
+	def, err := store.GetDefaultProvider(ctx, &modelpresetSpec.GetDefaultProviderRequest{})
+	if err != nil {
+		return err
+	}
+	if def.Body != nil {
+		snap.DefaultProvider = def.Body.DefaultProvider
+	}
+	token := ""
+	for {
+		resp, err := store.ListProviderPresets(ctx, &modelpresetSpec.ListProviderPresetsRequest{
+			IncludeDisabled: true,
+			PageToken:       token,
+		})
+		if err != nil {
+			return err
+		}
+		snap.Providers = append(snap.Providers, resp.Body.Providers...)
+		if resp.Body.NextPageToken == nil || *resp.Body.NextPageToken == "" {
+			return nil
+		}
+		token = *resp.Body.NextPageToken
+	}
+}
+
+func (w *ConsistencyWrapper) readSkills(ctx context.Context, snap *consistency.Snapshot) error {
+	store := w.app.skillStoreAPI.store
+	token := ""
+	for {
+		resp, err := store.ListSkills(ctx, &skillstoreSpec.ListSkillsRequest{
+			Types:           []skillstoreSpec.SkillType{skillstoreSpec.SkillTypeFS},
+			IncludeDisabled: true,
+			IncludeMissing:  true,
+			PageToken:       token,
+		})
+		if err != nil {
+			return err
+		}
+		snap.Skills = append(snap.Skills, resp.Body.SkillListItems...)
+		if resp.Body.NextPageToken == nil || *resp.Body.NextPageToken == "" {
+			return nil
+		}
+		token = *resp.Body.NextPageToken
+	}
+}
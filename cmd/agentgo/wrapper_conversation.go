@@ -15,10 +15,12 @@ type ConversationCollectionWrapper struct {
 func InitConversationCollectionWrapper(
 	c *ConversationCollectionWrapper,
 	conversationDir string,
+	attachmentBlobDir string,
 ) error {
 	conversationStoreAPI, err := conversationStore.NewConversationCollection(
 		conversationDir,
 		conversationStore.WithFTS(true),
+		conversationStore.WithAttachmentBlobDir(attachmentBlobDir),
 	)
 	if err != nil {
 		return err
@@ -75,6 +77,22 @@ func (ccw *ConversationCollectionWrapper) PutMessagesToConversation(
 	})
 }
 
+func (ccw *ConversationCollectionWrapper) GetAttachmentBlobStats(
+	req *spec.GetAttachmentBlobStatsRequest,
+) (*spec.GetAttachmentBlobStatsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GetAttachmentBlobStatsResponse, error) {
+		return ccw.store.GetAttachmentBlobStats(context.Background(), req)
+	})
+}
+
+func (ccw *ConversationCollectionWrapper) GCAttachmentBlobs(
+	req *spec.GCAttachmentBlobsRequest,
+) (*spec.GCAttachmentBlobsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GCAttachmentBlobsResponse, error) {
+		return ccw.store.GCAttachmentBlobs(context.Background(), req)
+	})
+}
+
 func (ccw *ConversationCollectionWrapper) close() {
 	if ccw == nil || ccw.store == nil {
 		return
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	assistantpresetSpec "github.com/flexigpt/flexigpt-app/internal/assistantpreset/spec"
+	conversationSpec "github.com/flexigpt/flexigpt-app/internal/conversation/spec"
+	"github.com/flexigpt/flexigpt-app/internal/diagnostics"
+	"github.com/flexigpt/flexigpt-app/internal/diagnostics/spec"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
+	mcpSpec "github.com/flexigpt/flexigpt-app/internal/mcp/spec"
+	"github.com/flexigpt/flexigpt-app/internal/middleware"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	schedulerSpec "github.com/flexigpt/flexigpt-app/internal/scheduler/spec"
+	settingSpec "github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	settingStore "github.com/flexigpt/flexigpt-app/internal/setting/store"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+	toolSpec "github.com/flexigpt/flexigpt-app/internal/tool/spec"
+)
+
+type DiagnosticsWrapper struct {
+	settings *settingStore.SettingStore
+	dataDir  string
+}
+
+// InitDiagnosticsWrapper keeps what the bundle is collected from. The
+// settings store must be initialized.
+func InitDiagnosticsWrapper(w *DiagnosticsWrapper, dataDir string, settings *SettingStoreWrapper) error {
+	if w == nil || settings == nil || settings.store == nil {
+		panic("initialising DiagnosticsWrapper on nil receivers")
+	}
+	w.settings = settings.store
+	w.dataDir = dataDir
+	return nil
+}
+
+// GenerateDiagnosticsBundle returns a zip to attach to bug reports. Stored
+// secrets are redacted by matching their SHA-256 against the bundle text.
+func (w *DiagnosticsWrapper) GenerateDiagnosticsBundle(
+	_ *spec.GenerateDiagnosticsBundleRequest,
+) (*spec.GenerateDiagnosticsBundleResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GenerateDiagnosticsBundleResponse, error) {
+		return w.generate(context.Background())
+	})
+}
+
+func (w *DiagnosticsWrapper) generate(ctx context.Context) (*spec.GenerateDiagnosticsBundleResponse, error) {
+	settingsResp, err := w.settings.GetSettings(ctx, &settingSpec.GetSettingsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	settings := *settingsResp.Body
+	settings.AuthKeys = make([]settingSpec.AuthKeyMeta, 0, len(settingsResp.Body.AuthKeys))
+	var secrets []diagnostics.Secret
+	for _, ak := range settingsResp.Body.AuthKeys {
+		if ak.NonEmpty {
+			secrets = append(secrets, diagnostics.Secret{
+				Label:  fmt.Sprintf("%s/%s", ak.Type, ak.KeyName),
+				SHA256: ak.SHA256,
+			})
+		}
+		ak.SHA256 = ""
+		settings.AuthKeys = append(settings.AuthKeys, ak)
+	}
+	home, _ := os.UserHomeDir()
+
+	var buf bytes.Buffer
+	b := diagnostics.NewBundle(&buf, diagnostics.NewRedactor(secrets, home))
+	add := func(name string, v any, err error) {
+		if err == nil {
+			err = b.AddJSON(name, v)
+		}
+		if err != nil {
+			b.AddError(name, err)
+		}
+	}
+	add("system.json", diagnostics.CollectSystemInfo(Version), nil)
+	add("settings.json", settings, nil)
+	add("schema_versions.json", map[string]string{
+		"settings":         settingSpec.SchemaVersion,
+		"modelPresets":     modelpresetSpec.SchemaVersion,
+		"assistantPresets": assistantpresetSpec.SchemaVersion,
+		"skills":           skillstoreSpec.SkillSchemaVersion,
+		"tools":            toolSpec.SchemaVersion,
+		"conversations":    conversationSpec.ConversationSchemaVersion,
+		"mcp":              mcpSpec.MCPSchemaVersion,
+		"scheduler":        schedulerSpec.SchemaVersion,
+	}, nil)
+	add("logs.json", appLogHandler.Recent(logging.RecentQuery{}), nil)
+	overlays, err := diagnostics.CollectOverlayStats(ctx, w.dataDir)
+	add("overlays.json", overlays, err)
+	if err := b.Close(); err != nil {
+		return nil, err
+	}
+
+	return &spec.GenerateDiagnosticsBundleResponse{
+		Body: &spec.GenerateDiagnosticsBundleResponseBody{
+			FileName: "flexigpt-diagnostics-" + time.Now().Format("20060102-150405") + ".zip",
+			Archive:  buf.Bytes(),
+		},
+	}, nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	conversationSpec "github.com/flexigpt/flexigpt-app/internal/conversation/spec"
+	"github.com/flexigpt/flexigpt-app/internal/fsutil"
+	"github.com/flexigpt/flexigpt-app/internal/middleware"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	settingSpec "github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// healthProbeTimeout bounds each store probe of HealthCheck.
+const healthProbeTimeout = 5 * time.Second
+
+// SubsystemHealth is the state of one store. LastError is the latest failed
+// probe and stays set after the store recovers, so the UI can show it.
+// WriteStatus is set for stores that turn read-only on disk errors; such a
+// store is not ready while read-only. Renamed
+// lists user names the store renamed at startup, old to new, because they
+// collided with reserved built-in names.
+type SubsystemHealth struct {
+	Name          string              `json:"name"`
+	Ready         bool                `json:"ready"`
+	SchemaVersion string              `json:"schemaVersion"`
+	Path          string              `json:"path"`
+	WriteStatus   *fsutil.WriteStatus `json:"writeStatus,omitempty"`
+	Renamed       map[string]string   `json:"renamed,omitempty"`
+	LastError     string              `json:"lastError,omitempty"`
+	LastErrorAt   *time.Time          `json:"lastErrorAt,omitempty"`
+	CheckedAt     time.Time           `json:"checkedAt"`
+}
+
+// HealthCheckResponse is ready only when every subsystem is.
+type HealthCheckResponse struct {
+	Ready      bool              `json:"ready"`
+	Subsystems []SubsystemHealth `json:"subsystems"`
+}
+
+type healthProbe struct {
+	name          string
+	schemaVersion string
+	path          string
+	probe         func(ctx context.Context) error
+	writeStatus   func() fsutil.WriteStatus
+	renamed       func() map[string]string
+}
+
+type HealthWrapper struct {
+	probes []healthProbe
+
+	mu       sync.Mutex
+	lastErrs map[string]SubsystemHealth
+}
+
+// InitHealthWrapper sets up probes for the stores of a, which must be open.
+// Each probe is a cheap read, so a store that lost its files reports an error.
+func InitHealthWrapper(w *HealthWrapper, a *App) error {
+	if w == nil || a == nil {
+		panic("initialising HealthWrapper on nil receivers")
+	}
+	w.lastErrs = map[string]SubsystemHealth{}
+	w.probes = []healthProbe{
+		{
+			name:          "setting",
+			schemaVersion: settingSpec.SchemaVersion,
+			path:          a.settingsDirPath,
+			probe: func(ctx context.Context) error {
+				_, err := a.settingStoreAPI.store.GetSettings(ctx, &settingSpec.GetSettingsRequest{})
+				return err
+			},
+		},
+		{
+			name:          "modelpreset",
+			schemaVersion: modelpresetSpec.SchemaVersion,
+			path:          a.modelPresetsDirPath,
+			probe: func(ctx context.Context) error {
+				_, err := a.modelPresetStoreAPI.store.ListProviderPresets(
+					ctx,
+					&modelpresetSpec.ListProviderPresetsRequest{PageSize: 1},
+				)
+				return err
+			},
+			writeStatus: func() fsutil.WriteStatus { return a.modelPresetStoreAPI.store.WriteStatus() },
+			renamed:     func() map[string]string { return a.aggregateAPI.providerRenames },
+		},
+		{
+			name:          "skill",
+			schemaVersion: skillstoreSpec.SkillSchemaVersion,
+			path:          a.skillsDirPath,
+			probe: func(ctx context.Context) error {
+				_, err := a.skillStoreAPI.store.ListSkillBundles(
+					ctx,
+					&skillstoreSpec.ListSkillBundlesRequest{PageSize: 1},
+				)
+				return err
+			},
+			writeStatus: func() fsutil.WriteStatus { return a.skillStoreAPI.store.WriteStatus() },
+			renamed:     func() map[string]string { return a.skillStoreAPI.bundleRenames },
+		},
+		{
+			name:          "conversation",
+			schemaVersion: conversationSpec.ConversationSchemaVersion,
+			path:          a.conversationsDirPath,
+			probe: func(ctx context.Context) error {
+				_, err := a.conversationStoreAPI.store.ListConversations(
+					ctx,
+					&conversationSpec.ListConversationsRequest{PageSize: 1},
+				)
+				return err
+			},
+		},
+	}
+	return nil
+}
+
+// HealthCheck probes every store and reports its readiness.
+func (w *HealthWrapper) HealthCheck() (*HealthCheckResponse, error) {
+	return middleware.WithRecoveryResp(func() (*HealthCheckResponse, error) {
+		resp := &HealthCheckResponse{Ready: true, Subsystems: make([]SubsystemHealth, 0, len(w.probes))}
+		for _, p := range w.probes {
+			h := w.check(p)
+			resp.Ready = resp.Ready && h.Ready
+			resp.Subsystems = append(resp.Subsystems, h)
+		}
+		return resp, nil
+	})
+}
+
+func (w *HealthWrapper) check(p healthProbe) SubsystemHealth {
+	ctx, cancel := context.WithTimeout(context.Background(), healthProbeTimeout)
+	defer cancel()
+	// A panicking store is reported like any other failure.
+	_, err := middleware.WithRecoveryResp(func() (struct{}, error) {
+		return struct{}{}, p.probe(ctx)
+	})
+
+	now := time.Now().UTC()
+	h := SubsystemHealth{
+		Name:          p.name,
+		Ready:         err == nil,
+		SchemaVersion: p.schemaVersion,
+		Path:          p.path,
+		CheckedAt:     now,
+	}
+	if p.writeStatus != nil {
+		ws := p.writeStatus()
+		h.WriteStatus = &ws
+		h.Ready = h.Ready && !ws.ReadOnly
+	}
+	if p.renamed != nil {
+		if renamed := p.renamed(); len(renamed) > 0 {
+			h.Renamed = maps.Clone(renamed)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		w.lastErrs[p.name] = SubsystemHealth{LastError: err.Error(), LastErrorAt: &now}
+	}
+	if last, ok := w.lastErrs[p.name]; ok {
+		h.LastError, h.LastErrorAt = last.LastError, last.LastErrorAt
+	}
+	return h
+}
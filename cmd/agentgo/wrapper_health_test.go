@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/flexigpt/flexigpt-app/internal/fsutil"
+)
+
+func TestHealthCheck_ReportsReadOnlyAndRenames(t *testing.T) {
+	var status fsutil.WriteStatus
+	var probeErr error
+	w := &HealthWrapper{lastErrs: map[string]SubsystemHealth{}}
+	w.probes = []healthProbe{{
+		name:        "skill",
+		probe:       func(context.Context) error { return probeErr },
+		writeStatus: func() fsutil.WriteStatus { return status },
+		renamed:     func() map[string]string { return map[string]string{"reserved": "reserved-1"} },
+	}}
+
+	resp, err := w.HealthCheck()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := resp.Subsystems[0]
+	if !resp.Ready || !h.Ready || h.WriteStatus == nil || h.WriteStatus.ReadOnly {
+		t.Fatalf("writable store = %+v", h)
+	}
+	if h.Renamed["reserved"] != "reserved-1" {
+		t.Fatalf("renamed = %v", h.Renamed)
+	}
+
+	status = fsutil.WriteStatus{ReadOnly: true, Cause: "disk full"}
+	resp, _ = w.HealthCheck()
+	if h := resp.Subsystems[0]; resp.Ready || h.Ready || !h.WriteStatus.ReadOnly || h.WriteStatus.Cause != "disk full" {
+		t.Fatalf("read-only store = %+v, ready %v", h, resp.Ready)
+	}
+
+	status, probeErr = fsutil.WriteStatus{}, errors.New("gone")
+	resp, _ = w.HealthCheck()
+	if h := resp.Subsystems[0]; h.Ready || h.LastError != "gone" || h.WriteStatus == nil {
+		t.Fatalf("failing store = %+v", h)
+	}
+	probeErr = nil
+	if resp, _ = w.HealthCheck(); !resp.Ready || resp.Subsystems[0].LastError != "gone" {
+		t.Fatalf("recovered store = %+v", resp.Subsystems[0])
+	}
+}
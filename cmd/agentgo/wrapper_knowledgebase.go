@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+
+	"github.com/flexigpt/flexigpt-app/internal/knowledgebase/spec"
+	knowledgebaseStore "github.com/flexigpt/flexigpt-app/internal/knowledgebase/store"
+	"github.com/flexigpt/flexigpt-app/internal/middleware"
+)
+
+type KnowledgeBaseWrapper struct {
+	store *knowledgebaseStore.KnowledgeBaseStore
+}
+
+// InitKnowledgeBaseWrapper opens the knowledge base store. Chunks and queries
+// are embedded through the aggregate wrapper so stored provider keys are used.
+// File and folder sources must pass the workspace trust check.
+func InitKnowledgeBaseWrapper(
+	w *KnowledgeBaseWrapper,
+	baseDir string,
+	aggregate *AggregrateWrapper,
+	trust knowledgebaseStore.PathTrust,
+) error {
+	if w == nil {
+		panic("initialising KnowledgeBaseWrapper on nil receiver")
+	}
+	st, err := knowledgebaseStore.NewKnowledgeBaseStore(
+		context.Background(),
+		baseDir,
+		aggregate.embed,
+		knowledgebaseStore.WithPathTrust(trust),
+	)
+	if err != nil {
+		return err
+	}
+	w.store = st
+	return nil
+}
+
+func (w *KnowledgeBaseWrapper) PostCollection(
+	req *spec.PostCollectionRequest,
+) (*spec.PostCollectionResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.PostCollectionResponse, error) {
+		return w.store.PostCollection(context.Background(), req)
+	})
+}
+
+func (w *KnowledgeBaseWrapper) PatchCollection(
+	req *spec.PatchCollectionRequest,
+) (*spec.PatchCollectionResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.PatchCollectionResponse, error) {
+		return w.store.PatchCollection(context.Background(), req)
+	})
+}
+
+func (w *KnowledgeBaseWrapper) DeleteCollection(
+	req *spec.DeleteCollectionRequest,
+) (*spec.DeleteCollectionResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.DeleteCollectionResponse, error) {
+		return w.store.DeleteCollection(context.Background(), req)
+	})
+}
+
+func (w *KnowledgeBaseWrapper) GetCollection(
+	req *spec.GetCollectionRequest,
+) (*spec.GetCollectionResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GetCollectionResponse, error) {
+		return w.store.GetCollection(context.Background(), req)
+	})
+}
+
+func (w *KnowledgeBaseWrapper) ListCollections(
+	req *spec.ListCollectionsRequest,
+) (*spec.ListCollectionsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ListCollectionsResponse, error) {
+		return w.store.ListCollections(context.Background(), req)
+	})
+}
+
+func (w *KnowledgeBaseWrapper) IndexCollection(
+	req *spec.IndexCollectionRequest,
+) (*spec.IndexCollectionResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.IndexCollectionResponse, error) {
+		subject := ""
+		if req != nil {
+			subject = req.CollectionID
+		}
+		return withProgress(progressKindKnowledgeBaseIndex, subject,
+			func(ctx context.Context) (*spec.IndexCollectionResponse, error) {
+				return w.store.IndexCollection(ctx, req)
+			})
+	})
+}
+
+func (w *KnowledgeBaseWrapper) QueryKnowledgeBase(
+	req *spec.QueryKnowledgeBaseRequest,
+) (*spec.QueryKnowledgeBaseResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.QueryKnowledgeBaseResponse, error) {
+		return w.store.QueryKnowledgeBase(context.Background(), req)
+	})
+}
+
+func (w *KnowledgeBaseWrapper) close() {
+	if w == nil || w.store == nil {
+		return
+	}
+	if err := w.store.Close(); err != nil {
+		appLogger.Error("failed to close knowledge base store", "error", err)
+	}
+	w.store = nil
+}
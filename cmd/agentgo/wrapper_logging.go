@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/flexigpt/flexigpt-app/internal/logging"
+	"github.com/flexigpt/flexigpt-app/internal/middleware"
+	settingSpec "github.com/flexigpt/flexigpt-app/internal/setting/spec"
+)
+
+// appLogHandler is the default slog handler, set up in main and configured
+// from the debug settings.
+var appLogHandler *logging.Handler
+
+type LogWrapper struct{}
+
+// GetRecentLogs returns the latest log records kept in memory for the
+// diagnostics panel, oldest first.
+func (w *LogWrapper) GetRecentLogs(query *logging.RecentQuery) ([]logging.Entry, error) {
+	return middleware.WithRecoveryResp(func() ([]logging.Entry, error) {
+		if query == nil {
+			query = &logging.RecentQuery{}
+		}
+		return appLogHandler.Recent(*query), nil
+	})
+}
+
+func logConfigFromDebugSettings(cfg settingSpec.DebugSettings) logging.Config {
+	out := logging.Config{
+		Level:  toSlogLevel(cfg.LogLevel),
+		Format: logging.FormatText,
+	}
+	if cfg.LogFormat == settingSpec.DebugLogFormatJSON {
+		out.Format = logging.FormatJSON
+	}
+	if len(cfg.ModuleLogLevels) > 0 {
+		out.ModuleLevels = make(map[string]slog.Level, len(cfg.ModuleLogLevels))
+		for module, level := range cfg.ModuleLogLevels {
+			out.ModuleLevels[module] = toSlogLevel(level)
+		}
+	}
+	return out
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/flexigpt/flexigpt-app/internal/mcp/store"
 
 	settingSpec "github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	settingStore "github.com/flexigpt/flexigpt-app/internal/setting/store"
 	"golang.org/x/oauth2"
 )
 
@@ -136,6 +137,7 @@ func (r *settingSecretResolver) ResolveSecret(
 		return "", err
 	}
 
+	ctx = settingStore.WithAuthKeyCaller(ctx, settingSpec.AuthKeyCallerMCP)
 	resp, err := r.store.GetAuthKey(ctx, &settingSpec.GetAuthKeyRequest{
 		Type:    settingSpec.AuthKeyTypeMCP,
 		KeyName: settingSpec.AuthKeyName(secret.GetMCPSecretRefStorageKey(ref)),
@@ -185,6 +187,7 @@ func (r *settingSecretResolver) SetMCPSecret(
 		return "", false, err
 	}
 
+	ctx = settingStore.WithAuthKeyCaller(ctx, settingSpec.AuthKeyCallerMCP)
 	resp, err := st.GetAuthKey(ctx, &settingSpec.GetAuthKeyRequest{
 		Type:    settingSpec.AuthKeyTypeMCP,
 		KeyName: keyName,
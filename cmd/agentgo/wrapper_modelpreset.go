@@ -2,25 +2,43 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"maps"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 
 	"github.com/flexigpt/flexigpt-app/internal/middleware"
 	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
 	modelpresetStore "github.com/flexigpt/flexigpt-app/internal/modelpreset/store"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
 )
 
+// modelPresetChangedEvent is the Wails event carrying a spec.PresetChangeEvent.
+const modelPresetChangedEvent = "modelpreset:changed"
+
 type ModelPresetStoreWrapper struct {
-	store *modelpresetStore.ModelPresetStore
+	store       *modelpresetStore.ModelPresetStore
+	unsubscribe func()
+	// resyncProvider re-adds a provider to the live provider set after its
+	// connection settings were patched.
+	resyncProvider func(ctx context.Context, name inferenceSpec.ProviderName) error
 }
 
 // InitModelPresetStoreWrapper initialises the wrapped store in `baseDir`.
+// With watch the store reloads its file when it changes on disk.
 func InitModelPresetStoreWrapper(
 	m *ModelPresetStoreWrapper,
 	baseDir string,
+	watch bool,
 ) error {
 	if m == nil {
 		panic("initialising model-preset store wrapper on nil receivers")
 	}
-	s, err := modelpresetStore.NewModelPresetStore(baseDir)
+	s, err := modelpresetStore.NewModelPresetStore(
+		baseDir,
+		modelpresetStore.WithFileWatch(watch),
+		modelpresetStore.WithBackups(storeBackupPolicy),
+	)
 	if err != nil {
 		return err
 	}
@@ -28,6 +46,31 @@ func InitModelPresetStoreWrapper(
 	return nil
 }
 
+// SetModelPresetProviderResync sets how patched providers are re-added to the
+// live provider set.
+func SetModelPresetProviderResync(
+	w *ModelPresetStoreWrapper,
+	resync func(ctx context.Context, name inferenceSpec.ProviderName) error,
+) {
+	if w != nil {
+		w.resyncProvider = resync
+	}
+}
+
+// SetModelPresetEventsAppContext forwards preset changes to the frontend so it
+// can refresh instead of polling ListProviderPresets.
+func SetModelPresetEventsAppContext(w *ModelPresetStoreWrapper, ctx context.Context) {
+	if w == nil || w.store == nil {
+		return
+	}
+	if w.unsubscribe != nil {
+		w.unsubscribe()
+	}
+	w.unsubscribe = w.store.Subscribe(func(ev spec.PresetChangeEvent) {
+		runtime.EventsEmit(ctx, modelPresetChangedEvent, ev)
+	})
+}
+
 func (w *ModelPresetStoreWrapper) PatchDefaultProvider(
 	req *spec.PatchDefaultProviderRequest,
 ) (*spec.PatchDefaultProviderResponse, error) {
@@ -48,7 +91,68 @@ func (w *ModelPresetStoreWrapper) PatchProviderPreset(
 	req *spec.PatchProviderPresetRequest,
 ) (*spec.PatchProviderPresetResponse, error) {
 	return middleware.WithRecoveryResp(func() (*spec.PatchProviderPresetResponse, error) {
-		return w.store.PatchProviderPreset(context.Background(), req)
+		ctx := context.Background()
+		var before *spec.ProviderPreset
+		if req != nil && w.resyncProvider != nil {
+			if got, err := w.store.GetProviderPreset(ctx, &spec.GetProviderPresetRequest{
+				ProviderName:    req.ProviderName,
+				IncludeDisabled: true,
+			}); err == nil {
+				before = got.Body
+			}
+		}
+		resp, err := w.store.PatchProviderPreset(ctx, req)
+		if err != nil || before == nil {
+			return resp, err
+		}
+		after, err := w.store.GetProviderPreset(ctx, &spec.GetProviderPresetRequest{
+			ProviderName:    req.ProviderName,
+			IncludeDisabled: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if providerConnectionChanged(*before, *after.Body) {
+			if err := w.resyncProvider(ctx, req.ProviderName); err != nil {
+				return nil, fmt.Errorf("provider %s saved but not reloaded: %w", req.ProviderName, err)
+			}
+		}
+		return resp, nil
+	})
+}
+
+// providerConnectionChanged reports whether a patch changed settings that are
+// applied when the provider is added to the provider set.
+func providerConnectionChanged(a, b spec.ProviderPreset) bool {
+	return a.SDKType != b.SDKType ||
+		a.Origin != b.Origin ||
+		a.ChatCompletionPathPrefix != b.ChatCompletionPathPrefix ||
+		a.APIKeyHeaderKey != b.APIKeyHeaderKey ||
+		!maps.Equal(a.DefaultHeaders, b.DefaultHeaders) ||
+		!equalPtr(a.Azure, b.Azure) ||
+		!equalPtr(a.TLS, b.TLS)
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (w *ModelPresetStoreWrapper) GetProviderPreset(
+	req *spec.GetProviderPresetRequest,
+) (*spec.GetProviderPresetResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GetProviderPresetResponse, error) {
+		return w.store.GetProviderPreset(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) GetProviderPresetsBatch(
+	req *spec.GetProviderPresetsBatchRequest,
+) (*spec.GetProviderPresetsBatchResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GetProviderPresetsBatchResponse, error) {
+		return w.store.GetProviderPresetsBatch(context.Background(), req)
 	})
 }
 
@@ -60,6 +164,22 @@ func (w *ModelPresetStoreWrapper) ListProviderPresets(
 	})
 }
 
+func (w *ModelPresetStoreWrapper) CountProviderPresets(
+	req *spec.CountProviderPresetsRequest,
+) (*spec.CountProviderPresetsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.CountProviderPresetsResponse, error) {
+		return w.store.CountProviderPresets(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) CountModelPresets(
+	req *spec.CountModelPresetsRequest,
+) (*spec.CountModelPresetsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.CountModelPresetsResponse, error) {
+		return w.store.CountModelPresets(context.Background(), req)
+	})
+}
+
 func (w *ModelPresetStoreWrapper) PostModelPreset(
 	req *spec.PostModelPresetRequest,
 ) (*spec.PostModelPresetResponse, error) {
@@ -76,6 +196,14 @@ func (w *ModelPresetStoreWrapper) PatchModelPreset(
 	})
 }
 
+func (w *ModelPresetStoreWrapper) PatchAllModelPresets(
+	req *spec.PatchAllModelPresetsRequest,
+) (*spec.PatchAllModelPresetsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.PatchAllModelPresetsResponse, error) {
+		return w.store.PatchAllModelPresets(context.Background(), req)
+	})
+}
+
 func (w *ModelPresetStoreWrapper) DeleteModelPreset(
 	req *spec.DeleteModelPresetRequest,
 ) (*spec.DeleteModelPresetResponse, error) {
@@ -92,9 +220,156 @@ func (w *ModelPresetStoreWrapper) GetModelPreset(
 	})
 }
 
+func (w *ModelPresetStoreWrapper) ResetBuiltInOverrides(
+	req *spec.ResetBuiltInOverridesRequest,
+) (*spec.ResetBuiltInOverridesResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ResetBuiltInOverridesResponse, error) {
+		return w.store.ResetBuiltInOverrides(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) ValidateProviderPreset(
+	req *spec.ValidateProviderPresetRequest,
+) (*spec.ValidateProviderPresetResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ValidateProviderPresetResponse, error) {
+		return w.store.ValidateProviderPreset(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) ProbeOrigin(
+	req *spec.ProbeOriginRequest,
+) (*spec.ProbeOriginResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ProbeOriginResponse, error) {
+		return w.store.ProbeOrigin(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) SetReasoningBudgetPercent(
+	req *spec.SetReasoningBudgetPercentRequest,
+) (*spec.SetReasoningBudgetPercentResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.SetReasoningBudgetPercentResponse, error) {
+		return w.store.SetReasoningBudgetPercent(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) PostEmbeddingPreset(
+	req *spec.PostEmbeddingPresetRequest,
+) (*spec.PostEmbeddingPresetResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.PostEmbeddingPresetResponse, error) {
+		return w.store.PostEmbeddingPreset(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) PatchEmbeddingPreset(
+	req *spec.PatchEmbeddingPresetRequest,
+) (*spec.PatchEmbeddingPresetResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.PatchEmbeddingPresetResponse, error) {
+		return w.store.PatchEmbeddingPreset(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) DeleteEmbeddingPreset(
+	req *spec.DeleteEmbeddingPresetRequest,
+) (*spec.DeleteEmbeddingPresetResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.DeleteEmbeddingPresetResponse, error) {
+		return w.store.DeleteEmbeddingPreset(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) GetEmbeddingPreset(
+	req *spec.GetEmbeddingPresetRequest,
+) (*spec.GetEmbeddingPresetResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GetEmbeddingPresetResponse, error) {
+		return w.store.GetEmbeddingPreset(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) ListEmbeddingPresets(
+	req *spec.ListEmbeddingPresetsRequest,
+) (*spec.ListEmbeddingPresetsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ListEmbeddingPresetsResponse, error) {
+		return w.store.ListEmbeddingPresets(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) PostParameterProfile(
+	req *spec.PostParameterProfileRequest,
+) (*spec.PostParameterProfileResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.PostParameterProfileResponse, error) {
+		return w.store.PostParameterProfile(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) PatchParameterProfile(
+	req *spec.PatchParameterProfileRequest,
+) (*spec.PatchParameterProfileResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.PatchParameterProfileResponse, error) {
+		return w.store.PatchParameterProfile(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) DeleteParameterProfile(
+	req *spec.DeleteParameterProfileRequest,
+) (*spec.DeleteParameterProfileResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.DeleteParameterProfileResponse, error) {
+		return w.store.DeleteParameterProfile(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) GetParameterProfile(
+	req *spec.GetParameterProfileRequest,
+) (*spec.GetParameterProfileResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GetParameterProfileResponse, error) {
+		return w.store.GetParameterProfile(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) ListParameterProfiles(
+	req *spec.ListParameterProfilesRequest,
+) (*spec.ListParameterProfilesResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ListParameterProfilesResponse, error) {
+		return w.store.ListParameterProfiles(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) ApplyParameterProfile(
+	req *spec.ApplyParameterProfileRequest,
+) (*spec.ApplyParameterProfileResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ApplyParameterProfileResponse, error) {
+		return w.store.ApplyParameterProfile(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) RetryModelPresetStoreWritable(
+	req *spec.RetryModelPresetStoreWritableRequest,
+) (*spec.RetryModelPresetStoreWritableResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.RetryModelPresetStoreWritableResponse, error) {
+		return w.store.RetryModelPresetStoreWritable(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) ListModelPresetStoreBackups(
+	req *spec.ListModelPresetStoreBackupsRequest,
+) (*spec.ListModelPresetStoreBackupsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ListModelPresetStoreBackupsResponse, error) {
+		return w.store.ListModelPresetStoreBackups(context.Background(), req)
+	})
+}
+
+func (w *ModelPresetStoreWrapper) RestoreModelPresetStoreFromBackup(
+	req *spec.RestoreModelPresetStoreFromBackupRequest,
+) (*spec.RestoreModelPresetStoreFromBackupResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.RestoreModelPresetStoreFromBackupResponse, error) {
+		return w.store.RestoreModelPresetStoreFromBackup(context.Background(), req)
+	})
+}
+
 func (s *ModelPresetStoreWrapper) close() {
 	if s == nil || s.store == nil {
 		return
 	}
+	if s.unsubscribe != nil {
+		s.unsubscribe()
+	}
 	s.store.Close()
 }
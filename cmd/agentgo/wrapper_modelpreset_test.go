@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+func TestPatchProviderPreset_ResyncsOnConnectionChange(t *testing.T) {
+	a := newApp(t.TempDir())
+	if err := a.openCLIModelPresets(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.shutdown(t.Context()) })
+	w := a.modelPresetStoreAPI
+	var resynced []inferenceSpec.ProviderName
+	SetModelPresetProviderResync(w, func(_ context.Context, name inferenceSpec.ProviderName) error {
+		resynced = append(resynced, name)
+		return nil
+	})
+
+	const name inferenceSpec.ProviderName = "user-gateway"
+	if _, err := w.store.PostProviderPreset(t.Context(), &modelpresetSpec.PostProviderPresetRequest{
+		ProviderName: name,
+		Body: &modelpresetSpec.PostProviderPresetRequestBody{
+			DisplayName:              "Gateway",
+			SDKType:                  inferenceSpec.ProviderSDKTypeOpenAIChatCompletions,
+			IsEnabled:                true,
+			Origin:                   "https://gateway.example.com",
+			ChatCompletionPathPrefix: modelpresetSpec.DefaultOpenAIChatCompletionsPrefix,
+			APIKeyHeaderKey:          modelpresetSpec.DefaultAuthorizationHeaderKey,
+		},
+	}); err != nil {
+		t.Fatalf("PostProviderPreset: %v", err)
+	}
+	patch := func(body modelpresetSpec.PatchProviderPresetRequestBody) {
+		t.Helper()
+		if _, err := w.PatchProviderPreset(&modelpresetSpec.PatchProviderPresetRequest{
+			ProviderName: name,
+			Body:         &body,
+		}); err != nil {
+			t.Fatalf("PatchProviderPreset: %v", err)
+		}
+	}
+
+	patch(modelpresetSpec.PatchProviderPresetRequestBody{IsEnabled: new(false)})
+	if len(resynced) != 0 {
+		t.Fatalf("resynced %v after a patch that keeps the connection", resynced)
+	}
+	patch(modelpresetSpec.PatchProviderPresetRequestBody{
+		TLS: &modelpresetSpec.ProviderTLSConfig{CABundlePath: "/etc/ssl/gateway.pem"},
+	})
+	if !slices.Equal(resynced, []inferenceSpec.ProviderName{name}) {
+		t.Fatalf("resynced %v, want %s after a tls change", resynced, name)
+	}
+
+	for _, body := range []modelpresetSpec.PatchProviderPresetRequestBody{
+		{Origin: new("https://gateway-2.example.com")},
+		{APIKeyHeaderKey: new("X-Api-Key")},
+		{DefaultHeaders: map[string]string{"X-Tenant": "acme"}},
+	} {
+		resynced = nil
+		patch(body)
+		if !slices.Equal(resynced, []inferenceSpec.ProviderName{name}) {
+			t.Fatalf("resynced %v, want %s after patch %+v", resynced, name, body)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"github.com/flexigpt/flexigpt-app/internal/middleware"
+)
+
+// progressEvent is the Wails event carrying a middleware.ProgressEvent.
+const progressEvent = "progress:event"
+
+// Kinds of the operations that report progress.
+const (
+	progressKindKnowledgeBaseIndex   = "knowledgeBaseIndex"
+	progressKindWorkspaceRefresh     = "workspaceRefresh"
+	progressKindRegistrySkillInstall = "registrySkillInstall"
+)
+
+// progressBus is shared by all wrappers. Wrappers start an operation around
+// long calls; stores report through middleware.ProgressFromContext.
+var progressBus = middleware.NewProgressBus()
+
+type ProgressWrapper struct {
+	unsubscribe func()
+}
+
+// SetProgressEventsAppContext forwards progress events to the frontend.
+func SetProgressEventsAppContext(w *ProgressWrapper, ctx context.Context) {
+	if w == nil {
+		return
+	}
+	if w.unsubscribe != nil {
+		w.unsubscribe()
+	}
+	w.unsubscribe = progressBus.Subscribe(func(ev middleware.ProgressEvent) {
+		runtime.EventsEmit(ctx, progressEvent, ev)
+	})
+}
+
+// ListOperations returns the latest progress of each running operation, so a
+// view opened mid-operation can catch up.
+func (w *ProgressWrapper) ListOperations() ([]middleware.ProgressEvent, error) {
+	return middleware.WithRecoveryResp(func() ([]middleware.ProgressEvent, error) {
+		return progressBus.Operations(), nil
+	})
+}
+
+// CancelOperation cancels a running operation by the ID from its events.
+func (w *ProgressWrapper) CancelOperation(operationID string) error {
+	_, err := middleware.WithRecoveryResp(func() (struct{}, error) {
+		return struct{}{}, progressBus.Cancel(operationID)
+	})
+	return err
+}
+
+// withProgress runs fn as a tracked operation and finishes it with fn's
+// error.
+func withProgress[T any](kind, subject string, fn func(ctx context.Context) (T, error)) (T, error) {
+	ctx, op := progressBus.Start(context.Background(), kind, subject)
+	resp, err := fn(ctx)
+	op.Finish(err)
+	return resp, err
+}
+
+func (w *ProgressWrapper) close() {
+	if w == nil || w.unsubscribe == nil {
+		return
+	}
+	w.unsubscribe()
+	w.unsubscribe = nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+
+	"github.com/flexigpt/flexigpt-app/internal/middleware"
+
+	conversationSpec "github.com/flexigpt/flexigpt-app/internal/conversation/spec"
+	conversationStore "github.com/flexigpt/flexigpt-app/internal/conversation/store"
+	inferencewrapperSpec "github.com/flexigpt/flexigpt-app/internal/inferencewrapper/spec"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	"github.com/flexigpt/flexigpt-app/internal/scheduler/spec"
+	schedulerStore "github.com/flexigpt/flexigpt-app/internal/scheduler/store"
+	skillruntimeSpec "github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
+)
+
+// scheduledTaskMetaKey marks conversations written by a scheduled task.
+const scheduledTaskMetaKey = "scheduledTaskID"
+
+type SchedulerWrapper struct {
+	store *schedulerStore.SchedulerStore
+}
+
+// InitSchedulerWrapper opens the scheduler store and starts its loop. Runs go
+// through the aggregate wrapper and are saved in the conversation store.
+func InitSchedulerWrapper(
+	w *SchedulerWrapper,
+	baseDir string,
+	aggregate *AggregrateWrapper,
+	conversations *ConversationCollectionWrapper,
+) error {
+	if w == nil || aggregate == nil || conversations == nil {
+		panic("initialising SchedulerWrapper on nil receivers")
+	}
+	st, err := schedulerStore.NewSchedulerStore(
+		context.Background(),
+		baseDir,
+		func(ctx context.Context, task spec.ScheduledTask, prompt string) (string, error) {
+			return runScheduledTask(ctx, aggregate, conversations.store, task, prompt)
+		},
+	)
+	if err != nil {
+		return err
+	}
+	w.store = st
+	return nil
+}
+
+func (w *SchedulerWrapper) PostScheduledTask(
+	req *spec.PostScheduledTaskRequest,
+) (*spec.PostScheduledTaskResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.PostScheduledTaskResponse, error) {
+		return w.store.PostScheduledTask(context.Background(), req)
+	})
+}
+
+func (w *SchedulerWrapper) PatchScheduledTask(
+	req *spec.PatchScheduledTaskRequest,
+) (*spec.PatchScheduledTaskResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.PatchScheduledTaskResponse, error) {
+		return w.store.PatchScheduledTask(context.Background(), req)
+	})
+}
+
+func (w *SchedulerWrapper) DeleteScheduledTask(
+	req *spec.DeleteScheduledTaskRequest,
+) (*spec.DeleteScheduledTaskResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.DeleteScheduledTaskResponse, error) {
+		return w.store.DeleteScheduledTask(context.Background(), req)
+	})
+}
+
+func (w *SchedulerWrapper) GetScheduledTask(
+	req *spec.GetScheduledTaskRequest,
+) (*spec.GetScheduledTaskResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GetScheduledTaskResponse, error) {
+		return w.store.GetScheduledTask(context.Background(), req)
+	})
+}
+
+func (w *SchedulerWrapper) ListScheduledTasks(
+	req *spec.ListScheduledTasksRequest,
+) (*spec.ListScheduledTasksResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ListScheduledTasksResponse, error) {
+		return w.store.ListScheduledTasks(context.Background(), req)
+	})
+}
+
+func (w *SchedulerWrapper) ToggleScheduledTask(
+	req *spec.ToggleScheduledTaskRequest,
+) (*spec.ToggleScheduledTaskResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ToggleScheduledTaskResponse, error) {
+		return w.store.ToggleScheduledTask(context.Background(), req)
+	})
+}
+
+func (w *SchedulerWrapper) RunScheduledTaskNow(
+	req *spec.RunScheduledTaskNowRequest,
+) (*spec.RunScheduledTaskNowResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.RunScheduledTaskNowResponse, error) {
+		return w.store.RunScheduledTaskNow(context.Background(), req)
+	})
+}
+
+func (w *SchedulerWrapper) close() {
+	if w == nil || w.store == nil {
+		return
+	}
+	if err := w.store.Close(); err != nil {
+		appLogger.Error("failed to close scheduler store", "error", err)
+	}
+	w.store = nil
+}
+
+// runScheduledTask sends prompt as a single user turn and saves the exchange
+// as a new conversation. A model error is returned after the conversation is
+// saved so the run still links to it.
+func runScheduledTask(
+	ctx context.Context,
+	agg *AggregrateWrapper,
+	conversations *conversationStore.ConversationCollection,
+	task spec.ScheduledTask,
+	prompt string,
+) (string, error) {
+	ref := task.ModelPresetRef
+	presetResp, err := agg.modelPresetStore.GetModelPreset(ctx, &modelpresetSpec.GetModelPresetRequest{
+		ProviderName:  ref.ProviderName,
+		ModelPresetID: ref.ModelPresetID,
+	})
+	if err != nil {
+		return "", err
+	}
+	modelParam := modelParamFromPreset(presetResp.Body.Model)
+
+	var sessionID string
+	if ss := task.SkillSession; ss != nil {
+		sessResp, err := agg.skillRuntime.CreateSkillSession(ctx, &skillruntimeSpec.CreateSkillSessionRequest{
+			Body: &skillruntimeSpec.CreateSkillSessionRequestBody{
+				MaxActivePerSession: ss.MaxActivePerSession,
+				AllowSkillRefs:      ss.AllowSkillRefs,
+				ActiveSkillRefs:     ss.ActiveSkillRefs,
+			},
+		})
+		if err != nil {
+			return "", fmt.Errorf("create skill session: %w", err)
+		}
+		sessionID = string(sessResp.Body.SessionID)
+		defer func() {
+			if _, err := agg.skillRuntime.CloseSkillSession(
+				context.WithoutCancel(ctx),
+				&skillruntimeSpec.CloseSkillSessionRequest{SessionID: sessResp.Body.SessionID},
+			); err != nil {
+				appLogger.Warn("scheduler: close skill session", "taskID", task.ID, "error", err)
+			}
+		}()
+	}
+
+	convID, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	user, err := newUserTextMessage(prompt, ref, modelParam)
+	if err != nil {
+		return "", err
+	}
+	startedAt := user.CreatedAt
+	if task.SkillSession != nil {
+		user.EnabledSkillRefs = task.SkillSession.AllowSkillRefs
+		user.ActiveSkillRefs = task.SkillSession.ActiveSkillRefs
+	}
+
+	resp, runErr := agg.providersetAPI.FetchCompletion(ctx, &inferencewrapperSpec.CompletionRequest{
+		Provider:      ref.ProviderName,
+		ModelPresetID: ref.ModelPresetID,
+		Body: &inferencewrapperSpec.CompletionRequestBody{
+			ModelParam:     &modelParam,
+			Current:        user,
+			SkillSessionID: sessionID,
+			ConversationID: convID.String(),
+		},
+	})
+	if resp == nil || resp.Body == nil || resp.Body.InferenceResponse == nil {
+		if runErr == nil {
+			runErr = errors.New("empty completion response")
+		}
+		return "", runErr
+	}
+	if len(resp.Body.HydratedCurrentInputs) > 0 {
+		user.Inputs = resp.Body.HydratedCurrentInputs
+	}
+
+	assistantMsgID, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	inf := resp.Body.InferenceResponse
+	assistant := conversationSpec.ConversationMessage{
+		ID:             assistantMsgID.String(),
+		CreatedAt:      time.Now().UTC(),
+		Role:           inferenceSpec.RoleAssistant,
+		ModelPresetRef: &ref,
+		Outputs:        inf.Outputs,
+		Usage:          inf.Usage,
+		Error:          inf.Error,
+	}
+	if resp.Body.ServedBy != nil {
+		assistant.ModelPresetRef = resp.Body.ServedBy
+	}
+	if runErr == nil && inf.Error != nil {
+		runErr = errors.New(inf.Error.Message)
+	}
+
+	now := time.Now().UTC()
+	if _, err := conversations.PutConversation(ctx, &conversationSpec.PutConversationRequest{
+		ID: convID.String(),
+		Body: &conversationSpec.PutConversationRequestBody{
+			Title:      fmt.Sprintf("%s - %s", task.DisplayName, startedAt.Local().Format("2006-01-02 15:04")),
+			CreatedAt:  startedAt,
+			ModifiedAt: now,
+			Messages:   []conversationSpec.ConversationMessage{user, assistant},
+			Meta:       map[string]any{scheduledTaskMetaKey: task.ID},
+		},
+	}); err != nil {
+		return "", errors.Join(runErr, fmt.Errorf("save conversation: %w", err))
+	}
+	return convID.String(), runErr
+}
+
+// newUserTextMessage builds a user turn holding a single text input.
+func newUserTextMessage(
+	text string,
+	ref modelpresetSpec.ModelPresetRef,
+	modelParam inferenceSpec.ModelParam,
+) (conversationSpec.ConversationMessage, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return conversationSpec.ConversationMessage{}, err
+	}
+	return conversationSpec.ConversationMessage{
+		ID:             id.String(),
+		CreatedAt:      time.Now().UTC(),
+		Role:           inferenceSpec.RoleUser,
+		ModelParam:     &modelParam,
+		ModelPresetRef: &ref,
+		Inputs: []inferenceSpec.InputUnion{{
+			Kind: inferenceSpec.InputKindInputMessage,
+			InputMessage: &inferenceSpec.InputOutputContent{
+				Role: inferenceSpec.RoleUser,
+				Contents: []inferenceSpec.InputOutputContentItemUnion{{
+					Kind:     inferenceSpec.ContentItemKindText,
+					TextItem: &inferenceSpec.ContentItemText{Text: text},
+				}},
+			},
+		}},
+	}, nil
+}
+
+// modelParamFromPreset fills the request knobs from a model preset, leaving
+// unset ones at their zero value for the provider defaults.
+func modelParamFromPreset(m modelpresetSpec.ModelPreset) inferenceSpec.ModelParam {
+	p := inferenceSpec.ModelParam{
+		Name:                        inferenceSpec.ModelName(m.Name),
+		Temperature:                 m.Temperature,
+		Reasoning:                   m.Reasoning,
+		CacheControl:                m.CacheControl,
+		OutputParam:                 m.OutputParam,
+		AdditionalParametersRawJSON: m.AdditionalParametersRawJSON,
+	}
+	if m.MaxPromptLength != nil {
+		p.MaxPromptLength = *m.MaxPromptLength
+	}
+	if m.MaxOutputLength != nil {
+		p.MaxOutputLength = *m.MaxOutputLength
+	}
+	if m.SystemPrompt != nil {
+		p.SystemPrompt = *m.SystemPrompt
+	}
+	if m.Timeout != nil {
+		p.Timeout = *m.Timeout
+	}
+	if m.StopSequences != nil {
+		p.StopSequences = *m.StopSequences
+	}
+	return p
+}
@@ -3,14 +3,20 @@ package main
 import (
 	"context"
 
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
 	"github.com/flexigpt/flexigpt-app/internal/middleware"
 
 	settingSpec "github.com/flexigpt/flexigpt-app/internal/setting/spec"
 	settingStore "github.com/flexigpt/flexigpt-app/internal/setting/store"
 )
 
+// settingChangedEvent is the Wails event carrying a settingSpec.SettingChangeEvent.
+const settingChangedEvent = "setting:changed"
+
 type SettingStoreWrapper struct {
-	store *settingStore.SettingStore
+	store       *settingStore.SettingStore
+	unsubscribe context.CancelFunc
 }
 
 // InitSettingStoreWrapper boots the underlying store and remembers the pointer.
@@ -25,11 +31,31 @@ func InitSettingStoreWrapper(
 	if err != nil {
 		return err
 	}
+	ss.RegisterPreferenceValidator(skillPreferenceNamespace, validateSkillPreference)
 	w.store = ss
 
 	return nil
 }
 
+// SetSettingEventsAppContext forwards settings changes to the frontend so it
+// does not have to re-fetch GetSettings after every mutation.
+func SetSettingEventsAppContext(w *SettingStoreWrapper, ctx context.Context) {
+	if w == nil || w.store == nil {
+		return
+	}
+	if w.unsubscribe != nil {
+		w.unsubscribe()
+	}
+	subCtx, cancel := context.WithCancel(ctx)
+	w.unsubscribe = cancel
+	events := w.store.Subscribe(subCtx, settingSpec.SettingChangeFilter{})
+	go func() {
+		for ev := range events {
+			runtime.EventsEmit(ctx, settingChangedEvent, ev)
+		}
+	}()
+}
+
 func (w *SettingStoreWrapper) SetAppTheme(
 	req *settingSpec.SetAppThemeRequest,
 ) (*settingSpec.SetAppThemeResponse, error) {
@@ -46,6 +72,30 @@ func (w *SettingStoreWrapper) SetDebugSettings(
 	})
 }
 
+func (w *SettingStoreWrapper) SetNetworkSettings(
+	req *settingSpec.SetNetworkSettingsRequest,
+) (*settingSpec.SetNetworkSettingsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.SetNetworkSettingsResponse, error) {
+		return w.store.SetNetworkSettings(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) SetBudgetSettings(
+	req *settingSpec.SetBudgetSettingsRequest,
+) (*settingSpec.SetBudgetSettingsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.SetBudgetSettingsResponse, error) {
+		return w.store.SetBudgetSettings(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) SetCompletionCacheSettings(
+	req *settingSpec.SetCompletionCacheSettingsRequest,
+) (*settingSpec.SetCompletionCacheSettingsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.SetCompletionCacheSettingsResponse, error) {
+		return w.store.SetCompletionCacheSettings(context.Background(), req)
+	})
+}
+
 func (w *SettingStoreWrapper) GetSettings(
 	req *settingSpec.GetSettingsRequest,
 ) (*settingSpec.GetSettingsResponse, error) {
@@ -58,7 +108,152 @@ func (w *SettingStoreWrapper) GetAuthKey(
 	req *settingSpec.GetAuthKeyRequest,
 ) (*settingSpec.GetAuthKeyResponse, error) {
 	return middleware.WithRecoveryResp(func() (*settingSpec.GetAuthKeyResponse, error) {
-		return w.store.GetAuthKey(context.Background(), req)
+		ctx := settingStore.WithAuthKeyCaller(context.Background(), settingSpec.AuthKeyCallerUI)
+		return w.store.GetAuthKey(ctx, req)
+	})
+}
+
+func (w *SettingStoreWrapper) GetAuthKeyAuditLog(
+	req *settingSpec.GetAuthKeyAuditLogRequest,
+) (*settingSpec.GetAuthKeyAuditLogResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.GetAuthKeyAuditLogResponse, error) {
+		return w.store.GetAuthKeyAuditLog(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) ExportSettings(
+	req *settingSpec.ExportSettingsRequest,
+) (*settingSpec.ExportSettingsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.ExportSettingsResponse, error) {
+		return w.store.ExportSettings(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) ImportSettings(
+	req *settingSpec.ImportSettingsRequest,
+) (*settingSpec.ImportSettingsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.ImportSettingsResponse, error) {
+		return w.store.ImportSettings(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) ListFeatureFlags(
+	req *settingSpec.ListFeatureFlagsRequest,
+) (*settingSpec.ListFeatureFlagsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.ListFeatureFlagsResponse, error) {
+		return w.store.ListFeatureFlags(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) SetFeatureFlag(
+	req *settingSpec.SetFeatureFlagRequest,
+) (*settingSpec.SetFeatureFlagResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.SetFeatureFlagResponse, error) {
+		return w.store.SetFeatureFlag(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) GetPreference(
+	req *settingSpec.GetPreferenceRequest,
+) (*settingSpec.GetPreferenceResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.GetPreferenceResponse, error) {
+		return w.store.GetPreference(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) SetPreference(
+	req *settingSpec.SetPreferenceRequest,
+) (*settingSpec.SetPreferenceResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.SetPreferenceResponse, error) {
+		return w.store.SetPreference(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) ListPreferences(
+	req *settingSpec.ListPreferencesRequest,
+) (*settingSpec.ListPreferencesResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.ListPreferencesResponse, error) {
+		return w.store.ListPreferences(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) PutProfile(
+	req *settingSpec.PutProfileRequest,
+) (*settingSpec.PutProfileResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.PutProfileResponse, error) {
+		return w.store.PutProfile(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) DeleteProfile(
+	req *settingSpec.DeleteProfileRequest,
+) (*settingSpec.DeleteProfileResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.DeleteProfileResponse, error) {
+		return w.store.DeleteProfile(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) GetProfile(
+	req *settingSpec.GetProfileRequest,
+) (*settingSpec.GetProfileResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.GetProfileResponse, error) {
+		return w.store.GetProfile(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) ListProfiles(
+	req *settingSpec.ListProfilesRequest,
+) (*settingSpec.ListProfilesResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.ListProfilesResponse, error) {
+		return w.store.ListProfiles(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) ListTrustedDirectories(
+	req *settingSpec.ListTrustedDirectoriesRequest,
+) (*settingSpec.ListTrustedDirectoriesResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.ListTrustedDirectoriesResponse, error) {
+		return w.store.ListTrustedDirectories(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) AddTrustedDirectory(
+	req *settingSpec.AddTrustedDirectoryRequest,
+) (*settingSpec.AddTrustedDirectoryResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.AddTrustedDirectoryResponse, error) {
+		return w.store.AddTrustedDirectory(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) RemoveTrustedDirectory(
+	req *settingSpec.RemoveTrustedDirectoryRequest,
+) (*settingSpec.RemoveTrustedDirectoryResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.RemoveTrustedDirectoryResponse, error) {
+		return w.store.RemoveTrustedDirectory(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) ConfirmPathTrust(
+	req *settingSpec.ConfirmPathTrustRequest,
+) (*settingSpec.ConfirmPathTrustResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.ConfirmPathTrustResponse, error) {
+		return w.store.ConfirmPathTrust(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) RevokePathTrust(
+	req *settingSpec.RevokePathTrustRequest,
+) (*settingSpec.RevokePathTrustResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.RevokePathTrustResponse, error) {
+		return w.store.RevokePathTrust(context.Background(), req)
+	})
+}
+
+func (w *SettingStoreWrapper) CheckPathTrust(
+	req *settingSpec.CheckPathTrustRequest,
+) (*settingSpec.CheckPathTrustResponse, error) {
+	return middleware.WithRecoveryResp(func() (*settingSpec.CheckPathTrustResponse, error) {
+		return w.store.CheckPathTrust(context.Background(), req)
 	})
 }
 
@@ -66,5 +261,8 @@ func (s *SettingStoreWrapper) close() {
 	if s == nil || s.store == nil {
 		return
 	}
+	if s.unsubscribe != nil {
+		s.unsubscribe()
+	}
 	s.store.Close()
 }
@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
+	"github.com/flexigpt/flexigpt-app/internal/featureflag"
 	"github.com/flexigpt/flexigpt-app/internal/middleware"
+	settingSpec "github.com/flexigpt/flexigpt-app/internal/setting/spec"
 	"github.com/flexigpt/flexigpt-app/internal/skillruntime"
 	skillruntimeSpec "github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
 	"github.com/flexigpt/flexigpt-app/internal/skillstore"
@@ -19,20 +24,121 @@ type SkillStoreWrapper struct {
 	runtime           *skillruntime.SkillRuntime
 	installedProvider skillruntime.Provider
 	provider          skillruntime.Provider
+	trust             skillPathTrust
+	profiles          activeProfileSource
+
+	// bundleRenames are the reserved bundle IDs renamed at startup, old ID to
+	// new ID, reported by HealthCheck.
+	bundleRenames map[string]string
+}
+
+// skillPathTrust gates filesystem skill locations on the workspace trust model.
+type skillPathTrust interface {
+	RequirePathTrust(ctx context.Context, path string) error
+}
+
+// activeProfileSource returns the active settings profile, or nil.
+type activeProfileSource interface {
+	ActiveProfile(ctx context.Context) (*settingSpec.Profile, error)
+}
+
+// Preferences of the skill subsystem in the settings store.
+const (
+	skillPreferenceNamespace = "skills"
+	// skillRegistryURLPreference is a JSON string naming the default skill
+	// registry base URL.
+	skillRegistryURLPreference = "registryURL"
+)
+
+type preferenceSource interface {
+	GetPreference(
+		ctx context.Context,
+		req *settingSpec.GetPreferenceRequest,
+	) (*settingSpec.GetPreferenceResponse, error)
+}
+
+// validateSkillPreference is the preference validator of the skills
+// namespace. Keys it does not know are accepted as is.
+func validateSkillPreference(key string, value json.RawMessage) error {
+	if key != skillRegistryURLPreference {
+		return nil
+	}
+	var registryURL string
+	if err := json.Unmarshal(value, &registryURL); err != nil {
+		return errors.New("registry URL must be a string")
+	}
+	return skillstore.ValidateSkillRegistryURL(registryURL)
 }
 
+// configuredSkillRegistryURL returns the registry URL preference, or "" when
+// it is unset or unusable, as a value stored before validation may be.
+func configuredSkillRegistryURL(ctx context.Context, prefs preferenceSource) string {
+	resp, err := prefs.GetPreference(ctx, &settingSpec.GetPreferenceRequest{
+		Namespace: skillPreferenceNamespace,
+		Key:       skillRegistryURLPreference,
+	})
+	if err != nil {
+		if !errors.Is(err, settingSpec.ErrPreferenceNotFound) {
+			appLogger.Warn("couldn't read the skill registry URL", "error", err)
+		}
+		return ""
+	}
+	var registryURL string
+	if resp.Body == nil || json.Unmarshal(resp.Body.Value, &registryURL) != nil {
+		appLogger.Warn("skill registry URL preference is not a string")
+		return ""
+	}
+	if err := skillstore.ValidateSkillRegistryURL(registryURL); err != nil {
+		appLogger.Warn("ignoring the skill registry URL preference", "error", err)
+		return ""
+	}
+	return registryURL
+}
+
+// InitSkillStoreWrapper opens the skill store and its runtime. Without
+// background the store neither watches its file nor checks skill presence,
+// and no migration runs; the CLI opens it that way. An empty registryURL
+// leaves registry requests to name their registry.
 func InitSkillStoreWrapper(
 	s *SkillStoreWrapper,
 	skillsDir string,
 	workspaceSkills *skilladapter.Adapter,
+	features featureflag.Gate,
+	trust skillPathTrust,
+	profiles activeProfileSource,
+	registryURL string,
+	background bool,
 ) error {
 	if s == nil {
 		return errors.New("skill store wrapper is nil")
 	}
-	st, err := skillstore.NewSkillStore(skillsDir)
+	// Pick up skill store files synced from other machines while running.
+	storeOptions := []skillstore.SkillStoreOption{
+		skillstore.WithFileWatch(background),
+		skillstore.WithPresenceLoop(background),
+		skillstore.WithBackups(storeBackupPolicy),
+		skillstore.WithSkillRegistryURL(registryURL),
+	}
+	if features != nil {
+		storeOptions = append(storeOptions, skillstore.WithFeatureGate(features))
+	}
+	st, err := skillstore.NewSkillStore(skillsDir, storeOptions...)
 	if err != nil {
 		return err
 	}
+	var bundleRenames map[string]string
+	if background {
+		renames, err := st.MigrateReservedSkillBundleIDs(context.Background())
+		if err != nil {
+			appLogger.Error("couldn't migrate reserved skill bundle IDs", "error", err)
+		}
+		for from, to := range renames {
+			if bundleRenames == nil {
+				bundleRenames = map[string]string{}
+			}
+			bundleRenames[string(from)] = string(to)
+		}
+	}
 	runtimeOptions := []skillruntime.SkillRuntimeOption{}
 	if workspaceSkills != nil {
 		runtimeOptions = append(
@@ -50,10 +156,17 @@ func InitSkillStoreWrapper(
 		st.Close()
 		return err
 	}
+	// Edits made to the store file outside the app reach running sessions.
+	st.SetExternalChangeHandler(rt.ScheduleInstalledResync)
+	// SKILL.md edits are rolled back when the runtime cannot index them.
+	st.SetReindexHandler(rt.ResyncInstalledSkill)
 	s.store = st
 	s.runtime = rt
 	s.installedProvider = installed
 	s.provider = installed
+	s.trust = trust
+	s.profiles = profiles
+	s.bundleRenames = bundleRenames
 	return nil
 }
 
@@ -78,9 +191,13 @@ func InitAggregateSkillProvider(
 	return nil
 }
 
+// mutateInstalledSkill runs mutation and then resyncs the installed runtime.
+// A nil refs resyncs every installed skill; otherwise only the skills refs
+// returns for the response are reindexed.
 func mutateInstalledSkill[T any](
 	ctx context.Context,
 	wrapper *SkillStoreWrapper,
+	refs func(T) []spec.SkillRef,
 	mutation func() (T, error),
 ) (T, error) {
 	var zero T
@@ -91,18 +208,34 @@ func mutateInstalledSkill[T any](
 	if err != nil {
 		return zero, err
 	}
-	if err := wrapper.runtime.ResyncInstalled(ctx); err != nil {
+	// Mutations arriving together, like the skills of a bulk import, share
+	// one resync.
+	if refs == nil {
+		err = wrapper.runtime.ScheduleInstalledResync(ctx)
+	} else {
+		err = wrapper.runtime.ScheduleInstalledSkillResync(ctx, refs(response)...)
+	}
+	if err != nil {
 		return zero, fmt.Errorf("sync installed Skills: %w", err)
 	}
 	return response, nil
 }
 
+// requireLocationTrust rejects skill packages registered from outside trusted
+// directories unless the user confirmed the path.
+func (s *SkillStoreWrapper) requireLocationTrust(ctx context.Context, location string) error {
+	if s.trust == nil || strings.TrimSpace(location) == "" {
+		return nil
+	}
+	return s.trust.RequirePathTrust(ctx, location)
+}
+
 func (s *SkillStoreWrapper) PutSkillBundle(
 	req *spec.PutSkillBundleRequest,
 ) (*spec.PutSkillBundleResponse, error) {
 	return middleware.WithRecoveryResp(func() (*spec.PutSkillBundleResponse, error) {
 		ctx := context.Background()
-		return mutateInstalledSkill(ctx, s, func() (*spec.PutSkillBundleResponse, error) {
+		return mutateInstalledSkill(ctx, s, nil, func() (*spec.PutSkillBundleResponse, error) {
 			return s.store.PutSkillBundle(ctx, req)
 		})
 	})
@@ -113,23 +246,62 @@ func (s *SkillStoreWrapper) PatchSkillBundle(
 ) (*spec.PatchSkillBundleResponse, error) {
 	return middleware.WithRecoveryResp(func() (*spec.PatchSkillBundleResponse, error) {
 		ctx := context.Background()
-		return mutateInstalledSkill(ctx, s, func() (*spec.PatchSkillBundleResponse, error) {
+		return mutateInstalledSkill(ctx, s, nil, func() (*spec.PatchSkillBundleResponse, error) {
 			return s.store.PatchSkillBundle(ctx, req)
 		})
 	})
 }
 
+// SetSkillBundleActivationPolicy needs no runtime resync: the policy is read
+// when a session is created.
+func (s *SkillStoreWrapper) SetSkillBundleActivationPolicy(
+	req *spec.SetSkillBundleActivationPolicyRequest,
+) (*spec.SetSkillBundleActivationPolicyResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.SetSkillBundleActivationPolicyResponse, error) {
+		return s.store.SetSkillBundleActivationPolicy(context.Background(), req)
+	})
+}
+
+// RenameSkillBundle needs no runtime resync: runtime skills refer to bundles
+// by ID.
+func (s *SkillStoreWrapper) RenameSkillBundle(
+	req *spec.RenameSkillBundleRequest,
+) (*spec.RenameSkillBundleResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.RenameSkillBundleResponse, error) {
+		return s.store.RenameSkillBundle(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) ResetBuiltInOverrides(
+	req *spec.ResetBuiltInOverridesRequest,
+) (*spec.ResetBuiltInOverridesResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ResetBuiltInOverridesResponse, error) {
+		ctx := context.Background()
+		return mutateInstalledSkill(ctx, s, nil, func() (*spec.ResetBuiltInOverridesResponse, error) {
+			return s.store.ResetBuiltInOverrides(ctx, req)
+		})
+	})
+}
+
 func (s *SkillStoreWrapper) DeleteSkillBundle(
 	req *spec.DeleteSkillBundleRequest,
 ) (*spec.DeleteSkillBundleResponse, error) {
 	return middleware.WithRecoveryResp(func() (*spec.DeleteSkillBundleResponse, error) {
 		ctx := context.Background()
-		return mutateInstalledSkill(ctx, s, func() (*spec.DeleteSkillBundleResponse, error) {
+		return mutateInstalledSkill(ctx, s, nil, func() (*spec.DeleteSkillBundleResponse, error) {
 			return s.store.DeleteSkillBundle(ctx, req)
 		})
 	})
 }
 
+func (s *SkillStoreWrapper) PurgeSkillBundle(
+	req *spec.PurgeSkillBundleRequest,
+) (*spec.PurgeSkillBundleResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.PurgeSkillBundleResponse, error) {
+		return s.store.PurgeSkillBundle(context.Background(), req)
+	})
+}
+
 func (s *SkillStoreWrapper) ListSkillBundles(
 	req *spec.ListSkillBundlesRequest,
 ) (*spec.ListSkillBundlesResponse, error) {
@@ -141,7 +313,15 @@ func (s *SkillStoreWrapper) ListSkillBundles(
 func (s *SkillStoreWrapper) PutSkill(req *spec.PutSkillRequest) (*spec.PutSkillResponse, error) {
 	return middleware.WithRecoveryResp(func() (*spec.PutSkillResponse, error) {
 		ctx := context.Background()
-		return mutateInstalledSkill(ctx, s, func() (*spec.PutSkillResponse, error) {
+		if req != nil && req.Body != nil && req.Body.SkillType == spec.SkillTypeFS {
+			if err := s.requireLocationTrust(ctx, req.Body.Location); err != nil {
+				return nil, err
+			}
+		}
+		refs := func(*spec.PutSkillResponse) []spec.SkillRef {
+			return []spec.SkillRef{{BundleID: req.BundleID, SkillSlug: req.SkillSlug}}
+		}
+		return mutateInstalledSkill(ctx, s, refs, func() (*spec.PutSkillResponse, error) {
 			return s.store.PutSkill(ctx, req)
 		})
 	})
@@ -152,50 +332,421 @@ func (s *SkillStoreWrapper) PutSkillArtifact(
 ) (*spec.PutSkillArtifactResponse, error) {
 	return middleware.WithRecoveryResp(func() (*spec.PutSkillArtifactResponse, error) {
 		ctx := context.Background()
-		return mutateInstalledSkill(ctx, s, func() (*spec.PutSkillArtifactResponse, error) {
+		refs := func(*spec.PutSkillArtifactResponse) []spec.SkillRef {
+			return []spec.SkillRef{{BundleID: req.BundleID, SkillSlug: req.SkillSlug}}
+		}
+		return mutateInstalledSkill(ctx, s, refs, func() (*spec.PutSkillArtifactResponse, error) {
 			return s.store.PutSkillArtifact(ctx, req)
 		})
 	})
 }
 
+// CreateSkillScaffold writes and registers a starter skill. The installed
+// runtime resync that follows validates the new package.
+func (s *SkillStoreWrapper) CreateSkillScaffold(
+	req *spec.CreateSkillScaffoldRequest,
+) (*spec.CreateSkillScaffoldResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.CreateSkillScaffoldResponse, error) {
+		ctx := context.Background()
+		if req != nil && req.Body != nil {
+			if err := s.requireLocationTrust(ctx, req.Body.Directory); err != nil {
+				return nil, err
+			}
+		}
+		refs := func(*spec.CreateSkillScaffoldResponse) []spec.SkillRef {
+			return []spec.SkillRef{{BundleID: req.BundleID, SkillSlug: req.SkillSlug}}
+		}
+		return mutateInstalledSkill(ctx, s, refs, func() (*spec.CreateSkillScaffoldResponse, error) {
+			return s.store.CreateSkillScaffold(ctx, req)
+		})
+	})
+}
+
+func (s *SkillStoreWrapper) ExportSkillBundle(
+	req *spec.ExportSkillBundleRequest,
+) (*spec.ExportSkillBundleResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ExportSkillBundleResponse, error) {
+		return s.store.ExportSkillBundle(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) ImportSkillBundle(
+	req *spec.ImportSkillBundleRequest,
+) (*spec.ImportSkillBundleResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ImportSkillBundleResponse, error) {
+		ctx := context.Background()
+		return mutateInstalledSkill(ctx, s, nil, func() (*spec.ImportSkillBundleResponse, error) {
+			return s.store.ImportSkillBundle(ctx, req)
+		})
+	})
+}
+
+func (s *SkillStoreWrapper) BrowseRegistrySkills(
+	req *spec.BrowseRegistrySkillsRequest,
+) (*spec.BrowseRegistrySkillsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.BrowseRegistrySkillsResponse, error) {
+		return s.store.BrowseRegistrySkills(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) InstallRegistrySkill(
+	req *spec.InstallRegistrySkillRequest,
+) (*spec.InstallRegistrySkillResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.InstallRegistrySkillResponse, error) {
+		subject := ""
+		if req != nil && req.Body != nil {
+			subject = req.Body.Name
+		}
+		return withProgress(progressKindRegistrySkillInstall, subject,
+			func(ctx context.Context) (*spec.InstallRegistrySkillResponse, error) {
+				return mutateInstalledSkill(ctx, s, nil, func() (*spec.InstallRegistrySkillResponse, error) {
+					return s.store.InstallRegistrySkill(ctx, req)
+				})
+			})
+	})
+}
+
+func (s *SkillStoreWrapper) ExportSkillStoreState(
+	req *spec.ExportSkillStoreStateRequest,
+) (*spec.ExportSkillStoreStateResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ExportSkillStoreStateResponse, error) {
+		return s.store.ExportSkillStoreState(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) RestoreSkillStoreState(
+	req *spec.RestoreSkillStoreStateRequest,
+) (*spec.RestoreSkillStoreStateResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.RestoreSkillStoreStateResponse, error) {
+		ctx := context.Background()
+		if req != nil && req.Body != nil && req.Body.DryRun {
+			return s.store.RestoreSkillStoreState(ctx, req)
+		}
+		if req != nil && req.Body != nil {
+			locations, err := skillstore.RestoredSkillLocations(req.Body.State)
+			if err != nil {
+				return nil, err
+			}
+			for _, location := range locations {
+				if err := s.requireLocationTrust(ctx, location); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return mutateInstalledSkill(ctx, s, nil, func() (*spec.RestoreSkillStoreStateResponse, error) {
+			return s.store.RestoreSkillStoreState(ctx, req)
+		})
+	})
+}
+
 func (s *SkillStoreWrapper) PatchSkill(req *spec.PatchSkillRequest) (*spec.PatchSkillResponse, error) {
 	return middleware.WithRecoveryResp(func() (*spec.PatchSkillResponse, error) {
 		ctx := context.Background()
-		return mutateInstalledSkill(ctx, s, func() (*spec.PatchSkillResponse, error) {
+		if req != nil && req.Body != nil && req.Body.Location != nil {
+			if err := s.requireLocationTrust(ctx, *req.Body.Location); err != nil {
+				return nil, err
+			}
+		}
+		refs := func(*spec.PatchSkillResponse) []spec.SkillRef {
+			return []spec.SkillRef{{BundleID: req.BundleID, SkillSlug: req.SkillSlug}}
+		}
+		return mutateInstalledSkill(ctx, s, refs, func() (*spec.PatchSkillResponse, error) {
 			return s.store.PatchSkill(ctx, req)
 		})
 	})
 }
 
+func (s *SkillStoreWrapper) BatchPatchSkills(
+	req *spec.BatchPatchSkillsRequest,
+) (*spec.BatchPatchSkillsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.BatchPatchSkillsResponse, error) {
+		ctx := context.Background()
+		refs := func(response *spec.BatchPatchSkillsResponse) []spec.SkillRef {
+			if response == nil || response.Body == nil {
+				return nil
+			}
+			return response.Body.Patched
+		}
+		return mutateInstalledSkill(ctx, s, refs, func() (*spec.BatchPatchSkillsResponse, error) {
+			return s.store.BatchPatchSkills(ctx, req)
+		})
+	})
+}
+
+func (s *SkillStoreWrapper) CloneSkill(req *spec.CloneSkillRequest) (*spec.CloneSkillResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.CloneSkillResponse, error) {
+		ctx := context.Background()
+		refs := func(*spec.CloneSkillResponse) []spec.SkillRef {
+			target := req.Body.TargetBundleID
+			if target == "" {
+				target = req.BundleID
+			}
+			return []spec.SkillRef{{BundleID: target, SkillSlug: req.Body.NewSkillSlug}}
+		}
+		return mutateInstalledSkill(ctx, s, refs, func() (*spec.CloneSkillResponse, error) {
+			return s.store.CloneSkill(ctx, req)
+		})
+	})
+}
+
+func (s *SkillStoreWrapper) MoveSkill(req *spec.MoveSkillRequest) (*spec.MoveSkillResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.MoveSkillResponse, error) {
+		ctx := context.Background()
+		refs := func(*spec.MoveSkillResponse) []spec.SkillRef {
+			return []spec.SkillRef{
+				{BundleID: req.BundleID, SkillSlug: req.SkillSlug},
+				{BundleID: req.Body.TargetBundleID, SkillSlug: req.SkillSlug},
+			}
+		}
+		return mutateInstalledSkill(ctx, s, refs, func() (*spec.MoveSkillResponse, error) {
+			return s.store.MoveSkill(ctx, req)
+		})
+	})
+}
+
 func (s *SkillStoreWrapper) DeleteSkill(req *spec.DeleteSkillRequest) (*spec.DeleteSkillResponse, error) {
 	return middleware.WithRecoveryResp(func() (*spec.DeleteSkillResponse, error) {
 		ctx := context.Background()
-		return mutateInstalledSkill(ctx, s, func() (*spec.DeleteSkillResponse, error) {
+		refs := func(*spec.DeleteSkillResponse) []spec.SkillRef {
+			return []spec.SkillRef{{BundleID: req.BundleID, SkillSlug: req.SkillSlug}}
+		}
+		return mutateInstalledSkill(ctx, s, refs, func() (*spec.DeleteSkillResponse, error) {
 			return s.store.DeleteSkill(ctx, req)
 		})
 	})
 }
 
+func (s *SkillStoreWrapper) UndeleteSkill(req *spec.UndeleteSkillRequest) (*spec.UndeleteSkillResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.UndeleteSkillResponse, error) {
+		ctx := context.Background()
+		refs := func(*spec.UndeleteSkillResponse) []spec.SkillRef {
+			return []spec.SkillRef{{BundleID: req.BundleID, SkillSlug: req.SkillSlug}}
+		}
+		return mutateInstalledSkill(ctx, s, refs, func() (*spec.UndeleteSkillResponse, error) {
+			return s.store.UndeleteSkill(ctx, req)
+		})
+	})
+}
+
 func (s *SkillStoreWrapper) GetSkill(req *spec.GetSkillRequest) (*spec.GetSkillResponse, error) {
 	return middleware.WithRecoveryResp(func() (*spec.GetSkillResponse, error) {
 		return s.store.GetSkill(context.Background(), req)
 	})
 }
 
+func (s *SkillStoreWrapper) GetSkillsBatch(req *spec.GetSkillsBatchRequest) (*spec.GetSkillsBatchResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GetSkillsBatchResponse, error) {
+		return s.store.GetSkillsBatch(context.Background(), req)
+	})
+}
+
 func (s *SkillStoreWrapper) ListSkills(req *spec.ListSkillsRequest) (*spec.ListSkillsResponse, error) {
 	return middleware.WithRecoveryResp(func() (*spec.ListSkillsResponse, error) {
 		return s.store.ListSkills(context.Background(), req)
 	})
 }
 
+func (s *SkillStoreWrapper) CountSkills(req *spec.CountSkillsRequest) (*spec.CountSkillsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.CountSkillsResponse, error) {
+		return s.store.CountSkills(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) TriggerPresenceCheck(
+	req *spec.TriggerPresenceCheckRequest,
+) (*spec.TriggerPresenceCheckResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.TriggerPresenceCheckResponse, error) {
+		return s.store.TriggerPresenceCheck(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) SearchSkills(req *spec.SearchSkillsRequest) (*spec.SearchSkillsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.SearchSkillsResponse, error) {
+		return s.store.SearchSkills(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) GetSkillStats(req *spec.GetSkillStatsRequest) (*spec.GetSkillStatsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GetSkillStatsResponse, error) {
+		return s.store.GetSkillStats(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) ListSkillConflicts(
+	req *spec.ListSkillConflictsRequest,
+) (*spec.ListSkillConflictsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ListSkillConflictsResponse, error) {
+		return s.store.ListSkillConflicts(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) GetBuiltInSkillDocs(
+	req *spec.GetBuiltInSkillDocsRequest,
+) (*spec.GetBuiltInSkillDocsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GetBuiltInSkillDocsResponse, error) {
+		return s.store.GetBuiltInSkillDocs(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) GetBuiltInSkillsUpdate(
+	req *spec.GetBuiltInSkillsUpdateRequest,
+) (*spec.GetBuiltInSkillsUpdateResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GetBuiltInSkillsUpdateResponse, error) {
+		return s.store.GetBuiltInSkillsUpdate(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) UpdateBuiltInSkills(
+	req *spec.UpdateBuiltInSkillsRequest,
+) (*spec.UpdateBuiltInSkillsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.UpdateBuiltInSkillsResponse, error) {
+		ctx := context.Background()
+		if req != nil && req.Body != nil && req.Body.DryRun {
+			return s.store.UpdateBuiltInSkills(ctx, req)
+		}
+		return mutateInstalledSkill(ctx, s, nil, func() (*spec.UpdateBuiltInSkillsResponse, error) {
+			return s.store.UpdateBuiltInSkills(ctx, req)
+		})
+	})
+}
+
+func (s *SkillStoreWrapper) MarkBuiltInSkillsUpdateSeen(
+	req *spec.MarkBuiltInSkillsUpdateSeenRequest,
+) (*spec.MarkBuiltInSkillsUpdateSeenResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.MarkBuiltInSkillsUpdateSeenResponse, error) {
+		return s.store.MarkBuiltInSkillsUpdateSeen(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) RetrySkillStoreWritable(
+	req *spec.RetrySkillStoreWritableRequest,
+) (*spec.RetrySkillStoreWritableResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.RetrySkillStoreWritableResponse, error) {
+		return s.store.RetrySkillStoreWritable(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) ListSkillStoreBackups(
+	req *spec.ListSkillStoreBackupsRequest,
+) (*spec.ListSkillStoreBackupsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ListSkillStoreBackupsResponse, error) {
+		return s.store.ListSkillStoreBackups(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) RestoreSkillStoreFromBackup(
+	req *spec.RestoreSkillStoreFromBackupRequest,
+) (*spec.RestoreSkillStoreFromBackupResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.RestoreSkillStoreFromBackupResponse, error) {
+		return s.store.RestoreSkillStoreFromBackup(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) GetSkillContent(
+	req *spec.GetSkillContentRequest,
+) (*spec.GetSkillContentResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GetSkillContentResponse, error) {
+		return s.store.GetSkillContent(context.Background(), req)
+	})
+}
+
+// UpdateSkillContent needs no follow-up resync: the store reindexes through
+// the runtime itself so that it can roll the edit back.
+func (s *SkillStoreWrapper) UpdateSkillContent(
+	req *spec.UpdateSkillContentRequest,
+) (*spec.UpdateSkillContentResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.UpdateSkillContentResponse, error) {
+		return s.store.UpdateSkillContent(context.Background(), req)
+	})
+}
+
 func (s *SkillStoreWrapper) CreateSkillSession(
 	req *skillruntimeSpec.CreateSkillSessionRequest,
 ) (*skillruntimeSpec.CreateSkillSessionResponse, error) {
 	return middleware.WithRecoveryResp(func() (*skillruntimeSpec.CreateSkillSessionResponse, error) {
-		return s.runtime.CreateSkillSession(context.Background(), req)
+		ctx := context.Background()
+		req, err := s.restrictToProfileSkills(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return s.runtime.CreateSkillSession(ctx, req)
 	})
 }
 
+// restrictToProfileSkills limits the installed skills a new session may use
+// to the enabled skills of the active profile. A profile without enabled
+// skills allows all of them; workspace skills are not affected.
+func (s *SkillStoreWrapper) restrictToProfileSkills(
+	ctx context.Context,
+	req *skillruntimeSpec.CreateSkillSessionRequest,
+) (*skillruntimeSpec.CreateSkillSessionRequest, error) {
+	if req == nil || req.Body == nil {
+		return req, nil
+	}
+	p, enabled, err := s.profileSkillFilter(ctx)
+	if err != nil || enabled == nil {
+		return req, err
+	}
+	body := *req.Body
+	body.AllowSkillRefs = skillruntime.FilterInstalledSkillRefs(body.AllowSkillRefs, enabled)
+	body.ActiveSkillRefs = skillruntime.FilterInstalledSkillRefs(body.ActiveSkillRefs, enabled)
+	if len(body.AllowSkillRefs) == 0 {
+		return nil, fmt.Errorf("%w: profile %s enables none of the requested skills",
+			skillruntimeSpec.ErrInvalidRequest, p.ID)
+	}
+	return &skillruntimeSpec.CreateSkillSessionRequest{Body: &body}, nil
+}
+
+// requireProfileSkills rejects activating installed skills that the active
+// profile does not enable in a session that already exists.
+func (s *SkillStoreWrapper) requireProfileSkills(ctx context.Context, refs []skillruntimeSpec.SkillRef) error {
+	p, enabled, err := s.profileSkillFilter(ctx)
+	if err != nil || enabled == nil {
+		return err
+	}
+	allowed := skillruntime.FilterInstalledSkillRefs(refs, enabled)
+	if len(allowed) == len(refs) {
+		return nil
+	}
+	var denied []string
+	for _, ref := range refs {
+		if !slices.Contains(allowed, ref) {
+			denied = append(denied, skillRefLabel(ref))
+		}
+	}
+	return fmt.Errorf("%w: profile %s does not enable %s",
+		skillruntimeSpec.ErrInvalidRequest, p.ID, strings.Join(denied, ", "))
+}
+
+// profileSkillFilter returns the active profile and a filter keeping the
+// installed skills it enables. The filter is nil when every skill is allowed.
+func (s *SkillStoreWrapper) profileSkillFilter(
+	ctx context.Context,
+) (*settingSpec.Profile, func(spec.SkillRef) bool, error) {
+	if s.profiles == nil {
+		return nil, nil, nil
+	}
+	p, err := s.profiles.ActiveProfile(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p == nil || len(p.EnabledSkills) == 0 {
+		return p, nil, nil
+	}
+	return p, func(ref spec.SkillRef) bool {
+		return slices.ContainsFunc(p.EnabledSkills, func(e spec.SkillRef) bool {
+			return e.BundleID == ref.BundleID && e.SkillSlug == ref.SkillSlug
+		})
+	}, nil
+}
+
+func skillRefLabel(ref skillruntimeSpec.SkillRef) string {
+	if ref.Identity != "" {
+		return ref.Identity
+	}
+	return string(ref.BundleID) + "/" + string(ref.SkillSlug)
+}
+
 func (s *SkillStoreWrapper) CloseSkillSession(
 	req *skillruntimeSpec.CloseSkillSessionRequest,
 ) (*skillruntimeSpec.CloseSkillSessionResponse, error) {
@@ -204,6 +755,44 @@ func (s *SkillStoreWrapper) CloseSkillSession(
 	})
 }
 
+func (s *SkillStoreWrapper) ListSkillSessions(
+	req *skillruntimeSpec.ListSkillSessionsRequest,
+) (*skillruntimeSpec.ListSkillSessionsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*skillruntimeSpec.ListSkillSessionsResponse, error) {
+		return s.runtime.ListSkillSessions(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) CloseAllSkillSessions(
+	req *skillruntimeSpec.CloseAllSkillSessionsRequest,
+) (*skillruntimeSpec.CloseAllSkillSessionsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*skillruntimeSpec.CloseAllSkillSessionsResponse, error) {
+		return s.runtime.CloseAllSkillSessions(context.Background(), req)
+	})
+}
+
+func (s *SkillStoreWrapper) ActivateSkillInSession(
+	req *skillruntimeSpec.ActivateSkillInSessionRequest,
+) (*skillruntimeSpec.ActivateSkillInSessionResponse, error) {
+	return middleware.WithRecoveryResp(func() (*skillruntimeSpec.ActivateSkillInSessionResponse, error) {
+		ctx := context.Background()
+		if req != nil && req.Body != nil {
+			if err := s.requireProfileSkills(ctx, req.Body.SkillRefs); err != nil {
+				return nil, err
+			}
+		}
+		return s.runtime.ActivateSkillInSession(ctx, req)
+	})
+}
+
+func (s *SkillStoreWrapper) DeactivateSkillInSession(
+	req *skillruntimeSpec.DeactivateSkillInSessionRequest,
+) (*skillruntimeSpec.DeactivateSkillInSessionResponse, error) {
+	return middleware.WithRecoveryResp(func() (*skillruntimeSpec.DeactivateSkillInSessionResponse, error) {
+		return s.runtime.DeactivateSkillInSession(context.Background(), req)
+	})
+}
+
 func (s *SkillStoreWrapper) GetSkillsPrompt(
 	req *skillruntimeSpec.GetSkillsPromptRequest,
 ) (*skillruntimeSpec.GetSkillsPromptResponse, error) {
@@ -220,6 +809,14 @@ func (s *SkillStoreWrapper) ListRuntimeSkills(
 	})
 }
 
+func (s *SkillStoreWrapper) GetSkillRuntimeStatus(
+	req *skillruntimeSpec.GetSkillRuntimeStatusRequest,
+) (*skillruntimeSpec.GetSkillRuntimeStatusResponse, error) {
+	return middleware.WithRecoveryResp(func() (*skillruntimeSpec.GetSkillRuntimeStatusResponse, error) {
+		return s.runtime.GetSkillRuntimeStatus(context.Background(), req)
+	})
+}
+
 func (s *SkillStoreWrapper) RenderSkill(
 	req *skillruntimeSpec.RenderSkillRequest,
 ) (*skillruntimeSpec.RenderSkillResponse, error) {
@@ -288,6 +885,11 @@ func (s *SkillStoreWrapper) close() {
 	if s == nil || s.store == nil {
 		return
 	}
+	if s.runtime != nil {
+		if err := s.runtime.FlushSkillActivations(context.Background()); err != nil {
+			appLogger.Warn("flush skill activations on close", "error", err)
+		}
+	}
 	s.store.Close()
 	s.runtime = nil
 	s.store = nil
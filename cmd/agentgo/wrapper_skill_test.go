@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+
+	settingSpec "github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	skillruntimeSpec "github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+type staticProfile struct{ p *settingSpec.Profile }
+
+func (s staticProfile) ActiveProfile(context.Context) (*settingSpec.Profile, error) { return s.p, nil }
+
+func TestRestrictToProfileSkills(t *testing.T) {
+	req := &skillruntimeSpec.CreateSkillSessionRequest{Body: &skillruntimeSpec.CreateSkillSessionRequestBody{
+		AllowSkillRefs: []skillruntimeSpec.SkillRef{
+			{BundleID: "b", SkillSlug: "docs", SkillID: "1"},
+			{BundleID: "b", SkillSlug: "deploy", SkillID: "2"},
+			{Identity: "workspace/root/rec"},
+		},
+		ActiveSkillRefs: []skillruntimeSpec.SkillRef{{BundleID: "b", SkillSlug: "deploy", SkillID: "2"}},
+	}}
+
+	w := &SkillStoreWrapper{profiles: staticProfile{}}
+	if got, err := w.restrictToProfileSkills(t.Context(), req); err != nil || got != req {
+		t.Fatalf("without a profile = %+v, %v", got, err)
+	}
+
+	w.profiles = staticProfile{p: &settingSpec.Profile{
+		ID:            "docs",
+		EnabledSkills: []spec.SkillRef{{BundleID: "b", SkillSlug: "docs"}},
+	}}
+	got, err := w.restrictToProfileSkills(t.Context(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Body.AllowSkillRefs) != 2 || got.Body.AllowSkillRefs[0].SkillSlug != "docs" ||
+		got.Body.AllowSkillRefs[1].Identity == "" {
+		t.Fatalf("allow = %+v, want docs and the workspace skill", got.Body.AllowSkillRefs)
+	}
+	if len(got.Body.ActiveSkillRefs) != 0 {
+		t.Fatalf("active = %+v, want none", got.Body.ActiveSkillRefs)
+	}
+	if len(req.Body.AllowSkillRefs) != 3 {
+		t.Fatal("caller's request was modified")
+	}
+
+	only := &skillruntimeSpec.CreateSkillSessionRequest{Body: &skillruntimeSpec.CreateSkillSessionRequestBody{
+		AllowSkillRefs: []skillruntimeSpec.SkillRef{{BundleID: "b", SkillSlug: "deploy", SkillID: "2"}},
+	}}
+	if _, err := w.restrictToProfileSkills(t.Context(), only); !errors.Is(err, skillruntimeSpec.ErrInvalidRequest) {
+		t.Fatalf("no enabled skill left = %v, want ErrInvalidRequest", err)
+	}
+}
+
+func TestRequireProfileSkills(t *testing.T) {
+	refs := []skillruntimeSpec.SkillRef{
+		{BundleID: "b", SkillSlug: "docs", SkillID: "1"},
+		{Identity: "workspace/root/rec"},
+	}
+	w := &SkillStoreWrapper{profiles: staticProfile{}}
+	if err := w.requireProfileSkills(t.Context(),
+		append(refs, skillruntimeSpec.SkillRef{BundleID: "b", SkillSlug: "deploy"})); err != nil {
+		t.Fatalf("without a profile = %v", err)
+	}
+
+	w.profiles = staticProfile{p: &settingSpec.Profile{
+		ID:            "docs",
+		EnabledSkills: []spec.SkillRef{{BundleID: "b", SkillSlug: "docs"}},
+	}}
+	if err := w.requireProfileSkills(t.Context(), refs); err != nil {
+		t.Fatalf("enabled and workspace skills = %v", err)
+	}
+	err := w.requireProfileSkills(t.Context(),
+		append(refs, skillruntimeSpec.SkillRef{BundleID: "b", SkillSlug: "deploy", SkillID: "2"}))
+	if !errors.Is(err, skillruntimeSpec.ErrInvalidRequest) || !strings.Contains(err.Error(), "b/deploy") {
+		t.Fatalf("skill outside the profile = %v, want ErrInvalidRequest naming it", err)
+	}
+
+	w.runtime = nil
+	_, err = w.ActivateSkillInSession(&skillruntimeSpec.ActivateSkillInSessionRequest{
+		Body: &skillruntimeSpec.UpdateSessionSkillsRequestBody{
+			SessionID: "s",
+			SkillRefs: []skillruntimeSpec.SkillRef{{BundleID: "b", SkillSlug: "deploy"}},
+		},
+	})
+	if !errors.Is(err, skillruntimeSpec.ErrInvalidRequest) {
+		t.Fatalf("ActivateSkillInSession = %v, want ErrInvalidRequest before the runtime is used", err)
+	}
+}
+
+func TestOpenCLISkills_UsesConfiguredRegistryURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/registry/index.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"skills":[{"name":"notes","description":"Take notes."}]}`))
+	}))
+	defer srv.Close()
+	keyring.MockInit()
+	dir := t.TempDir()
+
+	a := newApp(dir)
+	if err := a.openCLISettings(); err != nil {
+		t.Fatalf("openCLISettings: %v", err)
+	}
+	// The settings store rejects values the skill store could not use.
+	if _, err := a.settingStoreAPI.store.SetPreference(t.Context(), &settingSpec.SetPreferenceRequest{
+		Namespace: skillPreferenceNamespace,
+		Key:       skillRegistryURLPreference,
+		Body:      &settingSpec.SetPreferenceRequestBody{Value: json.RawMessage(`"ftp://example.com"`)},
+	}); !errors.Is(err, settingSpec.ErrInvalidPreference) {
+		t.Fatalf("SetPreference(ftp URL) = %v, want ErrInvalidPreference", err)
+	}
+	value, err := json.Marshal(srv.URL + "/registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.settingStoreAPI.store.SetPreference(t.Context(), &settingSpec.SetPreferenceRequest{
+		Namespace: skillPreferenceNamespace,
+		Key:       skillRegistryURLPreference,
+		Body:      &settingSpec.SetPreferenceRequestBody{Value: value},
+	}); err != nil {
+		t.Fatalf("SetPreference: %v", err)
+	}
+	a.shutdown(t.Context())
+
+	a = newApp(dir)
+	if err := a.openCLISkills(); err != nil {
+		t.Fatalf("openCLISkills: %v", err)
+	}
+	defer a.shutdown(t.Context())
+	resp, err := a.skillStoreAPI.store.BrowseRegistrySkills(t.Context(), &spec.BrowseRegistrySkillsRequest{})
+	if err != nil {
+		t.Fatalf("BrowseRegistrySkills: %v", err)
+	}
+	if len(resp.Body.Skills) != 1 || resp.Body.Skills[0].Name != "notes" {
+		t.Fatalf("skills = %+v", resp.Body.Skills)
+	}
+}
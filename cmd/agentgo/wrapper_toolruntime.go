@@ -17,8 +17,9 @@ type ToolRuntimeWrapper struct {
 func InitToolRuntimeWrapper(
 	trw *ToolRuntimeWrapper,
 	store *toolStore.ToolStore,
+	toolRunsDir string,
 ) error {
-	tr := toolruntime.NewToolRuntime(store)
+	tr := toolruntime.NewToolRuntime(store, toolruntime.WithRunAuditDir(toolRunsDir))
 	trw.store = store
 	trw.tr = tr
 	return nil
@@ -31,3 +32,19 @@ func (trw *ToolRuntimeWrapper) InvokeTool(
 		return trw.tr.InvokeTool(context.Background(), req)
 	})
 }
+
+func (trw *ToolRuntimeWrapper) ListToolRuns(
+	req *spec.ListToolRunsRequest,
+) (*spec.ListToolRunsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ListToolRunsResponse, error) {
+		return trw.tr.ListToolRuns(context.Background(), req)
+	})
+}
+
+func (trw *ToolRuntimeWrapper) ReplayToolRun(
+	req *spec.ReplayToolRunRequest,
+) (*spec.ReplayToolRunResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ReplayToolRunResponse, error) {
+		return trw.tr.ReplayToolRun(context.Background(), req)
+	})
+}
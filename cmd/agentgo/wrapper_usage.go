@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+
+	"github.com/flexigpt/flexigpt-app/internal/middleware"
+	"github.com/flexigpt/flexigpt-app/internal/usage/spec"
+	usageStore "github.com/flexigpt/flexigpt-app/internal/usage/store"
+)
+
+type UsageStoreWrapper struct {
+	store *usageStore.UsageStore
+}
+
+func InitUsageStoreWrapper(
+	w *UsageStoreWrapper,
+	baseDir string,
+) error {
+	if w == nil {
+		panic("initialising UsageStoreWrapper on nil receiver")
+	}
+	st, err := usageStore.NewUsageStore(context.Background(), baseDir)
+	if err != nil {
+		return err
+	}
+	w.store = st
+	return nil
+}
+
+func (w *UsageStoreWrapper) GetUsageSummary(
+	req *spec.GetUsageSummaryRequest,
+) (*spec.GetUsageSummaryResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.GetUsageSummaryResponse, error) {
+		return w.store.GetUsageSummary(context.Background(), req)
+	})
+}
+
+func (w *UsageStoreWrapper) ListUsageRecords(
+	req *spec.ListUsageRecordsRequest,
+) (*spec.ListUsageRecordsResponse, error) {
+	return middleware.WithRecoveryResp(func() (*spec.ListUsageRecordsResponse, error) {
+		return w.store.ListUsageRecords(context.Background(), req)
+	})
+}
+
+func (w *UsageStoreWrapper) close() {
+	if w == nil || w.store == nil {
+		return
+	}
+	if err := w.store.Close(); err != nil {
+		appLogger.Error("failed to close usage store", "error", err)
+	}
+	w.store = nil
+}
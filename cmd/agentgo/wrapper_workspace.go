@@ -186,15 +186,24 @@ func (w *WorkspaceWrapper) RefreshWorkspace(
 	request *workspace.RefreshWorkspaceRequest,
 ) (*workspace.RefreshWorkspaceResponse, error) {
 	return middleware.WithRecoveryResp(func() (*workspace.RefreshWorkspaceResponse, error) {
-		ctx := context.Background()
-		response, err := w.api.RefreshWorkspace(ctx, request)
-		if err != nil {
-			return nil, err
+		subject := ""
+		if request != nil {
+			subject = string(request.RootID)
 		}
-		if err := w.syncWorkspaceSkills(ctx, request.RootID); err != nil {
-			return nil, err
-		}
-		return response, nil
+		return withProgress(progressKindWorkspaceRefresh, subject,
+			func(ctx context.Context) (*workspace.RefreshWorkspaceResponse, error) {
+				op := middleware.ProgressFromContext(ctx)
+				op.Report("refreshing workspace", 0, 2)
+				response, err := w.api.RefreshWorkspace(ctx, request)
+				if err != nil {
+					return nil, err
+				}
+				op.Report("syncing workspace skills", 1, 2)
+				if err := w.syncWorkspaceSkills(ctx, request.RootID); err != nil {
+					return nil, err
+				}
+				return response, nil
+			})
 	})
 }
 
@@ -1509,6 +1509,12 @@ function AddEditModelPresetModalContent({
 							<div className="grid grid-cols-12 items-center gap-2">
 								<label htmlFor="model-preset-system-prompt" className="label col-span-3">
 									<span className="text-sm">System Prompt</span>
+									<span
+										className="tooltip tooltip-right"
+										data-tip="Supports {{date}}, {{time}}, {{weekday}}, {{modelName}} and {{providerName}}."
+									>
+										<FiHelpCircle size={12} />
+									</span>
 								</label>
 								<div className="col-span-9">
 									<textarea
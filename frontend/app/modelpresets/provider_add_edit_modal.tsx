@@ -174,6 +174,19 @@ function AddEditProviderPresetModalContent({
 				isEnabled: true,
 				displayName: SDK_DISPLAY_NAME[ProviderSDKType.ProviderSDKTypeGoogleGenerateContent],
 			},
+			[ProviderSDKType.ProviderSDKTypeOllama]: {
+				isEnabled: true,
+				displayName: SDK_DISPLAY_NAME[ProviderSDKType.ProviderSDKTypeOllama],
+			},
+			[ProviderSDKType.ProviderSDKTypeLlamaCPP]: {
+				isEnabled: true,
+				displayName: SDK_DISPLAY_NAME[ProviderSDKType.ProviderSDKTypeLlamaCPP],
+			},
+			// Azure providers need resource, deployment and API version, which this form does not collect.
+			[ProviderSDKType.ProviderSDKTypeAzureOpenAI]: {
+				isEnabled: false,
+				displayName: SDK_DISPLAY_NAME[ProviderSDKType.ProviderSDKTypeAzureOpenAI],
+			},
 		}),
 		[]
 	);
@@ -7,6 +7,9 @@ export enum ProviderSDKType {
 	ProviderSDKTypeOpenAIChatCompletions = 'providerSDKTypeOpenAIChatCompletions',
 	ProviderSDKTypeOpenAIResponses = 'providerSDKTypeOpenAIResponses',
 	ProviderSDKTypeGoogleGenerateContent = 'providerSDKTypeGoogleGenerateContent',
+	ProviderSDKTypeOllama = 'providerSDKTypeOllama',
+	ProviderSDKTypeLlamaCPP = 'providerSDKTypeLlamaCPP',
+	ProviderSDKTypeAzureOpenAI = 'providerSDKTypeAzureOpenAI',
 }
 
 export const SDK_DISPLAY_NAME: Record<ProviderSDKType, string> = {
@@ -14,6 +17,9 @@ export const SDK_DISPLAY_NAME: Record<ProviderSDKType, string> = {
 	[ProviderSDKType.ProviderSDKTypeOpenAIChatCompletions]: 'OpenAI ChatCompletions API',
 	[ProviderSDKType.ProviderSDKTypeOpenAIResponses]: 'OpenAI Responses API',
 	[ProviderSDKType.ProviderSDKTypeGoogleGenerateContent]: 'Google GenAI API',
+	[ProviderSDKType.ProviderSDKTypeOllama]: 'Ollama (local)',
+	[ProviderSDKType.ProviderSDKTypeLlamaCPP]: 'llama.cpp server (local)',
+	[ProviderSDKType.ProviderSDKTypeAzureOpenAI]: 'Azure OpenAI',
 };
 
 export const SDK_DEFAULTS: Record<
@@ -49,6 +55,28 @@ export const SDK_DEFAULTS: Record<
 			'Content-Type': 'application/json',
 		},
 	},
+	[ProviderSDKType.ProviderSDKTypeOllama]: {
+		chatPath: '/v1/chat/completions',
+		apiKeyHeaderKey: 'Authorization',
+		defaultHeaders: {
+			'Content-Type': 'application/json',
+		},
+	},
+	[ProviderSDKType.ProviderSDKTypeLlamaCPP]: {
+		chatPath: '/v1/chat/completions',
+		apiKeyHeaderKey: 'Authorization',
+		defaultHeaders: {
+			'Content-Type': 'application/json',
+		},
+	},
+	// The chat path is derived from the deployment by the backend.
+	[ProviderSDKType.ProviderSDKTypeAzureOpenAI]: {
+		chatPath: '',
+		apiKeyHeaderKey: 'api-key',
+		defaultHeaders: {
+			'Content-Type': 'application/json',
+		},
+	},
 };
 
 export enum RoleEnum {
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime/debug"
@@ -17,11 +16,14 @@ import (
 	"github.com/flexigpt/flexigpt-app/internal/assistantpreset/spec"
 	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
 	"github.com/flexigpt/flexigpt-app/internal/jsonutil"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 	"github.com/flexigpt/mapstore-go"
 	"github.com/flexigpt/mapstore-go/jsonencdec"
 	"github.com/flexigpt/mapstore-go/uuidv7filename"
 )
 
+var logger = logging.Module("assistantpreset.store")
+
 const (
 	maxPageSizeAssistantPresets           = 256
 	defaultPageSizeAssistantPresets       = 25
@@ -139,14 +141,14 @@ func NewAssistantPresetStore(baseDir string, opts ...Option) (*AssistantPresetSt
 		jsonencdec.JSONEncoderDecoder{},
 		mapstore.WithCreateIfNotExists(true),
 		mapstore.WithFileAutoFlush(true),
-		mapstore.WithFileLogger(slog.Default()),
+		mapstore.WithFileLogger(logger),
 	)
 	if err != nil {
 		_ = s.builtinData.Close()
 		return nil, err
 	}
 
-	dirOpts := []mapstore.DirOption{mapstore.WithDirLogger(slog.Default())}
+	dirOpts := []mapstore.DirOption{mapstore.WithDirLogger(logger)}
 	s.presetStore, err = mapstore.NewMapDirectoryStore(
 		s.baseDir,
 		true,
@@ -163,7 +165,7 @@ func NewAssistantPresetStore(baseDir string, opts ...Option) (*AssistantPresetSt
 	s.slugLock = newSlugLocks()
 	s.startCleanupLoop()
 
-	slog.Info("assistant-preset-store ready", "baseDir", s.baseDir)
+	logger.Info("assistant-preset-store ready", "baseDir", s.baseDir)
 	return s, nil
 }
 
@@ -275,7 +277,7 @@ func (s *AssistantPresetStore) PutAssistantPresetBundle(
 		return nil, err
 	}
 
-	slog.Info("putAssistantPresetBundle", "bundleID", req.BundleID)
+	logger.Info("putAssistantPresetBundle", "bundleID", req.BundleID)
 	return &spec.PutAssistantPresetBundleResponse{}, nil
 }
 
@@ -296,7 +298,7 @@ func (s *AssistantPresetStore) PatchAssistantPresetBundle(
 			); err != nil {
 				return nil, err
 			}
-			slog.Info(
+			logger.Info(
 				"patchAssistantPresetBundle",
 				"bundleID",
 				req.BundleID,
@@ -337,7 +339,7 @@ func (s *AssistantPresetStore) PatchAssistantPresetBundle(
 		return nil, err
 	}
 
-	slog.Info(
+	logger.Info(
 		"patchAssistantPresetBundle",
 		"bundleID",
 		req.BundleID,
@@ -411,7 +413,7 @@ func (s *AssistantPresetStore) DeleteAssistantPresetBundle(
 	}
 
 	s.kickCleanupLoop()
-	slog.Info("deleteAssistantPresetBundle", "bundleID", req.BundleID)
+	logger.Info("deleteAssistantPresetBundle", "bundleID", req.BundleID)
 	return &spec.DeleteAssistantPresetBundleResponse{}, nil
 }
 
@@ -639,7 +641,7 @@ func (s *AssistantPresetStore) PutAssistantPreset(
 		return nil, err
 	}
 
-	slog.Info(
+	logger.Info(
 		"putAssistantPreset",
 		"bundleID",
 		req.BundleID,
@@ -689,7 +691,7 @@ func (s *AssistantPresetStore) PatchAssistantPreset(
 		); err != nil {
 			return nil, err
 		}
-		slog.Info(
+		logger.Info(
 			"patchAssistantPreset",
 			"bundleID",
 			req.BundleID,
@@ -744,7 +746,7 @@ func (s *AssistantPresetStore) PatchAssistantPreset(
 		return nil, err
 	}
 
-	slog.Info(
+	logger.Info(
 		"patchAssistantPreset",
 		"bundleID",
 		req.BundleID,
@@ -806,7 +808,7 @@ func (s *AssistantPresetStore) DeleteAssistantPreset(
 		return nil, err
 	}
 
-	slog.Info(
+	logger.Info(
 		"deleteAssistantPreset",
 		"bundleID",
 		req.BundleID,
@@ -1172,7 +1174,7 @@ func (s *AssistantPresetStore) startCleanupLoop() {
 
 			defer func() {
 				if r := recover(); r != nil {
-					slog.Error(
+					logger.Error(
 						"panic in assistant preset bundle cleanup loop",
 						"err",
 						r,
@@ -1203,7 +1205,7 @@ func (s *AssistantPresetStore) sweepSoftDeleted() {
 
 	all, err := s.readAllBundles(false)
 	if err != nil {
-		slog.Error("assistant preset sweep readAllBundles failed", "err", err)
+		logger.Error("assistant preset sweep readAllBundles failed", "err", err)
 		return
 	}
 
@@ -1220,7 +1222,7 @@ func (s *AssistantPresetStore) sweepSoftDeleted() {
 
 		dirInfo, err := bundleitemutils.BuildBundleDir(bundle.ID, bundle.Slug)
 		if err != nil {
-			slog.Error(
+			logger.Error(
 				"assistant preset sweep BuildBundleDir failed",
 				"bundleID",
 				id,
@@ -1238,7 +1240,7 @@ func (s *AssistantPresetStore) sweepSoftDeleted() {
 			"",
 		)
 		if err != nil || len(files) != 0 {
-			slog.Warn(
+			logger.Warn(
 				"assistant preset sweep skipped non-empty bundle",
 				"bundleID",
 				id,
@@ -1252,12 +1254,12 @@ func (s *AssistantPresetStore) sweepSoftDeleted() {
 		changed = true
 		_ = os.RemoveAll(filepath.Join(s.baseDir, dirInfo.DirName))
 
-		slog.Info("hard-deleted assistant preset bundle", "bundleID", id)
+		logger.Info("hard-deleted assistant preset bundle", "bundleID", id)
 	}
 
 	if changed {
 		if err := s.writeAllBundles(all); err != nil {
-			slog.Error("assistant preset sweep writeAllBundles failed", "err", err)
+			logger.Error("assistant preset sweep writeAllBundles failed", "err", err)
 		}
 	}
 }
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"net/url"
 	"path/filepath"
 	"strings"
@@ -41,6 +40,10 @@ func BuildAttachmentForFile(ctx context.Context, pathInfo *PathInfo) (*Attachmen
 	baseMIMEType := toolOut.BaseMIMEType
 	extMode := toolOut.Mode
 	baseName := filepath.Base(pathInfo.Path)
+	if isHEICPath(pathInfo.Path) {
+		return nil, fmt.Errorf("%w: %s is a HEIC/HEIF image; convert it to JPEG or PNG to attach it",
+			ErrUnsupportedImage, baseName)
+	}
 
 	switch extMode {
 	case fstool.MIMEModeImage:
@@ -86,20 +89,21 @@ func BuildAttachmentForFile(ctx context.Context, pathInfo *PathInfo) (*Attachmen
 
 	case fstool.MIMEModeDocument:
 		// Documents (PDF, Office, etc.).
-		// As of now APIs and we internally only support PDF docs.
-		// PDFs can be treated as text (with extraction) or as original file.
-		if MIMEType(baseMIMEType) != MIMEApplicationPDF {
+		// Documents with a registered extractor can be attached as text.
+		// Only PDFs are accepted as original files by the APIs.
+		if !HasContentExtractor(MIMEType(baseMIMEType)) {
 			return buildUnreadableFileAttachment(*pathInfo), nil
 		}
+		modes := []AttachmentContentBlockMode{AttachmentContentBlockModeText}
+		if MIMEType(baseMIMEType) == MIMEApplicationPDF {
+			modes = append(modes, AttachmentContentBlockModeFile)
+		}
 
 		att := &Attachment{
-			Kind:  AttachmentFile,
-			Label: baseName,
-			Mode:  AttachmentContentBlockModeText,
-			AvailableContentBlockModes: []AttachmentContentBlockMode{
-				AttachmentContentBlockModeText,
-				AttachmentContentBlockModeFile,
-			},
+			Kind:                       AttachmentFile,
+			Label:                      baseName,
+			Mode:                       AttachmentContentBlockModeText,
+			AvailableContentBlockModes: modes,
 			FileRef: &FileRef{
 				PathInfo: *pathInfo,
 			},
@@ -283,7 +287,7 @@ func BuildContentBlocks(ctx context.Context, atts []Attachment, opts ...ContentB
 			case errors.Is(err, ErrExistingContentBlock):
 				// If content block already existed we should just reattach it.
 				b = att.ContentBlock
-				slog.Warn("got existing att", "a", att)
+				logger.Warn("got existing att", "a", att)
 
 			case errors.Is(err, ErrAttachmentModifiedSinceSnapshot) && !buildContentOptions.OverrideOriginal:
 				displayBlock, err := att.GetTextBlockWithDisplayNameOnly(
@@ -294,13 +298,26 @@ func BuildContentBlocks(ctx context.Context, atts []Attachment, opts ...ContentB
 				}
 				b = displayBlock
 			default:
-				slog.Warn("failed to build content block for attachment", "err", err, "attachment", att)
+				logger.Warn("failed to build content block for attachment", "err", err, "attachment", att)
 				// Skip this content block. It is ok if the build block skipped this because OnlyIfTextKind was set or
 				// any other error.
 				continue
 			}
 		}
 
+		if p := buildContentOptions.ImageProcessing; p != nil {
+			processed, err := processImageBlock(b, *p)
+			if errors.Is(err, ErrUnsupportedImage) || errors.Is(err, ErrImageTooLarge) {
+				// The provider would reject or mangle the image as is.
+				return nil, fmt.Errorf("image attachment %q: %w", att.Label, err)
+			}
+			if err != nil {
+				logger.Warn("failed to process image attachment; sending it as is", "err", err, "attachment", att.Label)
+			} else {
+				b = processed
+			}
+		}
+
 		blocks = append(blocks, *b)
 	}
 
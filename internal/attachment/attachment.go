@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 )
 
+var logger = logging.Module("attachment")
+
 // Attachment is a lightweight reference to external context (files, docs, images, etc.).
 type Attachment struct {
 	Kind  AttachmentKind `json:"kind"`
@@ -24,6 +28,7 @@ type Attachment struct {
 	ImageRef   *ImageRef   `json:"imageRef,omitempty"`
 	URLRef     *URLRef     `json:"urlRef,omitempty"`
 	GenericRef *GenericRef `json:"genericRef,omitempty"`
+	DirDiffRef *DirDiffRef `json:"dirDiffRef,omitempty"`
 
 	ContentBlock *ContentBlock `json:"contentBlock,omitempty"`
 }
@@ -32,6 +37,7 @@ type buildContentBlockOptions struct {
 	OverrideOriginal bool
 	OnlyIfTextKind   bool
 	ForceFetch       bool
+	ImageProcessing  *ImageProcessing
 }
 
 type ContentBlockOption func(*buildContentBlockOptions)
@@ -115,6 +121,17 @@ func (att *Attachment) BuildContentBlock(ctx context.Context, opts ...ContentBlo
 		}
 		return att.URLRef.BuildContentBlock(ctx, att.Mode, buildContentOptions.OnlyIfTextKind)
 
+	case AttachmentDirDiff:
+		if att.DirDiffRef == nil {
+			return nil, errors.New("invalid dir diff ref for attachment")
+		}
+		cb, err := att.DirDiffRef.BuildContentBlock(ctx)
+		if err != nil {
+			return nil, err
+		}
+		att.populateContentBlockSource(cb)
+		return cb, nil
+
 	default:
 		return nil, errors.New("unknown attachment kind")
 	}
@@ -180,6 +197,22 @@ func (att *Attachment) PopulateRef(ctx context.Context, replaceOrig bool) error
 		}
 		return nil
 
+	case AttachmentDirDiff:
+		if att.DirDiffRef == nil {
+			return errors.New("no dir diff ref for dir diff attachment")
+		}
+		if err := att.DirDiffRef.PopulateRef(ctx, replaceOrig); err != nil {
+			return err
+		}
+		if att.Label == "" {
+			att.Label = filepath.Base(att.DirDiffRef.OldDirPath) + " → " + filepath.Base(att.DirDiffRef.NewDirPath)
+		}
+		if att.Mode == "" {
+			att.Mode = AttachmentContentBlockModeDirDiff
+			att.AvailableContentBlockModes = []AttachmentContentBlockMode{AttachmentContentBlockModeDirDiff}
+		}
+		return nil
+
 	default:
 		return errors.New("unknown attachment kind")
 	}
@@ -253,6 +286,15 @@ func (att *Attachment) populateContentBlockSource(cb *ContentBlock) {
 		if rawURL != "" {
 			cb.URL = &rawURL
 		}
+
+	case AttachmentDirDiff:
+		if att.DirDiffRef == nil {
+			return
+		}
+
+		if name := strings.TrimSpace(att.Label); name != "" {
+			cb.FileName = &name
+		}
 	default:
 		return
 	}
@@ -284,6 +326,10 @@ func (att *Attachment) formatAsDisplayName() string {
 		if att.GenericRef != nil {
 			detail = strings.TrimSpace(att.GenericRef.Handle)
 		}
+	case AttachmentDirDiff:
+		if att.DirDiffRef != nil {
+			detail = att.DirDiffRef.OldDirPath + " -> " + att.DirDiffRef.NewDirPath
+		}
 	default:
 		detail = ""
 	}
@@ -318,6 +364,10 @@ func (att *Attachment) isModifiedSinceSnapshot() bool {
 		if att.GenericRef != nil {
 			return att.GenericRef.IsModified()
 		}
+	case AttachmentDirDiff:
+		if att.DirDiffRef != nil {
+			return att.DirDiffRef.IsModified()
+		}
 	default:
 		return false
 	}
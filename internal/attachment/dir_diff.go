@@ -0,0 +1,413 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxDirDiffScanFiles bounds the number of files collected from each side.
+	maxDirDiffScanFiles = 4096
+	// maxDirDiffTextFileBytes is the largest file for which a unified diff is produced.
+	maxDirDiffTextFileBytes = 256 * 1024
+	// maxDirDiffTotalDiffBytes bounds the sum of all unified diffs in a result.
+	maxDirDiffTotalDiffBytes = 1024 * 1024
+)
+
+type DirDiffFileStatus string
+
+const (
+	DirDiffFileAdded    DirDiffFileStatus = "added"
+	DirDiffFileRemoved  DirDiffFileStatus = "removed"
+	DirDiffFileModified DirDiffFileStatus = "modified"
+)
+
+// DirDiffFile is a single changed file between two directories.
+// UnifiedDiff is empty when DiffOmittedReason is set (binary, too large, or
+// the overall diff budget was exhausted).
+type DirDiffFile struct {
+	RelativePath      string            `json:"relativePath"`
+	Status            DirDiffFileStatus `json:"status"`
+	OldSize           int64             `json:"oldSize,omitempty"`
+	NewSize           int64             `json:"newSize,omitempty"`
+	UnifiedDiff       string            `json:"unifiedDiff,omitempty"`
+	DiffOmittedReason string            `json:"diffOmittedReason,omitempty"`
+}
+
+// DirDiffResult is the file-level diff of NewDirPath against OldDirPath.
+// Unchanged files are not listed.
+type DirDiffResult struct {
+	OldDirPath string        `json:"oldDirPath"`
+	NewDirPath string        `json:"newDirPath"`
+	Files      []DirDiffFile `json:"files"`
+	MaxFiles   int           `json:"maxFiles"` // max number of changed files returned (after clamping)
+	HasMore    bool          `json:"hasMore"`  // true if a scan or the MaxFiles limit truncated the result
+}
+
+type DirDiffAttachmentResult struct {
+	Attachment Attachment    `json:"attachment"`
+	Diff       DirDiffResult `json:"diff"`
+}
+
+// DirDiffRef carries the two directories of a directory diff attachment.
+// The diff itself is recomputed when the content block is built.
+type DirDiffRef struct {
+	OldDirPath string `json:"oldDirPath"`
+	NewDirPath string `json:"newDirPath"`
+	MaxFiles   int    `json:"maxFiles,omitempty"`
+
+	OrigOldDirPath string `json:"origOldDirPath"`
+	OrigNewDirPath string `json:"origNewDirPath"`
+}
+
+func (ref *DirDiffRef) PopulateRef(ctx context.Context, replaceOrig bool) error {
+	if ref == nil {
+		return errors.New("dir diff attachment missing ref")
+	}
+	ref.OldDirPath = strings.TrimSpace(ref.OldDirPath)
+	ref.NewDirPath = strings.TrimSpace(ref.NewDirPath)
+	if ref.OldDirPath == "" || ref.NewDirPath == "" {
+		return errors.New("dir diff attachment requires both directories")
+	}
+	if ref.OrigOldDirPath == "" || ref.OrigNewDirPath == "" || replaceOrig {
+		ref.OrigOldDirPath = ref.OldDirPath
+		ref.OrigNewDirPath = ref.NewDirPath
+	}
+	return nil
+}
+
+func (ref *DirDiffRef) IsModified() bool {
+	if ref == nil || ref.OrigOldDirPath == "" || ref.OrigNewDirPath == "" {
+		return false
+	}
+	return ref.OldDirPath != ref.OrigOldDirPath || ref.NewDirPath != ref.OrigNewDirPath
+}
+
+func (ref *DirDiffRef) BuildContentBlock(ctx context.Context) (*ContentBlock, error) {
+	res, err := DiffDirectories(ctx, ref.OldDirPath, ref.NewDirPath, ref.MaxFiles)
+	if err != nil {
+		return nil, err
+	}
+	text := res.FormatText()
+	return &ContentBlock{
+		Kind: ContentBlockText,
+		Text: &text,
+	}, nil
+}
+
+// BuildAttachmentForDirDiff diffs two directories and wraps the result in a
+// dir diff attachment that can be sent as a single text block.
+func BuildAttachmentForDirDiff(
+	ctx context.Context,
+	oldDirPath, newDirPath string,
+	maxFiles int,
+) (*DirDiffAttachmentResult, error) {
+	res, err := DiffDirectories(ctx, oldDirPath, newDirPath, maxFiles)
+	if err != nil {
+		return nil, err
+	}
+	att := Attachment{
+		Kind: AttachmentDirDiff,
+		Mode: AttachmentContentBlockModeDirDiff,
+		AvailableContentBlockModes: []AttachmentContentBlockMode{
+			AttachmentContentBlockModeDirDiff,
+		},
+		DirDiffRef: &DirDiffRef{
+			OldDirPath: res.OldDirPath,
+			NewDirPath: res.NewDirPath,
+			MaxFiles:   res.MaxFiles,
+		},
+	}
+	if err := att.PopulateRef(ctx, false); err != nil {
+		return nil, err
+	}
+	return &DirDiffAttachmentResult{Attachment: att, Diff: *res}, nil
+}
+
+// DiffDirectories computes a file-level diff between two directory trees.
+//
+//   - Files are matched by slash-separated path relative to each root.
+//   - Dot files and the directories skipped by WalkDirectoryWithFiles are ignored.
+//   - Text files up to maxDirDiffTextFileBytes get a unified diff; other
+//     changed files are listed with a DiffOmittedReason.
+//   - maxFiles caps the number of changed files returned and is clamped the
+//     same way as for directory walks.
+func DiffDirectories(
+	ctx context.Context,
+	oldDirPath, newDirPath string,
+	maxFiles int,
+) (*DirDiffResult, error) {
+	if maxFiles <= 0 || maxFiles > maxTotalDirWalkFiles {
+		maxFiles = maxTotalDirWalkFiles
+	}
+	oldRoot, err := resolveDiffRoot(oldDirPath)
+	if err != nil {
+		return nil, fmt.Errorf("old directory: %w", err)
+	}
+	newRoot, err := resolveDiffRoot(newDirPath)
+	if err != nil {
+		return nil, fmt.Errorf("new directory: %w", err)
+	}
+
+	oldFiles, oldMore, err := collectDiffFiles(ctx, oldRoot)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, newMore, err := collectDiffFiles(ctx, newRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(oldFiles)+len(newFiles))
+	for rel := range oldFiles {
+		paths = append(paths, rel)
+	}
+	for rel := range newFiles {
+		if _, ok := oldFiles[rel]; !ok {
+			paths = append(paths, rel)
+		}
+	}
+	slices.Sort(paths)
+
+	res := &DirDiffResult{
+		OldDirPath: oldRoot,
+		NewDirPath: newRoot,
+		Files:      []DirDiffFile{},
+		MaxFiles:   maxFiles,
+		HasMore:    oldMore || newMore,
+	}
+	diffBudget := maxDirDiffTotalDiffBytes
+
+	for _, rel := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		oldInfo, inOld := oldFiles[rel]
+		newInfo, inNew := newFiles[rel]
+
+		entry := DirDiffFile{RelativePath: rel}
+		switch {
+		case inOld && inNew:
+			same, err := sameFileContent(
+				filepath.Join(oldRoot, filepath.FromSlash(rel)), oldInfo.Size(),
+				filepath.Join(newRoot, filepath.FromSlash(rel)), newInfo.Size(),
+			)
+			if err != nil {
+				return nil, err
+			}
+			if same {
+				continue
+			}
+			entry.Status = DirDiffFileModified
+			entry.OldSize = oldInfo.Size()
+			entry.NewSize = newInfo.Size()
+		case inOld:
+			entry.Status = DirDiffFileRemoved
+			entry.OldSize = oldInfo.Size()
+		default:
+			entry.Status = DirDiffFileAdded
+			entry.NewSize = newInfo.Size()
+		}
+
+		if len(res.Files) >= maxFiles {
+			res.HasMore = true
+			break
+		}
+		fillUnifiedDiff(&entry, oldRoot, newRoot, &diffBudget)
+		res.Files = append(res.Files, entry)
+	}
+	return res, nil
+}
+
+// FormatText renders the diff as a single text document: a summary of changed
+// files followed by the unified diffs.
+func (r *DirDiffResult) FormatText() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Directory diff: %s -> %s\n", r.OldDirPath, r.NewDirPath)
+	if len(r.Files) == 0 {
+		sb.WriteString("No differences.\n")
+	}
+	for _, f := range r.Files {
+		fmt.Fprintf(&sb, "%s: %s", f.Status, f.RelativePath)
+		if f.DiffOmittedReason != "" {
+			fmt.Fprintf(&sb, " (%s)", f.DiffOmittedReason)
+		}
+		sb.WriteByte('\n')
+	}
+	if r.HasMore {
+		sb.WriteString("(more differences not shown)\n")
+	}
+	for _, f := range r.Files {
+		if f.UnifiedDiff == "" {
+			continue
+		}
+		sb.WriteByte('\n')
+		sb.WriteString(f.UnifiedDiff)
+	}
+	return sb.String()
+}
+
+func resolveDiffRoot(dirPath string) (string, error) {
+	dirPath = strings.TrimSpace(dirPath)
+	if dirPath == "" {
+		return "", errors.New("empty path")
+	}
+	if abs, err := filepath.Abs(dirPath); err == nil {
+		dirPath = abs
+	}
+	dirPath = filepath.Clean(dirPath)
+	info, err := os.Stat(dirPath)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("path %q is not a directory", dirPath)
+	}
+	return dirPath, nil
+}
+
+// collectDiffFiles returns the regular files under root keyed by slash
+// separated relative path. The bool reports whether the scan was truncated.
+func collectDiffFiles(ctx context.Context, root string) (map[string]fs.FileInfo, bool, error) {
+	files := map[string]fs.FileInfo{}
+	truncated := false
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// Unreadable subtrees are skipped, consistent with directory walks.
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && defaultSkippedDirectory(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		if len(files) >= maxDirDiffScanFiles {
+			truncated = true
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil //nolint:nilerr // File vanished while walking; skip it.
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = info
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return files, truncated, nil
+}
+
+func sameFileContent(aPath string, aSize int64, bPath string, bSize int64) (bool, error) {
+	if aSize != bSize {
+		return false, nil
+	}
+	af, err := os.Open(aPath)
+	if err != nil {
+		return false, err
+	}
+	defer af.Close()
+	bf, err := os.Open(bPath)
+	if err != nil {
+		return false, err
+	}
+	defer bf.Close()
+
+	const chunk = 32 * 1024
+	ab := make([]byte, chunk)
+	bb := make([]byte, chunk)
+	for {
+		an, aErr := io.ReadFull(af, ab)
+		bn, bErr := io.ReadFull(bf, bb)
+		if an != bn || !bytes.Equal(ab[:an], bb[:bn]) {
+			return false, nil
+		}
+		aDone := errors.Is(aErr, io.EOF) || errors.Is(aErr, io.ErrUnexpectedEOF)
+		bDone := errors.Is(bErr, io.EOF) || errors.Is(bErr, io.ErrUnexpectedEOF)
+		if aDone || bDone {
+			return aDone == bDone, nil
+		}
+		if aErr != nil {
+			return false, aErr
+		}
+		if bErr != nil {
+			return false, bErr
+		}
+	}
+}
+
+func fillUnifiedDiff(entry *DirDiffFile, oldRoot, newRoot string, budget *int) {
+	if entry.OldSize > maxDirDiffTextFileBytes || entry.NewSize > maxDirDiffTextFileBytes {
+		entry.DiffOmittedReason = "file too large for diff"
+		return
+	}
+
+	var oldText, newText string
+	if entry.Status != DirDiffFileAdded {
+		text, ok := readDiffText(filepath.Join(oldRoot, filepath.FromSlash(entry.RelativePath)))
+		if !ok {
+			entry.DiffOmittedReason = "binary or unreadable file"
+			return
+		}
+		oldText = text
+	}
+	if entry.Status != DirDiffFileRemoved {
+		text, ok := readDiffText(filepath.Join(newRoot, filepath.FromSlash(entry.RelativePath)))
+		if !ok {
+			entry.DiffOmittedReason = "binary or unreadable file"
+			return
+		}
+		newText = text
+	}
+
+	oldName, newName := "a/"+entry.RelativePath, "b/"+entry.RelativePath
+	switch entry.Status {
+	case DirDiffFileAdded:
+		oldName = "/dev/null"
+	case DirDiffFileRemoved:
+		newName = "/dev/null"
+	default:
+	}
+	diff := unifiedLineDiff(oldName, newName, oldText, newText)
+	if len(diff) > *budget {
+		entry.DiffOmittedReason = "diff size limit reached"
+		return
+	}
+	*budget -= len(diff)
+	entry.UnifiedDiff = diff
+}
+
+// readDiffText reads a file and reports whether it looks like UTF-8 text.
+func readDiffText(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return "", false
+	}
+	return string(data), true
+}
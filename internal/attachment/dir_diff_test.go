@@ -0,0 +1,149 @@
+package attachment
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedLineDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldText string
+		newText string
+		want    string
+	}{
+		{
+			name:    "identical",
+			oldText: "a\nb\n",
+			newText: "a\nb\n",
+			want:    "",
+		},
+		{
+			name:    "single_line_change_with_context",
+			oldText: "1\n2\n3\n4\n5\n6\n7\n8\n",
+			newText: "1\n2\n3\n4\nfive\n6\n7\n8\n",
+			want:    "--- a/f\n+++ b/f\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name:    "added_file",
+			oldText: "",
+			newText: "x\ny\n",
+			want:    "--- a/f\n+++ b/f\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			name:    "distant_changes_split_hunks",
+			oldText: "a\n1\n2\n3\n4\n5\n6\n7\n8\nb\n",
+			newText: "A\n1\n2\n3\n4\n5\n6\n7\n8\nB\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n" +
+				"@@ -7,4 +7,4 @@\n 6\n 7\n 8\n-b\n+B\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedLineDiff("a/f", "b/f", tt.oldText, tt.newText)
+			if got != tt.want {
+				t.Fatalf("unexpected diff:\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffDirectories(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+
+	write := func(root, rel, content string) {
+		t.Helper()
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	write(oldDir, "same.txt", "same\n")
+	write(newDir, "same.txt", "same\n")
+	write(oldDir, "sub/changed.go", "package x\n\nvar a = 1\n")
+	write(newDir, "sub/changed.go", "package x\n\nvar a = 2\n")
+	write(oldDir, "removed.md", "gone\n")
+	write(newDir, "added.md", "new\n")
+	write(oldDir, "bin.dat", "a\x00b")
+	write(newDir, "bin.dat", "a\x00c")
+	write(newDir, ".hidden", "ignored\n")
+	write(newDir, "node_modules/pkg/index.js", "ignored\n")
+
+	res, err := DiffDirectories(t.Context(), oldDir, newDir, 0)
+	if err != nil {
+		t.Fatalf("DiffDirectories: %v", err)
+	}
+	if res.HasMore {
+		t.Fatalf("did not expect HasMore")
+	}
+
+	got := map[string]DirDiffFile{}
+	for _, f := range res.Files {
+		got[f.RelativePath] = f
+	}
+	want := map[string]DirDiffFileStatus{
+		"added.md":       DirDiffFileAdded,
+		"bin.dat":        DirDiffFileModified,
+		"removed.md":     DirDiffFileRemoved,
+		"sub/changed.go": DirDiffFileModified,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d changed files, got %+v", len(want), res.Files)
+	}
+	for rel, status := range want {
+		if got[rel].Status != status {
+			t.Errorf("%s: status=%q want %q", rel, got[rel].Status, status)
+		}
+	}
+	if !strings.Contains(got["sub/changed.go"].UnifiedDiff, "-var a = 1\n+var a = 2\n") {
+		t.Errorf("unexpected diff for changed.go: %q", got["sub/changed.go"].UnifiedDiff)
+	}
+	if !strings.HasPrefix(got["added.md"].UnifiedDiff, "--- /dev/null\n+++ b/added.md\n") {
+		t.Errorf("unexpected diff for added.md: %q", got["added.md"].UnifiedDiff)
+	}
+	if got["bin.dat"].UnifiedDiff != "" || got["bin.dat"].DiffOmittedReason == "" {
+		t.Errorf("expected binary diff to be omitted, got %+v", got["bin.dat"])
+	}
+
+	t.Run("max_files_truncates", func(t *testing.T) {
+		res, err := DiffDirectories(t.Context(), oldDir, newDir, 1)
+		if err != nil {
+			t.Fatalf("DiffDirectories: %v", err)
+		}
+		if len(res.Files) != 1 || !res.HasMore {
+			t.Fatalf("expected 1 file and HasMore, got %d files, hasMore=%v", len(res.Files), res.HasMore)
+		}
+	})
+
+	t.Run("attachment_builds_text_block", func(t *testing.T) {
+		out, err := BuildAttachmentForDirDiff(t.Context(), oldDir, newDir, 0)
+		if err != nil {
+			t.Fatalf("BuildAttachmentForDirDiff: %v", err)
+		}
+		att := out.Attachment
+		if att.Kind != AttachmentDirDiff || att.DirDiffRef == nil || att.Label == "" {
+			t.Fatalf("unexpected attachment: %+v", att)
+		}
+		cb, err := att.BuildContentBlock(t.Context(), WithOnlyTextKindContentBlock(true))
+		if err != nil {
+			t.Fatalf("BuildContentBlock: %v", err)
+		}
+		if cb.Kind != ContentBlockText || cb.Text == nil ||
+			!strings.Contains(*cb.Text, "removed: removed.md") {
+			t.Fatalf("unexpected content block: %+v", cb)
+		}
+	})
+
+	t.Run("not_a_directory", func(t *testing.T) {
+		if _, err := DiffDirectories(t.Context(), filepath.Join(oldDir, "same.txt"), newDir, 0); err == nil {
+			t.Fatalf("expected error for file path")
+		}
+	})
+}
@@ -2,10 +2,11 @@ package attachment
 
 import (
 	"context"
-	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/flexigpt/flexigpt-app/internal/middleware"
 )
 
 // defaultSkippedDirectory reports directories that should not be traversed.
@@ -48,10 +49,25 @@ func defaultSkippedDirectory(name string) bool {
 //   - The directory where we actually hit the limit (if any) is also listed
 //     as an overflow entry with Partial = true and a count of remaining items
 //     (files + subdirs) in that directory.
-func WalkDirectoryWithFiles(ctx context.Context, dirPath string, maxFiles int) (*WalkDirectoryWithFilesResult, error) {
+//   - WithDirWalkFilter drops files and directories before any of the above,
+//     so filtered entries neither use up maxFiles nor count as overflow.
+func WalkDirectoryWithFiles(
+	ctx context.Context,
+	dirPath string,
+	maxFiles int,
+	opts ...DirWalkOption,
+) (*WalkDirectoryWithFilesResult, error) {
 	if maxFiles <= 0 || maxFiles > maxTotalDirWalkFiles {
 		maxFiles = maxTotalDirWalkFiles
 	}
+	walkOpts := dirWalkOptions{}
+	for _, o := range opts {
+		o(&walkOpts)
+	}
+	filter, err := compileDirWalkFilter(walkOpts.Filter)
+	if err != nil {
+		return nil, err
+	}
 	if dirPath == "" {
 		// Empty path. Nothing to walk.
 		return &WalkDirectoryWithFilesResult{
@@ -91,6 +107,7 @@ func WalkDirectoryWithFiles(ctx context.Context, dirPath string, maxFiles int) (
 
 	limitReached := false
 	var partialOverflow *DirectoryOverflowInfo
+	progress := middleware.ProgressFromContext(ctx)
 
 	for len(queue) > 0 && !limitReached {
 		// BFS: pop front.
@@ -101,11 +118,12 @@ func WalkDirectoryWithFiles(ctx context.Context, dirPath string, maxFiles int) (
 			// Caller canceled or deadline exceeded.
 			return nil, err
 		}
+		progress.Report("scanning "+node.absPath, int64(len(files)), 0)
 
 		entries, err := os.ReadDir(node.absPath)
 		if err != nil {
 			// Log and continue; if it's the root directory, propagate the error.
-			slog.Debug("error while walking directory", "path", node.absPath, "error", err)
+			logger.Debug("error while walking directory", "path", node.absPath, "error", err)
 			if node.absPath == dirPath {
 				return nil, err
 			}
@@ -126,9 +144,13 @@ func WalkDirectoryWithFiles(ctx context.Context, dirPath string, maxFiles int) (
 		dirEntries := make([]os.DirEntry, 0, len(entries))
 		for _, e := range entries {
 			name := e.Name()
+			rel := name
+			if node.relPath != "" {
+				rel = filepath.Join(node.relPath, name)
+			}
 
 			if e.IsDir() {
-				if defaultSkippedDirectory(name) {
+				if defaultSkippedDirectory(name) || !filter.allowDir(rel) {
 					continue
 				}
 				dirEntries = append(dirEntries, e)
@@ -136,7 +158,7 @@ func WalkDirectoryWithFiles(ctx context.Context, dirPath string, maxFiles int) (
 			}
 
 			// Skip dot files entirely.
-			if strings.HasPrefix(name, ".") {
+			if strings.HasPrefix(name, ".") || !filter.allowFile(rel) {
 				continue
 			}
 
@@ -171,7 +193,7 @@ func WalkDirectoryWithFiles(ctx context.Context, dirPath string, maxFiles int) (
 
 			info, err := e.Info()
 			if err != nil {
-				slog.Debug("stat error while walking directory", "path", fullPath, "error", err)
+				logger.Debug("stat error while walking directory", "path", fullPath, "error", err)
 				continue
 			}
 			if !info.Mode().IsRegular() {
@@ -240,7 +262,7 @@ func WalkDirectoryWithFiles(ctx context.Context, dirPath string, maxFiles int) (
 		itemCount := 0
 		entries, err := os.ReadDir(node.absPath)
 		if err != nil {
-			slog.Debug("error while summarizing overflow directory", "path", node.absPath, "error", err)
+			logger.Debug("error while summarizing overflow directory", "path", node.absPath, "error", err)
 			// Leave itemCount = 0 as "unknown".
 		} else {
 			for _, e := range entries {
@@ -0,0 +1,168 @@
+package attachment
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DirWalkFilter narrows which files a directory walk returns.
+//
+// Patterns use "/" as separator and are matched against the path relative to
+// the walked directory. A pattern without "/" matches the base name only, and
+// a "**" segment matches any number of directories. A directory matching an
+// exclude pattern is not descended into.
+type DirWalkFilter struct {
+	IncludePatterns []string  `json:"includePatterns,omitempty"`
+	ExcludePatterns []string  `json:"excludePatterns,omitempty"`
+	Extensions      []FileExt `json:"extensions,omitempty"`
+	// MaxDepth limits how deep files may be; files directly in the walked
+	// directory are at depth 1. Zero means no limit.
+	MaxDepth int `json:"maxDepth,omitempty"`
+}
+
+type dirWalkOptions struct {
+	Filter *DirWalkFilter
+}
+
+type DirWalkOption func(*dirWalkOptions)
+
+// WithDirWalkFilter restricts the walk to files accepted by filter.
+func WithDirWalkFilter(filter DirWalkFilter) DirWalkOption {
+	return func(o *dirWalkOptions) {
+		o.Filter = &filter
+	}
+}
+
+// compiledDirWalkFilter is a validated DirWalkFilter. The zero value accepts
+// everything.
+type compiledDirWalkFilter struct {
+	include    [][]string
+	exclude    [][]string
+	extensions map[string]struct{}
+	maxDepth   int
+}
+
+func compileDirWalkFilter(f *DirWalkFilter) (*compiledDirWalkFilter, error) {
+	c := &compiledDirWalkFilter{}
+	if f == nil {
+		return c, nil
+	}
+	if f.MaxDepth < 0 {
+		return nil, fmt.Errorf("invalid max depth %d", f.MaxDepth)
+	}
+	c.maxDepth = f.MaxDepth
+
+	compile := func(patterns []string) ([][]string, error) {
+		out := make([][]string, 0, len(patterns))
+		for _, p := range patterns {
+			p = strings.Trim(strings.TrimSpace(filepath.ToSlash(p)), "/")
+			if p == "" {
+				continue
+			}
+			segs := strings.Split(p, "/")
+			for _, s := range segs {
+				if _, err := path.Match(s, ""); err != nil {
+					return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+				}
+			}
+			out = append(out, segs)
+		}
+		return out, nil
+	}
+	var err error
+	if c.include, err = compile(f.IncludePatterns); err != nil {
+		return nil, err
+	}
+	if c.exclude, err = compile(f.ExcludePatterns); err != nil {
+		return nil, err
+	}
+
+	for _, ext := range f.Extensions {
+		e := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(string(ext)), "*"))
+		if e == "" {
+			continue
+		}
+		if !strings.HasPrefix(e, ".") {
+			e = "." + e
+		}
+		if c.extensions == nil {
+			c.extensions = map[string]struct{}{}
+		}
+		c.extensions[e] = struct{}{}
+	}
+	return c, nil
+}
+
+// allowDir reports whether a directory at relPath should be walked.
+func (c *compiledDirWalkFilter) allowDir(relPath string) bool {
+	if c.maxDepth > 0 && pathDepth(relPath) >= c.maxDepth {
+		return false
+	}
+	return !matchAnyWalkPattern(c.exclude, relPath)
+}
+
+// allowFile reports whether a file at relPath should be returned.
+func (c *compiledDirWalkFilter) allowFile(relPath string) bool {
+	if c.maxDepth > 0 && pathDepth(relPath) > c.maxDepth {
+		return false
+	}
+	if c.extensions != nil {
+		if _, ok := c.extensions[strings.ToLower(filepath.Ext(relPath))]; !ok {
+			return false
+		}
+	}
+	if matchAnyWalkPattern(c.exclude, relPath) {
+		return false
+	}
+	return len(c.include) == 0 || matchAnyWalkPattern(c.include, relPath)
+}
+
+func pathDepth(relPath string) int {
+	if relPath == "" {
+		return 0
+	}
+	return strings.Count(filepath.ToSlash(relPath), "/") + 1
+}
+
+func matchAnyWalkPattern(patterns [][]string, relPath string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	segs := strings.Split(filepath.ToSlash(relPath), "/")
+	for _, p := range patterns {
+		if len(p) == 1 && p[0] != "**" {
+			if ok, _ := path.Match(p[0], segs[len(segs)-1]); ok {
+				return true
+			}
+			continue
+		}
+		if matchWalkSegments(p, segs) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchWalkSegments(pattern, segs []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(segs); i++ {
+				if matchWalkSegments(rest, segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segs[0]); !ok {
+			return false
+		}
+		pattern, segs = pattern[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
@@ -588,6 +588,82 @@ func TestWalkDirectoryWithFiles_UnreadableSubdirOverflow_NoFiles(t *testing.T) {
 	}
 }
 
+func TestWalkDirectoryWithFiles_Filter(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	mustWriteFile(t, root, "main.go", 1)
+	mustWriteFile(t, root, "README.md", 1)
+	internal := mustMkdir(t, root, "internal")
+	mustWriteFile(t, internal, "a.go", 1)
+	mustWriteFile(t, internal, "a_test.go", 1)
+	pkg := mustMkdir(t, internal, "pkg")
+	mustWriteFile(t, pkg, "b.go", 1)
+	gen := mustMkdir(t, internal, "gen")
+	mustWriteFile(t, gen, "gen.go", 1)
+	deep := mustMkdir(t, pkg, "deep")
+	mustWriteFile(t, deep, "c.go", 1)
+	mustWriteFile(t, deep, "notes.txt", 1)
+
+	tests := []struct {
+		name    string
+		filter  DirWalkFilter
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "extensions",
+			filter: DirWalkFilter{Extensions: []FileExt{"GO"}},
+			want:   []string{"a.go", "a_test.go", "b.go", "c.go", "gen.go", "main.go"},
+		},
+		{
+			name:   "include under dir with max depth",
+			filter: DirWalkFilter{IncludePatterns: []string{"internal/**/*.go"}, MaxDepth: 3},
+			want:   []string{"a.go", "a_test.go", "b.go", "gen.go"},
+		},
+		{
+			name: "exclude dir and base name pattern",
+			filter: DirWalkFilter{
+				ExcludePatterns: []string{"internal/gen", "*_test.go"},
+				Extensions:      []FileExt{".go"},
+			},
+			want: []string{"a.go", "b.go", "c.go", "main.go"},
+		},
+		{
+			name:   "depth one is root files only",
+			filter: DirWalkFilter{MaxDepth: 1},
+			want:   []string{"README.md", "main.go"},
+		},
+		{
+			name:    "bad pattern",
+			filter:  DirWalkFilter{IncludePatterns: []string{"[a-"}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := WalkDirectoryWithFiles(t.Context(), root, 100, WithDirWalkFilter(tc.filter))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := namesFromPathInfos(res.Files)
+			want := slices.Sorted(slices.Values(tc.want))
+			if !slices.Equal(got, want) {
+				t.Fatalf("files = %v, want %v", got, want)
+			}
+			if res.HasMore {
+				t.Fatalf("filtered entries must not count as overflow: %+v", res.OverflowDirs)
+			}
+		})
+	}
+}
+
 func mustWriteFile(t *testing.T, dir, name string, size int) string {
 	t.Helper()
 	full := filepath.Join(dir, name)
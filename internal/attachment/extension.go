@@ -71,6 +71,9 @@ const (
 	ExtWEBP FileExt = ".webp"
 	ExtBMP  FileExt = ".bmp"
 	ExtSVG  FileExt = ".svg"
+	// HEIC and HEIF are recognized only to reject them clearly.
+	ExtHEIC FileExt = ".heic"
+	ExtHEIF FileExt = ".heif"
 
 	ExtPDF  FileExt = ".pdf"
 	ExtDOC  FileExt = ".doc"
@@ -0,0 +1,322 @@
+package attachment
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/flexigpt/flexigpt-app/internal/llmtoolsutil"
+	"github.com/flexigpt/llmtools-go/fstool"
+	llmtoolsSpec "github.com/flexigpt/llmtools-go/spec"
+)
+
+// maxExtractedPartBytes bounds how much of a single archive member (e.g.
+// word/document.xml) is inflated while extracting text.
+const maxExtractedPartBytes = 64 << 20
+
+// ContentExtractor returns the text content of the document at path.
+type ContentExtractor func(ctx context.Context, path string) (string, error)
+
+var (
+	contentExtractorsMu sync.RWMutex
+	contentExtractors   = map[MIMEType]ContentExtractor{
+		MIMEApplicationPDF:        extractTextWithFSTool,
+		MIMEApplicationOpenXMLDoc: extractDOCXText,
+		MIMEApplicationOpenXMLXLS: extractXLSXText,
+	}
+)
+
+// RegisterContentExtractor sets the extractor used to attach documents of
+// mimeType as text. A nil extractor removes the registration.
+func RegisterContentExtractor(mimeType MIMEType, extractor ContentExtractor) {
+	contentExtractorsMu.Lock()
+	defer contentExtractorsMu.Unlock()
+	if extractor == nil {
+		delete(contentExtractors, mimeType)
+		return
+	}
+	contentExtractors[mimeType] = extractor
+}
+
+// HasContentExtractor reports whether documents of mimeType can be attached as
+// text.
+func HasContentExtractor(mimeType MIMEType) bool {
+	_, ok := lookupContentExtractor(mimeType)
+	return ok
+}
+
+func lookupContentExtractor(mimeType MIMEType) (ContentExtractor, bool) {
+	contentExtractorsMu.RLock()
+	defer contentExtractorsMu.RUnlock()
+	ex, ok := contentExtractors[mimeType]
+	return ex, ok
+}
+
+// extractFileText reads path as text, using the registered extractor for
+// mimeType if there is one.
+func extractFileText(ctx context.Context, path string, mimeType MIMEType) (string, error) {
+	if ex, ok := lookupContentExtractor(mimeType); ok {
+		return ex(ctx, path)
+	}
+	return extractTextWithFSTool(ctx, path)
+}
+
+// extractTextWithFSTool reads text files as is and PDFs with fstool's text
+// extraction.
+func extractTextWithFSTool(ctx context.Context, path string) (string, error) {
+	toolOut, err := llmtoolsutil.ReadFile(ctx, fstool.ReadFileArgs{
+		Path:     path,
+		Encoding: "text",
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(toolOut) == 0 || toolOut[0].Kind != llmtoolsSpec.ToolOutputKindText ||
+		toolOut[0].TextItem == nil {
+		return "", ErrUnreadableFile
+	}
+	return toolOut[0].TextItem.Text, nil
+}
+
+// extractDOCXText returns the paragraphs of a Word document, one per line.
+func extractDOCXText(ctx context.Context, filePath string) (string, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnreadableFile, err)
+	}
+	defer zr.Close()
+
+	part, err := openZipPart(&zr.Reader, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+	defer part.Close()
+
+	var (
+		sb     strings.Builder
+		inText bool
+	)
+	dec := xml.NewDecoder(part)
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("%w: word/document.xml: %w", ErrUnreadableFile, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				sb.WriteByte('\t')
+			case "br", "cr":
+				sb.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				sb.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// extractXLSXText returns every worksheet of a workbook as tab separated rows,
+// each sheet preceded by a "# Sheet: <name>" line.
+func extractXLSXText(ctx context.Context, filePath string) (string, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnreadableFile, err)
+	}
+	defer zr.Close()
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeZipPart(&zr.Reader, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipPart(&zr.Reader, "xl/_rels/workbook.xml.rels", &rels); err != nil &&
+		!errors.Is(err, errZipPartMissing) {
+		return "", err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, r := range rels.Relationships {
+		target := strings.TrimPrefix(r.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = path.Join("xl", target)
+		}
+		targets[r.ID] = target
+	}
+
+	var shared struct {
+		Items []xlsxRichText `xml:"si"`
+	}
+	if err := decodeZipPart(&zr.Reader, "xl/sharedStrings.xml", &shared); err != nil &&
+		!errors.Is(err, errZipPartMissing) {
+		return "", err
+	}
+	sharedStrings := make([]string, len(shared.Items))
+	for i, si := range shared.Items {
+		sharedStrings[i] = si.text()
+	}
+
+	var sb strings.Builder
+	for i, sheet := range workbook.Sheets {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		target, ok := targets[sheet.RID]
+		if !ok {
+			target = fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)
+		}
+		var ws struct {
+			Rows []struct {
+				Cells []struct {
+					Ref    string       `xml:"r,attr"`
+					Type   string       `xml:"t,attr"`
+					Value  string       `xml:"v"`
+					Inline xlsxRichText `xml:"is"`
+				} `xml:"c"`
+			} `xml:"sheetData>row"`
+		}
+		if err := decodeZipPart(&zr.Reader, target, &ws); err != nil {
+			return "", err
+		}
+
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString("# Sheet: ")
+		sb.WriteString(sheet.Name)
+		sb.WriteByte('\n')
+		for _, row := range ws.Rows {
+			var cells []string
+			for _, c := range row.Cells {
+				val := c.Value
+				switch c.Type {
+				case "s":
+					if idx, err := strconv.Atoi(strings.TrimSpace(c.Value)); err == nil &&
+						idx >= 0 && idx < len(sharedStrings) {
+						val = sharedStrings[idx]
+					}
+				case "inlineStr":
+					val = c.Inline.text()
+				case "b":
+					val = strconv.FormatBool(c.Value == "1")
+				}
+				col := xlsxColumnIndex(c.Ref)
+				if col < len(cells) {
+					col = len(cells)
+				}
+				for len(cells) < col {
+					cells = append(cells, "")
+				}
+				cells = append(cells, val)
+			}
+			sb.WriteString(strings.Join(cells, "\t"))
+			sb.WriteByte('\n')
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// xlsxRichText is a shared or inline string: plain text or a list of runs.
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (rt xlsxRichText) text() string {
+	if len(rt.Runs) == 0 {
+		return rt.Text
+	}
+	var sb strings.Builder
+	for _, r := range rt.Runs {
+		sb.WriteString(r.Text)
+	}
+	return sb.String()
+}
+
+// xlsxColumnIndex returns the zero based column of a cell reference like
+// "C12", or -1 when ref has no column letters.
+func xlsxColumnIndex(ref string) int {
+	col := 0
+	n := 0
+	for _, r := range strings.ToUpper(ref) {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return -1
+	}
+	return col - 1
+}
+
+var errZipPartMissing = errors.New("document part missing")
+
+type zipPart struct {
+	io.Reader
+	io.Closer
+}
+
+func openZipPart(zr *zip.Reader, name string) (io.ReadCloser, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrUnreadableFile, name, err)
+		}
+		return zipPart{Reader: io.LimitReader(rc, maxExtractedPartBytes), Closer: rc}, nil
+	}
+	return nil, fmt.Errorf("%w: %w: %s", ErrUnreadableFile, errZipPartMissing, name)
+}
+
+func decodeZipPart(zr *zip.Reader, name string, v any) error {
+	part, err := openZipPart(zr, name)
+	if err != nil {
+		return err
+	}
+	defer part.Close()
+	if err := xml.NewDecoder(part).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrUnreadableFile, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package attachment
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const (
+	testDOCXDocument = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:body>
+<w:p><w:r><w:t>Quarterly</w:t></w:r><w:r><w:t xml:space="preserve"> report</w:t></w:r></w:p>
+<w:p><w:r><w:t>Name</w:t><w:tab/><w:t>Value</w:t></w:r></w:p>
+</w:body>
+</w:document>`
+
+	testXLSXWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+ xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Totals" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+	testXLSXRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/data.xml"/>
+</Relationships>`
+
+	testXLSXSharedStrings = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>Item</t></si><si><r><t>Co</t></r><r><t>unt</t></r></si><si><t>apples</t></si>
+</sst>`
+
+	testXLSXSheet = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="C2"><v>42</v></c></row>
+<row r="3"><c r="B3" t="inlineStr"><is><t>note</t></is></c></row>
+</sheetData>
+</worksheet>`
+)
+
+func TestContentExtractors_OfficeDocuments(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		file    string
+		parts   map[string]string
+		extract ContentExtractor
+		want    string
+	}{
+		{
+			name:    "docx paragraphs and tabs",
+			file:    "report.docx",
+			parts:   map[string]string{"word/document.xml": testDOCXDocument},
+			extract: extractDOCXText,
+			want:    "Quarterly report\nName\tValue",
+		},
+		{
+			name: "xlsx shared, inline and numeric cells",
+			file: "totals.xlsx",
+			parts: map[string]string{
+				"xl/workbook.xml":            testXLSXWorkbook,
+				"xl/_rels/workbook.xml.rels": testXLSXRels,
+				"xl/sharedStrings.xml":       testXLSXSharedStrings,
+				"xl/worksheets/data.xml":     testXLSXSheet,
+			},
+			extract: extractXLSXText,
+			want:    "# Sheet: Totals\nItem\tCount\napples\t\t42\n\tnote",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeTestZip(t, filepath.Join(dir, tc.file), tc.parts)
+			got, err := tc.extract(t.Context(), path)
+			if err != nil {
+				t.Fatalf("extract: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("unexpected text\nwant:\n%q\ngot:\n%q", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("missing part is unreadable", func(t *testing.T) {
+		path := writeTestZip(t, filepath.Join(dir, "empty.docx"), map[string]string{"other.xml": "<a/>"})
+		if _, err := extractDOCXText(t.Context(), path); err == nil {
+			t.Fatal("expected error for docx without document part")
+		}
+	})
+}
+
+func TestBuildAttachmentForFile_DOCXAsText(t *testing.T) {
+	path := writeTestZip(
+		t,
+		filepath.Join(t.TempDir(), "report.docx"),
+		map[string]string{"word/document.xml": testDOCXDocument},
+	)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modTime := info.ModTime().UTC()
+
+	att, err := BuildAttachmentForFile(t.Context(), &PathInfo{
+		Path:    path,
+		Name:    info.Name(),
+		Exists:  true,
+		Size:    info.Size(),
+		ModTime: &modTime,
+	})
+	if err != nil {
+		t.Fatalf("BuildAttachmentForFile: %v", err)
+	}
+	if att.Mode != AttachmentContentBlockModeText ||
+		!slices.Equal(att.AvailableContentBlockModes, []AttachmentContentBlockMode{AttachmentContentBlockModeText}) {
+		t.Fatalf("unexpected modes: %q %v", att.Mode, att.AvailableContentBlockModes)
+	}
+
+	block, err := att.FileRef.BuildContentBlock(t.Context(), att.Mode, true)
+	if err != nil {
+		t.Fatalf("BuildContentBlock: %v", err)
+	}
+	if block.Kind != ContentBlockText || block.Text == nil || *block.Text != "Quarterly report\nName\tValue" {
+		t.Fatalf("unexpected block: %+v", block)
+	}
+
+	t.Run("custom extractor", func(t *testing.T) {
+		RegisterContentExtractor(MIMEApplicationOpenXMLDoc, func(context.Context, string) (string, error) {
+			return "custom", nil
+		})
+		t.Cleanup(func() { RegisterContentExtractor(MIMEApplicationOpenXMLDoc, extractDOCXText) })
+
+		block, err := att.FileRef.BuildContentBlock(t.Context(), AttachmentContentBlockModeText, true)
+		if err != nil {
+			t.Fatalf("BuildContentBlock: %v", err)
+		}
+		if block.Text == nil || *block.Text != "custom" {
+			t.Fatalf("expected custom extractor output, got %+v", block)
+		}
+	})
+}
+
+func writeTestZip(t *testing.T, path string, parts map[string]string) string {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
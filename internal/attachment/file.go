@@ -22,6 +22,9 @@ type FileRef struct {
 	OrigPath    string    `json:"origPath"`
 	OrigSize    int64     `json:"origSize"`
 	OrigModTime time.Time `json:"origModTime"`
+
+	// Chunks is set for large text files attached in parts.
+	Chunks *FileChunks `json:"chunks,omitempty"`
 }
 
 func (ref *FileRef) PopulateRef(ctx context.Context, replaceOrig bool) error {
@@ -179,11 +182,11 @@ func (ref *FileRef) BuildContentBlock(
 		mimeType := MIMEType(toolOut.BaseMIMEType)
 		extensionMode := toolOut.Mode
 
-		if extensionMode != fstool.MIMEModeText && mimeType != MIMEApplicationPDF {
-			// Could not detect mime or non pdf text attachment sent, render as unreadable file.
+		if extensionMode != fstool.MIMEModeText && !HasContentExtractor(mimeType) {
+			// Could not detect mime or no text extractor for the document, render as unreadable file.
 			return nil, ErrUnreadableFile
 		}
-		// Text mode mimes and pdf with text extraction is supported.
+		// Text mode mimes and documents with a registered extractor are supported.
 		return ref.getTextBlock(ctx, mimeType)
 
 	case AttachmentContentBlockModeNotReadable,
@@ -205,6 +208,9 @@ func (ref *FileRef) getTextBlock(ctx context.Context, mimetype MIMEType) (*Conte
 	if path == "" {
 		return nil, errors.New("got invalid path")
 	}
+	if ref.Chunks != nil {
+		return ref.getChunkedTextBlock(ctx, mimetype)
+	}
 
 	c, err := ref.getTextFileContent(ctx, path, mimetype)
 	if err != nil {
@@ -223,26 +229,17 @@ func (ref *FileRef) getTextFileContent(
 	path string,
 	mimeType MIMEType,
 ) (*ContentBlock, error) {
-	// Fstool supports Text extraction of pdf too.
-	toolOut, err := llmtoolsutil.ReadFile(ctx, fstool.ReadFileArgs{
-		Path:     path,
-		Encoding: "text",
-	})
+	text, err := extractFileText(ctx, path, mimeType)
 	if err != nil {
 		return nil, err
 	}
-	if len(toolOut) == 0 || toolOut[0].Kind != llmtoolsSpec.ToolOutputKindText ||
-		toolOut[0].TextItem == nil {
-		return nil, ErrUnreadableFile
-	}
-	tItem := toolOut[0].TextItem
 
 	mStr := string(mimeType)
 	fname := filepath.Base(path)
 	filePath := path
 	return &ContentBlock{
 		Kind:     ContentBlockText,
-		Text:     &tItem.Text,
+		Text:     &text,
 		MIMEType: &mStr,
 		FileName: &fname,
 		FilePath: &filePath,
@@ -0,0 +1,258 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/flexigpt/flexigpt-app/internal/llmtoolsutil"
+	"github.com/flexigpt/llmtools-go/fstool"
+)
+
+const (
+	DefaultFileChunkSize    int64 = 256 * 1024
+	DefaultFileChunkOverlap int64 = 4 * 1024
+
+	minFileChunkSize int64 = 4 * 1024
+	maxFileChunkSize int64 = 4 * 1024 * 1024
+)
+
+// FileChunks splits a large text file into size-bounded, overlapping byte
+// ranges. Only the selected parts are read when the content block is built,
+// so neither the prompt nor the attachment itself carries the whole file.
+type FileChunks struct {
+	ChunkSize int64       `json:"chunkSize"`
+	Overlap   int64       `json:"overlap"`
+	TotalSize int64       `json:"totalSize"`
+	Parts     []FileChunk `json:"parts"`
+
+	// SelectedParts are the indexes of the parts sent to the model.
+	// Empty means only the first part.
+	SelectedParts []int `json:"selectedParts,omitempty"`
+}
+
+// FileChunk is one byte range of a chunked file. Ranges of neighbouring parts
+// overlap by up to FileChunks.Overlap bytes.
+type FileChunk struct {
+	Index  int   `json:"index"`
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+}
+
+type fileChunkOptions struct {
+	ChunkSize int64
+	Overlap   int64
+}
+
+type FileChunkOption func(*fileChunkOptions)
+
+// WithFileChunkSize sets the maximum bytes per part. Default
+// DefaultFileChunkSize, clamped to [4 KiB, 4 MiB].
+func WithFileChunkSize(size int64) FileChunkOption {
+	return func(o *fileChunkOptions) {
+		o.ChunkSize = size
+	}
+}
+
+// WithFileChunkOverlap sets the bytes repeated at the start of each part from
+// the end of the previous one. Default DefaultFileChunkOverlap, at most a
+// quarter of the chunk size.
+func WithFileChunkOverlap(overlap int64) FileChunkOption {
+	return func(o *fileChunkOptions) {
+		o.Overlap = overlap
+	}
+}
+
+// BuildChunkedAttachmentForFile returns a text attachment for a large text
+// file, split into parts. Parts end at a line break where one is available in
+// the second half of the chunk and never split a UTF-8 sequence.
+func BuildChunkedAttachmentForFile(
+	ctx context.Context,
+	pathInfo *PathInfo,
+	opts ...FileChunkOption,
+) (*Attachment, error) {
+	if pathInfo == nil {
+		return nil, errors.New("invalid input pathinfo")
+	}
+	if !pathInfo.Exists {
+		return nil, fmt.Errorf("file does not exist: %s", pathInfo.Path)
+	}
+	if pathInfo.IsDir {
+		return nil, fmt.Errorf("path %q is a directory; expected file", pathInfo.Path)
+	}
+
+	toolOut, err := llmtoolsutil.MIMEForPath(ctx, fstool.MIMEForPathArgs{
+		Path: pathInfo.Path,
+	})
+	if err != nil || toolOut == nil {
+		return nil, errors.Join(ErrUnreadableFile, err)
+	}
+	if toolOut.Mode != fstool.MIMEModeText {
+		return nil, fmt.Errorf("%w: chunking needs a text file: %s", ErrUnreadableFile, pathInfo.Path)
+	}
+
+	o := fileChunkOptions{ChunkSize: DefaultFileChunkSize, Overlap: DefaultFileChunkOverlap}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.ChunkSize = max(minFileChunkSize, min(o.ChunkSize, maxFileChunkSize))
+	o.Overlap = max(0, min(o.Overlap, o.ChunkSize/4))
+
+	att := &Attachment{
+		Kind:  AttachmentFile,
+		Label: filepath.Base(pathInfo.Path),
+		Mode:  AttachmentContentBlockModeText,
+		AvailableContentBlockModes: []AttachmentContentBlockMode{
+			AttachmentContentBlockModeText,
+		},
+		FileRef: &FileRef{
+			PathInfo: *pathInfo,
+		},
+	}
+	if err := att.PopulateRef(ctx, false); err != nil {
+		return nil, err
+	}
+
+	parts, err := splitFileChunks(ctx, att.FileRef.Path, att.FileRef.Size, o.ChunkSize, o.Overlap)
+	if err != nil {
+		return nil, err
+	}
+	att.FileRef.Chunks = &FileChunks{
+		ChunkSize: o.ChunkSize,
+		Overlap:   o.Overlap,
+		TotalSize: att.FileRef.Size,
+		Parts:     parts,
+	}
+	return att, nil
+}
+
+func splitFileChunks(
+	ctx context.Context,
+	path string,
+	total, chunkSize, overlap int64,
+) ([]FileChunk, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Join(ErrUnreadableFile, err)
+	}
+	defer f.Close()
+
+	parts := []FileChunk{}
+	buf := make([]byte, chunkSize)
+	var offset int64
+	for offset < total {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, err := f.ReadAt(buf[:min(chunkSize, total-offset)], offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, errors.Join(ErrUnreadableFile, err)
+		}
+		if n == 0 {
+			break
+		}
+		window := buf[:n]
+		end := len(window)
+		if offset+int64(n) < total {
+			end = chunkCutPoint(window)
+		}
+		parts = append(parts, FileChunk{Index: len(parts), Offset: offset, Size: int64(end)})
+		if offset+int64(end) >= total {
+			break
+		}
+
+		// Start the next part inside the overlap, on a rune boundary, and
+		// always move forward.
+		next := max(end-int(overlap), 1)
+		for next < end && !utf8.RuneStart(window[next]) {
+			next++
+		}
+		offset += int64(next)
+	}
+	return parts, nil
+}
+
+// chunkCutPoint returns where a full window should end: after the last line
+// break in its second half, else before any trailing partial UTF-8 sequence.
+func chunkCutPoint(window []byte) int {
+	half := len(window) / 2
+	if i := bytes.LastIndexByte(window[half:], '\n'); i >= 0 {
+		return half + i + 1
+	}
+	end := len(window)
+	for i := end - 1; i >= 0 && i >= end-utf8.UTFMax; i-- {
+		if utf8.RuneStart(window[i]) {
+			if !utf8.FullRune(window[i:end]) {
+				end = i
+			}
+			break
+		}
+	}
+	if end == 0 {
+		return len(window)
+	}
+	return end
+}
+
+// getChunkedTextBlock reads the selected parts and joins them, each under a
+// header giving its position in the file.
+func (ref *FileRef) getChunkedTextBlock(ctx context.Context, mimeType MIMEType) (*ContentBlock, error) {
+	chunks := ref.Chunks
+	if len(chunks.Parts) == 0 {
+		return nil, ErrUnreadableFile
+	}
+	selected := slices.Clone(chunks.SelectedParts)
+	if len(selected) == 0 {
+		selected = []int{0}
+	}
+	slices.Sort(selected)
+	selected = slices.Compact(selected)
+
+	f, err := os.Open(ref.Path)
+	if err != nil {
+		return nil, errors.Join(ErrUnreadableFile, err)
+	}
+	defer f.Close()
+
+	var sb strings.Builder
+	for _, idx := range selected {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if idx < 0 || idx >= len(chunks.Parts) {
+			return nil, fmt.Errorf("invalid chunk index %d; file has %d parts", idx, len(chunks.Parts))
+		}
+		part := chunks.Parts[idx]
+		buf := make([]byte, part.Size)
+		// A file that shrank since it was chunked yields a short last read.
+		n, err := f.ReadAt(buf, part.Offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, errors.Join(ErrUnreadableFile, err)
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "[part %d/%d, bytes %d-%d of %d]\n",
+			idx+1, len(chunks.Parts), part.Offset, part.Offset+int64(n), chunks.TotalSize)
+		sb.Write(buf[:n])
+	}
+
+	text := sb.String()
+	mStr := string(mimeType)
+	fname := filepath.Base(ref.Path)
+	filePath := ref.Path
+	return &ContentBlock{
+		Kind:     ContentBlockText,
+		Text:     &text,
+		MIMEType: &mStr,
+		FileName: &fname,
+		FilePath: &filePath,
+	}, nil
+}
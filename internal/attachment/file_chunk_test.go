@@ -0,0 +1,140 @@
+package attachment
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBuildChunkedAttachmentForFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		size    int64
+		overlap int64
+	}{
+		{
+			name:    "lines with overlap",
+			content: strings.Repeat("2026-10-15 INFO request handled in 12ms\n", 2000),
+			size:    8 * 1024,
+			overlap: 512,
+		},
+		{
+			name:    "no line breaks, multibyte runes",
+			content: strings.Repeat("日本語テキスト", 3000),
+			size:    4 * 1024,
+			overlap: 100,
+		},
+		{
+			name:    "smaller than one chunk",
+			content: "short\n",
+			size:    4 * 1024,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "big.log")
+			if err := os.WriteFile(path, []byte(tc.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			modTime := info.ModTime().UTC()
+
+			att, err := BuildChunkedAttachmentForFile(t.Context(), &PathInfo{
+				Path:    path,
+				Name:    info.Name(),
+				Exists:  true,
+				Size:    info.Size(),
+				ModTime: &modTime,
+			}, WithFileChunkSize(tc.size), WithFileChunkOverlap(tc.overlap))
+			if err != nil {
+				t.Fatalf("BuildChunkedAttachmentForFile: %v", err)
+			}
+			chunks := att.FileRef.Chunks
+			if chunks == nil || chunks.TotalSize != int64(len(tc.content)) {
+				t.Fatalf("unexpected chunks metadata: %+v", chunks)
+			}
+
+			var covered int64
+			for i, p := range chunks.Parts {
+				if p.Index != i || p.Size <= 0 || p.Size > chunks.ChunkSize {
+					t.Fatalf("bad part %d: %+v", i, p)
+				}
+				if p.Offset > covered || (i > 0 && covered-p.Offset > chunks.Overlap) {
+					t.Fatalf("part %d at %d leaves a gap or overlaps too much after %d", i, p.Offset, covered)
+				}
+				text := tc.content[p.Offset : p.Offset+p.Size]
+				if !utf8.ValidString(text) {
+					t.Fatalf("part %d splits a UTF-8 sequence", i)
+				}
+				covered = p.Offset + p.Size
+			}
+			if covered != chunks.TotalSize {
+				t.Fatalf("parts cover %d of %d bytes", covered, chunks.TotalSize)
+			}
+
+			last := len(chunks.Parts) - 1
+			chunks.SelectedParts = []int{last}
+			block, err := att.BuildContentBlock(t.Context())
+			if err != nil {
+				t.Fatalf("BuildContentBlock: %v", err)
+			}
+			p := chunks.Parts[last]
+			if block.Text == nil || !strings.HasSuffix(*block.Text, tc.content[p.Offset:]) ||
+				!strings.HasPrefix(*block.Text, "[part ") {
+				t.Fatalf("unexpected block text prefix: %.80q", *block.Text)
+			}
+
+			chunks.SelectedParts = []int{last + 1}
+			if _, err := att.BuildContentBlock(t.Context(), WithForceFetchContentBlock(true)); err == nil {
+				t.Fatal("expected error for out of range part")
+			}
+		})
+	}
+}
+
+func TestBuildChunkedAttachmentForFile_ShrunkFile(t *testing.T) {
+	content := strings.Repeat("line of text\n", 1000)
+	path := filepath.Join(t.TempDir(), "shrinking.log")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	att, err := BuildChunkedAttachmentForFile(t.Context(), &PathInfo{
+		Path:   path,
+		Name:   info.Name(),
+		Exists: true,
+		Size:   info.Size(),
+	}, WithFileChunkSize(4*1024))
+	if err != nil {
+		t.Fatalf("BuildChunkedAttachmentForFile: %v", err)
+	}
+	chunks := att.FileRef.Chunks
+	last := len(chunks.Parts) - 1
+	p := chunks.Parts[last]
+	// Cut the file in the middle of the last part, as a writer racing the
+	// snapshot check would.
+	if err := os.Truncate(path, p.Offset+p.Size/2); err != nil {
+		t.Fatal(err)
+	}
+
+	chunks.SelectedParts = []int{last}
+	block, err := att.FileRef.getChunkedTextBlock(t.Context(), MIMEType("text/plain"))
+	if err != nil {
+		t.Fatalf("getChunkedTextBlock: %v", err)
+	}
+	if strings.ContainsRune(*block.Text, 0) {
+		t.Fatal("block text is padded with NUL bytes")
+	}
+	if !strings.HasSuffix(*block.Text, content[p.Offset:p.Offset+p.Size/2]) {
+		t.Fatalf("unexpected block text suffix: %.80q", *block.Text)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -105,6 +106,16 @@ func (ref *ImageRef) BuildContentBlock(ctx context.Context) (*ContentBlock, erro
 	return buildImageBlockFromLocal(ctx, ref.Path)
 }
 
+// isHEICPath reports HEIC and HEIF images, which have no decoder here and are
+// not accepted by most providers.
+func isHEICPath(path string) bool {
+	switch FileExt(strings.ToLower(filepath.Ext(path))) {
+	case ExtHEIC, ExtHEIF:
+		return true
+	}
+	return false
+}
+
 func buildImageBlockFromLocal(ctx context.Context, path string) (*ContentBlock, error) {
 	toolOut, err := llmtoolsutil.ReadImage(ctx, imagetool.ReadImageArgs{
 		Path:              path,
@@ -0,0 +1,306 @@
+package attachment
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultJPEGQuality = 85
+	// maxDecodePixels bounds the memory an image may take once decoded; a
+	// small file can declare a huge canvas.
+	maxDecodePixels = 50_000_000
+)
+
+// ImageProcessing prepares image content blocks for a provider. JPEG, PNG and
+// GIF images are decoded. WebP images within the limits are sent unchanged;
+// those that would need a resize or conversion fail with ErrUnsupportedImage,
+// as do formats that cannot be read at all.
+type ImageProcessing struct {
+	// MaxDimension caps the longer side in pixels. Zero keeps the size.
+	MaxDimension int
+	// Format is "png" or "jpeg". Empty keeps JPEG as JPEG and writes PNG
+	// for everything else that has to be re-encoded.
+	Format string
+	// JPEGQuality is from 1 to 100. Zero means 85.
+	JPEGQuality int
+	// StripMetadata re-encodes JPEG and PNG images that need no other change,
+	// dropping their EXIF and text chunks.
+	StripMetadata bool
+}
+
+// WithImageProcessing resizes and re-encodes image content blocks built from
+// inline data. Nil leaves them as read.
+func WithImageProcessing(p *ImageProcessing) ContentBlockOption {
+	return func(o *buildContentBlockOptions) {
+		o.ImageProcessing = p
+	}
+}
+
+// processImageBlock returns cb with its image processed, or cb itself when
+// nothing had to change.
+func processImageBlock(cb *ContentBlock, p ImageProcessing) (*ContentBlock, error) {
+	if cb == nil || cb.Kind != ContentBlockImage || cb.Base64Data == nil {
+		return cb, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(*cb.Base64Data)
+	if err != nil {
+		return nil, fmt.Errorf("decode image data: %w", err)
+	}
+	out, mime, err := processImage(data, p)
+	if err != nil || out == nil {
+		return cb, err
+	}
+
+	processed := *cb
+	encoded := base64.StdEncoding.EncodeToString(out)
+	processed.Base64Data = &encoded
+	processed.MIMEType = &mime
+	if cb.FileName != nil {
+		name := imageFileName(*cb.FileName, mime)
+		processed.FileName = &name
+	}
+	return &processed, nil
+}
+
+// processImage applies p to an encoded image. It returns nil data when the
+// image is kept as is.
+func processImage(data []byte, p ImageProcessing) (out []byte, mime string, err error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		return nil, "", fmt.Errorf("%w: unknown format; convert the image to PNG or JPEG", ErrUnsupportedImage)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("read image header: %w", err)
+	}
+
+	target := p.Format
+	if target == "" {
+		target = "png"
+		if format == "jpeg" {
+			target = "jpeg"
+		}
+	}
+	resize := p.MaxDimension > 0 && max(cfg.Width, cfg.Height) > p.MaxDimension
+	convert := p.Format != "" && p.Format != format
+	strip := p.StripMetadata && (format == "jpeg" || format == "png")
+	if !resize && !convert && !strip {
+		return nil, "", nil
+	}
+	if format == "webp" {
+		return nil, "", errWebPDecode
+	}
+	if pixels := int64(cfg.Width) * int64(cfg.Height); pixels > maxDecodePixels {
+		return nil, "", fmt.Errorf("%w: %dx%d is over %d pixels",
+			ErrImageTooLarge, cfg.Width, cfg.Height, maxDecodePixels)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("decode image: %w", err)
+	}
+	img := toRGBA(src)
+	if format == "jpeg" {
+		// The EXIF orientation is lost on re-encoding, so apply it now.
+		img = orientImage(img, jpegOrientation(data))
+	}
+	if b := img.Bounds(); resize {
+		w, h := b.Dx(), b.Dy()
+		if w >= h {
+			h = max(h*p.MaxDimension/w, 1)
+			w = p.MaxDimension
+		} else {
+			w = max(w*p.MaxDimension/h, 1)
+			h = p.MaxDimension
+		}
+		img = downscaleImage(img, w, h)
+	}
+
+	var buf bytes.Buffer
+	switch target {
+	case "jpeg":
+		quality := p.JPEGQuality
+		if quality <= 0 || quality > 100 {
+			quality = defaultJPEGQuality
+		}
+		// JPEG has no alpha; flatten transparent areas onto white.
+		flat := image.NewRGBA(img.Bounds())
+		draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+		err = jpeg.Encode(&buf, flat, &jpeg.Options{Quality: quality})
+		mime = string(MIMEImageJPEG)
+	case "png":
+		err = png.Encode(&buf, img)
+		mime = string(MIMEImagePNG)
+	default:
+		return nil, "", fmt.Errorf("unsupported image format %q", target)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("encode %s image: %w", target, err)
+	}
+	return buf.Bytes(), mime, nil
+}
+
+// imageFileName swaps the extension of name for one matching mime.
+func imageFileName(name, mime string) string {
+	ext := filepath.Ext(name)
+	switch MIMEType(mime) {
+	case MIMEImageJPEG:
+		if strings.EqualFold(ext, string(ExtJPG)) || strings.EqualFold(ext, string(ExtJPEG)) {
+			return name
+		}
+		return strings.TrimSuffix(name, ext) + string(ExtJPG)
+	case MIMEImagePNG:
+		if strings.EqualFold(ext, string(ExtPNG)) {
+			return name
+		}
+		return strings.TrimSuffix(name, ext) + string(ExtPNG)
+	}
+	return name
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	if img, ok := src.(*image.RGBA); ok {
+		return img
+	}
+	b := src.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(img, img.Bounds(), src, b.Min, draw.Src)
+	return img
+}
+
+// downscaleImage shrinks src to w x h by averaging the source pixels each
+// target pixel covers.
+func downscaleImage(src *image.RGBA, w, h int) *image.RGBA {
+	sb := src.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		y0 := y * sh / h
+		y1 := max((y+1)*sh/h, y0+1)
+		for x := range w {
+			x0 := x * sw / w
+			x1 := max((x+1)*sw/w, x0+1)
+			var sum [4]uint64
+			for sy := y0; sy < y1; sy++ {
+				off := src.PixOffset(sb.Min.X+x0, sb.Min.Y+sy)
+				for range x1 - x0 {
+					for c := range 4 {
+						sum[c] += uint64(src.Pix[off+c])
+					}
+					off += 4
+				}
+			}
+			n := uint64((y1 - y0) * (x1 - x0))
+			d := dst.PixOffset(x, y)
+			for c := range 4 {
+				dst.Pix[d+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
+
+// orientImage turns src upright for an EXIF orientation value (1-8).
+func orientImage(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range h {
+		for x := range w {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			s := src.PixOffset(b.Min.X+x, b.Min.Y+y)
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], src.Pix[s:s+4])
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag of a JPEG, or returns 1.
+func jpegOrientation(data []byte) int {
+	const (
+		markerSOS  = 0xDA
+		markerAPP1 = 0xE1
+		tagOrient  = 0x0112
+	)
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == markerSOS || size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		seg := data[i+4 : i+2+size]
+		i += 2 + size
+		if marker != markerAPP1 || !bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			continue
+		}
+		tiff := seg[6:]
+		if len(tiff) < 8 {
+			return 1
+		}
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case "II":
+			order = binary.LittleEndian
+		case "MM":
+			order = binary.BigEndian
+		default:
+			return 1
+		}
+		ifd := int(order.Uint32(tiff[4:]))
+		if ifd+2 > len(tiff) {
+			return 1
+		}
+		entries := int(order.Uint16(tiff[ifd:]))
+		for e := range entries {
+			off := ifd + 2 + e*12
+			if off+12 > len(tiff) {
+				return 1
+			}
+			if order.Uint16(tiff[off:]) == tagOrient {
+				if v := int(order.Uint16(tiff[off+8:])); v >= 1 && v <= 8 {
+					return v
+				}
+				return 1
+			}
+		}
+		return 1
+	}
+	return 1
+}
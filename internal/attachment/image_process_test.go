@@ -0,0 +1,232 @@
+package attachment
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func encodeTestImage(t *testing.T, img image.Image, format string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, img, nil)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatalf("encode %s: %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+// withEXIFOrientation inserts an APP1 segment with only the orientation tag
+// right after the JPEG SOI marker.
+func withEXIFOrientation(data []byte, orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 0x2a, 0, 0, 0, 8,
+		0, 1,
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0,
+		0, 0, 0, 0,
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	size := len(payload) + 2
+	seg := append([]byte{0xFF, 0xE1, byte(size >> 8), byte(size)}, payload...)
+	out := append([]byte{}, data[:2]...)
+	out = append(out, seg...)
+	return append(out, data[2:]...)
+}
+
+func TestProcessImageBlock(t *testing.T) {
+	block := func(data []byte, mime, name string) *ContentBlock {
+		encoded := base64.StdEncoding.EncodeToString(data)
+		return &ContentBlock{Kind: ContentBlockImage, Base64Data: &encoded, MIMEType: &mime, FileName: &name}
+	}
+	decode := func(t *testing.T, cb *ContentBlock) (image.Config, string) {
+		t.Helper()
+		raw, err := base64.StdEncoding.DecodeString(*cb.Base64Data)
+		if err != nil {
+			t.Fatalf("decode base64: %v", err)
+		}
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("decode image: %v", err)
+		}
+		return cfg, format
+	}
+
+	tests := []struct {
+		name       string
+		in         *ContentBlock
+		p          ImageProcessing
+		wantSame   bool
+		wantW      int
+		wantH      int
+		wantFormat string
+		wantName   string
+		wantErr    error
+	}{
+		{
+			name:       "downscale keeps aspect and format",
+			in:         block(encodeTestImage(t, testImage(400, 200), "png"), "image/png", "wide.png"),
+			p:          ImageProcessing{MaxDimension: 100},
+			wantW:      100,
+			wantH:      50,
+			wantFormat: "png",
+			wantName:   "wide.png",
+		},
+		{
+			name:       "convert png to jpeg",
+			in:         block(encodeTestImage(t, testImage(40, 30), "png"), "image/png", "shot.png"),
+			p:          ImageProcessing{Format: "jpeg"},
+			wantW:      40,
+			wantH:      30,
+			wantFormat: "jpeg",
+			wantName:   "shot.jpg",
+		},
+		{
+			name: "strip applies exif rotation",
+			in: block(withEXIFOrientation(encodeTestImage(t, testImage(60, 20), "jpeg"), 6),
+				"image/jpeg", "phone.jpeg"),
+			p:          ImageProcessing{StripMetadata: true},
+			wantW:      20,
+			wantH:      60,
+			wantFormat: "jpeg",
+			wantName:   "phone.jpeg",
+		},
+		{
+			name:     "small image unchanged",
+			in:       block(encodeTestImage(t, testImage(40, 30), "png"), "image/png", "small.png"),
+			p:        ImageProcessing{MaxDimension: 100},
+			wantSame: true,
+		},
+		{
+			name:     "small webp unchanged",
+			in:       block(testWebPLossless(40, 30), "image/webp", "photo.webp"),
+			p:        ImageProcessing{MaxDimension: 100, StripMetadata: true},
+			wantSame: true,
+		},
+		{
+			name:    "webp needing resize is rejected",
+			in:      block(testWebPLossless(400, 300), "image/webp", "photo.webp"),
+			p:       ImageProcessing{MaxDimension: 100},
+			wantErr: ErrUnsupportedImage,
+		},
+		{
+			name:    "undecodable format is rejected",
+			in:      block([]byte("\x00\x01not an image"), "image/x-unknown", "photo.bin"),
+			p:       ImageProcessing{MaxDimension: 100, Format: "jpeg"},
+			wantErr: ErrUnsupportedImage,
+		},
+		{
+			name:    "oversized canvas is rejected before decoding",
+			in:      block(testPNGHeader(20000, 20000), "image/png", "huge.png"),
+			p:       ImageProcessing{MaxDimension: 100},
+			wantErr: ErrImageTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := processImageBlock(tt.in, tt.p)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("processImageBlock = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processImageBlock: %v", err)
+			}
+			if tt.wantSame {
+				if got != tt.in {
+					t.Fatalf("block was rewritten")
+				}
+				return
+			}
+			cfg, format := decode(t, got)
+			if cfg.Width != tt.wantW || cfg.Height != tt.wantH || format != tt.wantFormat {
+				t.Fatalf("got %dx%d %s, want %dx%d %s",
+					cfg.Width, cfg.Height, format, tt.wantW, tt.wantH, tt.wantFormat)
+			}
+			if *got.MIMEType != "image/"+tt.wantFormat || *got.FileName != tt.wantName {
+				t.Fatalf("mime=%q name=%q", *got.MIMEType, *got.FileName)
+			}
+			if tt.wantFormat == "jpeg" && jpegOrientation(mustDecodeBase64(t, *got.Base64Data)) != 1 {
+				t.Fatalf("orientation kept after re-encoding")
+			}
+		})
+	}
+}
+
+func mustDecodeBase64(t *testing.T, s string) []byte {
+	t.Helper()
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode base64: %v", err)
+	}
+	return raw
+}
+
+// testWebPLossless returns the header of a lossless WebP file; the pixel data
+// is never read.
+func testWebPLossless(w, h int) []byte {
+	out := []byte("RIFF\x00\x00\x00\x00WEBPVP8L\x05\x00\x00\x00\x2f")
+	out = binary.LittleEndian.AppendUint32(out, uint32(w-1)|uint32(h-1)<<14)
+	return append(out, make([]byte, 8)...)
+}
+
+// testPNGHeader returns a PNG signature and IHDR chunk declaring w x h; the
+// image data is missing, so only DecodeConfig succeeds.
+func testPNGHeader(w, h int) []byte {
+	ihdr := []byte("IHDR")
+	ihdr = binary.BigEndian.AppendUint32(ihdr, uint32(w))
+	ihdr = binary.BigEndian.AppendUint32(ihdr, uint32(h))
+	ihdr = append(ihdr, 8, 6, 0, 0, 0)
+	out := []byte("\x89PNG\r\n\x1a\n")
+	out = binary.BigEndian.AppendUint32(out, uint32(len(ihdr)-4))
+	out = append(out, ihdr...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(ihdr))
+}
+
+func TestDecodeWebPConfig(t *testing.T) {
+	lossy := []byte("RIFF\x00\x00\x00\x00WEBPVP8 \x00\x00\x00\x00\x00\x00\x00\x9d\x01\x2a")
+	lossy = binary.LittleEndian.AppendUint16(lossy, 640)
+	lossy = binary.LittleEndian.AppendUint16(lossy, 480)
+	extended := []byte("RIFF\x00\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00\x10\x00\x00\x00")
+	extended = append(extended, 0x1f, 0x03, 0x00, 0x57, 0x02, 0x00) // 800 x 600
+
+	for name, tt := range map[string]struct {
+		data []byte
+		w, h int
+	}{
+		"lossy":    {lossy, 640, 480},
+		"lossless": {testWebPLossless(123, 45), 123, 45},
+		"extended": {extended, 800, 600},
+	} {
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(tt.data))
+		if err != nil || format != "webp" || cfg.Width != tt.w || cfg.Height != tt.h {
+			t.Errorf("%s: got %dx%d %q, %v; want %dx%d", name, cfg.Width, cfg.Height, format, err, tt.w, tt.h)
+		}
+	}
+	if _, _, err := image.Decode(bytes.NewReader(lossy)); !errors.Is(err, ErrUnsupportedImage) {
+		t.Errorf("Decode(webp) = %v, want ErrUnsupportedImage", err)
+	}
+}
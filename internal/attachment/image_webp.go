@@ -0,0 +1,66 @@
+package attachment
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// The standard library has no WebP codec. Registering the header reader lets
+// image.DecodeConfig report WebP dimensions, so WebP attachments can be read
+// and sent unchanged. Pixel decoding is not available, so WebP images that
+// have to be resized or converted fail with ErrUnsupportedImage.
+func init() {
+	image.RegisterFormat("webp", "RIFF????WEBP", decodeWebP, decodeWebPConfig)
+}
+
+var errWebPDecode = fmt.Errorf(
+	"%w: WebP images cannot be resized or converted; convert the image to PNG or JPEG",
+	ErrUnsupportedImage,
+)
+
+func decodeWebP(io.Reader) (image.Image, error) {
+	return nil, errWebPDecode
+}
+
+// decodeWebPConfig reads the canvas size from the first chunk of a WebP file:
+// VP8 (lossy), VP8L (lossless) or VP8X (extended).
+func decodeWebPConfig(r io.Reader) (image.Config, error) {
+	var hdr [30]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return image.Config{}, fmt.Errorf("webp: read header: %w", err)
+	}
+	if string(hdr[0:4]) != "RIFF" || string(hdr[8:12]) != "WEBP" {
+		return image.Config{}, image.ErrFormat
+	}
+	data := hdr[20:]
+	var w, h int
+	switch string(hdr[12:16]) {
+	case "VP8 ":
+		// Frame tag (3 bytes), start code, then 14-bit width and height.
+		if data[3] != 0x9d || data[4] != 0x01 || data[5] != 0x2a {
+			return image.Config{}, errors.New("webp: invalid VP8 start code")
+		}
+		w = int(binary.LittleEndian.Uint16(data[6:]) & 0x3fff)
+		h = int(binary.LittleEndian.Uint16(data[8:]) & 0x3fff)
+	case "VP8L":
+		if data[0] != 0x2f {
+			return image.Config{}, errors.New("webp: invalid VP8L signature")
+		}
+		bits := binary.LittleEndian.Uint32(data[1:])
+		w = int(bits&0x3fff) + 1
+		h = int(bits>>14&0x3fff) + 1
+	case "VP8X":
+		w = int(uint32(data[4])|uint32(data[5])<<8|uint32(data[6])<<16) + 1
+		h = int(uint32(data[7])|uint32(data[8])<<8|uint32(data[9])<<16) + 1
+	default:
+		return image.Config{}, fmt.Errorf("webp: unknown chunk %q", hdr[12:16])
+	}
+	if w == 0 || h == 0 {
+		return image.Config{}, fmt.Errorf("webp: invalid size %dx%d", w, h)
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: w, Height: h}, nil
+}
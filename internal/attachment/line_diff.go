@@ -0,0 +1,202 @@
+package attachment
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// unifiedDiffContextLines is the number of unchanged lines kept around each change.
+	unifiedDiffContextLines = 3
+	// maxLineDiffCells bounds the LCS table; larger inputs are emitted as a
+	// single replace hunk instead of a minimal diff.
+	maxLineDiffCells = 1 << 22
+)
+
+type lineOpKind byte
+
+const (
+	lineOpEqual  lineOpKind = ' '
+	lineOpDelete lineOpKind = '-'
+	lineOpInsert lineOpKind = '+'
+)
+
+type lineOp struct {
+	kind lineOpKind
+	line string
+}
+
+// unifiedLineDiff renders a unified diff between oldText and newText.
+// It returns an empty string when both texts are identical.
+func unifiedLineDiff(oldName, newName, oldText, newText string) string {
+	ops := diffLines(splitDiffLines(oldText), splitDiffLines(newText))
+
+	var sb strings.Builder
+	for _, h := range groupDiffHunks(ops, unifiedDiffContextLines) {
+		if sb.Len() == 0 {
+			sb.WriteString("--- " + oldName + "\n")
+			sb.WriteString("+++ " + newName + "\n")
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n",
+			formatHunkRange(h.oldStart, h.oldCount),
+			formatHunkRange(h.newStart, h.newCount),
+		)
+		for _, op := range ops[h.from:h.to] {
+			sb.WriteByte(byte(op.kind))
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+func splitDiffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a line edit script. Common prefix/suffix are stripped
+// first, and the remaining middle is diffed with an LCS table.
+func diffLines(a, b []string) []lineOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]lineOp, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		ops = append(ops, lineOp{kind: lineOpEqual, line: l})
+	}
+
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+	if (len(midA)+1)*(len(midB)+1) > maxLineDiffCells {
+		for _, l := range midA {
+			ops = append(ops, lineOp{kind: lineOpDelete, line: l})
+		}
+		for _, l := range midB {
+			ops = append(ops, lineOp{kind: lineOpInsert, line: l})
+		}
+	} else {
+		ops = append(ops, lcsLineOps(midA, midB)...)
+	}
+
+	for _, l := range a[len(a)-suffix:] {
+		ops = append(ops, lineOp{kind: lineOpEqual, line: l})
+	}
+	return ops
+}
+
+func lcsLineOps(a, b []string) []lineOp {
+	n, m := len(a), len(b)
+	width := m + 1
+	// Table[i][j] is the LCS length of a[i:] and b[j:].
+	table := make([]int32, (n+1)*width)
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i*width+j] = table[(i+1)*width+j+1] + 1
+			} else {
+				table[i*width+j] = max(table[(i+1)*width+j], table[i*width+j+1])
+			}
+		}
+	}
+
+	ops := make([]lineOp, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, lineOp{kind: lineOpEqual, line: a[i]})
+			i++
+			j++
+		case table[(i+1)*width+j] >= table[i*width+j+1]:
+			ops = append(ops, lineOp{kind: lineOpDelete, line: a[i]})
+			i++
+		default:
+			ops = append(ops, lineOp{kind: lineOpInsert, line: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, lineOp{kind: lineOpDelete, line: a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, lineOp{kind: lineOpInsert, line: b[j]})
+	}
+	return ops
+}
+
+type diffHunk struct {
+	from, to           int // op index range [from, to)
+	oldStart, oldCount int
+	newStart, newCount int
+}
+
+func groupDiffHunks(ops []lineOp, contextLines int) []diffHunk {
+	hunks := []diffHunk{}
+	for idx := 0; idx < len(ops); {
+		if ops[idx].kind == lineOpEqual {
+			idx++
+			continue
+		}
+		from := max(0, idx-contextLines)
+		// Changes separated by at most 2*contextLines equal lines share a hunk.
+		last := idx
+		for next := idx + 1; next < len(ops) && next <= last+2*contextLines; next++ {
+			if ops[next].kind != lineOpEqual {
+				last = next
+			}
+		}
+		to := min(len(ops), last+contextLines+1)
+		hunks = append(hunks, newDiffHunk(ops, from, to))
+		idx = last + 1
+	}
+	return hunks
+}
+
+func newDiffHunk(ops []lineOp, from, to int) diffHunk {
+	h := diffHunk{from: from, to: to}
+	oldBefore, newBefore := 0, 0
+	for _, op := range ops[:from] {
+		if op.kind != lineOpInsert {
+			oldBefore++
+		}
+		if op.kind != lineOpDelete {
+			newBefore++
+		}
+	}
+	for _, op := range ops[from:to] {
+		if op.kind != lineOpInsert {
+			h.oldCount++
+		}
+		if op.kind != lineOpDelete {
+			h.newCount++
+		}
+	}
+	h.oldStart = oldBefore + 1
+	if h.oldCount == 0 {
+		h.oldStart = oldBefore
+	}
+	h.newStart = newBefore + 1
+	if h.newCount == 0 {
+		h.newStart = newBefore
+	}
+	return h
+}
+
+func formatHunkRange(start, count int) string {
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
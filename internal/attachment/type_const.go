@@ -10,6 +10,8 @@ var (
 	ErrUnreadableFile                  = errors.New("unreadable file")
 	ErrExistingContentBlock            = errors.New("content block already exists")
 	ErrAttachmentModifiedSinceSnapshot = errors.New("attachment modified since snapshot")
+	ErrUnsupportedImage                = errors.New("unsupported image")
+	ErrImageTooLarge                   = errors.New("image too large")
 )
 
 const (
@@ -27,6 +29,7 @@ const (
 	AttachmentDocIndex AttachmentKind = "docIndex"
 	AttachmentPR       AttachmentKind = "pr"
 	AttachmentCommit   AttachmentKind = "commit"
+	AttachmentDirDiff  AttachmentKind = "dirDiff"
 )
 
 type PathInfo struct {
@@ -87,4 +90,7 @@ type PathAttachmentsResult struct {
 	FileAttachments []Attachment                 `json:"fileAttachments"`
 	DirAttachments  []DirectoryAttachmentsResult `json:"dirAttachments"`
 	Errors          []string                     `json:"errors,omitempty"`
+	// UntrustedPaths lists inputs outside trusted directories that need an
+	// explicit trust confirmation before they can be attached.
+	UntrustedPaths []string `json:"untrustedPaths,omitempty"`
 }
@@ -20,6 +20,8 @@ const (
 	AttachmentContentBlockModePRPage     AttachmentContentBlockMode = "pr-page"
 	AttachmentContentBlockModeCommitDiff AttachmentContentBlockMode = "commit-diff"
 	AttachmentContentBlockModeCommitPage AttachmentContentBlockMode = "commit-page"
+
+	AttachmentContentBlockModeDirDiff AttachmentContentBlockMode = "dir-diff" // Unified diff of two directories
 )
 
 type AttachmentContentBlockKind string
@@ -47,6 +49,10 @@ type ContentBlock struct {
 	// For URL based attachments, Base64Data may or may not be present depending on AttachmentMode.
 	Base64Data *string `json:"base64Data,omitempty"`
 
+	// BlobRef is the "sha256:<hex>" digest of the decoded Base64Data when the
+	// payload is kept in the shared attachment blob store instead of inline.
+	BlobRef *string `json:"blobRef,omitempty"`
+
 	// URL is populated for URL-based attachments.
 	URL *string `json:"url,omitempty"`
 }
@@ -1,7 +1,6 @@
 package builtin
 
 import (
-	"log/slog"
 	"runtime/debug"
 	"sync"
 	"sync/atomic"
@@ -108,14 +107,14 @@ func (r *AsyncRebuilder) Trigger() {
 			r.mu.Unlock()
 
 			if rec != nil {
-				slog.Error("panic in async rebuild",
+				logger.Error("panic in async rebuild",
 					"err", rec,
 					"stack", debug.Stack())
 			}
 		}()
 
 		if err := r.fn(); err != nil {
-			slog.Error("async rebuild failed", "error", err)
+			logger.Error("async rebuild failed", "error", err)
 			return
 		}
 
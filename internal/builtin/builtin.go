@@ -4,8 +4,12 @@ import (
 	"embed"
 
 	"github.com/flexigpt/inference-go/modelpreset"
+
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 )
 
+var logger = logging.Module("builtin")
+
 //go:embed tools
 var BuiltInToolBundlesFS embed.FS
 
@@ -0,0 +1,109 @@
+package bundleitemutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrReservedName is returned when a user-chosen name falls in a namespace
+// reserved for built-in entities.
+var ErrReservedName = errors.New("name is reserved")
+
+// DefaultReservedPrefixes are reserved for app-shipped entities in every store.
+var DefaultReservedPrefixes = []string{"builtin-", "flexigpt-"}
+
+// ReservedNamespace describes names that users may not claim. Matching is
+// case-insensitive.
+type ReservedNamespace struct {
+	// Prefixes reserve every name starting with them, e.g. "builtin-".
+	Prefixes []string `json:"prefixes,omitempty"`
+
+	// Names reserve the name and its numbered variants, so that "openai" also
+	// reserves "openai2", "openai-2" and "openai_2".
+	Names []string `json:"names,omitempty"`
+}
+
+// DefaultReservedNamespace returns a namespace with the default prefixes.
+func DefaultReservedNamespace() ReservedNamespace {
+	return ReservedNamespace{Prefixes: append([]string(nil), DefaultReservedPrefixes...)}
+}
+
+// WithNames returns a copy of r that additionally reserves names.
+func (r ReservedNamespace) WithNames(names ...string) ReservedNamespace {
+	return ReservedNamespace{
+		Prefixes: append([]string(nil), r.Prefixes...),
+		Names:    append(append([]string(nil), r.Names...), names...),
+	}
+}
+
+// Digest identifies the reserved set independent of order and case. Stores
+// record it after migrating user names, so the migration runs again only when
+// the namespace changes, e.g. when a new built-in ships.
+func (r ReservedNamespace) Digest() string {
+	norm := func(in []string) []string {
+		out := make([]string, 0, len(in))
+		for _, v := range in {
+			if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+				out = append(out, v)
+			}
+		}
+		slices.Sort(out)
+		return slices.Compact(out)
+	}
+	h := sha256.New()
+	for _, p := range norm(r.Prefixes) {
+		fmt.Fprintf(h, "p:%s\n", p)
+	}
+	for _, n := range norm(r.Names) {
+		fmt.Fprintf(h, "n:%s\n", n)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Check returns an error wrapping ErrReservedName if name is reserved.
+func (r ReservedNamespace) Check(name string) error {
+	lower := strings.ToLower(strings.TrimSpace(name))
+	if lower == "" {
+		return nil
+	}
+	for _, p := range r.Prefixes {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p != "" && strings.HasPrefix(lower, p) {
+			return fmt.Errorf("%w: %q uses reserved prefix %q", ErrReservedName, name, p)
+		}
+	}
+	for _, n := range r.Names {
+		n = strings.ToLower(strings.TrimSpace(n))
+		if n == "" {
+			continue
+		}
+		rest, ok := strings.CutPrefix(lower, n)
+		if !ok {
+			continue
+		}
+		if rest == "" || isNumberedSuffix(rest) {
+			return fmt.Errorf("%w: %q collides with reserved name %q", ErrReservedName, name, n)
+		}
+	}
+	return nil
+}
+
+// isNumberedSuffix reports whether s is digits, optionally after one '-' or '_'.
+func isNumberedSuffix(s string) bool {
+	if s[0] == '-' || s[0] == '_' {
+		s = s[1:]
+	}
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,297 @@
+// Package consistency validates references between stores that no single
+// store can check, such as the default provider of the model presets or the
+// auth key a provider needs.
+package consistency
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/consistency/spec"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	settingSpec "github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// Store names used in issues.
+const (
+	StoreModelPreset = "modelpreset"
+	StoreSkill       = "skill"
+	StoreSetting     = "setting"
+)
+
+const skillMDFileName = "SKILL.md"
+
+// Snapshot is the store data a check runs on.
+type Snapshot struct {
+	DefaultProvider inferenceSpec.ProviderName
+	// Providers include disabled ones.
+	Providers []modelpresetSpec.ProviderPreset
+	AuthKeys  []settingSpec.AuthKeyMeta
+	// Skills include disabled ones.
+	Skills []skillstoreSpec.SkillListItem
+
+	// StoreErrors holds the stores that could not be read. Checks that need
+	// them are skipped and each is reported as an issue.
+	StoreErrors map[string]error
+}
+
+// Check returns the broken references in s. Skill locations are checked on
+// disk.
+func Check(s Snapshot) *spec.ConsistencyReport {
+	var issues []spec.ConsistencyIssue
+	stores := make([]string, 0, len(s.StoreErrors))
+	for store := range s.StoreErrors {
+		stores = append(stores, store)
+	}
+	sort.Strings(stores)
+	for _, store := range stores {
+		issues = append(issues, spec.ConsistencyIssue{
+			Code:     spec.ConsistencyIssueStoreUnavailable,
+			Severity: spec.ConsistencySeverityError,
+			Store:    store,
+			Message:  fmt.Sprintf("store could not be read: %v", s.StoreErrors[store]),
+		})
+	}
+
+	providers := liveProviders(s.Providers)
+	if s.StoreErrors[StoreModelPreset] == nil {
+		issues = append(issues, checkDefaultProvider(s.DefaultProvider, providers)...)
+		for _, pp := range providers {
+			issues = append(issues, checkDefaultModelPreset(pp)...)
+		}
+		if s.StoreErrors[StoreSetting] == nil {
+			issues = append(issues, checkAuthKeys(providers, s.AuthKeys)...)
+		}
+	}
+	if s.StoreErrors[StoreSkill] == nil {
+		issues = append(issues, checkSkillLocations(s.Skills)...)
+	}
+
+	report := &spec.ConsistencyReport{CheckedAt: time.Now().UTC(), OK: true, Issues: issues}
+	if report.Issues == nil {
+		report.Issues = []spec.ConsistencyIssue{}
+	}
+	for _, issue := range issues {
+		if issue.Severity == spec.ConsistencySeverityError {
+			report.OK = false
+		}
+	}
+	return report
+}
+
+// liveProviders drops soft-deleted providers and sorts the rest by name.
+func liveProviders(all []modelpresetSpec.ProviderPreset) []modelpresetSpec.ProviderPreset {
+	out := make([]modelpresetSpec.ProviderPreset, 0, len(all))
+	for _, pp := range all {
+		if pp.SoftDeletedAt == nil {
+			out = append(out, pp)
+		}
+	}
+	slices.SortFunc(out, func(a, b modelpresetSpec.ProviderPreset) int {
+		return cmp.Compare(string(a.Name), string(b.Name))
+	})
+	return out
+}
+
+func checkDefaultProvider(
+	name inferenceSpec.ProviderName,
+	providers []modelpresetSpec.ProviderPreset,
+) []spec.ConsistencyIssue {
+	var fallback []spec.ConsistencyFixSuggestion
+	for _, pp := range providers {
+		if pp.IsEnabled && pp.Name != name {
+			fallback = append(fallback, spec.ConsistencyFixSuggestion{
+				Action:      spec.ConsistencyFixSetDefaultProvider,
+				Description: fmt.Sprintf("Make %q the default provider", pp.Name),
+				Params:      map[string]string{"providerName": string(pp.Name)},
+			})
+			break
+		}
+	}
+
+	if name == "" {
+		return []spec.ConsistencyIssue{{
+			Code:        spec.ConsistencyIssueNoDefaultProvider,
+			Severity:    spec.ConsistencySeverityWarning,
+			Store:       StoreModelPreset,
+			Message:     "no default provider is set",
+			Suggestions: fallback,
+		}}
+	}
+	idx := slices.IndexFunc(providers, func(pp modelpresetSpec.ProviderPreset) bool { return pp.Name == name })
+	if idx < 0 {
+		return []spec.ConsistencyIssue{{
+			Code:        spec.ConsistencyIssueDefaultProviderMissing,
+			Severity:    spec.ConsistencySeverityError,
+			Store:       StoreModelPreset,
+			Subject:     string(name),
+			Message:     fmt.Sprintf("default provider %q does not exist", name),
+			Suggestions: fallback,
+		}}
+	}
+	if !providers[idx].IsEnabled {
+		return []spec.ConsistencyIssue{{
+			Code:     spec.ConsistencyIssueDefaultProviderDisabled,
+			Severity: spec.ConsistencySeverityWarning,
+			Store:    StoreModelPreset,
+			Subject:  string(name),
+			Message:  fmt.Sprintf("default provider %q is disabled", name),
+			Suggestions: append([]spec.ConsistencyFixSuggestion{{
+				Action:      spec.ConsistencyFixEnableProvider,
+				Description: fmt.Sprintf("Enable %q", name),
+				Params:      map[string]string{"providerName": string(name)},
+			}}, fallback...),
+		}}
+	}
+	return nil
+}
+
+func checkDefaultModelPreset(pp modelpresetSpec.ProviderPreset) []spec.ConsistencyIssue {
+	id := pp.DefaultModelPresetID
+	if id == "" || !pp.IsEnabled {
+		return nil
+	}
+	var fallback []spec.ConsistencyFixSuggestion
+	ids := make([]string, 0, len(pp.ModelPresets))
+	for mid := range pp.ModelPresets {
+		ids = append(ids, string(mid))
+	}
+	sort.Strings(ids)
+	for _, mid := range ids {
+		if m := pp.ModelPresets[modelpresetSpec.ModelPresetID(mid)]; m.IsEnabled && m.ID != id {
+			fallback = append(fallback, spec.ConsistencyFixSuggestion{
+				Action:      spec.ConsistencyFixSetDefaultModelPreset,
+				Description: fmt.Sprintf("Make %q the default model preset of %q", mid, pp.Name),
+				Params:      map[string]string{"providerName": string(pp.Name), "modelPresetID": mid},
+			})
+			break
+		}
+	}
+
+	m, ok := pp.ModelPresets[id]
+	if !ok {
+		return []spec.ConsistencyIssue{{
+			Code:        spec.ConsistencyIssueDefaultModelMissing,
+			Severity:    spec.ConsistencySeverityError,
+			Store:       StoreModelPreset,
+			Subject:     string(pp.Name),
+			Message:     fmt.Sprintf("default model preset %q of provider %q does not exist", id, pp.Name),
+			Suggestions: fallback,
+		}}
+	}
+	if !m.IsEnabled {
+		return []spec.ConsistencyIssue{{
+			Code:     spec.ConsistencyIssueDefaultModelDisabled,
+			Severity: spec.ConsistencySeverityWarning,
+			Store:    StoreModelPreset,
+			Subject:  string(pp.Name),
+			Message:  fmt.Sprintf("default model preset %q of provider %q is disabled", id, pp.Name),
+			Suggestions: append([]spec.ConsistencyFixSuggestion{{
+				Action:      spec.ConsistencyFixEnableModelPreset,
+				Description: fmt.Sprintf("Enable %q", id),
+				Params:      map[string]string{"providerName": string(pp.Name), "modelPresetID": string(id)},
+			}}, fallback...),
+		}}
+	}
+	return nil
+}
+
+// checkAuthKeys reports enabled providers that send an API key but have no
+// provider auth key of their name. Local servers such as Ollama run without a
+// key and are skipped.
+func checkAuthKeys(
+	providers []modelpresetSpec.ProviderPreset,
+	keys []settingSpec.AuthKeyMeta,
+) []spec.ConsistencyIssue {
+	present := map[settingSpec.AuthKeyName]bool{}
+	for _, k := range keys {
+		if k.Type == settingSpec.AuthKeyTypeProvider && k.NonEmpty {
+			present[k.KeyName] = true
+		}
+	}
+	var issues []spec.ConsistencyIssue
+	for _, pp := range providers {
+		if !pp.IsEnabled || pp.APIKeyHeaderKey == "" || modelpresetSpec.IsLocalSDKType(pp.SDKType) ||
+			present[settingSpec.AuthKeyName(pp.Name)] {
+			continue
+		}
+		issues = append(issues, spec.ConsistencyIssue{
+			Code:     spec.ConsistencyIssueProviderAuthKeyMissing,
+			Severity: spec.ConsistencySeverityWarning,
+			Store:    StoreSetting,
+			Subject:  string(pp.Name),
+			Message:  fmt.Sprintf("provider %q has no API key", pp.Name),
+			Suggestions: []spec.ConsistencyFixSuggestion{{
+				Action:      spec.ConsistencyFixSetAuthKey,
+				Description: fmt.Sprintf("Set the API key of %q", pp.Name),
+				Params: map[string]string{
+					"type":    string(settingSpec.AuthKeyTypeProvider),
+					"keyName": string(pp.Name),
+				},
+			}},
+		})
+	}
+	return issues
+}
+
+// checkSkillLocations reports user filesystem skills whose package is gone.
+// Missing enabled skills are errors, disabled ones warnings.
+func checkSkillLocations(skills []skillstoreSpec.SkillListItem) []spec.ConsistencyIssue {
+	sorted := slices.Clone(skills)
+	slices.SortFunc(sorted, func(a, b skillstoreSpec.SkillListItem) int {
+		if c := cmp.Compare(string(a.BundleID), string(b.BundleID)); c != 0 {
+			return c
+		}
+		return cmp.Compare(string(a.SkillSlug), string(b.SkillSlug))
+	})
+
+	var issues []spec.ConsistencyIssue
+	for _, item := range sorted {
+		sk := item.SkillDefinition
+		if item.IsBuiltIn || sk.Type != skillstoreSpec.SkillTypeFS {
+			continue
+		}
+		_, err := os.Stat(filepath.Join(sk.Location, skillMDFileName))
+		if err == nil {
+			continue
+		}
+		msg := fmt.Sprintf("skill package %q cannot be read: %v", sk.Location, err)
+		if errors.Is(err, os.ErrNotExist) {
+			msg = fmt.Sprintf("skill package %q does not exist", sk.Location)
+		}
+		severity := spec.ConsistencySeverityWarning
+		if sk.IsEnabled {
+			severity = spec.ConsistencySeverityError
+		}
+		params := map[string]string{"bundleID": string(item.BundleID), "skillSlug": string(item.SkillSlug)}
+		suggestions := []spec.ConsistencyFixSuggestion{{
+			Action:      spec.ConsistencyFixDeleteSkill,
+			Description: "Delete the skill",
+			Params:      params,
+		}}
+		if sk.IsEnabled {
+			suggestions = append([]spec.ConsistencyFixSuggestion{{
+				Action:      spec.ConsistencyFixDisableSkill,
+				Description: "Disable the skill until its package is back",
+				Params:      params,
+			}}, suggestions...)
+		}
+		issues = append(issues, spec.ConsistencyIssue{
+			Code:        spec.ConsistencyIssueSkillLocationMissing,
+			Severity:    severity,
+			Store:       StoreSkill,
+			Subject:     string(item.BundleID) + "/" + string(item.SkillSlug),
+			Message:     msg,
+			Suggestions: suggestions,
+		})
+	}
+	return issues
+}
@@ -0,0 +1,197 @@
+package consistency
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/consistency/spec"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	settingSpec "github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+func provider(name string, enabled bool, def string, models map[string]bool) modelpresetSpec.ProviderPreset {
+	pp := modelpresetSpec.ProviderPreset{
+		Name:                 inferenceSpec.ProviderName(name),
+		IsEnabled:            enabled,
+		APIKeyHeaderKey:      "Authorization",
+		DefaultModelPresetID: modelpresetSpec.ModelPresetID(def),
+		ModelPresets:         map[modelpresetSpec.ModelPresetID]modelpresetSpec.ModelPreset{},
+	}
+	for id, on := range models {
+		mid := modelpresetSpec.ModelPresetID(id)
+		pp.ModelPresets[mid] = modelpresetSpec.ModelPreset{ID: mid, IsEnabled: on}
+	}
+	return pp
+}
+
+func skill(slug, location string, enabled bool) skillstoreSpec.SkillListItem {
+	return skillstoreSpec.SkillListItem{
+		BundleID:  "b1",
+		SkillSlug: skillstoreSpec.SkillSlug(slug),
+		SkillDefinition: skillstoreSpec.Skill{
+			Type:      skillstoreSpec.SkillTypeFS,
+			Location:  location,
+			IsEnabled: enabled,
+		},
+	}
+}
+
+func providerKey(name string) settingSpec.AuthKeyMeta {
+	return settingSpec.AuthKeyMeta{
+		Type:     settingSpec.AuthKeyTypeProvider,
+		KeyName:  settingSpec.AuthKeyName(name),
+		NonEmpty: true,
+	}
+}
+
+func TestCheck(t *testing.T) {
+	present := t.TempDir()
+	if err := os.WriteFile(filepath.Join(present, skillMDFileName), []byte("---\n---\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(t.TempDir(), "gone")
+	deleted := time.Now()
+	gone := provider("gone", true, "", nil)
+	gone.SoftDeletedAt = &deleted
+	ollama := provider("ollama", true, "", nil)
+	ollama.SDKType = modelpresetSpec.ProviderSDKTypeOllama
+
+	type want struct {
+		code     spec.ConsistencyIssueCode
+		subject  string
+		severity spec.ConsistencySeverity
+		fix      spec.ConsistencyFixAction
+	}
+	tests := []struct {
+		name   string
+		snap   Snapshot
+		wantOK bool
+		want   []want
+	}{
+		{
+			name: "consistent",
+			snap: Snapshot{
+				DefaultProvider: "a",
+				Providers: []modelpresetSpec.ProviderPreset{
+					provider("a", true, "m1", map[string]bool{"m1": true}),
+				},
+				AuthKeys: []settingSpec.AuthKeyMeta{providerKey("a")},
+				Skills:   []skillstoreSpec.SkillListItem{skill("s1", present, true)},
+			},
+			wantOK: true,
+		},
+		{
+			name: "default-provider-soft-deleted",
+			snap: Snapshot{
+				DefaultProvider: "gone",
+				Providers: []modelpresetSpec.ProviderPreset{
+					gone, provider("b", true, "", nil), provider("a", false, "", nil),
+				},
+				AuthKeys: []settingSpec.AuthKeyMeta{providerKey("b")},
+			},
+			want: []want{{
+				spec.ConsistencyIssueDefaultProviderMissing, "gone", spec.ConsistencySeverityError,
+				spec.ConsistencyFixSetDefaultProvider,
+			}},
+		},
+		{
+			name: "no-default-provider",
+			snap: Snapshot{
+				Providers: []modelpresetSpec.ProviderPreset{provider("a", true, "", nil)},
+				AuthKeys:  []settingSpec.AuthKeyMeta{providerKey("a")},
+			},
+			wantOK: true,
+			want: []want{{
+				spec.ConsistencyIssueNoDefaultProvider, "", spec.ConsistencySeverityWarning,
+				spec.ConsistencyFixSetDefaultProvider,
+			}},
+		},
+		{
+			name: "default-models-and-auth-keys",
+			snap: Snapshot{
+				DefaultProvider: "a",
+				Providers: []modelpresetSpec.ProviderPreset{
+					provider("a", true, "m-missing", map[string]bool{"m1": true}),
+					provider("b", true, "m2", map[string]bool{"m2": false}),
+					provider("c", false, "m-missing", nil),
+				},
+				AuthKeys: []settingSpec.AuthKeyMeta{
+					providerKey("a"),
+					{Type: settingSpec.AuthKeyTypeProvider, KeyName: "b"},
+				},
+			},
+			want: []want{
+				{
+					spec.ConsistencyIssueDefaultModelMissing, "a", spec.ConsistencySeverityError,
+					spec.ConsistencyFixSetDefaultModelPreset,
+				},
+				{
+					spec.ConsistencyIssueDefaultModelDisabled, "b", spec.ConsistencySeverityWarning,
+					spec.ConsistencyFixEnableModelPreset,
+				},
+				{
+					spec.ConsistencyIssueProviderAuthKeyMissing, "b", spec.ConsistencySeverityWarning,
+					spec.ConsistencyFixSetAuthKey,
+				},
+			},
+		},
+		{
+			name: "local-provider-without-key",
+			snap: Snapshot{
+				DefaultProvider: "ollama",
+				Providers:       []modelpresetSpec.ProviderPreset{ollama},
+			},
+			wantOK: true,
+		},
+		{
+			name: "skill-locations",
+			snap: Snapshot{
+				Skills: []skillstoreSpec.SkillListItem{
+					skill("s2", missing, false),
+					skill("s1", missing, true),
+					skill("s3", present, true),
+				},
+				StoreErrors: map[string]error{StoreModelPreset: errors.New("boom")},
+			},
+			want: []want{
+				{spec.ConsistencyIssueStoreUnavailable, "", spec.ConsistencySeverityError, ""},
+				{
+					spec.ConsistencyIssueSkillLocationMissing, "b1/s1", spec.ConsistencySeverityError,
+					spec.ConsistencyFixDisableSkill,
+				},
+				{
+					spec.ConsistencyIssueSkillLocationMissing, "b1/s2", spec.ConsistencySeverityWarning,
+					spec.ConsistencyFixDeleteSkill,
+				},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			report := Check(tc.snap)
+			if report.OK != tc.wantOK {
+				t.Errorf("OK = %v, want %v", report.OK, tc.wantOK)
+			}
+			if len(report.Issues) != len(tc.want) {
+				t.Fatalf("issues = %+v, want %d", report.Issues, len(tc.want))
+			}
+			for i, w := range tc.want {
+				got := report.Issues[i]
+				if got.Code != w.code || got.Subject != w.subject || got.Severity != w.severity {
+					t.Errorf("issue %d = %+v, want %+v", i, got, w)
+				}
+				if w.fix == "" {
+					continue
+				}
+				if len(got.Suggestions) == 0 || got.Suggestions[0].Action != w.fix {
+					t.Errorf("issue %d suggestions = %+v, want %s first", i, got.Suggestions, w.fix)
+				}
+			}
+		})
+	}
+}
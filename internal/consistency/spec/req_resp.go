@@ -0,0 +1,78 @@
+package spec
+
+import "time"
+
+type ConsistencySeverity string
+
+const (
+	// ConsistencySeverityError breaks a feature, e.g. chats without a usable
+	// default provider.
+	ConsistencySeverityError   ConsistencySeverity = "error"
+	ConsistencySeverityWarning ConsistencySeverity = "warning"
+)
+
+type ConsistencyIssueCode string
+
+const (
+	ConsistencyIssueNoDefaultProvider       ConsistencyIssueCode = "noDefaultProvider"
+	ConsistencyIssueDefaultProviderMissing  ConsistencyIssueCode = "defaultProviderMissing"
+	ConsistencyIssueDefaultProviderDisabled ConsistencyIssueCode = "defaultProviderDisabled"
+	ConsistencyIssueDefaultModelMissing     ConsistencyIssueCode = "defaultModelPresetMissing"
+	ConsistencyIssueDefaultModelDisabled    ConsistencyIssueCode = "defaultModelPresetDisabled"
+	ConsistencyIssueSkillLocationMissing    ConsistencyIssueCode = "skillLocationMissing"
+	ConsistencyIssueProviderAuthKeyMissing  ConsistencyIssueCode = "providerAuthKeyMissing"
+	ConsistencyIssueStoreUnavailable        ConsistencyIssueCode = "storeUnavailable"
+)
+
+// ConsistencyFixAction names the store call that resolves an issue. The
+// suggestion params carry its arguments.
+type ConsistencyFixAction string
+
+const (
+	// Params: providerName.
+	ConsistencyFixSetDefaultProvider ConsistencyFixAction = "setDefaultProvider"
+	// Params: providerName.
+	ConsistencyFixEnableProvider ConsistencyFixAction = "enableProvider"
+	// Params: providerName, modelPresetID.
+	ConsistencyFixSetDefaultModelPreset ConsistencyFixAction = "setDefaultModelPreset"
+	// Params: providerName, modelPresetID.
+	ConsistencyFixEnableModelPreset ConsistencyFixAction = "enableModelPreset"
+	// Params: bundleID, skillSlug.
+	ConsistencyFixDisableSkill ConsistencyFixAction = "disableSkill"
+	// Params: bundleID, skillSlug.
+	ConsistencyFixDeleteSkill ConsistencyFixAction = "deleteSkill"
+	// Params: type, keyName.
+	ConsistencyFixSetAuthKey ConsistencyFixAction = "setAuthKey"
+)
+
+type ConsistencyFixSuggestion struct {
+	Action      ConsistencyFixAction `json:"action"`
+	Description string               `json:"description"`
+	Params      map[string]string    `json:"params,omitempty"`
+}
+
+// ConsistencyIssue is one broken reference. Subject identifies the referring
+// item within Store, e.g. a provider name or "bundleID/skillSlug".
+type ConsistencyIssue struct {
+	Code        ConsistencyIssueCode       `json:"code"`
+	Severity    ConsistencySeverity        `json:"severity"`
+	Store       string                     `json:"store"`
+	Subject     string                     `json:"subject"`
+	Message     string                     `json:"message"`
+	Suggestions []ConsistencyFixSuggestion `json:"suggestions,omitempty"`
+}
+
+type ConsistencyReport struct {
+	CheckedAt time.Time `json:"checkedAt"`
+	// OK is true when no issue has error severity.
+	OK     bool               `json:"ok"`
+	Issues []ConsistencyIssue `json:"issues"`
+}
+
+type ConsistencyCheckRequest struct{}
+
+// ConsistencyCheckResponse validates references between the setting, model
+// preset and skill stores. Fixes are suggested, never applied.
+type ConsistencyCheckResponse struct {
+	Body *ConsistencyReport
+}
@@ -67,10 +67,19 @@ type ListConversationsResponse struct {
 	Body *ListConversationsResponseBody
 }
 
+// SearchConversationsRequest searches conversation titles and message text.
+// Double quoted parts of Query must appear as phrases. The remaining fields
+// narrow the results; a conversation must match every filter that is set.
 type SearchConversationsRequest struct {
 	Query     string `query:"q"         required:"true"`
 	PageToken string `query:"pageToken"`
 	PageSize  int    `query:"pageSize"`
+
+	ModifiedAfter  *time.Time `query:"modifiedAfter"`
+	ModifiedBefore *time.Time `query:"modifiedBefore"`
+	// ProviderName and ModelName match any turn of the conversation.
+	ProviderName string `query:"providerName"`
+	ModelName    string `query:"modelName"`
 }
 
 type SearchConversationsResponseBody struct {
@@ -81,3 +90,29 @@ type SearchConversationsResponseBody struct {
 type SearchConversationsResponse struct {
 	Body *SearchConversationsResponseBody
 }
+
+// AttachmentBlobStats sizes the shared attachment blob store. Unreferenced
+// blobs are not used by any stored conversation and are removed by GC.
+type AttachmentBlobStats struct {
+	BlobCount         int   `json:"blobCount"`
+	TotalBytes        int64 `json:"totalBytes"`
+	UnreferencedCount int   `json:"unreferencedCount"`
+	UnreferencedBytes int64 `json:"unreferencedBytes"`
+}
+
+type GetAttachmentBlobStatsRequest struct{}
+
+type GetAttachmentBlobStatsResponse struct {
+	Body *AttachmentBlobStats
+}
+
+type GCAttachmentBlobsRequest struct{}
+
+type GCAttachmentBlobsResponseBody struct {
+	RemovedCount int   `json:"removedCount"`
+	FreedBytes   int64 `json:"freedBytes"`
+}
+
+type GCAttachmentBlobsResponse struct {
+	Body *GCAttachmentBlobsResponseBody
+}
@@ -0,0 +1,273 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/flexigpt/flexigpt-app/internal/conversation/spec"
+	"github.com/flexigpt/mapstore-go"
+	"github.com/flexigpt/mapstore-go/jsonencdec"
+)
+
+const attachmentBlobRefPrefix = "sha256:"
+
+// WithAttachmentBlobDir keeps attachment payloads in a content-addressed
+// store under dir. Conversations then hold only the blob digest, so the same
+// file attached in many turns or conversations is stored once.
+func WithAttachmentBlobDir(dir string) Option {
+	return func(cc *ConversationCollection) error {
+		if strings.TrimSpace(dir) == "" {
+			return errors.New("attachment blob dir cannot be empty")
+		}
+		if err := os.MkdirAll(dir, 0o770); err != nil {
+			return err
+		}
+		cc.blobDir = filepath.Clean(dir)
+		return nil
+	}
+}
+
+// GetAttachmentBlobStats reports the size of the attachment blob store and
+// how much of it GC would reclaim.
+func (cc *ConversationCollection) GetAttachmentBlobStats(
+	ctx context.Context,
+	_ *spec.GetAttachmentBlobStatsRequest,
+) (*spec.GetAttachmentBlobStatsResponse, error) {
+	if cc.blobDir == "" {
+		return nil, errors.New("attachment blob store is disabled")
+	}
+	cc.blobMu.Lock()
+	defer cc.blobMu.Unlock()
+
+	stats, err := cc.scanAttachmentBlobs(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	return &spec.GetAttachmentBlobStatsResponse{Body: stats}, nil
+}
+
+// GCAttachmentBlobs removes blobs no stored conversation references.
+func (cc *ConversationCollection) GCAttachmentBlobs(
+	ctx context.Context,
+	_ *spec.GCAttachmentBlobsRequest,
+) (*spec.GCAttachmentBlobsResponse, error) {
+	if cc.blobDir == "" {
+		return nil, errors.New("attachment blob store is disabled")
+	}
+	cc.blobMu.Lock()
+	defer cc.blobMu.Unlock()
+
+	stats, err := cc.scanAttachmentBlobs(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("attachment blobs gc", "removed", stats.UnreferencedCount, "freedBytes", stats.UnreferencedBytes)
+	return &spec.GCAttachmentBlobsResponse{
+		Body: &spec.GCAttachmentBlobsResponseBody{
+			RemovedCount: stats.UnreferencedCount,
+			FreedBytes:   stats.UnreferencedBytes,
+		},
+	}, nil
+}
+
+// externalizeAttachmentBlobs returns a copy of messages with inline base64
+// attachment payloads moved to the blob store. Payloads that are not valid
+// base64 are left inline.
+func (cc *ConversationCollection) externalizeAttachmentBlobs(
+	messages []spec.ConversationMessage,
+) ([]spec.ConversationMessage, error) {
+	if cc.blobDir == "" {
+		return messages, nil
+	}
+	out := slices.Clone(messages)
+	for i := range out {
+		if len(out[i].Attachments) == 0 {
+			continue
+		}
+		out[i].Attachments = slices.Clone(out[i].Attachments)
+		for j := range out[i].Attachments {
+			cb := out[i].Attachments[j].ContentBlock
+			if cb == nil || cb.Base64Data == nil || *cb.Base64Data == "" {
+				continue
+			}
+			raw, err := base64.StdEncoding.DecodeString(*cb.Base64Data)
+			if err != nil {
+				continue
+			}
+			ref, err := cc.writeAttachmentBlob(raw)
+			if err != nil {
+				return nil, err
+			}
+			next := *cb
+			next.Base64Data = nil
+			next.BlobRef = &ref
+			out[i].Attachments[j].ContentBlock = &next
+		}
+	}
+	return out, nil
+}
+
+// hydrateAttachmentBlobs fills in the payloads of blob backed content blocks.
+// Missing blobs are logged and left empty.
+func (cc *ConversationCollection) hydrateAttachmentBlobs(convo *spec.Conversation) {
+	if cc.blobDir == "" {
+		return
+	}
+	for i := range convo.Messages {
+		for j := range convo.Messages[i].Attachments {
+			cb := convo.Messages[i].Attachments[j].ContentBlock
+			if cb == nil || cb.BlobRef == nil || cb.Base64Data != nil {
+				continue
+			}
+			p, ok := cc.attachmentBlobPath(*cb.BlobRef)
+			if !ok {
+				logger.Warn("invalid attachment blob ref", "conversation", convo.ID, "ref", *cb.BlobRef)
+				continue
+			}
+			raw, err := os.ReadFile(p)
+			if err != nil {
+				logger.Warn("read attachment blob", "conversation", convo.ID, "ref", *cb.BlobRef, "error", err)
+				continue
+			}
+			data := base64.StdEncoding.EncodeToString(raw)
+			cb.Base64Data = &data
+		}
+	}
+}
+
+func (cc *ConversationCollection) writeAttachmentBlob(raw []byte) (string, error) {
+	sum := sha256.Sum256(raw)
+	ref := attachmentBlobRefPrefix + hex.EncodeToString(sum[:])
+	p, _ := cc.attachmentBlobPath(ref)
+	if _, err := os.Stat(p); err == nil {
+		return ref, nil
+	}
+
+	dir := filepath.Dir(p)
+	if err := os.MkdirAll(dir, 0o770); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return "", err
+	}
+	return ref, nil
+}
+
+// attachmentBlobPath maps "sha256:<hex>" to <blobDir>/<hex[:2]>/<hex>.
+func (cc *ConversationCollection) attachmentBlobPath(ref string) (string, bool) {
+	digest, ok := strings.CutPrefix(ref, attachmentBlobRefPrefix)
+	if !ok || !isBlobDigest(digest) {
+		return "", false
+	}
+	return filepath.Join(cc.blobDir, digest[:2], digest), true
+}
+
+func isBlobDigest(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil && strings.ToLower(s) == s
+}
+
+// scanAttachmentBlobs sizes the blob store against the blobs referenced by
+// stored conversations, deleting the unreferenced ones when remove is set.
+func (cc *ConversationCollection) scanAttachmentBlobs(
+	ctx context.Context,
+	remove bool,
+) (*spec.AttachmentBlobStats, error) {
+	referenced, err := cc.referencedAttachmentBlobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &spec.AttachmentBlobStats{}
+	err = filepath.WalkDir(cc.blobDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || !isBlobDigest(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		stats.BlobCount++
+		stats.TotalBytes += info.Size()
+		if _, ok := referenced[attachmentBlobRefPrefix+d.Name()]; ok {
+			return nil
+		}
+		if remove {
+			if err := os.Remove(p); err != nil {
+				return err
+			}
+		}
+		stats.UnreferencedCount++
+		stats.UnreferencedBytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (cc *ConversationCollection) referencedAttachmentBlobs(ctx context.Context) (map[string]struct{}, error) {
+	referenced := map[string]struct{}{}
+	token := ""
+	for {
+		fileEntries, next, err := cc.store.ListFiles(mapstore.ListingConfig{PageSize: spec.MaxPageSize}, token)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fileEntries {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			raw, err := cc.store.GetFileData(mapstore.FileKey{FileName: filepath.Base(f.BaseRelativePath)}, false)
+			if err != nil {
+				// A conversation that cannot be read may still reference blobs.
+				return nil, err
+			}
+			var convo spec.Conversation
+			if err := jsonencdec.MapToStructWithJSONTags(raw, &convo); err != nil {
+				return nil, err
+			}
+			for _, m := range convo.Messages {
+				for _, att := range m.Attachments {
+					if att.ContentBlock != nil && att.ContentBlock.BlobRef != nil {
+						referenced[*att.ContentBlock.BlobRef] = struct{}{}
+					}
+				}
+			}
+		}
+		if next == "" {
+			return referenced, nil
+		}
+		token = next
+	}
+}
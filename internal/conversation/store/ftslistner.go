@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"log/slog"
 	"os"
 	"runtime/debug"
 	"strings"
@@ -25,7 +24,7 @@ func NewFTSListner(e *ftsengine.Engine) mapstore.FileListener {
 	return func(ev mapstore.FileEvent) {
 		defer func() {
 			if r := recover(); r != nil {
-				slog.Error("fts listener panic",
+				logger.Error("fts listener panic",
 					"op", ev.Op, "file", ev.File, "recover", r,
 					"stack", string(debug.Stack()))
 			}
@@ -40,15 +39,15 @@ func NewFTSListner(e *ftsengine.Engine) mapstore.FileListener {
 		case mapstore.OpSetFile, mapstore.OpResetFile:
 			vals := extractFTS(ev.File, ev.Data)
 			if len(vals) == 0 {
-				slog.Warn("fts listener: nothing to index", "file", ev.File)
+				logger.Warn("fts listener: nothing to index", "file", ev.File)
 				return
 			}
 			if err := e.Upsert(ctx, ev.File, vals); err != nil {
-				slog.Error("fts upsert failed", "file", ev.File, "err", err)
+				logger.Error("fts upsert failed", "file", ev.File, "err", err)
 			}
 		case mapstore.OpDeleteFile:
 			if err := e.Delete(ctx, ev.File); err != nil {
-				slog.Error("fts delete failed", "file", ev.File, "err", err)
+				logger.Error("fts delete failed", "file", ev.File, "err", err)
 			}
 		case mapstore.OpSetKey, mapstore.OpDeleteKey:
 			// Do nothing as we dont do key operations.
@@ -87,13 +86,13 @@ func processFTSDataForFile(
 
 	raw, err := os.ReadFile(fullPath)
 	if err != nil {
-		slog.Error("conversation sync fts", "file", fullPath, "read error", err)
+		logger.Error("conversation sync fts", "file", fullPath, "read error", err)
 		return skipSyncDecision, nil
 	}
 
 	var m map[string]any
 	if err := json.Unmarshal(raw, &m); err != nil {
-		slog.Error("conversation sync fts", "file", fullPath, "json error", err)
+		logger.Error("conversation sync fts", "file", fullPath, "json error", err)
 		return skipSyncDecision, nil
 	}
 
@@ -103,7 +102,7 @@ func processFTSDataForFile(
 
 	pt := spec.Conversation{}
 	if err := json.Unmarshal(raw, &pt); err != nil {
-		slog.Error("conversation sync fts", "file", fullPath, "non conversation file error", err)
+		logger.Error("conversation sync fts", "file", fullPath, "non conversation file error", err)
 		return skipSyncDecision, nil
 	}
 
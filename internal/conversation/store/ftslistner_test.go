@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/flexigpt/flexigpt-app/internal/conversation/spec"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
 	inferenceSpec "github.com/flexigpt/inference-go/spec"
 )
 
@@ -266,6 +267,88 @@ func TestFTSScaleSearch(t *testing.T) {
 	}
 }
 
+func TestFTSSearchPhraseAndFilters(t *testing.T) {
+	dir := t.TempDir()
+	cc := newCollection(t, dir, true)
+	now := time.Now().UTC()
+
+	put := func(title, provider, model, answer string, modifiedAt time.Time) string {
+		t.Helper()
+		c := newConv(t, title)
+		c.ModifiedAt = modifiedAt
+		answerTurn := newTextTurn("m2", inferenceSpec.RoleAssistant, answer)
+		answerTurn.ModelParam = &inferenceSpec.ModelParam{Name: inferenceSpec.ModelName(model)}
+		answerTurn.ModelPresetRef = &modelpresetSpec.ModelPresetRef{
+			ProviderName:  inferenceSpec.ProviderName(provider),
+			ModelPresetID: modelpresetSpec.ModelPresetID(model),
+		}
+		c.Messages = []spec.ConversationMessage{
+			newTextTurn("m1", inferenceSpec.RoleUser, "why does this not compile"),
+			answerTurn,
+		}
+		if _, err := cc.PutConversation(t.Context(), getNewPutRequestFromConversation(c)); err != nil {
+			t.Fatalf("put %s: %v", title, err)
+		}
+		return c.ID
+	}
+	old := put("Rust notes", "openai", "gpt-5", "The borrow   checker rejects it.", now.Add(-48*time.Hour))
+	tokensOnly := put("Go notes", "anthropic", "claude-sonnet", "A checker for borrow rules.", now)
+	recent := put("More rust", "anthropic", "claude-sonnet", "Again the Borrow Checker.", now)
+
+	after := now.Add(-24 * time.Hour)
+	tests := []struct {
+		name string
+		req  spec.SearchConversationsRequest
+		want []string
+	}{
+		{
+			name: "phrase",
+			req:  spec.SearchConversationsRequest{Query: `"borrow checker"`},
+			want: []string{old, recent},
+		},
+		{
+			name: "phrase and model",
+			req:  spec.SearchConversationsRequest{Query: `"borrow checker"`, ModelName: "Claude-Sonnet"},
+			want: []string{recent},
+		},
+		{
+			name: "provider",
+			req:  spec.SearchConversationsRequest{Query: "borrow", ProviderName: "openai"},
+			want: []string{old},
+		},
+		{
+			name: "modified after",
+			req:  spec.SearchConversationsRequest{Query: "borrow", ModifiedAfter: &after},
+			want: []string{tokensOnly, recent},
+		},
+		{
+			name: "modified before",
+			req:  spec.SearchConversationsRequest{Query: "borrow", ModifiedBefore: &after},
+			want: []string{old},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := cc.SearchConversations(t.Context(), &tc.req)
+			if err != nil {
+				t.Fatalf("search: %v", err)
+			}
+			got := map[string]bool{}
+			for _, it := range res.Body.ConversationListItems {
+				got[it.ID] = true
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want %d hits, got %d: %v", len(tc.want), len(got), got)
+			}
+			for _, id := range tc.want {
+				if !got[id] {
+					t.Fatalf("missing hit %s in %v", id, got)
+				}
+			}
+		})
+	}
+}
+
 func newCollection(t *testing.T, dir string, withFTS bool) *ConversationCollection {
 	t.Helper()
 	cc, err := NewConversationCollection(
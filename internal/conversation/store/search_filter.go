@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/conversation/spec"
+	"github.com/flexigpt/mapstore-go"
+	"github.com/flexigpt/mapstore-go/jsonencdec"
+	"github.com/flexigpt/mapstore-go/uuidv7filename"
+)
+
+// maxFilteredSearchPages bounds how many index pages a single filtered
+// search scans before returning what it has with a continuation token.
+const maxFilteredSearchPages = 20
+
+// conversationSearchFilter holds the checks the FTS index cannot do: it only
+// matches tokens, so phrases, dates and models are verified on the stored
+// conversation.
+type conversationSearchFilter struct {
+	phrases        []string
+	modifiedAfter  *time.Time
+	modifiedBefore *time.Time
+	providerName   string
+	modelName      string
+}
+
+// newConversationSearchFilter returns nil when the request needs no checks
+// beyond the index.
+func newConversationSearchFilter(req *spec.SearchConversationsRequest) *conversationSearchFilter {
+	f := &conversationSearchFilter{
+		phrases:        searchPhrases(req.Query),
+		modifiedAfter:  req.ModifiedAfter,
+		modifiedBefore: req.ModifiedBefore,
+		providerName:   strings.TrimSpace(req.ProviderName),
+		modelName:      strings.TrimSpace(req.ModelName),
+	}
+	if len(f.phrases) == 0 && f.modifiedAfter == nil && f.modifiedBefore == nil &&
+		f.providerName == "" && f.modelName == "" {
+		return nil
+	}
+	return f
+}
+
+// searchPhrases returns the double quoted parts of q, lower-cased and with
+// whitespace collapsed.
+func searchPhrases(q string) []string {
+	var out []string
+	parts := strings.Split(q, `"`)
+	// Odd indexes are inside quotes; an unterminated quote is not a phrase.
+	for i := 1; i < len(parts)-1; i += 2 {
+		if p := normalizeSearchText(parts[i]); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func normalizeSearchText(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+func (f *conversationSearchFilter) match(convo *spec.Conversation, raw map[string]any) bool {
+	if f.modifiedAfter != nil && convo.ModifiedAt.Before(*f.modifiedAfter) {
+		return false
+	}
+	if f.modifiedBefore != nil && convo.ModifiedAt.After(*f.modifiedBefore) {
+		return false
+	}
+
+	if f.providerName != "" || f.modelName != "" {
+		providerOK, modelOK := f.providerName == "", f.modelName == ""
+		for _, m := range convo.Messages {
+			if m.ModelPresetRef != nil && strings.EqualFold(string(m.ModelPresetRef.ProviderName), f.providerName) {
+				providerOK = true
+			}
+			if m.ModelParam != nil && strings.EqualFold(string(m.ModelParam.Name), f.modelName) {
+				modelOK = true
+			}
+		}
+		if !providerOK || !modelOK {
+			return false
+		}
+	}
+
+	if len(f.phrases) > 0 {
+		vals := extractFTS("", raw)
+		text := normalizeSearchText(
+			vals["title"] + "\n" + vals["system"] + "\n" + vals["user"] + "\n" + vals["assistant"],
+		)
+		for _, p := range f.phrases {
+			if !strings.Contains(text, p) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// searchConversationsFiltered pages through index hits and keeps the ones the
+// filter accepts. It stops at index page boundaries, so the returned token
+// resumes after the last page scanned and a page may hold a few more than
+// pageSize items.
+func (cc *ConversationCollection) searchConversationsFiltered(
+	ctx context.Context,
+	req *spec.SearchConversationsRequest,
+	pageSize int,
+	f *conversationSearchFilter,
+) (*spec.SearchConversationsResponse, error) {
+	items := make([]spec.ConversationListItem, 0, pageSize)
+	token := req.PageToken
+	for range maxFilteredSearchPages {
+		hits, next, err := cc.fts.Search(ctx, req.Query, token, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range hits {
+			filename := filepath.Base(h.ID)
+			info, err := uuidv7filename.Parse(filename)
+			if err != nil {
+				continue
+			}
+			raw, err := cc.store.GetFileData(mapstore.FileKey{FileName: filename}, false)
+			if err != nil {
+				continue
+			}
+			var convo spec.Conversation
+			if err := jsonencdec.MapToStructWithJSONTags(raw, &convo); err != nil {
+				continue
+			}
+			if !f.match(&convo, raw) {
+				continue
+			}
+			modifiedAt := convo.ModifiedAt
+			items = append(items, spec.ConversationListItem{
+				ID:             info.ID,
+				SanatizedTitle: info.Suffix,
+				ModifiedAt:     &modifiedAt,
+			})
+		}
+		token = next
+		if token == "" || len(items) >= pageSize {
+			break
+		}
+	}
+	return &spec.SearchConversationsResponse{
+		Body: &spec.SearchConversationsResponseBody{
+			ConversationListItems: items,
+			NextPageToken:         &token,
+		},
+	}, nil
+}
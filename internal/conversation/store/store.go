@@ -3,12 +3,12 @@ package store
 import (
 	"context"
 	"errors"
-	"log/slog"
 	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/flexigpt/flexigpt-app/internal/conversation/spec"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 	"github.com/flexigpt/mapstore-go"
 	"github.com/flexigpt/mapstore-go/dirpartition"
 	"github.com/flexigpt/mapstore-go/ftsengine"
@@ -16,6 +16,8 @@ import (
 	"github.com/flexigpt/mapstore-go/uuidv7filename"
 )
 
+var logger = logging.Module("conversation.store")
+
 type ConversationCollection struct {
 	baseDir   string
 	enableFTS bool
@@ -26,6 +28,11 @@ type ConversationCollection struct {
 	ftsRebuildCtx    context.Context
 	ftsRebuildCancel context.CancelFunc
 	ftsRebuildWG     sync.WaitGroup
+
+	// blobDir is the content-addressed attachment blob store, if enabled.
+	// Writers hold blobMu for reading so GC never sees a half written put.
+	blobDir string
+	blobMu  sync.RWMutex
 }
 
 type Option func(*ConversationCollection) error
@@ -85,7 +92,7 @@ func NewConversationCollection(baseDir string, opts ...Option) (*ConversationCol
 				{Name: "assistant", Weight: 4},
 				{Name: "mtime", Unindexed: true},
 			},
-		}, ftsengine.WithLogger(slog.Default()))
+		}, ftsengine.WithLogger(logger))
 		if err != nil {
 			return nil, err
 		}
@@ -101,12 +108,12 @@ func NewConversationCollection(baseDir string, opts ...Option) (*ConversationCol
 				processFTSDataForFile,
 			)
 			if stat != nil {
-				slog.Info("conversation fts rebuild", "stat", stat)
+				logger.Info("conversation fts rebuild", "stat", stat)
 			}
 		})
 	}
 
-	optsDir := []mapstore.DirOption{mapstore.WithDirLogger(slog.Default())}
+	optsDir := []mapstore.DirOption{mapstore.WithDirLogger(logger)}
 	if cc.fts != nil {
 		optsDir = append(optsDir, mapstore.WithDirFileListeners(NewFTSListner(cc.fts)))
 	}
@@ -161,6 +168,13 @@ func (cc *ConversationCollection) PutConversation(
 		return nil, err
 	}
 
+	cc.blobMu.RLock()
+	defer cc.blobMu.RUnlock()
+	messages, err := cc.externalizeAttachmentBlobs(req.Body.Messages)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if there are files with same id as prefix
 	// We don't iterate as we expect only 1 file max with the id prefix of uuid.
 	fileEntries, _, err := cc.store.ListFiles(
@@ -182,7 +196,7 @@ func (cc *ConversationCollection) PutConversation(
 			mapstore.FileKey{FileName: filepath.Base(fileEntries[idx].BaseRelativePath)},
 		)
 		if err != nil {
-			slog.Warn("put conversation remove existing file", "error", err)
+			logger.Warn("put conversation remove existing file", "error", err)
 		}
 	}
 
@@ -192,7 +206,7 @@ func (cc *ConversationCollection) PutConversation(
 	currentConversation.Title = req.Body.Title
 	currentConversation.CreatedAt = req.Body.CreatedAt
 	currentConversation.ModifiedAt = req.Body.ModifiedAt
-	currentConversation.Messages = req.Body.Messages
+	currentConversation.Messages = messages
 	if req.Body.Meta != nil {
 		currentConversation.Meta = req.Body.Meta
 	}
@@ -223,7 +237,13 @@ func (cc *ConversationCollection) PutMessagesToConversation(
 
 	currentConversation := convoResp.Body
 	currentConversation.ModifiedAt = time.Now().UTC()
-	currentConversation.Messages = req.Body.Messages
+
+	cc.blobMu.RLock()
+	defer cc.blobMu.RUnlock()
+	currentConversation.Messages, err = cc.externalizeAttachmentBlobs(req.Body.Messages)
+	if err != nil {
+		return nil, err
+	}
 
 	filename, err := cc.fileNameFromConversation(*currentConversation)
 	if err != nil {
@@ -257,7 +277,7 @@ func (cc *ConversationCollection) DeleteConversation(
 	if err := cc.store.DeleteFile(mapstore.FileKey{FileName: filename}); err != nil {
 		return nil, err
 	}
-	slog.Info("delete conversation", "file", filename)
+	logger.Info("delete conversation", "file", filename)
 	return &spec.DeleteConversationResponse{}, nil
 }
 
@@ -286,6 +306,7 @@ func (cc *ConversationCollection) GetConversation(
 	if err := jsonencdec.MapToStructWithJSONTags(raw, &convo); err != nil {
 		return nil, err
 	}
+	cc.hydrateAttachmentBlobs(&convo)
 
 	return &spec.GetConversationResponse{Body: &convo}, nil
 }
@@ -365,6 +386,9 @@ func (cc *ConversationCollection) SearchConversations(
 	if req.PageSize > 0 && req.PageSize <= spec.MaxPageSize {
 		pageSize = req.PageSize
 	}
+	if f := newConversationSearchFilter(req); f != nil {
+		return cc.searchConversationsFiltered(ctx, req, pageSize, f)
+	}
 
 	hits, next, err := cc.fts.Search(ctx, req.Query, req.PageToken, pageSize)
 	if err != nil {
@@ -1,11 +1,13 @@
 package store
 
 import (
+	"encoding/base64"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/flexigpt/flexigpt-app/internal/attachment"
 	"github.com/flexigpt/flexigpt-app/internal/conversation/spec"
 	"github.com/flexigpt/mapstore-go/uuidv7filename"
 	"github.com/google/uuid"
@@ -549,6 +551,86 @@ func TestConversationCollectionListing(t *testing.T) {
 	})
 }
 
+func TestConversationCollectionAttachmentBlobs(t *testing.T) {
+	ctx := t.Context()
+	blobDir := filepath.Join(t.TempDir(), "blobs")
+	cc, err := NewConversationCollection(t.TempDir(), WithAttachmentBlobDir(blobDir))
+	if err != nil {
+		t.Fatalf("Failed to create conversation collection: %v", err)
+	}
+	t.Cleanup(func() { _ = cc.Close() })
+
+	payload := base64.StdEncoding.EncodeToString([]byte("\x89PNG fake image bytes"))
+	imageTurn := func(id string) spec.ConversationMessage {
+		m := newTextTurn(id, inferenceSpec.RoleUser, "see image")
+		data := payload
+		m.Attachments = []attachment.Attachment{{
+			Kind:  attachment.AttachmentImage,
+			Label: "shot.png",
+			ContentBlock: &attachment.ContentBlock{
+				Kind:       attachment.ContentBlockImage,
+				Base64Data: &data,
+			},
+		}}
+		return m
+	}
+
+	var convos []*spec.Conversation
+	for _, title := range []string{"First", "Second"} {
+		c, err := initConversation(title)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Messages = []spec.ConversationMessage{imageTurn("m1"), imageTurn("m2")}
+		if _, err := cc.PutConversation(ctx, getNewPutRequestFromConversation(c)); err != nil {
+			t.Fatalf("PutConversation: %v", err)
+		}
+		if c.Messages[0].Attachments[0].ContentBlock.BlobRef != nil {
+			t.Fatal("PutConversation must not modify the caller's messages")
+		}
+		convos = append(convos, c)
+	}
+
+	stats, err := cc.GetAttachmentBlobStats(ctx, &spec.GetAttachmentBlobStatsRequest{})
+	if err != nil {
+		t.Fatalf("GetAttachmentBlobStats: %v", err)
+	}
+	if stats.Body.BlobCount != 1 || stats.Body.UnreferencedCount != 0 {
+		t.Fatalf("expected one shared referenced blob, got %+v", stats.Body)
+	}
+
+	got, err := cc.GetConversation(ctx, &spec.GetConversationRequest{
+		ID: convos[0].ID, Title: convos[0].Title, ForceFetch: true,
+	})
+	if err != nil {
+		t.Fatalf("GetConversation: %v", err)
+	}
+	cb := got.Body.Messages[1].Attachments[0].ContentBlock
+	if cb.BlobRef == nil || cb.Base64Data == nil || *cb.Base64Data != payload {
+		t.Fatalf("expected hydrated blob payload, got %+v", cb)
+	}
+
+	for _, c := range convos {
+		if _, err := cc.DeleteConversation(ctx, &spec.DeleteConversationRequest{ID: c.ID, Title: c.Title}); err != nil {
+			t.Fatalf("DeleteConversation: %v", err)
+		}
+	}
+	gc, err := cc.GCAttachmentBlobs(ctx, &spec.GCAttachmentBlobsRequest{})
+	if err != nil {
+		t.Fatalf("GCAttachmentBlobs: %v", err)
+	}
+	if gc.Body.RemovedCount != 1 || gc.Body.FreedBytes == 0 {
+		t.Fatalf("expected the orphaned blob to be removed, got %+v", gc.Body)
+	}
+	stats, err = cc.GetAttachmentBlobStats(ctx, &spec.GetAttachmentBlobStatsRequest{})
+	if err != nil {
+		t.Fatalf("GetAttachmentBlobStats: %v", err)
+	}
+	if stats.Body.BlobCount != 0 {
+		t.Fatalf("expected empty blob store after gc, got %+v", stats.Body)
+	}
+}
+
 func getNewPutRequestFromConversation(c *spec.Conversation) *spec.PutConversationRequest {
 	return &spec.PutConversationRequest{
 		ID: c.ID,
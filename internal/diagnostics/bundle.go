@@ -0,0 +1,152 @@
+// Package diagnostics builds the zip users attach to bug reports. Every entry
+// is passed through a Redactor before it is written.
+package diagnostics
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/overlay"
+)
+
+// ManifestFileName lists the entries of a bundle and the ones that failed.
+const ManifestFileName = "manifest.json"
+
+// overlayDBSuffix names the overlay databases of the built-in data stores.
+const overlayDBSuffix = ".overlay.sqlite"
+
+type Manifest struct {
+	GeneratedAt time.Time         `json:"generatedAt"`
+	Files       []string          `json:"files"`
+	Errors      map[string]string `json:"errors,omitempty"`
+}
+
+// Bundle writes redacted entries to a zip archive.
+type Bundle struct {
+	zw       *zip.Writer
+	r        *Redactor
+	manifest Manifest
+}
+
+func NewBundle(w io.Writer, r *Redactor) *Bundle {
+	return &Bundle{
+		zw:       zip.NewWriter(w),
+		r:        r,
+		manifest: Manifest{GeneratedAt: time.Now().UTC(), Errors: map[string]string{}},
+	}
+}
+
+// AddJSON writes v as indented JSON after redacting it.
+func (b *Bundle) AddJSON(name string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(b.r.Value(decoded), "", "  ")
+	if err != nil {
+		return err
+	}
+	return b.add(name, out)
+}
+
+// AddText writes text after redacting it.
+func (b *Bundle) AddText(name, text string) error {
+	return b.add(name, []byte(b.r.String(text)))
+}
+
+// AddError records in the manifest that the entry name could not be
+// collected.
+func (b *Bundle) AddError(name string, err error) {
+	b.manifest.Errors[name] = b.r.String(err.Error())
+}
+
+// Close writes the manifest and finishes the archive.
+func (b *Bundle) Close() error {
+	if err := b.AddJSON(ManifestFileName, b.manifest); err != nil {
+		_ = b.zw.Close()
+		return err
+	}
+	return b.zw.Close()
+}
+
+func (b *Bundle) add(name string, data []byte) error {
+	f, err := b.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: b.manifest.GeneratedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if name != ManifestFileName {
+		b.manifest.Files = append(b.manifest.Files, name)
+	}
+	return nil
+}
+
+type SystemInfo struct {
+	AppVersion string `json:"appVersion"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	GoVersion  string `json:"goVersion"`
+	NumCPU     int    `json:"numCPU"`
+	// OSRelease is the PRETTY_NAME of /etc/os-release where present.
+	OSRelease string `json:"osRelease,omitempty"`
+}
+
+func CollectSystemInfo(appVersion string) SystemInfo {
+	info := SystemInfo{
+		AppVersion: appVersion,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		GoVersion:  runtime.Version(),
+		NumCPU:     runtime.NumCPU(),
+	}
+	if data, err := os.ReadFile("/etc/os-release"); err == nil {
+		for line := range strings.Lines(string(data)) {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(line), "PRETTY_NAME="); ok {
+				info.OSRelease = strings.Trim(v, `"`)
+			}
+		}
+	}
+	return info
+}
+
+// CollectOverlayStats reads the stats of every overlay database under
+// dataDir. Paths are made relative to dataDir.
+func CollectOverlayStats(ctx context.Context, dataDir string) ([]overlay.DBStats, error) {
+	var out []overlay.DBStats
+	err := filepath.WalkDir(dataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), overlayDBSuffix) {
+			return nil
+		}
+		stats, err := overlay.ReadDBStats(ctx, path)
+		if err != nil {
+			return err
+		}
+		if rel, err := filepath.Rel(dataDir, path); err == nil {
+			stats.Path = filepath.ToSlash(rel)
+		}
+		out = append(out, stats)
+		return nil
+	})
+	return out, err
+}
@@ -0,0 +1,120 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func sha(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestRedactorString(t *testing.T) {
+	r := NewRedactor([]Secret{
+		{Label: "provider/openai", SHA256: sha("sk-live-1234567890")},
+		{Label: "short", SHA256: sha("abc")},
+		{Label: "slashed", SHA256: sha("abc/def+ghi:jkl")},
+	}, "/home/alice")
+
+	cases := map[string]string{
+		`Authorization: Bearer sk-live-1234567890`:      `Authorization: Bearer [REDACTED:provider/openai]`,
+		`{"key":"sk-live-1234567890","n":1}`:            `{"key":"[REDACTED:provider/openai]","n":1}`,
+		`apiKey=sk-live-1234567890x`:                    `apiKey=sk-live-1234567890x`,
+		`value abc stays`:                               `value abc stays`,
+		`proxy http://bob:pw@proxy.local:8080 used`:     `proxy http://[REDACTED]@proxy.local:8080 used`,
+		`open /home/alice/.config/app failed`:           `open ~/.config/app failed`,
+		`GET /v1/models?key=sk-live-1234567890&alt=sse`: `GET /v1/models?key=[REDACTED:provider/openai]&alt=sse`,
+		`x-api-key:sk-live-1234567890`:                  `x-api-key:[REDACTED:provider/openai]`,
+		`path /keys/sk-live-1234567890/usage`:           `path /keys/[REDACTED:provider/openai]/usage`,
+		`b64 abc/def+ghi:jkl`:                           `b64 [REDACTED:slashed]`,
+	}
+	for in, want := range cases {
+		if got := r.String(in); got != want {
+			t.Errorf("String(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRedactorValue(t *testing.T) {
+	r := NewRedactor(nil, "")
+	var v any
+	if err := json.Unmarshal([]byte(`{
+		"authKeys": [{"keyName": "openai", "sha256": "abcd", "nonEmpty": true}],
+		"network": {"httpProxy": "http://u:p@h:1", "caBundlePath": ""},
+		"maxTokens": 10,
+		"accessToken": "",
+		"clientSecret": "s3cr3t"
+	}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(r.Value(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(out)
+	for _, want := range []string{
+		`"sha256":"[REDACTED]"`,
+		`"httpProxy":"http://[REDACTED]@h:1"`,
+		`"maxTokens":10`,
+		`"accessToken":""`,
+		`"clientSecret":"[REDACTED]"`,
+		`"keyName":"openai"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s in %s", want, got)
+		}
+	}
+}
+
+func TestBundle(t *testing.T) {
+	var buf bytes.Buffer
+	b := NewBundle(&buf, NewRedactor([]Secret{{Label: "k", SHA256: sha("secret-value-1")}}, ""))
+	if err := b.AddJSON("settings.json", map[string]any{"note": "uses secret-value-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddText("logs.txt", "token secret-value-1 sent"); err != nil {
+		t.Fatal(err)
+	}
+	b.AddError("overlays.json", errors.New("locked"))
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+	for name, data := range files {
+		if strings.Contains(data, "secret-value-1") {
+			t.Errorf("%s leaks the secret: %s", name, data)
+		}
+	}
+	var m Manifest
+	if err := json.Unmarshal([]byte(files[ManifestFileName]), &m); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(m.Files, ",") != "settings.json,logs.txt" || m.Errors["overlays.json"] != "locked" {
+		t.Fatalf("manifest: %+v", m)
+	}
+}
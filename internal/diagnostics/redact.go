@@ -0,0 +1,160 @@
+package diagnostics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+const (
+	redacted = "[REDACTED]"
+
+	// minSecretLength skips hashing tokens too short to be a stored secret.
+	minSecretLength = 8
+)
+
+// sensitiveKeySuffixes mark JSON keys whose string values are always
+// redacted, compared in lower case.
+var sensitiveKeySuffixes = []string{"secret", "password", "apikey", "api_key", "token", "authorization", "sha256"}
+
+// urlUserinfoRE matches the credentials of a URL such as a proxy setting.
+var urlUserinfoRE = regexp.MustCompile(`://[^/@\s"']+@`)
+
+// Secret identifies a stored secret by the hex SHA-256 the settings store
+// keeps for it. Label replaces the secret in redacted text.
+type Secret struct {
+	Label  string
+	SHA256 string
+}
+
+// Redactor removes secrets from text and decoded JSON. Stored secrets are
+// found by hashing every token and comparing it with their SHA-256, so the
+// plain secrets are never needed.
+type Redactor struct {
+	secrets map[string]string
+	homeDir string
+}
+
+// NewRedactor returns a redactor for secrets. homeDir, unless empty or "/",
+// is replaced by "~" so paths do not reveal the user name.
+func NewRedactor(secrets []Secret, homeDir string) *Redactor {
+	r := &Redactor{secrets: make(map[string]string, len(secrets)), homeDir: homeDir}
+	for _, s := range secrets {
+		if s.SHA256 != "" {
+			r.secrets[strings.ToLower(s.SHA256)] = s.Label
+		}
+	}
+	return r
+}
+
+// String returns s with stored secrets, URL credentials and the home
+// directory replaced.
+func (r *Redactor) String(s string) string {
+	if len(r.secrets) > 0 {
+		s = r.replaceSecrets(s)
+	}
+	s = urlUserinfoRE.ReplaceAllString(s, "://"+redacted+"@")
+	if len(r.homeDir) > 1 {
+		s = strings.ReplaceAll(s, r.homeDir, "~")
+	}
+	return s
+}
+
+// Value redacts decoded JSON in place and returns it. String values of
+// sensitive keys are dropped, all other strings go through String.
+func (r *Redactor) Value(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if s, ok := val.(string); ok && s != "" && isSensitiveKey(k) {
+				t[k] = redacted
+				continue
+			}
+			t[k] = r.Value(val)
+		}
+		return t
+	case []any:
+		for i, val := range t {
+			t[i] = r.Value(val)
+		}
+		return t
+	case string:
+		return r.String(t)
+	}
+	return v
+}
+
+func (r *Redactor) replaceSecrets(s string) string {
+	return replaceTokens(s, isTokenSeparator, func(tok string) string {
+		if label, ok := r.lookup(tok); ok {
+			return "[REDACTED:" + label + "]"
+		}
+		// Secrets may contain '/' or ':', so only split on them once the whole
+		// token failed to match, as in "?key=SECRET&alt=sse" or "x-api-key:SECRET".
+		return replaceTokens(tok, isInnerTokenSeparator, func(inner string) string {
+			if label, ok := r.lookup(inner); ok {
+				return "[REDACTED:" + label + "]"
+			}
+			return inner
+		})
+	})
+}
+
+// replaceTokens rewrites each run of s between separators with replace.
+func replaceTokens(s string, isSep func(rune) bool, replace func(string) string) string {
+	var b strings.Builder
+	start := -1
+	for i, c := range s {
+		if isSep(c) {
+			if start >= 0 {
+				b.WriteString(replace(s[start:i]))
+				start = -1
+			}
+			b.WriteRune(c)
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		b.WriteString(replace(s[start:]))
+	}
+	return b.String()
+}
+
+func (r *Redactor) lookup(tok string) (string, bool) {
+	if len(tok) < minSecretLength {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(tok))
+	label, ok := r.secrets[hex.EncodeToString(sum[:])]
+	return label, ok
+}
+
+func isTokenSeparator(c rune) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '"', '\'', '`', '=', ',', ';', '(', ')', '[', ']', '{', '}', '<', '>', '\\':
+		return true
+	}
+	return false
+}
+
+func isInnerTokenSeparator(c rune) bool {
+	switch c {
+	case ':', '/', '?', '&':
+		return true
+	}
+	return false
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, suffix := range sensitiveKeySuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package spec
+
+type GenerateDiagnosticsBundleRequest struct{}
+
+type GenerateDiagnosticsBundleResponseBody struct {
+	FileName string `json:"fileName"`
+	Archive  []byte `json:"archive"`
+}
+
+// GenerateDiagnosticsBundleResponse carries a zip of redacted settings,
+// store schema versions, recent logs, overlay database stats and OS info.
+type GenerateDiagnosticsBundleResponse struct {
+	Body *GenerateDiagnosticsBundleResponseBody
+}
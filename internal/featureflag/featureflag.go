@@ -0,0 +1,108 @@
+// Package featureflag declares the experimental behaviors stores can gate and
+// the environment overrides for them. Persisted values live in settings.
+package featureflag
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Name identifies a feature flag.
+type Name string
+
+const (
+	// IncrementalHydration rewrites only changed files when built-in skill
+	// packages are hydrated to disk, instead of replacing the whole directory.
+	IncrementalHydration Name = "incrementalHydration"
+	// JournaledUserWrites appends skill store mutations to a journal that is
+	// compacted into the store file later, instead of rewriting the file on
+	// every write.
+	JournaledUserWrites Name = "journaledUserWrites"
+)
+
+// EnvPrefix prefixes the environment variables that override flags, e.g.
+// FLEXIGPT_FEATURE_INCREMENTAL_HYDRATION=true.
+const EnvPrefix = "FLEXIGPT_FEATURE_"
+
+// Definition describes a known flag.
+type Definition struct {
+	Name        Name   `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+// Definitions lists every known flag.
+var Definitions = []Definition{
+	{
+		Name:        IncrementalHydration,
+		Description: "Rewrite only changed files when hydrating built-in skills.",
+		Default:     true,
+	},
+	{
+		Name:        JournaledUserWrites,
+		Description: "Journal skill store writes and compact them into the store file when idle.",
+		Default:     true,
+	},
+}
+
+// Gate reports whether a flag is enabled. Stores consult it for
+// experimental behavior.
+type Gate interface {
+	FeatureEnabled(name Name) bool
+}
+
+// GateFunc adapts a function to Gate.
+type GateFunc func(name Name) bool
+
+func (f GateFunc) FeatureEnabled(name Name) bool { return f(name) }
+
+// Lookup returns the definition of name.
+func Lookup(name Name) (Definition, bool) {
+	for _, d := range Definitions {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Definition{}, false
+}
+
+// Enabled reports whether name is enabled by gate, falling back to the
+// environment override and then the flag default when gate is nil.
+func Enabled(gate Gate, name Name) bool {
+	if gate != nil {
+		return gate.FeatureEnabled(name)
+	}
+	if v, ok := EnvOverride(name); ok {
+		return v
+	}
+	d, _ := Lookup(name)
+	return d.Default
+}
+
+// EnvVarName returns the environment variable overriding name.
+func EnvVarName(name Name) string {
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	for i, r := range string(name) {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// EnvOverride returns the value set for name in the environment, if any.
+func EnvOverride(name Name) (value, ok bool) {
+	raw, found := os.LookupEnv(EnvVarName(name))
+	if !found {
+		return false, false
+	}
+	v, err := strconv.ParseBool(strings.TrimSpace(raw))
+	if err != nil {
+		return false, false
+	}
+	return v, true
+}
@@ -0,0 +1,194 @@
+package fsutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrBackupNotFound is returned when restoring a backup that does not exist.
+var ErrBackupNotFound = errors.New("backup not found")
+
+// backupTimeLayout sorts in time order and is safe in file names.
+const backupTimeLayout = "20060102T150405.000000000Z"
+
+// BackupPolicy bounds the backups kept per file. A zero field is no bound.
+type BackupPolicy struct {
+	MaxCopies int
+	MaxAge    time.Duration
+	// MinInterval skips a backup while the newest one is younger, so bursts
+	// of writes, such as background bookkeeping, do not rotate out older
+	// backups. Restores always back up first.
+	MinInterval time.Duration
+}
+
+// BackupInfo describes one backup of a file.
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	Size      int64     `json:"size"`
+}
+
+// FileBackups keeps timestamped copies of files in one directory. A nil
+// *FileBackups keeps no backups.
+type FileBackups struct {
+	dir    string
+	policy BackupPolicy
+}
+
+func NewFileBackups(dir string, policy BackupPolicy) *FileBackups {
+	return &FileBackups{dir: filepath.Clean(dir), policy: policy}
+}
+
+// Backup copies the current content of path, if any, and then removes the
+// backups of path the policy no longer keeps. It does nothing while the newest
+// backup is younger than the policy's MinInterval.
+func (b *FileBackups) Backup(path string) error {
+	return b.backup(path, false)
+}
+
+func (b *FileBackups) backup(path string, force bool) error {
+	if b == nil {
+		return nil
+	}
+	if !force && b.policy.MinInterval > 0 {
+		backups, err := b.List(path)
+		if err != nil {
+			return err
+		}
+		if len(backups) > 0 && time.Since(backups[0].CreatedAt) < b.policy.MinInterval {
+			return nil
+		}
+	}
+	src, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(b.dir, 0o755); err != nil {
+		return err
+	}
+	prefix, ext := backupNameParts(path)
+	name := prefix + time.Now().UTC().Format(backupTimeLayout) + ext
+	if err := writeFileAtomic(filepath.Join(b.dir, name), src); err != nil {
+		return fmt.Errorf("backup %s: %w", filepath.Base(path), err)
+	}
+	return b.prune(path)
+}
+
+// List returns the backups of path, newest first.
+func (b *FileBackups) List(path string) ([]BackupInfo, error) {
+	if b == nil {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(b.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prefix, ext := backupNameParts(path)
+	var out []BackupInfo
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		at, err := time.Parse(backupTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext))
+		if err != nil {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, BackupInfo{Name: name, CreatedAt: at, Size: fi.Size()})
+	}
+	slices.SortFunc(out, func(x, y BackupInfo) int { return y.CreatedAt.Compare(x.CreatedAt) })
+	return out, nil
+}
+
+// Read returns the content of the backup of path called name.
+func (b *FileBackups) Read(path, name string) ([]byte, error) {
+	backups, err := b.List(path)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(backups, func(bi BackupInfo) bool { return bi.Name == name }) {
+		return nil, fmt.Errorf("%w: %q", ErrBackupNotFound, name)
+	}
+	return os.ReadFile(filepath.Join(b.dir, name))
+}
+
+// Restore backs up the current content of path and then atomically replaces
+// it with the backup of path called name.
+func (b *FileBackups) Restore(path, name string) error {
+	data, err := b.Read(path, name)
+	if err != nil {
+		return err
+	}
+	if err := b.backup(path, true); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, bytes.NewReader(data))
+}
+
+func (b *FileBackups) prune(path string) error {
+	backups, err := b.List(path)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-b.policy.MaxAge)
+	var errs []error
+	for i, bi := range backups {
+		// The newest backup is kept regardless of its age.
+		keep := i == 0 || ((b.policy.MaxCopies <= 0 || i < b.policy.MaxCopies) &&
+			(b.policy.MaxAge <= 0 || bi.CreatedAt.After(cutoff)))
+		if keep {
+			continue
+		}
+		if err := os.Remove(filepath.Join(b.dir, bi.Name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// backupNameParts splits the backup names of path around the timestamp, e.g.
+// "modelpresets." and ".json".
+func backupNameParts(path string) (prefix, ext string) {
+	base := filepath.Base(path)
+	ext = filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + ".", ext
+}
+
+func writeFileAtomic(path string, r io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
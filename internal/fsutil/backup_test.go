@@ -0,0 +1,108 @@
+package fsutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFileBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "store.json")
+	b := NewFileBackups(filepath.Join(dir, "backups"), BackupPolicy{MaxCopies: 2, MaxAge: time.Hour})
+
+	// Nothing to back up yet.
+	if err := b.Backup(path); err != nil {
+		t.Fatalf("Backup(missing): %v", err)
+	}
+	for i := range 3 {
+		if err := os.WriteFile(path, []byte(strconv.Itoa(i)), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Backup(path); err != nil {
+			t.Fatalf("Backup: %v", err)
+		}
+	}
+	// Unrelated and expired files in the directory.
+	if err := os.WriteFile(filepath.Join(dir, "backups", "other.json"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := "store." + time.Now().Add(-2*time.Hour).UTC().Format(backupTimeLayout) + ".json"
+	if err := os.WriteFile(filepath.Join(dir, "backups", old), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("3"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Backup(path); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	backups, err := b.List(path)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups kept, got %+v", backups)
+	}
+	for i, want := range []string{"3", "2"} {
+		data, err := b.Read(path, backups[i].Name)
+		if err != nil || string(data) != want {
+			t.Fatalf("backup %d: %q, %v; want %q", i, data, err, want)
+		}
+	}
+	if _, err := b.Read(path, old); !errors.Is(err, ErrBackupNotFound) {
+		t.Fatalf("expired backup: %v", err)
+	}
+	if _, err := b.Read(path, "../store.json"); !errors.Is(err, ErrBackupNotFound) {
+		t.Fatalf("path outside backups: %v", err)
+	}
+
+	var disabled *FileBackups
+	if err := disabled.Backup(path); err != nil {
+		t.Fatalf("nil Backup: %v", err)
+	}
+}
+
+func TestFileBackups_MinInterval(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "store.json")
+	b := NewFileBackups(filepath.Join(dir, "backups"), BackupPolicy{MaxCopies: 2, MinInterval: time.Hour})
+
+	for i := range 5 {
+		if err := os.WriteFile(path, []byte(strconv.Itoa(i)), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Backup(path); err != nil {
+			t.Fatalf("Backup: %v", err)
+		}
+	}
+	backups, err := b.List(path)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("expected one backup within the interval, got %+v", backups)
+	}
+	if data, _ := b.Read(path, backups[0].Name); string(data) != "0" {
+		t.Fatalf("kept backup %q, want the first content", data)
+	}
+
+	// A restore backs up the current content regardless of the interval.
+	if err := b.Restore(path, backups[0].Name); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	backups, err = b.List(path)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected the restore to add a backup, got %+v", backups)
+	}
+	if data, _ := b.Read(path, backups[0].Name); string(data) != "4" {
+		t.Fatalf("restore backup %q, want the replaced content", data)
+	}
+}
@@ -0,0 +1,36 @@
+package fsutil
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrLocked is returned by LockFile when another process holds the lock.
+var ErrLocked = errors.New("file is locked by another process")
+
+// FileLock is an exclusive lock on a file, held until Unlock or until the
+// process exits.
+type FileLock struct {
+	f *os.File
+}
+
+// LockFile takes an exclusive lock on path, creating the file if needed. It
+// does not wait: if another process, or another FileLock of this process,
+// holds the lock it returns ErrLocked.
+func LockFile(path string) (*FileLock, error) {
+	f, err := lockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileLock{f: f}, nil
+}
+
+// Unlock releases the lock. It is a no-op on a nil or released lock.
+func (l *FileLock) Unlock() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
@@ -0,0 +1,29 @@
+package fsutil
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instance.lock")
+	first, err := LockFile(path)
+	if err != nil {
+		t.Fatalf("LockFile: %v", err)
+	}
+	if _, err := LockFile(path); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second LockFile = %v, want ErrLocked", err)
+	}
+	if err := first.Unlock(); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if err := first.Unlock(); err != nil {
+		t.Fatalf("second Unlock: %v", err)
+	}
+	again, err := LockFile(path)
+	if err != nil {
+		t.Fatalf("LockFile after Unlock: %v", err)
+	}
+	_ = again.Unlock()
+}
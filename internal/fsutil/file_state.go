@@ -0,0 +1,99 @@
+package fsutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrExternalModification is returned when a file changed on disk between a
+// store reading and writing it, e.g. because the user edited it by hand.
+var ErrExternalModification = errors.New("file was modified externally")
+
+// FileState identifies the content of a file at one point in time. The
+// digest catches edits that keep the size and a coarse modification time.
+type FileState struct {
+	Exists  bool
+	Size    int64
+	ModTime time.Time
+	Digest  string
+}
+
+// ReadFileState returns the state of path. A missing file is a valid state
+// with Exists false.
+func ReadFileState(path string) (FileState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return FileState{}, nil
+	}
+	if err != nil {
+		return FileState{}, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return FileState{}, err
+	}
+	sum := sha256.Sum256(data)
+	return FileState{
+		Exists:  true,
+		Size:    int64(len(data)),
+		ModTime: fi.ModTime(),
+		Digest:  hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// ReadFileStateSince returns last without reading path when the file still
+// has the size and modification time of last, and the full state otherwise.
+// It trades the digest check for O(1) IO; callers use it where an edit that
+// keeps both would be caught later by a full ReadFileState.
+func ReadFileStateSince(path string, last FileState) (FileState, error) {
+	if !last.Exists {
+		return ReadFileState(path)
+	}
+	fi, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return FileState{}, nil
+	}
+	if err != nil {
+		return FileState{}, err
+	}
+	if fi.Size() == last.Size && fi.ModTime().Equal(last.ModTime) {
+		return last, nil
+	}
+	return ReadFileState(path)
+}
+
+// SameContent reports whether s and o hold the same bytes. A touched file
+// with unchanged content is the same.
+func (s FileState) SameContent(o FileState) bool {
+	return s.Exists == o.Exists && s.Digest == o.Digest
+}
+
+// CheckFileUnchanged returns ErrExternalModification if the content of path
+// differs from before.
+func CheckFileUnchanged(path string, before FileState) error {
+	now, err := ReadFileState(path)
+	if err != nil {
+		return err
+	}
+	if !now.SameContent(before) {
+		return fmt.Errorf("%w: %s", ErrExternalModification, path)
+	}
+	return nil
+}
+
+// RefreshModTime sets the modification time of path to the current time if
+// now differs in content from last but not in size or modification time.
+// Callers caching a file by its stat, such as a map file store, then reread
+// it.
+func RefreshModTime(path string, last, now FileState) error {
+	if !last.Exists || !now.Exists || last.SameContent(now) ||
+		last.Size != now.Size || !last.ModTime.Equal(now.ModTime) {
+		return nil
+	}
+	t := time.Now()
+	return os.Chtimes(path, t, t)
+}
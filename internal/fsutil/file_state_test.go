@@ -0,0 +1,98 @@
+package fsutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	missing, err := ReadFileState(path)
+	if err != nil || missing.Exists {
+		t.Fatalf("missing file: %+v, %v", missing, err)
+	}
+
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"a":1}`)
+	before, err := ReadFileState(path)
+	if err != nil || !before.Exists || before.Size != 7 {
+		t.Fatalf("state: %+v, %v", before, err)
+	}
+	if missing.SameContent(before) {
+		t.Fatal("missing and existing file compare equal")
+	}
+	if err := CheckFileUnchanged(path, before); err != nil {
+		t.Fatalf("unchanged: %v", err)
+	}
+
+	// Touching keeps the content.
+	if err := os.Chtimes(path, time.Now(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckFileUnchanged(path, before); err != nil {
+		t.Fatalf("touched: %v", err)
+	}
+
+	// Same size and mtime, different bytes.
+	write(`{"a":2}`)
+	if err := CheckFileUnchanged(path, before); !errors.Is(err, ErrExternalModification) {
+		t.Fatalf("edited: %v", err)
+	}
+
+	now, err := ReadFileState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RefreshModTime(path, before, now); err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := ReadFileState(path)
+	if err != nil || refreshed.ModTime.Equal(mtime) || !refreshed.SameContent(now) {
+		t.Fatalf("refreshed: %+v, %v", refreshed, err)
+	}
+}
+
+func TestReadFileStateSince(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	if err := os.WriteFile(path, []byte(`{"a":1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	last, err := ReadFileState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged stat: the remembered state is returned as is.
+	got, err := ReadFileStateSince(path, last)
+	if err != nil || got != last {
+		t.Fatalf("unchanged: %+v, %v", got, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"a":10}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err = ReadFileStateSince(path, last)
+	if err != nil || got.SameContent(last) || got.Size != 8 {
+		t.Fatalf("resized: %+v, %v", got, err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err = ReadFileStateSince(path, last)
+	if err != nil || got.Exists {
+		t.Fatalf("removed: %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,71 @@
+package fsutil
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// FileWatcher reports changes to a single file. It watches the parent
+// directory, so a file replaced by rename, as sync clients and editors do,
+// keeps being watched.
+type FileWatcher struct {
+	w        *fsnotify.Watcher
+	path     string
+	debounce time.Duration
+}
+
+// NewFileWatcher starts watching path. Bursts of events are coalesced until
+// debounce passes without a new one.
+func NewFileWatcher(path string, debounce time.Duration) (*FileWatcher, error) {
+	path = filepath.Clean(path)
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Add(filepath.Dir(path)); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	return &FileWatcher{w: w, path: path, debounce: debounce}, nil
+}
+
+// Run calls onChange after each debounced change of the file and onError for
+// watcher errors, until ctx is done. It closes the watcher before returning.
+func (fw *FileWatcher) Run(ctx context.Context, onChange func(), onError func(error)) {
+	defer fw.w.Close()
+
+	timer := time.NewTimer(fw.debounce)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-fw.w.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(ev.Name) != fw.path || ev.Op == fsnotify.Chmod {
+				continue
+			}
+			timer.Reset(fw.debounce)
+		case err, ok := <-fw.w.Errors:
+			if !ok {
+				return
+			}
+			if onError != nil {
+				onError(err)
+			}
+		case <-timer.C:
+			onChange()
+		}
+	}
+}
+
+// Close stops a watcher that is not running.
+func (fw *FileWatcher) Close() error {
+	return fw.w.Close()
+}
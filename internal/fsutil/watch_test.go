@@ -0,0 +1,67 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileWatcher(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "store.json")
+	if err := os.WriteFile(path, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fw, err := NewFileWatcher(path, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewFileWatcher: %v", err)
+	}
+	var calls atomic.Int32
+	changed := make(chan struct{}, 8)
+	go fw.Run(t.Context(), func() {
+		calls.Add(1)
+		changed <- struct{}{}
+	}, nil)
+
+	wait := func(what string) {
+		t.Helper()
+		select {
+		case <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+
+	// A burst of writes is reported once.
+	for i := range 3 {
+		if err := os.WriteFile(path, []byte{'0' + byte(i)}, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wait("write")
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("burst reported %d times", got)
+	}
+
+	// Other files in the directory are ignored.
+	if err := os.WriteFile(filepath.Join(dir, "other.json"), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replacing the file by rename keeps it watched.
+	tmp := filepath.Join(dir, "store.json.tmp")
+	for range 2 {
+		if err := os.WriteFile(tmp, []byte(`{"a":1}`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+		wait("rename")
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("expected 3 changes, got %d", got)
+	}
+}
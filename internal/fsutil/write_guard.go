@@ -0,0 +1,117 @@
+package fsutil
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrStoreReadOnly is returned for mutations of a store whose last write
+// failed because the disk is full or its file is locked.
+var ErrStoreReadOnly = errors.New("store is read-only")
+
+// Windows error codes for a locked file or a full disk.
+const (
+	winErrorSharingViolation = syscall.Errno(32)
+	winErrorLockViolation    = syscall.Errno(33)
+	winErrorHandleDiskFull   = syscall.Errno(39)
+	winErrorDiskFull         = syscall.Errno(112)
+)
+
+// IsWriteUnavailable reports whether err means writes cannot succeed until
+// the user frees space or releases a lock, as opposed to a bad request.
+func IsWriteUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, errno := range []syscall.Errno{syscall.ENOSPC, syscall.EDQUOT, syscall.EROFS, syscall.EAGAIN} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	if runtime.GOOS == "windows" {
+		for _, errno := range []syscall.Errno{
+			winErrorSharingViolation, winErrorLockViolation, winErrorHandleDiskFull, winErrorDiskFull,
+		} {
+			if errors.Is(err, errno) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteStatus describes whether a store accepts writes. Cause and Since are
+// set while it is read-only.
+type WriteStatus struct {
+	ReadOnly bool       `json:"readOnly"`
+	Cause    string     `json:"cause,omitempty"`
+	Since    *time.Time `json:"since,omitempty"`
+}
+
+// WriteGuard flips a store to read-only after a write fails with an error
+// IsWriteUnavailable accepts. The zero value is writable.
+type WriteGuard struct {
+	mu    sync.Mutex
+	cause error
+	since time.Time
+}
+
+// Check returns ErrStoreReadOnly, wrapping the cause, while read-only.
+func (g *WriteGuard) Check() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.cause == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrStoreReadOnly, g.cause)
+}
+
+// Write runs write unless the guard is read-only. A failure of write that
+// IsWriteUnavailable accepts makes the guard read-only.
+func (g *WriteGuard) Write(write func() error) error {
+	if err := g.Check(); err != nil {
+		return err
+	}
+	return g.observe(write())
+}
+
+// Retry runs write even while read-only and makes the guard writable again
+// when it succeeds.
+func (g *WriteGuard) Retry(write func() error) error {
+	err := write()
+	if err == nil {
+		g.mu.Lock()
+		g.cause = nil
+		g.since = time.Time{}
+		g.mu.Unlock()
+		return nil
+	}
+	return g.observe(err)
+}
+
+func (g *WriteGuard) Status() WriteStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.cause == nil {
+		return WriteStatus{}
+	}
+	since := g.since
+	return WriteStatus{ReadOnly: true, Cause: g.cause.Error(), Since: &since}
+}
+
+func (g *WriteGuard) observe(err error) error {
+	if !IsWriteUnavailable(err) {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.cause == nil {
+		g.since = time.Now().UTC()
+	}
+	g.cause = err
+	return fmt.Errorf("%w: %w", ErrStoreReadOnly, err)
+}
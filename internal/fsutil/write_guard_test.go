@@ -0,0 +1,40 @@
+package fsutil
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+	"testing"
+)
+
+func TestWriteGuard(t *testing.T) {
+	var g WriteGuard
+	badRequest := errors.New("bad request")
+	if err := g.Write(func() error { return badRequest }); !errors.Is(err, badRequest) || g.Status().ReadOnly {
+		t.Fatalf("ordinary error: %v, status %+v", err, g.Status())
+	}
+
+	diskFull := &fs.PathError{Op: "write", Path: "store.json", Err: syscall.ENOSPC}
+	err := g.Write(func() error { return diskFull })
+	if !errors.Is(err, ErrStoreReadOnly) || !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("disk full: %v", err)
+	}
+	st := g.Status()
+	if !st.ReadOnly || st.Since == nil || st.Cause != diskFull.Error() {
+		t.Fatalf("status: %+v", st)
+	}
+
+	called := false
+	if err := g.Write(func() error { called = true; return nil }); !errors.Is(err, ErrStoreReadOnly) || called {
+		t.Fatalf("write while read-only: %v, called %v", err, called)
+	}
+	if err := g.Retry(func() error { return diskFull }); !errors.Is(err, ErrStoreReadOnly) || !g.Status().ReadOnly {
+		t.Fatalf("failed retry: %v", err)
+	}
+	if err := g.Retry(func() error { return nil }); err != nil || g.Status().ReadOnly {
+		t.Fatalf("retry: %v, status %+v", err, g.Status())
+	}
+	if err := g.Write(func() error { return nil }); err != nil {
+		t.Fatalf("write after retry: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+// Package idempotency replays the result of a mutating call that is retried
+// with the same idempotency key, so that a caller who gave up waiting, e.g. a
+// renderer IPC call that timed out, can retry without applying the change
+// twice.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL is how long a successful result is replayed.
+	DefaultTTL = 10 * time.Minute
+	// DefaultMaxEntries bounds the results a cache keeps.
+	DefaultMaxEntries = 512
+)
+
+// ErrKeyReused is returned when a key is used again for a different request.
+var ErrKeyReused = errors.New("idempotency key reused with a different request")
+
+var errPanicked = errors.New("idempotent call panicked")
+
+// Cache remembers the results of keyed calls. Only successful results are
+// replayed after the call returns; a failed call can be retried with the same
+// key. The zero value is not usable; a nil *Cache runs every call.
+type Cache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	fingerprint [sha256.Size]byte
+	done        chan struct{}
+	expires     time.Time // zero while the call runs
+	result      any
+	err         error
+}
+
+func NewCache(ttl time.Duration, maxEntries int) *Cache {
+	return &Cache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    map[string]*entry{},
+	}
+}
+
+// Do runs fn once per op and key while its result is cached, and returns the
+// first result to every later call with the same key. req identifies the
+// request; a call reusing a key for a different req fails with ErrKeyReused.
+// A call made while the first one runs waits for it, or for ctx. An empty key
+// always runs fn.
+func Do[T any](ctx context.Context, c *Cache, op, key string, req any, fn func() (T, error)) (T, error) {
+	var zero T
+	if c == nil || key == "" {
+		return fn()
+	}
+	raw, err := json.Marshal(req)
+	if err != nil {
+		return zero, fmt.Errorf("idempotency fingerprint: %w", err)
+	}
+	fingerprint := sha256.Sum256(raw)
+	id := op + "\x00" + key
+
+	c.mu.Lock()
+	c.pruneLocked()
+	if e, ok := c.entries[id]; ok {
+		c.mu.Unlock()
+		if e.fingerprint != fingerprint {
+			return zero, fmt.Errorf("%w: %s %q", ErrKeyReused, op, key)
+		}
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+		result, _ := e.result.(T)
+		return result, e.err
+	}
+	e := &entry{fingerprint: fingerprint, done: make(chan struct{}), err: errPanicked}
+	c.entries[id] = e
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		if e.err != nil {
+			delete(c.entries, id)
+		} else {
+			e.expires = c.now().Add(c.ttl)
+		}
+		c.mu.Unlock()
+		close(e.done)
+	}()
+	result, err := fn()
+	e.result, e.err = result, err
+	return result, err
+}
+
+// pruneLocked drops expired results, then the oldest ones beyond maxEntries.
+// Running calls are kept. Caller must hold mu.
+func (c *Cache) pruneLocked() {
+	now := c.now()
+	for id, e := range c.entries {
+		if !e.expires.IsZero() && !now.Before(e.expires) {
+			delete(c.entries, id)
+		}
+	}
+	for len(c.entries) >= c.maxEntries {
+		oldestID := ""
+		var oldest time.Time
+		for id, e := range c.entries {
+			if !e.expires.IsZero() && (oldestID == "" || e.expires.Before(oldest)) {
+				oldestID, oldest = id, e.expires
+			}
+		}
+		if oldestID == "" {
+			return
+		}
+		delete(c.entries, oldestID)
+	}
+}
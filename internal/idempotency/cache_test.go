@@ -0,0 +1,113 @@
+package idempotency
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testReq struct {
+	Name string `json:"name"`
+}
+
+func TestDo(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("replays success", func(t *testing.T) {
+		c := NewCache(time.Minute, 8)
+		var calls int
+		fn := func() (int, error) { calls++; return calls, nil }
+		for range 3 {
+			got, err := Do(ctx, c, "op", "k1", testReq{"a"}, fn)
+			if err != nil || got != 1 {
+				t.Fatalf("Do = %d, %v; want 1, nil", got, err)
+			}
+		}
+		if got, _ := Do(ctx, c, "other", "k1", testReq{"a"}, fn); got != 2 {
+			t.Fatalf("same key for another op = %d, want 2", got)
+		}
+		if got, _ := Do(ctx, c, "op", "", testReq{"a"}, fn); got != 3 {
+			t.Fatalf("empty key = %d, want 3", got)
+		}
+	})
+
+	t.Run("retries failure", func(t *testing.T) {
+		c := NewCache(time.Minute, 8)
+		errBoom := errors.New("boom")
+		_, err := Do(ctx, c, "op", "k", testReq{}, func() (int, error) { return 0, errBoom })
+		if !errors.Is(err, errBoom) {
+			t.Fatalf("first err = %v", err)
+		}
+		got, err := Do(ctx, c, "op", "k", testReq{}, func() (int, error) { return 7, nil })
+		if err != nil || got != 7 {
+			t.Fatalf("retry = %d, %v; want 7, nil", got, err)
+		}
+	})
+
+	t.Run("rejects reused key", func(t *testing.T) {
+		c := NewCache(time.Minute, 8)
+		fn := func() (int, error) { return 1, nil }
+		if _, err := Do(ctx, c, "op", "k", testReq{"a"}, fn); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Do(ctx, c, "op", "k", testReq{"b"}, fn); !errors.Is(err, ErrKeyReused) {
+			t.Fatalf("err = %v, want ErrKeyReused", err)
+		}
+	})
+
+	t.Run("expires and evicts", func(t *testing.T) {
+		c := NewCache(time.Minute, 2)
+		now := time.Now()
+		c.now = func() time.Time { return now }
+		var calls int
+		fn := func() (int, error) { calls++; return calls, nil }
+		_, _ = Do(ctx, c, "op", "a", testReq{}, fn)
+		now = now.Add(time.Second)
+		_, _ = Do(ctx, c, "op", "b", testReq{}, fn)
+		_, _ = Do(ctx, c, "op", "c", testReq{}, fn) // evicts a
+		if got, _ := Do(ctx, c, "op", "a", testReq{}, fn); got != 4 {
+			t.Fatalf("evicted key = %d, want 4", got)
+		}
+		now = now.Add(2 * time.Minute)
+		if got, _ := Do(ctx, c, "op", "c", testReq{}, fn); got != 5 {
+			t.Fatalf("expired key = %d, want 5", got)
+		}
+	})
+
+	t.Run("concurrent duplicates run once", func(t *testing.T) {
+		c := NewCache(time.Minute, 8)
+		var calls atomic.Int32
+		release := make(chan struct{})
+		fn := func() (int32, error) {
+			<-release
+			return calls.Add(1), nil
+		}
+		var wg sync.WaitGroup
+		results := make([]int32, 8)
+		for i := range results {
+			wg.Go(func() {
+				results[i], _ = Do(ctx, c, "op", "k", testReq{}, fn)
+			})
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		for i, got := range results {
+			if got != 1 {
+				t.Fatalf("results[%d] = %d, want 1", i, got)
+			}
+		}
+	})
+
+	t.Run("nil cache runs every call", func(t *testing.T) {
+		var calls int
+		for range 2 {
+			_, _ = Do(ctx, nil, "op", "k", testReq{}, func() (int, error) { calls++; return calls, nil })
+		}
+		if calls != 2 {
+			t.Fatalf("calls = %d, want 2", calls)
+		}
+	})
+}
@@ -5,24 +5,33 @@ import (
 	"strings"
 
 	"github.com/flexigpt/flexigpt-app/internal/attachment"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
 	inferenceSpec "github.com/flexigpt/inference-go/spec"
 )
 
 func buildContentItemsFromAttachments(
 	ctx context.Context,
 	atts []attachment.Attachment,
+	imageProcessing *modelpresetSpec.ProviderImageProcessing,
 ) ([]inferenceSpec.InputOutputContentItemUnion, error) {
 	items := make([]inferenceSpec.InputOutputContentItemUnion, 0)
 	if len(atts) == 0 {
 		return items, nil
 	}
 
-	blocks, err := attachment.BuildContentBlocks(
-		ctx,
-		atts,
+	opts := []attachment.ContentBlockOption{
 		attachment.WithOverrideOriginalContentBlock(true),
 		attachment.WithOnlyTextKindContentBlock(false),
-	)
+	}
+	if imageProcessing != nil && !imageProcessing.IsZero() {
+		opts = append(opts, attachment.WithImageProcessing(&attachment.ImageProcessing{
+			MaxDimension:  imageProcessing.MaxDimension,
+			Format:        string(imageProcessing.Format),
+			JPEGQuality:   imageProcessing.JPEGQuality,
+			StripMetadata: imageProcessing.StripMetadata,
+		}))
+	}
+	blocks, err := attachment.BuildContentBlocks(ctx, atts, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,292 @@
+package inferencewrapper
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+const (
+	defaultCompletionCacheMaxBytes = 256 * 1024 * 1024
+	defaultCompletionCacheTTL      = 7 * 24 * time.Hour
+	completionCacheFileExt         = ".json"
+)
+
+// CompletionCacheConfig is read before every completion, so changes apply
+// without restarting.
+type CompletionCacheConfig struct {
+	Enabled bool
+	// MaxBytes bounds the cache on disk; least recently used entries are
+	// evicted first. Zero uses 256 MiB.
+	MaxBytes int64
+	// TTL is how long an entry may be served. Zero uses 7 days.
+	TTL time.Duration
+}
+
+// WithCompletionCache stores successful completions under dir and serves
+// identical requests from it while config reports the cache as enabled.
+func WithCompletionCache(
+	dir string,
+	config func(context.Context) (CompletionCacheConfig, error),
+) ProviderSetOption {
+	return func(ps *ProviderSetAPI) {
+		if strings.TrimSpace(dir) == "" || config == nil {
+			ps.completionCache = nil
+			return
+		}
+		ps.completionCache = &completionCache{
+			dir:     filepath.Clean(dir),
+			config:  config,
+			entries: map[string]*completionCacheEntry{},
+		}
+	}
+}
+
+type completionCacheEntry struct {
+	size   int64
+	usedAt time.Time
+}
+
+type cachedCompletion struct {
+	CreatedAt time.Time                              `json:"createdAt"`
+	Provider  inferenceSpec.ProviderName             `json:"provider"`
+	Response  *inferenceSpec.FetchCompletionResponse `json:"response"`
+}
+
+// completionCache is a directory of one JSON file per cached response, named
+// by the request key. The index of sizes and use times is kept in memory and
+// rebuilt from the directory on first use.
+type completionCache struct {
+	dir    string
+	config func(context.Context) (CompletionCacheConfig, error)
+
+	mu      sync.Mutex
+	loaded  bool
+	entries map[string]*completionCacheEntry
+	size    int64
+}
+
+// key hashes the request as sent to the provider. Stream and timeout do not
+// change the answer and are left out. Returns "" when the cache is disabled.
+func (c *completionCache) key(
+	ctx context.Context,
+	provider inferenceSpec.ProviderName,
+	req *inferenceSpec.FetchCompletionRequest,
+) (string, CompletionCacheConfig) {
+	if c == nil {
+		return "", CompletionCacheConfig{}
+	}
+	cfg, err := c.config(ctx)
+	if err != nil || !cfg.Enabled {
+		return "", cfg
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultCompletionCacheMaxBytes
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultCompletionCacheTTL
+	}
+
+	keyReq := *req
+	keyReq.ModelParam.Stream = false
+	keyReq.ModelParam.Timeout = 0
+	// Struct fields marshal in declaration order and map keys sorted, so
+	// equal requests always hash the same.
+	raw, err := json.Marshal(struct {
+		Provider inferenceSpec.ProviderName            `json:"provider"`
+		Request  *inferenceSpec.FetchCompletionRequest `json:"request"`
+	}{provider, &keyReq})
+	if err != nil {
+		return "", cfg
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), cfg
+}
+
+func (c *completionCache) get(key string, cfg CompletionCacheConfig) *inferenceSpec.FetchCompletionResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadLocked()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.removeLocked(key)
+		return nil
+	}
+	var cc cachedCompletion
+	if err := json.Unmarshal(data, &cc); err != nil || cc.Response == nil ||
+		time.Since(cc.CreatedAt) > cfg.TTL {
+		c.removeLocked(key)
+		return nil
+	}
+	now := time.Now()
+	e.usedAt = now
+	// The modification time carries the use time across restarts.
+	_ = os.Chtimes(path, now, now)
+	return cc.Response
+}
+
+func (c *completionCache) put(
+	key string,
+	cfg CompletionCacheConfig,
+	provider inferenceSpec.ProviderName,
+	resp *inferenceSpec.FetchCompletionResponse,
+) error {
+	data, err := json.Marshal(cachedCompletion{
+		CreatedAt: time.Now().UTC(),
+		Provider:  provider,
+		Response:  resp,
+	})
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > cfg.MaxBytes {
+		return errors.New("completion larger than the cache")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadLocked()
+	if err := os.MkdirAll(c.dir, 0o770); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.dir, "*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if old, ok := c.entries[key]; ok {
+		c.size -= old.size
+	}
+	c.entries[key] = &completionCacheEntry{size: int64(len(data)), usedAt: time.Now()}
+	c.size += int64(len(data))
+	c.evictLocked(cfg.MaxBytes)
+	return nil
+}
+
+// loadLocked indexes the cache directory once. Caller must hold mu.
+func (c *completionCache) loadLocked() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, de := range dirEntries {
+		name := de.Name()
+		if de.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(name, ".tmp") {
+			_ = os.Remove(filepath.Join(c.dir, name))
+			continue
+		}
+		key, ok := strings.CutSuffix(name, completionCacheFileExt)
+		if !ok {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		c.entries[key] = &completionCacheEntry{size: info.Size(), usedAt: info.ModTime()}
+		c.size += info.Size()
+	}
+}
+
+// evictLocked drops least recently used entries until the cache fits in
+// maxBytes. Caller must hold mu.
+func (c *completionCache) evictLocked(maxBytes int64) {
+	for c.size > maxBytes && len(c.entries) > 0 {
+		var (
+			oldestKey string
+			oldest    time.Time
+		)
+		for k, e := range c.entries {
+			if oldestKey == "" || e.usedAt.Before(oldest) {
+				oldestKey, oldest = k, e.usedAt
+			}
+		}
+		c.removeLocked(oldestKey)
+	}
+}
+
+// removeLocked deletes an entry and its file. Caller must hold mu.
+func (c *completionCache) removeLocked(key string) {
+	if e, ok := c.entries[key]; ok {
+		c.size -= e.size
+		delete(c.entries, key)
+	}
+	_ = os.Remove(c.path(key))
+}
+
+// replayCachedCompletion feeds the text and thinking of a cached response to
+// handler as the stream events a live completion would have produced, so
+// that streaming callers render cache hits the same way.
+func replayCachedCompletion(
+	resp *inferenceSpec.FetchCompletionResponse,
+	handler inferenceSpec.StreamHandler,
+) error {
+	for _, out := range resp.Outputs {
+		switch {
+		case out.ReasoningMessage != nil:
+			parts := out.ReasoningMessage.Thinking
+			if len(parts) == 0 {
+				parts = out.ReasoningMessage.Summary
+			}
+			for _, text := range parts {
+				if err := handler(inferenceSpec.StreamEvent{
+					Kind:     inferenceSpec.StreamContentKindThinking,
+					Thinking: &inferenceSpec.StreamThinkingChunk{Text: text},
+				}); err != nil {
+					return err
+				}
+			}
+		case out.OutputMessage != nil:
+			for _, item := range out.OutputMessage.Contents {
+				if item.TextItem == nil {
+					continue
+				}
+				if err := handler(inferenceSpec.StreamEvent{
+					Kind: inferenceSpec.StreamContentKindText,
+					Text: &inferenceSpec.StreamTextChunk{Text: item.TextItem.Text},
+				}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *completionCache) path(key string) string {
+	return filepath.Join(c.dir, key+completionCacheFileExt)
+}
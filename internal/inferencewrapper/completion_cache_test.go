@@ -0,0 +1,45 @@
+package inferencewrapper
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+func TestReplayCachedCompletion(t *testing.T) {
+	resp := &inferenceSpec.FetchCompletionResponse{Outputs: []inferenceSpec.OutputUnion{
+		{
+			Kind:             inferenceSpec.OutputKindReasoningMessage,
+			ReasoningMessage: &inferenceSpec.ReasoningContent{Summary: []string{"weighing"}},
+		},
+		{
+			Kind: inferenceSpec.OutputKindOutputMessage,
+			OutputMessage: &inferenceSpec.InputOutputContent{Contents: []inferenceSpec.InputOutputContentItemUnion{
+				{Kind: inferenceSpec.ContentItemKindText, TextItem: &inferenceSpec.ContentItemText{Text: "hello"}},
+				{Kind: inferenceSpec.ContentItemKindRefusal, RefusalItem: &inferenceSpec.ContentItemRefusal{}},
+				{Kind: inferenceSpec.ContentItemKindText, TextItem: &inferenceSpec.ContentItemText{Text: " world"}},
+			}},
+		},
+	}}
+
+	var got []string
+	handler := makeStreamHandler(
+		func(text string) error { got = append(got, "text:"+text); return nil },
+		func(thinking string) error { got = append(got, "thinking:"+thinking); return nil },
+	)
+	if err := replayCachedCompletion(resp, handler); err != nil {
+		t.Fatalf("replayCachedCompletion: %v", err)
+	}
+	want := []string{"thinking:weighing", "text:hello", "text: world"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+
+	stop := errors.New("client gone")
+	handler = makeStreamHandler(func(string) error { return stop }, nil)
+	if err := replayCachedCompletion(resp, handler); !errors.Is(err, stop) {
+		t.Fatalf("replay error = %v, want the handler's", err)
+	}
+}
@@ -0,0 +1,62 @@
+package inferencewrapper
+
+import (
+	"context"
+
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	usageSpec "github.com/flexigpt/flexigpt-app/internal/usage/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// GuardEmbeddings runs fetch under the provider's rate limits and records the
+// input tokens fetch reports as usage. Embedding presets carry no pricing, so
+// the usage is recorded unpriced.
+func (ps *ProviderSetAPI) GuardEmbeddings(
+	ctx context.Context,
+	provider inferenceSpec.ProviderName,
+	modelName inferenceSpec.ModelName,
+	inputs []string,
+	fetch func(ctx context.Context) (inputTokens int, err error),
+) error {
+	pResp, err := ps.mpStore.GetProviderPreset(ctx, &modelpresetSpec.GetProviderPresetRequest{
+		ProviderName:    provider,
+		IncludeDisabled: true,
+	})
+	if err != nil {
+		return err
+	}
+	var est int64
+	for _, in := range inputs {
+		est += int64(len(in) / approxBytesPerToken)
+	}
+	release, err := ps.acquireRateLimit(ctx, provider, pResp.Body.RateLimits, est)
+	if err != nil {
+		return err
+	}
+	tokens, err := fetch(ctx)
+	if err != nil {
+		release(nil)
+		return err
+	}
+	u := &inferenceSpec.Usage{InputTokensTotal: int64(tokens), InputTokensUncached: int64(tokens)}
+	if tokens > 0 {
+		release(u)
+	} else {
+		// Google does not report embedding tokens; keep the estimate.
+		release(nil)
+	}
+	if ps.usageStore == nil {
+		return nil
+	}
+	if _, err := ps.usageStore.RecordUsage(context.WithoutCancel(ctx), &usageSpec.RecordUsageRequest{
+		Body: &usageSpec.UsageRecord{
+			ProviderName:        provider,
+			ModelName:           modelName,
+			InputTokensTotal:    u.InputTokensTotal,
+			InputTokensUncached: u.InputTokensUncached,
+		},
+	}); err != nil {
+		ps.logger.Error("usage: record embeddings failed", "provider", provider, "err", err)
+	}
+	return nil
+}
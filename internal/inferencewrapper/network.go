@@ -0,0 +1,394 @@
+package inferencewrapper
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flexigpt/inference-go/debugclient"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+	"github.com/google/uuid"
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/flexigpt/flexigpt-app/internal/inferencewrapper/spec"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+)
+
+// NetworkConfig controls how provider HTTP clients reach the network. With no
+// proxy set the proxy environment variables apply.
+type NetworkConfig struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	// SOCKSProxy is used for schemes without a proxy of their own.
+	SOCKSProxy string
+	NoProxy    string
+
+	// CABundlePath is a PEM file trusted in addition to the system roots.
+	CABundlePath string
+
+	InsecureSkipVerifyProviders []inferenceSpec.ProviderName
+}
+
+// providerNetwork builds one transport per provider from the live config.
+// Providers capture their HTTP client once, so they get a providerTransport
+// that looks the transport up per request and sees later config changes.
+type providerNetwork struct {
+	mu         sync.Mutex
+	cfg        *NetworkConfig
+	rootCAs    *x509.CertPool
+	transports map[inferenceSpec.ProviderName]*http.Transport
+	// rewrites and tls survive config changes; they belong to the provider.
+	rewrites map[inferenceSpec.ProviderName]*providerRequestRewrite
+	tls      map[inferenceSpec.ProviderName]*providerTLS
+	// resolveAuthKey backs the authKey placeholders of templated headers.
+	resolveAuthKey AuthKeyResolver
+}
+
+// AuthKeyResolver returns the secret of a setting store auth key.
+type AuthKeyResolver func(ctx context.Context, keyType, keyName string) (string, error)
+
+// providerTLS is the checked TLS material of one provider preset.
+type providerTLS struct {
+	caPEM      []byte
+	clientCert *tls.Certificate
+}
+
+func newProviderTLS(c spec.ProviderTLS) (*providerTLS, error) {
+	out := &providerTLS{}
+	if c.CABundlePath != "" {
+		pem, err := os.ReadFile(c.CABundlePath)
+		if err != nil {
+			return nil, fmt.Errorf("read provider CA bundle: %w", err)
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			return nil, errors.New("provider CA bundle has no PEM certificates")
+		}
+		out.caPEM = pem
+	}
+	if c.ClientCertPEM != "" || c.ClientKeyPEM != "" {
+		cert, err := tls.X509KeyPair([]byte(c.ClientCertPEM), []byte(c.ClientKeyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		out.clientCert = &cert
+	}
+	return out, nil
+}
+
+// providerRequestRewrite adjusts requests that inference-go cannot shape
+// itself, such as query parameters some APIs require or default headers whose
+// value is a template expanded per request.
+type providerRequestRewrite struct {
+	query       url.Values
+	dropHeaders []string
+	headers     map[string]string
+}
+
+// splitTemplatedHeaders separates default headers with placeholders from the
+// static ones inference-go can send as they are.
+func splitTemplatedHeaders(defaults map[string]string) (static, templated map[string]string) {
+	for k, v := range defaults {
+		if len(modelpresetSpec.HeaderPlaceholders(v)) == 0 {
+			if static == nil {
+				static = map[string]string{}
+			}
+			static[k] = v
+			continue
+		}
+		if templated == nil {
+			templated = map[string]string{}
+		}
+		templated[k] = v
+	}
+	return static, templated
+}
+
+// azureRequestRewrite adds the api-version parameter and, unless the key is
+// sent as a bearer token, drops the bearer header the OpenAI SDK always sets.
+func azureRequestRewrite(c modelpresetSpec.AzureOpenAIConfig, apiKeyHeaderKey string) *providerRequestRewrite {
+	r := &providerRequestRewrite{
+		query: url.Values{modelpresetSpec.AzureOpenAIAPIVersionQueryKey: {c.APIVersion}},
+	}
+	if apiKeyHeaderKey != "" && !strings.EqualFold(apiKeyHeaderKey, inferenceSpec.DefaultAuthorizationHeaderKey) {
+		r.dropHeaders = []string{inferenceSpec.DefaultAuthorizationHeaderKey}
+	}
+	return r
+}
+
+func (r *providerRequestRewrite) apply(
+	req *http.Request, provider inferenceSpec.ProviderName, resolveAuthKey AuthKeyResolver,
+) (*http.Request, error) {
+	out := req.Clone(req.Context())
+	if len(r.query) > 0 {
+		q := out.URL.Query()
+		for k, v := range r.query {
+			q[k] = v
+		}
+		out.URL.RawQuery = q.Encode()
+	}
+	for _, h := range r.dropHeaders {
+		out.Header.Del(h)
+	}
+	if len(r.headers) == 0 {
+		return out, nil
+	}
+	resolve := headerPlaceholderResolver(req.Context(), provider, time.Now().UTC(), resolveAuthKey)
+	for k, tmpl := range r.headers {
+		v, err := modelpresetSpec.ExpandHeaderPlaceholders(tmpl, resolve)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", k, err)
+		}
+		out.Header.Set(k, v)
+	}
+	return out, nil
+}
+
+// headerPlaceholderResolver resolves header placeholders for one request, so
+// every header of it sees the same time and request ID. Auth keys outside
+// what modelpresetSpec.HeaderAuthKeyAllowed permits are refused, even if a
+// preset that skipped validation names them.
+func headerPlaceholderResolver(
+	ctx context.Context,
+	provider inferenceSpec.ProviderName,
+	now time.Time,
+	resolveAuthKey AuthKeyResolver,
+) func(string) (string, error) {
+	var requestID string
+	return func(name string) (string, error) {
+		switch name {
+		case modelpresetSpec.HeaderPlaceholderDate:
+			return now.Format(http.TimeFormat), nil
+		case modelpresetSpec.HeaderPlaceholderTimestamp:
+			return strconv.FormatInt(now.Unix(), 10), nil
+		case modelpresetSpec.HeaderPlaceholderRequestID:
+			if requestID == "" {
+				requestID = uuid.NewString()
+			}
+			return requestID, nil
+		}
+		keyType, keyName, ok := modelpresetSpec.HeaderAuthKeyPlaceholder(name)
+		if !ok {
+			return "", fmt.Errorf("unknown header placeholder %q", name)
+		}
+		if !modelpresetSpec.HeaderAuthKeyAllowed(provider, keyType, keyName) {
+			return "", fmt.Errorf("header placeholder {{%s}} may not be sent to provider %s", name, provider)
+		}
+		if resolveAuthKey == nil {
+			return "", fmt.Errorf("no auth key resolver for {{%s}}", name)
+		}
+		secret, err := resolveAuthKey(ctx, keyType, keyName)
+		if err != nil {
+			return "", fmt.Errorf("auth key %s/%s: %w", keyType, keyName, err)
+		}
+		return secret, nil
+	}
+}
+
+func (n *providerNetwork) setRewrite(provider inferenceSpec.ProviderName, r *providerRequestRewrite) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if r == nil {
+		delete(n.rewrites, provider)
+		return
+	}
+	if n.rewrites == nil {
+		n.rewrites = map[inferenceSpec.ProviderName]*providerRequestRewrite{}
+	}
+	n.rewrites[provider] = r
+}
+
+// setTLS replaces the TLS material of a provider and drops its transport so
+// the next request dials with it.
+func (n *providerNetwork) setTLS(provider inferenceSpec.ProviderName, t *providerTLS) {
+	n.mu.Lock()
+	prev := n.transports[provider]
+	delete(n.transports, provider)
+	if t == nil {
+		delete(n.tls, provider)
+	} else {
+		if n.tls == nil {
+			n.tls = map[inferenceSpec.ProviderName]*providerTLS{}
+		}
+		n.tls[provider] = t
+	}
+	n.mu.Unlock()
+	if prev != nil {
+		prev.CloseIdleConnections()
+	}
+}
+
+func (n *providerNetwork) rewrite(provider inferenceSpec.ProviderName) (*providerRequestRewrite, AuthKeyResolver) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.rewrites[provider], n.resolveAuthKey
+}
+
+// SetNetworkConfig applies proxy and TLS settings to all provider HTTP
+// clients, including ones already created. Nil restores the defaults.
+func (ps *ProviderSetAPI) SetNetworkConfig(cfg *NetworkConfig) error {
+	if ps == nil {
+		return nil
+	}
+	var (
+		next    *NetworkConfig
+		rootCAs *x509.CertPool
+	)
+	if cfg != nil {
+		cloned := *cfg
+		cloned.InsecureSkipVerifyProviders = slices.Clone(cfg.InsecureSkipVerifyProviders)
+		next = &cloned
+		if next.CABundlePath != "" {
+			pem, err := os.ReadFile(next.CABundlePath)
+			if err != nil {
+				return fmt.Errorf("read CA bundle: %w", err)
+			}
+			rootCAs = systemCertPool()
+			if !rootCAs.AppendCertsFromPEM(pem) {
+				return errors.New("CA bundle has no PEM certificates")
+			}
+		}
+	}
+
+	n := &ps.network
+	n.mu.Lock()
+	previous := n.transports
+	n.cfg = next
+	n.rootCAs = rootCAs
+	n.transports = nil
+	n.mu.Unlock()
+
+	for _, t := range previous {
+		t.CloseIdleConnections()
+	}
+	return nil
+}
+
+// HTTPTransport returns the transport requests to provider go through, so
+// requests made outside inference-go see the same proxy, CA, TLS and request
+// rewrites. A provider that was never added gets the network config only.
+func (ps *ProviderSetAPI) HTTPTransport(provider inferenceSpec.ProviderName) http.RoundTripper {
+	return &providerTransport{network: &ps.network, provider: provider}
+}
+
+func (n *providerNetwork) transport(provider inferenceSpec.ProviderName) http.RoundTripper {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ptls := n.tls[provider]
+	if n.cfg == nil && ptls == nil {
+		return http.DefaultTransport
+	}
+	if t, ok := n.transports[provider]; ok {
+		return t
+	}
+
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return http.DefaultTransport
+	}
+	t := base.Clone()
+	cfg := n.cfg
+	if cfg == nil {
+		cfg = &NetworkConfig{}
+	}
+	if cfg.HTTPProxy != "" || cfg.HTTPSProxy != "" || cfg.SOCKSProxy != "" {
+		proxyCfg := httpproxy.Config{
+			HTTPProxy:  cfg.HTTPProxy,
+			HTTPSProxy: cfg.HTTPSProxy,
+			NoProxy:    cfg.NoProxy,
+		}
+		if proxyCfg.HTTPProxy == "" {
+			proxyCfg.HTTPProxy = cfg.SOCKSProxy
+		}
+		if proxyCfg.HTTPSProxy == "" {
+			proxyCfg.HTTPSProxy = cfg.SOCKSProxy
+		}
+		proxyFunc := proxyCfg.ProxyFunc()
+		t.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+	}
+	insecure := slices.Contains(cfg.InsecureSkipVerifyProviders, provider)
+	rootCAs := n.rootCAs
+	if ptls != nil && ptls.caPEM != nil {
+		rootCAs = systemCertPool()
+		if n.rootCAs != nil {
+			rootCAs = n.rootCAs.Clone()
+		}
+		rootCAs.AppendCertsFromPEM(ptls.caPEM)
+	}
+	if rootCAs != nil || insecure || ptls != nil {
+		tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+		if t.TLSClientConfig != nil {
+			tlsCfg = t.TLSClientConfig.Clone()
+		}
+		tlsCfg.RootCAs = rootCAs
+		//nolint:gosec // Explicit per-provider opt-in from network settings.
+		tlsCfg.InsecureSkipVerify = insecure
+		if ptls != nil && ptls.clientCert != nil {
+			tlsCfg.Certificates = []tls.Certificate{*ptls.clientCert}
+		}
+		t.TLSClientConfig = tlsCfg
+	}
+
+	if n.transports == nil {
+		n.transports = map[inferenceSpec.ProviderName]*http.Transport{}
+	}
+	n.transports[provider] = t
+	return t
+}
+
+// systemCertPool returns a copy of the system roots, or an empty pool where
+// they cannot be loaded.
+func systemCertPool() *x509.CertPool {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		return x509.NewCertPool()
+	}
+	return pool
+}
+
+type providerTransport struct {
+	network  *providerNetwork
+	provider inferenceSpec.ProviderName
+}
+
+func (t *providerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r, resolveAuthKey := t.network.rewrite(t.provider); r != nil {
+		rewritten, err := r.apply(req, t.provider, resolveAuthKey)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", t.provider, err)
+		}
+		req = rewritten
+	}
+	resp, err := t.network.transport(t.provider).RoundTrip(req)
+	recordHTTPAttempt(req, resp)
+	return resp, err
+}
+
+// providerDebugger is the per-provider debugger handed to inference-go. It
+// routes the provider client through the network transport, below the shared
+// debug transport.
+type providerDebugger struct {
+	*debugclient.HTTPCompletionDebugger
+
+	transport http.RoundTripper
+}
+
+func (d *providerDebugger) HTTPClient(base *http.Client) *http.Client {
+	client := &http.Client{}
+	if base != nil {
+		client = new(*base)
+	}
+	client.Transport = d.transport
+	return d.HTTPCompletionDebugger.HTTPClient(client)
+}
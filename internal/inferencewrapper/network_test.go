@@ -0,0 +1,123 @@
+package inferencewrapper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+func TestHeaderPlaceholderResolver(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var asked []string
+	resolveAuthKey := func(_ context.Context, keyType, keyName string) (string, error) {
+		asked = append(asked, keyType+"/"+keyName)
+		if keyName == "missing" {
+			return "", errors.New("not found")
+		}
+		return "secret-" + keyName, nil
+	}
+	resolve := headerPlaceholderResolver(t.Context(), "gateway", now, resolveAuthKey)
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{name: "date", want: "Sun, 01 Mar 2026 12:00:00 GMT"},
+		{name: "timestamp", want: strconv.FormatInt(now.Unix(), 10)},
+		{name: "authKey:providerHeader/signing", want: "secret-signing"},
+		{name: "authKey:provider/gateway", want: "secret-gateway"},
+		{name: "authKey:provider/openai", wantErr: "may not be sent"},
+		{name: "authKey:mcp/github", wantErr: "may not be sent"},
+		{name: "authKey:providerHeader/missing", wantErr: "not found"},
+		{name: "bogus", wantErr: "unknown header placeholder"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolve(tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolve(%q) = %q, %v; want error containing %q", tt.name, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("resolve(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+			}
+		})
+	}
+	for _, ref := range asked {
+		if ref == "provider/openai" || ref == "mcp/github" {
+			t.Fatalf("refused key %s was read", ref)
+		}
+	}
+
+	first, _ := resolve("requestID")
+	second, _ := resolve("requestID")
+	if first == "" || first != second {
+		t.Fatalf("requestID not stable within a request: %q, %q", first, second)
+	}
+}
+
+func TestProviderTransport_ExpandsTemplatedHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	const provider inferenceSpec.ProviderName = "gateway"
+	n := &providerNetwork{
+		resolveAuthKey: func(_ context.Context, _, keyName string) (string, error) {
+			return "secret-" + keyName, nil
+		},
+	}
+	n.setRewrite(provider, &providerRequestRewrite{headers: map[string]string{
+		"X-Signature": "v1={{authKey:providerHeader/signing}}.{{timestamp}}",
+		"X-Request":   "{{requestID}}",
+	}})
+	client := &http.Client{Transport: &providerTransport{network: n, provider: provider}}
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if sig := got.Get("X-Signature"); !strings.HasPrefix(sig, "v1=secret-signing.") || strings.Contains(sig, "{{") {
+		t.Fatalf("X-Signature = %q", sig)
+	}
+	if got.Get("X-Request") == "" || strings.Contains(got.Get("X-Request"), "{{") {
+		t.Fatalf("X-Request = %q", got.Get("X-Request"))
+	}
+	if req.Header.Get("X-Signature") != "" {
+		t.Fatal("caller's request was modified")
+	}
+
+	n.setRewrite(provider, &providerRequestRewrite{headers: map[string]string{
+		"X-Key": "{{authKey:provider/openai}}",
+	}})
+	req, err = http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	if resp, err := client.Do(req); err == nil {
+		resp.Body.Close()
+		t.Fatal("request with another provider's key was sent")
+	}
+	if got != nil {
+		t.Fatal("server saw the refused request")
+	}
+}
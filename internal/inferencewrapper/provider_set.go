@@ -8,6 +8,9 @@ import (
 	"maps"
 	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/flexigpt/inference-go"
 	"github.com/flexigpt/inference-go/capabilityoverride"
@@ -20,16 +23,23 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/flexigpt/flexigpt-app/internal/inferencewrapper/spec"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
 	modelpresetStore "github.com/flexigpt/flexigpt-app/internal/modelpreset/store"
 	"github.com/flexigpt/flexigpt-app/internal/skillruntime"
 	skillruntimeSpec "github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
 	toolStore "github.com/flexigpt/flexigpt-app/internal/tool/store"
+	usageStore "github.com/flexigpt/flexigpt-app/internal/usage/store"
 )
 
+var logger = logging.Module("inference")
+
 const (
 	defaultFlushIntervalMillis = 32
 	defaultFlushChunkSize      = 512
+	// localProviderAPIKey stands in for the API key of local servers, which
+	// usually run without one; inference-go only initializes keyed providers.
+	localProviderAPIKey = "local"
 )
 
 // ProviderSetAPI is a thin aggregator on top of inference-go's ProviderSetAPI.
@@ -44,10 +54,19 @@ type ProviderSetAPI struct {
 	mpStore            *modelpresetStore.ModelPresetStore
 	skillRuntime       *skillruntime.SkillRuntime
 	mcpInferenceBridge *MCPInferenceBridge
+	usageStore         *usageStore.UsageStore
+	budgetGuard        *usageStore.BudgetGuard
+	rateLimiters       rateLimiters
+	completionCache    *completionCache
+	// localProviders holds the names of providers with a local SDK type.
+	localProviders sync.Map
+	// providers holds the names of all added providers.
+	providers sync.Map
 
 	logger             *slog.Logger
 	debugger           *debugclient.HTTPCompletionDebugger
 	initialDebugConfig *debugclient.DebugConfig
+	network            providerNetwork
 
 	skillsRunScriptEnabled bool
 }
@@ -77,6 +96,21 @@ func WithSkillsRunScriptEnabled(enabled bool) ProviderSetOption {
 	return func(ps *ProviderSetAPI) { ps.skillsRunScriptEnabled = enabled }
 }
 
+// WithUsageStore records the token usage and cost of every completion.
+func WithUsageStore(us *usageStore.UsageStore) ProviderSetOption {
+	return func(ps *ProviderSetAPI) { ps.usageStore = us }
+}
+
+// WithBudgetGuard checks provider spend limits before each completion.
+func WithBudgetGuard(g *usageStore.BudgetGuard) ProviderSetOption {
+	return func(ps *ProviderSetAPI) { ps.budgetGuard = g }
+}
+
+// WithAuthKeyResolver lets templated provider headers reference auth keys.
+func WithAuthKeyResolver(resolve AuthKeyResolver) ProviderSetOption {
+	return func(ps *ProviderSetAPI) { ps.network.resolveAuthKey = resolve }
+}
+
 // NewProviderSetAPI creates a new ProviderSetAPI wrapper.
 //
 //   - ts:   tool store used to hydrate ToolChoices when needed.
@@ -104,7 +138,7 @@ func NewProviderSetAPI(
 	}
 	allOpts := make([]inference.ProviderSetOption, 0, 2)
 	if ps.logger == nil {
-		ps.logger = slog.Default()
+		ps.logger = logger
 	}
 	allOpts = append(allOpts, inference.WithLogger(ps.logger))
 	// Always install a debugger so runtime debug config changes work even if debugging starts out disabled.
@@ -119,7 +153,10 @@ func NewProviderSetAPI(
 	ps.debugger = dbg
 	allOpts = append(allOpts,
 		inference.WithDebugClientBuilder(func(p inferenceSpec.ProviderParam) inferenceSpec.CompletionDebugger {
-			return dbg
+			return &providerDebugger{
+				HTTPCompletionDebugger: dbg,
+				transport:              &providerTransport{network: &ps.network, provider: p.Name},
+			}
 		}),
 	)
 
@@ -167,16 +204,50 @@ func (ps *ProviderSetAPI) AddProvider(
 		return nil, errors.New("invalid params")
 	}
 
+	staticHeaders, templatedHeaders := splitTemplatedHeaders(req.Body.DefaultHeaders)
 	cfg := &inference.AddProviderConfig{
-		SDKType:                  req.Body.SDKType,
+		SDKType:                  modelpresetSpec.WireSDKType(req.Body.SDKType, req.Body.ChatCompletionPathPrefix),
 		Origin:                   req.Body.Origin,
 		ChatCompletionPathPrefix: req.Body.ChatCompletionPathPrefix,
 		APIKeyHeaderKey:          req.Body.APIKeyHeaderKey,
-		DefaultHeaders:           req.Body.DefaultHeaders,
+		DefaultHeaders:           staticHeaders,
+	}
+	var rewrite *providerRequestRewrite
+	if req.Body.SDKType == modelpresetSpec.ProviderSDKTypeAzureOpenAI {
+		if req.Body.Azure == nil {
+			return nil, errors.New("azure config is required for azure openai providers")
+		}
+		cfg.ChatCompletionPathPrefix = req.Body.Azure.ChatCompletionPathPrefix()
+		rewrite = azureRequestRewrite(*req.Body.Azure, req.Body.APIKeyHeaderKey)
+	}
+	if templatedHeaders != nil {
+		if rewrite == nil {
+			rewrite = &providerRequestRewrite{}
+		}
+		rewrite.headers = templatedHeaders
+	}
+	var tlsMaterial *providerTLS
+	if req.Body.TLS != nil {
+		t, err := newProviderTLS(*req.Body.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s tls: %w", req.Provider, err)
+		}
+		tlsMaterial = t
 	}
 	if _, err := ps.inner.AddProvider(ctx, req.Provider, cfg); err != nil {
 		return nil, err
 	}
+	ps.providers.Store(req.Provider, struct{}{})
+	ps.network.setRewrite(req.Provider, rewrite)
+	ps.network.setTLS(req.Provider, tlsMaterial)
+	if modelpresetSpec.IsLocalSDKType(req.Body.SDKType) {
+		ps.localProviders.Store(req.Provider, struct{}{})
+		if err := ps.inner.SetProviderAPIKey(ctx, req.Provider, localProviderAPIKey); err != nil {
+			return nil, err
+		}
+	} else {
+		ps.localProviders.Delete(req.Provider)
+	}
 
 	return &spec.AddProviderResponse{}, nil
 }
@@ -192,10 +263,25 @@ func (ps *ProviderSetAPI) DeleteProvider(
 	if err := ps.inner.DeleteProvider(ctx, req.Provider); err != nil {
 		return nil, err
 	}
+	ps.providers.Delete(req.Provider)
+	ps.localProviders.Delete(req.Provider)
+	ps.network.setRewrite(req.Provider, nil)
+	ps.network.setTLS(req.Provider, nil)
 
 	return &spec.DeleteProviderResponse{}, nil
 }
 
+// ProviderNames returns the names of the added providers, sorted.
+func (ps *ProviderSetAPI) ProviderNames() []inferenceSpec.ProviderName {
+	var names []inferenceSpec.ProviderName
+	ps.providers.Range(func(k, _ any) bool {
+		names = append(names, k.(inferenceSpec.ProviderName))
+		return true
+	})
+	slices.Sort(names)
+	return names
+}
+
 // SetProviderAPIKey forwards to inference-go ProviderSetAPI.SetProviderAPIKey.
 func (ps *ProviderSetAPI) SetProviderAPIKey(
 	ctx context.Context,
@@ -204,7 +290,11 @@ func (ps *ProviderSetAPI) SetProviderAPIKey(
 	if req == nil || req.Body == nil {
 		return nil, errors.New("got empty provider input")
 	}
-	if err := ps.inner.SetProviderAPIKey(ctx, req.Provider, req.Body.APIKey); err != nil {
+	apiKey := req.Body.APIKey
+	if _, ok := ps.localProviders.Load(req.Provider); ok && apiKey == "" {
+		apiKey = localProviderAPIKey
+	}
+	if err := ps.inner.SetProviderAPIKey(ctx, req.Provider, apiKey); err != nil {
 		return nil, err
 	}
 	return &spec.SetProviderAPIKeyResponse{}, nil
@@ -212,9 +302,24 @@ func (ps *ProviderSetAPI) SetProviderAPIKey(
 
 // FetchCompletion builds a normalized inference-go FetchCompletionRequest from
 // app-level conversation types and calls inference-go's FetchCompletion.
+// Failed calls are retried and failed over per the provider's resilience
+// settings.
 func (ps *ProviderSetAPI) FetchCompletion(
 	ctx context.Context,
 	req *spec.CompletionRequest,
+) (*spec.CompletionResponse, error) {
+	var streamed atomic.Bool
+	primary := trackStreaming(req, &streamed)
+	resp, err := ps.fetchCompletion(ctx, primary)
+	if err == nil || streamed.Load() || ctx.Err() != nil {
+		return resp, err
+	}
+	return ps.failover(ctx, req, resp, err)
+}
+
+func (ps *ProviderSetAPI) fetchCompletion(
+	ctx context.Context,
+	req *spec.CompletionRequest,
 ) (*spec.CompletionResponse, error) {
 	if req == nil || req.Body == nil {
 		return nil, errors.New("got empty completion input")
@@ -238,6 +343,7 @@ func (ps *ProviderSetAPI) FetchCompletion(
 	if modelParam.Name == "" {
 		return nil, errors.New("model name is required")
 	}
+	modelParam.SystemPrompt = expandSystemPrompt(modelParam.SystemPrompt, req.Provider, modelParam.Name, time.Now())
 
 	if len(body.Current.ToolChoices) > 0 {
 		return nil, errors.New("prepopulated tool choices are not allowed in fetch completion, need tool store choices")
@@ -263,8 +369,12 @@ func (ps *ProviderSetAPI) FetchCompletion(
 		return nil, err
 	}
 
+	pp, err := ps.getProviderPreset(ctx, req.Provider, req.ModelPresetID)
+	if err != nil {
+		return nil, err
+	}
 	// Flatten full conversation (history + current) into InputUnion list.
-	inputs, currentInputs, err := ps.buildInputs(ctx, body)
+	inputs, currentInputs, err := ps.buildInputs(ctx, body, pp.ImageProcessing)
 	if err != nil {
 		return nil, err
 	}
@@ -409,7 +519,39 @@ func (ps *ProviderSetAPI) FetchCompletion(
 		}
 	}
 
-	b, err := ps.inner.FetchCompletion(ctx, req.Provider, infReq, opts)
+	cacheKey, cacheCfg := ps.completionCache.key(ctx, req.Provider, infReq)
+	if cacheKey != "" && !body.SkipCacheRead {
+		if cached := ps.completionCache.get(cacheKey, cacheCfg); cached != nil {
+			if opts.StreamHandler != nil {
+				if err := replayCachedCompletion(cached, opts.StreamHandler); err != nil {
+					return nil, err
+				}
+			}
+			return &spec.CompletionResponse{Body: &spec.CompletionResponseBody{
+				InferenceResponse:     cached,
+				HydratedCurrentInputs: currentInputs,
+				CacheHit:              true,
+			}}, nil
+		}
+	}
+
+	// A cache hit costs nothing, so only a provider call is held to the budget.
+	if ps.budgetGuard != nil && !body.OverrideBudget {
+		pricing, err := ps.presetPricing(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if err := ps.budgetGuard.CheckBudget(ctx, req.Provider, pricing); err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := ps.fetchWithRetry(ctx, req, infReq, opts)
+	if err == nil && b != nil && b.Error == nil && cacheKey != "" && !body.SkipCacheWrite {
+		if cerr := ps.completionCache.put(cacheKey, cacheCfg, req.Provider, b); cerr != nil {
+			ps.logger.Warn("completion cache write failed", "provider", req.Provider, "err", cerr)
+		}
+	}
 	if b != nil && mcpDebugDetails != nil {
 		b.DebugDetails = mergeCompletionDebugDetails(b.DebugDetails, "mcp", mcpDebugDetails)
 	}
@@ -475,7 +617,7 @@ func inferenceProviderPresetFromApp(pp modelpresetSpec.ProviderPreset) modelpres
 	return modelpreset.ProviderPreset{
 		Name:                     pp.Name,
 		DisplayName:              string(pp.DisplayName),
-		SDKType:                  pp.SDKType,
+		SDKType:                  modelpresetSpec.WireSDKType(pp.SDKType, pp.ChatCompletionPathPrefix),
 		Origin:                   pp.Origin,
 		ChatCompletionPathPrefix: pp.ChatCompletionPathPrefix,
 		APIKeyHeaderKey:          pp.APIKeyHeaderKey,
@@ -541,9 +683,11 @@ func (ps *ProviderSetAPI) resolveModelParam(
 // buildInputs flattens History + Current into a single InputUnion slice.
 // Attachments are always built from top level param and added to the union.
 // If the caller hydrates it then there is a possibility of duplicates.
+// Image attachments are prepared per the provider's imageProcessing.
 func (ps *ProviderSetAPI) buildInputs(
 	ctx context.Context,
 	body *spec.CompletionRequestBody,
+	imageProcessing *modelpresetSpec.ProviderImageProcessing,
 ) (all, current []inferenceSpec.InputUnion, err error) {
 	out := make([]inferenceSpec.InputUnion, 0)
 
@@ -575,7 +719,7 @@ func (ps *ProviderSetAPI) buildInputs(
 	}
 
 	// Always process attachments into content items.
-	msgContentItems, err := buildContentItemsFromAttachments(ctx, cur.Attachments)
+	msgContentItems, err := buildContentItemsFromAttachments(ctx, cur.Attachments, imageProcessing)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -618,6 +762,24 @@ func (ps *ProviderSetAPI) buildInputs(
 	return out, currentOut, nil
 }
 
+// expandSystemPrompt fills the model preset placeholders of the caller's
+// system prompt. It runs before skill and MCP prompt parts are appended so
+// their text is never rewritten.
+func expandSystemPrompt(
+	prompt string,
+	provider inferenceSpec.ProviderName,
+	modelName inferenceSpec.ModelName,
+	now time.Time,
+) string {
+	return modelpresetSpec.ExpandSystemPromptPlaceholders(prompt, map[string]string{
+		modelpresetSpec.SystemPromptPlaceholderDate:         now.Format(time.DateOnly),
+		modelpresetSpec.SystemPromptPlaceholderTime:         now.Format("15:04 MST"),
+		modelpresetSpec.SystemPromptPlaceholderWeekday:      now.Weekday().String(),
+		modelpresetSpec.SystemPromptPlaceholderModelName:    string(modelName),
+		modelpresetSpec.SystemPromptPlaceholderProviderName: string(provider),
+	})
+}
+
 func appendToSystemPrompt(base string, parts ...string) string {
 	base = strings.TrimSpace(base)
 	var out []string
@@ -0,0 +1,283 @@
+package inferencewrapper
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+
+	"github.com/flexigpt/flexigpt-app/internal/inferencewrapper/spec"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+)
+
+const (
+	rateLimitWindow      = time.Minute
+	defaultMaxQueueDepth = 32
+	// approxBytesPerToken converts the serialized request size into a token
+	// estimate until the provider reports actual usage.
+	approxBytesPerToken = 4
+)
+
+// rateLimiters holds one limiter per provider that has rate limits.
+type rateLimiters struct {
+	mu     sync.Mutex
+	byName map[inferenceSpec.ProviderName]*rateLimiter
+}
+
+func (r *rateLimiters) get(
+	name inferenceSpec.ProviderName,
+	limits modelpresetSpec.ProviderRateLimits,
+) *rateLimiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.byName == nil {
+		r.byName = map[inferenceSpec.ProviderName]*rateLimiter{}
+	}
+	l, ok := r.byName[name]
+	if !ok {
+		l = &rateLimiter{changed: make(chan struct{}), now: time.Now}
+		r.byName[name] = l
+	}
+	l.setLimits(limits)
+	return l
+}
+
+func (r *rateLimiters) has(name inferenceSpec.ProviderName) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.byName[name]
+	return ok
+}
+
+func (r *rateLimiters) status(name inferenceSpec.ProviderName) []spec.ProviderQueueStatus {
+	r.mu.Lock()
+	names := make([]inferenceSpec.ProviderName, 0, len(r.byName))
+	limiters := make(map[inferenceSpec.ProviderName]*rateLimiter, len(r.byName))
+	for n, l := range r.byName {
+		if name == "" || n == name {
+			names = append(names, n)
+			limiters[n] = l
+		}
+	}
+	r.mu.Unlock()
+
+	slices.Sort(names)
+	out := make([]spec.ProviderQueueStatus, 0, len(names))
+	for _, n := range names {
+		st := limiters[n].status()
+		st.Provider = n
+		out = append(out, st)
+	}
+	return out
+}
+
+type rateEvent struct {
+	at     time.Time
+	tokens int64
+}
+
+// rateLimiter admits requests in FIFO order while the last minute stays
+// within the configured requests and tokens per minute.
+type rateLimiter struct {
+	mu       sync.Mutex
+	limits   modelpresetSpec.ProviderRateLimits
+	events   []*rateEvent
+	queue    []*struct{}
+	inFlight int
+	// changed is closed and replaced whenever waiters should re-check.
+	changed chan struct{}
+	now     func() time.Time
+}
+
+func (l *rateLimiter) setLimits(limits modelpresetSpec.ProviderRateLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limits != limits {
+		l.limits = limits
+		l.broadcast()
+	}
+}
+
+// acquire waits for capacity for a request of about estTokens tokens. The
+// returned release must be called once the request is done, with the
+// reported usage, if any, replacing the estimate.
+func (l *rateLimiter) acquire(
+	ctx context.Context,
+	estTokens int64,
+) (release func(u *inferenceSpec.Usage), err error) {
+	l.mu.Lock()
+	maxDepth := l.limits.MaxQueueDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxQueueDepth
+	}
+	if !l.limits.IsZero() && len(l.queue) >= maxDepth {
+		l.mu.Unlock()
+		return nil, spec.ErrRateLimitQueueFull
+	}
+	ticket := &struct{}{}
+	l.queue = append(l.queue, ticket)
+
+	for {
+		now := l.now()
+		l.prune(now)
+		var timer *time.Timer
+		if l.queue[0] == ticket {
+			wait := l.waitFor(now, estTokens)
+			if wait <= 0 {
+				l.queue = l.queue[1:]
+				ev := &rateEvent{at: now, tokens: estTokens}
+				l.events = append(l.events, ev)
+				l.inFlight++
+				l.broadcast()
+				l.mu.Unlock()
+				return l.releaseFunc(ev), nil
+			}
+			timer = time.NewTimer(wait)
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		var timeout <-chan time.Time
+		if timer != nil {
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			l.mu.Lock()
+			if i := slices.Index(l.queue, ticket); i >= 0 {
+				l.queue = slices.Delete(l.queue, i, i+1)
+			}
+			l.broadcast()
+			l.mu.Unlock()
+			return nil, ctx.Err()
+		case <-changed:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		l.mu.Lock()
+	}
+}
+
+func (l *rateLimiter) releaseFunc(ev *rateEvent) func(u *inferenceSpec.Usage) {
+	var once sync.Once
+	return func(u *inferenceSpec.Usage) {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if u != nil {
+				ev.tokens = max(u.InputTokensTotal+u.OutputTokens, 0)
+			}
+			l.inFlight--
+			l.broadcast()
+		})
+	}
+}
+
+// waitFor returns how long the head of the queue must wait. A request is
+// always admitted into an empty window so oversized requests cannot starve.
+// Caller must hold mu.
+func (l *rateLimiter) waitFor(now time.Time, estTokens int64) time.Duration {
+	var wait time.Duration
+	if rpm := l.limits.RequestsPerMinute; rpm > 0 && len(l.events) >= rpm {
+		wait = l.events[len(l.events)-rpm].at.Add(rateLimitWindow).Sub(now)
+	}
+	if tpm := int64(l.limits.TokensPerMinute); tpm > 0 && len(l.events) > 0 {
+		var used int64
+		for _, ev := range l.events {
+			used += ev.tokens
+		}
+		// Expire events oldest first until the request fits.
+		for i := 0; used+estTokens > tpm && i < len(l.events); i++ {
+			used -= l.events[i].tokens
+			wait = max(wait, l.events[i].at.Add(rateLimitWindow).Sub(now))
+		}
+	}
+	return wait
+}
+
+// prune drops events older than the window. Caller must hold mu.
+func (l *rateLimiter) prune(now time.Time) {
+	cutoff := now.Add(-rateLimitWindow)
+	i := 0
+	for i < len(l.events) && !l.events[i].at.After(cutoff) {
+		i++
+	}
+	if i > 0 {
+		l.events = slices.Delete(l.events, 0, i)
+	}
+}
+
+// broadcast wakes all waiters. Caller must hold mu.
+func (l *rateLimiter) broadcast() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func (l *rateLimiter) status() spec.ProviderQueueStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(l.now())
+	st := spec.ProviderQueueStatus{
+		RateLimits:       l.limits,
+		QueueDepth:       len(l.queue),
+		InFlight:         l.inFlight,
+		RequestsInWindow: len(l.events),
+	}
+	for _, ev := range l.events {
+		st.TokensInWindow += ev.tokens
+	}
+	return st
+}
+
+// GetProviderQueueStatus reports the rate limit queues of providers that have
+// served at least one rate limited completion.
+func (ps *ProviderSetAPI) GetProviderQueueStatus(
+	ctx context.Context,
+	req *spec.GetProviderQueueStatusRequest,
+) (*spec.GetProviderQueueStatusResponse, error) {
+	var name inferenceSpec.ProviderName
+	if req != nil {
+		name = req.Provider
+	}
+	return &spec.GetProviderQueueStatusResponse{
+		Body: &spec.GetProviderQueueStatusResponseBody{Providers: ps.rateLimiters.status(name)},
+	}, nil
+}
+
+// acquireRateLimit waits for the provider's rate limits to admit the request.
+// Providers without limits are not queued and get a no-op release.
+func (ps *ProviderSetAPI) acquireRateLimit(
+	ctx context.Context,
+	provider inferenceSpec.ProviderName,
+	limits *modelpresetSpec.ProviderRateLimits,
+	estTokens int64,
+) (func(u *inferenceSpec.Usage), error) {
+	if limits == nil || limits.IsZero() {
+		// Keep an existing limiter so its queue drains and status stays
+		// visible after the limits are cleared.
+		if !ps.rateLimiters.has(provider) {
+			return func(*inferenceSpec.Usage) {}, nil
+		}
+		limits = &modelpresetSpec.ProviderRateLimits{}
+	}
+	return ps.rateLimiters.get(provider, *limits).acquire(ctx, estTokens)
+}
+
+// estimateRequestTokens approximates the prompt from its serialized size and
+// adds the output budget.
+func estimateRequestTokens(req *inferenceSpec.FetchCompletionRequest) int64 {
+	var est int64
+	if raw, err := json.Marshal(req.Inputs); err == nil {
+		est = int64(len(raw) / approxBytesPerToken)
+	}
+	est += int64(len(req.ModelParam.SystemPrompt) / approxBytesPerToken)
+	return est + int64(max(req.ModelParam.MaxOutputLength, 0))
+}
@@ -0,0 +1,315 @@
+package inferencewrapper
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+
+	"github.com/flexigpt/flexigpt-app/internal/inferencewrapper/spec"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+)
+
+const (
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+)
+
+var defaultRetryOnStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// httpAttempt collects the last provider HTTP response seen by the transport
+// during one completion attempt.
+type httpAttempt struct {
+	mu         sync.Mutex
+	status     int
+	retryAfter time.Duration
+}
+
+type httpAttemptKey struct{}
+
+func withHTTPAttempt(ctx context.Context) (context.Context, *httpAttempt) {
+	a := &httpAttempt{}
+	return context.WithValue(ctx, httpAttemptKey{}, a), a
+}
+
+// recordHTTPAttempt is called by the provider transport for every response.
+func recordHTTPAttempt(req *http.Request, resp *http.Response) {
+	a, ok := req.Context().Value(httpAttemptKey{}).(*httpAttempt)
+	if !ok || resp == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.status = resp.StatusCode
+	a.retryAfter = 0
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		a.retryAfter = time.Duration(secs) * time.Second
+	}
+}
+
+func (a *httpAttempt) get() (status int, retryAfter time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.status, a.retryAfter
+}
+
+// providerError is a failed completion attempt together with what the
+// transport saw. It reads as the underlying error.
+type providerError struct {
+	err     error
+	status  int
+	timeout bool
+}
+
+func (e *providerError) Error() string { return e.err.Error() }
+func (e *providerError) Unwrap() error { return e.err }
+
+func (e *providerError) failoverable() bool {
+	return e.timeout || e.status >= http.StatusInternalServerError
+}
+
+// fetchWithRetry sends the completion under the provider's rate limits and
+// retries it per the provider's retry policy. Nothing is retried once output
+// has been streamed to the caller.
+func (ps *ProviderSetAPI) fetchWithRetry(
+	ctx context.Context,
+	req *spec.CompletionRequest,
+	infReq *inferenceSpec.FetchCompletionRequest,
+	opts *inferenceSpec.FetchCompletionOptions,
+) (*inferenceSpec.FetchCompletionResponse, error) {
+	pp, err := ps.getProviderPreset(ctx, req.Provider, req.ModelPresetID)
+	if err != nil {
+		return nil, err
+	}
+	var policy modelpresetSpec.ProviderRetryPolicy
+	if pp.Resilience != nil && pp.Resilience.Retry != nil {
+		policy = *pp.Resilience.Retry
+	}
+	maxAttempts := max(policy.MaxAttempts, 1)
+	retryOn := policy.RetryOnStatusCodes
+	if len(retryOn) == 0 {
+		retryOn = defaultRetryOnStatusCodes
+	}
+
+	var streamed atomic.Bool
+	if opts.StreamHandler != nil {
+		handler := opts.StreamHandler
+		opts.StreamHandler = func(ev inferenceSpec.StreamEvent) error {
+			streamed.Store(true)
+			return handler(ev)
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		release, err := ps.acquireRateLimit(ctx, req.Provider, pp.RateLimits, estimateRequestTokens(infReq))
+		if err != nil {
+			return nil, err
+		}
+		attemptCtx, httpAttempt := withHTTPAttempt(ctx)
+		b, err := ps.inner.FetchCompletion(attemptCtx, req.Provider, infReq, opts)
+		if b != nil {
+			release(b.Usage)
+		} else {
+			release(nil)
+		}
+		if b != nil && b.Usage != nil {
+			ps.recordUsage(ctx, req, infReq.ModelParam.Name, b.Usage)
+		}
+		if err == nil || ctx.Err() != nil {
+			return b, err
+		}
+
+		status, retryAfter := httpAttempt.get()
+		perr := &providerError{err: err, status: status, timeout: isTimeoutError(err)}
+		retryable := perr.timeout || slices.Contains(retryOn, status)
+		if !retryable || attempt >= maxAttempts || streamed.Load() {
+			return b, perr
+		}
+
+		wait := max(retryBackoff(policy, attempt), retryAfter)
+		if maxWait := durationMS(policy.MaxBackoffMS, defaultMaxBackoff); wait > maxWait {
+			wait = maxWait
+		}
+		ps.logger.Warn("completion attempt failed; retrying",
+			"provider", req.Provider, "attempt", attempt, "status", status, "wait", wait, "err", err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return b, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// failover tries the provider's failover presets in order after the primary
+// failed with a 5xx status or a timeout. It returns the primary result when
+// no failover succeeds.
+func (ps *ProviderSetAPI) failover(
+	ctx context.Context,
+	req *spec.CompletionRequest,
+	primaryResp *spec.CompletionResponse,
+	primaryErr error,
+) (*spec.CompletionResponse, error) {
+	var perr *providerError
+	if !errors.As(primaryErr, &perr) || !perr.failoverable() {
+		return primaryResp, primaryErr
+	}
+	pp, err := ps.getProviderPreset(ctx, req.Provider, req.ModelPresetID)
+	if err != nil || pp.Resilience == nil || len(pp.Resilience.Failover) == 0 {
+		return primaryResp, primaryErr
+	}
+	baseParam, err := ps.resolveModelParam(req.Body)
+	if err != nil {
+		return primaryResp, primaryErr
+	}
+
+	for _, ref := range pp.Resilience.Failover {
+		presp, err := ps.mpStore.GetModelPreset(ctx, &modelpresetSpec.GetModelPresetRequest{
+			ProviderName:  ref.ProviderName,
+			ModelPresetID: ref.ModelPresetID,
+		})
+		if err != nil || presp == nil || presp.Body == nil {
+			ps.logger.Warn("failover preset unavailable; skipping",
+				"provider", ref.ProviderName, "modelPresetID", ref.ModelPresetID, "err", err)
+			continue
+		}
+
+		body := *req.Body
+		body.ModelParam = failoverModelParam(*baseParam, presp.Body.Model)
+		var streamed atomic.Bool
+		next := trackStreaming(&spec.CompletionRequest{
+			Provider:         ref.ProviderName,
+			ModelPresetID:    ref.ModelPresetID,
+			Body:             &body,
+			OnStreamText:     req.OnStreamText,
+			OnStreamThinking: req.OnStreamThinking,
+		}, &streamed)
+
+		ps.logger.Warn("failing over completion",
+			"from", req.Provider, "to", ref.ProviderName, "modelPresetID", ref.ModelPresetID, "err", primaryErr)
+		resp, err := ps.fetchCompletion(ctx, next)
+		if err == nil {
+			if resp != nil && resp.Body != nil {
+				servedBy := ref
+				resp.Body.ServedBy = &servedBy
+			}
+			return resp, nil
+		}
+		if streamed.Load() || ctx.Err() != nil {
+			return resp, err
+		}
+		ps.logger.Warn("failover completion failed",
+			"provider", ref.ProviderName, "modelPresetID", ref.ModelPresetID, "err", err)
+	}
+	return primaryResp, primaryErr
+}
+
+func (ps *ProviderSetAPI) getProviderPreset(
+	ctx context.Context,
+	provider inferenceSpec.ProviderName,
+	modelPresetID modelpresetSpec.ModelPresetID,
+) (modelpresetSpec.ProviderPreset, error) {
+	presp, err := ps.mpStore.GetModelPreset(ctx, &modelpresetSpec.GetModelPresetRequest{
+		ProviderName:    provider,
+		ModelPresetID:   modelPresetID,
+		IncludeDisabled: true,
+	})
+	if err != nil {
+		return modelpresetSpec.ProviderPreset{}, err
+	}
+	if presp == nil || presp.Body == nil {
+		return modelpresetSpec.ProviderPreset{}, errors.New("GetModelPreset: empty response")
+	}
+	return presp.Body.Provider, nil
+}
+
+// failoverModelParam keeps the caller's prompt settings and takes the model
+// and its model-specific knobs from the failover preset.
+func failoverModelParam(
+	base inferenceSpec.ModelParam,
+	mp modelpresetSpec.ModelPreset,
+) *inferenceSpec.ModelParam {
+	p := base
+	p.Name = inferenceSpec.ModelName(mp.Name)
+	p.Temperature = mp.Temperature
+	p.Reasoning = mp.Reasoning
+	p.CacheControl = mp.CacheControl
+	p.AdditionalParametersRawJSON = mp.AdditionalParametersRawJSON
+	if mp.MaxPromptLength != nil {
+		p.MaxPromptLength = *mp.MaxPromptLength
+	}
+	if mp.MaxOutputLength != nil {
+		p.MaxOutputLength = *mp.MaxOutputLength
+	}
+	if mp.Timeout != nil {
+		p.Timeout = *mp.Timeout
+	}
+	return &p
+}
+
+// trackStreaming returns a copy of req whose stream callbacks set streamed.
+func trackStreaming(req *spec.CompletionRequest, streamed *atomic.Bool) *spec.CompletionRequest {
+	if req == nil {
+		return nil
+	}
+	out := *req
+	if req.OnStreamText != nil {
+		out.OnStreamText = func(text string) error {
+			streamed.Store(true)
+			return req.OnStreamText(text)
+		}
+	}
+	if req.OnStreamThinking != nil {
+		out.OnStreamThinking = func(thinking string) error {
+			streamed.Store(true)
+			return req.OnStreamThinking(thinking)
+		}
+	}
+	return &out
+}
+
+// retryBackoff doubles the initial backoff per attempt, capped at the
+// maximum, and keeps a random half of it as jitter.
+func retryBackoff(policy modelpresetSpec.ProviderRetryPolicy, attempt int) time.Duration {
+	initial := durationMS(policy.InitialBackoffMS, defaultInitialBackoff)
+	maxBackoff := durationMS(policy.MaxBackoffMS, defaultMaxBackoff)
+	d := initial
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, maxBackoff)
+	half := d / 2
+	//nolint:gosec // Jitter does not need a secure source.
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
+
+func durationMS(ms int, fallback time.Duration) time.Duration {
+	if ms <= 0 {
+		return fallback
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -1,20 +1,39 @@
 package spec
 
 import (
+	"errors"
+
 	inferenceSpec "github.com/flexigpt/inference-go/spec"
 
 	conversationSpec "github.com/flexigpt/flexigpt-app/internal/conversation/spec"
 	mcpSpec "github.com/flexigpt/flexigpt-app/internal/mcp/spec"
 	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
 	toolSpec "github.com/flexigpt/flexigpt-app/internal/tool/spec"
+	usageSpec "github.com/flexigpt/flexigpt-app/internal/usage/spec"
 )
 
+// ErrRateLimitQueueFull is returned when a provider's rate limit queue is at
+// its maximum depth.
+var ErrRateLimitQueueFull = errors.New("provider rate limit queue is full")
+
 type AddProviderRequestBody struct {
 	SDKType                  inferenceSpec.ProviderSDKType `json:"sdkType"`
 	Origin                   string                        `json:"origin"`
 	ChatCompletionPathPrefix string                        `json:"chatCompletionPathPrefix"`
 	APIKeyHeaderKey          string                        `json:"apiKeyHeaderKey"`
 	DefaultHeaders           map[string]string             `json:"defaultHeaders"`
+	// Azure is required for Azure OpenAI providers. It determines the chat
+	// completion path and the api-version query parameter.
+	Azure *modelpresetSpec.AzureOpenAIConfig `json:"azure,omitempty"`
+	// TLS is the provider's private CA and client certificate, with the PEM
+	// secrets already read from the setting store.
+	TLS *ProviderTLS `json:"-"`
+}
+
+type ProviderTLS struct {
+	CABundlePath  string
+	ClientCertPEM string
+	ClientKeyPEM  string
 }
 
 type AddProviderRequest struct {
@@ -67,6 +86,17 @@ type CompletionRequestBody struct {
 
 	MCPContext     *mcpSpec.MCPConversationContext `json:"mcpContext,omitempty"`
 	SkillSessionID string                          `json:"skillSessionID,omitempty"`
+
+	// ConversationID attributes the recorded usage of this call. Optional.
+	ConversationID string `json:"conversationID,omitempty"`
+	// OverrideBudget skips the budget check, e.g. after the user confirmed
+	// an ErrBudgetExceeded or ErrBudgetUnpriced prompt.
+	OverrideBudget bool `json:"overrideBudget,omitempty"`
+
+	// SkipCacheRead always asks the provider; the fresh response still
+	// replaces the cached one unless SkipCacheWrite is set too.
+	SkipCacheRead  bool `json:"skipCacheRead,omitempty"`
+	SkipCacheWrite bool `json:"skipCacheWrite,omitempty"`
 }
 
 type CompletionRequest struct {
@@ -82,8 +112,64 @@ type CompletionRequest struct {
 type CompletionResponseBody struct {
 	InferenceResponse     *inferenceSpec.FetchCompletionResponse `json:"inferenceResponse,omitempty"`
 	HydratedCurrentInputs []inferenceSpec.InputUnion             `json:"hydratedCurrentInputs,omitempty"`
+	// ServedBy is set when a failover preset answered instead of the
+	// requested one.
+	ServedBy *modelpresetSpec.ModelPresetRef `json:"servedBy,omitempty"`
+	// CacheHit is set when the response came from the completion cache.
+	CacheHit bool `json:"cacheHit,omitempty"`
+	// BudgetExceeded or BudgetUnpriced is set when the budget check refused
+	// the completion. The request can be retried with OverrideBudget.
+	BudgetExceeded *usageSpec.BudgetExceededError `json:"budgetExceeded,omitempty"`
+	BudgetUnpriced *usageSpec.BudgetUnpricedError `json:"budgetUnpriced,omitempty"`
 }
 
 type CompletionResponse struct {
 	Body *CompletionResponseBody
 }
+
+type CompletionStreamEventKind string
+
+const (
+	CompletionStreamEventText     CompletionStreamEventKind = "text"
+	CompletionStreamEventThinking CompletionStreamEventKind = "thinking"
+	CompletionStreamEventDone     CompletionStreamEventKind = "done"
+	CompletionStreamEventError    CompletionStreamEventKind = "error"
+)
+
+// CompletionStreamEvent is one event of a streamed completion. Seq increases
+// per request; text and thinking events carry deltas and the final done or
+// error event carries the response, if any.
+type CompletionStreamEvent struct {
+	RequestID string                    `json:"requestID"`
+	Seq       int64                     `json:"seq"`
+	Kind      CompletionStreamEventKind `json:"kind"`
+	Text      string                    `json:"text,omitempty"`
+	Response  *CompletionResponseBody   `json:"response,omitempty"`
+	Error     string                    `json:"error,omitempty"`
+	Canceled  bool                      `json:"canceled,omitempty"`
+}
+
+// ProviderQueueStatus is the rate limiter state of one provider. Window counts
+// cover the last minute; tokens of in-flight requests are estimates.
+type ProviderQueueStatus struct {
+	Provider         inferenceSpec.ProviderName         `json:"provider"`
+	RateLimits       modelpresetSpec.ProviderRateLimits `json:"rateLimits"`
+	QueueDepth       int                                `json:"queueDepth"`
+	InFlight         int                                `json:"inFlight"`
+	RequestsInWindow int                                `json:"requestsInWindow"`
+	TokensInWindow   int64                              `json:"tokensInWindow"`
+}
+
+type GetProviderQueueStatusRequest struct {
+	// Provider limits the status to one provider. Empty returns all
+	// providers that have rate limits.
+	Provider inferenceSpec.ProviderName `query:"provider"`
+}
+
+type GetProviderQueueStatusResponseBody struct {
+	Providers []ProviderQueueStatus `json:"providers"`
+}
+
+type GetProviderQueueStatusResponse struct {
+	Body *GetProviderQueueStatusResponseBody
+}
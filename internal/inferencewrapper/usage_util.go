@@ -0,0 +1,73 @@
+package inferencewrapper
+
+import (
+	"context"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+
+	"github.com/flexigpt/flexigpt-app/internal/inferencewrapper/spec"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	usageSpec "github.com/flexigpt/flexigpt-app/internal/usage/spec"
+	usageStore "github.com/flexigpt/flexigpt-app/internal/usage/store"
+)
+
+// recordUsage stores the usage of a completion priced with the model preset
+// pricing. Failures are logged; they never fail the completion.
+func (ps *ProviderSetAPI) recordUsage(
+	ctx context.Context,
+	req *spec.CompletionRequest,
+	modelName inferenceSpec.ModelName,
+	u *inferenceSpec.Usage,
+) {
+	if ps.usageStore == nil || u == nil {
+		return
+	}
+	// The completion may have been canceled; the usage was still billed.
+	ctx = context.WithoutCancel(ctx)
+
+	pricing, err := ps.presetPricing(ctx, req)
+	if err != nil {
+		ps.logger.Warn("usage: model preset lookup failed; recording unpriced usage",
+			"provider", req.Provider, "modelPresetID", req.ModelPresetID, "err", err)
+	}
+	cost, priced := usageStore.ComputeCost(u, pricing)
+
+	if _, err := ps.usageStore.RecordUsage(ctx, &usageSpec.RecordUsageRequest{
+		Body: &usageSpec.UsageRecord{
+			ProviderName:        req.Provider,
+			ModelPresetID:       req.ModelPresetID,
+			ModelName:           modelName,
+			ConversationID:      req.Body.ConversationID,
+			InputTokensTotal:    u.InputTokensTotal,
+			InputTokensCached:   u.InputTokensCached,
+			InputTokensUncached: u.InputTokensUncached,
+			OutputTokens:        u.OutputTokens,
+			ReasoningTokens:     u.ReasoningTokens,
+			CostUSD:             cost,
+			Priced:              priced,
+		},
+	}); err != nil {
+		ps.logger.Error("usage: record failed", "provider", req.Provider, "err", err)
+	}
+}
+
+// presetPricing returns the pricing of the requested model preset, nil when
+// the preset has none.
+func (ps *ProviderSetAPI) presetPricing(
+	ctx context.Context,
+	req *spec.CompletionRequest,
+) (*modelpresetSpec.ModelPricing, error) {
+	presp, err := ps.mpStore.GetModelPreset(ctx, &modelpresetSpec.GetModelPresetRequest{
+		ProviderName:    req.Provider,
+		ModelPresetID:   req.ModelPresetID,
+		IncludeDisabled: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var pricing *modelpresetSpec.ModelPricing
+	if presp != nil && presp.Body != nil {
+		pricing = presp.Body.Model.Pricing
+	}
+	return pricing, nil
+}
@@ -0,0 +1,102 @@
+package spec
+
+import (
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+)
+
+type PostCollectionRequestBody struct {
+	DisplayName       string                            `json:"displayName"       required:"true"`
+	EmbeddingPresetID modelpresetSpec.EmbeddingPresetID `json:"embeddingPresetID" required:"true"`
+	Sources           []Source                          `json:"sources"           required:"true"`
+	// Zero values use DefaultChunkSize and DefaultChunkOverlap.
+	ChunkSize    int `json:"chunkSize,omitempty"`
+	ChunkOverlap int `json:"chunkOverlap,omitempty"`
+}
+
+type PostCollectionRequest struct {
+	Body *PostCollectionRequestBody
+}
+
+type PostCollectionResponse struct {
+	Body *Collection
+}
+
+// PatchCollectionRequestBody patches a collection. Nil fields are left
+// unchanged.
+type PatchCollectionRequestBody struct {
+	DisplayName       *string                            `json:"displayName,omitempty"`
+	EmbeddingPresetID *modelpresetSpec.EmbeddingPresetID `json:"embeddingPresetID,omitempty"`
+	Sources           *[]Source                          `json:"sources,omitempty"`
+	ChunkSize         *int                               `json:"chunkSize,omitempty"`
+	ChunkOverlap      *int                               `json:"chunkOverlap,omitempty"`
+}
+
+type PatchCollectionRequest struct {
+	CollectionID string `path:"collectionID" required:"true"`
+	Body         *PatchCollectionRequestBody
+}
+
+type PatchCollectionResponse struct {
+	Body *Collection
+}
+
+type DeleteCollectionRequest struct {
+	CollectionID string `path:"collectionID" required:"true"`
+}
+
+type DeleteCollectionResponse struct{}
+
+type GetCollectionRequest struct {
+	CollectionID string `path:"collectionID" required:"true"`
+}
+
+type GetCollectionResponse struct {
+	Body *Collection
+}
+
+type ListCollectionsRequest struct{}
+
+type ListCollectionsResponseBody struct {
+	Collections []Collection `json:"collections"`
+}
+
+type ListCollectionsResponse struct {
+	Body *ListCollectionsResponseBody
+}
+
+// IndexCollectionRequest reads, chunks and embeds every source of the
+// collection, replacing its previous index.
+type IndexCollectionRequest struct {
+	CollectionID string `path:"collectionID" required:"true"`
+}
+
+type IndexCollectionResponseBody struct {
+	Collection Collection        `json:"collection"`
+	Skipped    []SkippedDocument `json:"skipped,omitempty"`
+}
+
+type IndexCollectionResponse struct {
+	Body *IndexCollectionResponseBody
+}
+
+type QueryKnowledgeBaseRequestBody struct {
+	CollectionIDs []string `json:"collectionIDs" required:"true"`
+	Query         string   `json:"query"         required:"true"`
+	// Zero uses DefaultTopK.
+	TopK int `json:"topK,omitempty"`
+	// Chunks scoring below MinScore are dropped.
+	MinScore float64 `json:"minScore,omitempty"`
+}
+
+type QueryKnowledgeBaseRequest struct {
+	Body *QueryKnowledgeBaseRequestBody
+}
+
+type QueryKnowledgeBaseResponseBody struct {
+	// Chunks are ordered by descending score.
+	Chunks []KnowledgeChunk `json:"chunks"`
+}
+
+type QueryKnowledgeBaseResponse struct {
+	Body *QueryKnowledgeBaseResponseBody
+}
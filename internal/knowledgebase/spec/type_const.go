@@ -0,0 +1,84 @@
+package spec
+
+import (
+	"errors"
+	"time"
+
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+)
+
+const (
+	KnowledgeBaseDBFileName = "knowledgebase.sqlite"
+
+	MaxSourcesPerCollection = 64
+
+	DefaultChunkSize    = 1000
+	DefaultChunkOverlap = 200
+	MinChunkSize        = 200
+	MaxChunkSize        = 8000
+
+	DefaultTopK = 5
+	MaxTopK     = 50
+)
+
+var (
+	ErrInvalidDir           = errors.New("invalid directory")
+	ErrInvalidRequest       = errors.New("invalid request")
+	ErrCollectionNotFound   = errors.New("knowledge base collection not found")
+	ErrCollectionNotIndexed = errors.New("knowledge base collection is not indexed")
+)
+
+// SourceKind says how a collection source is read.
+type SourceKind string
+
+const (
+	// SourceKindFolder indexes the readable files under a local directory.
+	SourceKindFolder SourceKind = "folder"
+	SourceKindFile   SourceKind = "file"
+	// SourceKindURL indexes the text content of a web page.
+	SourceKindURL SourceKind = "url"
+)
+
+// Source is one location a collection reads documents from. Location is an
+// absolute path for folders and files, and an http(s) URL for pages.
+type Source struct {
+	Kind     SourceKind `json:"kind"`
+	Location string     `json:"location"`
+}
+
+// Collection is a set of document sources embedded with one embedding preset.
+//
+// ChunkSize and ChunkOverlap are in characters. Changing the sources, the
+// chunking or the embedding preset drops the index until the collection is
+// indexed again.
+type Collection struct {
+	ID                string                            `json:"id"`
+	DisplayName       string                            `json:"displayName"`
+	EmbeddingPresetID modelpresetSpec.EmbeddingPresetID `json:"embeddingPresetID"`
+	Sources           []Source                          `json:"sources"`
+	ChunkSize         int                               `json:"chunkSize"`
+	ChunkOverlap      int                               `json:"chunkOverlap"`
+	CreatedAt         time.Time                         `json:"createdAt"`
+	ModifiedAt        time.Time                         `json:"modifiedAt"`
+
+	IndexedAt     *time.Time `json:"indexedAt,omitempty"`
+	DocumentCount int        `json:"documentCount"`
+	ChunkCount    int        `json:"chunkCount"`
+	Dimensions    int        `json:"dimensions,omitempty"`
+}
+
+// SkippedDocument is a document that could not be read while indexing.
+type SkippedDocument struct {
+	Location string `json:"location"`
+	Reason   string `json:"reason"`
+}
+
+// KnowledgeChunk is a piece of an indexed document matching a query. Score is
+// the cosine similarity to the query.
+type KnowledgeChunk struct {
+	CollectionID string  `json:"collectionID"`
+	Location     string  `json:"location"`
+	ChunkIndex   int     `json:"chunkIndex"`
+	Text         string  `json:"text"`
+	Score        float64 `json:"score"`
+}
@@ -0,0 +1,299 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/flexigpt/llmtools-go/fstool"
+
+	"github.com/flexigpt/flexigpt-app/internal/attachment"
+	"github.com/flexigpt/flexigpt-app/internal/knowledgebase/spec"
+	"github.com/flexigpt/flexigpt-app/internal/llmtoolsutil"
+	"github.com/flexigpt/flexigpt-app/internal/middleware"
+)
+
+const (
+	// embedBatchSize keeps each embeddings request well below provider input
+	// and payload limits.
+	embedBatchSize         = 64
+	maxDocumentRunes       = 2 << 20
+	maxChunksPerCollection = 50000
+)
+
+var errNotText = errors.New("not a readable text document")
+
+type document struct {
+	location string
+	text     string
+}
+
+type pendingChunk struct {
+	location string
+	index    int
+	text     string
+}
+
+// IndexCollection reads, chunks and embeds the collection's sources and
+// replaces its index. Documents that cannot be read are reported as skipped;
+// an embedding failure leaves the previous index in place.
+func (s *KnowledgeBaseStore) IndexCollection(
+	ctx context.Context,
+	req *spec.IndexCollectionRequest,
+) (*spec.IndexCollectionResponse, error) {
+	if req == nil || req.CollectionID == "" {
+		return nil, fmt.Errorf("%w: collectionID required", spec.ErrInvalidRequest)
+	}
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+
+	c, err := s.getCollection(ctx, req.CollectionID)
+	if err != nil {
+		return nil, err
+	}
+	// Trust may have been revoked since the sources were saved.
+	if err := s.requireSourceTrust(ctx, c.Sources); err != nil {
+		return nil, err
+	}
+
+	progress := middleware.ProgressFromContext(ctx)
+	var (
+		docs    []document
+		skipped []spec.SkippedDocument
+	)
+	for i, src := range c.Sources {
+		progress.Report("reading "+src.Location, int64(i), int64(len(c.Sources)))
+		d, sk, err := loadSource(ctx, src)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			skipped = append(skipped, spec.SkippedDocument{Location: src.Location, Reason: err.Error()})
+			continue
+		}
+		docs = append(docs, d...)
+		skipped = append(skipped, sk...)
+	}
+
+	var chunks []pendingChunk
+	for _, d := range docs {
+		for i, text := range chunkText(d.text, c.ChunkSize, c.ChunkOverlap) {
+			chunks = append(chunks, pendingChunk{location: d.location, index: i, text: text})
+		}
+	}
+	if len(chunks) > maxChunksPerCollection {
+		return nil, fmt.Errorf("%w: collection yields %d chunks, at most %d are supported",
+			spec.ErrInvalidRequest, len(chunks), maxChunksPerCollection)
+	}
+
+	vectors := make([][]float32, 0, len(chunks))
+	for start := 0; start < len(chunks); start += embedBatchSize {
+		progress.Report("embedding chunks", int64(start), int64(len(chunks)))
+		batch := chunks[start:min(start+embedBatchSize, len(chunks))]
+		inputs := make([]string, len(batch))
+		for i, ch := range batch {
+			inputs[i] = ch.text
+		}
+		out, err := s.embed(ctx, c.EmbeddingPresetID, inputs)
+		if err != nil {
+			return nil, fmt.Errorf("embed chunks: %w", err)
+		}
+		if len(out) != len(batch) {
+			return nil, fmt.Errorf("embed chunks: got %d vectors for %d inputs", len(out), len(batch))
+		}
+		vectors = append(vectors, out...)
+	}
+	dims := 0
+	for i, v := range vectors {
+		if i == 0 {
+			dims = len(v)
+		}
+		if len(v) == 0 || len(v) != dims {
+			return nil, fmt.Errorf("embed chunks: vector %d has %d dimensions, want %d", i, len(v), dims)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM chunks WHERE collection_id = ?`, c.ID); err != nil {
+		return nil, fmt.Errorf("drop collection index: %w", err)
+	}
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO chunks (collection_id, location, chunk_index, content, embedding) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	for i, ch := range chunks {
+		if _, err := stmt.ExecContext(ctx, c.ID, ch.location, ch.index, ch.text,
+			encodeVector(normalize(vectors[i]))); err != nil {
+			return nil, fmt.Errorf("insert chunk: %w", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	c.IndexedAt = &now
+	c.DocumentCount = len(docs)
+	c.ChunkCount = len(chunks)
+	c.Dimensions = dims
+	if _, err := tx.ExecContext(ctx, `UPDATE collections SET indexed_at_ns = ?, document_count = ?,
+		chunk_count = ?, dimensions = ? WHERE id = ?`,
+		now.UnixNano(), c.DocumentCount, c.ChunkCount, c.Dimensions, c.ID,
+	); err != nil {
+		return nil, fmt.Errorf("update collection: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	logger.Info("indexCollection", "collectionID", c.ID, "documents", c.DocumentCount,
+		"chunks", c.ChunkCount, "skipped", len(skipped))
+	return &spec.IndexCollectionResponse{
+		Body: &spec.IndexCollectionResponseBody{Collection: *c, Skipped: skipped},
+	}, nil
+}
+
+// loadSource reads the documents of a source. Unreadable files inside a
+// folder are returned as skipped rather than failing the source.
+func loadSource(ctx context.Context, src spec.Source) ([]document, []spec.SkippedDocument, error) {
+	loc := strings.TrimSpace(src.Location)
+	switch src.Kind {
+	case spec.SourceKindFile:
+		info, err := llmtoolsutil.StatPath(ctx, fstool.StatPathArgs{Path: loc})
+		if err != nil {
+			return nil, nil, err
+		}
+		if !info.Exists || info.IsDir {
+			return nil, nil, fmt.Errorf("%s is not a file", loc)
+		}
+		text, err := loadFile(ctx, attachment.PathInfo{
+			Path:    info.Path,
+			Name:    info.Name,
+			Exists:  info.Exists,
+			Size:    info.SizeBytes,
+			ModTime: info.ModTime,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return []document{{location: info.Path, text: text}}, nil, nil
+
+	case spec.SourceKindFolder:
+		walk, err := attachment.WalkDirectoryWithFiles(ctx, loc, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		var (
+			docs    []document
+			skipped []spec.SkippedDocument
+		)
+		for _, pi := range walk.Files {
+			text, err := loadFile(ctx, pi)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, nil, ctx.Err()
+				}
+				skipped = append(skipped, spec.SkippedDocument{Location: pi.Path, Reason: err.Error()})
+				continue
+			}
+			docs = append(docs, document{location: pi.Path, text: text})
+		}
+		if walk.HasMore {
+			skipped = append(skipped, spec.SkippedDocument{
+				Location: loc,
+				Reason:   fmt.Sprintf("only the first %d files were indexed", walk.MaxFiles),
+			})
+		}
+		return docs, skipped, nil
+
+	case spec.SourceKindURL:
+		att, err := attachment.BuildAttachmentForURLWithContext(ctx, loc)
+		if err != nil {
+			return nil, nil, err
+		}
+		text, err := attachmentText(ctx, att)
+		if err != nil {
+			return nil, nil, err
+		}
+		// A page that cannot be fetched comes back as a bare link.
+		if strings.TrimSpace(text) == loc {
+			return nil, nil, errors.New("page content could not be fetched")
+		}
+		return []document{{location: loc, text: text}}, nil, nil
+	}
+	return nil, nil, fmt.Errorf("unknown source kind %q", src.Kind)
+}
+
+func loadFile(ctx context.Context, pi attachment.PathInfo) (string, error) {
+	att, err := attachment.BuildAttachmentForFile(ctx, &pi)
+	if err != nil {
+		return "", err
+	}
+	if att.Mode == attachment.AttachmentContentBlockModeNotReadable {
+		return "", errNotText
+	}
+	return attachmentText(ctx, att)
+}
+
+func attachmentText(ctx context.Context, att *attachment.Attachment) (string, error) {
+	cb, err := att.BuildContentBlock(ctx, attachment.WithOnlyTextKindContentBlock(true))
+	if err != nil {
+		if errors.Is(err, attachment.ErrNonTextContentBlock) {
+			return "", errNotText
+		}
+		return "", err
+	}
+	if cb == nil || cb.Kind != attachment.ContentBlockText || cb.Text == nil {
+		return "", errNotText
+	}
+	text := *cb.Text
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("document is empty")
+	}
+	if len([]rune(text)) > maxDocumentRunes {
+		return "", fmt.Errorf("document is longer than %d characters", maxDocumentRunes)
+	}
+	return text, nil
+}
+
+// chunkText splits text into pieces of at most size characters, each
+// starting overlap characters before the end of the previous one. Pieces end
+// at a line break or space in their second half when there is one.
+func chunkText(text string, size, overlap int) []string {
+	runes := []rune(text)
+	var out []string
+	for start := 0; start < len(runes); {
+		end := min(start+size, len(runes))
+		if end < len(runes) {
+			end = chunkCutPoint(runes, start, end)
+		}
+		if piece := strings.TrimSpace(string(runes[start:end])); piece != "" {
+			out = append(out, piece)
+		}
+		if end >= len(runes) {
+			break
+		}
+		start = max(end-overlap, start+1)
+	}
+	return out
+}
+
+func chunkCutPoint(runes []rune, start, end int) int {
+	half := start + (end-start)/2
+	for i := end - 1; i >= half; i-- {
+		if runes[i] == '\n' {
+			return i + 1
+		}
+	}
+	for i := end - 1; i >= half; i-- {
+		if unicode.IsSpace(runes[i]) {
+			return i + 1
+		}
+	}
+	return end
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/flexigpt/flexigpt-app/internal/knowledgebase/spec"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+)
+
+type scoredChunk struct {
+	collectionID string
+	rowID        int64
+	score        float64
+}
+
+// QueryKnowledgeBase returns the chunks of the given collections closest to
+// the query. The query is embedded once per embedding preset in use.
+func (s *KnowledgeBaseStore) QueryKnowledgeBase(
+	ctx context.Context,
+	req *spec.QueryKnowledgeBaseRequest,
+) (*spec.QueryKnowledgeBaseResponse, error) {
+	if req == nil || req.Body == nil {
+		return nil, fmt.Errorf("%w: body required", spec.ErrInvalidRequest)
+	}
+	query := strings.TrimSpace(req.Body.Query)
+	if query == "" || len(req.Body.CollectionIDs) == 0 {
+		return nil, fmt.Errorf("%w: query and collectionIDs required", spec.ErrInvalidRequest)
+	}
+	topK := req.Body.TopK
+	if topK <= 0 {
+		topK = spec.DefaultTopK
+	}
+	if topK > spec.MaxTopK {
+		return nil, fmt.Errorf("%w: topK must be at most %d", spec.ErrInvalidRequest, spec.MaxTopK)
+	}
+
+	queryVectors := map[modelpresetSpec.EmbeddingPresetID][]float32{}
+	var scored []scoredChunk
+	for _, id := range slices.Compact(slices.Sorted(slices.Values(req.Body.CollectionIDs))) {
+		c, err := s.getCollection(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if c.IndexedAt == nil {
+			return nil, fmt.Errorf("%w: %s", spec.ErrCollectionNotIndexed, id)
+		}
+		qv, ok := queryVectors[c.EmbeddingPresetID]
+		if !ok {
+			out, err := s.embed(ctx, c.EmbeddingPresetID, []string{query})
+			if err != nil {
+				return nil, fmt.Errorf("embed query: %w", err)
+			}
+			if len(out) != 1 || len(out[0]) == 0 {
+				return nil, fmt.Errorf("embed query: got %d vectors", len(out))
+			}
+			qv = normalize(out[0])
+			queryVectors[c.EmbeddingPresetID] = qv
+		}
+		if c.Dimensions != 0 && len(qv) != c.Dimensions {
+			return nil, fmt.Errorf("%w: collection %s has %d dimensions but the query has %d; index it again",
+				spec.ErrCollectionNotIndexed, id, c.Dimensions, len(qv))
+		}
+		hits, err := s.scoreCollection(ctx, id, qv, req.Body.MinScore)
+		if err != nil {
+			return nil, err
+		}
+		scored = append(scored, hits...)
+		scored = topScored(scored, topK)
+	}
+
+	chunks := make([]spec.KnowledgeChunk, 0, len(scored))
+	for _, sc := range scored {
+		ch := spec.KnowledgeChunk{CollectionID: sc.collectionID, Score: sc.score}
+		if err := s.db.QueryRowContext(ctx,
+			`SELECT location, chunk_index, content FROM chunks WHERE rowid = ?`, sc.rowID,
+		).Scan(&ch.Location, &ch.ChunkIndex, &ch.Text); err != nil {
+			return nil, fmt.Errorf("read chunk: %w", err)
+		}
+		chunks = append(chunks, ch)
+	}
+	return &spec.QueryKnowledgeBaseResponse{
+		Body: &spec.QueryKnowledgeBaseResponseBody{Chunks: chunks},
+	}, nil
+}
+
+// scoreCollection scans the embeddings of a collection. Vectors are stored
+// normalized, so the dot product is the cosine similarity.
+func (s *KnowledgeBaseStore) scoreCollection(
+	ctx context.Context,
+	collectionID string,
+	qv []float32,
+	minScore float64,
+) ([]scoredChunk, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT rowid, embedding FROM chunks WHERE collection_id = ?`, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("query chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var out []scoredChunk
+	for rows.Next() {
+		var (
+			rowID int64
+			blob  []byte
+		)
+		if err := rows.Scan(&rowID, &blob); err != nil {
+			return nil, err
+		}
+		v := decodeVector(blob)
+		if len(v) != len(qv) {
+			continue
+		}
+		var dot float64
+		for i := range v {
+			dot += float64(v[i]) * float64(qv[i])
+		}
+		if dot >= minScore {
+			out = append(out, scoredChunk{collectionID: collectionID, rowID: rowID, score: dot})
+		}
+	}
+	return out, rows.Err()
+}
+
+func topScored(in []scoredChunk, k int) []scoredChunk {
+	slices.SortStableFunc(in, func(a, b scoredChunk) int { return cmp.Compare(b.score, a.score) })
+	return in[:min(k, len(in))]
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := math.Sqrt(sum)
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 0, 4*len(v))
+	for _, x := range v {
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(x))
+	}
+	return buf
+}
+
+func decodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
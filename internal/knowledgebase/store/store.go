@@ -0,0 +1,453 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/flexigpt/flexigpt-app/internal/knowledgebase/spec"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+
+	_ "github.com/glebarez/go-sqlite"
+)
+
+var logger = logging.Module("knowledgebase.store")
+
+const createSchemaSQL = `
+CREATE TABLE IF NOT EXISTS collections (
+	id                  TEXT PRIMARY KEY,
+	display_name        TEXT NOT NULL,
+	embedding_preset_id TEXT NOT NULL,
+	sources             TEXT NOT NULL,
+	chunk_size          INTEGER NOT NULL,
+	chunk_overlap       INTEGER NOT NULL,
+	created_at_ns       INTEGER NOT NULL,
+	modified_at_ns      INTEGER NOT NULL,
+	indexed_at_ns       INTEGER NOT NULL DEFAULT 0,
+	document_count      INTEGER NOT NULL DEFAULT 0,
+	chunk_count         INTEGER NOT NULL DEFAULT 0,
+	dimensions          INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS chunks (
+	collection_id TEXT NOT NULL,
+	location      TEXT NOT NULL,
+	chunk_index   INTEGER NOT NULL,
+	content       TEXT NOT NULL,
+	embedding     BLOB NOT NULL,
+	PRIMARY KEY (collection_id, location, chunk_index)
+);
+`
+
+const collectionColumns = `id, display_name, embedding_preset_id, sources, chunk_size, chunk_overlap,
+	created_at_ns, modified_at_ns, indexed_at_ns, document_count, chunk_count, dimensions`
+
+// Embedder returns one vector per input, in input order, using the given
+// embedding preset.
+type Embedder func(
+	ctx context.Context,
+	presetID modelpresetSpec.EmbeddingPresetID,
+	inputs []string,
+) ([][]float32, error)
+
+// PathTrust returns an error unless the user trusts path. File and folder
+// sources are read and sent to the embedding provider, so they are held to
+// the workspace trust model.
+type PathTrust func(ctx context.Context, path string) error
+
+// KnowledgeBaseStore keeps document collections and their chunk embeddings in
+// SQLite. Queries score every chunk of the selected collections, which is
+// fast enough for the personal-scale collections it is meant for.
+type KnowledgeBaseStore struct {
+	db    *sql.DB
+	embed Embedder
+	trust PathTrust
+
+	// indexMu serializes indexing so two runs never interleave their writes.
+	indexMu sync.Mutex
+}
+
+type Option func(*KnowledgeBaseStore) error
+
+// WithPathTrust checks every file and folder source when a collection is
+// created or patched and again before it is indexed.
+func WithPathTrust(trust PathTrust) Option {
+	return func(s *KnowledgeBaseStore) error {
+		s.trust = trust
+		return nil
+	}
+}
+
+func NewKnowledgeBaseStore(
+	ctx context.Context,
+	baseDir string,
+	embed Embedder,
+	opts ...Option,
+) (*KnowledgeBaseStore, error) {
+	if strings.TrimSpace(baseDir) == "" {
+		return nil, fmt.Errorf("%w: baseDir", spec.ErrInvalidDir)
+	}
+	if embed == nil {
+		return nil, errors.New("knowledge base: embedder required")
+	}
+	if err := os.MkdirAll(baseDir, 0o770); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", dataSourceName(filepath.Join(baseDir, spec.KnowledgeBaseDBFileName)))
+	if err != nil {
+		return nil, fmt.Errorf("open knowledge base database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("ping knowledge base database: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, createSchemaSQL); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("initialize knowledge base schema: %w", err)
+	}
+	s := &KnowledgeBaseStore{db: db, embed: embed}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *KnowledgeBaseStore) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// PostCollection registers a collection. It is not searchable until indexed.
+func (s *KnowledgeBaseStore) PostCollection(
+	ctx context.Context,
+	req *spec.PostCollectionRequest,
+) (*spec.PostCollectionResponse, error) {
+	if req == nil || req.Body == nil {
+		return nil, fmt.Errorf("%w: body required", spec.ErrInvalidRequest)
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	c := spec.Collection{
+		ID:                id.String(),
+		DisplayName:       strings.TrimSpace(req.Body.DisplayName),
+		EmbeddingPresetID: req.Body.EmbeddingPresetID,
+		Sources:           req.Body.Sources,
+		ChunkSize:         req.Body.ChunkSize,
+		ChunkOverlap:      req.Body.ChunkOverlap,
+		CreatedAt:         now,
+		ModifiedAt:        now,
+	}
+	if c.ChunkSize == 0 {
+		c.ChunkSize = spec.DefaultChunkSize
+	}
+	if c.ChunkOverlap == 0 {
+		c.ChunkOverlap = min(spec.DefaultChunkOverlap, c.ChunkSize/2)
+	}
+	if err := validateCollection(&c); err != nil {
+		return nil, err
+	}
+	if err := s.requireSourceTrust(ctx, c.Sources); err != nil {
+		return nil, err
+	}
+	sources, err := json.Marshal(c.Sources)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO collections (`+collectionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, 0)`,
+		c.ID, c.DisplayName, string(c.EmbeddingPresetID), string(sources), c.ChunkSize, c.ChunkOverlap,
+		c.CreatedAt.UnixNano(), c.ModifiedAt.UnixNano(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert collection: %w", err)
+	}
+	return &spec.PostCollectionResponse{Body: &c}, nil
+}
+
+// PatchCollection updates a collection. Any change other than the display
+// name drops the index.
+func (s *KnowledgeBaseStore) PatchCollection(
+	ctx context.Context,
+	req *spec.PatchCollectionRequest,
+) (*spec.PatchCollectionResponse, error) {
+	if req == nil || req.Body == nil || req.CollectionID == "" {
+		return nil, fmt.Errorf("%w: collectionID and body required", spec.ErrInvalidRequest)
+	}
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+
+	c, err := s.getCollection(ctx, req.CollectionID)
+	if err != nil {
+		return nil, err
+	}
+	b := req.Body
+	stale := false
+	if b.DisplayName != nil {
+		c.DisplayName = strings.TrimSpace(*b.DisplayName)
+	}
+	if b.EmbeddingPresetID != nil && *b.EmbeddingPresetID != c.EmbeddingPresetID {
+		c.EmbeddingPresetID = *b.EmbeddingPresetID
+		stale = true
+	}
+	if b.Sources != nil {
+		c.Sources = *b.Sources
+		stale = true
+	}
+	if b.ChunkSize != nil && *b.ChunkSize != c.ChunkSize {
+		c.ChunkSize = *b.ChunkSize
+		stale = true
+	}
+	if b.ChunkOverlap != nil && *b.ChunkOverlap != c.ChunkOverlap {
+		c.ChunkOverlap = *b.ChunkOverlap
+		stale = true
+	}
+	if err := validateCollection(c); err != nil {
+		return nil, err
+	}
+	if b.Sources != nil {
+		if err := s.requireSourceTrust(ctx, c.Sources); err != nil {
+			return nil, err
+		}
+	}
+	sources, err := json.Marshal(c.Sources)
+	if err != nil {
+		return nil, err
+	}
+	c.ModifiedAt = time.Now().UTC()
+	if stale {
+		c.IndexedAt = nil
+		c.DocumentCount, c.ChunkCount, c.Dimensions = 0, 0, 0
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, `UPDATE collections SET display_name = ?, embedding_preset_id = ?,
+		sources = ?, chunk_size = ?, chunk_overlap = ?, modified_at_ns = ?, indexed_at_ns = ?,
+		document_count = ?, chunk_count = ?, dimensions = ? WHERE id = ?`,
+		c.DisplayName, string(c.EmbeddingPresetID), string(sources), c.ChunkSize, c.ChunkOverlap,
+		c.ModifiedAt.UnixNano(), unixNanoOrZero(c.IndexedAt), c.DocumentCount, c.ChunkCount, c.Dimensions,
+		c.ID,
+	); err != nil {
+		return nil, fmt.Errorf("update collection: %w", err)
+	}
+	if stale {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM chunks WHERE collection_id = ?`, c.ID); err != nil {
+			return nil, fmt.Errorf("drop collection index: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &spec.PatchCollectionResponse{Body: c}, nil
+}
+
+// DeleteCollection removes a collection and its index.
+func (s *KnowledgeBaseStore) DeleteCollection(
+	ctx context.Context,
+	req *spec.DeleteCollectionRequest,
+) (*spec.DeleteCollectionResponse, error) {
+	if req == nil || req.CollectionID == "" {
+		return nil, fmt.Errorf("%w: collectionID required", spec.ErrInvalidRequest)
+	}
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, err := tx.ExecContext(ctx, `DELETE FROM collections WHERE id = ?`, req.CollectionID)
+	if err != nil {
+		return nil, fmt.Errorf("delete collection: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, fmt.Errorf("%w: %s", spec.ErrCollectionNotFound, req.CollectionID)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM chunks WHERE collection_id = ?`, req.CollectionID); err != nil {
+		return nil, fmt.Errorf("delete collection index: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &spec.DeleteCollectionResponse{}, nil
+}
+
+func (s *KnowledgeBaseStore) GetCollection(
+	ctx context.Context,
+	req *spec.GetCollectionRequest,
+) (*spec.GetCollectionResponse, error) {
+	if req == nil || req.CollectionID == "" {
+		return nil, fmt.Errorf("%w: collectionID required", spec.ErrInvalidRequest)
+	}
+	c, err := s.getCollection(ctx, req.CollectionID)
+	if err != nil {
+		return nil, err
+	}
+	return &spec.GetCollectionResponse{Body: c}, nil
+}
+
+// ListCollections returns all collections sorted by display name.
+func (s *KnowledgeBaseStore) ListCollections(
+	ctx context.Context,
+	_ *spec.ListCollectionsRequest,
+) (*spec.ListCollectionsResponse, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+collectionColumns+` FROM collections ORDER BY display_name COLLATE NOCASE, id`)
+	if err != nil {
+		return nil, fmt.Errorf("query collections: %w", err)
+	}
+	defer rows.Close()
+
+	out := []spec.Collection{}
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &spec.ListCollectionsResponse{Body: &spec.ListCollectionsResponseBody{Collections: out}}, nil
+}
+
+func (s *KnowledgeBaseStore) getCollection(ctx context.Context, id string) (*spec.Collection, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+collectionColumns+` FROM collections WHERE id = ?`, id)
+	c, err := scanCollection(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", spec.ErrCollectionNotFound, id)
+	}
+	return c, err
+}
+
+func scanCollection(row interface{ Scan(dest ...any) error }) (*spec.Collection, error) {
+	var (
+		c                            spec.Collection
+		presetID, sources            string
+		createdNS, modifiedNS, idxNS int64
+	)
+	if err := row.Scan(&c.ID, &c.DisplayName, &presetID, &sources, &c.ChunkSize, &c.ChunkOverlap,
+		&createdNS, &modifiedNS, &idxNS, &c.DocumentCount, &c.ChunkCount, &c.Dimensions); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(sources), &c.Sources); err != nil {
+		return nil, fmt.Errorf("collection %s: invalid sources: %w", c.ID, err)
+	}
+	c.EmbeddingPresetID = modelpresetSpec.EmbeddingPresetID(presetID)
+	c.CreatedAt = time.Unix(0, createdNS).UTC()
+	c.ModifiedAt = time.Unix(0, modifiedNS).UTC()
+	if idxNS != 0 {
+		t := time.Unix(0, idxNS).UTC()
+		c.IndexedAt = &t
+	}
+	return &c, nil
+}
+
+func validateCollection(c *spec.Collection) error {
+	if c.DisplayName == "" {
+		return fmt.Errorf("%w: displayName is empty", spec.ErrInvalidRequest)
+	}
+	if c.EmbeddingPresetID == "" {
+		return fmt.Errorf("%w: embeddingPresetID is empty", spec.ErrInvalidRequest)
+	}
+	if len(c.Sources) == 0 || len(c.Sources) > spec.MaxSourcesPerCollection {
+		return fmt.Errorf("%w: between 1 and %d sources required", spec.ErrInvalidRequest,
+			spec.MaxSourcesPerCollection)
+	}
+	for i, src := range c.Sources {
+		if err := validateSource(src); err != nil {
+			return fmt.Errorf("%w: sources[%d]: %w", spec.ErrInvalidRequest, i, err)
+		}
+	}
+	if c.ChunkSize < spec.MinChunkSize || c.ChunkSize > spec.MaxChunkSize {
+		return fmt.Errorf("%w: chunkSize must be between %d and %d", spec.ErrInvalidRequest,
+			spec.MinChunkSize, spec.MaxChunkSize)
+	}
+	if c.ChunkOverlap < 0 || c.ChunkOverlap > c.ChunkSize/2 {
+		return fmt.Errorf("%w: chunkOverlap must be between 0 and half the chunkSize", spec.ErrInvalidRequest)
+	}
+	return nil
+}
+
+// requireSourceTrust rejects file and folder sources outside the trusted
+// directories unless the user confirmed them.
+func (s *KnowledgeBaseStore) requireSourceTrust(ctx context.Context, sources []spec.Source) error {
+	if s.trust == nil {
+		return nil
+	}
+	for i, src := range sources {
+		if src.Kind != spec.SourceKindFolder && src.Kind != spec.SourceKindFile {
+			continue
+		}
+		if err := s.trust(ctx, filepath.Clean(strings.TrimSpace(src.Location))); err != nil {
+			return fmt.Errorf("sources[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateSource(src spec.Source) error {
+	loc := strings.TrimSpace(src.Location)
+	if loc == "" {
+		return errors.New("location is empty")
+	}
+	switch src.Kind {
+	case spec.SourceKindFolder, spec.SourceKindFile:
+		if !filepath.IsAbs(loc) {
+			return fmt.Errorf("%s path %q is not absolute", src.Kind, loc)
+		}
+	case spec.SourceKindURL:
+		u, err := url.Parse(loc)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url %q is not an absolute http(s) URL", loc)
+		}
+	default:
+		return fmt.Errorf("unknown kind %q", src.Kind)
+	}
+	return nil
+}
+
+func unixNanoOrZero(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func dataSourceName(path string) string {
+	normalized := filepath.ToSlash(filepath.Clean(path))
+	if filepath.VolumeName(path) != "" && !strings.HasPrefix(normalized, "/") {
+		normalized = "/" + normalized
+	}
+	value := &url.URL{Scheme: "file", Path: normalized}
+	query := value.Query()
+	query.Set("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "busy_timeout(5000)")
+	value.RawQuery = query.Encode()
+	return value.String()
+}
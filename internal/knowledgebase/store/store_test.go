@@ -0,0 +1,240 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/flexigpt-app/internal/knowledgebase/spec"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+)
+
+// wordEmbedder hashes words into a small vector so texts sharing words score
+// higher.
+func wordEmbedder() Embedder {
+	return func(_ context.Context, _ modelpresetSpec.EmbeddingPresetID, inputs []string) ([][]float32, error) {
+		out := make([][]float32, len(inputs))
+		for i, in := range inputs {
+			v := make([]float32, 32)
+			for w := range strings.FieldsSeq(strings.ToLower(in)) {
+				h := fnv.New32a()
+				_, _ = h.Write([]byte(strings.Trim(w, ".,")))
+				v[h.Sum32()%32]++
+			}
+			out[i] = v
+		}
+		return out, nil
+	}
+}
+
+func TestChunkText(t *testing.T) {
+	text := strings.Repeat("alpha beta gamma delta\n", 20)
+	chunks := chunkText(text, 100, 20)
+	if len(chunks) < 5 {
+		t.Fatalf("got %d chunks", len(chunks))
+	}
+	for i, c := range chunks {
+		if len([]rune(c)) > 100 {
+			t.Fatalf("chunk %d has %d runes", i, len([]rune(c)))
+		}
+		if !strings.HasSuffix(c, "delta") {
+			t.Fatalf("chunk %d not cut at a line break: %q", i, c)
+		}
+	}
+	if got := chunkText("  short  ", 100, 20); len(got) != 1 || got[0] != "short" {
+		t.Fatalf("short text: %q", got)
+	}
+	if got := chunkText(strings.Repeat("x", 250), 100, 0); len(got) != 3 {
+		t.Fatalf("unbroken text: %d chunks", len(got))
+	}
+}
+
+func TestKnowledgeBaseStore(t *testing.T) {
+	ctx := t.Context()
+	docsDir := t.TempDir()
+	files := map[string]string{
+		"fruit.txt": "Apples and oranges are fruit. Apples grow on trees.",
+		"go.md":     "# Go\nGo is a programming language with goroutines and channels.",
+		"notes.txt": "Meeting notes about the quarterly budget review.",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(docsDir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(docsDir, "blob.bin"), []byte{0, 1, 2, 0xff, 0xfe}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewKnowledgeBaseStore(ctx, t.TempDir(), wordEmbedder())
+	if err != nil {
+		t.Fatalf("NewKnowledgeBaseStore: %v", err)
+	}
+	defer s.Close()
+
+	for name, body := range map[string]*spec.PostCollectionRequestBody{
+		"no sources": {DisplayName: "x", EmbeddingPresetID: "e"},
+		"relative path": {DisplayName: "x", EmbeddingPresetID: "e",
+			Sources: []spec.Source{{Kind: spec.SourceKindFolder, Location: "docs"}}},
+		"bad url": {DisplayName: "x", EmbeddingPresetID: "e",
+			Sources: []spec.Source{{Kind: spec.SourceKindURL, Location: "ftp://example.com"}}},
+		"big overlap": {DisplayName: "x", EmbeddingPresetID: "e", ChunkSize: 400, ChunkOverlap: 300,
+			Sources: []spec.Source{{Kind: spec.SourceKindFolder, Location: docsDir}}},
+	} {
+		if _, err := s.PostCollection(ctx, &spec.PostCollectionRequest{Body: body}); !errors.Is(
+			err, spec.ErrInvalidRequest) {
+			t.Errorf("%s: got %v, want ErrInvalidRequest", name, err)
+		}
+	}
+
+	post, err := s.PostCollection(ctx, &spec.PostCollectionRequest{Body: &spec.PostCollectionRequestBody{
+		DisplayName:       "Docs",
+		EmbeddingPresetID: "small",
+		Sources:           []spec.Source{{Kind: spec.SourceKindFolder, Location: docsDir}},
+	}})
+	if err != nil {
+		t.Fatalf("PostCollection: %v", err)
+	}
+	c := post.Body
+	if c.ChunkSize != spec.DefaultChunkSize || c.ChunkOverlap != spec.DefaultChunkOverlap {
+		t.Fatalf("chunking defaults: %d/%d", c.ChunkSize, c.ChunkOverlap)
+	}
+
+	query := &spec.QueryKnowledgeBaseRequest{Body: &spec.QueryKnowledgeBaseRequestBody{
+		CollectionIDs: []string{c.ID}, Query: "which fruit grow on trees", TopK: 2,
+	}}
+	if _, err := s.QueryKnowledgeBase(ctx, query); !errors.Is(err, spec.ErrCollectionNotIndexed) {
+		t.Fatalf("query before index: got %v", err)
+	}
+
+	idx, err := s.IndexCollection(ctx, &spec.IndexCollectionRequest{CollectionID: c.ID})
+	if err != nil {
+		t.Fatalf("IndexCollection: %v", err)
+	}
+	got := idx.Body.Collection
+	if got.DocumentCount != 3 || got.ChunkCount != 3 || got.Dimensions != 32 || got.IndexedAt == nil {
+		t.Fatalf("indexed collection: %+v", got)
+	}
+	if len(idx.Body.Skipped) != 1 || filepath.Base(idx.Body.Skipped[0].Location) != "blob.bin" {
+		t.Fatalf("skipped: %+v", idx.Body.Skipped)
+	}
+
+	res, err := s.QueryKnowledgeBase(ctx, query)
+	if err != nil {
+		t.Fatalf("QueryKnowledgeBase: %v", err)
+	}
+	hits := res.Body.Chunks
+	if len(hits) != 2 || filepath.Base(hits[0].Location) != "fruit.txt" || hits[0].Score < hits[1].Score {
+		t.Fatalf("hits: %+v", hits)
+	}
+	if hits[0].CollectionID != c.ID || !strings.Contains(hits[0].Text, "Apples") {
+		t.Fatalf("top hit: %+v", hits[0])
+	}
+
+	// Renaming keeps the index; changing the chunking drops it.
+	name := "Renamed"
+	if _, err := s.PatchCollection(ctx, &spec.PatchCollectionRequest{
+		CollectionID: c.ID, Body: &spec.PatchCollectionRequestBody{DisplayName: &name},
+	}); err != nil {
+		t.Fatalf("PatchCollection rename: %v", err)
+	}
+	if _, err := s.QueryKnowledgeBase(ctx, query); err != nil {
+		t.Fatalf("query after rename: %v", err)
+	}
+	size := 500
+	patched, err := s.PatchCollection(ctx, &spec.PatchCollectionRequest{
+		CollectionID: c.ID, Body: &spec.PatchCollectionRequestBody{ChunkSize: &size},
+	})
+	if err != nil {
+		t.Fatalf("PatchCollection chunkSize: %v", err)
+	}
+	if patched.Body.IndexedAt != nil || patched.Body.ChunkCount != 0 || patched.Body.DisplayName != name {
+		t.Fatalf("patched collection: %+v", patched.Body)
+	}
+	if _, err := s.QueryKnowledgeBase(ctx, query); !errors.Is(err, spec.ErrCollectionNotIndexed) {
+		t.Fatalf("query after chunk change: got %v", err)
+	}
+
+	list, err := s.ListCollections(ctx, &spec.ListCollectionsRequest{})
+	if err != nil || len(list.Body.Collections) != 1 || list.Body.Collections[0].DisplayName != name {
+		t.Fatalf("ListCollections: %+v, %v", list, err)
+	}
+	if _, err := s.DeleteCollection(ctx, &spec.DeleteCollectionRequest{CollectionID: c.ID}); err != nil {
+		t.Fatalf("DeleteCollection: %v", err)
+	}
+	if _, err := s.GetCollection(ctx, &spec.GetCollectionRequest{CollectionID: c.ID}); !errors.Is(
+		err, spec.ErrCollectionNotFound) {
+		t.Fatalf("get after delete: got %v", err)
+	}
+	if _, err := s.DeleteCollection(ctx, &spec.DeleteCollectionRequest{CollectionID: c.ID}); !errors.Is(
+		err, spec.ErrCollectionNotFound) {
+		t.Fatalf("second delete: got %v", err)
+	}
+}
+
+func TestKnowledgeBaseStore_RequiresPathTrust(t *testing.T) {
+	ctx := t.Context()
+	trustedDir, untrustedDir := t.TempDir(), t.TempDir()
+	for _, dir := range []string{trustedDir, untrustedDir} {
+		if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("Some text."), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	errNotTrusted := errors.New("path is outside trusted directories")
+	trusted := map[string]bool{trustedDir: true}
+	var checked []string
+	s, err := NewKnowledgeBaseStore(ctx, t.TempDir(), wordEmbedder(),
+		WithPathTrust(func(_ context.Context, path string) error {
+			checked = append(checked, path)
+			if !trusted[path] {
+				return errNotTrusted
+			}
+			return nil
+		}))
+	if err != nil {
+		t.Fatalf("NewKnowledgeBaseStore: %v", err)
+	}
+	defer s.Close()
+
+	_, err = s.PostCollection(ctx, &spec.PostCollectionRequest{Body: &spec.PostCollectionRequestBody{
+		DisplayName: "Untrusted", EmbeddingPresetID: "small",
+		Sources: []spec.Source{{Kind: spec.SourceKindFolder, Location: untrustedDir}},
+	}})
+	if !errors.Is(err, errNotTrusted) {
+		t.Fatalf("post untrusted folder: got %v, want the trust error", err)
+	}
+
+	post, err := s.PostCollection(ctx, &spec.PostCollectionRequest{Body: &spec.PostCollectionRequestBody{
+		DisplayName: "Docs", EmbeddingPresetID: "small",
+		Sources: []spec.Source{
+			{Kind: spec.SourceKindFolder, Location: trustedDir},
+			{Kind: spec.SourceKindURL, Location: "https://example.com/page"},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("post trusted folder: %v", err)
+	}
+	if len(checked) != 2 || checked[1] != trustedDir {
+		t.Fatalf("checked paths = %q, want only the folder sources", checked)
+	}
+	id := post.Body.ID
+
+	sources := []spec.Source{{Kind: spec.SourceKindFile, Location: filepath.Join(untrustedDir, "a.txt")}}
+	_, err = s.PatchCollection(ctx, &spec.PatchCollectionRequest{
+		CollectionID: id, Body: &spec.PatchCollectionRequestBody{Sources: &sources},
+	})
+	if !errors.Is(err, errNotTrusted) {
+		t.Fatalf("patch untrusted file: got %v, want the trust error", err)
+	}
+
+	// Revoking trust stops indexing before anything is read or embedded.
+	delete(trusted, trustedDir)
+	if _, err := s.IndexCollection(ctx, &spec.IndexCollectionRequest{CollectionID: id}); !errors.Is(
+		err, errNotTrusted) {
+		t.Fatalf("index revoked folder: got %v, want the trust error", err)
+	}
+}
@@ -0,0 +1,194 @@
+// Package logging provides the application slog handler. It filters records
+// by per-module levels, writes them as text or JSON and keeps the most recent
+// ones in memory for diagnostics.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"maps"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Format selects the encoding of written records.
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// ModuleKey is the attribute naming the module a record belongs to.
+const ModuleKey = "module"
+
+// recentCapacity is the number of records kept for Recent.
+const recentCapacity = 1000
+
+// Config holds the runtime settings of a Handler. ModuleLevels maps dotted
+// module names to levels; a name also applies to its sub-modules, so
+// "skill" covers "skill.store" unless that has a level of its own. Modules
+// without a match use Level.
+type Config struct {
+	Level        slog.Level
+	ModuleLevels map[string]slog.Level
+	Format       Format
+}
+
+// Handler is a slog.Handler whose config can be changed while in use. All
+// handlers derived through WithAttrs and WithGroup share the config and the
+// recent records of the handler they came from.
+type Handler struct {
+	state *handlerState
+	text  slog.Handler
+	json  slog.Handler
+
+	module string
+	groups string
+	attrs  []slog.Attr
+}
+
+type handlerState struct {
+	mu       sync.RWMutex
+	cfg      Config
+	minLevel slog.Level
+	recent   recentBuffer
+}
+
+// NewHandler returns a handler writing to w.
+func NewHandler(w io.Writer, cfg Config) *Handler {
+	// Filtering is done here, the inner handlers accept every record.
+	opts := &slog.HandlerOptions{Level: slog.Level(math.MinInt)}
+	h := &Handler{
+		state: &handlerState{recent: recentBuffer{entries: make([]Entry, recentCapacity)}},
+		text:  slog.NewTextHandler(w, opts),
+		json:  slog.NewJSONHandler(w, opts),
+	}
+	h.SetConfig(cfg)
+	return h
+}
+
+// SetConfig replaces the config of h and every handler derived from it.
+func (h *Handler) SetConfig(cfg Config) {
+	if h == nil {
+		return
+	}
+	cfg.ModuleLevels = maps.Clone(cfg.ModuleLevels)
+	if cfg.Format != FormatJSON {
+		cfg.Format = FormatText
+	}
+	minLevel := cfg.Level
+	for _, l := range cfg.ModuleLevels {
+		minLevel = min(minLevel, l)
+	}
+
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+	h.state.cfg = cfg
+	h.state.minLevel = minLevel
+}
+
+// Config returns a copy of the current config.
+func (h *Handler) Config() Config {
+	if h == nil {
+		return Config{}
+	}
+	h.state.mu.RLock()
+	defer h.state.mu.RUnlock()
+	cfg := h.state.cfg
+	cfg.ModuleLevels = maps.Clone(cfg.ModuleLevels)
+	return cfg
+}
+
+// ModuleEnabled reports whether records of module at level are written.
+func (h *Handler) ModuleEnabled(module string, level slog.Level) bool {
+	h.state.mu.RLock()
+	defer h.state.mu.RUnlock()
+	return level >= h.state.levelFor(module)
+}
+
+// Enabled reports whether level can be written. A handler that does not know
+// its module yet answers for the most verbose module and leaves the rest to
+// Handle.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	h.state.mu.RLock()
+	defer h.state.mu.RUnlock()
+	if h.module == "" {
+		return level >= h.state.minLevel
+	}
+	return level >= h.state.levelFor(h.module)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	module := h.module
+	if module == "" && h.groups == "" {
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == ModuleKey {
+				module = a.Value.String()
+				return false
+			}
+			return true
+		})
+	}
+
+	h.state.mu.RLock()
+	enabled := r.Level >= h.state.levelFor(module)
+	format := h.state.cfg.Format
+	h.state.mu.RUnlock()
+	if !enabled {
+		return nil
+	}
+
+	h.state.recent.add(h.entry(module, r))
+	if format == FormatJSON {
+		return h.json.Handle(ctx, r)
+	}
+	return h.text.Handle(ctx, r)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.text = h.text.WithAttrs(attrs)
+	h2.json = h.json.WithAttrs(attrs)
+	h2.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	h2.attrs = append(h2.attrs, h.attrs...)
+	for _, a := range attrs {
+		if h.groups == "" && a.Key == ModuleKey {
+			h2.module = a.Value.String()
+		}
+		h2.attrs = append(h2.attrs, slog.Attr{Key: h.groups + a.Key, Value: a.Value})
+	}
+	return &h2
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.text = h.text.WithGroup(name)
+	h2.json = h.json.WithGroup(name)
+	h2.groups = h.groups + name + "."
+	return &h2
+}
+
+// levelFor returns the level of the longest configured prefix of module.
+// The caller holds s.mu.
+func (s *handlerState) levelFor(module string) slog.Level {
+	for name := module; name != ""; {
+		if l, ok := s.cfg.ModuleLevels[name]; ok {
+			return l
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return s.cfg.Level
+}
@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandlerModuleLevels(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(&buf, Config{
+		Level: slog.LevelInfo,
+		ModuleLevels: map[string]slog.Level{
+			"skill":       slog.LevelWarn,
+			"skill.store": slog.LevelDebug,
+		},
+	})
+	log := slog.New(h)
+
+	log.Debug("root debug")
+	log.Info("root info")
+	log.With(ModuleKey, "skill.runtime").Info("runtime info")
+	log.With(ModuleKey, "skill.store.fs").Debug("store debug")
+	log.Debug("inline", ModuleKey, "skill.store")
+	log.WithGroup("g").With(ModuleKey, "skill").Info("grouped", "k", "v")
+
+	got := []string{}
+	for _, e := range h.Recent(RecentQuery{}) {
+		got = append(got, e.Message)
+	}
+	want := "root info,store debug,inline,grouped"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("recent messages = %v, want %s", got, want)
+	}
+	if strings.Contains(buf.String(), "runtime info") || !strings.Contains(buf.String(), "store debug") {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+
+	if !h.ModuleEnabled("skill.store.x", slog.LevelDebug) || h.ModuleEnabled("skill.x", slog.LevelInfo) {
+		t.Fatal("ModuleEnabled does not follow the longest prefix")
+	}
+	if !h.Enabled(t.Context(), slog.LevelDebug) {
+		t.Fatal("handler without module must let debug through to Handle")
+	}
+
+	last := h.Recent(RecentQuery{Limit: 1})
+	if len(last) != 1 || last[0].Module != "" || last[0].Attrs["g.module"] != "skill" || last[0].Attrs["g.k"] != "v" {
+		t.Fatalf("grouped entry: %+v", last)
+	}
+	warn := slog.LevelWarn
+	if n := len(h.Recent(RecentQuery{MinLevel: &warn})); n != 0 {
+		t.Fatalf("MinLevel filter returned %d entries", n)
+	}
+	if n := len(h.Recent(RecentQuery{Module: "skill"})); n != 2 {
+		t.Fatalf("module filter returned %d entries", n)
+	}
+}
+
+func TestHandlerFormatSwitch(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(&buf, Config{Level: slog.LevelInfo, Format: FormatJSON})
+	log := slog.New(h).With("a", 1)
+
+	log.Info("first")
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil || rec["msg"] != "first" {
+		t.Fatalf("json output %q: %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	h.SetConfig(Config{Level: slog.LevelInfo, Format: FormatText})
+	log.Info("second")
+	if !strings.HasPrefix(buf.String(), "time=") || !strings.Contains(buf.String(), "a=1") {
+		t.Fatalf("text output %q", buf.String())
+	}
+}
+
+func TestRecentBufferWraps(t *testing.T) {
+	h := NewHandler(&bytes.Buffer{}, Config{Level: slog.LevelInfo})
+	log := slog.New(h)
+	for i := range recentCapacity + 5 {
+		log.Info("m", "i", i)
+	}
+	got := h.Recent(RecentQuery{})
+	if len(got) != recentCapacity || got[0].Attrs["i"] != "5" || got[len(got)-1].Attrs["i"] != "1004" {
+		t.Fatalf("wrapped buffer: len=%d first=%v", len(got), got[0].Attrs)
+	}
+}
+
+func TestModuleLogger(t *testing.T) {
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	h := NewHandler(&bytes.Buffer{}, Config{
+		Level:        slog.LevelWarn,
+		ModuleLevels: map[string]slog.Level{"modelpreset": slog.LevelDebug},
+	})
+	// Created before the default is set, as package vars are.
+	log := Module("modelpreset.store")
+	slog.SetDefault(slog.New(h))
+
+	log.Debug("loaded", "n", 2)
+	Module("other").Info("dropped")
+	got := h.Recent(RecentQuery{})
+	if len(got) != 1 || got[0].Module != "modelpreset.store" || got[0].Attrs["n"] != "2" {
+		t.Fatalf("recent: %+v", got)
+	}
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Module returns a logger that tags records with the module name and writes
+// them to slog.Default. The default logger is looked up on every record, so
+// packages can keep the result in a package var.
+func Module(name string) *slog.Logger {
+	return slog.New(&moduleHandler{module: name})
+}
+
+type moduleHandler struct {
+	module string
+	ops    []func(slog.Handler) slog.Handler
+}
+
+func (h *moduleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if lh, ok := slog.Default().Handler().(*Handler); ok {
+		return lh.ModuleEnabled(h.module, level)
+	}
+	return slog.Default().Handler().Enabled(ctx, level)
+}
+
+func (h *moduleHandler) Handle(ctx context.Context, r slog.Record) error {
+	target := slog.Default().Handler().WithAttrs([]slog.Attr{slog.String(ModuleKey, h.module)})
+	for _, op := range h.ops {
+		target = op(target)
+	}
+	return target.Handle(ctx, r)
+}
+
+func (h *moduleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(t slog.Handler) slog.Handler { return t.WithAttrs(attrs) })
+}
+
+func (h *moduleHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(t slog.Handler) slog.Handler { return t.WithGroup(name) })
+}
+
+func (h *moduleHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, 0, len(h.ops)+1)
+	ops = append(ops, h.ops...)
+	return &moduleHandler{module: h.module, ops: append(ops, op)}
+}
@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a record kept for diagnostics. Attrs holds the record attributes
+// as text, keyed by their dotted group path.
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	Level   slog.Level        `json:"level"`
+	Module  string            `json:"module,omitempty"`
+	Message string            `json:"message"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+}
+
+// RecentQuery filters Recent. A nil MinLevel matches every level and Module
+// matches the module and its sub-modules. Limit caps the result to the newest
+// entries; zero means all kept entries.
+type RecentQuery struct {
+	MinLevel *slog.Level `json:"minLevel,omitempty"`
+	Module   string      `json:"module,omitempty"`
+	Limit    int         `json:"limit,omitempty"`
+}
+
+// Recent returns the kept records matching q, oldest first.
+func (h *Handler) Recent(q RecentQuery) []Entry {
+	if h == nil {
+		return []Entry{}
+	}
+	return h.state.recent.list(q)
+}
+
+type recentBuffer struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+func (b *recentBuffer) add(e Entry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[b.next] = e
+	b.next++
+	if b.next == len(b.entries) {
+		b.next = 0
+		b.full = true
+	}
+}
+
+func (b *recentBuffer) list(q RecentQuery) []Entry {
+	b.mu.Lock()
+	ordered := make([]Entry, 0, len(b.entries))
+	if b.full {
+		ordered = append(ordered, b.entries[b.next:]...)
+	}
+	ordered = append(ordered, b.entries[:b.next]...)
+	b.mu.Unlock()
+
+	out := make([]Entry, 0, len(ordered))
+	for _, e := range ordered {
+		if q.MinLevel != nil && e.Level < *q.MinLevel {
+			continue
+		}
+		if q.Module != "" && e.Module != q.Module && !strings.HasPrefix(e.Module, q.Module+".") {
+			continue
+		}
+		out = append(out, e)
+	}
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[len(out)-q.Limit:]
+	}
+	return out
+}
+
+func (h *Handler) entry(module string, r slog.Record) Entry {
+	e := Entry{
+		Time:    r.Time,
+		Level:   r.Level,
+		Module:  module,
+		Message: r.Message,
+	}
+	if len(h.attrs) == 0 && r.NumAttrs() == 0 {
+		return e
+	}
+	e.Attrs = make(map[string]string, len(h.attrs)+r.NumAttrs())
+	for _, a := range h.attrs {
+		addAttr(e.Attrs, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(e.Attrs, h.groups, a)
+		return true
+	})
+	return e
+}
+
+func addAttr(dst map[string]string, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(dst, prefix, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	dst[prefix+a.Key] = v.String()
+}
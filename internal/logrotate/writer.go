@@ -2,10 +2,12 @@ package logrotate
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -30,6 +32,12 @@ type Options struct {
 	// When MaximumLifetime == 0, no log rotation will occur.
 	MaximumLifetime time.Duration
 
+	// MaximumFiles defines how many ".log" files are kept in Directory.
+	// After each rotation the oldest files beyond this count are removed,
+	// oldest being the first by file name as produced by DefaultFilenameFunc.
+	// When MaximumFiles == 0, no files are removed.
+	MaximumFiles int
+
 	// FileNameFunc specifies the name a new file will take.
 	// FileNameFunc must ensure collisions in filenames do not occur.
 	// Do not rely on timestamps to be unique, high throughput writes
@@ -212,9 +220,43 @@ func (w *Writer) rotate() error {
 	w.bytesWritten = 0
 	w.ts = time.Now().UTC()
 
+	if w.opts.MaximumFiles > 0 {
+		if err := w.pruneFiles(); err != nil {
+			w.logger.Warn("failed to remove old log files", "error", err)
+		}
+	}
 	return nil
 }
 
+// pruneFiles removes the oldest log files so at most MaximumFiles remain.
+func (w *Writer) pruneFiles() error {
+	entries, err := os.ReadDir(w.opts.Directory)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && strings.HasSuffix(e.Name(), ".log") {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) <= w.opts.MaximumFiles {
+		return nil
+	}
+	slices.Sort(names)
+	current := filepath.Base(w.f.Name())
+	var errs []error
+	for _, name := range names[:len(names)-w.opts.MaximumFiles] {
+		if name == current {
+			continue
+		}
+		if err := os.Remove(filepath.Join(w.opts.Directory, name)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (w *Writer) closeCurrentFile() error {
 	if err := w.flushCurrentFile(); err != nil {
 		return err
@@ -147,6 +147,53 @@ func TestRotateOnFileSize(t *testing.T) {
 	}
 }
 
+func TestPruneOldFiles(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	dir, cleanup := setup(t)
+	defer cleanup()
+
+	// Older files from earlier runs, plus a file the writer must not touch.
+	for _, name := range []string{"2020-01-01.log", "2020-01-02.log", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n := 0
+	w, err := New(logger, Options{
+		Directory:       dir,
+		MaximumFileSize: 4,
+		MaximumFiles:    2,
+		FileNameFunc: func() string {
+			n++
+			return fmt.Sprintf("2030-01-%02d.log", n)
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	for _, write := range []string{"aaaa", "bbbb", "cccc"} {
+		if _, err := w.Write([]byte(write)); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.Name())
+	}
+	want := []string{"2030-01-02.log", "2030-01-03.log", "notes.txt"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected files %v, got %v", want, got)
+	}
+}
+
 func TestRotateOnLifetime(t *testing.T) {
 	slogOpts := &slog.HandlerOptions{
 		Level: slog.LevelDebug,
@@ -14,9 +14,12 @@ import (
 	"time"
 
 	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 	"github.com/flexigpt/flexigpt-app/internal/mcp/spec"
 )
 
+var logger = logging.Module("mcp.auth")
+
 const (
 	defaultOAuthLoopbackTTL  = 5 * time.Minute
 	defaultOAuthCallbackPath = "/mcp/oauth/callback"
@@ -86,9 +89,9 @@ func NewOAuthLoopbackBroker(ctx context.Context, opts *OAuthLoopbackBrokerOption
 		callbackPath = "/" + callbackPath
 	}
 
-	logger := options.Logger
-	if logger == nil {
-		logger = slog.Default()
+	brokerLogger := options.Logger
+	if brokerLogger == nil {
+		brokerLogger = logger
 	}
 	listenAddr := strings.TrimSpace(options.ListenAddr)
 	if listenAddr == "" {
@@ -103,7 +106,7 @@ func NewOAuthLoopbackBroker(ctx context.Context, opts *OAuthLoopbackBrokerOption
 	b := &OAuthLoopbackBroker{
 		ttl:             ttl,
 		callbackPath:    callbackPath,
-		logger:          logger,
+		logger:          brokerLogger,
 		listener:        ln,
 		pendingByServer: map[oauthPendingKey]*pendingOAuthAuthorization{},
 		pendingByState:  map[string]*pendingOAuthAuthorization{},
@@ -125,7 +128,7 @@ func NewOAuthLoopbackBroker(ctx context.Context, opts *OAuthLoopbackBrokerOption
 
 	go func() {
 		if err := b.server.Serve(ln); err != nil && err != http.ErrServerClosed {
-			logger.Warn("mcp oauth loopback server stopped unexpectedly", "err", err)
+			brokerLogger.Warn("mcp oauth loopback server stopped unexpectedly", "err", err)
 		}
 	}()
 
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"maps"
 	"slices"
 	"sort"
@@ -13,12 +12,15 @@ import (
 	"time"
 
 	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 	"github.com/flexigpt/flexigpt-app/internal/mcp/apps"
 	"github.com/flexigpt/flexigpt-app/internal/mcp/auth"
 	"github.com/flexigpt/flexigpt-app/internal/mcp/spec"
 	"github.com/flexigpt/flexigpt-app/internal/mcp/store"
 )
 
+var logger = logging.Module("mcp.runtime")
+
 type ClientSession interface {
 	Close(ctx context.Context) error
 	Ping(ctx context.Context) error
@@ -419,14 +421,14 @@ func (m *MCPRuntimeManager) OnClientNotification(ctx context.Context, event Clie
 		m.scheduleNotificationRefresh(ctx, event.BundleID, event.ServerID, string(event.Kind))
 
 	case ClientNotificationResourceUpdated:
-		slog.Info(
+		logger.Info(
 			"mcp resource updated notification received",
 			"serverID", event.ServerID,
 			"uri", event.ResourceURI,
 		)
 
 	case ClientNotificationProgress:
-		slog.Debug(
+		logger.Debug(
 			"mcp progress notification received",
 			"serverID", event.ServerID,
 			"progress", event.Progress,
@@ -453,7 +455,7 @@ func (m *MCPRuntimeManager) ListTools(
 	})
 	if err != nil {
 		// Best effort: keep the snapshot if the config read fails.
-		slog.Warn("mcp: tool policy overlay skipped", "serverID", req.ServerID, "err", err)
+		logger.Warn("mcp: tool policy overlay skipped", "serverID", req.ServerID, "err", err)
 	}
 
 	sort.Slice(snap.Tools, func(i, j int) bool {
@@ -892,7 +894,7 @@ func (m *MCPRuntimeManager) refreshFromNotification(
 	m.rememberSnapshotLocked(state, snap, now)
 	state.lastError = ""
 
-	slog.Info("mcp discovery refreshed from notification", "serverID", serverID, "reason", reason)
+	logger.Info("mcp discovery refreshed from notification", "serverID", serverID, "reason", reason)
 }
 
 func (m *MCPRuntimeManager) currentSnapshot(
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 	"github.com/flexigpt/flexigpt-app/internal/mcp/apps"
 	"github.com/flexigpt/flexigpt-app/internal/mcp/auth"
 	"github.com/flexigpt/flexigpt-app/internal/mcp/runtime"
@@ -18,6 +19,8 @@ import (
 	mcpSDK "github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+var logger = logging.Module("mcp.client")
+
 const (
 	defaultStdioTerminateDuration = 5 * time.Second
 	defaultHTTPMaxRetries         = 5
@@ -33,14 +36,14 @@ type Factory struct {
 }
 
 func NewFactory() *Factory {
-	return &Factory{logger: slog.Default()}
+	return &Factory{logger: logger}
 }
 
-func NewFactoryWithLogger(logger *slog.Logger) *Factory {
-	if logger == nil {
-		logger = slog.Default()
+func NewFactoryWithLogger(l *slog.Logger) *Factory {
+	if l == nil {
+		l = logger
 	}
-	return &Factory{logger: logger}
+	return &Factory{logger: l}
 }
 
 func (f *Factory) Connect(
@@ -235,7 +238,7 @@ func (f *Factory) log() *slog.Logger {
 	if f != nil && f.logger != nil {
 		return f.logger
 	}
-	return slog.Default()
+	return logger
 }
 
 func newStreamableHTTPClient(headers map[string]string) *http.Client {
@@ -485,7 +485,7 @@ func (s *Session) log() *slog.Logger {
 	if s != nil && s.logger != nil {
 		return s.logger
 	}
-	return slog.Default()
+	return logger
 }
 
 func initResultCapabilities(initResult *mcpSDK.InitializeResult) *mcpSDK.ServerCapabilities {
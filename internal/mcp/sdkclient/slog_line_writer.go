@@ -19,19 +19,19 @@ type slogLineWriter struct {
 }
 
 func newSlogLineWriter(
-	logger *slog.Logger,
+	l *slog.Logger,
 	serverID string,
 	message string,
 	redactor *auth.SecretRedactor,
 ) *slogLineWriter {
-	if logger == nil {
-		logger = slog.Default()
+	if l == nil {
+		l = logger
 	}
 	if message == "" {
 		message = "mcp process log"
 	}
 	return &slogLineWriter{
-		logger:   logger,
+		logger:   l,
 		serverID: serverID,
 		message:  message,
 		redactor: redactor,
@@ -3,7 +3,6 @@ package store
 import (
 	"context"
 	"fmt"
-	"log/slog"
 	"os"
 	"path/filepath"
 	"slices"
@@ -14,11 +13,14 @@ import (
 
 	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
 	"github.com/flexigpt/flexigpt-app/internal/jsonutil"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 	"github.com/flexigpt/flexigpt-app/internal/mcp/spec"
 	"github.com/flexigpt/mapstore-go"
 	"github.com/flexigpt/mapstore-go/jsonencdec"
 )
 
+var logger = logging.Module("mcp.store")
+
 const (
 	builtInSnapshotMaxAge = 24 * time.Hour
 	softDeleteGraceMCP    = 48 * time.Hour
@@ -76,7 +78,7 @@ func NewMCPStore(ctx context.Context, baseDir string) (*Store, error) {
 		jsonencdec.JSONEncoderDecoder{},
 		mapstore.WithCreateIfNotExists(true),
 		mapstore.WithFileAutoFlush(true),
-		mapstore.WithFileLogger(slog.Default()),
+		mapstore.WithFileLogger(logger),
 	)
 	if err != nil {
 		return nil, err
@@ -1025,7 +1027,7 @@ func (s *Store) sweepSoftDeletedBundles() {
 
 	sc, err := s.readAll(context.Background(), false)
 	if err != nil {
-		slog.Error("mcp store: sweep readAll failed", "err", err)
+		logger.Error("mcp store: sweep readAll failed", "err", err)
 		return
 	}
 
@@ -1045,11 +1047,11 @@ func (s *Store) sweepSoftDeletedBundles() {
 		delete(sc.Bundles, id)
 		delete(sc.Servers, id)
 		changed = true
-		slog.Info("mcp store: hard-deleted soft-deleted bundle", "bundleID", id)
+		logger.Info("mcp store: hard-deleted soft-deleted bundle", "bundleID", id)
 	}
 	if changed {
 		if err := s.writeAll(sc); err != nil {
-			slog.Error("mcp store: sweep writeAll failed", "err", err)
+			logger.Error("mcp store: sweep writeAll failed", "err", err)
 		}
 	}
 }
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/precondition"
+	"github.com/flexigpt/flexigpt-app/internal/validation"
+)
+
+// ErrorResponse is the structured form of an error returned by a bound
+// method. It is used only when the error carries more than a message, so
+// plain errors keep reaching the frontend as strings.
+type ErrorResponse struct {
+	Message string `json:"message"`
+	// Issues lists every invalid field of a rejected write.
+	Issues validation.Errors `json:"issues,omitempty"`
+	// Conflict is set when a write was refused because the entity changed
+	// since the caller read it.
+	Conflict *ConflictResponse `json:"conflict,omitempty"`
+}
+
+// ConflictResponse carries the entity as currently stored, so the frontend
+// can show it or merge the user's edit into it.
+type ConflictResponse struct {
+	ExpectedModifiedAt time.Time `json:"expectedModifiedAt"`
+	ModifiedAt         time.Time `json:"modifiedAt"`
+	Current            any       `json:"current"`
+}
+
+// FormatError is the Wails ErrorFormatter. Errors wrapping validation.Errors
+// or a precondition conflict are returned as an ErrorResponse so the frontend
+// can show the issues next to their form fields or the stored entity; all
+// other errors are returned as their message.
+func FormatError(err error) any {
+	if err == nil {
+		return nil
+	}
+	issues := validation.IssuesOf(err)
+	conflict, isConflict := precondition.AsConflict(err)
+	if len(issues) == 0 && !isConflict {
+		return err.Error()
+	}
+	resp := &ErrorResponse{Message: err.Error(), Issues: issues}
+	if isConflict {
+		resp.Conflict = &ConflictResponse{
+			ExpectedModifiedAt: conflict.ExpectedModifiedAt(),
+			ModifiedAt:         conflict.CurrentModifiedAt(),
+			Current:            conflict.CurrentValue(),
+		}
+	}
+	return resp
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/precondition"
+	"github.com/flexigpt/flexigpt-app/internal/validation"
+)
+
+func TestFormatError(t *testing.T) {
+	if got := FormatError(nil); got != nil {
+		t.Fatalf("FormatError(nil) = %v", got)
+	}
+	if got := FormatError(errors.New("boom")); got != "boom" {
+		t.Fatalf("plain error = %#v, want its message", got)
+	}
+
+	r := &validation.Report{}
+	r.Addf("name", validation.CodeRequired, "name is empty")
+	r.Warnf("origin", validation.CodeInvalid, "plain http")
+	err := fmt.Errorf("invalid skill: %w", r.Err())
+	got, ok := FormatError(err).(*ErrorResponse)
+	if !ok {
+		t.Fatalf("validation error = %#v, want *ErrorResponse", FormatError(err))
+	}
+	if got.Message != err.Error() || len(got.Issues) != 1 || got.Issues[0].Field != "name" {
+		t.Fatalf("response = %+v", got)
+	}
+
+	stale := time.Unix(100, 0).UTC()
+	err = fmt.Errorf("patch skill: %w", precondition.CheckModifiedAt(&stale, time.Unix(200, 0).UTC(), "stored"))
+	got, ok = FormatError(err).(*ErrorResponse)
+	if !ok || got.Conflict == nil {
+		t.Fatalf("conflict error = %#v, want *ErrorResponse with a conflict", FormatError(err))
+	}
+	if got.Conflict.Current != "stored" || !got.Conflict.ExpectedModifiedAt.Equal(stale) || len(got.Issues) != 0 {
+		t.Fatalf("conflict = %+v", got.Conflict)
+	}
+}
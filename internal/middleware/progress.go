@@ -0,0 +1,250 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// progressReportInterval limits how often an operation forwards Report calls
+// to listeners. The final report of a counted operation is always sent.
+const progressReportInterval = 150 * time.Millisecond
+
+var ErrOperationNotFound = errors.New("operation not found")
+
+type ProgressPhase string
+
+const (
+	ProgressPhaseStarted  ProgressPhase = "started"
+	ProgressPhaseRunning  ProgressPhase = "running"
+	ProgressPhaseDone     ProgressPhase = "done"
+	ProgressPhaseFailed   ProgressPhase = "failed"
+	ProgressPhaseCanceled ProgressPhase = "canceled"
+)
+
+// ProgressEvent describes the state of a long running operation. Kind names
+// the operation type (e.g. "knowledgeBaseIndex") and Subject the item it
+// works on. Current and Total count work units; Total is zero when unknown.
+type ProgressEvent struct {
+	OperationID string        `json:"operationID"`
+	Kind        string        `json:"kind"`
+	Subject     string        `json:"subject,omitempty"`
+	Phase       ProgressPhase `json:"phase"`
+	Message     string        `json:"message,omitempty"`
+	Current     int64         `json:"current,omitempty"`
+	Total       int64         `json:"total,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	At          time.Time     `json:"at"`
+}
+
+// ProgressListener receives progress events. It is called synchronously by
+// the reporting goroutine and must not block.
+type ProgressListener func(event ProgressEvent)
+
+// ProgressBus tracks running operations and fans their progress out to
+// listeners.
+type ProgressBus struct {
+	mu        sync.Mutex
+	nextID    uint64
+	listeners map[uint64]ProgressListener
+	ops       map[string]*Operation
+}
+
+func NewProgressBus() *ProgressBus {
+	return &ProgressBus{
+		listeners: map[uint64]ProgressListener{},
+		ops:       map[string]*Operation{},
+	}
+}
+
+// Subscribe registers listener and returns a func that unregisters it.
+func (b *ProgressBus) Subscribe(listener ProgressListener) (unsubscribe func()) {
+	if b == nil || listener == nil {
+		return func() {}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	id := b.nextID
+	b.listeners[id] = listener
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.listeners, id)
+		})
+	}
+}
+
+// Start registers an operation and returns a context carrying it. The
+// context is canceled by Cancel or when the operation finishes. Code deeper
+// in the call chain reports through ProgressFromContext.
+func (b *ProgressBus) Start(ctx context.Context, kind, subject string) (context.Context, *Operation) {
+	opCtx, cancel := context.WithCancel(ctx)
+	op := &Operation{
+		bus:     b,
+		id:      uuid.Must(uuid.NewV7()).String(),
+		kind:    kind,
+		subject: subject,
+		cancel:  cancel,
+	}
+	b.mu.Lock()
+	b.ops[op.id] = op
+	b.mu.Unlock()
+
+	op.emit(ProgressEvent{Phase: ProgressPhaseStarted}, true)
+	return context.WithValue(opCtx, progressContextKey{}, op), op
+}
+
+// Cancel cancels the context of a running operation. The operation reports
+// ProgressPhaseCanceled once its work has stopped.
+func (b *ProgressBus) Cancel(operationID string) error {
+	b.mu.Lock()
+	op, ok := b.ops[operationID]
+	b.mu.Unlock()
+	if !ok {
+		return ErrOperationNotFound
+	}
+	op.cancel()
+	return nil
+}
+
+// Operations returns the latest event of each running operation, oldest
+// first.
+func (b *ProgressBus) Operations() []ProgressEvent {
+	b.mu.Lock()
+	ops := slices.Collect(maps.Values(b.ops))
+	b.mu.Unlock()
+
+	out := make([]ProgressEvent, 0, len(ops))
+	for _, op := range ops {
+		op.mu.Lock()
+		out = append(out, op.last)
+		op.mu.Unlock()
+	}
+	slices.SortFunc(out, func(x, y ProgressEvent) int { return strings.Compare(x.OperationID, y.OperationID) })
+	return out
+}
+
+func (b *ProgressBus) publish(ev ProgressEvent) {
+	b.mu.Lock()
+	listeners := slices.Collect(maps.Values(b.listeners))
+	b.mu.Unlock()
+	for _, l := range listeners {
+		l(ev)
+	}
+}
+
+// Operation reports the progress of one running operation. A nil *Operation
+// is valid and ignores all calls, so code can report unconditionally.
+type Operation struct {
+	bus     *ProgressBus
+	id      string
+	kind    string
+	subject string
+	cancel  context.CancelFunc
+
+	mu       sync.Mutex
+	last     ProgressEvent
+	lastSent time.Time
+	finished bool
+}
+
+type progressContextKey struct{}
+
+// ProgressFromContext returns the operation started with ProgressBus.Start,
+// or nil.
+func ProgressFromContext(ctx context.Context) *Operation {
+	op, _ := ctx.Value(progressContextKey{}).(*Operation)
+	return op
+}
+
+func (o *Operation) ID() string {
+	if o == nil {
+		return ""
+	}
+	return o.id
+}
+
+// Report records progress. Reports closer together than
+// progressReportInterval are coalesced.
+func (o *Operation) Report(message string, current, total int64) {
+	if o == nil {
+		return
+	}
+	final := total > 0 && current >= total
+	o.emit(ProgressEvent{
+		Phase:   ProgressPhaseRunning,
+		Message: message,
+		Current: current,
+		Total:   total,
+	}, final)
+}
+
+// Finish ends the operation with the outcome of err and releases its
+// context. Only the first call has an effect.
+func (o *Operation) Finish(err error) {
+	if o == nil {
+		return
+	}
+	ev := ProgressEvent{Phase: ProgressPhaseDone}
+	switch {
+	case errors.Is(err, context.Canceled):
+		ev.Phase = ProgressPhaseCanceled
+	case err != nil:
+		ev.Phase = ProgressPhaseFailed
+		ev.Error = err.Error()
+	}
+
+	o.mu.Lock()
+	if o.finished {
+		o.mu.Unlock()
+		return
+	}
+	o.finished = true
+	ev.Current, ev.Total = o.last.Current, o.last.Total
+	o.mu.Unlock()
+
+	o.bus.mu.Lock()
+	delete(o.bus.ops, o.id)
+	o.bus.mu.Unlock()
+	o.cancel()
+	o.send(ev)
+}
+
+func (o *Operation) emit(ev ProgressEvent, force bool) {
+	o.mu.Lock()
+	if o.finished {
+		o.mu.Unlock()
+		return
+	}
+	ev = o.stamp(ev)
+	o.last = ev
+	if !force && ev.At.Sub(o.lastSent) < progressReportInterval {
+		o.mu.Unlock()
+		return
+	}
+	o.lastSent = ev.At
+	o.mu.Unlock()
+	o.bus.publish(ev)
+}
+
+func (o *Operation) send(ev ProgressEvent) {
+	o.bus.publish(o.stamp(ev))
+}
+
+func (o *Operation) stamp(ev ProgressEvent) ProgressEvent {
+	ev.OperationID = o.id
+	ev.Kind = o.kind
+	ev.Subject = o.subject
+	ev.At = time.Now().UTC()
+	return ev
+}
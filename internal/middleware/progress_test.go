@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type eventLog struct {
+	mu     sync.Mutex
+	events []ProgressEvent
+}
+
+func (l *eventLog) add(ev ProgressEvent) {
+	l.mu.Lock()
+	l.events = append(l.events, ev)
+	l.mu.Unlock()
+}
+
+func (l *eventLog) phases() []ProgressPhase {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]ProgressPhase, len(l.events))
+	for i, ev := range l.events {
+		out[i] = ev.Phase
+	}
+	return out
+}
+
+func TestProgressBus(t *testing.T) {
+	bus := NewProgressBus()
+	var log eventLog
+	unsubscribe := bus.Subscribe(log.add)
+
+	ctx, op := bus.Start(t.Context(), "index", "c1")
+	if ProgressFromContext(ctx) != op {
+		t.Fatal("operation not in context")
+	}
+	// Rapid reports are coalesced, the final one is kept.
+	for i := range int64(10) {
+		ProgressFromContext(ctx).Report("embedding", i+1, 10)
+	}
+	ops := bus.Operations()
+	if len(ops) != 1 || ops[0].OperationID != op.ID() || ops[0].Current != 10 {
+		t.Fatalf("operations: %+v", ops)
+	}
+	op.Finish(nil)
+	op.Finish(errors.New("ignored"))
+
+	got := log.phases()
+	if len(got) < 3 || got[0] != ProgressPhaseStarted || got[len(got)-1] != ProgressPhaseDone || len(got) > 5 {
+		t.Fatalf("phases: %v", got)
+	}
+	if last := log.events[len(log.events)-1]; last.Kind != "index" || last.Subject != "c1" || last.Current != 10 {
+		t.Fatalf("done event: %+v", last)
+	}
+	if len(bus.Operations()) != 0 || ctx.Err() == nil {
+		t.Fatal("finished operation still registered or its context is live")
+	}
+
+	ctx, op = bus.Start(t.Context(), "walk", "")
+	if err := bus.Cancel(op.ID()); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	<-ctx.Done()
+	op.Finish(ctx.Err())
+	if got := log.phases(); got[len(got)-1] != ProgressPhaseCanceled {
+		t.Fatalf("phases after cancel: %v", got)
+	}
+	if err := bus.Cancel(op.ID()); !errors.Is(err, ErrOperationNotFound) {
+		t.Fatalf("cancel finished operation: %v", err)
+	}
+
+	unsubscribe()
+	n := len(log.phases())
+	_, op = bus.Start(context.Background(), "walk", "")
+	op.Finish(errors.New("boom"))
+	if len(log.phases()) != n {
+		t.Fatal("unsubscribed listener still called")
+	}
+
+	// A nil operation ignores calls.
+	var none *Operation
+	none.Report("x", 1, 2)
+	none.Finish(nil)
+	if ProgressFromContext(context.Background()) != nil || none.ID() != "" {
+		t.Fatal("expected no operation")
+	}
+}
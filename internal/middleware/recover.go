@@ -5,15 +5,19 @@ import (
 	"log/slog"
 	"runtime/debug"
 	"strings"
+
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 )
 
+var logger = logging.Module("middleware")
+
 // WithRecoveryResp is a helper that recovers from any panic, logs the stack trace,
 // and returns an error to the caller. T must match the response type of your function.
 func WithRecoveryResp[T any](fn func() (T, error)) (result T, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			// Log the panic plus stack trace.
-			slog.Error("panic recovered",
+			logger.Error("panic recovered",
 				slog.Any("panic", r),
 				slog.String("stacktrace", string(debug.Stack())),
 			)
@@ -33,9 +37,9 @@ func WithRecoveryResp[T any](fn func() (T, error)) (result T, err error) {
 	result, err = fn()
 	if err != nil {
 		msg := err.Error()
-		slog.Error("response", "error", msg)
+		logger.Error("response", "error", msg)
 		if !isStackTraceSkippable(msg) {
-			slog.Error(string(debug.Stack()))
+			logger.Error(string(debug.Stack()))
 		}
 	}
 	return result, err
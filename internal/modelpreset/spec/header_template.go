@@ -0,0 +1,102 @@
+package spec
+
+import (
+	"errors"
+	"strings"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// Placeholders a provider default header value may contain, written as
+// "{{name}}" like system prompt placeholders. They are resolved for every
+// request, so gateways that want a fresh date or a signed value see one.
+const (
+	// HeaderPlaceholderDate is the request time as an HTTP date.
+	HeaderPlaceholderDate = "date"
+	// HeaderPlaceholderTimestamp is the request time in Unix seconds.
+	HeaderPlaceholderTimestamp = "timestamp"
+	// HeaderPlaceholderRequestID is a new UUID per request.
+	HeaderPlaceholderRequestID = "requestID"
+	// HeaderPlaceholderAuthKeyPrefix starts "authKey:<type>/<name>", the
+	// secret of a setting store auth key. See HeaderAuthKeyAllowed for the
+	// keys a provider may read.
+	HeaderPlaceholderAuthKeyPrefix = "authKey:"
+)
+
+// Setting store auth key types a header placeholder may read.
+const (
+	// HeaderAuthKeyTypeProvider is the API key type; a provider may only read
+	// its own key.
+	HeaderAuthKeyTypeProvider = "provider"
+	// HeaderAuthKeyTypeProviderHeader holds secrets stored for use in
+	// provider headers.
+	HeaderAuthKeyTypeProviderHeader = "providerHeader"
+)
+
+// HeaderAuthKeyAllowed reports whether the headers of provider may carry the
+// auth key keyType/keyName. Other secrets, such as another provider's API key
+// or MCP tokens, must not be sent to the provider's origin.
+func HeaderAuthKeyAllowed(provider inferenceSpec.ProviderName, keyType, keyName string) bool {
+	switch keyType {
+	case HeaderAuthKeyTypeProviderHeader:
+		return true
+	case HeaderAuthKeyTypeProvider:
+		return keyName == string(provider)
+	}
+	return false
+}
+
+// IsHeaderPlaceholder reports whether name is a supported header placeholder.
+func IsHeaderPlaceholder(name string) bool {
+	switch name {
+	case HeaderPlaceholderDate, HeaderPlaceholderTimestamp, HeaderPlaceholderRequestID:
+		return true
+	}
+	_, _, ok := HeaderAuthKeyPlaceholder(name)
+	return ok
+}
+
+// HeaderAuthKeyPlaceholder splits an "authKey:<type>/<name>" placeholder.
+func HeaderAuthKeyPlaceholder(name string) (keyType, keyName string, ok bool) {
+	ref, ok := strings.CutPrefix(name, HeaderPlaceholderAuthKeyPrefix)
+	if !ok {
+		return "", "", false
+	}
+	keyType, keyName, ok = strings.Cut(ref, "/")
+	if !ok || keyType == "" || keyName == "" {
+		return "", "", false
+	}
+	return keyType, keyName, true
+}
+
+// HeaderPlaceholders returns the placeholder names used in a header value, in
+// order of appearance.
+func HeaderPlaceholders(value string) []string {
+	var names []string
+	for _, m := range systemPromptPlaceholderRe.FindAllStringSubmatch(value, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// ExpandHeaderPlaceholders replaces the supported placeholders in value with
+// what resolve returns for them. Unknown placeholders are left as written.
+func ExpandHeaderPlaceholders(value string, resolve func(name string) (string, error)) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+	var errs []error
+	out := systemPromptPlaceholderRe.ReplaceAllStringFunc(value, func(m string) string {
+		name := systemPromptPlaceholderRe.FindStringSubmatch(m)[1]
+		if !IsHeaderPlaceholder(name) {
+			return m
+		}
+		v, err := resolve(name)
+		if err != nil {
+			errs = append(errs, err)
+			return m
+		}
+		return v
+	})
+	return out, errors.Join(errs...)
+}
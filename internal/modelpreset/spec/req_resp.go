@@ -1,8 +1,13 @@
 package spec
 
 import (
+	"time"
+
 	"github.com/flexigpt/inference-go/capabilityoverride"
 	inferenceSpec "github.com/flexigpt/inference-go/spec"
+
+	"github.com/flexigpt/flexigpt-app/internal/fsutil"
+	"github.com/flexigpt/flexigpt-app/internal/validation"
 )
 
 type PatchDefaultProviderRequestBody struct {
@@ -10,10 +15,21 @@ type PatchDefaultProviderRequestBody struct {
 }
 
 type PatchDefaultProviderRequest struct {
-	Body *PatchDefaultProviderRequestBody
+	IdempotencyKey string `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool   `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body           *PatchDefaultProviderRequestBody
+}
+
+// PatchDefaultProviderResponseBody is the default provider a dry run would
+// save.
+type PatchDefaultProviderResponseBody struct {
+	DefaultProvider inferenceSpec.ProviderName `json:"defaultProvider"`
 }
 
-type PatchDefaultProviderResponse struct{}
+// PatchDefaultProviderResponse has a Body only for dry runs.
+type PatchDefaultProviderResponse struct {
+	Body *PatchDefaultProviderResponseBody
+}
 
 type GetDefaultProviderRequest struct{}
 
@@ -35,13 +51,28 @@ type PostProviderPresetRequestBody struct {
 	APIKeyHeaderKey      string                                        `json:"apiKeyHeaderKey,omitempty"`
 	DefaultHeaders       map[string]string                             `json:"defaultHeaders,omitempty"`
 	CapabilitiesOverride *capabilityoverride.ModelCapabilitiesOverride `json:"capabilitiesOverride,omitempty"`
+	RateLimits           *ProviderRateLimits                           `json:"rateLimits,omitempty"`
+	Resilience           *ProviderResilience                           `json:"resilience,omitempty"`
+	Azure                *AzureOpenAIConfig                            `json:"azure,omitempty"`
+	TLS                  *ProviderTLSConfig                            `json:"tls,omitempty"`
+	ImageProcessing      *ProviderImageProcessing                      `json:"imageProcessing,omitempty"`
 }
 type PostProviderPresetRequest struct {
-	ProviderName inferenceSpec.ProviderName `path:"providerName" required:"true"`
-	Body         *PostProviderPresetRequestBody
+	ProviderName   inferenceSpec.ProviderName `path:"providerName" required:"true"`
+	IdempotencyKey string                     `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool                       `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body           *PostProviderPresetRequestBody
 }
 
-type PostProviderPresetResponse struct{}
+// PostProviderPresetResponseBody is the provider a dry run would create.
+type PostProviderPresetResponseBody struct {
+	ProviderPreset ProviderPreset `json:"providerPreset"`
+}
+
+// PostProviderPresetResponse has a Body only for dry runs.
+type PostProviderPresetResponse struct {
+	Body *PostProviderPresetResponseBody
+}
 
 // PatchProviderPresetRequestBody patches an existing provider preset.
 //
@@ -49,8 +80,14 @@ type PostProviderPresetResponse struct{}
 //   - nil pointer fields => not provided
 //   - DefaultHeaders nil => not provided
 //   - DefaultHeaders {} => replace with empty map
+//   - RateLimits=&{} => clear rate limits
+//   - Resilience=&{} => clear retry policy and failover
+//   - Azure replaces the deployment and re-derives chatCompletionPathPrefix
+//   - TLS=&{} => clear custom CA and client certificate
+//   - ImageProcessing=&{} => send image attachments unchanged
 //   - only user providers can patch provider metadata/capabilities
-//   - built-ins only support isEnabled and defaultModelPresetID
+//   - built-ins only support isEnabled, defaultModelPresetID, rateLimits,
+//     resilience and imageProcessing
 type PatchProviderPresetRequestBody struct {
 	DisplayName              *ProviderDisplayName           `json:"displayName,omitempty"`
 	SDKType                  *inferenceSpec.ProviderSDKType `json:"sdkType,omitempty"`
@@ -62,19 +99,189 @@ type PatchProviderPresetRequestBody struct {
 	DefaultModelPresetID     *ModelPresetID                 `json:"defaultModelPresetID,omitempty"`
 
 	CapabilitiesOverride *capabilityoverride.ModelCapabilitiesOverride `json:"capabilitiesOverride,omitempty"`
+	RateLimits           *ProviderRateLimits                           `json:"rateLimits,omitempty"`
+	Resilience           *ProviderResilience                           `json:"resilience,omitempty"`
+	Azure                *AzureOpenAIConfig                            `json:"azure,omitempty"`
+	TLS                  *ProviderTLSConfig                            `json:"tls,omitempty"`
+	ImageProcessing      *ProviderImageProcessing                      `json:"imageProcessing,omitempty"`
+
+	// ExpectedModifiedAt, if set, makes the patch fail with a conflict unless
+	// the provider is still at this modifiedAt.
+	ExpectedModifiedAt *time.Time `json:"expectedModifiedAt,omitempty"`
 }
 
 type PatchProviderPresetRequest struct {
-	ProviderName inferenceSpec.ProviderName `path:"providerName" required:"true"`
-	Body         *PatchProviderPresetRequestBody
+	ProviderName   inferenceSpec.ProviderName `path:"providerName" required:"true"`
+	IdempotencyKey string                     `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool                       `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body           *PatchProviderPresetRequestBody
 }
 
-type PatchProviderPresetResponse struct{}
+// PatchProviderPresetResponseBody is the provider a dry run would save.
+type PatchProviderPresetResponseBody struct {
+	ProviderPreset ProviderPreset `json:"providerPreset"`
+}
+
+// PatchProviderPresetResponse has a Body only for dry runs.
+type PatchProviderPresetResponse struct {
+	Body *PatchProviderPresetResponseBody
+}
 
 type DeleteProviderPresetRequest struct {
+	ProviderName   inferenceSpec.ProviderName `path:"providerName" required:"true"`
+	IdempotencyKey string                     `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool                       `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+}
+
+// DeleteProviderPresetResponseBody is the soft-deleted provider a dry run
+// would save.
+type DeleteProviderPresetResponseBody struct {
+	ProviderPreset ProviderPreset `json:"providerPreset"`
+}
+
+// DeleteProviderPresetResponse has a Body only for dry runs.
+type DeleteProviderPresetResponse struct {
+	Body *DeleteProviderPresetResponseBody
+}
+
+type UndeleteProviderPresetRequest struct {
+	ProviderName inferenceSpec.ProviderName `path:"providerName" required:"true"`
+}
+type UndeleteProviderPresetResponse struct{}
+
+// ListSoftDeletedRequest lists the user provider presets pending purge.
+type ListSoftDeletedRequest struct{}
+
+// SoftDeletedProviderPreset is a deleted provider preset. The cleanup loop
+// hard-deletes it on its first sweep after PurgeAt.
+type SoftDeletedProviderPreset struct {
+	ProviderPreset   ProviderPreset `json:"providerPreset"`
+	PurgeAt          time.Time      `json:"purgeAt"`
+	RemainingGraceMS int64          `json:"remainingGraceMS"`
+}
+
+type ListSoftDeletedResponseBody struct {
+	// Sorted newest deletion first.
+	ProviderPresets []SoftDeletedProviderPreset `json:"providerPresets"`
+}
+
+type ListSoftDeletedResponse struct {
+	Body *ListSoftDeletedResponseBody
+}
+
+// ResetBuiltInOverridesRequest resets the listed built-in providers, or all
+// built-in providers when ProviderNames is empty.
+type ResetBuiltInOverridesRequest struct {
+	ProviderNames []inferenceSpec.ProviderName `query:"providerNames"`
+}
+
+type ResetBuiltInOverridesResponseBody struct {
+	ResetProviderNames []inferenceSpec.ProviderName `json:"resetProviderNames"`
+}
+
+type ResetBuiltInOverridesResponse struct {
+	Body *ResetBuiltInOverridesResponseBody
+}
+
+type DiscoverProviderModelsRequestBody struct {
+	// ModelsPath overrides the model-listing path, which is otherwise derived
+	// from the provider's chat completion path (e.g. "/v1/models").
+	ModelsPath string `json:"modelsPath,omitempty"`
+
+	// AcceptAll creates user model presets for every discovered model that
+	// does not exist yet. Only valid for user providers.
+	AcceptAll bool `json:"acceptAll,omitempty"`
+
+	// APIKey is filled in by the app from the stored auth key, never by callers.
+	APIKey string `json:"-"`
+}
+
+type DiscoverProviderModelsRequest struct {
 	ProviderName inferenceSpec.ProviderName `path:"providerName" required:"true"`
+	Body         *DiscoverProviderModelsRequestBody
+}
+
+type DiscoveredModelPreset struct {
+	ModelPreset ModelPreset `json:"modelPreset"`
+	// Exists reports that the provider already has a preset with this ID.
+	Exists bool `json:"exists"`
+}
+
+type DiscoverProviderModelsResponseBody struct {
+	Models   []DiscoveredModelPreset `json:"models"`
+	Accepted []ModelPresetID         `json:"accepted,omitempty"`
+}
+
+type DiscoverProviderModelsResponse struct {
+	Body *DiscoverProviderModelsResponseBody
+}
+
+type ProbeOriginRequestBody struct {
+	// BaseURL is the gateway address, with or without an API path such as
+	// "/v1" or "/openai/v1".
+	BaseURL string `json:"baseURL" required:"true"`
+	// APIKey is only sent to BaseURL and is not stored.
+	APIKey string `json:"apiKey,omitempty"`
+}
+
+type ProbeOriginRequest struct {
+	Body *ProbeOriginRequestBody
+}
+
+type ProbeEndpointKind string
+
+const (
+	ProbeEndpointChatCompletions ProbeEndpointKind = "chatCompletions"
+	ProbeEndpointModels          ProbeEndpointKind = "models"
+	ProbeEndpointEmbeddings      ProbeEndpointKind = "embeddings"
+)
+
+type ProbedEndpoint struct {
+	Kind ProbeEndpointKind `json:"kind"`
+	Path string            `json:"path"`
+	// Found is set when the gateway answered with anything but not found.
+	Found      bool   `json:"found"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type ProbeOriginResponseBody struct {
+	Endpoints []ProbedEndpoint `json:"endpoints"`
+	// Models are the model names the gateway listed, if it has a listing.
+	Models []ModelName `json:"models,omitempty"`
+	// Provider is a pre-filled provider to post after the user names it.
+	Provider PostProviderPresetRequestBody `json:"provider"`
+}
+
+type ProbeOriginResponse struct {
+	Body *ProbeOriginResponseBody
+}
+
+type ValidateProviderPresetRequestBody struct {
+	// Preset validates an unsaved provider instead of the stored one.
+	// Timestamps are not checked for it, as the store sets them on write.
+	Preset *ProviderPreset `json:"preset,omitempty"`
+
+	// CheckReachability also probes the provider origin over the network.
+	CheckReachability bool `json:"checkReachability,omitempty"`
+}
+
+type ValidateProviderPresetRequest struct {
+	ProviderName inferenceSpec.ProviderName `path:"providerName" required:"true"`
+	Body         *ValidateProviderPresetRequestBody
+}
+
+type ValidateProviderPresetResponseBody struct {
+	ProviderName inferenceSpec.ProviderName `json:"providerName"`
+	// Valid reports that no issue has error severity. Issue fields are JSON
+	// paths such as "modelPresets.gpt-4o.temperature".
+	Valid  bool              `json:"valid"`
+	Issues validation.Errors `json:"issues"`
+}
+
+type ValidateProviderPresetResponse struct {
+	Body *ValidateProviderPresetResponseBody
 }
-type DeleteProviderPresetResponse struct{}
 
 type PostModelPresetRequestBody struct {
 	ModelPresetPatch
@@ -83,14 +290,34 @@ type PostModelPresetRequestBody struct {
 	Slug        ModelSlug        `json:"slug"        required:"true"`
 	DisplayName ModelDisplayName `json:"displayName" required:"true"`
 	IsEnabled   bool             `json:"isEnabled"   required:"true"`
+	Tags        []string         `json:"tags,omitempty"`
+	Pricing     *ModelPricing    `json:"pricing,omitempty"`
+
+	// ExpectedProviderModifiedAt, if set, makes the create fail with a
+	// conflict unless the provider preset is unchanged since it was read.
+	ExpectedProviderModifiedAt *time.Time `json:"expectedProviderModifiedAt,omitempty"`
 }
 
 type PostModelPresetRequest struct {
-	ProviderName  inferenceSpec.ProviderName `path:"providerName"  required:"true"`
-	ModelPresetID ModelPresetID              `path:"modelPresetID" required:"true"`
-	Body          *PostModelPresetRequestBody
+	ProviderName   inferenceSpec.ProviderName `path:"providerName"  required:"true"`
+	ModelPresetID  ModelPresetID              `path:"modelPresetID" required:"true"`
+	IdempotencyKey string                     `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool                       `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body           *PostModelPresetRequestBody
+}
+
+// PostModelPresetResponseBody is the model preset a dry run would create and
+// its provider afterwards.
+type PostModelPresetResponseBody struct {
+	ModelPreset    ModelPreset    `json:"modelPreset"`
+	ProviderPreset ProviderPreset `json:"providerPreset"`
 }
-type PostModelPresetResponse struct{}
+
+// PostModelPresetResponse has a Body only for dry runs.
+type PostModelPresetResponse struct{ Body *PostModelPresetResponseBody }
+
+// PatchClearValue clears a numeric model preset knob in PatchModelPreset.
+const PatchClearValue = -1
 
 // PatchModelPresetRequestBody patches a stored model preset.
 //
@@ -98,6 +325,13 @@ type PostModelPresetResponse struct{}
 //   - nil pointer/object fields => not provided
 //   - StopSequences=nil => not provided
 //   - StopSequences=&[]{} => explicitly set to empty
+//   - Tags=nil => not provided, Tags=&[]{} => clear all tags
+//   - Pricing=nil => not provided, Pricing=&{} => clear pricing
+//   - Temperature, MaxPromptLength, MaxOutputLength or Timeout < 0 => clear,
+//     e.g. new(PatchClearValue), or new(float64(PatchClearValue)) for
+//     Temperature
+//   - Reasoning=&{} => clear reasoning
+//   - the patched preset must still set reasoning or temperature
 //   - at least one field/override field must be supplied
 type PatchModelPresetRequestBody struct {
 	ModelPresetPatch
@@ -106,20 +340,75 @@ type PatchModelPresetRequestBody struct {
 	Slug        *ModelSlug        `json:"slug,omitempty"`
 	DisplayName *ModelDisplayName `json:"displayName,omitempty"`
 	IsEnabled   *bool             `json:"isEnabled,omitempty"`
+	Tags        *[]string         `json:"tags,omitempty"`
+	Pricing     *ModelPricing     `json:"pricing,omitempty"`
+
+	// ExpectedModifiedAt, if set, makes the patch fail with a conflict unless
+	// the model preset is still at this modifiedAt.
+	ExpectedModifiedAt *time.Time `json:"expectedModifiedAt,omitempty"`
 }
 
 type PatchModelPresetRequest struct {
-	ProviderName  inferenceSpec.ProviderName `path:"providerName"  required:"true"`
-	ModelPresetID ModelPresetID              `path:"modelPresetID" required:"true"`
-	Body          *PatchModelPresetRequestBody
+	ProviderName   inferenceSpec.ProviderName `path:"providerName"  required:"true"`
+	ModelPresetID  ModelPresetID              `path:"modelPresetID" required:"true"`
+	IdempotencyKey string                     `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool                       `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body           *PatchModelPresetRequestBody
+}
+
+// PatchModelPresetResponseBody is the model preset a dry run would save and
+// its provider afterwards.
+type PatchModelPresetResponseBody struct {
+	ModelPreset    ModelPreset    `json:"modelPreset"`
+	ProviderPreset ProviderPreset `json:"providerPreset"`
+}
+
+// PatchModelPresetResponse has a Body only for dry runs.
+type PatchModelPresetResponse struct{ Body *PatchModelPresetResponseBody }
+
+// PatchAllModelPresetsRequestBody sets IsEnabled on every model preset of a
+// provider. A non-empty Tags limits it to presets with any of the tags.
+type PatchAllModelPresetsRequestBody struct {
+	IsEnabled bool     `json:"isEnabled"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+type PatchAllModelPresetsRequest struct {
+	ProviderName   inferenceSpec.ProviderName `path:"providerName" required:"true"`
+	IdempotencyKey string                     `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool                       `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body           *PatchAllModelPresetsRequestBody
+}
+
+type PatchAllModelPresetsResponseBody struct {
+	// UpdatedModelPresetIDs lists the presets whose flag changed, or would
+	// change in a dry run, sorted.
+	UpdatedModelPresetIDs []ModelPresetID `json:"updatedModelPresetIDs"`
+}
+
+type PatchAllModelPresetsResponse struct {
+	Body *PatchAllModelPresetsResponseBody
 }
-type PatchModelPresetResponse struct{}
 
 type DeleteModelPresetRequest struct {
-	ProviderName  inferenceSpec.ProviderName `path:"providerName"  required:"true"`
-	ModelPresetID ModelPresetID              `path:"modelPresetID" required:"true"`
+	ProviderName   inferenceSpec.ProviderName `path:"providerName"  required:"true"`
+	ModelPresetID  ModelPresetID              `path:"modelPresetID" required:"true"`
+	IdempotencyKey string                     `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool                       `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+}
+
+// DeleteModelPresetResponseBody is the model preset a dry run would delete
+// and its provider afterwards, whose default model is reset if it was the
+// deleted one.
+type DeleteModelPresetResponseBody struct {
+	ModelPreset    ModelPreset    `json:"modelPreset"`
+	ProviderPreset ProviderPreset `json:"providerPreset"`
+}
+
+// DeleteModelPresetResponse has a Body only for dry runs.
+type DeleteModelPresetResponse struct {
+	Body *DeleteModelPresetResponseBody
 }
-type DeleteModelPresetResponse struct{}
 
 type GetModelPresetRequest struct {
 	ProviderName  inferenceSpec.ProviderName `path:"providerName"  required:"true"`
@@ -142,18 +431,77 @@ type GetModelPresetResponse struct {
 	Body *GetModelPresetResponseBody
 }
 
+type GetProviderPresetRequest struct {
+	ProviderName inferenceSpec.ProviderName `path:"providerName" required:"true"`
+
+	// If false, a disabled provider returns ErrProviderNotFound.
+	IncludeDisabled bool `query:"includeDisabled"`
+}
+
+type GetProviderPresetResponse struct {
+	Body *ProviderPreset
+}
+
+type GetProviderPresetsBatchRequestBody struct {
+	ProviderNames   []inferenceSpec.ProviderName `json:"providerNames"             required:"true"`
+	IncludeDisabled bool                         `json:"includeDisabled,omitempty"`
+}
+
+type GetProviderPresetsBatchRequest struct {
+	Body *GetProviderPresetsBatchRequestBody
+}
+
+// GetProviderPresetsBatchItem holds either the provider or why it could not
+// be fetched.
+type GetProviderPresetsBatchItem struct {
+	ProviderName inferenceSpec.ProviderName `json:"providerName"`
+	Provider     *ProviderPreset            `json:"provider,omitempty"`
+	Error        string                     `json:"error,omitempty"`
+}
+
+type GetProviderPresetsBatchResponseBody struct {
+	// Items follow the order of the requested ProviderNames.
+	Items []GetProviderPresetsBatchItem `json:"items"`
+}
+
+type GetProviderPresetsBatchResponse struct {
+	Body *GetProviderPresetsBatchResponseBody
+}
+
+// ProviderPresetSortBy orders ListProviderPresets. Ties are broken by name.
+type ProviderPresetSortBy string
+
+const (
+	// ProviderPresetSortByModifiedAt lists the most recently modified first.
+	// It is the default.
+	ProviderPresetSortByModifiedAt ProviderPresetSortBy = "modifiedAt"
+	// ProviderPresetSortByCreatedAt lists the newest first.
+	ProviderPresetSortByCreatedAt ProviderPresetSortBy = "createdAt"
+	// ProviderPresetSortByDisplayName sorts alphabetically, ignoring case.
+	ProviderPresetSortByDisplayName ProviderPresetSortBy = "displayName"
+)
+
 type ProviderPageToken struct {
-	Names           []inferenceSpec.ProviderName `json:"n,omitempty"` //nolint:tagliatelle // PageToken Specific.
-	IncludeDisabled bool                         `json:"d,omitempty"` //nolint:tagliatelle // PageToken Specific.
-	PageSize        int                          `json:"s,omitempty"` //nolint:tagliatelle // PageToken Specific.
-	CursorSlug      inferenceSpec.ProviderName   `json:"c,omitempty"` //nolint:tagliatelle // PageToken Specific.
+	Names           []inferenceSpec.ProviderName    `json:"n,omitempty"`   //nolint:tagliatelle // PageToken Specific.
+	IncludeDisabled bool                            `json:"d,omitempty"`   //nolint:tagliatelle // PageToken Specific.
+	PageSize        int                             `json:"s,omitempty"`   //nolint:tagliatelle // PageToken Specific.
+	CursorSlug      inferenceSpec.ProviderName      `json:"c,omitempty"`   //nolint:tagliatelle // PageToken Specific.
+	Tags            []string                        `json:"t,omitempty"`   //nolint:tagliatelle // PageToken Specific.
+	SDKTypes        []inferenceSpec.ProviderSDKType `json:"sdk,omitempty"` //nolint:tagliatelle // PageToken Specific.
+	SortBy          ProviderPresetSortBy            `json:"o,omitempty"`   //nolint:tagliatelle // PageToken Specific.
 }
 
 type ListProviderPresetsRequest struct {
 	Names           []inferenceSpec.ProviderName `query:"names"`
 	IncludeDisabled bool                         `query:"includeDisabled"`
-	PageSize        int                          `query:"pageSize"`
-	PageToken       string                       `query:"pageToken"`
+	// Tags keeps only model presets carrying at least one of the tags;
+	// providers left without model presets are dropped.
+	Tags []string `query:"tags"`
+	// SDKTypes keeps only providers of one of the types.
+	SDKTypes  []inferenceSpec.ProviderSDKType `query:"sdkTypes"`
+	SortBy    ProviderPresetSortBy            `query:"sortBy"`
+	PageSize  int                             `query:"pageSize"`
+	PageToken string                          `query:"pageToken"`
 }
 type ListProviderPresetsResponseBody struct {
 	Providers     []ProviderPreset `json:"providers"`
@@ -162,3 +510,340 @@ type ListProviderPresetsResponseBody struct {
 type ListProviderPresetsResponse struct {
 	Body *ListProviderPresetsResponseBody
 }
+
+// CountProviderPresetsRequest takes the ListProviderPresets filters.
+type CountProviderPresetsRequest struct {
+	Names           []inferenceSpec.ProviderName    `query:"names"`
+	IncludeDisabled bool                            `query:"includeDisabled"`
+	Tags            []string                        `query:"tags"`
+	SDKTypes        []inferenceSpec.ProviderSDKType `query:"sdkTypes"`
+}
+
+// PresetCount counts matching presets. DisabledCount is part of Count.
+type PresetCount struct {
+	Count         int `json:"count"`
+	DisabledCount int `json:"disabledCount"`
+}
+
+type CountProviderPresetsResponse struct {
+	Body *PresetCount
+}
+
+// CountModelPresetsRequest counts the model presets of the providers that
+// ListProviderPresets would return for the same filters.
+type CountModelPresetsRequest struct {
+	Names           []inferenceSpec.ProviderName    `query:"names"`
+	IncludeDisabled bool                            `query:"includeDisabled"`
+	Tags            []string                        `query:"tags"`
+	SDKTypes        []inferenceSpec.ProviderSDKType `query:"sdkTypes"`
+}
+
+type CountModelPresetsResponse struct {
+	Body *PresetCount
+}
+
+type PostEmbeddingPresetRequestBody struct {
+	DisplayName  EmbeddingPresetDisplayName `json:"displayName"          required:"true"`
+	ProviderName inferenceSpec.ProviderName `json:"providerName"         required:"true"`
+	ModelName    ModelName                  `json:"modelName"            required:"true"`
+	Dimensions   int                        `json:"dimensions,omitempty"`
+	IsEnabled    bool                       `json:"isEnabled"            required:"true"`
+}
+
+type PostEmbeddingPresetRequest struct {
+	EmbeddingPresetID EmbeddingPresetID `path:"embeddingPresetID" required:"true"`
+	IdempotencyKey    string            `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun            bool              `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body              *PostEmbeddingPresetRequestBody
+}
+
+// PostEmbeddingPresetResponseBody is the embedding preset a dry run would
+// create.
+type PostEmbeddingPresetResponseBody struct {
+	EmbeddingPreset EmbeddingPreset `json:"embeddingPreset"`
+}
+
+// PostEmbeddingPresetResponse has a Body only for dry runs.
+type PostEmbeddingPresetResponse struct {
+	Body *PostEmbeddingPresetResponseBody
+}
+
+// PatchEmbeddingPresetRequestBody patches an embedding preset. Nil fields are
+// not provided; Dimensions=&0 restores the model's native size.
+type PatchEmbeddingPresetRequestBody struct {
+	DisplayName  *EmbeddingPresetDisplayName `json:"displayName,omitempty"`
+	ProviderName *inferenceSpec.ProviderName `json:"providerName,omitempty"`
+	ModelName    *ModelName                  `json:"modelName,omitempty"`
+	Dimensions   *int                        `json:"dimensions,omitempty"`
+	IsEnabled    *bool                       `json:"isEnabled,omitempty"`
+}
+
+type PatchEmbeddingPresetRequest struct {
+	EmbeddingPresetID EmbeddingPresetID `path:"embeddingPresetID" required:"true"`
+	IdempotencyKey    string            `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun            bool              `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body              *PatchEmbeddingPresetRequestBody
+}
+
+// PatchEmbeddingPresetResponseBody is the embedding preset a dry run would
+// save.
+type PatchEmbeddingPresetResponseBody struct {
+	EmbeddingPreset EmbeddingPreset `json:"embeddingPreset"`
+}
+
+// PatchEmbeddingPresetResponse has a Body only for dry runs.
+type PatchEmbeddingPresetResponse struct {
+	Body *PatchEmbeddingPresetResponseBody
+}
+
+type DeleteEmbeddingPresetRequest struct {
+	EmbeddingPresetID EmbeddingPresetID `path:"embeddingPresetID" required:"true"`
+	IdempotencyKey    string            `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun            bool              `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+}
+
+// DeleteEmbeddingPresetResponseBody is the embedding preset a dry run would
+// delete.
+type DeleteEmbeddingPresetResponseBody struct {
+	EmbeddingPreset EmbeddingPreset `json:"embeddingPreset"`
+}
+
+// DeleteEmbeddingPresetResponse has a Body only for dry runs.
+type DeleteEmbeddingPresetResponse struct {
+	Body *DeleteEmbeddingPresetResponseBody
+}
+
+type GetEmbeddingPresetRequest struct {
+	EmbeddingPresetID EmbeddingPresetID `path:"embeddingPresetID" required:"true"`
+
+	// If false, a disabled preset returns an error.
+	IncludeDisabled bool `query:"includeDisabled"`
+}
+
+type GetEmbeddingPresetResponse struct {
+	Body *EmbeddingPreset
+}
+
+type ListEmbeddingPresetsRequest struct {
+	IncludeDisabled bool `query:"includeDisabled"`
+}
+
+type ListEmbeddingPresetsResponseBody struct {
+	EmbeddingPresets []EmbeddingPreset `json:"embeddingPresets"`
+}
+
+type ListEmbeddingPresetsResponse struct {
+	Body *ListEmbeddingPresetsResponseBody
+}
+
+type PostParameterProfileRequestBody struct {
+	DisplayName   ParameterProfileDisplayName   `json:"displayName"             required:"true"`
+	Description   string                        `json:"description,omitempty"`
+	Temperature   *float64                      `json:"temperature,omitempty"`
+	Reasoning     *inferenceSpec.ReasoningParam `json:"reasoning,omitempty"`
+	StopSequences *[]string                     `json:"stopSequences,omitempty"`
+}
+
+type PostParameterProfileRequest struct {
+	ParameterProfileID ParameterProfileID `path:"parameterProfileID" required:"true"`
+	IdempotencyKey     string             `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun             bool               `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body               *PostParameterProfileRequestBody
+}
+
+// PostParameterProfileResponseBody is the parameter profile a dry run would
+// create.
+type PostParameterProfileResponseBody struct {
+	ParameterProfile ParameterProfile `json:"parameterProfile"`
+}
+
+// PostParameterProfileResponse has a Body only for dry runs.
+type PostParameterProfileResponse struct {
+	Body *PostParameterProfileResponseBody
+}
+
+// PatchParameterProfileRequestBody patches a parameter profile. Nil fields
+// are not provided; a negative temperature and an empty reasoning drop the
+// parameter from the profile.
+type PatchParameterProfileRequestBody struct {
+	DisplayName   *ParameterProfileDisplayName  `json:"displayName,omitempty"`
+	Description   *string                       `json:"description,omitempty"`
+	Temperature   *float64                      `json:"temperature,omitempty"`
+	Reasoning     *inferenceSpec.ReasoningParam `json:"reasoning,omitempty"`
+	StopSequences *[]string                     `json:"stopSequences,omitempty"`
+
+	// ExpectedModifiedAt, if set, makes the patch fail with a conflict unless
+	// the profile is still at this modifiedAt.
+	ExpectedModifiedAt *time.Time `json:"expectedModifiedAt,omitempty"`
+}
+
+type PatchParameterProfileRequest struct {
+	ParameterProfileID ParameterProfileID `path:"parameterProfileID" required:"true"`
+	IdempotencyKey     string             `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun             bool               `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body               *PatchParameterProfileRequestBody
+}
+
+// PatchParameterProfileResponseBody is the parameter profile a dry run would
+// save.
+type PatchParameterProfileResponseBody struct {
+	ParameterProfile ParameterProfile `json:"parameterProfile"`
+}
+
+// PatchParameterProfileResponse has a Body only for dry runs.
+type PatchParameterProfileResponse struct {
+	Body *PatchParameterProfileResponseBody
+}
+
+type DeleteParameterProfileRequest struct {
+	ParameterProfileID ParameterProfileID `path:"parameterProfileID" required:"true"`
+	IdempotencyKey     string             `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun             bool               `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	// ExpectedModifiedAt, if set, makes the delete fail with a conflict unless
+	// the profile is still at this modifiedAt.
+	ExpectedModifiedAt *time.Time `query:"expectedModifiedAt" required:"false"`
+}
+
+// DeleteParameterProfileResponseBody is the parameter profile a dry run would
+// delete.
+type DeleteParameterProfileResponseBody struct {
+	ParameterProfile ParameterProfile `json:"parameterProfile"`
+}
+
+// DeleteParameterProfileResponse has a Body only for dry runs.
+type DeleteParameterProfileResponse struct {
+	Body *DeleteParameterProfileResponseBody
+}
+
+type GetParameterProfileRequest struct {
+	ParameterProfileID ParameterProfileID `path:"parameterProfileID" required:"true"`
+}
+
+type GetParameterProfileResponse struct {
+	Body *ParameterProfile
+}
+
+type ListParameterProfilesRequest struct{}
+
+type ListParameterProfilesResponseBody struct {
+	ParameterProfiles []ParameterProfile `json:"parameterProfiles"`
+}
+
+type ListParameterProfilesResponse struct {
+	Body *ListParameterProfilesResponseBody
+}
+
+type ApplyParameterProfileRequestBody struct {
+	ModelPresets []ModelPresetRef `json:"modelPresets" required:"true"`
+
+	// ExpectedModifiedAt, if set, makes the apply fail with a conflict unless
+	// the profile is still at this modifiedAt, e.g. the one a dry run showed.
+	ExpectedModifiedAt *time.Time `json:"expectedModifiedAt,omitempty"`
+}
+
+// ApplyParameterProfileRequest stamps a parameter profile onto user model
+// presets. Either every listed preset is updated or none is.
+type ApplyParameterProfileRequest struct {
+	ParameterProfileID ParameterProfileID `path:"parameterProfileID" required:"true"`
+	IdempotencyKey     string             `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun             bool               `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body               *ApplyParameterProfileRequestBody
+}
+
+type ApplyParameterProfileResponseBody struct {
+	// UpdatedModelPresets lists the presets the profile changed.
+	UpdatedModelPresets []ModelPresetRef `json:"updatedModelPresets"`
+	// ModelPresets are the changed presets as a dry run would save them, in
+	// the order of UpdatedModelPresets. Only dry runs set it.
+	ModelPresets []ModelPreset `json:"modelPresets,omitempty"`
+}
+
+type ApplyParameterProfileResponse struct {
+	Body *ApplyParameterProfileResponseBody
+}
+
+// SetReasoningBudgetPercentRequestBody sizes a hybridWithTokens reasoning
+// budget as a share of the model preset's maxOutputLength.
+type SetReasoningBudgetPercentRequestBody struct {
+	Percent float64 `json:"percent" required:"true" doc:"Share of maxOutputLength, above 0 and below 100."`
+}
+
+type SetReasoningBudgetPercentRequest struct {
+	ProviderName  inferenceSpec.ProviderName `path:"providerName"  required:"true"`
+	ModelPresetID ModelPresetID              `path:"modelPresetID" required:"true"`
+	DryRun        bool                       `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body          *SetReasoningBudgetPercentRequestBody
+}
+
+// SetReasoningBudgetPercentResponseBody is the model preset with the computed
+// reasoning budget.
+type SetReasoningBudgetPercentResponseBody struct {
+	ModelPreset ModelPreset `json:"modelPreset"`
+}
+
+type SetReasoningBudgetPercentResponse struct {
+	Body *SetReasoningBudgetPercentResponseBody
+}
+
+type GenerateEmbeddingsRequestBody struct {
+	Inputs []string `json:"inputs" required:"true"`
+
+	// APIKey is filled in by the app from the stored auth key, never by callers.
+	APIKey string `json:"-"`
+}
+
+type GenerateEmbeddingsRequest struct {
+	EmbeddingPresetID EmbeddingPresetID `path:"embeddingPresetID" required:"true"`
+	Body              *GenerateEmbeddingsRequestBody
+}
+
+type GenerateEmbeddingsResponseBody struct {
+	EmbeddingPresetID EmbeddingPresetID          `json:"embeddingPresetID"`
+	ProviderName      inferenceSpec.ProviderName `json:"providerName"`
+	ModelName         ModelName                  `json:"modelName"`
+	// Dimensions is the length of every returned vector.
+	Dimensions int `json:"dimensions"`
+	// Embeddings holds one vector per input, in input order.
+	Embeddings [][]float32 `json:"embeddings"`
+	// InputTokens is zero when the provider does not report usage.
+	InputTokens int `json:"inputTokens,omitempty"`
+}
+
+type GenerateEmbeddingsResponse struct {
+	Body *GenerateEmbeddingsResponseBody
+}
+
+// RetryModelPresetStoreWritableRequest retries writing the user presets
+// after the store turned read-only.
+type RetryModelPresetStoreWritableRequest struct{}
+
+type RetryModelPresetStoreWritableResponseBody struct {
+	fsutil.WriteStatus
+}
+
+type RetryModelPresetStoreWritableResponse struct {
+	Body *RetryModelPresetStoreWritableResponseBody
+}
+
+type ListModelPresetStoreBackupsRequest struct{}
+
+type ListModelPresetStoreBackupsResponseBody struct {
+	// Backups of the presets file, newest first.
+	Backups []fsutil.BackupInfo `json:"backups"`
+}
+
+type ListModelPresetStoreBackupsResponse struct {
+	Body *ListModelPresetStoreBackupsResponseBody
+}
+
+type RestoreModelPresetStoreFromBackupRequestBody struct {
+	Name string `json:"name" required:"true"`
+}
+
+// RestoreModelPresetStoreFromBackupRequest replaces the user presets with a
+// backup. The replaced content is backed up first.
+type RestoreModelPresetStoreFromBackupRequest struct {
+	Body *RestoreModelPresetStoreFromBackupRequestBody
+}
+
+type RestoreModelPresetStoreFromBackupResponse struct{}
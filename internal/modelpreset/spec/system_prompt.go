@@ -0,0 +1,61 @@
+package spec
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MaxSystemPromptLength bounds the system prompt stored on a model preset, in
+// bytes.
+const MaxSystemPromptLength = 32 * 1024
+
+// Placeholders a model preset system prompt may contain, written as
+// "{{name}}". They are expanded when a completion is sent.
+const (
+	SystemPromptPlaceholderDate         = "date"
+	SystemPromptPlaceholderTime         = "time"
+	SystemPromptPlaceholderWeekday      = "weekday"
+	SystemPromptPlaceholderModelName    = "modelName"
+	SystemPromptPlaceholderProviderName = "providerName"
+)
+
+var systemPromptPlaceholderRe = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+// IsSystemPromptPlaceholder reports whether name is a supported placeholder.
+func IsSystemPromptPlaceholder(name string) bool {
+	switch name {
+	case SystemPromptPlaceholderDate,
+		SystemPromptPlaceholderTime,
+		SystemPromptPlaceholderWeekday,
+		SystemPromptPlaceholderModelName,
+		SystemPromptPlaceholderProviderName:
+		return true
+	}
+	return false
+}
+
+// SystemPromptPlaceholders returns the placeholder names used in prompt, in
+// order of appearance.
+func SystemPromptPlaceholders(prompt string) []string {
+	var names []string
+	for _, m := range systemPromptPlaceholderRe.FindAllStringSubmatch(prompt, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// ExpandSystemPromptPlaceholders replaces the placeholders in prompt that have
+// an entry in values. Unknown placeholders are left as written. Other prompt
+// templates, such as those of scheduled tasks, pass their own names.
+func ExpandSystemPromptPlaceholders(prompt string, values map[string]string) string {
+	if !strings.Contains(prompt, "{{") {
+		return prompt
+	}
+	return systemPromptPlaceholderRe.ReplaceAllStringFunc(prompt, func(m string) string {
+		name := systemPromptPlaceholderRe.FindStringSubmatch(m)[1]
+		if v, ok := values[name]; ok {
+			return v
+		}
+		return m
+	})
+}
@@ -2,6 +2,8 @@ package spec
 
 import (
 	"errors"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/flexigpt/inference-go/capabilityoverride"
@@ -31,9 +33,42 @@ const (
 
 	DefaultOpenAIOrigin                = "https://api.openai.com"
 	DefaultOpenAIChatCompletionsPrefix = "/v1/chat/completions"
+
+	DefaultGoogleGenerateContentOrigin = "https://generativelanguage.googleapis.com"
+	DefaultGoogleGenerateContentPrefix = "/"
+	//nolint:gosec // The header name, not a key.
+	DefaultGoogleGenerateContentAPIKeyHeaderKey = "x-goog-api-key"
+)
+
+var (
+	OpenAIChatCompletionsDefaultHeaders = map[string]string{"content-type": "application/json"}
+	AnthropicDefaultHeaders             = map[string]string{
+		"content-type":      "application/json",
+		"anthropic-version": "2023-06-01",
+	}
+	GoogleGenerateContentDefaultHeaders = map[string]string{"content-type": "application/json"}
+)
+
+// Local inference servers. Their SDK types are resolved to a wire SDK type by
+// WireSDKType before a provider is handed to inference-go.
+const (
+	ProviderSDKTypeOllama   inferenceSpec.ProviderSDKType = "providerSDKTypeOllama"
+	ProviderSDKTypeLlamaCPP inferenceSpec.ProviderSDKType = "providerSDKTypeLlamaCPP"
+
+	DefaultOllamaOrigin     = "http://127.0.0.1:11434"
+	DefaultOllamaModelsPath = "/api/tags"
+	DefaultLlamaCPPOrigin   = "http://127.0.0.1:8080"
 )
 
-var OpenAIChatCompletionsDefaultHeaders = map[string]string{"content-type": "application/json"}
+// Azure OpenAI providers address one deployment of an Azure OpenAI resource
+// through the OpenAI Chat Completions API.
+const (
+	ProviderSDKTypeAzureOpenAI inferenceSpec.ProviderSDKType = "providerSDKTypeAzureOpenAI"
+
+	//nolint:gosec // The header name, not a key.
+	DefaultAzureOpenAIAPIKeyHeaderKey = "api-key"
+	AzureOpenAIAPIVersionQueryKey     = "api-version"
+)
 
 var (
 	ErrInvalidDir = errors.New("invalid directory")
@@ -42,12 +77,23 @@ var (
 	ErrProviderPresetAlreadyExists = errors.New("provider preset already exists")
 	ErrBuiltInProviderAbsent       = errors.New("provider not found in built-in data")
 	ErrNilProvider                 = errors.New("provider preset is nil")
+	ErrProviderPresetDeleting      = errors.New("provider preset is pending deletion")
+	ErrProviderNameReserved        = errors.New("provider name is reserved")
 
 	ErrModelPresetNotFound      = errors.New("model preset not found")
 	ErrModelPresetAlreadyExists = errors.New("model preset already exists")
 	ErrNilModelPreset           = errors.New("model preset is nil")
 	ErrNoModelPresets           = errors.New("provider has no model presets")
 
+	ErrEmbeddingPresetNotFound      = errors.New("embedding preset not found")
+	ErrEmbeddingPresetAlreadyExists = errors.New("embedding preset already exists")
+	ErrEmbeddingsUnsupported        = errors.New("provider does not support embeddings")
+
+	ErrParameterProfileNotFound      = errors.New("parameter profile not found")
+	ErrParameterProfileAlreadyExists = errors.New("parameter profile already exists")
+
+	ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+
 	ErrInvalidTimestamp = errors.New("zero timestamp")
 	ErrBuiltInReadOnly  = errors.New("built-in resource is read-only")
 )
@@ -59,6 +105,12 @@ type (
 	ModelPresetID    string
 
 	ProviderDisplayName string
+
+	EmbeddingPresetID          string
+	EmbeddingPresetDisplayName string
+
+	ParameterProfileID          string
+	ParameterProfileDisplayName string
 )
 
 // ModelPresetRef identifies a model preset inside a provider namespace.
@@ -72,6 +124,117 @@ func (r ModelPresetRef) IsZero() bool {
 	return r.ProviderName == "" || r.ModelPresetID == ""
 }
 
+// ModelPricing is the price of a model in USD per million tokens. A zero
+// CachedInputPerMTok bills cached input at InputPerMTok.
+type ModelPricing struct {
+	InputPerMTok       float64 `json:"inputPerMTok"`
+	CachedInputPerMTok float64 `json:"cachedInputPerMTok,omitempty"`
+	OutputPerMTok      float64 `json:"outputPerMTok"`
+}
+
+func (p ModelPricing) IsZero() bool {
+	return p == ModelPricing{}
+}
+
+// ProviderRateLimits throttles completions sent to a provider. Zero rates are
+// unlimited. MaxQueueDepth bounds how many requests may wait for capacity
+// before new ones are rejected; zero uses the wrapper default.
+type ProviderRateLimits struct {
+	RequestsPerMinute int `json:"requestsPerMinute,omitempty"`
+	TokensPerMinute   int `json:"tokensPerMinute,omitempty"`
+	MaxQueueDepth     int `json:"maxQueueDepth,omitempty"`
+}
+
+func (l ProviderRateLimits) IsZero() bool {
+	return l == ProviderRateLimits{}
+}
+
+// ProviderRetryPolicy retries failed completions with exponential backoff.
+// MaxAttempts counts the first attempt; zero or one disables retries. Zero
+// backoffs and empty RetryOnStatusCodes use the wrapper defaults. Timeouts
+// are always retried.
+type ProviderRetryPolicy struct {
+	MaxAttempts        int   `json:"maxAttempts,omitempty"`
+	InitialBackoffMS   int   `json:"initialBackoffMS,omitempty"`
+	MaxBackoffMS       int   `json:"maxBackoffMS,omitempty"`
+	RetryOnStatusCodes []int `json:"retryOnStatusCodes,omitempty"`
+}
+
+func (p ProviderRetryPolicy) IsZero() bool {
+	return p.MaxAttempts == 0 && p.InitialBackoffMS == 0 && p.MaxBackoffMS == 0 &&
+		len(p.RetryOnStatusCodes) == 0
+}
+
+// ProviderResilience configures how completions recover from provider
+// failures. Failover presets are tried in order once the provider still fails
+// with a 5xx status or a timeout after its retries.
+type ProviderResilience struct {
+	Retry    *ProviderRetryPolicy `json:"retry,omitempty"`
+	Failover []ModelPresetRef     `json:"failover,omitempty"`
+}
+
+func (r ProviderResilience) IsZero() bool {
+	return (r.Retry == nil || r.Retry.IsZero()) && len(r.Failover) == 0
+}
+
+// AzureOpenAIConfig identifies an Azure OpenAI deployment. The chat completion
+// path of an Azure provider is derived from it and the API version is sent as
+// the api-version query parameter.
+type AzureOpenAIConfig struct {
+	ResourceName   string `json:"resourceName"`
+	DeploymentName string `json:"deploymentName"`
+	APIVersion     string `json:"apiVersion"`
+}
+
+// Origin is the default endpoint of the Azure resource.
+func (c AzureOpenAIConfig) Origin() string {
+	return "https://" + c.ResourceName + ".openai.azure.com"
+}
+
+func (c AzureOpenAIConfig) ChatCompletionPathPrefix() string {
+	return "/openai/deployments/" + url.PathEscape(c.DeploymentName) + "/chat/completions"
+}
+
+// ProviderTLSConfig lets a provider reach a gateway behind a private PKI. The
+// client certificate and key are PEM secrets kept as setting store auth keys
+// of type "providerTLS"; only their key names are stored here.
+type ProviderTLSConfig struct {
+	// CABundlePath is a PEM file trusted in addition to the system roots and
+	// the CA bundle of the network settings.
+	CABundlePath      string `json:"caBundlePath,omitempty"`
+	ClientCertKeyName string `json:"clientCertKeyName,omitempty"`
+	ClientKeyKeyName  string `json:"clientKeyKeyName,omitempty"`
+}
+
+func (c ProviderTLSConfig) IsZero() bool {
+	return c == ProviderTLSConfig{}
+}
+
+type ImageFormat string
+
+const (
+	ImageFormatPNG  ImageFormat = "png"
+	ImageFormatJPEG ImageFormat = "jpeg"
+)
+
+// ProviderImageProcessing prepares image attachments before they are sent to
+// the provider. Any re-encoded image loses its EXIF data, after its
+// orientation has been applied to the pixels.
+type ProviderImageProcessing struct {
+	// MaxDimension caps the longer side in pixels. Zero keeps the size.
+	MaxDimension int `json:"maxDimension,omitempty"`
+	// Format converts images to PNG or JPEG. Empty keeps the format.
+	Format ImageFormat `json:"format,omitempty"`
+	// JPEGQuality is used when encoding JPEG, from 1 to 100. Zero means 85.
+	JPEGQuality int `json:"jpegQuality,omitempty"`
+	// StripMetadata re-encodes images that need no other change.
+	StripMetadata bool `json:"stripMetadata,omitempty"`
+}
+
+func (c ProviderImageProcessing) IsZero() bool {
+	return c == ProviderImageProcessing{}
+}
+
 // ModelPresetPatch is the reusable set of persisted model-preset knobs.
 //
 // PATCH semantics:
@@ -80,7 +243,8 @@ func (r ModelPresetRef) IsZero() bool {
 //   - nil => not provided
 //   - non-nil empty slice => explicitly set to empty
 //
-// Note: PATCH does not support generic "clear to nil" semantics.
+// PatchModelPreset clears numeric knobs and reasoning through sentinel
+// values; see PatchModelPresetRequestBody.
 type ModelPresetPatch struct {
 	Stream          *bool                         `json:"stream,omitempty"`
 	MaxPromptLength *int                          `json:"maxPromptLength,omitempty"`
@@ -113,6 +277,13 @@ type ModelPreset struct {
 	Slug          ModelSlug        `json:"slug"          required:"true"`
 	IsEnabled     bool             `json:"isEnabled"     required:"true"`
 
+	// Tags group presets by workload (e.g. "coding", "cheap", "long-context").
+	// Built-in presets carry their tags in the overlay store.
+	Tags []string `json:"tags,omitempty"`
+	// Pricing is used to compute the cost of recorded usage. Built-in
+	// presets carry their pricing in the overlay store.
+	Pricing *ModelPricing `json:"pricing,omitempty"`
+
 	CreatedAt  time.Time `json:"createdAt"`
 	ModifiedAt time.Time `json:"modifiedAt"`
 	IsBuiltIn  bool      `json:"isBuiltIn"`
@@ -136,13 +307,130 @@ type ProviderPreset struct {
 	// CapabilitiesOverride is a provider-wide stored override. Model overrides take precedence.
 	// This is NOT the derived/effective capability profile.
 	CapabilitiesOverride *capabilityoverride.ModelCapabilitiesOverride `json:"capabilitiesOverride,omitempty"`
+	// RateLimits is applied per provider by the inference wrapper. Built-in
+	// providers carry their limits in the overlay store.
+	RateLimits *ProviderRateLimits `json:"rateLimits,omitempty"`
+	// Resilience is applied by the inference wrapper. Built-in providers
+	// carry it in the overlay store.
+	Resilience *ProviderResilience `json:"resilience,omitempty"`
+	// Azure is required for, and only valid with, ProviderSDKTypeAzureOpenAI.
+	Azure *AzureOpenAIConfig `json:"azure,omitempty"`
+	// TLS is only supported on user providers.
+	TLS *ProviderTLSConfig `json:"tls,omitempty"`
+	// ImageProcessing is applied by the inference wrapper. Built-in providers
+	// carry it in the overlay store; hosted vision providers default to a
+	// MaxDimension.
+	ImageProcessing *ProviderImageProcessing `json:"imageProcessing,omitempty"`
 
 	DefaultModelPresetID ModelPresetID                 `json:"defaultModelPresetID"`
 	ModelPresets         map[ModelPresetID]ModelPreset `json:"modelPresets"`
+
+	// SoftDeletedAt is set while a deleted user provider is recoverable via undelete.
+	SoftDeletedAt *time.Time `json:"softDeletedAt,omitempty"`
+}
+
+// EmbeddingPreset binds an embedding model served by a provider preset. It
+// reuses the provider's origin, headers and auth key.
+type EmbeddingPreset struct {
+	SchemaVersion string                     `json:"schemaVersion" required:"true"`
+	ID            EmbeddingPresetID          `json:"id"            required:"true"`
+	DisplayName   EmbeddingPresetDisplayName `json:"displayName"   required:"true"`
+	ProviderName  inferenceSpec.ProviderName `json:"providerName"  required:"true"`
+	// ModelName is the embedding model; for Azure OpenAI providers it is the
+	// embedding deployment name.
+	ModelName ModelName `json:"modelName" required:"true"`
+	// Dimensions requests shortened vectors from models that support it. Zero
+	// keeps the model's native size.
+	Dimensions int  `json:"dimensions,omitempty"`
+	IsEnabled  bool `json:"isEnabled"            required:"true"`
+
+	CreatedAt  time.Time `json:"createdAt"`
+	ModifiedAt time.Time `json:"modifiedAt"`
 }
 
 type PresetsSchema struct {
-	SchemaVersion   string                                        `json:"schemaVersion"`
-	DefaultProvider inferenceSpec.ProviderName                    `json:"defaultProvider"`
-	ProviderPresets map[inferenceSpec.ProviderName]ProviderPreset `json:"providerPresets"`
+	SchemaVersion    string                                        `json:"schemaVersion"`
+	DefaultProvider  inferenceSpec.ProviderName                    `json:"defaultProvider"`
+	ProviderPresets  map[inferenceSpec.ProviderName]ProviderPreset `json:"providerPresets"`
+	EmbeddingPresets map[EmbeddingPresetID]EmbeddingPreset         `json:"embeddingPresets,omitempty"`
+
+	ParameterProfiles map[ParameterProfileID]ParameterProfile `json:"parameterProfiles,omitempty"`
+
+	// ReservedNamesDigest is the digest of the reserved provider namespace the
+	// provider names were last migrated against.
+	ReservedNamesDigest string `json:"reservedNamesDigest,omitempty"`
+}
+
+// ParameterProfile is a named set of tuned model parameters that can be
+// stamped onto many user model presets. Nil fields are left alone when the
+// profile is applied; an empty StopSequences clears the model's stop
+// sequences. Top-p is not part of a profile because model presets have no
+// top-p parameter.
+type ParameterProfile struct {
+	SchemaVersion string                      `json:"schemaVersion"         required:"true"`
+	ID            ParameterProfileID          `json:"id"                    required:"true"`
+	DisplayName   ParameterProfileDisplayName `json:"displayName"           required:"true"`
+	Description   string                      `json:"description,omitempty"`
+
+	Temperature   *float64                      `json:"temperature,omitempty"`
+	Reasoning     *inferenceSpec.ReasoningParam `json:"reasoning,omitempty"`
+	StopSequences *[]string                     `json:"stopSequences,omitempty"`
+
+	CreatedAt  time.Time `json:"createdAt"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+// PresetChangeKind classifies a PresetChangeEvent.
+type PresetChangeKind string
+
+const (
+	PresetChangeProviderCreated        PresetChangeKind = "providerCreated"
+	PresetChangeProviderUpdated        PresetChangeKind = "providerUpdated"
+	PresetChangeProviderDeleted        PresetChangeKind = "providerDeleted"
+	PresetChangeModelCreated           PresetChangeKind = "modelCreated"
+	PresetChangeModelUpdated           PresetChangeKind = "modelUpdated"
+	PresetChangeModelDeleted           PresetChangeKind = "modelDeleted"
+	PresetChangeDefaultProviderChanged PresetChangeKind = "defaultProviderChanged"
+	PresetChangeEmbeddingCreated       PresetChangeKind = "embeddingCreated"
+	PresetChangeEmbeddingUpdated       PresetChangeKind = "embeddingUpdated"
+	PresetChangeEmbeddingDeleted       PresetChangeKind = "embeddingDeleted"
+	// PresetChangeReloaded reports that the presets file was changed outside
+	// the app or restored from a backup; any preset may have changed and
+	// ProviderName is empty.
+	PresetChangeReloaded PresetChangeKind = "reloaded"
+)
+
+// PresetChangeEvent reports a committed change to provider, model or
+// embedding presets. ModelPresetID is set for model events only and
+// EmbeddingPresetID for embedding events only.
+type PresetChangeEvent struct {
+	Kind              PresetChangeKind           `json:"kind"`
+	ProviderName      inferenceSpec.ProviderName `json:"providerName"`
+	ModelPresetID     ModelPresetID              `json:"modelPresetID,omitempty"`
+	EmbeddingPresetID EmbeddingPresetID          `json:"embeddingPresetID,omitempty"`
+	At                time.Time                  `json:"at"`
+}
+
+// IsLocalSDKType reports whether t is served by a local inference server.
+func IsLocalSDKType(t inferenceSpec.ProviderSDKType) bool {
+	return t == ProviderSDKTypeOllama || t == ProviderSDKTypeLlamaCPP
+}
+
+// WireSDKType returns the SDK type inference-go uses to talk to a provider.
+// Local servers speak the Anthropic Messages API when the chat completion path
+// ends in "/messages" and the OpenAI Chat Completions API otherwise.
+func WireSDKType(
+	t inferenceSpec.ProviderSDKType,
+	chatCompletionPathPrefix string,
+) inferenceSpec.ProviderSDKType {
+	if t == ProviderSDKTypeAzureOpenAI {
+		return inferenceSpec.ProviderSDKTypeOpenAIChatCompletions
+	}
+	if !IsLocalSDKType(t) {
+		return t
+	}
+	if strings.HasSuffix(strings.TrimRight(chatCompletionPathPrefix, "/"), "/messages") {
+		return inferenceSpec.ProviderSDKTypeAnthropic
+	}
+	return inferenceSpec.ProviderSDKTypeOpenAIChatCompletions
 }
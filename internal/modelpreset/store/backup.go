@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+)
+
+// ListModelPresetStoreBackups returns the backups of the presets file. It is
+// empty unless the store was opened WithBackups.
+func (s *ModelPresetStore) ListModelPresetStoreBackups(
+	ctx context.Context,
+	_ *spec.ListModelPresetStoreBackupsRequest,
+) (*spec.ListModelPresetStoreBackupsResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	backups, err := s.backups.List(s.userFilePath())
+	if err != nil {
+		return nil, err
+	}
+	return &spec.ListModelPresetStoreBackupsResponse{
+		Body: &spec.ListModelPresetStoreBackupsResponseBody{Backups: backups},
+	}, nil
+}
+
+// RestoreModelPresetStoreFromBackup validates a backup and writes it as the
+// user presets. Subscribers get a reloaded event.
+func (s *ModelPresetStore) RestoreModelPresetStoreFromBackup(
+	ctx context.Context,
+	req *spec.RestoreModelPresetStoreFromBackupRequest,
+) (*spec.RestoreModelPresetStoreFromBackupResponse, error) {
+	if req == nil || req.Body == nil || strings.TrimSpace(req.Body.Name) == "" {
+		return nil, errors.New("backup name required")
+	}
+	if s.backups == nil {
+		return nil, errors.New("model preset backups are disabled")
+	}
+	data, err := s.backups.Read(s.userFilePath(), req.Body.Name)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("backup %q: %w", req.Body.Name, err)
+	}
+	if _, err := decodeUserPresets(raw); err != nil {
+		return nil, fmt.Errorf("backup %q: %w", req.Body.Name, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The backup replaces the file without the current content being read,
+	// so a corrupt file can be restored.
+	err = s.writeGuard.Write(func() error {
+		s.invalidateProviderSnapshot()
+		if err := s.backups.Restore(s.userFilePath(), req.Body.Name); err != nil {
+			return err
+		}
+		return s.observeWrittenUserFile()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.readAllUserPresets(); err != nil {
+		return nil, err
+	}
+	logger.Info("model presets restored from backup", "name", req.Body.Name)
+	s.enqueuePresetChanges(spec.PresetChangeEvent{Kind: spec.PresetChangeReloaded})
+	return &spec.RestoreModelPresetStoreFromBackupResponse{}, nil
+}
@@ -2,9 +2,13 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"sync"
 	"time"
 
@@ -26,11 +30,41 @@ type builtInModelKey spec.ModelPresetID
 func (builtInModelKey) Group() overlay.GroupID { return "models" }
 func (k builtInModelKey) ID() overlay.KeyID    { return overlay.KeyID(k) }
 
+type builtInModelTagsKey spec.ModelPresetID
+
+func (builtInModelTagsKey) Group() overlay.GroupID { return "modelTags" }
+func (k builtInModelTagsKey) ID() overlay.KeyID    { return overlay.KeyID(k) }
+
+type builtInModelPricingKey spec.ModelPresetID
+
+func (builtInModelPricingKey) Group() overlay.GroupID { return "modelPricing" }
+func (k builtInModelPricingKey) ID() overlay.KeyID    { return overlay.KeyID(k) }
+
+type builtInModelSystemPromptKey spec.ModelPresetID
+
+func (builtInModelSystemPromptKey) Group() overlay.GroupID { return "modelSystemPrompts" }
+func (k builtInModelSystemPromptKey) ID() overlay.KeyID    { return overlay.KeyID(k) }
+
 type builtInProviderDefaultModelIDKey inferenceSpec.ProviderName
 
 func (builtInProviderDefaultModelIDKey) Group() overlay.GroupID { return "providerDefaultModelIDs" }
 func (k builtInProviderDefaultModelIDKey) ID() overlay.KeyID    { return overlay.KeyID(k) }
 
+type builtInProviderRateLimitsKey inferenceSpec.ProviderName
+
+func (builtInProviderRateLimitsKey) Group() overlay.GroupID { return "providerRateLimits" }
+func (k builtInProviderRateLimitsKey) ID() overlay.KeyID    { return overlay.KeyID(k) }
+
+type builtInProviderResilienceKey inferenceSpec.ProviderName
+
+func (builtInProviderResilienceKey) Group() overlay.GroupID { return "providerResilience" }
+func (k builtInProviderResilienceKey) ID() overlay.KeyID    { return overlay.KeyID(k) }
+
+type builtInProviderImageKey inferenceSpec.ProviderName
+
+func (builtInProviderImageKey) Group() overlay.GroupID { return "providerImageProcessing" }
+func (k builtInProviderImageKey) ID() overlay.KeyID    { return overlay.KeyID(k) }
+
 // BuiltInPresets loads built-in preset assets and maintains an overlay store.
 type BuiltInPresets struct {
 	// Immutable original data.
@@ -42,6 +76,8 @@ type BuiltInPresets struct {
 	mu         sync.RWMutex
 	viewProv   map[inferenceSpec.ProviderName]spec.ProviderPreset
 	viewModels map[inferenceSpec.ProviderName]map[spec.ModelPresetID]spec.ModelPreset
+	// Bumped on every change of the view.
+	viewGen uint64
 
 	// IO.
 	overlayBaseDir string
@@ -49,7 +85,13 @@ type BuiltInPresets struct {
 	store                              *overlay.Store
 	providerOverlayFlags               *overlay.TypedGroup[builtInProviderKey, bool]
 	modelOverlayFlags                  *overlay.TypedGroup[builtInModelKey, bool]
+	modelTagsOverlayFlags              *overlay.TypedGroup[builtInModelTagsKey, []string]
+	modelPricingOverlayFlags           *overlay.TypedGroup[builtInModelPricingKey, spec.ModelPricing]
+	modelSystemPromptOverlayFlags      *overlay.TypedGroup[builtInModelSystemPromptKey, string]
 	providerDefaultModelIDOverlayFlags *overlay.TypedGroup[builtInProviderDefaultModelIDKey, spec.ModelPresetID]
+	providerRateLimitsOverlayFlags     *overlay.TypedGroup[builtInProviderRateLimitsKey, spec.ProviderRateLimits]
+	providerResilienceOverlayFlags     *overlay.TypedGroup[builtInProviderResilienceKey, spec.ProviderResilience]
+	providerImageOverlayFlags          *overlay.TypedGroup[builtInProviderImageKey, spec.ProviderImageProcessing]
 
 	rebuilder *builtin.AsyncRebuilder
 }
@@ -77,7 +119,13 @@ func NewBuiltInPresets(
 		filepath.Join(overlayBaseDir, spec.ModelPresetsBuiltInOverlayDBFileName),
 		overlay.WithKeyType[builtInProviderKey](),
 		overlay.WithKeyType[builtInModelKey](),
+		overlay.WithKeyType[builtInModelTagsKey](),
+		overlay.WithKeyType[builtInModelPricingKey](),
+		overlay.WithKeyType[builtInModelSystemPromptKey](),
 		overlay.WithKeyType[builtInProviderDefaultModelIDKey](),
+		overlay.WithKeyType[builtInProviderRateLimitsKey](),
+		overlay.WithKeyType[builtInProviderResilienceKey](),
+		overlay.WithKeyType[builtInProviderImageKey](),
 	)
 	if err != nil {
 		return nil, err
@@ -102,16 +150,49 @@ func NewBuiltInPresets(
 	if err != nil {
 		return nil, err
 	}
+	modelTagsOverlayFlags, err := overlay.NewTypedGroup[builtInModelTagsKey, []string](ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	modelPricingOverlayFlags, err := overlay.NewTypedGroup[builtInModelPricingKey, spec.ModelPricing](ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	modelSystemPromptOverlayFlags, err := overlay.NewTypedGroup[builtInModelSystemPromptKey, string](ctx, store)
+	if err != nil {
+		return nil, err
+	}
 
 	providerDefaultModelIDOverlayFlags, err := overlay.NewTypedGroup[
 		builtInProviderDefaultModelIDKey, spec.ModelPresetID](ctx, store)
 	if err != nil {
 		return nil, err
 	}
+	providerRateLimitsOverlayFlags, err := overlay.NewTypedGroup[
+		builtInProviderRateLimitsKey, spec.ProviderRateLimits](ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	providerResilienceOverlayFlags, err := overlay.NewTypedGroup[
+		builtInProviderResilienceKey, spec.ProviderResilience](ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	providerImageOverlayFlags, err := overlay.NewTypedGroup[
+		builtInProviderImageKey, spec.ProviderImageProcessing](ctx, store)
+	if err != nil {
+		return nil, err
+	}
 
 	bi.providerOverlayFlags = providerOverlayFlags
 	bi.modelOverlayFlags = modelOverlayFlags
+	bi.modelTagsOverlayFlags = modelTagsOverlayFlags
+	bi.modelPricingOverlayFlags = modelPricingOverlayFlags
+	bi.modelSystemPromptOverlayFlags = modelSystemPromptOverlayFlags
 	bi.providerDefaultModelIDOverlayFlags = providerDefaultModelIDOverlayFlags
+	bi.providerRateLimitsOverlayFlags = providerRateLimitsOverlayFlags
+	bi.providerResilienceOverlayFlags = providerResilienceOverlayFlags
+	bi.providerImageOverlayFlags = providerImageOverlayFlags
 
 	for _, o := range opts {
 		o(bi)
@@ -204,7 +285,7 @@ func (b *BuiltInPresets) SetProviderEnabled(
 	pp := b.viewProv[name]
 	pp.IsEnabled = enabled
 	pp.ModifiedAt = flag.ModifiedAt
-	b.viewProv[name] = pp
+	b.setViewProvider(name, pp)
 	cloned := cloneProviderPreset(pp)
 	b.mu.Unlock()
 
@@ -239,7 +320,160 @@ func (b *BuiltInPresets) SetModelPresetEnabled(
 		pp.ModelPresets = map[spec.ModelPresetID]spec.ModelPreset{}
 	}
 	pp.ModelPresets[modelID] = mp
-	b.viewProv[provider] = pp
+	b.setViewProvider(provider, pp)
+	b.mu.Unlock()
+
+	b.rebuilder.Trigger()
+	return cloneModelPreset(mp), nil
+}
+
+// SetModelPresetsEnabled toggles several model presets of a provider with a
+// single overlay write.
+func (b *BuiltInPresets) SetModelPresetsEnabled(
+	ctx context.Context,
+	provider inferenceSpec.ProviderName,
+	modelIDs []spec.ModelPresetID,
+	enabled bool,
+) error {
+	keys := make([]builtInModelKey, 0, len(modelIDs))
+	for _, mid := range modelIDs {
+		if _, err := b.GetBuiltInModelPreset(ctx, provider, mid); err != nil {
+			return err
+		}
+		keys = append(keys, getModelKey(provider, mid))
+	}
+	flags, err := b.modelOverlayFlags.SetFlags(ctx, keys, enabled)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	pp := b.viewProv[provider]
+	if pp.ModelPresets == nil {
+		pp.ModelPresets = map[spec.ModelPresetID]spec.ModelPreset{}
+	}
+	for i, mid := range modelIDs {
+		mp := b.viewModels[provider][mid]
+		mp.IsEnabled = enabled
+		mp.ModifiedAt = flags[i].ModifiedAt
+		b.viewModels[provider][mid] = mp
+		pp.ModelPresets[mid] = mp
+	}
+	b.setViewProvider(provider, pp)
+	b.mu.Unlock()
+
+	b.rebuilder.Trigger()
+	return nil
+}
+
+// SetModelPresetTags replaces the tags of a model preset.
+func (b *BuiltInPresets) SetModelPresetTags(
+	ctx context.Context,
+	provider inferenceSpec.ProviderName,
+	modelID spec.ModelPresetID,
+	tags []string,
+) (spec.ModelPreset, error) {
+	mp, err := b.GetBuiltInModelPreset(ctx, provider, modelID)
+	if err != nil {
+		return mp, err
+	}
+	tags = slices.Clone(tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	flag, err := b.modelTagsOverlayFlags.SetFlag(
+		ctx, builtInModelTagsKey(getModelKey(provider, modelID)), tags)
+	if err != nil {
+		return spec.ModelPreset{}, err
+	}
+	if len(tags) == 0 {
+		tags = nil
+	}
+
+	b.mu.Lock()
+	mp.Tags = tags
+	mp.ModifiedAt = flag.ModifiedAt
+	b.viewModels[provider][modelID] = mp
+
+	pp := b.viewProv[provider]
+	if pp.ModelPresets == nil {
+		pp.ModelPresets = map[spec.ModelPresetID]spec.ModelPreset{}
+	}
+	pp.ModelPresets[modelID] = mp
+	b.setViewProvider(provider, pp)
+	b.mu.Unlock()
+
+	b.rebuilder.Trigger()
+	return cloneModelPreset(mp), nil
+}
+
+// SetModelPresetPricing replaces the pricing of a model preset. Zero pricing
+// clears it.
+func (b *BuiltInPresets) SetModelPresetPricing(
+	ctx context.Context,
+	provider inferenceSpec.ProviderName,
+	modelID spec.ModelPresetID,
+	pricing spec.ModelPricing,
+) (spec.ModelPreset, error) {
+	mp, err := b.GetBuiltInModelPreset(ctx, provider, modelID)
+	if err != nil {
+		return mp, err
+	}
+	flag, err := b.modelPricingOverlayFlags.SetFlag(
+		ctx, builtInModelPricingKey(getModelKey(provider, modelID)), pricing)
+	if err != nil {
+		return spec.ModelPreset{}, err
+	}
+
+	b.mu.Lock()
+	mp.Pricing = nil
+	if !pricing.IsZero() {
+		mp.Pricing = &pricing
+	}
+	mp.ModifiedAt = flag.ModifiedAt
+	b.viewModels[provider][modelID] = mp
+
+	pp := b.viewProv[provider]
+	if pp.ModelPresets == nil {
+		pp.ModelPresets = map[spec.ModelPresetID]spec.ModelPreset{}
+	}
+	pp.ModelPresets[modelID] = mp
+	b.setViewProvider(provider, pp)
+	b.mu.Unlock()
+
+	b.rebuilder.Trigger()
+	return cloneModelPreset(mp), nil
+}
+
+// SetModelPresetSystemPrompt replaces the system prompt of a model preset.
+// Resetting the overrides restores the catalog prompt.
+func (b *BuiltInPresets) SetModelPresetSystemPrompt(
+	ctx context.Context,
+	provider inferenceSpec.ProviderName,
+	modelID spec.ModelPresetID,
+	prompt string,
+) (spec.ModelPreset, error) {
+	mp, err := b.GetBuiltInModelPreset(ctx, provider, modelID)
+	if err != nil {
+		return mp, err
+	}
+	flag, err := b.modelSystemPromptOverlayFlags.SetFlag(
+		ctx, builtInModelSystemPromptKey(getModelKey(provider, modelID)), prompt)
+	if err != nil {
+		return spec.ModelPreset{}, err
+	}
+
+	b.mu.Lock()
+	mp.SystemPrompt = &prompt
+	mp.ModifiedAt = flag.ModifiedAt
+	b.viewModels[provider][modelID] = mp
+
+	pp := b.viewProv[provider]
+	if pp.ModelPresets == nil {
+		pp.ModelPresets = map[spec.ModelPresetID]spec.ModelPreset{}
+	}
+	pp.ModelPresets[modelID] = mp
+	b.setViewProvider(provider, pp)
 	b.mu.Unlock()
 
 	b.rebuilder.Trigger()
@@ -292,7 +526,7 @@ func (b *BuiltInPresets) SetDefaultModelPreset(
 	pp := b.viewProv[provider]
 	pp.DefaultModelPresetID = modelID
 	pp.ModifiedAt = flag.ModifiedAt
-	b.viewProv[provider] = pp
+	b.setViewProvider(provider, pp)
 	cloned := cloneProviderPreset(pp)
 	b.mu.Unlock()
 
@@ -300,6 +534,269 @@ func (b *BuiltInPresets) SetDefaultModelPreset(
 	return cloned, nil
 }
 
+// SetProviderRateLimits replaces the rate limits of a provider. Zero limits
+// clear them.
+func (b *BuiltInPresets) SetProviderRateLimits(
+	ctx context.Context,
+	provider inferenceSpec.ProviderName,
+	limits spec.ProviderRateLimits,
+) (spec.ProviderPreset, error) {
+	if _, ok := b.providers[provider]; !ok {
+		return spec.ProviderPreset{}, spec.ErrProviderNotFound
+	}
+	flag, err := b.providerRateLimitsOverlayFlags.SetFlag(
+		ctx, builtInProviderRateLimitsKey(provider), limits)
+	if err != nil {
+		return spec.ProviderPreset{}, err
+	}
+
+	b.mu.Lock()
+	pp := b.viewProv[provider]
+	pp.RateLimits = nil
+	if !limits.IsZero() {
+		pp.RateLimits = &limits
+	}
+	pp.ModifiedAt = flag.ModifiedAt
+	b.setViewProvider(provider, pp)
+	cloned := cloneProviderPreset(pp)
+	b.mu.Unlock()
+
+	b.rebuilder.Trigger()
+	return cloned, nil
+}
+
+// SetProviderResilience replaces the retry policy and failover list of a
+// provider. A zero value clears them.
+func (b *BuiltInPresets) SetProviderResilience(
+	ctx context.Context,
+	provider inferenceSpec.ProviderName,
+	resilience spec.ProviderResilience,
+) (spec.ProviderPreset, error) {
+	if _, ok := b.providers[provider]; !ok {
+		return spec.ProviderPreset{}, spec.ErrProviderNotFound
+	}
+	flag, err := b.providerResilienceOverlayFlags.SetFlag(
+		ctx, builtInProviderResilienceKey(provider), resilience)
+	if err != nil {
+		return spec.ProviderPreset{}, err
+	}
+
+	b.mu.Lock()
+	pp := b.viewProv[provider]
+	pp.Resilience = nil
+	if !resilience.IsZero() {
+		pp.Resilience = cloneProviderResilience(&resilience)
+	}
+	pp.ModifiedAt = flag.ModifiedAt
+	b.setViewProvider(provider, pp)
+	cloned := cloneProviderPreset(pp)
+	b.mu.Unlock()
+
+	b.rebuilder.Trigger()
+	return cloned, nil
+}
+
+// SetProviderImageProcessing replaces how image attachments are prepared for
+// a provider. A zero value sends them unchanged.
+func (b *BuiltInPresets) SetProviderImageProcessing(
+	ctx context.Context,
+	provider inferenceSpec.ProviderName,
+	c spec.ProviderImageProcessing,
+) (spec.ProviderPreset, error) {
+	if _, ok := b.providers[provider]; !ok {
+		return spec.ProviderPreset{}, spec.ErrProviderNotFound
+	}
+	flag, err := b.providerImageOverlayFlags.SetFlag(
+		ctx, builtInProviderImageKey(provider), c)
+	if err != nil {
+		return spec.ProviderPreset{}, err
+	}
+
+	b.mu.Lock()
+	pp := b.viewProv[provider]
+	pp.ImageProcessing = nil
+	if !c.IsZero() {
+		pp.ImageProcessing = &c
+	}
+	pp.ModifiedAt = flag.ModifiedAt
+	b.setViewProvider(provider, pp)
+	cloned := cloneProviderPreset(pp)
+	b.mu.Unlock()
+
+	b.rebuilder.Trigger()
+	return cloned, nil
+}
+
+// ResetOverrides drops the overlay entries (enabled flags, tags, pricing,
+// system prompts, rate limits, resilience, image processing and default
+// model) of the given
+// providers, or of all built-in providers when none are given, restoring their
+// pristine built-in values.
+func (b *BuiltInPresets) ResetOverrides(
+	ctx context.Context,
+	providers ...inferenceSpec.ProviderName,
+) ([]inferenceSpec.ProviderName, error) {
+	if len(providers) == 0 {
+		providers = slices.Sorted(maps.Keys(b.providers))
+	}
+	for _, name := range providers {
+		if _, ok := b.providers[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", spec.ErrBuiltInProviderAbsent, name)
+		}
+	}
+
+	for _, name := range providers {
+		if err := b.providerOverlayFlags.DeleteKey(ctx, builtInProviderKey(name)); err != nil {
+			return nil, err
+		}
+		if err := b.providerDefaultModelIDOverlayFlags.DeleteKey(
+			ctx, builtInProviderDefaultModelIDKey(name)); err != nil {
+			return nil, err
+		}
+		if err := b.providerRateLimitsOverlayFlags.DeleteKey(
+			ctx, builtInProviderRateLimitsKey(name)); err != nil {
+			return nil, err
+		}
+		if err := b.providerResilienceOverlayFlags.DeleteKey(
+			ctx, builtInProviderResilienceKey(name)); err != nil {
+			return nil, err
+		}
+		if err := b.providerImageOverlayFlags.DeleteKey(
+			ctx, builtInProviderImageKey(name)); err != nil {
+			return nil, err
+		}
+		for mid := range b.models[name] {
+			key := getModelKey(name, mid)
+			if err := b.modelOverlayFlags.DeleteKey(ctx, key); err != nil {
+				return nil, err
+			}
+			if err := b.modelTagsOverlayFlags.DeleteKey(ctx, builtInModelTagsKey(key)); err != nil {
+				return nil, err
+			}
+			if err := b.modelPricingOverlayFlags.DeleteKey(ctx, builtInModelPricingKey(key)); err != nil {
+				return nil, err
+			}
+			if err := b.modelSystemPromptOverlayFlags.DeleteKey(ctx, builtInModelSystemPromptKey(key)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	b.mu.Lock()
+	err := b.rebuildSnapshot(ctx)
+	b.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(providers), nil
+}
+
+// CompactOverlay deletes overlay flags that set a built-in provider or model
+// preset to its default, or that refer to one no longer built in, and shrinks
+// the overlay database. It returns how many flags were deleted.
+func (b *BuiltInPresets) CompactOverlay(ctx context.Context) (int, error) {
+	providers := make(map[overlay.KeyID]spec.ProviderPreset, len(b.providers))
+	for name, p := range b.providers {
+		providers[builtInProviderKey(name).ID()] = p
+	}
+	models := map[overlay.KeyID]spec.ModelPreset{}
+	for name, mm := range b.models {
+		for id, m := range mm {
+			models[getModelKey(name, id).ID()] = m
+		}
+	}
+	provider := func(key overlay.KeyID, isDefault func(spec.ProviderPreset) bool) bool {
+		p, ok := providers[key]
+		return !ok || isDefault(p)
+	}
+	model := func(key overlay.KeyID, isDefault func(spec.ModelPreset) bool) bool {
+		m, ok := models[key]
+		return !ok || isDefault(m)
+	}
+
+	pruned := 0
+	err := errors.Join(
+		pruneOverlayGroup(ctx, b.providerOverlayFlags, &pruned, func(key overlay.KeyID, v bool) bool {
+			return provider(key, func(p spec.ProviderPreset) bool { return p.IsEnabled == v })
+		}),
+		pruneOverlayGroup(ctx, b.providerDefaultModelIDOverlayFlags, &pruned,
+			func(key overlay.KeyID, v spec.ModelPresetID) bool {
+				return provider(key, func(p spec.ProviderPreset) bool { return p.DefaultModelPresetID == v })
+			}),
+		pruneOverlayGroup(ctx, b.providerRateLimitsOverlayFlags, &pruned,
+			func(key overlay.KeyID, v spec.ProviderRateLimits) bool {
+				return provider(key, func(p spec.ProviderPreset) bool {
+					return isDefaultOverlayValue(p.RateLimits, v, v.IsZero())
+				})
+			}),
+		pruneOverlayGroup(ctx, b.providerResilienceOverlayFlags, &pruned,
+			func(key overlay.KeyID, v spec.ProviderResilience) bool {
+				return provider(key, func(p spec.ProviderPreset) bool {
+					return isDefaultOverlayValue(p.Resilience, v, v.IsZero())
+				})
+			}),
+		pruneOverlayGroup(ctx, b.providerImageOverlayFlags, &pruned,
+			func(key overlay.KeyID, v spec.ProviderImageProcessing) bool {
+				return provider(key, func(p spec.ProviderPreset) bool {
+					return isDefaultOverlayValue(p.ImageProcessing, v, v.IsZero())
+				})
+			}),
+		pruneOverlayGroup(ctx, b.modelOverlayFlags, &pruned, func(key overlay.KeyID, v bool) bool {
+			return model(key, func(m spec.ModelPreset) bool { return m.IsEnabled == v })
+		}),
+		pruneOverlayGroup(ctx, b.modelTagsOverlayFlags, &pruned, func(key overlay.KeyID, v []string) bool {
+			return model(key, func(m spec.ModelPreset) bool { return slices.Equal(m.Tags, v) })
+		}),
+		pruneOverlayGroup(ctx, b.modelPricingOverlayFlags, &pruned, func(key overlay.KeyID, v spec.ModelPricing) bool {
+			return model(key, func(m spec.ModelPreset) bool {
+				return isDefaultOverlayValue(m.Pricing, v, v.IsZero())
+			})
+		}),
+		pruneOverlayGroup(ctx, b.modelSystemPromptOverlayFlags, &pruned, func(key overlay.KeyID, v string) bool {
+			return model(key, func(m spec.ModelPreset) bool { return m.SystemPrompt != nil && *m.SystemPrompt == v })
+		}),
+	)
+	if pruned > 0 {
+		b.mu.Lock()
+		err = errors.Join(err, b.rebuildSnapshot(ctx))
+		b.mu.Unlock()
+	}
+	if err != nil {
+		return pruned, err
+	}
+	return pruned, b.store.Vacuum(ctx)
+}
+
+// GetOverlayStats reports the flag counts and file size of the overlay
+// database.
+func (b *BuiltInPresets) GetOverlayStats(ctx context.Context) (overlay.DBStats, error) {
+	return b.store.Stats(ctx)
+}
+
+// pruneOverlayGroup deletes the flags of g that isDefault matches and adds
+// their count to pruned.
+func pruneOverlayGroup[K overlay.Key, V any](
+	ctx context.Context,
+	g *overlay.TypedGroup[K, V],
+	pruned *int,
+	isDefault func(key overlay.KeyID, value V) bool,
+) error {
+	n, err := g.PruneFlags(ctx, func(key overlay.KeyID, flag overlay.TypedFlag[V]) bool {
+		return isDefault(key, flag.Value)
+	})
+	*pruned += n
+	return err
+}
+
+// isDefaultOverlayValue reports whether an overlay value that replaces base,
+// clearing it when zero, leaves base as it is.
+func isDefaultOverlayValue[V any](base *V, value V, zero bool) bool {
+	if base == nil {
+		return zero
+	}
+	return reflect.DeepEqual(*base, value)
+}
+
 // rebuildSnapshot applies overlay flags onto the immutable base sets.
 // Caller must hold write lock.
 func (b *BuiltInPresets) rebuildSnapshot(ctx context.Context) error {
@@ -315,6 +812,39 @@ func (b *BuiltInPresets) rebuildSnapshot(ctx context.Context) error {
 				m.IsEnabled = flag.Value
 				m.ModifiedAt = flag.ModifiedAt
 			}
+			if flag, ok, err := b.modelTagsOverlayFlags.GetFlag(
+				ctx, builtInModelTagsKey(getModelKey(pname, mid))); err != nil {
+				return err
+			} else if ok {
+				m.Tags = nil
+				if len(flag.Value) > 0 {
+					m.Tags = slices.Clone(flag.Value)
+				}
+				if flag.ModifiedAt.After(m.ModifiedAt) {
+					m.ModifiedAt = flag.ModifiedAt
+				}
+			}
+			if flag, ok, err := b.modelPricingOverlayFlags.GetFlag(
+				ctx, builtInModelPricingKey(getModelKey(pname, mid))); err != nil {
+				return err
+			} else if ok {
+				m.Pricing = nil
+				if !flag.Value.IsZero() {
+					m.Pricing = cloneModelPricing(&flag.Value)
+				}
+				if flag.ModifiedAt.After(m.ModifiedAt) {
+					m.ModifiedAt = flag.ModifiedAt
+				}
+			}
+			if flag, ok, err := b.modelSystemPromptOverlayFlags.GetFlag(
+				ctx, builtInModelSystemPromptKey(getModelKey(pname, mid))); err != nil {
+				return err
+			} else if ok {
+				m.SystemPrompt = new(flag.Value)
+				if flag.ModifiedAt.After(m.ModifiedAt) {
+					m.ModifiedAt = flag.ModifiedAt
+				}
+			}
 			sub[mid] = m
 		}
 		newModels[pname] = sub
@@ -337,6 +867,42 @@ func (b *BuiltInPresets) rebuildSnapshot(ctx context.Context) error {
 				p.ModifiedAt = flag.ModifiedAt
 			}
 		}
+		if flag, ok, err := b.providerRateLimitsOverlayFlags.GetFlag(
+			ctx, builtInProviderRateLimitsKey(pname)); err != nil {
+			return err
+		} else if ok {
+			p.RateLimits = nil
+			if !flag.Value.IsZero() {
+				p.RateLimits = cloneProviderRateLimits(&flag.Value)
+			}
+			if flag.ModifiedAt.After(p.ModifiedAt) {
+				p.ModifiedAt = flag.ModifiedAt
+			}
+		}
+		if flag, ok, err := b.providerResilienceOverlayFlags.GetFlag(
+			ctx, builtInProviderResilienceKey(pname)); err != nil {
+			return err
+		} else if ok {
+			p.Resilience = nil
+			if !flag.Value.IsZero() {
+				p.Resilience = cloneProviderResilience(&flag.Value)
+			}
+			if flag.ModifiedAt.After(p.ModifiedAt) {
+				p.ModifiedAt = flag.ModifiedAt
+			}
+		}
+		if flag, ok, err := b.providerImageOverlayFlags.GetFlag(
+			ctx, builtInProviderImageKey(pname)); err != nil {
+			return err
+		} else if ok {
+			p.ImageProcessing = nil
+			if !flag.Value.IsZero() {
+				p.ImageProcessing = cloneProviderImageProcessing(&flag.Value)
+			}
+			if flag.ModifiedAt.After(p.ModifiedAt) {
+				p.ModifiedAt = flag.ModifiedAt
+			}
+		}
 		// Need to apply the overlayed model presets.
 		p.ModelPresets = newModels[pname]
 
@@ -345,9 +911,24 @@ func (b *BuiltInPresets) rebuildSnapshot(ctx context.Context) error {
 
 	b.viewProv = newProv
 	b.viewModels = newModels
+	b.viewGen++
 	return nil
 }
 
+// setViewProvider replaces one provider of the view. Caller must hold write
+// lock.
+func (b *BuiltInPresets) setViewProvider(name inferenceSpec.ProviderName, pp spec.ProviderPreset) {
+	b.viewProv[name] = pp
+	b.viewGen++
+}
+
+// viewGeneration returns a counter that changes whenever the view does.
+func (b *BuiltInPresets) viewGeneration() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.viewGen
+}
+
 func getModelKey(pName inferenceSpec.ProviderName, modelID spec.ModelPresetID) builtInModelKey {
 	return builtInModelKey(fmt.Sprintf("%s::%s", pName, modelID))
 }
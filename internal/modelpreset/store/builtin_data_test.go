@@ -191,7 +191,9 @@ func TestBuiltInPresetsInitializedFromInferenceCatalog(t *testing.T) {
 			t.Errorf("provider %s displayName got %q want %q",
 				providerName, appProvider.DisplayName, inferenceProvider.DisplayName)
 		}
-		if appProvider.SDKType != inferenceProvider.SDKType {
+		// Local servers carry their own SDK type but keep the catalog's wire protocol.
+		wireSDKType := spec.WireSDKType(appProvider.SDKType, appProvider.ChatCompletionPathPrefix)
+		if wireSDKType != inferenceProvider.SDKType {
 			t.Errorf("provider %s sdkType got %q want %q",
 				providerName, appProvider.SDKType, inferenceProvider.SDKType)
 		}
@@ -585,6 +587,154 @@ func TestSetDefaultModelPreset(t *testing.T) {
 	}
 }
 
+func TestBuiltInPresetsResetOverrides(t *testing.T) {
+	ctx := t.Context()
+	bi, _ := mustNewBuiltInPresets(t, time.Hour)
+	defer closeBuiltInPresetsForTest(t, bi)
+
+	pristineProviders, pristineModels, err := bi.ListBuiltInPresets(ctx)
+	if err != nil {
+		t.Fatalf("ListBuiltInPresets: %v", err)
+	}
+	providerName, modelID := anyProviderWithNonDefaultModel(t, pristineProviders, pristineModels)
+	pristine := pristineProviders[providerName]
+	pristineModel := pristineModels[providerName][modelID]
+
+	if _, err := bi.SetProviderEnabled(ctx, providerName, !pristine.IsEnabled); err != nil {
+		t.Fatalf("SetProviderEnabled: %v", err)
+	}
+	if _, err := bi.SetDefaultModelPreset(ctx, providerName, modelID); err != nil {
+		t.Fatalf("SetDefaultModelPreset: %v", err)
+	}
+	if _, err := bi.SetModelPresetEnabled(ctx, providerName, modelID, !pristineModel.IsEnabled); err != nil {
+		t.Fatalf("SetModelPresetEnabled: %v", err)
+	}
+	if _, err := bi.SetModelPresetTags(ctx, providerName, modelID, []string{"reset-me"}); err != nil {
+		t.Fatalf("SetModelPresetTags: %v", err)
+	}
+
+	t.Run("unknown_provider", func(t *testing.T) {
+		if _, err := bi.ResetOverrides(ctx, "ghost-provider"); err == nil {
+			t.Fatal("expected error for unknown provider")
+		}
+		got, err := bi.GetBuiltInProvider(ctx, providerName)
+		if err != nil {
+			t.Fatalf("GetBuiltInProvider: %v", err)
+		}
+		if got.IsEnabled == pristine.IsEnabled {
+			t.Fatal("failed reset must not touch other overrides")
+		}
+	})
+
+	t.Run("scoped_reset", func(t *testing.T) {
+		reset, err := bi.ResetOverrides(ctx, providerName)
+		if err != nil {
+			t.Fatalf("ResetOverrides: %v", err)
+		}
+		if !slices.Equal(reset, []inferenceSpec.ProviderName{providerName}) {
+			t.Fatalf("reset=%v", reset)
+		}
+
+		got, err := bi.GetBuiltInProvider(ctx, providerName)
+		if err != nil {
+			t.Fatalf("GetBuiltInProvider: %v", err)
+		}
+		if got.IsEnabled != pristine.IsEnabled ||
+			got.DefaultModelPresetID != pristine.DefaultModelPresetID {
+			t.Fatalf("provider not restored: enabled=%v default=%s", got.IsEnabled, got.DefaultModelPresetID)
+		}
+		mp, err := bi.GetBuiltInModelPreset(ctx, providerName, modelID)
+		if err != nil {
+			t.Fatalf("GetBuiltInModelPreset: %v", err)
+		}
+		if mp.IsEnabled != pristineModel.IsEnabled || !slices.Equal(mp.Tags, pristineModel.Tags) {
+			t.Fatalf("model not restored: enabled=%v tags=%v", mp.IsEnabled, mp.Tags)
+		}
+		if _, ok, err := bi.providerOverlayFlags.GetFlag(ctx, builtInProviderKey(providerName)); err != nil || ok {
+			t.Fatalf("provider overlay still present: ok=%v err=%v", ok, err)
+		}
+	})
+
+	t.Run("reset_all", func(t *testing.T) {
+		if _, err := bi.SetProviderEnabled(ctx, providerName, !pristine.IsEnabled); err != nil {
+			t.Fatalf("SetProviderEnabled: %v", err)
+		}
+		reset, err := bi.ResetOverrides(ctx)
+		if err != nil {
+			t.Fatalf("ResetOverrides(all): %v", err)
+		}
+		if len(reset) != len(pristineProviders) {
+			t.Fatalf("reset %d providers, want %d", len(reset), len(pristineProviders))
+		}
+		got, err := bi.GetBuiltInProvider(ctx, providerName)
+		if err != nil {
+			t.Fatalf("GetBuiltInProvider: %v", err)
+		}
+		if got.IsEnabled != pristine.IsEnabled {
+			t.Fatalf("provider enabled=%v want %v", got.IsEnabled, pristine.IsEnabled)
+		}
+	})
+}
+
+func TestBuiltInPresetsCompactOverlay(t *testing.T) {
+	ctx := t.Context()
+	bi, _ := mustNewBuiltInPresets(t, time.Hour)
+	defer closeBuiltInPresetsForTest(t, bi)
+
+	pristineProviders, pristineModels, err := bi.ListBuiltInPresets(ctx)
+	if err != nil {
+		t.Fatalf("ListBuiltInPresets: %v", err)
+	}
+	providerName, modelID := anyProviderWithNonDefaultModel(t, pristineProviders, pristineModels)
+	pristine := pristineProviders[providerName]
+	pristineModel := pristineModels[providerName][modelID]
+
+	// Toggled back to the defaults: prunable.
+	for _, enabled := range []bool{!pristine.IsEnabled, pristine.IsEnabled} {
+		if _, err := bi.SetProviderEnabled(ctx, providerName, enabled); err != nil {
+			t.Fatalf("SetProviderEnabled: %v", err)
+		}
+	}
+	if _, err := bi.SetModelPresetTags(ctx, providerName, modelID, pristineModel.Tags); err != nil {
+		t.Fatalf("SetModelPresetTags: %v", err)
+	}
+	// A real override: kept.
+	if _, err := bi.SetModelPresetEnabled(ctx, providerName, modelID, !pristineModel.IsEnabled); err != nil {
+		t.Fatalf("SetModelPresetEnabled: %v", err)
+	}
+	// A provider that is no longer built in: prunable.
+	if _, err := bi.providerOverlayFlags.SetFlag(ctx, "gone-provider", true); err != nil {
+		t.Fatalf("SetFlag: %v", err)
+	}
+
+	stats, err := bi.GetOverlayStats(ctx)
+	if err != nil {
+		t.Fatalf("GetOverlayStats: %v", err)
+	}
+	if stats.Flags["providers"] != 2 || stats.Flags["modelTags"] != 1 || stats.Flags["models"] != 1 {
+		t.Fatalf("stats before compaction: %+v", stats.Flags)
+	}
+
+	pruned, err := bi.CompactOverlay(ctx)
+	if err != nil || pruned != 3 {
+		t.Fatalf("CompactOverlay = %d, %v; want 3", pruned, err)
+	}
+	stats, err = bi.GetOverlayStats(ctx)
+	if err != nil {
+		t.Fatalf("GetOverlayStats: %v", err)
+	}
+	if stats.Flags["providers"] != 0 || stats.Flags["modelTags"] != 0 || stats.Flags["models"] != 1 {
+		t.Fatalf("stats after compaction: %+v", stats.Flags)
+	}
+	mp, err := bi.GetBuiltInModelPreset(ctx, providerName, modelID)
+	if err != nil {
+		t.Fatalf("GetBuiltInModelPreset: %v", err)
+	}
+	if mp.IsEnabled == pristineModel.IsEnabled {
+		t.Fatal("compaction dropped a real override")
+	}
+}
+
 func TestListBuiltInPresetsReturnsIndependentCopies(t *testing.T) {
 	ctx := t.Context()
 	bi, _ := mustNewBuiltInPresets(t, time.Hour)
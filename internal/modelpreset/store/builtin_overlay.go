@@ -96,6 +96,26 @@ var builtInProviderDefaultHeaderOverlays = map[inferenceSpec.ProviderName]map[st
 	},
 }
 
+// builtInProviderSDKTypeOverlays tags the catalog's local servers with their
+// local SDK types. The catalog's chat paths keep the wire protocol unchanged.
+var builtInProviderSDKTypeOverlays = map[inferenceSpec.ProviderName]inferenceSpec.ProviderSDKType{
+	modelpreset.ProviderOllama:   spec.ProviderSDKTypeOllama,
+	modelpreset.ProviderLlamaCPP: spec.ProviderSDKTypeLlamaCPP,
+}
+
+// builtInProviderImageProcessing caps image attachments for hosted vision
+// providers at the size they resize to anyway, so larger images are not
+// uploaded and billed in full. Local servers keep images unchanged.
+var builtInProviderImageProcessing = map[inferenceSpec.ProviderName]spec.ProviderImageProcessing{
+	modelpreset.ProviderAnthropic:       {MaxDimension: 1568},
+	modelpreset.ProviderGoogleGemini:    {MaxDimension: 3072},
+	modelpreset.ProviderMistral:         {MaxDimension: 1540},
+	modelpreset.ProviderOpenAIChat:      {MaxDimension: 2048},
+	modelpreset.ProviderOpenAIResponses: {MaxDimension: 2048},
+	modelpreset.ProviderOpenRouter:      {MaxDimension: 2048},
+	modelpreset.ProviderXAI:             {MaxDimension: 2048},
+}
+
 func (b *BuiltInPresets) populateDataFromInferenceCatalog(ctx context.Context) error {
 	catalog := modelpreset.DefaultCatalog()
 	if len(catalog.Providers) == 0 {
@@ -166,11 +186,21 @@ func appProviderPresetFromInference(
 		maps.Copy(headers, extra)
 	}
 
+	sdkType := in.SDKType
+	if t, ok := builtInProviderSDKTypeOverlays[in.Name]; ok {
+		sdkType = t
+	}
+
+	var imageProcessing *spec.ProviderImageProcessing
+	if c, ok := builtInProviderImageProcessing[in.Name]; ok {
+		imageProcessing = &c
+	}
+
 	return spec.ProviderPreset{
 		SchemaVersion:            spec.SchemaVersion,
 		Name:                     in.Name,
 		DisplayName:              spec.ProviderDisplayName(in.DisplayName),
-		SDKType:                  in.SDKType,
+		SDKType:                  sdkType,
 		IsEnabled:                true,
 		CreatedAt:                ts,
 		ModifiedAt:               ts,
@@ -180,6 +210,7 @@ func appProviderPresetFromInference(
 		APIKeyHeaderKey:          in.APIKeyHeaderKey,
 		DefaultHeaders:           headers,
 		CapabilitiesOverride:     capabilityoverride.CloneModelCapabilitiesOverride(in.CapabilitiesOverride),
+		ImageProcessing:          imageProcessing,
 		DefaultModelPresetID:     defaultModelID,
 		ModelPresets:             cloneModelPresetMap(models),
 	}
@@ -41,6 +41,60 @@ func cloneProviderPreset(pp spec.ProviderPreset) spec.ProviderPreset {
 	out.DefaultHeaders = maps.Clone(pp.DefaultHeaders)
 	out.ModelPresets = cloneModelPresetMap(pp.ModelPresets)
 	out.CapabilitiesOverride = capabilityoverride.CloneModelCapabilitiesOverride(pp.CapabilitiesOverride)
+	out.RateLimits = cloneProviderRateLimits(pp.RateLimits)
+	out.Resilience = cloneProviderResilience(pp.Resilience)
+	out.Azure = cloneAzureOpenAIConfig(pp.Azure)
+	out.TLS = cloneProviderTLSConfig(pp.TLS)
+	out.ImageProcessing = cloneProviderImageProcessing(pp.ImageProcessing)
+	if pp.SoftDeletedAt != nil {
+		t := *pp.SoftDeletedAt
+		out.SoftDeletedAt = &t
+	}
+	return out
+}
+
+func cloneProviderRateLimits(in *spec.ProviderRateLimits) *spec.ProviderRateLimits {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
+
+func cloneAzureOpenAIConfig(in *spec.AzureOpenAIConfig) *spec.AzureOpenAIConfig {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
+
+func cloneProviderTLSConfig(in *spec.ProviderTLSConfig) *spec.ProviderTLSConfig {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
+
+func cloneProviderImageProcessing(in *spec.ProviderImageProcessing) *spec.ProviderImageProcessing {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
+
+func cloneProviderResilience(in *spec.ProviderResilience) *spec.ProviderResilience {
+	if in == nil {
+		return nil
+	}
+	out := &spec.ProviderResilience{Failover: slices.Clone(in.Failover)}
+	if in.Retry != nil {
+		retry := *in.Retry
+		retry.RetryOnStatusCodes = slices.Clone(in.Retry.RetryOnStatusCodes)
+		out.Retry = &retry
+	}
 	return out
 }
 
@@ -57,15 +111,20 @@ func cloneModelPresetMap(
 func cloneModelPreset(mp spec.ModelPreset) spec.ModelPreset {
 	out := mp
 	out.ModelPresetPatch = cloneModelPresetPatch(mp.ModelPresetPatch)
+	out.Tags = slices.Clone(mp.Tags)
+	out.Pricing = cloneModelPricing(mp.Pricing)
 	return out
 }
 
-func cloneModelPresetPatch(in spec.ModelPresetPatch) spec.ModelPresetPatch {
-	var stopSequences *[]string
-	if in.StopSequences != nil {
-		s := slices.Clone(*in.StopSequences)
-		stopSequences = &s
+func cloneModelPricing(in *spec.ModelPricing) *spec.ModelPricing {
+	if in == nil {
+		return nil
 	}
+	out := *in
+	return &out
+}
+
+func cloneModelPresetPatch(in spec.ModelPresetPatch) spec.ModelPresetPatch {
 	return spec.ModelPresetPatch{
 		Stream:                      cloneBoolPtr(in.Stream),
 		MaxPromptLength:             cloneIntPtr(in.MaxPromptLength),
@@ -76,12 +135,28 @@ func cloneModelPresetPatch(in spec.ModelPresetPatch) spec.ModelPresetPatch {
 		Timeout:                     cloneIntPtr(in.Timeout),
 		CacheControl:                cloneCacheControl(in.CacheControl),
 		OutputParam:                 cloneOutputParam(in.OutputParam),
-		StopSequences:               stopSequences,
+		StopSequences:               cloneStopSequences(in.StopSequences),
 		AdditionalParametersRawJSON: cloneStringPtr(in.AdditionalParametersRawJSON),
 		CapabilitiesOverride:        capabilityoverride.CloneModelCapabilitiesOverride(in.CapabilitiesOverride),
 	}
 }
 
+func cloneParameterProfile(p spec.ParameterProfile) spec.ParameterProfile {
+	out := p
+	out.Temperature = cloneFloat64Ptr(p.Temperature)
+	out.Reasoning = cloneReasoningParam(p.Reasoning)
+	out.StopSequences = cloneStopSequences(p.StopSequences)
+	return out
+}
+
+func cloneStopSequences(in *[]string) *[]string {
+	if in == nil {
+		return nil
+	}
+	out := slices.Clone(*in)
+	return &out
+}
+
 func cloneCacheControl(in *inferenceSpec.CacheControl) *inferenceSpec.CacheControl {
 	if in == nil {
 		return nil
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// CountProviderPresets counts the providers ListProviderPresets would return.
+func (s *ModelPresetStore) CountProviderPresets(
+	ctx context.Context, req *spec.CountProviderPresetsRequest,
+) (*spec.CountProviderPresetsResponse, error) {
+	if req == nil {
+		req = &spec.CountProviderPresetsRequest{}
+	}
+	providers, err := s.filteredProviderPresets(
+		ctx, newProviderPresetFilter(req.Names, req.Tags, req.SDKTypes, req.IncludeDisabled),
+	)
+	if err != nil {
+		return nil, err
+	}
+	out := &spec.PresetCount{Count: len(providers)}
+	for _, p := range providers {
+		if !p.IsEnabled {
+			out.DisabledCount++
+		}
+	}
+	return &spec.CountProviderPresetsResponse{Body: out}, nil
+}
+
+// CountModelPresets counts the model presets of the matching providers. A
+// model preset of a disabled provider counts as disabled.
+func (s *ModelPresetStore) CountModelPresets(
+	ctx context.Context, req *spec.CountModelPresetsRequest,
+) (*spec.CountModelPresetsResponse, error) {
+	if req == nil {
+		req = &spec.CountModelPresetsRequest{}
+	}
+	providers, err := s.filteredProviderPresets(
+		ctx, newProviderPresetFilter(req.Names, req.Tags, req.SDKTypes, req.IncludeDisabled),
+	)
+	if err != nil {
+		return nil, err
+	}
+	out := &spec.PresetCount{}
+	for _, p := range providers {
+		out.Count += len(p.ModelPresets)
+		for _, mp := range p.ModelPresets {
+			if !p.IsEnabled || !mp.IsEnabled {
+				out.DisabledCount++
+			}
+		}
+	}
+	return &spec.CountModelPresetsResponse{Body: out}, nil
+}
+
+func newProviderPresetFilter(
+	names []inferenceSpec.ProviderName,
+	tags []string,
+	sdkTypes []inferenceSpec.ProviderSDKType,
+	includeDisabled bool,
+) providerPresetFilter {
+	f := providerPresetFilter{
+		names:           map[inferenceSpec.ProviderName]struct{}{},
+		tags:            map[string]struct{}{},
+		sdkTypes:        map[inferenceSpec.ProviderSDKType]struct{}{},
+		includeDisabled: includeDisabled,
+	}
+	for _, n := range names {
+		f.names[n] = struct{}{}
+	}
+	for _, tag := range tags {
+		f.tags[tag] = struct{}{}
+	}
+	for _, t := range sdkTypes {
+		f.sdkTypes[t] = struct{}{}
+	}
+	return f
+}
@@ -0,0 +1,360 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+const (
+	discoverDefaultModelsPath = "/v1/models"
+	discoverMaxResponseBytes  = 8 << 20
+	discoverMaxIDLength       = 64
+	discoverTimeout           = 30 * time.Second
+)
+
+// discoverChatPathSuffixes are trimmed from the chat completion path to find
+// the API root that also serves the model listing.
+var discoverChatPathSuffixes = []string{"chat/completions", "responses", "messages"}
+
+// DiscoverProviderModels lists the models served by a provider's
+// OpenAI-compatible model-listing endpoint, or by the Ollama daemon's tag
+// listing for Ollama providers, and returns them as candidate model presets.
+// With AcceptAll, candidates not yet in the store are created as user model
+// presets in a single write.
+func (s *ModelPresetStore) DiscoverProviderModels(
+	ctx context.Context, req *spec.DiscoverProviderModelsRequest,
+) (*spec.DiscoverProviderModelsResponse, error) {
+	if req == nil || req.ProviderName == "" {
+		return nil, fmt.Errorf("%w: providerName required", spec.ErrInvalidDir)
+	}
+	body := req.Body
+	if body == nil {
+		body = &spec.DiscoverProviderModelsRequestBody{}
+	}
+
+	pp, err := s.builtinData.GetBuiltInProvider(ctx, req.ProviderName)
+	isBuiltIn := err == nil
+	if isBuiltIn && body.AcceptAll {
+		return nil, fmt.Errorf("%w: providerName: %q",
+			spec.ErrBuiltInReadOnly, req.ProviderName)
+	}
+	if !isBuiltIn {
+		s.mu.RLock()
+		all, err := s.readAllUserPresets()
+		s.mu.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+		if pp, err = getUserProviderPreset(all, req.ProviderName); err != nil {
+			return nil, err
+		}
+	}
+
+	models, err := s.fetchProviderModels(ctx, pp, body.ModelsPath, body.APIKey)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	candidates := buildDiscoveredModelPresets(models, now)
+	markExistingModelPresets(candidates, pp.ModelPresets)
+
+	out := &spec.DiscoverProviderModelsResponseBody{Models: candidates}
+	if body.AcceptAll {
+		accepted, err := s.acceptDiscoveredModelPresets(req.ProviderName, candidates, now)
+		if err != nil {
+			return nil, err
+		}
+		out.Accepted = accepted
+	}
+	return &spec.DiscoverProviderModelsResponse{Body: out}, nil
+}
+
+// acceptDiscoveredModelPresets adds every candidate that is not yet present on
+// the user provider. Existence is re-checked under the lock.
+func (s *ModelPresetStore) acceptDiscoveredModelPresets(
+	providerName inferenceSpec.ProviderName,
+	candidates []spec.DiscoveredModelPreset,
+	now time.Time,
+) ([]spec.ModelPresetID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAllUserPresets()
+	if err != nil {
+		return nil, err
+	}
+	pp, err := getUserProviderPreset(all, providerName)
+	if err != nil {
+		return nil, err
+	}
+	if pp.ModelPresets == nil {
+		pp.ModelPresets = map[spec.ModelPresetID]spec.ModelPreset{}
+	}
+
+	fresh := slices.Clone(candidates)
+	markExistingModelPresets(fresh, pp.ModelPresets)
+
+	var accepted []spec.ModelPresetID
+	for _, c := range fresh {
+		if c.Exists {
+			continue
+		}
+		mp := cloneModelPreset(c.ModelPreset)
+		if err := validateModelPreset(&mp); err != nil {
+			return nil, fmt.Errorf("model %q: %w", mp.Name, err)
+		}
+		pp.ModelPresets[mp.ID] = mp
+		accepted = append(accepted, mp.ID)
+	}
+	if len(accepted) == 0 {
+		return nil, nil
+	}
+
+	pp.ModifiedAt = now
+	all.ProviderPresets[providerName] = pp
+	if err := s.writeAllUserPresets(all); err != nil {
+		return nil, err
+	}
+	s.notify(spec.PresetChangeModelCreated, providerName, accepted...)
+	logger.Info("discoverProviderModels: accepted",
+		"provider", providerName, "count", len(accepted))
+	return accepted, nil
+}
+
+type discoveredModel struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name,omitempty"`
+}
+
+type ollamaTag struct {
+	Name  string `json:"name"`
+	Model string `json:"model"`
+}
+
+// discoverModelsResponse accepts both the OpenAI listing ("data") and the
+// Ollama tag listing ("models").
+type discoverModelsResponse struct {
+	Data   []discoveredModel `json:"data"`
+	Models []ollamaTag       `json:"models"`
+}
+
+func (s *ModelPresetStore) fetchProviderModels(
+	ctx context.Context, pp spec.ProviderPreset, modelsPath, apiKey string,
+) ([]discoveredModel, error) {
+	endpoint, err := providerModelsURL(pp, modelsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	setProviderHeaders(httpReq, pp, apiKey)
+
+	resp, err := s.providerHTTPClient(pp.Name, discoverTimeout).Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("list models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, discoverMaxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("list models: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("list models: %s: %s",
+			resp.Status, strings.TrimSpace(string(raw[:min(len(raw), 512)])))
+	}
+
+	var parsed discoverModelsResponse
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("list models: invalid response: %w", err)
+	}
+	models := parsed.Data
+	for _, t := range parsed.Models {
+		id := t.Model
+		if id == "" {
+			id = t.Name
+		}
+		models = append(models, discoveredModel{ID: id, DisplayName: t.Name})
+	}
+	return models, nil
+}
+
+// setProviderHeaders applies the provider's default headers and API key the
+// way its SDK would send them.
+func setProviderHeaders(httpReq *http.Request, pp spec.ProviderPreset, apiKey string) {
+	for k, v := range pp.DefaultHeaders {
+		httpReq.Header.Set(k, v)
+	}
+	if apiKey != "" && pp.APIKeyHeaderKey != "" {
+		if strings.EqualFold(pp.APIKeyHeaderKey, "Authorization") {
+			httpReq.Header.Set(pp.APIKeyHeaderKey, "Bearer "+apiKey)
+		} else {
+			httpReq.Header.Set(pp.APIKeyHeaderKey, apiKey)
+		}
+	}
+}
+
+func providerOrigin(pp spec.ProviderPreset) (string, error) {
+	origin := strings.TrimRight(strings.TrimSpace(pp.Origin), "/")
+	if origin == "" {
+		return "", errors.New("provider origin is empty")
+	}
+	if _, err := url.ParseRequestURI(origin); err != nil {
+		return "", fmt.Errorf("invalid provider origin %q: %w", origin, err)
+	}
+	return origin, nil
+}
+
+// providerAPIRoot returns the chat completion path with its endpoint suffix
+// removed, e.g. "/v1/" for "/v1/chat/completions", or fallback when the path
+// has no known suffix.
+func providerAPIRoot(pp spec.ProviderPreset, fallback string) string {
+	chatPath := strings.TrimRight(strings.TrimSpace(pp.ChatCompletionPathPrefix), "/")
+	for _, suffix := range discoverChatPathSuffixes {
+		if root, ok := strings.CutSuffix(chatPath, suffix); ok && strings.HasSuffix(root, "/") {
+			return root
+		}
+	}
+	return fallback
+}
+
+// providerModelsURL resolves the model-listing URL. Without an explicit path,
+// the chat completion path with its endpoint suffix replaced by "models" is
+// used, e.g. "/v1/chat/completions" becomes "/v1/models". Ollama providers
+// list the daemon's pulled models instead.
+func providerModelsURL(pp spec.ProviderPreset, modelsPath string) (string, error) {
+	origin, err := providerOrigin(pp)
+	if err != nil {
+		return "", err
+	}
+
+	p := strings.TrimSpace(modelsPath)
+	if p == "" && pp.SDKType == spec.ProviderSDKTypeOllama {
+		p = spec.DefaultOllamaModelsPath
+	}
+	if p == "" {
+		p = discoverDefaultModelsPath
+		if root := providerAPIRoot(pp, ""); root != "" {
+			p = root + "models"
+		}
+	}
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return origin + p, nil
+}
+
+// buildDiscoveredModelPresets converts listed models into candidate presets.
+// Model names are kept verbatim; IDs and slugs are sanitized to the tag
+// format and de-duplicated.
+func buildDiscoveredModelPresets(
+	models []discoveredModel, now time.Time,
+) []spec.DiscoveredModelPreset {
+	out := make([]spec.DiscoveredModelPreset, 0, len(models))
+	usedIDs := map[spec.ModelPresetID]struct{}{}
+	seenNames := map[string]struct{}{}
+
+	for _, m := range models {
+		name := strings.TrimSpace(m.ID)
+		if name == "" {
+			continue
+		}
+		if _, dup := seenNames[name]; dup {
+			continue
+		}
+		seenNames[name] = struct{}{}
+
+		id := uniqueModelPresetID(sanitizeModelPresetID(name), usedIDs)
+		displayName := strings.TrimSpace(m.DisplayName)
+		if displayName == "" {
+			displayName = name
+		}
+		out = append(out, spec.DiscoveredModelPreset{
+			ModelPreset: spec.ModelPreset{
+				SchemaVersion: spec.SchemaVersion,
+				ID:            id,
+				Name:          spec.ModelName(name),
+				DisplayName:   spec.ModelDisplayName(displayName),
+				Slug:          spec.ModelSlug(id),
+				IsEnabled:     true,
+				// Sampling is left unset so the provider's defaults apply.
+				CreatedAt:  now,
+				ModifiedAt: now,
+			},
+		})
+	}
+
+	slices.SortFunc(out, func(a, b spec.DiscoveredModelPreset) int {
+		return strings.Compare(string(a.ModelPreset.ID), string(b.ModelPreset.ID))
+	})
+	return out
+}
+
+// markExistingModelPresets flags candidates whose ID or model name is already
+// used by a preset of the provider.
+func markExistingModelPresets(
+	candidates []spec.DiscoveredModelPreset,
+	existing map[spec.ModelPresetID]spec.ModelPreset,
+) {
+	names := make(map[spec.ModelName]struct{}, len(existing))
+	for _, mp := range existing {
+		names[mp.Name] = struct{}{}
+	}
+	for i := range candidates {
+		mp := candidates[i].ModelPreset
+		_, idTaken := existing[mp.ID]
+		_, nameTaken := names[mp.Name]
+		candidates[i].Exists = idTaken || nameTaken
+	}
+}
+
+func sanitizeModelPresetID(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('-')
+		}
+	}
+	id := b.String()
+	if c := id[0]; (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && c != '_' {
+		id = "m-" + id
+	}
+	if len(id) > discoverMaxIDLength {
+		id = id[:discoverMaxIDLength]
+	}
+	return id
+}
+
+func uniqueModelPresetID(
+	base string, used map[spec.ModelPresetID]struct{},
+) spec.ModelPresetID {
+	id := spec.ModelPresetID(base)
+	for n := 2; ; n++ {
+		if _, taken := used[id]; !taken {
+			break
+		}
+		suffix := fmt.Sprintf("-%d", n)
+		id = spec.ModelPresetID(base[:min(len(base), discoverMaxIDLength-len(suffix))] + suffix)
+	}
+	used[id] = struct{}{}
+	return id
+}
@@ -0,0 +1,263 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+const (
+	// maxEmbeddingInputs matches the per-request limit of the OpenAI API.
+	maxEmbeddingInputs        = 2048
+	embeddingMaxResponseBytes = 64 << 20
+	// embeddingDefaultAPIRoot is used when the chat completion path has no
+	// recognizable endpoint suffix.
+	embeddingDefaultAPIRoot  = "/v1/"
+	googleEmbeddingAPIPrefix = "/v1beta/models/"
+	embeddingTimeout         = 2 * time.Minute
+)
+
+// supportsEmbeddings reports whether providers of the SDK type expose an
+// embeddings endpoint. The Anthropic API has none.
+func supportsEmbeddings(t inferenceSpec.ProviderSDKType) bool {
+	return isKnownSDKType(t) && t != inferenceSpec.ProviderSDKTypeAnthropic
+}
+
+// GenerateEmbeddings embeds the inputs with the preset's model. Google
+// providers use the batchEmbedContents API; every other provider the
+// OpenAI-compatible embeddings endpoint next to its chat completion path.
+func (s *ModelPresetStore) GenerateEmbeddings(
+	ctx context.Context, req *spec.GenerateEmbeddingsRequest,
+) (*spec.GenerateEmbeddingsResponse, error) {
+	if req == nil || req.Body == nil || req.EmbeddingPresetID == "" {
+		return nil, fmt.Errorf("%w: embeddingPresetID and inputs required", spec.ErrInvalidDir)
+	}
+	inputs := req.Body.Inputs
+	if len(inputs) == 0 || len(inputs) > maxEmbeddingInputs {
+		return nil, fmt.Errorf("%w: between 1 and %d inputs required", spec.ErrInvalidDir, maxEmbeddingInputs)
+	}
+	for i, in := range inputs {
+		if strings.TrimSpace(in) == "" {
+			return nil, fmt.Errorf("%w: inputs[%d] is empty", spec.ErrInvalidDir, i)
+		}
+	}
+
+	epResp, err := s.GetEmbeddingPreset(ctx, &spec.GetEmbeddingPresetRequest{
+		EmbeddingPresetID: req.EmbeddingPresetID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ep := *epResp.Body
+	pp, err := s.getProviderPreset(ctx, ep.ProviderName)
+	if err != nil {
+		return nil, err
+	}
+	if !pp.IsEnabled {
+		return nil, fmt.Errorf("%w: %s is disabled", spec.ErrProviderNotFound, pp.Name)
+	}
+	if !supportsEmbeddings(pp.SDKType) {
+		return nil, fmt.Errorf("%w: %s (sdkType %q)", spec.ErrEmbeddingsUnsupported, pp.Name, pp.SDKType)
+	}
+
+	var (
+		vectors [][]float32
+		tokens  int
+	)
+	if pp.SDKType == inferenceSpec.ProviderSDKTypeGoogleGenerateContent {
+		vectors, err = s.fetchGoogleEmbeddings(ctx, pp, ep, inputs, req.Body.APIKey)
+	} else {
+		vectors, tokens, err = s.fetchOpenAIEmbeddings(ctx, pp, ep, inputs, req.Body.APIKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(inputs) {
+		return nil, fmt.Errorf("embeddings: got %d vectors for %d inputs", len(vectors), len(inputs))
+	}
+	dims := len(vectors[0])
+	for i, v := range vectors {
+		if len(v) == 0 || len(v) != dims {
+			return nil, fmt.Errorf("embeddings: vector %d has %d dimensions, want %d", i, len(v), dims)
+		}
+	}
+
+	return &spec.GenerateEmbeddingsResponse{
+		Body: &spec.GenerateEmbeddingsResponseBody{
+			EmbeddingPresetID: ep.ID,
+			ProviderName:      ep.ProviderName,
+			ModelName:         ep.ModelName,
+			Dimensions:        dims,
+			Embeddings:        vectors,
+			InputTokens:       tokens,
+		},
+	}, nil
+}
+
+type openAIEmbeddingsRequest struct {
+	Model          spec.ModelName `json:"model"`
+	Input          []string       `json:"input"`
+	Dimensions     int            `json:"dimensions,omitempty"`
+	EncodingFormat string         `json:"encoding_format"`
+}
+
+type openAIEmbeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+	} `json:"usage"`
+}
+
+func (s *ModelPresetStore) fetchOpenAIEmbeddings(
+	ctx context.Context,
+	pp spec.ProviderPreset,
+	ep spec.EmbeddingPreset,
+	inputs []string,
+	apiKey string,
+) (vectors [][]float32, tokens int, err error) {
+	endpoint, err := openAIEmbeddingsURL(pp, ep.ModelName)
+	if err != nil {
+		return nil, 0, err
+	}
+	var parsed openAIEmbeddingsResponse
+	if err := s.postEmbeddings(ctx, pp, endpoint, apiKey, openAIEmbeddingsRequest{
+		Model:          ep.ModelName,
+		Input:          inputs,
+		Dimensions:     ep.Dimensions,
+		EncodingFormat: "float",
+	}, &parsed); err != nil {
+		return nil, 0, err
+	}
+	vectors = make([][]float32, len(inputs))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, 0, fmt.Errorf("embeddings: index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, parsed.Usage.PromptTokens, nil
+}
+
+// openAIEmbeddingsURL places the embeddings endpoint next to the chat
+// completion endpoint. Azure addresses the embedding model by deployment.
+func openAIEmbeddingsURL(pp spec.ProviderPreset, model spec.ModelName) (string, error) {
+	origin, err := providerOrigin(pp)
+	if err != nil {
+		return "", err
+	}
+	if pp.SDKType == spec.ProviderSDKTypeAzureOpenAI {
+		if pp.Azure == nil {
+			return "", errors.New("azure config is missing")
+		}
+		q := url.Values{spec.AzureOpenAIAPIVersionQueryKey: {pp.Azure.APIVersion}}
+		return origin + "/openai/deployments/" + url.PathEscape(string(model)) + "/embeddings?" + q.Encode(), nil
+	}
+	return origin + providerAPIRoot(pp, embeddingDefaultAPIRoot) + "embeddings", nil
+}
+
+type googleEmbedPart struct {
+	Text string `json:"text"`
+}
+
+type googleEmbedContent struct {
+	Parts []googleEmbedPart `json:"parts"`
+}
+
+type googleEmbedContentRequest struct {
+	Model                string             `json:"model"`
+	Content              googleEmbedContent `json:"content"`
+	OutputDimensionality int                `json:"outputDimensionality,omitempty"`
+}
+
+type googleBatchEmbedResponse struct {
+	Embeddings []struct {
+		Values []float32 `json:"values"`
+	} `json:"embeddings"`
+}
+
+func (s *ModelPresetStore) fetchGoogleEmbeddings(
+	ctx context.Context,
+	pp spec.ProviderPreset,
+	ep spec.EmbeddingPreset,
+	inputs []string,
+	apiKey string,
+) ([][]float32, error) {
+	origin, err := providerOrigin(pp)
+	if err != nil {
+		return nil, err
+	}
+	model := strings.TrimPrefix(string(ep.ModelName), "models/")
+	endpoint := origin + strings.TrimRight(strings.TrimSpace(pp.ChatCompletionPathPrefix), "/") +
+		googleEmbeddingAPIPrefix + url.PathEscape(model) + ":batchEmbedContents"
+
+	requests := make([]googleEmbedContentRequest, 0, len(inputs))
+	for _, in := range inputs {
+		requests = append(requests, googleEmbedContentRequest{
+			Model:                "models/" + model,
+			Content:              googleEmbedContent{Parts: []googleEmbedPart{{Text: in}}},
+			OutputDimensionality: ep.Dimensions,
+		})
+	}
+	var parsed googleBatchEmbedResponse
+	if err := s.postEmbeddings(ctx, pp, endpoint, apiKey, map[string]any{"requests": requests}, &parsed); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, 0, len(parsed.Embeddings))
+	for _, e := range parsed.Embeddings {
+		vectors = append(vectors, e.Values)
+	}
+	return vectors, nil
+}
+
+// postEmbeddings sends the request through the provider's transport, so the
+// app's proxy, CA and TLS settings apply.
+func (s *ModelPresetStore) postEmbeddings(
+	ctx context.Context,
+	pp spec.ProviderPreset,
+	endpoint, apiKey string,
+	payload, out any,
+) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	setProviderHeaders(httpReq, pp, apiKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := s.providerHTTPClient(pp.Name, embeddingTimeout).Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("embeddings: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, embeddingMaxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("embeddings: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("embeddings: %s: %s",
+			resp.Status, strings.TrimSpace(string(body[:min(len(body), 512)])))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("embeddings: invalid response: %w", err)
+	}
+	return nil
+}
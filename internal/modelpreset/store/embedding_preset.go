@@ -0,0 +1,307 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/idempotency"
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// maxEmbeddingDimensions bounds a requested vector size; the largest
+// embedding models today produce a few thousand dimensions.
+const maxEmbeddingDimensions = 16384
+
+// PostEmbeddingPreset creates an embedding preset on a built-in or user
+// provider.
+func (s *ModelPresetStore) PostEmbeddingPreset(
+	ctx context.Context, req *spec.PostEmbeddingPresetRequest,
+) (*spec.PostEmbeddingPresetResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.postEmbeddingPreset(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PostEmbeddingPreset", req.IdempotencyKey, req,
+		func() (*spec.PostEmbeddingPresetResponse, error) { return s.postEmbeddingPreset(ctx, req) })
+}
+
+func (s *ModelPresetStore) postEmbeddingPreset(
+	ctx context.Context, req *spec.PostEmbeddingPresetRequest,
+) (*spec.PostEmbeddingPresetResponse, error) {
+	if req == nil || req.Body == nil || req.EmbeddingPresetID == "" {
+		return nil, fmt.Errorf("%w: embeddingPresetID required", spec.ErrInvalidDir)
+	}
+
+	now := time.Now().UTC()
+	ep := spec.EmbeddingPreset{
+		SchemaVersion: spec.SchemaVersion,
+		ID:            req.EmbeddingPresetID,
+		DisplayName:   req.Body.DisplayName,
+		ProviderName:  req.Body.ProviderName,
+		ModelName:     req.Body.ModelName,
+		Dimensions:    req.Body.Dimensions,
+		IsEnabled:     req.Body.IsEnabled,
+		CreatedAt:     now,
+		ModifiedAt:    now,
+	}
+	if err := validateEmbeddingPreset(&ep); err != nil {
+		return nil, err
+	}
+	if err := s.checkEmbeddingProvider(ctx, ep.ProviderName); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAllUserPresets()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := all.EmbeddingPresets[ep.ID]; ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrEmbeddingPresetAlreadyExists, ep.ID)
+	}
+	if req.DryRun {
+		return &spec.PostEmbeddingPresetResponse{
+			Body: &spec.PostEmbeddingPresetResponseBody{EmbeddingPreset: ep},
+		}, nil
+	}
+	if all.EmbeddingPresets == nil {
+		all.EmbeddingPresets = map[spec.EmbeddingPresetID]spec.EmbeddingPreset{}
+	}
+	all.EmbeddingPresets[ep.ID] = ep
+	if err := s.writeAllUserPresets(all); err != nil {
+		return nil, err
+	}
+	s.notifyEmbeddingPreset(spec.PresetChangeEmbeddingCreated, ep)
+	logger.Info("postEmbeddingPreset", "embeddingPresetID", ep.ID, "provider", ep.ProviderName)
+	return &spec.PostEmbeddingPresetResponse{}, nil
+}
+
+// PatchEmbeddingPreset updates an embedding preset.
+func (s *ModelPresetStore) PatchEmbeddingPreset(
+	ctx context.Context, req *spec.PatchEmbeddingPresetRequest,
+) (*spec.PatchEmbeddingPresetResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.patchEmbeddingPreset(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PatchEmbeddingPreset", req.IdempotencyKey, req,
+		func() (*spec.PatchEmbeddingPresetResponse, error) { return s.patchEmbeddingPreset(ctx, req) })
+}
+
+func (s *ModelPresetStore) patchEmbeddingPreset(
+	ctx context.Context, req *spec.PatchEmbeddingPresetRequest,
+) (*spec.PatchEmbeddingPresetResponse, error) {
+	if req == nil || req.Body == nil || req.EmbeddingPresetID == "" {
+		return nil, fmt.Errorf("%w: embeddingPresetID required", spec.ErrInvalidDir)
+	}
+	b := req.Body
+	if b.DisplayName == nil && b.ProviderName == nil && b.ModelName == nil &&
+		b.Dimensions == nil && b.IsEnabled == nil {
+		return nil, fmt.Errorf("%w: at least one embedding preset field must be supplied", spec.ErrInvalidDir)
+	}
+	if b.ProviderName != nil {
+		if err := s.checkEmbeddingProvider(ctx, *b.ProviderName); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAllUserPresets()
+	if err != nil {
+		return nil, err
+	}
+	ep, ok := all.EmbeddingPresets[req.EmbeddingPresetID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrEmbeddingPresetNotFound, req.EmbeddingPresetID)
+	}
+	before := ep
+	if b.DisplayName != nil {
+		ep.DisplayName = *b.DisplayName
+	}
+	if b.ProviderName != nil {
+		ep.ProviderName = *b.ProviderName
+	}
+	if b.ModelName != nil {
+		ep.ModelName = *b.ModelName
+	}
+	if b.Dimensions != nil {
+		ep.Dimensions = *b.Dimensions
+	}
+	if b.IsEnabled != nil {
+		ep.IsEnabled = *b.IsEnabled
+	}
+	if err := validateEmbeddingPreset(&ep); err != nil {
+		return nil, fmt.Errorf("invalid patched embedding preset: %w", err)
+	}
+	changed := ep != before
+	if changed {
+		ep.ModifiedAt = time.Now().UTC()
+	}
+	if req.DryRun {
+		return &spec.PatchEmbeddingPresetResponse{
+			Body: &spec.PatchEmbeddingPresetResponseBody{EmbeddingPreset: ep},
+		}, nil
+	}
+	if !changed {
+		return &spec.PatchEmbeddingPresetResponse{}, nil
+	}
+
+	all.EmbeddingPresets[ep.ID] = ep
+	if err := s.writeAllUserPresets(all); err != nil {
+		return nil, err
+	}
+	s.notifyEmbeddingPreset(spec.PresetChangeEmbeddingUpdated, ep)
+	logger.Info("patchEmbeddingPreset", "embeddingPresetID", ep.ID, "enabled", ep.IsEnabled)
+	return &spec.PatchEmbeddingPresetResponse{}, nil
+}
+
+// DeleteEmbeddingPreset removes an embedding preset.
+func (s *ModelPresetStore) DeleteEmbeddingPreset(
+	ctx context.Context, req *spec.DeleteEmbeddingPresetRequest,
+) (*spec.DeleteEmbeddingPresetResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.deleteEmbeddingPreset(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "DeleteEmbeddingPreset", req.IdempotencyKey, req,
+		func() (*spec.DeleteEmbeddingPresetResponse, error) { return s.deleteEmbeddingPreset(ctx, req) })
+}
+
+func (s *ModelPresetStore) deleteEmbeddingPreset(
+	ctx context.Context, req *spec.DeleteEmbeddingPresetRequest,
+) (*spec.DeleteEmbeddingPresetResponse, error) {
+	if req == nil || req.EmbeddingPresetID == "" {
+		return nil, fmt.Errorf("%w: embeddingPresetID required", spec.ErrInvalidDir)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAllUserPresets()
+	if err != nil {
+		return nil, err
+	}
+	ep, ok := all.EmbeddingPresets[req.EmbeddingPresetID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrEmbeddingPresetNotFound, req.EmbeddingPresetID)
+	}
+	if req.DryRun {
+		return &spec.DeleteEmbeddingPresetResponse{
+			Body: &spec.DeleteEmbeddingPresetResponseBody{EmbeddingPreset: ep},
+		}, nil
+	}
+	delete(all.EmbeddingPresets, req.EmbeddingPresetID)
+	if err := s.writeAllUserPresets(all); err != nil {
+		return nil, err
+	}
+	s.notifyEmbeddingPreset(spec.PresetChangeEmbeddingDeleted, ep)
+	logger.Info("deleteEmbeddingPreset", "embeddingPresetID", ep.ID)
+	return &spec.DeleteEmbeddingPresetResponse{}, nil
+}
+
+// GetEmbeddingPreset returns one embedding preset.
+func (s *ModelPresetStore) GetEmbeddingPreset(
+	ctx context.Context, req *spec.GetEmbeddingPresetRequest,
+) (*spec.GetEmbeddingPresetResponse, error) {
+	if req == nil || req.EmbeddingPresetID == "" {
+		return nil, fmt.Errorf("%w: embeddingPresetID required", spec.ErrInvalidDir)
+	}
+	s.mu.RLock()
+	all, err := s.readAllUserPresets()
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	ep, ok := all.EmbeddingPresets[req.EmbeddingPresetID]
+	if !ok || (!ep.IsEnabled && !req.IncludeDisabled) {
+		return nil, fmt.Errorf("%w: %s", spec.ErrEmbeddingPresetNotFound, req.EmbeddingPresetID)
+	}
+	return &spec.GetEmbeddingPresetResponse{Body: &ep}, nil
+}
+
+// ListEmbeddingPresets returns the embedding presets sorted by ID.
+func (s *ModelPresetStore) ListEmbeddingPresets(
+	ctx context.Context, req *spec.ListEmbeddingPresetsRequest,
+) (*spec.ListEmbeddingPresetsResponse, error) {
+	includeDisabled := req != nil && req.IncludeDisabled
+	s.mu.RLock()
+	all, err := s.readAllUserPresets()
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]spec.EmbeddingPreset, 0, len(all.EmbeddingPresets))
+	for _, ep := range all.EmbeddingPresets {
+		if ep.IsEnabled || includeDisabled {
+			out = append(out, ep)
+		}
+	}
+	slices.SortFunc(out, func(a, b spec.EmbeddingPreset) int { return cmp.Compare(a.ID, b.ID) })
+	return &spec.ListEmbeddingPresetsResponse{
+		Body: &spec.ListEmbeddingPresetsResponseBody{EmbeddingPresets: out},
+	}, nil
+}
+
+// getProviderPreset returns a built-in or live user provider preset.
+func (s *ModelPresetStore) getProviderPreset(
+	ctx context.Context, name inferenceSpec.ProviderName,
+) (spec.ProviderPreset, error) {
+	if s.builtinData != nil {
+		if pp, err := s.builtinData.GetBuiltInProvider(ctx, name); err == nil {
+			return pp, nil
+		}
+	}
+	s.mu.RLock()
+	all, err := s.readAllUserPresets()
+	s.mu.RUnlock()
+	if err != nil {
+		return spec.ProviderPreset{}, err
+	}
+	return getUserProviderPreset(all, name)
+}
+
+// checkEmbeddingProvider verifies that the provider exists and speaks an API
+// with an embeddings endpoint.
+func (s *ModelPresetStore) checkEmbeddingProvider(ctx context.Context, name inferenceSpec.ProviderName) error {
+	pp, err := s.getProviderPreset(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !supportsEmbeddings(pp.SDKType) {
+		return fmt.Errorf("%w: %s (sdkType %q)", spec.ErrEmbeddingsUnsupported, name, pp.SDKType)
+	}
+	return nil
+}
+
+func validateEmbeddingPreset(ep *spec.EmbeddingPreset) error {
+	if ep.SchemaVersion != spec.SchemaVersion {
+		return fmt.Errorf("schemaVersion %q not equal to %q", ep.SchemaVersion, spec.SchemaVersion)
+	}
+	if err := bundleitemutils.ValidateTag(string(ep.ID)); err != nil {
+		return fmt.Errorf("invalid id: %w", err)
+	}
+	if strings.TrimSpace(string(ep.DisplayName)) == "" {
+		return errors.New("displayName is empty")
+	}
+	if ep.ProviderName == "" {
+		return errors.New("providerName is empty")
+	}
+	if err := validateModelName(ep.ModelName); err != nil {
+		return fmt.Errorf("modelName: %w", err)
+	}
+	if ep.Dimensions < 0 || ep.Dimensions > maxEmbeddingDimensions {
+		return fmt.Errorf("dimensions must be between 0 and %d", maxEmbeddingDimensions)
+	}
+	if ep.CreatedAt.IsZero() || ep.ModifiedAt.IsZero() {
+		return spec.ErrInvalidTimestamp
+	}
+	return nil
+}
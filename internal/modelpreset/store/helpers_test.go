@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"runtime"
 	"strings"
@@ -260,3 +261,7 @@ func providerDisplayNamePtr(v spec.ProviderDisplayName) *spec.ProviderDisplayNam
 func mpidPtr(v spec.ModelPresetID) *spec.ModelPresetID { return new(v) }
 
 func isWindows() bool { return runtime.GOOS == windowsGOOS }
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
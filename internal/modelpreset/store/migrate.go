@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+)
+
+// presetsMigration upgrades the raw user file from one schemaVersion to the
+// next. Steps work on the raw map, so they can move or drop fields the current
+// structs no longer decode.
+type presetsMigration struct {
+	From  string
+	To    string
+	Apply func(raw map[string]any) error
+}
+
+// presetsMigrations is the ordered upgrade chain ending at spec.SchemaVersion.
+var presetsMigrations = []presetsMigration{
+	{
+		// Files written before versioning carry no schemaVersion anywhere.
+		From:  "",
+		To:    spec.SchemaVersion,
+		Apply: stampPresetsSchemaVersion,
+	},
+}
+
+// Migrate upgrades modelpresets.json to spec.SchemaVersion. The file as it was
+// is kept next to it as a backup before the upgraded data is written.
+func (s *ModelPresetStore) Migrate(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := s.userStore.GetAll(true)
+	if err != nil {
+		return fmt.Errorf("migrate: read store: %w", err)
+	}
+	from, _ := raw["schemaVersion"].(string)
+	if from == spec.SchemaVersion {
+		return nil
+	}
+
+	steps, err := presetsMigrationPath(from, spec.SchemaVersion)
+	if err != nil {
+		return err
+	}
+	if err := s.backupUserPresetsFile(from); err != nil {
+		return fmt.Errorf("migrate: backup: %w", err)
+	}
+	for _, step := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := step.Apply(raw); err != nil {
+			return fmt.Errorf("migrate %q -> %q: %w", step.From, step.To, err)
+		}
+		raw["schemaVersion"] = step.To
+	}
+	if err := s.userStore.SetAll(raw); err != nil {
+		return fmt.Errorf("migrate: write store: %w", err)
+	}
+	logger.Info("model presets migrated", "from", from, "to", spec.SchemaVersion, "steps", len(steps))
+	return nil
+}
+
+// presetsMigrationPath returns the steps leading from one version to another.
+func presetsMigrationPath(from, to string) ([]presetsMigration, error) {
+	var steps []presetsMigration
+	for version := from; version != to; {
+		var next *presetsMigration
+		for i := range presetsMigrations {
+			if presetsMigrations[i].From == version {
+				next = &presetsMigrations[i]
+				break
+			}
+		}
+		if next == nil {
+			// Versions are dates, so a later one was written by a newer app.
+			if version > to {
+				return nil, fmt.Errorf("%w: %q is newer than %q",
+					spec.ErrUnsupportedSchemaVersion, version, to)
+			}
+			return nil, fmt.Errorf("%w: no migration from %q",
+				spec.ErrUnsupportedSchemaVersion, version)
+		}
+		if len(steps) > len(presetsMigrations) {
+			return nil, fmt.Errorf("%w: migration cycle at %q",
+				spec.ErrUnsupportedSchemaVersion, version)
+		}
+		steps = append(steps, *next)
+		version = next.To
+	}
+	return steps, nil
+}
+
+func (s *ModelPresetStore) backupUserPresetsFile(version string) error {
+	src := filepath.Join(s.baseDir, spec.ModelPresetsFile)
+	data, err := os.ReadFile(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if version == "" {
+		version = "unversioned"
+	}
+	dst := strings.TrimSuffix(src, ".json") + "." + version + ".bak.json"
+	return os.WriteFile(dst, data, 0o600)
+}
+
+// stampPresetsSchemaVersion sets schemaVersion on every provider and model
+// preset that lacks one.
+func stampPresetsSchemaVersion(raw map[string]any) error {
+	providers, _ := raw["providerPresets"].(map[string]any)
+	for name, v := range providers {
+		pp, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("provider %q: unexpected type %T", name, v)
+		}
+		if version, _ := pp["schemaVersion"].(string); version == "" {
+			pp["schemaVersion"] = spec.SchemaVersion
+		}
+		models, _ := pp["modelPresets"].(map[string]any)
+		for id, mv := range models {
+			mp, ok := mv.(map[string]any)
+			if !ok {
+				return fmt.Errorf("model preset %q/%q: unexpected type %T", name, id, mv)
+			}
+			if version, _ := mp["schemaVersion"].(string); version == "" {
+				mp["schemaVersion"] = spec.SchemaVersion
+			}
+		}
+	}
+	return nil
+}
@@ -4,18 +4,31 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"reflect"
 	"slices"
 	"time"
 
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/idempotency"
 	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	"github.com/flexigpt/flexigpt-app/internal/precondition"
 	"github.com/flexigpt/inference-go/capabilityoverride"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
 )
 
 // PatchModelPreset updates a model preset.
 func (s *ModelPresetStore) PatchModelPreset(
 	ctx context.Context, req *spec.PatchModelPresetRequest,
+) (*spec.PatchModelPresetResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.patchModelPreset(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PatchModelPreset", req.IdempotencyKey, req,
+		func() (*spec.PatchModelPresetResponse, error) { return s.patchModelPreset(ctx, req) })
+}
+
+func (s *ModelPresetStore) patchModelPreset(
+	ctx context.Context, req *spec.PatchModelPresetRequest,
 ) (*spec.PatchModelPresetResponse, error) {
 	if req == nil || req.Body == nil ||
 		req.ProviderName == "" || req.ModelPresetID == "" {
@@ -32,18 +45,73 @@ func (s *ModelPresetStore) PatchModelPreset(
 	// Built-in branch.
 	if _, err := s.builtinData.GetBuiltInProvider(ctx, req.ProviderName); err == nil {
 		if hasAnyReadOnlyBuiltInModelPatch(req.Body) {
-			return nil, fmt.Errorf("%w: only isEnabled can be patched for built-in model presets",
+			return nil, fmt.Errorf(
+				"%w: only isEnabled, tags, pricing and systemPrompt can be patched for built-in model presets",
 				spec.ErrBuiltInReadOnly)
 		}
-		if req.Body.IsEnabled == nil {
-			return nil, fmt.Errorf("%w: isEnabled must be supplied for built-in model presets",
-				spec.ErrInvalidDir)
-		}
 		currentMP, err := s.builtinData.GetBuiltInModelPreset(ctx, req.ProviderName, req.ModelPresetID)
 		if err != nil {
 			return nil, err
 		}
-		if currentMP.IsEnabled == *req.Body.IsEnabled {
+		err = precondition.CheckModifiedAt(req.Body.ExpectedModifiedAt, currentMP.ModifiedAt, currentMP)
+		if err != nil {
+			return nil, err
+		}
+		if req.DryRun {
+			return s.dryRunBuiltInModelPatch(ctx, req, currentMP)
+		}
+		if req.Body.Tags != nil {
+			if err := bundleitemutils.ValidateTags(*req.Body.Tags); err != nil {
+				return nil, fmt.Errorf("%w: invalid tags: %w", spec.ErrInvalidDir, err)
+			}
+			if !slices.Equal(currentMP.Tags, *req.Body.Tags) {
+				if _, err := s.builtinData.SetModelPresetTags(
+					ctx,
+					req.ProviderName, req.ModelPresetID, *req.Body.Tags,
+				); err != nil {
+					return nil, err
+				}
+				s.notify(spec.PresetChangeModelUpdated, req.ProviderName, req.ModelPresetID)
+				logger.Info("patchModelPreset.builtin",
+					"provider", req.ProviderName, "modelPresetID", req.ModelPresetID,
+					"tags", *req.Body.Tags)
+			}
+		}
+		if req.Body.Pricing != nil {
+			if err := validateModelPricing(req.Body.Pricing); err != nil {
+				return nil, fmt.Errorf("%w: invalid pricing: %w", spec.ErrInvalidDir, err)
+			}
+			if !equalModelPricing(currentMP.Pricing, req.Body.Pricing) {
+				if _, err := s.builtinData.SetModelPresetPricing(
+					ctx,
+					req.ProviderName, req.ModelPresetID, *req.Body.Pricing,
+				); err != nil {
+					return nil, err
+				}
+				s.notify(spec.PresetChangeModelUpdated, req.ProviderName, req.ModelPresetID)
+				logger.Info("patchModelPreset.builtin",
+					"provider", req.ProviderName, "modelPresetID", req.ModelPresetID,
+					"pricing", *req.Body.Pricing)
+			}
+		}
+		if req.Body.SystemPrompt != nil {
+			if err := validateSystemPrompt(req.Body.SystemPrompt); err != nil {
+				return nil, fmt.Errorf("%w: invalid systemPrompt: %w", spec.ErrInvalidDir, err)
+			}
+			if currentMP.SystemPrompt == nil || *currentMP.SystemPrompt != *req.Body.SystemPrompt {
+				if _, err := s.builtinData.SetModelPresetSystemPrompt(
+					ctx,
+					req.ProviderName, req.ModelPresetID, *req.Body.SystemPrompt,
+				); err != nil {
+					return nil, err
+				}
+				s.notify(spec.PresetChangeModelUpdated, req.ProviderName, req.ModelPresetID)
+				logger.Info("patchModelPreset.builtin",
+					"provider", req.ProviderName, "modelPresetID", req.ModelPresetID,
+					"systemPromptLength", len(*req.Body.SystemPrompt))
+			}
+		}
+		if req.Body.IsEnabled == nil || currentMP.IsEnabled == *req.Body.IsEnabled {
 			return &spec.PatchModelPresetResponse{}, nil
 		}
 
@@ -53,7 +121,8 @@ func (s *ModelPresetStore) PatchModelPreset(
 		); err != nil {
 			return nil, err
 		}
-		slog.Info("patchModelPreset.builtin",
+		s.notify(spec.PresetChangeModelUpdated, req.ProviderName, req.ModelPresetID)
+		logger.Info("patchModelPreset.builtin",
 			"provider", req.ProviderName, "modelPresetID", req.ModelPresetID,
 			"enabled", *req.Body.IsEnabled)
 		return &spec.PatchModelPresetResponse{}, nil
@@ -63,41 +132,203 @@ func (s *ModelPresetStore) PatchModelPreset(
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	all, err := s.readAllUserPresets(false)
+	all, err := s.readAllUserPresets()
 	if err != nil {
 		return nil, err
 	}
-	pp, ok := all.ProviderPresets[req.ProviderName]
-	if !ok {
-		return nil, fmt.Errorf("%w: %s", spec.ErrProviderNotFound, req.ProviderName)
+	pp, err := getUserProviderPreset(all, req.ProviderName)
+	if err != nil {
+		return nil, err
 	}
 	mp, ok := pp.ModelPresets[req.ModelPresetID]
 	if !ok {
 		return nil, fmt.Errorf("%w: %s", spec.ErrModelPresetNotFound, req.ModelPresetID)
 	}
+	if err := precondition.CheckModifiedAt(req.Body.ExpectedModifiedAt, mp.ModifiedAt, mp); err != nil {
+		return nil, err
+	}
 	changed := applyModelPresetPatch(&mp, req.Body)
 
-	if err := validateModelPreset(&mp); err != nil {
+	validate := validateModelPreset
+	if req.Body.Reasoning != nil || req.Body.Temperature != nil {
+		validate = validateAuthoredModelPreset
+	}
+	if err := validate(&mp); err != nil {
 		return nil, fmt.Errorf("invalid patched model preset: %w", err)
 	}
+	// Stored budgets are only rechecked when the patch touches them.
+	if req.Body.Reasoning != nil || req.Body.MaxOutputLength != nil {
+		if err := validateReasoningForSDK(pp, &mp); err != nil {
+			return nil, fmt.Errorf("invalid patched model preset: %w", err)
+		}
+	}
+	if changed {
+		mp.ModifiedAt = time.Now().UTC()
+		pp.ModelPresets[req.ModelPresetID] = mp
+		pp.ModifiedAt = mp.ModifiedAt
+	}
+	if req.DryRun {
+		body := &spec.PatchModelPresetResponseBody{ModelPreset: mp, ProviderPreset: pp}
+		return &spec.PatchModelPresetResponse{Body: body}, nil
+	}
 	if !changed {
 		return &spec.PatchModelPresetResponse{}, nil
 	}
 
-	mp.ModifiedAt = time.Now().UTC()
-	pp.ModelPresets[req.ModelPresetID] = mp
-	pp.ModifiedAt = mp.ModifiedAt
 	all.ProviderPresets[req.ProviderName] = pp
 
 	if err := s.writeAllUserPresets(all); err != nil {
 		return nil, err
 	}
-	slog.Info("patchModelPreset",
+	s.notify(spec.PresetChangeModelUpdated, req.ProviderName, req.ModelPresetID)
+	logger.Info("patchModelPreset",
 		"provider", req.ProviderName, "modelPresetID", req.ModelPresetID,
 		"enabled", mp.IsEnabled)
 	return &spec.PatchModelPresetResponse{}, nil
 }
 
+// dryRunBuiltInModelPatch validates the overlay fields of a patch of the
+// built-in model preset mp and returns it as the patch would leave it.
+func (s *ModelPresetStore) dryRunBuiltInModelPatch(
+	ctx context.Context, req *spec.PatchModelPresetRequest, mp spec.ModelPreset,
+) (*spec.PatchModelPresetResponse, error) {
+	body := req.Body
+	if body.Tags != nil {
+		if err := bundleitemutils.ValidateTags(*body.Tags); err != nil {
+			return nil, fmt.Errorf("%w: invalid tags: %w", spec.ErrInvalidDir, err)
+		}
+		mp.Tags = slices.Clone(*body.Tags)
+	}
+	if body.Pricing != nil {
+		if err := validateModelPricing(body.Pricing); err != nil {
+			return nil, fmt.Errorf("%w: invalid pricing: %w", spec.ErrInvalidDir, err)
+		}
+		mp.Pricing = cloneModelPricing(body.Pricing)
+	}
+	if body.SystemPrompt != nil {
+		if err := validateSystemPrompt(body.SystemPrompt); err != nil {
+			return nil, fmt.Errorf("%w: invalid systemPrompt: %w", spec.ErrInvalidDir, err)
+		}
+		mp.SystemPrompt = new(*body.SystemPrompt)
+	}
+	if body.IsEnabled != nil {
+		mp.IsEnabled = *body.IsEnabled
+	}
+	mp.ModifiedAt = time.Now().UTC()
+
+	pp, err := s.builtinData.GetBuiltInProvider(ctx, req.ProviderName)
+	if err != nil {
+		return nil, err
+	}
+	pp.ModelPresets[mp.ID] = mp
+	return &spec.PatchModelPresetResponse{
+		Body: &spec.PatchModelPresetResponseBody{ModelPreset: mp, ProviderPreset: pp},
+	}, nil
+}
+
+// PatchAllModelPresets enables or disables all model presets of a provider,
+// optionally only those with any of the given tags, in one write.
+func (s *ModelPresetStore) PatchAllModelPresets(
+	ctx context.Context, req *spec.PatchAllModelPresetsRequest,
+) (*spec.PatchAllModelPresetsResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.patchAllModelPresets(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PatchAllModelPresets", req.IdempotencyKey, req,
+		func() (*spec.PatchAllModelPresetsResponse, error) { return s.patchAllModelPresets(ctx, req) })
+}
+
+func (s *ModelPresetStore) patchAllModelPresets(
+	ctx context.Context, req *spec.PatchAllModelPresetsRequest,
+) (*spec.PatchAllModelPresetsResponse, error) {
+	if req == nil || req.Body == nil || req.ProviderName == "" {
+		return nil, fmt.Errorf("%w: providerName & body required", spec.ErrInvalidDir)
+	}
+	var wantTags map[string]struct{}
+	if len(req.Body.Tags) != 0 {
+		wantTags = make(map[string]struct{}, len(req.Body.Tags))
+		for _, tag := range req.Body.Tags {
+			wantTags[tag] = struct{}{}
+		}
+	}
+	selectIDs := func(models map[spec.ModelPresetID]spec.ModelPreset) []spec.ModelPresetID {
+		var ids []spec.ModelPresetID
+		for id, mp := range models {
+			if mp.IsEnabled == req.Body.IsEnabled {
+				continue
+			}
+			if wantTags != nil && !hasAnyTag(mp.Tags, wantTags) {
+				continue
+			}
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+	resp := func(ids []spec.ModelPresetID) *spec.PatchAllModelPresetsResponse {
+		if ids == nil {
+			ids = []spec.ModelPresetID{}
+		}
+		return &spec.PatchAllModelPresetsResponse{
+			Body: &spec.PatchAllModelPresetsResponseBody{UpdatedModelPresetIDs: ids},
+		}
+	}
+
+	// Built-in branch.
+	if s.builtinData != nil {
+		if bpp, err := s.builtinData.GetBuiltInProvider(ctx, req.ProviderName); err == nil {
+			ids := selectIDs(bpp.ModelPresets)
+			if len(ids) == 0 || req.DryRun {
+				return resp(ids), nil
+			}
+			if err := s.builtinData.SetModelPresetsEnabled(
+				ctx, req.ProviderName, ids, req.Body.IsEnabled,
+			); err != nil {
+				return nil, err
+			}
+			s.notify(spec.PresetChangeModelUpdated, req.ProviderName, ids...)
+			logger.Info("patchAllModelPresets.builtin",
+				"provider", req.ProviderName, "count", len(ids), "enabled", req.Body.IsEnabled)
+			return resp(ids), nil
+		}
+	}
+
+	// User branch.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAllUserPresets()
+	if err != nil {
+		return nil, err
+	}
+	pp, err := getUserProviderPreset(all, req.ProviderName)
+	if err != nil {
+		return nil, err
+	}
+	ids := selectIDs(pp.ModelPresets)
+	if len(ids) == 0 || req.DryRun {
+		return resp(ids), nil
+	}
+
+	now := time.Now().UTC()
+	for _, id := range ids {
+		mp := pp.ModelPresets[id]
+		mp.IsEnabled = req.Body.IsEnabled
+		mp.ModifiedAt = now
+		pp.ModelPresets[id] = mp
+	}
+	pp.ModifiedAt = now
+	all.ProviderPresets[req.ProviderName] = pp
+
+	if err := s.writeAllUserPresets(all); err != nil {
+		return nil, err
+	}
+	s.notify(spec.PresetChangeModelUpdated, req.ProviderName, ids...)
+	logger.Info("patchAllModelPresets",
+		"provider", req.ProviderName, "count", len(ids), "enabled", req.Body.IsEnabled)
+	return resp(ids), nil
+}
+
 func validateModelPresetPatchRequestBody(body *spec.PatchModelPresetRequestBody) error {
 	if body == nil {
 		return errors.New("body is required")
@@ -117,6 +348,8 @@ func hasAnyModelPresetPatchMutation(body *spec.PatchModelPresetRequestBody) bool
 		body.Slug != nil ||
 		body.DisplayName != nil ||
 		body.IsEnabled != nil ||
+		body.Tags != nil ||
+		body.Pricing != nil ||
 		hasModelPresetPatchValue(body.ModelPresetPatch)
 }
 
@@ -124,10 +357,12 @@ func hasAnyReadOnlyBuiltInModelPatch(body *spec.PatchModelPresetRequestBody) boo
 	if body == nil {
 		return false
 	}
+	patch := body.ModelPresetPatch
+	patch.SystemPrompt = nil
 	return body.Name != nil ||
 		body.Slug != nil ||
 		body.DisplayName != nil ||
-		hasModelPresetPatchValue(body.ModelPresetPatch)
+		hasModelPresetPatchValue(patch)
 }
 
 func hasModelPresetPatchValue(p spec.ModelPresetPatch) bool {
@@ -160,27 +395,46 @@ func applyModelPresetPatch(dst *spec.ModelPreset, body *spec.PatchModelPresetReq
 	if body.IsEnabled != nil {
 		dst.IsEnabled = *body.IsEnabled
 	}
+	if body.Tags != nil {
+		dst.Tags = nil
+		if len(*body.Tags) > 0 {
+			dst.Tags = slices.Clone(*body.Tags)
+		}
+	}
+	if body.Pricing != nil {
+		dst.Pricing = nil
+		if !body.Pricing.IsZero() {
+			dst.Pricing = cloneModelPricing(body.Pricing)
+		}
+	}
 
 	if body.Stream != nil {
 		dst.Stream = cloneBoolPtr(body.Stream)
 	}
+	// Negative numbers and an empty reasoning clear the knob.
 	if body.MaxPromptLength != nil {
-		dst.MaxPromptLength = cloneIntPtr(body.MaxPromptLength)
+		dst.MaxPromptLength = clearableIntPtr(body.MaxPromptLength)
 	}
 	if body.MaxOutputLength != nil {
-		dst.MaxOutputLength = cloneIntPtr(body.MaxOutputLength)
+		dst.MaxOutputLength = clearableIntPtr(body.MaxOutputLength)
 	}
 	if body.Temperature != nil {
-		dst.Temperature = cloneFloat64Ptr(body.Temperature)
+		dst.Temperature = nil
+		if *body.Temperature >= 0 {
+			dst.Temperature = cloneFloat64Ptr(body.Temperature)
+		}
 	}
 	if body.Reasoning != nil {
-		dst.Reasoning = cloneReasoningParam(body.Reasoning)
+		dst.Reasoning = nil
+		if *body.Reasoning != (inferenceSpec.ReasoningParam{}) {
+			dst.Reasoning = cloneReasoningParam(body.Reasoning)
+		}
 	}
 	if body.SystemPrompt != nil {
 		dst.SystemPrompt = cloneStringPtr(body.SystemPrompt)
 	}
 	if body.Timeout != nil {
-		dst.Timeout = cloneIntPtr(body.Timeout)
+		dst.Timeout = clearableIntPtr(body.Timeout)
 	}
 	if body.CacheControl != nil {
 		dst.CacheControl = cloneCacheControl(body.CacheControl)
@@ -203,3 +457,22 @@ func applyModelPresetPatch(dst *spec.ModelPreset, body *spec.PatchModelPresetReq
 	after := cloneModelPreset(*dst)
 	return !reflect.DeepEqual(before, after)
 }
+
+func clearableIntPtr(v *int) *int {
+	if *v < 0 {
+		return nil
+	}
+	return cloneIntPtr(v)
+}
+
+// equalModelPricing treats nil and zero pricing as equal.
+func equalModelPricing(a, b *spec.ModelPricing) bool {
+	var av, bv spec.ModelPricing
+	if a != nil {
+		av = *a
+	}
+	if b != nil {
+		bv = *b
+	}
+	return av == bv
+}
@@ -0,0 +1,404 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/idempotency"
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	"github.com/flexigpt/flexigpt-app/internal/precondition"
+	"github.com/flexigpt/flexigpt-app/internal/validation"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// maxProfileTemperature is the highest temperature any supported provider
+// accepts.
+const maxProfileTemperature = 2.0
+
+// PostParameterProfile creates a parameter profile.
+func (s *ModelPresetStore) PostParameterProfile(
+	ctx context.Context, req *spec.PostParameterProfileRequest,
+) (*spec.PostParameterProfileResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.postParameterProfile(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PostParameterProfile", req.IdempotencyKey, req,
+		func() (*spec.PostParameterProfileResponse, error) { return s.postParameterProfile(ctx, req) })
+}
+
+func (s *ModelPresetStore) postParameterProfile(
+	ctx context.Context, req *spec.PostParameterProfileRequest,
+) (*spec.PostParameterProfileResponse, error) {
+	if req == nil || req.Body == nil || req.ParameterProfileID == "" {
+		return nil, fmt.Errorf("%w: parameterProfileID required", spec.ErrInvalidDir)
+	}
+
+	now := time.Now().UTC()
+	p := spec.ParameterProfile{
+		SchemaVersion: spec.SchemaVersion,
+		ID:            req.ParameterProfileID,
+		DisplayName:   req.Body.DisplayName,
+		Description:   req.Body.Description,
+		Temperature:   cloneFloat64Ptr(req.Body.Temperature),
+		Reasoning:     cloneReasoningParam(req.Body.Reasoning),
+		StopSequences: cloneStopSequences(req.Body.StopSequences),
+		CreatedAt:     now,
+		ModifiedAt:    now,
+	}
+	if err := validateParameterProfile(&p); err != nil {
+		return nil, fmt.Errorf("%w: %w", spec.ErrInvalidDir, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAllUserPresets()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := all.ParameterProfiles[p.ID]; ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrParameterProfileAlreadyExists, p.ID)
+	}
+	if req.DryRun {
+		return &spec.PostParameterProfileResponse{
+			Body: &spec.PostParameterProfileResponseBody{ParameterProfile: p},
+		}, nil
+	}
+	if all.ParameterProfiles == nil {
+		all.ParameterProfiles = map[spec.ParameterProfileID]spec.ParameterProfile{}
+	}
+	all.ParameterProfiles[p.ID] = p
+	if err := s.writeAllUserPresets(all); err != nil {
+		return nil, err
+	}
+	logger.Info("postParameterProfile", "parameterProfileID", p.ID)
+	return &spec.PostParameterProfileResponse{}, nil
+}
+
+// PatchParameterProfile updates a parameter profile. Model presets the
+// profile was applied to are not changed.
+func (s *ModelPresetStore) PatchParameterProfile(
+	ctx context.Context, req *spec.PatchParameterProfileRequest,
+) (*spec.PatchParameterProfileResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.patchParameterProfile(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PatchParameterProfile", req.IdempotencyKey, req,
+		func() (*spec.PatchParameterProfileResponse, error) { return s.patchParameterProfile(ctx, req) })
+}
+
+func (s *ModelPresetStore) patchParameterProfile(
+	ctx context.Context, req *spec.PatchParameterProfileRequest,
+) (*spec.PatchParameterProfileResponse, error) {
+	if req == nil || req.Body == nil || req.ParameterProfileID == "" {
+		return nil, fmt.Errorf("%w: parameterProfileID required", spec.ErrInvalidDir)
+	}
+	b := req.Body
+	if b.DisplayName == nil && b.Description == nil && b.Temperature == nil &&
+		b.Reasoning == nil && b.StopSequences == nil {
+		return nil, fmt.Errorf("%w: at least one parameter profile field must be supplied", spec.ErrInvalidDir)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAllUserPresets()
+	if err != nil {
+		return nil, err
+	}
+	p, ok := all.ParameterProfiles[req.ParameterProfileID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrParameterProfileNotFound, req.ParameterProfileID)
+	}
+	if err := precondition.CheckModifiedAt(b.ExpectedModifiedAt, p.ModifiedAt, p); err != nil {
+		return nil, err
+	}
+	before := cloneParameterProfile(p)
+	if b.DisplayName != nil {
+		p.DisplayName = *b.DisplayName
+	}
+	if b.Description != nil {
+		p.Description = *b.Description
+	}
+	if b.Temperature != nil {
+		p.Temperature = nil
+		if *b.Temperature >= 0 {
+			p.Temperature = cloneFloat64Ptr(b.Temperature)
+		}
+	}
+	if b.Reasoning != nil {
+		p.Reasoning = nil
+		if *b.Reasoning != (inferenceSpec.ReasoningParam{}) {
+			p.Reasoning = cloneReasoningParam(b.Reasoning)
+		}
+	}
+	if b.StopSequences != nil {
+		p.StopSequences = cloneStopSequences(b.StopSequences)
+	}
+	if err := validateParameterProfile(&p); err != nil {
+		return nil, fmt.Errorf("%w: invalid patched parameter profile: %w", spec.ErrInvalidDir, err)
+	}
+	changed := !reflect.DeepEqual(before, p)
+	if changed {
+		p.ModifiedAt = time.Now().UTC()
+	}
+	if req.DryRun {
+		return &spec.PatchParameterProfileResponse{
+			Body: &spec.PatchParameterProfileResponseBody{ParameterProfile: p},
+		}, nil
+	}
+	if !changed {
+		return &spec.PatchParameterProfileResponse{}, nil
+	}
+
+	all.ParameterProfiles[p.ID] = p
+	if err := s.writeAllUserPresets(all); err != nil {
+		return nil, err
+	}
+	logger.Info("patchParameterProfile", "parameterProfileID", p.ID)
+	return &spec.PatchParameterProfileResponse{}, nil
+}
+
+// DeleteParameterProfile removes a parameter profile. Model presets the
+// profile was applied to keep their parameters.
+func (s *ModelPresetStore) DeleteParameterProfile(
+	ctx context.Context, req *spec.DeleteParameterProfileRequest,
+) (*spec.DeleteParameterProfileResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.deleteParameterProfile(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "DeleteParameterProfile", req.IdempotencyKey, req,
+		func() (*spec.DeleteParameterProfileResponse, error) { return s.deleteParameterProfile(ctx, req) })
+}
+
+func (s *ModelPresetStore) deleteParameterProfile(
+	ctx context.Context, req *spec.DeleteParameterProfileRequest,
+) (*spec.DeleteParameterProfileResponse, error) {
+	if req == nil || req.ParameterProfileID == "" {
+		return nil, fmt.Errorf("%w: parameterProfileID required", spec.ErrInvalidDir)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAllUserPresets()
+	if err != nil {
+		return nil, err
+	}
+	p, ok := all.ParameterProfiles[req.ParameterProfileID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrParameterProfileNotFound, req.ParameterProfileID)
+	}
+	if err := precondition.CheckModifiedAt(req.ExpectedModifiedAt, p.ModifiedAt, p); err != nil {
+		return nil, err
+	}
+	if req.DryRun {
+		return &spec.DeleteParameterProfileResponse{
+			Body: &spec.DeleteParameterProfileResponseBody{ParameterProfile: p},
+		}, nil
+	}
+	delete(all.ParameterProfiles, req.ParameterProfileID)
+	if err := s.writeAllUserPresets(all); err != nil {
+		return nil, err
+	}
+	logger.Info("deleteParameterProfile", "parameterProfileID", req.ParameterProfileID)
+	return &spec.DeleteParameterProfileResponse{}, nil
+}
+
+// GetParameterProfile returns one parameter profile.
+func (s *ModelPresetStore) GetParameterProfile(
+	ctx context.Context, req *spec.GetParameterProfileRequest,
+) (*spec.GetParameterProfileResponse, error) {
+	if req == nil || req.ParameterProfileID == "" {
+		return nil, fmt.Errorf("%w: parameterProfileID required", spec.ErrInvalidDir)
+	}
+	s.mu.RLock()
+	all, err := s.readAllUserPresets()
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	p, ok := all.ParameterProfiles[req.ParameterProfileID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrParameterProfileNotFound, req.ParameterProfileID)
+	}
+	return &spec.GetParameterProfileResponse{Body: &p}, nil
+}
+
+// ListParameterProfiles returns the parameter profiles sorted by ID.
+func (s *ModelPresetStore) ListParameterProfiles(
+	ctx context.Context, _ *spec.ListParameterProfilesRequest,
+) (*spec.ListParameterProfilesResponse, error) {
+	s.mu.RLock()
+	all, err := s.readAllUserPresets()
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]spec.ParameterProfile, 0, len(all.ParameterProfiles))
+	for _, p := range all.ParameterProfiles {
+		out = append(out, p)
+	}
+	slices.SortFunc(out, func(a, b spec.ParameterProfile) int { return cmp.Compare(a.ID, b.ID) })
+	return &spec.ListParameterProfilesResponse{
+		Body: &spec.ListParameterProfilesResponseBody{ParameterProfiles: out},
+	}, nil
+}
+
+// ApplyParameterProfile copies the parameters set in a profile onto user
+// model presets in one write. Built-in model presets are read-only, so
+// listing one fails the whole request. A dry run returns the changed presets
+// without saving them.
+func (s *ModelPresetStore) ApplyParameterProfile(
+	ctx context.Context, req *spec.ApplyParameterProfileRequest,
+) (*spec.ApplyParameterProfileResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.applyParameterProfileToPresets(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "ApplyParameterProfile", req.IdempotencyKey, req,
+		func() (*spec.ApplyParameterProfileResponse, error) { return s.applyParameterProfileToPresets(ctx, req) })
+}
+
+func (s *ModelPresetStore) applyParameterProfileToPresets(
+	ctx context.Context, req *spec.ApplyParameterProfileRequest,
+) (*spec.ApplyParameterProfileResponse, error) {
+	if req == nil || req.Body == nil || req.ParameterProfileID == "" || len(req.Body.ModelPresets) == 0 {
+		return nil, fmt.Errorf("%w: parameterProfileID and modelPresets required", spec.ErrInvalidDir)
+	}
+	for _, ref := range req.Body.ModelPresets {
+		if ref.IsZero() || ref.ProviderName == "" || ref.ModelPresetID == "" {
+			return nil, fmt.Errorf("%w: modelPresets need providerName and modelPresetID", spec.ErrInvalidDir)
+		}
+		if _, err := s.builtinData.GetBuiltInProvider(ctx, ref.ProviderName); err == nil {
+			return nil, fmt.Errorf("%w: model preset %s/%s",
+				spec.ErrBuiltInReadOnly, ref.ProviderName, ref.ModelPresetID)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAllUserPresets()
+	if err != nil {
+		return nil, err
+	}
+	profile, ok := all.ParameterProfiles[req.ParameterProfileID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrParameterProfileNotFound, req.ParameterProfileID)
+	}
+	err = precondition.CheckModifiedAt(req.Body.ExpectedModifiedAt, profile.ModifiedAt, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	updated := []spec.ModelPresetRef{}
+	for _, ref := range req.Body.ModelPresets {
+		pp, err := getUserProviderPreset(all, ref.ProviderName)
+		if err != nil {
+			return nil, err
+		}
+		mp, ok := pp.ModelPresets[ref.ModelPresetID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s/%s", spec.ErrModelPresetNotFound, ref.ProviderName, ref.ModelPresetID)
+		}
+		if !applyParameterProfile(&mp, profile) {
+			continue
+		}
+		mp.ModifiedAt = now
+		if err := validateModelPreset(&mp); err != nil {
+			return nil, fmt.Errorf("%w: model preset %s/%s: %w",
+				spec.ErrInvalidDir, ref.ProviderName, ref.ModelPresetID, err)
+		}
+		if err := validateReasoningForSDK(pp, &mp); err != nil {
+			return nil, fmt.Errorf("%w: model preset %s/%s: %w",
+				spec.ErrInvalidDir, ref.ProviderName, ref.ModelPresetID, err)
+		}
+		pp.ModelPresets[ref.ModelPresetID] = mp
+		pp.ModifiedAt = now
+		all.ProviderPresets[ref.ProviderName] = pp
+		if !slices.Contains(updated, ref) {
+			updated = append(updated, ref)
+		}
+	}
+
+	if req.DryRun {
+		presets := make([]spec.ModelPreset, 0, len(updated))
+		for _, ref := range updated {
+			presets = append(presets, all.ProviderPresets[ref.ProviderName].ModelPresets[ref.ModelPresetID])
+		}
+		return &spec.ApplyParameterProfileResponse{
+			Body: &spec.ApplyParameterProfileResponseBody{UpdatedModelPresets: updated, ModelPresets: presets},
+		}, nil
+	}
+	if len(updated) > 0 {
+		if err := s.writeAllUserPresets(all); err != nil {
+			return nil, err
+		}
+		for _, ref := range updated {
+			s.notify(spec.PresetChangeModelUpdated, ref.ProviderName, ref.ModelPresetID)
+		}
+	}
+	logger.Info("applyParameterProfile",
+		"parameterProfileID", profile.ID, "requested", len(req.Body.ModelPresets), "updated", len(updated))
+	return &spec.ApplyParameterProfileResponse{
+		Body: &spec.ApplyParameterProfileResponseBody{UpdatedModelPresets: updated},
+	}, nil
+}
+
+// applyParameterProfile sets the parameters of p on mp and reports whether
+// mp changed.
+func applyParameterProfile(mp *spec.ModelPreset, p spec.ParameterProfile) bool {
+	before := cloneModelPreset(*mp)
+	if p.Temperature != nil {
+		mp.Temperature = cloneFloat64Ptr(p.Temperature)
+	}
+	if p.Reasoning != nil {
+		mp.Reasoning = cloneReasoningParam(p.Reasoning)
+	}
+	if p.StopSequences != nil {
+		mp.StopSequences = nil
+		if len(*p.StopSequences) > 0 {
+			mp.StopSequences = cloneStopSequences(p.StopSequences)
+		}
+	}
+	return !reflect.DeepEqual(before, cloneModelPreset(*mp))
+}
+
+func validateParameterProfile(p *spec.ParameterProfile) error {
+	r := &validation.Report{}
+	if p.SchemaVersion != spec.SchemaVersion {
+		r.Addf("schemaVersion", validation.CodeUnsupported, "schemaVersion %q not equal to %q",
+			p.SchemaVersion, spec.SchemaVersion)
+	}
+	r.Checkf("id", validation.CodeInvalid, "invalid id", bundleitemutils.ValidateTag(string(p.ID)))
+	if strings.TrimSpace(string(p.DisplayName)) == "" {
+		r.Addf("displayName", validation.CodeRequired, "displayName is empty")
+	}
+	if p.Temperature == nil && p.Reasoning == nil && p.StopSequences == nil {
+		r.Addf("temperature", validation.CodeRequired,
+			"at least one of temperature, reasoning or stopSequences must be set")
+	}
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > maxProfileTemperature) {
+		r.Addf("temperature", validation.CodeOutOfRange, "temperature must be between 0 and %g",
+			maxProfileTemperature)
+	}
+	if p.Reasoning != nil {
+		r.Checkf("reasoning", validation.CodeInvalid, "invalid reasoning", validateReasoning(p.Reasoning))
+	}
+	r.Checkf("stopSequences", validation.CodeInvalid, "invalid stopSequences",
+		validateStopSequences(p.StopSequences))
+	if p.CreatedAt.IsZero() {
+		r.Check("createdAt", validation.CodeRequired, spec.ErrInvalidTimestamp)
+	}
+	if p.ModifiedAt.IsZero() {
+		r.Check("modifiedAt", validation.CodeRequired, spec.ErrInvalidTimestamp)
+	}
+	return r.Err()
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+const (
+	probeMaxResponseBytes = 1 << 20
+	probeTimeout          = 10 * time.Second
+)
+
+// probeAPIKeyHeaders are the key header styles OpenAI-compatible gateways use,
+// tried in order until one is not rejected as unauthorized.
+var probeAPIKeyHeaders = []string{
+	spec.DefaultAuthorizationHeaderKey,
+	spec.DefaultAzureOpenAIAPIKeyHeaderKey,
+	spec.DefaultAnthropicAuthorizationHeaderKey,
+}
+
+// ProbeOrigin checks which OpenAI-compatible endpoints a gateway serves and
+// returns a provider preset body filled in from what it found. Nothing is
+// stored.
+func (s *ModelPresetStore) ProbeOrigin(
+	ctx context.Context, req *spec.ProbeOriginRequest,
+) (*spec.ProbeOriginResponse, error) {
+	if req == nil || req.Body == nil || strings.TrimSpace(req.Body.BaseURL) == "" {
+		return nil, fmt.Errorf("%w: baseURL required", spec.ErrInvalidDir)
+	}
+	base, roots, err := probeAPIRoots(req.Body.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", spec.ErrInvalidDir, err)
+	}
+
+	// The gateway is not a provider yet, so it gets the shared network
+	// settings; redirects to another origin are refused.
+	p := &originProber{
+		origin: base.Scheme + "://" + base.Host,
+		apiKey: req.Body.APIKey,
+		client: s.providerHTTPClient("", probeTimeout),
+	}
+	var res *probeRootResult
+	for _, root := range roots {
+		res = p.probeRoot(ctx, root)
+		if res.found() {
+			break
+		}
+	}
+
+	out := &spec.ProbeOriginResponseBody{
+		Endpoints: res.endpoints,
+		Models:    res.models,
+		Provider: spec.PostProviderPresetRequestBody{
+			DisplayName:              spec.ProviderDisplayName(base.Hostname()),
+			SDKType:                  inferenceSpec.ProviderSDKTypeOpenAIChatCompletions,
+			IsEnabled:                true,
+			Origin:                   p.origin,
+			ChatCompletionPathPrefix: res.root + "chat/completions",
+			APIKeyHeaderKey:          res.header,
+			DefaultHeaders:           maps.Clone(spec.OpenAIChatCompletionsDefaultHeaders),
+		},
+	}
+	logger.Info("probeOrigin", "origin", p.origin, "root", res.root, "found", res.found())
+	return &spec.ProbeOriginResponse{Body: out}, nil
+}
+
+// probeAPIRoots splits baseURL into its origin and the API roots to try. A
+// path ending in a version segment such as "/v1" is the root itself;
+// otherwise "<path>/v1/" is tried before "<path>/".
+func probeAPIRoots(baseURL string) (*url.URL, []string, error) {
+	u, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid baseURL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, nil, fmt.Errorf("baseURL %q must be an http(s) URL", baseURL)
+	}
+	p := strings.TrimRight(u.Path, "/")
+	last := p[strings.LastIndex(p, "/")+1:]
+	if len(last) > 1 && last[0] == 'v' && last[1] >= '0' && last[1] <= '9' {
+		return u, []string{p + "/"}, nil
+	}
+	return u, []string{p + "/v1/", p + "/"}, nil
+}
+
+type originProber struct {
+	origin string
+	apiKey string
+	client *http.Client
+}
+
+type probeRootResult struct {
+	root      string
+	header    string
+	endpoints []spec.ProbedEndpoint
+	models    []spec.ModelName
+}
+
+func (r *probeRootResult) found() bool {
+	return slices.ContainsFunc(r.endpoints, func(e spec.ProbedEndpoint) bool {
+		return e.Found && e.Kind != spec.ProbeEndpointEmbeddings
+	})
+}
+
+// probeRoot probes the model listing first, as it tells the key header style
+// apart without spending tokens, then the chat and embedding endpoints with
+// an empty body a live endpoint rejects as invalid.
+func (p *originProber) probeRoot(ctx context.Context, root string) *probeRootResult {
+	res := &probeRootResult{root: root, header: spec.DefaultAuthorizationHeaderKey}
+	headers := probeAPIKeyHeaders
+	if p.apiKey == "" {
+		headers = headers[:1]
+	}
+
+	modelsEP, header, raw := p.probe(ctx, spec.ProbeEndpointModels, http.MethodGet, root+"models", headers)
+	if modelsEP.Found && modelsEP.StatusCode >= 200 && modelsEP.StatusCode <= 299 {
+		models, err := probeModelNames(raw)
+		if err != nil {
+			modelsEP.Found = false
+			modelsEP.Error = err.Error()
+		}
+		res.models = models
+	}
+	if modelsEP.Found {
+		res.header = header
+		headers = []string{header}
+	}
+
+	chatEP, header, _ := p.probe(
+		ctx, spec.ProbeEndpointChatCompletions, http.MethodPost, root+"chat/completions", headers)
+	if chatEP.Found && !modelsEP.Found {
+		res.header = header
+		headers = []string{header}
+	}
+	embeddingsEP, _, _ := p.probe(ctx, spec.ProbeEndpointEmbeddings, http.MethodPost, root+"embeddings", headers)
+
+	res.endpoints = []spec.ProbedEndpoint{chatEP, modelsEP, embeddingsEP}
+	return res
+}
+
+// probe requests path with each key header in turn until one is not rejected
+// as unauthorized, and returns the endpoint state, that header and the body.
+func (p *originProber) probe(
+	ctx context.Context, kind spec.ProbeEndpointKind, method, path string, headers []string,
+) (ep spec.ProbedEndpoint, header string, raw []byte) {
+	ep = spec.ProbedEndpoint{Kind: kind, Path: path}
+	for _, header = range headers {
+		var body io.Reader
+		if method == http.MethodPost {
+			body = bytes.NewReader([]byte("{}"))
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, method, p.origin+path, body)
+		if err != nil {
+			ep.Error = err.Error()
+			return ep, header, nil
+		}
+		httpReq.Header.Set("Accept", "application/json")
+		if body != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+		setProviderHeaders(httpReq, spec.ProviderPreset{APIKeyHeaderKey: header}, p.apiKey)
+
+		resp, err := p.client.Do(httpReq)
+		if err != nil {
+			ep.Error = err.Error()
+			return ep, header, nil
+		}
+		raw, err = io.ReadAll(io.LimitReader(resp.Body, probeMaxResponseBytes))
+		resp.Body.Close()
+		ep.StatusCode = resp.StatusCode
+		ep.Found = probeEndpointExists(resp.StatusCode)
+		if err != nil {
+			ep.Error = err.Error()
+		}
+		if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden {
+			return ep, header, raw
+		}
+	}
+	// Every header style was refused; report the endpoint with the default.
+	return ep, headers[0], raw
+}
+
+// probeEndpointExists reports whether a status means the route is served,
+// even if the probe request itself was refused.
+func probeEndpointExists(status int) bool {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden,
+		http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusTooManyRequests:
+		return true
+	}
+	return status >= 200 && status <= 299
+}
+
+// probeModelNames reads an OpenAI model listing, rejecting other 2xx bodies
+// such as a web page served for every path.
+func probeModelNames(raw []byte) ([]spec.ModelName, error) {
+	var parsed struct {
+		Data *[]discoveredModel `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil || parsed.Data == nil {
+		return nil, errors.New("response is not an OpenAI model listing")
+	}
+	var names []spec.ModelName
+	for _, m := range *parsed.Data {
+		if id := strings.TrimSpace(m.ID); id != "" {
+			names = append(names, spec.ModelName(id))
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
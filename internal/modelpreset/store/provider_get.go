@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+)
+
+// GetProviderPreset returns one built-in or user provider preset with its
+// model presets.
+func (s *ModelPresetStore) GetProviderPreset(
+	ctx context.Context, req *spec.GetProviderPresetRequest,
+) (*spec.GetProviderPresetResponse, error) {
+	if req == nil || req.ProviderName == "" {
+		return nil, fmt.Errorf("%w: providerName required", spec.ErrInvalidDir)
+	}
+	pp, err := s.getProviderPreset(ctx, req.ProviderName)
+	if err != nil {
+		return nil, err
+	}
+	if !pp.IsEnabled && !req.IncludeDisabled {
+		return nil, fmt.Errorf("%w: %s", spec.ErrProviderNotFound, req.ProviderName)
+	}
+	pp = cloneProviderPreset(pp)
+	return &spec.GetProviderPresetResponse{Body: &pp}, nil
+}
+
+// GetProviderPresetsBatch fetches several provider presets in one call. A
+// provider that cannot be fetched gets an item error instead of failing the
+// batch.
+func (s *ModelPresetStore) GetProviderPresetsBatch(
+	ctx context.Context, req *spec.GetProviderPresetsBatchRequest,
+) (*spec.GetProviderPresetsBatchResponse, error) {
+	if req == nil || req.Body == nil || len(req.Body.ProviderNames) == 0 {
+		return nil, fmt.Errorf("%w: providerNames required", spec.ErrInvalidDir)
+	}
+	if len(req.Body.ProviderNames) > spec.MaxPageSize {
+		return nil, fmt.Errorf("%w: at most %d providerNames", spec.ErrInvalidDir, spec.MaxPageSize)
+	}
+	items := make([]spec.GetProviderPresetsBatchItem, 0, len(req.Body.ProviderNames))
+	for _, name := range req.Body.ProviderNames {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		item := spec.GetProviderPresetsBatchItem{ProviderName: name}
+		resp, err := s.GetProviderPreset(ctx, &spec.GetProviderPresetRequest{
+			ProviderName:    name,
+			IncludeDisabled: req.Body.IncludeDisabled,
+		})
+		if err != nil {
+			item.Error = err.Error()
+		} else {
+			item.Provider = resp.Body
+		}
+		items = append(items, item)
+	}
+	return &spec.GetProviderPresetsBatchResponse{
+		Body: &spec.GetProviderPresetsBatchResponseBody{Items: items},
+	}, nil
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/flexigpt/flexigpt-app/internal/fsutil"
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// providerListSnapshot holds the built-in and live user providers as of one
+// state of the presets file and one generation of the built-in view. Pages of
+// ListProviderPresets share it until either changes, so they neither decode
+// the presets file nor sort all providers again. Providers in it are never
+// modified; page clones the ones it returns.
+type providerListSnapshot struct {
+	userState  fsutil.FileState
+	builtInGen uint64
+	providers  []spec.ProviderPreset
+
+	sortMu sync.Mutex
+	sorted map[spec.ProviderPresetSortBy]*sortedProviders
+}
+
+// sortedProviders is the snapshot in one order, with the position of each
+// provider to resume after a cursor.
+type sortedProviders struct {
+	providers []spec.ProviderPreset
+	pos       map[inferenceSpec.ProviderName]int
+}
+
+// providerListSnapshot returns the current snapshot, rebuilding it if the
+// presets file or the built-in view changed since it was taken.
+func (s *ModelPresetStore) providerListSnapshot(ctx context.Context) (*providerListSnapshot, error) {
+	// Read the generation first: a view change racing the rebuild below only
+	// makes the next call rebuild again.
+	var gen uint64
+	if s.builtinData != nil {
+		gen = s.builtinData.viewGeneration()
+	}
+	if snap := s.providerSnapshot.Load(); snap != nil && snap.builtInGen == gen {
+		st, err := fsutil.ReadFileStateSince(s.userFilePath(), snap.userState)
+		if err != nil {
+			return nil, err
+		}
+		if st.SameContent(snap.userState) {
+			return snap, nil
+		}
+	}
+
+	snap := &providerListSnapshot{builtInGen: gen}
+	if s.builtinData != nil {
+		bi, _, _ := s.builtinData.ListBuiltInPresets(ctx)
+		for _, p := range bi {
+			snap.providers = append(snap.providers, p)
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, err := s.readAllUserPresets()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range user.ProviderPresets {
+		if isSoftDeletedProviderPreset(p) {
+			continue
+		}
+		snap.providers = append(snap.providers, p)
+	}
+	s.fileStateMu.Lock()
+	snap.userState = s.userFileState
+	s.fileStateMu.Unlock()
+	// Publish under mu so a write cannot slip in between the read and the
+	// store and leave an older snapshot behind its invalidation.
+	s.providerSnapshot.Store(snap)
+	return snap, nil
+}
+
+// invalidateProviderSnapshot drops the snapshot after a write. Caller must
+// hold mu for writing.
+func (s *ModelPresetStore) invalidateProviderSnapshot() {
+	s.providerSnapshot.Store(nil)
+}
+
+// order returns the providers sorted by by, sorting them on first use.
+func (snap *providerListSnapshot) order(by spec.ProviderPresetSortBy) *sortedProviders {
+	snap.sortMu.Lock()
+	defer snap.sortMu.Unlock()
+	if sp, ok := snap.sorted[by]; ok {
+		return sp
+	}
+	sp := &sortedProviders{
+		providers: slices.SortedFunc(slices.Values(snap.providers), func(a, b spec.ProviderPreset) int {
+			return compareProviderPresets(by, a, b)
+		}),
+		pos: make(map[inferenceSpec.ProviderName]int, len(snap.providers)),
+	}
+	for i, p := range sp.providers {
+		sp.pos[p.Name] = i
+	}
+	if snap.sorted == nil {
+		snap.sorted = map[spec.ProviderPresetSortBy]*sortedProviders{}
+	}
+	snap.sorted[by] = sp
+	return sp
+}
+
+// page returns clones of up to pageSize providers matching f that follow
+// cursor in order by, and whether more follow. An unknown cursor starts from
+// the beginning.
+func (snap *providerListSnapshot) page(
+	by spec.ProviderPresetSortBy,
+	f providerPresetFilter,
+	cursor inferenceSpec.ProviderName,
+	pageSize int,
+) (page []spec.ProviderPreset, more bool) {
+	sp := snap.order(by)
+	start := 0
+	if i, ok := sp.pos[cursor]; ok && cursor != "" {
+		start = i + 1
+	}
+
+	page = make([]spec.ProviderPreset, 0, pageSize)
+	for _, p := range sp.providers[start:] {
+		if !f.matches(p) {
+			continue
+		}
+		p = cloneProviderPreset(p)
+		if !f.filterModelTags(&p) {
+			continue
+		}
+		if len(page) == pageSize {
+			return page, true
+		}
+		page = append(page, p)
+	}
+	return page, false
+}
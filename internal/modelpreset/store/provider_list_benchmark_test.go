@@ -0,0 +1,120 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// BenchmarkListProviderPresets pages through n user providers. With the
+// snapshot warm, the first and a late page should cost about the same for
+// every n; Cold shows the cost of rebuilding it.
+func BenchmarkListProviderPresets(b *testing.B) {
+	for _, n := range []int{100, 1000} {
+		st := newBenchStore(b)
+		seedUserProviders(b, st, n)
+		first := &spec.ListProviderPresetsRequest{IncludeDisabled: true, PageSize: spec.DefaultPageSize}
+		last := lastProviderPageRequest(b, st, first)
+
+		b.Run(fmt.Sprintf("%dProviders_FirstPage", n), func(b *testing.B) {
+			benchmarkListProviderPresets(b, st, first, false)
+		})
+		b.Run(fmt.Sprintf("%dProviders_LastPage", n), func(b *testing.B) {
+			benchmarkListProviderPresets(b, st, last, false)
+		})
+		b.Run(fmt.Sprintf("%dProviders_FirstPage_Cold", n), func(b *testing.B) {
+			benchmarkListProviderPresets(b, st, first, true)
+		})
+	}
+}
+
+func benchmarkListProviderPresets(
+	b *testing.B,
+	st *ModelPresetStore,
+	req *spec.ListProviderPresetsRequest,
+	cold bool,
+) {
+	b.Helper()
+	ctx := b.Context()
+	for b.Loop() {
+		if cold {
+			st.providerSnapshot.Store(nil)
+		}
+		resp, err := st.ListProviderPresets(ctx, req)
+		if err != nil {
+			b.Fatalf("ListProviderPresets: %v", err)
+		}
+		if len(resp.Body.Providers) == 0 {
+			b.Fatal("empty page")
+		}
+	}
+}
+
+func newBenchStore(b *testing.B) *ModelPresetStore {
+	b.Helper()
+	st, err := NewModelPresetStore(b.TempDir())
+	if err != nil {
+		b.Fatalf("NewModelPresetStore: %v", err)
+	}
+	b.Cleanup(func() { _ = st.Close() })
+	return st
+}
+
+// seedUserProviders writes n enabled user providers in a single write.
+func seedUserProviders(b *testing.B, st *ModelPresetStore, n int) {
+	b.Helper()
+	now := time.Now().UTC()
+	all := spec.PresetsSchema{
+		SchemaVersion:   spec.SchemaVersion,
+		ProviderPresets: make(map[inferenceSpec.ProviderName]spec.ProviderPreset, n),
+	}
+	for i := range n {
+		name := inferenceSpec.ProviderName(fmt.Sprintf("user-bench-%04d", i))
+		at := now.Add(time.Duration(i) * time.Second)
+		all.ProviderPresets[name] = spec.ProviderPreset{
+			SchemaVersion:            spec.SchemaVersion,
+			Name:                     name,
+			DisplayName:              spec.ProviderDisplayName(name),
+			SDKType:                  inferenceSpec.ProviderSDKTypeOpenAIChatCompletions,
+			IsEnabled:                true,
+			CreatedAt:                at,
+			ModifiedAt:               at,
+			Origin:                   "https://api." + string(name) + ".example.test",
+			ChatCompletionPathPrefix: spec.DefaultOpenAIChatCompletionsPrefix,
+			APIKeyHeaderKey:          spec.DefaultAuthorizationHeaderKey,
+			DefaultHeaders:           spec.OpenAIChatCompletionsDefaultHeaders,
+			ModelPresets:             map[spec.ModelPresetID]spec.ModelPreset{},
+		}
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	// Writes refuse a file they have not read.
+	if _, err := st.readAllUserPresets(); err != nil {
+		b.Fatalf("readAllUserPresets: %v", err)
+	}
+	if err := st.writeAllUserPresets(all); err != nil {
+		b.Fatalf("writeAllUserPresets: %v", err)
+	}
+}
+
+// lastProviderPageRequest follows page tokens from req to the last page.
+func lastProviderPageRequest(
+	b *testing.B,
+	st *ModelPresetStore,
+	req *spec.ListProviderPresetsRequest,
+) *spec.ListProviderPresetsRequest {
+	b.Helper()
+	for {
+		resp, err := st.ListProviderPresets(b.Context(), req)
+		if err != nil {
+			b.Fatalf("ListProviderPresets: %v", err)
+		}
+		if resp.Body.NextPageToken == nil {
+			return req
+		}
+		req = &spec.ListProviderPresetsRequest{PageToken: *resp.Body.NextPageToken}
+	}
+}
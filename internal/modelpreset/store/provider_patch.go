@@ -4,31 +4,59 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"maps"
 	"reflect"
+	"strings"
 	"time"
 
+	"github.com/flexigpt/flexigpt-app/internal/idempotency"
 	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	"github.com/flexigpt/flexigpt-app/internal/precondition"
+	"github.com/flexigpt/flexigpt-app/internal/validation"
 	"github.com/flexigpt/inference-go/capabilityoverride"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
 )
 
 // PatchProviderPreset updates a provider preset.
 //
-// User providers support partial updates to metadata, default model, and capabilities override.
+// User providers support partial updates to metadata, endpoint and header
+// fields, default model, and capabilities override. Each supplied field is
+// validated before the patched preset as a whole.
 //
 // Built-in providers only support overlaying:
 //   - isEnabled
 //   - defaultModelPresetID
+//   - rateLimits
+//   - resilience
+//   - imageProcessing
 func (s *ModelPresetStore) PatchProviderPreset(
 	ctx context.Context, req *spec.PatchProviderPresetRequest,
+) (*spec.PatchProviderPresetResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.patchProviderPreset(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PatchProviderPreset", req.IdempotencyKey, req,
+		func() (*spec.PatchProviderPresetResponse, error) { return s.patchProviderPreset(ctx, req) })
+}
+
+func (s *ModelPresetStore) patchProviderPreset(
+	ctx context.Context, req *spec.PatchProviderPresetRequest,
 ) (*spec.PatchProviderPresetResponse, error) {
 	if req == nil || req.Body == nil || req.ProviderName == "" {
 		return nil, fmt.Errorf("%w: providerName required", spec.ErrInvalidDir)
 	}
-	if err := validateProviderPresetPatchRequestBody(req.Body); err != nil {
+	if err := validateProviderPresetPatchRequestBody(req.ProviderName, req.Body); err != nil {
 		return nil, fmt.Errorf("%w: %w", spec.ErrInvalidDir, err)
 	}
+	if err := validateProviderRateLimits(req.Body.RateLimits); err != nil {
+		return nil, fmt.Errorf("%w: rateLimits: %w", spec.ErrInvalidDir, err)
+	}
+	if err := validateProviderResilience(req.ProviderName, req.Body.Resilience); err != nil {
+		return nil, fmt.Errorf("%w: resilience: %w", spec.ErrInvalidDir, err)
+	}
+	if err := validateProviderImageProcessing(req.Body.ImageProcessing); err != nil {
+		return nil, fmt.Errorf("%w: imageProcessing: %w", spec.ErrInvalidDir, err)
+	}
 	if req.Body.DefaultModelPresetID != nil {
 		if *req.Body.DefaultModelPresetID == "" {
 			return nil, fmt.Errorf("%w: defaultModelPresetID cannot be empty", spec.ErrInvalidDir)
@@ -40,9 +68,18 @@ func (s *ModelPresetStore) PatchProviderPreset(
 
 	if currentPP, err := s.builtinData.GetBuiltInProvider(ctx, req.ProviderName); err == nil {
 		if hasAnyReadOnlyBuiltInProviderPatch(req.Body) {
-			return nil, fmt.Errorf("%w: only isEnabled and defaultModelPresetID can be patched for built-in providers",
+			return nil, fmt.Errorf(
+				"%w: only isEnabled, defaultModelPresetID, rateLimits, resilience "+
+					"and imageProcessing can be patched for built-in providers",
 				spec.ErrBuiltInReadOnly)
 		}
+		err = precondition.CheckModifiedAt(req.Body.ExpectedModifiedAt, currentPP.ModifiedAt, currentPP)
+		if err != nil {
+			return nil, err
+		}
+		if req.DryRun {
+			return dryRunBuiltInProviderPatch(currentPP, req.Body)
+		}
 		changed := false
 		if req.Body.IsEnabled != nil && currentPP.IsEnabled != *req.Body.IsEnabled {
 			if _, err := s.builtinData.SetProviderEnabled(ctx,
@@ -63,8 +100,39 @@ func (s *ModelPresetStore) PatchProviderPreset(
 			}
 			changed = true
 		}
+
+		if req.Body.RateLimits != nil &&
+			!equalProviderRateLimits(currentPP.RateLimits, req.Body.RateLimits) {
+			if _, err := s.builtinData.SetProviderRateLimits(
+				ctx, req.ProviderName, *req.Body.RateLimits,
+			); err != nil {
+				return nil, err
+			}
+			changed = true
+		}
+
+		if req.Body.Resilience != nil &&
+			!equalProviderResilience(currentPP.Resilience, req.Body.Resilience) {
+			if _, err := s.builtinData.SetProviderResilience(
+				ctx, req.ProviderName, *req.Body.Resilience,
+			); err != nil {
+				return nil, err
+			}
+			changed = true
+		}
+
+		if req.Body.ImageProcessing != nil &&
+			!equalProviderImageProcessing(currentPP.ImageProcessing, req.Body.ImageProcessing) {
+			if _, err := s.builtinData.SetProviderImageProcessing(
+				ctx, req.ProviderName, *req.Body.ImageProcessing,
+			); err != nil {
+				return nil, err
+			}
+			changed = true
+		}
 		if changed {
-			slog.Info("patchProviderPreset.builtin", "provider", req.ProviderName)
+			s.notify(spec.PresetChangeProviderUpdated, req.ProviderName)
+			logger.Info("patchProviderPreset.builtin", "provider", req.ProviderName)
 		}
 
 		return &spec.PatchProviderPresetResponse{}, nil
@@ -73,37 +141,75 @@ func (s *ModelPresetStore) PatchProviderPreset(
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	all, err := s.readAllUserPresets(false)
+	all, err := s.readAllUserPresets()
 	if err != nil {
 		return nil, err
 	}
 
-	pp, ok := all.ProviderPresets[req.ProviderName]
-	if !ok {
-		return nil, fmt.Errorf("%w: %s", spec.ErrProviderNotFound, req.ProviderName)
+	pp, err := getUserProviderPreset(all, req.ProviderName)
+	if err != nil {
+		return nil, err
+	}
+	if err := precondition.CheckModifiedAt(req.Body.ExpectedModifiedAt, pp.ModifiedAt, pp); err != nil {
+		return nil, err
 	}
 
 	changed := applyProviderPresetPatch(&pp, req.Body)
 	if err := validateProviderPreset(&pp); err != nil {
 		return nil, fmt.Errorf("invalid patched provider preset: %w", err)
 	}
-
+	if changed {
+		pp.ModifiedAt = time.Now().UTC()
+	}
+	if req.DryRun {
+		return &spec.PatchProviderPresetResponse{Body: &spec.PatchProviderPresetResponseBody{ProviderPreset: pp}}, nil
+	}
 	if !changed {
 		return &spec.PatchProviderPresetResponse{}, nil
 	}
 
-	pp.ModifiedAt = time.Now().UTC()
 	all.ProviderPresets[req.ProviderName] = pp
 
 	if err := s.writeAllUserPresets(all); err != nil {
 		return nil, err
 	}
 
-	slog.Info("patchProviderPreset", "provider", req.ProviderName)
+	s.notify(spec.PresetChangeProviderUpdated, req.ProviderName)
+	logger.Info("patchProviderPreset", "provider", req.ProviderName)
 
 	return &spec.PatchProviderPresetResponse{}, nil
 }
 
+// dryRunBuiltInProviderPatch returns the built-in provider pp as the overlay
+// fields of body would leave it. body has been validated.
+func dryRunBuiltInProviderPatch(
+	pp spec.ProviderPreset, body *spec.PatchProviderPresetRequestBody,
+) (*spec.PatchProviderPresetResponse, error) {
+	if body.IsEnabled != nil {
+		pp.IsEnabled = *body.IsEnabled
+	}
+	if body.DefaultModelPresetID != nil {
+		if _, ok := pp.ModelPresets[*body.DefaultModelPresetID]; !ok {
+			return nil, fmt.Errorf("%w: %s", spec.ErrModelPresetNotFound, *body.DefaultModelPresetID)
+		}
+		pp.DefaultModelPresetID = *body.DefaultModelPresetID
+	}
+	if body.RateLimits != nil {
+		pp.RateLimits = cloneProviderRateLimits(body.RateLimits)
+	}
+	if body.Resilience != nil {
+		pp.Resilience = cloneProviderResilience(body.Resilience)
+	}
+	if body.ImageProcessing != nil {
+		pp.ImageProcessing = nil
+		if !body.ImageProcessing.IsZero() {
+			pp.ImageProcessing = cloneProviderImageProcessing(body.ImageProcessing)
+		}
+	}
+	pp.ModifiedAt = time.Now().UTC()
+	return &spec.PatchProviderPresetResponse{Body: &spec.PatchProviderPresetResponseBody{ProviderPreset: pp}}, nil
+}
+
 func hasAnyReadOnlyBuiltInProviderPatch(body *spec.PatchProviderPresetRequestBody) bool {
 	if body == nil {
 		return false
@@ -114,10 +220,14 @@ func hasAnyReadOnlyBuiltInProviderPatch(body *spec.PatchProviderPresetRequestBod
 		body.ChatCompletionPathPrefix != nil ||
 		body.APIKeyHeaderKey != nil ||
 		body.DefaultHeaders != nil ||
-		body.CapabilitiesOverride != nil
+		body.CapabilitiesOverride != nil ||
+		body.Azure != nil ||
+		body.TLS != nil
 }
 
-func validateProviderPresetPatchRequestBody(body *spec.PatchProviderPresetRequestBody) error {
+func validateProviderPresetPatchRequestBody(
+	provider inferenceSpec.ProviderName, body *spec.PatchProviderPresetRequestBody,
+) error {
 	if body == nil {
 		return errors.New("body is required")
 	}
@@ -125,7 +235,26 @@ func validateProviderPresetPatchRequestBody(body *spec.PatchProviderPresetReques
 		return errors.New("at least one provider preset field must be supplied")
 	}
 
-	return nil
+	// Check each supplied field on its own, so the error names the field
+	// instead of the merged preset failing as a whole.
+	r := &validation.Report{}
+	if body.DisplayName != nil && strings.TrimSpace(string(*body.DisplayName)) == "" {
+		r.Addf("displayName", validation.CodeRequired, "displayName: must not be empty")
+	}
+	if body.Origin != nil {
+		checkProviderOrigin(r, *body.Origin)
+	}
+	if body.ChatCompletionPathPrefix != nil {
+		checkChatCompletionPathPrefix(r, *body.ChatCompletionPathPrefix)
+	}
+	if body.APIKeyHeaderKey != nil || body.DefaultHeaders != nil {
+		apiKeyHeader := ""
+		if body.APIKeyHeaderKey != nil {
+			apiKeyHeader = *body.APIKeyHeaderKey
+		}
+		checkProviderHeaders(r, provider, apiKeyHeader, body.DefaultHeaders)
+	}
+	return r.Err()
 }
 
 func hasAnyProviderPatchMutation(body *spec.PatchProviderPresetRequestBody) bool {
@@ -140,7 +269,45 @@ func hasAnyProviderPatchMutation(body *spec.PatchProviderPresetRequestBody) bool
 		body.APIKeyHeaderKey != nil ||
 		body.DefaultHeaders != nil ||
 		body.DefaultModelPresetID != nil ||
-		body.CapabilitiesOverride != nil
+		body.CapabilitiesOverride != nil ||
+		body.RateLimits != nil ||
+		body.Resilience != nil ||
+		body.Azure != nil ||
+		body.TLS != nil ||
+		body.ImageProcessing != nil
+}
+
+// equalProviderRateLimits treats nil and zero limits as equal.
+func equalProviderRateLimits(a, b *spec.ProviderRateLimits) bool {
+	var za, zb spec.ProviderRateLimits
+	if a != nil {
+		za = *a
+	}
+	if b != nil {
+		zb = *b
+	}
+	return za == zb
+}
+
+// equalProviderImageProcessing treats nil and zero settings as equal.
+func equalProviderImageProcessing(a, b *spec.ProviderImageProcessing) bool {
+	var za, zb spec.ProviderImageProcessing
+	if a != nil {
+		za = *a
+	}
+	if b != nil {
+		zb = *b
+	}
+	return za == zb
+}
+
+// equalProviderResilience treats nil and zero resilience as equal.
+func equalProviderResilience(a, b *spec.ProviderResilience) bool {
+	aZero, bZero := a == nil || a.IsZero(), b == nil || b.IsZero()
+	if aZero || bZero {
+		return aZero == bZero
+	}
+	return reflect.DeepEqual(a, b)
 }
 
 func applyProviderPresetPatch(dst *spec.ProviderPreset, body *spec.PatchProviderPresetRequestBody) bool {
@@ -173,6 +340,40 @@ func applyProviderPresetPatch(dst *spec.ProviderPreset, body *spec.PatchProvider
 	if body.CapabilitiesOverride != nil {
 		dst.CapabilitiesOverride = capabilityoverride.CloneModelCapabilitiesOverride(body.CapabilitiesOverride)
 	}
+	if body.RateLimits != nil {
+		dst.RateLimits = nil
+		if !body.RateLimits.IsZero() {
+			dst.RateLimits = cloneProviderRateLimits(body.RateLimits)
+		}
+	}
+	if body.Resilience != nil {
+		dst.Resilience = nil
+		if !body.Resilience.IsZero() {
+			dst.Resilience = cloneProviderResilience(body.Resilience)
+		}
+	}
+	if body.Azure != nil {
+		// The origin follows the resource unless it was set to a custom
+		// endpoint, e.g. a private link, or is patched along.
+		derived := before.Azure == nil || dst.Origin == before.Azure.Origin()
+		if body.Origin == nil && (derived || strings.TrimSpace(dst.Origin) == "") {
+			dst.Origin = body.Azure.Origin()
+		}
+		dst.Azure = cloneAzureOpenAIConfig(body.Azure)
+		dst.ChatCompletionPathPrefix = body.Azure.ChatCompletionPathPrefix()
+	}
+	if body.TLS != nil {
+		dst.TLS = nil
+		if !body.TLS.IsZero() {
+			dst.TLS = cloneProviderTLSConfig(body.TLS)
+		}
+	}
+	if body.ImageProcessing != nil {
+		dst.ImageProcessing = nil
+		if !body.ImageProcessing.IsZero() {
+			dst.ImageProcessing = cloneProviderImageProcessing(body.ImageProcessing)
+		}
+	}
 
 	after := cloneProviderPreset(*dst)
 	return !reflect.DeepEqual(before, after)
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	"github.com/flexigpt/flexigpt-app/internal/validation"
+	"github.com/flexigpt/inference-go/capabilityoverride"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// reasoningBudgetRule is what a provider API accepts for a hybridWithTokens
+// reasoning budget. A zero bound is not checked.
+type reasoningBudgetRule struct {
+	// name is how the provider calls the budget, used in errors.
+	name string
+	// levelsOnly APIs take effort levels and reject token budgets.
+	levelsOnly bool
+	minTokens  int
+	maxTokens  int
+	// zeroAllowed and minusOneAllowed admit the special budgets 0 (off) and
+	// -1 (dynamic) below minTokens.
+	zeroAllowed     bool
+	minusOneAllowed bool
+	// belowMaxOutput is set when the budget is part of max_tokens and must
+	// leave room for the answer.
+	belowMaxOutput bool
+}
+
+// reasoningBudgetRulesBySDKType are the API-wide limits of each SDK type.
+// Model specific limits, such as the Gemini maximum, come from the
+// hybridTokenBudgetCapabilities of the provider and model capability
+// overrides, which replace the matching fields. SDK types without an entry are
+// only checked against those overrides.
+var reasoningBudgetRulesBySDKType = map[inferenceSpec.ProviderSDKType]reasoningBudgetRule{
+	inferenceSpec.ProviderSDKTypeAnthropic: {
+		name:           "Anthropic thinking budget",
+		minTokens:      1024,
+		belowMaxOutput: true,
+	},
+	inferenceSpec.ProviderSDKTypeGoogleGenerateContent: {
+		name:            "Gemini thinking budget",
+		minTokens:       1,
+		zeroAllowed:     true,
+		minusOneAllowed: true,
+	},
+	inferenceSpec.ProviderSDKTypeOpenAIChatCompletions: {name: "OpenAI reasoning effort", levelsOnly: true},
+	inferenceSpec.ProviderSDKTypeOpenAIResponses:       {name: "OpenAI reasoning effort", levelsOnly: true},
+	spec.ProviderSDKTypeAzureOpenAI:                    {name: "Azure OpenAI reasoning effort", levelsOnly: true},
+}
+
+// reasoningBudgetRuleFor returns the budget rule of the SDK type with the
+// token budget capabilities of the provider and model overrides applied.
+func reasoningBudgetRuleFor(pp spec.ProviderPreset, mp *spec.ModelPreset) (reasoningBudgetRule, bool) {
+	rule, ok := reasoningBudgetRulesBySDKType[pp.SDKType]
+	if rule.levelsOnly {
+		return rule, true
+	}
+	caps := capabilityoverride.DeriveModelCapabilities(
+		inferenceSpec.ModelCapabilities{}, pp.CapabilitiesOverride, mp.CapabilitiesOverride,
+	)
+	if caps.ReasoningCapabilities == nil || caps.ReasoningCapabilities.HybridTokenBudgetCapabilities == nil {
+		return rule, ok
+	}
+	budget := caps.ReasoningCapabilities.HybridTokenBudgetCapabilities
+	if rule.name == "" {
+		rule.name = "reasoning budget"
+	}
+	rule.minTokens = budget.MinAllowed
+	rule.maxTokens = budget.MaxAllowed
+	rule.zeroAllowed = budget.ZeroAllowed
+	rule.minusOneAllowed = budget.MinusOneAllowed
+	return rule, true
+}
+
+// validateReasoningForSDK checks the reasoning budget of mp against the
+// limits of the provider's API and the model's budget capabilities, which
+// validateModelPreset cannot see.
+func validateReasoningForSDK(pp spec.ProviderPreset, mp *spec.ModelPreset) error {
+	if mp.Reasoning == nil || mp.Reasoning.Type != inferenceSpec.ReasoningTypeHybridWithTokens {
+		return nil
+	}
+	tokens := mp.Reasoning.Tokens
+	rule, ok := reasoningBudgetRuleFor(pp, mp)
+	if !ok {
+		if tokens > 0 {
+			return nil
+		}
+		rule = reasoningBudgetRule{name: "reasoning budget"}
+	}
+	r := &validation.Report{}
+	switch {
+	case rule.levelsOnly:
+		r.Addf("reasoning.type", validation.CodeUnsupported,
+			"%s is set with levels (%s), not a token budget", rule.name, inferenceSpec.ReasoningTypeSingleWithLevels)
+	case tokens == 0 && rule.zeroAllowed, tokens == -1 && rule.minusOneAllowed:
+	case rule.minTokens > 0 && tokens < rule.minTokens:
+		r.Addf("reasoning.tokens", validation.CodeOutOfRange,
+			"%s %d is below the minimum of %d tokens", rule.name, tokens, rule.minTokens)
+	case tokens <= 0:
+		r.Addf("reasoning.tokens", validation.CodeOutOfRange,
+			"%s %d is not supported by this model; set a positive token budget", rule.name, tokens)
+	case rule.maxTokens > 0 && tokens > rule.maxTokens:
+		r.Addf("reasoning.tokens", validation.CodeOutOfRange,
+			"%s %d is above the maximum of %d tokens", rule.name, tokens, rule.maxTokens)
+	case rule.belowMaxOutput && mp.MaxOutputLength != nil && *mp.MaxOutputLength > 0 &&
+		tokens >= *mp.MaxOutputLength:
+		r.Addf("reasoning.tokens", validation.CodeOutOfRange,
+			"%s %d must be less than maxOutputLength %d", rule.name, tokens, *mp.MaxOutputLength)
+	}
+	return r.Err()
+}
+
+// reasoningBudgetTokens returns percent of maxOutputLength, rounded down.
+func reasoningBudgetTokens(maxOutputLength *int, percent float64) (int, error) {
+	if math.IsNaN(percent) || percent <= 0 || percent >= 100 {
+		return 0, fmt.Errorf("percent %g must be above 0 and below 100", percent)
+	}
+	if maxOutputLength == nil || *maxOutputLength <= 0 {
+		return 0, errors.New("maxOutputLength must be set to size a reasoning budget by percent")
+	}
+	tokens := int(float64(*maxOutputLength) * percent / 100)
+	if tokens <= 0 {
+		return 0, fmt.Errorf("%g%% of maxOutputLength %d is less than one token", percent, *maxOutputLength)
+	}
+	return tokens, nil
+}
+
+// SetReasoningBudgetPercent switches a user model preset to a hybridWithTokens
+// reasoning budget of the given share of its maxOutputLength and checks it
+// against the provider's limits.
+func (s *ModelPresetStore) SetReasoningBudgetPercent(
+	ctx context.Context, req *spec.SetReasoningBudgetPercentRequest,
+) (*spec.SetReasoningBudgetPercentResponse, error) {
+	if req == nil || req.Body == nil || req.ProviderName == "" || req.ModelPresetID == "" {
+		return nil, fmt.Errorf("%w: providerName & modelPresetID required", spec.ErrInvalidDir)
+	}
+	if _, err := s.builtinData.GetBuiltInProvider(ctx, req.ProviderName); err == nil {
+		return nil, fmt.Errorf("%w: providerName: %q", spec.ErrBuiltInReadOnly, req.ProviderName)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAllUserPresets()
+	if err != nil {
+		return nil, err
+	}
+	pp, err := getUserProviderPreset(all, req.ProviderName)
+	if err != nil {
+		return nil, err
+	}
+	mp, ok := pp.ModelPresets[req.ModelPresetID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrModelPresetNotFound, req.ModelPresetID)
+	}
+	tokens, err := reasoningBudgetTokens(mp.MaxOutputLength, req.Body.Percent)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", spec.ErrInvalidDir, err)
+	}
+
+	reasoning := &inferenceSpec.ReasoningParam{Type: inferenceSpec.ReasoningTypeHybridWithTokens, Tokens: tokens}
+	if mp.Reasoning != nil {
+		reasoning.SummaryStyle = cloneReasoningParam(mp.Reasoning).SummaryStyle
+	}
+	changed := mp.Reasoning == nil || mp.Reasoning.Type != reasoning.Type || mp.Reasoning.Tokens != tokens
+	mp.Reasoning = reasoning
+	if err := validateModelPreset(&mp); err != nil {
+		return nil, err
+	}
+	if err := validateReasoningForSDK(pp, &mp); err != nil {
+		return nil, err
+	}
+	if changed {
+		mp.ModifiedAt = time.Now().UTC()
+	}
+	body := &spec.SetReasoningBudgetPercentResponseBody{ModelPreset: mp}
+	if req.DryRun || !changed {
+		return &spec.SetReasoningBudgetPercentResponse{Body: body}, nil
+	}
+
+	pp.ModelPresets[req.ModelPresetID] = mp
+	pp.ModifiedAt = mp.ModifiedAt
+	all.ProviderPresets[req.ProviderName] = pp
+	if err := s.writeAllUserPresets(all); err != nil {
+		return nil, err
+	}
+	s.notify(spec.PresetChangeModelUpdated, req.ProviderName, req.ModelPresetID)
+	logger.Info("setReasoningBudgetPercent",
+		"provider", req.ProviderName, "modelPresetID", req.ModelPresetID,
+		"percent", req.Body.Percent, "tokens", tokens)
+	return &spec.SetReasoningBudgetPercentResponse{Body: body}, nil
+}
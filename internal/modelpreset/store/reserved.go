@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// reservedProviderRenamePrefix is prepended to user providers whose name
+// collides with the reserved namespace.
+const reservedProviderRenamePrefix = "user-"
+
+// MigrateReservedProviderNames renames user providers whose names fall in the
+// reserved namespace, e.g. ones created before a built-in of the same name
+// shipped. It runs once per namespace: the file records the namespace digest
+// and later calls return no renames until the namespace changes. It returns
+// the applied renames, old name to new name. Callers own state keyed by
+// provider name, such as auth keys, and tell the user about the renames.
+func (s *ModelPresetStore) MigrateReservedProviderNames(
+	ctx context.Context,
+) (map[inferenceSpec.ProviderName]inferenceSpec.ProviderName, error) {
+	ns, err := s.reservedProviderNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAllUserPresets()
+	if err != nil {
+		return nil, err
+	}
+	digest := ns.Digest()
+	if all.ReservedNamesDigest == digest {
+		return map[inferenceSpec.ProviderName]inferenceSpec.ProviderName{}, nil
+	}
+
+	renames := map[inferenceSpec.ProviderName]inferenceSpec.ProviderName{}
+	now := time.Now().UTC()
+	for _, name := range slices.Sorted(maps.Keys(all.ProviderPresets)) {
+		if ns.Check(string(name)) == nil {
+			continue
+		}
+		base := reservedProviderRenamePrefix + string(name)
+		newName := inferenceSpec.ProviderName(base)
+		for n := 2; ; n++ {
+			_, taken := all.ProviderPresets[newName]
+			if !taken && ns.Check(string(newName)) == nil {
+				break
+			}
+			newName = inferenceSpec.ProviderName(fmt.Sprintf("%s-%d", base, n))
+		}
+
+		pp := all.ProviderPresets[name]
+		pp.Name = newName
+		pp.ModifiedAt = now
+		delete(all.ProviderPresets, name)
+		all.ProviderPresets[newName] = pp
+		if all.DefaultProvider == name {
+			all.DefaultProvider = newName
+		}
+		renames[name] = newName
+	}
+	all.ReservedNamesDigest = digest
+	if err := s.writeAllUserPresets(all); err != nil {
+		return nil, err
+	}
+	for from, to := range renames {
+		s.notify(spec.PresetChangeProviderDeleted, from)
+		s.notify(spec.PresetChangeProviderCreated, to)
+		logger.Warn("migrateReservedProviderNames", "from", from, "to", to)
+	}
+	return renames, nil
+}
+
+// checkReservedProviderName rejects new user provider names in the reserved
+// namespace.
+func (s *ModelPresetStore) checkReservedProviderName(
+	ctx context.Context, name inferenceSpec.ProviderName,
+) error {
+	ns, err := s.reservedProviderNamespace(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ns.Check(string(name)); err != nil {
+		return fmt.Errorf("%w: %w", spec.ErrProviderNameReserved, err)
+	}
+	return nil
+}
+
+// reservedProviderNamespace is the configured namespace plus all built-in
+// provider names.
+func (s *ModelPresetStore) reservedProviderNamespace(
+	ctx context.Context,
+) (bundleitemutils.ReservedNamespace, error) {
+	if s.builtinData == nil {
+		return s.reserved, nil
+	}
+	builtIns, _, err := s.builtinData.ListBuiltInPresets(ctx)
+	if err != nil {
+		return bundleitemutils.ReservedNamespace{}, err
+	}
+	names := make([]string, 0, len(builtIns))
+	for name := range builtIns {
+		names = append(names, string(name))
+	}
+	return s.reserved.WithNames(names...), nil
+}
@@ -0,0 +1,167 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// providerSDKDefaults are filled into a new provider preset whose fields are
+// left empty.
+type providerSDKDefaults struct {
+	origin                   string
+	chatCompletionPathPrefix string
+	apiKeyHeaderKey          string
+	defaultHeaders           map[string]string
+}
+
+var providerSDKDefaultsByType = map[inferenceSpec.ProviderSDKType]providerSDKDefaults{
+	inferenceSpec.ProviderSDKTypeAnthropic: {
+		origin:                   spec.DefaultAnthropicOrigin,
+		chatCompletionPathPrefix: spec.DefaultAnthropicChatCompletionPrefix,
+		apiKeyHeaderKey:          spec.DefaultAnthropicAuthorizationHeaderKey,
+		defaultHeaders:           spec.AnthropicDefaultHeaders,
+	},
+	inferenceSpec.ProviderSDKTypeGoogleGenerateContent: {
+		origin:                   spec.DefaultGoogleGenerateContentOrigin,
+		chatCompletionPathPrefix: spec.DefaultGoogleGenerateContentPrefix,
+		apiKeyHeaderKey:          spec.DefaultGoogleGenerateContentAPIKeyHeaderKey,
+		defaultHeaders:           spec.GoogleGenerateContentDefaultHeaders,
+	},
+	spec.ProviderSDKTypeOllama: {
+		origin:                   spec.DefaultOllamaOrigin,
+		chatCompletionPathPrefix: spec.DefaultOpenAIChatCompletionsPrefix,
+		apiKeyHeaderKey:          spec.DefaultAuthorizationHeaderKey,
+		defaultHeaders:           spec.OpenAIChatCompletionsDefaultHeaders,
+	},
+	spec.ProviderSDKTypeLlamaCPP: {
+		origin:                   spec.DefaultLlamaCPPOrigin,
+		chatCompletionPathPrefix: spec.DefaultOpenAIChatCompletionsPrefix,
+		apiKeyHeaderKey:          spec.DefaultAuthorizationHeaderKey,
+		defaultHeaders:           spec.OpenAIChatCompletionsDefaultHeaders,
+	},
+	// Origin and path are derived from the Azure config.
+	spec.ProviderSDKTypeAzureOpenAI: {
+		apiKeyHeaderKey: spec.DefaultAzureOpenAIAPIKeyHeaderKey,
+		defaultHeaders:  spec.OpenAIChatCompletionsDefaultHeaders,
+	},
+}
+
+// ApplyProviderSDKDefaults fills the empty connection fields of a new provider
+// preset with the defaults of its SDK type, so a local server can be added by
+// SDK type alone.
+func ApplyProviderSDKDefaults(body *spec.PostProviderPresetRequestBody) {
+	if body == nil {
+		return
+	}
+	if body.SDKType == spec.ProviderSDKTypeAzureOpenAI && body.Azure != nil {
+		if strings.TrimSpace(body.Origin) == "" {
+			body.Origin = body.Azure.Origin()
+		}
+		body.ChatCompletionPathPrefix = body.Azure.ChatCompletionPathPrefix()
+	}
+	d, ok := providerSDKDefaultsByType[body.SDKType]
+	if !ok {
+		return
+	}
+	if strings.TrimSpace(body.Origin) == "" {
+		body.Origin = d.origin
+	}
+	if strings.TrimSpace(body.ChatCompletionPathPrefix) == "" {
+		body.ChatCompletionPathPrefix = d.chatCompletionPathPrefix
+	}
+	if strings.TrimSpace(body.APIKeyHeaderKey) == "" {
+		body.APIKeyHeaderKey = d.apiKeyHeaderKey
+	}
+	if body.DefaultHeaders == nil {
+		body.DefaultHeaders = maps.Clone(d.defaultHeaders)
+	}
+}
+
+var (
+	googleAPIVersionSegment = regexp.MustCompile(`^v\d+(alpha|beta)?\d*$`)
+
+	azureResourceNameRe   = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9-]{0,62}[a-zA-Z0-9]$`)
+	azureDeploymentNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,63}$`)
+	azureAPIVersionRe     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(-preview)?$`)
+)
+
+func isKnownSDKType(t inferenceSpec.ProviderSDKType) bool {
+	switch t {
+	case inferenceSpec.ProviderSDKTypeAnthropic,
+		inferenceSpec.ProviderSDKTypeOpenAIChatCompletions,
+		inferenceSpec.ProviderSDKTypeOpenAIResponses,
+		inferenceSpec.ProviderSDKTypeGoogleGenerateContent,
+		spec.ProviderSDKTypeOllama,
+		spec.ProviderSDKTypeLlamaCPP,
+		spec.ProviderSDKTypeAzureOpenAI:
+		return true
+	}
+	return false
+}
+
+// validateProviderSDKConfig applies the rules specific to the provider's SDK
+// type. Unknown SDK types are left to inference-go.
+func validateProviderSDKConfig(pp *spec.ProviderPreset) error {
+	if pp.Azure != nil && pp.SDKType != spec.ProviderSDKTypeAzureOpenAI {
+		return fmt.Errorf("azure is only valid for sdkType %q", spec.ProviderSDKTypeAzureOpenAI)
+	}
+	chatPath := strings.TrimRight(strings.TrimSpace(pp.ChatCompletionPathPrefix), "/")
+	switch {
+	case pp.SDKType == spec.ProviderSDKTypeAzureOpenAI:
+		if err := validateAzureOpenAIConfig(pp.Azure); err != nil {
+			return fmt.Errorf("azure: %w", err)
+		}
+		if want := pp.Azure.ChatCompletionPathPrefix(); chatPath != want {
+			return fmt.Errorf("chatCompletionPathPrefix %q does not match azure deployment path %q",
+				pp.ChatCompletionPathPrefix, want)
+		}
+	case pp.SDKType == inferenceSpec.ProviderSDKTypeAnthropic:
+		// The Anthropic SDK appends "v1/messages" to whatever precedes it.
+		if !strings.HasSuffix(chatPath, "/v1/messages") {
+			return fmt.Errorf("chatCompletionPathPrefix %q must end in /v1/messages",
+				pp.ChatCompletionPathPrefix)
+		}
+	case pp.SDKType == inferenceSpec.ProviderSDKTypeGoogleGenerateContent:
+		// The GenAI SDK treats the path as a base path and appends the API
+		// version and "models/{model}:generateContent" itself.
+		for seg := range strings.SplitSeq(strings.Trim(chatPath, "/"), "/") {
+			if seg == "models" || strings.Contains(seg, ":") || googleAPIVersionSegment.MatchString(seg) {
+				return fmt.Errorf("chatCompletionPathPrefix %q must be a base path without the API version "+
+					"or model segments", pp.ChatCompletionPathPrefix)
+			}
+		}
+		if strings.EqualFold(strings.TrimSpace(pp.APIKeyHeaderKey), spec.DefaultAuthorizationHeaderKey) {
+			return fmt.Errorf("apiKeyHeaderKey %q is not supported; Gemini API keys are sent as %s",
+				pp.APIKeyHeaderKey, spec.DefaultGoogleGenerateContentAPIKeyHeaderKey)
+		}
+	case spec.IsLocalSDKType(pp.SDKType):
+		if !strings.HasSuffix(chatPath, "/chat/completions") && !strings.HasSuffix(chatPath, "/messages") {
+			return fmt.Errorf("chatCompletionPathPrefix %q must end in /chat/completions or /messages",
+				pp.ChatCompletionPathPrefix)
+		}
+	}
+	return nil
+}
+
+func validateAzureOpenAIConfig(c *spec.AzureOpenAIConfig) error {
+	if c == nil {
+		return errors.New("resourceName, deploymentName and apiVersion are required")
+	}
+	var errs []error
+	if !azureResourceNameRe.MatchString(c.ResourceName) {
+		errs = append(errs, fmt.Errorf("invalid resourceName %q", c.ResourceName))
+	}
+	if !azureDeploymentNameRe.MatchString(c.DeploymentName) {
+		errs = append(errs, fmt.Errorf("invalid deploymentName %q", c.DeploymentName))
+	}
+	if !azureAPIVersionRe.MatchString(c.APIVersion) {
+		errs = append(errs, fmt.Errorf("invalid apiVersion %q, want YYYY-MM-DD or YYYY-MM-DD-preview", c.APIVersion))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,231 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// ProviderPresetPurgeHandler is invoked after a soft-deleted provider preset
+// has been hard-deleted, e.g. to drop its auth key.
+type ProviderPresetPurgeHandler func(ctx context.Context, providerName inferenceSpec.ProviderName) error
+
+// SetProviderPresetPurgeHandler registers the callback run for every provider
+// removed by the soft-delete sweeper.
+func (s *ModelPresetStore) SetProviderPresetPurgeHandler(handler ProviderPresetPurgeHandler) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purgeHandler = handler
+}
+
+// UndeleteProviderPreset restores a soft-deleted provider preset that is still
+// within its grace period.
+func (s *ModelPresetStore) UndeleteProviderPreset(
+	ctx context.Context, req *spec.UndeleteProviderPresetRequest,
+) (*spec.UndeleteProviderPresetResponse, error) {
+	if req == nil || req.ProviderName == "" {
+		return nil, fmt.Errorf("%w: providerName required", spec.ErrInvalidDir)
+	}
+	if _, err := s.builtinData.GetBuiltInProvider(ctx, req.ProviderName); err == nil {
+		return nil, fmt.Errorf("%w: providerName: %q",
+			spec.ErrBuiltInReadOnly, req.ProviderName)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAllUserPresets()
+	if err != nil {
+		return nil, err
+	}
+	pp, ok := all.ProviderPresets[req.ProviderName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrProviderNotFound, req.ProviderName)
+	}
+	if !isSoftDeletedProviderPreset(pp) {
+		return nil, fmt.Errorf("provider %q is not deleted", req.ProviderName)
+	}
+
+	pp.SoftDeletedAt = nil
+	pp.ModifiedAt = time.Now().UTC()
+	all.ProviderPresets[req.ProviderName] = pp
+
+	if err := s.writeAllUserPresets(all); err != nil {
+		return nil, err
+	}
+	// The provider reappears in listings.
+	s.notify(spec.PresetChangeProviderCreated, req.ProviderName)
+	logger.Info("undeleteProviderPreset", "provider", req.ProviderName)
+	return &spec.UndeleteProviderPresetResponse{}, nil
+}
+
+// ListSoftDeleted returns the user provider presets pending purge, newest
+// deletion first.
+func (s *ModelPresetStore) ListSoftDeleted(
+	ctx context.Context, _ *spec.ListSoftDeletedRequest,
+) (*spec.ListSoftDeletedResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	all, err := s.readAllUserPresets()
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	deleted := []spec.SoftDeletedProviderPreset{}
+	for _, pp := range all.ProviderPresets {
+		if !isSoftDeletedProviderPreset(pp) {
+			continue
+		}
+		purgeAt := pp.SoftDeletedAt.Add(softDeleteGraceProviderPresets)
+		deleted = append(deleted, spec.SoftDeletedProviderPreset{
+			ProviderPreset:   pp,
+			PurgeAt:          purgeAt,
+			RemainingGraceMS: max(purgeAt.Sub(now), 0).Milliseconds(),
+		})
+	}
+	slices.SortFunc(deleted, func(a, b spec.SoftDeletedProviderPreset) int {
+		return cmp.Or(
+			b.ProviderPreset.SoftDeletedAt.Compare(*a.ProviderPreset.SoftDeletedAt),
+			cmp.Compare(a.ProviderPreset.Name, b.ProviderPreset.Name),
+		)
+	})
+	return &spec.ListSoftDeletedResponse{
+		Body: &spec.ListSoftDeletedResponseBody{ProviderPresets: deleted},
+	}, nil
+}
+
+func (s *ModelPresetStore) startCleanupLoop() {
+	s.cleanOnce.Do(func() {
+		s.cleanKick = make(chan struct{}, 1)
+		s.cleanCtx, s.cleanStop = context.WithCancel(context.Background())
+
+		s.wg.Go(func() {
+			tick := time.NewTicker(cleanupIntervalProviderPresets)
+			defer tick.Stop()
+
+			// Run once at start.
+			s.compactBuiltInOverlay(s.cleanCtx)
+			s.sweepSoftDeleted(s.cleanCtx)
+
+			for {
+				select {
+				case <-s.cleanCtx.Done():
+					return
+				case <-tick.C:
+				case <-s.cleanKick:
+				}
+				s.sweepSoftDeleted(s.cleanCtx)
+			}
+		})
+	})
+}
+
+func (s *ModelPresetStore) kickCleanupLoop() {
+	if s.cleanKick == nil {
+		return
+	}
+	select {
+	case s.cleanKick <- struct{}{}:
+	default:
+	}
+}
+
+// compactBuiltInOverlay drops built-in overrides that no longer change
+// anything, such as flags left behind by a reset or a removed built-in.
+func (s *ModelPresetStore) compactBuiltInOverlay(ctx context.Context) {
+	if s.builtinData == nil {
+		return
+	}
+	n, err := s.builtinData.CompactOverlay(ctx)
+	if err != nil {
+		logger.Error("compactBuiltInOverlay", "err", err)
+		return
+	}
+	if n > 0 {
+		logger.Info("compacted built-in preset overlay", "deleted", n)
+	}
+}
+
+// sweepSoftDeleted hard-deletes provider presets whose grace period expired.
+func (s *ModelPresetStore) sweepSoftDeleted(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("sweepSoftDeleted: panic", "panic", r)
+		}
+	}()
+
+	s.mu.Lock()
+	all, err := s.readAllUserPresets()
+	if err != nil {
+		s.mu.Unlock()
+		logger.Error("sweepSoftDeleted/readAllUserPresets", "err", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	var purged []inferenceSpec.ProviderName
+	for name, pp := range all.ProviderPresets {
+		if !isSoftDeletedProviderPreset(pp) {
+			continue
+		}
+		if now.Sub(*pp.SoftDeletedAt) < softDeleteGraceProviderPresets {
+			continue
+		}
+		delete(all.ProviderPresets, name)
+		purged = append(purged, name)
+	}
+
+	if len(purged) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	err = s.writeAllUserPresets(all)
+	handler := s.purgeHandler
+	s.mu.Unlock()
+	if err != nil {
+		logger.Error("sweepSoftDeleted/writeAllUserPresets", "err", err)
+		return
+	}
+
+	for _, name := range purged {
+		logger.Info("hard-deleted provider preset", "provider", name)
+		if handler == nil {
+			continue
+		}
+		if err := handler(ctx, name); err != nil {
+			logger.Error("sweepSoftDeleted/purgeHandler", "provider", name, "err", err)
+		}
+	}
+}
+
+// getUserProviderPreset returns a live user provider. Soft-deleted providers
+// are reported as not found and pending deletion.
+func getUserProviderPreset(
+	all spec.PresetsSchema, name inferenceSpec.ProviderName,
+) (spec.ProviderPreset, error) {
+	pp, ok := all.ProviderPresets[name]
+	if !ok {
+		return spec.ProviderPreset{}, fmt.Errorf("%w: %s", spec.ErrProviderNotFound, name)
+	}
+	if isSoftDeletedProviderPreset(pp) {
+		return spec.ProviderPreset{}, fmt.Errorf("%w: %w: %s",
+			spec.ErrProviderNotFound, spec.ErrProviderPresetDeleting, name)
+	}
+	return pp, nil
+}
+
+func isSoftDeletedProviderPreset(pp spec.ProviderPreset) bool {
+	return pp.SoftDeletedAt != nil && !pp.SoftDeletedAt.IsZero()
+}
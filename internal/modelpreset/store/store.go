@@ -5,24 +5,39 @@
 package store
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
-	"log/slog"
 	"maps"
 	"path/filepath"
 	"slices"
-	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/fsutil"
+	"github.com/flexigpt/flexigpt-app/internal/idempotency"
 	"github.com/flexigpt/flexigpt-app/internal/jsonutil"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	"github.com/flexigpt/flexigpt-app/internal/precondition"
 	"github.com/flexigpt/inference-go/capabilityoverride"
 	inferenceSpec "github.com/flexigpt/inference-go/spec"
 	"github.com/flexigpt/mapstore-go"
 	"github.com/flexigpt/mapstore-go/jsonencdec"
 )
 
+// logger tags records of this package for per-module log levels.
+var logger = logging.Module("modelpreset.store")
+
+const (
+	softDeleteGraceProviderPresets = 48 * time.Hour
+	cleanupIntervalProviderPresets = 24 * time.Hour
+)
+
 // ModelPresetStore is the main storage façade for provider / model-preset data.
 type ModelPresetStore struct {
 	baseDir string
@@ -34,12 +49,104 @@ type ModelPresetStore struct {
 	builtinData *BuiltInPresets
 
 	mu sync.RWMutex // Guards userStore modifications.
+	// Turns the user store read-only when the disk is full or the file is
+	// locked.
+	writeGuard fsutil.WriteGuard
+	// Copies of the presets file taken before each write; nil disables them.
+	backups *fsutil.FileBackups
+	// Results of keyed mutations, replayed to retries with the same key.
+	replay *idempotency.Cache
+	// State of the user presets file as last read or written; a write is
+	// refused if the file changed since. Readers hold mu only for reading.
+	fileStateMu   sync.Mutex
+	userFileState fsutil.FileState
+	// State of the file after the last write of this process, so the file
+	// watcher can tell it from an external change. Guarded by fileStateMu.
+	writtenFileState fsutil.FileState
+
+	// Names user providers may not take, in addition to built-in names.
+	reserved bundleitemutils.ReservedNamespace
+
+	// Soft-deleted provider presets are hard-deleted by a background sweeper.
+	purgeHandler ProviderPresetPurgeHandler // Guarded by mu.
+	cleanOnce    sync.Once
+	cleanKick    chan struct{}
+	cleanCtx     context.Context
+	cleanStop    context.CancelFunc
+	wg           sync.WaitGroup
+
+	// Change events for subscribers; dispatch stops with the cleanup loop.
+	notifier presetNotifier
+
+	// Providers as last listed, reused by later pages until a change.
+	providerSnapshot atomic.Pointer[providerListSnapshot]
+
+	// Transport of the store's own provider requests; see SetProviderTransport.
+	providerTransport atomic.Pointer[ProviderTransportFunc]
+}
+
+type modelPresetStoreOptions struct {
+	reserved     *bundleitemutils.ReservedNamespace
+	fileWatch    bool
+	backupPolicy *fsutil.BackupPolicy
+}
+
+type ModelPresetStoreOption func(*modelPresetStoreOptions) error
+
+// WithReservedProviderNamespace replaces the default reserved prefixes for
+// user provider names. Built-in provider names are always reserved.
+func WithReservedProviderNamespace(ns bundleitemutils.ReservedNamespace) ModelPresetStoreOption {
+	return func(options *modelPresetStoreOptions) error {
+		options.reserved = &ns
+		return nil
+	}
+}
+
+// WithFileWatch makes the store notify subscribers with a reloaded event when
+// the presets file is changed outside the app.
+func WithFileWatch(enabled bool) ModelPresetStoreOption {
+	return func(options *modelPresetStoreOptions) error {
+		options.fileWatch = enabled
+		return nil
+	}
+}
+
+// WithBackups copies the presets file into the backups directory of the
+// store before each write, keeping copies within policy.
+func WithBackups(policy fsutil.BackupPolicy) ModelPresetStoreOption {
+	return func(options *modelPresetStoreOptions) error {
+		if policy.MaxCopies < 0 || policy.MaxAge < 0 {
+			return errors.New("backup policy must not be negative")
+		}
+		options.backupPolicy = &policy
+		return nil
+	}
 }
 
 // NewModelPresetStore initialises the storage in baseDir.
 // Built-in data are automatically loaded and overlaid.
-func NewModelPresetStore(baseDir string) (*ModelPresetStore, error) {
-	s := &ModelPresetStore{baseDir: filepath.Clean(baseDir)}
+func NewModelPresetStore(baseDir string, opts ...ModelPresetStoreOption) (*ModelPresetStore, error) {
+	options := modelPresetStoreOptions{}
+	for _, option := range opts {
+		if option == nil {
+			continue
+		}
+		if err := option(&options); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &ModelPresetStore{
+		baseDir: filepath.Clean(baseDir),
+		replay:  idempotency.NewCache(idempotency.DefaultTTL, idempotency.DefaultMaxEntries),
+	}
+	s.reserved = bundleitemutils.DefaultReservedNamespace()
+	if options.reserved != nil {
+		s.reserved = *options.reserved
+	}
+	if options.backupPolicy != nil {
+		s.backups = fsutil.NewFileBackups(filepath.Join(s.baseDir, "backups"), *options.backupPolicy)
+	}
 	ctx := context.Background()
 	bi, err := NewBuiltInPresets(ctx, baseDir, spec.BuiltInSnapshotMaxAge)
 	if err != nil {
@@ -68,13 +175,22 @@ func NewModelPresetStore(baseDir string) (*ModelPresetStore, error) {
 		jsonencdec.JSONEncoderDecoder{},
 		mapstore.WithCreateIfNotExists(true),
 		mapstore.WithFileAutoFlush(true),
-		mapstore.WithFileLogger(slog.Default()),
+		mapstore.WithFileLogger(logger),
 	)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.Migrate(ctx); err != nil {
+		_ = s.Close()
+		return nil, fmt.Errorf("model presets migration failed: %w", err)
+	}
+	s.startCleanupLoop()
+	s.startNotifier()
+	if options.fileWatch {
+		s.startFileWatch()
+	}
 
-	slog.Info("model-preset store ready", "baseDir", s.baseDir)
+	logger.Info("model-preset store ready", "baseDir", s.baseDir)
 	return s, nil
 }
 
@@ -82,15 +198,19 @@ func (s *ModelPresetStore) Close() error {
 	if s == nil {
 		return nil
 	}
+	if s.cleanStop != nil {
+		s.cleanStop()
+	}
+	s.wg.Wait()
 	if s.builtinData != nil {
 		if err := s.builtinData.Close(); err != nil {
-			slog.Error("builtinData close failed", "err", err)
+			logger.Error("builtinData close failed", "err", err)
 		}
 		s.builtinData = nil
 	}
 	if s.userStore != nil {
 		if err := s.userStore.Close(); err != nil {
-			slog.Error("userStore close failed", "err", err)
+			logger.Error("userStore close failed", "err", err)
 		}
 		s.userStore = nil
 	}
@@ -102,7 +222,7 @@ func (s *ModelPresetStore) GetDefaultProvider(
 ) (*spec.GetDefaultProviderResponse, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	all, err := s.readAllUserPresets(false)
+	all, err := s.readAllUserPresets()
 	if err != nil {
 		return nil, err
 	}
@@ -122,6 +242,16 @@ func (s *ModelPresetStore) GetDefaultProvider(
 
 func (s *ModelPresetStore) PatchDefaultProvider(
 	ctx context.Context, req *spec.PatchDefaultProviderRequest,
+) (*spec.PatchDefaultProviderResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.patchDefaultProvider(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PatchDefaultProvider", req.IdempotencyKey, req,
+		func() (*spec.PatchDefaultProviderResponse, error) { return s.patchDefaultProvider(ctx, req) })
+}
+
+func (s *ModelPresetStore) patchDefaultProvider(
+	ctx context.Context, req *spec.PatchDefaultProviderRequest,
 ) (*spec.PatchDefaultProviderResponse, error) {
 	if req == nil || req.Body == nil || req.Body.DefaultProvider == "" {
 		return nil, fmt.Errorf("%w: providerName required", spec.ErrProviderNotFound)
@@ -137,12 +267,12 @@ func (s *ModelPresetStore) PatchDefaultProvider(
 	}
 	if !found {
 		s.mu.RLock()
-		all, err := s.readAllUserPresets(false)
+		all, err := s.readAllUserPresets()
 		s.mu.RUnlock()
 		if err != nil {
 			return nil, err
 		}
-		if _, ok := all.ProviderPresets[providerName]; ok {
+		if pp, ok := all.ProviderPresets[providerName]; ok && !isSoftDeletedProviderPreset(pp) {
 			found = true
 		}
 	}
@@ -155,10 +285,16 @@ func (s *ModelPresetStore) PatchDefaultProvider(
 		)
 	}
 
+	if req.DryRun {
+		return &spec.PatchDefaultProviderResponse{
+			Body: &spec.PatchDefaultProviderResponseBody{DefaultProvider: providerName},
+		}, nil
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	all, err := s.readAllUserPresets(false)
+	all, err := s.readAllUserPresets()
 	if err != nil {
 		return nil, err
 	}
@@ -167,13 +303,24 @@ func (s *ModelPresetStore) PatchDefaultProvider(
 		return nil, err
 	}
 
-	slog.Info("patchDefaultProvider", "defaultProvider", providerName)
+	s.notify(spec.PresetChangeDefaultProviderChanged, providerName)
+	logger.Info("patchDefaultProvider", "defaultProvider", providerName)
 	return &spec.PatchDefaultProviderResponse{}, nil
 }
 
 // PostProviderPreset creates a new provider preset.
 func (s *ModelPresetStore) PostProviderPreset(
 	ctx context.Context, req *spec.PostProviderPresetRequest,
+) (*spec.PostProviderPresetResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.postProviderPreset(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PostProviderPreset", req.IdempotencyKey, req,
+		func() (*spec.PostProviderPresetResponse, error) { return s.postProviderPreset(ctx, req) })
+}
+
+func (s *ModelPresetStore) postProviderPreset(
+	ctx context.Context, req *spec.PostProviderPresetRequest,
 ) (*spec.PostProviderPresetResponse, error) {
 	if req == nil || req.Body == nil || req.ProviderName == "" {
 		return nil, fmt.Errorf("%w: providerName & body required", spec.ErrInvalidDir)
@@ -185,6 +332,11 @@ func (s *ModelPresetStore) PostProviderPreset(
 			spec.ErrBuiltInReadOnly, req.ProviderName)
 	}
 
+	if err := s.checkReservedProviderName(ctx, req.ProviderName); err != nil {
+		return nil, err
+	}
+
+	ApplyProviderSDKDefaults(req.Body)
 	now := time.Now().UTC()
 
 	// Build object.
@@ -203,6 +355,15 @@ func (s *ModelPresetStore) PostProviderPreset(
 		DefaultHeaders:           maps.Clone(req.Body.DefaultHeaders),
 		ModelPresets:             map[spec.ModelPresetID]spec.ModelPreset{},
 		CapabilitiesOverride:     capabilityoverride.CloneModelCapabilitiesOverride(req.Body.CapabilitiesOverride),
+		RateLimits:               cloneProviderRateLimits(req.Body.RateLimits),
+		Resilience:               cloneProviderResilience(req.Body.Resilience),
+		Azure:                    cloneAzureOpenAIConfig(req.Body.Azure),
+	}
+	if req.Body.TLS != nil && !req.Body.TLS.IsZero() {
+		pp.TLS = cloneProviderTLSConfig(req.Body.TLS)
+	}
+	if req.Body.ImageProcessing != nil && !req.Body.ImageProcessing.IsZero() {
+		pp.ImageProcessing = cloneProviderImageProcessing(req.Body.ImageProcessing)
 	}
 
 	// Validate.
@@ -213,25 +374,45 @@ func (s *ModelPresetStore) PostProviderPreset(
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	all, err := s.readAllUserPresets(false)
+	all, err := s.readAllUserPresets()
 	if err != nil {
 		return nil, err
 	}
-	if _, ok := all.ProviderPresets[req.ProviderName]; ok {
+	if existing, ok := all.ProviderPresets[req.ProviderName]; ok {
+		if isSoftDeletedProviderPreset(existing) {
+			return nil, fmt.Errorf("%w: %s", spec.ErrProviderPresetDeleting, req.ProviderName)
+		}
 		return nil, fmt.Errorf("%w: %s", spec.ErrProviderPresetAlreadyExists, req.ProviderName)
 	}
+	if req.DryRun {
+		return &spec.PostProviderPresetResponse{Body: &spec.PostProviderPresetResponseBody{ProviderPreset: pp}}, nil
+	}
 
 	all.ProviderPresets[req.ProviderName] = pp
 	if err := s.writeAllUserPresets(all); err != nil {
 		return nil, err
 	}
-	slog.Info("postProviderPreset", "provider", req.ProviderName)
+	s.notify(spec.PresetChangeProviderCreated, req.ProviderName)
+	logger.Info("postProviderPreset", "provider", req.ProviderName)
 	return &spec.PostProviderPresetResponse{}, nil
 }
 
-// DeleteProviderPreset removes a provider if it has no model presets.
+// DeleteProviderPreset soft-deletes a provider if it has no model presets and
+// no embedding preset uses it.
+// The provider stays recoverable via UndeleteProviderPreset until the grace
+// period ends and the sweeper removes it.
 func (s *ModelPresetStore) DeleteProviderPreset(
 	ctx context.Context, req *spec.DeleteProviderPresetRequest,
+) (*spec.DeleteProviderPresetResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.deleteProviderPreset(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "DeleteProviderPreset", req.IdempotencyKey, req,
+		func() (*spec.DeleteProviderPresetResponse, error) { return s.deleteProviderPreset(ctx, req) })
+}
+
+func (s *ModelPresetStore) deleteProviderPreset(
+	ctx context.Context, req *spec.DeleteProviderPresetRequest,
 ) (*spec.DeleteProviderPresetResponse, error) {
 	if req == nil || req.ProviderName == "" {
 		return nil, fmt.Errorf("%w: providerName required", spec.ErrInvalidDir)
@@ -245,28 +426,42 @@ func (s *ModelPresetStore) DeleteProviderPreset(
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	all, err := s.readAllUserPresets(true)
+	all, err := s.readAllUserPresets()
 	if err != nil {
 		return nil, err
 	}
-	pp, ok := all.ProviderPresets[req.ProviderName]
-	if !ok {
-		return nil, fmt.Errorf("%w: %s", spec.ErrProviderNotFound, req.ProviderName)
+	pp, err := getUserProviderPreset(all, req.ProviderName)
+	if err != nil {
+		return nil, err
 	}
 	if len(pp.ModelPresets) != 0 {
 		return nil, fmt.Errorf("provider %q is not empty", req.ProviderName)
 	}
+	// Embedding presets would be left pointing at a missing provider.
+	for _, id := range slices.Sorted(maps.Keys(all.EmbeddingPresets)) {
+		if all.EmbeddingPresets[id].ProviderName == req.ProviderName {
+			return nil, fmt.Errorf("provider %q is used by embedding preset %q", req.ProviderName, id)
+		}
+	}
 	// If the deleted provider was the selected user default, we dont allow to delete.
 	if all.DefaultProvider == req.ProviderName {
 		return nil, fmt.Errorf("provider %q is the default provider", req.ProviderName)
 	}
 
-	delete(all.ProviderPresets, req.ProviderName)
+	now := time.Now().UTC()
+	pp.SoftDeletedAt = &now
+	pp.ModifiedAt = now
+	if req.DryRun {
+		return &spec.DeleteProviderPresetResponse{Body: &spec.DeleteProviderPresetResponseBody{ProviderPreset: pp}}, nil
+	}
+	all.ProviderPresets[req.ProviderName] = pp
 
 	if err := s.writeAllUserPresets(all); err != nil {
 		return nil, err
 	}
-	slog.Info("deleteProviderPreset", "provider", req.ProviderName)
+	s.kickCleanupLoop()
+	s.notify(spec.PresetChangeProviderDeleted, req.ProviderName)
+	logger.Info("deleteProviderPreset", "provider", req.ProviderName)
 	return &spec.DeleteProviderPresetResponse{}, nil
 }
 
@@ -278,6 +473,9 @@ func (s *ModelPresetStore) ListProviderPresets(
 	pageSize := spec.DefaultPageSize
 	includeDisabled := false
 	want := map[inferenceSpec.ProviderName]struct{}{}
+	wantTags := map[string]struct{}{}
+	wantSDK := map[inferenceSpec.ProviderSDKType]struct{}{}
+	sortBy := spec.ProviderPresetSortByModifiedAt
 	cursor := inferenceSpec.ProviderName("")
 
 	// Token overrides everything.
@@ -292,6 +490,15 @@ func (s *ModelPresetStore) ListProviderPresets(
 			for _, n := range tok.Names {
 				want[n] = struct{}{}
 			}
+			for _, tag := range tok.Tags {
+				wantTags[tag] = struct{}{}
+			}
+			for _, t := range tok.SDKTypes {
+				wantSDK[t] = struct{}{}
+			}
+			if tok.SortBy != "" {
+				sortBy = tok.SortBy
+			}
 		}
 	} else if req != nil {
 		if req.PageSize > 0 && req.PageSize <= spec.DefaultPageSize {
@@ -301,8 +508,82 @@ func (s *ModelPresetStore) ListProviderPresets(
 		for _, n := range req.Names {
 			want[n] = struct{}{}
 		}
+		for _, tag := range req.Tags {
+			wantTags[tag] = struct{}{}
+		}
+		for _, t := range req.SDKTypes {
+			wantSDK[t] = struct{}{}
+		}
+		if req.SortBy != "" {
+			sortBy = req.SortBy
+		}
 	}
+	switch sortBy {
+	case spec.ProviderPresetSortByModifiedAt, spec.ProviderPresetSortByCreatedAt,
+		spec.ProviderPresetSortByDisplayName:
+	default:
+		return nil, fmt.Errorf("%w: unknown sortBy %q", spec.ErrInvalidDir, sortBy)
+	}
+
+	snap, err := s.providerListSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page, more := snap.page(sortBy, providerPresetFilter{
+		names:           want,
+		tags:            wantTags,
+		sdkTypes:        wantSDK,
+		includeDisabled: includeDisabled,
+	}, cursor, pageSize)
+
+	var nextToken *string
+	if more {
+		// Preserve filter parameters in token.
+		names := make([]inferenceSpec.ProviderName, 0, len(want))
+		for n := range want {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		tags := slices.Sorted(maps.Keys(wantTags))
+		sdkTypes := slices.Sorted(maps.Keys(wantSDK))
 
+		tok := spec.ProviderPageToken{
+			Names:           names,
+			Tags:            tags,
+			SDKTypes:        sdkTypes,
+			SortBy:          sortBy,
+			IncludeDisabled: includeDisabled,
+			PageSize:        pageSize,
+			CursorSlug:      page[len(page)-1].Name,
+		}
+		ns := jsonutil.Base64JSONEncode(tok)
+		nextToken = &ns
+	}
+
+	return &spec.ListProviderPresetsResponse{
+		Body: &spec.ListProviderPresetsResponseBody{
+			Providers:     page,
+			NextPageToken: nextToken,
+		},
+	}, nil
+}
+
+// providerPresetFilter holds the ListProviderPresets filters. Empty sets
+// match everything.
+type providerPresetFilter struct {
+	names           map[inferenceSpec.ProviderName]struct{}
+	tags            map[string]struct{}
+	sdkTypes        map[inferenceSpec.ProviderSDKType]struct{}
+	includeDisabled bool
+}
+
+// filteredProviderPresets returns clones of the built-in and live user
+// providers matching f, in no particular order. With a tag filter, model
+// presets without a matching tag are removed from the clones.
+func (s *ModelPresetStore) filteredProviderPresets(
+	ctx context.Context,
+	f providerPresetFilter,
+) ([]spec.ProviderPreset, error) {
 	// Collect built-ins.
 	all := make([]spec.ProviderPreset, 0)
 	if s.builtinData != nil {
@@ -314,79 +595,87 @@ func (s *ModelPresetStore) ListProviderPresets(
 	}
 	// Collect user.
 	s.mu.RLock()
-	user, err := s.readAllUserPresets(false)
+	user, err := s.readAllUserPresets()
 	s.mu.RUnlock()
 	if err != nil {
 		return nil, err
 	}
 	for _, p := range user.ProviderPresets {
+		if isSoftDeletedProviderPreset(p) {
+			continue
+		}
 		all = append(all, cloneProviderPreset(p))
 	}
 
 	// Filtering.
 	filtered := make([]spec.ProviderPreset, 0, len(all))
 	for _, p := range all {
-		if len(want) != 0 {
-			if _, ok := want[p.Name]; !ok {
-				continue
-			}
+		if f.matches(p) && f.filterModelTags(&p) {
+			filtered = append(filtered, p)
 		}
-		if !includeDisabled && !p.IsEnabled {
-			continue
-		}
-		filtered = append(filtered, p)
 	}
+	return filtered, nil
+}
 
-	// Ordering.
-	sort.Slice(filtered, func(i, j int) bool {
-		if filtered[i].ModifiedAt.Equal(filtered[j].ModifiedAt) {
-			return filtered[i].Name < filtered[j].Name
-		}
-		return filtered[i].ModifiedAt.After(filtered[j].ModifiedAt)
-	})
-
-	// Cursor.
-	start := 0
-	if cursor != "" {
-		for i, p := range filtered {
-			if p.Name == cursor {
-				start = i + 1
-				break
-			}
+// matches reports whether p passes the name, enabled and SDK type filters.
+func (f providerPresetFilter) matches(p spec.ProviderPreset) bool {
+	if len(f.names) != 0 {
+		if _, ok := f.names[p.Name]; !ok {
+			return false
 		}
 	}
-
-	end := min(start+pageSize, len(filtered))
-	var nextToken *string
-	if end < len(filtered) {
-		// Preserve filter parameters in token.
-		names := make([]inferenceSpec.ProviderName, 0, len(want))
-		for n := range want {
-			names = append(names, n)
+	if !f.includeDisabled && !p.IsEnabled {
+		return false
+	}
+	if len(f.sdkTypes) != 0 {
+		if _, ok := f.sdkTypes[p.SDKType]; !ok {
+			return false
 		}
-		slices.Sort(names)
+	}
+	return true
+}
 
-		tok := spec.ProviderPageToken{
-			Names:           names,
-			IncludeDisabled: includeDisabled,
-			PageSize:        pageSize,
-			CursorSlug:      filtered[end-1].Name,
-		}
-		ns := jsonutil.Base64JSONEncode(tok)
-		nextToken = &ns
+// filterModelTags removes the model presets without a wanted tag from p,
+// which must be a clone, and reports whether any are left.
+func (f providerPresetFilter) filterModelTags(p *spec.ProviderPreset) bool {
+	if len(f.tags) == 0 {
+		return true
 	}
+	maps.DeleteFunc(p.ModelPresets, func(_ spec.ModelPresetID, mp spec.ModelPreset) bool {
+		return !hasAnyTag(mp.Tags, f.tags)
+	})
+	return len(p.ModelPresets) != 0
+}
 
-	return &spec.ListProviderPresetsResponse{
-		Body: &spec.ListProviderPresetsResponseBody{
-			Providers:     filtered[start:end],
-			NextPageToken: nextToken,
-		},
-	}, nil
+func compareProviderPresets(by spec.ProviderPresetSortBy, a, b spec.ProviderPreset) int {
+	c := 0
+	switch by {
+	case spec.ProviderPresetSortByCreatedAt:
+		c = b.CreatedAt.Compare(a.CreatedAt)
+	case spec.ProviderPresetSortByDisplayName:
+		c = cmp.Compare(strings.ToLower(string(a.DisplayName)), strings.ToLower(string(b.DisplayName)))
+	default:
+		c = b.ModifiedAt.Compare(a.ModifiedAt)
+	}
+	if c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Name, b.Name)
 }
 
 // PostModelPreset creates a new model preset on a user provider.
 func (s *ModelPresetStore) PostModelPreset(
 	ctx context.Context, req *spec.PostModelPresetRequest,
+) (*spec.PostModelPresetResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.postModelPreset(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PostModelPreset", req.IdempotencyKey, req,
+		func() (*spec.PostModelPresetResponse, error) { return s.postModelPreset(ctx, req) })
+}
+
+func (s *ModelPresetStore) postModelPreset(
+	ctx context.Context, req *spec.PostModelPresetRequest,
 ) (*spec.PostModelPresetResponse, error) {
 	if req == nil || req.Body == nil ||
 		req.ProviderName == "" || req.ModelPresetID == "" {
@@ -414,13 +703,15 @@ func (s *ModelPresetStore) PostModelPreset(
 		DisplayName:      req.Body.DisplayName,
 		Slug:             req.Body.Slug,
 		IsEnabled:        req.Body.IsEnabled,
+		Tags:             slices.Clone(req.Body.Tags),
+		Pricing:          cloneModelPricing(req.Body.Pricing),
 		ModelPresetPatch: cloneModelPresetPatch(req.Body.ModelPresetPatch),
 
 		CreatedAt:  now,
 		ModifiedAt: now,
 		IsBuiltIn:  false,
 	}
-	if err := validateModelPreset(&mp); err != nil {
+	if err := validateAuthoredModelPreset(&mp); err != nil {
 		return nil, err
 	}
 
@@ -428,13 +719,19 @@ func (s *ModelPresetStore) PostModelPreset(
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	all, err := s.readAllUserPresets(false)
+	all, err := s.readAllUserPresets()
 	if err != nil {
 		return nil, err
 	}
-	pp, ok := all.ProviderPresets[req.ProviderName]
-	if !ok {
-		return nil, fmt.Errorf("%w: %s", spec.ErrProviderNotFound, req.ProviderName)
+	pp, err := getUserProviderPreset(all, req.ProviderName)
+	if err != nil {
+		return nil, err
+	}
+	if err := precondition.CheckModifiedAt(req.Body.ExpectedProviderModifiedAt, pp.ModifiedAt, pp); err != nil {
+		return nil, err
+	}
+	if err := validateReasoningForSDK(pp, &mp); err != nil {
+		return nil, err
 	}
 
 	if pp.ModelPresets == nil {
@@ -446,12 +743,17 @@ func (s *ModelPresetStore) PostModelPreset(
 
 	pp.ModelPresets[req.ModelPresetID] = mp
 	pp.ModifiedAt = now
+	if req.DryRun {
+		body := &spec.PostModelPresetResponseBody{ModelPreset: mp, ProviderPreset: pp}
+		return &spec.PostModelPresetResponse{Body: body}, nil
+	}
 	all.ProviderPresets[req.ProviderName] = pp
 
 	if err := s.writeAllUserPresets(all); err != nil {
 		return nil, err
 	}
-	slog.Info("postModelPreset",
+	s.notify(spec.PresetChangeModelCreated, req.ProviderName, req.ModelPresetID)
+	logger.Info("postModelPreset",
 		"provider", req.ProviderName, "modelPresetID", req.ModelPresetID)
 	return &spec.PostModelPresetResponse{}, nil
 }
@@ -459,6 +761,16 @@ func (s *ModelPresetStore) PostModelPreset(
 // DeleteModelPreset removes a model preset.
 func (s *ModelPresetStore) DeleteModelPreset(
 	ctx context.Context, req *spec.DeleteModelPresetRequest,
+) (*spec.DeleteModelPresetResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.deleteModelPreset(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "DeleteModelPreset", req.IdempotencyKey, req,
+		func() (*spec.DeleteModelPresetResponse, error) { return s.deleteModelPreset(ctx, req) })
+}
+
+func (s *ModelPresetStore) deleteModelPreset(
+	ctx context.Context, req *spec.DeleteModelPresetRequest,
 ) (*spec.DeleteModelPresetResponse, error) {
 	if req == nil || req.ProviderName == "" || req.ModelPresetID == "" {
 		return nil, fmt.Errorf("%w: providerName & modelPresetID required", spec.ErrInvalidDir)
@@ -472,15 +784,16 @@ func (s *ModelPresetStore) DeleteModelPreset(
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	all, err := s.readAllUserPresets(false)
+	all, err := s.readAllUserPresets()
 	if err != nil {
 		return nil, err
 	}
-	pp, ok := all.ProviderPresets[req.ProviderName]
-	if !ok {
-		return nil, fmt.Errorf("%w: %s", spec.ErrProviderNotFound, req.ProviderName)
+	pp, err := getUserProviderPreset(all, req.ProviderName)
+	if err != nil {
+		return nil, err
 	}
-	if _, ok := pp.ModelPresets[req.ModelPresetID]; !ok {
+	deleted, ok := pp.ModelPresets[req.ModelPresetID]
+	if !ok {
 		return nil, fmt.Errorf("%w: %s", spec.ErrModelPresetNotFound, req.ModelPresetID)
 	}
 	delete(pp.ModelPresets, req.ModelPresetID)
@@ -489,12 +802,17 @@ func (s *ModelPresetStore) DeleteModelPreset(
 		pp.DefaultModelPresetID = ""
 	}
 	pp.ModifiedAt = time.Now().UTC()
+	if req.DryRun {
+		body := &spec.DeleteModelPresetResponseBody{ModelPreset: deleted, ProviderPreset: pp}
+		return &spec.DeleteModelPresetResponse{Body: body}, nil
+	}
 	all.ProviderPresets[req.ProviderName] = pp
 
 	if err := s.writeAllUserPresets(all); err != nil {
 		return nil, err
 	}
-	slog.Info("deleteModelPreset",
+	s.notify(spec.PresetChangeModelDeleted, req.ProviderName, req.ModelPresetID)
+	logger.Info("deleteModelPreset",
 		"provider", req.ProviderName, "modelPresetID", req.ModelPresetID)
 	return &spec.DeleteModelPresetResponse{}, nil
 }
@@ -545,13 +863,13 @@ func (s *ModelPresetStore) GetModelPreset(
 
 	// 2) User provider/model.
 	s.mu.RLock()
-	all, err := s.readAllUserPresets(false)
+	all, err := s.readAllUserPresets()
 	s.mu.RUnlock()
 	if err != nil {
 		return nil, err
 	}
 	pp, ok := all.ProviderPresets[provider]
-	if !ok {
+	if !ok || isSoftDeletedProviderPreset(pp) {
 		return nil, spec.ErrProviderNotFound
 	}
 	mp, ok := pp.ModelPresets[modelID]
@@ -579,11 +897,50 @@ func (s *ModelPresetStore) GetModelPreset(
 	}, nil
 }
 
-func (s *ModelPresetStore) readAllUserPresets(force bool) (spec.PresetsSchema, error) {
-	raw, err := s.userStore.GetAll(force)
+// ResetBuiltInOverrides clears the enabled flags, tags, system prompts and
+// default-model overrides of built-in providers and their model presets.
+func (s *ModelPresetStore) ResetBuiltInOverrides(
+	ctx context.Context, req *spec.ResetBuiltInOverridesRequest,
+) (*spec.ResetBuiltInOverridesResponse, error) {
+	if s.builtinData == nil {
+		return nil, spec.ErrBuiltInProviderAbsent
+	}
+	var names []inferenceSpec.ProviderName
+	if req != nil {
+		names = req.ProviderNames
+	}
+	reset, err := s.builtinData.ResetOverrides(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range reset {
+		s.notify(spec.PresetChangeProviderUpdated, name)
+	}
+	logger.Info("resetBuiltInOverrides", "providers", reset)
+	return &spec.ResetBuiltInOverridesResponse{
+		Body: &spec.ResetBuiltInOverridesResponseBody{ResetProviderNames: reset},
+	}, nil
+}
+
+// readAllUserPresets returns the user presets. The file is checked on every
+// call, so edits made outside the app are reloaded instead of serving a
+// stale snapshot; mutations therefore always apply on top of the file as it
+// is on disk.
+func (s *ModelPresetStore) readAllUserPresets() (spec.PresetsSchema, error) {
+	// A missing file is recreated on the next write; serve the cached data.
+	st, err := s.observeUserFile(false)
+	if err != nil {
+		return spec.PresetsSchema{}, err
+	}
+	raw, err := s.userStore.GetAll(st.Exists)
 	if err != nil {
 		return spec.PresetsSchema{}, err
 	}
+	return decodeUserPresets(raw)
+}
+
+// decodeUserPresets decodes and validates the content of a presets file.
+func decodeUserPresets(raw map[string]any) (spec.PresetsSchema, error) {
 	var ps spec.PresetsSchema
 	if err := jsonencdec.MapToStructWithJSONTags(raw, &ps); err != nil {
 		return ps, err
@@ -603,14 +960,140 @@ func (s *ModelPresetStore) readAllUserPresets(force bool) (spec.PresetsSchema, e
 			return spec.PresetsSchema{}, fmt.Errorf("invalid stored provider preset %q: %w", pp.Name, err)
 		}
 	}
+	for id, ep := range ps.EmbeddingPresets {
+		if err := validateEmbeddingPreset(&ep); err != nil {
+			return spec.PresetsSchema{}, fmt.Errorf("invalid stored embedding preset %q: %w", id, err)
+		}
+	}
+	for id, p := range ps.ParameterProfiles {
+		if err := validateParameterProfile(&p); err != nil {
+			return spec.PresetsSchema{}, fmt.Errorf("invalid stored parameter profile %q: %w", id, err)
+		}
+	}
 
 	return ps, nil
 }
 
 func (s *ModelPresetStore) writeAllUserPresets(ps spec.PresetsSchema) error {
+	return s.writeUserPresetsWith(ps, s.writeGuard.Write)
+}
+
+// writeUserPresetsWith writes ps through run, the write or the retry of the
+// write guard.
+func (s *ModelPresetStore) writeUserPresetsWith(ps spec.PresetsSchema, run func(func() error) error) error {
 	mp, err := jsonencdec.StructWithJSONTagsToMap(ps)
 	if err != nil {
 		return err
 	}
-	return s.userStore.SetAll(mp)
+	return run(func() error {
+		s.fileStateMu.Lock()
+		before := s.userFileState
+		s.fileStateMu.Unlock()
+		// Every mutation reads under mu before writing, so external edits made
+		// before that read are merged and only later ones conflict.
+		if err := fsutil.CheckFileUnchanged(s.userFilePath(), before); err != nil {
+			// Make the caller's retry read the edit, even one that kept the
+			// size and mtime.
+			_, _ = s.observeUserFile(true)
+			return err
+		}
+		s.invalidateProviderSnapshot()
+		if err := s.backups.Backup(s.userFilePath()); err != nil {
+			return err
+		}
+		if err := s.userStore.SetAll(mp); err != nil {
+			return err
+		}
+		return s.observeWrittenUserFile()
+	})
+}
+
+func (s *ModelPresetStore) userFilePath() string {
+	return filepath.Join(s.baseDir, spec.ModelPresetsFile)
+}
+
+// observeUserFile records the current state of the user presets file.
+// Without full, a file with the size and mtime last recorded keeps its cached
+// digest and is not read, so reads cost a stat. With full the file is always
+// hashed, and if its content changed without its size or mtime changing, the
+// mtime is bumped so the next forced read of the map store reloads it.
+func (s *ModelPresetStore) observeUserFile(full bool) (fsutil.FileState, error) {
+	s.fileStateMu.Lock()
+	last := s.userFileState
+	s.fileStateMu.Unlock()
+	var st fsutil.FileState
+	var err error
+	if full {
+		st, err = fsutil.ReadFileState(s.userFilePath())
+	} else {
+		st, err = fsutil.ReadFileStateSince(s.userFilePath(), last)
+	}
+	if err != nil {
+		return st, err
+	}
+	s.fileStateMu.Lock()
+	defer s.fileStateMu.Unlock()
+	if full {
+		if err := fsutil.RefreshModTime(s.userFilePath(), s.userFileState, st); err != nil {
+			logger.Debug("model preset store refresh mtime failed", "err", err)
+		}
+	}
+	s.userFileState = st
+	return st, nil
+}
+
+// observeWrittenUserFile records the state of the user presets file after a
+// write of this process.
+func (s *ModelPresetStore) observeWrittenUserFile() error {
+	st, err := s.observeUserFile(true)
+	if err != nil {
+		return err
+	}
+	s.fileStateMu.Lock()
+	s.writtenFileState = st
+	s.fileStateMu.Unlock()
+	return nil
+}
+
+// WriteStatus reports whether the user presets accept writes.
+func (s *ModelPresetStore) WriteStatus() fsutil.WriteStatus {
+	return s.writeGuard.Status()
+}
+
+// RetryModelPresetStoreWritable rewrites the current user presets and makes
+// the store writable again if that succeeds. A store that is still
+// unavailable is reported in the body rather than as an error.
+func (s *ModelPresetStore) RetryModelPresetStoreWritable(
+	ctx context.Context,
+	_ *spec.RetryModelPresetStoreWritableRequest,
+) (*spec.RetryModelPresetStoreWritableResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAllUserPresets()
+	if err != nil {
+		return nil, err
+	}
+	err = s.writeUserPresetsWith(all, s.writeGuard.Retry)
+	if err != nil && !errors.Is(err, fsutil.ErrStoreReadOnly) {
+		return nil, err
+	}
+	if err == nil {
+		logger.Info("model preset store writable again")
+	}
+	return &spec.RetryModelPresetStoreWritableResponse{
+		Body: &spec.RetryModelPresetStoreWritableResponseBody{WriteStatus: s.writeGuard.Status()},
+	}, nil
+}
+
+func hasAnyTag(tags []string, want map[string]struct{}) bool {
+	for _, tag := range tags {
+		if _, ok := want[tag]; ok {
+			return true
+		}
+	}
+	return false
 }
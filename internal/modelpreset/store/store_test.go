@@ -1,14 +1,30 @@
 package store
 
 import (
+	"cmp"
+	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/fsutil"
 	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	"github.com/flexigpt/flexigpt-app/internal/precondition"
+	"github.com/flexigpt/flexigpt-app/internal/validation"
+	"github.com/flexigpt/inference-go/capabilityoverride"
+	"github.com/flexigpt/inference-go/modelpreset"
 	inferenceSpec "github.com/flexigpt/inference-go/spec"
 )
 
@@ -395,6 +411,128 @@ func TestModelPresetStore_PatchProviderPreset_UserProvider(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "change_endpoint_fields",
+			req: &spec.PatchProviderPresetRequest{
+				ProviderName: prov,
+				Body: &spec.PatchProviderPresetRequestBody{
+					APIKeyHeaderKey:          new("X-Api-Key"),
+					DefaultHeaders:           map[string]string{"X-Org": "o1"},
+					ChatCompletionPathPrefix: new("/v2/chat/completions"),
+				},
+			},
+			verify: func(t *testing.T) {
+				t.Helper()
+				pp := getProviderByName(t, st, ctx, prov, true)
+				if pp.APIKeyHeaderKey != "X-Api-Key" || pp.ChatCompletionPathPrefix != "/v2/chat/completions" ||
+					!maps.Equal(pp.DefaultHeaders, map[string]string{"X-Org": "o1"}) {
+					t.Fatalf("endpoint fields not patched: %+v", pp)
+				}
+				if pp.DisplayName != "Patched Name" {
+					t.Fatalf("unpatched displayName changed: %q", pp.DisplayName)
+				}
+			},
+		},
+		{
+			name: "invalid_display_name",
+			req: &spec.PatchProviderPresetRequest{
+				ProviderName: prov,
+				Body:         &spec.PatchProviderPresetRequestBody{DisplayName: providerDisplayNamePtr("  ")},
+			},
+			wantErrText: "displayName:",
+		},
+		{
+			name: "invalid_origin",
+			req: &spec.PatchProviderPresetRequest{
+				ProviderName: prov,
+				Body:         &spec.PatchProviderPresetRequestBody{Origin: new("ftp://patched.example.test")},
+			},
+			wantErrText: "origin:",
+		},
+		{
+			name: "invalid_path_prefix",
+			req: &spec.PatchProviderPresetRequest{
+				ProviderName: prov,
+				Body:         &spec.PatchProviderPresetRequestBody{ChatCompletionPathPrefix: new("v1/chat")},
+			},
+			wantErrText: "chatCompletionPathPrefix:",
+		},
+		{
+			name: "invalid_api_key_header",
+			req: &spec.PatchProviderPresetRequest{
+				ProviderName: prov,
+				Body:         &spec.PatchProviderPresetRequestBody{APIKeyHeaderKey: new("bad header")},
+			},
+			wantErrText: "apiKeyHeaderKey:",
+		},
+		{
+			name: "invalid_default_header",
+			req: &spec.PatchProviderPresetRequest{
+				ProviderName: prov,
+				Body: &spec.PatchProviderPresetRequestBody{
+					DefaultHeaders: map[string]string{"X-Ok": "a\r\nb"},
+				},
+			},
+			wantErrText: "defaultHeaders.X-Ok:",
+		},
+		{
+			name: "templated_default_headers",
+			req: &spec.PatchProviderPresetRequest{
+				ProviderName: prov,
+				Body: &spec.PatchProviderPresetRequestBody{
+					DefaultHeaders: map[string]string{
+						"X-Date":      "{{date}}",
+						"X-Signature": "v1={{ authKey:providerHeader/signing }}.{{timestamp}}",
+					},
+				},
+			},
+			verify: func(t *testing.T) {
+				t.Helper()
+				pp := getProviderByName(t, st, ctx, prov, true)
+				if pp.DefaultHeaders["X-Signature"] != "v1={{ authKey:providerHeader/signing }}.{{timestamp}}" {
+					t.Fatalf("templated header not stored as written: %+v", pp.DefaultHeaders)
+				}
+			},
+		},
+		{
+			name: "unknown_header_placeholder",
+			req: &spec.PatchProviderPresetRequest{
+				ProviderName: prov,
+				Body: &spec.PatchProviderPresetRequestBody{
+					DefaultHeaders: map[string]string{"X-Sig": "{{authKey:gateway}}"},
+				},
+			},
+			wantErrText: "defaultHeaders.X-Sig:",
+		},
+		{
+			name: "header_reads_other_provider_key",
+			req: &spec.PatchProviderPresetRequest{
+				ProviderName: prov,
+				Body: &spec.PatchProviderPresetRequestBody{
+					DefaultHeaders: map[string]string{"X-Key": "{{authKey:provider/openai}}"},
+				},
+			},
+			wantErrText: "may only read",
+		},
+		{
+			name: "header_reads_mcp_key",
+			req: &spec.PatchProviderPresetRequest{
+				ProviderName: prov,
+				Body: &spec.PatchProviderPresetRequestBody{
+					DefaultHeaders: map[string]string{"X-Key": "{{authKey:mcp/github}}"},
+				},
+			},
+			wantErrText: "may only read",
+		},
+		{
+			name: "header_reads_own_key",
+			req: &spec.PatchProviderPresetRequest{
+				ProviderName: prov,
+				Body: &spec.PatchProviderPresetRequestBody{
+					DefaultHeaders: map[string]string{"X-Key": "{{authKey:provider/" + string(prov) + "}}"},
+				},
+			},
+		},
 		{
 			name: "disable_provider",
 			req: &spec.PatchProviderPresetRequest{
@@ -585,6 +723,136 @@ func TestModelPresetStore_DeleteProviderPreset(t *testing.T) {
 	}
 }
 
+func TestModelPresetStore_DeleteProviderPreset_SoftDeleteUndeleteAndSweep(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+
+	prov := inferenceSpec.ProviderName("user-prov-soft")
+	postUserProvider(t, st, prov, true)
+
+	deleteProv := func(t *testing.T) {
+		t.Helper()
+		if _, err := st.DeleteProviderPreset(ctx, &spec.DeleteProviderPresetRequest{ProviderName: prov}); err != nil {
+			t.Fatalf("DeleteProviderPreset: %v", err)
+		}
+	}
+	listed := func(t *testing.T) bool {
+		t.Helper()
+		resp, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
+			Names:           []inferenceSpec.ProviderName{prov},
+			IncludeDisabled: true,
+		})
+		if err != nil {
+			t.Fatalf("ListProviderPresets: %v", err)
+		}
+		return len(resp.Body.Providers) == 1
+	}
+
+	t.Run("soft_deleted_is_hidden_and_read_only", func(t *testing.T) {
+		deleteProv(t)
+		if listed(t) {
+			t.Fatalf("soft-deleted provider must not be listed")
+		}
+		_, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+			ProviderName: prov,
+			Body: &spec.PostProviderPresetRequestBody{
+				DisplayName:              "again",
+				SDKType:                  inferenceSpec.ProviderSDKTypeOpenAIChatCompletions,
+				Origin:                   "https://example.com",
+				ChatCompletionPathPrefix: "/v1/chat/completions",
+			},
+		})
+		wantErrIs(t, err, spec.ErrProviderPresetDeleting)
+		_, err = st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+			ProviderName: prov,
+			Body:         &spec.PatchProviderPresetRequestBody{IsEnabled: new(false)},
+		})
+		wantErrIs(t, err, spec.ErrProviderPresetDeleting)
+		_, err = st.DeleteProviderPreset(ctx, &spec.DeleteProviderPresetRequest{ProviderName: prov})
+		wantErrIs(t, err, spec.ErrProviderNotFound)
+	})
+
+	t.Run("undelete_restores", func(t *testing.T) {
+		if _, err := st.UndeleteProviderPreset(ctx, &spec.UndeleteProviderPresetRequest{ProviderName: prov}); err != nil {
+			t.Fatalf("UndeleteProviderPreset: %v", err)
+		}
+		if !listed(t) {
+			t.Fatalf("undeleted provider must be listed")
+		}
+		_, err := st.UndeleteProviderPreset(ctx, &spec.UndeleteProviderPresetRequest{ProviderName: prov})
+		wantErrContains(t, err, "not deleted")
+	})
+
+	t.Run("sweep_purges_after_grace", func(t *testing.T) {
+		// Stop the background sweeper so only explicit sweeps run.
+		st.cleanStop()
+		st.wg.Wait()
+		deleteProv(t)
+
+		// Sweeping within the grace period keeps the provider.
+		st.sweepSoftDeleted(ctx)
+		if _, err := st.UndeleteProviderPreset(ctx, &spec.UndeleteProviderPresetRequest{ProviderName: prov}); err != nil {
+			t.Fatalf("UndeleteProviderPreset within grace: %v", err)
+		}
+		deleteProv(t)
+
+		st.mu.Lock()
+		all, err := st.readAllUserPresets()
+		if err == nil {
+			pp := all.ProviderPresets[prov]
+			expired := time.Now().UTC().Add(-softDeleteGraceProviderPresets - time.Minute)
+			pp.SoftDeletedAt = &expired
+			all.ProviderPresets[prov] = pp
+			err = st.writeAllUserPresets(all)
+		}
+		st.mu.Unlock()
+		if err != nil {
+			t.Fatalf("backdate soft delete: %v", err)
+		}
+
+		var purged []inferenceSpec.ProviderName
+		st.SetProviderPresetPurgeHandler(func(_ context.Context, name inferenceSpec.ProviderName) error {
+			purged = append(purged, name)
+			return nil
+		})
+		st.sweepSoftDeleted(ctx)
+
+		if !slices.Equal(purged, []inferenceSpec.ProviderName{prov}) {
+			t.Fatalf("purge handler calls = %v", purged)
+		}
+		_, err = st.UndeleteProviderPreset(ctx, &spec.UndeleteProviderPresetRequest{ProviderName: prov})
+		wantErrIs(t, err, spec.ErrProviderNotFound)
+	})
+}
+
+func TestModelPresetStore_ListSoftDeleted(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+	live := inferenceSpec.ProviderName("user-prov-live")
+	gone := inferenceSpec.ProviderName("user-prov-gone")
+	postUserProvider(t, st, live, true)
+	postUserProvider(t, st, gone, true)
+	if _, err := st.DeleteProviderPreset(ctx, &spec.DeleteProviderPresetRequest{ProviderName: gone}); err != nil {
+		t.Fatalf("DeleteProviderPreset: %v", err)
+	}
+
+	resp, err := st.ListSoftDeleted(ctx, &spec.ListSoftDeletedRequest{})
+	if err != nil {
+		t.Fatalf("ListSoftDeleted: %v", err)
+	}
+	deleted := resp.Body.ProviderPresets
+	if len(deleted) != 1 || deleted[0].ProviderPreset.Name != gone {
+		t.Fatalf("deleted = %+v, want only %s", deleted, gone)
+	}
+	wantPurgeAt := deleted[0].ProviderPreset.SoftDeletedAt.Add(softDeleteGraceProviderPresets)
+	if !deleted[0].PurgeAt.Equal(wantPurgeAt) {
+		t.Fatalf("purgeAt = %v, want %v", deleted[0].PurgeAt, wantPurgeAt)
+	}
+	if ms := deleted[0].RemainingGraceMS; ms <= 0 || ms > softDeleteGraceProviderPresets.Milliseconds() {
+		t.Fatalf("remainingGraceMS = %d", ms)
+	}
+}
+
 func TestModelPresetStore_ModelPreset_UserCRUD(t *testing.T) {
 	st := newStore(t)
 	ctx := t.Context()
@@ -796,8 +1064,8 @@ func TestModelPresetStore_ModelPreset_UserCRUD(t *testing.T) {
 		}
 
 		reasoning := inferenceSpec.ReasoningParam{
-			Type:   inferenceSpec.ReasoningTypeHybridWithTokens,
-			Tokens: 8,
+			Type:  inferenceSpec.ReasoningTypeSingleWithLevels,
+			Level: inferenceSpec.ReasoningLevelLow,
 		}
 		_, err = st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
 			ProviderName:  userProv,
@@ -967,540 +1235,1242 @@ func TestModelPresetStore_ModelPreset_BuiltInToggle_ViaPatchModelPreset(t *testi
 	}
 }
 
-func TestModelPresetStore_ListProviderPresets_FilterAndPaging(t *testing.T) {
+func TestModelPresetStore_PatchAllModelPresets(t *testing.T) {
+	t.Parallel()
+
 	st := newStore(t)
 	ctx := t.Context()
 
-	p1 := inferenceSpec.ProviderName("user-p1")
-	p2 := inferenceSpec.ProviderName("user-p2")
-	p3 := inferenceSpec.ProviderName("user-p3")
-
-	postUserProvider(t, st, p1, true)
-	postUserProvider(t, st, p2, false)
-	postUserProvider(t, st, p3, true)
-
-	names := []inferenceSpec.ProviderName{p1, p2, p3}
-
-	t.Run("disabled_filtered_out_by_default", func(t *testing.T) {
-		resp, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
-			Names: names,
+	patchAll := func(
+		pn inferenceSpec.ProviderName, enabled bool, tags ...string,
+	) ([]spec.ModelPresetID, error) {
+		resp, err := st.PatchAllModelPresets(ctx, &spec.PatchAllModelPresetsRequest{
+			ProviderName: pn,
+			Body:         &spec.PatchAllModelPresetsRequestBody{IsEnabled: enabled, Tags: tags},
 		})
 		if err != nil {
-			t.Fatalf("ListProviderPresets: %v", err)
+			return nil, err
 		}
-		if len(resp.Body.Providers) != 2 {
-			t.Fatalf("expected 2 enabled providers, got %d", len(resp.Body.Providers))
+		return resp.Body.UpdatedModelPresetIDs, nil
+	}
+
+	t.Run("builtin", func(t *testing.T) {
+		pn, pp := anyBuiltInProviderFromStore(t, st)
+		if len(pp.ModelPresets) == 0 {
+			t.Skip("built-in provider has no models")
 		}
-		for _, p := range resp.Body.Providers {
-			if p.Name == p2 {
-				t.Fatalf("did not expect disabled provider to be returned")
+		if _, err := patchAll(pn, false); err != nil {
+			t.Fatalf("PatchAllModelPresets(disable): %v", err)
+		}
+		for id, mp := range getProviderByName(t, st, ctx, pn, true).ModelPresets {
+			if mp.IsEnabled {
+				t.Fatalf("built-in model %q still enabled", id)
 			}
 		}
-	})
+		ids, err := patchAll(pn, false)
+		if err != nil || len(ids) != 0 {
+			t.Fatalf("expected no-op, got %v %v", ids, err)
+		}
 
-	t.Run("include_disabled", func(t *testing.T) {
-		resp, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
-			Names:           names,
-			IncludeDisabled: true,
-		})
+		ids, err = patchAll(pn, true)
 		if err != nil {
-			t.Fatalf("ListProviderPresets: %v", err)
+			t.Fatalf("PatchAllModelPresets(enable): %v", err)
 		}
-		if len(resp.Body.Providers) != 3 {
-			t.Fatalf("expected 3 providers, got %d", len(resp.Body.Providers))
+		if len(ids) != len(pp.ModelPresets) {
+			t.Fatalf("updated %d models, want %d", len(ids), len(pp.ModelPresets))
 		}
 	})
 
-	t.Run("paging_pageSize_1_and_token_roundtrip", func(t *testing.T) {
-		seen := map[inferenceSpec.ProviderName]bool{}
-
-		req := &spec.ListProviderPresetsRequest{
-			Names:           names,
-			IncludeDisabled: true,
-			PageSize:        1,
+	t.Run("user_with_tags", func(t *testing.T) {
+		pn := inferenceSpec.ProviderName("user-bulk")
+		postUserProvider(t, st, pn, true)
+		for _, id := range []spec.ModelPresetID{"m1", "m2", "m3"} {
+			postUserModelPreset(t, ctx, st, pn, id, true)
 		}
-
-		var token *string
-		for i := range 10 {
-			if token != nil {
-				req = &spec.ListProviderPresetsRequest{PageToken: *token}
-			}
-			resp, err := st.ListProviderPresets(ctx, req)
-			if err != nil {
-				t.Fatalf("ListProviderPresets(page %d): %v", i, err)
-			}
-			for _, p := range resp.Body.Providers {
-				seen[p.Name] = true
-			}
-			token = resp.Body.NextPageToken
-			if token == nil {
-				break
-			}
+		_, err := st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+			ProviderName:  pn,
+			ModelPresetID: "m2",
+			Body:          &spec.PatchModelPresetRequestBody{Tags: &[]string{"legacy"}},
+		})
+		if err != nil {
+			t.Fatalf("PatchModelPreset(tags): %v", err)
 		}
 
-		for _, n := range names {
-			if !seen[n] {
-				t.Fatalf("paging missed provider %q", n)
+		ids, err := patchAll(pn, false, "legacy")
+		if err != nil {
+			t.Fatalf("PatchAllModelPresets(tag): %v", err)
+		}
+		if !slices.Equal(ids, []spec.ModelPresetID{"m2"}) {
+			t.Fatalf("updated %v, want [m2]", ids)
+		}
+		ids, err = patchAll(pn, false)
+		if err != nil {
+			t.Fatalf("PatchAllModelPresets(all): %v", err)
+		}
+		if !slices.Equal(ids, []spec.ModelPresetID{"m1", "m3"}) {
+			t.Fatalf("updated %v, want [m1 m3]", ids)
+		}
+		for id, mp := range getProviderByName(t, st, ctx, pn, true).ModelPresets {
+			if mp.IsEnabled {
+				t.Fatalf("user model %q still enabled", id)
 			}
 		}
 	})
 
-	t.Run("invalid_page_token_is_ignored_no_error", func(t *testing.T) {
-		_, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
-			PageToken: "not-base64!!!",
-		})
-		if err != nil {
-			t.Fatalf("expected no error for invalid token, got %v", err)
+	t.Run("errors", func(t *testing.T) {
+		if _, err := st.PatchAllModelPresets(ctx, nil); !errors.Is(err, spec.ErrInvalidDir) {
+			t.Fatalf("nil request: got %v", err)
+		}
+		if _, err := patchAll("missing", false); !errors.Is(err, spec.ErrProviderNotFound) {
+			t.Fatalf("missing provider: got %v", err)
 		}
 	})
 }
 
-func TestModelPresetStore_ListProviderPresets_TokenPreservesFilters(t *testing.T) {
+func TestModelPresetStore_ModelPresetTags(t *testing.T) {
 	t.Parallel()
 
 	st := newStore(t)
 	ctx := t.Context()
 
-	// Ensure >1 providers and include a disabled one.
-	p1 := inferenceSpec.ProviderName("user-tok-1")
-	p2 := inferenceSpec.ProviderName("user-tok-2") // disabled
-	p3 := inferenceSpec.ProviderName("user-tok-3")
-
-	postUserProvider(t, st, p1, true)
-	postUserProvider(t, st, p2, false)
-	postUserProvider(t, st, p3, true)
-
-	names := []inferenceSpec.ProviderName{p1, p2, p3}
+	pn := inferenceSpec.ProviderName("user-tags")
+	postUserProvider(t, st, pn, true)
+	postUserModelPreset(t, ctx, st, pn, "m-plain", true)
 
-	// First call to get token.
-	resp1, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
-		Names:           names,
-		IncludeDisabled: true,
-		PageSize:        1,
+	temp := 0.2
+	_, err := st.PostModelPreset(ctx, &spec.PostModelPresetRequest{
+		ProviderName:  pn,
+		ModelPresetID: "m-coding",
+		Body: &spec.PostModelPresetRequestBody{
+			Name:             "m-coding",
+			Slug:             "m-coding",
+			DisplayName:      "M Coding",
+			IsEnabled:        true,
+			Tags:             []string{"coding", "cheap"},
+			ModelPresetPatch: spec.ModelPresetPatch{Temperature: &temp},
+		},
 	})
 	if err != nil {
-		t.Fatalf("ListProviderPresets: %v", err)
+		t.Fatalf("PostModelPreset(tags): %v", err)
 	}
-	if len(resp1.Body.Providers) != 1 {
-		t.Fatalf("expected 1 provider, got %d", len(resp1.Body.Providers))
+
+	_, err = st.PostModelPreset(ctx, &spec.PostModelPresetRequest{
+		ProviderName:  pn,
+		ModelPresetID: "m-bad",
+		Body: &spec.PostModelPresetRequestBody{
+			Name:             "m-bad",
+			Slug:             "m-bad",
+			DisplayName:      "M Bad",
+			Tags:             []string{"dup", "dup"},
+			ModelPresetPatch: spec.ModelPresetPatch{Temperature: &temp},
+		},
+	})
+	if err == nil {
+		t.Fatalf("expected duplicate tags to be rejected")
 	}
-	if resp1.Body.NextPageToken == nil {
-		t.Fatalf("expected next page token")
+
+	listTagged := func(tags ...string) []spec.ProviderPreset {
+		t.Helper()
+		resp, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
+			Names: []inferenceSpec.ProviderName{pn},
+			Tags:  tags,
+		})
+		if err != nil {
+			t.Fatalf("ListProviderPresets(tags=%v): %v", tags, err)
+		}
+		return resp.Body.Providers
 	}
 
-	// Decode returned token and assert it preserved our filters.
-	tok := decodeProviderPageToken(t, *resp1.Body.NextPageToken)
-	if tok.PageSize != 1 {
-		t.Fatalf("token PageSize mismatch: got=%d want=1", tok.PageSize)
+	got := listTagged("coding")
+	if len(got) != 1 || len(got[0].ModelPresets) != 1 {
+		t.Fatalf("expected one provider with one tagged model, got %+v", got)
 	}
-	if !tok.IncludeDisabled {
-		t.Fatalf("token IncludeDisabled mismatch: got=false want=true")
+	if _, ok := got[0].ModelPresets["m-coding"]; !ok {
+		t.Fatalf("expected m-coding in tag-filtered listing")
 	}
-	if len(tok.Names) != len(names) {
-		t.Fatalf("token Names length mismatch: got=%d want=%d", len(tok.Names), len(names))
+	if got := listTagged("long-context"); len(got) != 0 {
+		t.Fatalf("expected no providers for unused tag, got %d", len(got))
 	}
-}
 
-func TestModelPresetStore_ListProviderPresets_PageSizeClamping_Heavy(t *testing.T) {
-	// This test intentionally creates DefaultPageSize+1 user providers to verify clamp behavior.
-	// It can be skipped in -short runs.
-	if testing.Short() {
-		t.Skip("skipping heavy paging test in short mode")
+	// Patch replaces tags; an explicit empty slice clears them.
+	_, err = st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+		ProviderName:  pn,
+		ModelPresetID: "m-plain",
+		Body:          &spec.PatchModelPresetRequestBody{Tags: &[]string{"long-context"}},
+	})
+	if err != nil {
+		t.Fatalf("PatchModelPreset(tags): %v", err)
+	}
+	got = listTagged("long-context", "coding")
+	if len(got) != 1 || len(got[0].ModelPresets) != 2 {
+		t.Fatalf("expected both models for tag union, got %+v", got)
+	}
+
+	_, err = st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+		ProviderName:  pn,
+		ModelPresetID: "m-coding",
+		Body:          &spec.PatchModelPresetRequestBody{Tags: &[]string{}},
+	})
+	if err != nil {
+		t.Fatalf("PatchModelPreset(clear tags): %v", err)
+	}
+	if got := getProviderByName(t, st, ctx, pn, true); len(got.ModelPresets["m-coding"].Tags) != 0 {
+		t.Fatalf("expected tags cleared, got %v", got.ModelPresets["m-coding"].Tags)
 	}
+
+	_, err = st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+		ProviderName:  pn,
+		ModelPresetID: "m-plain",
+		Body:          &spec.PatchModelPresetRequestBody{Tags: &[]string{testInvalidTagInput}},
+	})
+	wantErrContains(t, err, testInvalidTagText)
+
+	t.Run("builtin_tags_via_overlay", func(t *testing.T) {
+		bpn, bpp := anyBuiltInProviderFromStore(t, st)
+		mid, _ := anyModelID(bpp)
+		if mid == "" {
+			t.Skip("built-in provider has no models")
+		}
+		_, err := st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+			ProviderName:  bpn,
+			ModelPresetID: mid,
+			Body:          &spec.PatchModelPresetRequestBody{Tags: &[]string{"builtin_tag"}},
+		})
+		if err != nil {
+			t.Fatalf("PatchModelPreset(builtin tags): %v", err)
+		}
+		resp, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
+			Tags:            []string{"builtin_tag"},
+			IncludeDisabled: true,
+		})
+		if err != nil {
+			t.Fatalf("ListProviderPresets: %v", err)
+		}
+		if len(resp.Body.Providers) != 1 || resp.Body.Providers[0].Name != bpn {
+			t.Fatalf("expected only built-in provider %q, got %+v", bpn, resp.Body.Providers)
+		}
+		if !slices.Equal(resp.Body.Providers[0].ModelPresets[mid].Tags, []string{"builtin_tag"}) {
+			t.Fatalf("unexpected built-in tags: %v", resp.Body.Providers[0].ModelPresets[mid].Tags)
+		}
+	})
+}
+
+func TestModelPresetStore_ModelPresetPricing(t *testing.T) {
 	t.Parallel()
 
-	dir := t.TempDir()
-	st := newStoreAtDir(t, dir)
+	st := newStore(t)
 	ctx := t.Context()
 
-	total := spec.DefaultPageSize + 1
-	names := make([]inferenceSpec.ProviderName, 0, total)
+	pn := inferenceSpec.ProviderName("user-pricing")
+	postUserProvider(t, st, pn, true)
+	postUserModelPreset(t, ctx, st, pn, "m1", true)
 
-	for i := range total {
-		pn := inferenceSpec.ProviderName("user-many-" + strconv.Itoa(i))
-		postUserProvider(t, st, pn, true)
-		names = append(names, pn)
+	pricing := spec.ModelPricing{InputPerMTok: 3, CachedInputPerMTok: 0.3, OutputPerMTok: 15}
+	_, err := st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+		ProviderName:  pn,
+		ModelPresetID: "m1",
+		Body:          &spec.PatchModelPresetRequestBody{Pricing: &pricing},
+	})
+	if err != nil {
+		t.Fatalf("PatchModelPreset(pricing): %v", err)
+	}
+	got := getProviderByName(t, st, ctx, pn, true).ModelPresets["m1"].Pricing
+	if got == nil || *got != pricing {
+		t.Fatalf("unexpected pricing: %+v", got)
 	}
 
-	// Ask for an invalid page size (> DefaultPageSize), expecting clamp back to DefaultPageSize.
-	resp, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
-		Names:           names,
-		IncludeDisabled: true,
-		PageSize:        spec.MaxPageSize + 1000,
+	_, err = st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+		ProviderName:  pn,
+		ModelPresetID: "m1",
+		Body:          &spec.PatchModelPresetRequestBody{Pricing: &spec.ModelPricing{OutputPerMTok: -1}},
+	})
+	wantErrContains(t, err, "outputPerMTok")
+
+	_, err = st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+		ProviderName:  pn,
+		ModelPresetID: "m1",
+		Body:          &spec.PatchModelPresetRequestBody{Pricing: &spec.ModelPricing{}},
 	})
 	if err != nil {
-		t.Fatalf("ListProviderPresets: %v", err)
+		t.Fatalf("PatchModelPreset(clear pricing): %v", err)
 	}
-	if len(resp.Body.Providers) != spec.DefaultPageSize {
-		t.Fatalf("expected clamped page size %d, got %d", spec.DefaultPageSize, len(resp.Body.Providers))
+	if got := getProviderByName(t, st, ctx, pn, true).ModelPresets["m1"].Pricing; got != nil {
+		t.Fatalf("expected pricing cleared, got %+v", got)
 	}
-	if resp.Body.NextPageToken == nil {
-		t.Fatalf("expected next token for %d providers", total)
+
+	t.Run("builtin_pricing_via_overlay", func(t *testing.T) {
+		bpn, bpp := anyBuiltInProviderFromStore(t, st)
+		mid, _ := anyModelID(bpp)
+		if mid == "" {
+			t.Skip("built-in provider has no models")
+		}
+		_, err := st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+			ProviderName:  bpn,
+			ModelPresetID: mid,
+			Body:          &spec.PatchModelPresetRequestBody{Pricing: &pricing},
+		})
+		if err != nil {
+			t.Fatalf("PatchModelPreset(builtin pricing): %v", err)
+		}
+		got := getProviderByName(t, st, ctx, bpn, true).ModelPresets[mid].Pricing
+		if got == nil || *got != pricing {
+			t.Fatalf("unexpected built-in pricing: %+v", got)
+		}
+	})
+}
+
+func TestModelPresetStore_PatchModelPreset_ClearKnobs(t *testing.T) {
+	t.Parallel()
+
+	st := newStore(t)
+	ctx := t.Context()
+
+	pn := inferenceSpec.ProviderName("user-clear")
+	postUserProvider(t, st, pn, true)
+	postUserModelPreset(t, ctx, st, pn, "m1", true)
+
+	patch := func(body *spec.PatchModelPresetRequestBody) error {
+		_, err := st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+			ProviderName:  pn,
+			ModelPresetID: "m1",
+			Body:          body,
+		})
+		return err
+	}
+	model := func() spec.ModelPreset {
+		return getProviderByName(t, st, ctx, pn, true).ModelPresets["m1"]
 	}
 
-	tok := decodeProviderPageToken(t, *resp.Body.NextPageToken)
-	if tok.PageSize != spec.DefaultPageSize {
-		t.Fatalf("expected token page size clamped to %d, got %d", spec.DefaultPageSize, tok.PageSize)
+	if err := patch(&spec.PatchModelPresetRequestBody{ModelPresetPatch: spec.ModelPresetPatch{
+		MaxOutputLength: new(100),
+		Timeout:         new(30),
+	}}); err != nil {
+		t.Fatalf("PatchModelPreset(set): %v", err)
+	}
+	if m := model(); m.MaxOutputLength == nil || *m.MaxOutputLength != 100 || m.Timeout == nil {
+		t.Fatalf("unexpected knobs: %+v", m)
 	}
-}
 
-func TestModelPresetStore_PatchProviderPreset_User_BothFields_NoOp_AtomicOnError(t *testing.T) {
-	type setupOut struct {
-		prov inferenceSpec.ProviderName
+	if err := patch(&spec.PatchModelPresetRequestBody{ModelPresetPatch: spec.ModelPresetPatch{
+		MaxOutputLength: new(spec.PatchClearValue),
+		Timeout:         new(spec.PatchClearValue),
+	}}); err != nil {
+		t.Fatalf("PatchModelPreset(clear): %v", err)
+	}
+	if m := model(); m.MaxOutputLength != nil || m.Timeout != nil {
+		t.Fatalf("expected knobs cleared, got %+v", m)
 	}
 
-	setup := func(t *testing.T) (*ModelPresetStore, setupOut) {
+	reasoning := inferenceSpec.ReasoningParam{
+		Type:  inferenceSpec.ReasoningTypeSingleWithLevels,
+		Level: inferenceSpec.ReasoningLevelLow,
+	}
+	if err := patch(&spec.PatchModelPresetRequestBody{ModelPresetPatch: spec.ModelPresetPatch{
+		Reasoning:   &reasoning,
+		Temperature: new(float64(spec.PatchClearValue)),
+	}}); err != nil {
+		t.Fatalf("PatchModelPreset(swap temperature for reasoning): %v", err)
+	}
+	if m := model(); m.Temperature != nil || m.Reasoning == nil || *m.Reasoning != reasoning {
+		t.Fatalf("unexpected temperature/reasoning: %+v", m)
+	}
+
+	err := patch(&spec.PatchModelPresetRequestBody{
+		ModelPresetPatch: spec.ModelPresetPatch{Reasoning: &inferenceSpec.ReasoningParam{}},
+	})
+	wantErrContains(t, err, "either reasoning or temperature must be set")
+	if m := model(); m.Reasoning == nil {
+		t.Fatal("reasoning cleared despite failed patch")
+	}
+
+	err = patch(&spec.PatchModelPresetRequestBody{DisplayName: new(spec.ModelDisplayName("  "))})
+	if err == nil {
+		t.Fatal("expected error for blank display name")
+	}
+}
+
+func TestModelPresetStore_ModelPresetSystemPrompt(t *testing.T) {
+	t.Parallel()
+
+	st := newStore(t)
+	ctx := t.Context()
+
+	pn := inferenceSpec.ProviderName("user-sysprompt")
+	postUserProvider(t, st, pn, true)
+	postUserModelPreset(t, ctx, st, pn, "m1", true)
+
+	patch := func(provider inferenceSpec.ProviderName, mid spec.ModelPresetID, prompt string) error {
 		t.Helper()
-		st := newStore(t)
-		ctx := t.Context()
+		body := &spec.PatchModelPresetRequestBody{}
+		body.SystemPrompt = &prompt
+		_, err := st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+			ProviderName:  provider,
+			ModelPresetID: mid,
+			Body:          body,
+		})
+		return err
+	}
 
-		prov := inferenceSpec.ProviderName("user-prov-patch-both")
-		postUserProvider(t, st, prov, true)
-		postUserModelPreset(t, ctx, st, prov, "m1", true)
-		postUserModelPreset(t, ctx, st, prov, "m2", true)
+	prompt := "Always answer concisely in Spanish. Today is {{date}} ({{ weekday }}); you are {{modelName}}."
+	if err := patch(pn, "m1", prompt); err != nil {
+		t.Fatalf("PatchModelPreset(systemPrompt): %v", err)
+	}
+	got := getProviderByName(t, st, ctx, pn, true).ModelPresets["m1"].SystemPrompt
+	if got == nil || *got != prompt {
+		t.Fatalf("unexpected system prompt: %v", got)
+	}
 
-		// Set default to m1.
+	wantErrContains(t, patch(pn, "m1", "Hello {{user}}"), "unsupported placeholder {{user}}")
+	wantErrContains(t, patch(pn, "m1", strings.Repeat("x", spec.MaxSystemPromptLength+1)), "exceeds")
+
+	t.Run("builtin_system_prompt_via_overlay", func(t *testing.T) {
+		bpn, bpp := anyBuiltInProviderFromStore(t, st)
+		mid, _ := anyModelID(bpp)
+		if mid == "" {
+			t.Skip("built-in provider has no models")
+		}
+		if err := patch(bpn, mid, "Reply in Spanish."); err != nil {
+			t.Fatalf("PatchModelPreset(builtin systemPrompt): %v", err)
+		}
+		got := getProviderByName(t, st, ctx, bpn, true).ModelPresets[mid].SystemPrompt
+		if got == nil || *got != "Reply in Spanish." {
+			t.Fatalf("unexpected built-in system prompt: %v", got)
+		}
+		wantErrContains(t, patch(bpn, mid, "{{nope}}"), "unsupported placeholder")
+
+		if _, err := st.ResetBuiltInOverrides(ctx, &spec.ResetBuiltInOverridesRequest{
+			ProviderNames: []inferenceSpec.ProviderName{bpn},
+		}); err != nil {
+			t.Fatalf("ResetBuiltInOverrides: %v", err)
+		}
+		got = getProviderByName(t, st, ctx, bpn, true).ModelPresets[mid].SystemPrompt
+		if got != nil && *got == "Reply in Spanish." {
+			t.Fatal("expected reset to restore the catalog system prompt")
+		}
+	})
+}
+
+func TestModelPresetStore_ProviderRateLimits(t *testing.T) {
+	t.Parallel()
+
+	st := newStore(t)
+	ctx := t.Context()
+
+	pn := inferenceSpec.ProviderName("user-ratelimits")
+	postUserProvider(t, st, pn, true)
+
+	limits := spec.ProviderRateLimits{RequestsPerMinute: 60, TokensPerMinute: 100000, MaxQueueDepth: 8}
+	_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+		ProviderName: pn,
+		Body:         &spec.PatchProviderPresetRequestBody{RateLimits: &limits},
+	})
+	if err != nil {
+		t.Fatalf("PatchProviderPreset(rateLimits): %v", err)
+	}
+	got := getProviderByName(t, st, ctx, pn, true).RateLimits
+	if got == nil || *got != limits {
+		t.Fatalf("unexpected rate limits: %+v", got)
+	}
+
+	_, err = st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+		ProviderName: pn,
+		Body: &spec.PatchProviderPresetRequestBody{
+			RateLimits: &spec.ProviderRateLimits{RequestsPerMinute: -1},
+		},
+	})
+	wantErrContains(t, err, "requestsPerMinute")
+
+	_, err = st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+		ProviderName: pn,
+		Body:         &spec.PatchProviderPresetRequestBody{RateLimits: &spec.ProviderRateLimits{}},
+	})
+	if err != nil {
+		t.Fatalf("PatchProviderPreset(clear rateLimits): %v", err)
+	}
+	if got := getProviderByName(t, st, ctx, pn, true).RateLimits; got != nil {
+		t.Fatalf("expected rate limits cleared, got %+v", got)
+	}
+
+	t.Run("builtin_rate_limits_via_overlay", func(t *testing.T) {
+		bpn, _ := anyBuiltInProviderFromStore(t, st)
 		_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
-			ProviderName: prov,
-			Body: &spec.PatchProviderPresetRequestBody{
-				DefaultModelPresetID: mpidPtr("m1"),
-			},
+			ProviderName: bpn,
+			Body:         &spec.PatchProviderPresetRequestBody{RateLimits: &limits},
 		})
 		if err != nil {
-			t.Fatalf("seed PatchProviderPreset(default=m1): %v", err)
+			t.Fatalf("PatchProviderPreset(builtin rateLimits): %v", err)
 		}
-		return st, setupOut{prov: prov}
+		got := getProviderByName(t, st, ctx, bpn, true).RateLimits
+		if got == nil || *got != limits {
+			t.Fatalf("unexpected built-in rate limits: %+v", got)
+		}
+
+		if _, err := st.ResetBuiltInOverrides(ctx, &spec.ResetBuiltInOverridesRequest{
+			ProviderNames: []inferenceSpec.ProviderName{bpn},
+		}); err != nil {
+			t.Fatalf("ResetBuiltInOverrides: %v", err)
+		}
+		if got := getProviderByName(t, st, ctx, bpn, true).RateLimits; got != nil {
+			t.Fatalf("expected built-in rate limits reset, got %+v", got)
+		}
+	})
+}
+
+func TestModelPresetStore_ProviderResilience(t *testing.T) {
+	t.Parallel()
+
+	st := newStore(t)
+	ctx := t.Context()
+
+	pn := inferenceSpec.ProviderName("user-resilience")
+	postUserProvider(t, st, pn, true)
+
+	resilience := spec.ProviderResilience{
+		Retry: &spec.ProviderRetryPolicy{
+			MaxAttempts:        3,
+			InitialBackoffMS:   200,
+			MaxBackoffMS:       2000,
+			RetryOnStatusCodes: []int{429, 503},
+		},
+		Failover: []spec.ModelPresetRef{{ProviderName: "other", ModelPresetID: "m1"}},
+	}
+	_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+		ProviderName: pn,
+		Body:         &spec.PatchProviderPresetRequestBody{Resilience: &resilience},
+	})
+	if err != nil {
+		t.Fatalf("PatchProviderPreset(resilience): %v", err)
+	}
+	got := getProviderByName(t, st, ctx, pn, true).Resilience
+	if !reflect.DeepEqual(got, &resilience) {
+		t.Fatalf("unexpected resilience: %+v", got)
 	}
 
-	tests := []struct {
-		name        string
-		req         func(out setupOut) *spec.PatchProviderPresetRequest
-		wantErrIs   error
-		wantErrText string
-		verify      func(t *testing.T, st *ModelPresetStore, out setupOut, before spec.ProviderPreset)
+	invalid := []struct {
+		name       string
+		resilience spec.ProviderResilience
+		wantErr    string
 	}{
 		{
-			name: "both_fields_applied",
-			req: func(out setupOut) *spec.PatchProviderPresetRequest {
-				return &spec.PatchProviderPresetRequest{
-					ProviderName: out.prov,
-					Body: &spec.PatchProviderPresetRequestBody{
-						IsEnabled:            new(false),
-						DefaultModelPresetID: mpidPtr("m2"),
-					},
-				}
-			},
-			verify: func(t *testing.T, st *ModelPresetStore, out setupOut, _ spec.ProviderPreset) {
-				t.Helper()
-				got := getProviderByName(t, st, t.Context(), out.prov, true)
-				if got.IsEnabled != false {
-					t.Fatalf("isEnabled not applied: got=%v want=false", got.IsEnabled)
-				}
-				if got.DefaultModelPresetID != "m2" {
-					t.Fatalf("default model not applied: got=%q want=m2", got.DefaultModelPresetID)
-				}
-			},
+			name:       "too_many_attempts",
+			resilience: spec.ProviderResilience{Retry: &spec.ProviderRetryPolicy{MaxAttempts: 50}},
+			wantErr:    "maxAttempts",
 		},
 		{
-			name: "no_op_does_not_bump_modifiedAt",
-			req: func(out setupOut) *spec.PatchProviderPresetRequest {
-				return &spec.PatchProviderPresetRequest{
-					ProviderName: out.prov,
-					Body: &spec.PatchProviderPresetRequestBody{
-						IsEnabled:            new(true),
-						DefaultModelPresetID: mpidPtr("m1"),
-					},
-				}
-			},
-			verify: func(t *testing.T, st *ModelPresetStore, out setupOut, before spec.ProviderPreset) {
-				t.Helper()
-				time.Sleep(2 * time.Millisecond) // make bump observable if it happens
-				_ = out
-				after := getProviderByName(t, st, t.Context(), before.Name, true)
-				if !after.ModifiedAt.Equal(before.ModifiedAt) {
-					t.Fatalf("ModifiedAt should not change on no-op: before=%v after=%v",
-						before.ModifiedAt, after.ModifiedAt)
-				}
+			name: "backoff_order",
+			resilience: spec.ProviderResilience{
+				Retry: &spec.ProviderRetryPolicy{InitialBackoffMS: 5000, MaxBackoffMS: 100},
 			},
+			wantErr: "initialBackoffMS",
 		},
 		{
-			name: "atomic_on_error_default_model_missing_does_not_persist_isEnabled",
-			req: func(out setupOut) *spec.PatchProviderPresetRequest {
-				return &spec.PatchProviderPresetRequest{
-					ProviderName: out.prov,
-					Body: &spec.PatchProviderPresetRequestBody{
-						IsEnabled:            new(false),
-						DefaultModelPresetID: mpidPtr("missing"),
-					},
-				}
-			},
-			wantErrIs: spec.ErrModelPresetNotFound,
-			verify: func(t *testing.T, st *ModelPresetStore, out setupOut, _ spec.ProviderPreset) {
-				t.Helper()
-				got := getProviderByName(t, st, t.Context(), out.prov, true)
-				if got.IsEnabled != true {
-					t.Fatalf("isEnabled must not be persisted when request fails: got=%v want=true", got.IsEnabled)
-				}
+			name:       "bad_status",
+			resilience: spec.ProviderResilience{Retry: &spec.ProviderRetryPolicy{RetryOnStatusCodes: []int{42}}},
+			wantErr:    "invalid HTTP status",
+		},
+		{
+			name: "self_failover",
+			resilience: spec.ProviderResilience{
+				Failover: []spec.ModelPresetRef{{ProviderName: pn, ModelPresetID: "m1"}},
 			},
+			wantErr: "another provider",
+		},
+		{
+			name: "duplicate_failover",
+			resilience: spec.ProviderResilience{Failover: []spec.ModelPresetRef{
+				{ProviderName: "other", ModelPresetID: "m1"},
+				{ProviderName: "other", ModelPresetID: "m1"},
+			}},
+			wantErr: "duplicate",
 		},
 	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+				ProviderName: pn,
+				Body:         &spec.PatchProviderPresetRequestBody{Resilience: &tc.resilience},
+			})
+			wantErrContains(t, err, tc.wantErr)
+		})
+	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			st, out := setup(t)
-			ctx := t.Context()
+	_, err = st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+		ProviderName: pn,
+		Body:         &spec.PatchProviderPresetRequestBody{Resilience: &spec.ProviderResilience{}},
+	})
+	if err != nil {
+		t.Fatalf("PatchProviderPreset(clear resilience): %v", err)
+	}
+	if got := getProviderByName(t, st, ctx, pn, true).Resilience; got != nil {
+		t.Fatalf("expected resilience cleared, got %+v", got)
+	}
 
-			before := getProviderByName(t, st, ctx, out.prov, true)
+	t.Run("builtin_resilience_via_overlay", func(t *testing.T) {
+		bpn, _ := anyBuiltInProviderFromStore(t, st)
+		_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+			ProviderName: bpn,
+			Body:         &spec.PatchProviderPresetRequestBody{Resilience: &resilience},
+		})
+		if err != nil {
+			t.Fatalf("PatchProviderPreset(builtin resilience): %v", err)
+		}
+		got := getProviderByName(t, st, ctx, bpn, true).Resilience
+		if !reflect.DeepEqual(got, &resilience) {
+			t.Fatalf("unexpected built-in resilience: %+v", got)
+		}
+	})
+}
 
-			_, err := st.PatchProviderPreset(ctx, tt.req(out))
-			if tt.wantErrIs != nil {
-				wantErrIs(t, err, tt.wantErrIs)
-				if tt.verify != nil {
-					tt.verify(t, st, out, before)
-				}
-				return
+func TestModelPresetStore_ProviderImageProcessing(t *testing.T) {
+	t.Parallel()
+
+	st := newStore(t)
+	ctx := t.Context()
+
+	pn := inferenceSpec.ProviderName("user-images")
+	postUserProvider(t, st, pn, true)
+
+	imageProcessing := spec.ProviderImageProcessing{
+		MaxDimension:  2048,
+		Format:        spec.ImageFormatJPEG,
+		JPEGQuality:   80,
+		StripMetadata: true,
+	}
+	_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+		ProviderName: pn,
+		Body:         &spec.PatchProviderPresetRequestBody{ImageProcessing: &imageProcessing},
+	})
+	if err != nil {
+		t.Fatalf("PatchProviderPreset(imageProcessing): %v", err)
+	}
+	got := getProviderByName(t, st, ctx, pn, true).ImageProcessing
+	if !reflect.DeepEqual(got, &imageProcessing) {
+		t.Fatalf("unexpected imageProcessing: %+v", got)
+	}
+
+	invalid := []struct {
+		name    string
+		c       spec.ProviderImageProcessing
+		wantErr string
+	}{
+		{name: "negative_dimension", c: spec.ProviderImageProcessing{MaxDimension: -1}, wantErr: "maxDimension"},
+		{name: "huge_dimension", c: spec.ProviderImageProcessing{MaxDimension: 100000}, wantErr: "maxDimension"},
+		{name: "unknown_format", c: spec.ProviderImageProcessing{Format: "webp"}, wantErr: "format"},
+		{name: "bad_quality", c: spec.ProviderImageProcessing{JPEGQuality: 101}, wantErr: "jpegQuality"},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+				ProviderName: pn,
+				Body:         &spec.PatchProviderPresetRequestBody{ImageProcessing: &tc.c},
+			})
+			wantErrContains(t, err, tc.wantErr)
+		})
+	}
+
+	_, err = st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+		ProviderName: pn,
+		Body:         &spec.PatchProviderPresetRequestBody{ImageProcessing: &spec.ProviderImageProcessing{}},
+	})
+	if err != nil {
+		t.Fatalf("PatchProviderPreset(clear imageProcessing): %v", err)
+	}
+	if got := getProviderByName(t, st, ctx, pn, true).ImageProcessing; got != nil {
+		t.Fatalf("expected imageProcessing cleared, got %+v", got)
+	}
+
+	t.Run("builtin_image_processing_via_overlay", func(t *testing.T) {
+		bpn, _ := anyBuiltInProviderFromStore(t, st)
+		_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+			ProviderName: bpn,
+			Body:         &spec.PatchProviderPresetRequestBody{ImageProcessing: &imageProcessing},
+		})
+		if err != nil {
+			t.Fatalf("PatchProviderPreset(builtin imageProcessing): %v", err)
+		}
+		got := getProviderByName(t, st, ctx, bpn, true).ImageProcessing
+		if !reflect.DeepEqual(got, &imageProcessing) {
+			t.Fatalf("unexpected built-in imageProcessing: %+v", got)
+		}
+	})
+
+	t.Run("builtin_vision_default_can_be_cleared", func(t *testing.T) {
+		st := newStore(t)
+		bpn := modelpreset.ProviderAnthropic
+		got := getProviderByName(t, st, ctx, bpn, true).ImageProcessing
+		if got == nil || got.MaxDimension != 1568 {
+			t.Fatalf("expected seeded maxDimension, got %+v", got)
+		}
+		_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+			ProviderName: bpn,
+			Body:         &spec.PatchProviderPresetRequestBody{ImageProcessing: &spec.ProviderImageProcessing{}},
+		})
+		if err != nil {
+			t.Fatalf("PatchProviderPreset(clear builtin imageProcessing): %v", err)
+		}
+		if got := getProviderByName(t, st, ctx, bpn, true).ImageProcessing; got != nil {
+			t.Fatalf("expected built-in imageProcessing cleared, got %+v", got)
+		}
+	})
+}
+func TestModelPresetStore_ListProviderPresets_FilterAndPaging(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+
+	p1 := inferenceSpec.ProviderName("user-p1")
+	p2 := inferenceSpec.ProviderName("user-p2")
+	p3 := inferenceSpec.ProviderName("user-p3")
+
+	postUserProvider(t, st, p1, true)
+	postUserProvider(t, st, p2, false)
+	postUserProvider(t, st, p3, true)
+
+	names := []inferenceSpec.ProviderName{p1, p2, p3}
+
+	t.Run("disabled_filtered_out_by_default", func(t *testing.T) {
+		resp, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
+			Names: names,
+		})
+		if err != nil {
+			t.Fatalf("ListProviderPresets: %v", err)
+		}
+		if len(resp.Body.Providers) != 2 {
+			t.Fatalf("expected 2 enabled providers, got %d", len(resp.Body.Providers))
+		}
+		for _, p := range resp.Body.Providers {
+			if p.Name == p2 {
+				t.Fatalf("did not expect disabled provider to be returned")
 			}
-			if tt.wantErrText != "" {
-				wantErrContains(t, err, tt.wantErrText)
-				return
+		}
+	})
+
+	t.Run("include_disabled", func(t *testing.T) {
+		resp, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
+			Names:           names,
+			IncludeDisabled: true,
+		})
+		if err != nil {
+			t.Fatalf("ListProviderPresets: %v", err)
+		}
+		if len(resp.Body.Providers) != 3 {
+			t.Fatalf("expected 3 providers, got %d", len(resp.Body.Providers))
+		}
+	})
+
+	t.Run("paging_pageSize_1_and_token_roundtrip", func(t *testing.T) {
+		seen := map[inferenceSpec.ProviderName]bool{}
+
+		req := &spec.ListProviderPresetsRequest{
+			Names:           names,
+			IncludeDisabled: true,
+			PageSize:        1,
+		}
+
+		var token *string
+		for i := range 10 {
+			if token != nil {
+				req = &spec.ListProviderPresetsRequest{PageToken: *token}
 			}
+			resp, err := st.ListProviderPresets(ctx, req)
 			if err != nil {
-				t.Fatalf("unexpected: %v", err)
+				t.Fatalf("ListProviderPresets(page %d): %v", i, err)
 			}
-			if tt.verify != nil {
-				tt.verify(t, st, out, before)
+			for _, p := range resp.Body.Providers {
+				seen[p.Name] = true
+			}
+			token = resp.Body.NextPageToken
+			if token == nil {
+				break
+			}
+		}
+
+		for _, n := range names {
+			if !seen[n] {
+				t.Fatalf("paging missed provider %q", n)
 			}
+		}
+	})
+
+	t.Run("invalid_page_token_is_ignored_no_error", func(t *testing.T) {
+		_, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
+			PageToken: "not-base64!!!",
 		})
+		if err != nil {
+			t.Fatalf("expected no error for invalid token, got %v", err)
+		}
+	})
+}
+
+func TestModelPresetStore_ListProviderPresets_TokenPreservesFilters(t *testing.T) {
+	t.Parallel()
+
+	st := newStore(t)
+	ctx := t.Context()
+
+	// Ensure >1 providers and include a disabled one.
+	p1 := inferenceSpec.ProviderName("user-tok-1")
+	p2 := inferenceSpec.ProviderName("user-tok-2") // disabled
+	p3 := inferenceSpec.ProviderName("user-tok-3")
+
+	postUserProvider(t, st, p1, true)
+	postUserProvider(t, st, p2, false)
+	postUserProvider(t, st, p3, true)
+
+	names := []inferenceSpec.ProviderName{p1, p2, p3}
+
+	// First call to get token.
+	resp1, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
+		Names:           names,
+		IncludeDisabled: true,
+		PageSize:        1,
+	})
+	if err != nil {
+		t.Fatalf("ListProviderPresets: %v", err)
+	}
+	if len(resp1.Body.Providers) != 1 {
+		t.Fatalf("expected 1 provider, got %d", len(resp1.Body.Providers))
+	}
+	if resp1.Body.NextPageToken == nil {
+		t.Fatalf("expected next page token")
+	}
+
+	// Decode returned token and assert it preserved our filters.
+	tok := decodeProviderPageToken(t, *resp1.Body.NextPageToken)
+	if tok.PageSize != 1 {
+		t.Fatalf("token PageSize mismatch: got=%d want=1", tok.PageSize)
+	}
+	if !tok.IncludeDisabled {
+		t.Fatalf("token IncludeDisabled mismatch: got=false want=true")
+	}
+	if len(tok.Names) != len(names) {
+		t.Fatalf("token Names length mismatch: got=%d want=%d", len(tok.Names), len(names))
+	}
+	if len(tok.Tags) != 0 {
+		t.Fatalf("token Tags mismatch: got=%v want=none", tok.Tags)
 	}
 }
 
-func TestModelPresetStore_PatchProviderPreset_BuiltIn_BothFieldsAndErrors(t *testing.T) {
+func TestModelPresetStore_ListProviderPresets_SortByAndSDKTypes(t *testing.T) {
+	t.Parallel()
+
 	st := newStore(t)
 	ctx := t.Context()
 
-	pn2, pp2 := builtInProviderWithAtLeastNModelsFromStore(t, ctx, st, 2)
-	alt := anotherModelID(pp2, pp2.DefaultModelPresetID)
-	if alt == "" {
-		t.Skip("no alternate model found")
+	names := []inferenceSpec.ProviderName{"sort-b", "sort-a", "sort-c"}
+	for _, n := range names {
+		postUserProvider(t, st, n, true)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	listAll := func(req spec.ListProviderPresetsRequest) []inferenceSpec.ProviderName {
+		t.Helper()
+		req.Names = names
+		req.PageSize = 1
+		wantSort := cmp.Or(req.SortBy, spec.ProviderPresetSortByModifiedAt)
+		wantSDK := req.SDKTypes
+		var got []inferenceSpec.ProviderName
+		for {
+			resp, err := st.ListProviderPresets(ctx, &req)
+			if err != nil {
+				t.Fatalf("ListProviderPresets: %v", err)
+			}
+			for _, p := range resp.Body.Providers {
+				got = append(got, p.Name)
+			}
+			if resp.Body.NextPageToken == nil {
+				return got
+			}
+			tok := decodeProviderPageToken(t, *resp.Body.NextPageToken)
+			if tok.SortBy != wantSort || !slices.Equal(tok.SDKTypes, wantSDK) {
+				t.Fatalf("token lost sortBy/sdkTypes: %+v", tok)
+			}
+			req = spec.ListProviderPresetsRequest{PageToken: *resp.Body.NextPageToken}
+		}
 	}
 
 	tests := []struct {
-		name        string
-		req         *spec.PatchProviderPresetRequest
-		wantErrIs   error
-		wantErrText string
-		verify      func(t *testing.T)
+		name string
+		req  spec.ListProviderPresetsRequest
+		want []inferenceSpec.ProviderName
 	}{
 		{
-			name: "invalid_default_model_id_tag_rejected",
-			req: &spec.PatchProviderPresetRequest{
-				ProviderName: pn2,
-				Body: &spec.PatchProviderPresetRequestBody{
-					DefaultModelPresetID: mpidPtr(testInvalidTagInput),
-				},
-			},
-			wantErrText: testInvalidTagText,
+			name: "displayName",
+			req:  spec.ListProviderPresetsRequest{SortBy: spec.ProviderPresetSortByDisplayName},
+			want: []inferenceSpec.ProviderName{"sort-a", "sort-b", "sort-c"},
 		},
 		{
-			name: "unknown_default_model_returns_not_found",
-			req: &spec.PatchProviderPresetRequest{
-				ProviderName: pn2,
-				Body: &spec.PatchProviderPresetRequestBody{
-					DefaultModelPresetID: mpidPtr(testGhostID),
-				},
-			},
-			wantErrIs: spec.ErrModelPresetNotFound,
+			name: "createdAt",
+			req:  spec.ListProviderPresetsRequest{SortBy: spec.ProviderPresetSortByCreatedAt},
+			want: []inferenceSpec.ProviderName{"sort-c", "sort-a", "sort-b"},
 		},
 		{
-			name: "both_fields_applied",
-			req: &spec.PatchProviderPresetRequest{
-				ProviderName: pn2,
-				Body: &spec.PatchProviderPresetRequestBody{
-					IsEnabled:            new(!pp2.IsEnabled),
-					DefaultModelPresetID: new(alt),
-				},
+			name: "sdkType-match",
+			req: spec.ListProviderPresetsRequest{
+				SortBy:   spec.ProviderPresetSortByDisplayName,
+				SDKTypes: []inferenceSpec.ProviderSDKType{inferenceSpec.ProviderSDKTypeOpenAIChatCompletions},
 			},
-			verify: func(t *testing.T) {
-				t.Helper()
-				got := getProviderByName(t, st, ctx, pn2, true)
-				if got.IsEnabled == pp2.IsEnabled {
-					t.Fatalf("expected isEnabled to change")
-				}
-				if got.DefaultModelPresetID != alt {
-					t.Fatalf("defaultModelPresetID not applied: got=%q want=%q", got.DefaultModelPresetID, alt)
-				}
+			want: []inferenceSpec.ProviderName{"sort-a", "sort-b", "sort-c"},
+		},
+		{
+			name: "sdkType-miss",
+			req: spec.ListProviderPresetsRequest{
+				SDKTypes: []inferenceSpec.ProviderSDKType{inferenceSpec.ProviderSDKTypeAnthropic},
 			},
+			want: nil,
 		},
 	}
+	for _, tc := range tests {
+		if got := listAll(tc.req); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			_, err := st.PatchProviderPreset(ctx, tt.req)
-			if tt.wantErrIs != nil {
-				wantErrIs(t, err, tt.wantErrIs)
-				return
-			}
-			if tt.wantErrText != "" {
-				wantErrContains(t, err, tt.wantErrText)
-				return
-			}
-			if err != nil {
-				t.Fatalf("unexpected: %v", err)
-			}
-			if tt.verify != nil {
-				tt.verify(t)
-			}
-		})
+	_, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{SortBy: "size"})
+	if !errors.Is(err, spec.ErrInvalidDir) {
+		t.Fatalf("expected ErrInvalidDir for unknown sortBy, got %v", err)
 	}
 }
 
-func TestModelPresetStore_PatchModelPreset_AdditionalErrors(t *testing.T) {
+func TestModelPresetStore_CountPresets(t *testing.T) {
+	t.Parallel()
+
 	st := newStore(t)
 	ctx := t.Context()
 
-	bpn, _ := anyBuiltInProviderFromStore(t, st)
+	p1 := inferenceSpec.ProviderName("count-1")
+	p2 := inferenceSpec.ProviderName("count-2") // disabled
+	postUserProvider(t, st, p1, true)
+	postUserProvider(t, st, p2, false)
+	postUserModelPreset(t, ctx, st, p1, "m1", true)
+	postUserModelPreset(t, ctx, st, p1, "m2", false)
+	postUserModelPreset(t, ctx, st, p2, "m3", true)
+	names := []inferenceSpec.ProviderName{p1, p2}
 
 	tests := []struct {
-		name      string
-		req       *spec.PatchModelPresetRequest
-		wantErrIs error
+		name            string
+		includeDisabled bool
+		providers       spec.PresetCount
+		models          spec.PresetCount
 	}{
 		{
-			name:      testNilRequest,
-			req:       nil,
-			wantErrIs: spec.ErrInvalidDir,
-		},
-		{
-			name: testNilBody,
-			req: &spec.PatchModelPresetRequest{
-				ProviderName:  "p",
-				ModelPresetID: "m",
-				Body:          nil,
-			},
-			wantErrIs: spec.ErrInvalidDir,
-		},
-		{
-			name: testEmptyProviderName,
-			req: &spec.PatchModelPresetRequest{
-				ProviderName:  "",
-				ModelPresetID: "m",
-				Body:          &spec.PatchModelPresetRequestBody{IsEnabled: new(false)},
-			},
-			wantErrIs: spec.ErrInvalidDir,
-		},
-		{
-			name: "empty_modelPresetID",
-			req: &spec.PatchModelPresetRequest{
-				ProviderName:  "p",
-				ModelPresetID: "",
-				Body:          &spec.PatchModelPresetRequestBody{IsEnabled: new(false)},
-			},
-			wantErrIs: spec.ErrInvalidDir,
+			"include-disabled", true,
+			spec.PresetCount{Count: 2, DisabledCount: 1}, spec.PresetCount{Count: 3, DisabledCount: 2},
 		},
 		{
-			name: "builtin_provider_unknown_model",
-			req: &spec.PatchModelPresetRequest{
-				ProviderName:  bpn,
-				ModelPresetID: testGhostID,
-				Body:          &spec.PatchModelPresetRequestBody{IsEnabled: new(false)},
-			},
-			wantErrIs: spec.ErrModelPresetNotFound,
+			"enabled-only", false,
+			spec.PresetCount{Count: 1}, spec.PresetCount{Count: 2, DisabledCount: 1},
 		},
 	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			_, err := st.PatchModelPreset(ctx, tt.req)
-			wantErrIs(t, err, tt.wantErrIs)
+	for _, tc := range tests {
+		pc, err := st.CountProviderPresets(ctx, &spec.CountProviderPresetsRequest{
+			Names: names, IncludeDisabled: tc.includeDisabled,
 		})
+		if err != nil {
+			t.Fatalf("%s: CountProviderPresets: %v", tc.name, err)
+		}
+		if *pc.Body != tc.providers {
+			t.Errorf("%s: providers = %+v, want %+v", tc.name, *pc.Body, tc.providers)
+		}
+		mc, err := st.CountModelPresets(ctx, &spec.CountModelPresetsRequest{
+			Names: names, IncludeDisabled: tc.includeDisabled,
+		})
+		if err != nil {
+			t.Fatalf("%s: CountModelPresets: %v", tc.name, err)
+		}
+		if *mc.Body != tc.models {
+			t.Errorf("%s: models = %+v, want %+v", tc.name, *mc.Body, tc.models)
+		}
 	}
 }
 
-func TestModelPresetStore_DeleteModelPreset_AdditionalCases(t *testing.T) {
+func TestModelPresetStore_GetProviderPreset(t *testing.T) {
+	t.Parallel()
+
 	st := newStore(t)
 	ctx := t.Context()
 
-	userProv := inferenceSpec.ProviderName("user-del-model")
-	postUserProvider(t, st, userProv, true)
-	postUserModelPreset(t, ctx, st, userProv, "m1", true)
-	postUserModelPreset(t, ctx, st, userProv, "m2", true)
+	on := inferenceSpec.ProviderName("get-on")
+	off := inferenceSpec.ProviderName("get-off")
+	postUserProvider(t, st, on, true)
+	postUserProvider(t, st, off, false)
+	postUserModelPreset(t, ctx, st, on, "m1", true)
 
-	// Set default to m1.
-	_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
-		ProviderName: userProv,
-		Body: &spec.PatchProviderPresetRequestBody{
-			DefaultModelPresetID: mpidPtr("m1"),
+	resp, err := st.GetProviderPreset(ctx, &spec.GetProviderPresetRequest{ProviderName: on})
+	if err != nil {
+		t.Fatalf("GetProviderPreset: %v", err)
+	}
+	if resp.Body.Name != on || len(resp.Body.ModelPresets) != 1 {
+		t.Fatalf("unexpected provider: %+v", resp.Body)
+	}
+	// The result is a copy.
+	delete(resp.Body.ModelPresets, "m1")
+	again, err := st.GetProviderPreset(ctx, &spec.GetProviderPresetRequest{ProviderName: on})
+	if err != nil || len(again.Body.ModelPresets) != 1 {
+		t.Fatalf("store state leaked through response: %+v, %v", again, err)
+	}
+
+	if _, err := st.GetProviderPreset(ctx, &spec.GetProviderPresetRequest{ProviderName: off}); !errors.Is(
+		err, spec.ErrProviderNotFound,
+	) {
+		t.Fatalf("disabled provider: got %v", err)
+	}
+	if _, err := st.GetProviderPreset(ctx, &spec.GetProviderPresetRequest{
+		ProviderName: off, IncludeDisabled: true,
+	}); err != nil {
+		t.Fatalf("disabled provider with includeDisabled: %v", err)
+	}
+
+	batch, err := st.GetProviderPresetsBatch(ctx, &spec.GetProviderPresetsBatchRequest{
+		Body: &spec.GetProviderPresetsBatchRequestBody{
+			ProviderNames: []inferenceSpec.ProviderName{"nope", on, off},
 		},
 	})
 	if err != nil {
-		t.Fatalf("PatchProviderPreset(set default): %v", err)
+		t.Fatalf("GetProviderPresetsBatch: %v", err)
+	}
+	items := batch.Body.Items
+	if len(items) != 3 ||
+		items[0].ProviderName != "nope" || items[1].ProviderName != on || items[2].ProviderName != off {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+	if items[0].Error == "" || items[1].Provider == nil || items[1].Error != "" || items[2].Error == "" {
+		t.Fatalf("unexpected item results: %+v", items)
 	}
 
-	builtinName, _ := anyBuiltInProviderFromStore(t, st)
+	if _, err := st.GetProviderPresetsBatch(ctx, &spec.GetProviderPresetsBatchRequest{}); !errors.Is(
+		err, spec.ErrInvalidDir,
+	) {
+		t.Fatalf("empty batch: got %v", err)
+	}
+}
+
+func TestModelPresetStore_ListProviderPresets_PageSizeClamping_Heavy(t *testing.T) {
+	// This test intentionally creates DefaultPageSize+1 user providers to verify clamp behavior.
+	// It can be skipped in -short runs.
+	if testing.Short() {
+		t.Skip("skipping heavy paging test in short mode")
+	}
+	t.Parallel()
+
+	dir := t.TempDir()
+	st := newStoreAtDir(t, dir)
+	ctx := t.Context()
+
+	total := spec.DefaultPageSize + 1
+	names := make([]inferenceSpec.ProviderName, 0, total)
+
+	for i := range total {
+		pn := inferenceSpec.ProviderName("user-many-" + strconv.Itoa(i))
+		postUserProvider(t, st, pn, true)
+		names = append(names, pn)
+	}
+
+	// Ask for an invalid page size (> DefaultPageSize), expecting clamp back to DefaultPageSize.
+	resp, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
+		Names:           names,
+		IncludeDisabled: true,
+		PageSize:        spec.MaxPageSize + 1000,
+	})
+	if err != nil {
+		t.Fatalf("ListProviderPresets: %v", err)
+	}
+	if len(resp.Body.Providers) != spec.DefaultPageSize {
+		t.Fatalf("expected clamped page size %d, got %d", spec.DefaultPageSize, len(resp.Body.Providers))
+	}
+	if resp.Body.NextPageToken == nil {
+		t.Fatalf("expected next token for %d providers", total)
+	}
+
+	tok := decodeProviderPageToken(t, *resp.Body.NextPageToken)
+	if tok.PageSize != spec.DefaultPageSize {
+		t.Fatalf("expected token page size clamped to %d, got %d", spec.DefaultPageSize, tok.PageSize)
+	}
+}
+
+func TestModelPresetStore_PatchProviderPreset_User_BothFields_NoOp_AtomicOnError(t *testing.T) {
+	type setupOut struct {
+		prov inferenceSpec.ProviderName
+	}
+
+	setup := func(t *testing.T) (*ModelPresetStore, setupOut) {
+		t.Helper()
+		st := newStore(t)
+		ctx := t.Context()
+
+		prov := inferenceSpec.ProviderName("user-prov-patch-both")
+		postUserProvider(t, st, prov, true)
+		postUserModelPreset(t, ctx, st, prov, "m1", true)
+		postUserModelPreset(t, ctx, st, prov, "m2", true)
+
+		// Set default to m1.
+		_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+			ProviderName: prov,
+			Body: &spec.PatchProviderPresetRequestBody{
+				DefaultModelPresetID: mpidPtr("m1"),
+			},
+		})
+		if err != nil {
+			t.Fatalf("seed PatchProviderPreset(default=m1): %v", err)
+		}
+		return st, setupOut{prov: prov}
+	}
 
 	tests := []struct {
 		name        string
-		req         *spec.DeleteModelPresetRequest
+		req         func(out setupOut) *spec.PatchProviderPresetRequest
 		wantErrIs   error
 		wantErrText string
-		verify      func(t *testing.T)
+		verify      func(t *testing.T, st *ModelPresetStore, out setupOut, before spec.ProviderPreset)
 	}{
 		{
-			name:      testNilRequest,
-			req:       nil,
-			wantErrIs: spec.ErrInvalidDir,
-		},
-		{
-			name: testEmptyProviderName,
-			req: &spec.DeleteModelPresetRequest{
-				ProviderName:  "",
-				ModelPresetID: "m",
+			name: "both_fields_applied",
+			req: func(out setupOut) *spec.PatchProviderPresetRequest {
+				return &spec.PatchProviderPresetRequest{
+					ProviderName: out.prov,
+					Body: &spec.PatchProviderPresetRequestBody{
+						IsEnabled:            new(false),
+						DefaultModelPresetID: mpidPtr("m2"),
+					},
+				}
 			},
-			wantErrIs: spec.ErrInvalidDir,
-		},
-		{
-			name: "empty_modelPresetID",
-			req: &spec.DeleteModelPresetRequest{
-				ProviderName:  "p",
-				ModelPresetID: "",
+			verify: func(t *testing.T, st *ModelPresetStore, out setupOut, _ spec.ProviderPreset) {
+				t.Helper()
+				got := getProviderByName(t, st, t.Context(), out.prov, true)
+				if got.IsEnabled != false {
+					t.Fatalf("isEnabled not applied: got=%v want=false", got.IsEnabled)
+				}
+				if got.DefaultModelPresetID != "m2" {
+					t.Fatalf("default model not applied: got=%q want=m2", got.DefaultModelPresetID)
+				}
 			},
-			wantErrIs: spec.ErrInvalidDir,
 		},
 		{
-			name: "builtin_readonly",
-			req: &spec.DeleteModelPresetRequest{
-				ProviderName:  builtinName,
-				ModelPresetID: "anything",
+			name: "no_op_does_not_bump_modifiedAt",
+			req: func(out setupOut) *spec.PatchProviderPresetRequest {
+				return &spec.PatchProviderPresetRequest{
+					ProviderName: out.prov,
+					Body: &spec.PatchProviderPresetRequestBody{
+						IsEnabled:            new(true),
+						DefaultModelPresetID: mpidPtr("m1"),
+					},
+				}
 			},
-			wantErrIs: spec.ErrBuiltInReadOnly,
-		},
-		{
-			name: testUnknownProvider,
-			req: &spec.DeleteModelPresetRequest{
-				ProviderName:  testGhostID,
-				ModelPresetID: "m1",
+			verify: func(t *testing.T, st *ModelPresetStore, out setupOut, before spec.ProviderPreset) {
+				t.Helper()
+				time.Sleep(2 * time.Millisecond) // make bump observable if it happens
+				_ = out
+				after := getProviderByName(t, st, t.Context(), before.Name, true)
+				if !after.ModifiedAt.Equal(before.ModifiedAt) {
+					t.Fatalf("ModifiedAt should not change on no-op: before=%v after=%v",
+						before.ModifiedAt, after.ModifiedAt)
+				}
 			},
-			wantErrIs: spec.ErrProviderNotFound,
 		},
 		{
-			name: "unknown_model",
-			req: &spec.DeleteModelPresetRequest{
-				ProviderName:  userProv,
-				ModelPresetID: testGhostID,
+			name: "atomic_on_error_default_model_missing_does_not_persist_isEnabled",
+			req: func(out setupOut) *spec.PatchProviderPresetRequest {
+				return &spec.PatchProviderPresetRequest{
+					ProviderName: out.prov,
+					Body: &spec.PatchProviderPresetRequestBody{
+						IsEnabled:            new(false),
+						DefaultModelPresetID: mpidPtr("missing"),
+					},
+				}
 			},
 			wantErrIs: spec.ErrModelPresetNotFound,
-		},
-		{
-			name: "delete_non_default_does_not_reset_default",
-			req: &spec.DeleteModelPresetRequest{
-				ProviderName:  userProv,
-				ModelPresetID: "m2",
+			verify: func(t *testing.T, st *ModelPresetStore, out setupOut, _ spec.ProviderPreset) {
+				t.Helper()
+				got := getProviderByName(t, st, t.Context(), out.prov, true)
+				if got.IsEnabled != true {
+					t.Fatalf("isEnabled must not be persisted when request fails: got=%v want=true", got.IsEnabled)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, out := setup(t)
+			ctx := t.Context()
+
+			before := getProviderByName(t, st, ctx, out.prov, true)
+
+			_, err := st.PatchProviderPreset(ctx, tt.req(out))
+			if tt.wantErrIs != nil {
+				wantErrIs(t, err, tt.wantErrIs)
+				if tt.verify != nil {
+					tt.verify(t, st, out, before)
+				}
+				return
+			}
+			if tt.wantErrText != "" {
+				wantErrContains(t, err, tt.wantErrText)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected: %v", err)
+			}
+			if tt.verify != nil {
+				tt.verify(t, st, out, before)
+			}
+		})
+	}
+}
+
+func TestModelPresetStore_PatchProviderPreset_BuiltIn_BothFieldsAndErrors(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+
+	pn2, pp2 := builtInProviderWithAtLeastNModelsFromStore(t, ctx, st, 2)
+	alt := anotherModelID(pp2, pp2.DefaultModelPresetID)
+	if alt == "" {
+		t.Skip("no alternate model found")
+	}
+
+	tests := []struct {
+		name        string
+		req         *spec.PatchProviderPresetRequest
+		wantErrIs   error
+		wantErrText string
+		verify      func(t *testing.T)
+	}{
+		{
+			name: "invalid_default_model_id_tag_rejected",
+			req: &spec.PatchProviderPresetRequest{
+				ProviderName: pn2,
+				Body: &spec.PatchProviderPresetRequestBody{
+					DefaultModelPresetID: mpidPtr(testInvalidTagInput),
+				},
+			},
+			wantErrText: testInvalidTagText,
+		},
+		{
+			name: "unknown_default_model_returns_not_found",
+			req: &spec.PatchProviderPresetRequest{
+				ProviderName: pn2,
+				Body: &spec.PatchProviderPresetRequestBody{
+					DefaultModelPresetID: mpidPtr(testGhostID),
+				},
+			},
+			wantErrIs: spec.ErrModelPresetNotFound,
+		},
+		{
+			name: "both_fields_applied",
+			req: &spec.PatchProviderPresetRequest{
+				ProviderName: pn2,
+				Body: &spec.PatchProviderPresetRequestBody{
+					IsEnabled:            new(!pp2.IsEnabled),
+					DefaultModelPresetID: new(alt),
+				},
 			},
 			verify: func(t *testing.T) {
 				t.Helper()
-				pp := getProviderByName(t, st, ctx, userProv, true)
-				if pp.DefaultModelPresetID != "m1" {
-					t.Fatalf("default must remain m1: got=%q", pp.DefaultModelPresetID)
+				got := getProviderByName(t, st, ctx, pn2, true)
+				if got.IsEnabled == pp2.IsEnabled {
+					t.Fatalf("expected isEnabled to change")
+				}
+				if got.DefaultModelPresetID != alt {
+					t.Fatalf("defaultModelPresetID not applied: got=%q want=%q", got.DefaultModelPresetID, alt)
 				}
 			},
 		},
@@ -1508,7 +2478,7 @@ func TestModelPresetStore_DeleteModelPreset_AdditionalCases(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := st.DeleteModelPreset(ctx, tt.req)
+			_, err := st.PatchProviderPreset(ctx, tt.req)
 			if tt.wantErrIs != nil {
 				wantErrIs(t, err, tt.wantErrIs)
 				return
@@ -1524,226 +2494,2415 @@ func TestModelPresetStore_DeleteModelPreset_AdditionalCases(t *testing.T) {
 				tt.verify(t)
 			}
 		})
-	}
+	}
+}
+
+func TestModelPresetStore_PatchModelPreset_AdditionalErrors(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+
+	bpn, _ := anyBuiltInProviderFromStore(t, st)
+
+	tests := []struct {
+		name      string
+		req       *spec.PatchModelPresetRequest
+		wantErrIs error
+	}{
+		{
+			name:      testNilRequest,
+			req:       nil,
+			wantErrIs: spec.ErrInvalidDir,
+		},
+		{
+			name: testNilBody,
+			req: &spec.PatchModelPresetRequest{
+				ProviderName:  "p",
+				ModelPresetID: "m",
+				Body:          nil,
+			},
+			wantErrIs: spec.ErrInvalidDir,
+		},
+		{
+			name: testEmptyProviderName,
+			req: &spec.PatchModelPresetRequest{
+				ProviderName:  "",
+				ModelPresetID: "m",
+				Body:          &spec.PatchModelPresetRequestBody{IsEnabled: new(false)},
+			},
+			wantErrIs: spec.ErrInvalidDir,
+		},
+		{
+			name: "empty_modelPresetID",
+			req: &spec.PatchModelPresetRequest{
+				ProviderName:  "p",
+				ModelPresetID: "",
+				Body:          &spec.PatchModelPresetRequestBody{IsEnabled: new(false)},
+			},
+			wantErrIs: spec.ErrInvalidDir,
+		},
+		{
+			name: "builtin_provider_unknown_model",
+			req: &spec.PatchModelPresetRequest{
+				ProviderName:  bpn,
+				ModelPresetID: testGhostID,
+				Body:          &spec.PatchModelPresetRequestBody{IsEnabled: new(false)},
+			},
+			wantErrIs: spec.ErrModelPresetNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.PatchModelPreset(ctx, tt.req)
+			wantErrIs(t, err, tt.wantErrIs)
+		})
+	}
+}
+
+func TestModelPresetStore_DeleteModelPreset_AdditionalCases(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+
+	userProv := inferenceSpec.ProviderName("user-del-model")
+	postUserProvider(t, st, userProv, true)
+	postUserModelPreset(t, ctx, st, userProv, "m1", true)
+	postUserModelPreset(t, ctx, st, userProv, "m2", true)
+
+	// Set default to m1.
+	_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+		ProviderName: userProv,
+		Body: &spec.PatchProviderPresetRequestBody{
+			DefaultModelPresetID: mpidPtr("m1"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("PatchProviderPreset(set default): %v", err)
+	}
+
+	builtinName, _ := anyBuiltInProviderFromStore(t, st)
+
+	tests := []struct {
+		name        string
+		req         *spec.DeleteModelPresetRequest
+		wantErrIs   error
+		wantErrText string
+		verify      func(t *testing.T)
+	}{
+		{
+			name:      testNilRequest,
+			req:       nil,
+			wantErrIs: spec.ErrInvalidDir,
+		},
+		{
+			name: testEmptyProviderName,
+			req: &spec.DeleteModelPresetRequest{
+				ProviderName:  "",
+				ModelPresetID: "m",
+			},
+			wantErrIs: spec.ErrInvalidDir,
+		},
+		{
+			name: "empty_modelPresetID",
+			req: &spec.DeleteModelPresetRequest{
+				ProviderName:  "p",
+				ModelPresetID: "",
+			},
+			wantErrIs: spec.ErrInvalidDir,
+		},
+		{
+			name: "builtin_readonly",
+			req: &spec.DeleteModelPresetRequest{
+				ProviderName:  builtinName,
+				ModelPresetID: "anything",
+			},
+			wantErrIs: spec.ErrBuiltInReadOnly,
+		},
+		{
+			name: testUnknownProvider,
+			req: &spec.DeleteModelPresetRequest{
+				ProviderName:  testGhostID,
+				ModelPresetID: "m1",
+			},
+			wantErrIs: spec.ErrProviderNotFound,
+		},
+		{
+			name: "unknown_model",
+			req: &spec.DeleteModelPresetRequest{
+				ProviderName:  userProv,
+				ModelPresetID: testGhostID,
+			},
+			wantErrIs: spec.ErrModelPresetNotFound,
+		},
+		{
+			name: "delete_non_default_does_not_reset_default",
+			req: &spec.DeleteModelPresetRequest{
+				ProviderName:  userProv,
+				ModelPresetID: "m2",
+			},
+			verify: func(t *testing.T) {
+				t.Helper()
+				pp := getProviderByName(t, st, ctx, userProv, true)
+				if pp.DefaultModelPresetID != "m1" {
+					t.Fatalf("default must remain m1: got=%q", pp.DefaultModelPresetID)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.DeleteModelPreset(ctx, tt.req)
+			if tt.wantErrIs != nil {
+				wantErrIs(t, err, tt.wantErrIs)
+				return
+			}
+			if tt.wantErrText != "" {
+				wantErrContains(t, err, tt.wantErrText)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected: %v", err)
+			}
+			if tt.verify != nil {
+				tt.verify(t)
+			}
+		})
+	}
+}
+
+func TestModelPresetStore_PostModelPreset_AdditionalValidation(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+
+	userProv := inferenceSpec.ProviderName("user-model-validate-more")
+	postUserProvider(t, st, userProv, true)
+
+	temp := 0.1
+	intPtr := func(v int) *int { return &v }
+
+	baseReq := func() *spec.PostModelPresetRequest {
+		return &spec.PostModelPresetRequest{
+			ProviderName:  userProv,
+			ModelPresetID: "m1",
+			Body: &spec.PostModelPresetRequestBody{
+				Name:        "n1",
+				Slug:        "m1",
+				DisplayName: "Model 1",
+				IsEnabled:   true,
+				ModelPresetPatch: spec.ModelPresetPatch{
+					Temperature: &temp,
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		req         func() *spec.PostModelPresetRequest
+		wantErrText string
+	}{
+		{
+			name: "empty_model_name",
+			req: func() *spec.PostModelPresetRequest {
+				r := baseReq()
+				r.Body.Name = ""
+				return r
+			},
+			wantErrText: "name is empty",
+		},
+		{
+			name: "empty_display_name",
+			req: func() *spec.PostModelPresetRequest {
+				r := baseReq()
+				r.Body.DisplayName = ""
+				return r
+			},
+			wantErrText: "displayName is empty",
+		},
+		{
+			name: "negative_max_prompt_length",
+			req: func() *spec.PostModelPresetRequest {
+				r := baseReq()
+				r.Body.MaxPromptLength = intPtr(-1)
+				return r
+			},
+			wantErrText: "maxPromptLength must be >= 0",
+		},
+		{
+			name: "negative_max_output_length",
+			req: func() *spec.PostModelPresetRequest {
+				r := baseReq()
+				r.Body.MaxOutputLength = intPtr(-1)
+				return r
+			},
+			wantErrText: "maxOutputLength must be >= 0",
+		},
+		{
+			name: "negative_timeout",
+			req: func() *spec.PostModelPresetRequest {
+				r := baseReq()
+				r.Body.Timeout = intPtr(-1)
+				return r
+			},
+			wantErrText: "timeout must be >= 0",
+		},
+		{
+			name: "stop_sequence_empty_string",
+			req: func() *spec.PostModelPresetRequest {
+				r := baseReq()
+				r.Body.StopSequences = new([]string{""})
+				return r
+			},
+			wantErrText: "stopSequences[0] is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := st.PostModelPreset(ctx, tt.req())
+			if tt.wantErrText != "" {
+				wantErrContains(t, err, tt.wantErrText)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected: %v", err)
+			}
+		})
+	}
+}
+
+func TestModelPresetStore_PostModelPreset_ReportsAllFields(t *testing.T) {
+	st := newStore(t)
+	userProv := inferenceSpec.ProviderName("user-model-validate-all")
+	postUserProvider(t, st, userProv, true)
+
+	timeout := -1
+	_, err := st.PostModelPreset(t.Context(), &spec.PostModelPresetRequest{
+		ProviderName:  userProv,
+		ModelPresetID: "m1",
+		Body: &spec.PostModelPresetRequestBody{
+			Name:             "n1",
+			Slug:             "m1",
+			ModelPresetPatch: spec.ModelPresetPatch{Timeout: &timeout},
+		},
+	})
+	issues := validation.IssuesOf(err)
+	want := []string{"displayName", "temperature", "timeout"}
+	if got := issues.Fields(); !slices.Equal(got, want) {
+		t.Fatalf("fields = %v, want %v (err %v)", got, want, err)
+	}
+	if issues[2].Code != validation.CodeOutOfRange {
+		t.Fatalf("timeout code = %q, want %q", issues[2].Code, validation.CodeOutOfRange)
+	}
+}
+
+func TestModelPresetStore_PatchProviderPreset_ExpectedModifiedAt(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+	name := inferenceSpec.ProviderName("user-precondition")
+	postUserProvider(t, st, name, true)
+	read := getProviderByName(t, st, ctx, name, true).ModifiedAt
+
+	patch := func(displayName string) error {
+		dn := spec.ProviderDisplayName(displayName)
+		_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+			ProviderName: name,
+			Body:         &spec.PatchProviderPresetRequestBody{DisplayName: &dn, ExpectedModifiedAt: &read},
+		})
+		return err
+	}
+	if err := patch("First"); err != nil {
+		t.Fatalf("patch at current modifiedAt: %v", err)
+	}
+	err := patch("Second")
+	wantErrIs(t, err, precondition.ErrConflict)
+	current, ok := precondition.Current[spec.ProviderPreset](err)
+	if !ok || current.DisplayName != "First" {
+		t.Fatalf("conflict current = %q, %v; want First", current.DisplayName, ok)
+	}
+	if got := getProviderByName(t, st, ctx, name, true).DisplayName; got != "First" {
+		t.Fatalf("displayName after conflict = %q, want First", got)
+	}
+}
+
+func TestModelPresetStore_PostModelPreset_ExpectedProviderModifiedAt(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+	name := inferenceSpec.ProviderName("user-post-precondition")
+	postUserProvider(t, st, name, true)
+	read := getProviderByName(t, st, ctx, name, true).ModifiedAt
+
+	post := func(id spec.ModelPresetID) error {
+		temp := 0.1
+		_, err := st.PostModelPreset(ctx, &spec.PostModelPresetRequest{
+			ProviderName:  name,
+			ModelPresetID: id,
+			Body: &spec.PostModelPresetRequestBody{
+				Name:                       spec.ModelName(id),
+				Slug:                       spec.ModelSlug(id),
+				DisplayName:                spec.ModelDisplayName(id),
+				IsEnabled:                  true,
+				ModelPresetPatch:           spec.ModelPresetPatch{Temperature: &temp},
+				ExpectedProviderModifiedAt: &read,
+			},
+		})
+		return err
+	}
+	if err := post("m1"); err != nil {
+		t.Fatalf("post at current provider modifiedAt: %v", err)
+	}
+	// The first create changed the provider.
+	err := post("m2")
+	wantErrIs(t, err, precondition.ErrConflict)
+	current, ok := precondition.Current[spec.ProviderPreset](err)
+	if _, has := current.ModelPresets["m1"]; !ok || !has {
+		t.Fatalf("conflict current = %+v, %v; want the provider with m1", current, ok)
+	}
+	if _, has := getProviderByName(t, st, ctx, name, true).ModelPresets["m2"]; has {
+		t.Fatal("m2 was created despite the conflict")
+	}
+}
+
+func TestModelPresetStore_DeleteModelPreset_DryRun(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+	name := inferenceSpec.ProviderName("user-dry-run")
+	postUserProvider(t, st, name, true)
+	postUserModelPreset(t, ctx, st, name, "m1", true)
+	if _, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+		ProviderName: name,
+		Body:         &spec.PatchProviderPresetRequestBody{DefaultModelPresetID: mpidPtr("m1")},
+	}); err != nil {
+		t.Fatalf("PatchProviderPreset(set default): %v", err)
+	}
+
+	resp, err := st.DeleteModelPreset(ctx, &spec.DeleteModelPresetRequest{
+		ProviderName:  name,
+		ModelPresetID: "m1",
+		DryRun:        true,
+	})
+	if err != nil {
+		t.Fatalf("DeleteModelPreset(dry run): %v", err)
+	}
+	if resp.Body == nil || resp.Body.ModelPreset.ID != "m1" {
+		t.Fatalf("dry run body = %+v, want deleted m1", resp.Body)
+	}
+	if got := resp.Body.ProviderPreset.DefaultModelPresetID; got != "" {
+		t.Fatalf("dry run defaultModelPresetID = %q, want reset", got)
+	}
+
+	pp := getProviderByName(t, st, ctx, name, true)
+	if _, ok := pp.ModelPresets["m1"]; !ok || pp.DefaultModelPresetID != "m1" {
+		t.Fatalf("store changed by dry run: default=%q presets=%v", pp.DefaultModelPresetID, pp.ModelPresets)
+	}
+}
+
+func TestModelPresetStore_DryRunProviderAndEmbeddingWrites(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+	name := inferenceSpec.ProviderName("user-dry-run")
+	postUserProvider(t, st, name, true)
+	postUserModelPreset(t, ctx, st, name, "m1", true)
+
+	before, err := st.GetDefaultProvider(ctx, &spec.GetDefaultProviderRequest{})
+	if err != nil {
+		t.Fatalf("GetDefaultProvider: %v", err)
+	}
+	def, err := st.PatchDefaultProvider(ctx, &spec.PatchDefaultProviderRequest{
+		DryRun: true,
+		Body:   &spec.PatchDefaultProviderRequestBody{DefaultProvider: name},
+	})
+	if err != nil || def.Body == nil || def.Body.DefaultProvider != name {
+		t.Fatalf("PatchDefaultProvider dry run = %+v, %v", def, err)
+	}
+	after, err := st.GetDefaultProvider(ctx, &spec.GetDefaultProviderRequest{})
+	if err != nil || after.Body.DefaultProvider != before.Body.DefaultProvider {
+		t.Fatalf("dry run changed the default provider: %+v, %v", after, err)
+	}
+
+	all, err := st.PatchAllModelPresets(ctx, &spec.PatchAllModelPresetsRequest{
+		ProviderName: name,
+		DryRun:       true,
+		Body:         &spec.PatchAllModelPresetsRequestBody{IsEnabled: false},
+	})
+	if err != nil || !slices.Equal(all.Body.UpdatedModelPresetIDs, []spec.ModelPresetID{"m1"}) {
+		t.Fatalf("PatchAllModelPresets dry run = %+v, %v; want m1", all, err)
+	}
+	if !getProviderByName(t, st, ctx, name, true).ModelPresets["m1"].IsEnabled {
+		t.Fatal("dry run disabled m1")
+	}
+
+	body := &spec.PostEmbeddingPresetRequestBody{
+		DisplayName: "Small", ProviderName: name, ModelName: "text-embedding-3-small", IsEnabled: true,
+	}
+	posted, err := st.PostEmbeddingPreset(ctx, &spec.PostEmbeddingPresetRequest{
+		EmbeddingPresetID: "small", DryRun: true, Body: body,
+	})
+	if err != nil || posted.Body == nil || posted.Body.EmbeddingPreset.ID != "small" {
+		t.Fatalf("PostEmbeddingPreset dry run = %+v, %v", posted, err)
+	}
+	_, err = st.GetEmbeddingPreset(ctx, &spec.GetEmbeddingPresetRequest{EmbeddingPresetID: "small"})
+	wantErrIs(t, err, spec.ErrEmbeddingPresetNotFound)
+
+	for range 2 {
+		if _, err := st.PostEmbeddingPreset(ctx, &spec.PostEmbeddingPresetRequest{
+			EmbeddingPresetID: "small", IdempotencyKey: "post-small", Body: body,
+		}); err != nil {
+			t.Fatalf("PostEmbeddingPreset with idempotency key: %v", err)
+		}
+	}
+
+	patched, err := st.PatchEmbeddingPreset(ctx, &spec.PatchEmbeddingPresetRequest{
+		EmbeddingPresetID: "small",
+		DryRun:            true,
+		Body:              &spec.PatchEmbeddingPresetRequestBody{Dimensions: new(256)},
+	})
+	if err != nil || patched.Body == nil || patched.Body.EmbeddingPreset.Dimensions != 256 {
+		t.Fatalf("PatchEmbeddingPreset dry run = %+v, %v", patched, err)
+	}
+	deleted, err := st.DeleteEmbeddingPreset(ctx, &spec.DeleteEmbeddingPresetRequest{
+		EmbeddingPresetID: "small", DryRun: true,
+	})
+	if err != nil || deleted.Body == nil || deleted.Body.EmbeddingPreset.ID != "small" {
+		t.Fatalf("DeleteEmbeddingPreset dry run = %+v, %v", deleted, err)
+	}
+	got, err := st.GetEmbeddingPreset(ctx, &spec.GetEmbeddingPresetRequest{EmbeddingPresetID: "small"})
+	if err != nil || got.Body.Dimensions != 0 {
+		t.Fatalf("store changed by dry runs: %+v, %v", got, err)
+	}
+}
+
+func TestModelPresetStore_ApplyParameterProfile(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+	name := inferenceSpec.ProviderName("user-profiles")
+	postUserProvider(t, st, name, true)
+	postUserModelPreset(t, ctx, st, name, "m1", true)
+	postUserModelPreset(t, ctx, st, name, "m2", true)
+
+	temp := 0.2
+	stops := []string{"END"}
+	reasoning := inferenceSpec.ReasoningParam{
+		Type:  inferenceSpec.ReasoningTypeSingleWithLevels,
+		Level: inferenceSpec.ReasoningLevelHigh,
+	}
+	if _, err := st.PostParameterProfile(ctx, &spec.PostParameterProfileRequest{
+		ParameterProfileID: "precise",
+		Body: &spec.PostParameterProfileRequestBody{
+			DisplayName:   "Precise",
+			Temperature:   &temp,
+			Reasoning:     &reasoning,
+			StopSequences: &stops,
+		},
+	}); err != nil {
+		t.Fatalf("PostParameterProfile: %v", err)
+	}
+	if _, err := st.PostParameterProfile(ctx, &spec.PostParameterProfileRequest{
+		ParameterProfileID: "precise",
+		Body:               &spec.PostParameterProfileRequestBody{DisplayName: "Again", Temperature: &temp},
+	}); !errors.Is(err, spec.ErrParameterProfileAlreadyExists) {
+		t.Fatalf("duplicate post err = %v, want ErrParameterProfileAlreadyExists", err)
+	}
+
+	builtinName, builtin := anyBuiltInProviderFromStore(t, st)
+	var builtinModel spec.ModelPresetID
+	for id := range builtin.ModelPresets {
+		builtinModel = id
+		break
+	}
+	_, err := st.ApplyParameterProfile(ctx, &spec.ApplyParameterProfileRequest{
+		ParameterProfileID: "precise",
+		Body: &spec.ApplyParameterProfileRequestBody{ModelPresets: []spec.ModelPresetRef{
+			{ProviderName: name, ModelPresetID: "m1"},
+			{ProviderName: builtinName, ModelPresetID: builtinModel},
+		}},
+	})
+	if !errors.Is(err, spec.ErrBuiltInReadOnly) {
+		t.Fatalf("apply to built-in err = %v, want ErrBuiltInReadOnly", err)
+	}
+	if m1 := getProviderByName(t, st, ctx, name, true).ModelPresets["m1"]; m1.StopSequences != nil {
+		t.Fatalf("m1 changed by rejected apply: %+v", m1.StopSequences)
+	}
+
+	profile, err := st.GetParameterProfile(ctx, &spec.GetParameterProfileRequest{ParameterProfileID: "precise"})
+	if err != nil {
+		t.Fatalf("GetParameterProfile: %v", err)
+	}
+	stale := profile.Body.ModifiedAt.Add(-time.Second)
+	both := []spec.ModelPresetRef{{ProviderName: name, ModelPresetID: "m1"}, {ProviderName: name, ModelPresetID: "m2"}}
+	_, err = st.ApplyParameterProfile(ctx, &spec.ApplyParameterProfileRequest{
+		ParameterProfileID: "precise",
+		Body:               &spec.ApplyParameterProfileRequestBody{ModelPresets: both, ExpectedModifiedAt: &stale},
+	})
+	if !errors.Is(err, precondition.ErrConflict) {
+		t.Fatalf("apply with stale expectedModifiedAt err = %v, want ErrConflict", err)
+	}
+
+	preview, err := st.ApplyParameterProfile(ctx, &spec.ApplyParameterProfileRequest{
+		ParameterProfileID: "precise",
+		DryRun:             true,
+		Body: &spec.ApplyParameterProfileRequestBody{
+			ModelPresets: both, ExpectedModifiedAt: &profile.Body.ModifiedAt,
+		},
+	})
+	if err != nil {
+		t.Fatalf("ApplyParameterProfile dry run: %v", err)
+	}
+	if got := preview.Body.ModelPresets; len(got) != 2 || got[0].ID != "m1" ||
+		got[0].Temperature == nil || *got[0].Temperature != temp {
+		t.Fatalf("dry run presets = %+v, want m1 and m2 with the profile applied", got)
+	}
+	if m1 := getProviderByName(t, st, ctx, name, true).ModelPresets["m1"]; m1.StopSequences != nil {
+		t.Fatalf("dry run saved m1: %+v", m1.StopSequences)
+	}
+
+	resp, err := st.ApplyParameterProfile(ctx, &spec.ApplyParameterProfileRequest{
+		ParameterProfileID: "precise",
+		Body:               &spec.ApplyParameterProfileRequestBody{ModelPresets: both},
+	})
+	if err != nil {
+		t.Fatalf("ApplyParameterProfile: %v", err)
+	}
+	if len(resp.Body.ModelPresets) != 0 {
+		t.Fatalf("applied presets returned outside a dry run: %+v", resp.Body.ModelPresets)
+	}
+	if got := resp.Body.UpdatedModelPresets; len(got) != 2 {
+		t.Fatalf("updated = %+v, want m1 and m2", got)
+	}
+	pp := getProviderByName(t, st, ctx, name, true)
+	for _, id := range []spec.ModelPresetID{"m1", "m2"} {
+		mp := pp.ModelPresets[id]
+		if mp.Temperature == nil || *mp.Temperature != temp {
+			t.Fatalf("%s temperature = %v, want %v", id, mp.Temperature, temp)
+		}
+		if mp.Reasoning == nil || mp.Reasoning.Level != inferenceSpec.ReasoningLevelHigh {
+			t.Fatalf("%s reasoning = %+v", id, mp.Reasoning)
+		}
+		if mp.StopSequences == nil || !slices.Equal(*mp.StopSequences, stops) {
+			t.Fatalf("%s stopSequences = %v, want %v", id, mp.StopSequences, stops)
+		}
+	}
+
+	again, err := st.ApplyParameterProfile(ctx, &spec.ApplyParameterProfileRequest{
+		ParameterProfileID: "precise",
+		Body: &spec.ApplyParameterProfileRequestBody{ModelPresets: []spec.ModelPresetRef{
+			{ProviderName: name, ModelPresetID: "m1"},
+		}},
+	})
+	if err != nil || len(again.Body.UpdatedModelPresets) != 0 {
+		t.Fatalf("reapply = %+v, %v; want no updates", again, err)
+	}
+
+	patched, err := st.PatchParameterProfile(ctx, &spec.PatchParameterProfileRequest{
+		ParameterProfileID: "precise",
+		DryRun:             true,
+		Body: &spec.PatchParameterProfileRequestBody{
+			DisplayName: new(spec.ParameterProfileDisplayName("Exact")),
+		},
+	})
+	if err != nil || patched.Body == nil || patched.Body.ParameterProfile.DisplayName != "Exact" {
+		t.Fatalf("PatchParameterProfile dry run = %+v, %v", patched, err)
+	}
+	if _, err := st.DeleteParameterProfile(ctx, &spec.DeleteParameterProfileRequest{
+		ParameterProfileID: "precise",
+		ExpectedModifiedAt: &stale,
+	}); !errors.Is(err, precondition.ErrConflict) {
+		t.Fatalf("delete with stale expectedModifiedAt err = %v, want ErrConflict", err)
+	}
+	if _, err := st.DeleteParameterProfile(ctx, &spec.DeleteParameterProfileRequest{
+		ParameterProfileID: "precise",
+		DryRun:             true,
+	}); err != nil {
+		t.Fatalf("DeleteParameterProfile dry run: %v", err)
+	}
+	if _, err := st.DeleteParameterProfile(ctx, &spec.DeleteParameterProfileRequest{
+		ParameterProfileID: "precise",
+		IdempotencyKey:     "delete-precise",
+	}); err != nil {
+		t.Fatalf("DeleteParameterProfile: %v", err)
+	}
+	if _, err := st.DeleteParameterProfile(ctx, &spec.DeleteParameterProfileRequest{
+		ParameterProfileID: "precise",
+		IdempotencyKey:     "delete-precise",
+	}); err != nil {
+		t.Fatalf("DeleteParameterProfile retry was not replayed: %v", err)
+	}
+	list, err := st.ListParameterProfiles(ctx, &spec.ListParameterProfilesRequest{})
+	if err != nil || len(list.Body.ParameterProfiles) != 0 {
+		t.Fatalf("ListParameterProfiles = %+v, %v; want empty", list, err)
+	}
+	if mp := getProviderByName(t, st, ctx, name, true).ModelPresets["m1"]; mp.Temperature == nil {
+		t.Fatal("deleting the profile reset applied parameters")
+	}
+}
+
+func TestModelPresetStore_SetReasoningBudgetPercent(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+	anthropic := inferenceSpec.ProviderName("user-anthropic")
+	if _, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+		ProviderName: anthropic,
+		Body: &spec.PostProviderPresetRequestBody{
+			DisplayName:              "Anthropic",
+			SDKType:                  inferenceSpec.ProviderSDKTypeAnthropic,
+			IsEnabled:                true,
+			Origin:                   spec.DefaultAnthropicOrigin,
+			ChatCompletionPathPrefix: spec.DefaultAnthropicChatCompletionPrefix,
+			APIKeyHeaderKey:          spec.DefaultAnthropicAuthorizationHeaderKey,
+			DefaultHeaders:           spec.AnthropicDefaultHeaders,
+		},
+	}); err != nil {
+		t.Fatalf("PostProviderPreset: %v", err)
+	}
+	openai := inferenceSpec.ProviderName("user-openai")
+	postUserProvider(t, st, openai, true)
+	for _, name := range []inferenceSpec.ProviderName{anthropic, openai} {
+		postUserModelPreset(t, ctx, st, name, "m1", true)
+		if _, err := st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+			ProviderName:  name,
+			ModelPresetID: "m1",
+			Body: &spec.PatchModelPresetRequestBody{
+				ModelPresetPatch: spec.ModelPresetPatch{MaxOutputLength: new(8000)},
+			},
+		}); err != nil {
+			t.Fatalf("PatchModelPreset(%s maxOutputLength): %v", name, err)
+		}
+	}
+	setPercent := func(
+		name inferenceSpec.ProviderName, percent float64,
+	) (*spec.SetReasoningBudgetPercentResponse, error) {
+		return st.SetReasoningBudgetPercent(ctx, &spec.SetReasoningBudgetPercentRequest{
+			ProviderName:  name,
+			ModelPresetID: "m1",
+			Body:          &spec.SetReasoningBudgetPercentRequestBody{Percent: percent},
+		})
+	}
+
+	resp, err := setPercent(anthropic, 25)
+	if err != nil {
+		t.Fatalf("SetReasoningBudgetPercent(25): %v", err)
+	}
+	if r := resp.Body.ModelPreset.Reasoning; r == nil || r.Type != inferenceSpec.ReasoningTypeHybridWithTokens ||
+		r.Tokens != 2000 {
+		t.Fatalf("reasoning = %+v, want hybridWithTokens 2000", r)
+	}
+	if got := getProviderByName(t, st, ctx, anthropic, true).ModelPresets["m1"].Reasoning; got == nil ||
+		got.Tokens != 2000 {
+		t.Fatalf("stored reasoning = %+v, want 2000 tokens", got)
+	}
+
+	_, err = setPercent(anthropic, 10)
+	wantErrContains(t, err, "Anthropic thinking budget 800 is below the minimum of 1024 tokens")
+	if got := validation.IssuesOf(err).Fields(); !slices.Equal(got, []string{"reasoning.tokens"}) {
+		t.Fatalf("fields = %v, want reasoning.tokens", got)
+	}
+	if _, err := setPercent(anthropic, 100); !errors.Is(err, spec.ErrInvalidDir) {
+		t.Fatalf("percent 100 err = %v, want ErrInvalidDir", err)
+	}
+
+	_, err = st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+		ProviderName:  anthropic,
+		ModelPresetID: "m1",
+		Body: &spec.PatchModelPresetRequestBody{
+			ModelPresetPatch: spec.ModelPresetPatch{MaxOutputLength: new(2000)},
+		},
+	})
+	wantErrContains(t, err, "must be less than maxOutputLength 2000")
+
+	_, err = setPercent(openai, 25)
+	wantErrContains(t, err, "OpenAI reasoning effort is set with levels")
+	if got := validation.IssuesOf(err).Fields(); !slices.Equal(got, []string{"reasoning.type"}) {
+		t.Fatalf("fields = %v, want reasoning.type", got)
+	}
+}
+
+func TestModelPresetStore_ReasoningBudgetCapabilities(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+	postProvider := func(name inferenceSpec.ProviderName, body spec.PostProviderPresetRequestBody) {
+		t.Helper()
+		body.DisplayName, body.IsEnabled = spec.ProviderDisplayName(name), true
+		if _, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+			ProviderName: name,
+			Body:         &body,
+		}); err != nil {
+			t.Fatalf("PostProviderPreset(%s): %v", name, err)
+		}
+	}
+	gemini := inferenceSpec.ProviderName("user-gemini")
+	postProvider(gemini, spec.PostProviderPresetRequestBody{
+		SDKType:                  inferenceSpec.ProviderSDKTypeGoogleGenerateContent,
+		Origin:                   spec.DefaultGoogleGenerateContentOrigin,
+		ChatCompletionPathPrefix: spec.DefaultGoogleGenerateContentPrefix,
+		APIKeyHeaderKey:          spec.DefaultGoogleGenerateContentAPIKeyHeaderKey,
+		DefaultHeaders:           spec.GoogleGenerateContentDefaultHeaders,
+	})
+	anthropic := inferenceSpec.ProviderName("user-anthropic")
+	postProvider(anthropic, spec.PostProviderPresetRequestBody{
+		SDKType:                  inferenceSpec.ProviderSDKTypeAnthropic,
+		Origin:                   spec.DefaultAnthropicOrigin,
+		ChatCompletionPathPrefix: spec.DefaultAnthropicChatCompletionPrefix,
+		APIKeyHeaderKey:          spec.DefaultAnthropicAuthorizationHeaderKey,
+		DefaultHeaders:           spec.AnthropicDefaultHeaders,
+	})
+	post := func(
+		name inferenceSpec.ProviderName, id spec.ModelPresetID, tokens int,
+		override *capabilityoverride.ModelCapabilitiesOverride,
+	) error {
+		_, err := st.PostModelPreset(ctx, &spec.PostModelPresetRequest{
+			ProviderName:  name,
+			ModelPresetID: id,
+			Body: &spec.PostModelPresetRequestBody{
+				Name:        spec.ModelName(id),
+				Slug:        spec.ModelSlug(id),
+				DisplayName: spec.ModelDisplayName(id),
+				IsEnabled:   true,
+				ModelPresetPatch: spec.ModelPresetPatch{
+					Reasoning: &inferenceSpec.ReasoningParam{
+						Type:   inferenceSpec.ReasoningTypeHybridWithTokens,
+						Tokens: tokens,
+					},
+					CapabilitiesOverride: override,
+				},
+			},
+		})
+		return err
+	}
+
+	// Gemini turns thinking off with 0 and makes it dynamic with -1.
+	for i, tokens := range []int{0, -1, 32768} {
+		if err := post(gemini, spec.ModelPresetID("g"+strconv.Itoa(i)), tokens, nil); err != nil {
+			t.Fatalf("gemini budget %d: %v", tokens, err)
+		}
+	}
+
+	flash := &capabilityoverride.ModelCapabilitiesOverride{
+		ReasoningCapabilities: &capabilityoverride.ReasoningCapabilitiesOverride{
+			HybridTokenBudgetCapabilities: &capabilityoverride.ReasoningTokenBudgetCapabilitiesOverride{
+				MinAllowed: new(1),
+				MaxAllowed: new(24576),
+			},
+		},
+	}
+	wantErrContains(t, post(gemini, "flash-max", 30000, flash),
+		"Gemini thinking budget 30000 is above the maximum of 24576 tokens")
+	// The override does not allow 0, so the model cannot turn thinking off.
+	wantErrContains(t, post(gemini, "flash-off", 0, flash), "Gemini thinking budget 0 is below the minimum of 1")
+	if err := post(gemini, "flash", 24576, flash); err != nil {
+		t.Fatalf("flash budget at the maximum: %v", err)
+	}
+
+	wantErrContains(t, post(anthropic, "a0", 0, nil), "Anthropic thinking budget 0 is below the minimum of 1024")
+	wantErrContains(t, post(anthropic, "a1", -1, nil), "Anthropic thinking budget -1 is below the minimum of 1024")
+}
+
+func TestModelPresetStore_ProviderTLS(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+	name := inferenceSpec.ProviderName("user-gateway")
+	postUserProvider(t, st, name, true)
+	patchTLS := func(c spec.ProviderTLSConfig) error {
+		_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+			ProviderName: name,
+			Body:         &spec.PatchProviderPresetRequestBody{TLS: &c},
+		})
+		return err
+	}
+
+	want := spec.ProviderTLSConfig{
+		CABundlePath:      filepath.Join(t.TempDir(), "ca.pem"),
+		ClientCertKeyName: "gateway-cert",
+		ClientKeyKeyName:  "gateway-key",
+	}
+	if err := patchTLS(want); err != nil {
+		t.Fatalf("PatchProviderPreset(tls): %v", err)
+	}
+	if got := getProviderByName(t, st, ctx, name, true).TLS; got == nil || *got != want {
+		t.Fatalf("tls = %+v, want %+v", got, want)
+	}
+
+	wantErrContains(t, patchTLS(spec.ProviderTLSConfig{ClientCertKeyName: "gateway-cert"}),
+		"clientCertKeyName and clientKeyKeyName must be set together")
+	wantErrContains(t, patchTLS(spec.ProviderTLSConfig{CABundlePath: "ca.pem"}), "must be absolute")
+
+	if err := patchTLS(spec.ProviderTLSConfig{}); err != nil {
+		t.Fatalf("PatchProviderPreset(clear tls): %v", err)
+	}
+	if got := getProviderByName(t, st, ctx, name, true).TLS; got != nil {
+		t.Fatalf("tls = %+v, want cleared", got)
+	}
+
+	builtinName, _ := anyBuiltInProviderFromStore(t, st)
+	_, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+		ProviderName: builtinName,
+		Body:         &spec.PatchProviderPresetRequestBody{TLS: &want},
+	})
+	if !errors.Is(err, spec.ErrBuiltInReadOnly) {
+		t.Fatalf("built-in tls err = %v, want ErrBuiltInReadOnly", err)
+	}
+}
+
+func TestModelPresetStore_ListProviderPresets_PageTokenOverridesRequestParams(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+
+	p2 := inferenceSpec.ProviderName("user-override-2") // disabled, older
+	p1 := inferenceSpec.ProviderName("user-override-1") // enabled, newer
+
+	postUserProvider(t, st, p2, false)
+	time.Sleep(2 * time.Millisecond)
+	postUserProvider(t, st, p1, true)
+
+	resp1, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
+		Names:           []inferenceSpec.ProviderName{p1, p2},
+		IncludeDisabled: true,
+		PageSize:        1,
+	})
+	if err != nil {
+		t.Fatalf("ListProviderPresets(page1): %v", err)
+	}
+	if len(resp1.Body.Providers) != 1 {
+		t.Fatalf("expected 1 provider on page1, got %d", len(resp1.Body.Providers))
+	}
+	if resp1.Body.Providers[0].Name != p1 {
+		t.Fatalf("expected page1 to return %q first, got %q", p1, resp1.Body.Providers[0].Name)
+	}
+	if resp1.Body.NextPageToken == nil {
+		t.Fatalf("expected next page token")
+	}
+
+	// Intentionally provide conflicting params; token must win.
+	resp2, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
+		PageToken:       *resp1.Body.NextPageToken,
+		Names:           []inferenceSpec.ProviderName{p1}, // would exclude p2 if not overridden
+		IncludeDisabled: false,                            // would exclude disabled if not overridden
+		PageSize:        999,
+	})
+	if err != nil {
+		t.Fatalf("ListProviderPresets(page2): %v", err)
+	}
+	if len(resp2.Body.Providers) != 1 {
+		t.Fatalf("expected 1 provider on page2, got %d", len(resp2.Body.Providers))
+	}
+	if resp2.Body.Providers[0].Name != p2 {
+		t.Fatalf("expected page2 to return %q due to token override, got %q", p2, resp2.Body.Providers[0].Name)
+	}
+	if resp2.Body.Providers[0].IsEnabled {
+		t.Fatalf("expected returned provider to be disabled")
+	}
+}
+
+func TestModelPresetStore_ListProviderPresets_Base64ButInvalidJSONToken_Ignored(t *testing.T) {
+	st := newStore(t)
+	ctx := t.Context()
+
+	disabled := inferenceSpec.ProviderName("user-disabled-token-test")
+	postUserProvider(t, st, disabled, false)
+
+	// Base64-valid, but NOT JSON.
+	token := base64.StdEncoding.EncodeToString([]byte("not-json"))
+
+	resp, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
+		PageToken: token,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for _, p := range resp.Body.Providers {
+		if p.Name == disabled {
+			t.Fatalf("expected disabled provider to be filtered out when token is ignored")
+		}
+	}
+}
+
+func TestModelPresetStore_UserData_PersistsAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	st := newStoreAtDir(t, dir)
+	ctx := t.Context()
+
+	prov := inferenceSpec.ProviderName("user-persist-prov")
+	postUserProvider(t, st, prov, true)
+	postUserModelPreset(t, ctx, st, prov, "m1", true)
+
+	_, err := st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+		ProviderName:  prov,
+		ModelPresetID: "m1",
+		Body:          &spec.PatchModelPresetRequestBody{IsEnabled: new(false)},
+	})
+	if err != nil {
+		t.Fatalf("PatchModelPreset: %v", err)
+	}
+
+	_, err = st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+		ProviderName: prov,
+		Body: &spec.PatchProviderPresetRequestBody{
+			DefaultModelPresetID: mpidPtr("m1"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("PatchProviderPreset: %v", err)
+	}
+
+	closeAndSleepOnWindows(t, st)
+
+	st2 := newStoreAtDir(t, dir)
+
+	pp := getProviderByName(t, st2, ctx, prov, true)
+	if pp.DefaultModelPresetID != "m1" {
+		t.Fatalf("defaultModelPresetID not persisted: got=%q want=m1", pp.DefaultModelPresetID)
+	}
+	m1 := pp.ModelPresets["m1"]
+	if m1.ID != "m1" {
+		t.Fatalf("model not persisted")
+	}
+	if m1.IsEnabled != false {
+		t.Fatalf("model enabled flag not persisted: got=%v want=false", m1.IsEnabled)
+	}
+}
+
+func TestModelPresetStore_UserData_ReloadsExternalModification(t *testing.T) {
+	dir := t.TempDir()
+	st := newStoreAtDir(t, dir)
+	ctx := t.Context()
+
+	prov := inferenceSpec.ProviderName("user-ext-prov")
+	postUserProvider(t, st, prov, true)
+
+	// A second store on the same directory stands in for an external editor.
+	other := newStoreAtDir(t, dir)
+	postUserModelPreset(t, ctx, other, prov, "m-ext", true)
+
+	pp := getProviderByName(t, st, ctx, prov, true)
+	if _, ok := pp.ModelPresets["m-ext"]; !ok {
+		t.Fatalf("external modification not reloaded: models=%v", slices.Collect(maps.Keys(pp.ModelPresets)))
+	}
+
+	// In-app writes apply on top of the external modification.
+	postUserModelPreset(t, ctx, st, prov, "m-app", true)
+	pp = getProviderByName(t, other, ctx, prov, true)
+	for _, id := range []spec.ModelPresetID{"m-ext", "m-app"} {
+		if _, ok := pp.ModelPresets[id]; !ok {
+			t.Fatalf("model %q missing after in-app write", id)
+		}
+	}
+}
+
+func TestModelPresetStore_UserData_ExternalModificationDuringWrite(t *testing.T) {
+	dir := t.TempDir()
+	st := newStoreAtDir(t, dir)
+	ctx := t.Context()
+
+	prov := inferenceSpec.ProviderName("user-ext-prov")
+	postUserProvider(t, st, prov, true)
+	other := newStoreAtDir(t, dir)
+
+	st.mu.Lock()
+	all, err := st.readAllUserPresets()
+	if err == nil {
+		postUserModelPreset(t, ctx, other, prov, "m-ext", true)
+		err = st.writeAllUserPresets(all)
+	}
+	st.mu.Unlock()
+	if !errors.Is(err, fsutil.ErrExternalModification) {
+		t.Fatalf("expected external modification, got %v", err)
+	}
+
+	// A retried mutation reloads the file and keeps the external edit.
+	postUserModelPreset(t, ctx, st, prov, "m-app", true)
+	pp := getProviderByName(t, other, ctx, prov, true)
+	for _, id := range []spec.ModelPresetID{"m-ext", "m-app"} {
+		if _, ok := pp.ModelPresets[id]; !ok {
+			t.Fatalf("model %q missing after retried write", id)
+		}
+	}
+}
+
+func TestModelPresetStore_FileWatch_ReloadedEvent(t *testing.T) {
+	dir := t.TempDir()
+	ctx := t.Context()
+	st, err := NewModelPresetStore(dir, WithFileWatch(true))
+	if err != nil {
+		t.Fatalf("NewModelPresetStore: %v", err)
+	}
+	t.Cleanup(func() { closeAndSleepOnWindows(t, st) })
+
+	reloaded := make(chan struct{}, 8)
+	st.Subscribe(func(ev spec.PresetChangeEvent) {
+		if ev.Kind == spec.PresetChangeReloaded {
+			reloaded <- struct{}{}
+		}
+	})
+
+	// Writes of the store itself are not external changes.
+	prov := inferenceSpec.ProviderName("user-watch-prov")
+	postUserProvider(t, st, prov, true)
+	select {
+	case <-reloaded:
+		t.Fatal("in-app write reported as external change")
+	case <-time.After(4 * fileWatchDebouncePresets):
+	}
+
+	other := newStoreAtDir(t, dir)
+	postUserModelPreset(t, ctx, other, prov, "m-ext", true)
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reloaded event")
+	}
+}
+
+func TestModelPresetStore_RestoreFromBackup(t *testing.T) {
+	dir := t.TempDir()
+	ctx := t.Context()
+	st, err := NewModelPresetStore(dir, WithBackups(fsutil.BackupPolicy{MaxCopies: 5}))
+	if err != nil {
+		t.Fatalf("NewModelPresetStore: %v", err)
+	}
+	t.Cleanup(func() { closeAndSleepOnWindows(t, st) })
+
+	prov := inferenceSpec.ProviderName("user-backup-prov")
+	postUserProvider(t, st, prov, true)
+	postUserModelPreset(t, ctx, st, prov, "m1", true)
+	postUserModelPreset(t, ctx, st, prov, "m2", true)
+	listed, err := st.ListModelPresetStoreBackups(ctx, &spec.ListModelPresetStoreBackupsRequest{})
+	if err != nil || len(listed.Body.Backups) < 2 {
+		t.Fatalf("ListModelPresetStoreBackups: %+v, %v", listed, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, spec.ModelPresetsFile), []byte("{corrupt"), 0o600); err != nil {
+		t.Fatalf("corrupt presets file: %v", err)
+	}
+	if _, err := st.RestoreModelPresetStoreFromBackup(ctx, &spec.RestoreModelPresetStoreFromBackupRequest{
+		Body: &spec.RestoreModelPresetStoreFromBackupRequestBody{Name: listed.Body.Backups[0].Name},
+	}); err != nil {
+		t.Fatalf("RestoreModelPresetStoreFromBackup: %v", err)
+	}
+	pp := getProviderByName(t, st, ctx, prov, true)
+	if _, ok := pp.ModelPresets["m1"]; !ok {
+		t.Fatal("restored presets lack m1")
+	}
+	if _, ok := pp.ModelPresets["m2"]; ok {
+		t.Fatal("restored presets have m2, written after the backup")
+	}
+
+	// The corrupt file was backed up too and cannot be restored.
+	listed, err = st.ListModelPresetStoreBackups(ctx, &spec.ListModelPresetStoreBackupsRequest{})
+	if err != nil {
+		t.Fatalf("ListModelPresetStoreBackups: %v", err)
+	}
+	if _, err := st.RestoreModelPresetStoreFromBackup(ctx, &spec.RestoreModelPresetStoreFromBackupRequest{
+		Body: &spec.RestoreModelPresetStoreFromBackupRequestBody{Name: listed.Body.Backups[0].Name},
+	}); err == nil {
+		t.Fatal("restored a corrupt backup")
+	}
+}
+
+func TestModelPresetStore_RetryWritableBacksUp(t *testing.T) {
+	dir := t.TempDir()
+	ctx := t.Context()
+	st, err := NewModelPresetStore(dir, WithBackups(fsutil.BackupPolicy{MaxCopies: 5}))
+	if err != nil {
+		t.Fatalf("NewModelPresetStore: %v", err)
+	}
+	t.Cleanup(func() { closeAndSleepOnWindows(t, st) })
+
+	prov := inferenceSpec.ProviderName("user-retry-prov")
+	postUserProvider(t, st, prov, true)
+	backups := func() int {
+		t.Helper()
+		listed, err := st.ListModelPresetStoreBackups(ctx, &spec.ListModelPresetStoreBackupsRequest{})
+		if err != nil {
+			t.Fatalf("ListModelPresetStoreBackups: %v", err)
+		}
+		return len(listed.Body.Backups)
+	}
+	before := backups()
+
+	resp, err := st.RetryModelPresetStoreWritable(ctx, &spec.RetryModelPresetStoreWritableRequest{})
+	if err != nil {
+		t.Fatalf("RetryModelPresetStoreWritable: %v", err)
+	}
+	if resp.Body.WriteStatus.ReadOnly {
+		t.Fatalf("write status = %+v", resp.Body.WriteStatus)
+	}
+	if got := backups(); got != before+1 {
+		t.Fatalf("backups = %d, want %d", got, before+1)
+	}
+	getProviderByName(t, st, ctx, prov, true)
+}
+
+func TestModelPresetStore_UserData_SameStatEditFailsWrite(t *testing.T) {
+	dir := t.TempDir()
+	st := newStoreAtDir(t, dir)
+	ctx := t.Context()
+
+	prov := inferenceSpec.ProviderName("user-stat-prov")
+	postUserProvider(t, st, prov, true)
+	path := filepath.Join(dir, spec.ModelPresetsFile)
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An edit that keeps the size and mtime is invisible to a stat. It is
+	// renamed into place so that the startup sweep cannot stat it halfway.
+	edited := strings.Replace(string(data), "USER-STAT-PROV", "USER-STAT-EDIT", 1)
+	tmp := path + ".edit"
+	if err := os.WriteFile(tmp, []byte(edited), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tmp, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{}); err != nil {
+		t.Fatalf("ListProviderPresets: %v", err)
+	}
+	if got, _ := os.Stat(path); !got.ModTime().Equal(fi.ModTime()) {
+		t.Fatal("read touched the presets file")
+	}
+
+	_, err = st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+		ProviderName: "user-stat-other",
+		Body: &spec.PostProviderPresetRequestBody{
+			DisplayName:              "OTHER",
+			SDKType:                  inferenceSpec.ProviderSDKTypeOpenAIChatCompletions,
+			Origin:                   "https://api.other.example.test",
+			ChatCompletionPathPrefix: spec.DefaultOpenAIChatCompletionsPrefix,
+		},
+	})
+	if !errors.Is(err, fsutil.ErrExternalModification) {
+		t.Fatalf("write over a same-stat edit = %v, want ErrExternalModification", err)
+	}
+
+	// The retry reads the edit and keeps it.
+	postUserProvider(t, st, "user-stat-other", true)
+	if got := getProviderByName(t, st, ctx, prov, true).DisplayName; got != "USER-STAT-EDIT" {
+		t.Fatalf("displayName = %q, want the external edit", got)
+	}
+}
+
+func TestModelPresetStore_Migrate(t *testing.T) {
+	dir := t.TempDir()
+	ctx := t.Context()
+	file := filepath.Join(dir, spec.ModelPresetsFile)
+
+	st := newStoreAtDir(t, dir)
+	prov := inferenceSpec.ProviderName("legacy-prov")
+	postUserProvider(t, st, prov, true)
+	postUserModelPreset(t, ctx, st, prov, "m1", true)
+	closeAndSleepOnWindows(t, st)
+
+	rewrite := func(edit func(raw map[string]any)) {
+		t.Helper()
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		var raw map[string]any
+		if err := json.Unmarshal(data, &raw); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		edit(raw)
+		data, err = json.Marshal(raw)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		if err := os.WriteFile(file, data, 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	// Strip every schemaVersion, as in files written before versioning.
+	rewrite(func(raw map[string]any) {
+		delete(raw, "schemaVersion")
+		for _, pv := range raw["providerPresets"].(map[string]any) {
+			pp := pv.(map[string]any)
+			delete(pp, "schemaVersion")
+			for _, mv := range pp["modelPresets"].(map[string]any) {
+				delete(mv.(map[string]any), "schemaVersion")
+			}
+		}
+	})
+
+	st2 := newStoreAtDir(t, dir)
+	pp := getProviderByName(t, st2, ctx, prov, true)
+	if pp.SchemaVersion != spec.SchemaVersion || pp.ModelPresets["m1"].SchemaVersion != spec.SchemaVersion {
+		t.Fatalf("migrated versions provider=%q model=%q",
+			pp.SchemaVersion, pp.ModelPresets["m1"].SchemaVersion)
+	}
+	mustFileExists(t, filepath.Join(dir, "modelpresets.unversioned.bak.json"))
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var migrated map[string]any
+	if err := json.Unmarshal(data, &migrated); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if migrated["schemaVersion"] != spec.SchemaVersion {
+		t.Fatalf("migrated file schemaVersion=%v", migrated["schemaVersion"])
+	}
+	closeAndSleepOnWindows(t, st2)
+
+	// A file from a newer app is refused rather than rewritten.
+	rewrite(func(raw map[string]any) { raw["schemaVersion"] = "2999-01-01" })
+	if _, err := NewModelPresetStore(dir); !errors.Is(err, spec.ErrUnsupportedSchemaVersion) {
+		t.Fatalf("NewModelPresetStore with newer file err=%v", err)
+	}
+}
+
+func TestModelPresetStore_ValidateProviderPreset(t *testing.T) {
+	ctx := t.Context()
+	st := newStore(t)
+
+	t.Run("stored-provider-is-valid", func(t *testing.T) {
+		postUserProvider(t, st, "valid-prov", true)
+		postUserModelPreset(t, ctx, st, "valid-prov", "m1", true)
+		out, err := st.ValidateProviderPreset(ctx, &spec.ValidateProviderPresetRequest{
+			ProviderName: "valid-prov",
+		})
+		if err != nil {
+			t.Fatalf("ValidateProviderPreset: %v", err)
+		}
+		if !out.Body.Valid {
+			t.Fatalf("expected valid, issues=%+v", out.Body.Issues)
+		}
+	})
+
+	t.Run("candidate-reports-every-issue", func(t *testing.T) {
+		temp := 3.0
+		out, err := st.ValidateProviderPreset(ctx, &spec.ValidateProviderPresetRequest{
+			ProviderName: "draft",
+			Body: &spec.ValidateProviderPresetRequestBody{
+				Preset: &spec.ProviderPreset{
+					SchemaVersion:            spec.SchemaVersion,
+					DisplayName:              "Draft",
+					SDKType:                  inferenceSpec.ProviderSDKTypeOpenAIChatCompletions,
+					Origin:                   "ftp://api.example.test",
+					ChatCompletionPathPrefix: "v1/chat/completions/",
+					APIKeyHeaderKey:          "Bad Header",
+					DefaultHeaders:           map[string]string{"X-Trace": "a\r\nb"},
+					DefaultModelPresetID:     "missing",
+					ModelPresets: map[spec.ModelPresetID]spec.ModelPreset{
+						"no-knobs": {
+							SchemaVersion: spec.SchemaVersion,
+							ID:            "no-knobs",
+							Name:          "no-knobs",
+							DisplayName:   "No knobs",
+							Slug:          "no-knobs",
+						},
+						"hot": {
+							SchemaVersion:    spec.SchemaVersion,
+							ID:               "hot",
+							Name:             "hot",
+							DisplayName:      "Hot",
+							Slug:             "hot",
+							ModelPresetPatch: spec.ModelPresetPatch{Temperature: &temp},
+						},
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("ValidateProviderPreset: %v", err)
+		}
+		if out.Body.Valid {
+			t.Fatal("expected invalid report")
+		}
+		want := map[string]validation.Severity{
+			"origin":                            validation.SeverityError,
+			"chatCompletionPathPrefix":          validation.SeverityError,
+			"apiKeyHeaderKey":                   validation.SeverityError,
+			"defaultHeaders.X-Trace":            validation.SeverityError,
+			"defaultModelPresetID":              validation.SeverityError,
+			"modelPresets.no-knobs.temperature": validation.SeverityError,
+			"modelPresets.hot.temperature":      validation.SeverityWarning,
+		}
+		got := map[string]validation.Severity{}
+		for _, issue := range out.Body.Issues {
+			if _, seen := got[issue.Field]; !seen || issue.Severity == validation.SeverityError {
+				got[issue.Field] = issue.Severity
+			}
+		}
+		for field, sev := range want {
+			if got[field] != sev {
+				t.Errorf("field %q severity=%q want %q (issues=%+v)", field, got[field], sev, out.Body.Issues)
+			}
+		}
+	})
+
+	t.Run("unknown-provider", func(t *testing.T) {
+		_, err := st.ValidateProviderPreset(ctx, &spec.ValidateProviderPresetRequest{
+			ProviderName: "nope",
+		})
+		wantErrIs(t, err, spec.ErrProviderNotFound)
+	})
+
+	t.Run("reachability", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		origin := srv.URL
+		srv.Close()
+
+		postUserProvider(t, st, "gone-prov", true)
+		postUserModelPreset(t, ctx, st, "gone-prov", "m1", true)
+		if _, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+			ProviderName: "gone-prov",
+			Body:         &spec.PatchProviderPresetRequestBody{Origin: &origin},
+		}); err != nil {
+			t.Fatalf("PatchProviderPreset: %v", err)
+		}
+		out, err := st.ValidateProviderPreset(ctx, &spec.ValidateProviderPresetRequest{
+			ProviderName: "gone-prov",
+			Body:         &spec.ValidateProviderPresetRequestBody{CheckReachability: true},
+		})
+		if err != nil {
+			t.Fatalf("ValidateProviderPreset: %v", err)
+		}
+		if !out.Body.Valid {
+			t.Fatalf("unreachable origin must only warn, issues=%+v", out.Body.Issues)
+		}
+		found := false
+		for _, issue := range out.Body.Issues {
+			if issue.Field == "origin" && strings.Contains(issue.Message, "unreachable") {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("missing reachability warning, issues=%+v", out.Body.Issues)
+		}
+	})
+
+	t.Run("reachability-uses-provider-transport", func(t *testing.T) {
+		var used []inferenceSpec.ProviderName
+		st.SetProviderTransport(func(provider inferenceSpec.ProviderName) http.RoundTripper {
+			used = append(used, provider)
+			return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+			})
+		})
+		defer st.SetProviderTransport(nil)
+
+		out, err := st.ValidateProviderPreset(ctx, &spec.ValidateProviderPresetRequest{
+			ProviderName: "valid-prov",
+			Body:         &spec.ValidateProviderPresetRequestBody{CheckReachability: true},
+		})
+		if err != nil {
+			t.Fatalf("ValidateProviderPreset: %v", err)
+		}
+		if !out.Body.Valid || len(out.Body.Issues) != 0 {
+			t.Fatalf("issues=%+v", out.Body.Issues)
+		}
+		if !slices.Equal(used, []inferenceSpec.ProviderName{"valid-prov"}) {
+			t.Fatalf("transport used for %v", used)
+		}
+	})
+
+	t.Run("writes-agree-with-report", func(t *testing.T) {
+		body := &spec.PostProviderPresetRequestBody{
+			DisplayName:              "Agree",
+			SDKType:                  inferenceSpec.ProviderSDKTypeOpenAIChatCompletions,
+			Origin:                   "ftp://api.example.test",
+			ChatCompletionPathPrefix: spec.DefaultOpenAIChatCompletionsPrefix,
+			APIKeyHeaderKey:          spec.DefaultAuthorizationHeaderKey,
+			DefaultHeaders:           map[string]string{"X-Sig": "{{bogus}}"},
+			RateLimits:               &spec.ProviderRateLimits{RequestsPerMinute: -1},
+		}
+		_, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{ProviderName: "agree", Body: body})
+		gotFields := validation.IssuesOf(err).Fields()
+		wantFields := []string{"origin", "defaultHeaders.X-Sig", "rateLimits"}
+		if !slices.Equal(gotFields, wantFields) {
+			t.Fatalf("post issues = %v (err %v), want %v", gotFields, err, wantFields)
+		}
+
+		out, err := st.ValidateProviderPreset(ctx, &spec.ValidateProviderPresetRequest{
+			ProviderName: "agree",
+			Body: &spec.ValidateProviderPresetRequestBody{Preset: &spec.ProviderPreset{
+				SchemaVersion:            spec.SchemaVersion,
+				DisplayName:              body.DisplayName,
+				SDKType:                  body.SDKType,
+				Origin:                   body.Origin,
+				ChatCompletionPathPrefix: body.ChatCompletionPathPrefix,
+				APIKeyHeaderKey:          body.APIKeyHeaderKey,
+				DefaultHeaders:           body.DefaultHeaders,
+				RateLimits:               body.RateLimits,
+			}},
+		})
+		if err != nil {
+			t.Fatalf("ValidateProviderPreset: %v", err)
+		}
+		var reported []string
+		for _, issue := range out.Body.Issues {
+			if issue.Severity == validation.SeverityError {
+				reported = append(reported, issue.Field)
+			}
+		}
+		if !slices.Equal(reported, wantFields) {
+			t.Fatalf("report errors = %v, want %v", reported, wantFields)
+		}
+	})
+
+	t.Run("unknown-sdk-type-warns", func(t *testing.T) {
+		out, err := st.ValidateProviderPreset(ctx, &spec.ValidateProviderPresetRequest{
+			ProviderName: "future",
+			Body: &spec.ValidateProviderPresetRequestBody{Preset: &spec.ProviderPreset{
+				SchemaVersion:            spec.SchemaVersion,
+				DisplayName:              "Future",
+				SDKType:                  "futureSDK",
+				Origin:                   "https://api.future.example.test",
+				ChatCompletionPathPrefix: "/v1/generate",
+				APIKeyHeaderKey:          spec.DefaultAuthorizationHeaderKey,
+			}},
+		})
+		if err != nil {
+			t.Fatalf("ValidateProviderPreset: %v", err)
+		}
+		if !out.Body.Valid {
+			t.Fatalf("unknown sdkType must only warn, issues=%+v", out.Body.Issues)
+		}
+	})
+}
+
+func TestModelPresetStore_DiscoverProviderModels(t *testing.T) {
+	var gotPath, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[
+			{"id":"m-existing"},
+			{"id":"gpt-x.1","display_name":"GPT X"},
+			{"id":"org/model:latest"},
+			{"id":"9b-chat"}
+		]}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	st := newStore(t)
+	ctx := t.Context()
+	prov := inferenceSpec.ProviderName("user-discover")
+	_, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+		ProviderName: prov,
+		Body: &spec.PostProviderPresetRequestBody{
+			DisplayName:              "Discover",
+			SDKType:                  inferenceSpec.ProviderSDKTypeOpenAIChatCompletions,
+			IsEnabled:                true,
+			Origin:                   srv.URL,
+			ChatCompletionPathPrefix: spec.DefaultOpenAIChatCompletionsPrefix,
+			APIKeyHeaderKey:          spec.DefaultAuthorizationHeaderKey,
+		},
+	})
+	if err != nil {
+		t.Fatalf("PostProviderPreset: %v", err)
+	}
+	postUserModelPreset(t, ctx, st, prov, "m-existing", true)
+
+	t.Run("candidates", func(t *testing.T) {
+		resp, err := st.DiscoverProviderModels(ctx, &spec.DiscoverProviderModelsRequest{
+			ProviderName: prov,
+			Body:         &spec.DiscoverProviderModelsRequestBody{APIKey: "sk-test"},
+		})
+		if err != nil {
+			t.Fatalf("DiscoverProviderModels: %v", err)
+		}
+		if gotPath != "/v1/models" {
+			t.Fatalf("path=%q want /v1/models", gotPath)
+		}
+		if gotAuth != "Bearer sk-test" {
+			t.Fatalf("authorization=%q", gotAuth)
+		}
+
+		got := map[spec.ModelName]spec.DiscoveredModelPreset{}
+		for _, m := range resp.Body.Models {
+			got[m.ModelPreset.Name] = m
+		}
+		if len(got) != 4 {
+			t.Fatalf("got %d candidates, want 4", len(got))
+		}
+		if !got["m-existing"].Exists {
+			t.Fatalf("m-existing should be marked existing")
+		}
+		if c := got["gpt-x.1"]; c.Exists || c.ModelPreset.ID != "gpt-x-1" || c.ModelPreset.DisplayName != "GPT X" {
+			t.Fatalf("unexpected candidate: %+v", c)
+		}
+		if id := got["org/model:latest"].ModelPreset.ID; id != "org-model-latest" {
+			t.Fatalf("sanitized id=%q", id)
+		}
+		if id := got["9b-chat"].ModelPreset.ID; id != "m-9b-chat" {
+			t.Fatalf("sanitized id=%q", id)
+		}
+		for name, c := range got {
+			if c.ModelPreset.Temperature != nil || c.ModelPreset.Reasoning != nil {
+				t.Fatalf("candidate %q sets sampling: %+v", name, c.ModelPreset)
+			}
+		}
+		if len(resp.Body.Accepted) != 0 {
+			t.Fatalf("nothing should be accepted without acceptAll")
+		}
+	})
+
+	t.Run("accept-all", func(t *testing.T) {
+		resp, err := st.DiscoverProviderModels(ctx, &spec.DiscoverProviderModelsRequest{
+			ProviderName: prov,
+			Body:         &spec.DiscoverProviderModelsRequestBody{AcceptAll: true},
+		})
+		if err != nil {
+			t.Fatalf("DiscoverProviderModels(acceptAll): %v", err)
+		}
+		if len(resp.Body.Accepted) != 3 {
+			t.Fatalf("accepted=%v want 3", resp.Body.Accepted)
+		}
+		pp := getProviderByName(t, st, ctx, prov, true)
+		for _, id := range []spec.ModelPresetID{"m-existing", "gpt-x-1", "org-model-latest", "m-9b-chat"} {
+			if _, ok := pp.ModelPresets[id]; !ok {
+				t.Fatalf("model %q missing after accept", id)
+			}
+		}
+		if mp := pp.ModelPresets["gpt-x-1"]; mp.Temperature != nil {
+			t.Fatalf("accepted preset has temperature %v", *mp.Temperature)
+		}
+		// Presets without sampling stay editable.
+		if _, err := st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+			ProviderName:  prov,
+			ModelPresetID: "gpt-x-1",
+			Body:          &spec.PatchModelPresetRequestBody{IsEnabled: new(false)},
+		}); err != nil {
+			t.Fatalf("PatchModelPreset(discovered): %v", err)
+		}
+
+		// A second run has nothing new to accept.
+		resp, err = st.DiscoverProviderModels(ctx, &spec.DiscoverProviderModelsRequest{
+			ProviderName: prov,
+			Body:         &spec.DiscoverProviderModelsRequestBody{AcceptAll: true},
+		})
+		if err != nil {
+			t.Fatalf("DiscoverProviderModels(acceptAll again): %v", err)
+		}
+		if len(resp.Body.Accepted) != 0 {
+			t.Fatalf("accepted=%v want none", resp.Body.Accepted)
+		}
+	})
+
+	t.Run("uses-provider-transport", func(t *testing.T) {
+		var used []inferenceSpec.ProviderName
+		st.SetProviderTransport(func(provider inferenceSpec.ProviderName) http.RoundTripper {
+			used = append(used, provider)
+			return http.DefaultTransport
+		})
+		defer st.SetProviderTransport(nil)
+
+		if _, err := st.DiscoverProviderModels(ctx, &spec.DiscoverProviderModelsRequest{
+			ProviderName: prov,
+		}); err != nil {
+			t.Fatalf("DiscoverProviderModels: %v", err)
+		}
+		if len(used) != 1 || used[0] != prov {
+			t.Fatalf("transport used for %v, want [%s]", used, prov)
+		}
+	})
+
+	t.Run("builtin-accept-all-read-only", func(t *testing.T) {
+		pn, _ := anyBuiltInProviderFromStore(t, st)
+		_, err := st.DiscoverProviderModels(ctx, &spec.DiscoverProviderModelsRequest{
+			ProviderName: pn,
+			Body:         &spec.DiscoverProviderModelsRequestBody{AcceptAll: true},
+		})
+		if !errors.Is(err, spec.ErrBuiltInReadOnly) {
+			t.Fatalf("err=%v want ErrBuiltInReadOnly", err)
+		}
+	})
+
+	t.Run("unknown-provider", func(t *testing.T) {
+		_, err := st.DiscoverProviderModels(ctx, &spec.DiscoverProviderModelsRequest{
+			ProviderName: "nope",
+		})
+		if !errors.Is(err, spec.ErrProviderNotFound) {
+			t.Fatalf("err=%v want ErrProviderNotFound", err)
+		}
+	})
+}
+
+func TestModelPresetStore_ProbeOrigin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("api-key") != "gw-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/openai/v1/models":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"gpt-b"},{"id":"gpt-a"},{"id":"gpt-b"}]}`))
+		case "/openai/v1/chat/completions":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	st := newStore(t)
+	ctx := t.Context()
+
+	t.Run("detects_root_and_header", func(t *testing.T) {
+		resp, err := st.ProbeOrigin(ctx, &spec.ProbeOriginRequest{
+			Body: &spec.ProbeOriginRequestBody{BaseURL: srv.URL + "/openai/", APIKey: "gw-key"},
+		})
+		if err != nil {
+			t.Fatalf("ProbeOrigin: %v", err)
+		}
+		found := map[spec.ProbeEndpointKind]bool{}
+		for _, ep := range resp.Body.Endpoints {
+			found[ep.Kind] = ep.Found
+		}
+		if !found[spec.ProbeEndpointChatCompletions] || !found[spec.ProbeEndpointModels] ||
+			found[spec.ProbeEndpointEmbeddings] {
+			t.Fatalf("endpoints=%+v", resp.Body.Endpoints)
+		}
+		if !slices.Equal(resp.Body.Models, []spec.ModelName{"gpt-a", "gpt-b"}) {
+			t.Fatalf("models=%v", resp.Body.Models)
+		}
+		body := resp.Body.Provider
+		if body.Origin != srv.URL || body.ChatCompletionPathPrefix != "/openai/v1/chat/completions" ||
+			body.APIKeyHeaderKey != spec.DefaultAzureOpenAIAPIKeyHeaderKey {
+			t.Fatalf("provider=%+v", body)
+		}
+		if _, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+			ProviderName: "user-probed",
+			DryRun:       true,
+			Body:         &body,
+		}); err != nil {
+			t.Fatalf("probed provider does not post: %v", err)
+		}
+	})
+
+	t.Run("nothing_found", func(t *testing.T) {
+		resp, err := st.ProbeOrigin(ctx, &spec.ProbeOriginRequest{
+			Body: &spec.ProbeOriginRequestBody{BaseURL: srv.URL},
+		})
+		if err != nil {
+			t.Fatalf("ProbeOrigin: %v", err)
+		}
+		for _, ep := range resp.Body.Endpoints {
+			if ep.Found && ep.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("unexpected endpoint %+v", ep)
+			}
+		}
+	})
+
+	t.Run("invalid_base_url", func(t *testing.T) {
+		_, err := st.ProbeOrigin(ctx, &spec.ProbeOriginRequest{
+			Body: &spec.ProbeOriginRequestBody{BaseURL: "ftp://gateway.test"},
+		})
+		if !errors.Is(err, spec.ErrInvalidDir) {
+			t.Fatalf("err=%v, want ErrInvalidDir", err)
+		}
+	})
+
+	t.Run("uses_transport_and_refuses_cross_origin_redirect", func(t *testing.T) {
+		var leaked bool
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			leaked = true
+		}))
+		defer other.Close()
+		redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, other.URL+r.URL.Path, http.StatusTemporaryRedirect)
+		}))
+		defer redirector.Close()
+		var used int
+		st.SetProviderTransport(func(inferenceSpec.ProviderName) http.RoundTripper {
+			used++
+			return http.DefaultTransport
+		})
+		defer st.SetProviderTransport(nil)
+
+		resp, err := st.ProbeOrigin(ctx, &spec.ProbeOriginRequest{
+			Body: &spec.ProbeOriginRequestBody{BaseURL: redirector.URL, APIKey: "gw-key"},
+		})
+		if err != nil {
+			t.Fatalf("ProbeOrigin: %v", err)
+		}
+		if used == 0 {
+			t.Fatal("provider transport not used")
+		}
+		if leaked {
+			t.Fatal("probe followed a redirect to another origin")
+		}
+		for _, ep := range resp.Body.Endpoints {
+			if ep.Found {
+				t.Fatalf("redirected endpoint reported found: %+v", ep)
+			}
+		}
+	})
+}
+
+func TestModelPresetStore_EmbeddingPresets(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		// Out of order on purpose; vectors are placed by index.
+		_, _ = w.Write([]byte(`{"data":[
+			{"index":1,"embedding":[0.3,0.4]},
+			{"index":0,"embedding":[0.1,0.2]}
+		],"usage":{"prompt_tokens":7}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	st := newStore(t)
+	ctx := t.Context()
+	prov := inferenceSpec.ProviderName("user-embed")
+	if _, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+		ProviderName: prov,
+		Body: &spec.PostProviderPresetRequestBody{
+			DisplayName:              "Embed",
+			SDKType:                  inferenceSpec.ProviderSDKTypeOpenAIChatCompletions,
+			IsEnabled:                true,
+			Origin:                   srv.URL,
+			ChatCompletionPathPrefix: spec.DefaultOpenAIChatCompletionsPrefix,
+			APIKeyHeaderKey:          spec.DefaultAuthorizationHeaderKey,
+		},
+	}); err != nil {
+		t.Fatalf("PostProviderPreset: %v", err)
+	}
+
+	post := func(id spec.EmbeddingPresetID, body spec.PostEmbeddingPresetRequestBody) error {
+		t.Helper()
+		_, err := st.PostEmbeddingPreset(ctx, &spec.PostEmbeddingPresetRequest{EmbeddingPresetID: id, Body: &body})
+		return err
+	}
+	valid := spec.PostEmbeddingPresetRequestBody{
+		DisplayName:  "Small",
+		ProviderName: prov,
+		ModelName:    "text-embedding-3-small",
+		Dimensions:   2,
+		IsEnabled:    true,
+	}
+	if err := post("small", valid); err != nil {
+		t.Fatalf("PostEmbeddingPreset: %v", err)
+	}
+
+	t.Run("post_errors", func(t *testing.T) {
+		wantErrIs(t, post("small", valid), spec.ErrEmbeddingPresetAlreadyExists)
+
+		unknown := valid
+		unknown.ProviderName = "nope"
+		wantErrIs(t, post("other", unknown), spec.ErrProviderNotFound)
+
+		badDims := valid
+		badDims.Dimensions = -1
+		wantErrContains(t, post("other", badDims), "dimensions")
+
+		if _, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+			ProviderName: "user-anthropic",
+			Body: &spec.PostProviderPresetRequestBody{
+				DisplayName: "Anthropic",
+				SDKType:     inferenceSpec.ProviderSDKTypeAnthropic,
+				IsEnabled:   true,
+			},
+		}); err != nil {
+			t.Fatalf("PostProviderPreset: %v", err)
+		}
+		anthropic := valid
+		anthropic.ProviderName = "user-anthropic"
+		wantErrIs(t, post("other", anthropic), spec.ErrEmbeddingsUnsupported)
+	})
+
+	t.Run("generate", func(t *testing.T) {
+		resp, err := st.GenerateEmbeddings(ctx, &spec.GenerateEmbeddingsRequest{
+			EmbeddingPresetID: "small",
+			Body:              &spec.GenerateEmbeddingsRequestBody{Inputs: []string{"a", "b"}, APIKey: "sk-test"},
+		})
+		if err != nil {
+			t.Fatalf("GenerateEmbeddings: %v", err)
+		}
+		if gotPath != "/v1/embeddings" || gotAuth != "Bearer sk-test" {
+			t.Fatalf("path=%q authorization=%q", gotPath, gotAuth)
+		}
+		if gotBody["model"] != "text-embedding-3-small" || gotBody["dimensions"] != float64(2) {
+			t.Fatalf("unexpected request body: %v", gotBody)
+		}
+		b := resp.Body
+		if b.Dimensions != 2 || b.InputTokens != 7 || len(b.Embeddings) != 2 ||
+			b.Embeddings[0][0] != 0.1 || b.Embeddings[1][1] != 0.4 {
+			t.Fatalf("unexpected response: %+v", b)
+		}
+
+		_, err = st.GenerateEmbeddings(ctx, &spec.GenerateEmbeddingsRequest{
+			EmbeddingPresetID: "small",
+			Body:              &spec.GenerateEmbeddingsRequestBody{Inputs: []string{" "}},
+		})
+		wantErrIs(t, err, spec.ErrInvalidDir)
+	})
+
+	t.Run("generate_uses_provider_transport", func(t *testing.T) {
+		var used []inferenceSpec.ProviderName
+		st.SetProviderTransport(func(provider inferenceSpec.ProviderName) http.RoundTripper {
+			used = append(used, provider)
+			return http.DefaultTransport
+		})
+		defer st.SetProviderTransport(nil)
+
+		if _, err := st.GenerateEmbeddings(ctx, &spec.GenerateEmbeddingsRequest{
+			EmbeddingPresetID: "small",
+			Body:              &spec.GenerateEmbeddingsRequestBody{Inputs: []string{"a", "b"}},
+		}); err != nil {
+			t.Fatalf("GenerateEmbeddings: %v", err)
+		}
+		if !slices.Equal(used, []inferenceSpec.ProviderName{prov}) {
+			t.Fatalf("transport used for %v", used)
+		}
+	})
+
+	t.Run("patch_list_delete", func(t *testing.T) {
+		if _, err := st.PatchEmbeddingPreset(ctx, &spec.PatchEmbeddingPresetRequest{
+			EmbeddingPresetID: "small",
+			Body:              &spec.PatchEmbeddingPresetRequestBody{IsEnabled: new(false)},
+		}); err != nil {
+			t.Fatalf("PatchEmbeddingPreset: %v", err)
+		}
+		list, err := st.ListEmbeddingPresets(ctx, &spec.ListEmbeddingPresetsRequest{})
+		if err != nil {
+			t.Fatalf("ListEmbeddingPresets: %v", err)
+		}
+		if len(list.Body.EmbeddingPresets) != 0 {
+			t.Fatalf("disabled preset listed: %+v", list.Body.EmbeddingPresets)
+		}
+		_, err = st.GenerateEmbeddings(ctx, &spec.GenerateEmbeddingsRequest{
+			EmbeddingPresetID: "small",
+			Body:              &spec.GenerateEmbeddingsRequestBody{Inputs: []string{"a"}},
+		})
+		wantErrIs(t, err, spec.ErrEmbeddingPresetNotFound)
+
+		got, err := st.GetEmbeddingPreset(ctx, &spec.GetEmbeddingPresetRequest{
+			EmbeddingPresetID: "small",
+			IncludeDisabled:   true,
+		})
+		if err != nil || got.Body.IsEnabled || got.Body.ModelName != "text-embedding-3-small" {
+			t.Fatalf("GetEmbeddingPreset: %+v, %v", got, err)
+		}
+
+		_, err = st.DeleteProviderPreset(ctx, &spec.DeleteProviderPresetRequest{ProviderName: prov})
+		wantErrContains(t, err, "embedding preset")
+
+		if _, err := st.DeleteEmbeddingPreset(ctx, &spec.DeleteEmbeddingPresetRequest{
+			EmbeddingPresetID: "small",
+		}); err != nil {
+			t.Fatalf("DeleteEmbeddingPreset: %v", err)
+		}
+		_, err = st.DeleteEmbeddingPreset(ctx, &spec.DeleteEmbeddingPresetRequest{EmbeddingPresetID: "small"})
+		wantErrIs(t, err, spec.ErrEmbeddingPresetNotFound)
+
+		if _, err := st.DeleteProviderPreset(ctx, &spec.DeleteProviderPresetRequest{ProviderName: prov}); err != nil {
+			t.Fatalf("DeleteProviderPreset after its embedding preset: %v", err)
+		}
+	})
+}
+
+func TestModelPresetStore_LocalProviders(t *testing.T) {
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"models":[
+			{"name":"llama3.2:latest","model":"llama3.2:latest"},
+			{"name":"qwen3:8b","model":"qwen3:8b"}
+		]}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	st := newStore(t)
+	ctx := t.Context()
+
+	t.Run("builtins-tagged", func(t *testing.T) {
+		for name, want := range map[inferenceSpec.ProviderName]inferenceSpec.ProviderSDKType{
+			"ollama":   spec.ProviderSDKTypeOllama,
+			"llamacpp": spec.ProviderSDKTypeLlamaCPP,
+		} {
+			pp := getProviderByName(t, st, ctx, name, true)
+			if pp.SDKType != want {
+				t.Fatalf("%s sdkType=%q want %q", name, pp.SDKType, want)
+			}
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		_, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+			ProviderName: "my-llamacpp",
+			Body: &spec.PostProviderPresetRequestBody{
+				DisplayName: "My llama.cpp",
+				SDKType:     spec.ProviderSDKTypeLlamaCPP,
+				IsEnabled:   true,
+			},
+		})
+		if err != nil {
+			t.Fatalf("PostProviderPreset: %v", err)
+		}
+		pp := getProviderByName(t, st, ctx, "my-llamacpp", true)
+		if pp.Origin != spec.DefaultLlamaCPPOrigin ||
+			pp.ChatCompletionPathPrefix != spec.DefaultOpenAIChatCompletionsPrefix ||
+			pp.APIKeyHeaderKey != spec.DefaultAuthorizationHeaderKey {
+			t.Fatalf("defaults not applied: %+v", pp)
+		}
+	})
+
+	t.Run("invalid-path", func(t *testing.T) {
+		_, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+			ProviderName: "bad-ollama",
+			Body: &spec.PostProviderPresetRequestBody{
+				DisplayName:              "Bad",
+				SDKType:                  spec.ProviderSDKTypeOllama,
+				IsEnabled:                true,
+				ChatCompletionPathPrefix: "/api/chat",
+			},
+		})
+		if err == nil {
+			t.Fatal("expected error for unsupported chat path")
+		}
+	})
+
+	t.Run("wire-sdk-type", func(t *testing.T) {
+		tests := []struct {
+			sdkType inferenceSpec.ProviderSDKType
+			path    string
+			want    inferenceSpec.ProviderSDKType
+		}{
+			{spec.ProviderSDKTypeOllama, "/v1/messages", inferenceSpec.ProviderSDKTypeAnthropic},
+			{spec.ProviderSDKTypeOllama, "/v1/chat/completions", inferenceSpec.ProviderSDKTypeOpenAIChatCompletions},
+			{spec.ProviderSDKTypeLlamaCPP, "/v1/chat/completions/", inferenceSpec.ProviderSDKTypeOpenAIChatCompletions},
+			{
+				inferenceSpec.ProviderSDKTypeOpenAIResponses, "/v1/responses",
+				inferenceSpec.ProviderSDKTypeOpenAIResponses,
+			},
+		}
+		for _, tc := range tests {
+			if got := spec.WireSDKType(tc.sdkType, tc.path); got != tc.want {
+				t.Fatalf("WireSDKType(%q, %q)=%q want %q", tc.sdkType, tc.path, got, tc.want)
+			}
+		}
+	})
+
+	t.Run("ollama-discovery", func(t *testing.T) {
+		_, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+			ProviderName: "my-ollama",
+			Body: &spec.PostProviderPresetRequestBody{
+				DisplayName: "My Ollama",
+				SDKType:     spec.ProviderSDKTypeOllama,
+				IsEnabled:   true,
+				Origin:      srv.URL,
+			},
+		})
+		if err != nil {
+			t.Fatalf("PostProviderPreset: %v", err)
+		}
+		resp, err := st.DiscoverProviderModels(ctx, &spec.DiscoverProviderModelsRequest{
+			ProviderName: "my-ollama",
+			Body:         &spec.DiscoverProviderModelsRequestBody{AcceptAll: true},
+		})
+		if err != nil {
+			t.Fatalf("DiscoverProviderModels: %v", err)
+		}
+		if gotPath != spec.DefaultOllamaModelsPath {
+			t.Fatalf("path=%q want %q", gotPath, spec.DefaultOllamaModelsPath)
+		}
+		if len(resp.Body.Accepted) != 2 {
+			t.Fatalf("accepted=%v want 2", resp.Body.Accepted)
+		}
+		pp := getProviderByName(t, st, ctx, "my-ollama", true)
+		if mp, ok := pp.ModelPresets["llama3-2-latest"]; !ok || mp.Name != "llama3.2:latest" {
+			t.Fatalf("unexpected model presets: %+v", pp.ModelPresets)
+		}
+	})
 }
 
-func TestModelPresetStore_PostModelPreset_AdditionalValidation(t *testing.T) {
+func TestModelPresetStore_NativeSDKProviders(t *testing.T) {
 	st := newStore(t)
 	ctx := t.Context()
 
-	userProv := inferenceSpec.ProviderName("user-model-validate-more")
-	postUserProvider(t, st, userProv, true)
+	post := func(name inferenceSpec.ProviderName, body spec.PostProviderPresetRequestBody) error {
+		t.Helper()
+		body.DisplayName = spec.ProviderDisplayName(name)
+		body.IsEnabled = true
+		_, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{ProviderName: name, Body: &body})
+		return err
+	}
 
-	temp := 0.1
-	intPtr := func(v int) *int { return &v }
+	t.Run("defaults", func(t *testing.T) {
+		if err := post("my-anthropic", spec.PostProviderPresetRequestBody{
+			SDKType: inferenceSpec.ProviderSDKTypeAnthropic,
+		}); err != nil {
+			t.Fatalf("PostProviderPreset(anthropic): %v", err)
+		}
+		pp := getProviderByName(t, st, ctx, "my-anthropic", true)
+		if pp.Origin != spec.DefaultAnthropicOrigin ||
+			pp.ChatCompletionPathPrefix != spec.DefaultAnthropicChatCompletionPrefix ||
+			pp.APIKeyHeaderKey != spec.DefaultAnthropicAuthorizationHeaderKey ||
+			pp.DefaultHeaders["anthropic-version"] == "" {
+			t.Fatalf("anthropic defaults not applied: %+v", pp)
+		}
 
-	baseReq := func() *spec.PostModelPresetRequest {
-		return &spec.PostModelPresetRequest{
-			ProviderName:  userProv,
-			ModelPresetID: "m1",
-			Body: &spec.PostModelPresetRequestBody{
-				Name:        "n1",
-				Slug:        "m1",
-				DisplayName: "Model 1",
-				IsEnabled:   true,
-				ModelPresetPatch: spec.ModelPresetPatch{
-					Temperature: &temp,
-				},
-			},
+		if err := post("my-gemini", spec.PostProviderPresetRequestBody{
+			SDKType: inferenceSpec.ProviderSDKTypeGoogleGenerateContent,
+		}); err != nil {
+			t.Fatalf("PostProviderPreset(gemini): %v", err)
 		}
-	}
+		pp = getProviderByName(t, st, ctx, "my-gemini", true)
+		if pp.Origin != spec.DefaultGoogleGenerateContentOrigin ||
+			pp.APIKeyHeaderKey != spec.DefaultGoogleGenerateContentAPIKeyHeaderKey {
+			t.Fatalf("gemini defaults not applied: %+v", pp)
+		}
+	})
 
 	tests := []struct {
-		name        string
-		req         func() *spec.PostModelPresetRequest
-		wantErrText string
+		name    string
+		body    spec.PostProviderPresetRequestBody
+		wantErr bool
 	}{
 		{
-			name: "empty_model_name",
-			req: func() *spec.PostModelPresetRequest {
-				r := baseReq()
-				r.Body.Name = ""
-				return r
+			name: "anthropic-proxy-path",
+			body: spec.PostProviderPresetRequestBody{
+				SDKType: inferenceSpec.ProviderSDKTypeAnthropic, ChatCompletionPathPrefix: "/proxy/v1/messages/",
 			},
-			wantErrText: "name is empty",
 		},
 		{
-			name: "empty_display_name",
-			req: func() *spec.PostModelPresetRequest {
-				r := baseReq()
-				r.Body.DisplayName = ""
-				return r
+			name: "anthropic-wrong-path",
+			body: spec.PostProviderPresetRequestBody{
+				SDKType: inferenceSpec.ProviderSDKTypeAnthropic, ChatCompletionPathPrefix: "/v1/chat/completions",
 			},
-			wantErrText: "displayName is empty",
+			wantErr: true,
 		},
 		{
-			name: "negative_max_prompt_length",
-			req: func() *spec.PostModelPresetRequest {
-				r := baseReq()
-				r.Body.MaxPromptLength = intPtr(-1)
-				return r
+			name: "gemini-base-path",
+			body: spec.PostProviderPresetRequestBody{
+				SDKType: inferenceSpec.ProviderSDKTypeGoogleGenerateContent, ChatCompletionPathPrefix: "/gateway",
 			},
-			wantErrText: "maxPromptLength must be >= 0",
 		},
 		{
-			name: "negative_max_output_length",
-			req: func() *spec.PostModelPresetRequest {
-				r := baseReq()
-				r.Body.MaxOutputLength = intPtr(-1)
-				return r
+			name: "gemini-version-in-path",
+			body: spec.PostProviderPresetRequestBody{
+				SDKType: inferenceSpec.ProviderSDKTypeGoogleGenerateContent, ChatCompletionPathPrefix: "/v1beta",
 			},
-			wantErrText: "maxOutputLength must be >= 0",
+			wantErr: true,
 		},
 		{
-			name: "negative_timeout",
-			req: func() *spec.PostModelPresetRequest {
-				r := baseReq()
-				r.Body.Timeout = intPtr(-1)
-				return r
+			name: "gemini-model-in-path",
+			body: spec.PostProviderPresetRequestBody{
+				SDKType:                  inferenceSpec.ProviderSDKTypeGoogleGenerateContent,
+				ChatCompletionPathPrefix: "/models/gemini-pro:generateContent",
 			},
-			wantErrText: "timeout must be >= 0",
+			wantErr: true,
 		},
 		{
-			name: "stop_sequence_empty_string",
-			req: func() *spec.PostModelPresetRequest {
-				r := baseReq()
-				r.Body.StopSequences = new([]string{""})
-				return r
+			name: "gemini-bearer-header",
+			body: spec.PostProviderPresetRequestBody{
+				SDKType:         inferenceSpec.ProviderSDKTypeGoogleGenerateContent,
+				APIKeyHeaderKey: spec.DefaultAuthorizationHeaderKey,
 			},
-			wantErrText: "stopSequences[0] is empty",
+			wantErr: true,
 		},
 	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			_, err := st.PostModelPreset(ctx, tt.req())
-			if tt.wantErrText != "" {
-				wantErrContains(t, err, tt.wantErrText)
-				return
-			}
-			if err != nil {
-				t.Fatalf("unexpected: %v", err)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := post(inferenceSpec.ProviderName("p-"+tc.name), tc.body)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err=%v wantErr=%v", err, tc.wantErr)
 			}
 		})
 	}
 }
 
-func TestModelPresetStore_ListProviderPresets_PageTokenOverridesRequestParams(t *testing.T) {
+func TestModelPresetStore_AzureOpenAIProvider(t *testing.T) {
 	st := newStore(t)
 	ctx := t.Context()
+	azure := &spec.AzureOpenAIConfig{
+		ResourceName:   "contoso-eu",
+		DeploymentName: "gpt-4o-prod",
+		APIVersion:     "2024-10-21",
+	}
 
-	p2 := inferenceSpec.ProviderName("user-override-2") // disabled, older
-	p1 := inferenceSpec.ProviderName("user-override-1") // enabled, newer
-
-	postUserProvider(t, st, p2, false)
-	time.Sleep(2 * time.Millisecond)
-	postUserProvider(t, st, p1, true)
-
-	resp1, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
-		Names:           []inferenceSpec.ProviderName{p1, p2},
-		IncludeDisabled: true,
-		PageSize:        1,
+	_, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+		ProviderName: "azure-prod",
+		Body: &spec.PostProviderPresetRequestBody{
+			DisplayName: "Azure prod",
+			SDKType:     spec.ProviderSDKTypeAzureOpenAI,
+			IsEnabled:   true,
+			Azure:       azure,
+		},
 	})
 	if err != nil {
-		t.Fatalf("ListProviderPresets(page1): %v", err)
-	}
-	if len(resp1.Body.Providers) != 1 {
-		t.Fatalf("expected 1 provider on page1, got %d", len(resp1.Body.Providers))
+		t.Fatalf("PostProviderPreset: %v", err)
 	}
-	if resp1.Body.Providers[0].Name != p1 {
-		t.Fatalf("expected page1 to return %q first, got %q", p1, resp1.Body.Providers[0].Name)
+	pp := getProviderByName(t, st, ctx, "azure-prod", true)
+	if pp.Origin != "https://contoso-eu.openai.azure.com" ||
+		pp.ChatCompletionPathPrefix != "/openai/deployments/gpt-4o-prod/chat/completions" ||
+		pp.APIKeyHeaderKey != spec.DefaultAzureOpenAIAPIKeyHeaderKey ||
+		pp.Azure == nil || *pp.Azure != *azure {
+		t.Fatalf("unexpected azure provider: %+v", pp)
 	}
-	if resp1.Body.NextPageToken == nil {
-		t.Fatalf("expected next page token")
+	if got := spec.WireSDKType(pp.SDKType, pp.ChatCompletionPathPrefix); got !=
+		inferenceSpec.ProviderSDKTypeOpenAIChatCompletions {
+		t.Fatalf("wire sdkType=%q", got)
 	}
 
-	// Intentionally provide conflicting params; token must win.
-	resp2, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
-		PageToken:       *resp1.Body.NextPageToken,
-		Names:           []inferenceSpec.ProviderName{p1}, // would exclude p2 if not overridden
-		IncludeDisabled: false,                            // would exclude disabled if not overridden
-		PageSize:        999,
+	t.Run("patch-deployment", func(t *testing.T) {
+		next := *azure
+		next.DeploymentName = "gpt-4o-mini"
+		if _, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+			ProviderName: "azure-prod",
+			Body:         &spec.PatchProviderPresetRequestBody{Azure: &next},
+		}); err != nil {
+			t.Fatalf("PatchProviderPreset: %v", err)
+		}
+		pp := getProviderByName(t, st, ctx, "azure-prod", true)
+		if pp.ChatCompletionPathPrefix != "/openai/deployments/gpt-4o-mini/chat/completions" {
+			t.Fatalf("path not re-derived: %q", pp.ChatCompletionPathPrefix)
+		}
 	})
-	if err != nil {
-		t.Fatalf("ListProviderPresets(page2): %v", err)
-	}
-	if len(resp2.Body.Providers) != 1 {
-		t.Fatalf("expected 1 provider on page2, got %d", len(resp2.Body.Providers))
-	}
-	if resp2.Body.Providers[0].Name != p2 {
-		t.Fatalf("expected page2 to return %q due to token override, got %q", p2, resp2.Body.Providers[0].Name)
+
+	t.Run("patch-resource", func(t *testing.T) {
+		if _, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+			ProviderName: "azure-moved",
+			Body: &spec.PostProviderPresetRequestBody{
+				DisplayName: "Azure moved",
+				SDKType:     spec.ProviderSDKTypeAzureOpenAI,
+				Azure:       azure,
+			},
+		}); err != nil {
+			t.Fatalf("PostProviderPreset: %v", err)
+		}
+		patchAzure := func(body spec.PatchProviderPresetRequestBody) spec.ProviderPreset {
+			t.Helper()
+			if _, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+				ProviderName: "azure-moved",
+				Body:         &body,
+			}); err != nil {
+				t.Fatalf("PatchProviderPreset: %v", err)
+			}
+			return getProviderByName(t, st, ctx, "azure-moved", true)
+		}
+		next := *azure
+		next.ResourceName = "contoso-us"
+		if pp := patchAzure(spec.PatchProviderPresetRequestBody{Azure: &next}); pp.Origin !=
+			"https://contoso-us.openai.azure.com" {
+			t.Fatalf("origin not re-derived: %q", pp.Origin)
+		}
+
+		// A custom endpoint is kept when the resource changes.
+		custom := "https://gateway.contoso.example"
+		patchAzure(spec.PatchProviderPresetRequestBody{Origin: &custom})
+		next.ResourceName = "contoso-ap"
+		if pp := patchAzure(spec.PatchProviderPresetRequestBody{Azure: &next}); pp.Origin != custom {
+			t.Fatalf("custom origin replaced: %q", pp.Origin)
+		}
+	})
+
+	tests := []struct {
+		name string
+		body spec.PostProviderPresetRequestBody
+	}{
+		{name: "missing-config", body: spec.PostProviderPresetRequestBody{
+			SDKType: spec.ProviderSDKTypeAzureOpenAI, Origin: "https://x.openai.azure.com",
+			ChatCompletionPathPrefix: "/openai/deployments/d/chat/completions",
+		}},
+		{name: "bad-resource", body: spec.PostProviderPresetRequestBody{
+			SDKType: spec.ProviderSDKTypeAzureOpenAI,
+			Azure:   &spec.AzureOpenAIConfig{ResourceName: "a_b", DeploymentName: "d", APIVersion: "2024-10-21"},
+		}},
+		{name: "bad-api-version", body: spec.PostProviderPresetRequestBody{
+			SDKType: spec.ProviderSDKTypeAzureOpenAI,
+			Azure:   &spec.AzureOpenAIConfig{ResourceName: "res", DeploymentName: "d", APIVersion: "latest"},
+		}},
+		{name: "azure-on-other-sdk", body: spec.PostProviderPresetRequestBody{
+			SDKType: inferenceSpec.ProviderSDKTypeOpenAIChatCompletions, Origin: "https://api.example.com",
+			ChatCompletionPathPrefix: spec.DefaultOpenAIChatCompletionsPrefix,
+			Azure:                    azure,
+		}},
 	}
-	if resp2.Body.Providers[0].IsEnabled {
-		t.Fatalf("expected returned provider to be disabled")
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.body.DisplayName = "Invalid"
+			_, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+				ProviderName: inferenceSpec.ProviderName("p-" + tc.name),
+				Body:         &tc.body,
+			})
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+		})
 	}
 }
 
-func TestModelPresetStore_ListProviderPresets_Base64ButInvalidJSONToken_Ignored(t *testing.T) {
-	st := newStore(t)
+func TestModelPresetStore_ReservedProviderNames(t *testing.T) {
 	ctx := t.Context()
 
-	disabled := inferenceSpec.ProviderName("user-disabled-token-test")
-	postUserProvider(t, st, disabled, false)
+	t.Run("post-rejects-reserved", func(t *testing.T) {
+		st := newStore(t)
+		builtIn, _ := anyBuiltInProviderFromStore(t, st)
+		for _, name := range []inferenceSpec.ProviderName{
+			"builtin-custom",
+			"FlexiGPT-proxy",
+			builtIn + "2",
+			builtIn + "-3",
+		} {
+			_, err := st.PostProviderPreset(ctx, &spec.PostProviderPresetRequest{
+				ProviderName: name,
+				Body: &spec.PostProviderPresetRequestBody{
+					DisplayName:              "Reserved",
+					SDKType:                  inferenceSpec.ProviderSDKTypeOpenAIChatCompletions,
+					IsEnabled:                true,
+					Origin:                   "https://api.example.test",
+					ChatCompletionPathPrefix: spec.DefaultOpenAIChatCompletionsPrefix,
+					APIKeyHeaderKey:          spec.DefaultAuthorizationHeaderKey,
+				},
+			})
+			if !errors.Is(err, spec.ErrProviderNameReserved) || !errors.Is(err, bundleitemutils.ErrReservedName) {
+				t.Fatalf("PostProviderPreset(%q) err=%v want ErrProviderNameReserved", name, err)
+			}
+		}
+		// Names merely sharing a built-in prefix stay available.
+		postUserProvider(t, st, builtIn+"-proxy", true)
+	})
 
-	// Base64-valid, but NOT JSON.
-	token := base64.StdEncoding.EncodeToString([]byte("not-json"))
+	t.Run("migrate-renames-collisions", func(t *testing.T) {
+		dir := t.TempDir()
+		st := newStoreAtDir(t, dir)
+		postUserProvider(t, st, "acme-prov", true)
+		postUserProvider(t, st, "other-prov", true)
+		if _, err := st.PatchDefaultProvider(ctx, &spec.PatchDefaultProviderRequest{
+			Body: &spec.PatchDefaultProviderRequestBody{DefaultProvider: "acme-prov"},
+		}); err != nil {
+			t.Fatalf("PatchDefaultProvider: %v", err)
+		}
+		closeAndSleepOnWindows(t, st)
 
-	resp, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{
-		PageToken: token,
-	})
-	if err != nil {
-		t.Fatalf("expected no error, got %v", err)
-	}
+		// A newer catalog reserves the "acme-" namespace.
+		st2, err := NewModelPresetStore(dir, WithReservedProviderNamespace(
+			bundleitemutils.ReservedNamespace{Prefixes: []string{"acme-"}},
+		))
+		if err != nil {
+			t.Fatalf("NewModelPresetStore: %v", err)
+		}
+		t.Cleanup(func() { closeAndSleepOnWindows(t, st2) })
 
-	for _, p := range resp.Body.Providers {
-		if p.Name == disabled {
-			t.Fatalf("expected disabled provider to be filtered out when token is ignored")
+		renames, err := st2.MigrateReservedProviderNames(ctx)
+		if err != nil {
+			t.Fatalf("MigrateReservedProviderNames: %v", err)
 		}
-	}
+		if len(renames) != 1 || renames["acme-prov"] != "user-acme-prov" {
+			t.Fatalf("renames=%v", renames)
+		}
+		pp := getProviderByName(t, st2, ctx, "user-acme-prov", true)
+		if pp.Name != "user-acme-prov" {
+			t.Fatalf("renamed provider name=%q", pp.Name)
+		}
+		def, err := st2.GetDefaultProvider(ctx, &spec.GetDefaultProviderRequest{})
+		if err != nil {
+			t.Fatalf("GetDefaultProvider: %v", err)
+		}
+		if def.Body.DefaultProvider != "user-acme-prov" {
+			t.Fatalf("default provider=%q", def.Body.DefaultProvider)
+		}
+
+		renames, err = st2.MigrateReservedProviderNames(ctx)
+		if err != nil || len(renames) != 0 {
+			t.Fatalf("second migration renames=%v err=%v", renames, err)
+		}
+		if all, err := st2.readAllUserPresets(); err != nil || all.ReservedNamesDigest == "" {
+			t.Fatalf("migration did not record the namespace digest: %v", err)
+		}
+		closeAndSleepOnWindows(t, st2)
+
+		// Only a changed namespace migrates again.
+		st3, err := NewModelPresetStore(dir, WithReservedProviderNamespace(
+			bundleitemutils.ReservedNamespace{Prefixes: []string{"acme-", "other-"}},
+		))
+		if err != nil {
+			t.Fatalf("NewModelPresetStore: %v", err)
+		}
+		t.Cleanup(func() { closeAndSleepOnWindows(t, st3) })
+		renames, err = st3.MigrateReservedProviderNames(ctx)
+		if err != nil || len(renames) != 1 || renames["other-prov"] != "user-other-prov" {
+			t.Fatalf("migration after namespace change renames=%v err=%v", renames, err)
+		}
+	})
 }
 
-func TestModelPresetStore_UserData_PersistsAcrossReopen(t *testing.T) {
-	dir := t.TempDir()
-	st := newStoreAtDir(t, dir)
+func TestModelPresetStore_Subscribe(t *testing.T) {
 	ctx := t.Context()
+	st := newStore(t)
 
-	prov := inferenceSpec.ProviderName("user-persist-prov")
-	postUserProvider(t, st, prov, true)
-	postUserModelPreset(t, ctx, st, prov, "m1", true)
+	events := make(chan spec.PresetChangeEvent, 32)
+	unsubscribe := st.Subscribe(func(ev spec.PresetChangeEvent) {
+		// Listeners may call back into the store.
+		_, _ = st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{})
+		events <- ev
+	})
 
-	_, err := st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
-		ProviderName:  prov,
+	next := func() spec.PresetChangeEvent {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for preset change event")
+			return spec.PresetChangeEvent{}
+		}
+	}
+
+	postUserProvider(t, st, "watch-prov", true)
+	postUserModelPreset(t, ctx, st, "watch-prov", "m1", true)
+	if _, err := st.PatchModelPreset(ctx, &spec.PatchModelPresetRequest{
+		ProviderName:  "watch-prov",
 		ModelPresetID: "m1",
 		Body:          &spec.PatchModelPresetRequestBody{IsEnabled: new(false)},
-	})
-	if err != nil {
+	}); err != nil {
 		t.Fatalf("PatchModelPreset: %v", err)
 	}
-
-	_, err = st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
-		ProviderName: prov,
-		Body: &spec.PatchProviderPresetRequestBody{
-			DefaultModelPresetID: mpidPtr("m1"),
-		},
-	})
-	if err != nil {
-		t.Fatalf("PatchProviderPreset: %v", err)
+	if _, err := st.DeleteModelPreset(ctx, &spec.DeleteModelPresetRequest{
+		ProviderName: "watch-prov", ModelPresetID: "m1",
+	}); err != nil {
+		t.Fatalf("DeleteModelPreset: %v", err)
+	}
+	if _, err := st.DeleteProviderPreset(ctx, &spec.DeleteProviderPresetRequest{
+		ProviderName: "watch-prov",
+	}); err != nil {
+		t.Fatalf("DeleteProviderPreset: %v", err)
 	}
 
-	closeAndSleepOnWindows(t, st)
-
-	st2 := newStoreAtDir(t, dir)
-
-	pp := getProviderByName(t, st2, ctx, prov, true)
-	if pp.DefaultModelPresetID != "m1" {
-		t.Fatalf("defaultModelPresetID not persisted: got=%q want=m1", pp.DefaultModelPresetID)
+	want := []spec.PresetChangeEvent{
+		{Kind: spec.PresetChangeProviderCreated, ProviderName: "watch-prov"},
+		{Kind: spec.PresetChangeModelCreated, ProviderName: "watch-prov", ModelPresetID: "m1"},
+		{Kind: spec.PresetChangeModelUpdated, ProviderName: "watch-prov", ModelPresetID: "m1"},
+		{Kind: spec.PresetChangeModelDeleted, ProviderName: "watch-prov", ModelPresetID: "m1"},
+		{Kind: spec.PresetChangeProviderDeleted, ProviderName: "watch-prov"},
 	}
-	m1 := pp.ModelPresets["m1"]
-	if m1.ID != "m1" {
-		t.Fatalf("model not persisted")
+	for i, w := range want {
+		got := next()
+		if got.Kind != w.Kind || got.ProviderName != w.ProviderName || got.ModelPresetID != w.ModelPresetID {
+			t.Fatalf("event[%d]=%+v want %+v", i, got, w)
+		}
+		if got.At.IsZero() {
+			t.Fatalf("event[%d] has zero timestamp", i)
+		}
 	}
-	if m1.IsEnabled != false {
-		t.Fatalf("model enabled flag not persisted: got=%v want=false", m1.IsEnabled)
+
+	unsubscribe()
+	postUserProvider(t, st, "watch-prov-2", true)
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event after unsubscribe: %+v", ev)
+	case <-time.After(100 * time.Millisecond):
 	}
 }
 
@@ -1888,7 +5047,7 @@ func TestModelPresetStore_PostModelPreset_InvalidOutputParamAndReasoningErrors(t
 				r.Body.Temperature = nil
 				r.Body.Reasoning = &inferenceSpec.ReasoningParam{
 					Type:   inferenceSpec.ReasoningTypeHybridWithTokens,
-					Tokens: 0,
+					Tokens: -2,
 				}
 				return r
 			},
@@ -1926,3 +5085,55 @@ func TestModelPresetStore_PostModelPreset_InvalidOutputParamAndReasoningErrors(t
 		})
 	}
 }
+
+func TestModelPresetStore_ListProviderPresets_SnapshotReuse(t *testing.T) {
+	t.Parallel()
+
+	st := newStore(t)
+	ctx := t.Context()
+
+	postUserProvider(t, st, "user-snap-1", true)
+	postUserProvider(t, st, "user-snap-2", true)
+
+	list := func() *providerListSnapshot {
+		t.Helper()
+		if _, err := st.ListProviderPresets(ctx, &spec.ListProviderPresetsRequest{IncludeDisabled: true}); err != nil {
+			t.Fatalf("ListProviderPresets: %v", err)
+		}
+		snap := st.providerSnapshot.Load()
+		if snap == nil {
+			t.Fatal("listing did not keep a snapshot")
+		}
+		return snap
+	}
+
+	first := list()
+	if again := list(); again != first {
+		t.Fatal("unchanged store rebuilt the snapshot")
+	}
+
+	postUserProvider(t, st, "user-snap-3", true)
+	afterWrite := list()
+	if afterWrite == first {
+		t.Fatal("user write kept the old snapshot")
+	}
+	if got := listProvidersByNames(t, st, ctx, []inferenceSpec.ProviderName{"user-snap-3"}, true); len(got) != 1 {
+		t.Fatalf("new provider not listed: %+v", got)
+	}
+
+	name, pp := anyBuiltInProviderFromStore(t, st)
+	enabled := !pp.IsEnabled
+	if _, err := st.PatchProviderPreset(ctx, &spec.PatchProviderPresetRequest{
+		ProviderName: name,
+		Body:         &spec.PatchProviderPresetRequestBody{IsEnabled: &enabled},
+	}); err != nil {
+		t.Fatalf("PatchProviderPreset(%q): %v", name, err)
+	}
+	if list() == afterWrite {
+		t.Fatal("built-in change kept the old snapshot")
+	}
+	got := listProvidersByNames(t, st, ctx, []inferenceSpec.ProviderName{name}, true)
+	if len(got) != 1 || got[0].IsEnabled != enabled {
+		t.Fatalf("built-in %q listed as %+v, want isEnabled=%v", name, got, enabled)
+	}
+}
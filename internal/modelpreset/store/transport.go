@@ -0,0 +1,53 @@
+package store
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// ProviderTransportFunc returns the HTTP transport requests to a provider go
+// through, with the app's proxy, CA and per-provider TLS settings applied. A
+// provider it does not know gets the shared network settings.
+type ProviderTransportFunc func(provider inferenceSpec.ProviderName) http.RoundTripper
+
+// SetProviderTransport routes the requests the store makes itself, such as
+// model discovery, origin probes, reachability checks and embeddings, through
+// the provider transports. Until it is set they use http.DefaultTransport.
+func (s *ModelPresetStore) SetProviderTransport(transport ProviderTransportFunc) {
+	if s == nil {
+		return
+	}
+	s.providerTransport.Store(&transport)
+}
+
+// providerHTTPClient returns a client for requests to provider. Redirects to
+// another origin are refused, as they would carry the API key header along.
+func (s *ModelPresetStore) providerHTTPClient(
+	provider inferenceSpec.ProviderName, timeout time.Duration,
+) *http.Client {
+	var transport http.RoundTripper
+	if f := s.providerTransport.Load(); f != nil && *f != nil {
+		transport = (*f)(provider)
+	}
+	return &http.Client{
+		Transport:     transport,
+		Timeout:       timeout,
+		CheckRedirect: sameOriginRedirect,
+	}
+}
+
+var errCrossOriginRedirect = errors.New("redirect to another origin refused")
+
+func sameOriginRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	first := via[0].URL
+	if req.URL.Scheme != first.Scheme || req.URL.Host != first.Host {
+		return errCrossOriginRedirect
+	}
+	return nil
+}
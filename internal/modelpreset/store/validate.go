@@ -3,10 +3,18 @@ package store
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
 	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	"github.com/flexigpt/flexigpt-app/internal/validation"
 	"github.com/flexigpt/inference-go/capabilityoverride"
 	inferenceSpec "github.com/flexigpt/inference-go/spec"
 )
@@ -24,121 +32,295 @@ func ValidateModelPreset(model *spec.ModelPreset) error {
 }
 
 // validateProviderPreset performs structural and referential checks for a
-// provider together with its embedded model presets.
+// provider together with its embedded model presets. Every invalid field is
+// reported as validation.Errors; warnings are left to ValidateProviderPreset.
 func validateProviderPreset(pp *spec.ProviderPreset) error {
 	if pp == nil {
 		return spec.ErrNilProvider
 	}
-	if pp.SchemaVersion != spec.SchemaVersion {
-		return fmt.Errorf("provider %q: schemaVersion %q not equal to %q",
-			pp.Name, pp.SchemaVersion, spec.SchemaVersion)
-	}
-	if err := validateProviderName(pp.Name); err != nil {
+	r := &validation.Report{}
+	checkProviderPreset(r, pp, true)
+	if err := r.Err(); err != nil {
 		return fmt.Errorf("provider %q: %w", pp.Name, err)
 	}
+	return nil
+}
+
+// checkProviderPreset adds the errors and warnings of pp to r. Timestamps are
+// skipped for an unsaved candidate, as the store sets them on write.
+func checkProviderPreset(r *validation.Report, pp *spec.ProviderPreset, checkTimestamps bool) {
+	if pp.SchemaVersion != spec.SchemaVersion {
+		r.Addf("schemaVersion", validation.CodeUnsupported, "schemaVersion %q not equal to %q",
+			pp.SchemaVersion, spec.SchemaVersion)
+	}
+	r.Check("name", validation.CodeRequired, validateProviderName(pp.Name))
 	if strings.TrimSpace(string(pp.DisplayName)) == "" {
-		return fmt.Errorf("provider %q: displayName is empty", pp.Name)
+		r.Addf("displayName", validation.CodeRequired, "displayName is empty")
 	}
-	if pp.CreatedAt.IsZero() || pp.ModifiedAt.IsZero() {
-		return fmt.Errorf("provider %q: %w", pp.Name, spec.ErrInvalidTimestamp)
+	if checkTimestamps && (pp.CreatedAt.IsZero() || pp.ModifiedAt.IsZero()) {
+		r.Check("createdAt", validation.CodeRequired, spec.ErrInvalidTimestamp)
 	}
-	if strings.TrimSpace(pp.Origin) == "" {
-		return fmt.Errorf("provider %q: origin is empty", pp.Name)
+	switch {
+	case strings.TrimSpace(string(pp.SDKType)) == "":
+		r.Addf("sdkType", validation.CodeRequired, "sdkType is empty")
+	case !isKnownSDKType(pp.SDKType):
+		r.Warnf("sdkType", validation.CodeUnsupported,
+			"sdkType %q is not known to this version and is passed to inference-go as is", pp.SDKType)
 	}
-	if strings.TrimSpace(pp.ChatCompletionPathPrefix) == "" {
-		return fmt.Errorf("provider %q: chatCompletionPathPrefix is empty", pp.Name)
+	r.Check("sdkType", validation.CodeInvalid, validateProviderSDKConfig(pp))
+
+	checkProviderOrigin(r, pp.Origin)
+	checkChatCompletionPathPrefix(r, pp.ChatCompletionPathPrefix)
+	checkProviderHeaders(r, pp.Name, pp.APIKeyHeaderKey, pp.DefaultHeaders)
+
+	r.Checkf("capabilitiesOverride", validation.CodeInvalid, "capabilitiesOverride",
+		capabilityoverride.ValidateModelCapabilitiesOverride(pp.CapabilitiesOverride))
+	r.Checkf("rateLimits", validation.CodeInvalid, "rateLimits", validateProviderRateLimits(pp.RateLimits))
+	r.Checkf("resilience", validation.CodeInvalid, "resilience", validateProviderResilience(pp.Name, pp.Resilience))
+	r.Checkf("tls", validation.CodeInvalid, "tls", validateProviderTLS(pp.TLS))
+	r.Checkf("imageProcessing", validation.CodeInvalid, "imageProcessing",
+		validateProviderImageProcessing(pp.ImageProcessing))
+
+	if len(pp.ModelPresets) == 0 {
+		r.Warnf("modelPresets", validation.CodeRequired, "provider has no model presets")
+	}
+	for _, id := range slices.Sorted(maps.Keys(pp.ModelPresets)) {
+		mp := pp.ModelPresets[id]
+		mr := &validation.Report{}
+		// A candidate is authored by the user, like a posted preset.
+		checkModelPreset(mr, &mp, checkTimestamps, !checkTimestamps)
+		if mp.ID != id {
+			mr.Addf("id", validation.CodeMismatch, "id %q does not match key %q", mp.ID, id)
+		}
+		r.Include("modelPresets."+string(id), fmt.Sprintf("model %q", id), mr.Issues())
 	}
-	if err := capabilityoverride.ValidateModelCapabilitiesOverride(pp.CapabilitiesOverride); err != nil {
-		return fmt.Errorf("provider %q: capabilitiesOverride: %w", pp.Name, err)
+
+	if pp.DefaultModelPresetID != "" {
+		mp, ok := pp.ModelPresets[pp.DefaultModelPresetID]
+		switch {
+		case !ok:
+			r.Check("defaultModelPresetID", validation.CodeInvalid, fmt.Errorf(
+				"defaultModelPresetID %q not present: %w", pp.DefaultModelPresetID, spec.ErrModelPresetNotFound))
+		case !mp.IsEnabled:
+			r.Warnf("defaultModelPresetID", validation.CodeInvalid,
+				"default model preset %q is disabled", pp.DefaultModelPresetID)
+		}
 	}
-	// Per-model validation and duplicate ID detection.
-	seenModel := map[spec.ModelPresetID]string{}
-	for mid, mp := range pp.ModelPresets {
-		if err := validateModelPreset(&mp); err != nil {
-			return fmt.Errorf("provider %q, model %q: %w", pp.Name, mid, err)
+}
+
+// checkProviderOrigin requires an http(s) origin. Plain http to a remote host
+// and a path are accepted with a warning.
+func checkProviderOrigin(r *validation.Report, origin string) {
+	const field = "origin"
+	origin = strings.TrimSpace(origin)
+	if origin == "" {
+		r.Addf(field, validation.CodeRequired, "origin is empty")
+		return
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		r.Addf(field, validation.CodeInvalid, "origin: invalid URL: %v", err)
+		return
+	}
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if !isLoopbackHost(u.Hostname()) {
+			r.Warnf(field, validation.CodeInvalid, "origin uses plain http; API keys are sent unencrypted")
 		}
-		if prev := seenModel[mid]; prev != "" {
-			return fmt.Errorf("provider %q: duplicate modelPresetID %q (also in %s)",
-				pp.Name, mid, prev)
+	default:
+		r.Addf(field, validation.CodeUnsupported, "origin: scheme must be http or https, got %q", u.Scheme)
+		return
+	}
+	if u.Host == "" {
+		r.Addf(field, validation.CodeRequired, "origin: no host")
+	}
+	if u.Path != "" && u.Path != "/" {
+		r.Warnf(field, validation.CodeInvalid, "origin has path %q; it belongs in chatCompletionPathPrefix", u.Path)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		r.Addf(field, validation.CodeInvalid, "origin: must not have a query or fragment")
+	}
+}
+
+func checkChatCompletionPathPrefix(r *validation.Report, prefix string) {
+	const field = "chatCompletionPathPrefix"
+	if strings.TrimSpace(prefix) == "" {
+		r.Addf(field, validation.CodeRequired, "chatCompletionPathPrefix is empty")
+		return
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		r.Addf(field, validation.CodeInvalid, "chatCompletionPathPrefix: must start with \"/\"")
+	}
+	if strings.ContainsAny(prefix, "?# \t") {
+		r.Addf(field, validation.CodeInvalid,
+			"chatCompletionPathPrefix: must not contain a query, fragment or spaces")
+	}
+	if len(prefix) > 1 && strings.HasSuffix(prefix, "/") {
+		r.Warnf(field, validation.CodeInvalid, "chatCompletionPathPrefix has a trailing \"/\"")
+	}
+}
+
+// checkProviderHeaders checks header names and values, and that templated
+// values only use known placeholders and auth keys the provider may send.
+func checkProviderHeaders(
+	r *validation.Report, provider inferenceSpec.ProviderName, apiKeyHeader string, defaults map[string]string,
+) {
+	if strings.TrimSpace(apiKeyHeader) == "" {
+		r.Warnf("apiKeyHeaderKey", validation.CodeRequired,
+			"apiKeyHeaderKey is empty; requests are sent without an API key")
+	} else if !isHTTPHeaderName(apiKeyHeader) {
+		r.Addf("apiKeyHeaderKey", validation.CodeInvalid,
+			"apiKeyHeaderKey: %q is not a valid header name", apiKeyHeader)
+	}
+
+	seen := map[string]string{}
+	for _, name := range slices.Sorted(maps.Keys(defaults)) {
+		field := "defaultHeaders." + name
+		if !isHTTPHeaderName(name) {
+			r.Addf(field, validation.CodeInvalid, "%s: %q is not a valid header name", field, name)
+			continue
+		}
+		if strings.ContainsAny(defaults[name], "\r\n\x00") {
+			r.Addf(field, validation.CodeInvalid, "%s: header value contains control characters", field)
+		}
+		for _, p := range spec.HeaderPlaceholders(defaults[name]) {
+			if !spec.IsHeaderPlaceholder(p) {
+				r.Addf(field, validation.CodeUnsupported, "%s: unknown header placeholder {{%s}}", field, p)
+				continue
+			}
+			if keyType, keyName, ok := spec.HeaderAuthKeyPlaceholder(p); ok &&
+				!spec.HeaderAuthKeyAllowed(provider, keyType, keyName) {
+				r.Addf(field, validation.CodeUnsupported,
+					"%s: {{%s}} may only read the provider's own %s key or %s keys",
+					field, p, spec.HeaderAuthKeyTypeProvider, spec.HeaderAuthKeyTypeProviderHeader)
+			}
+		}
+		canonical := http.CanonicalHeaderKey(name)
+		if prev, ok := seen[canonical]; ok {
+			r.Warnf(field, validation.CodeDuplicate, "header duplicates %q; only one value is sent", prev)
+		}
+		seen[canonical] = name
+		if apiKeyHeader != "" && strings.EqualFold(name, apiKeyHeader) {
+			r.Warnf(field, validation.CodeConflict, "header is replaced by the API key header")
 		}
-		seenModel[mid] = string(mid)
 	}
+}
 
-	// DefaultModelPresetID must exist if set.
-	if pp.DefaultModelPresetID != "" {
-		if _, ok := pp.ModelPresets[pp.DefaultModelPresetID]; !ok {
-			return fmt.Errorf("provider %q: defaultModelPresetID %q not present: %w",
-				pp.Name, pp.DefaultModelPresetID, spec.ErrModelPresetNotFound)
+// isHTTPHeaderName reports whether name is an RFC 9110 token.
+func isHTTPHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
 		}
 	}
-	return nil
+	return true
 }
 
-// validateModelPreset performs structural validation for a single model preset.
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// validateModelPreset performs structural validation for a single model
+// preset. It reports every invalid field as validation.Errors.
 func validateModelPreset(mp *spec.ModelPreset) error {
 	if mp == nil {
 		return spec.ErrNilModelPreset
 	}
+	r := &validation.Report{}
+	checkModelPreset(r, mp, true, false)
+	return r.Err()
+}
+
+// validateAuthoredModelPreset is validateModelPreset for a preset the user
+// created or whose sampling was patched, which must also set reasoning or
+// temperature. Discovered presets may set neither, so the provider's defaults
+// apply to them.
+func validateAuthoredModelPreset(mp *spec.ModelPreset) error {
+	if mp == nil {
+		return spec.ErrNilModelPreset
+	}
+	r := &validation.Report{}
+	checkModelPreset(r, mp, true, true)
+	return r.Err()
+}
+
+func checkModelPreset(r *validation.Report, mp *spec.ModelPreset, checkTimestamps, requireSampling bool) {
 	if mp.SchemaVersion != spec.SchemaVersion {
-		return fmt.Errorf("schemaVersion %q not equal to %q",
+		r.Addf("schemaVersion", validation.CodeUnsupported, "schemaVersion %q not equal to %q",
 			mp.SchemaVersion, spec.SchemaVersion)
 	}
-	if err := validateModelPresetID(mp.ID); err != nil {
-		return err
-	}
-	if err := validateModelName(mp.Name); err != nil {
-		return err
-	}
-	if err := validateModelSlug(mp.Slug); err != nil {
-		return err
-	}
+	r.Check("id", validation.CodeInvalid, validateModelPresetID(mp.ID))
+	r.Check("name", validation.CodeInvalid, validateModelName(mp.Name))
+	r.Check("slug", validation.CodeInvalid, validateModelSlug(mp.Slug))
 	if strings.TrimSpace(string(mp.DisplayName)) == "" {
-		return errors.New("displayName is empty")
+		r.Addf("displayName", validation.CodeRequired, "displayName is empty")
+	}
+	r.Checkf("tags", validation.CodeInvalid, "invalid tags", bundleitemutils.ValidateTags(mp.Tags))
+	r.Checkf("pricing", validation.CodeInvalid, "invalid pricing", validateModelPricing(mp.Pricing))
+	if checkTimestamps && mp.CreatedAt.IsZero() {
+		r.Check("createdAt", validation.CodeRequired, spec.ErrInvalidTimestamp)
 	}
-	if mp.CreatedAt.IsZero() || mp.ModifiedAt.IsZero() {
-		return spec.ErrInvalidTimestamp
+	if checkTimestamps && mp.ModifiedAt.IsZero() {
+		r.Check("modifiedAt", validation.CodeRequired, spec.ErrInvalidTimestamp)
 	}
 
-	// Either Reasoning or Temperature must be provided (both cannot be nil).
-	if mp.Reasoning == nil && mp.Temperature == nil {
-		return errors.New("either reasoning or temperature must be set")
+	if requireSampling && mp.Reasoning == nil && mp.Temperature == nil {
+		r.Addf("temperature", validation.CodeRequired, "either reasoning or temperature must be set")
+	}
+	if mp.Temperature != nil && (*mp.Temperature < 0 || *mp.Temperature > 2) {
+		r.Warnf("temperature", validation.CodeOutOfRange,
+			"temperature %v is outside the usual 0-2 range", *mp.Temperature)
 	}
 
 	if mp.MaxPromptLength != nil && *mp.MaxPromptLength < 0 {
-		return errors.New("maxPromptLength must be >= 0")
+		r.Addf("maxPromptLength", validation.CodeOutOfRange, "maxPromptLength must be >= 0")
 	}
 	if mp.MaxOutputLength != nil && *mp.MaxOutputLength < 0 {
-		return errors.New("maxOutputLength must be >= 0")
+		r.Addf("maxOutputLength", validation.CodeOutOfRange, "maxOutputLength must be >= 0")
 	}
 	if mp.Timeout != nil && *mp.Timeout < 0 {
-		return errors.New("timeout must be >= 0")
-	}
-
-	if err := validateCacheControl(mp.CacheControl); err != nil {
-		return fmt.Errorf("invalid cacheControl: %w", err)
+		r.Addf("timeout", validation.CodeOutOfRange, "timeout must be >= 0")
 	}
 
-	// Reasoning checks (optional).
+	r.Checkf("cacheControl", validation.CodeInvalid, "invalid cacheControl", validateCacheControl(mp.CacheControl))
 	if mp.Reasoning != nil {
-		if err := validateReasoning(mp.Reasoning); err != nil {
-			return fmt.Errorf("invalid reasoning: %w", err)
-		}
+		r.Checkf("reasoning", validation.CodeInvalid, "invalid reasoning", validateReasoning(mp.Reasoning))
 	}
-
 	if mp.OutputParam != nil {
-		if err := validateOutputParam(mp.OutputParam); err != nil {
-			return fmt.Errorf("invalid outputParam: %w", err)
-		}
+		r.Checkf("outputParam", validation.CodeInvalid, "invalid outputParam", validateOutputParam(mp.OutputParam))
 	}
+	r.Checkf("stopSequences", validation.CodeInvalid, "invalid stopSequences",
+		validateStopSequences(mp.StopSequences))
+	r.Checkf("systemPrompt", validation.CodeInvalid, "invalid systemPrompt", validateSystemPrompt(mp.SystemPrompt))
+	r.Checkf("capabilitiesOverride", validation.CodeInvalid, "capabilitiesOverride",
+		capabilityoverride.ValidateModelCapabilitiesOverride(mp.CapabilitiesOverride))
+}
 
-	if err := validateStopSequences(mp.StopSequences); err != nil {
-		return fmt.Errorf("invalid stopSequences: %w", err)
+// validateSystemPrompt bounds the prompt and rejects placeholders that would
+// be sent to the model unexpanded.
+func validateSystemPrompt(prompt *string) error {
+	if prompt == nil {
+		return nil
 	}
-
-	if err := capabilityoverride.ValidateModelCapabilitiesOverride(mp.CapabilitiesOverride); err != nil {
-		return fmt.Errorf("capabilitiesOverride: %w", err)
+	if len(*prompt) > spec.MaxSystemPromptLength {
+		return fmt.Errorf("length %d exceeds %d bytes", len(*prompt), spec.MaxSystemPromptLength)
+	}
+	for _, name := range spec.SystemPromptPlaceholders(*prompt) {
+		if !spec.IsSystemPromptPlaceholder(name) {
+			return fmt.Errorf("unsupported placeholder {{%s}}", name)
+		}
 	}
-
 	return nil
 }
 
@@ -265,8 +447,10 @@ func validateModelPresetID(id spec.ModelPresetID) error {
 func validateReasoning(r *inferenceSpec.ReasoningParam) error {
 	switch r.Type {
 	case inferenceSpec.ReasoningTypeHybridWithTokens:
-		if r.Tokens <= 0 {
-			return errors.New("tokens must be >0 for hybridWithTokens")
+		// 0 (off) and -1 (dynamic) are checked against the model's budget
+		// capabilities by validateReasoningForSDK.
+		if r.Tokens < -1 {
+			return errors.New("tokens must be >0, or 0 or -1 where the model allows them, for hybridWithTokens")
 		}
 	case inferenceSpec.ReasoningTypeSingleWithLevels:
 		switch r.Level {
@@ -323,3 +507,118 @@ func validateCacheControl(cc *inferenceSpec.CacheControl) error {
 	}
 	return nil
 }
+
+func validateModelPricing(p *spec.ModelPricing) error {
+	if p == nil {
+		return nil
+	}
+	check := func(name string, v float64) error {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%s must be a finite number >= 0", name)
+		}
+		return nil
+	}
+	return errors.Join(
+		check("inputPerMTok", p.InputPerMTok),
+		check("cachedInputPerMTok", p.CachedInputPerMTok),
+		check("outputPerMTok", p.OutputPerMTok),
+	)
+}
+
+func validateProviderRateLimits(l *spec.ProviderRateLimits) error {
+	if l == nil {
+		return nil
+	}
+	check := func(name string, v int) error {
+		if v < 0 {
+			return fmt.Errorf("%s must be >= 0", name)
+		}
+		return nil
+	}
+	return errors.Join(
+		check("requestsPerMinute", l.RequestsPerMinute),
+		check("tokensPerMinute", l.TokensPerMinute),
+		check("maxQueueDepth", l.MaxQueueDepth),
+	)
+}
+
+// validateProviderTLS checks the shape of the TLS settings only. The CA file
+// and the key secrets are loaded, and reported, when the provider is added.
+func validateProviderTLS(c *spec.ProviderTLSConfig) error {
+	if c == nil {
+		return nil
+	}
+	var errs []error
+	if c.CABundlePath != "" && !filepath.IsAbs(c.CABundlePath) {
+		errs = append(errs, fmt.Errorf("caBundlePath %q must be absolute", c.CABundlePath))
+	}
+	if (c.ClientCertKeyName == "") != (c.ClientKeyKeyName == "") {
+		errs = append(errs, errors.New("clientCertKeyName and clientKeyKeyName must be set together"))
+	}
+	for _, name := range []string{c.ClientCertKeyName, c.ClientKeyKeyName} {
+		if name != strings.TrimSpace(name) {
+			errs = append(errs, fmt.Errorf("auth key name %q has surrounding whitespace", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+const maxImageDimension = 16384
+
+func validateProviderImageProcessing(c *spec.ProviderImageProcessing) error {
+	if c == nil {
+		return nil
+	}
+	var errs []error
+	if c.MaxDimension < 0 || c.MaxDimension > maxImageDimension {
+		errs = append(errs, fmt.Errorf("maxDimension must be between 0 and %d", maxImageDimension))
+	}
+	switch c.Format {
+	case "", spec.ImageFormatPNG, spec.ImageFormatJPEG:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"format %q must be %q or %q", c.Format, spec.ImageFormatPNG, spec.ImageFormatJPEG))
+	}
+	if c.JPEGQuality < 0 || c.JPEGQuality > 100 {
+		errs = append(errs, errors.New("jpegQuality must be between 0 and 100"))
+	}
+	return errors.Join(errs...)
+}
+
+const maxRetryAttempts = 10
+
+func validateProviderResilience(name inferenceSpec.ProviderName, r *spec.ProviderResilience) error {
+	if r == nil {
+		return nil
+	}
+	if p := r.Retry; p != nil {
+		if p.MaxAttempts < 0 || p.MaxAttempts > maxRetryAttempts {
+			return fmt.Errorf("retry.maxAttempts must be between 0 and %d", maxRetryAttempts)
+		}
+		if p.InitialBackoffMS < 0 || p.MaxBackoffMS < 0 {
+			return errors.New("retry backoffs must be >= 0")
+		}
+		if p.MaxBackoffMS > 0 && p.InitialBackoffMS > p.MaxBackoffMS {
+			return errors.New("retry.initialBackoffMS exceeds retry.maxBackoffMS")
+		}
+		for _, code := range p.RetryOnStatusCodes {
+			if code < 100 || code > 599 {
+				return fmt.Errorf("retry.retryOnStatusCodes: invalid HTTP status %d", code)
+			}
+		}
+	}
+	seen := make(map[spec.ModelPresetRef]bool, len(r.Failover))
+	for i, ref := range r.Failover {
+		if ref.IsZero() {
+			return fmt.Errorf("failover[%d]: providerName and modelPresetID required", i)
+		}
+		if ref.ProviderName == name {
+			return fmt.Errorf("failover[%d]: must reference another provider", i)
+		}
+		if seen[ref] {
+			return fmt.Errorf("failover[%d]: duplicate %s/%s", i, ref.ProviderName, ref.ModelPresetID)
+		}
+		seen[ref] = true
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	"github.com/flexigpt/flexigpt-app/internal/validation"
+)
+
+const reachabilityTimeout = 10 * time.Second
+
+// ValidateProviderPreset runs the checks writes are held to and reports all
+// findings per field, together with warnings about values that are accepted
+// but likely wrong.
+func (s *ModelPresetStore) ValidateProviderPreset(
+	ctx context.Context, req *spec.ValidateProviderPresetRequest,
+) (*spec.ValidateProviderPresetResponse, error) {
+	if req == nil || req.ProviderName == "" {
+		return nil, fmt.Errorf("%w: providerName required", spec.ErrInvalidDir)
+	}
+	body := req.Body
+	if body == nil {
+		body = &spec.ValidateProviderPresetRequestBody{}
+	}
+
+	var pp spec.ProviderPreset
+	candidate := body.Preset != nil
+	if candidate {
+		pp = cloneProviderPreset(*body.Preset)
+		if pp.Name == "" {
+			pp.Name = req.ProviderName
+		}
+	} else if builtIn, err := s.builtinData.GetBuiltInProvider(ctx, req.ProviderName); err == nil {
+		pp = builtIn
+	} else {
+		s.mu.RLock()
+		all, err := s.readAllUserPresets()
+		s.mu.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+		if pp, err = getUserProviderPreset(all, req.ProviderName); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &validation.Report{}
+	checkProviderPreset(r, &pp, !candidate)
+	if body.CheckReachability && r.OK() {
+		s.checkReachability(ctx, r, &pp)
+	}
+	return &spec.ValidateProviderPresetResponse{
+		Body: &spec.ValidateProviderPresetResponseBody{
+			ProviderName: pp.Name,
+			Valid:        r.OK(),
+			Issues:       r.Issues(),
+		},
+	}, nil
+}
+
+// checkReachability warns about an origin that cannot be connected to over
+// the provider's transport. Any HTTP response, including an error status,
+// counts as reachable.
+func (s *ModelPresetStore) checkReachability(ctx context.Context, r *validation.Report, pp *spec.ProviderPreset) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodHead, strings.TrimSpace(pp.Origin), nil)
+	if err != nil {
+		r.Warnf("origin", validation.CodeInvalid, "cannot probe origin: %v", err)
+		return
+	}
+	resp, err := s.providerHTTPClient(pp.Name, reachabilityTimeout).Do(httpReq)
+	if err != nil {
+		r.Warnf("origin", validation.CodeInvalid, "origin is unreachable: %v", err)
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,200 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/fsutil"
+	"github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+// Sync clients write a file in several steps; wait for them to finish.
+const fileWatchDebouncePresets = 250 * time.Millisecond
+
+// PresetChangeListener receives committed preset changes. Listeners run on a
+// single dispatch goroutine in commit order and may call back into the store.
+type PresetChangeListener func(event spec.PresetChangeEvent)
+
+// presetNotifier queues events without blocking writers, which publish while
+// holding the store lock, and delivers them from the dispatch goroutine.
+type presetNotifier struct {
+	mu        sync.Mutex
+	nextID    uint64
+	listeners map[uint64]PresetChangeListener
+	queue     []spec.PresetChangeEvent
+	kick      chan struct{}
+}
+
+// Subscribe registers listener for preset change events and returns a func
+// that unregisters it.
+func (s *ModelPresetStore) Subscribe(listener PresetChangeListener) (unsubscribe func()) {
+	if s == nil || listener == nil {
+		return func() {}
+	}
+	n := &s.notifier
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.listeners == nil {
+		n.listeners = map[uint64]PresetChangeListener{}
+	}
+	n.nextID++
+	id := n.nextID
+	n.listeners[id] = listener
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			n.mu.Lock()
+			defer n.mu.Unlock()
+			delete(n.listeners, id)
+		})
+	}
+}
+
+// notify queues one event per target. Targets without a model preset ID are
+// provider-level events.
+func (s *ModelPresetStore) notify(
+	kind spec.PresetChangeKind,
+	providerName inferenceSpec.ProviderName,
+	modelPresetIDs ...spec.ModelPresetID,
+) {
+	if len(modelPresetIDs) == 0 {
+		s.enqueuePresetChanges(spec.PresetChangeEvent{Kind: kind, ProviderName: providerName})
+		return
+	}
+	events := make([]spec.PresetChangeEvent, 0, len(modelPresetIDs))
+	for _, id := range modelPresetIDs {
+		events = append(events, spec.PresetChangeEvent{
+			Kind: kind, ProviderName: providerName, ModelPresetID: id,
+		})
+	}
+	s.enqueuePresetChanges(events...)
+}
+
+// notifyEmbeddingPreset queues an embedding preset event.
+func (s *ModelPresetStore) notifyEmbeddingPreset(kind spec.PresetChangeKind, ep spec.EmbeddingPreset) {
+	s.enqueuePresetChanges(spec.PresetChangeEvent{
+		Kind: kind, ProviderName: ep.ProviderName, EmbeddingPresetID: ep.ID,
+	})
+}
+
+func (s *ModelPresetStore) enqueuePresetChanges(events ...spec.PresetChangeEvent) {
+	n := &s.notifier
+	n.mu.Lock()
+	if len(n.listeners) == 0 {
+		n.mu.Unlock()
+		return
+	}
+	now := time.Now().UTC()
+	for _, ev := range events {
+		ev.At = now
+		n.queue = append(n.queue, ev)
+	}
+	n.mu.Unlock()
+
+	select {
+	case n.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (s *ModelPresetStore) startNotifier() {
+	s.notifier.kick = make(chan struct{}, 1)
+	s.wg.Go(func() {
+		for {
+			select {
+			case <-s.cleanCtx.Done():
+				return
+			case <-s.notifier.kick:
+			}
+			s.dispatchPresetChanges()
+		}
+	})
+}
+
+func (s *ModelPresetStore) dispatchPresetChanges() {
+	n := &s.notifier
+	n.mu.Lock()
+	events := n.queue
+	n.queue = nil
+	listeners := make([]PresetChangeListener, 0, len(n.listeners))
+	for _, l := range n.listeners {
+		listeners = append(listeners, l)
+	}
+	n.mu.Unlock()
+
+	for _, ev := range events {
+		for _, l := range listeners {
+			deliverPresetChange(l, ev)
+		}
+	}
+}
+
+func deliverPresetChange(listener PresetChangeListener, ev spec.PresetChangeEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("preset change listener: panic", "panic", r, "kind", ev.Kind)
+		}
+	}()
+	listener(ev)
+}
+
+// startFileWatch notifies subscribers when the presets file changes outside
+// the app. Reads always reload a changed file, so nothing else is refreshed.
+func (s *ModelPresetStore) startFileWatch() {
+	fw, err := fsutil.NewFileWatcher(s.userFilePath(), fileWatchDebouncePresets)
+	if err != nil {
+		logger.Warn("model presets file watch unavailable", "err", err)
+		return
+	}
+	last, err := fsutil.ReadFileState(s.userFilePath())
+	if err != nil {
+		logger.Warn("model presets file state", "err", err)
+	}
+	s.wg.Go(func() {
+		fw.Run(
+			s.cleanCtx,
+			func() { last = s.reloadIfChangedExternally(last) },
+			func(err error) { logger.Warn("model presets file watch", "err", err) },
+		)
+	})
+}
+
+// reloadIfChangedExternally validates the presets file if its content differs
+// from last and publishes a reloaded event unless this store wrote it. It
+// returns the state to compare the next change with.
+func (s *ModelPresetStore) reloadIfChangedExternally(last fsutil.FileState) fsutil.FileState {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("reloadIfChangedExternally: panic", "panic", r)
+		}
+	}()
+
+	// Writers hold mu until they recorded the written state.
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st, err := fsutil.ReadFileState(s.userFilePath())
+	if err != nil || !st.Exists || st.SameContent(last) {
+		return last
+	}
+	s.fileStateMu.Lock()
+	own := st.SameContent(s.writtenFileState)
+	s.fileStateMu.Unlock()
+	if own {
+		return st
+	}
+
+	// Reads trust the size and mtime; record the edit so they reload it.
+	if _, err := s.observeUserFile(true); err != nil {
+		logger.Error("model presets file state", "file", s.userFilePath(), "err", err)
+		return st
+	}
+	if _, err := s.readAllUserPresets(); err != nil {
+		logger.Error("model presets: external modification is invalid", "file", s.userFilePath(), "err", err)
+		return st
+	}
+	logger.Info("model presets reloaded after external modification", "file", s.userFilePath())
+	s.enqueuePresetChanges(spec.PresetChangeEvent{Kind: spec.PresetChangeReloaded})
+	return st
+}
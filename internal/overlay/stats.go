@@ -0,0 +1,61 @@
+package overlay
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+const sqlCountFlagsByGroup = `
+SELECT g.group_id, COUNT(f.key_id)
+  FROM groups g
+  LEFT JOIN flags f ON f.group_id = g.group_id
+ GROUP BY g.group_id;`
+
+// DBStats summarizes an overlay database file.
+type DBStats struct {
+	Path      string          `json:"path"`
+	SizeBytes int64           `json:"sizeBytes"`
+	Flags     map[GroupID]int `json:"flags"`
+}
+
+// ReadDBStats counts the flags per group of the database at path. The file is
+// opened read-only, so it can be read while a Store has it open.
+func ReadDBStats(ctx context.Context, path string) (DBStats, error) {
+	if _, err := os.Stat(path); err != nil {
+		return DBStats{}, err
+	}
+	db, err := sql.Open("sqlite", path+"?busy_timeout=5000&_pragma=query_only(1)")
+	if err != nil {
+		return DBStats{}, fmt.Errorf("overlay: open sqlite: %w", err)
+	}
+	defer db.Close()
+
+	return readDBStats(ctx, db, path)
+}
+
+func readDBStats(ctx context.Context, db *sql.DB, path string) (DBStats, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return DBStats{}, err
+	}
+	rows, err := db.QueryContext(ctx, sqlCountFlagsByGroup)
+	if err != nil {
+		return DBStats{}, err
+	}
+	defer rows.Close()
+
+	out := DBStats{Path: path, SizeBytes: fi.Size(), Flags: map[GroupID]int{}}
+	for rows.Next() {
+		var (
+			group string
+			n     int
+		)
+		if err := rows.Scan(&group, &n); err != nil {
+			return DBStats{}, err
+		}
+		out.Flags[GroupID(group)] = n
+	}
+	return out, rows.Err()
+}
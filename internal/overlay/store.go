@@ -53,12 +53,24 @@ UPDATE flags
 DELETE FROM flags
  WHERE group_id = ?
    AND key_id   = ?;`
+
+	sqlSelectGroupFlags = `
+SELECT key_id, value, created_at, modified_at
+  FROM flags
+ WHERE group_id = ?;`
 )
 
+// sqlConn is the subset of *sql.DB and *sql.Tx the flag queries need.
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type Store struct {
-	mu  sync.RWMutex
-	db  *sql.DB
-	reg map[GroupID]struct{}
+	mu   sync.RWMutex
+	db   *sql.DB
+	path string
+	reg  map[GroupID]struct{}
 
 	closeOnce sync.Once
 	closeErr  error
@@ -107,8 +119,9 @@ func NewOverlayStore(ctx context.Context, path string, opts ...Option) (*Store,
 	}
 
 	st := &Store{
-		db:  db,
-		reg: make(map[GroupID]struct{}),
+		db:   db,
+		path: path,
+		reg:  make(map[GroupID]struct{}),
 	}
 	for _, opt := range opts {
 		if err := opt(st); err != nil {
@@ -132,7 +145,7 @@ func (s *Store) GetFlag(ctx context.Context, k Key) (Flag, bool, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	return s.getFlagSQL(ctx, k.Group(), k.ID())
+	return s.getFlagSQL(ctx, s.db, k.Group(), k.ID())
 }
 
 func (s *Store) SetFlag(ctx context.Context, k Key, val json.RawMessage) (Flag, error) {
@@ -143,7 +156,39 @@ func (s *Store) SetFlag(ctx context.Context, k Key, val json.RawMessage) (Flag,
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.setFlagSQL(ctx, k.Group(), k.ID(), val, time.Now().UTC())
+	return s.setFlagSQL(ctx, s.db, k.Group(), k.ID(), val, time.Now().UTC())
+}
+
+// SetFlags stores val under every key in one transaction: either all flags
+// are written or none is. Flags are returned in key order.
+func (s *Store) SetFlags(ctx context.Context, keys []Key, val json.RawMessage) ([]Flag, error) {
+	for _, k := range keys {
+		if err := s.ensureRegistered(k.Group()); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	out := make([]Flag, 0, len(keys))
+	for _, k := range keys {
+		f, err := s.setFlagSQL(ctx, tx, k.Group(), k.ID(), val, now)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
 func (s *Store) DeleteKey(ctx context.Context, k Key) error {
@@ -163,6 +208,93 @@ func (s *Store) DeleteKey(ctx context.Context, k Key) error {
 	return err
 }
 
+// PruneFlags deletes, in one transaction, the flags of group for which prune
+// reports true, and returns how many it deleted. An error from prune aborts
+// without deleting anything.
+func (s *Store) PruneFlags(
+	ctx context.Context,
+	group GroupID,
+	prune func(key KeyID, flag Flag) (bool, error),
+) (int, error) {
+	if err := s.ensureRegistered(group); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, sqlSelectGroupFlags, string(group))
+	if err != nil {
+		return 0, err
+	}
+	var doomed []KeyID
+	for rows.Next() {
+		var (
+			key  string
+			flag Flag
+			raw  []byte
+		)
+		if err := rows.Scan(&key, &raw, &flag.CreatedAt, &flag.ModifiedAt); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		flag.Value = json.RawMessage(raw)
+		ok, err := prune(KeyID(key), flag)
+		if err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("overlay: prune %s/%s: %w", group, key, err)
+		}
+		if ok {
+			doomed = append(doomed, KeyID(key))
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, key := range doomed {
+		if _, err := tx.ExecContext(ctx, sqlDeleteFlag, string(group), string(key)); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(doomed), nil
+}
+
+// Vacuum rewrites the database to release the pages of deleted flags and
+// truncates the write-ahead log.
+func (s *Store) Vacuum(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.ExecContext(ctx, "VACUUM;"); err != nil {
+		return fmt.Errorf("overlay: vacuum: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE);"); err != nil {
+		return fmt.Errorf("overlay: checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Stats counts the flags per group of the open database.
+func (s *Store) Stats(ctx context.Context) (DBStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return readDBStats(ctx, s.db, s.path)
+}
+
 // Close blocks until any in-flight Get/Set/Delete finishes (they hold s.mu),
 // then closes the underlying *sql.DB exactly once.
 func (s *Store) Close() error {
@@ -188,18 +320,19 @@ func (s *Store) ensureRegistered(group GroupID) error {
 
 func (s *Store) setFlagSQL(
 	ctx context.Context,
+	conn sqlConn,
 	group GroupID,
 	key KeyID,
 	val json.RawMessage,
 	now time.Time,
 ) (Flag, error) {
-	old, exists, err := s.getFlagSQL(ctx, group, key)
+	old, exists, err := s.getFlagSQL(ctx, conn, group, key)
 	if err != nil {
 		return Flag{}, err
 	}
 
 	if !exists {
-		_, err = s.db.ExecContext(ctx, sqlInsertFlag,
+		_, err = conn.ExecContext(ctx, sqlInsertFlag,
 			string(group), string(key), []byte(val), now, now)
 		if err != nil {
 			return Flag{}, err
@@ -207,7 +340,7 @@ func (s *Store) setFlagSQL(
 		return Flag{Value: val, CreatedAt: now, ModifiedAt: now}, nil
 	}
 
-	_, err = s.db.ExecContext(ctx, sqlUpdateFlag,
+	_, err = conn.ExecContext(ctx, sqlUpdateFlag,
 		[]byte(val), now, string(group), string(key))
 	if err != nil {
 		return Flag{}, err
@@ -215,12 +348,12 @@ func (s *Store) setFlagSQL(
 	return Flag{Value: val, CreatedAt: old.CreatedAt, ModifiedAt: now}, nil
 }
 
-func (s *Store) getFlagSQL(ctx context.Context, group GroupID, key KeyID) (Flag, bool, error) {
+func (s *Store) getFlagSQL(ctx context.Context, conn sqlConn, group GroupID, key KeyID) (Flag, bool, error) {
 	var (
 		raw               []byte
 		created, modified time.Time
 	)
-	err := s.db.QueryRowContext(ctx, sqlSelectFlag, string(group), string(key)).
+	err := conn.QueryRowContext(ctx, sqlSelectFlag, string(group), string(key)).
 		Scan(&raw, &created, &modified)
 
 	switch err {
@@ -166,6 +166,82 @@ func TestPersistenceAcrossReopen(t *testing.T) {
 	}
 }
 
+func TestReadDBStats(t *testing.T) {
+	st, path := tmpStore(t, WithKeyType[BundleID](), WithKeyType[TemplateID]())
+	for _, id := range []string{"a", "b"} {
+		if _, err := st.SetFlag(t.Context(), BundleID(id), marshalBool(true)); err != nil {
+			t.Fatalf("set: %v", err)
+		}
+	}
+
+	stats, err := ReadDBStats(t.Context(), path)
+	if err != nil {
+		t.Fatalf("ReadDBStats: %v", err)
+	}
+	if stats.Flags["bundles"] != 2 || stats.Flags["templates"] != 0 || len(stats.Flags) != 2 || stats.SizeBytes == 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	// The store stays writable while stats are read.
+	if _, err := st.SetFlag(t.Context(), TemplateID("t"), marshalBool(false)); err != nil {
+		t.Fatalf("set after stats: %v", err)
+	}
+	if _, err := ReadDBStats(t.Context(), filepath.Join(t.TempDir(), "missing.db")); !os.IsNotExist(err) {
+		t.Fatalf("missing file: %v", err)
+	}
+}
+
+func TestPruneFlagsAndVacuum(t *testing.T) {
+	st, path := tmpStore(t, WithKeyType[BundleID](), WithKeyType[TemplateID]())
+	ctx := t.Context()
+	for i := range 50 {
+		if _, err := st.SetFlag(ctx, BundleID("b"+strconv.Itoa(i)), marshalBool(i%5 == 0)); err != nil {
+			t.Fatalf("set: %v", err)
+		}
+	}
+	if _, err := st.SetFlag(ctx, TemplateID("t"), marshalBool(false)); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	bundles, err := NewTypedGroup[BundleID, bool](ctx, st)
+	if err != nil {
+		t.Fatalf("NewTypedGroup: %v", err)
+	}
+	n, err := bundles.PruneFlags(ctx, func(_ KeyID, flag TypedFlag[bool]) bool { return !flag.Value })
+	if err != nil || n != 40 {
+		t.Fatalf("PruneFlags = %d, %v; want 40", n, err)
+	}
+	if err := st.Vacuum(ctx); err != nil {
+		t.Fatalf("Vacuum: %v", err)
+	}
+	stats, err := st.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Path != path || stats.Flags["bundles"] != 10 || stats.Flags["templates"] != 1 || stats.SizeBytes == 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if ok, _ := getEnabled(t, st, BundleID("b5"), false); !ok {
+		t.Fatal("kept flag lost its value")
+	}
+
+	// A failing prune deletes nothing.
+	_, err = st.PruneFlags(ctx, "bundles", func(key KeyID, _ Flag) (bool, error) {
+		if key == "b10" {
+			return false, os.ErrInvalid
+		}
+		return true, nil
+	})
+	if err == nil {
+		t.Fatal("expected prune error")
+	}
+	if stats, _ := st.Stats(ctx); stats.Flags["bundles"] != 10 {
+		t.Fatalf("failed prune deleted flags: %+v", stats)
+	}
+	if _, err := st.PruneFlags(ctx, "other", nil); err == nil {
+		t.Fatal("expected error for unregistered group")
+	}
+}
+
 func TestConcurrentAccess(t *testing.T) {
 	const n = 100
 	st, _ := tmpStore(t, WithKeyType[BundleID]())
@@ -321,6 +397,45 @@ func TestTypedGroup_Bool(t *testing.T) {
 	}
 }
 
+func TestTypedGroup_SetFlags(t *testing.T) {
+	st, _ := tmpStore(t, WithKeyType[BundleID](), WithKeyType[TemplateID]())
+	defer st.db.Close()
+	grp, err := NewTypedGroup[BundleID, bool](t.Context(), st)
+	if err != nil {
+		t.Fatalf("NewTypedGroup: %v", err)
+	}
+	if _, err := grp.SetFlag(t.Context(), "a", false); err != nil {
+		t.Fatalf("SetFlag: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	got, err := grp.SetFlags(t.Context(), []BundleID{"a", "b"}, true)
+	if err != nil {
+		t.Fatalf("SetFlags: %v", err)
+	}
+	if len(got) != 2 || !got[0].Value || !got[1].Value {
+		t.Fatalf("unexpected flags: %+v", got)
+	}
+	if !got[0].ModifiedAt.After(got[0].CreatedAt) || !got[1].CreatedAt.Equal(got[1].ModifiedAt) {
+		t.Fatalf("unexpected timestamps: %+v", got)
+	}
+	for _, k := range []BundleID{"a", "b"} {
+		tf, ok, err := grp.GetFlag(t.Context(), k)
+		if err != nil || !ok || !tf.Value {
+			t.Fatalf("GetFlag(%s): %v %v %v", k, err, ok, tf.Value)
+		}
+	}
+
+	// An unregistered key fails the whole batch before anything is written.
+	_, err = st.SetFlags(t.Context(), []Key{BundleID("d"), OtherID("x")}, marshalBool(true))
+	if err == nil {
+		t.Fatal("expected error for unregistered group")
+	}
+	if _, ok, _ := grp.GetFlag(t.Context(), "d"); ok {
+		t.Fatal("flag written despite failed batch")
+	}
+}
+
 func TestTypedGroup_IntStringStructSliceMap(t *testing.T) {
 	st, _ := tmpStore(t, WithKeyType[TemplateID]())
 	defer st.db.Close()
@@ -81,7 +81,57 @@ func (g *TypedGroup[K, ValT]) SetFlag(
 	}, nil
 }
 
+// SetFlags stores one typed value under all keys in one transaction.
+func (g *TypedGroup[K, ValT]) SetFlags(
+	ctx context.Context,
+	keys []K,
+	value ValT,
+) ([]TypedFlag[ValT], error) {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	anyKeys := make([]Key, 0, len(keys))
+	for _, k := range keys {
+		anyKeys = append(anyKeys, k)
+	}
+
+	flags, err := g.store.SetFlags(ctx, anyKeys, jsonValue)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]TypedFlag[ValT], 0, len(flags))
+	for _, f := range flags {
+		out = append(out, TypedFlag[ValT]{
+			Value:      value,
+			CreatedAt:  f.CreatedAt,
+			ModifiedAt: f.ModifiedAt,
+		})
+	}
+	return out, nil
+}
+
 // DeleteKey removes an entry from the group.
 func (g *TypedGroup[K, ValT]) DeleteKey(ctx context.Context, k K) error {
 	return g.store.DeleteKey(ctx, k)
 }
+
+// PruneFlags deletes the flags of the group for which prune reports true and
+// returns how many were deleted. A value that does not decode aborts the
+// prune.
+func (g *TypedGroup[K, ValT]) PruneFlags(
+	ctx context.Context,
+	prune func(key KeyID, flag TypedFlag[ValT]) bool,
+) (int, error) {
+	return g.store.PruneFlags(ctx, g.groupID, func(key KeyID, flag Flag) (bool, error) {
+		var value ValT
+		if err := json.Unmarshal(flag.Value, &value); err != nil {
+			return false, err
+		}
+		return prune(key, TypedFlag[ValT]{
+			Value:      value,
+			CreatedAt:  flag.CreatedAt,
+			ModifiedAt: flag.ModifiedAt,
+		}), nil
+	})
+}
@@ -0,0 +1,70 @@
+// Package precondition implements optimistic concurrency for store writes: a
+// request names the ModifiedAt it last read, and the write is refused if the
+// stored entity changed since.
+package precondition
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrConflict is matched by every ConflictError.
+var ErrConflict = errors.New("modified since it was read")
+
+// ConflictError reports a failed expectedModifiedAt precondition together
+// with the entity as currently stored, so a caller can show or merge it.
+type ConflictError[T any] struct {
+	Expected   time.Time
+	ModifiedAt time.Time
+	Current    T
+}
+
+func (e *ConflictError[T]) Error() string {
+	return fmt.Sprintf("%v: expected modifiedAt %s, current %s",
+		ErrConflict, e.Expected.Format(time.RFC3339Nano), e.ModifiedAt.Format(time.RFC3339Nano))
+}
+
+func (e *ConflictError[T]) Unwrap() error { return ErrConflict }
+
+func (e *ConflictError[T]) ExpectedModifiedAt() time.Time { return e.Expected }
+func (e *ConflictError[T]) CurrentModifiedAt() time.Time  { return e.ModifiedAt }
+func (e *ConflictError[T]) CurrentValue() any             { return e.Current }
+
+// Conflict is a ConflictError of any entity type, for callers that pass the
+// stored entity on without knowing its type.
+type Conflict interface {
+	error
+	ExpectedModifiedAt() time.Time
+	CurrentModifiedAt() time.Time
+	CurrentValue() any
+}
+
+// AsConflict returns the ConflictError in err's chain, whatever its entity
+// type.
+func AsConflict(err error) (Conflict, bool) {
+	var c Conflict
+	if errors.As(err, &c) {
+		return c, true
+	}
+	return nil, false
+}
+
+// CheckModifiedAt returns a ConflictError carrying current if expected is set
+// and differs from modifiedAt. A nil expected always passes.
+func CheckModifiedAt[T any](expected *time.Time, modifiedAt time.Time, current T) error {
+	if expected == nil || expected.Equal(modifiedAt) {
+		return nil
+	}
+	return &ConflictError[T]{Expected: *expected, ModifiedAt: modifiedAt, Current: current}
+}
+
+// Current returns the entity carried by a ConflictError[T] in err's chain.
+func Current[T any](err error) (T, bool) {
+	var conflict *ConflictError[T]
+	if errors.As(err, &conflict) {
+		return conflict.Current, true
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,43 @@
+package precondition
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type item struct{ Name string }
+
+func TestCheckModifiedAt(t *testing.T) {
+	stored := time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.UTC)
+	current := item{Name: "current"}
+
+	if err := CheckModifiedAt(nil, stored, current); err != nil {
+		t.Fatalf("nil expected: %v", err)
+	}
+	same := stored.In(time.FixedZone("x", 3600))
+	if err := CheckModifiedAt(&same, stored, current); err != nil {
+		t.Fatalf("same instant in another zone: %v", err)
+	}
+
+	stale := stored.Add(-time.Second)
+	err := fmt.Errorf("wrapped: %w", CheckModifiedAt(&stale, stored, current))
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("err = %v, want ErrConflict", err)
+	}
+	got, ok := Current[item](err)
+	if !ok || got != current {
+		t.Fatalf("Current = %+v, %v; want %+v", got, ok, current)
+	}
+	if _, ok := Current[string](err); ok {
+		t.Fatalf("Current with another type matched")
+	}
+	c, ok := AsConflict(err)
+	if !ok || c.CurrentValue() != current || !c.CurrentModifiedAt().Equal(stored) {
+		t.Fatalf("AsConflict = %v, %v", c, ok)
+	}
+	if _, ok := AsConflict(errors.New("other")); ok {
+		t.Fatal("AsConflict matched a plain error")
+	}
+}
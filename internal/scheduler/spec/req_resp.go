@@ -0,0 +1,89 @@
+package spec
+
+import (
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+)
+
+type PostScheduledTaskRequestBody struct {
+	DisplayName    string                         `json:"displayName"            required:"true"`
+	Schedule       string                         `json:"schedule"               required:"true"`
+	PromptTemplate string                         `json:"promptTemplate"         required:"true"`
+	ModelPresetRef modelpresetSpec.ModelPresetRef `json:"modelPresetRef"         required:"true"`
+	SkillSession   *TaskSkillSession              `json:"skillSession,omitempty"`
+	IsEnabled      bool                           `json:"isEnabled"`
+}
+
+type PostScheduledTaskRequest struct {
+	Body *PostScheduledTaskRequestBody
+}
+
+type PostScheduledTaskResponse struct {
+	Body *ScheduledTask
+}
+
+// PatchScheduledTaskRequestBody patches a task. Nil fields are left
+// unchanged; ClearSkillSession removes the skill session config.
+type PatchScheduledTaskRequestBody struct {
+	DisplayName       *string                         `json:"displayName,omitempty"`
+	Schedule          *string                         `json:"schedule,omitempty"`
+	PromptTemplate    *string                         `json:"promptTemplate,omitempty"`
+	ModelPresetRef    *modelpresetSpec.ModelPresetRef `json:"modelPresetRef,omitempty"`
+	SkillSession      *TaskSkillSession               `json:"skillSession,omitempty"`
+	ClearSkillSession bool                            `json:"clearSkillSession,omitempty"`
+}
+
+type PatchScheduledTaskRequest struct {
+	TaskID string `path:"taskID" required:"true"`
+	Body   *PatchScheduledTaskRequestBody
+}
+
+type PatchScheduledTaskResponse struct {
+	Body *ScheduledTask
+}
+
+type DeleteScheduledTaskRequest struct {
+	TaskID string `path:"taskID" required:"true"`
+}
+
+type DeleteScheduledTaskResponse struct{}
+
+type GetScheduledTaskRequest struct {
+	TaskID string `path:"taskID" required:"true"`
+}
+
+type GetScheduledTaskResponse struct {
+	Body *ScheduledTask
+}
+
+type ListScheduledTasksRequest struct{}
+
+type ListScheduledTasksResponseBody struct {
+	Tasks []ScheduledTask `json:"tasks"`
+}
+
+type ListScheduledTasksResponse struct {
+	Body *ListScheduledTasksResponseBody
+}
+
+type ToggleScheduledTaskRequestBody struct {
+	IsEnabled bool `json:"isEnabled"`
+}
+
+type ToggleScheduledTaskRequest struct {
+	TaskID string `path:"taskID" required:"true"`
+	Body   *ToggleScheduledTaskRequestBody
+}
+
+type ToggleScheduledTaskResponse struct {
+	Body *ScheduledTask
+}
+
+// RunScheduledTaskNowRequest runs a task immediately, enabled or not. The
+// call returns when the run has finished; its schedule is unchanged.
+type RunScheduledTaskNowRequest struct {
+	TaskID string `path:"taskID" required:"true"`
+}
+
+type RunScheduledTaskNowResponse struct {
+	Body *ScheduledTask
+}
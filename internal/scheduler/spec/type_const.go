@@ -0,0 +1,90 @@
+package spec
+
+import (
+	"errors"
+	"time"
+
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	skillruntimeSpec "github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
+)
+
+const (
+	SchedulerStoreFileName = "scheduledtasks.json"
+	SchemaVersion          = "2026-10-15"
+
+	MaxScheduledTasks       = 128
+	MaxPromptTemplateLength = 32 * 1024
+)
+
+var (
+	ErrInvalidDir      = errors.New("invalid directory")
+	ErrInvalidRequest  = errors.New("invalid scheduled task request")
+	ErrInvalidSchedule = errors.New("invalid schedule")
+	ErrTaskNotFound    = errors.New("scheduled task not found")
+	ErrTaskRunning     = errors.New("scheduled task is already running")
+)
+
+// Placeholders a prompt template may contain, written as "{{name}}". They are
+// expanded when the task runs.
+const (
+	PromptPlaceholderDate     = "date"
+	PromptPlaceholderTime     = "time"
+	PromptPlaceholderWeekday  = "weekday"
+	PromptPlaceholderTaskName = "taskName"
+)
+
+type TaskRunStatus string
+
+const (
+	TaskRunStatusSucceeded TaskRunStatus = "succeeded"
+	TaskRunStatusFailed    TaskRunStatus = "failed"
+)
+
+// TaskRunTrigger says what started a run.
+type TaskRunTrigger string
+
+const (
+	TaskRunTriggerSchedule TaskRunTrigger = "schedule"
+	TaskRunTriggerManual   TaskRunTrigger = "manual"
+)
+
+// TaskSkillSession configures the skill session opened for each run. The
+// session is closed when the run ends.
+type TaskSkillSession struct {
+	AllowSkillRefs      []skillruntimeSpec.SkillRef `json:"allowSkillRefs,omitempty"`
+	ActiveSkillRefs     []skillruntimeSpec.SkillRef `json:"activeSkillRefs,omitempty"`
+	MaxActivePerSession int                         `json:"maxActivePerSession,omitempty"`
+}
+
+// TaskRun is the outcome of one run. A successful run stores its transcript
+// as a new conversation.
+type TaskRun struct {
+	Trigger        TaskRunTrigger `json:"trigger"`
+	Status         TaskRunStatus  `json:"status"`
+	StartedAt      time.Time      `json:"startedAt"`
+	FinishedAt     time.Time      `json:"finishedAt"`
+	ConversationID string         `json:"conversationID,omitempty"`
+	Error          string         `json:"error,omitempty"`
+}
+
+// ScheduledTask sends a prompt to a model preset on a recurring schedule.
+//
+// Schedule is a five-field cron expression (minute, hour, day of month,
+// month, day of week) evaluated in local time, or one of @hourly, @daily,
+// @weekly and @monthly. A run missed while the app was closed happens once
+// at the next start.
+type ScheduledTask struct {
+	ID             string                         `json:"id"`
+	DisplayName    string                         `json:"displayName"`
+	Schedule       string                         `json:"schedule"`
+	PromptTemplate string                         `json:"promptTemplate"`
+	ModelPresetRef modelpresetSpec.ModelPresetRef `json:"modelPresetRef"`
+	SkillSession   *TaskSkillSession              `json:"skillSession,omitempty"`
+	IsEnabled      bool                           `json:"isEnabled"`
+
+	CreatedAt  time.Time `json:"createdAt"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+	// NextRunAt is nil while the task is disabled.
+	NextRunAt *time.Time `json:"nextRunAt,omitempty"`
+	LastRun   *TaskRun   `json:"lastRun,omitempty"`
+}
@@ -0,0 +1,510 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/flexigpt/flexigpt-app/internal/logging"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	"github.com/flexigpt/flexigpt-app/internal/scheduler/spec"
+	"github.com/flexigpt/mapstore-go"
+	"github.com/flexigpt/mapstore-go/jsonencdec"
+)
+
+var logger = logging.Module("scheduler.store")
+
+const (
+	// tickInterval is how often due tasks are looked for. Schedules have
+	// minute resolution.
+	tickInterval = 30 * time.Second
+	// runTimeout bounds one run, including tool calls made by the model.
+	runTimeout = 15 * time.Minute
+)
+
+// TaskRunner sends the expanded prompt of a task and stores the transcript,
+// returning the ID of the conversation it wrote.
+type TaskRunner func(ctx context.Context, task spec.ScheduledTask, prompt string) (conversationID string, err error)
+
+type storeSchema struct {
+	SchemaVersion string                        `json:"schemaVersion"`
+	Tasks         map[string]spec.ScheduledTask `json:"tasks"`
+}
+
+// SchedulerStore keeps scheduled tasks in a JSON file and runs them from a
+// background loop.
+type SchedulerStore struct {
+	file *mapstore.MapFileStore
+	run  TaskRunner
+	now  func() time.Time
+
+	// mu serializes read-modify-write of the file.
+	mu sync.Mutex
+
+	runningMu sync.Mutex
+	running   map[string]bool
+
+	loopCtx  context.Context
+	loopStop context.CancelFunc
+	loopWG   sync.WaitGroup
+	runWG    sync.WaitGroup
+}
+
+func NewSchedulerStore(ctx context.Context, baseDir string, run TaskRunner) (*SchedulerStore, error) {
+	return newSchedulerStore(ctx, baseDir, run, time.Now)
+}
+
+func newSchedulerStore(
+	_ context.Context,
+	baseDir string,
+	run TaskRunner,
+	now func() time.Time,
+) (*SchedulerStore, error) {
+	if baseDir == "" {
+		return nil, fmt.Errorf("%w: baseDir is empty", spec.ErrInvalidDir)
+	}
+	if run == nil {
+		return nil, fmt.Errorf("%w: task runner is nil", spec.ErrInvalidRequest)
+	}
+	baseDir = filepath.Clean(baseDir)
+	if err := os.MkdirAll(baseDir, 0o755); err != nil {
+		return nil, err
+	}
+	def, err := jsonencdec.StructWithJSONTagsToMap(storeSchema{
+		SchemaVersion: spec.SchemaVersion,
+		Tasks:         map[string]spec.ScheduledTask{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	file, err := mapstore.NewMapFileStore(
+		filepath.Join(baseDir, spec.SchedulerStoreFileName),
+		def,
+		jsonencdec.JSONEncoderDecoder{},
+		mapstore.WithCreateIfNotExists(true),
+		mapstore.WithFileAutoFlush(true),
+		mapstore.WithFileLogger(logger),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SchedulerStore{
+		file:    file,
+		run:     run,
+		now:     now,
+		running: map[string]bool{},
+	}
+	s.loopCtx, s.loopStop = context.WithCancel(context.Background())
+	//nolint:contextcheck // Background loop.
+	s.loopWG.Go(s.loop)
+	return s, nil
+}
+
+// Close stops the loop and waits for in-flight runs, which are canceled.
+func (s *SchedulerStore) Close() error {
+	if s == nil || s.file == nil {
+		return nil
+	}
+	s.loopStop()
+	s.loopWG.Wait()
+	s.runWG.Wait()
+	return s.file.Close()
+}
+
+func (s *SchedulerStore) PostScheduledTask(
+	_ context.Context,
+	req *spec.PostScheduledTaskRequest,
+) (*spec.PostScheduledTaskResponse, error) {
+	if req == nil || req.Body == nil {
+		return nil, fmt.Errorf("%w: body required", spec.ErrInvalidRequest)
+	}
+	b := req.Body
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	t := spec.ScheduledTask{
+		ID:             id.String(),
+		DisplayName:    strings.TrimSpace(b.DisplayName),
+		Schedule:       strings.TrimSpace(b.Schedule),
+		PromptTemplate: b.PromptTemplate,
+		ModelPresetRef: b.ModelPresetRef,
+		SkillSession:   b.SkillSession,
+		IsEnabled:      b.IsEnabled,
+		CreatedAt:      now,
+		ModifiedAt:     now,
+	}
+	if err := s.schedule(&t); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(sc.Tasks) >= spec.MaxScheduledTasks {
+		return nil, fmt.Errorf("%w: at most %d tasks are supported", spec.ErrInvalidRequest, spec.MaxScheduledTasks)
+	}
+	sc.Tasks[t.ID] = t
+	if err := s.writeAll(sc); err != nil {
+		return nil, err
+	}
+	logger.Info("scheduled task created", "taskID", t.ID, "schedule", t.Schedule)
+	return &spec.PostScheduledTaskResponse{Body: &t}, nil
+}
+
+func (s *SchedulerStore) PatchScheduledTask(
+	_ context.Context,
+	req *spec.PatchScheduledTaskRequest,
+) (*spec.PatchScheduledTaskResponse, error) {
+	if req == nil || req.Body == nil || req.TaskID == "" {
+		return nil, fmt.Errorf("%w: taskID and body required", spec.ErrInvalidRequest)
+	}
+	b := req.Body
+	t, err := s.update(req.TaskID, func(t *spec.ScheduledTask) error {
+		if b.DisplayName != nil {
+			t.DisplayName = strings.TrimSpace(*b.DisplayName)
+		}
+		if b.Schedule != nil {
+			t.Schedule = strings.TrimSpace(*b.Schedule)
+		}
+		if b.PromptTemplate != nil {
+			t.PromptTemplate = *b.PromptTemplate
+		}
+		if b.ModelPresetRef != nil {
+			t.ModelPresetRef = *b.ModelPresetRef
+		}
+		if b.ClearSkillSession {
+			t.SkillSession = nil
+		} else if b.SkillSession != nil {
+			t.SkillSession = b.SkillSession
+		}
+		return s.schedule(t)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &spec.PatchScheduledTaskResponse{Body: t}, nil
+}
+
+func (s *SchedulerStore) DeleteScheduledTask(
+	_ context.Context,
+	req *spec.DeleteScheduledTaskRequest,
+) (*spec.DeleteScheduledTaskResponse, error) {
+	if req == nil || req.TaskID == "" {
+		return nil, fmt.Errorf("%w: taskID required", spec.ErrInvalidRequest)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := sc.Tasks[req.TaskID]; !ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrTaskNotFound, req.TaskID)
+	}
+	delete(sc.Tasks, req.TaskID)
+	if err := s.writeAll(sc); err != nil {
+		return nil, err
+	}
+	logger.Info("scheduled task deleted", "taskID", req.TaskID)
+	return &spec.DeleteScheduledTaskResponse{}, nil
+}
+
+func (s *SchedulerStore) GetScheduledTask(
+	_ context.Context,
+	req *spec.GetScheduledTaskRequest,
+) (*spec.GetScheduledTaskResponse, error) {
+	if req == nil || req.TaskID == "" {
+		return nil, fmt.Errorf("%w: taskID required", spec.ErrInvalidRequest)
+	}
+	t, err := s.getTask(req.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	return &spec.GetScheduledTaskResponse{Body: t}, nil
+}
+
+// ListScheduledTasks returns all tasks sorted by display name.
+func (s *SchedulerStore) ListScheduledTasks(
+	_ context.Context,
+	_ *spec.ListScheduledTasksRequest,
+) (*spec.ListScheduledTasksResponse, error) {
+	s.mu.Lock()
+	sc, err := s.readAll()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	tasks := slices.Collect(maps.Values(sc.Tasks))
+	slices.SortFunc(tasks, func(a, b spec.ScheduledTask) int {
+		if c := strings.Compare(strings.ToLower(a.DisplayName), strings.ToLower(b.DisplayName)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return &spec.ListScheduledTasksResponse{
+		Body: &spec.ListScheduledTasksResponseBody{Tasks: tasks},
+	}, nil
+}
+
+// ToggleScheduledTask enables or disables a task. Enabling schedules the
+// next run from now, so runs missed while disabled are not caught up.
+func (s *SchedulerStore) ToggleScheduledTask(
+	_ context.Context,
+	req *spec.ToggleScheduledTaskRequest,
+) (*spec.ToggleScheduledTaskResponse, error) {
+	if req == nil || req.Body == nil || req.TaskID == "" {
+		return nil, fmt.Errorf("%w: taskID and body required", spec.ErrInvalidRequest)
+	}
+	t, err := s.update(req.TaskID, func(t *spec.ScheduledTask) error {
+		t.IsEnabled = req.Body.IsEnabled
+		return s.schedule(t)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("scheduled task toggled", "taskID", t.ID, "enabled", t.IsEnabled)
+	return &spec.ToggleScheduledTaskResponse{Body: t}, nil
+}
+
+// RunScheduledTaskNow runs a task and waits for it. A failed run is recorded
+// on the task and is not an error of this call.
+func (s *SchedulerStore) RunScheduledTaskNow(
+	ctx context.Context,
+	req *spec.RunScheduledTaskNowRequest,
+) (*spec.RunScheduledTaskNowResponse, error) {
+	if req == nil || req.TaskID == "" {
+		return nil, fmt.Errorf("%w: taskID required", spec.ErrInvalidRequest)
+	}
+	t, err := s.getTask(req.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.markRunning(t.ID) {
+		return nil, fmt.Errorf("%w: %s", spec.ErrTaskRunning, t.ID)
+	}
+	defer s.clearRunning(t.ID)
+
+	t, err = s.runTask(ctx, *t, spec.TaskRunTriggerManual)
+	if err != nil {
+		return nil, err
+	}
+	return &spec.RunScheduledTaskNowResponse{Body: t}, nil
+}
+
+func (s *SchedulerStore) loop() {
+	// The first check waits for a tick so that overdue tasks do not start
+	// while the rest of the app is still being set up.
+	tick := time.NewTicker(tickInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-s.loopCtx.Done():
+			return
+		case <-tick.C:
+			s.runDue()
+		}
+	}
+}
+
+// runDue starts every enabled task whose next run time has passed. Each
+// run gets its own goroutine so a slow task does not delay the others.
+func (s *SchedulerStore) runDue() {
+	s.mu.Lock()
+	sc, err := s.readAll()
+	s.mu.Unlock()
+	if err != nil {
+		logger.Error("scheduler: read tasks", "error", err)
+		return
+	}
+	now := s.now()
+	for _, t := range sc.Tasks {
+		if !t.IsEnabled || t.NextRunAt == nil || t.NextRunAt.After(now) {
+			continue
+		}
+		if !s.markRunning(t.ID) {
+			continue
+		}
+		s.runWG.Go(func() {
+			defer s.clearRunning(t.ID)
+			if _, err := s.runTask(s.loopCtx, t, spec.TaskRunTriggerSchedule); err != nil &&
+				!errors.Is(err, spec.ErrTaskNotFound) {
+				logger.Error("scheduler: record run", "taskID", t.ID, "error", err)
+			}
+		})
+	}
+}
+
+// runTask runs t and records the outcome. Scheduled runs move NextRunAt
+// past the finish time; manual runs leave it alone.
+func (s *SchedulerStore) runTask(
+	ctx context.Context,
+	t spec.ScheduledTask,
+	trigger spec.TaskRunTrigger,
+) (*spec.ScheduledTask, error) {
+	run := spec.TaskRun{Trigger: trigger, StartedAt: s.now().UTC()}
+	logger.Info("scheduled task started", "taskID", t.ID, "trigger", trigger)
+
+	runCtx, cancel := context.WithTimeout(ctx, runTimeout)
+	convID, err := s.run(runCtx, t, expandPrompt(t, run.StartedAt.Local()))
+	cancel()
+
+	run.FinishedAt = s.now().UTC()
+	run.ConversationID = convID
+	if err != nil {
+		run.Status = spec.TaskRunStatusFailed
+		run.Error = err.Error()
+		logger.Warn("scheduled task failed", "taskID", t.ID, "error", err)
+	} else {
+		run.Status = spec.TaskRunStatusSucceeded
+		logger.Info("scheduled task finished", "taskID", t.ID, "conversationID", convID)
+	}
+
+	return s.update(t.ID, func(cur *spec.ScheduledTask) error {
+		cur.LastRun = &run
+		if trigger == spec.TaskRunTriggerSchedule {
+			return s.schedule(cur)
+		}
+		return nil
+	})
+}
+
+// schedule validates t and sets its next run time.
+func (s *SchedulerStore) schedule(t *spec.ScheduledTask) error {
+	if t.DisplayName == "" {
+		return fmt.Errorf("%w: displayName is empty", spec.ErrInvalidRequest)
+	}
+	if strings.TrimSpace(t.PromptTemplate) == "" {
+		return fmt.Errorf("%w: promptTemplate is empty", spec.ErrInvalidRequest)
+	}
+	if len(t.PromptTemplate) > spec.MaxPromptTemplateLength {
+		return fmt.Errorf("%w: promptTemplate is longer than %d bytes",
+			spec.ErrInvalidRequest, spec.MaxPromptTemplateLength)
+	}
+	if t.ModelPresetRef.IsZero() {
+		return fmt.Errorf("%w: modelPresetRef needs providerName and modelPresetID", spec.ErrInvalidRequest)
+	}
+	sched, err := parseSchedule(t.Schedule)
+	if err != nil {
+		return err
+	}
+	next := sched.next(s.now())
+	if next.IsZero() {
+		return fmt.Errorf("%w: %q never matches", spec.ErrInvalidSchedule, t.Schedule)
+	}
+	t.NextRunAt = nil
+	if t.IsEnabled {
+		next = next.UTC()
+		t.NextRunAt = &next
+	}
+	return nil
+}
+
+func (s *SchedulerStore) update(
+	id string,
+	fn func(t *spec.ScheduledTask) error,
+) (*spec.ScheduledTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	t, ok := sc.Tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrTaskNotFound, id)
+	}
+	if err := fn(&t); err != nil {
+		return nil, err
+	}
+	t.ModifiedAt = s.now().UTC()
+	sc.Tasks[id] = t
+	if err := s.writeAll(sc); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *SchedulerStore) getTask(id string) (*spec.ScheduledTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	t, ok := sc.Tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrTaskNotFound, id)
+	}
+	return &t, nil
+}
+
+func (s *SchedulerStore) markRunning(id string) bool {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	if s.running[id] {
+		return false
+	}
+	s.running[id] = true
+	return true
+}
+
+func (s *SchedulerStore) clearRunning(id string) {
+	s.runningMu.Lock()
+	delete(s.running, id)
+	s.runningMu.Unlock()
+}
+
+func (s *SchedulerStore) readAll() (storeSchema, error) {
+	raw, err := s.file.GetAll(false)
+	if err != nil {
+		return storeSchema{}, err
+	}
+	var sc storeSchema
+	if err := jsonencdec.MapToStructWithJSONTags(raw, &sc); err != nil {
+		return storeSchema{}, err
+	}
+	if sc.SchemaVersion != "" && sc.SchemaVersion != spec.SchemaVersion {
+		return storeSchema{}, fmt.Errorf("scheduler store schemaVersion %q != %q",
+			sc.SchemaVersion, spec.SchemaVersion)
+	}
+	if sc.Tasks == nil {
+		sc.Tasks = map[string]spec.ScheduledTask{}
+	}
+	return sc, nil
+}
+
+func (s *SchedulerStore) writeAll(sc storeSchema) error {
+	sc.SchemaVersion = spec.SchemaVersion
+	mp, err := jsonencdec.StructWithJSONTagsToMap(sc)
+	if err != nil {
+		return err
+	}
+	return s.file.SetAll(mp)
+}
+
+// expandPrompt fills the placeholders of the task's prompt template. Unknown
+// placeholders are left as written.
+func expandPrompt(t spec.ScheduledTask, now time.Time) string {
+	return modelpresetSpec.ExpandSystemPromptPlaceholders(t.PromptTemplate, map[string]string{
+		spec.PromptPlaceholderDate:     now.Format(time.DateOnly),
+		spec.PromptPlaceholderTime:     now.Format("15:04 MST"),
+		spec.PromptPlaceholderWeekday:  now.Weekday().String(),
+		spec.PromptPlaceholderTaskName: t.DisplayName,
+	})
+}
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	"github.com/flexigpt/flexigpt-app/internal/scheduler/spec"
+)
+
+func TestScheduleNext(t *testing.T) {
+	base := time.Date(2026, 10, 15, 10, 7, 30, 0, time.UTC) // Thursday.
+	for _, tc := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 15, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 10, 15, 10, 15, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)},
+		{"30 8-11 * * *", time.Date(2026, 10, 15, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches.
+		{"0 0 1 * 6", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+	} {
+		s, err := parseSchedule(tc.expr)
+		if err != nil {
+			t.Errorf("%q: %v", tc.expr, err)
+			continue
+		}
+		if got := s.next(base); !got.Equal(tc.want) {
+			t.Errorf("%q: next = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *", "@yearly"} {
+		if _, err := parseSchedule(expr); !errors.Is(err, spec.ErrInvalidSchedule) {
+			t.Errorf("%q: got %v, want ErrInvalidSchedule", expr, err)
+		}
+	}
+	s, err := parseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.next(base); !got.IsZero() {
+		t.Errorf("Feb 30: next = %v", got)
+	}
+}
+
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
+
+type fakeRunner struct {
+	mu      sync.Mutex
+	prompts []string
+	err     error
+}
+
+func (f *fakeRunner) run(_ context.Context, task spec.ScheduledTask, prompt string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prompts = append(f.prompts, prompt)
+	if f.err != nil {
+		return "", f.err
+	}
+	return "conv-" + task.ID, nil
+}
+
+func TestSchedulerStore(t *testing.T) {
+	ctx := t.Context()
+	runner := &fakeRunner{}
+	clock := &testClock{now: time.Date(2026, 10, 15, 10, 7, 0, 0, time.Local)}
+	s, err := newSchedulerStore(ctx, t.TempDir(), runner.run, clock.Now)
+	if err != nil {
+		t.Fatalf("newSchedulerStore: %v", err)
+	}
+	defer s.Close()
+
+	ref := modelpresetSpec.ModelPresetRef{ProviderName: "openai", ModelPresetID: "gpt"}
+	for name, body := range map[string]*spec.PostScheduledTaskRequestBody{
+		"no name":     {Schedule: "@daily", PromptTemplate: "p", ModelPresetRef: ref},
+		"no prompt":   {DisplayName: "x", Schedule: "@daily", ModelPresetRef: ref},
+		"no preset":   {DisplayName: "x", Schedule: "@daily", PromptTemplate: "p"},
+		"bad cron":    {DisplayName: "x", Schedule: "daily", PromptTemplate: "p", ModelPresetRef: ref},
+		"never fires": {DisplayName: "x", Schedule: "0 0 31 4 *", PromptTemplate: "p", ModelPresetRef: ref},
+	} {
+		if _, err := s.PostScheduledTask(ctx, &spec.PostScheduledTaskRequest{Body: body}); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+
+	post, err := s.PostScheduledTask(ctx, &spec.PostScheduledTaskRequest{Body: &spec.PostScheduledTaskRequestBody{
+		DisplayName:    "Digest",
+		Schedule:       "0 9 * * *",
+		PromptTemplate: "{{taskName}} for {{date}} ({{ weekday }}) {{unknown}}",
+		ModelPresetRef: ref,
+		IsEnabled:      true,
+	}})
+	if err != nil {
+		t.Fatalf("PostScheduledTask: %v", err)
+	}
+	task := post.Body
+	wantNext := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+	if task.NextRunAt == nil || !task.NextRunAt.Equal(wantNext) {
+		t.Fatalf("NextRunAt = %v, want %v", task.NextRunAt, wantNext)
+	}
+
+	// Not due yet.
+	s.runDue()
+	s.runWG.Wait()
+	if len(runner.prompts) != 0 {
+		t.Fatalf("ran early: %q", runner.prompts)
+	}
+
+	clock.Set(wantNext.Add(30 * time.Second))
+	s.runDue()
+	s.runWG.Wait()
+	if len(runner.prompts) != 1 || runner.prompts[0] != "Digest for 2026-10-16 (Friday) {{unknown}}" {
+		t.Fatalf("prompts: %q", runner.prompts)
+	}
+	got, err := s.GetScheduledTask(ctx, &spec.GetScheduledTaskRequest{TaskID: task.ID})
+	if err != nil {
+		t.Fatalf("GetScheduledTask: %v", err)
+	}
+	if r := got.Body.LastRun; r == nil || r.Status != spec.TaskRunStatusSucceeded ||
+		r.Trigger != spec.TaskRunTriggerSchedule || r.ConversationID != "conv-"+task.ID {
+		t.Fatalf("last run: %+v", got.Body.LastRun)
+	}
+	if want := wantNext.AddDate(0, 0, 1); !got.Body.NextRunAt.Equal(want) {
+		t.Fatalf("NextRunAt after run = %v, want %v", got.Body.NextRunAt, want)
+	}
+
+	toggled, err := s.ToggleScheduledTask(ctx, &spec.ToggleScheduledTaskRequest{
+		TaskID: task.ID, Body: &spec.ToggleScheduledTaskRequestBody{IsEnabled: false},
+	})
+	if err != nil || toggled.Body.IsEnabled || toggled.Body.NextRunAt != nil {
+		t.Fatalf("ToggleScheduledTask: %+v, %v", toggled, err)
+	}
+	clock.Set(wantNext.AddDate(0, 0, 3))
+	s.runDue()
+	s.runWG.Wait()
+	if len(runner.prompts) != 1 {
+		t.Fatalf("disabled task ran")
+	}
+
+	// Manual runs work while disabled, record failures and keep the schedule.
+	runner.err = errors.New("provider down")
+	ran, err := s.RunScheduledTaskNow(ctx, &spec.RunScheduledTaskNowRequest{TaskID: task.ID})
+	if err != nil {
+		t.Fatalf("RunScheduledTaskNow: %v", err)
+	}
+	if r := ran.Body.LastRun; r.Status != spec.TaskRunStatusFailed || r.Trigger != spec.TaskRunTriggerManual ||
+		r.Error != "provider down" || ran.Body.NextRunAt != nil {
+		t.Fatalf("manual run: %+v", ran.Body)
+	}
+
+	schedule := "*/30 * * * *"
+	patched, err := s.PatchScheduledTask(ctx, &spec.PatchScheduledTaskRequest{
+		TaskID: task.ID, Body: &spec.PatchScheduledTaskRequestBody{Schedule: &schedule},
+	})
+	if err != nil || patched.Body.Schedule != schedule || patched.Body.LastRun == nil {
+		t.Fatalf("PatchScheduledTask: %+v, %v", patched, err)
+	}
+
+	list, err := s.ListScheduledTasks(ctx, &spec.ListScheduledTasksRequest{})
+	if err != nil || len(list.Body.Tasks) != 1 {
+		t.Fatalf("ListScheduledTasks: %+v, %v", list, err)
+	}
+	if _, err := s.DeleteScheduledTask(ctx, &spec.DeleteScheduledTaskRequest{TaskID: task.ID}); err != nil {
+		t.Fatalf("DeleteScheduledTask: %v", err)
+	}
+	if _, err := s.RunScheduledTaskNow(ctx, &spec.RunScheduledTaskNowRequest{TaskID: task.ID}); !errors.Is(
+		err, spec.ErrTaskNotFound) {
+		t.Fatalf("run after delete: got %v", err)
+	}
+}
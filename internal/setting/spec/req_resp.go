@@ -1,5 +1,15 @@
 package spec
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/featureflag"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+	toolSpec "github.com/flexigpt/flexigpt-app/internal/tool/spec"
+)
+
 type SetAppThemeRequestBody struct {
 	Type ThemeType `json:"type" required:"true"`
 	Name string    `json:"name" required:"true"`
@@ -12,9 +22,11 @@ type SetAppThemeRequest struct {
 type SetAppThemeResponse struct{}
 
 type SetDebugSettingsRequestBody struct {
-	LogLLMReqResp           bool          `json:"logLLMReqResp"           required:"true"`
-	DisableContentStripping bool          `json:"disableContentStripping" required:"true"`
-	LogLevel                DebugLogLevel `json:"logLevel"                required:"true"`
+	LogLLMReqResp           bool                     `json:"logLLMReqResp"             required:"true"`
+	DisableContentStripping bool                     `json:"disableContentStripping"   required:"true"`
+	LogLevel                DebugLogLevel            `json:"logLevel"                  required:"true"`
+	LogFormat               DebugLogFormat           `json:"logFormat,omitempty"`
+	ModuleLogLevels         map[string]DebugLogLevel `json:"moduleLogLevels,omitempty"`
 }
 
 type SetDebugSettingsRequest struct {
@@ -23,6 +35,38 @@ type SetDebugSettingsRequest struct {
 
 type SetDebugSettingsResponse struct{}
 
+type SetNetworkSettingsRequestBody struct {
+	NetworkSettings
+}
+
+// SetNetworkSettingsRequest replaces the network settings as a whole.
+type SetNetworkSettingsRequest struct {
+	Body *SetNetworkSettingsRequestBody
+}
+
+type SetNetworkSettingsResponse struct{}
+
+type SetBudgetSettingsRequestBody struct {
+	BudgetSettings
+}
+
+// SetBudgetSettingsRequest replaces the budget settings as a whole.
+type SetBudgetSettingsRequest struct {
+	Body *SetBudgetSettingsRequestBody
+}
+
+type SetBudgetSettingsResponse struct{}
+
+type SetCompletionCacheSettingsRequestBody struct {
+	CompletionCacheSettings
+}
+
+type SetCompletionCacheSettingsRequest struct {
+	Body *SetCompletionCacheSettingsRequestBody
+}
+
+type SetCompletionCacheSettingsResponse struct{}
+
 // AuthKeyMeta is the public view of one stored key (no secret, only SHA).
 type AuthKeyMeta struct {
 	Type     AuthKeyType `json:"type"`
@@ -47,39 +91,295 @@ type GetAuthKeyResponse struct {
 	Body *GetAuthKeyResponseBody
 }
 
+// GetAuthKeyAuditLogRequest lists recent secret reads, optionally for one
+// type or key.
+type GetAuthKeyAuditLogRequest struct {
+	Type    AuthKeyType `query:"type"    required:"false"`
+	KeyName AuthKeyName `query:"keyName" required:"false"`
+}
+
+type GetAuthKeyAuditLogResponseBody struct {
+	// Entries are newest first.
+	Entries []AuthKeyAuditEntry `json:"entries"`
+}
+
+type GetAuthKeyAuditLogResponse struct {
+	Body *GetAuthKeyAuditLogResponseBody
+}
+
 type SetAuthKeyRequestBody struct {
 	Secret string `json:"secret" required:"true"`
 }
 
 // SetAuthKeyRequest creates or updates a key (idempotent).
 type SetAuthKeyRequest struct {
-	Type    AuthKeyType `path:"type"`
-	KeyName AuthKeyName `path:"keyName"`
-	Body    *SetAuthKeyRequestBody
+	Type           AuthKeyType `path:"type"`
+	KeyName        AuthKeyName `path:"keyName"`
+	IdempotencyKey string      `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool        `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body           *SetAuthKeyRequestBody
+}
+
+// SetAuthKeyResponseBody describes the key a dry run would save. The secret
+// is never echoed back.
+type SetAuthKeyResponseBody struct {
+	AuthKey AuthKeyMeta `json:"authKey"`
 }
 
-type SetAuthKeyResponse struct{}
+// SetAuthKeyResponse has a Body only for dry runs.
+type SetAuthKeyResponse struct {
+	Body *SetAuthKeyResponseBody
+}
 
 // DeleteAuthKeyRequest removes a key (if not built-in).
 type DeleteAuthKeyRequest struct {
-	Type    AuthKeyType `path:"type"`
-	KeyName AuthKeyName `path:"keyName"`
+	Type           AuthKeyType `path:"type"`
+	KeyName        AuthKeyName `path:"keyName"`
+	IdempotencyKey string      `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool        `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+}
+
+// DeleteAuthKeyResponseBody describes the key a dry run would delete;
+// AuthKey is nil when no such key is stored.
+type DeleteAuthKeyResponseBody struct {
+	AuthKey *AuthKeyMeta `json:"authKey,omitempty"`
 }
 
-type DeleteAuthKeyResponse struct{}
+// DeleteAuthKeyResponse has a Body only for dry runs.
+type DeleteAuthKeyResponse struct {
+	Body *DeleteAuthKeyResponseBody
+}
 
-// GetSettingsRequest fetches everything (theme + debug + keys). Secrets are omitted.
+// GetSettingsRequest fetches everything (theme + debug + network + keys). Secrets are omitted.
 type GetSettingsRequest struct {
 	ForceFetch bool `query:"forceFetch" doc:"Refresh from disk before reading." required:"false"`
 }
 
 type GetSettingsResponseBody struct {
-	AppTheme AppTheme      `json:"appTheme"`
-	Debug    DebugSettings `json:"debug"`
-	AuthKeys []AuthKeyMeta `json:"authKeys"`
+	AppTheme AppTheme        `json:"appTheme"`
+	Debug    DebugSettings   `json:"debug"`
+	Network  NetworkSettings `json:"network"`
+	Budget   BudgetSettings  `json:"budget"`
+	AuthKeys []AuthKeyMeta   `json:"authKeys"`
+
+	CompletionCache CompletionCacheSettings `json:"completionCache"`
 }
 
 // GetSettingsResponse returns the current settings without secrets.
 type GetSettingsResponse struct {
 	Body *GetSettingsResponseBody
 }
+
+type ExportSettingsRequest struct{}
+
+type ExportSettingsResponseBody struct {
+	Settings SettingsExport `json:"settings"`
+}
+
+type ExportSettingsResponse struct {
+	Body *ExportSettingsResponseBody
+}
+
+type ImportSettingsRequestBody struct {
+	Settings SettingsExport `json:"settings" required:"true"`
+}
+
+// ImportSettingsRequest applies the theme of an export and adds an empty
+// placeholder for every auth key not present yet. Existing keys are kept.
+type ImportSettingsRequest struct {
+	Body *ImportSettingsRequestBody
+}
+
+type ImportSettingsResponseBody struct {
+	CreatedAuthKeys  []AuthKeyRef `json:"createdAuthKeys"`
+	ExistingAuthKeys []AuthKeyRef `json:"existingAuthKeys"`
+}
+
+type ImportSettingsResponse struct {
+	Body *ImportSettingsResponseBody
+}
+
+type ListFeatureFlagsRequest struct{}
+
+type ListFeatureFlagsResponseBody struct {
+	FeatureFlags []FeatureFlag `json:"featureFlags"`
+}
+
+type ListFeatureFlagsResponse struct {
+	Body *ListFeatureFlagsResponseBody
+}
+
+type SetFeatureFlagRequestBody struct {
+	// Enabled persists an opt-in or opt-out; nil clears it back to the default.
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+type SetFeatureFlagRequest struct {
+	Name           featureflag.Name `path:"name" required:"true"`
+	IdempotencyKey string           `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	Body           *SetFeatureFlagRequestBody
+}
+
+type SetFeatureFlagResponse struct{}
+
+type GetPreferenceRequest struct {
+	Namespace string `path:"namespace" required:"true"`
+	Key       string `path:"key"       required:"true"`
+}
+
+type GetPreferenceResponse struct {
+	Body *Preference
+}
+
+type SetPreferenceRequestBody struct {
+	// Value is any JSON value; null removes the preference.
+	Value json.RawMessage `json:"value" required:"true"`
+}
+
+type SetPreferenceRequest struct {
+	Namespace string `path:"namespace" required:"true"`
+	Key       string `path:"key"       required:"true"`
+	Body      *SetPreferenceRequestBody
+}
+
+type SetPreferenceResponse struct{}
+
+type ListPreferencesRequest struct {
+	Namespace string `query:"namespace" doc:"Only list this namespace." required:"false"`
+}
+
+type ListPreferencesResponseBody struct {
+	Preferences []Preference `json:"preferences"`
+}
+
+type ListPreferencesResponse struct {
+	Body *ListPreferencesResponseBody
+}
+
+type ListTrustedDirectoriesRequest struct{}
+
+type ListTrustedDirectoriesResponseBody struct {
+	TrustedRoots   []TrustedDirectory  `json:"trustedRoots"`
+	ConfirmedPaths []TrustConfirmation `json:"confirmedPaths"`
+}
+
+type ListTrustedDirectoriesResponse struct {
+	Body *ListTrustedDirectoriesResponseBody
+}
+
+type AddTrustedDirectoryRequestBody struct {
+	Path string `json:"path" required:"true"`
+}
+
+// AddTrustedDirectoryRequest trusts an existing directory and its subtree.
+type AddTrustedDirectoryRequest struct {
+	Body *AddTrustedDirectoryRequestBody
+}
+
+type AddTrustedDirectoryResponse struct{}
+
+type RemoveTrustedDirectoryRequest struct {
+	Path string `query:"path" required:"true"`
+}
+
+type RemoveTrustedDirectoryResponse struct{}
+
+type ConfirmPathTrustRequestBody struct {
+	Path    string       `json:"path"    required:"true"`
+	Purpose TrustPurpose `json:"purpose" required:"true"`
+}
+
+// ConfirmPathTrustRequest records an explicit confirmation for one path
+// outside the trusted directories.
+type ConfirmPathTrustRequest struct {
+	Body *ConfirmPathTrustRequestBody
+}
+
+type ConfirmPathTrustResponse struct{}
+
+type RevokePathTrustRequest struct {
+	Path string `query:"path" required:"true"`
+}
+
+type RevokePathTrustResponse struct{}
+
+type CheckPathTrustRequest struct {
+	Path string `query:"path" required:"true"`
+}
+
+type CheckPathTrustResponse struct {
+	Body *PathTrust
+}
+
+type PutProfileRequestBody struct {
+	DisplayName    string                          `json:"displayName"              required:"true"`
+	Description    string                          `json:"description,omitempty"`
+	ModelPresetRef *modelpresetSpec.ModelPresetRef `json:"modelPresetRef,omitempty"`
+	EnabledSkills  []skillstoreSpec.SkillRef       `json:"enabledSkills,omitempty"`
+	ToolAllowlist  []toolSpec.ToolRef              `json:"toolAllowlist,omitempty"`
+	Preferences    map[string]map[string]any       `json:"preferences,omitempty"`
+	// ExpectedModifiedAt, if set, makes replacing an existing profile fail
+	// with a conflict unless the profile is still at this modifiedAt.
+	ExpectedModifiedAt *time.Time `json:"expectedModifiedAt,omitempty"`
+}
+
+// PutProfileRequest creates or replaces a profile.
+type PutProfileRequest struct {
+	ProfileID      string `path:"profileID" required:"true"`
+	IdempotencyKey string `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool   `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body           *PutProfileRequestBody
+}
+
+type PutProfileResponseBody struct {
+	Profile Profile `json:"profile"`
+}
+
+// PutProfileResponse has a Body only for dry runs.
+type PutProfileResponse struct {
+	Body *PutProfileResponseBody
+}
+
+type DeleteProfileRequest struct {
+	ProfileID      string `path:"profileID" required:"true"`
+	IdempotencyKey string `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool   `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+}
+
+type DeleteProfileResponseBody struct {
+	Profile Profile `json:"profile"`
+}
+
+// DeleteProfileResponse has a Body only for dry runs.
+type DeleteProfileResponse struct {
+	Body *DeleteProfileResponseBody
+}
+
+type GetProfileRequest struct {
+	ProfileID string `path:"profileID" required:"true"`
+}
+
+type GetProfileResponse struct {
+	Body *Profile
+}
+
+type ListProfilesRequest struct{}
+
+type ListProfilesResponseBody struct {
+	Profiles        []Profile `json:"profiles"`
+	ActiveProfileID string    `json:"activeProfileID,omitempty"`
+}
+
+type ListProfilesResponse struct {
+	Body *ListProfilesResponseBody
+}
+
+// SwitchProfileRequest makes a profile the active one. An empty ProfileID
+// only clears the active profile.
+type SwitchProfileRequest struct {
+	ProfileID string `path:"profileID"`
+}
+
+type SwitchProfileResponse struct {
+	Body *Profile
+}
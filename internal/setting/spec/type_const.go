@@ -1,6 +1,15 @@
 package spec
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/featureflag"
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+	toolSpec "github.com/flexigpt/flexigpt-app/internal/tool/spec"
+)
 
 const (
 	SchemaVersion = "2026-03-27"
@@ -12,8 +21,17 @@ var (
 	ErrInvalidTheme           = errors.New("invalid app theme")
 	ErrInvalidAuthKey         = errors.New("invalid auth key")
 	ErrInvalidDebugSettings   = errors.New("invalid debug settings")
+	ErrInvalidNetwork         = errors.New("invalid network settings")
+	ErrInvalidBudget          = errors.New("invalid budget settings")
+	ErrInvalidCompletionCache = errors.New("invalid completion cache settings")
 	ErrAuthKeyNotFound        = errors.New("auth key not found")
 	ErrBuiltInAuthKeyReadOnly = errors.New("built-in auth key is read-only")
+	ErrUnknownFeatureFlag     = errors.New("unknown feature flag")
+	ErrPathNotTrusted         = errors.New("path is outside trusted directories")
+	ErrInvalidPreference      = errors.New("invalid preference")
+	ErrPreferenceNotFound     = errors.New("preference not found")
+	ErrInvalidProfile         = errors.New("invalid profile")
+	ErrProfileNotFound        = errors.New("profile not found")
 )
 
 type ThemeType string
@@ -45,10 +63,183 @@ const (
 	DebugLogLevelError DebugLogLevel = "error"
 )
 
+// DebugLogFormat selects how log files are written. Empty means text.
+type DebugLogFormat string
+
+const (
+	DebugLogFormatText DebugLogFormat = "text"
+	DebugLogFormatJSON DebugLogFormat = "json"
+)
+
+// DebugSettings controls logging. ModuleLogLevels overrides LogLevel for
+// dotted module names such as "skill.store"; a module also covers its
+// sub-modules.
 type DebugSettings struct {
-	LogLLMReqResp           bool          `json:"logLLMReqResp"`
-	DisableContentStripping bool          `json:"disableContentStripping"`
-	LogLevel                DebugLogLevel `json:"logLevel"`
+	LogLLMReqResp           bool                     `json:"logLLMReqResp"`
+	DisableContentStripping bool                     `json:"disableContentStripping"`
+	LogLevel                DebugLogLevel            `json:"logLevel"`
+	LogFormat               DebugLogFormat           `json:"logFormat,omitempty"`
+	ModuleLogLevels         map[string]DebugLogLevel `json:"moduleLogLevels,omitempty"`
+}
+
+// NetworkSettings controls how provider HTTP clients reach the network. With
+// no proxy set the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment is used.
+type NetworkSettings struct {
+	HTTPProxy  string `json:"httpProxy,omitempty"`
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	// SOCKSProxy is used for requests whose scheme has no proxy of its own.
+	SOCKSProxy string `json:"socksProxy,omitempty"`
+	NoProxy    string `json:"noProxy,omitempty"`
+
+	// CABundlePath is a PEM file trusted in addition to the system roots.
+	CABundlePath string `json:"caBundlePath,omitempty"`
+	// InsecureSkipVerifyProviders lists providers whose TLS certificates
+	// are not verified.
+	InsecureSkipVerifyProviders []string `json:"insecureSkipVerifyProviders,omitempty"`
+}
+
+// ProviderBudget caps the spend on one provider in USD. A zero limit is no
+// limit. Days and months are calendar periods in UTC.
+type ProviderBudget struct {
+	DailyLimitUSD   float64 `json:"dailyLimitUSD,omitempty"`
+	MonthlyLimitUSD float64 `json:"monthlyLimitUSD,omitempty"`
+}
+
+// BudgetSettings holds the spend limits checked before each completion.
+type BudgetSettings struct {
+	Providers map[string]ProviderBudget `json:"providers,omitempty"`
+}
+
+// CompletionCacheSettings controls the on-disk cache of completion
+// responses. Zero size and TTL use the defaults.
+type CompletionCacheSettings struct {
+	Enabled    bool `json:"enabled"`
+	MaxSizeMB  int  `json:"maxSizeMB,omitempty"`
+	TTLMinutes int  `json:"ttlMinutes,omitempty"`
+}
+
+// FeatureFlagSource tells where the effective value of a feature flag comes from.
+type FeatureFlagSource string
+
+const (
+	FeatureFlagSourceDefault  FeatureFlagSource = "default"
+	FeatureFlagSourceSettings FeatureFlagSource = "settings"
+	FeatureFlagSourceEnv      FeatureFlagSource = "env"
+)
+
+// FeatureFlag is the effective state of a known flag. An environment override
+// wins over the persisted setting, which wins over the default.
+type FeatureFlag struct {
+	featureflag.Definition
+
+	Enabled bool              `json:"enabled"`
+	Source  FeatureFlagSource `json:"source"`
+	EnvVar  string            `json:"envVar"`
+}
+
+// TrustPurpose tells why a path outside trusted directories was confirmed.
+type TrustPurpose string
+
+const (
+	TrustPurposeAttachment TrustPurpose = "attachment"
+	TrustPurposeSkill      TrustPurpose = "skill"
+)
+
+// TrustedDirectory is a root whose whole subtree may be attached or used for
+// skills without confirmation.
+type TrustedDirectory struct {
+	Path    string    `json:"path"`
+	AddedAt time.Time `json:"addedAt"`
+}
+
+// TrustConfirmation records that the user explicitly allowed one path outside
+// the trusted directories.
+type TrustConfirmation struct {
+	Path        string       `json:"path"`
+	Purpose     TrustPurpose `json:"purpose"`
+	ConfirmedAt time.Time    `json:"confirmedAt"`
+}
+
+// WorkspaceTrust is keyed by normalized absolute path.
+type WorkspaceTrust struct {
+	TrustedRoots   map[string]TrustedDirectory  `json:"trustedRoots,omitempty"`
+	ConfirmedPaths map[string]TrustConfirmation `json:"confirmedPaths,omitempty"`
+}
+
+// PathTrust is the trust decision for one path. TrustedRoot is set when the
+// path lies under a trusted directory.
+type PathTrust struct {
+	Path        string `json:"path"`
+	Trusted     bool   `json:"trusted"`
+	TrustedRoot string `json:"trustedRoot,omitempty"`
+	Confirmed   bool   `json:"confirmed"`
+}
+
+// Preference is one value of the preferences bag. Namespaces keep the keys
+// of different subsystems, e.g. "editor" or "sidebar", apart.
+type Preference struct {
+	Namespace string          `json:"namespace"`
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// Profile is a named configuration, e.g. one per client, that the user can
+// switch to in one step. Switching makes its model preset the default and
+// writes its preferences into the preferences bag. While it is active, its
+// skill and tool lists limit what new skill sessions and completions use.
+type Profile struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	Description string `json:"description,omitempty"`
+
+	ModelPresetRef *modelpresetSpec.ModelPresetRef `json:"modelPresetRef,omitempty"`
+	// EnabledSkills limits the installed skills new skill sessions may use.
+	// Empty allows every skill.
+	EnabledSkills []skillstoreSpec.SkillRef `json:"enabledSkills,omitempty"`
+	// ToolAllowlist limits the tools offered while the profile is active.
+	// Empty allows every tool.
+	ToolAllowlist []toolSpec.ToolRef `json:"toolAllowlist,omitempty"`
+	// Preferences is keyed by namespace, then key, like the preferences bag.
+	Preferences map[string]map[string]any `json:"preferences,omitempty"`
+
+	CreatedAt  time.Time `json:"createdAt"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+// SettingChangeKind classifies a SettingChangeEvent.
+type SettingChangeKind string
+
+const (
+	SettingChangeAppTheme        SettingChangeKind = "appThemeChanged"
+	SettingChangeDebug           SettingChangeKind = "debugSettingsChanged"
+	SettingChangeNetwork         SettingChangeKind = "networkSettingsChanged"
+	SettingChangeBudget          SettingChangeKind = "budgetSettingsChanged"
+	SettingChangeCompletionCache SettingChangeKind = "completionCacheSettingsChanged"
+	SettingChangeAuthKeySet      SettingChangeKind = "authKeySet"
+	SettingChangeAuthKeyDeleted  SettingChangeKind = "authKeyDeleted"
+	SettingChangeFeatureFlag     SettingChangeKind = "featureFlagChanged"
+	SettingChangePreference      SettingChangeKind = "preferenceChanged"
+	SettingChangeProfile         SettingChangeKind = "profileChanged"
+	SettingChangeActiveProfile   SettingChangeKind = "activeProfileChanged"
+)
+
+// SettingChangeEvent reports a committed settings change. It never carries
+// secrets; the fields matching Kind identify what changed.
+type SettingChangeEvent struct {
+	Kind        SettingChangeKind `json:"kind"`
+	AuthKeyType AuthKeyType       `json:"authKeyType,omitempty"`
+	AuthKeyName AuthKeyName       `json:"authKeyName,omitempty"`
+	FeatureFlag featureflag.Name  `json:"featureFlag,omitempty"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Key         string            `json:"key,omitempty"`
+	ProfileID   string            `json:"profileID,omitempty"`
+	At          time.Time         `json:"at"`
+}
+
+// SettingChangeFilter selects events for a subscriber. Empty Kinds selects
+// every kind.
+type SettingChangeFilter struct {
+	Kinds []SettingChangeKind `json:"kinds,omitempty"`
 }
 
 // AuthKeyType groups keys (e.g. "provider", "github").
@@ -59,6 +250,12 @@ const (
 	// AuthKeyTypeMCP is the dedicated namespace for MCP-related secrets.
 	// Use this for MCP transport auth tokens, headers, and env wiring.
 	AuthKeyTypeMCP AuthKeyType = "mcp"
+	// AuthKeyTypeProviderTLS holds PEM client certificates and keys that
+	// provider presets reference by key name.
+	AuthKeyTypeProviderTLS AuthKeyType = "providerTLS"
+	// AuthKeyTypeProviderHeader holds secrets that provider default headers
+	// read through "{{authKey:providerHeader/<name>}}" placeholders.
+	AuthKeyTypeProviderHeader AuthKeyType = "providerHeader"
 )
 
 // AuthKeyName is the unique key within its type.
@@ -73,9 +270,60 @@ type AuthKey struct {
 
 type AuthKeysSchema map[AuthKeyType]map[AuthKeyName]AuthKey
 
+// AuthKeyCaller names the subsystem that read a secret.
+type AuthKeyCaller string
+
+const (
+	AuthKeyCallerUnknown        AuthKeyCaller = "unknown"
+	AuthKeyCallerUI             AuthKeyCaller = "ui"
+	AuthKeyCallerProviders      AuthKeyCaller = "providers"
+	AuthKeyCallerModelDiscovery AuthKeyCaller = "modelDiscovery"
+	AuthKeyCallerEmbeddings     AuthKeyCaller = "embeddings"
+	AuthKeyCallerMCP            AuthKeyCaller = "mcp"
+	AuthKeyCallerMigration      AuthKeyCaller = "migration"
+)
+
+// AuthKeyAuditEntry records one read of a secret.
+type AuthKeyAuditEntry struct {
+	Type    AuthKeyType   `json:"type"`
+	KeyName AuthKeyName   `json:"keyName"`
+	Caller  AuthKeyCaller `json:"caller"`
+	At      time.Time     `json:"at"`
+}
+
+// AuthKeyRef names one auth key without any secret material.
+type AuthKeyRef struct {
+	Type    AuthKeyType `json:"type"`
+	KeyName AuthKeyName `json:"keyName"`
+}
+
+// SettingsExport is a portable settings document. It carries no secrets,
+// only the names of the auth keys so they can be filled in again.
+type SettingsExport struct {
+	SchemaVersion string       `json:"schemaVersion"`
+	ExportedAt    time.Time    `json:"exportedAt"`
+	AppTheme      AppTheme     `json:"appTheme"`
+	AuthKeys      []AuthKeyRef `json:"authKeys"`
+}
+
 type SettingsSchema struct {
 	SchemaVersion string         `json:"schemaVersion"`
 	AppTheme      AppTheme       `json:"appTheme"`
 	Debug         DebugSettings  `json:"debug"`
 	AuthKeys      AuthKeysSchema `json:"authKeys"`
+
+	Network         NetworkSettings         `json:"network"`
+	Budget          BudgetSettings          `json:"budget"`
+	CompletionCache CompletionCacheSettings `json:"completionCache"`
+
+	// FeatureFlags holds user opt-ins to experimental behavior.
+	FeatureFlags map[featureflag.Name]bool `json:"featureFlags,omitempty"`
+
+	WorkspaceTrust WorkspaceTrust `json:"workspaceTrust"`
+
+	// Preferences holds JSON values keyed by namespace, then key.
+	Preferences map[string]map[string]any `json:"preferences,omitempty"`
+
+	Profiles        map[string]Profile `json:"profiles,omitempty"`
+	ActiveProfileID string             `json:"activeProfileID,omitempty"`
 }
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/setting/spec"
+)
+
+// authKeyAuditLogSize bounds the in-memory log of secret reads.
+const authKeyAuditLogSize = 500
+
+type authKeyCallerKey struct{}
+
+// authKeyAuditLog keeps the most recent secret reads of this process,
+// oldest first.
+type authKeyAuditLog struct {
+	mu      sync.Mutex
+	entries []spec.AuthKeyAuditEntry
+}
+
+// WithAuthKeyCaller tags ctx with the subsystem reading secrets, for the
+// auth key audit log.
+func WithAuthKeyCaller(ctx context.Context, caller spec.AuthKeyCaller) context.Context {
+	return context.WithValue(ctx, authKeyCallerKey{}, caller)
+}
+
+func authKeyCallerFromContext(ctx context.Context) spec.AuthKeyCaller {
+	if ctx != nil {
+		if caller, ok := ctx.Value(authKeyCallerKey{}).(spec.AuthKeyCaller); ok && caller != "" {
+			return caller
+		}
+	}
+	return spec.AuthKeyCallerUnknown
+}
+
+// GetAuthKeyAuditLog returns the recorded secret reads, newest first. The
+// log is kept in memory and covers the running app only.
+func (s *SettingStore) GetAuthKeyAuditLog(
+	_ context.Context,
+	req *spec.GetAuthKeyAuditLogRequest,
+) (*spec.GetAuthKeyAuditLogResponse, error) {
+	var (
+		t    spec.AuthKeyType
+		name spec.AuthKeyName
+	)
+	if req != nil {
+		t = spec.AuthKeyType(strings.TrimSpace(string(req.Type)))
+		name = spec.AuthKeyName(strings.TrimSpace(string(req.KeyName)))
+	}
+
+	a := &s.authKeyAudit
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]spec.AuthKeyAuditEntry, 0, len(a.entries))
+	for i := len(a.entries) - 1; i >= 0; i-- {
+		e := a.entries[i]
+		if (t != "" && e.Type != t) || (name != "" && e.KeyName != name) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return &spec.GetAuthKeyAuditLogResponse{
+		Body: &spec.GetAuthKeyAuditLogResponseBody{Entries: out},
+	}, nil
+}
+
+func (s *SettingStore) recordAuthKeyRead(ctx context.Context, t spec.AuthKeyType, name spec.AuthKeyName) {
+	a := &s.authKeyAudit
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.entries) >= authKeyAuditLogSize {
+		n := copy(a.entries, a.entries[len(a.entries)-authKeyAuditLogSize+1:])
+		a.entries = a.entries[:n]
+	}
+	a.entries = append(a.entries, spec.AuthKeyAuditEntry{
+		Type:    t,
+		KeyName: name,
+		Caller:  authKeyCallerFromContext(ctx),
+		At:      time.Now().UTC(),
+	})
+}
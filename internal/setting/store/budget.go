@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	"github.com/flexigpt/mapstore-go/jsonencdec"
+)
+
+const settingKeyBudget = "budget"
+
+// SetBudgetSettings validates and persists the per-provider spend limits.
+// They are read on every completion through GetProviderBudget, so no applier
+// is involved.
+func (s *SettingStore) SetBudgetSettings(
+	_ context.Context,
+	req *spec.SetBudgetSettingsRequest,
+) (*spec.SetBudgetSettingsResponse, error) {
+	if req == nil || req.Body == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+
+	cfg, err := normalizeBudgetSettings(req.Body.BudgetSettings)
+	if err != nil {
+		return nil, err
+	}
+	val, err := jsonencdec.StructWithJSONTagsToMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.budgetMu.Lock()
+	if err := s.store.SetKey([]string{settingKeyBudget}, val); err != nil {
+		s.budget = nil
+		s.budgetMu.Unlock()
+		return nil, err
+	}
+	s.budget = &cfg
+	s.budgetMu.Unlock()
+
+	s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeBudget})
+	logger.Info("budget settings updated", "providers", slices.Sorted(maps.Keys(cfg.Providers)))
+	return &spec.SetBudgetSettingsResponse{}, nil
+}
+
+// GetProviderBudget returns the limits of one provider; the zero value means
+// no limits.
+func (s *SettingStore) GetProviderBudget(ctx context.Context, provider string) (spec.ProviderBudget, error) {
+	s.budgetMu.Lock()
+	defer s.budgetMu.Unlock()
+	if s.budget == nil {
+		resp, err := s.GetSettings(ctx, &spec.GetSettingsRequest{})
+		if err != nil {
+			return spec.ProviderBudget{}, err
+		}
+		s.budget = &resp.Body.Budget
+	}
+	return s.budget.Providers[provider], nil
+}
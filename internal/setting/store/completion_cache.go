@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+
+	"github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	"github.com/flexigpt/mapstore-go/jsonencdec"
+)
+
+const settingKeyCompletionCache = "completionCache"
+
+// SetCompletionCacheSettings validates and persists the completion cache
+// settings. The cache reads them on every completion.
+func (s *SettingStore) SetCompletionCacheSettings(
+	_ context.Context,
+	req *spec.SetCompletionCacheSettingsRequest,
+) (*spec.SetCompletionCacheSettingsResponse, error) {
+	if req == nil || req.Body == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+
+	cfg, err := normalizeCompletionCacheSettings(req.Body.CompletionCacheSettings)
+	if err != nil {
+		return nil, err
+	}
+	val, err := jsonencdec.StructWithJSONTagsToMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SetKey([]string{settingKeyCompletionCache}, val); err != nil {
+		return nil, err
+	}
+
+	s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeCompletionCache})
+	logger.Info("completion cache settings updated",
+		"enabled", cfg.Enabled, "maxSizeMB", cfg.MaxSizeMB, "ttlMinutes", cfg.TTLMinutes)
+	return &spec.SetCompletionCacheSettingsResponse{}, nil
+}
+
+func (s *SettingStore) GetCompletionCacheSettings(ctx context.Context) (spec.CompletionCacheSettings, error) {
+	resp, err := s.GetSettings(ctx, &spec.GetSettingsRequest{})
+	if err != nil {
+		return spec.CompletionCacheSettings{}, err
+	}
+	return resp.Body.CompletionCache, nil
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flexigpt/flexigpt-app/internal/featureflag"
+	"github.com/flexigpt/flexigpt-app/internal/idempotency"
+	"github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	"github.com/flexigpt/mapstore-go/jsonencdec"
+)
+
+const settingKeyFeatureFlags = "featureFlags"
+
+// ListFeatureFlags returns the effective state of every known feature flag.
+func (s *SettingStore) ListFeatureFlags(
+	_ context.Context,
+	_ *spec.ListFeatureFlagsRequest,
+) (*spec.ListFeatureFlagsResponse, error) {
+	persisted, err := s.persistedFeatureFlags()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]spec.FeatureFlag, 0, len(featureflag.Definitions))
+	for _, d := range featureflag.Definitions {
+		out = append(out, resolveFeatureFlag(d, persisted))
+	}
+	return &spec.ListFeatureFlagsResponse{
+		Body: &spec.ListFeatureFlagsResponseBody{FeatureFlags: out},
+	}, nil
+}
+
+// SetFeatureFlag persists an opt-in or opt-out, or clears it when Enabled is nil.
+// Environment overrides still take precedence.
+func (s *SettingStore) SetFeatureFlag(
+	ctx context.Context, req *spec.SetFeatureFlagRequest,
+) (*spec.SetFeatureFlagResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.setFeatureFlag(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "SetFeatureFlag", req.IdempotencyKey, req,
+		func() (*spec.SetFeatureFlagResponse, error) { return s.setFeatureFlag(ctx, req) })
+}
+
+func (s *SettingStore) setFeatureFlag(
+	_ context.Context,
+	req *spec.SetFeatureFlagRequest,
+) (*spec.SetFeatureFlagResponse, error) {
+	if req == nil || req.Body == nil || req.Name == "" {
+		return nil, spec.ErrInvalidArgument
+	}
+	if _, ok := featureflag.Lookup(req.Name); !ok {
+		return nil, fmt.Errorf("%w: %q", spec.ErrUnknownFeatureFlag, req.Name)
+	}
+
+	keyPath := []string{settingKeyFeatureFlags, string(req.Name)}
+	if req.Body.Enabled == nil {
+		if err := s.store.DeleteKey(keyPath); err != nil {
+			return nil, err
+		}
+		s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeFeatureFlag, FeatureFlag: req.Name})
+		logger.Info("feature flag cleared", "name", req.Name)
+		return &spec.SetFeatureFlagResponse{}, nil
+	}
+	if err := s.store.SetKey(keyPath, *req.Body.Enabled); err != nil {
+		return nil, err
+	}
+	s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeFeatureFlag, FeatureFlag: req.Name})
+	logger.Info("feature flag updated", "name", req.Name, "enabled", *req.Body.Enabled)
+	return &spec.SetFeatureFlagResponse{}, nil
+}
+
+// FeatureEnabled implements featureflag.Gate. Unknown flags are disabled.
+func (s *SettingStore) FeatureEnabled(name featureflag.Name) bool {
+	d, ok := featureflag.Lookup(name)
+	if !ok {
+		return false
+	}
+	var persisted map[featureflag.Name]bool
+	if s != nil && s.store != nil {
+		var err error
+		if persisted, err = s.persistedFeatureFlags(); err != nil {
+			logger.Warn("feature flags unavailable, using defaults", "error", err)
+		}
+	}
+	return resolveFeatureFlag(d, persisted).Enabled
+}
+
+func (s *SettingStore) persistedFeatureFlags() (map[featureflag.Name]bool, error) {
+	raw, err := s.store.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+	var schema spec.SettingsSchema
+	if err := jsonencdec.MapToStructWithJSONTags(raw, &schema); err != nil {
+		return nil, err
+	}
+	return schema.FeatureFlags, nil
+}
+
+func resolveFeatureFlag(
+	d featureflag.Definition,
+	persisted map[featureflag.Name]bool,
+) spec.FeatureFlag {
+	f := spec.FeatureFlag{
+		Definition: d,
+		Enabled:    d.Default,
+		Source:     spec.FeatureFlagSourceDefault,
+		EnvVar:     featureflag.EnvVarName(d.Name),
+	}
+	if v, ok := persisted[d.Name]; ok {
+		f.Enabled = v
+		f.Source = spec.FeatureFlagSourceSettings
+	}
+	if v, ok := featureflag.EnvOverride(d.Name); ok {
+		f.Enabled = v
+		f.Source = spec.FeatureFlagSourceEnv
+	}
+	return f
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	"github.com/flexigpt/mapstore-go/jsonencdec"
+)
+
+const settingKeyNetwork = "network"
+
+func (s *SettingStore) SetNetworkSettingsApplier(applier NetworkSettingsApplier) {
+	if s == nil {
+		return
+	}
+	s.networkSettingsApplier = applier
+}
+
+// ApplyCurrentNetworkSettings hands the persisted network settings to the
+// registered applier.
+func (s *SettingStore) ApplyCurrentNetworkSettings(ctx context.Context, forceFetch bool) error {
+	if s == nil {
+		return nil
+	}
+
+	resp, err := s.GetSettings(ctx, &spec.GetSettingsRequest{ForceFetch: forceFetch})
+	if err != nil {
+		return err
+	}
+	if resp == nil || resp.Body == nil {
+		return errors.New("get settings: empty response body")
+	}
+	return s.applyNetworkSettings(ctx, resp.Body.Network)
+}
+
+// SetNetworkSettings validates and persists proxy and TLS settings, then
+// applies them to the provider HTTP clients.
+func (s *SettingStore) SetNetworkSettings(
+	ctx context.Context,
+	req *spec.SetNetworkSettingsRequest,
+) (*spec.SetNetworkSettingsResponse, error) {
+	if req == nil || req.Body == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+
+	cfg, err := normalizeNetworkSettings(req.Body.NetworkSettings)
+	if err != nil {
+		return nil, err
+	}
+	val, err := jsonencdec.StructWithJSONTagsToMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SetKey([]string{settingKeyNetwork}, val); err != nil {
+		return nil, err
+	}
+	if err := s.applyNetworkSettings(ctx, cfg); err != nil {
+		return nil, fmt.Errorf("network settings saved but runtime apply failed: %w", err)
+	}
+
+	s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeNetwork})
+	logger.Info(
+		"network settings updated",
+		"httpProxy", cfg.HTTPProxy != "",
+		"httpsProxy", cfg.HTTPSProxy != "",
+		"socksProxy", cfg.SOCKSProxy != "",
+		"caBundle", cfg.CABundlePath,
+		"insecureSkipVerifyProviders", cfg.InsecureSkipVerifyProviders,
+	)
+	return &spec.SetNetworkSettingsResponse{}, nil
+}
+
+func (s *SettingStore) applyNetworkSettings(ctx context.Context, cfg spec.NetworkSettings) error {
+	if s == nil || s.networkSettingsApplier == nil {
+		return nil
+	}
+	return s.networkSettingsApplier(ctx, cfg)
+}
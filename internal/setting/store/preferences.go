@@ -0,0 +1,203 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	"github.com/flexigpt/mapstore-go/jsonencdec"
+)
+
+const settingKeyPreferences = "preferences"
+
+var preferenceNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// PreferenceValidator checks a value before it is stored under key in the
+// namespace it was registered for.
+type PreferenceValidator func(key string, value json.RawMessage) error
+
+// RegisterPreferenceValidator installs the validator for a namespace,
+// replacing any previous one. A nil validator removes it.
+func (s *SettingStore) RegisterPreferenceValidator(namespace string, validator PreferenceValidator) {
+	s.preferenceMu.Lock()
+	defer s.preferenceMu.Unlock()
+	if validator == nil {
+		delete(s.preferenceValidators, namespace)
+		return
+	}
+	if s.preferenceValidators == nil {
+		s.preferenceValidators = map[string]PreferenceValidator{}
+	}
+	s.preferenceValidators[namespace] = validator
+}
+
+// GetPreference returns one stored preference.
+func (s *SettingStore) GetPreference(
+	_ context.Context,
+	req *spec.GetPreferenceRequest,
+) (*spec.GetPreferenceResponse, error) {
+	if req == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+	if err := validatePreferenceRef(req.Namespace, req.Key); err != nil {
+		return nil, err
+	}
+	prefs, err := s.preferences()
+	if err != nil {
+		return nil, err
+	}
+	value, ok := prefs[req.Namespace][req.Key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", spec.ErrPreferenceNotFound, req.Namespace, req.Key)
+	}
+	pref, err := newPreference(req.Namespace, req.Key, value)
+	if err != nil {
+		return nil, err
+	}
+	return &spec.GetPreferenceResponse{Body: &pref}, nil
+}
+
+// SetPreference stores a JSON value after running the namespace validator.
+// A null value removes the preference.
+func (s *SettingStore) SetPreference(
+	_ context.Context,
+	req *spec.SetPreferenceRequest,
+) (*spec.SetPreferenceResponse, error) {
+	if req == nil || req.Body == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+	if err := validatePreferenceRef(req.Namespace, req.Key); err != nil {
+		return nil, err
+	}
+	raw := bytes.TrimSpace(req.Body.Value)
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%w: value required", spec.ErrInvalidPreference)
+	}
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, fmt.Errorf("%w: %w", spec.ErrInvalidPreference, err)
+	}
+
+	if value == nil {
+		if err := s.deletePreference(req.Namespace, req.Key); err != nil {
+			return nil, err
+		}
+		s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangePreference, Namespace: req.Namespace, Key: req.Key})
+		logger.Info("preference removed", "namespace", req.Namespace, "key", req.Key)
+		return &spec.SetPreferenceResponse{}, nil
+	}
+
+	if err := s.runPreferenceValidator(req.Namespace, req.Key, raw); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.SetKey([]string{settingKeyPreferences, req.Namespace, req.Key}, value); err != nil {
+		return nil, err
+	}
+	s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangePreference, Namespace: req.Namespace, Key: req.Key})
+	logger.Info("preference updated", "namespace", req.Namespace, "key", req.Key)
+	return &spec.SetPreferenceResponse{}, nil
+}
+
+// ListPreferences returns stored preferences sorted by namespace and key,
+// optionally limited to one namespace.
+func (s *SettingStore) ListPreferences(
+	_ context.Context,
+	req *spec.ListPreferencesRequest,
+) (*spec.ListPreferencesResponse, error) {
+	namespace := ""
+	if req != nil {
+		namespace = strings.TrimSpace(req.Namespace)
+	}
+	if namespace != "" && !preferenceNameRe.MatchString(namespace) {
+		return nil, fmt.Errorf("%w: namespace %q", spec.ErrInvalidPreference, namespace)
+	}
+	prefs, err := s.preferences()
+	if err != nil {
+		return nil, err
+	}
+
+	out := []spec.Preference{}
+	for _, ns := range slices.Sorted(maps.Keys(prefs)) {
+		if namespace != "" && ns != namespace {
+			continue
+		}
+		for _, key := range slices.Sorted(maps.Keys(prefs[ns])) {
+			pref, err := newPreference(ns, key, prefs[ns][key])
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, pref)
+		}
+	}
+	return &spec.ListPreferencesResponse{
+		Body: &spec.ListPreferencesResponseBody{Preferences: out},
+	}, nil
+}
+
+func (s *SettingStore) runPreferenceValidator(namespace, key string, raw json.RawMessage) error {
+	s.preferenceMu.RLock()
+	validator := s.preferenceValidators[namespace]
+	s.preferenceMu.RUnlock()
+	if validator == nil {
+		return nil
+	}
+	if err := validator(key, raw); err != nil {
+		return fmt.Errorf("%w: %s/%s: %w", spec.ErrInvalidPreference, namespace, key, err)
+	}
+	return nil
+}
+
+func (s *SettingStore) deletePreference(namespace, key string) error {
+	prefs, err := s.preferences()
+	if err != nil {
+		return err
+	}
+	values, ok := prefs[namespace]
+	if !ok {
+		return nil
+	}
+	if _, ok := values[key]; !ok {
+		return nil
+	}
+	if len(values) == 1 {
+		return s.store.DeleteKey([]string{settingKeyPreferences, namespace})
+	}
+	return s.store.DeleteKey([]string{settingKeyPreferences, namespace, key})
+}
+
+func (s *SettingStore) preferences() (map[string]map[string]any, error) {
+	raw, err := s.store.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+	var schema spec.SettingsSchema
+	if err := jsonencdec.MapToStructWithJSONTags(raw, &schema); err != nil {
+		return nil, err
+	}
+	return schema.Preferences, nil
+}
+
+func newPreference(namespace, key string, value any) (spec.Preference, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return spec.Preference{}, err
+	}
+	return spec.Preference{Namespace: namespace, Key: key, Value: raw}, nil
+}
+
+func validatePreferenceRef(namespace, key string) error {
+	if !preferenceNameRe.MatchString(namespace) {
+		return fmt.Errorf("%w: namespace %q", spec.ErrInvalidPreference, namespace)
+	}
+	if !preferenceNameRe.MatchString(key) {
+		return fmt.Errorf("%w: key %q", spec.ErrInvalidPreference, key)
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/idempotency"
+	"github.com/flexigpt/flexigpt-app/internal/precondition"
+	"github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	"github.com/flexigpt/mapstore-go/jsonencdec"
+)
+
+const (
+	settingKeyProfiles        = "profiles"
+	settingKeyActiveProfileID = "activeProfileID"
+)
+
+// PutProfile creates or replaces a profile. Its preferences are checked with
+// the registered preference validators.
+func (s *SettingStore) PutProfile(
+	ctx context.Context, req *spec.PutProfileRequest,
+) (*spec.PutProfileResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.putProfile(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PutProfile", req.IdempotencyKey, req,
+		func() (*spec.PutProfileResponse, error) { return s.putProfile(ctx, req) })
+}
+
+func (s *SettingStore) putProfile(
+	_ context.Context,
+	req *spec.PutProfileRequest,
+) (*spec.PutProfileResponse, error) {
+	if req == nil || req.Body == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+	if !preferenceNameRe.MatchString(req.ProfileID) {
+		return nil, fmt.Errorf("%w: id %q", spec.ErrInvalidProfile, req.ProfileID)
+	}
+	b := req.Body
+	name := strings.TrimSpace(b.DisplayName)
+	if name == "" {
+		return nil, fmt.Errorf("%w: displayName is empty", spec.ErrInvalidProfile)
+	}
+	if b.ModelPresetRef != nil && (b.ModelPresetRef.ProviderName == "" || b.ModelPresetRef.ModelPresetID == "") {
+		return nil, fmt.Errorf("%w: modelPresetRef needs providerName and modelPresetID", spec.ErrInvalidProfile)
+	}
+	for ns, values := range b.Preferences {
+		for key, value := range values {
+			if err := validatePreferenceRef(ns, key); err != nil {
+				return nil, fmt.Errorf("%w: %w", spec.ErrInvalidProfile, err)
+			}
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: preference %s/%s: %w", spec.ErrInvalidProfile, ns, key, err)
+			}
+			if err := s.runPreferenceValidator(ns, key, raw); err != nil {
+				return nil, fmt.Errorf("%w: %w", spec.ErrInvalidProfile, err)
+			}
+		}
+	}
+
+	schema, err := s.profilesSchema()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	p := spec.Profile{
+		ID:             req.ProfileID,
+		DisplayName:    name,
+		Description:    strings.TrimSpace(b.Description),
+		ModelPresetRef: b.ModelPresetRef,
+		EnabledSkills:  b.EnabledSkills,
+		ToolAllowlist:  b.ToolAllowlist,
+		Preferences:    b.Preferences,
+		CreatedAt:      now,
+		ModifiedAt:     now,
+	}
+	if existing, ok := schema.Profiles[p.ID]; ok {
+		if err := precondition.CheckModifiedAt(b.ExpectedModifiedAt, existing.ModifiedAt, existing); err != nil {
+			return nil, err
+		}
+		p.CreatedAt = existing.CreatedAt
+	} else if b.ExpectedModifiedAt != nil {
+		return nil, fmt.Errorf("%w: %s", spec.ErrProfileNotFound, p.ID)
+	}
+	if req.DryRun {
+		return &spec.PutProfileResponse{Body: &spec.PutProfileResponseBody{Profile: p}}, nil
+	}
+	val, err := jsonencdec.StructWithJSONTagsToMap(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SetKey([]string{settingKeyProfiles, p.ID}, val); err != nil {
+		return nil, err
+	}
+	s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeProfile, ProfileID: p.ID})
+	logger.Info("profile saved", "profileID", p.ID)
+	return &spec.PutProfileResponse{}, nil
+}
+
+// DeleteProfile removes a profile, clearing it first if it is active.
+func (s *SettingStore) DeleteProfile(
+	ctx context.Context, req *spec.DeleteProfileRequest,
+) (*spec.DeleteProfileResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.deleteProfile(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "DeleteProfile", req.IdempotencyKey, req,
+		func() (*spec.DeleteProfileResponse, error) { return s.deleteProfile(ctx, req) })
+}
+
+func (s *SettingStore) deleteProfile(
+	_ context.Context,
+	req *spec.DeleteProfileRequest,
+) (*spec.DeleteProfileResponse, error) {
+	if req == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+	schema, err := s.profilesSchema()
+	if err != nil {
+		return nil, err
+	}
+	p, ok := schema.Profiles[req.ProfileID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrProfileNotFound, req.ProfileID)
+	}
+	if req.DryRun {
+		return &spec.DeleteProfileResponse{Body: &spec.DeleteProfileResponseBody{Profile: p}}, nil
+	}
+	if schema.ActiveProfileID == req.ProfileID {
+		if err := s.store.DeleteKey([]string{settingKeyActiveProfileID}); err != nil {
+			return nil, err
+		}
+		s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeActiveProfile})
+	}
+	if len(schema.Profiles) == 1 {
+		err = s.store.DeleteKey([]string{settingKeyProfiles})
+	} else {
+		err = s.store.DeleteKey([]string{settingKeyProfiles, req.ProfileID})
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeProfile, ProfileID: req.ProfileID})
+	logger.Info("profile deleted", "profileID", req.ProfileID)
+	return &spec.DeleteProfileResponse{}, nil
+}
+
+func (s *SettingStore) GetProfile(
+	_ context.Context,
+	req *spec.GetProfileRequest,
+) (*spec.GetProfileResponse, error) {
+	if req == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+	schema, err := s.profilesSchema()
+	if err != nil {
+		return nil, err
+	}
+	p, ok := schema.Profiles[req.ProfileID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrProfileNotFound, req.ProfileID)
+	}
+	return &spec.GetProfileResponse{Body: &p}, nil
+}
+
+// ListProfiles returns the profiles sorted by ID and the active profile.
+func (s *SettingStore) ListProfiles(
+	_ context.Context,
+	_ *spec.ListProfilesRequest,
+) (*spec.ListProfilesResponse, error) {
+	schema, err := s.profilesSchema()
+	if err != nil {
+		return nil, err
+	}
+	body := &spec.ListProfilesResponseBody{
+		Profiles:        make([]spec.Profile, 0, len(schema.Profiles)),
+		ActiveProfileID: schema.ActiveProfileID,
+	}
+	for _, id := range slices.Sorted(maps.Keys(schema.Profiles)) {
+		body.Profiles = append(body.Profiles, schema.Profiles[id])
+	}
+	return &spec.ListProfilesResponse{Body: body}, nil
+}
+
+// SwitchProfile writes the profile's preferences and marks it active. Every
+// preference is validated before anything is written, and the previous
+// preferences are restored if marking the profile active fails. The caller
+// applies the model preset, which lives in another store.
+func (s *SettingStore) SwitchProfile(
+	_ context.Context,
+	req *spec.SwitchProfileRequest,
+) (*spec.SwitchProfileResponse, error) {
+	if req == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+	if req.ProfileID == "" {
+		if err := s.store.DeleteKey([]string{settingKeyActiveProfileID}); err != nil {
+			return nil, err
+		}
+		s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeActiveProfile})
+		logger.Info("active profile cleared")
+		return &spec.SwitchProfileResponse{}, nil
+	}
+
+	schema, err := s.profilesSchema()
+	if err != nil {
+		return nil, err
+	}
+	p, ok := schema.Profiles[req.ProfileID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", spec.ErrProfileNotFound, req.ProfileID)
+	}
+	prefs, changed, err := s.mergeProfilePreferences(schema.Preferences, p)
+	if err != nil {
+		return nil, fmt.Errorf("apply profile %s: %w", p.ID, err)
+	}
+	if err := s.setPreferences(prefs); err != nil {
+		return nil, err
+	}
+	if err := s.store.SetKey([]string{settingKeyActiveProfileID}, p.ID); err != nil {
+		if rerr := s.setPreferences(schema.Preferences); rerr != nil {
+			err = errors.Join(err, fmt.Errorf("restore preferences: %w", rerr))
+		}
+		return nil, err
+	}
+	for _, ref := range changed {
+		s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangePreference, Namespace: ref[0], Key: ref[1]})
+	}
+	s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeActiveProfile, ProfileID: p.ID})
+	logger.Info("profile switched", "profileID", p.ID)
+	return &spec.SwitchProfileResponse{Body: &p}, nil
+}
+
+// ActiveProfile returns the active profile, or nil when none is active.
+func (s *SettingStore) ActiveProfile(context.Context) (*spec.Profile, error) {
+	schema, err := s.profilesSchema()
+	if err != nil {
+		return nil, err
+	}
+	if schema.ActiveProfileID == "" {
+		return nil, nil
+	}
+	p, ok := schema.Profiles[schema.ActiveProfileID]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+// mergeProfilePreferences validates the preferences of p and returns cur with
+// them applied, plus the namespace/key pairs it set. A null value removes
+// the preference, as it does in SetPreference.
+func (s *SettingStore) mergeProfilePreferences(
+	cur map[string]map[string]any,
+	p spec.Profile,
+) (merged map[string]map[string]any, changed [][2]string, err error) {
+	merged = make(map[string]map[string]any, len(cur))
+	for ns, values := range cur {
+		merged[ns] = maps.Clone(values)
+	}
+	for _, ns := range slices.Sorted(maps.Keys(p.Preferences)) {
+		for _, key := range slices.Sorted(maps.Keys(p.Preferences[ns])) {
+			if err := validatePreferenceRef(ns, key); err != nil {
+				return nil, nil, err
+			}
+			value := p.Preferences[ns][key]
+			changed = append(changed, [2]string{ns, key})
+			if value == nil {
+				delete(merged[ns], key)
+				if len(merged[ns]) == 0 {
+					delete(merged, ns)
+				}
+				continue
+			}
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("preference %s/%s: %w", ns, key, err)
+			}
+			if err := s.runPreferenceValidator(ns, key, raw); err != nil {
+				return nil, nil, err
+			}
+			if merged[ns] == nil {
+				merged[ns] = map[string]any{}
+			}
+			merged[ns][key] = value
+		}
+	}
+	return merged, changed, nil
+}
+
+// setPreferences replaces the whole preferences bag in one write.
+func (s *SettingStore) setPreferences(prefs map[string]map[string]any) error {
+	if len(prefs) == 0 {
+		return s.store.DeleteKey([]string{settingKeyPreferences})
+	}
+	value := make(map[string]any, len(prefs))
+	for ns, values := range prefs {
+		value[ns] = values
+	}
+	return s.store.SetKey([]string{settingKeyPreferences}, value)
+}
+
+func (s *SettingStore) profilesSchema() (spec.SettingsSchema, error) {
+	raw, err := s.store.GetAll(false)
+	if err != nil {
+		return spec.SettingsSchema{}, err
+	}
+	var schema spec.SettingsSchema
+	if err := jsonencdec.MapToStructWithJSONTags(raw, &schema); err != nil {
+		return spec.SettingsSchema{}, err
+	}
+	return schema, nil
+}
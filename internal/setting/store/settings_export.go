@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	"github.com/flexigpt/mapstore-go/jsonencdec"
+)
+
+// ExportSettings returns the theme and the names of all auth keys. Secrets
+// and their hashes are left out.
+func (s *SettingStore) ExportSettings(
+	ctx context.Context,
+	_ *spec.ExportSettingsRequest,
+) (*spec.ExportSettingsResponse, error) {
+	current, err := s.GetSettings(ctx, &spec.GetSettingsRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	out := spec.SettingsExport{
+		SchemaVersion: spec.SchemaVersion,
+		ExportedAt:    time.Now().UTC(),
+		AppTheme:      current.Body.AppTheme,
+		AuthKeys:      make([]spec.AuthKeyRef, 0, len(current.Body.AuthKeys)),
+	}
+	for _, ak := range current.Body.AuthKeys {
+		out.AuthKeys = append(out.AuthKeys, spec.AuthKeyRef{Type: ak.Type, KeyName: ak.KeyName})
+	}
+	return &spec.ExportSettingsResponse{
+		Body: &spec.ExportSettingsResponseBody{Settings: out},
+	}, nil
+}
+
+// ImportSettings applies an exported theme and creates empty auth keys for
+// the names not present yet. The document is validated up front and applied
+// in one write, so a failed import changes nothing.
+func (s *SettingStore) ImportSettings(
+	_ context.Context,
+	req *spec.ImportSettingsRequest,
+) (*spec.ImportSettingsResponse, error) {
+	if req == nil || req.Body == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+	in := req.Body.Settings
+	if in.SchemaVersion != spec.SchemaVersion {
+		return nil, fmt.Errorf("%w: schemaVersion %q not equal to %q",
+			spec.ErrInvalidArgument, in.SchemaVersion, spec.SchemaVersion)
+	}
+
+	theme := in.AppTheme
+	if err := validateTheme(&theme); err != nil {
+		return nil, err
+	}
+	refs := make([]spec.AuthKeyRef, 0, len(in.AuthKeys))
+	seen := map[spec.AuthKeyRef]bool{}
+	for _, ref := range in.AuthKeys {
+		t, name, err := normalizeAuthKeyRef(ref.Type, ref.KeyName)
+		if err != nil {
+			return nil, fmt.Errorf("%w: auth key %q/%q", err, ref.Type, ref.KeyName)
+		}
+		ref = spec.AuthKeyRef{Type: t, KeyName: name}
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	raw, err := s.store.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+	themeMap, err := jsonencdec.StructWithJSONTagsToMap(theme)
+	if err != nil {
+		return nil, err
+	}
+	raw[settingKeyAppTheme] = themeMap
+
+	authKeys, _ := raw[settingKeyAuthKeys].(map[string]any)
+	if authKeys == nil {
+		authKeys = map[string]any{}
+		raw[settingKeyAuthKeys] = authKeys
+	}
+	out := &spec.ImportSettingsResponseBody{
+		CreatedAuthKeys:  []spec.AuthKeyRef{},
+		ExistingAuthKeys: []spec.AuthKeyRef{},
+	}
+	for _, ref := range refs {
+		names, _ := authKeys[string(ref.Type)].(map[string]any)
+		if names == nil {
+			names = map[string]any{}
+			authKeys[string(ref.Type)] = names
+		}
+		if _, ok := names[string(ref.KeyName)]; ok {
+			out.ExistingAuthKeys = append(out.ExistingAuthKeys, ref)
+			continue
+		}
+		names[string(ref.KeyName)] = map[string]any{
+			settingKeySecret:   "",
+			settingKeySHA256:   computeSHA(""),
+			settingKeyNonEmpty: false,
+		}
+		out.CreatedAuthKeys = append(out.CreatedAuthKeys, ref)
+	}
+
+	if err := s.store.SetAll(raw); err != nil {
+		return nil, err
+	}
+	s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeAppTheme})
+	for _, ref := range out.CreatedAuthKeys {
+		s.publish(spec.SettingChangeEvent{
+			Kind: spec.SettingChangeAuthKeySet, AuthKeyType: ref.Type, AuthKeyName: ref.KeyName,
+		})
+	}
+
+	logger.Info(
+		"settings imported",
+		"theme", theme.Name,
+		"createdAuthKeys", len(out.CreatedAuthKeys),
+		"existingAuthKeys", len(out.ExistingAuthKeys),
+	)
+	return &spec.ImportSettingsResponse{Body: out}, nil
+}
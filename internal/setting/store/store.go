@@ -6,23 +6,50 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"log/slog"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
+	"github.com/flexigpt/flexigpt-app/internal/idempotency"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 	"github.com/flexigpt/flexigpt-app/internal/setting/spec"
 	"github.com/flexigpt/mapstore-go"
 	"github.com/flexigpt/mapstore-go/jsonencdec"
 	"github.com/flexigpt/mapstore-go/keyringencdec"
 )
 
+var logger = logging.Module("setting.store")
+
 type DebugSettingsApplier func(context.Context, spec.DebugSettings) error
 
+type NetworkSettingsApplier func(context.Context, spec.NetworkSettings) error
+
 type SettingStore struct {
 	store                *mapstore.MapFileStore
 	encEncrypt           mapstore.IOEncoderDecoder
 	debugSettingsApplier DebugSettingsApplier
+
+	networkSettingsApplier NetworkSettingsApplier
+
+	// App-managed directories that are always trusted.
+	implicitTrustMu      sync.RWMutex
+	implicitTrustedRoots []string
+
+	// Per-namespace checks run before a preference is stored.
+	preferenceMu         sync.RWMutex
+	preferenceValidators map[string]PreferenceValidator
+
+	// Budget settings are read before every completion; cached until the
+	// next SetBudgetSettings.
+	budgetMu sync.Mutex
+	budget   *spec.BudgetSettings
+
+	notifier     settingNotifier
+	authKeyAudit authKeyAuditLog
+
+	// Results of keyed mutations, replayed to retries with the same key.
+	replay *idempotency.Cache
 }
 
 const (
@@ -50,6 +77,7 @@ func NewSettingStore(baseDir string) (*SettingStore, error) {
 	}
 	st := &SettingStore{
 		encEncrypt: encoderDecoder,
+		replay:     idempotency.NewCache(idempotency.DefaultTTL, idempotency.DefaultMaxEntries),
 	}
 
 	defaultMap, err := jsonencdec.StructWithJSONTagsToMap(DefaultSettingsData)
@@ -65,7 +93,7 @@ func NewSettingStore(baseDir string) (*SettingStore, error) {
 		mapstore.WithCreateIfNotExists(true),
 		mapstore.WithFileAutoFlush(true),
 		mapstore.WithValueEncDecGetter(st.valueEncDecGetter),
-		mapstore.WithFileLogger(slog.Default()),
+		mapstore.WithFileLogger(logger),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("file store init failed: %w", err)
@@ -75,7 +103,7 @@ func NewSettingStore(baseDir string) (*SettingStore, error) {
 	if err := st.Migrate(context.Background()); err != nil {
 		return nil, fmt.Errorf("settings migration failed: %w", err)
 	}
-	slog.Info("settings store ready", "file", file)
+	logger.Info("settings store ready", "file", file)
 	return st, nil
 }
 
@@ -84,6 +112,7 @@ func (s *SettingStore) Close() error {
 		return nil
 	}
 
+	s.closeSubscribers()
 	if s.store != nil {
 		_ = s.store.Close()
 	}
@@ -203,13 +232,13 @@ func (s *SettingStore) Migrate(ctx context.Context) error {
 	}
 
 	if addedBuiltInAuthKeys > 0 || debugChanged {
-		slog.Info(
+		logger.Info(
 			"settings migration complete",
 			"addedBuiltInAuthKeys", addedBuiltInAuthKeys,
 			"debugChanged", debugChanged,
 		)
 	} else {
-		slog.Info("settings migration: no changes needed")
+		logger.Info("settings migration: no changes needed")
 	}
 
 	return nil
@@ -234,7 +263,8 @@ func (s *SettingStore) SetAppTheme(
 		return nil, err
 	}
 
-	slog.Info("appTheme updated", "type", theme.Type, "name", theme.Name)
+	s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeAppTheme})
+	logger.Info("appTheme updated", "type", theme.Type, "name", theme.Name)
 	return &spec.SetAppThemeResponse{}, nil
 }
 
@@ -251,6 +281,8 @@ func (s *SettingStore) SetDebugSettings(
 		LogLLMReqResp:           req.Body.LogLLMReqResp,
 		DisableContentStripping: req.Body.DisableContentStripping,
 		LogLevel:                req.Body.LogLevel,
+		LogFormat:               req.Body.LogFormat,
+		ModuleLogLevels:         req.Body.ModuleLogLevels,
 	}
 	if err := validateDebugSettings(&cfg); err != nil {
 		return nil, err
@@ -267,17 +299,30 @@ func (s *SettingStore) SetDebugSettings(
 		return nil, fmt.Errorf("debug settings saved but runtime apply failed: %w", err)
 	}
 
-	slog.Info(
+	s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeDebug})
+	logger.Info(
 		"debug settings updated",
 		"logLLMReqResp", cfg.LogLLMReqResp,
 		"disableContentStripping", cfg.DisableContentStripping,
 		"logLevel", cfg.LogLevel,
+		"logFormat", cfg.LogFormat,
+		"moduleLogLevels", cfg.ModuleLogLevels,
 	)
 	return &spec.SetDebugSettingsResponse{}, nil
 }
 
 // SetAuthKey inserts or updates one auth-key.
 func (s *SettingStore) SetAuthKey(
+	ctx context.Context, req *spec.SetAuthKeyRequest,
+) (*spec.SetAuthKeyResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.setAuthKey(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "SetAuthKey", req.IdempotencyKey, req,
+		func() (*spec.SetAuthKeyResponse, error) { return s.setAuthKey(ctx, req) })
+}
+
+func (s *SettingStore) setAuthKey(
 	_ context.Context,
 	req *spec.SetAuthKeyRequest,
 ) (*spec.SetAuthKeyResponse, error) {
@@ -297,6 +342,14 @@ func (s *SettingStore) SetAuthKey(
 		SHA256:   computeSHA(req.Body.Secret),
 		NonEmpty: nonEmptySecret,
 	}
+	if req.DryRun {
+		return &spec.SetAuthKeyResponse{Body: &spec.SetAuthKeyResponseBody{AuthKey: spec.AuthKeyMeta{
+			Type:     t,
+			KeyName:  keyName,
+			SHA256:   newAk.SHA256,
+			NonEmpty: newAk.NonEmpty,
+		}}}, nil
+	}
 
 	// Persist secret (encrypted) then sha (plain).
 	secretPath := []string{settingKeyAuthKeys, string(t), string(keyName), settingKeySecret}
@@ -318,7 +371,8 @@ func (s *SettingStore) SetAuthKey(
 		return nil, err
 	}
 
-	slog.Info("authKey set",
+	s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeAuthKeySet, AuthKeyType: t, AuthKeyName: keyName})
+	logger.Info("authKey set",
 		"type", t, "keyName", keyName,
 		"builtIn", isBuiltInKey(t, keyName))
 	return &spec.SetAuthKeyResponse{}, nil
@@ -326,6 +380,16 @@ func (s *SettingStore) SetAuthKey(
 
 // DeleteAuthKey removes a key unless it is marked built-in.
 func (s *SettingStore) DeleteAuthKey(
+	ctx context.Context, req *spec.DeleteAuthKeyRequest,
+) (*spec.DeleteAuthKeyResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.deleteAuthKey(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "DeleteAuthKey", req.IdempotencyKey, req,
+		func() (*spec.DeleteAuthKeyResponse, error) { return s.deleteAuthKey(ctx, req) })
+}
+
+func (s *SettingStore) deleteAuthKey(
 	_ context.Context,
 	req *spec.DeleteAuthKeyRequest,
 ) (*spec.DeleteAuthKeyResponse, error) {
@@ -339,6 +403,13 @@ func (s *SettingStore) DeleteAuthKey(
 	if isBuiltInKey(t, keyName) {
 		return nil, spec.ErrBuiltInAuthKeyReadOnly
 	}
+	if req.DryRun {
+		body, err := s.deleteAuthKeyPreview(t, keyName)
+		if err != nil {
+			return nil, err
+		}
+		return &spec.DeleteAuthKeyResponse{Body: body}, nil
+	}
 
 	// Delete the key map entirely (secret + sha).
 	keyPath := []string{settingKeyAuthKeys, string(t), string(keyName)}
@@ -357,13 +428,39 @@ func (s *SettingStore) DeleteAuthKey(
 			_ = s.store.DeleteKey([]string{settingKeyAuthKeys, string(t)})
 		}
 	}
-	slog.Info("authKey deleted", "type", t, "keyName", keyName)
+	s.publish(spec.SettingChangeEvent{Kind: spec.SettingChangeAuthKeyDeleted, AuthKeyType: t, AuthKeyName: keyName})
+	logger.Info("authKey deleted", "type", t, "keyName", keyName)
 	return &spec.DeleteAuthKeyResponse{}, nil
 }
 
-// GetAuthKey returns the decrypted secret for one key.
+// deleteAuthKeyPreview describes the stored key without its secret. AuthKey is
+// nil if there is none.
+func (s *SettingStore) deleteAuthKeyPreview(
+	t spec.AuthKeyType,
+	keyName spec.AuthKeyName,
+) (*spec.DeleteAuthKeyResponseBody, error) {
+	raw, err := s.store.GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+	var schema spec.SettingsSchema
+	if err := jsonencdec.MapToStructWithJSONTags(raw, &schema); err != nil {
+		return nil, err
+	}
+	ak, ok := schema.AuthKeys[t][keyName]
+	if !ok {
+		return &spec.DeleteAuthKeyResponseBody{}, nil
+	}
+	return &spec.DeleteAuthKeyResponseBody{
+		AuthKey: &spec.AuthKeyMeta{Type: t, KeyName: keyName, SHA256: ak.SHA256, NonEmpty: ak.NonEmpty},
+	}, nil
+}
+
+// GetAuthKey returns the decrypted secret for one key. Each successful read
+// is recorded in the auth key audit log under the caller set with
+// WithAuthKeyCaller.
 func (s *SettingStore) GetAuthKey(
-	_ context.Context,
+	ctx context.Context,
 	req *spec.GetAuthKeyRequest,
 ) (*spec.GetAuthKeyResponse, error) {
 	if req == nil || req.Type == "" || req.KeyName == "" {
@@ -393,6 +490,7 @@ func (s *SettingStore) GetAuthKey(
 		return nil, spec.ErrAuthKeyNotFound
 	}
 
+	s.recordAuthKeyRead(ctx, t, keyName)
 	return &spec.GetAuthKeyResponse{
 		Body: &spec.GetAuthKeyResponseBody{
 			Secret:   ak.Secret,
@@ -429,7 +527,11 @@ func (s *SettingStore) GetSettings(
 		Body: &spec.GetSettingsResponseBody{
 			AppTheme: schema.AppTheme,
 			Debug:    schema.Debug,
+			Network:  schema.Network,
+			Budget:   schema.Budget,
 			AuthKeys: []spec.AuthKeyMeta{},
+
+			CompletionCache: schema.CompletionCache,
 		},
 	}
 	for t, m := range schema.AuthKeys {
@@ -4,16 +4,21 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"math"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/flexigpt/mapstore-go"
 	"github.com/flexigpt/mapstore-go/jsonencdec"
 
+	"github.com/flexigpt/flexigpt-app/internal/featureflag"
 	"github.com/flexigpt/flexigpt-app/internal/setting/spec"
 )
 
@@ -102,6 +107,20 @@ func TestValidateDebugSettings(t *testing.T) {
 		{"warn", &spec.DebugSettings{LogLevel: spec.DebugLogLevelWarn}, false},
 		{"error", &spec.DebugSettings{LogLevel: spec.DebugLogLevelError}, false},
 		{"invalid", &spec.DebugSettings{LogLevel: spec.DebugLogLevel(testInvalidLogLevelTrace)}, true},
+		{"json modules", &spec.DebugSettings{
+			LogLevel:        spec.DebugLogLevelInfo,
+			LogFormat:       spec.DebugLogFormatJSON,
+			ModuleLogLevels: map[string]spec.DebugLogLevel{"skill.store": spec.DebugLogLevelDebug},
+		}, false},
+		{"bad format", &spec.DebugSettings{LogLevel: spec.DebugLogLevelInfo, LogFormat: "xml"}, true},
+		{"bad module", &spec.DebugSettings{
+			LogLevel:        spec.DebugLogLevelInfo,
+			ModuleLogLevels: map[string]spec.DebugLogLevel{"Skill..store": spec.DebugLogLevelDebug},
+		}, true},
+		{"bad module level", &spec.DebugSettings{
+			LogLevel:        spec.DebugLogLevelInfo,
+			ModuleLogLevels: map[string]spec.DebugLogLevel{"skill": "verbose"},
+		}, true},
 	}
 
 	for _, tc := range cases {
@@ -624,6 +643,952 @@ func TestGetSettings_Sorting(t *testing.T) {
 	}
 }
 
+func TestSettingStore_FeatureFlags(t *testing.T) {
+	defaultMap := map[string]any{
+		settingKeySchemaVersion: spec.SchemaVersion,
+		settingKeyAppTheme: map[string]any{
+			settingJSONKeyType: spec.ThemeSystem,
+			settingJSONKeyName: spec.ThemeNameSystem,
+		},
+		settingKeyAuthKeys: map[string]any{},
+	}
+	store, cleanup := integrationTestStore(t, defaultMap)
+	defer cleanup()
+
+	ctx := t.Context()
+	name := featureflag.IncrementalHydration
+	t.Setenv(featureflag.EnvVarName(name), "")
+	_ = os.Unsetenv(featureflag.EnvVarName(name))
+
+	find := func() spec.FeatureFlag {
+		t.Helper()
+		out, err := store.ListFeatureFlags(ctx, &spec.ListFeatureFlagsRequest{})
+		if err != nil {
+			t.Fatalf("ListFeatureFlags failed: %v", err)
+		}
+		if len(out.Body.FeatureFlags) != len(featureflag.Definitions) {
+			t.Fatalf("got %d flags want %d", len(out.Body.FeatureFlags), len(featureflag.Definitions))
+		}
+		for _, f := range out.Body.FeatureFlags {
+			if f.Name == name {
+				return f
+			}
+		}
+		t.Fatalf("flag %q not listed", name)
+		return spec.FeatureFlag{}
+	}
+
+	if f := find(); !f.Enabled || f.Source != spec.FeatureFlagSourceDefault {
+		t.Fatalf("default flag = %+v", f)
+	}
+	if f := find(); f.EnvVar != "FLEXIGPT_FEATURE_INCREMENTAL_HYDRATION" {
+		t.Fatalf("EnvVar = %q", f.EnvVar)
+	}
+
+	if _, err := store.SetFeatureFlag(ctx, &spec.SetFeatureFlagRequest{
+		Name: name,
+		Body: &spec.SetFeatureFlagRequestBody{Enabled: new(false)},
+	}); err != nil {
+		t.Fatalf("SetFeatureFlag failed: %v", err)
+	}
+	if f := find(); f.Enabled || f.Source != spec.FeatureFlagSourceSettings {
+		t.Fatalf("persisted flag = %+v", f)
+	}
+	if store.FeatureEnabled(name) {
+		t.Fatalf("FeatureEnabled = true after opt-out")
+	}
+
+	t.Setenv(featureflag.EnvVarName(name), "true")
+	if f := find(); !f.Enabled || f.Source != spec.FeatureFlagSourceEnv {
+		t.Fatalf("env flag = %+v", f)
+	}
+	_ = os.Unsetenv(featureflag.EnvVarName(name))
+
+	if _, err := store.SetFeatureFlag(ctx, &spec.SetFeatureFlagRequest{
+		Name: name,
+		Body: &spec.SetFeatureFlagRequestBody{},
+	}); err != nil {
+		t.Fatalf("clear SetFeatureFlag failed: %v", err)
+	}
+	if f := find(); !f.Enabled || f.Source != spec.FeatureFlagSourceDefault {
+		t.Fatalf("cleared flag = %+v", f)
+	}
+
+	_, err := store.SetFeatureFlag(ctx, &spec.SetFeatureFlagRequest{
+		Name: "noSuchFlag",
+		Body: &spec.SetFeatureFlagRequestBody{Enabled: new(true)},
+	})
+	if !errors.Is(err, spec.ErrUnknownFeatureFlag) {
+		t.Fatalf("unknown flag err = %v", err)
+	}
+	if store.FeatureEnabled("noSuchFlag") {
+		t.Fatalf("unknown flag reported enabled")
+	}
+}
+
+func TestSettingStore_ExportImportSettings(t *testing.T) {
+	defaultMap := map[string]any{
+		settingKeySchemaVersion: spec.SchemaVersion,
+		settingKeyAppTheme: map[string]any{
+			settingJSONKeyType: spec.ThemeSystem,
+			settingJSONKeyName: spec.ThemeNameSystem,
+		},
+		settingKeyAuthKeys: map[string]any{},
+	}
+	src, cleanupSrc := integrationTestStore(t, defaultMap)
+	defer cleanupSrc()
+	dst, cleanupDst := integrationTestStore(t, defaultMap)
+	defer cleanupDst()
+
+	ctx := t.Context()
+	if _, err := src.SetAppTheme(ctx, &spec.SetAppThemeRequest{
+		Body: &spec.SetAppThemeRequestBody{Type: spec.ThemeDark, Name: spec.ThemeNameDark},
+	}); err != nil {
+		t.Fatalf("SetAppTheme failed: %v", err)
+	}
+	for _, name := range []spec.AuthKeyName{testAuthNameP1, testAuthNameP2} {
+		if _, err := src.SetAuthKey(ctx, &spec.SetAuthKeyRequest{
+			Type:    testAuthTypeProvider,
+			KeyName: name,
+			Body:    &spec.SetAuthKeyRequestBody{Secret: "secret-" + string(name)},
+		}); err != nil {
+			t.Fatalf("SetAuthKey failed: %v", err)
+		}
+	}
+	if _, err := dst.SetAuthKey(ctx, &spec.SetAuthKeyRequest{
+		Type:    testAuthTypeProvider,
+		KeyName: testAuthNameP2,
+		Body:    &spec.SetAuthKeyRequestBody{Secret: "kept"},
+	}); err != nil {
+		t.Fatalf("SetAuthKey failed: %v", err)
+	}
+
+	exp, err := src.ExportSettings(ctx, &spec.ExportSettingsRequest{})
+	if err != nil {
+		t.Fatalf("ExportSettings failed: %v", err)
+	}
+	doc := exp.Body.Settings
+	if doc.AppTheme.Type != spec.ThemeDark {
+		t.Fatalf("exported theme = %+v", doc.AppTheme)
+	}
+	wantRefs := []spec.AuthKeyRef{
+		{Type: testAuthTypeProvider, KeyName: testAuthNameP1},
+		{Type: testAuthTypeProvider, KeyName: testAuthNameP2},
+	}
+	if !reflect.DeepEqual(doc.AuthKeys, wantRefs) {
+		t.Fatalf("exported keys = %+v, want %+v", doc.AuthKeys, wantRefs)
+	}
+
+	bad := doc
+	bad.AppTheme = spec.AppTheme{Type: spec.ThemeLight, Name: testInvalidThemeNameWrong}
+	if _, err := dst.ImportSettings(ctx, &spec.ImportSettingsRequest{
+		Body: &spec.ImportSettingsRequestBody{Settings: bad},
+	}); !errors.Is(err, spec.ErrInvalidTheme) {
+		t.Fatalf("import with bad theme err = %v", err)
+	}
+
+	// A document from another schema, or with a bad key name after a valid
+	// theme, changes nothing.
+	stale := doc
+	stale.SchemaVersion = "2020-01-01"
+	if _, err := dst.ImportSettings(ctx, &spec.ImportSettingsRequest{
+		Body: &spec.ImportSettingsRequestBody{Settings: stale},
+	}); !errors.Is(err, spec.ErrInvalidArgument) {
+		t.Fatalf("import with stale schema err = %v", err)
+	}
+	badKey := doc
+	badKey.AuthKeys = append(slices.Clone(doc.AuthKeys), spec.AuthKeyRef{Type: testAuthTypeProvider, KeyName: ""})
+	if _, err := dst.ImportSettings(ctx, &spec.ImportSettingsRequest{
+		Body: &spec.ImportSettingsRequestBody{Settings: badKey},
+	}); err == nil {
+		t.Fatal("import with an empty key name succeeded")
+	}
+	if got, err := dst.GetSettings(ctx, nil); err != nil || got.Body.AppTheme.Type == spec.ThemeDark ||
+		len(got.Body.AuthKeys) != 1 {
+		t.Fatalf("failed imports changed settings: %+v, %v", got, err)
+	}
+
+	imp, err := dst.ImportSettings(ctx, &spec.ImportSettingsRequest{
+		Body: &spec.ImportSettingsRequestBody{Settings: doc},
+	})
+	if err != nil {
+		t.Fatalf("ImportSettings failed: %v", err)
+	}
+	if !reflect.DeepEqual(imp.Body.CreatedAuthKeys, wantRefs[:1]) ||
+		!reflect.DeepEqual(imp.Body.ExistingAuthKeys, wantRefs[1:]) {
+		t.Fatalf("import result = %+v", imp.Body)
+	}
+
+	got, err := dst.GetSettings(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetSettings failed: %v", err)
+	}
+	if got.Body.AppTheme != doc.AppTheme {
+		t.Fatalf("imported theme = %+v", got.Body.AppTheme)
+	}
+	placeholder, err := dst.GetAuthKey(ctx, &spec.GetAuthKeyRequest{
+		Type:    testAuthTypeProvider,
+		KeyName: testAuthNameP1,
+	})
+	if err != nil {
+		t.Fatalf("GetAuthKey placeholder failed: %v", err)
+	}
+	if placeholder.Body.NonEmpty || placeholder.Body.Secret != "" {
+		t.Fatalf("placeholder = %+v", placeholder.Body)
+	}
+	kept, err := dst.GetAuthKey(ctx, &spec.GetAuthKeyRequest{
+		Type:    testAuthTypeProvider,
+		KeyName: testAuthNameP2,
+	})
+	if err != nil || kept.Body.Secret != "kept" {
+		t.Fatalf("existing key = %+v, %v", kept, err)
+	}
+}
+
+func TestSettingStore_Preferences(t *testing.T) {
+	defaultMap := map[string]any{
+		settingKeySchemaVersion: spec.SchemaVersion,
+		settingKeyAppTheme: map[string]any{
+			settingJSONKeyType: spec.ThemeSystem,
+			settingJSONKeyName: spec.ThemeNameSystem,
+		},
+		settingKeyAuthKeys: map[string]any{},
+	}
+	store, cleanup := integrationTestStore(t, defaultMap)
+	defer cleanup()
+
+	ctx := t.Context()
+	set := func(ns, key, value string) error {
+		_, err := store.SetPreference(ctx, &spec.SetPreferenceRequest{
+			Namespace: ns,
+			Key:       key,
+			Body:      &spec.SetPreferenceRequestBody{Value: json.RawMessage(value)},
+		})
+		return err
+	}
+
+	store.RegisterPreferenceValidator("editor", func(key string, value json.RawMessage) error {
+		if key != "fontSize" {
+			return nil
+		}
+		var size int
+		if err := json.Unmarshal(value, &size); err != nil || size < 8 {
+			return errors.New("fontSize must be an integer >= 8")
+		}
+		return nil
+	})
+
+	if err := set("editor", "fontSize", `14`); err != nil {
+		t.Fatalf("SetPreference fontSize failed: %v", err)
+	}
+	if err := set("sidebar", "layout", `{"collapsed":true,"width":240}`); err != nil {
+		t.Fatalf("SetPreference layout failed: %v", err)
+	}
+	if err := set("editor", "fontSize", `"big"`); !errors.Is(err, spec.ErrInvalidPreference) {
+		t.Fatalf("validator err = %v", err)
+	}
+	if err := set("editor", "bad key", `1`); !errors.Is(err, spec.ErrInvalidPreference) {
+		t.Fatalf("bad key err = %v", err)
+	}
+	if err := set("editor", "tabs", `{`); !errors.Is(err, spec.ErrInvalidPreference) {
+		t.Fatalf("bad json err = %v", err)
+	}
+
+	got, err := store.GetPreference(ctx, &spec.GetPreferenceRequest{Namespace: "editor", Key: "fontSize"})
+	if err != nil {
+		t.Fatalf("GetPreference failed: %v", err)
+	}
+	if string(got.Body.Value) != `14` {
+		t.Fatalf("fontSize = %s", got.Body.Value)
+	}
+
+	list, err := store.ListPreferences(ctx, &spec.ListPreferencesRequest{})
+	if err != nil {
+		t.Fatalf("ListPreferences failed: %v", err)
+	}
+	if len(list.Body.Preferences) != 2 ||
+		list.Body.Preferences[0].Namespace != "editor" ||
+		list.Body.Preferences[1].Namespace != "sidebar" {
+		t.Fatalf("preferences = %+v", list.Body.Preferences)
+	}
+	if string(list.Body.Preferences[1].Value) != `{"collapsed":true,"width":240}` {
+		t.Fatalf("layout = %s", list.Body.Preferences[1].Value)
+	}
+
+	if err := set("editor", "fontSize", `null`); err != nil {
+		t.Fatalf("remove preference failed: %v", err)
+	}
+	_, err = store.GetPreference(ctx, &spec.GetPreferenceRequest{Namespace: "editor", Key: "fontSize"})
+	if !errors.Is(err, spec.ErrPreferenceNotFound) {
+		t.Fatalf("removed preference err = %v", err)
+	}
+	list, err = store.ListPreferences(ctx, &spec.ListPreferencesRequest{Namespace: "editor"})
+	if err != nil || len(list.Body.Preferences) != 0 {
+		t.Fatalf("editor preferences after remove = %+v, %v", list, err)
+	}
+}
+
+func TestSettingStore_Profiles(t *testing.T) {
+	defaultMap := map[string]any{
+		settingKeySchemaVersion: spec.SchemaVersion,
+		settingKeyAppTheme: map[string]any{
+			settingJSONKeyType: spec.ThemeSystem,
+			settingJSONKeyName: spec.ThemeNameSystem,
+		},
+		settingKeyAuthKeys: map[string]any{},
+	}
+	store, cleanup := integrationTestStore(t, defaultMap)
+	defer cleanup()
+
+	ctx := t.Context()
+	store.RegisterPreferenceValidator("editor", func(key string, value json.RawMessage) error {
+		var size int
+		if key == "fontSize" && (json.Unmarshal(value, &size) != nil || size < 8) {
+			return errors.New("fontSize must be an integer >= 8")
+		}
+		return nil
+	})
+	put := func(id string, body spec.PutProfileRequestBody) error {
+		_, err := store.PutProfile(ctx, &spec.PutProfileRequest{ProfileID: id, Body: &body})
+		return err
+	}
+
+	if err := put("bad id", spec.PutProfileRequestBody{DisplayName: "x"}); !errors.Is(err, spec.ErrInvalidProfile) {
+		t.Fatalf("bad id err = %v", err)
+	}
+	if err := put("acme", spec.PutProfileRequestBody{DisplayName: " "}); !errors.Is(err, spec.ErrInvalidProfile) {
+		t.Fatalf("empty name err = %v", err)
+	}
+	err := put("acme", spec.PutProfileRequestBody{
+		DisplayName: "Acme",
+		Preferences: map[string]map[string]any{"editor": {"fontSize": 4}},
+	})
+	if !errors.Is(err, spec.ErrInvalidProfile) {
+		t.Fatalf("invalid preference err = %v", err)
+	}
+
+	if err := put("acme", spec.PutProfileRequestBody{
+		DisplayName: "Acme",
+		Preferences: map[string]map[string]any{"editor": {"fontSize": 16}},
+	}); err != nil {
+		t.Fatalf("PutProfile acme failed: %v", err)
+	}
+	if err := put("globex", spec.PutProfileRequestBody{
+		DisplayName: "Globex",
+		Preferences: map[string]map[string]any{"editor": {"fontSize": 12}},
+	}); err != nil {
+		t.Fatalf("PutProfile globex failed: %v", err)
+	}
+
+	fontSize := func() string {
+		t.Helper()
+		got, err := store.GetPreference(ctx, &spec.GetPreferenceRequest{Namespace: "editor", Key: "fontSize"})
+		if err != nil {
+			t.Fatalf("GetPreference failed: %v", err)
+		}
+		return string(got.Body.Value)
+	}
+	switchTo := func(id string) {
+		t.Helper()
+		if _, err := store.SwitchProfile(ctx, &spec.SwitchProfileRequest{ProfileID: id}); err != nil {
+			t.Fatalf("SwitchProfile %q failed: %v", id, err)
+		}
+	}
+
+	switchTo("acme")
+	if got := fontSize(); got != `16` {
+		t.Fatalf("fontSize after acme = %s", got)
+	}
+	switchTo("globex")
+	if got := fontSize(); got != `12` {
+		t.Fatalf("fontSize after globex = %s", got)
+	}
+	list, err := store.ListProfiles(ctx, &spec.ListProfilesRequest{})
+	if err != nil {
+		t.Fatalf("ListProfiles failed: %v", err)
+	}
+	if len(list.Body.Profiles) != 2 || list.Body.Profiles[0].ID != "acme" || list.Body.ActiveProfileID != "globex" {
+		t.Fatalf("profiles = %+v", list.Body)
+	}
+
+	active, err := store.ActiveProfile(ctx)
+	if err != nil || active == nil || active.ID != "globex" {
+		t.Fatalf("ActiveProfile = %+v, %v", active, err)
+	}
+
+	// A preference that no longer validates leaves everything as it was.
+	if err := put("initech", spec.PutProfileRequestBody{
+		DisplayName: "Initech",
+		Preferences: map[string]map[string]any{"editor": {"fontSize": 20, "wrap": "soft"}},
+	}); err != nil {
+		t.Fatalf("PutProfile initech failed: %v", err)
+	}
+	store.RegisterPreferenceValidator("editor", func(key string, _ json.RawMessage) error {
+		if key == "wrap" {
+			return errors.New("wrap is no longer supported")
+		}
+		return nil
+	})
+	if _, err := store.SwitchProfile(ctx, &spec.SwitchProfileRequest{ProfileID: "initech"}); err == nil {
+		t.Fatal("switch to a profile with an invalid preference succeeded")
+	}
+	if got := fontSize(); got != `12` {
+		t.Fatalf("fontSize after failed switch = %s", got)
+	}
+	if active, _ := store.ActiveProfile(ctx); active == nil || active.ID != "globex" {
+		t.Fatalf("active profile after failed switch = %+v", active)
+	}
+	if _, err := store.DeleteProfile(ctx, &spec.DeleteProfileRequest{ProfileID: "initech"}); err != nil {
+		t.Fatalf("DeleteProfile initech failed: %v", err)
+	}
+
+	if _, err := store.SwitchProfile(ctx, &spec.SwitchProfileRequest{ProfileID: "missing"}); !errors.Is(
+		err, spec.ErrProfileNotFound) {
+		t.Fatalf("switch missing err = %v", err)
+	}
+	if _, err := store.DeleteProfile(ctx, &spec.DeleteProfileRequest{ProfileID: "globex"}); err != nil {
+		t.Fatalf("DeleteProfile failed: %v", err)
+	}
+	list, err = store.ListProfiles(ctx, &spec.ListProfilesRequest{})
+	if err != nil || len(list.Body.Profiles) != 1 || list.Body.ActiveProfileID != "" {
+		t.Fatalf("profiles after delete = %+v, %v", list, err)
+	}
+	if _, err := store.GetProfile(ctx, &spec.GetProfileRequest{ProfileID: "globex"}); !errors.Is(
+		err, spec.ErrProfileNotFound) {
+		t.Fatalf("get deleted err = %v", err)
+	}
+}
+
+func TestSettingStore_NetworkSettings(t *testing.T) {
+	defaultMap := map[string]any{
+		settingKeySchemaVersion: spec.SchemaVersion,
+		settingKeyAppTheme: map[string]any{
+			settingJSONKeyType: spec.ThemeSystem,
+			settingJSONKeyName: spec.ThemeNameSystem,
+		},
+		settingKeyAuthKeys: map[string]any{},
+	}
+	store, cleanup := integrationTestStore(t, defaultMap)
+	defer cleanup()
+
+	ctx := t.Context()
+	var applied []spec.NetworkSettings
+	store.SetNetworkSettingsApplier(func(_ context.Context, cfg spec.NetworkSettings) error {
+		applied = append(applied, cfg)
+		return nil
+	})
+
+	set := func(cfg spec.NetworkSettings) error {
+		_, err := store.SetNetworkSettings(ctx, &spec.SetNetworkSettingsRequest{
+			Body: &spec.SetNetworkSettingsRequestBody{NetworkSettings: cfg},
+		})
+		return err
+	}
+
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	for name, cfg := range map[string]spec.NetworkSettings{
+		"no scheme":       {HTTPSProxy: "proxy.local:8080"},
+		"socks as http":   {SOCKSProxy: "http://proxy.local:1080"},
+		"relative ca":     {CABundlePath: "ca.pem"},
+		"missing ca":      {CABundlePath: filepath.Join(t.TempDir(), "missing.pem")},
+		"ca without pem":  {CABundlePath: notPEM},
+		"empty provider":  {InsecureSkipVerifyProviders: []string{" "}},
+		"unsupported ftp": {HTTPProxy: "ftp://proxy.local"},
+	} {
+		if err := set(cfg); !errors.Is(err, spec.ErrInvalidNetwork) {
+			t.Fatalf("%s: err = %v", name, err)
+		}
+	}
+	if len(applied) != 0 {
+		t.Fatalf("applier called for invalid settings: %+v", applied)
+	}
+
+	if err := set(spec.NetworkSettings{
+		HTTPSProxy:                  " http://proxy.local:3128 ",
+		SOCKSProxy:                  "socks5://proxy.local:1080",
+		NoProxy:                     "localhost,127.0.0.1",
+		InsecureSkipVerifyProviders: []string{"zeta", "alpha", "zeta"},
+	}); err != nil {
+		t.Fatalf("SetNetworkSettings failed: %v", err)
+	}
+	want := spec.NetworkSettings{
+		HTTPSProxy:                  "http://proxy.local:3128",
+		SOCKSProxy:                  "socks5://proxy.local:1080",
+		NoProxy:                     "localhost,127.0.0.1",
+		InsecureSkipVerifyProviders: []string{"alpha", "zeta"},
+	}
+	if len(applied) != 1 || !reflect.DeepEqual(applied[0], want) {
+		t.Fatalf("applied = %+v, want %+v", applied, want)
+	}
+
+	got, err := store.GetSettings(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetSettings failed: %v", err)
+	}
+	if !reflect.DeepEqual(got.Body.Network, want) {
+		t.Fatalf("persisted network = %+v, want %+v", got.Body.Network, want)
+	}
+
+	applied = nil
+	if err := store.ApplyCurrentNetworkSettings(ctx, true); err != nil {
+		t.Fatalf("ApplyCurrentNetworkSettings failed: %v", err)
+	}
+	if len(applied) != 1 || !reflect.DeepEqual(applied[0], want) {
+		t.Fatalf("reapplied = %+v", applied)
+	}
+}
+
+func TestSettingStore_BudgetSettings(t *testing.T) {
+	defaultMap := map[string]any{
+		settingKeySchemaVersion: spec.SchemaVersion,
+		settingKeyAppTheme: map[string]any{
+			settingJSONKeyType: spec.ThemeSystem,
+			settingJSONKeyName: spec.ThemeNameSystem,
+		},
+		settingKeyAuthKeys: map[string]any{},
+	}
+	store, cleanup := integrationTestStore(t, defaultMap)
+	defer cleanup()
+
+	ctx := t.Context()
+	set := func(providers map[string]spec.ProviderBudget) error {
+		_, err := store.SetBudgetSettings(ctx, &spec.SetBudgetSettingsRequest{
+			Body: &spec.SetBudgetSettingsRequestBody{BudgetSettings: spec.BudgetSettings{Providers: providers}},
+		})
+		return err
+	}
+
+	for name, providers := range map[string]map[string]spec.ProviderBudget{
+		"negative daily": {"openai": {DailyLimitUSD: -1}},
+		"nan monthly":    {"openai": {MonthlyLimitUSD: math.NaN()}},
+		"empty provider": {" ": {DailyLimitUSD: 1}},
+	} {
+		if err := set(providers); !errors.Is(err, spec.ErrInvalidBudget) {
+			t.Fatalf("%s: err = %v", name, err)
+		}
+	}
+
+	if err := set(map[string]spec.ProviderBudget{
+		" openai ":  {DailyLimitUSD: 2, MonthlyLimitUSD: 20},
+		"anthropic": {},
+	}); err != nil {
+		t.Fatalf("SetBudgetSettings failed: %v", err)
+	}
+	got, err := store.GetSettings(ctx, &spec.GetSettingsRequest{ForceFetch: true})
+	if err != nil {
+		t.Fatalf("GetSettings failed: %v", err)
+	}
+	want := spec.BudgetSettings{Providers: map[string]spec.ProviderBudget{
+		"openai": {DailyLimitUSD: 2, MonthlyLimitUSD: 20},
+	}}
+	if !reflect.DeepEqual(got.Body.Budget, want) {
+		t.Fatalf("persisted budget = %+v, want %+v", got.Body.Budget, want)
+	}
+
+	b, err := store.GetProviderBudget(ctx, "anthropic")
+	if err != nil || b != (spec.ProviderBudget{}) {
+		t.Fatalf("GetProviderBudget(anthropic) = %+v, %v", b, err)
+	}
+	if b, _ := store.GetProviderBudget(ctx, "openai"); b.MonthlyLimitUSD != 20 {
+		t.Fatalf("GetProviderBudget(openai) = %+v", b)
+	}
+
+	// The cached limits follow later updates.
+	if err := set(map[string]spec.ProviderBudget{"openai": {DailyLimitUSD: 5}}); err != nil {
+		t.Fatalf("SetBudgetSettings failed: %v", err)
+	}
+	if b, _ := store.GetProviderBudget(ctx, "openai"); b != (spec.ProviderBudget{DailyLimitUSD: 5}) {
+		t.Fatalf("GetProviderBudget(openai) after update = %+v", b)
+	}
+}
+
+func TestSettingStore_CompletionCacheSettings(t *testing.T) {
+	defaultMap := map[string]any{
+		settingKeySchemaVersion: spec.SchemaVersion,
+		settingKeyAppTheme: map[string]any{
+			settingJSONKeyType: spec.ThemeSystem,
+			settingJSONKeyName: spec.ThemeNameSystem,
+		},
+		settingKeyAuthKeys: map[string]any{},
+	}
+	store, cleanup := integrationTestStore(t, defaultMap)
+	defer cleanup()
+
+	ctx := t.Context()
+	set := func(cfg spec.CompletionCacheSettings) error {
+		_, err := store.SetCompletionCacheSettings(ctx, &spec.SetCompletionCacheSettingsRequest{
+			Body: &spec.SetCompletionCacheSettingsRequestBody{CompletionCacheSettings: cfg},
+		})
+		return err
+	}
+
+	for name, cfg := range map[string]spec.CompletionCacheSettings{
+		"negative size": {Enabled: true, MaxSizeMB: -1},
+		"huge size":     {Enabled: true, MaxSizeMB: maxCompletionCacheSizeMB + 1},
+		"negative ttl":  {Enabled: true, TTLMinutes: -5},
+	} {
+		if err := set(cfg); !errors.Is(err, spec.ErrInvalidCompletionCache) {
+			t.Fatalf("%s: err = %v", name, err)
+		}
+	}
+
+	got, err := store.GetCompletionCacheSettings(ctx)
+	if err != nil || got != (spec.CompletionCacheSettings{}) {
+		t.Fatalf("default completion cache = %+v, %v", got, err)
+	}
+
+	want := spec.CompletionCacheSettings{Enabled: true, MaxSizeMB: 64, TTLMinutes: 90}
+	if err := set(want); err != nil {
+		t.Fatalf("SetCompletionCacheSettings failed: %v", err)
+	}
+	resp, err := store.GetSettings(ctx, &spec.GetSettingsRequest{ForceFetch: true})
+	if err != nil {
+		t.Fatalf("GetSettings failed: %v", err)
+	}
+	if resp.Body.CompletionCache != want {
+		t.Fatalf("persisted completion cache = %+v, want %+v", resp.Body.CompletionCache, want)
+	}
+}
+
+func TestSettingStore_AuthKeyAuditLog(t *testing.T) {
+	defaultMap := map[string]any{
+		settingKeySchemaVersion: spec.SchemaVersion,
+		settingKeyAppTheme: map[string]any{
+			settingJSONKeyType: spec.ThemeSystem,
+			settingJSONKeyName: spec.ThemeNameSystem,
+		},
+		settingKeyAuthKeys: map[string]any{},
+	}
+	store, cleanup := integrationTestStore(t, defaultMap)
+	defer cleanup()
+
+	ctx := t.Context()
+	for _, name := range []spec.AuthKeyName{testAuthNameP1, testAuthNameP2} {
+		if _, err := store.SetAuthKey(ctx, &spec.SetAuthKeyRequest{
+			Type:    testAuthTypeProvider,
+			KeyName: name,
+			Body:    &spec.SetAuthKeyRequestBody{Secret: testSecretX},
+		}); err != nil {
+			t.Fatalf("SetAuthKey failed: %v", err)
+		}
+	}
+
+	read := func(ctx context.Context, name spec.AuthKeyName) {
+		t.Helper()
+		if _, err := store.GetAuthKey(ctx, &spec.GetAuthKeyRequest{
+			Type:    testAuthTypeProvider,
+			KeyName: name,
+		}); err != nil {
+			t.Fatalf("GetAuthKey failed: %v", err)
+		}
+	}
+	read(ctx, testAuthNameP1)
+	read(WithAuthKeyCaller(ctx, spec.AuthKeyCallerProviders), testAuthNameP2)
+	read(WithAuthKeyCaller(ctx, spec.AuthKeyCallerUI), testAuthNameP1)
+	if _, err := store.GetAuthKey(ctx, &spec.GetAuthKeyRequest{
+		Type:    testAuthTypeProvider,
+		KeyName: testAuthTypeMissing,
+	}); !errors.Is(err, spec.ErrAuthKeyNotFound) {
+		t.Fatalf("missing key err = %v", err)
+	}
+
+	out, err := store.GetAuthKeyAuditLog(ctx, &spec.GetAuthKeyAuditLogRequest{})
+	if err != nil {
+		t.Fatalf("GetAuthKeyAuditLog failed: %v", err)
+	}
+	gotCallers := make([]spec.AuthKeyCaller, 0, len(out.Body.Entries))
+	for _, e := range out.Body.Entries {
+		gotCallers = append(gotCallers, e.Caller)
+	}
+	wantCallers := []spec.AuthKeyCaller{
+		spec.AuthKeyCallerUI,
+		spec.AuthKeyCallerProviders,
+		spec.AuthKeyCallerUnknown,
+	}
+	if !reflect.DeepEqual(gotCallers, wantCallers) {
+		t.Fatalf("callers = %v, want %v", gotCallers, wantCallers)
+	}
+
+	out, err = store.GetAuthKeyAuditLog(ctx, &spec.GetAuthKeyAuditLogRequest{KeyName: testAuthNameP2})
+	if err != nil {
+		t.Fatalf("GetAuthKeyAuditLog filtered failed: %v", err)
+	}
+	if len(out.Body.Entries) != 1 || out.Body.Entries[0].KeyName != testAuthNameP2 {
+		t.Fatalf("filtered entries = %+v", out.Body.Entries)
+	}
+
+	for range authKeyAuditLogSize {
+		read(ctx, testAuthNameP1)
+	}
+	out, err = store.GetAuthKeyAuditLog(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetAuthKeyAuditLog failed: %v", err)
+	}
+	if len(out.Body.Entries) != authKeyAuditLogSize {
+		t.Fatalf("log size = %d, want %d", len(out.Body.Entries), authKeyAuditLogSize)
+	}
+	for _, e := range out.Body.Entries {
+		if e.Caller != spec.AuthKeyCallerUnknown {
+			t.Fatalf("old entry kept after overflow: %+v", e)
+		}
+	}
+}
+
+func TestSettingStore_AuthKeyDryRun(t *testing.T) {
+	defaultMap := map[string]any{
+		settingKeySchemaVersion: spec.SchemaVersion,
+		settingKeyAppTheme: map[string]any{
+			settingJSONKeyType: spec.ThemeSystem,
+			settingJSONKeyName: spec.ThemeNameSystem,
+		},
+		settingKeyAuthKeys: map[string]any{},
+	}
+	store, cleanup := integrationTestStore(t, defaultMap)
+	defer cleanup()
+
+	ctx := t.Context()
+	set, err := store.SetAuthKey(ctx, &spec.SetAuthKeyRequest{
+		Type:    testAuthTypeProvider,
+		KeyName: testAuthNameP1,
+		DryRun:  true,
+		Body:    &spec.SetAuthKeyRequestBody{Secret: testSecretX},
+	})
+	if err != nil {
+		t.Fatalf("SetAuthKey dry run failed: %v", err)
+	}
+	if set.Body == nil || !set.Body.AuthKey.NonEmpty || set.Body.AuthKey.SHA256 == "" {
+		t.Fatalf("dry-run set body = %+v", set.Body)
+	}
+	if _, err := store.GetAuthKey(ctx, &spec.GetAuthKeyRequest{
+		Type:    testAuthTypeProvider,
+		KeyName: testAuthNameP1,
+	}); !errors.Is(err, spec.ErrAuthKeyNotFound) {
+		t.Fatalf("dry-run set stored the key: err = %v", err)
+	}
+
+	del, err := store.DeleteAuthKey(ctx, &spec.DeleteAuthKeyRequest{
+		Type:    testAuthTypeProvider,
+		KeyName: testAuthNameP1,
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatalf("DeleteAuthKey dry run on missing key failed: %v", err)
+	}
+	if del.Body == nil || del.Body.AuthKey != nil {
+		t.Fatalf("dry-run delete of missing key body = %+v", del.Body)
+	}
+
+	if _, err := store.SetAuthKey(ctx, &spec.SetAuthKeyRequest{
+		Type:    testAuthTypeProvider,
+		KeyName: testAuthNameP1,
+		Body:    &spec.SetAuthKeyRequestBody{Secret: testSecretX},
+	}); err != nil {
+		t.Fatalf("SetAuthKey failed: %v", err)
+	}
+	del, err = store.DeleteAuthKey(ctx, &spec.DeleteAuthKeyRequest{
+		Type:    testAuthTypeProvider,
+		KeyName: testAuthNameP1,
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatalf("DeleteAuthKey dry run failed: %v", err)
+	}
+	if del.Body == nil || del.Body.AuthKey == nil || del.Body.AuthKey.SHA256 != set.Body.AuthKey.SHA256 {
+		t.Fatalf("dry-run delete body = %+v", del.Body)
+	}
+	if _, err := store.GetAuthKey(ctx, &spec.GetAuthKeyRequest{
+		Type:    testAuthTypeProvider,
+		KeyName: testAuthNameP1,
+	}); err != nil {
+		t.Fatalf("dry-run delete removed the key: %v", err)
+	}
+}
+
+func TestSettingStore_Subscribe(t *testing.T) {
+	defaultMap := map[string]any{
+		settingKeySchemaVersion: spec.SchemaVersion,
+		settingKeyAppTheme: map[string]any{
+			settingJSONKeyType: spec.ThemeSystem,
+			settingJSONKeyName: spec.ThemeNameSystem,
+		},
+		settingKeyAuthKeys: map[string]any{},
+	}
+	store, cleanup := integrationTestStore(t, defaultMap)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	all := store.Subscribe(ctx, spec.SettingChangeFilter{})
+	keys := store.Subscribe(ctx, spec.SettingChangeFilter{
+		Kinds: []spec.SettingChangeKind{spec.SettingChangeAuthKeySet, spec.SettingChangeAuthKeyDeleted},
+	})
+
+	if _, err := store.SetAppTheme(ctx, &spec.SetAppThemeRequest{
+		Body: &spec.SetAppThemeRequestBody{Type: spec.ThemeDark, Name: spec.ThemeNameDark},
+	}); err != nil {
+		t.Fatalf("SetAppTheme failed: %v", err)
+	}
+	if _, err := store.SetAuthKey(ctx, &spec.SetAuthKeyRequest{
+		Type:    testAuthTypeCustom,
+		KeyName: testAuthNameK,
+		Body:    &spec.SetAuthKeyRequestBody{Secret: testSecretX},
+	}); err != nil {
+		t.Fatalf("SetAuthKey failed: %v", err)
+	}
+	if _, err := store.DeleteAuthKey(ctx, &spec.DeleteAuthKeyRequest{
+		Type:    testAuthTypeCustom,
+		KeyName: testAuthNameK,
+	}); err != nil {
+		t.Fatalf("DeleteAuthKey failed: %v", err)
+	}
+
+	next := func(ch <-chan spec.SettingChangeEvent) spec.SettingChangeEvent {
+		t.Helper()
+		select {
+		case ev := <-ch:
+			return ev
+		default:
+			t.Fatal("no event queued")
+			return spec.SettingChangeEvent{}
+		}
+	}
+	wantAll := []spec.SettingChangeKind{
+		spec.SettingChangeAppTheme,
+		spec.SettingChangeAuthKeySet,
+		spec.SettingChangeAuthKeyDeleted,
+	}
+	for _, want := range wantAll {
+		if ev := next(all); ev.Kind != want {
+			t.Fatalf("all subscriber got %q want %q", ev.Kind, want)
+		}
+	}
+	ev := next(keys)
+	if ev.Kind != spec.SettingChangeAuthKeySet || ev.AuthKeyType != testAuthTypeCustom ||
+		ev.AuthKeyName != testAuthNameK || ev.At.IsZero() {
+		t.Fatalf("filtered event = %+v", ev)
+	}
+	if ev := next(keys); ev.Kind != spec.SettingChangeAuthKeyDeleted {
+		t.Fatalf("filtered event = %+v", ev)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-keys:
+		if ok {
+			t.Fatal("unexpected event after cancel")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, ok := <-store.Subscribe(t.Context(), spec.SettingChangeFilter{}); ok {
+		t.Fatal("subscribe after close returned an open channel")
+	}
+}
+
+func TestSettingStore_WorkspaceTrust(t *testing.T) {
+	defaultMap := map[string]any{
+		settingKeySchemaVersion: spec.SchemaVersion,
+		settingKeyAppTheme: map[string]any{
+			settingJSONKeyType: spec.ThemeSystem,
+			settingJSONKeyName: spec.ThemeNameSystem,
+		},
+		settingKeyAuthKeys: map[string]any{},
+	}
+	store, cleanup := integrationTestStore(t, defaultMap)
+	defer cleanup()
+
+	ctx := t.Context()
+	root := t.TempDir()
+	inside := filepath.Join(root, "project", "notes.md")
+	outside := filepath.Join(t.TempDir(), "report.pdf")
+	managed := t.TempDir()
+
+	check := func(path string) spec.PathTrust {
+		t.Helper()
+		out, err := store.CheckPathTrust(ctx, &spec.CheckPathTrustRequest{Path: path})
+		if err != nil {
+			t.Fatalf("CheckPathTrust(%q): %v", path, err)
+		}
+		return *out.Body
+	}
+
+	if err := store.RequirePathTrust(ctx, inside); !errors.Is(err, spec.ErrPathNotTrusted) {
+		t.Fatalf("RequirePathTrust before trust err=%v", err)
+	}
+
+	if _, err := store.AddTrustedDirectory(ctx, &spec.AddTrustedDirectoryRequest{
+		Body: &spec.AddTrustedDirectoryRequestBody{Path: root},
+	}); err != nil {
+		t.Fatalf("AddTrustedDirectory: %v", err)
+	}
+	if pt := check(inside); !pt.Trusted || pt.Confirmed || pt.TrustedRoot == "" {
+		t.Fatalf("inside trust = %+v", pt)
+	}
+	if pt := check(outside); pt.Trusted {
+		t.Fatalf("outside trust = %+v", pt)
+	}
+	// A sibling sharing the root's name prefix is not inside it.
+	if pt := check(root + "-sibling"); pt.Trusted {
+		t.Fatalf("sibling trust = %+v", pt)
+	}
+
+	if _, err := store.AddTrustedDirectory(ctx, &spec.AddTrustedDirectoryRequest{
+		Body: &spec.AddTrustedDirectoryRequestBody{Path: filepath.Join(root, "missing")},
+	}); err == nil {
+		t.Fatalf("AddTrustedDirectory on missing dir succeeded")
+	}
+
+	if _, err := store.ConfirmPathTrust(ctx, &spec.ConfirmPathTrustRequest{
+		Body: &spec.ConfirmPathTrustRequestBody{Path: outside, Purpose: spec.TrustPurposeAttachment},
+	}); err != nil {
+		t.Fatalf("ConfirmPathTrust: %v", err)
+	}
+	if pt := check(outside); !pt.Trusted || !pt.Confirmed {
+		t.Fatalf("confirmed trust = %+v", pt)
+	}
+	if _, err := store.ConfirmPathTrust(ctx, &spec.ConfirmPathTrustRequest{
+		Body: &spec.ConfirmPathTrustRequestBody{Path: outside, Purpose: "other"},
+	}); !errors.Is(err, spec.ErrInvalidArgument) {
+		t.Fatalf("ConfirmPathTrust bad purpose err=%v", err)
+	}
+
+	list, err := store.ListTrustedDirectories(ctx, &spec.ListTrustedDirectoriesRequest{})
+	if err != nil {
+		t.Fatalf("ListTrustedDirectories: %v", err)
+	}
+	if len(list.Body.TrustedRoots) != 1 || len(list.Body.ConfirmedPaths) != 1 {
+		t.Fatalf("list = %+v", list.Body)
+	}
+	if list.Body.ConfirmedPaths[0].Purpose != spec.TrustPurposeAttachment {
+		t.Fatalf("confirmation purpose = %q", list.Body.ConfirmedPaths[0].Purpose)
+	}
+
+	if _, err := store.RevokePathTrust(ctx, &spec.RevokePathTrustRequest{Path: outside}); err != nil {
+		t.Fatalf("RevokePathTrust: %v", err)
+	}
+	if _, err := store.RemoveTrustedDirectory(ctx, &spec.RemoveTrustedDirectoryRequest{Path: root}); err != nil {
+		t.Fatalf("RemoveTrustedDirectory: %v", err)
+	}
+	if pt := check(inside); pt.Trusted {
+		t.Fatalf("inside trust after removal = %+v", pt)
+	}
+	if pt := check(outside); pt.Trusted {
+		t.Fatalf("outside trust after revoke = %+v", pt)
+	}
+
+	store.SetImplicitTrustedRoots(managed)
+	if err := store.RequirePathTrust(ctx, filepath.Join(managed, "skill")); err != nil {
+		t.Fatalf("implicit root not trusted: %v", err)
+	}
+}
+
 // integrationTestStore sets up a real mapstore.MapFileStore backed by a temp file.
 // Returns the SettingStore (with store and encEncrypt populated) and a cleanup func.
 func integrationTestStore(t *testing.T, defaultMap map[string]any) (store *SettingStore, cleanup func()) {
@@ -1,55 +1,50 @@
 package store
 
 import (
+	"crypto/x509"
 	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	"github.com/flexigpt/flexigpt-app/internal/validation"
 )
 
-// validateTheme checks a theme for correctness.
+// validateTheme checks a theme for correctness. Invalid fields are reported
+// as validation.Errors wrapped in spec.ErrInvalidTheme.
 func validateTheme(th *spec.AppTheme) error {
 	if th == nil {
 		return spec.ErrInvalidTheme
 	}
+	r := &validation.Report{}
 	switch th.Type {
-	case spec.ThemeSystem:
-		if th.Name != spec.ThemeNameSystem {
-			return fmt.Errorf(
-				"%w: type and name required. input - type %s, name %s",
-				spec.ErrInvalidTheme,
-				th.Type,
-				th.Name,
-			)
-		}
-		return nil
-	case spec.ThemeLight:
-		if th.Name != spec.ThemeNameLight {
-			return fmt.Errorf(
-				"%w: type and name required. input - type %s, name %s",
-				spec.ErrInvalidTheme,
-				th.Type,
-				th.Name,
-			)
+	case spec.ThemeSystem, spec.ThemeLight, spec.ThemeDark:
+		if want := builtInThemeNames[th.Type]; th.Name != want {
+			r.Addf("name", validation.CodeMismatch, "name must be %q for type %s, got %q", want, th.Type, th.Name)
 		}
-		return nil
-	case spec.ThemeDark:
-		if th.Name != spec.ThemeNameDark {
-			return fmt.Errorf(
-				"%w: type and name required. input - type %s, name %s",
-				spec.ErrInvalidTheme,
-				th.Type,
-				th.Name,
-			)
-		}
-		return nil
 	case spec.ThemeOther:
 		if th.Name == "" {
-			return fmt.Errorf("%w: name required", spec.ErrInvalidTheme)
+			r.Addf("name", validation.CodeRequired, "name required")
 		}
-		return nil
 	default:
-		return spec.ErrInvalidTheme
+		r.Addf("type", validation.CodeUnsupported, "unsupported type %q", th.Type)
+	}
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("%w: %w", spec.ErrInvalidTheme, err)
 	}
+	return nil
+}
+
+// builtInThemeNames is the only name allowed for each built-in theme type.
+var builtInThemeNames = map[spec.ThemeType]string{
+	spec.ThemeSystem: spec.ThemeNameSystem,
+	spec.ThemeLight:  spec.ThemeNameLight,
+	spec.ThemeDark:   spec.ThemeNameDark,
 }
 
 func normalizeDebugSettings(cfg spec.DebugSettings) (spec.DebugSettings, bool) {
@@ -75,13 +70,137 @@ func validateDebugSettings(cfg *spec.DebugSettings) error {
 		return spec.ErrInvalidDebugSettings
 	}
 
-	switch cfg.LogLevel {
+	if !isDebugLogLevel(cfg.LogLevel) {
+		return fmt.Errorf("%w: unsupported logLevel %q", spec.ErrInvalidDebugSettings, cfg.LogLevel)
+	}
+	switch cfg.LogFormat {
+	case "", spec.DebugLogFormatText, spec.DebugLogFormatJSON:
+	default:
+		return fmt.Errorf("%w: unsupported logFormat %q", spec.ErrInvalidDebugSettings, cfg.LogFormat)
+	}
+	for module, level := range cfg.ModuleLogLevels {
+		if !debugModuleNameRE.MatchString(module) {
+			return fmt.Errorf("%w: invalid log module %q", spec.ErrInvalidDebugSettings, module)
+		}
+		if !isDebugLogLevel(level) {
+			return fmt.Errorf("%w: unsupported logLevel %q for module %q", spec.ErrInvalidDebugSettings, level, module)
+		}
+	}
+	return nil
+}
+
+// debugModuleNameRE matches dotted module names like "skill.store".
+var debugModuleNameRE = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)*$`)
+
+func isDebugLogLevel(level spec.DebugLogLevel) bool {
+	switch level {
 	case spec.DebugLogLevelDebug,
 		spec.DebugLogLevelInfo,
 		spec.DebugLogLevelWarn,
 		spec.DebugLogLevelError:
-		return nil
-	default:
-		return fmt.Errorf("%w: unsupported logLevel %q", spec.ErrInvalidDebugSettings, cfg.LogLevel)
+		return true
+	}
+	return false
+}
+
+// normalizeNetworkSettings trims and validates network settings. Proxies must
+// be absolute URLs of a supported scheme and the CA bundle must be an
+// absolute path to a PEM file with at least one certificate.
+func normalizeNetworkSettings(cfg spec.NetworkSettings) (spec.NetworkSettings, error) {
+	out := spec.NetworkSettings{
+		HTTPProxy:    strings.TrimSpace(cfg.HTTPProxy),
+		HTTPSProxy:   strings.TrimSpace(cfg.HTTPSProxy),
+		SOCKSProxy:   strings.TrimSpace(cfg.SOCKSProxy),
+		NoProxy:      strings.TrimSpace(cfg.NoProxy),
+		CABundlePath: strings.TrimSpace(cfg.CABundlePath),
+	}
+	for _, proxy := range []struct {
+		name    string
+		value   string
+		schemes []string
+	}{
+		{"httpProxy", out.HTTPProxy, []string{"http", "https", "socks5", "socks5h"}},
+		{"httpsProxy", out.HTTPSProxy, []string{"http", "https", "socks5", "socks5h"}},
+		{"socksProxy", out.SOCKSProxy, []string{"socks5", "socks5h"}},
+	} {
+		if proxy.value == "" {
+			continue
+		}
+		u, err := url.Parse(proxy.value)
+		if err != nil || u.Host == "" || !slices.Contains(proxy.schemes, u.Scheme) {
+			return out, fmt.Errorf(
+				"%w: %s must be a URL with scheme %s",
+				spec.ErrInvalidNetwork,
+				proxy.name,
+				strings.Join(proxy.schemes, "/"),
+			)
+		}
+	}
+
+	if out.CABundlePath != "" {
+		if !filepath.IsAbs(out.CABundlePath) {
+			return out, fmt.Errorf("%w: caBundlePath must be absolute", spec.ErrInvalidNetwork)
+		}
+		pem, err := os.ReadFile(out.CABundlePath)
+		if err != nil {
+			return out, fmt.Errorf("%w: caBundlePath: %w", spec.ErrInvalidNetwork, err)
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			return out, fmt.Errorf("%w: caBundlePath has no PEM certificates", spec.ErrInvalidNetwork)
+		}
+	}
+
+	for _, name := range cfg.InsecureSkipVerifyProviders {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return out, fmt.Errorf("%w: empty provider name", spec.ErrInvalidNetwork)
+		}
+		if !slices.Contains(out.InsecureSkipVerifyProviders, name) {
+			out.InsecureSkipVerifyProviders = append(out.InsecureSkipVerifyProviders, name)
+		}
+	}
+	slices.Sort(out.InsecureSkipVerifyProviders)
+	return out, nil
+}
+
+// normalizeBudgetSettings trims provider names, drops providers without any
+// limit and rejects negative or non-finite limits.
+func normalizeBudgetSettings(cfg spec.BudgetSettings) (spec.BudgetSettings, error) {
+	out := spec.BudgetSettings{}
+	for name, b := range cfg.Providers {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return out, fmt.Errorf("%w: empty provider name", spec.ErrInvalidBudget)
+		}
+		for _, v := range []float64{b.DailyLimitUSD, b.MonthlyLimitUSD} {
+			if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+				return out, fmt.Errorf("%w: %s: limits must be finite numbers >= 0", spec.ErrInvalidBudget, name)
+			}
+		}
+		if b == (spec.ProviderBudget{}) {
+			continue
+		}
+		if out.Providers == nil {
+			out.Providers = map[string]spec.ProviderBudget{}
+		}
+		out.Providers[name] = b
+	}
+	return out, nil
+}
+
+const (
+	maxCompletionCacheSizeMB  = 10 * 1024
+	maxCompletionCacheTTLMins = 365 * 24 * 60
+)
+
+func normalizeCompletionCacheSettings(cfg spec.CompletionCacheSettings) (spec.CompletionCacheSettings, error) {
+	if cfg.MaxSizeMB < 0 || cfg.MaxSizeMB > maxCompletionCacheSizeMB {
+		return cfg, fmt.Errorf("%w: maxSizeMB must be between 0 and %d",
+			spec.ErrInvalidCompletionCache, maxCompletionCacheSizeMB)
+	}
+	if cfg.TTLMinutes < 0 || cfg.TTLMinutes > maxCompletionCacheTTLMins {
+		return cfg, fmt.Errorf("%w: ttlMinutes must be between 0 and %d",
+			spec.ErrInvalidCompletionCache, maxCompletionCacheTTLMins)
 	}
+	return cfg, nil
 }
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/setting/spec"
+)
+
+// settingEventBuffer is the number of undelivered events a subscriber may
+// lag behind before further events are dropped for it.
+const settingEventBuffer = 64
+
+// settingNotifier fans committed changes out to subscriber channels. Sends
+// never block writers.
+type settingNotifier struct {
+	mu          sync.Mutex
+	nextID      uint64
+	subscribers map[uint64]settingSubscriber
+	closed      bool
+	done        chan struct{}
+}
+
+type settingSubscriber struct {
+	kinds map[spec.SettingChangeKind]bool
+	ch    chan spec.SettingChangeEvent
+}
+
+// Subscribe returns a channel of settings changes matching filter. The
+// channel is closed when ctx is done or the store is closed. A subscriber
+// that falls behind loses events rather than stalling writers.
+func (s *SettingStore) Subscribe(
+	ctx context.Context,
+	filter spec.SettingChangeFilter,
+) <-chan spec.SettingChangeEvent {
+	ch := make(chan spec.SettingChangeEvent, settingEventBuffer)
+	n := &s.notifier
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		close(ch)
+		return ch
+	}
+	if n.subscribers == nil {
+		n.subscribers = map[uint64]settingSubscriber{}
+		n.done = make(chan struct{})
+	}
+	sub := settingSubscriber{ch: ch}
+	if len(filter.Kinds) > 0 {
+		sub.kinds = make(map[spec.SettingChangeKind]bool, len(filter.Kinds))
+		for _, k := range filter.Kinds {
+			sub.kinds[k] = true
+		}
+	}
+	n.nextID++
+	id := n.nextID
+	n.subscribers[id] = sub
+	done := n.done
+	n.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if sub, ok := n.subscribers[id]; ok {
+			delete(n.subscribers, id)
+			close(sub.ch)
+		}
+	}()
+	return ch
+}
+
+func (s *SettingStore) publish(ev spec.SettingChangeEvent) {
+	n := &s.notifier
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.subscribers) == 0 {
+		return
+	}
+	ev.At = time.Now().UTC()
+	for _, sub := range n.subscribers {
+		if sub.kinds != nil && !sub.kinds[ev.Kind] {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			logger.Warn("settings subscriber is behind, dropping event", "kind", ev.Kind)
+		}
+	}
+}
+
+// closeSubscribers closes every subscriber channel and refuses new ones.
+func (s *SettingStore) closeSubscribers() {
+	n := &s.notifier
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	n.closed = true
+	for id, sub := range n.subscribers {
+		delete(n.subscribers, id)
+		close(sub.ch)
+	}
+	if n.done != nil {
+		close(n.done)
+	}
+}
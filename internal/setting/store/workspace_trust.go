@@ -0,0 +1,273 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/setting/spec"
+	"github.com/flexigpt/mapstore-go/jsonencdec"
+)
+
+const (
+	settingKeyWorkspaceTrust = "workspaceTrust"
+	settingKeyTrustedRoots   = "trustedRoots"
+	settingKeyConfirmedPaths = "confirmedPaths"
+)
+
+// SetImplicitTrustedRoots registers app-managed directories, e.g. the skills
+// directory, that are trusted without appearing in settings.
+func (s *SettingStore) SetImplicitTrustedRoots(paths ...string) {
+	roots := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if n, err := normalizeTrustPath(p); err == nil {
+			roots = append(roots, n)
+		}
+	}
+	s.implicitTrustMu.Lock()
+	defer s.implicitTrustMu.Unlock()
+	s.implicitTrustedRoots = roots
+}
+
+// ListTrustedDirectories returns the trusted roots and per-path
+// confirmations, sorted by path.
+func (s *SettingStore) ListTrustedDirectories(
+	_ context.Context,
+	_ *spec.ListTrustedDirectoriesRequest,
+) (*spec.ListTrustedDirectoriesResponse, error) {
+	trust, err := s.workspaceTrust()
+	if err != nil {
+		return nil, err
+	}
+	body := &spec.ListTrustedDirectoriesResponseBody{
+		TrustedRoots:   make([]spec.TrustedDirectory, 0, len(trust.TrustedRoots)),
+		ConfirmedPaths: make([]spec.TrustConfirmation, 0, len(trust.ConfirmedPaths)),
+	}
+	for _, p := range slices.Sorted(maps.Keys(trust.TrustedRoots)) {
+		body.TrustedRoots = append(body.TrustedRoots, trust.TrustedRoots[p])
+	}
+	for _, p := range slices.Sorted(maps.Keys(trust.ConfirmedPaths)) {
+		body.ConfirmedPaths = append(body.ConfirmedPaths, trust.ConfirmedPaths[p])
+	}
+	return &spec.ListTrustedDirectoriesResponse{Body: body}, nil
+}
+
+// AddTrustedDirectory trusts an existing directory and everything below it.
+func (s *SettingStore) AddTrustedDirectory(
+	_ context.Context,
+	req *spec.AddTrustedDirectoryRequest,
+) (*spec.AddTrustedDirectoryResponse, error) {
+	if req == nil || req.Body == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+	p, err := normalizeTrustPath(req.Body.Path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%w: %q is not a directory", spec.ErrInvalidArgument, p)
+	}
+
+	val, err := jsonencdec.StructWithJSONTagsToMap(spec.TrustedDirectory{
+		Path:    p,
+		AddedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SetKey(
+		[]string{settingKeyWorkspaceTrust, settingKeyTrustedRoots, p}, val,
+	); err != nil {
+		return nil, err
+	}
+	logger.Info("trusted directory added", "path", p)
+	return &spec.AddTrustedDirectoryResponse{}, nil
+}
+
+// RemoveTrustedDirectory stops trusting a root. Removing an unknown root is a
+// no-op.
+func (s *SettingStore) RemoveTrustedDirectory(
+	_ context.Context,
+	req *spec.RemoveTrustedDirectoryRequest,
+) (*spec.RemoveTrustedDirectoryResponse, error) {
+	if req == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+	p, err := normalizeTrustPath(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.DeleteKey(
+		[]string{settingKeyWorkspaceTrust, settingKeyTrustedRoots, p},
+	); err != nil {
+		return nil, err
+	}
+	logger.Info("trusted directory removed", "path", p)
+	return &spec.RemoveTrustedDirectoryResponse{}, nil
+}
+
+// ConfirmPathTrust records the user's explicit confirmation for one path
+// outside the trusted directories.
+func (s *SettingStore) ConfirmPathTrust(
+	_ context.Context,
+	req *spec.ConfirmPathTrustRequest,
+) (*spec.ConfirmPathTrustResponse, error) {
+	if req == nil || req.Body == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+	switch req.Body.Purpose {
+	case spec.TrustPurposeAttachment, spec.TrustPurposeSkill:
+	default:
+		return nil, fmt.Errorf("%w: unknown trust purpose %q", spec.ErrInvalidArgument, req.Body.Purpose)
+	}
+	p, err := normalizeTrustPath(req.Body.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := jsonencdec.StructWithJSONTagsToMap(spec.TrustConfirmation{
+		Path:        p,
+		Purpose:     req.Body.Purpose,
+		ConfirmedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SetKey(
+		[]string{settingKeyWorkspaceTrust, settingKeyConfirmedPaths, p}, val,
+	); err != nil {
+		return nil, err
+	}
+	logger.Info("path trust confirmed", "path", p, "purpose", req.Body.Purpose)
+	return &spec.ConfirmPathTrustResponse{}, nil
+}
+
+// RevokePathTrust drops a recorded confirmation. Revoking an unknown path is a
+// no-op.
+func (s *SettingStore) RevokePathTrust(
+	_ context.Context,
+	req *spec.RevokePathTrustRequest,
+) (*spec.RevokePathTrustResponse, error) {
+	if req == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+	p, err := normalizeTrustPath(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.DeleteKey(
+		[]string{settingKeyWorkspaceTrust, settingKeyConfirmedPaths, p},
+	); err != nil {
+		return nil, err
+	}
+	logger.Info("path trust revoked", "path", p)
+	return &spec.RevokePathTrustResponse{}, nil
+}
+
+// CheckPathTrust reports whether path is under a trusted directory or has
+// been confirmed explicitly.
+func (s *SettingStore) CheckPathTrust(
+	_ context.Context,
+	req *spec.CheckPathTrustRequest,
+) (*spec.CheckPathTrustResponse, error) {
+	if req == nil {
+		return nil, spec.ErrInvalidArgument
+	}
+	pt, err := s.pathTrust(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &spec.CheckPathTrustResponse{Body: &pt}, nil
+}
+
+// RequirePathTrust returns ErrPathNotTrusted unless path is trusted.
+func (s *SettingStore) RequirePathTrust(_ context.Context, path string) error {
+	pt, err := s.pathTrust(path)
+	if err != nil {
+		return err
+	}
+	if !pt.Trusted {
+		return fmt.Errorf("%w: %s", spec.ErrPathNotTrusted, pt.Path)
+	}
+	return nil
+}
+
+func (s *SettingStore) pathTrust(path string) (spec.PathTrust, error) {
+	p, err := normalizeTrustPath(path)
+	if err != nil {
+		return spec.PathTrust{}, err
+	}
+	trust, err := s.workspaceTrust()
+	if err != nil {
+		return spec.PathTrust{}, err
+	}
+
+	out := spec.PathTrust{Path: p}
+	s.implicitTrustMu.RLock()
+	roots := slices.Clone(s.implicitTrustedRoots)
+	s.implicitTrustMu.RUnlock()
+	roots = append(roots, slices.Sorted(maps.Keys(trust.TrustedRoots))...)
+	for _, root := range roots {
+		if isWithinDir(root, p) {
+			out.Trusted = true
+			out.TrustedRoot = root
+			break
+		}
+	}
+	if _, ok := trust.ConfirmedPaths[p]; ok {
+		out.Trusted = true
+		out.Confirmed = true
+	}
+	return out, nil
+}
+
+func (s *SettingStore) workspaceTrust() (spec.WorkspaceTrust, error) {
+	raw, err := s.store.GetAll(false)
+	if err != nil {
+		return spec.WorkspaceTrust{}, err
+	}
+	var schema spec.SettingsSchema
+	if err := jsonencdec.MapToStructWithJSONTags(raw, &schema); err != nil {
+		return spec.WorkspaceTrust{}, err
+	}
+	return schema.WorkspaceTrust, nil
+}
+
+// normalizeTrustPath makes path absolute and clean, resolving symlinks when
+// the path exists so a link cannot smuggle in an untrusted target.
+func normalizeTrustPath(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", fmt.Errorf("%w: path is empty", spec.ErrInvalidArgument)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return abs, nil
+		}
+		return "", err
+	}
+	return resolved, nil
+}
+
+func isWithinDir(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
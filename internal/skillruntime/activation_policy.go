@@ -0,0 +1,85 @@
+package skillruntime
+
+import (
+	"context"
+	"errors"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// applyBundleActivationPolicies picks the active refs of a new session whose
+// caller named none: the allowed skills of always-active bundles. Bundles
+// marked never, like on-demand ones, contribute nothing. Workspace refs are
+// never pre-activated. Callers that name active refs get exactly those.
+func (s *SkillRuntime) applyBundleActivationPolicies(
+	ctx context.Context,
+	allow []spec.SkillRef,
+) []spec.SkillRef {
+	policies, err := s.bundleActivationPolicies(ctx)
+	if err != nil {
+		logger.Warn("skill bundle activation policies unavailable", "error", err)
+		return nil
+	}
+	var active []spec.SkillRef
+	seen := map[string]struct{}{}
+	for _, ref := range allow {
+		installed, ok := installedSkillRef(ref)
+		if !ok || policies[installed.BundleID] != skillstoreSpec.SkillBundleActivationAlwaysActive {
+			continue
+		}
+		key := refKey(ref)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		active = append(active, ref)
+	}
+	return active
+}
+
+// bundleActivationPolicies returns the non-default policies of all installed
+// bundles.
+func (s *SkillRuntime) bundleActivationPolicies(
+	ctx context.Context,
+) (map[bundleitemutils.BundleID]skillstoreSpec.SkillBundleActivationPolicy, error) {
+	policies := map[bundleitemutils.BundleID]skillstoreSpec.SkillBundleActivationPolicy{}
+	token := ""
+	for {
+		response, err := s.store.ListSkillBundles(ctx, &skillstoreSpec.ListSkillBundlesRequest{
+			IncludeDisabled: true,
+			PageSize:        256,
+			PageToken:       token,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if response == nil || response.Body == nil {
+			return nil, errors.New("Skill Store returned an empty bundle list response")
+		}
+		for _, bundle := range response.Body.SkillBundles {
+			switch bundle.ActivationPolicy {
+			case skillstoreSpec.SkillBundleActivationAlwaysActive, skillstoreSpec.SkillBundleActivationNever:
+				policies[bundle.ID] = bundle.ActivationPolicy
+			}
+		}
+		if response.Body.NextPageToken == nil || *response.Body.NextPageToken == "" {
+			return policies, nil
+		}
+		token = *response.Body.NextPageToken
+	}
+}
+
+// FilterInstalledSkillRefs returns refs without the installed skills keep
+// rejects. Workspace refs are kept.
+func FilterInstalledSkillRefs(refs []spec.SkillRef, keep func(skillstoreSpec.SkillRef) bool) []spec.SkillRef {
+	out := make([]spec.SkillRef, 0, len(refs))
+	for _, ref := range refs {
+		if installed, ok := installedSkillRef(ref); ok && !keep(installed) {
+			continue
+		}
+		out = append(out, ref)
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package skillruntime
+
+import (
+	"testing"
+
+	"github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+func TestCreateSkillSession_PoliciesOnlyPickDefaultActiveRefs(t *testing.T) {
+	s := newTestStore(t)
+	installSkill(t, s, "notes")
+	rt, err := NewSkillRuntime(s)
+	if err != nil {
+		t.Fatalf("NewSkillRuntime: %v", err)
+	}
+	skill, err := s.GetSkill(t.Context(), &skillstoreSpec.GetSkillRequest{BundleID: "b1", SkillSlug: "notes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := []spec.SkillRef{{BundleID: "b1", SkillSlug: "notes", SkillID: skill.Body.ID}}
+	setPolicy := func(policy skillstoreSpec.SkillBundleActivationPolicy) {
+		t.Helper()
+		req := &skillstoreSpec.SetSkillBundleActivationPolicyRequest{
+			BundleID: "b1",
+			Body:     &skillstoreSpec.SetSkillBundleActivationPolicyRequestBody{ActivationPolicy: policy},
+		}
+		if _, err := s.SetSkillBundleActivationPolicy(t.Context(), req); err != nil {
+			t.Fatalf("SetSkillBundleActivationPolicy: %v", err)
+		}
+	}
+	activeCount := func(active []spec.SkillRef) int {
+		t.Helper()
+		resp, err := rt.CreateSkillSession(t.Context(), &spec.CreateSkillSessionRequest{
+			Body: &spec.CreateSkillSessionRequestBody{AllowSkillRefs: refs, ActiveSkillRefs: active},
+		})
+		if err != nil {
+			t.Fatalf("CreateSkillSession: %v", err)
+		}
+		return len(resp.Body.ActiveSkillRefs)
+	}
+
+	setPolicy(skillstoreSpec.SkillBundleActivationAlwaysActive)
+	if got := activeCount(nil); got != 1 {
+		t.Fatalf("always-active default = %d active, want 1", got)
+	}
+	setPolicy(skillstoreSpec.SkillBundleActivationNever)
+	if got := activeCount(nil); got != 0 {
+		t.Fatalf("never default = %d active, want 0", got)
+	}
+	if got := activeCount(refs); got != 1 {
+		t.Fatalf("explicit ref under never = %d active, want 1", got)
+	}
+}
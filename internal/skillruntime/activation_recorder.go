@@ -0,0 +1,97 @@
+package skillruntime
+
+import (
+	"context"
+	"time"
+
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// Activations are written to the store at most this often, so that creating
+// and updating sessions does not rewrite the skill store each time.
+const skillActivationFlushDelay = 5 * time.Second
+
+// queueSkillActivations counts one activation of each ref, and a session for
+// each ref in newInSession, towards the next flush.
+func (s *SkillRuntime) queueSkillActivations(refs, newInSession []skillstoreSpec.SkillRef) {
+	now := time.Now().UTC()
+	s.activationMu.Lock()
+	defer s.activationMu.Unlock()
+	if s.activationPending == nil {
+		s.activationPending = map[skillstoreSpec.SkillRef]skillstoreSpec.SkillUsage{}
+	}
+	counted := map[skillstoreSpec.SkillRef]bool{}
+	for _, ref := range refs {
+		key := activationKey(ref)
+		if counted[key] {
+			continue
+		}
+		counted[key] = true
+		usage := s.activationPending[key]
+		usage.ActivationCount++
+		usage.LastActivatedAt = &now
+		s.activationPending[key] = usage
+	}
+	for _, ref := range newInSession {
+		key := activationKey(ref)
+		usage := s.activationPending[key]
+		usage.SessionCount++
+		s.activationPending[key] = usage
+	}
+	if s.activationTimer == nil {
+		s.activationTimer = time.AfterFunc(skillActivationFlushDelay, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), runtimeResyncTimeout)
+			defer cancel()
+			if err := s.FlushSkillActivations(ctx); err != nil {
+				logger.Warn("record skill activations failed", "error", err)
+			}
+		})
+	}
+}
+
+// FlushSkillActivations writes the activations collected since the last flush
+// to the store in one write. Owners call it before closing the store.
+// Activations that fail to write are dropped.
+func (s *SkillRuntime) FlushSkillActivations(ctx context.Context) error {
+	s.activationFlushMu.Lock()
+	defer s.activationFlushMu.Unlock()
+
+	s.activationMu.Lock()
+	pending := s.activationPending
+	s.activationPending = nil
+	if s.activationTimer != nil {
+		s.activationTimer.Stop()
+		s.activationTimer = nil
+	}
+	s.activationFlushing = pending
+	s.activationMu.Unlock()
+
+	defer func() {
+		s.activationMu.Lock()
+		s.activationFlushing = nil
+		s.activationMu.Unlock()
+	}()
+	if len(pending) == 0 {
+		return nil
+	}
+	return s.store.RecordSkillUsage(ctx, pending)
+}
+
+// unflushedLastActivation returns the last activation of ref that has not
+// reached the store yet, or the zero time.
+func (s *SkillRuntime) unflushedLastActivation(ref skillstoreSpec.SkillRef) time.Time {
+	key := activationKey(ref)
+	s.activationMu.Lock()
+	defer s.activationMu.Unlock()
+	var last time.Time
+	for _, usage := range []skillstoreSpec.SkillUsage{s.activationPending[key], s.activationFlushing[key]} {
+		if usage.LastActivatedAt != nil && usage.LastActivatedAt.After(last) {
+			last = *usage.LastActivatedAt
+		}
+	}
+	return last
+}
+
+func activationKey(ref skillstoreSpec.SkillRef) skillstoreSpec.SkillRef {
+	return skillstoreSpec.SkillRef{BundleID: ref.BundleID, SkillSlug: ref.SkillSlug}
+}
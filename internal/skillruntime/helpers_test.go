@@ -0,0 +1,57 @@
+package skillruntime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flexigpt/flexigpt-app/internal/skillstore"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+func newTestStore(t *testing.T) *skillstore.SkillStore {
+	t.Helper()
+	s, err := skillstore.NewSkillStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewSkillStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if _, err := s.PutSkillBundle(t.Context(), &skillstoreSpec.PutSkillBundleRequest{
+		BundleID: "b1",
+		Body: &skillstoreSpec.PutSkillBundleRequestBody{
+			Slug:        "b1",
+			DisplayName: "B1",
+			IsEnabled:   true,
+		},
+	}); err != nil {
+		t.Fatalf("PutSkillBundle: %v", err)
+	}
+	return s
+}
+
+// installSkill writes a SKILL.md package named name and installs it as
+// b1/name. It returns the package directory.
+func installSkill(t *testing.T, s *skillstore.SkillStore, name string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	doc := "---\nname: " + name + "\ndescription: " + name + " skill\n---\n\n# " + name + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PutSkill(t.Context(), &skillstoreSpec.PutSkillRequest{
+		BundleID:  "b1",
+		SkillSlug: skillstoreSpec.SkillSlug(name),
+		Body: &skillstoreSpec.PutSkillRequestBody{
+			SkillType: skillstoreSpec.SkillTypeFS,
+			Location:  dir,
+			Name:      name,
+			IsEnabled: true,
+		},
+	}); err != nil {
+		t.Fatalf("PutSkill %s: %v", name, err)
+	}
+	return dir
+}
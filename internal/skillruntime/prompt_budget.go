@@ -0,0 +1,111 @@
+package skillruntime
+
+import (
+	"context"
+
+	"github.com/flexigpt/agentskills-go"
+	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
+	"github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
+)
+
+// promptBytesPerToken is the rough ratio used to turn a token budget into
+// bytes and to estimate the tokens of a prompt.
+const promptBytesPerToken = 4
+
+// skillsPromptBudget returns the tighter of the filter's bounds in bytes, or 0
+// when it has none.
+func skillsPromptBudget(f *spec.RuntimeSkillFilter) int {
+	budget := f.MaxBytes
+	if f.MaxTokens > 0 {
+		if b := f.MaxTokens * promptBytesPerToken; budget == 0 || b < budget {
+			budget = b
+		}
+	}
+	return budget
+}
+
+// fitSkillsPrompt keeps every active skill and as many inactive skills as fit
+// in budget bytes. Inactive skills are kept in sortSkillDefs order, by type,
+// name and location, so the last of that order are omitted first; how
+// recently a skill was used does not matter. The caller has already found
+// that the prompt with all of them does not fit.
+func (s *SkillRuntime) fitSkillsPrompt(
+	ctx context.Context,
+	filter *agentskills.SkillFilter,
+	budget int,
+) (*spec.GetSkillsPromptResponseBody, error) {
+	list := func(activity agentskillsSpec.SkillActivity) ([]agentskillsSpec.SkillRecord, error) {
+		return s.runtime.ListSkills(ctx, &agentskills.SkillListFilter{
+			Types:          filter.Types,
+			LocationPrefix: filter.LocationPrefix,
+			AllowSkills:    filter.AllowSkills,
+			SessionID:      filter.SessionID,
+			Activity:       activity,
+		})
+	}
+	var active []agentskillsSpec.SkillDef
+	if filter.SessionID != "" && filter.Activity != agentskillsSpec.SkillActivityInactive {
+		records, err := list(agentskillsSpec.SkillActivityActive)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			active = append(active, record.Def)
+		}
+	}
+	records, err := list(agentskillsSpec.SkillActivityInactive)
+	if err != nil {
+		return nil, err
+	}
+	inactive := make([]agentskillsSpec.SkillDef, 0, len(records))
+	for _, record := range records {
+		inactive = append(inactive, record.Def)
+	}
+	sortSkillDefs(inactive)
+
+	render := func(keep int) (string, error) {
+		value := *filter
+		if keep == 0 {
+			// An empty allowlist means all skills, so ask for the active
+			// section alone instead.
+			if len(active) == 0 {
+				return "", nil
+			}
+			value.Activity = agentskillsSpec.SkillActivityActive
+			return s.runtime.SkillsPrompt(ctx, &value)
+		}
+		value.AllowSkills = append(append([]agentskillsSpec.SkillDef(nil), active...), inactive[:keep]...)
+		return s.runtime.SkillsPrompt(ctx, &value)
+	}
+
+	best, err := render(0)
+	if err != nil {
+		return nil, err
+	}
+	if len(best) > budget {
+		return &spec.GetSkillsPromptResponseBody{
+			Prompt:        best,
+			OmittedSkills: len(inactive),
+			OverBudget:    true,
+		}, nil
+	}
+	// render(lo) fits and render(hi) does not.
+	lo, hi := 0, len(inactive)
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		prompt, err := render(mid)
+		if err != nil {
+			return nil, err
+		}
+		if len(prompt) <= budget {
+			lo, best = mid, prompt
+		} else {
+			hi = mid
+		}
+	}
+	return &spec.GetSkillsPromptResponseBody{Prompt: best, OmittedSkills: len(inactive) - lo}, nil
+}
+
+func estimatePromptTokens(prompt string) int {
+	return (len(prompt) + promptBytesPerToken - 1) / promptBytesPerToken
+}
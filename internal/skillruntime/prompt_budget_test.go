@@ -0,0 +1,130 @@
+package skillruntime
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+func TestGetSkillsPrompt_Budget(t *testing.T) {
+	s := newTestStore(t)
+	names := []string{"alpha", "bravo", "charlie"}
+	for _, name := range names {
+		installSkill(t, s, name)
+	}
+	rt, err := NewSkillRuntime(s)
+	if err != nil {
+		t.Fatalf("NewSkillRuntime: %v", err)
+	}
+	refs := map[string]spec.SkillRef{}
+	for _, name := range names {
+		skill, err := s.GetSkill(t.Context(), &skillstoreSpec.GetSkillRequest{
+			BundleID:  "b1",
+			SkillSlug: skillstoreSpec.SkillSlug(name),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs[name] = spec.SkillRef{BundleID: "b1", SkillSlug: skillstoreSpec.SkillSlug(name), SkillID: skill.Body.ID}
+	}
+	prompt := func(filter *spec.RuntimeSkillFilter) *spec.GetSkillsPromptResponseBody {
+		t.Helper()
+		resp, err := rt.GetSkillsPrompt(t.Context(), &spec.GetSkillsPromptRequest{
+			Body: &spec.GetSkillsPromptRequestBody{Filter: filter},
+		})
+		if err != nil {
+			t.Fatalf("GetSkillsPrompt: %v", err)
+		}
+		return resp.Body
+	}
+	all := []spec.SkillRef{refs["alpha"], refs["bravo"], refs["charlie"]}
+	full := prompt(&spec.RuntimeSkillFilter{AllowSkillRefs: all}).Prompt
+	firstTwo := prompt(&spec.RuntimeSkillFilter{
+		AllowSkillRefs: []spec.SkillRef{refs["alpha"], refs["bravo"]},
+	}).Prompt
+	for _, name := range names {
+		if !strings.Contains(full, name) {
+			t.Fatalf("full prompt lacks %s:\n%s", name, full)
+		}
+	}
+
+	session, err := rt.CreateSkillSession(t.Context(), &spec.CreateSkillSessionRequest{
+		Body: &spec.CreateSkillSessionRequestBody{
+			AllowSkillRefs:  all,
+			ActiveSkillRefs: []spec.SkillRef{refs["charlie"]},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateSkillSession: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		filter       spec.RuntimeSkillFilter
+		wantPrompt   string
+		wantOmitted  int
+		wantOver     bool
+		wantIncludes []string
+		wantExcludes []string
+	}{
+		{
+			name:         "fits",
+			filter:       spec.RuntimeSkillFilter{MaxBytes: len(full)},
+			wantPrompt:   full,
+			wantIncludes: names,
+		},
+		{
+			name:         "drops-last-inactive-skills-first",
+			filter:       spec.RuntimeSkillFilter{MaxBytes: len(firstTwo)},
+			wantPrompt:   firstTwo,
+			wantOmitted:  1,
+			wantIncludes: []string{"alpha", "bravo"},
+			wantExcludes: []string{"charlie"},
+		},
+		{
+			name:         "token-budget",
+			filter:       spec.RuntimeSkillFilter{MaxTokens: len(firstTwo) / promptBytesPerToken},
+			wantOmitted:  2,
+			wantExcludes: []string{"bravo", "charlie"},
+		},
+		{
+			name: "active-skill-over-budget",
+			filter: spec.RuntimeSkillFilter{
+				SessionID: session.Body.SessionID,
+				MaxBytes:  1,
+			},
+			wantOmitted:  2,
+			wantOver:     true,
+			wantIncludes: []string{"charlie"},
+			wantExcludes: []string{"alpha", "bravo"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.filter.AllowSkillRefs = all
+			got := prompt(&tc.filter)
+			if tc.wantPrompt != "" && got.Prompt != tc.wantPrompt {
+				t.Errorf("prompt = %q, want %q", got.Prompt, tc.wantPrompt)
+			}
+			if got.OmittedSkills != tc.wantOmitted || got.OverBudget != tc.wantOver {
+				t.Errorf("omitted = %d, over = %v, want %d, %v",
+					got.OmittedSkills, got.OverBudget, tc.wantOmitted, tc.wantOver)
+			}
+			if got.SizeBytes != len(got.Prompt) || got.EstimatedTokens != estimatePromptTokens(got.Prompt) {
+				t.Errorf("size = %d, tokens = %d for %d bytes", got.SizeBytes, got.EstimatedTokens, len(got.Prompt))
+			}
+			for _, name := range tc.wantIncludes {
+				if !strings.Contains(got.Prompt, name) {
+					t.Errorf("prompt lacks %s:\n%s", name, got.Prompt)
+				}
+			}
+			for _, name := range tc.wantExcludes {
+				if strings.Contains(got.Prompt, name) {
+					t.Errorf("prompt has omitted %s:\n%s", name, got.Prompt)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package skillruntime
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
+	"github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// orderActiveSkillDefs sorts defs in place. The session keeps this order for
+// its initial active skills, so it is the order of their prompt bodies.
+func (s *SkillRuntime) orderActiveSkillDefs(
+	ctx context.Context,
+	order spec.SkillPromptOrder,
+	activeRefs []spec.SkillRef,
+	resolved resolvedAllowSkillRefs,
+	defs []agentskillsSpec.SkillDef,
+) {
+	sortSkillDefs(defs)
+
+	switch order {
+	case spec.SkillPromptOrderPriority:
+		rank := map[agentskillsSpec.SkillDef]int{}
+		for idx, ref := range activeRefs {
+			definition, ok := resolved.RefToDef[refKey(ref)]
+			if !ok {
+				continue
+			}
+			if _, found := rank[definition]; !found {
+				rank[definition] = idx
+			}
+		}
+		sort.SliceStable(defs, func(left, right int) bool {
+			return rank[defs[left]] < rank[defs[right]]
+		})
+
+	case spec.SkillPromptOrderMRU:
+		installed := make([]skillstoreSpec.SkillRef, 0, len(activeRefs))
+		for _, ref := range activeRefs {
+			if value, ok := installedSkillRef(ref); ok {
+				installed = append(installed, value)
+			}
+		}
+		activations, err := s.store.GetSkillLastActivations(ctx, installed)
+		if err != nil {
+			logger.Warn("skill prompt order: last activations unavailable", "error", err)
+			return
+		}
+		lastActivated := map[agentskillsSpec.SkillDef]time.Time{}
+		for _, ref := range activeRefs {
+			value, ok := installedSkillRef(ref)
+			if !ok {
+				continue
+			}
+			definition, ok := resolved.RefToDef[refKey(ref)]
+			if !ok {
+				continue
+			}
+			at := activations[value]
+			if unflushed := s.unflushedLastActivation(value); unflushed.After(at) {
+				at = unflushed
+			}
+			if at.After(lastActivated[definition]) {
+				lastActivated[definition] = at
+			}
+		}
+		// Never-activated skills have a zero time and keep alphabetical order at the end.
+		sort.SliceStable(defs, func(left, right int) bool {
+			return lastActivated[defs[left]].After(lastActivated[defs[right]])
+		})
+
+	default:
+	}
+}
+
+// recordSkillActivations records the activation time and usage of installed
+// skills so that later sessions can order them most-recently-used first.
+func (s *SkillRuntime) recordSkillActivations(
+	sessionID agentskillsSpec.SessionID,
+	refs []spec.SkillRef,
+) {
+	installed := make([]skillstoreSpec.SkillRef, 0, len(refs))
+	for _, ref := range refs {
+		if value, ok := installedSkillRef(ref); ok {
+			installed = append(installed, value)
+		}
+	}
+	if len(installed) == 0 {
+		return
+	}
+	s.queueSkillActivations(installed, s.markSessionActivations(sessionID, installed))
+}
+
+func installedSkillRef(ref spec.SkillRef) (skillstoreSpec.SkillRef, bool) {
+	if ref.Identity != "" {
+		if !strings.HasPrefix(ref.Identity, installedIdentityPrefix) {
+			return skillstoreSpec.SkillRef{}, false
+		}
+		value, err := parseInstalledIdentity(ref.Identity)
+		return value, err == nil
+	}
+	if ref.BundleID == "" || ref.SkillSlug == "" {
+		return skillstoreSpec.SkillRef{}, false
+	}
+	return skillstoreSpec.SkillRef{
+		BundleID:  ref.BundleID,
+		SkillSlug: ref.SkillSlug,
+		SkillID:   ref.SkillID,
+	}, true
+}
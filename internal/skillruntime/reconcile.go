@@ -3,7 +3,6 @@ package skillruntime
 import (
 	"context"
 	"errors"
-	"log/slog"
 	"maps"
 	"sort"
 	"strings"
@@ -83,10 +82,11 @@ func (s *SkillRuntime) ResyncInstalled(ctx context.Context) error {
 	}
 	s.rtResyncMu.Lock()
 	defer s.rtResyncMu.Unlock()
-	view, err := s.installedDesiredView(ctx, true)
+	view, conflicts, err := s.installedDesiredView(ctx, true, nil)
 	if err != nil {
 		return err
 	}
+	s.store.SetSkillConflicts(conflicts)
 
 	return s.reconcilePartitionsLocked(
 		ctx,
@@ -96,6 +96,110 @@ func (s *SkillRuntime) ResyncInstalled(ctx context.Context) error {
 	)
 }
 
+// ResyncInstalledSkill strictly reindexes one installed skill, e.g. to
+// validate an edited SKILL.md. Other installed skills keep their runtime
+// state, so a broken unrelated skill cannot fail the call.
+func (s *SkillRuntime) ResyncInstalledSkill(ctx context.Context, ref skillstoreSpec.SkillRef) error {
+	if err := s.ensureConfigured(); err != nil {
+		return err
+	}
+	return s.resyncInstalledSkills(ctx, []skillstoreSpec.SkillRef{ref})[installedKeyForRef(ref)]
+}
+
+// installedSkillKey names an installed skill regardless of its ID.
+type installedSkillKey struct {
+	bundleID  bundleitemutils.BundleID
+	skillSlug skillstoreSpec.SkillSlug
+}
+
+func installedKeyForRef(ref skillstoreSpec.SkillRef) installedSkillKey {
+	return installedSkillKey{bundleID: ref.BundleID, skillSlug: ref.SkillSlug}
+}
+
+// installedSkillDef is the runtime definition listed for an enabled installed
+// skill, or the error building it.
+type installedSkillDef struct {
+	definition agentskillsSpec.SkillDef
+	err        error
+}
+
+// resyncInstalledSkills strictly reindexes the named installed skills from one
+// listing of the store and reports each skill's error separately. Definitions
+// that left the installed partition, like those of deleted, disabled or moved
+// skills, are removed best effort. Unnamed skills keep their runtime state.
+func (s *SkillRuntime) resyncInstalledSkills(
+	ctx context.Context,
+	refs []skillstoreSpec.SkillRef,
+) map[installedSkillKey]error {
+	errs := map[installedSkillKey]error{}
+	s.rtResyncMu.Lock()
+	defer s.rtResyncMu.Unlock()
+	listed := map[installedSkillKey]installedSkillDef{}
+	view, conflicts, err := s.installedDesiredView(ctx, false, listed)
+	if err != nil {
+		for _, ref := range refs {
+			errs[installedKeyForRef(ref)] = err
+		}
+		return errs
+	}
+	s.store.SetSkillConflicts(conflicts)
+
+	installed := cloneRuntimeDesiredView(s.managedInstalled)
+	var stale []agentskillsSpec.SkillDef
+	for definition := range installed.definitions {
+		if _, ok := view.definitions[definition]; !ok {
+			stale = append(stale, definition)
+			delete(installed.definitions, definition)
+		}
+	}
+	sortSkillDefs(stale)
+	touched := map[installedSkillKey]agentskillsSpec.SkillDef{}
+	for _, ref := range refs {
+		key := installedKeyForRef(ref)
+		entry, ok := listed[key]
+		if !ok {
+			// Gone or disabled; its old definition is stale.
+			continue
+		}
+		if entry.err != nil {
+			errs[key] = entry.err
+			continue
+		}
+		installed.definitions[entry.definition] = view.definitions[entry.definition]
+		touched[key] = entry.definition
+	}
+	desired := mergeDesiredPartitions(installed, s.managedWorkspaces)
+
+	managed := maps.Clone(s.managedRuntime)
+	apply := func(definitions []agentskillsSpec.SkillDef, mode runtimeApplyMode) error {
+		current := map[agentskillsSpec.SkillDef]string{}
+		target := newRuntimeDesiredView()
+		for _, definition := range definitions {
+			if version, ok := managed[definition]; ok {
+				current[definition] = version
+			}
+			if version, ok := desired.definitions[definition]; ok {
+				target.definitions[definition] = version
+			}
+			delete(managed, definition)
+		}
+		applied, err := s.runtimeApplyDesired(ctx, current, target, mode)
+		maps.Copy(managed, applied)
+		return err
+	}
+	if len(stale) > 0 {
+		_ = apply(stale, runtimeApplyBestEffort)
+	}
+	for key, definition := range touched {
+		if err := apply([]agentskillsSpec.SkillDef{definition}, runtimeApplyStrict); err != nil {
+			errs[key] = err
+		}
+	}
+	s.managedInstalled = installed
+	s.managedRuntime = managed
+	return errs
+}
+
 func (s *SkillRuntime) bestEffortInstalledResync(
 	ctx context.Context,
 	reason string,
@@ -105,13 +209,13 @@ func (s *SkillRuntime) bestEffortInstalledResync(
 	}
 	defer func() {
 		if recovered := recover(); recovered != nil {
-			slog.Error("skill runtime resync: panic", "reason", reason, "panic", recovered)
+			logger.Error("skill runtime resync: panic", "reason", reason, "panic", recovered)
 		}
 	}()
 	ctx, cancel := context.WithTimeout(ctx, runtimeResyncTimeout)
 	defer cancel()
 	if err := s.resyncInstalledBestEffort(ctx); err != nil {
-		slog.Error("skill runtime resync failed", "reason", reason, "err", err)
+		logger.Error("skill runtime resync failed", "reason", reason, "err", err)
 	}
 }
 
@@ -121,10 +225,11 @@ func (s *SkillRuntime) resyncInstalledBestEffort(ctx context.Context) error {
 	}
 	s.rtResyncMu.Lock()
 	defer s.rtResyncMu.Unlock()
-	view, err := s.installedDesiredView(ctx, true)
+	view, conflicts, err := s.installedDesiredView(ctx, true, nil)
 	if err != nil {
 		return err
 	}
+	s.store.SetSkillConflicts(conflicts)
 
 	return s.reconcilePartitionsLocked(
 		ctx,
@@ -134,10 +239,14 @@ func (s *SkillRuntime) resyncInstalledBestEffort(ctx context.Context) error {
 	)
 }
 
+// installedDesiredView also reports enabled skills that resolve to the same
+// runtime type and name. A non-nil listed map receives every enabled skill's
+// definition or definition error.
 func (s *SkillRuntime) installedDesiredView(
 	ctx context.Context,
 	logInvalid bool,
-) (runtimeDesiredView, error) {
+	listed map[installedSkillKey]installedSkillDef,
+) (runtimeDesiredView, []skillstoreSpec.SkillConflict, error) {
 	bundles := map[bundleitemutils.BundleID]skillstoreSpec.SkillBundle{}
 	bundleToken := ""
 	for {
@@ -147,10 +256,10 @@ func (s *SkillRuntime) installedDesiredView(
 			PageToken:       bundleToken,
 		})
 		if err != nil {
-			return runtimeDesiredView{}, err
+			return runtimeDesiredView{}, nil, err
 		}
 		if response == nil || response.Body == nil {
-			return runtimeDesiredView{}, errors.New("Skill Store returned an empty bundle list response")
+			return runtimeDesiredView{}, nil, errors.New("Skill Store returned an empty bundle list response")
 		}
 		for _, bundle := range response.Body.SkillBundles {
 			bundles[bundle.ID] = bundle
@@ -162,6 +271,7 @@ func (s *SkillRuntime) installedDesiredView(
 	}
 
 	view := newRuntimeDesiredView()
+	owners := map[runtimeSkillName][]skillstoreSpec.SkillRef{}
 	skillToken := ""
 	for {
 		response, err := s.store.ListSkills(ctx, &skillstoreSpec.ListSkillsRequest{
@@ -171,10 +281,10 @@ func (s *SkillRuntime) installedDesiredView(
 			PageToken:           skillToken,
 		})
 		if err != nil {
-			return runtimeDesiredView{}, err
+			return runtimeDesiredView{}, nil, err
 		}
 		if response == nil || response.Body == nil {
-			return runtimeDesiredView{}, errors.New("Skill Store returned an empty Skill list response")
+			return runtimeDesiredView{}, nil, errors.New("Skill Store returned an empty Skill list response")
 		}
 		for _, item := range response.Body.SkillListItems {
 			bundle, ok := bundles[item.BundleID]
@@ -182,9 +292,13 @@ func (s *SkillRuntime) installedDesiredView(
 				continue
 			}
 			definition, err := s.runtimeDefForStoreSkill(item.SkillDefinition)
+			if listed != nil {
+				key := installedSkillKey{bundleID: item.BundleID, skillSlug: item.SkillSlug}
+				listed[key] = installedSkillDef{definition: definition, err: err}
+			}
 			if err != nil {
 				if logInvalid {
-					slog.Error(
+					logger.Error(
 						"runtime desired Skill has invalid definition",
 						"bundleID",
 						item.BundleID,
@@ -196,6 +310,12 @@ func (s *SkillRuntime) installedDesiredView(
 				}
 				continue
 			}
+			key := runtimeSkillName{definition.Type, definition.Name}
+			owners[key] = append(owners[key], skillstoreSpec.SkillRef{
+				BundleID:  item.BundleID,
+				SkillSlug: item.SkillSlug,
+				SkillID:   item.SkillDefinition.ID,
+			})
 			view.add(
 				definition,
 				"installed:"+item.SkillDefinition.Digest+
@@ -207,7 +327,47 @@ func (s *SkillRuntime) installedDesiredView(
 		}
 		skillToken = *response.Body.NextPageToken
 	}
-	return view, nil
+	return view, runtimeSkillConflicts(owners), nil
+}
+
+type runtimeSkillName struct {
+	typ  string
+	name string
+}
+
+func runtimeSkillConflicts(
+	owners map[runtimeSkillName][]skillstoreSpec.SkillRef,
+) []skillstoreSpec.SkillConflict {
+	var conflicts []skillstoreSpec.SkillConflict
+	for key, refs := range owners {
+		if len(refs) < 2 {
+			continue
+		}
+		logger.Warn(
+			"enabled Skills share a runtime name",
+			"type", key.typ,
+			"name", key.name,
+			"skills", len(refs),
+		)
+		sort.Slice(refs, func(left, right int) bool {
+			if refs[left].BundleID != refs[right].BundleID {
+				return refs[left].BundleID < refs[right].BundleID
+			}
+			return refs[left].SkillSlug < refs[right].SkillSlug
+		})
+		conflicts = append(conflicts, skillstoreSpec.SkillConflict{
+			RuntimeType: key.typ,
+			RuntimeName: key.name,
+			Skills:      refs,
+		})
+	}
+	sort.Slice(conflicts, func(left, right int) bool {
+		if conflicts[left].RuntimeType != conflicts[right].RuntimeType {
+			return conflicts[left].RuntimeType < conflicts[right].RuntimeType
+		}
+		return conflicts[left].RuntimeName < conflicts[right].RuntimeName
+	})
+	return conflicts
 }
 
 func cloneWorkspaceDesiredViews(
@@ -282,15 +442,17 @@ func (s *SkillRuntime) runtimeApplyDesired(
 	sortSkillDefs(additions)
 
 	for _, definition := range additions {
-		if _, err := s.runtime.AddSkill(ctx, definition); err != nil {
+		record, err := s.runtime.AddSkill(ctx, definition)
+		if err != nil {
 			if errors.Is(err, agentskillsSpec.ErrSkillAlreadyExists) {
 				present[definition] = desired.definitions[definition]
 				continue
 			}
+			s.recordRuntimeFailure(definition, runtimeOpAdd, err)
 			if mode == runtimeApplyStrict {
 				return present, err
 			}
-			slog.Error(
+			logger.Error(
 				"skill runtime add failed",
 				"type",
 				definition.Type,
@@ -303,6 +465,7 @@ func (s *SkillRuntime) runtimeApplyDesired(
 			)
 			continue
 		}
+		s.recordRuntimeIndexed(definition, record)
 		present[definition] = desired.definitions[definition]
 	}
 
@@ -317,10 +480,11 @@ func (s *SkillRuntime) runtimeApplyDesired(
 	for _, definition := range reindexes {
 		if _, err := s.runtime.RemoveSkill(ctx, definition); err != nil &&
 			!errors.Is(err, agentskillsSpec.ErrSkillNotFound) {
+			s.recordRuntimeFailure(definition, runtimeOpRemove, err)
 			if mode == runtimeApplyStrict {
 				return present, err
 			}
-			slog.Error(
+			logger.Error(
 				"skill runtime reindex removal failed",
 				"type", definition.Type,
 				"name", definition.Name,
@@ -330,11 +494,14 @@ func (s *SkillRuntime) runtimeApplyDesired(
 			continue
 		}
 		delete(present, definition)
-		if _, err := s.runtime.AddSkill(ctx, definition); err != nil {
+		s.forgetRuntimeStatus(definition)
+		record, err := s.runtime.AddSkill(ctx, definition)
+		if err != nil {
+			s.recordRuntimeFailure(definition, runtimeOpAdd, err)
 			if mode == runtimeApplyStrict {
 				return present, err
 			}
-			slog.Error(
+			logger.Error(
 				"skill runtime reindex add failed",
 				"type", definition.Type,
 				"name", definition.Name,
@@ -343,6 +510,7 @@ func (s *SkillRuntime) runtimeApplyDesired(
 			)
 			continue
 		}
+		s.recordRuntimeIndexed(definition, record)
 		present[definition] = desired.definitions[definition]
 	}
 
@@ -357,12 +525,14 @@ func (s *SkillRuntime) runtimeApplyDesired(
 		if _, err := s.runtime.RemoveSkill(ctx, definition); err != nil {
 			if errors.Is(err, agentskillsSpec.ErrSkillNotFound) {
 				delete(present, definition)
+				s.forgetRuntimeStatus(definition)
 				continue
 			}
+			s.recordRuntimeFailure(definition, runtimeOpRemove, err)
 			if mode == runtimeApplyStrict {
 				return present, err
 			}
-			slog.Error(
+			logger.Error(
 				"skill runtime remove failed",
 				"type",
 				definition.Type,
@@ -376,6 +546,7 @@ func (s *SkillRuntime) runtimeApplyDesired(
 			continue
 		}
 		delete(present, definition)
+		s.forgetRuntimeStatus(definition)
 	}
 	return present, nil
 }
@@ -0,0 +1,107 @@
+package skillruntime
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+func TestResyncInstalledSkill_IgnoresUnrelatedBrokenSkill(t *testing.T) {
+	s := newTestStore(t)
+	installSkill(t, s, "good")
+	broken := installSkill(t, s, "broken")
+	if err := os.WriteFile(filepath.Join(broken, "SKILL.md"), []byte("not a skill"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rt, err := NewSkillRuntime(s)
+	if err != nil {
+		t.Fatalf("NewSkillRuntime: %v", err)
+	}
+	if err := rt.ResyncInstalled(t.Context()); err == nil {
+		t.Fatal("strict full resync accepted the broken skill")
+	}
+
+	s.SetReindexHandler(rt.ResyncInstalledSkill)
+	resp, err := s.UpdateSkillContent(t.Context(), &skillstoreSpec.UpdateSkillContentRequest{
+		BundleID:  "b1",
+		SkillSlug: "good",
+		Body: &skillstoreSpec.UpdateSkillContentRequestBody{
+			Description:  "edited",
+			MarkdownBody: "# good\n\nEDITED",
+		},
+	})
+	if err != nil {
+		t.Fatalf("UpdateSkillContent: %v", err)
+	}
+	if resp.Body.Skill.Description != "edited" {
+		t.Fatalf("unexpected skill: %+v", resp.Body.Skill)
+	}
+
+	definition, err := rt.runtimeDefForStoreSkill(resp.Body.Skill)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rt.managedRuntime[definition]; !ok {
+		t.Fatal("edited skill is not indexed")
+	}
+}
+
+func TestScheduleInstalledSkillResync_ReportsOnlyOwnErrors(t *testing.T) {
+	s := newTestStore(t)
+	installSkill(t, s, "good")
+	broken := installSkill(t, s, "broken")
+	if err := os.WriteFile(filepath.Join(broken, "SKILL.md"), []byte("not a skill"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rt, err := NewSkillRuntime(s)
+	if err != nil {
+		t.Fatalf("NewSkillRuntime: %v", err)
+	}
+
+	refs := []skillstoreSpec.SkillRef{
+		{BundleID: "b1", SkillSlug: "good"},
+		{BundleID: "b1", SkillSlug: "broken"},
+	}
+	errs := make([]error, len(refs))
+	var wg sync.WaitGroup
+	for i, ref := range refs {
+		wg.Go(func() { errs[i] = rt.ScheduleInstalledSkillResync(t.Context(), ref) })
+	}
+	wg.Wait()
+	if errs[0] != nil {
+		t.Fatalf("good skill got %v", errs[0])
+	}
+	if errs[1] == nil {
+		t.Fatal("broken skill resynced without error")
+	}
+
+	resp, err := s.GetSkill(t.Context(), &skillstoreSpec.GetSkillRequest{BundleID: "b1", SkillSlug: "good"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	definition, err := rt.runtimeDefForStoreSkill(*resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rt.managedRuntime[definition]; !ok {
+		t.Fatal("good skill is not indexed")
+	}
+
+	if _, err := s.DeleteSkill(t.Context(), &skillstoreSpec.DeleteSkillRequest{
+		BundleID:  "b1",
+		SkillSlug: "good",
+	}); err != nil {
+		t.Fatalf("DeleteSkill: %v", err)
+	}
+	// The broken skill is not dirty, so it cannot fail the removal.
+	if err := rt.ScheduleInstalledSkillResync(t.Context(), refs[0]); err != nil {
+		t.Fatalf("resync deleted skill: %v", err)
+	}
+	if _, ok := rt.managedRuntime[definition]; ok {
+		t.Fatal("deleted skill is still indexed")
+	}
+}
@@ -0,0 +1,139 @@
+package skillruntime
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"time"
+
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+const (
+	// Resync requests this close together share one reconcile.
+	installedResyncDebounce = 50 * time.Millisecond
+	// A steady stream of requests still reconciles at least this often.
+	installedResyncMaxDelay = 500 * time.Millisecond
+)
+
+// installedResyncBatch collects the requests that arrived since the last
+// reconcile started. Requests for the whole partition set full; the others
+// mark only their skills dirty. All of them wait for the one reconcile that
+// covers the batch.
+type installedResyncBatch struct {
+	requests int
+	full     bool
+	dirty    map[installedSkillKey]skillstoreSpec.SkillRef
+	started  time.Time
+	timer    *time.Timer
+	done     chan struct{}
+	err      error
+	skillErr map[installedSkillKey]error
+}
+
+// ScheduleInstalledResync resyncs the installed partition like
+// ResyncInstalled, but coalesces calls made within a short window into one
+// reconcile, so that a burst of store mutations such as a bulk import lists
+// and diffs the store once. It returns after a reconcile that started after
+// the call has finished, with that reconcile's error, or when ctx is done.
+func (s *SkillRuntime) ScheduleInstalledResync(ctx context.Context) error {
+	return s.scheduleInstalledResync(ctx, true, nil)
+}
+
+// ScheduleInstalledSkillResync is ScheduleInstalledResync for mutations that
+// touched only the given skills. The batch reindexes just the dirty skills,
+// and the call returns only the errors of its own skills.
+func (s *SkillRuntime) ScheduleInstalledSkillResync(
+	ctx context.Context,
+	refs ...skillstoreSpec.SkillRef,
+) error {
+	if len(refs) == 0 {
+		return s.ensureConfigured()
+	}
+	return s.scheduleInstalledResync(ctx, false, refs)
+}
+
+func (s *SkillRuntime) scheduleInstalledResync(
+	ctx context.Context,
+	full bool,
+	refs []skillstoreSpec.SkillRef,
+) error {
+	if err := s.ensureConfigured(); err != nil {
+		return err
+	}
+	s.resyncBatchMu.Lock()
+	batch := s.resyncBatch
+	if batch == nil {
+		batch = &installedResyncBatch{
+			dirty:   map[installedSkillKey]skillstoreSpec.SkillRef{},
+			started: time.Now(),
+			done:    make(chan struct{}),
+		}
+		batch.timer = time.AfterFunc(installedResyncDebounce, func() { s.flushInstalledResync(batch) })
+		s.resyncBatch = batch
+	} else if time.Since(batch.started)+installedResyncDebounce < installedResyncMaxDelay {
+		batch.timer.Reset(installedResyncDebounce)
+	}
+	batch.requests++
+	batch.full = batch.full || full
+	for _, ref := range refs {
+		batch.dirty[installedKeyForRef(ref)] = ref
+	}
+	s.resyncBatchMu.Unlock()
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if full {
+		return batch.err
+	}
+	var errs []error
+	seen := map[installedSkillKey]bool{}
+	for _, ref := range refs {
+		key := installedKeyForRef(ref)
+		if err := batch.skillErr[key]; err != nil && !seen[key] {
+			seen[key] = true
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flushInstalledResync runs the reconcile for batch. A timer reset racing the
+// first run fires again; that run finds the batch gone and returns.
+func (s *SkillRuntime) flushInstalledResync(batch *installedResyncBatch) {
+	s.resyncBatchMu.Lock()
+	if s.resyncBatch != batch {
+		s.resyncBatchMu.Unlock()
+		return
+	}
+	// Later requests start a new batch, whose reconcile sees their changes.
+	s.resyncBatch = nil
+	s.resyncBatchMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), runtimeResyncTimeout)
+	defer cancel()
+	if batch.full {
+		batch.err = s.ResyncInstalled(ctx)
+	}
+	// A successful full reconcile indexed every dirty skill too. After a
+	// failed one, the dirty skills are retried one by one so that each caller
+	// learns only about its own skills.
+	if len(batch.dirty) > 0 && (!batch.full || batch.err != nil) {
+		refs := slices.Collect(maps.Values(batch.dirty))
+		batch.skillErr = s.resyncInstalledSkills(ctx, refs)
+	}
+	if batch.requests > 1 {
+		logger.Debug(
+			"skill runtime resync coalesced",
+			"requests", batch.requests,
+			"full", batch.full,
+			"skills", len(batch.dirty),
+			"error", batch.err,
+		)
+	}
+	close(batch.done)
+}
@@ -29,6 +29,11 @@ func (s *SkillRuntime) CreateSkillSession(
 	if len(req.Body.AllowSkillRefs) == 0 {
 		return nil, fmt.Errorf("%w: allowSkillRefs required", errSkillInvalidRequest)
 	}
+	switch req.Body.PromptOrder {
+	case "", spec.SkillPromptOrderAlphabetical, spec.SkillPromptOrderPriority, spec.SkillPromptOrderMRU:
+	default:
+		return nil, fmt.Errorf("%w: unknown promptOrder %q", errSkillInvalidRequest, req.Body.PromptOrder)
+	}
 	for _, ref := range req.Body.AllowSkillRefs {
 		if err := validateSkillRef(ref); err != nil {
 			return nil, fmt.Errorf("%w: invalid allowSkillRef: %w", errSkillInvalidRequest, err)
@@ -41,9 +46,13 @@ func (s *SkillRuntime) CreateSkillSession(
 	}
 	if sessionID := strings.TrimSpace(string(req.Body.CloseSessionID)); sessionID != "" {
 		_ = s.runtime.CloseSession(ctx, agentskillsSpec.SessionID(sessionID))
+		s.untrackSession(agentskillsSpec.SessionID(sessionID))
 	}
 
 	activeRefs := normalizeActiveRefsSubsetOfAllow(req.Body.AllowSkillRefs, req.Body.ActiveSkillRefs)
+	if len(req.Body.ActiveSkillRefs) == 0 {
+		activeRefs = s.applyBundleActivationPolicies(ctx, req.Body.AllowSkillRefs)
+	}
 	resolved := s.resolveAllowSkillRefs(ctx, req.Body.AllowSkillRefs)
 	if len(resolved.AllowDefs) == 0 {
 		options := []agentskills.SessionOption{}
@@ -54,6 +63,7 @@ func (s *SkillRuntime) CreateSkillSession(
 		if err != nil {
 			return nil, err
 		}
+		s.trackSession(sessionID, req.Body.MaxActivePerSession, req.Body.AllowSkillRefs)
 		return &spec.CreateSkillSessionResponse{Body: &spec.CreateSkillSessionResponseBody{
 			SessionID:       sessionID,
 			ActiveSkillRefs: []spec.SkillRef{},
@@ -70,7 +80,7 @@ func (s *SkillRuntime) CreateSkillSession(
 	for definition := range activeDefinitions {
 		activeDefs = append(activeDefs, definition)
 	}
-	sortSkillDefs(activeDefs)
+	s.orderActiveSkillDefs(ctx, req.Body.PromptOrder, activeRefs, resolved, activeDefs)
 
 	if len(activeDefs) > 0 {
 		records, err := s.runtime.ListSkills(ctx, &agentskills.SkillListFilter{
@@ -111,6 +121,7 @@ func (s *SkillRuntime) CreateSkillSession(
 	if err != nil {
 		return nil, err
 	}
+	s.trackSession(sessionID, req.Body.MaxActivePerSession, req.Body.AllowSkillRefs)
 
 	records, err := s.runtime.ListSkills(ctx, &agentskills.SkillListFilter{
 		SessionID:   sessionID,
@@ -131,6 +142,7 @@ func (s *SkillRuntime) CreateSkillSession(
 	if output == nil {
 		output = []spec.SkillRef{}
 	}
+	s.recordSkillActivations(sessionID, output)
 	return &spec.CreateSkillSessionResponse{Body: &spec.CreateSkillSessionResponseBody{
 		SessionID:       sessionID,
 		ActiveSkillRefs: output,
@@ -150,6 +162,7 @@ func (s *SkillRuntime) CloseSkillSession(
 	if err := s.runtime.CloseSession(ctx, req.SessionID); err != nil {
 		return nil, err
 	}
+	s.untrackSession(req.SessionID)
 	return &spec.CloseSkillSessionResponse{}, nil
 }
 
@@ -160,9 +173,15 @@ func (s *SkillRuntime) GetSkillsPrompt(
 	if err := s.ensureConfigured(); err != nil {
 		return nil, fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
 	}
-	var filter *agentskills.SkillFilter
+	var (
+		filter *agentskills.SkillFilter
+		budget int
+	)
 	if req != nil && req.Body != nil && req.Body.Filter != nil {
 		value := req.Body.Filter
+		if value.MaxBytes < 0 || value.MaxTokens < 0 {
+			return nil, fmt.Errorf("%w: negative prompt budget", errSkillInvalidRequest)
+		}
 		var allowed []agentskillsSpec.SkillDef
 		if len(value.AllowSkillRefs) > 0 {
 			for _, ref := range value.AllowSkillRefs {
@@ -190,12 +209,23 @@ func (s *SkillRuntime) GetSkillsPrompt(
 			SessionID:      value.SessionID,
 			Activity:       value.Activity,
 		}
+		budget = skillsPromptBudget(value)
 	}
 	prompt, err := s.runtime.SkillsPrompt(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
-	return &spec.GetSkillsPromptResponse{Body: &spec.GetSkillsPromptResponseBody{Prompt: prompt}}, nil
+	body := &spec.GetSkillsPromptResponseBody{Prompt: prompt}
+	if budget > 0 && len(prompt) > budget {
+		if filter.Activity == agentskillsSpec.SkillActivityActive {
+			body.OverBudget = true
+		} else if body, err = s.fitSkillsPrompt(ctx, filter, budget); err != nil {
+			return nil, err
+		}
+	}
+	body.SizeBytes = len(body.Prompt)
+	body.EstimatedTokens = estimatePromptTokens(body.Prompt)
+	return &spec.GetSkillsPromptResponse{Body: body}, nil
 }
 
 func (s *SkillRuntime) ListRuntimeSkills(
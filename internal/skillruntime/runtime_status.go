@@ -0,0 +1,122 @@
+package skillruntime
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
+	"github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+const (
+	runtimeOpAdd    = "add"
+	runtimeOpRemove = "remove"
+)
+
+// runtimeDefStatus is the last known catalog outcome for one definition.
+type runtimeDefStatus struct {
+	digest    string
+	indexedAt time.Time
+
+	lastError   string
+	lastErrorOp string
+	lastErrorAt time.Time
+}
+
+// GetSkillRuntimeStatus reports whether an installed skill is registered in
+// the runtime catalog and the outcome of the last add or remove attempt.
+func (s *SkillRuntime) GetSkillRuntimeStatus(
+	ctx context.Context,
+	req *spec.GetSkillRuntimeStatusRequest,
+) (*spec.GetSkillRuntimeStatusResponse, error) {
+	if req == nil || req.BundleID == "" || req.SkillSlug == "" {
+		return nil, fmt.Errorf("%w: bundleID and skillSlug required", spec.ErrInvalidRequest)
+	}
+	if err := s.ensureConfigured(); err != nil {
+		return nil, err
+	}
+	// Disabled skills are reported too, as not desired.
+	resp, err := s.store.GetSkill(ctx, &skillstoreSpec.GetSkillRequest{
+		BundleID:        req.BundleID,
+		SkillSlug:       req.SkillSlug,
+		IncludeDisabled: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	skill := *resp.Body
+
+	body := &spec.GetSkillRuntimeStatusResponseBody{
+		SkillRef: spec.SkillRef{BundleID: req.BundleID, SkillSlug: req.SkillSlug, SkillID: skill.ID},
+	}
+	definition, err := s.runtimeDefForStoreSkill(skill)
+	if err != nil {
+		body.DefinitionError = err.Error()
+		return &spec.GetSkillRuntimeStatusResponse{Body: body}, nil
+	}
+	body.Type = definition.Type
+	body.Name = definition.Name
+
+	s.rtResyncMu.Lock()
+	_, body.Desired = s.managedInstalled.definitions[definition]
+	_, body.Registered = s.managedRuntime[definition]
+	s.rtResyncMu.Unlock()
+
+	s.statusMu.Lock()
+	status, ok := s.defStatus[definition]
+	s.statusMu.Unlock()
+	if ok {
+		body.IndexedDigest = status.digest
+		if !status.indexedAt.IsZero() {
+			indexedAt := status.indexedAt
+			body.IndexedAt = &indexedAt
+		}
+		if status.lastError != "" {
+			lastErrorAt := status.lastErrorAt
+			body.LastError = status.lastError
+			body.LastErrorOp = status.lastErrorOp
+			body.LastErrorAt = &lastErrorAt
+		}
+	}
+	return &spec.GetSkillRuntimeStatusResponse{Body: body}, nil
+}
+
+func (s *SkillRuntime) recordRuntimeIndexed(
+	definition agentskillsSpec.SkillDef,
+	record agentskillsSpec.SkillRecord,
+) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	if s.defStatus == nil {
+		s.defStatus = map[agentskillsSpec.SkillDef]runtimeDefStatus{}
+	}
+	s.defStatus[definition] = runtimeDefStatus{
+		digest:    record.Digest,
+		indexedAt: time.Now().UTC(),
+	}
+}
+
+func (s *SkillRuntime) recordRuntimeFailure(
+	definition agentskillsSpec.SkillDef,
+	op string,
+	err error,
+) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	if s.defStatus == nil {
+		s.defStatus = map[agentskillsSpec.SkillDef]runtimeDefStatus{}
+	}
+	status := s.defStatus[definition]
+	status.lastError = err.Error()
+	status.lastErrorOp = op
+	status.lastErrorAt = time.Now().UTC()
+	s.defStatus[definition] = status
+}
+
+func (s *SkillRuntime) forgetRuntimeStatus(definition agentskillsSpec.SkillDef) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	delete(s.defStatus, definition)
+}
@@ -0,0 +1,106 @@
+package skillruntime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+func TestGetSkillRuntimeStatus(t *testing.T) {
+	s := newTestStore(t)
+	installSkill(t, s, "good")
+	installSkill(t, s, "off")
+	broken := installSkill(t, s, "broken")
+	if err := os.WriteFile(filepath.Join(broken, "SKILL.md"), []byte("not a skill"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PatchSkill(t.Context(), &skillstoreSpec.PatchSkillRequest{
+		BundleID:  "b1",
+		SkillSlug: "off",
+		Body:      &skillstoreSpec.PatchSkillRequestBody{IsEnabled: new(false)},
+	}); err != nil {
+		t.Fatalf("PatchSkill: %v", err)
+	}
+	rt, err := NewSkillRuntime(s)
+	if err != nil {
+		t.Fatalf("NewSkillRuntime: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		req            *spec.GetSkillRuntimeStatusRequest
+		wantErr        bool
+		wantErrIs      error
+		wantDesired    bool
+		wantRegistered bool
+		wantIndexed    bool
+		wantLastErrOp  string
+	}{
+		{
+			name:           "loaded",
+			req:            &spec.GetSkillRuntimeStatusRequest{BundleID: "b1", SkillSlug: "good"},
+			wantDesired:    true,
+			wantRegistered: true,
+			wantIndexed:    true,
+		},
+		{
+			name: "disabled",
+			req:  &spec.GetSkillRuntimeStatusRequest{BundleID: "b1", SkillSlug: "off"},
+		},
+		{
+			name:          "index-error",
+			req:           &spec.GetSkillRuntimeStatusRequest{BundleID: "b1", SkillSlug: "broken"},
+			wantDesired:   true,
+			wantLastErrOp: runtimeOpAdd,
+		},
+		{
+			name:    "missing-skill",
+			req:     &spec.GetSkillRuntimeStatusRequest{BundleID: "b1", SkillSlug: "missing"},
+			wantErr: true,
+		},
+		{
+			name:      "invalid-request",
+			req:       &spec.GetSkillRuntimeStatusRequest{BundleID: "b1"},
+			wantErr:   true,
+			wantErrIs: spec.ErrInvalidRequest,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := rt.GetSkillRuntimeStatus(t.Context(), tc.req)
+			if tc.wantErr {
+				if err == nil || (tc.wantErrIs != nil && !errors.Is(err, tc.wantErrIs)) {
+					t.Fatalf("err = %v, want %v", err, tc.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetSkillRuntimeStatus: %v", err)
+			}
+			got := resp.Body
+			if got.SkillRef.SkillSlug != tc.req.SkillSlug || got.SkillRef.SkillID == "" ||
+				got.Name != string(tc.req.SkillSlug) {
+				t.Errorf("skill = %+v", got)
+			}
+			if got.Desired != tc.wantDesired || got.Registered != tc.wantRegistered {
+				t.Errorf("desired = %v, registered = %v, want %v, %v",
+					got.Desired, got.Registered, tc.wantDesired, tc.wantRegistered)
+			}
+			if indexed := got.IndexedDigest != "" && got.IndexedAt != nil; indexed != tc.wantIndexed {
+				t.Errorf("indexed digest = %q at %v", got.IndexedDigest, got.IndexedAt)
+			}
+			if got.LastErrorOp != tc.wantLastErrOp || (got.LastError != "") != (tc.wantLastErrOp != "") ||
+				(got.LastErrorAt != nil) != (tc.wantLastErrOp != "") {
+				t.Errorf("last error = %q op %q at %v, want op %q",
+					got.LastError, got.LastErrorOp, got.LastErrorAt, tc.wantLastErrOp)
+			}
+			if got.DefinitionError != "" {
+				t.Errorf("definition error = %q", got.DefinitionError)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package skillruntime
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/flexigpt/agentskills-go"
+	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
+	"github.com/flexigpt/flexigpt-app/internal/llmtoolsutil"
+	"github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
+)
+
+// ActivateSkillInSession loads skills into a live session in addition to the
+// skills already active in it.
+func (s *SkillRuntime) ActivateSkillInSession(
+	ctx context.Context,
+	req *spec.ActivateSkillInSessionRequest,
+) (*spec.ActivateSkillInSessionResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("%w: missing request", errSkillInvalidRequest)
+	}
+	body, err := s.updateSessionSkills(ctx, req.Body, true)
+	if err != nil {
+		return nil, err
+	}
+	requested := map[string]struct{}{}
+	for _, ref := range req.Body.SkillRefs {
+		requested[refKey(ref)] = struct{}{}
+	}
+	activated := make([]spec.SkillRef, 0, len(req.Body.SkillRefs))
+	for _, ref := range body.ActiveSkillRefs {
+		if _, ok := requested[refKey(ref)]; ok {
+			activated = append(activated, ref)
+		}
+	}
+	s.recordSkillActivations(body.SessionID, activated)
+	return &spec.ActivateSkillInSessionResponse{Body: body}, nil
+}
+
+// DeactivateSkillInSession unloads skills from a live session. Other active
+// skills stay loaded.
+func (s *SkillRuntime) DeactivateSkillInSession(
+	ctx context.Context,
+	req *spec.DeactivateSkillInSessionRequest,
+) (*spec.DeactivateSkillInSessionResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("%w: missing request", errSkillInvalidRequest)
+	}
+	body, err := s.updateSessionSkills(ctx, req.Body, false)
+	if err != nil {
+		return nil, err
+	}
+	return &spec.DeactivateSkillInSessionResponse{Body: body}, nil
+}
+
+// updateSessionSkills drives the session through the same skills-load and
+// skills-unload tools the model uses, so limits and insert checks match. The
+// response lists every skill active in the session afterwards.
+func (s *SkillRuntime) updateSessionSkills(
+	ctx context.Context,
+	body *spec.UpdateSessionSkillsRequestBody,
+	activate bool,
+) (*spec.UpdateSessionSkillsResponseBody, error) {
+	if err := s.ensureConfigured(); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+	}
+	if body == nil {
+		return nil, fmt.Errorf("%w: missing request", errSkillInvalidRequest)
+	}
+	sessionID := agentskillsSpec.SessionID(strings.TrimSpace(string(body.SessionID)))
+	if sessionID == "" {
+		return nil, fmt.Errorf("%w: sessionID required", errSkillInvalidRequest)
+	}
+	if len(body.SkillRefs) == 0 {
+		return nil, fmt.Errorf("%w: skillRefs required", errSkillInvalidRequest)
+	}
+	for _, ref := range body.SkillRefs {
+		if err := validateSkillRef(ref); err != nil {
+			return nil, fmt.Errorf("%w: invalid skillRef: %w", errSkillInvalidRequest, err)
+		}
+	}
+
+	resolved := s.resolveAllowSkillRefs(ctx, body.SkillRefs)
+	for _, ref := range body.SkillRefs {
+		if _, ok := resolved.RefToDef[refKey(ref)]; !ok {
+			return nil, fmt.Errorf("%w: %s", spec.ErrSkillNotFound, refKey(ref))
+		}
+	}
+	handles, err := s.sessionSkillHandles(ctx, resolved.AllowDefs)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		functionID string
+		args       any
+	)
+	if activate {
+		functionID = string(agentskillsSpec.FuncIDSkillsLoad)
+		args = agentskillsSpec.LoadArgs{Skills: handles, Mode: agentskillsSpec.LoadModeAdd}
+	} else {
+		functionID = string(agentskillsSpec.FuncIDSkillsUnload)
+		args = agentskillsSpec.UnloadArgs{Skills: handles}
+	}
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	registry, err := s.runtime.NewSessionRegistry(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := llmtoolsutil.CallUsingRegistry(ctx, registry, functionID, raw); err != nil {
+		return nil, err
+	}
+
+	var known []spec.SkillRef
+	if activate {
+		known = s.addSessionRefs(sessionID, body.SkillRefs)
+	} else {
+		known = s.addSessionRefs(sessionID, nil)
+	}
+	session := s.resolveAllowSkillRefs(ctx, known)
+	records, err := s.runtime.ListSkills(ctx, &agentskills.SkillListFilter{
+		SessionID: sessionID,
+		Activity:  agentskillsSpec.SkillActivityActive,
+	})
+	if err != nil {
+		return nil, err
+	}
+	active := map[agentskillsSpec.SkillDef]struct{}{}
+	for _, record := range records {
+		active[record.Def] = struct{}{}
+	}
+	return &spec.UpdateSessionSkillsResponseBody{
+		SessionID:       sessionID,
+		ActiveSkillRefs: buildActiveSkillRefs(session.DefToRefs, active),
+	}, nil
+}
+
+// sessionSkillHandles maps definitions to the LLM-facing handles the session
+// tools accept. Skills sharing a name and location are disambiguated by the
+// catalog with a hash suffix, which is mirrored here.
+func (s *SkillRuntime) sessionSkillHandles(
+	ctx context.Context,
+	defs []agentskillsSpec.SkillDef,
+) ([]agentskillsSpec.SkillHandle, error) {
+	records, err := s.runtime.ListSkills(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	type handleKey struct{ name, location string }
+	names := map[agentskillsSpec.SkillDef]string{}
+	groups := map[handleKey]int{}
+	for _, record := range records {
+		names[record.Def] = record.Name
+		groups[handleKey{record.Name, record.Def.Location}]++
+	}
+
+	handles := make([]agentskillsSpec.SkillHandle, 0, len(defs))
+	for _, def := range defs {
+		name, ok := names[def]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", spec.ErrSkillNotFound, def.Name)
+		}
+		if groups[handleKey{name, def.Location}] > 1 {
+			sum := sha256.Sum256([]byte(def.Type + "\x00" + def.Name + "\x00" + def.Location))
+			name += "#" + hex.EncodeToString(sum[:])[:8]
+		}
+		handles = append(handles, agentskillsSpec.SkillHandle{Name: name, Location: def.Location})
+	}
+	return handles, nil
+}
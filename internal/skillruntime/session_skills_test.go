@@ -0,0 +1,186 @@
+package skillruntime
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/agentskills-go"
+	"github.com/flexigpt/agentskills-go/fsskillprovider"
+	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
+	"github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// mirrorProvider serves filesystem skills under another type, so the catalog
+// holds two skills with the same name and location.
+type mirrorProvider struct {
+	*fsskillprovider.Provider
+}
+
+const mirrorProviderType = "mirror"
+
+func (mirrorProvider) Type() string { return mirrorProviderType }
+
+func (p mirrorProvider) Index(
+	ctx context.Context,
+	def agentskillsSpec.SkillDef,
+) (agentskillsSpec.ProviderSkillIndexRecord, error) {
+	def.Type = fsskillprovider.Type
+	record, err := p.Provider.Index(ctx, def)
+	record.Key.Type = mirrorProviderType
+	return record, err
+}
+
+func (p mirrorProvider) LoadBody(ctx context.Context, key agentskillsSpec.ProviderSkillKey) (string, error) {
+	key.Type = fsskillprovider.Type
+	return p.Provider.LoadBody(ctx, key)
+}
+
+func installedRef(t *testing.T, s *skillstore.SkillStore, name string) spec.SkillRef {
+	t.Helper()
+	skill, err := s.GetSkill(t.Context(), &skillstoreSpec.GetSkillRequest{
+		BundleID:  "b1",
+		SkillSlug: skillstoreSpec.SkillSlug(name),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec.SkillRef{BundleID: "b1", SkillSlug: skillstoreSpec.SkillSlug(name), SkillID: skill.Body.ID}
+}
+
+func TestUpdateSessionSkills(t *testing.T) {
+	s := newTestStore(t)
+	for _, name := range []string{"alpha", "bravo", "charlie"} {
+		installSkill(t, s, name)
+	}
+	rt, err := NewSkillRuntime(s)
+	if err != nil {
+		t.Fatalf("NewSkillRuntime: %v", err)
+	}
+	alpha, bravo, charlie := installedRef(t, s, "alpha"), installedRef(t, s, "bravo"), installedRef(t, s, "charlie")
+	created, err := rt.CreateSkillSession(t.Context(), &spec.CreateSkillSessionRequest{
+		Body: &spec.CreateSkillSessionRequestBody{
+			AllowSkillRefs:  []spec.SkillRef{alpha, bravo},
+			ActiveSkillRefs: []spec.SkillRef{alpha},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateSkillSession: %v", err)
+	}
+	sessionID := created.Body.SessionID
+
+	update := func(activate bool, refs ...spec.SkillRef) ([]spec.SkillRef, error) {
+		body := &spec.UpdateSessionSkillsRequestBody{SessionID: sessionID, SkillRefs: refs}
+		if activate {
+			resp, err := rt.ActivateSkillInSession(t.Context(), &spec.ActivateSkillInSessionRequest{Body: body})
+			if err != nil {
+				return nil, err
+			}
+			return resp.Body.ActiveSkillRefs, nil
+		}
+		resp, err := rt.DeactivateSkillInSession(t.Context(), &spec.DeactivateSkillInSessionRequest{Body: body})
+		if err != nil {
+			return nil, err
+		}
+		return resp.Body.ActiveSkillRefs, nil
+	}
+
+	steps := []struct {
+		name     string
+		activate bool
+		refs     []spec.SkillRef
+		want     []spec.SkillRef
+	}{
+		{"activate-keeps-active-set", true, []spec.SkillRef{bravo}, []spec.SkillRef{alpha, bravo}},
+		// charlie is outside the allow refs the session was created with.
+		{"activate-new-ref", true, []spec.SkillRef{charlie}, []spec.SkillRef{alpha, bravo, charlie}},
+		{"deactivate", false, []spec.SkillRef{alpha}, []spec.SkillRef{bravo, charlie}},
+		{"deactivate-inactive", false, []spec.SkillRef{alpha}, []spec.SkillRef{bravo, charlie}},
+		{"deactivate-all", false, []spec.SkillRef{bravo, charlie}, []spec.SkillRef{}},
+	}
+	for _, step := range steps {
+		got, err := update(step.activate, step.refs...)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Fatalf("%s: active = %+v, want %+v", step.name, got, step.want)
+		}
+	}
+
+	missing := spec.SkillRef{BundleID: "b1", SkillSlug: "missing", SkillID: "x"}
+	if _, err := update(true, missing); !errors.Is(err, spec.ErrSkillNotFound) {
+		t.Fatalf("missing ref err = %v", err)
+	}
+	if _, err := update(true); !errors.Is(err, errSkillInvalidRequest) {
+		t.Fatalf("empty refs err = %v", err)
+	}
+	if _, err := rt.ActivateSkillInSession(t.Context(), &spec.ActivateSkillInSessionRequest{
+		Body: &spec.UpdateSessionSkillsRequestBody{SkillRefs: []spec.SkillRef{alpha}},
+	}); !errors.Is(err, errSkillInvalidRequest) {
+		t.Fatalf("missing session err = %v", err)
+	}
+}
+
+func TestUpdateSessionSkills_DisambiguatesHandles(t *testing.T) {
+	s := newTestStore(t)
+	installSkill(t, s, "alpha")
+	filesystem, err := fsskillprovider.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	runtime, err := agentskills.New(
+		agentskills.WithProvider(filesystem),
+		agentskills.WithProvider(mirrorProvider{filesystem}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt, err := NewSkillRuntime(s, WithRuntime(runtime))
+	if err != nil {
+		t.Fatalf("NewSkillRuntime: %v", err)
+	}
+	alpha := installedRef(t, s, "alpha")
+	def, ok := rt.definitionForSkillRef(t.Context(), alpha)
+	if !ok {
+		t.Fatal("alpha has no runtime definition")
+	}
+	if _, err := runtime.AddSkill(t.Context(), agentskillsSpec.SkillDef{
+		Type:     mirrorProviderType,
+		Name:     def.Name,
+		Location: def.Location,
+	}); err != nil {
+		t.Fatalf("AddSkill mirror: %v", err)
+	}
+
+	handles, err := rt.sessionSkillHandles(t.Context(), []agentskillsSpec.SkillDef{def})
+	if err != nil {
+		t.Fatalf("sessionSkillHandles: %v", err)
+	}
+	if len(handles) != 1 || !strings.HasPrefix(handles[0].Name, "alpha#") || handles[0].Location != def.Location {
+		t.Fatalf("handles = %+v", handles)
+	}
+
+	created, err := rt.CreateSkillSession(t.Context(), &spec.CreateSkillSessionRequest{
+		Body: &spec.CreateSkillSessionRequestBody{AllowSkillRefs: []spec.SkillRef{alpha}},
+	})
+	if err != nil {
+		t.Fatalf("CreateSkillSession: %v", err)
+	}
+	resp, err := rt.ActivateSkillInSession(t.Context(), &spec.ActivateSkillInSessionRequest{
+		Body: &spec.UpdateSessionSkillsRequestBody{
+			SessionID: created.Body.SessionID,
+			SkillRefs: []spec.SkillRef{alpha},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ActivateSkillInSession: %v", err)
+	}
+	if want := []spec.SkillRef{alpha}; !reflect.DeepEqual(resp.Body.ActiveSkillRefs, want) {
+		t.Fatalf("active = %+v, want %+v", resp.Body.ActiveSkillRefs, want)
+	}
+}
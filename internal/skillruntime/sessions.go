@@ -0,0 +1,167 @@
+package skillruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/flexigpt/agentskills-go"
+	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
+	"github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+type skillSessionInfo struct {
+	createdAt time.Time
+	maxActive int
+	// activated holds the installed skills already counted towards usage in
+	// this session.
+	activated map[skillstoreSpec.SkillRef]struct{}
+	// refs are the allow refs of the session and every ref activated in it
+	// since; they name its active skills in responses.
+	refs []spec.SkillRef
+}
+
+func (s *SkillRuntime) trackSession(
+	sessionID agentskillsSpec.SessionID,
+	maxActive int,
+	allowRefs []spec.SkillRef,
+) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	if s.sessions == nil {
+		s.sessions = map[agentskillsSpec.SessionID]skillSessionInfo{}
+	}
+	s.sessions[sessionID] = skillSessionInfo{
+		createdAt: time.Now().UTC(),
+		maxActive: maxActive,
+		activated: map[skillstoreSpec.SkillRef]struct{}{},
+		refs:      slices.Clone(allowRefs),
+	}
+}
+
+// addSessionRefs remembers refs activated in a tracked session and returns
+// all refs known for it. Untracked sessions only know refs.
+func (s *SkillRuntime) addSessionRefs(
+	sessionID agentskillsSpec.SessionID,
+	refs []spec.SkillRef,
+) []spec.SkillRef {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	info, ok := s.sessions[sessionID]
+	if !ok {
+		return slices.Clone(refs)
+	}
+	for _, ref := range refs {
+		if !slices.ContainsFunc(info.refs, func(known spec.SkillRef) bool { return refKey(known) == refKey(ref) }) {
+			info.refs = append(info.refs, ref)
+		}
+	}
+	s.sessions[sessionID] = info
+	return slices.Clone(info.refs)
+}
+
+// markSessionActivations returns the refs activated in sessionID for the first
+// time and remembers them. Untracked sessions report none.
+func (s *SkillRuntime) markSessionActivations(
+	sessionID agentskillsSpec.SessionID,
+	refs []skillstoreSpec.SkillRef,
+) []skillstoreSpec.SkillRef {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	info, ok := s.sessions[sessionID]
+	if !ok {
+		return nil
+	}
+	var out []skillstoreSpec.SkillRef
+	for _, ref := range refs {
+		key := skillstoreSpec.SkillRef{BundleID: ref.BundleID, SkillSlug: ref.SkillSlug}
+		if _, seen := info.activated[key]; seen {
+			continue
+		}
+		info.activated[key] = struct{}{}
+		out = append(out, key)
+	}
+	return out
+}
+
+func (s *SkillRuntime) untrackSession(sessionID agentskillsSpec.SessionID) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	delete(s.sessions, sessionID)
+}
+
+func (s *SkillRuntime) trackedSessions() map[agentskillsSpec.SessionID]skillSessionInfo {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	return maps.Clone(s.sessions)
+}
+
+// ListSkillSessions lists open sessions, oldest first. Sessions the runtime
+// has expired are dropped from the list as they are found.
+func (s *SkillRuntime) ListSkillSessions(
+	ctx context.Context,
+	_ *spec.ListSkillSessionsRequest,
+) (*spec.ListSkillSessionsResponse, error) {
+	if err := s.ensureConfigured(); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+	}
+	sessions := []spec.SkillSession{}
+	for id, info := range s.trackedSessions() {
+		records, err := s.runtime.ListSkills(ctx, &agentskills.SkillListFilter{
+			SessionID: id,
+			Activity:  agentskillsSpec.SkillActivityActive,
+		})
+		if err != nil {
+			if errors.Is(err, agentskillsSpec.ErrSessionNotFound) {
+				s.untrackSession(id)
+				continue
+			}
+			return nil, err
+		}
+		sessions = append(sessions, spec.SkillSession{
+			SessionID:           id,
+			CreatedAt:           info.createdAt,
+			ActiveSkillCount:    len(records),
+			MaxActivePerSession: info.maxActive,
+		})
+	}
+	sort.Slice(sessions, func(left, right int) bool {
+		if !sessions[left].CreatedAt.Equal(sessions[right].CreatedAt) {
+			return sessions[left].CreatedAt.Before(sessions[right].CreatedAt)
+		}
+		return sessions[left].SessionID < sessions[right].SessionID
+	})
+	return &spec.ListSkillSessionsResponse{Body: &spec.ListSkillSessionsResponseBody{Sessions: sessions}}, nil
+}
+
+// CloseAllSkillSessions closes every session created by CreateSkillSession.
+// Sessions the runtime already expired count as closed. A failing session
+// does not stop the others; the errors are returned together with the count
+// of sessions that did close.
+func (s *SkillRuntime) CloseAllSkillSessions(
+	ctx context.Context,
+	_ *spec.CloseAllSkillSessionsRequest,
+) (*spec.CloseAllSkillSessionsResponse, error) {
+	if err := s.ensureConfigured(); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+	}
+	closed := 0
+	var errs []error
+	for id := range s.trackedSessions() {
+		if err := s.runtime.CloseSession(ctx, id); err != nil &&
+			!errors.Is(err, agentskillsSpec.ErrSessionNotFound) {
+			errs = append(errs, fmt.Errorf("close session %s: %w", id, err))
+			continue
+		}
+		s.untrackSession(id)
+		closed++
+	}
+	return &spec.CloseAllSkillSessionsResponse{
+		Body: &spec.CloseAllSkillSessionsResponseBody{Closed: closed},
+	}, errors.Join(errs...)
+}
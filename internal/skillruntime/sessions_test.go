@@ -0,0 +1,105 @@
+package skillruntime
+
+import (
+	"testing"
+
+	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
+	"github.com/flexigpt/flexigpt-app/internal/skillruntime/spec"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+func TestCloseAllSkillSessions_ExpiredSessionCountsAsClosed(t *testing.T) {
+	s := newTestStore(t)
+	installSkill(t, s, "notes")
+	rt, err := NewSkillRuntime(s)
+	if err != nil {
+		t.Fatalf("NewSkillRuntime: %v", err)
+	}
+	skill, err := s.GetSkill(t.Context(), &skillstoreSpec.GetSkillRequest{BundleID: "b1", SkillSlug: "notes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.CreateSkillSession(t.Context(), &spec.CreateSkillSessionRequest{
+		Body: &spec.CreateSkillSessionRequestBody{
+			AllowSkillRefs: []spec.SkillRef{{BundleID: "b1", SkillSlug: "notes", SkillID: skill.Body.ID}},
+		},
+	}); err != nil {
+		t.Fatalf("CreateSkillSession: %v", err)
+	}
+	// A tracked session the runtime no longer knows, as after expiry.
+	rt.trackSession(agentskillsSpec.SessionID("expired"), 1, nil)
+
+	resp, err := rt.CloseAllSkillSessions(t.Context(), &spec.CloseAllSkillSessionsRequest{})
+	if err != nil {
+		t.Fatalf("CloseAllSkillSessions: %v", err)
+	}
+	if resp.Body.Closed != 2 {
+		t.Fatalf("closed = %d, want 2", resp.Body.Closed)
+	}
+	if left := rt.trackedSessions(); len(left) != 0 {
+		t.Fatalf("still tracked: %v", left)
+	}
+}
+
+func TestRecordSkillActivations_BatchesAndCountsEachSessionOnce(t *testing.T) {
+	s := newTestStore(t)
+	installSkill(t, s, "notes")
+	rt, err := NewSkillRuntime(s)
+	if err != nil {
+		t.Fatalf("NewSkillRuntime: %v", err)
+	}
+	skill, err := s.GetSkill(t.Context(), &skillstoreSpec.GetSkillRequest{BundleID: "b1", SkillSlug: "notes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := spec.SkillRef{BundleID: "b1", SkillSlug: "notes", SkillID: skill.Body.ID}
+	create := func() agentskillsSpec.SessionID {
+		resp, err := rt.CreateSkillSession(t.Context(), &spec.CreateSkillSessionRequest{
+			Body: &spec.CreateSkillSessionRequestBody{
+				AllowSkillRefs:  []spec.SkillRef{ref},
+				ActiveSkillRefs: []spec.SkillRef{ref},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CreateSkillSession: %v", err)
+		}
+		return resp.Body.SessionID
+	}
+
+	// Sessions a, b, then a again: two sessions, three activations.
+	first := create()
+	create()
+	if _, err := rt.ActivateSkillInSession(t.Context(), &spec.ActivateSkillInSessionRequest{
+		Body: &spec.UpdateSessionSkillsRequestBody{SessionID: first, SkillRefs: []spec.SkillRef{ref}},
+	}); err != nil {
+		t.Fatalf("ActivateSkillInSession: %v", err)
+	}
+
+	usage := func() skillstoreSpec.SkillUsage {
+		t.Helper()
+		stats, err := s.GetSkillStats(t.Context(), &skillstoreSpec.GetSkillStatsRequest{
+			BundleIDs: []skillstoreSpec.SkillBundleID{"b1"},
+		})
+		if err != nil {
+			t.Fatalf("GetSkillStats: %v", err)
+		}
+		if len(stats.Body.Stats) != 1 {
+			t.Fatalf("stats = %+v", stats.Body.Stats)
+		}
+		return stats.Body.Stats[0].Usage
+	}
+
+	// The activations are batched into one write.
+	if u := usage(); u.ActivationCount != 0 {
+		t.Fatalf("usage written before the flush: %+v", u)
+	}
+	if at := rt.unflushedLastActivation(skillstoreSpec.SkillRef{BundleID: "b1", SkillSlug: "notes"}); at.IsZero() {
+		t.Fatal("unflushed activation not visible to prompt ordering")
+	}
+	if err := rt.FlushSkillActivations(t.Context()); err != nil {
+		t.Fatalf("FlushSkillActivations: %v", err)
+	}
+	if u := usage(); u.ActivationCount != 3 || u.SessionCount != 2 || u.LastActivatedAt == nil {
+		t.Fatalf("usage = %+v, want 3 activations in 2 sessions", u)
+	}
+}
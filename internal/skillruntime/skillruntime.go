@@ -3,7 +3,6 @@ package skillruntime
 import (
 	"context"
 	"errors"
-	"log/slog"
 	"slices"
 	"sync"
 	"time"
@@ -12,10 +11,14 @@ import (
 	"github.com/flexigpt/agentskills-go/fsskillprovider"
 	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
 	"github.com/flexigpt/flexigpt-app/internal/artifactstore"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 	"github.com/flexigpt/flexigpt-app/internal/skillstore"
+	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
 	"github.com/flexigpt/flexigpt-app/internal/workspace/skilladapter"
 )
 
+var logger = logging.Module("skill.runtime")
+
 const (
 	runtimeResyncTimeout             = 30 * time.Second
 	runtimeForegroundValidateTimeout = 15 * time.Second
@@ -31,9 +34,29 @@ type SkillRuntime struct {
 
 	rtResyncMu sync.Mutex
 
+	// Pending coalesced installed resync, if any.
+	resyncBatchMu sync.Mutex
+	resyncBatch   *installedResyncBatch
+
 	managedInstalled  runtimeDesiredView
 	managedWorkspaces map[artifactstore.RootID]runtimeDesiredView
 	managedRuntime    map[agentskillsSpec.SkillDef]string
+
+	statusMu  sync.Mutex
+	defStatus map[agentskillsSpec.SkillDef]runtimeDefStatus
+
+	// Sessions created through CreateSkillSession; the Agent Skills runtime
+	// does not list its own.
+	sessionsMu sync.Mutex
+	sessions   map[agentskillsSpec.SessionID]skillSessionInfo
+
+	// Skill activations not yet written to the store, and the ones being
+	// written by a flush.
+	activationMu       sync.Mutex
+	activationFlushMu  sync.Mutex
+	activationPending  map[skillstoreSpec.SkillRef]skillstoreSpec.SkillUsage
+	activationFlushing map[skillstoreSpec.SkillRef]skillstoreSpec.SkillUsage
+	activationTimer    *time.Timer
 }
 
 type skillRuntimeOptions struct {
@@ -109,7 +132,7 @@ func NewSkillRuntime(
 		}
 		options.runtime, err = agentskills.New(
 			agentskills.WithProvider(filesystemProvider),
-			agentskills.WithLogger(slog.Default()),
+			agentskills.WithLogger(logger),
 		)
 		if err != nil {
 			return nil, err
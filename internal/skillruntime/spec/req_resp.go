@@ -2,6 +2,7 @@ package spec
 
 import (
 	"errors"
+	"time"
 
 	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
 	skillstoreSpec "github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
@@ -35,6 +36,11 @@ type RuntimeSkillFilter struct {
 
 	SessionID agentskillsSpec.SessionID     `json:"sessionID,omitempty"`
 	Activity  agentskillsSpec.SkillActivity `json:"activity,omitempty"`
+
+	// MaxBytes and MaxTokens bound the skills prompt; zero means no bound.
+	// Tokens are estimated from bytes. Only GetSkillsPrompt reads them.
+	MaxBytes  int `json:"maxBytes,omitempty"`
+	MaxTokens int `json:"maxTokens,omitempty"`
 }
 type GetSkillsPromptRequestBody struct {
 	Filter *RuntimeSkillFilter `json:"filter,omitempty"`
@@ -46,19 +52,45 @@ type GetSkillsPromptRequest struct {
 
 type GetSkillsPromptResponseBody struct {
 	Prompt string `json:"prompt"`
+
+	SizeBytes       int `json:"sizeBytes"`
+	EstimatedTokens int `json:"estimatedTokens"`
+	// OmittedSkills counts inactive skills left out to fit the budget.
+	// Active skill bodies are never left out, so OverBudget can still be set.
+	OmittedSkills int  `json:"omittedSkills,omitempty"`
+	OverBudget    bool `json:"overBudget,omitempty"`
 }
 
 type GetSkillsPromptResponse struct {
 	Body *GetSkillsPromptResponseBody
 }
 
+// SkillPromptOrder selects how the initial active skills of a session are
+// ordered in the skills prompt. Skills loaded later are appended in
+// activation order.
+type SkillPromptOrder string
+
+const (
+	// SkillPromptOrderAlphabetical orders by skill type, name and location. It is the default.
+	SkillPromptOrderAlphabetical SkillPromptOrder = "alphabetical"
+	// SkillPromptOrderPriority keeps the order of ActiveSkillRefs.
+	SkillPromptOrderPriority SkillPromptOrder = "priority"
+	// SkillPromptOrderMRU puts the most recently activated installed skills first.
+	SkillPromptOrderMRU SkillPromptOrder = "mru"
+)
+
 type CreateSkillSessionRequestBody struct {
 	// Optional: close this previous session (best-effort) before creating a new one.
 	CloseSessionID agentskillsSpec.SessionID `json:"closeSessionID,omitempty"`
 
 	MaxActivePerSession int        `json:"maxActivePerSession,omitempty"`
 	AllowSkillRefs      []SkillRef `json:"allowSkillRefs,omitempty"`
-	ActiveSkillRefs     []SkillRef `json:"activeSkillRefs,omitempty"`
+	// ActiveSkillRefs defaults to the allowed skills of always-active bundles.
+	// Bundle activation policies apply only to that default; refs given here
+	// are activated as requested.
+	ActiveSkillRefs []SkillRef `json:"activeSkillRefs,omitempty"`
+
+	PromptOrder SkillPromptOrder `json:"promptOrder,omitempty"`
 }
 
 // CreateSkillSessionRequest creates a session using stable source identities.
@@ -80,6 +112,35 @@ type CloseSkillSessionRequest struct {
 }
 type CloseSkillSessionResponse struct{}
 
+// SkillSession describes an open session created by CreateSkillSession.
+type SkillSession struct {
+	SessionID        agentskillsSpec.SessionID `json:"sessionID"`
+	CreatedAt        time.Time                 `json:"createdAt"`
+	ActiveSkillCount int                       `json:"activeSkillCount"`
+	// MaxActivePerSession is 0 when the session uses the runtime default.
+	MaxActivePerSession int `json:"maxActivePerSession,omitempty"`
+}
+
+type ListSkillSessionsRequest struct{}
+
+type ListSkillSessionsResponseBody struct {
+	Sessions []SkillSession `json:"sessions"`
+}
+
+type ListSkillSessionsResponse struct {
+	Body *ListSkillSessionsResponseBody
+}
+
+type CloseAllSkillSessionsRequest struct{}
+
+type CloseAllSkillSessionsResponseBody struct {
+	Closed int `json:"closed"`
+}
+
+type CloseAllSkillSessionsResponse struct {
+	Body *CloseAllSkillSessionsResponseBody
+}
+
 type RenderSkillRequestBody struct {
 	SkillRef  SkillRef          `json:"skillRef"            required:"true"`
 	Arguments map[string]string `json:"arguments,omitempty"`
@@ -177,3 +238,67 @@ type InvokeSkillToolResponseBody struct {
 type InvokeSkillToolResponse struct {
 	Body *InvokeSkillToolResponseBody
 }
+
+type UpdateSessionSkillsRequestBody struct {
+	SessionID agentskillsSpec.SessionID `json:"sessionID" required:"true"`
+	SkillRefs []SkillRef                `json:"skillRefs" required:"true"`
+}
+
+// ActivateSkillInSessionRequest adds skills to the active set of a live session.
+type ActivateSkillInSessionRequest struct {
+	Body *UpdateSessionSkillsRequestBody
+}
+
+// DeactivateSkillInSessionRequest removes skills from the active set of a live session.
+type DeactivateSkillInSessionRequest struct {
+	Body *UpdateSessionSkillsRequestBody
+}
+
+type UpdateSessionSkillsResponseBody struct {
+	SessionID agentskillsSpec.SessionID `json:"sessionID"`
+	// ActiveSkillRefs lists every skill active in the session after the change,
+	// not only the requested ones.
+	ActiveSkillRefs []SkillRef `json:"activeSkillRefs"`
+}
+
+type ActivateSkillInSessionResponse struct {
+	Body *UpdateSessionSkillsResponseBody
+}
+
+type DeactivateSkillInSessionResponse struct {
+	Body *UpdateSessionSkillsResponseBody
+}
+
+type GetSkillRuntimeStatusRequest struct {
+	BundleID  skillstoreSpec.SkillBundleID `path:"bundleID"  required:"true"`
+	SkillSlug skillstoreSpec.SkillSlug     `path:"skillSlug" required:"true"`
+}
+
+// GetSkillRuntimeStatusResponseBody reports how the runtime sees one
+// installed skill as of the last resync.
+type GetSkillRuntimeStatusResponseBody struct {
+	SkillRef SkillRef `json:"skillRef"`
+
+	Type string `json:"type,omitempty"`
+	Name string `json:"name,omitempty"`
+
+	// Desired is true when the skill and its bundle are enabled.
+	Desired bool `json:"desired"`
+	// Registered is true when the runtime catalog currently holds the skill.
+	Registered bool `json:"registered"`
+
+	IndexedDigest string     `json:"indexedDigest,omitempty"`
+	IndexedAt     *time.Time `json:"indexedAt,omitempty"`
+
+	// LastError is the last failed add or remove for the skill; it is cleared
+	// by the next successful one. DefinitionError reports a skill that could
+	// not be resolved to a runtime definition.
+	LastError       string     `json:"lastError,omitempty"`
+	LastErrorOp     string     `json:"lastErrorOp,omitempty"`
+	LastErrorAt     *time.Time `json:"lastErrorAt,omitempty"`
+	DefinitionError string     `json:"definitionError,omitempty"`
+}
+
+type GetSkillRuntimeStatusResponse struct {
+	Body *GetSkillRuntimeStatusResponseBody
+}
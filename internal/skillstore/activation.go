@@ -0,0 +1,195 @@
+package skillstore
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// skillUsageRecord is the persisted usage counter of one skill.
+type skillUsageRecord struct {
+	ActivationCount int64 `json:"activationCount"`
+	SessionCount    int64 `json:"sessionCount"`
+}
+
+// RecordSkillActivations stores now as the last-activation time of refs and
+// counts one activation of each. Refs are keyed by bundle and slug; SkillID
+// is not checked.
+func (s *SkillStore) RecordSkillActivations(ctx context.Context, refs []spec.SkillRef) error {
+	now := time.Now().UTC()
+	usage := make(map[spec.SkillRef]spec.SkillUsage, len(refs))
+	for _, ref := range refs {
+		usage[spec.SkillRef{BundleID: ref.BundleID, SkillSlug: ref.SkillSlug}] = spec.SkillUsage{
+			ActivationCount: 1,
+			LastActivatedAt: &now,
+		}
+	}
+	return s.RecordSkillUsage(ctx, usage)
+}
+
+// RecordSkillUsage adds the counts of usage to the stored usage of each skill
+// and moves its last-activation time forward to usage's, in one write. The
+// skill runtime collects activations and records them in batches with it.
+func (s *SkillStore) RecordSkillUsage(ctx context.Context, usage map[spec.SkillRef]spec.SkillUsage) error {
+	if len(usage) == 0 {
+		return nil
+	}
+	for ref := range usage {
+		if ref.BundleID == "" || ref.SkillSlug == "" {
+			return fmt.Errorf("%w: bundleID and skillSlug required", errSkillInvalidRequest)
+		}
+	}
+
+	return s.withUserWrite(ctx, "recordSkillUsage", func(sc *skillStoreSchema) error {
+		if sc.LastActivatedAt == nil {
+			sc.LastActivatedAt = map[bundleitemutils.BundleID]map[spec.SkillSlug]time.Time{}
+		}
+		if sc.Usage == nil {
+			sc.Usage = map[bundleitemutils.BundleID]map[spec.SkillSlug]skillUsageRecord{}
+		}
+		for ref, u := range usage {
+			if u.LastActivatedAt != nil {
+				if sc.LastActivatedAt[ref.BundleID] == nil {
+					sc.LastActivatedAt[ref.BundleID] = map[spec.SkillSlug]time.Time{}
+				}
+				at := u.LastActivatedAt.UTC()
+				if at.After(sc.LastActivatedAt[ref.BundleID][ref.SkillSlug]) {
+					sc.LastActivatedAt[ref.BundleID][ref.SkillSlug] = at
+				}
+			}
+
+			if sc.Usage[ref.BundleID] == nil {
+				sc.Usage[ref.BundleID] = map[spec.SkillSlug]skillUsageRecord{}
+			}
+			record := sc.Usage[ref.BundleID][ref.SkillSlug]
+			record.ActivationCount += u.ActivationCount
+			record.SessionCount += u.SessionCount
+			sc.Usage[ref.BundleID][ref.SkillSlug] = record
+		}
+		return nil
+	})
+}
+
+// GetSkillLastActivations returns the last-activation time of each ref that
+// has been activated before. Refs never activated are absent from the result.
+func (s *SkillStore) GetSkillLastActivations(
+	ctx context.Context,
+	refs []spec.SkillRef,
+) (map[spec.SkillRef]time.Time, error) {
+	if s == nil {
+		return nil, fmt.Errorf("%w: nil store", errSkillInvalidRequest)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	all, err := s.readAllUser(ctx, false)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[spec.SkillRef]time.Time, len(refs))
+	for _, ref := range refs {
+		if _, deleted := all.DeletedSkills[ref.BundleID][ref.SkillSlug]; deleted {
+			continue
+		}
+		if at, ok := all.LastActivatedAt[ref.BundleID][ref.SkillSlug]; ok {
+			out[ref] = at
+		}
+	}
+	return out, nil
+}
+
+// GetSkillStats reports the usage of every built-in and user skill, so
+// unused ones can be found and pruned.
+func (s *SkillStore) GetSkillStats(
+	ctx context.Context,
+	req *spec.GetSkillStatsRequest,
+) (*spec.GetSkillStatsResponse, error) {
+	var bundleIDs []bundleitemutils.BundleID
+	if req != nil {
+		bundleIDs = req.BundleIDs
+	}
+	wanted := func(id bundleitemutils.BundleID) bool {
+		return len(bundleIDs) == 0 || slices.Contains(bundleIDs, id)
+	}
+
+	s.mu.RLock()
+	user, err := s.readAllUser(ctx, false)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := []spec.SkillStats{}
+	add := func(b spec.SkillBundle, sk spec.Skill, builtIn bool) {
+		st := spec.SkillStats{
+			BundleID:  b.ID,
+			SkillSlug: sk.Slug,
+			IsBuiltIn: builtIn,
+			IsEnabled: b.IsEnabled && sk.IsEnabled,
+		}
+		if u := user.skillUsage(b.ID, sk.Slug); u != nil {
+			st.Usage = *u
+		}
+		stats = append(stats, st)
+	}
+
+	if s.builtin != nil {
+		biBundles, biSkills, err := s.builtin.ListBuiltInSkills(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for bid, sm := range biSkills {
+			if !wanted(bid) {
+				continue
+			}
+			for _, sk := range sm {
+				add(biBundles[bid], sk, true)
+			}
+		}
+	}
+	for bid, b := range user.Bundles {
+		if isSoftDeletedSkillBundle(b) || !wanted(bid) {
+			continue
+		}
+		for _, sk := range user.Skills[bid] {
+			add(b, sk, false)
+		}
+	}
+
+	slices.SortFunc(stats, func(a, b spec.SkillStats) int {
+		if c := cmp.Compare(b.Usage.ActivationCount, a.Usage.ActivationCount); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.BundleID, b.BundleID); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.SkillSlug, b.SkillSlug)
+	})
+	return &spec.GetSkillStatsResponse{Body: &spec.GetSkillStatsResponseBody{Stats: stats}}, nil
+}
+
+// skillUsage returns the usage of a skill, or nil if it was never activated.
+func (sc *skillStoreSchema) skillUsage(bid bundleitemutils.BundleID, slug spec.SkillSlug) *spec.SkillUsage {
+	record, counted := sc.Usage[bid][slug]
+	last, activated := sc.LastActivatedAt[bid][slug]
+	if !counted && !activated {
+		return nil
+	}
+	u := &spec.SkillUsage{
+		ActivationCount: record.ActivationCount,
+		SessionCount:    record.SessionCount,
+	}
+	if activated {
+		u.LastActivatedAt = &last
+	}
+	return u
+}
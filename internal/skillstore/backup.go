@@ -0,0 +1,90 @@
+package skillstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/flexigpt/mapstore-go/jsonencdec"
+
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// ListSkillStoreBackups returns the backups of the user store file. It is
+// empty unless the store was opened WithBackups.
+func (s *SkillStore) ListSkillStoreBackups(
+	ctx context.Context,
+	_ *spec.ListSkillStoreBackupsRequest,
+) (*spec.ListSkillStoreBackupsResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	backups, err := s.backups.List(s.userFilePath())
+	if err != nil {
+		return nil, err
+	}
+	return &spec.ListSkillStoreBackupsResponse{
+		Body: &spec.ListSkillStoreBackupsResponseBody{Backups: backups},
+	}, nil
+}
+
+// RestoreSkillStoreFromBackup validates a backup and writes it as the user
+// store, then runs the external change handler so the runtime picks it up.
+// Skill packages on disk are not restored.
+func (s *SkillStore) RestoreSkillStoreFromBackup(
+	ctx context.Context,
+	req *spec.RestoreSkillStoreFromBackupRequest,
+) (*spec.RestoreSkillStoreFromBackupResponse, error) {
+	if req == nil || req.Body == nil || strings.TrimSpace(req.Body.Name) == "" {
+		return nil, fmt.Errorf("%w: backup name is required", errSkillInvalidRequest)
+	}
+	if s.backups == nil {
+		return nil, fmt.Errorf("%w: backups are disabled", errSkillInvalidRequest)
+	}
+	data, err := s.backups.Read(s.userFilePath(), req.Body.Name)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: backup %q: %w", errSkillInvalidRequest, req.Body.Name, err)
+	}
+	var sc skillStoreSchema
+	if err := jsonencdec.MapToStructWithJSONTags(raw, &sc); err != nil {
+		return nil, fmt.Errorf("%w: backup %q: %w", errSkillInvalidRequest, req.Body.Name, err)
+	}
+	if err := normalizeSkillStoreSchema(&sc); err != nil {
+		return nil, fmt.Errorf("%w: backup %q: %w", errSkillInvalidRequest, req.Body.Name, err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// The backup replaces the file without the current content being read,
+	// so a corrupt file can be restored.
+	s.writeMu.Lock()
+	s.mu.Lock()
+	err = s.writeGuard.Write(func() error { return s.backups.Restore(s.userFilePath(), req.Body.Name) })
+	if err == nil {
+		// Writes journaled since the backup are undone with the rest.
+		s.clearUserJournal()
+		_, err = s.readAllUser(ctx, true)
+		s.rememberUserFileStat()
+	}
+	handler := s.externalChangeHandler
+	s.mu.Unlock()
+	s.writeMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("skill store restored from backup", "name", req.Body.Name)
+
+	if handler != nil {
+		if err := handler(ctx); err != nil {
+			logger.Error("skill store external change handler", "err", err)
+		}
+	}
+	return &spec.RestoreSkillStoreFromBackupResponse{}, nil
+}
@@ -0,0 +1,43 @@
+package skillstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// GetSkillsBatch fetches several skills in one call. A skill that cannot be
+// fetched gets an item error instead of failing the batch.
+func (s *SkillStore) GetSkillsBatch(
+	ctx context.Context,
+	req *spec.GetSkillsBatchRequest,
+) (*spec.GetSkillsBatchResponse, error) {
+	if req == nil || req.Body == nil || len(req.Body.SkillRefs) == 0 {
+		return nil, fmt.Errorf("%w: skillRefs required", errSkillInvalidRequest)
+	}
+	if len(req.Body.SkillRefs) > skillsMaxPageSize {
+		return nil, fmt.Errorf("%w: at most %d skillRefs", errSkillInvalidRequest, skillsMaxPageSize)
+	}
+
+	items := make([]spec.GetSkillsBatchItem, 0, len(req.Body.SkillRefs))
+	for _, ref := range req.Body.SkillRefs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		item := spec.GetSkillsBatchItem{SkillRef: ref}
+		resp, err := s.GetSkill(ctx, &spec.GetSkillRequest{
+			BundleID:        ref.BundleID,
+			SkillSlug:       ref.SkillSlug,
+			IncludeDisabled: req.Body.IncludeDisabled,
+		})
+		if err != nil {
+			item.Error = err.Error()
+		} else {
+			item.Skill = resp.Body
+			item.SkillRef.SkillID = resp.Body.ID
+		}
+		items = append(items, item)
+	}
+	return &spec.GetSkillsBatchResponse{Body: &spec.GetSkillsBatchResponseBody{Items: items}}, nil
+}
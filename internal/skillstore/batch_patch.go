@@ -0,0 +1,136 @@
+package skillstore
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// BatchPatchSkills sets IsEnabled on the named skills and on every skill of
+// the named bundles. User skills are updated in a single store write;
+// built-in skills are validated before anything is written.
+func (s *SkillStore) BatchPatchSkills(
+	ctx context.Context,
+	req *spec.BatchPatchSkillsRequest,
+) (*spec.BatchPatchSkillsResponse, error) {
+	if req == nil || req.Body == nil || (len(req.Body.SkillRefs) == 0 && len(req.Body.BundleIDs) == 0) {
+		return nil, fmt.Errorf("%w: skillRefs or bundleIDs required", errSkillInvalidRequest)
+	}
+	enabled := req.Body.IsEnabled
+
+	// A nil slug set selects the whole bundle.
+	selected := map[bundleitemutils.BundleID]map[spec.SkillSlug]struct{}{}
+	for _, id := range req.Body.BundleIDs {
+		if id == "" {
+			return nil, fmt.Errorf("%w: empty bundleID", errSkillInvalidRequest)
+		}
+		selected[id] = nil
+	}
+	for _, ref := range req.Body.SkillRefs {
+		if ref.BundleID == "" {
+			return nil, fmt.Errorf("%w: empty bundleID", errSkillInvalidRequest)
+		}
+		if err := bundleitemutils.ValidateItemSlug(ref.SkillSlug); err != nil {
+			return nil, fmt.Errorf("%w: invalid skillSlug %q", errSkillInvalidRequest, ref.SkillSlug)
+		}
+		slugs, ok := selected[ref.BundleID]
+		if ok && slugs == nil {
+			continue
+		}
+		if slugs == nil {
+			slugs = map[spec.SkillSlug]struct{}{}
+			selected[ref.BundleID] = slugs
+		}
+		slugs[ref.SkillSlug] = struct{}{}
+	}
+
+	var builtInRefs, userRefs []spec.SkillRef
+	userSelected := map[bundleitemutils.BundleID]map[spec.SkillSlug]struct{}{}
+	for _, id := range slices.Sorted(maps.Keys(selected)) {
+		if s.builtin == nil {
+			userSelected[id] = selected[id]
+			continue
+		}
+		if _, err := s.builtin.GetBuiltInSkillBundle(ctx, id); err != nil {
+			userSelected[id] = selected[id]
+			continue
+		}
+		slugs := selected[id]
+		if slugs == nil {
+			slugs = map[spec.SkillSlug]struct{}{}
+			for slug := range s.builtin.skills[id] {
+				slugs[slug] = struct{}{}
+			}
+		}
+		for _, slug := range slices.Sorted(maps.Keys(slugs)) {
+			sk, err := s.builtin.GetBuiltInSkill(ctx, id, slug)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s/%s", err, id, slug)
+			}
+			builtInRefs = append(builtInRefs, spec.SkillRef{BundleID: id, SkillSlug: slug, SkillID: sk.ID})
+		}
+	}
+
+	if len(userSelected) > 0 {
+		if err := s.withUserWrite(ctx, "batchPatchSkills", func(sc *skillStoreSchema) error {
+			userRefs = userRefs[:0]
+			now := time.Now().UTC()
+			for _, id := range slices.Sorted(maps.Keys(userSelected)) {
+				bundle, ok := sc.Bundles[id]
+				if !ok {
+					return fmt.Errorf("%w: %s", errSkillBundleNotFound, id)
+				}
+				if isSoftDeletedSkillBundle(bundle) {
+					return fmt.Errorf("%w: %s", errSkillBundleDeleting, id)
+				}
+				values := sc.Skills[id]
+				slugs := userSelected[id]
+				if slugs == nil {
+					slugs = map[spec.SkillSlug]struct{}{}
+					for slug := range values {
+						slugs[slug] = struct{}{}
+					}
+				}
+				for _, slug := range slices.Sorted(maps.Keys(slugs)) {
+					sk, ok := values[slug]
+					if !ok {
+						return fmt.Errorf("%w: %s/%s", errSkillNotFound, id, slug)
+					}
+					if sk.IsEnabled != enabled {
+						sk.IsEnabled = enabled
+						sk.ModifiedAt = now
+						values[slug] = sk
+					}
+					userRefs = append(userRefs, spec.SkillRef{BundleID: id, SkillSlug: slug, SkillID: sk.ID})
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(builtInRefs) > 0 {
+		s.writeMu.Lock()
+		err := s.builtin.SetSkillsEnabled(ctx, builtInRefs, enabled)
+		s.writeMu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	patched := slices.Concat(builtInRefs, userRefs)
+	slices.SortFunc(patched, func(a, b spec.SkillRef) int {
+		return cmp.Or(cmp.Compare(a.BundleID, b.BundleID), cmp.Compare(a.SkillSlug, b.SkillSlug))
+	})
+	logger.Info("batchPatchSkills", "skills", len(patched), "isEnabled", enabled)
+	return &spec.BatchPatchSkillsResponse{
+		Body: &spec.BatchPatchSkillsResponseBody{Patched: patched},
+	}, nil
+}
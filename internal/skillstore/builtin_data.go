@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/fs"
-	"log/slog"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,8 +22,9 @@ import (
 )
 
 const (
-	builtInSkillBundlesGroupID = "bundles"
-	builtInSkillSkillsGroupID  = "skills"
+	builtInSkillBundlesGroupID        = "bundles"
+	builtInSkillSkillsGroupID         = "skills"
+	builtInSkillBundlePoliciesGroupID = "bundlePolicies"
 )
 
 type builtInSkillBundleID bundleitemutils.BundleID
@@ -28,6 +32,11 @@ type builtInSkillBundleID bundleitemutils.BundleID
 func (builtInSkillBundleID) Group() overlay.GroupID { return builtInSkillBundlesGroupID }
 func (k builtInSkillBundleID) ID() overlay.KeyID    { return overlay.KeyID(k) }
 
+type builtInSkillBundlePolicyID bundleitemutils.BundleID
+
+func (builtInSkillBundlePolicyID) Group() overlay.GroupID { return builtInSkillBundlePoliciesGroupID }
+func (k builtInSkillBundlePolicyID) ID() overlay.KeyID    { return overlay.KeyID(k) }
+
 type builtInSkillKey string
 
 func (builtInSkillKey) Group() overlay.GroupID { return builtInSkillSkillsGroupID }
@@ -50,6 +59,7 @@ type BuiltInSkills struct {
 	store          *overlay.Store
 	bundleFlags    *overlay.TypedGroup[builtInSkillBundleID, bool]
 	skillFlags     *overlay.TypedGroup[builtInSkillKey, bool]
+	bundlePolicies *overlay.TypedGroup[builtInSkillBundlePolicyID, spec.SkillBundleActivationPolicy]
 
 	rebuilder *builtin.AsyncRebuilder
 }
@@ -84,6 +94,7 @@ func NewBuiltInSkills(
 		filepath.Join(overlayBaseDir, spec.SkillBuiltInOverlayDBFileName),
 		overlay.WithKeyType[builtInSkillBundleID](),
 		overlay.WithKeyType[builtInSkillKey](),
+		overlay.WithKeyType[builtInSkillBundlePolicyID](),
 	)
 	if err != nil {
 		return nil, err
@@ -117,6 +128,15 @@ func NewBuiltInSkills(
 	}
 	b.skillFlags = skillFlags
 
+	bundlePolicies, err := overlay.NewTypedGroup[builtInSkillBundlePolicyID, spec.SkillBundleActivationPolicy](
+		ctx,
+		store,
+	)
+	if err != nil {
+		return nil, err
+	}
+	b.bundlePolicies = bundlePolicies
+
 	for _, o := range opts {
 		o(b)
 	}
@@ -134,7 +154,7 @@ func NewBuiltInSkills(
 		},
 	)
 	b.rebuilder.MarkFresh()
-	slog.Info("skills builtin loaded", "bundles", len(b.bundles), "skillsBundles", len(b.skills))
+	logger.Info("skills builtin loaded", "bundles", len(b.bundles), "skillsBundles", len(b.skills))
 
 	return b, nil
 }
@@ -212,6 +232,56 @@ func (b *BuiltInSkills) GetBuiltInSkill(
 	return cloneSkill(sk), nil
 }
 
+// ReadBuiltInSkillDocument returns the skill record together with the raw
+// SKILL.md bytes read from the embedded source FS.
+func (b *BuiltInSkills) ReadBuiltInSkillDocument(
+	ctx context.Context,
+	bundleID bundleitemutils.BundleID,
+	slug spec.SkillSlug,
+) (spec.Skill, []byte, error) {
+	sk, pkg, err := b.BuiltInSkillPackageFS(ctx, bundleID, slug)
+	if err != nil {
+		return spec.Skill{}, nil, err
+	}
+	raw, err := fs.ReadFile(pkg, skillMDFileName)
+	if err != nil {
+		return spec.Skill{}, nil, fmt.Errorf("read built-in %s/%s: %w", bundleID, slug, err)
+	}
+	return sk, raw, nil
+}
+
+// BuiltInSkillPackageFS returns the embedded package directory of a built-in skill.
+func (b *BuiltInSkills) BuiltInSkillPackageFS(
+	ctx context.Context,
+	bundleID bundleitemutils.BundleID,
+	slug spec.SkillSlug,
+) (spec.Skill, fs.FS, error) {
+	sk, err := b.GetBuiltInSkill(ctx, bundleID, slug)
+	if err != nil {
+		return spec.Skill{}, nil, err
+	}
+	sub, err := fsutil.ResolveFS(b.skillsFS, b.skillsDir)
+	if err != nil {
+		return spec.Skill{}, nil, err
+	}
+	location := builtInSkillLocation(sk)
+	if location == "" {
+		return sk, sub, nil
+	}
+	pkg, err := fs.Sub(sub, location)
+	if err != nil {
+		return spec.Skill{}, nil, fmt.Errorf("open built-in %s/%s: %w", bundleID, slug, err)
+	}
+	return sk, pkg, nil
+}
+
+// builtInSkillLocation returns the slash-separated package path of a built-in
+// skill relative to the embedded skills root; empty means the root itself.
+func builtInSkillLocation(sk spec.Skill) string {
+	location := strings.ReplaceAll(sk.Location, "\\", "/")
+	return strings.TrimPrefix(path.Clean("/"+location), "/")
+}
+
 func (b *BuiltInSkills) SetSkillBundleEnabled(
 	ctx context.Context,
 	id bundleitemutils.BundleID,
@@ -239,6 +309,33 @@ func (b *BuiltInSkills) SetSkillBundleEnabled(
 	return cloneBundle(sb), nil
 }
 
+func (b *BuiltInSkills) SetSkillBundleActivationPolicy(
+	ctx context.Context,
+	id bundleitemutils.BundleID,
+	policy spec.SkillBundleActivationPolicy,
+) (spec.SkillBundle, error) {
+	if _, ok := b.bundles[id]; !ok {
+		return spec.SkillBundle{}, errSkillBundleNotFound
+	}
+
+	flag, err := b.bundlePolicies.SetFlag(ctx, builtInSkillBundlePolicyID(id), policy)
+	if err != nil {
+		return spec.SkillBundle{}, err
+	}
+
+	b.mu.Lock()
+
+	sb := b.viewBundles[id]
+	sb.ActivationPolicy = policy
+	sb.ModifiedAt = flag.ModifiedAt
+	b.viewBundles[id] = sb
+
+	b.mu.Unlock()
+
+	b.rebuilder.Trigger()
+	return cloneBundle(sb), nil
+}
+
 func (b *BuiltInSkills) SetSkillEnabled(
 	ctx context.Context,
 	bundleID bundleitemutils.BundleID,
@@ -271,6 +368,177 @@ func (b *BuiltInSkills) SetSkillEnabled(
 	return cloneSkill(sk), nil
 }
 
+// SetSkillsEnabled sets the enabled flag of several built-in skills and
+// rebuilds the snapshot once. All skills are validated before any flag is
+// written.
+func (b *BuiltInSkills) SetSkillsEnabled(
+	ctx context.Context,
+	refs []spec.SkillRef,
+	enabled bool,
+) error {
+	for _, ref := range refs {
+		if _, ok := b.skills[ref.BundleID]; !ok {
+			return fmt.Errorf("%w: %s", errSkillBundleNotFound, ref.BundleID)
+		}
+		if _, ok := b.skills[ref.BundleID][ref.SkillSlug]; !ok {
+			return fmt.Errorf("%w: %s", errSkillNotFound, ref.SkillSlug)
+		}
+	}
+
+	defer b.rebuilder.Trigger()
+	for _, ref := range refs {
+		flag, err := b.skillFlags.SetFlag(ctx, getBuiltInSkillKey(ref.BundleID, ref.SkillSlug), enabled)
+		if err != nil {
+			return err
+		}
+		b.mu.Lock()
+		sk := b.viewSkills[ref.BundleID][ref.SkillSlug]
+		sk.IsEnabled = enabled
+		sk.ModifiedAt = flag.ModifiedAt
+		b.viewSkills[ref.BundleID][ref.SkillSlug] = sk
+		b.mu.Unlock()
+	}
+	return nil
+}
+
+// ResetOverrides drops the enabled-flag and activation-policy overlay entries
+// of the given bundles and their skills, or of all built-in bundles when none
+// are given.
+func (b *BuiltInSkills) ResetOverrides(
+	ctx context.Context,
+	bundleIDs ...bundleitemutils.BundleID,
+) ([]bundleitemutils.BundleID, error) {
+	if len(bundleIDs) == 0 {
+		bundleIDs = slices.Sorted(maps.Keys(b.bundles))
+	}
+	for _, id := range bundleIDs {
+		if _, ok := b.bundles[id]; !ok {
+			return nil, fmt.Errorf("%w: %s", errSkillBundleNotFound, id)
+		}
+	}
+
+	for _, id := range bundleIDs {
+		if err := b.bundleFlags.DeleteKey(ctx, builtInSkillBundleID(id)); err != nil {
+			return nil, err
+		}
+		if err := b.bundlePolicies.DeleteKey(ctx, builtInSkillBundlePolicyID(id)); err != nil {
+			return nil, err
+		}
+		for slug := range b.skills[id] {
+			if err := b.skillFlags.DeleteKey(ctx, getBuiltInSkillKey(id, slug)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	b.mu.Lock()
+	err := b.rebuildSnapshot(ctx)
+	b.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(bundleIDs), nil
+}
+
+// OverrideFlags returns the enabled flags stored in the overlay, leaving out
+// bundles and skills that use their default.
+func (b *BuiltInSkills) OverrideFlags(ctx context.Context) (
+	map[bundleitemutils.BundleID]bool,
+	map[bundleitemutils.BundleID]map[spec.SkillSlug]bool,
+	error,
+) {
+	bundleFlags := map[bundleitemutils.BundleID]bool{}
+	skillFlags := map[bundleitemutils.BundleID]map[spec.SkillSlug]bool{}
+	for bid := range b.bundles {
+		flag, ok, err := b.bundleFlags.GetFlag(ctx, builtInSkillBundleID(bid))
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			bundleFlags[bid] = flag.Value
+		}
+	}
+	for bid, sm := range b.skills {
+		for slug := range sm {
+			flag, ok, err := b.skillFlags.GetFlag(ctx, getBuiltInSkillKey(bid, slug))
+			if err != nil {
+				return nil, nil, err
+			}
+			if !ok {
+				continue
+			}
+			if skillFlags[bid] == nil {
+				skillFlags[bid] = map[spec.SkillSlug]bool{}
+			}
+			skillFlags[bid][slug] = flag.Value
+		}
+	}
+	return bundleFlags, skillFlags, nil
+}
+
+// CompactOverlay deletes overlay flags that set a built-in bundle or skill to
+// its default, or that refer to one no longer built in, and shrinks the
+// overlay database. It returns how many flags were deleted.
+func (b *BuiltInSkills) CompactOverlay(ctx context.Context) (int, error) {
+	bundles := make(map[overlay.KeyID]spec.SkillBundle, len(b.bundles))
+	for id, sb := range b.bundles {
+		bundles[builtInSkillBundleID(id).ID()] = sb
+	}
+	skills := map[overlay.KeyID]spec.Skill{}
+	for id, sm := range b.skills {
+		for slug, sk := range sm {
+			skills[getBuiltInSkillKey(id, slug).ID()] = sk
+		}
+	}
+
+	pruned := 0
+	n, err := b.bundleFlags.PruneFlags(ctx, func(key overlay.KeyID, flag overlay.TypedFlag[bool]) bool {
+		sb, ok := bundles[key]
+		return !ok || sb.IsEnabled == flag.Value
+	})
+	if err != nil {
+		return pruned, err
+	}
+	pruned += n
+	n, err = b.bundlePolicies.PruneFlags(ctx,
+		func(key overlay.KeyID, flag overlay.TypedFlag[spec.SkillBundleActivationPolicy]) bool {
+			sb, ok := bundles[key]
+			return !ok || sb.ActivationPolicy == flag.Value
+		})
+	if err != nil {
+		return pruned, err
+	}
+	pruned += n
+	n, err = b.skillFlags.PruneFlags(ctx, func(key overlay.KeyID, flag overlay.TypedFlag[bool]) bool {
+		sk, ok := skills[key]
+		return !ok || sk.IsEnabled == flag.Value
+	})
+	if err != nil {
+		return pruned, err
+	}
+	pruned += n
+
+	if pruned > 0 {
+		b.mu.Lock()
+		err := b.rebuildSnapshot(ctx)
+		b.mu.Unlock()
+		if err != nil {
+			return pruned, err
+		}
+	}
+	if err := b.store.Vacuum(ctx); err != nil {
+		return pruned, err
+	}
+	logger.Info("skills builtin overlay compacted", "pruned", pruned)
+	return pruned, nil
+}
+
+// GetOverlayStats reports the flag counts and file size of the overlay
+// database.
+func (b *BuiltInSkills) GetOverlayStats(ctx context.Context) (overlay.DBStats, error) {
+	return b.store.Stats(ctx)
+}
+
 func (b *BuiltInSkills) populateDataFromFS(ctx context.Context) error {
 	sub, err := fsutil.ResolveFS(b.skillsFS, b.skillsDir)
 	if err != nil {
@@ -360,6 +628,14 @@ func (b *BuiltInSkills) rebuildSnapshot(ctx context.Context) error {
 			sb.IsEnabled = flag.Value
 			sb.ModifiedAt = flag.ModifiedAt
 		}
+		if flag, ok, err := b.bundlePolicies.GetFlag(ctx, builtInSkillBundlePolicyID(bid)); err != nil {
+			return err
+		} else if ok {
+			sb.ActivationPolicy = flag.Value
+			if flag.ModifiedAt.After(sb.ModifiedAt) {
+				sb.ModifiedAt = flag.ModifiedAt
+			}
+		}
 		newBundles[bid] = sb
 	}
 
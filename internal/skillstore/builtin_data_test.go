@@ -394,6 +394,135 @@ func TestBuiltInSkills_SetFlags_AndPersistence(t *testing.T) {
 	}
 }
 
+func TestBuiltInSkills_ResetOverrides(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	fsys := os.DirFS(filepath.Join(".", "testdata"))
+	b, err := NewBuiltInSkills(ctx, t.TempDir(), time.Second, WithBuiltInSkillsFS(fsys, "."))
+	if err != nil {
+		t.Fatalf("NewBuiltInSkills: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = b.Close()
+	})
+
+	bundleID := bundleitemutils.BundleID(builtinBundleID)
+	slug := spec.SkillSlug(builtinSkillSlug)
+	pristineBundle, err := b.GetBuiltInSkillBundle(ctx, bundleID)
+	if err != nil {
+		t.Fatalf("GetBuiltInSkillBundle: %v", err)
+	}
+	pristineSkill, err := b.GetBuiltInSkill(ctx, bundleID, slug)
+	if err != nil {
+		t.Fatalf("GetBuiltInSkill: %v", err)
+	}
+
+	if _, err := b.SetSkillBundleEnabled(ctx, bundleID, !pristineBundle.IsEnabled); err != nil {
+		t.Fatalf("SetSkillBundleEnabled: %v", err)
+	}
+	if _, err := b.SetSkillEnabled(ctx, bundleID, slug, !pristineSkill.IsEnabled); err != nil {
+		t.Fatalf("SetSkillEnabled: %v", err)
+	}
+
+	if _, err := b.ResetOverrides(ctx, "ghost-bundle"); !errors.Is(err, errSkillBundleNotFound) {
+		t.Fatalf("ResetOverrides(unknown) err=%v want errSkillBundleNotFound", err)
+	}
+
+	reset, err := b.ResetOverrides(ctx, bundleID)
+	if err != nil {
+		t.Fatalf("ResetOverrides: %v", err)
+	}
+	if len(reset) != 1 || reset[0] != bundleID {
+		t.Fatalf("reset=%v", reset)
+	}
+
+	gotBundle, err := b.GetBuiltInSkillBundle(ctx, bundleID)
+	if err != nil {
+		t.Fatalf("GetBuiltInSkillBundle: %v", err)
+	}
+	if gotBundle.IsEnabled != pristineBundle.IsEnabled {
+		t.Fatalf("bundle enabled=%v want %v", gotBundle.IsEnabled, pristineBundle.IsEnabled)
+	}
+	gotSkill, err := b.GetBuiltInSkill(ctx, bundleID, slug)
+	if err != nil {
+		t.Fatalf("GetBuiltInSkill: %v", err)
+	}
+	if gotSkill.IsEnabled != pristineSkill.IsEnabled {
+		t.Fatalf("skill enabled=%v want %v", gotSkill.IsEnabled, pristineSkill.IsEnabled)
+	}
+	if _, ok, err := b.skillFlags.GetFlag(ctx, getBuiltInSkillKey(bundleID, slug)); err != nil || ok {
+		t.Fatalf("skill overlay still present: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestBuiltInSkills_CompactOverlay(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	fsys := os.DirFS(filepath.Join(".", "testdata"))
+	b, err := NewBuiltInSkills(ctx, t.TempDir(), time.Second, WithBuiltInSkillsFS(fsys, "."))
+	if err != nil {
+		t.Fatalf("NewBuiltInSkills: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = b.Close()
+	})
+
+	bundleID := bundleitemutils.BundleID(builtinBundleID)
+	slug := spec.SkillSlug(builtinSkillSlug)
+	pristineBundle, err := b.GetBuiltInSkillBundle(ctx, bundleID)
+	if err != nil {
+		t.Fatalf("GetBuiltInSkillBundle: %v", err)
+	}
+	pristineSkill, err := b.GetBuiltInSkill(ctx, bundleID, slug)
+	if err != nil {
+		t.Fatalf("GetBuiltInSkill: %v", err)
+	}
+
+	// Toggled back to the default: prunable.
+	for _, enabled := range []bool{!pristineBundle.IsEnabled, pristineBundle.IsEnabled} {
+		if _, err := b.SetSkillBundleEnabled(ctx, bundleID, enabled); err != nil {
+			t.Fatalf("SetSkillBundleEnabled: %v", err)
+		}
+	}
+	// A real override: kept.
+	if _, err := b.SetSkillEnabled(ctx, bundleID, slug, !pristineSkill.IsEnabled); err != nil {
+		t.Fatalf("SetSkillEnabled: %v", err)
+	}
+	// A skill that is no longer built in: prunable.
+	if _, err := b.skillFlags.SetFlag(ctx, getBuiltInSkillKey(bundleID, "gone"), true); err != nil {
+		t.Fatalf("SetFlag: %v", err)
+	}
+
+	stats, err := b.GetOverlayStats(ctx)
+	if err != nil {
+		t.Fatalf("GetOverlayStats: %v", err)
+	}
+	if stats.Flags[builtInSkillBundlesGroupID] != 1 || stats.Flags[builtInSkillSkillsGroupID] != 2 {
+		t.Fatalf("stats before compaction: %+v", stats.Flags)
+	}
+
+	pruned, err := b.CompactOverlay(ctx)
+	if err != nil || pruned != 2 {
+		t.Fatalf("CompactOverlay = %d, %v; want 2", pruned, err)
+	}
+	stats, err = b.GetOverlayStats(ctx)
+	if err != nil {
+		t.Fatalf("GetOverlayStats: %v", err)
+	}
+	if stats.Flags[builtInSkillBundlesGroupID] != 0 || stats.Flags[builtInSkillSkillsGroupID] != 1 {
+		t.Fatalf("stats after compaction: %+v", stats.Flags)
+	}
+	gotSkill, err := b.GetBuiltInSkill(ctx, bundleID, slug)
+	if err != nil {
+		t.Fatalf("GetBuiltInSkill: %v", err)
+	}
+	if gotSkill.IsEnabled == pristineSkill.IsEnabled {
+		t.Fatal("compaction dropped a real override")
+	}
+}
+
 func TestBuiltInSkills_ConcurrentFlagUpdates(t *testing.T) {
 	t.Parallel()
 
@@ -513,3 +642,82 @@ func TestGetBuiltInSkillKey(t *testing.T) {
 		t.Fatalf("got %q", got)
 	}
 }
+
+func TestSkillStore_GetBuiltInSkillDocs(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	ctx := t.Context()
+
+	_, skills, err := s.builtin.ListBuiltInSkills(ctx)
+	if err != nil {
+		t.Fatalf("ListBuiltInSkills: %v", err)
+	}
+	for bid, sm := range skills {
+		for slug, sk := range sm {
+			// Docs must be readable even when the built-in is disabled.
+			if _, err := s.builtin.SetSkillEnabled(ctx, bid, slug, false); err != nil {
+				t.Fatalf("SetSkillEnabled: %v", err)
+			}
+			resp, err := s.GetBuiltInSkillDocs(ctx, &spec.GetBuiltInSkillDocsRequest{
+				BundleID:  bid,
+				SkillSlug: slug,
+			})
+			if err != nil {
+				t.Fatalf("GetBuiltInSkillDocs(%s/%s): %v", bid, slug, err)
+			}
+			body := resp.Body
+			if body.Name != sk.Name {
+				t.Fatalf("name mismatch: got %q want %q", body.Name, sk.Name)
+			}
+			if !strings.HasPrefix(body.Content, "---") || body.Frontmatter["name"] != sk.Name {
+				t.Fatalf("frontmatter not returned for %s/%s", bid, slug)
+			}
+			if strings.TrimSpace(body.MarkdownBody) == "" {
+				t.Fatalf("empty markdown body for %s/%s", bid, slug)
+			}
+
+			content, err := s.GetSkillContent(ctx, &spec.GetSkillContentRequest{
+				BundleID:     bid,
+				SkillSlug:    slug,
+				IncludeFiles: true,
+			})
+			if err != nil {
+				t.Fatalf("GetSkillContent(%s/%s): %v", bid, slug, err)
+			}
+			if !content.Body.IsBuiltIn || content.Body.Content != body.Content {
+				t.Fatalf("GetSkillContent differs from GetBuiltInSkillDocs for %s/%s", bid, slug)
+			}
+			return
+		}
+	}
+	t.Skip("no built-in skills available")
+}
+
+func TestSkillStore_GetBuiltInSkillDocs_Errors(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	ctx := t.Context()
+
+	tests := []struct {
+		name    string
+		req     *spec.GetBuiltInSkillDocsRequest
+		wantErr error
+	}{
+		{"nil-req", nil, errSkillInvalidRequest},
+		{"missing-slug", &spec.GetBuiltInSkillDocsRequest{BundleID: "x"}, errSkillInvalidRequest},
+		{"bad-slug", &spec.GetBuiltInSkillDocsRequest{BundleID: "x", SkillSlug: badSlug}, errSkillInvalidRequest},
+		{
+			"user-bundle",
+			&spec.GetBuiltInSkillDocsRequest{BundleID: spec.BaseSkillBundleID, SkillSlug: "s1"},
+			errSkillBundleNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.GetBuiltInSkillDocs(ctx, tc.req)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package skillstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flexigpt/agentskills-go"
+	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// GetBuiltInSkillDocs returns the parsed SKILL.md of a built-in skill. It reads
+// the embedded package directly, so disabled built-ins can be documented before
+// they are ever enabled or loaded into a runtime session.
+func (s *SkillStore) GetBuiltInSkillDocs(
+	ctx context.Context,
+	req *spec.GetBuiltInSkillDocsRequest,
+) (*spec.GetBuiltInSkillDocsResponse, error) {
+	if req == nil || req.BundleID == "" || req.SkillSlug == "" {
+		return nil, fmt.Errorf("%w: bundleID and skillSlug required", errSkillInvalidRequest)
+	}
+	if err := bundleitemutils.ValidateItemSlug(req.SkillSlug); err != nil {
+		return nil, fmt.Errorf("%w: invalid skillSlug", errSkillInvalidRequest)
+	}
+	if s.builtin == nil {
+		return nil, fmt.Errorf("%w: %s", errSkillBundleNotFound, req.BundleID)
+	}
+	if _, err := s.builtin.GetBuiltInSkillBundle(ctx, req.BundleID); err != nil {
+		return nil, fmt.Errorf("%w: %s", errSkillBundleNotFound, req.BundleID)
+	}
+
+	skill, raw, err := s.builtin.ReadBuiltInSkillDocument(ctx, req.BundleID, req.SkillSlug)
+	if err != nil {
+		return nil, err
+	}
+	body, err := parseSkillDocs(req.BundleID, req.SkillSlug, skill.Name, raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse built-in %s/%s: %w", req.BundleID, req.SkillSlug, err)
+	}
+	return &spec.GetBuiltInSkillDocsResponse{Body: body}, nil
+}
+
+func parseSkillDocs(
+	bundleID bundleitemutils.BundleID,
+	slug spec.SkillSlug,
+	name string,
+	raw []byte,
+) (*spec.GetBuiltInSkillDocsResponseBody, error) {
+	document, warnings, err := agentskills.ParseSkillDocument(
+		raw,
+		agentskillsSpec.ParseSkillDocumentOptions{ExpectedName: name},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &spec.GetBuiltInSkillDocsResponseBody{
+		BundleID:     bundleID,
+		SkillSlug:    slug,
+		Name:         document.Name,
+		DisplayName:  document.DisplayName,
+		Description:  document.Description,
+		Insert:       document.Insert,
+		Arguments:    append([]agentskillsSpec.SkillArgument(nil), document.Arguments...),
+		SourceTags:   append([]string(nil), document.Tags...),
+		Frontmatter:  cloneAnyMap(document.RawFrontmatter),
+		MarkdownBody: document.MarkdownBody,
+		Content:      string(raw),
+		Warnings:     warnings,
+	}, nil
+}
@@ -0,0 +1,241 @@
+package skillstore
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// builtInHydrateManifest is written next to the hydrated built-in skills so
+// the next app version can diff against the packages it replaces.
+type builtInHydrateManifest struct {
+	Digest string                      `json:"digest"`
+	Skills []builtInSkillPackageDigest `json:"skills"`
+	// Files maps each hydrated file to its sha256, letting the next sync skip
+	// files that did not change.
+	Files map[string]string `json:"files,omitempty"`
+}
+
+type builtInSkillPackageDigest struct {
+	BundleID  bundleitemutils.BundleID `json:"bundleID"`
+	SkillSlug spec.SkillSlug           `json:"skillSlug"`
+	Name      string                   `json:"name"`
+	Digest    string                   `json:"digest"`
+}
+
+// GetBuiltInSkillsUpdate returns the last recorded replacement of the
+// built-in skills, so the UI can show what changed with an app update.
+func (s *SkillStore) GetBuiltInSkillsUpdate(
+	ctx context.Context,
+	req *spec.GetBuiltInSkillsUpdateRequest,
+) (*spec.GetBuiltInSkillsUpdateResponse, error) {
+	s.embeddedMaterializeMu.Lock()
+	defer s.embeddedMaterializeMu.Unlock()
+
+	update, err := s.readBuiltInSkillsUpdate()
+	if err != nil {
+		return nil, err
+	}
+	return &spec.GetBuiltInSkillsUpdateResponse{
+		Body: &spec.GetBuiltInSkillsUpdateResponseBody{Update: update},
+	}, nil
+}
+
+// UpdateBuiltInSkills re-hydrates the built-in skills when the embedded ones
+// differ from the hydrated copy. A dry run only reports the diff.
+func (s *SkillStore) UpdateBuiltInSkills(
+	ctx context.Context,
+	req *spec.UpdateBuiltInSkillsRequest,
+) (*spec.UpdateBuiltInSkillsResponse, error) {
+	if req == nil || req.Body == nil {
+		return nil, fmt.Errorf("%w: missing body", errSkillInvalidRequest)
+	}
+	update, applied, err := s.updateBuiltInEmbeddedFS(ctx, req.Body.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	return &spec.UpdateBuiltInSkillsResponse{
+		Body: &spec.UpdateBuiltInSkillsResponseBody{Applied: applied, Update: update},
+	}, nil
+}
+
+// MarkBuiltInSkillsUpdateSeen flags the recorded update as shown to the user.
+func (s *SkillStore) MarkBuiltInSkillsUpdateSeen(
+	ctx context.Context,
+	req *spec.MarkBuiltInSkillsUpdateSeenRequest,
+) (*spec.MarkBuiltInSkillsUpdateSeenResponse, error) {
+	s.embeddedMaterializeMu.Lock()
+	defer s.embeddedMaterializeMu.Unlock()
+
+	update, err := s.readBuiltInSkillsUpdate()
+	if err != nil {
+		return nil, err
+	}
+	if update != nil && !update.Seen {
+		update.Seen = true
+		if err := s.writeBuiltInSkillsUpdate(*update); err != nil {
+			return nil, err
+		}
+	}
+	return &spec.MarkBuiltInSkillsUpdateSeenResponse{}, nil
+}
+
+func (s *SkillStore) builtInSkillsUpdatePath() string {
+	return filepath.Join(s.baseDir, spec.SkillBuiltInUpdateFileName)
+}
+
+func (s *SkillStore) readBuiltInSkillsUpdate() (*spec.BuiltInSkillsUpdate, error) {
+	raw, err := os.ReadFile(s.builtInSkillsUpdatePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var update spec.BuiltInSkillsUpdate
+	if err := json.Unmarshal(raw, &update); err != nil {
+		return nil, fmt.Errorf("read built-in skills update: %w", err)
+	}
+	return &update, nil
+}
+
+func (s *SkillStore) writeBuiltInSkillsUpdate(update spec.BuiltInSkillsUpdate) error {
+	raw, err := json.MarshalIndent(update, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.builtInSkillsUpdatePath(), raw, 0o600)
+}
+
+// packageDigests digests every built-in skill package in the scanned embedded
+// root.
+func (b *BuiltInSkills) packageDigests(root fsScan) (builtInHydrateManifest, error) {
+	manifest := builtInHydrateManifest{Skills: []builtInSkillPackageDigest{}}
+	for _, bid := range slices.Sorted(maps.Keys(b.skills)) {
+		for _, slug := range slices.Sorted(maps.Keys(b.skills[bid])) {
+			sk := b.skills[bid][slug]
+			pkg, err := root.sub(builtInSkillLocation(sk))
+			if err != nil {
+				return manifest, fmt.Errorf("digest built-in %s/%s: %w", bid, slug, err)
+			}
+			manifest.Skills = append(manifest.Skills, builtInSkillPackageDigest{
+				BundleID:  bid,
+				SkillSlug: slug,
+				Name:      sk.Name,
+				Digest:    pkg.digest(),
+			})
+		}
+	}
+	return manifest, nil
+}
+
+// hydratedPackageDigests returns the package digests of the skills hydrated
+// in dir. Directories hydrated before manifests existed are digested using
+// the current built-in skill locations; packages missing there are left out.
+func (b *BuiltInSkills) hydratedPackageDigests(dir string) ([]builtInSkillPackageDigest, error) {
+	if manifest, ok := readHydrateManifest(dir); ok {
+		return manifest.Skills, nil
+	}
+
+	root := os.DirFS(dir)
+	var digests []builtInSkillPackageDigest
+	for _, bid := range slices.Sorted(maps.Keys(b.skills)) {
+		for _, slug := range slices.Sorted(maps.Keys(b.skills[bid])) {
+			sk := b.skills[bid][slug]
+			location := builtInSkillLocation(sk)
+			if location != "" {
+				if _, err := fs.Stat(root, location); err != nil {
+					continue
+				}
+			}
+			digest, err := skillPackageDigest(root, location)
+			if err != nil {
+				return nil, fmt.Errorf("digest hydrated %s/%s: %w", bid, slug, err)
+			}
+			digests = append(digests, builtInSkillPackageDigest{
+				BundleID:  bid,
+				SkillSlug: slug,
+				Name:      sk.Name,
+				Digest:    digest,
+			})
+		}
+	}
+	return digests, nil
+}
+
+// readHydrateManifest reads the manifest written by the last hydration of
+// dir. It reports false when there is none or it cannot be parsed.
+func readHydrateManifest(dir string) (builtInHydrateManifest, bool) {
+	var manifest builtInHydrateManifest
+	raw, err := os.ReadFile(filepath.Join(dir, embeddedHydrateManifestFile))
+	if err != nil {
+		return manifest, false
+	}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return manifest, false
+	}
+	return manifest, true
+}
+
+func skillPackageDigest(root fs.FS, location string) (string, error) {
+	if location == "" {
+		return fsDigestSHA256(root)
+	}
+	pkg, err := fs.Sub(root, location)
+	if err != nil {
+		return "", err
+	}
+	return fsDigestSHA256(pkg)
+}
+
+// diffBuiltInPackageDigests lists the skills added, removed or changed going
+// from before to after, ordered by bundle and slug.
+func diffBuiltInPackageDigests(before, after []builtInSkillPackageDigest) []spec.BuiltInSkillChange {
+	type key struct {
+		bid  bundleitemutils.BundleID
+		slug spec.SkillSlug
+	}
+	old := make(map[key]builtInSkillPackageDigest, len(before))
+	for _, d := range before {
+		old[key{d.BundleID, d.SkillSlug}] = d
+	}
+
+	changes := []spec.BuiltInSkillChange{}
+	for _, d := range after {
+		k := key{d.BundleID, d.SkillSlug}
+		prev, ok := old[k]
+		delete(old, k)
+		change := spec.BuiltInSkillChange{BundleID: d.BundleID, SkillSlug: d.SkillSlug, Name: d.Name}
+		switch {
+		case !ok:
+			change.Kind = spec.BuiltInSkillAdded
+		case prev.Digest != d.Digest:
+			change.Kind = spec.BuiltInSkillChanged
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	for _, d := range old {
+		changes = append(changes, spec.BuiltInSkillChange{
+			Kind:      spec.BuiltInSkillRemoved,
+			BundleID:  d.BundleID,
+			SkillSlug: d.SkillSlug,
+			Name:      d.Name,
+		})
+	}
+	slices.SortFunc(changes, func(a, b spec.BuiltInSkillChange) int {
+		return cmp.Or(cmp.Compare(a.BundleID, b.BundleID), cmp.Compare(a.SkillSlug, b.SkillSlug))
+	})
+	return changes
+}
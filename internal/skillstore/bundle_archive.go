@@ -0,0 +1,497 @@
+package skillstore
+
+import (
+	"archive/tar"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/agentskills-go"
+	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
+	"github.com/flexigpt/mapstore-go/uuidv7filename"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+const (
+	skillBundleArchiveManifest  = "bundle.json"
+	skillBundleArchiveSkillsDir = "skills"
+
+	maxSkillBundleArchiveBytes = 64 << 20
+	maxSkillBundleArchiveFiles = 4096
+)
+
+// skillBundleArchive is the bundle.json manifest of an exported bundle. Skill
+// files live under skills/<skillSlug>/ next to it.
+type skillBundleArchive struct {
+	SchemaVersion string              `json:"schemaVersion"`
+	Bundle        archivedSkillBundle `json:"bundle"`
+	Skills        []archivedSkill     `json:"skills"`
+	ExportedAt    time.Time           `json:"exportedAt"`
+}
+
+type archivedSkillBundle struct {
+	Slug             spec.SkillBundleSlug             `json:"slug"`
+	DisplayName      string                           `json:"displayName"`
+	Description      string                           `json:"description,omitempty"`
+	IsEnabled        bool                             `json:"isEnabled"`
+	ActivationPolicy spec.SkillBundleActivationPolicy `json:"activationPolicy,omitempty"`
+}
+
+type archivedSkill struct {
+	Slug        spec.SkillSlug `json:"slug"`
+	Name        string         `json:"name"`
+	DisplayName string         `json:"displayName,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	IsEnabled   bool           `json:"isEnabled"`
+}
+
+// ExportSkillBundle packages a user bundle and the directories of its
+// filesystem skills into a tar.gz that ImportSkillBundle can ingest.
+func (s *SkillStore) ExportSkillBundle(
+	ctx context.Context,
+	req *spec.ExportSkillBundleRequest,
+) (*spec.ExportSkillBundleResponse, error) {
+	if req == nil || req.BundleID == "" {
+		return nil, fmt.Errorf("%w: bundleID required", errSkillInvalidRequest)
+	}
+	if s.builtin != nil {
+		if _, err := s.builtin.GetBuiltInSkillBundle(ctx, req.BundleID); err == nil {
+			return nil, fmt.Errorf("%w: built-in bundle %q cannot be exported", errSkillBuiltInReadOnly, req.BundleID)
+		}
+	}
+
+	s.mu.RLock()
+	sc, err := s.readAllUser(ctx, false)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	bundle, ok := sc.Bundles[req.BundleID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errSkillBundleNotFound, req.BundleID)
+	}
+	if isSoftDeletedSkillBundle(bundle) {
+		return nil, fmt.Errorf("%w: %s", errSkillBundleDeleting, req.BundleID)
+	}
+
+	manifest := skillBundleArchive{
+		SchemaVersion: spec.SkillSchemaVersion,
+		Bundle: archivedSkillBundle{
+			Slug:             bundle.Slug,
+			DisplayName:      bundle.DisplayName,
+			Description:      bundle.Description,
+			IsEnabled:        bundle.IsEnabled,
+			ActivationPolicy: bundle.ActivationPolicy,
+		},
+		ExportedAt: time.Now().UTC(),
+	}
+	skills := sc.Skills[req.BundleID]
+	slugs := slices.Sorted(maps.Keys(skills))
+	for _, slug := range slugs {
+		sk := skills[slug]
+		if sk.Type != spec.SkillTypeFS {
+			continue
+		}
+		manifest.Skills = append(manifest.Skills, archivedSkill{
+			Slug:        sk.Slug,
+			Name:        sk.Name,
+			DisplayName: sk.DisplayName,
+			Description: sk.Description,
+			Tags:        slices.Clone(sk.Tags),
+			IsEnabled:   sk.IsEnabled,
+		})
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	w := &skillBundleArchiveWriter{tw: tw}
+	if err := w.writeFile(skillBundleArchiveManifest, manifestJSON, manifest.ExportedAt); err != nil {
+		return nil, err
+	}
+	for _, as := range manifest.Skills {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		location := skills[as.Slug].Location
+		if err := w.addDir(location, path.Join(skillBundleArchiveSkillsDir, string(as.Slug))); err != nil {
+			return nil, fmt.Errorf("skill %q: %w", as.Slug, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	logger.Info("exportSkillBundle", "bundleID", req.BundleID, "skills", len(manifest.Skills), "bytes", buf.Len())
+	return &spec.ExportSkillBundleResponse{
+		Body: &spec.ExportSkillBundleResponseBody{
+			FileName: string(bundle.Slug) + ".skillbundle.tar.gz",
+			Archive:  buf.Bytes(),
+		},
+	}, nil
+}
+
+// ImportSkillBundle creates a new user bundle from an archive produced by
+// ExportSkillBundle. Skill files are extracted into managed package
+// directories, so imported skills never point at paths from the exporting
+// machine.
+func (s *SkillStore) ImportSkillBundle(
+	ctx context.Context,
+	req *spec.ImportSkillBundleRequest,
+) (resp *spec.ImportSkillBundleResponse, err error) {
+	if req == nil || req.Body == nil || len(req.Body.Archive) == 0 {
+		return nil, fmt.Errorf("%w: archive required", errSkillInvalidRequest)
+	}
+
+	manifest, files, err := readSkillBundleArchive(req.Body.Archive)
+	if err != nil {
+		return nil, err
+	}
+
+	bundleID := req.Body.BundleID
+	if bundleID == "" {
+		id, err := uuidv7filename.NewUUIDv7String()
+		if err != nil {
+			return nil, err
+		}
+		bundleID = bundleitemutils.BundleID(id)
+	}
+	if err := validateManagedPathSegment(string(bundleID), "bundleID"); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+	}
+	if s.builtin != nil {
+		if _, err := s.builtin.GetBuiltInSkillBundle(ctx, bundleID); err == nil {
+			return nil, fmt.Errorf("%w: bundleID %q", errSkillBuiltInReadOnly, bundleID)
+		}
+	}
+
+	slug := manifest.Bundle.Slug
+	if req.Body.Slug != "" {
+		slug = req.Body.Slug
+	}
+	if err := bundleitemutils.ValidateBundleSlug(slug); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	bundle := spec.SkillBundle{
+		SchemaVersion:    spec.SkillSchemaVersion,
+		ID:               bundleID,
+		Slug:             slug,
+		DisplayName:      manifest.Bundle.DisplayName,
+		Description:      manifest.Bundle.Description,
+		IsEnabled:        manifest.Bundle.IsEnabled,
+		ActivationPolicy: manifest.Bundle.ActivationPolicy,
+		IsBuiltIn:        false,
+		CreatedAt:        now,
+		ModifiedAt:       now,
+	}
+	if err := validateSkillBundle(&bundle); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+	}
+
+	type pendingSkill struct {
+		skill spec.Skill
+		files map[string][]byte
+	}
+	pending := make([]pendingSkill, 0, len(manifest.Skills))
+	seen := map[spec.SkillSlug]bool{}
+	for _, as := range manifest.Skills {
+		if err := bundleitemutils.ValidateItemSlug(as.Slug); err != nil {
+			return nil, fmt.Errorf("%w: invalid skillSlug %q", errSkillInvalidRequest, as.Slug)
+		}
+		if seen[as.Slug] {
+			return nil, fmt.Errorf("%w: duplicate skillSlug %q in archive", errSkillInvalidRequest, as.Slug)
+		}
+		seen[as.Slug] = true
+
+		prefix := path.Join(skillBundleArchiveSkillsDir, string(as.Slug)) + "/"
+		skillFiles := map[string][]byte{}
+		for name, data := range files {
+			if rel, ok := strings.CutPrefix(name, prefix); ok {
+				skillFiles[rel] = data
+			}
+		}
+		skillMD, ok := skillFiles[skillMDFileName]
+		if !ok {
+			return nil, fmt.Errorf("%w: skill %q has no %s", errSkillInvalidRequest, as.Slug, skillMDFileName)
+		}
+		document, warnings, err := agentskills.ParseSkillDocument(
+			skillMD,
+			agentskillsSpec.ParseSkillDocumentOptions{ExpectedName: as.Name},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%w: skill %q: %w", errSkillInvalidRequest, as.Slug, err)
+		}
+		location, err := managedSkillPackageLocation(s.baseDir, string(bundleID), document.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+		}
+
+		id, err := uuidv7filename.NewUUIDv7String()
+		if err != nil {
+			return nil, err
+		}
+		sk := spec.Skill{
+			SchemaVersion:   spec.SkillSchemaVersion,
+			ID:              bundleitemutils.ItemID(id),
+			Slug:            as.Slug,
+			Type:            spec.SkillTypeFS,
+			Location:        location,
+			Name:            document.Name,
+			DisplayName:     cmp.Or(as.DisplayName, document.DisplayName),
+			Description:     cmp.Or(as.Description, document.Description),
+			Tags:            slices.Clone(as.Tags),
+			Insert:          document.Insert,
+			Arguments:       append([]spec.SkillArgument(nil), document.Arguments...),
+			RawFrontmatter:  cloneAnyMap(document.RawFrontmatter),
+			RuntimeWarnings: append([]string(nil), warnings...),
+			Presence:        &spec.SkillPresence{Status: spec.SkillPresenceUnknown},
+			IsEnabled:       as.IsEnabled,
+			IsBuiltIn:       false,
+			CreatedAt:       now,
+			ModifiedAt:      now,
+		}
+		if err := validateSkill(&sk); err != nil {
+			return nil, fmt.Errorf("%w: skill %q: %w", errSkillInvalidRequest, as.Slug, err)
+		}
+		pending = append(pending, pendingSkill{skill: sk, files: skillFiles})
+	}
+
+	var createdDirs []string
+	defer func() {
+		if err != nil {
+			for _, dir := range createdDirs {
+				_ = os.RemoveAll(dir)
+			}
+		}
+	}()
+
+	if err := s.withUserWrite(ctx, "importSkillBundle", func(sc *skillStoreSchema) error {
+		createdDirs = createdDirs[:0]
+		if _, exists := sc.Bundles[bundleID]; exists {
+			return fmt.Errorf("%w: bundleID %q", errSkillConflict, bundleID)
+		}
+		if err := s.checkReservedBundleID(ctx, bundleID); err != nil {
+			return err
+		}
+
+		sm := make(map[spec.SkillSlug]spec.Skill, len(pending))
+		for _, p := range pending {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := writeSkillPackageFiles(p.skill.Location, p.files); err != nil {
+				return err
+			}
+			createdDirs = append(createdDirs, p.skill.Location)
+			sm[p.skill.Slug] = p.skill
+		}
+		sc.Bundles[bundleID] = bundle
+		sc.Skills[bundleID] = sm
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	out := make([]spec.Skill, 0, len(pending))
+	for _, p := range pending {
+		out = append(out, cloneSkill(p.skill))
+	}
+	logger.Info("importSkillBundle", "bundleID", bundleID, "skills", len(out))
+	return &spec.ImportSkillBundleResponse{
+		Body: &spec.ImportSkillBundleResponseBody{
+			SkillBundle: cloneBundle(bundle),
+			Skills:      out,
+		},
+	}, nil
+}
+
+type skillBundleArchiveWriter struct {
+	tw    *tar.Writer
+	files int
+	bytes int64
+}
+
+func (w *skillBundleArchiveWriter) writeFile(name string, data []byte, modTime time.Time) error {
+	if err := w.reserve(int64(len(data))); err != nil {
+		return err
+	}
+	if err := w.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+	}); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(data)
+	return err
+}
+
+// addDir archives the regular files below root under prefix. Symlinks and
+// other special files are skipped.
+func (w *skillBundleArchiveWriter) addDir(root, prefix string) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: location is not a directory", errSkillInvalidRequest)
+	}
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return w.writeFile(path.Join(prefix, filepath.ToSlash(rel)), data, fi.ModTime())
+	})
+}
+
+func (w *skillBundleArchiveWriter) reserve(size int64) error {
+	w.files++
+	w.bytes += size
+	if w.files > maxSkillBundleArchiveFiles || w.bytes > maxSkillBundleArchiveBytes {
+		return fmt.Errorf("%w: bundle exceeds %d files or %d bytes",
+			errSkillInvalidRequest, maxSkillBundleArchiveFiles, maxSkillBundleArchiveBytes)
+	}
+	return nil
+}
+
+// readSkillBundleArchive decodes the manifest and regular files of an
+// archive.
+func readSkillBundleArchive(archive []byte) (*skillBundleArchive, map[string][]byte, error) {
+	files, err := readTarGzFiles(archive)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw, ok := files[skillBundleArchiveManifest]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: archive has no %s", errSkillInvalidRequest, skillBundleArchiveManifest)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var manifest skillBundleArchive
+	if err := dec.Decode(&manifest); err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid %s: %w", errSkillInvalidRequest, skillBundleArchiveManifest, err)
+	}
+	if manifest.SchemaVersion != spec.SkillSchemaVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported archive schemaVersion %q",
+			errSkillInvalidRequest, manifest.SchemaVersion)
+	}
+	return &manifest, files, nil
+}
+
+// readTarGzFiles returns the regular files of a tar.gz archive keyed by
+// cleaned slash separated path. Entries that would escape the archive root
+// are rejected.
+func readTarGzFiles(archive []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a gzip archive: %w", errSkillInvalidRequest, err)
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	var total int64
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid archive: %w", errSkillInvalidRequest, err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return nil, fmt.Errorf("%w: unsupported archive entry %q", errSkillInvalidRequest, hdr.Name)
+		}
+		name := path.Clean(hdr.Name)
+		if strings.Contains(hdr.Name, `\`) || !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, fmt.Errorf("%w: unsafe archive path %q", errSkillInvalidRequest, hdr.Name)
+		}
+		if _, dup := files[name]; dup {
+			return nil, fmt.Errorf("%w: duplicate archive path %q", errSkillInvalidRequest, hdr.Name)
+		}
+		if len(files) >= maxSkillBundleArchiveFiles {
+			return nil, fmt.Errorf("%w: archive has more than %d files", errSkillInvalidRequest, maxSkillBundleArchiveFiles)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxSkillBundleArchiveBytes-total+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid archive: %w", errSkillInvalidRequest, err)
+		}
+		total += int64(len(data))
+		if total > maxSkillBundleArchiveBytes {
+			return nil, fmt.Errorf("%w: archive exceeds %d bytes", errSkillInvalidRequest, maxSkillBundleArchiveBytes)
+		}
+		files[name] = data
+	}
+	return files, nil
+}
+
+// writeSkillPackageFiles creates dir and writes files, keyed by slash
+// separated relative paths, below it.
+func writeSkillPackageFiles(dir string, files map[string][]byte) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return err
+	}
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("%w: managed skill directory already exists", errSkillConflict)
+		}
+		return err
+	}
+	for _, rel := range slices.Sorted(maps.Keys(files)) {
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p, files[rel], 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
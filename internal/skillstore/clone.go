@@ -0,0 +1,173 @@
+package skillstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/agentskills-go"
+	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
+	"github.com/flexigpt/mapstore-go/uuidv7filename"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// CloneSkill copies a user skill under a new slug. The clone gets a new ID
+// and no usage. Filesystem skills must be cloned with CopyPackage: a clone
+// sharing the source package would resolve to the same runtime skill.
+func (s *SkillStore) CloneSkill(
+	ctx context.Context,
+	req *spec.CloneSkillRequest,
+) (resp *spec.CloneSkillResponse, err error) {
+	if req == nil || req.Body == nil || req.BundleID == "" || req.SkillSlug == "" {
+		return nil, fmt.Errorf("%w: bundleID, skillSlug and body required", errSkillInvalidRequest)
+	}
+	if err := bundleitemutils.ValidateItemSlug(req.SkillSlug); err != nil {
+		return nil, fmt.Errorf("%w: invalid skillSlug", errSkillInvalidRequest)
+	}
+	if err := bundleitemutils.ValidateItemSlug(req.Body.NewSkillSlug); err != nil {
+		return nil, fmt.Errorf("%w: invalid newSkillSlug", errSkillInvalidRequest)
+	}
+	src, dst := req.BundleID, req.Body.TargetBundleID
+	if dst == "" {
+		dst = src
+	}
+	if src == dst && req.SkillSlug == req.Body.NewSkillSlug {
+		return nil, fmt.Errorf("%w: newSkillSlug must differ from skillSlug", errSkillInvalidRequest)
+	}
+	if s.builtin != nil {
+		for _, id := range []bundleitemutils.BundleID{src, dst} {
+			if _, err := s.builtin.GetBuiltInSkillBundle(ctx, id); err == nil {
+				return nil, fmt.Errorf("%w: bundleID %q", errSkillBuiltInReadOnly, id)
+			}
+		}
+	}
+
+	var createdDir string
+	var created spec.Skill
+	defer func() {
+		if err != nil && createdDir != "" {
+			_ = os.RemoveAll(createdDir)
+		}
+	}()
+
+	if err := s.withUserWrite(ctx, "cloneSkill", func(sc *skillStoreSchema) error {
+		for _, id := range []bundleitemutils.BundleID{src, dst} {
+			b, ok := sc.Bundles[id]
+			if !ok {
+				return fmt.Errorf("%w: %s", errSkillBundleNotFound, id)
+			}
+			if isSoftDeletedSkillBundle(b) {
+				return fmt.Errorf("%w: %s", errSkillBundleDeleting, id)
+			}
+		}
+		source, ok := sc.Skills[src][req.SkillSlug]
+		if !ok {
+			return fmt.Errorf("%w: %s", errSkillNotFound, req.SkillSlug)
+		}
+		if _, exists := sc.Skills[dst][req.Body.NewSkillSlug]; exists {
+			return fmt.Errorf("%w: duplicate skillSlug in bundle", errSkillConflict)
+		}
+
+		uuid, err := uuidv7filename.NewUUIDv7String()
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		sk := cloneSkill(source)
+		sk.ID = bundleitemutils.ItemID(uuid)
+		sk.Slug = req.Body.NewSkillSlug
+		sk.Presence = &spec.SkillPresence{Status: spec.SkillPresenceUnknown}
+		sk.Digest = ""
+		sk.CreatedAt = now
+		sk.ModifiedAt = now
+		if name := strings.TrimSpace(req.Body.DisplayName); name != "" {
+			sk.DisplayName = name
+		}
+
+		switch {
+		case source.Type == spec.SkillTypeFS && !req.Body.CopyPackage:
+			return fmt.Errorf("%w: %q skills must be cloned with copyPackage", errSkillInvalidRequest, spec.SkillTypeFS)
+		case source.Type != spec.SkillTypeFS && req.Body.CopyPackage:
+			return fmt.Errorf("%w: only %q skill packages can be copied", errSkillInvalidRequest, spec.SkillTypeFS)
+		}
+		if req.Body.CopyPackage {
+			name := string(req.Body.NewSkillSlug)
+			location, err := managedSkillPackageLocation(s.baseDir, string(dst), name)
+			if err != nil {
+				return fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+			}
+			document, err := copySkillPackage(source.Location, source.Name, location, name)
+			if err != nil {
+				return err
+			}
+			createdDir = location
+			sk.Location = location
+			sk.Name = document.Name
+			sk.RawFrontmatter = cloneAnyMap(document.RawFrontmatter)
+		}
+		if err := validateSkill(&sk); err != nil {
+			return err
+		}
+
+		if sc.Skills[dst] == nil {
+			sc.Skills[dst] = map[spec.SkillSlug]spec.Skill{}
+		}
+		sc.Skills[dst][sk.Slug] = sk
+		created = sk
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	logger.Info("cloneSkill",
+		"bundleID", src, "skillSlug", req.SkillSlug,
+		"targetBundleID", dst, "newSkillSlug", req.Body.NewSkillSlug,
+		"copyPackage", req.Body.CopyPackage)
+	return &spec.CloneSkillResponse{
+		Body: &spec.CloneSkillResponseBody{Skill: cloneSkill(created)},
+	}, nil
+}
+
+// copySkillPackage copies the package at from to the new directory to and
+// renames the skill in its SKILL.md, which must match the directory name.
+func copySkillPackage(from, fromName, to, toName string) (agentskillsSpec.SkillDocument, error) {
+	raw, err := os.ReadFile(filepath.Join(from, skillMDFileName))
+	if err != nil {
+		return agentskillsSpec.SkillDocument{}, fmt.Errorf("read source package: %w", err)
+	}
+	document, _, err := agentskills.ParseSkillDocument(
+		raw,
+		agentskillsSpec.ParseSkillDocumentOptions{ExpectedName: fromName},
+	)
+	if err != nil {
+		return agentskillsSpec.SkillDocument{}, fmt.Errorf("%w: source %s: %w",
+			errSkillInvalidRequest, skillMDFileName, err)
+	}
+	document.Name = toName
+	skillMD, err := agentskills.MarshalSkillDocument(document)
+	if err != nil {
+		return agentskillsSpec.SkillDocument{}, fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+	}
+
+	if _, err := os.Stat(to); err == nil {
+		return agentskillsSpec.SkillDocument{}, fmt.Errorf(
+			"%w: managed skill directory already exists", errSkillConflict)
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+		return agentskillsSpec.SkillDocument{}, err
+	}
+	if err := os.CopyFS(to, os.DirFS(from)); err != nil {
+		_ = os.RemoveAll(to)
+		return agentskillsSpec.SkillDocument{}, fmt.Errorf("copy skill package: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(to, skillMDFileName), skillMD, 0o600); err != nil {
+		_ = os.RemoveAll(to)
+		return agentskillsSpec.SkillDocument{}, err
+	}
+	return document, nil
+}
@@ -0,0 +1,63 @@
+package skillstore
+
+import (
+	"context"
+	"slices"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// SetSkillConflicts replaces the runtime name conflicts. The skill runtime
+// calls it after every resync of installed skills.
+func (s *SkillStore) SetSkillConflicts(conflicts []spec.SkillConflict) {
+	if s == nil {
+		return
+	}
+	out := make([]spec.SkillConflict, 0, len(conflicts))
+	for _, c := range conflicts {
+		out = append(out, cloneSkillConflict(c))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conflicts = out
+}
+
+// ListSkillConflicts returns the conflicts found by the last runtime resync.
+func (s *SkillStore) ListSkillConflicts(
+	_ context.Context,
+	_ *spec.ListSkillConflictsRequest,
+) (*spec.ListSkillConflictsResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]spec.SkillConflict, 0, len(s.conflicts))
+	for _, c := range s.conflicts {
+		out = append(out, cloneSkillConflict(c))
+	}
+	return &spec.ListSkillConflictsResponse{
+		Body: &spec.ListSkillConflictsResponseBody{Conflicts: out},
+	}, nil
+}
+
+// skillConflict returns the conflict a skill takes part in, if any.
+func (s *SkillStore) skillConflict(
+	bundleID bundleitemutils.BundleID,
+	slug spec.SkillSlug,
+) *spec.SkillConflict {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, c := range s.conflicts {
+		if slices.ContainsFunc(c.Skills, func(ref spec.SkillRef) bool {
+			return ref.BundleID == bundleID && ref.SkillSlug == slug
+		}) {
+			out := cloneSkillConflict(c)
+			return &out
+		}
+	}
+	return nil
+}
+
+func cloneSkillConflict(c spec.SkillConflict) spec.SkillConflict {
+	c.Skills = slices.Clone(c.Skills)
+	return c
+}
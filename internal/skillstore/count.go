@@ -0,0 +1,55 @@
+package skillstore
+
+import (
+	"context"
+
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// CountSkills counts the built-in and user skills ListSkills would return.
+func (s *SkillStore) CountSkills(ctx context.Context, req *spec.CountSkillsRequest) (*spec.CountSkillsResponse, error) {
+	if req == nil {
+		req = &spec.CountSkillsRequest{}
+	}
+	filter := newSkillListFilter(
+		req.BundleIDs, req.Types, req.Inserts, req.Tags, req.IncludeDisabled, req.IncludeMissing,
+	)
+	out := &spec.CountSkillsResponseBody{}
+	count := func(b spec.SkillBundle, sk spec.Skill) {
+		if !filter.skill(b, sk) {
+			return
+		}
+		out.Count++
+		if !b.IsEnabled || !sk.IsEnabled {
+			out.DisabledCount++
+		}
+	}
+
+	if s.builtin != nil {
+		biBundles, biSkills, err := s.builtin.ListBuiltInSkills(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for bid, b := range biBundles {
+			for _, sk := range biSkills[bid] {
+				count(b, sk)
+			}
+		}
+	}
+
+	s.mu.RLock()
+	user, err := s.readAllUser(ctx, false)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	for bid, b := range user.Bundles {
+		if isSoftDeletedSkillBundle(b) {
+			continue
+		}
+		for _, sk := range user.Skills[bid] {
+			count(b, sk)
+		}
+	}
+	return &spec.CountSkillsResponse{Body: out}, nil
+}
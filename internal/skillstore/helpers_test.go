@@ -6,13 +6,14 @@ import (
 	"testing"
 
 	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/featureflag"
 	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
 )
 
-func newTestSkillStore(t *testing.T) *SkillStore {
+func newTestSkillStore(t *testing.T, opts ...SkillStoreOption) *SkillStore {
 	t.Helper()
 
-	s, err := NewSkillStore(t.TempDir())
+	s, err := NewSkillStore(t.TempDir(), opts...)
 	if err != nil {
 		t.Fatalf("NewSkillStore: %v", err)
 	}
@@ -20,6 +21,14 @@ func newTestSkillStore(t *testing.T) *SkillStore {
 	return s
 }
 
+// withoutFeature turns off one feature flag and leaves the others at their
+// defaults.
+func withoutFeature(off featureflag.Name) SkillStoreOption {
+	return WithFeatureGate(featureflag.GateFunc(func(name featureflag.Name) bool {
+		return name != off && featureflag.Enabled(nil, name)
+	}))
+}
+
 func putBundle(t *testing.T, s *SkillStore, bid, slug, displayName string, enabled bool) {
 	t.Helper()
 	_, err := s.PutSkillBundle(t.Context(), &spec.PutSkillBundleRequest{
@@ -89,7 +98,7 @@ func writeAllUserLocked(t *testing.T, s *SkillStore, sc skillStoreSchema) {
 	defer s.mu.Unlock()
 	defer s.writeMu.Unlock()
 
-	if err := s.writeAllUser(sc); err != nil {
+	if err := s.writeAllUser(t.Context(), sc); err != nil {
 		t.Fatalf("writeAllUser: %v", err)
 	}
 }
@@ -101,6 +110,7 @@ func setUserStoreAllLocked(t *testing.T, s *SkillStore, mp map[string]any) {
 	defer s.mu.Unlock()
 	defer s.writeMu.Unlock()
 
+	s.invalidateUserCache()
 	if err := s.userStore.SetAll(mp); err != nil {
 		t.Fatalf("userStore.SetAll: %v", err)
 	}
@@ -112,7 +122,7 @@ func readAllUserLocked(t *testing.T, s *SkillStore, force bool) (skillStoreSchem
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	return s.readAllUser(force)
+	return s.readAllUser(t.Context(), force)
 }
 
 func buildSkillMD(name, desc, body string) []byte {
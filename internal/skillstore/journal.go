@@ -0,0 +1,307 @@
+package skillstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/flexigpt/mapstore-go/jsonencdec"
+
+	"github.com/flexigpt/flexigpt-app/internal/featureflag"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+const (
+	// A write that would grow the journal past this many records compacts
+	// it into the store file instead.
+	userJournalMaxRecords = 512
+	// Quiet period after the last journaled write before compacting.
+	userJournalCompactDelay = 2 * time.Second
+)
+
+// userJournalDepth is how deep below each top-level key of the user store
+// document the journal records changes: one bundle, or one skill of a
+// bundle. Other keys are recorded whole.
+var userJournalDepth = map[string]int{
+	"bundles":         2,
+	"skills":          3,
+	"lastActivatedAt": 3,
+	"usage":           3,
+	"deletedSkills":   3,
+}
+
+// userJournalRecord is one line of the user store journal. It sets or
+// deletes the value at Keys in the store document.
+type userJournalRecord struct {
+	Keys   []string        `json:"keys"`
+	Value  json.RawMessage `json:"value,omitempty"`
+	Delete bool            `json:"delete,omitempty"`
+}
+
+func (s *SkillStore) userJournalPath() string {
+	return filepath.Join(s.baseDir, spec.SkillBundlesJournalFileName)
+}
+
+func (s *SkillStore) journalEnabled() bool {
+	return featureflag.Enabled(s.features, featureflag.JournaledUserWrites)
+}
+
+// journalUserWrite appends the difference between before and after to the
+// journal. It falls back to rewriting the store file when the journal would
+// grow too long. Caller must hold writeMu and mu.
+func (s *SkillStore) journalUserWrite(ctx context.Context, before map[string]any, after skillStoreSchema) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	after.SchemaVersion = spec.SkillSchemaVersion
+	afterMap, err := jsonencdec.StructWithJSONTagsToMap(after)
+	if err != nil {
+		return err
+	}
+	records, err := diffUserStoreMaps(before, afterMap)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	if len(s.journal)+len(records) > userJournalMaxRecords {
+		return s.writeAllUser(ctx, after)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	err = s.writeGuard.Write(func() error {
+		f, err := os.OpenFile(s.userJournalPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(buf.Bytes()); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			_ = f.Close()
+			return err
+		}
+		return f.Close()
+	})
+	if err != nil {
+		return err
+	}
+	s.journal = append(s.journal, records...)
+	s.invalidateUserCache()
+	s.kickJournalCompaction()
+	return nil
+}
+
+// clearUserJournal drops the journal once its records are in the store file
+// or must not be applied anymore. Caller must hold mu for writing.
+func (s *SkillStore) clearUserJournal() {
+	s.journal = nil
+	s.invalidateUserCache()
+	if err := os.Remove(s.userJournalPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warn("remove skill store journal", "err", err)
+	}
+}
+
+// compactUserJournal rewrites the store file with the journal applied.
+func (s *SkillStore) compactUserJournal(ctx context.Context) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.journal) == 0 {
+		return nil
+	}
+	all, err := s.readAllUser(ctx, true)
+	if err != nil {
+		return err
+	}
+	n := len(s.journal)
+	if err := s.writeAllUser(ctx, all); err != nil {
+		return err
+	}
+	logger.Debug("compacted skill store journal", "records", n)
+	return nil
+}
+
+// recoverUserJournal applies a journal left by a previous run that did not
+// compact it. A torn last line from an interrupted append is dropped.
+func (s *SkillStore) recoverUserJournal(ctx context.Context) error {
+	raw, err := os.ReadFile(s.userJournalPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var records []userJournalRecord
+	sc := bufio.NewScanner(bytes.NewReader(raw))
+	sc.Buffer(make([]byte, 0, 64*1024), len(raw)+1)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record userJournalRecord
+		if err := json.Unmarshal(line, &record); err != nil || len(record.Keys) == 0 {
+			logger.Warn("skill store journal: dropping unreadable record", "err", err)
+			break
+		}
+		records = append(records, record)
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.journal = records
+	if len(records) == 0 {
+		s.clearUserJournal()
+		return nil
+	}
+	all, err := s.readAllUser(ctx, true)
+	if err != nil {
+		return fmt.Errorf("skill store journal: %w", err)
+	}
+	if err := s.writeAllUser(ctx, all); err != nil {
+		return err
+	}
+	logger.Info("skill store journal recovered", "records", len(records))
+	return nil
+}
+
+func (s *SkillStore) startJournalCompactLoop() {
+	if s.cleanCtx == nil {
+		return
+	}
+	s.journalKick = make(chan struct{}, 1)
+	s.wg.Go(func() {
+		for {
+			select {
+			case <-s.cleanCtx.Done():
+				return
+			case <-s.journalKick:
+			}
+			// Wait for writes to go quiet.
+			timer := time.NewTimer(userJournalCompactDelay)
+		quiet:
+			for {
+				select {
+				case <-s.cleanCtx.Done():
+					timer.Stop()
+					return
+				case <-s.journalKick:
+					timer.Reset(userJournalCompactDelay)
+				case <-timer.C:
+					break quiet
+				}
+			}
+			if err := s.compactUserJournal(s.cleanCtx); err != nil {
+				logger.Error("compact skill store journal", "err", err)
+			}
+		}
+	})
+}
+
+func (s *SkillStore) kickJournalCompaction() {
+	if s.journalKick == nil {
+		return
+	}
+	select {
+	case s.journalKick <- struct{}{}:
+	default:
+	}
+}
+
+// diffUserStoreMaps lists the records turning the store document before
+// into after, at the depth given by userJournalDepth.
+func diffUserStoreMaps(before, after map[string]any) ([]userJournalRecord, error) {
+	var records []userJournalRecord
+	var walk func(keys []string, before, after map[string]any, depth int) error
+	walk = func(keys []string, before, after map[string]any, depth int) error {
+		for _, k := range slices.Sorted(maps.Keys(before)) {
+			if _, ok := after[k]; !ok {
+				records = append(records, userJournalRecord{Keys: append(slices.Clone(keys), k), Delete: true})
+			}
+		}
+		for _, k := range slices.Sorted(maps.Keys(after)) {
+			value := after[k]
+			prev, ok := before[k]
+			if ok && reflect.DeepEqual(prev, value) {
+				continue
+			}
+			d := depth - 1
+			if len(keys) == 0 {
+				d = userJournalDepth[k] - 1
+			}
+			prevMap, prevIsMap := prev.(map[string]any)
+			valueMap, valueIsMap := value.(map[string]any)
+			if ok && prevIsMap && valueIsMap && d > 0 {
+				if err := walk(append(slices.Clone(keys), k), prevMap, valueMap, d); err != nil {
+					return err
+				}
+				continue
+			}
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			records = append(records, userJournalRecord{Keys: append(slices.Clone(keys), k), Value: raw})
+		}
+		return nil
+	}
+	if err := walk(nil, before, after, 0); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// applyUserJournal replays records on the store document data in place.
+func applyUserJournal(data map[string]any, records []userJournalRecord) error {
+	for _, record := range records {
+		parent := data
+		for _, k := range record.Keys[:len(record.Keys)-1] {
+			next, ok := parent[k].(map[string]any)
+			if !ok {
+				if record.Delete {
+					parent = nil
+					break
+				}
+				next = map[string]any{}
+				parent[k] = next
+			}
+			parent = next
+		}
+		last := record.Keys[len(record.Keys)-1]
+		if record.Delete {
+			if parent != nil {
+				delete(parent, last)
+			}
+			continue
+		}
+		var value any
+		if err := json.Unmarshal(record.Value, &value); err != nil {
+			return fmt.Errorf("skill store journal %v: %w", record.Keys, err)
+		}
+		parent[last] = value
+	}
+	return nil
+}
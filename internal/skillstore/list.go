@@ -2,12 +2,9 @@ package skillstore
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"slices"
 	"sort"
-	"strings"
-	"time"
 
 	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
 	"github.com/flexigpt/flexigpt-app/internal/jsonutil"
@@ -35,16 +32,24 @@ func (s *SkillStore) ListSkills(ctx context.Context, req *spec.ListSkillsRequest
 		slices.Sort(tok.Inserts)
 		tok.Tags = slices.Clone(req.Tags)
 		sort.Strings(tok.Tags)
+		tok.OrderBy = req.OrderBy
+	}
+	if !isValidSkillListOrder(tok.OrderBy) {
+		return nil, fmt.Errorf("%w: unknown orderBy %q", errSkillInvalidRequest, tok.OrderBy)
 	}
 
 	if tok.Phase == "" {
-		if s.builtin != nil {
+		switch {
+		case req != nil && req.Merged:
+			tok.Phase = spec.ListSkillPhaseMerged
+		case s.builtin != nil:
 			tok.Phase = spec.ListSkillPhaseBuiltIn
-		} else {
+		default:
 			tok.Phase = spec.ListSkillPhaseUser
 		}
 	}
-	if tok.Phase != spec.ListSkillPhaseBuiltIn && tok.Phase != spec.ListSkillPhaseUser {
+	if tok.Phase != spec.ListSkillPhaseBuiltIn && tok.Phase != spec.ListSkillPhaseUser &&
+		tok.Phase != spec.ListSkillPhaseMerged {
 		return nil, fmt.Errorf("%w: invalid phase", errSkillInvalidRequest)
 	}
 	// Defensive: if built-ins are not configured, never stay in built-in phase.
@@ -57,153 +62,73 @@ func (s *SkillStore) ListSkills(ctx context.Context, req *spec.ListSkillsRequest
 		pageSize = skillsDefaultPageSize
 	}
 
-	bFilter := map[bundleitemutils.BundleID]struct{}{}
-	for _, id := range tok.BundleIDs {
-		bFilter[id] = struct{}{}
-	}
-	tFilter := map[spec.SkillType]struct{}{}
-	for _, ty := range tok.Types {
-		tFilter[ty] = struct{}{}
-	}
-	iFilter := map[spec.SkillInsert]struct{}{}
-	for _, in := range tok.Inserts {
-		if in != "" {
-			iFilter[in] = struct{}{}
-		}
-	}
-	tagFilter := map[string]struct{}{}
-	for _, tag := range tok.Tags {
-		tagFilter[tag] = struct{}{}
-	}
+	filter := newSkillListFilter(
+		tok.BundleIDs, tok.Types, tok.Inserts, tok.Tags, tok.IncludeDisabled, tok.IncludeMissing,
+	)
 
-	include := func(bundle spec.SkillBundle, sk spec.Skill) bool {
-		if len(bFilter) > 0 {
-			if _, ok := bFilter[bundle.ID]; !ok {
-				return false
-			}
-		}
-		if len(tFilter) > 0 {
-			if _, ok := tFilter[sk.Type]; !ok {
-				return false
-			}
-		}
-		insert := sk.Insert
-		if insert == "" {
-			insert = spec.SkillInsertInstructions
-		}
-		if len(iFilter) > 0 {
-			if _, ok := iFilter[insert]; !ok {
-				return false
-			}
+	out := make([]spec.SkillListItem, 0, pageSize)
+	// True when we switched phases to "user" but couldn't scan users in this call
+	// (because the page filled on the last built-in item).
+	pendingUserScan := false
+
+	// Built-ins and users in one stream (PAGED).
+	if tok.Phase == spec.ListSkillPhaseMerged {
+		s.mu.RLock()
+		user, err := s.readAllUser(ctx, false)
+		s.mu.RUnlock()
+		if err != nil {
+			return nil, err
 		}
-		if !tok.IncludeDisabled && (!bundle.IsEnabled || !sk.IsEnabled) {
-			return false
+		items, err := s.builtInSkillItems(ctx, filter)
+		if err != nil {
+			return nil, err
 		}
-		if !tok.IncludeMissing && sk.Presence != nil && sk.Presence.Status == spec.SkillPresenceMissing {
-			return false
+		items = append(items, s.userSkillItems(user, filter)...)
+
+		order := resolveSkillListOrder(tok.OrderBy, spec.ListSkillPhaseMerged)
+		page, next, err := pageSkillItems(order, items, tok.DirTok, pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad cursor", errSkillInvalidRequest)
 		}
-		if len(tagFilter) > 0 {
-			hit := false
-			for _, tag := range sk.Tags {
-				if _, ok := tagFilter[tag]; ok {
-					hit = true
-					break
-				}
-			}
-			if !hit {
-				return false
+		for _, it := range page {
+			it.SkillDefinition = cloneSkill(it.SkillDefinition)
+			if it.IsBuiltIn {
+				it.Usage = user.skillUsage(it.BundleID, it.SkillSlug)
+				it.Conflict = s.skillConflict(it.BundleID, it.SkillSlug)
 			}
+			out = append(out, it)
 		}
-		return true
+		tok.DirTok = next
 	}
 
-	out := make([]spec.SkillListItem, 0, pageSize)
-	// True when we switched phases to "user" but couldn't scan users in this call
-	// (because the page filled on the last built-in item).
-	pendingUserScan := false
-
 	// Built-ins (PAGED).
 	if tok.Phase == spec.ListSkillPhaseBuiltIn && s.builtin != nil && len(out) < pageSize {
-		biBundles, biSkills, err := s.builtin.ListBuiltInSkills(ctx)
+		biItems, err := s.builtInSkillItems(ctx, filter)
 		if err != nil {
 			return nil, err
 		}
-
-		// Cursor is bundleID|skillSlug (lexicographic in this ordering).
-		var curBid bundleitemutils.BundleID
-		var curSlug spec.SkillSlug
-		if tok.BuiltInCursor != "" {
-			parts := strings.Split(tok.BuiltInCursor, "|")
-			if len(parts) != 2 {
-				return nil, fmt.Errorf("%w: bad built-in cursor", errSkillInvalidRequest)
-			}
-			curBid = bundleitemutils.BundleID(parts[0])
-			curSlug = spec.SkillSlug(parts[1])
+		// Usage of built-ins lives in the user store.
+		s.mu.RLock()
+		user, err := s.readAllUser(ctx, false)
+		s.mu.RUnlock()
+		if err != nil {
+			return nil, err
 		}
 
-		bids := make([]bundleitemutils.BundleID, 0, len(biBundles))
-		for bid := range biBundles {
-			bids = append(bids, bid)
+		order := resolveSkillListOrder(tok.OrderBy, spec.ListSkillPhaseBuiltIn)
+		page, next, err := pageSkillItems(order, biItems, tok.BuiltInCursor, pageSize-len(out))
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad built-in cursor", errSkillInvalidRequest)
 		}
-		slices.Sort(bids)
-
-		moreBuiltins := false
-		var lastBuiltInCursor string
-
-	emitBuiltins:
-		for _, bid := range bids {
-			b := biBundles[bid]
-			if len(bFilter) > 0 {
-				if _, ok := bFilter[bid]; !ok {
-					continue
-				}
-			}
-			if !tok.IncludeDisabled && !b.IsEnabled {
-				continue
-			}
-
-			sm := biSkills[bid]
-			slugs := make([]spec.SkillSlug, 0, len(sm))
-			for slug := range sm {
-				slugs = append(slugs, slug)
-			}
-			slices.Sort(slugs)
-
-			for _, slug := range slugs {
-				// Seek “strictly after” the cursor in (bid asc, slug asc).
-				if tok.BuiltInCursor != "" {
-					if bid < curBid || (bid == curBid && slug <= curSlug) {
-						continue
-					}
-				}
-
-				sk := sm[slug]
-				if !include(b, sk) {
-					continue
-				}
-
-				// Fill the page; once full, keep scanning until we find *one more includable*
-				// built-in item to prove there's another page.
-				if len(out) < pageSize {
-					out = append(out, spec.SkillListItem{
-						BundleID:        b.ID,
-						BundleSlug:      b.Slug,
-						SkillSlug:       sk.Slug,
-						IsBuiltIn:       true,
-						SkillDefinition: cloneSkill(sk),
-					})
-					lastBuiltInCursor = string(bid) + "|" + string(slug)
-					continue
-				}
-				// We already filled the page, and found another includable item => definitely more.
-				moreBuiltins = true
-				break emitBuiltins
-			}
+		for _, it := range page {
+			it.SkillDefinition = cloneSkill(it.SkillDefinition)
+			it.Usage = user.skillUsage(it.BundleID, it.SkillSlug)
+			it.Conflict = s.skillConflict(it.BundleID, it.SkillSlug)
+			out = append(out, it)
 		}
 
-		if moreBuiltins {
-			tok.Phase = spec.ListSkillPhaseBuiltIn
-			tok.BuiltInCursor = lastBuiltInCursor
+		if next != "" {
+			tok.BuiltInCursor = next
 		} else {
 			// Built-ins exhausted; move to users.
 			tok.Phase = spec.ListSkillPhaseUser
@@ -220,101 +145,29 @@ func (s *SkillStore) ListSkills(ctx context.Context, req *spec.ListSkillsRequest
 	// Users (paged) - only if we still need more items.
 	if tok.Phase == spec.ListSkillPhaseUser && len(out) < pageSize {
 		s.mu.RLock()
-		user, err := s.readAllUser(false)
+		cache, err := s.readUserCache(ctx)
 		s.mu.RUnlock()
 		if err != nil {
 			return nil, err
 		}
 
-		userItems := make([]spec.SkillListItem, 0)
-
-		for bid, b := range user.Bundles {
-			if isSoftDeletedSkillBundle(b) {
-				continue
-			}
-			if len(bFilter) > 0 {
-				if _, ok := bFilter[bid]; !ok {
-					continue
-				}
-			}
-			if !tok.IncludeDisabled && !b.IsEnabled {
-				continue
-			}
-
-			sm := user.Skills[bid]
-			for _, sk := range sm {
-				if include(b, sk) {
-					userItems = append(userItems, spec.SkillListItem{
-						BundleID:        b.ID,
-						BundleSlug:      b.Slug,
-						SkillSlug:       sk.Slug,
-						IsBuiltIn:       false,
-						SkillDefinition: sk,
-					})
-				}
-			}
-		}
-
-		sort.Slice(userItems, func(i, j int) bool {
-			a := userItems[i].SkillDefinition
-			b := userItems[j].SkillDefinition
-			if a.ModifiedAt.Equal(b.ModifiedAt) {
-				if userItems[i].BundleID == userItems[j].BundleID {
-					return userItems[i].SkillSlug < userItems[j].SkillSlug
-				}
-				return userItems[i].BundleID < userItems[j].BundleID
-			}
-			return a.ModifiedAt.After(b.ModifiedAt)
-		})
-
-		start := 0
-		if tok.DirTok != "" {
-			c, err := parseSkillCursor(tok.DirTok)
-			if err != nil {
-				return nil, fmt.Errorf("%w: bad cursor", errSkillInvalidRequest)
-			}
-			// Seek strictly after cursor in ordering:
-			// (ModifiedAt desc, BundleID asc, SkillSlug asc).
-			start = sort.Search(len(userItems), func(i int) bool {
-				it := userItems[i]
-				mt := it.SkillDefinition.ModifiedAt
-				if mt.Before(c.ModTime) {
-					return true
-				}
-				if mt.Equal(c.ModTime) {
-					if it.BundleID > c.BundleID {
-						return true
-					}
-					return it.BundleID == c.BundleID && it.SkillSlug > c.SkillSlug
-				}
-				return false
-			})
-		}
-
-		need := pageSize - len(out)
-		end := min(start+need, len(userItems))
-
-		for i := start; i < end; i++ {
-			// Ensure deep clone of nested pointers/slices.
-			userItems[i].SkillDefinition = cloneSkill(userItems[i].SkillDefinition)
-			out = append(out, userItems[i])
-		}
-
-		if end < len(userItems) {
-			last := userItems[end-1]
-			tok.DirTok = buildSkillCursor(last.BundleID, last.SkillSlug, last.SkillDefinition.ModifiedAt)
-		} else {
-			tok.DirTok = ""
+		order := resolveSkillListOrder(tok.OrderBy, spec.ListSkillPhaseUser)
+		page, next, err := s.pageUserSkills(cache, order, filter, tok.DirTok, pageSize-len(out))
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad cursor", errSkillInvalidRequest)
 		}
+		out = append(out, page...)
+		tok.DirTok = next
 	}
 
 	var nextTok *string
 	// More pages exist if:
 	// - more built-ins exist (cursor is set), or
-	// - more users exist (DirTok set), or
+	// - more users or merged items exist (DirTok set), or
 	// - we switched to user phase but could not scan users in this call (page filled on last built-in).
 	if (tok.Phase == spec.ListSkillPhaseBuiltIn && tok.BuiltInCursor != "") ||
-		(tok.Phase == spec.ListSkillPhaseUser && (tok.DirTok != "" || pendingUserScan)) {
+		(tok.Phase == spec.ListSkillPhaseUser && (tok.DirTok != "" || pendingUserScan)) ||
+		(tok.Phase == spec.ListSkillPhaseMerged && tok.DirTok != "") {
 		s := jsonutil.Base64JSONEncode(tok)
 		nextTok = &s
 	}
@@ -327,30 +180,185 @@ func (s *SkillStore) ListSkills(ctx context.Context, req *spec.ListSkillsRequest
 	}, nil
 }
 
-type skillCursor struct {
-	ModTime   time.Time
-	BundleID  bundleitemutils.BundleID
-	SkillSlug spec.SkillSlug
+// builtInSkillItems returns the built-in skills matching filter, without
+// usage or conflicts.
+func (s *SkillStore) builtInSkillItems(ctx context.Context, filter skillListFilter) ([]spec.SkillListItem, error) {
+	if s.builtin == nil {
+		return nil, nil
+	}
+	biBundles, biSkills, err := s.builtin.ListBuiltInSkills(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]spec.SkillListItem, 0)
+	for bid, b := range biBundles {
+		if !filter.bundle(b) {
+			continue
+		}
+		for _, sk := range biSkills[bid] {
+			if filter.skill(b, sk) {
+				items = append(items, spec.SkillListItem{
+					BundleID:        b.ID,
+					BundleSlug:      b.Slug,
+					SkillSlug:       sk.Slug,
+					IsBuiltIn:       true,
+					SkillDefinition: sk,
+				})
+			}
+		}
+	}
+	return items, nil
+}
+
+// userSkillItems returns the user skills matching filter, skipping
+// soft-deleted bundles.
+func (s *SkillStore) userSkillItems(user skillStoreSchema, filter skillListFilter) []spec.SkillListItem {
+	items := make([]spec.SkillListItem, 0)
+	for bid, b := range user.Bundles {
+		if isSoftDeletedSkillBundle(b) || !filter.bundle(b) {
+			continue
+		}
+		for _, sk := range user.Skills[bid] {
+			if filter.skill(b, sk) {
+				items = append(items, spec.SkillListItem{
+					BundleID:        b.ID,
+					BundleSlug:      b.Slug,
+					SkillSlug:       sk.Slug,
+					IsBuiltIn:       false,
+					SkillDefinition: sk,
+					Usage:           user.skillUsage(b.ID, sk.Slug),
+					Conflict:        s.skillConflict(b.ID, sk.Slug),
+				})
+			}
+		}
+	}
+	return items
 }
 
-func buildSkillCursor(bid bundleitemutils.BundleID, slug spec.SkillSlug, t time.Time) string {
-	// Stable, opaque-ish cursor encoded as a plain string inside tok.DirTok.
-	// DirTok itself is wrapped in Base64 JSON token anyway.
-	return fmt.Sprintf("%s|%s|%s", t.Format(time.RFC3339Nano), bid, slug)
+// skillListFilter holds the ListSkills filters. Empty sets match everything.
+type skillListFilter struct {
+	bundleIDs       map[bundleitemutils.BundleID]struct{}
+	types           map[spec.SkillType]struct{}
+	inserts         map[spec.SkillInsert]struct{}
+	tags            map[string]struct{}
+	includeDisabled bool
+	includeMissing  bool
 }
 
-func parseSkillCursor(s string) (skillCursor, error) {
-	parts := strings.Split(s, "|")
-	if len(parts) != 3 {
-		return skillCursor{}, errors.New("bad cursor")
+func newSkillListFilter(
+	bundleIDs []bundleitemutils.BundleID,
+	types []spec.SkillType,
+	inserts []spec.SkillInsert,
+	tags []string,
+	includeDisabled, includeMissing bool,
+) skillListFilter {
+	f := skillListFilter{
+		bundleIDs:       map[bundleitemutils.BundleID]struct{}{},
+		types:           map[spec.SkillType]struct{}{},
+		inserts:         map[spec.SkillInsert]struct{}{},
+		tags:            map[string]struct{}{},
+		includeDisabled: includeDisabled,
+		includeMissing:  includeMissing,
 	}
-	t, err := time.Parse(time.RFC3339Nano, parts[0])
-	if err != nil {
-		return skillCursor{}, err
+	for _, id := range bundleIDs {
+		f.bundleIDs[id] = struct{}{}
 	}
-	return skillCursor{
-		ModTime:   t,
-		BundleID:  bundleitemutils.BundleID(parts[1]),
-		SkillSlug: spec.SkillSlug(parts[2]),
-	}, nil
+	for _, ty := range types {
+		f.types[ty] = struct{}{}
+	}
+	for _, in := range inserts {
+		if in != "" {
+			f.inserts[in] = struct{}{}
+		}
+	}
+	for _, tag := range tags {
+		f.tags[tag] = struct{}{}
+	}
+	return f
+}
+
+// bundle reports whether skills of bundle can match at all.
+func (f skillListFilter) bundle(bundle spec.SkillBundle) bool {
+	if len(f.bundleIDs) > 0 {
+		if _, ok := f.bundleIDs[bundle.ID]; !ok {
+			return false
+		}
+	}
+	return f.includeDisabled || bundle.IsEnabled
+}
+
+func (f skillListFilter) skill(bundle spec.SkillBundle, sk spec.Skill) bool {
+	if !f.bundle(bundle) {
+		return false
+	}
+	if len(f.types) > 0 {
+		if _, ok := f.types[sk.Type]; !ok {
+			return false
+		}
+	}
+	insert := sk.Insert
+	if insert == "" {
+		insert = spec.SkillInsertInstructions
+	}
+	if len(f.inserts) > 0 {
+		if _, ok := f.inserts[insert]; !ok {
+			return false
+		}
+	}
+	if !f.includeDisabled && !sk.IsEnabled {
+		return false
+	}
+	if !f.includeMissing && sk.Presence != nil && sk.Presence.Status == spec.SkillPresenceMissing {
+		return false
+	}
+	if len(f.tags) > 0 {
+		hit := false
+		for _, tag := range sk.Tags {
+			if _, ok := f.tags[tag]; ok {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			return false
+		}
+	}
+	return true
+}
+
+// pageSkillItems sorts items by order and returns up to need items strictly
+// after cursor, with the cursor of the next page or "" on the last one.
+func pageSkillItems(
+	order spec.SkillListOrder,
+	items []spec.SkillListItem,
+	cursor string,
+	need int,
+) (page []spec.SkillListItem, next string, err error) {
+	keys := make([]skillSortKey, len(items))
+	idx := make([]int, len(items))
+	for i, it := range items {
+		keys[i] = skillSortKeyOf(order, it)
+		idx[i] = i
+	}
+	slices.SortFunc(idx, func(a, b int) int { return compareSkillSortKeys(order, keys[a], keys[b]) })
+
+	start := 0
+	if cursor != "" {
+		c, err := parseSkillCursor(order, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		start = sort.Search(len(idx), func(i int) bool {
+			return compareSkillSortKeys(order, keys[idx[i]], c) > 0
+		})
+	}
+	end := min(start+need, len(idx))
+	page = make([]spec.SkillListItem, 0, end-start)
+	for _, i := range idx[start:end] {
+		page = append(page, items[i])
+	}
+	if end < len(idx) && end > start {
+		next = buildSkillCursor(order, keys[idx[end-1]])
+	}
+	return page, next, nil
 }
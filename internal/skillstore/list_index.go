@@ -0,0 +1,110 @@
+package skillstore
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// userStoreCache is a parsed user store document shared by readers. It is
+// never modified once cached; a write replaces it as a whole. The sorted
+// list indexes are derived from it on first use.
+type userStoreCache struct {
+	doc skillStoreSchema
+
+	indexMu sync.Mutex
+	indexes map[spec.SkillListOrder][]skillSortKey
+}
+
+// readUserCache returns the cached user store document, parsing it first if
+// needed. The document must not be modified. Caller must hold mu.
+func (s *SkillStore) readUserCache(ctx context.Context) (*userStoreCache, error) {
+	if cached := s.userCache.Load(); cached != nil {
+		return cached, nil
+	}
+	if _, err := s.readAllUser(ctx, false); err != nil {
+		return nil, err
+	}
+	return s.userCache.Load(), nil
+}
+
+// skillIndex returns the sort keys of all skills in live user bundles,
+// sorted by order.
+func (c *userStoreCache) skillIndex(order spec.SkillListOrder) []skillSortKey {
+	c.indexMu.Lock()
+	defer c.indexMu.Unlock()
+	if index, ok := c.indexes[order]; ok {
+		return index
+	}
+
+	var index []skillSortKey
+	for bid, b := range c.doc.Bundles {
+		if isSoftDeletedSkillBundle(b) {
+			continue
+		}
+		for _, sk := range c.doc.Skills[bid] {
+			index = append(index, skillSortKeyOf(order, spec.SkillListItem{
+				BundleID:        b.ID,
+				BundleSlug:      b.Slug,
+				SkillSlug:       sk.Slug,
+				SkillDefinition: sk,
+			}))
+		}
+	}
+	sort.Slice(index, func(i, j int) bool { return compareSkillSortKeys(order, index[i], index[j]) < 0 })
+	if c.indexes == nil {
+		c.indexes = map[spec.SkillListOrder][]skillSortKey{}
+	}
+	c.indexes[order] = index
+	return index
+}
+
+// pageUserSkills is pageSkillItems over the user skills of the cache. It
+// seeks the cursor in the sorted index and filters only the entries it
+// walks, instead of sorting every matching skill per page.
+func (s *SkillStore) pageUserSkills(
+	cache *userStoreCache,
+	order spec.SkillListOrder,
+	filter skillListFilter,
+	cursor string,
+	need int,
+) (page []spec.SkillListItem, next string, err error) {
+	index := cache.skillIndex(order)
+	start := 0
+	if cursor != "" {
+		c, err := parseSkillCursor(order, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		start = sort.Search(len(index), func(i int) bool {
+			return compareSkillSortKeys(order, index[i], c) > 0
+		})
+	}
+
+	page = make([]spec.SkillListItem, 0, need)
+	for i := start; i < len(index); i++ {
+		key := index[i]
+		b := cache.doc.Bundles[key.BundleID]
+		sk := cache.doc.Skills[key.BundleID][key.SkillSlug]
+		if !filter.skill(b, sk) {
+			continue
+		}
+		if len(page) == need {
+			// Another match exists, so there is a next page.
+			next = buildSkillCursor(order, skillSortKeyOf(order, page[len(page)-1]))
+			break
+		}
+		page = append(page, spec.SkillListItem{
+			BundleID:        b.ID,
+			BundleSlug:      b.Slug,
+			SkillSlug:       sk.Slug,
+			IsBuiltIn:       false,
+			SkillDefinition: cloneSkill(sk),
+			Usage:           cache.doc.skillUsage(b.ID, sk.Slug),
+			Conflict:        s.skillConflict(b.ID, sk.Slug),
+		})
+	}
+	return page, next, nil
+}
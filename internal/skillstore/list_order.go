@@ -0,0 +1,122 @@
+package skillstore
+
+import (
+	"cmp"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// skillListOrderBundleID is the default built-in order. It is not accepted
+// from callers.
+const skillListOrderBundleID spec.SkillListOrder = "bundleID"
+
+func isValidSkillListOrder(o spec.SkillListOrder) bool {
+	switch o {
+	case spec.SkillListOrderDefault,
+		spec.SkillListOrderModifiedAtDesc,
+		spec.SkillListOrderCreatedAtDesc,
+		spec.SkillListOrderNameAsc,
+		spec.SkillListOrderNameDesc,
+		spec.SkillListOrderBundle:
+		return true
+	}
+	return false
+}
+
+// resolveSkillListOrder maps the default order to the concrete order of a
+// listing phase.
+func resolveSkillListOrder(o spec.SkillListOrder, phase spec.ListSkillPhase) spec.SkillListOrder {
+	if o != spec.SkillListOrderDefault {
+		return o
+	}
+	if phase == spec.ListSkillPhaseBuiltIn {
+		return skillListOrderBundleID
+	}
+	return spec.SkillListOrderModifiedAtDesc
+}
+
+// skillSortKey is the part of a list item an order compares. BundleID and
+// SkillSlug break ties so that every order is total.
+type skillSortKey struct {
+	Time      time.Time
+	Text      string
+	BundleID  bundleitemutils.BundleID
+	SkillSlug spec.SkillSlug
+}
+
+func skillSortKeyOf(o spec.SkillListOrder, it spec.SkillListItem) skillSortKey {
+	k := skillSortKey{BundleID: it.BundleID, SkillSlug: it.SkillSlug}
+	switch o {
+	case spec.SkillListOrderModifiedAtDesc:
+		k.Time = it.SkillDefinition.ModifiedAt
+	case spec.SkillListOrderCreatedAtDesc:
+		k.Time = it.SkillDefinition.CreatedAt
+	case spec.SkillListOrderNameAsc, spec.SkillListOrderNameDesc:
+		name := it.SkillDefinition.DisplayName
+		if name == "" {
+			name = it.SkillDefinition.Name
+		}
+		k.Text = strings.ToLower(name)
+	case spec.SkillListOrderBundle:
+		k.Text = string(it.BundleSlug)
+	}
+	return k
+}
+
+func compareSkillSortKeys(o spec.SkillListOrder, a, b skillSortKey) int {
+	c := 0
+	switch o {
+	case spec.SkillListOrderModifiedAtDesc, spec.SkillListOrderCreatedAtDesc:
+		c = b.Time.Compare(a.Time)
+	case spec.SkillListOrderNameDesc:
+		c = cmp.Compare(b.Text, a.Text)
+	case spec.SkillListOrderNameAsc, spec.SkillListOrderBundle:
+		c = cmp.Compare(a.Text, b.Text)
+	}
+	if c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.BundleID, b.BundleID); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.SkillSlug, b.SkillSlug)
+}
+
+// buildSkillCursor encodes the key of the last listed item as
+// "key|bundleID|skillSlug". The key may itself contain "|".
+func buildSkillCursor(o spec.SkillListOrder, k skillSortKey) string {
+	key := k.Text
+	if o == spec.SkillListOrderModifiedAtDesc || o == spec.SkillListOrderCreatedAtDesc {
+		key = k.Time.Format(time.RFC3339Nano)
+	}
+	return key + "|" + string(k.BundleID) + "|" + string(k.SkillSlug)
+}
+
+func parseSkillCursor(o spec.SkillListOrder, s string) (skillSortKey, error) {
+	i := strings.LastIndex(s, "|")
+	if i < 0 {
+		return skillSortKey{}, errors.New("bad cursor")
+	}
+	j := strings.LastIndex(s[:i], "|")
+	if j < 0 {
+		return skillSortKey{}, errors.New("bad cursor")
+	}
+	k := skillSortKey{
+		BundleID:  bundleitemutils.BundleID(s[j+1 : i]),
+		SkillSlug: spec.SkillSlug(s[i+1:]),
+	}
+	if o == spec.SkillListOrderModifiedAtDesc || o == spec.SkillListOrderCreatedAtDesc {
+		t, err := time.Parse(time.RFC3339Nano, s[:j])
+		if err != nil {
+			return skillSortKey{}, err
+		}
+		k.Time = t
+	} else {
+		k.Text = s[:j]
+	}
+	return k, nil
+}
@@ -1,160 +1,490 @@
 package skillstore
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
-	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/flexigpt/flexigpt-app/internal/featureflag"
 	"github.com/flexigpt/flexigpt-app/internal/fsutil"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
 )
 
-const embeddedHydrateDigestFile = ".embeddedfs.sha256"
+const (
+	embeddedHydrateDigestFile   = ".embeddedfs.sha256"
+	embeddedHydrateManifestFile = ".embeddedfs.manifest.json"
+	// Suffix of the directory keeping the files replaced by the last update.
+	embeddedHydrateSnapshotSuffix = ".previous"
+)
+
+func (s *SkillStore) materializeBuiltInEmbeddedFS(ctx context.Context) error {
+	_, _, err := s.updateBuiltInEmbeddedFS(ctx, false)
+	return err
+}
 
-func (s *SkillStore) materializeBuiltInEmbeddedFS(
+// updateBuiltInEmbeddedFS hydrates the embedded skills into
+// embeddedHydrateDir when their digest changed and reports the per-skill
+// diff. The replaced files are kept in a snapshot directory and the update
+// is recorded for GetBuiltInSkillsUpdate. With dryRun nothing is written.
+func (s *SkillStore) updateBuiltInEmbeddedFS(
 	ctx context.Context,
-) (err error) {
+	dryRun bool,
+) (update spec.BuiltInSkillsUpdate, applied bool, err error) {
 	if s == nil || s.builtin == nil || s.builtin.skillsFS == nil {
-		return nil
+		return update, false, nil
 	}
 
 	s.embeddedMaterializeMu.Lock()
 	defer s.embeddedMaterializeMu.Unlock()
 	defer func() {
 		if recovered := recover(); recovered != nil {
-			slog.Error("materialize built-in Skills: panic", "panic", recovered)
+			logger.Error("materialize built-in Skills: panic", "panic", recovered)
 			err = fmt.Errorf("materialize built-in Skills panic: %v", recovered)
 		}
 	}()
 
 	sub, err := fsutil.ResolveFS(s.builtin.skillsFS, s.builtin.skillsDir)
 	if err != nil {
-		return err
+		return update, false, err
 	}
-	digest, err := fsDigestSHA256(sub)
+	scan, err := scanFS(sub)
 	if err != nil {
-		return err
+		return update, false, err
 	}
+	digest := scan.digest()
 	destination := s.embeddedHydrateDir
 	if strings.TrimSpace(destination) == "" {
-		return errors.New("embedded Skill hydration directory is empty")
+		return update, false, errors.New("embedded Skill hydration directory is empty")
 	}
 
 	digestPath := filepath.Join(destination, embeddedHydrateDigestFile)
 	previous, _ := os.ReadFile(digestPath)
-	if strings.TrimSpace(string(previous)) == digest {
-		return nil
+	update = spec.BuiltInSkillsUpdate{
+		FromDigest: strings.TrimSpace(string(previous)),
+		ToDigest:   digest,
+		Changes:    []spec.BuiltInSkillChange{},
 	}
-	if err := os.MkdirAll(filepath.Dir(destination), 0o755); err != nil {
-		return err
+	if update.FromDigest == digest {
+		return update, false, nil
 	}
-	temporary := fmt.Sprintf("%s.tmp-%d", destination, time.Now().UnixNano())
-	_ = os.RemoveAll(temporary)
-	if err := os.MkdirAll(temporary, 0o755); err != nil {
-		return err
+
+	manifest, err := s.builtin.packageDigests(scan)
+	if err != nil {
+		return update, false, err
 	}
-	defer func() { _ = os.RemoveAll(temporary) }()
-	if err := copyFSToDir(sub, temporary); err != nil {
-		return err
+	manifest.Digest = digest
+	manifest.Files = scan.fileDigests()
+	hydrated := false
+	if info, err := os.Stat(destination); err == nil && info.IsDir() {
+		hydrated = true
+		before, err := s.builtin.hydratedPackageDigests(destination)
+		if err != nil {
+			return update, false, err
+		}
+		update.Changes = diffBuiltInPackageDigests(before, manifest.Skills)
 	}
-	if err := os.WriteFile(
-		filepath.Join(temporary, embeddedHydrateDigestFile),
-		[]byte(digest+"\n"),
-		0o600,
-	); err != nil {
-		return err
+	if dryRun {
+		return update, false, nil
 	}
+	manifestRaw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return update, false, err
+	}
+	snapshotDir := destination + embeddedHydrateSnapshotSuffix
 
-	previousDir := ""
-	if _, err := os.Stat(destination); err == nil {
-		previousDir = fmt.Sprintf("%s.old-%d", destination, time.Now().UnixNano())
-		if err := os.Rename(destination, previousDir); err != nil {
-			return err
-		}
+	if err := os.MkdirAll(filepath.Dir(destination), 0o755); err != nil {
+		return update, false, err
 	}
-	if err := os.Rename(temporary, destination); err != nil {
+	if hydrated && featureflag.Enabled(s.features, featureflag.IncrementalHydration) {
+		// File digests are only trusted when the last sync completed.
+		var previousFiles map[string]string
+		if previous, ok := readHydrateManifest(destination); ok && update.FromDigest != "" &&
+			previous.Digest == update.FromDigest {
+			previousFiles = previous.Files
+		}
+		if err := snapshotHydratedDir(destination, snapshotDir); err != nil {
+			return update, false, err
+		}
+		// Drop the digest first so an interrupted sync is redone next start.
+		if err := os.Remove(digestPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return update, false, err
+		}
+		changed, err := syncScanToDir(scan, destination, previousFiles)
+		if err != nil {
+			return update, false, err
+		}
+		if err := os.WriteFile(
+			filepath.Join(destination, embeddedHydrateManifestFile),
+			manifestRaw,
+			0o600,
+		); err != nil {
+			return update, false, err
+		}
+		if err := os.WriteFile(digestPath, []byte(digest+"\n"), 0o600); err != nil {
+			return update, false, err
+		}
+		logger.Info(
+			"incrementally hydrated embedded skills fs",
+			"dir", destination,
+			"digest", digest,
+			"changed", changed,
+		)
+	} else {
+		temporary := fmt.Sprintf("%s.tmp-%d", destination, time.Now().UnixNano())
+		_ = os.RemoveAll(temporary)
+		if err := os.MkdirAll(temporary, 0o755); err != nil {
+			return update, false, err
+		}
+		defer func() { _ = os.RemoveAll(temporary) }()
+		if err := writeScanToDir(scan, temporary); err != nil {
+			return update, false, err
+		}
+		if err := os.WriteFile(
+			filepath.Join(temporary, embeddedHydrateManifestFile),
+			manifestRaw,
+			0o600,
+		); err != nil {
+			return update, false, err
+		}
+		if err := os.WriteFile(
+			filepath.Join(temporary, embeddedHydrateDigestFile),
+			[]byte(digest+"\n"),
+			0o600,
+		); err != nil {
+			return update, false, err
+		}
+
+		previousDir := ""
+		if hydrated {
+			previousDir = fmt.Sprintf("%s.old-%d", destination, time.Now().UnixNano())
+			if err := os.Rename(destination, previousDir); err != nil {
+				return update, false, err
+			}
+		}
+		if err := os.Rename(temporary, destination); err != nil {
+			if previousDir != "" {
+				_ = os.Rename(previousDir, destination)
+			}
+			return update, false, err
+		}
 		if previousDir != "" {
-			_ = os.Rename(previousDir, destination)
+			_ = os.RemoveAll(snapshotDir)
+			if err := os.Rename(previousDir, snapshotDir); err != nil {
+				logger.Warn("keep built-in skills snapshot", "dir", snapshotDir, "err", err)
+				_ = os.RemoveAll(previousDir)
+			}
 		}
+		logger.Info("hydrated embedded skills fs", "dir", destination, "digest", digest)
+	}
+
+	// A first hydration has nothing to compare against and is not recorded.
+	if !hydrated {
+		return update, true, nil
+	}
+	update.UpdatedAt = time.Now().UTC()
+	if info, err := os.Stat(snapshotDir); err == nil && info.IsDir() {
+		update.SnapshotDir = snapshotDir
+	}
+	if err := s.writeBuiltInSkillsUpdate(update); err != nil {
+		logger.Warn("record built-in skills update", "err", err)
+	}
+	logger.Info("built-in skills updated", "from", update.FromDigest, "to", digest, "changes", len(update.Changes))
+	return update, true, nil
+}
+
+// snapshotHydratedDir replaces snapshotDir with a copy of destination.
+func snapshotHydratedDir(destination, snapshotDir string) error {
+	temporary := fmt.Sprintf("%s.tmp-%d", snapshotDir, time.Now().UnixNano())
+	_ = os.RemoveAll(temporary)
+	if err := copyFSToDir(os.DirFS(destination), temporary); err != nil {
+		_ = os.RemoveAll(temporary)
 		return err
 	}
-	if previousDir != "" {
-		_ = os.RemoveAll(previousDir)
+	if err := os.RemoveAll(snapshotDir); err != nil {
+		_ = os.RemoveAll(temporary)
+		return err
 	}
-	slog.Info("hydrated embedded skills fs", "dir", destination, "digest", digest)
-	return nil
+	return os.Rename(temporary, snapshotDir)
 }
 
-func fsDigestSHA256(fsys fs.FS) (string, error) {
-	hash := sha256.New()
-	var paths []string
+// embeddedFSWorkers bounds the goroutines that read, hash and write files
+// while hydrating the embedded skills.
+var embeddedFSWorkers = min(runtime.GOMAXPROCS(0), 8)
+
+// fsFile is a regular file of a scanned tree.
+type fsFile struct {
+	Path    string // slash-separated, relative to the tree root
+	Perm    fs.FileMode
+	Content []byte
+	Digest  string // hex sha256 of Content
+}
+
+// fsScan is a tree read into memory once. Dirs and Files are sorted by path.
+type fsScan struct {
+	Dirs  []string
+	Files []fsFile
+}
+
+// scanFS reads and hashes every file of fsys on a bounded pool of workers.
+func scanFS(fsys fs.FS) (fsScan, error) {
+	var scan fsScan
 	if err := fs.WalkDir(fsys, ".", func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if !entry.IsDir() {
-			paths = append(paths, path)
+		if entry.IsDir() {
+			scan.Dirs = append(scan.Dirs, path)
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		permission := info.Mode().Perm()
+		if permission == 0 {
+			permission = 0o644
 		}
+		scan.Files = append(scan.Files, fsFile{Path: path, Perm: permission})
 		return nil
 	}); err != nil {
-		return "", err
+		return fsScan{}, err
 	}
-	sort.Strings(paths)
-	for _, path := range paths {
-		content, err := fs.ReadFile(fsys, path)
+	sort.Strings(scan.Dirs)
+	sort.Slice(scan.Files, func(i, j int) bool { return scan.Files[i].Path < scan.Files[j].Path })
+
+	err := forEachParallel(len(scan.Files), func(i int) error {
+		file := &scan.Files[i]
+		content, err := fs.ReadFile(fsys, file.Path)
 		if err != nil {
-			return "", err
+			return err
 		}
-		_, _ = io.WriteString(hash, path)
+		sum := sha256.Sum256(content)
+		file.Content = content
+		file.Digest = hex.EncodeToString(sum[:])
+		return nil
+	})
+	if err != nil {
+		return fsScan{}, err
+	}
+	return scan, nil
+}
+
+// digest is the tree digest of the scan, equal to fsDigestSHA256 of the
+// scanned filesystem.
+func (sc fsScan) digest() string {
+	hash := sha256.New()
+	for _, file := range sc.Files {
+		_, _ = io.WriteString(hash, file.Path)
 		_, _ = hash.Write([]byte{0})
-		_, _ = hash.Write(content)
+		_, _ = hash.Write(file.Content)
 		_, _ = hash.Write([]byte{0})
 	}
-	return hex.EncodeToString(hash.Sum(nil)), nil
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// sub returns the part of the scan below the directory location, with paths
+// relative to it.
+func (sc fsScan) sub(location string) (fsScan, error) {
+	location = path.Clean(location)
+	if location == "." {
+		return sc, nil
+	}
+	if _, found := slices.BinarySearch(sc.Dirs, location); !found {
+		return fsScan{}, &fs.PathError{Op: "sub", Path: location, Err: fs.ErrNotExist}
+	}
+	prefix := location + "/"
+	out := fsScan{Dirs: []string{"."}}
+	for _, dir := range sc.Dirs {
+		if rel, ok := strings.CutPrefix(dir, prefix); ok {
+			out.Dirs = append(out.Dirs, rel)
+		}
+	}
+	for _, file := range sc.Files {
+		if rel, ok := strings.CutPrefix(file.Path, prefix); ok {
+			file.Path = rel
+			out.Files = append(out.Files, file)
+		}
+	}
+	return out, nil
+}
+
+// fileDigests maps the path of every scanned file to its digest.
+func (sc fsScan) fileDigests() map[string]string {
+	digests := make(map[string]string, len(sc.Files))
+	for _, file := range sc.Files {
+		digests[file.Path] = file.Digest
+	}
+	return digests
+}
+
+// forEachParallel calls fn for every index in [0, n) on up to
+// embeddedFSWorkers goroutines. It stops handing out indexes after the first
+// error and returns it.
+func forEachParallel(n int, fn func(i int) error) error {
+	var (
+		wg       sync.WaitGroup
+		next     atomic.Int64
+		failed   atomic.Bool
+		errOnce  sync.Once
+		firstErr error
+	)
+	for range min(embeddedFSWorkers, n) {
+		wg.Go(func() {
+			for !failed.Load() {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				if err := fn(i); err != nil {
+					errOnce.Do(func() { firstErr = err })
+					failed.Store(true)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+	return firstErr
+}
+
+func fsDigestSHA256(fsys fs.FS) (string, error) {
+	scan, err := scanFS(fsys)
+	if err != nil {
+		return "", err
+	}
+	return scan.digest(), nil
 }
 
 func copyFSToDir(fsys fs.FS, destination string) error {
-	return fs.WalkDir(fsys, ".", func(path string, entry fs.DirEntry, err error) error {
-		if err != nil {
+	scan, err := scanFS(fsys)
+	if err != nil {
+		return err
+	}
+	return writeScanToDir(scan, destination)
+}
+
+// writeScanToDir creates the scanned directories under destination and then
+// writes the files in parallel.
+func writeScanToDir(scan fsScan, destination string) error {
+	for _, dir := range scan.Dirs {
+		if err := os.MkdirAll(filepath.Join(destination, filepath.FromSlash(dir)), 0o755); err != nil {
 			return err
 		}
-		outputPath := filepath.Join(destination, filepath.FromSlash(path))
-		if entry.IsDir() {
-			return os.MkdirAll(outputPath, 0o755)
+	}
+	return forEachParallel(len(scan.Files), func(i int) error {
+		file := scan.Files[i]
+		return os.WriteFile(filepath.Join(destination, filepath.FromSlash(file.Path)), file.Content, file.Perm)
+	})
+}
+
+// syncFSToDir makes destination mirror fsys, rewriting only files whose
+// content differs and removing entries fsys no longer has. The hydration
+// digest and manifest files are left alone. It returns the number of paths
+// written or removed.
+func syncFSToDir(fsys fs.FS, destination string) (int, error) {
+	scan, err := scanFS(fsys)
+	if err != nil {
+		return 0, err
+	}
+	return syncScanToDir(scan, destination, nil)
+}
+
+// syncScanToDir is syncFSToDir for a scanned tree. A file whose digest in
+// previous matches and whose size on disk is unchanged is skipped without
+// being read; files missing from previous are compared byte by byte.
+func syncScanToDir(scan fsScan, destination string, previous map[string]string) (int, error) {
+	wanted := map[string]bool{embeddedHydrateDigestFile: true, embeddedHydrateManifestFile: true}
+	for _, dir := range scan.Dirs {
+		wanted[filepath.FromSlash(dir)] = true
+		outputPath := filepath.Join(destination, filepath.FromSlash(dir))
+		if info, err := os.Lstat(outputPath); err == nil && !info.IsDir() {
+			if err := os.Remove(outputPath); err != nil {
+				return 0, err
+			}
 		}
-		if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
-			return err
+		if err := os.MkdirAll(outputPath, 0o755); err != nil {
+			return 0, err
 		}
-		info, err := entry.Info()
-		if err != nil {
+	}
+	for _, file := range scan.Files {
+		wanted[filepath.FromSlash(file.Path)] = true
+	}
+
+	var written atomic.Int64
+	err := forEachParallel(len(scan.Files), func(i int) error {
+		file := scan.Files[i]
+		outputPath := filepath.Join(destination, filepath.FromSlash(file.Path))
+		if unchangedOnDisk(file, outputPath, previous) {
+			return nil
+		}
+		if err := os.RemoveAll(outputPath); err != nil {
 			return err
 		}
-		permission := info.Mode().Perm()
-		if permission == 0 {
-			permission = 0o644
+		if err := os.WriteFile(outputPath, file.Content, file.Perm); err != nil {
+			return err
 		}
-		input, err := fsys.Open(path)
+		written.Add(1)
+		return nil
+	})
+	changed := int(written.Load())
+	if err != nil {
+		return changed, err
+	}
+
+	var stale []string
+	err = filepath.WalkDir(destination, func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		defer input.Close()
-		output, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, permission)
+		rel, err := filepath.Rel(destination, path)
 		if err != nil {
 			return err
 		}
-		if _, err := io.Copy(output, input); err != nil {
-			_ = output.Close()
-			return err
+		if rel == "." || wanted[rel] {
+			return nil
+		}
+		stale = append(stale, path)
+		if entry.IsDir() {
+			return fs.SkipDir
 		}
-		return output.Close()
+		return nil
 	})
+	if err != nil {
+		return changed, err
+	}
+	for _, path := range stale {
+		if err := os.RemoveAll(path); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+func unchangedOnDisk(file fsFile, outputPath string, previous map[string]string) bool {
+	info, err := os.Lstat(outputPath)
+	if err != nil || !info.Mode().IsRegular() || info.Size() != int64(len(file.Content)) {
+		return false
+	}
+	if digest, ok := previous[file.Path]; ok {
+		return digest == file.Digest
+	}
+	existing, err := os.ReadFile(outputPath)
+	return err == nil && bytes.Equal(existing, file.Content)
 }
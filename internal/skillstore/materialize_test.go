@@ -1,10 +1,16 @@
 package skillstore
 
 import (
+	"encoding/json"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
 	"testing/fstest"
+
+	"github.com/flexigpt/flexigpt-app/internal/featureflag"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
 )
 
 func TestFSDigestSHA256_StableAndSensitive(t *testing.T) {
@@ -60,3 +66,241 @@ func TestCopyFSToDir_CopiesNestedFiles(t *testing.T) {
 		t.Fatalf("copied content = %q, want %q", content, "hello\n")
 	}
 }
+
+func TestSyncFSToDir_RewritesOnlyChangedAndRemovesStale(t *testing.T) {
+	t.Parallel()
+
+	destination := t.TempDir()
+	initial := fstest.MapFS{
+		"keep.txt":        &fstest.MapFile{Data: []byte("same")},
+		"edit.txt":        &fstest.MapFile{Data: []byte("old")},
+		"gone/stale.txt":  &fstest.MapFile{Data: []byte("stale")},
+		"nested/note.txt": &fstest.MapFile{Data: []byte("note")},
+	}
+	if err := copyFSToDir(initial, destination); err != nil {
+		t.Fatalf("copyFSToDir: %v", err)
+	}
+	digestPath := filepath.Join(destination, embeddedHydrateDigestFile)
+	if err := os.WriteFile(digestPath, []byte("digest\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	next := fstest.MapFS{
+		"keep.txt":        &fstest.MapFile{Data: []byte("same")},
+		"edit.txt":        &fstest.MapFile{Data: []byte("new")},
+		"nested/note.txt": &fstest.MapFile{Data: []byte("note")},
+	}
+	changed, err := syncFSToDir(next, destination)
+	if err != nil {
+		t.Fatalf("syncFSToDir: %v", err)
+	}
+	// edit.txt rewritten and the gone/ directory removed.
+	if changed != 2 {
+		t.Fatalf("changed = %d, want 2", changed)
+	}
+	content, err := os.ReadFile(filepath.Join(destination, "edit.txt"))
+	if err != nil || string(content) != "new" {
+		t.Fatalf("edit.txt = %q, %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(destination, "gone")); !os.IsNotExist(err) {
+		t.Fatalf("stale directory still present: %v", err)
+	}
+	if _, err := os.Stat(digestPath); err != nil {
+		t.Fatalf("digest file removed: %v", err)
+	}
+}
+
+func TestScanFS_SubDigestsAndSkipsUnchanged(t *testing.T) {
+	t.Parallel()
+
+	source := fstest.MapFS{
+		"pkg/SKILL.md":     &fstest.MapFile{Data: []byte("skill")},
+		"pkg/notes/a.txt":  &fstest.MapFile{Data: []byte("a")},
+		"pkg-other/b.txt":  &fstest.MapFile{Data: []byte("b")},
+		"top-level-readme": &fstest.MapFile{Data: []byte("readme")},
+	}
+	scan, err := scanFS(source)
+	if err != nil {
+		t.Fatalf("scanFS: %v", err)
+	}
+	want, err := fsDigestSHA256(source)
+	if err != nil {
+		t.Fatalf("fsDigestSHA256: %v", err)
+	}
+	if got := scan.digest(); got != want {
+		t.Fatalf("scan digest = %q, want %q", got, want)
+	}
+	pkgFS, err := fs.Sub(source, "pkg")
+	if err != nil {
+		t.Fatalf("fs.Sub: %v", err)
+	}
+	wantPkg, err := fsDigestSHA256(pkgFS)
+	if err != nil {
+		t.Fatalf("fsDigestSHA256(pkg): %v", err)
+	}
+	pkg, err := scan.sub("pkg")
+	if err != nil {
+		t.Fatalf("sub: %v", err)
+	}
+	if got := pkg.digest(); got != wantPkg {
+		t.Fatalf("package digest = %q, want %q", got, wantPkg)
+	}
+	if _, err := scan.sub("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("sub(missing) = %v, want ErrNotExist", err)
+	}
+
+	destination := t.TempDir()
+	if err := writeScanToDir(scan, destination); err != nil {
+		t.Fatalf("writeScanToDir: %v", err)
+	}
+	// Same size, different content: a matching recorded digest skips the
+	// file without reading it.
+	tampered := filepath.Join(destination, "pkg", "notes", "a.txt")
+	if err := os.WriteFile(tampered, []byte("x"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	changed, err := syncScanToDir(scan, destination, scan.fileDigests())
+	if err != nil {
+		t.Fatalf("syncScanToDir: %v", err)
+	}
+	if changed != 0 {
+		t.Fatalf("changed = %d, want 0", changed)
+	}
+	changed, err = syncScanToDir(scan, destination, nil)
+	if err != nil {
+		t.Fatalf("syncScanToDir: %v", err)
+	}
+	if content, err := os.ReadFile(tampered); err != nil || changed != 1 || string(content) != "a" {
+		t.Fatalf("changed = %d, content = %q, err = %v", changed, content, err)
+	}
+}
+
+func TestSkillStore_UpdateBuiltInSkills(t *testing.T) {
+	t.Run("incremental", func(t *testing.T) {
+		checkUpdateBuiltInSkills(t, newTestSkillStore(t))
+	})
+	t.Run("full", func(t *testing.T) {
+		checkUpdateBuiltInSkills(t, newTestSkillStore(t, withoutFeature(featureflag.IncrementalHydration)))
+	})
+}
+
+func checkUpdateBuiltInSkills(t *testing.T, s *SkillStore) {
+	t.Helper()
+	ctx := t.Context()
+
+	got, err := s.GetBuiltInSkillsUpdate(ctx, &spec.GetBuiltInSkillsUpdateRequest{})
+	if err != nil {
+		t.Fatalf("GetBuiltInSkillsUpdate: %v", err)
+	}
+	if got.Body.Update != nil {
+		t.Fatalf("first hydration recorded an update: %+v", got.Body.Update)
+	}
+
+	// Pretend the hydrated copy came from an older app version: one skill
+	// had other content, one did not exist and one has since been dropped.
+	manifestPath := filepath.Join(s.embeddedHydrateDir, embeddedHydrateManifestFile)
+	raw, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("ReadFile manifest: %v", err)
+	}
+	var manifest builtInHydrateManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		t.Fatalf("Unmarshal manifest: %v", err)
+	}
+	if len(manifest.Skills) < 2 {
+		t.Fatalf("need at least two built-in skills, got %d", len(manifest.Skills))
+	}
+	changed, added := manifest.Skills[0], manifest.Skills[1]
+	manifest.Skills[0].Digest = "old"
+	manifest.Skills = append(manifest.Skills[:1], manifest.Skills[2:]...)
+	removed := builtInSkillPackageDigest{BundleID: changed.BundleID, SkillSlug: "retired-skill", Digest: "x"}
+	manifest.Skills = append(manifest.Skills, removed)
+	raw, err = json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("Marshal manifest: %v", err)
+	}
+	if err := os.WriteFile(manifestPath, raw, 0o600); err != nil {
+		t.Fatalf("WriteFile manifest: %v", err)
+	}
+	digestPath := filepath.Join(s.embeddedHydrateDir, embeddedHydrateDigestFile)
+	if err := os.WriteFile(digestPath, []byte("old-digest\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile digest: %v", err)
+	}
+
+	want := map[spec.SkillSlug]spec.BuiltInSkillChangeKind{
+		changed.SkillSlug: spec.BuiltInSkillChanged,
+		added.SkillSlug:   spec.BuiltInSkillAdded,
+		removed.SkillSlug: spec.BuiltInSkillRemoved,
+	}
+	checkChanges := func(changes []spec.BuiltInSkillChange) {
+		t.Helper()
+		if len(changes) != len(want) {
+			t.Fatalf("changes = %+v, want %v", changes, want)
+		}
+		for _, c := range changes {
+			if want[c.SkillSlug] != c.Kind {
+				t.Fatalf("change %s = %s, want %s", c.SkillSlug, c.Kind, want[c.SkillSlug])
+			}
+		}
+	}
+
+	dry, err := s.UpdateBuiltInSkills(ctx, &spec.UpdateBuiltInSkillsRequest{
+		Body: &spec.UpdateBuiltInSkillsRequestBody{DryRun: true},
+	})
+	if err != nil {
+		t.Fatalf("UpdateBuiltInSkills(dry run): %v", err)
+	}
+	if dry.Body.Applied || dry.Body.Update.FromDigest != "old-digest" {
+		t.Fatalf("dry run = %+v", dry.Body)
+	}
+	checkChanges(dry.Body.Update.Changes)
+	if content, _ := os.ReadFile(digestPath); string(content) != "old-digest\n" {
+		t.Fatalf("dry run rewrote digest: %q", content)
+	}
+
+	applied, err := s.UpdateBuiltInSkills(ctx, &spec.UpdateBuiltInSkillsRequest{
+		Body: &spec.UpdateBuiltInSkillsRequestBody{},
+	})
+	if err != nil {
+		t.Fatalf("UpdateBuiltInSkills: %v", err)
+	}
+	if !applied.Body.Applied {
+		t.Fatal("update not applied")
+	}
+	checkChanges(applied.Body.Update.Changes)
+	snapshot := applied.Body.Update.SnapshotDir
+	if snapshot == "" {
+		t.Fatal("no snapshot recorded")
+	}
+	if content, err := os.ReadFile(filepath.Join(snapshot, embeddedHydrateDigestFile)); err != nil ||
+		string(content) != "old-digest\n" {
+		t.Fatalf("snapshot digest = %q, %v", content, err)
+	}
+
+	got, err = s.GetBuiltInSkillsUpdate(ctx, &spec.GetBuiltInSkillsUpdateRequest{})
+	if err != nil {
+		t.Fatalf("GetBuiltInSkillsUpdate: %v", err)
+	}
+	if got.Body.Update == nil || got.Body.Update.Seen {
+		t.Fatalf("recorded update = %+v", got.Body.Update)
+	}
+	checkChanges(got.Body.Update.Changes)
+
+	if _, err := s.MarkBuiltInSkillsUpdateSeen(ctx, &spec.MarkBuiltInSkillsUpdateSeenRequest{}); err != nil {
+		t.Fatalf("MarkBuiltInSkillsUpdateSeen: %v", err)
+	}
+	got, err = s.GetBuiltInSkillsUpdate(ctx, &spec.GetBuiltInSkillsUpdateRequest{})
+	if err != nil || got.Body.Update == nil || !got.Body.Update.Seen {
+		t.Fatalf("update after seen = %+v, %v", got, err)
+	}
+
+	again, err := s.UpdateBuiltInSkills(ctx, &spec.UpdateBuiltInSkillsRequest{
+		Body: &spec.UpdateBuiltInSkillsRequestBody{},
+	})
+	if err != nil {
+		t.Fatalf("UpdateBuiltInSkills(again): %v", err)
+	}
+	if again.Body.Applied || len(again.Body.Update.Changes) != 0 {
+		t.Fatalf("second update = %+v", again.Body)
+	}
+}
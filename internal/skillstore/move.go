@@ -0,0 +1,128 @@
+package skillstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// MoveSkill relocates a user skill to another enabled user bundle in one
+// write. A package the app manages for the source bundle is moved to the
+// managed directory of the target bundle.
+func (s *SkillStore) MoveSkill(
+	ctx context.Context,
+	req *spec.MoveSkillRequest,
+) (*spec.MoveSkillResponse, error) {
+	if req == nil || req.Body == nil || req.BundleID == "" || req.SkillSlug == "" ||
+		req.Body.TargetBundleID == "" {
+		return nil, fmt.Errorf("%w: bundleID, skillSlug and targetBundleID required", errSkillInvalidRequest)
+	}
+	if err := bundleitemutils.ValidateItemSlug(req.SkillSlug); err != nil {
+		return nil, fmt.Errorf("%w: invalid skillSlug", errSkillInvalidRequest)
+	}
+	src, dst := req.BundleID, req.Body.TargetBundleID
+	if src == dst {
+		return nil, fmt.Errorf("%w: skill is already in bundle %s", errSkillInvalidRequest, dst)
+	}
+	if s.builtin != nil {
+		for _, id := range []bundleitemutils.BundleID{src, dst} {
+			if _, err := s.builtin.GetBuiltInSkillBundle(ctx, id); err == nil {
+				return nil, fmt.Errorf("%w: bundleID %q", errSkillBuiltInReadOnly, id)
+			}
+		}
+	}
+
+	// Set once the package directory was moved, so a failed write can undo it.
+	var movedFrom, movedTo string
+	err := s.withUserWrite(ctx, "moveSkill", func(snapshot *skillStoreSchema) error {
+		srcBundle, ok := snapshot.Bundles[src]
+		if !ok {
+			return fmt.Errorf("%w: %s", errSkillBundleNotFound, src)
+		}
+		if isSoftDeletedSkillBundle(srcBundle) {
+			return fmt.Errorf("%w: %s", errSkillBundleDeleting, src)
+		}
+		skill, ok := snapshot.Skills[src][req.SkillSlug]
+		if !ok {
+			return fmt.Errorf("%w: %s", errSkillNotFound, req.SkillSlug)
+		}
+		dstBundle, ok := snapshot.Bundles[dst]
+		if !ok {
+			return fmt.Errorf("%w: %s", errSkillBundleNotFound, dst)
+		}
+		if isSoftDeletedSkillBundle(dstBundle) {
+			return fmt.Errorf("%w: %s", errSkillBundleDeleting, dst)
+		}
+		if !dstBundle.IsEnabled {
+			return fmt.Errorf("%w: %s", errSkillBundleDisabled, dst)
+		}
+		if _, exists := snapshot.Skills[dst][req.SkillSlug]; exists {
+			return fmt.Errorf("%w: skillSlug %q in bundle %s", errSkillConflict, req.SkillSlug, dst)
+		}
+
+		if isManagedSkillPackageLocation(s.baseDir, string(src), skill.Name, skill.Location) {
+			target, err := managedSkillPackageLocation(s.baseDir, string(dst), skill.Name)
+			if err != nil {
+				return fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+			}
+			if _, err := os.Stat(target); err == nil {
+				return fmt.Errorf("%w: package %q already exists", errSkillConflict, target)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Rename(skill.Location, target); err != nil {
+				return fmt.Errorf("move skill package: %w", err)
+			}
+			movedFrom, movedTo = skill.Location, target
+			skill.Location = target
+		}
+
+		skill.ModifiedAt = time.Now().UTC()
+		delete(snapshot.Skills[src], req.SkillSlug)
+		if snapshot.Skills[dst] == nil {
+			snapshot.Skills[dst] = map[spec.SkillSlug]spec.Skill{}
+		}
+		snapshot.Skills[dst][req.SkillSlug] = skill
+		snapshot.moveSkillActivity(src, dst, req.SkillSlug)
+		return nil
+	})
+	if err != nil {
+		if movedTo != "" {
+			if rerr := os.Rename(movedTo, movedFrom); rerr != nil {
+				logger.Error("restore moved Skill package failed", "location", movedFrom, "error", rerr)
+			}
+		}
+		return nil, err
+	}
+
+	logger.Info("moveSkill", "bundleID", src, "skillSlug", req.SkillSlug, "targetBundleID", dst)
+	return &spec.MoveSkillResponse{}, nil
+}
+
+// moveSkillActivity rekeys the activation history of a skill to another
+// bundle.
+func (sc *skillStoreSchema) moveSkillActivity(src, dst bundleitemutils.BundleID, slug spec.SkillSlug) {
+	if at, ok := sc.LastActivatedAt[src][slug]; ok {
+		delete(sc.LastActivatedAt[src], slug)
+		if sc.LastActivatedAt[dst] == nil {
+			sc.LastActivatedAt[dst] = map[spec.SkillSlug]time.Time{}
+		}
+		sc.LastActivatedAt[dst][slug] = at
+	}
+	if usage, ok := sc.Usage[src][slug]; ok {
+		delete(sc.Usage[src], slug)
+		if sc.Usage[dst] == nil {
+			sc.Usage[dst] = map[spec.SkillSlug]skillUsageRecord{}
+		}
+		sc.Usage[dst][slug] = usage
+	}
+}
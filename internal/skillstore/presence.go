@@ -0,0 +1,182 @@
+package skillstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// TriggerPresenceCheck stats the SKILL.md of every user filesystem skill and
+// records the outcome in Skill.Presence.
+func (s *SkillStore) TriggerPresenceCheck(
+	ctx context.Context,
+	req *spec.TriggerPresenceCheckRequest,
+) (*spec.TriggerPresenceCheckResponse, error) {
+	var bundleIDs []bundleitemutils.BundleID
+	if req != nil {
+		bundleIDs = req.BundleIDs
+	}
+	body, err := s.checkPresence(ctx, bundleIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &spec.TriggerPresenceCheckResponse{Body: body}, nil
+}
+
+func (s *SkillStore) startPresenceLoop() {
+	if s.cleanCtx == nil {
+		return
+	}
+	s.wg.Go(func() {
+		tick := time.NewTicker(presenceCheckIntervalSkills)
+		defer tick.Stop()
+
+		for {
+			if _, err := s.checkPresence(s.cleanCtx, nil); err != nil && s.cleanCtx.Err() == nil {
+				logger.Error("skill presence check failed", "err", err)
+			}
+			select {
+			case <-s.cleanCtx.Done():
+				return
+			case <-tick.C:
+			}
+		}
+	})
+}
+
+// checkPresence stats packages without holding store locks and then applies
+// the results to skills whose location did not change in the meantime. The
+// store is only written when a skill's presence status changes, so a check
+// that finds everything as before leaves the file alone, and the recorded
+// check and sighting times are those of the last change.
+func (s *SkillStore) checkPresence(
+	ctx context.Context,
+	bundleIDs []bundleitemutils.BundleID,
+) (*spec.TriggerPresenceCheckResponseBody, error) {
+	s.mu.RLock()
+	snapshot, err := s.readAllUser(ctx, false)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		bundleID bundleitemutils.BundleID
+		slug     spec.SkillSlug
+	}
+	type result struct {
+		location string
+		err      error
+	}
+	now := time.Now().UTC()
+	out := &spec.TriggerPresenceCheckResponseBody{CheckedAt: now}
+	changed := map[key]result{}
+	for bid, skills := range snapshot.Skills {
+		if len(bundleIDs) > 0 && !slices.Contains(bundleIDs, bid) {
+			continue
+		}
+		if b, ok := snapshot.Bundles[bid]; !ok || isSoftDeletedSkillBundle(b) {
+			continue
+		}
+		for slug, sk := range skills {
+			if sk.Type != spec.SkillTypeFS {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			r := result{location: sk.Location, err: statSkillPackage(sk.Location)}
+			next := nextPresence(sk.Presence, r.err, now)
+			if presenceChanged(sk.Presence, next) {
+				changed[key{bid, slug}] = r
+			}
+
+			out.Checked++
+			switch next.Status {
+			case spec.SkillPresencePresent:
+				out.Present++
+			case spec.SkillPresenceMissing:
+				out.Missing++
+			default:
+				out.Errored++
+			}
+		}
+	}
+
+	if len(changed) > 0 {
+		if err := s.withUserWrite(ctx, "checkPresence", func(sc *skillStoreSchema) error {
+			for k, r := range changed {
+				sk, ok := sc.Skills[k.bundleID][k.slug]
+				if !ok || sk.Location != r.location {
+					continue
+				}
+				sk.Presence = nextPresence(sk.Presence, r.err, now)
+				sc.Skills[k.bundleID][k.slug] = sk
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if out.Missing > 0 || out.Errored > 0 {
+		logger.Info("skill presence check", "checked", out.Checked, "missing", out.Missing, "errored", out.Errored)
+	}
+	return out, nil
+}
+
+// statSkillPackage returns nil when location holds a SKILL.md file.
+func statSkillPackage(location string) error {
+	st, err := os.Stat(filepath.Join(location, skillMDFileName))
+	if err != nil {
+		return err
+	}
+	if !st.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", skillMDFileName)
+	}
+	return nil
+}
+
+func nextPresence(prev *spec.SkillPresence, statErr error, now time.Time) *spec.SkillPresence {
+	next := clonePresence(prev)
+	if next == nil {
+		next = &spec.SkillPresence{}
+	}
+	next.LastCheckedAt = &now
+
+	switch {
+	case statErr == nil:
+		next.Status = spec.SkillPresencePresent
+		next.LastSeenAt = &now
+		next.MissingSince = nil
+		next.LastCheckError = ""
+	case errors.Is(statErr, os.ErrNotExist):
+		if next.Status != spec.SkillPresenceMissing || next.MissingSince == nil {
+			next.MissingSince = &now
+		}
+		next.Status = spec.SkillPresenceMissing
+		next.LastCheckError = ""
+	default:
+		next.Status = spec.SkillPresenceError
+		next.LastCheckError = statErr.Error()
+	}
+	return next
+}
+
+// presenceChanged reports whether next records a different outcome than prev.
+// Check and sighting times alone do not count.
+func presenceChanged(prev, next *spec.SkillPresence) bool {
+	if prev == nil {
+		return true
+	}
+	return prev.Status != next.Status ||
+		prev.LastCheckError != next.LastCheckError ||
+		(prev.MissingSince == nil) != (next.MissingSince == nil)
+}
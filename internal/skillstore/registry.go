@@ -0,0 +1,330 @@
+package skillstore
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/agentskills-go"
+	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
+	"github.com/flexigpt/mapstore-go/uuidv7filename"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// A skill registry serves index.json below its base URL, listing skills whose
+// packages are tar.gz archives with SKILL.md at the root or inside a single
+// top-level directory.
+const (
+	skillRegistryIndexFile = "index.json"
+
+	maxSkillRegistryIndexBytes = 8 << 20
+)
+
+var skillRegistryHTTPClient = &http.Client{Timeout: 60 * time.Second}
+
+type skillRegistryIndex struct {
+	Skills []spec.RegistrySkill `json:"skills"`
+}
+
+// BrowseRegistrySkills lists the registry index, filtered to entries whose
+// name, display name, description or tags contain every query term.
+func (s *SkillStore) BrowseRegistrySkills(
+	ctx context.Context,
+	req *spec.BrowseRegistrySkillsRequest,
+) (*spec.BrowseRegistrySkillsResponse, error) {
+	if req == nil {
+		req = &spec.BrowseRegistrySkillsRequest{}
+	}
+	base, err := s.skillRegistryBase(req.RegistryURL)
+	if err != nil {
+		return nil, err
+	}
+	index, err := fetchSkillRegistryIndex(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := strings.Fields(strings.ToLower(req.Query))
+	out := make([]spec.RegistrySkill, 0, len(index.Skills))
+	for _, rs := range index.Skills {
+		text := strings.ToLower(strings.Join(
+			append([]string{rs.Name, rs.DisplayName, rs.Description}, rs.Tags...), "\n",
+		))
+		if !slices.ContainsFunc(terms, func(t string) bool { return !strings.Contains(text, t) }) {
+			out = append(out, rs)
+		}
+	}
+	return &spec.BrowseRegistrySkillsResponse{
+		Body: &spec.BrowseRegistrySkillsResponseBody{
+			RegistryURL: base.String(),
+			Skills:      out,
+		},
+	}, nil
+}
+
+// InstallRegistrySkill downloads a registry skill package, verifies its
+// checksum and adds it to a user bundle as a managed filesystem skill.
+func (s *SkillStore) InstallRegistrySkill(
+	ctx context.Context,
+	req *spec.InstallRegistrySkillRequest,
+) (resp *spec.InstallRegistrySkillResponse, err error) {
+	if req == nil || req.Body == nil || req.BundleID == "" || strings.TrimSpace(req.Body.Name) == "" {
+		return nil, fmt.Errorf("%w: bundleID, name and body required", errSkillInvalidRequest)
+	}
+	slug := cmp.Or(req.Body.SkillSlug, spec.SkillSlug(strings.TrimSpace(req.Body.Name)))
+	if err := bundleitemutils.ValidateItemSlug(slug); err != nil {
+		return nil, fmt.Errorf("%w: invalid skillSlug", errSkillInvalidRequest)
+	}
+	if s.builtin != nil {
+		if _, err := s.builtin.GetBuiltInSkillBundle(ctx, req.BundleID); err == nil {
+			return nil, fmt.Errorf("%w: bundleID %q", errSkillBuiltInReadOnly, req.BundleID)
+		}
+	}
+
+	base, err := s.skillRegistryBase(req.Body.RegistryURL)
+	if err != nil {
+		return nil, err
+	}
+	index, err := fetchSkillRegistryIndex(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.Body.Name)
+	i := slices.IndexFunc(index.Skills, func(rs spec.RegistrySkill) bool {
+		return rs.Name == name && (req.Body.Version == "" || rs.Version == req.Body.Version)
+	})
+	if i < 0 {
+		return nil, fmt.Errorf("%w: registry skill %q", errSkillNotFound, name)
+	}
+	rs := index.Skills[i]
+
+	archive, err := downloadRegistrySkillArchive(ctx, base, rs)
+	if err != nil {
+		return nil, err
+	}
+	files, err := readTarGzFiles(archive)
+	if err != nil {
+		return nil, err
+	}
+	files, err = registrySkillPackageFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	document, warnings, err := agentskills.ParseSkillDocument(
+		files[skillMDFileName],
+		agentskillsSpec.ParseSkillDocumentOptions{ExpectedName: rs.Name},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: registry skill %q: %w", errSkillInvalidRequest, rs.Name, err)
+	}
+	location, err := managedSkillPackageLocation(s.baseDir, string(req.BundleID), document.Name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+	}
+
+	var createdDir string
+	var created spec.Skill
+	defer func() {
+		if err != nil && createdDir != "" {
+			_ = os.RemoveAll(createdDir)
+		}
+	}()
+
+	if err := s.withUserWrite(ctx, "installRegistrySkill", func(sc *skillStoreSchema) error {
+		b, ok := sc.Bundles[req.BundleID]
+		if !ok {
+			return fmt.Errorf("%w: %s", errSkillBundleNotFound, req.BundleID)
+		}
+		if isSoftDeletedSkillBundle(b) {
+			return fmt.Errorf("%w: %s", errSkillBundleDeleting, req.BundleID)
+		}
+		sm := sc.Skills[req.BundleID]
+		if sm == nil {
+			sm = map[spec.SkillSlug]spec.Skill{}
+			sc.Skills[req.BundleID] = sm
+		}
+		if _, exists := sm[slug]; exists {
+			return fmt.Errorf("%w: duplicate skillSlug in bundle", errSkillConflict)
+		}
+
+		if err := writeSkillPackageFiles(location, files); err != nil {
+			return err
+		}
+		createdDir = location
+
+		id, err := uuidv7filename.NewUUIDv7String()
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		sk := spec.Skill{
+			SchemaVersion:   spec.SkillSchemaVersion,
+			ID:              bundleitemutils.ItemID(id),
+			Slug:            slug,
+			Type:            spec.SkillTypeFS,
+			Location:        location,
+			Name:            document.Name,
+			DisplayName:     cmp.Or(document.DisplayName, rs.DisplayName),
+			Description:     cmp.Or(document.Description, rs.Description),
+			Tags:            slices.Clone(rs.Tags),
+			Insert:          document.Insert,
+			Arguments:       append([]spec.SkillArgument(nil), document.Arguments...),
+			RawFrontmatter:  cloneAnyMap(document.RawFrontmatter),
+			RuntimeWarnings: append([]string(nil), warnings...),
+			Presence:        &spec.SkillPresence{Status: spec.SkillPresenceUnknown},
+			IsEnabled:       req.Body.IsEnabled,
+			IsBuiltIn:       false,
+			CreatedAt:       now,
+			ModifiedAt:      now,
+		}
+		if err := validateSkill(&sk); err != nil {
+			return fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+		}
+		sm[slug] = sk
+		created = sk
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	logger.Info("installRegistrySkill",
+		"bundleID", req.BundleID, "skillSlug", slug, "name", rs.Name, "version", rs.Version)
+	return &spec.InstallRegistrySkillResponse{
+		Body: &spec.InstallRegistrySkillResponseBody{
+			Skill:         cloneSkill(created),
+			RegistrySkill: rs,
+		},
+	}, nil
+}
+
+func (s *SkillStore) skillRegistryBase(override string) (*url.URL, error) {
+	raw := cmp.Or(strings.TrimSpace(override), s.registryURL)
+	if raw == "" {
+		return nil, fmt.Errorf("%w: %w", errSkillInvalidRequest, errSkillRegistryNotConfigured)
+	}
+	return parseSkillRegistryURL(raw)
+}
+
+// ValidateSkillRegistryURL reports whether raw can serve as a registry base
+// URL, e.g. before it is passed to WithSkillRegistryURL.
+func ValidateSkillRegistryURL(raw string) error {
+	_, err := parseSkillRegistryURL(strings.TrimSpace(raw))
+	return err
+}
+
+// parseSkillRegistryURL validates an http(s) base URL and gives it a trailing
+// slash so index and archive paths resolve below it.
+func parseSkillRegistryURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid registry URL: %w", errSkillInvalidRequest, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: registry URL must be an absolute http or https URL", errSkillInvalidRequest)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	u.RawQuery, u.Fragment = "", ""
+	return u, nil
+}
+
+func fetchSkillRegistryIndex(ctx context.Context, base *url.URL) (*skillRegistryIndex, error) {
+	raw, err := getSkillRegistryResource(ctx, base.JoinPath(skillRegistryIndexFile), maxSkillRegistryIndexBytes)
+	if err != nil {
+		return nil, err
+	}
+	var index skillRegistryIndex
+	if err := json.Unmarshal(raw, &index); err != nil {
+		return nil, fmt.Errorf("invalid registry %s: %w", skillRegistryIndexFile, err)
+	}
+	return &index, nil
+}
+
+func downloadRegistrySkillArchive(
+	ctx context.Context,
+	base *url.URL,
+	rs spec.RegistrySkill,
+) ([]byte, error) {
+	want, err := hex.DecodeString(strings.TrimSpace(rs.SHA256))
+	if err != nil || len(want) != sha256.Size {
+		return nil, fmt.Errorf("%w: registry skill %q has no valid sha256", errSkillInvalidRequest, rs.Name)
+	}
+	ref, err := url.Parse(rs.ArchiveURL)
+	if err != nil || rs.ArchiveURL == "" {
+		return nil, fmt.Errorf("%w: registry skill %q has no valid archiveURL", errSkillInvalidRequest, rs.Name)
+	}
+	archiveURL := base.ResolveReference(ref)
+	if archiveURL.Scheme != "http" && archiveURL.Scheme != "https" {
+		return nil, fmt.Errorf("%w: registry skill %q archiveURL must be http or https",
+			errSkillInvalidRequest, rs.Name)
+	}
+
+	archive, err := getSkillRegistryResource(ctx, archiveURL, maxSkillBundleArchiveBytes)
+	if err != nil {
+		return nil, err
+	}
+	if got := sha256.Sum256(archive); !slices.Equal(got[:], want) {
+		return nil, fmt.Errorf("%w: registry skill %q checksum mismatch", errSkillInvalidRequest, rs.Name)
+	}
+	return archive, nil
+}
+
+func getSkillRegistryResource(ctx context.Context, u *url.URL, limit int64) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := skillRegistryHTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry GET %s: %s", u.Redacted(), resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("registry GET %s: response exceeds %d bytes", u.Redacted(), limit)
+	}
+	return data, nil
+}
+
+// registrySkillPackageFiles returns the package files rooted at SKILL.md,
+// unwrapping a single top-level directory.
+func registrySkillPackageFiles(files map[string][]byte) (map[string][]byte, error) {
+	if _, ok := files[skillMDFileName]; ok {
+		return files, nil
+	}
+	var top string
+	for name := range files {
+		dir, _, ok := strings.Cut(name, "/")
+		if !ok || (top != "" && dir != top) {
+			return nil, fmt.Errorf("%w: skill archive has no %s", errSkillInvalidRequest, skillMDFileName)
+		}
+		top = dir
+	}
+	out := make(map[string][]byte, len(files))
+	for name, data := range files {
+		out[strings.TrimPrefix(name, top+"/")] = data
+	}
+	if _, ok := out[skillMDFileName]; !ok {
+		return nil, fmt.Errorf("%w: skill archive has no %s", errSkillInvalidRequest, skillMDFileName)
+	}
+	return out, nil
+}
@@ -0,0 +1,148 @@
+package skillstore
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/fsutil"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+const (
+	externalChangePollIntervalSkills = 2 * time.Second
+	// Sync clients write a file in several steps; wait for them to finish.
+	externalChangeDebounceSkills = 250 * time.Millisecond
+)
+
+// ExternalChangeHandler is invoked after the user store file was modified
+// outside the app and the store snapshot has been reloaded.
+type ExternalChangeHandler func(context.Context) error
+
+// SetExternalChangeHandler registers the callback used after external reloads,
+// typically a runtime resync of installed Skills.
+func (s *SkillStore) SetExternalChangeHandler(handler ExternalChangeHandler) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.externalChangeHandler = handler
+}
+
+func (s *SkillStore) userFilePath() string {
+	return filepath.Join(s.baseDir, spec.SkillBundlesMetaFileName)
+}
+
+// rememberUserFileStat records the on-disk state the in-memory snapshot
+// corresponds to. Caller must hold s.mu for writing.
+func (s *SkillStore) rememberUserFileStat() {
+	st, err := fsutil.ReadFileState(s.userFilePath())
+	if err != nil {
+		s.userFileStat = nil
+		return
+	}
+	s.userFileStat = &st
+}
+
+func (s *SkillStore) startExternalChangeLoop(watch bool) {
+	if s.cleanCtx == nil {
+		return
+	}
+	if watch {
+		fw, err := fsutil.NewFileWatcher(s.userFilePath(), externalChangeDebounceSkills)
+		if err == nil {
+			s.wg.Go(func() {
+				fw.Run(
+					s.cleanCtx,
+					func() { s.reloadIfChangedExternally(s.cleanCtx) },
+					func(err error) { logger.Warn("skill store file watch", "err", err) },
+				)
+			})
+			return
+		}
+		logger.Warn("skill store file watch unavailable; polling", "err", err)
+	}
+	s.wg.Go(func() {
+		tick := time.NewTicker(externalChangePollIntervalSkills)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-s.cleanCtx.Done():
+				return
+			case <-tick.C:
+			}
+			s.reloadIfChangedExternally(s.cleanCtx)
+		}
+	})
+}
+
+// reloadIfChangedExternally reloads the user store when its file no longer
+// matches the last state the store loaded or wrote, then runs the external
+// change handler. It holds writeMu so it never interleaves with in-app writes.
+func (s *SkillStore) reloadIfChangedExternally(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("reloadIfChangedExternally: panic", "panic", r)
+		}
+	}()
+
+	s.writeMu.Lock()
+	st, err := fsutil.ReadFileState(s.userFilePath())
+	if err != nil || !st.Exists {
+		s.writeMu.Unlock()
+		logger.Debug("skill store file unreadable", "exists", st.Exists, "err", err)
+		return
+	}
+
+	s.mu.Lock()
+	if s.userFileStat != nil && s.userFileStat.SameContent(st) {
+		s.userFileStat = &st
+		s.mu.Unlock()
+		s.writeMu.Unlock()
+		return
+	}
+	s.refreshUserFileCache(st)
+	_, err = s.readAllUser(ctx, true)
+	// Remember the state even on failure so a broken edit is reported once
+	// rather than on every poll; the next save is picked up again.
+	s.userFileStat = &st
+	handler := s.externalChangeHandler
+	s.mu.Unlock()
+	s.writeMu.Unlock()
+
+	if err != nil {
+		logger.Error("skill store: external modification is invalid", "file", s.userFilePath(), "err", err)
+		return
+	}
+	logger.Info("skill store reloaded after external modification", "file", s.userFilePath())
+
+	if handler != nil {
+		if err := handler(ctx); err != nil {
+			logger.Error("skill store external change handler", "err", err)
+		}
+	}
+}
+
+// refreshUserFileCache makes the next forced read of the user store reload
+// the file if its content changed without its size or mtime changing. Caller
+// must hold s.mu.
+func (s *SkillStore) refreshUserFileCache(st fsutil.FileState) {
+	if s.userFileStat == nil {
+		return
+	}
+	if err := fsutil.RefreshModTime(s.userFilePath(), *s.userFileStat, st); err != nil {
+		logger.Debug("skill store refresh mtime failed", "err", err)
+	}
+}
+
+// checkUserFileUnchanged reports a conflict if the content of the user store
+// file differs from before, the state observed before reading a snapshot.
+func (s *SkillStore) checkUserFileUnchanged(before fsutil.FileState) error {
+	if err := fsutil.CheckFileUnchanged(s.userFilePath(), before); err != nil {
+		return fmt.Errorf("%w: %w; retry", errSkillConflict, err)
+	}
+	return nil
+}
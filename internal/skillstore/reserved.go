@@ -0,0 +1,160 @@
+package skillstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// reservedBundleRenamePrefix is prepended to user bundle IDs that collide with
+// the reserved namespace.
+const reservedBundleRenamePrefix = "user-"
+
+// MigrateReservedSkillBundleIDs renames user bundles whose IDs fall in the
+// reserved namespace, moving managed skill packages along with them. Like
+// MigrateReservedProviderNames it runs once per namespace digest. It returns
+// the applied renames, old ID to new ID.
+func (s *SkillStore) MigrateReservedSkillBundleIDs(
+	ctx context.Context,
+) (map[bundleitemutils.BundleID]bundleitemutils.BundleID, error) {
+	ns, err := s.reservedBundleNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := ns.Digest()
+	s.mu.RLock()
+	current, err := s.readAllUser(ctx, false)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if current.ReservedNamesDigest == digest {
+		return map[bundleitemutils.BundleID]bundleitemutils.BundleID{}, nil
+	}
+
+	renames := map[bundleitemutils.BundleID]bundleitemutils.BundleID{}
+	type dirMove struct{ from, to string }
+	var moved []dirMove
+
+	err = s.withUserWrite(ctx, "migrateReservedSkillBundleIDs", func(sc *skillStoreSchema) error {
+		clear(renames)
+		moved = moved[:0]
+		sc.ReservedNamesDigest = digest
+		now := time.Now().UTC()
+
+		for _, oldID := range slices.Sorted(maps.Keys(sc.Bundles)) {
+			if oldID == spec.BaseSkillBundleID || ns.Check(string(oldID)) == nil {
+				continue
+			}
+			base := reservedBundleRenamePrefix + string(oldID)
+			newID := bundleitemutils.BundleID(base)
+			for n := 2; ; n++ {
+				_, taken := sc.Bundles[newID]
+				if !taken && ns.Check(string(newID)) == nil {
+					break
+				}
+				newID = bundleitemutils.BundleID(fmt.Sprintf("%s-%d", base, n))
+			}
+
+			bundle := sc.Bundles[oldID]
+			bundle.ID = newID
+			bundle.ModifiedAt = now
+			delete(sc.Bundles, oldID)
+			sc.Bundles[newID] = bundle
+
+			skills, trashed := sc.Skills[oldID], sc.DeletedSkills[oldID]
+			hasManaged := false
+			for _, m := range []map[spec.SkillSlug]spec.Skill{skills, trashed} {
+				for slug, sk := range m {
+					if !isManagedSkillPackageLocation(s.baseDir, string(oldID), sk.Name, sk.Location) {
+						continue
+					}
+					location, err := managedSkillPackageLocation(s.baseDir, string(newID), sk.Name)
+					if err != nil {
+						return err
+					}
+					sk.Location = location
+					sk.ModifiedAt = now
+					m[slug] = sk
+					hasManaged = true
+				}
+			}
+			delete(sc.Skills, oldID)
+			if skills != nil {
+				sc.Skills[newID] = skills
+			}
+			if trashed != nil {
+				delete(sc.DeletedSkills, oldID)
+				sc.DeletedSkills[newID] = trashed
+			}
+			if activations, ok := sc.LastActivatedAt[oldID]; ok {
+				delete(sc.LastActivatedAt, oldID)
+				sc.LastActivatedAt[newID] = activations
+			}
+			if usage, ok := sc.Usage[oldID]; ok {
+				delete(sc.Usage, oldID)
+				sc.Usage[newID] = usage
+			}
+			renames[oldID] = newID
+
+			if hasManaged {
+				root := filepath.Join(s.baseDir, userCreatedSkillsDirName)
+				from, to := filepath.Join(root, string(oldID)), filepath.Join(root, string(newID))
+				if err := os.Rename(from, to); err != nil && !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+				moved = append(moved, dirMove{from: from, to: to})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Put package directories back so they match the unchanged store file.
+		for _, m := range moved {
+			_ = os.Rename(m.to, m.from)
+		}
+		return nil, err
+	}
+
+	for from, to := range renames {
+		logger.Warn("migrateReservedSkillBundleIDs", "from", from, "to", to)
+	}
+	return renames, nil
+}
+
+// checkReservedBundleID rejects new user bundle IDs in the reserved namespace.
+func (s *SkillStore) checkReservedBundleID(ctx context.Context, id bundleitemutils.BundleID) error {
+	ns, err := s.reservedBundleNamespace(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ns.Check(string(id)); err != nil {
+		return fmt.Errorf("%w: %w", errSkillBundleReserved, err)
+	}
+	return nil
+}
+
+// reservedBundleNamespace is the configured namespace plus all built-in bundle IDs.
+func (s *SkillStore) reservedBundleNamespace(ctx context.Context) (bundleitemutils.ReservedNamespace, error) {
+	if s.builtin == nil {
+		return s.reserved, nil
+	}
+	bundles, _, err := s.builtin.ListBuiltInSkills(ctx)
+	if err != nil {
+		return bundleitemutils.ReservedNamespace{}, err
+	}
+	ids := make([]string, 0, len(bundles))
+	for id := range bundles {
+		ids = append(ids, string(id))
+	}
+	return s.reserved.WithNames(ids...), nil
+}
@@ -0,0 +1,174 @@
+package skillstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/agentskills-go"
+	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
+	"github.com/flexigpt/mapstore-go/uuidv7filename"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// skillScaffoldDirs are the optional package directories of the Agent Skills
+// layout. They are created empty next to SKILL.md.
+var skillScaffoldDirs = []string{"scripts", "references", "assets"}
+
+// CreateSkillScaffold writes a starter skill package and registers it as a
+// user skill. The package directory must not exist yet.
+func (s *SkillStore) CreateSkillScaffold(
+	ctx context.Context,
+	req *spec.CreateSkillScaffoldRequest,
+) (resp *spec.CreateSkillScaffoldResponse, err error) {
+	if req == nil || req.Body == nil || req.BundleID == "" || req.SkillSlug == "" {
+		return nil, fmt.Errorf("%w: bundleID, skillSlug and body required", errSkillInvalidRequest)
+	}
+	if err := bundleitemutils.ValidateItemSlug(req.SkillSlug); err != nil {
+		return nil, fmt.Errorf("%w: invalid skillSlug", errSkillInvalidRequest)
+	}
+	if s.builtin != nil {
+		if _, err := s.builtin.GetBuiltInSkillBundle(ctx, req.BundleID); err == nil {
+			return nil, fmt.Errorf("%w: bundleID %q", errSkillBuiltInReadOnly, req.BundleID)
+		}
+	}
+
+	name := strings.TrimSpace(req.Body.Name)
+	if name == "" {
+		name = string(req.SkillSlug)
+	}
+	displayName := strings.TrimSpace(req.Body.DisplayName)
+	if displayName == "" {
+		displayName = humanizeSkillName(name)
+	}
+	description := strings.TrimSpace(req.Body.Description)
+
+	skillMD, err := agentskills.MarshalSkillDocument(agentskillsSpec.SkillDocument{
+		Name:         name,
+		DisplayName:  displayName,
+		Description:  description,
+		Insert:       spec.SkillInsertInstructions,
+		MarkdownBody: skillScaffoldBody(displayName, description),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid skill scaffold: %w", errSkillInvalidRequest, err)
+	}
+	document, documentWarnings, err := agentskills.ParseSkillDocument(
+		skillMD,
+		agentskillsSpec.ParseSkillDocumentOptions{ExpectedName: name},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: generated skill scaffold is invalid: %w", errSkillInvalidRequest, err)
+	}
+
+	var location string
+	if dir := strings.TrimSpace(req.Body.Directory); dir != "" {
+		if !filepath.IsAbs(dir) {
+			return nil, fmt.Errorf("%w: directory must be absolute", errSkillInvalidRequest)
+		}
+		if err := validateManagedPathSegment(name, "Skill name"); err != nil {
+			return nil, fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+		}
+		location = filepath.Join(filepath.Clean(dir), name)
+	} else {
+		location, err = managedSkillPackageLocation(s.baseDir, string(req.BundleID), name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+		}
+	}
+
+	var createdDir string
+	var created spec.Skill
+	defer func() {
+		if err != nil && createdDir != "" {
+			_ = os.RemoveAll(createdDir)
+		}
+	}()
+
+	if err := s.withUserWrite(ctx, "createSkillScaffold", func(sc *skillStoreSchema) error {
+		b, ok := sc.Bundles[req.BundleID]
+		if !ok {
+			return fmt.Errorf("%w: %s", errSkillBundleNotFound, req.BundleID)
+		}
+		if isSoftDeletedSkillBundle(b) {
+			return fmt.Errorf("%w: %s", errSkillBundleDeleting, req.BundleID)
+		}
+
+		sm := sc.Skills[req.BundleID]
+		if sm == nil {
+			sm = map[spec.SkillSlug]spec.Skill{}
+			sc.Skills[req.BundleID] = sm
+		}
+		if _, exists := sm[req.SkillSlug]; exists {
+			return fmt.Errorf("%w: duplicate skillSlug in bundle", errSkillConflict)
+		}
+
+		if err := createManagedSkillPackage(location, string(skillMD)); err != nil {
+			return err
+		}
+		createdDir = location
+		for _, d := range skillScaffoldDirs {
+			if err := os.Mkdir(filepath.Join(location, d), 0o755); err != nil {
+				return err
+			}
+		}
+
+		uuid, err := uuidv7filename.NewUUIDv7String()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		sk := spec.Skill{
+			SchemaVersion:   spec.SkillSchemaVersion,
+			ID:              bundleitemutils.ItemID(uuid),
+			Slug:            req.SkillSlug,
+			Type:            spec.SkillTypeFS,
+			Location:        location,
+			Name:            document.Name,
+			DisplayName:     document.DisplayName,
+			Description:     document.Description,
+			Insert:          document.Insert,
+			RawFrontmatter:  cloneAnyMap(document.RawFrontmatter),
+			RuntimeWarnings: append([]string(nil), documentWarnings...),
+			Presence:        &spec.SkillPresence{Status: spec.SkillPresenceUnknown},
+			IsEnabled:       req.Body.IsEnabled,
+			CreatedAt:       now,
+			ModifiedAt:      now,
+		}
+		if err := validateSkill(&sk); err != nil {
+			return err
+		}
+
+		sm[req.SkillSlug] = sk
+		created = sk
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return &spec.CreateSkillScaffoldResponse{
+		Body: &spec.CreateSkillScaffoldResponseBody{Skill: cloneSkill(created)},
+	}, nil
+}
+
+// skillScaffoldBody is the starter markdown the user is expected to edit.
+func skillScaffoldBody(displayName, description string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", displayName)
+	if description != "" {
+		fmt.Fprintf(&b, "%s\n\n", description)
+	}
+	b.WriteString("## When to use\n\n")
+	b.WriteString("Describe the requests or situations this skill is meant for.\n\n")
+	b.WriteString("## Instructions\n\n")
+	b.WriteString("1. List the steps to follow.\n")
+	b.WriteString("2. Put helper scripts in `scripts/`, reference documents in `references/` ")
+	b.WriteString("and templates or other files in `assets/`.\n")
+	return b.String()
+}
@@ -0,0 +1,327 @@
+package skillstore
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/flexigpt/agentskills-go"
+	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+const (
+	maxSkillSearchTerms     = 16
+	maxSkillSearchDocBytes  = 256 << 10
+	skillSearchSnippetBytes = 160
+)
+
+// Field weights; a prefix match scores half of an exact token match.
+var skillSearchFieldWeights = map[string]float64{
+	"slug":        5,
+	"name":        5,
+	"displayName": 5,
+	"tags":        4,
+	"description": 3,
+	"frontmatter": 2,
+	"body":        1,
+}
+
+// SearchSkills ranks built-in and user skills by how well every query term
+// matches their metadata, SKILL.md frontmatter and markdown body.
+func (s *SkillStore) SearchSkills(
+	ctx context.Context,
+	req *spec.SearchSkillsRequest,
+) (*spec.SearchSkillsResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("%w: query required", errSkillInvalidRequest)
+	}
+	terms := skillSearchTerms(req.Query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: query required", errSkillInvalidRequest)
+	}
+	limit := req.Limit
+	if limit <= 0 || limit > skillsMaxPageSize {
+		limit = skillsDefaultPageSize
+	}
+
+	include := func(b spec.SkillBundle, sk spec.Skill) bool {
+		if len(req.BundleIDs) > 0 && !slices.Contains(req.BundleIDs, b.ID) {
+			return false
+		}
+		if !req.IncludeDisabled && (!b.IsEnabled || !sk.IsEnabled) {
+			return false
+		}
+		return req.IncludeMissing || sk.Presence == nil || sk.Presence.Status != spec.SkillPresenceMissing
+	}
+
+	s.mu.RLock()
+	user, err := s.readAllUser(ctx, false)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []spec.SkillSearchResult
+	consider := func(b spec.SkillBundle, sk spec.Skill, builtIn bool) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !include(b, sk) {
+			return nil
+		}
+		frontmatter, body := s.skillSearchDocument(ctx, b.ID, sk, builtIn)
+		score, highlights := matchSkill(terms, sk, frontmatter, body)
+		if score == 0 {
+			return nil
+		}
+		results = append(results, spec.SkillSearchResult{
+			SkillListItem: spec.SkillListItem{
+				BundleID:        b.ID,
+				BundleSlug:      b.Slug,
+				SkillSlug:       sk.Slug,
+				IsBuiltIn:       builtIn,
+				SkillDefinition: cloneSkill(sk),
+				Usage:           user.skillUsage(b.ID, sk.Slug),
+				Conflict:        s.skillConflict(b.ID, sk.Slug),
+			},
+			Score:      score,
+			Highlights: highlights,
+		})
+		return nil
+	}
+
+	if s.builtin != nil {
+		biBundles, biSkills, err := s.builtin.ListBuiltInSkills(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for bid, sm := range biSkills {
+			for _, sk := range sm {
+				if err := consider(biBundles[bid], sk, true); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for bid, b := range user.Bundles {
+		if isSoftDeletedSkillBundle(b) {
+			continue
+		}
+		for _, sk := range user.Skills[bid] {
+			if err := consider(b, sk, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	slices.SortFunc(results, func(a, b spec.SkillSearchResult) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.BundleID, b.BundleID); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.SkillSlug, b.SkillSlug)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return &spec.SearchSkillsResponse{
+		Body: &spec.SearchSkillsResponseBody{Results: results},
+	}, nil
+}
+
+// skillSearchDocument returns the SKILL.md frontmatter and markdown body of a
+// skill. Unreadable documents fall back to the stored frontmatter.
+func (s *SkillStore) skillSearchDocument(
+	ctx context.Context,
+	bundleID bundleitemutils.BundleID,
+	sk spec.Skill,
+	builtIn bool,
+) (frontmatter map[string]any, body string) {
+	var raw []byte
+	switch {
+	case builtIn:
+		_, doc, err := s.builtin.ReadBuiltInSkillDocument(ctx, bundleID, sk.Slug)
+		if err == nil {
+			raw = doc
+		}
+	case sk.Type == spec.SkillTypeFS:
+		p := filepath.Join(sk.Location, skillMDFileName)
+		if st, err := os.Stat(p); err == nil && st.Size() <= maxSkillSearchDocBytes {
+			raw, _ = os.ReadFile(p)
+		}
+	}
+	if len(raw) == 0 || len(raw) > maxSkillSearchDocBytes {
+		return sk.RawFrontmatter, ""
+	}
+	document, _, err := agentskills.ParseSkillDocument(
+		raw,
+		agentskillsSpec.ParseSkillDocumentOptions{ExpectedName: sk.Name},
+	)
+	if err != nil {
+		return sk.RawFrontmatter, ""
+	}
+	return document.RawFrontmatter, document.MarkdownBody
+}
+
+// matchSkill scores a skill against terms. Every term has to match at least
+// one field, otherwise the score is 0.
+func matchSkill(
+	terms []string,
+	sk spec.Skill,
+	frontmatter map[string]any,
+	body string,
+) (float64, []spec.SkillSearchHighlight) {
+	type field struct {
+		name, weightKey, value string
+	}
+	fields := []field{
+		{"slug", "slug", string(sk.Slug)},
+		{"name", "name", sk.Name},
+		{"displayName", "displayName", sk.DisplayName},
+		{"tags", "tags", strings.Join(sk.Tags, ", ")},
+		{"description", "description", sk.Description},
+	}
+	for _, key := range slices.Sorted(maps.Keys(frontmatter)) {
+		v := frontmatterSearchText(frontmatter[key])
+		// Skip values the skill fields already cover.
+		if v == "" || (key == "name" && v == sk.Name) || (key == "description" && v == sk.Description) {
+			continue
+		}
+		fields = append(fields, field{"frontmatter." + key, "frontmatter", v})
+	}
+	fields = append(fields, field{"body", "body", body})
+
+	matched := make([]bool, len(terms))
+	var score float64
+	var highlights []spec.SkillSearchHighlight
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		best := make([]float64, len(terms))
+		var spans []spec.SkillSearchSpan
+		for _, tok := range tokenizeSearchText(f.value) {
+			hit := false
+			for i, term := range terms {
+				switch {
+				case tok.text == term:
+					best[i] = 1
+					hit = true
+				case strings.HasPrefix(tok.text, term):
+					best[i] = max(best[i], 0.5)
+					hit = true
+				}
+			}
+			if hit {
+				spans = append(spans, spec.SkillSearchSpan{Start: tok.start, End: tok.end})
+			}
+		}
+		if len(spans) == 0 {
+			continue
+		}
+		for i, b := range best {
+			if b > 0 {
+				matched[i] = true
+				score += b * skillSearchFieldWeights[f.weightKey]
+			}
+		}
+		highlights = append(highlights, searchHighlight(f.name, f.value, spans))
+	}
+	if slices.Contains(matched, false) {
+		return 0, nil
+	}
+	return score, highlights
+}
+
+// searchHighlight cuts a snippet around the first span of value and rebases
+// the spans that fall inside it.
+func searchHighlight(field, value string, spans []spec.SkillSearchSpan) spec.SkillSearchHighlight {
+	start, end := 0, len(value)
+	if len(value) > skillSearchSnippetBytes {
+		start = max(0, spans[0].Start-skillSearchSnippetBytes/4)
+		end = min(len(value), start+skillSearchSnippetBytes)
+		for start > 0 && !utf8.RuneStart(value[start]) {
+			start--
+		}
+		for end < len(value) && !utf8.RuneStart(value[end]) {
+			end++
+		}
+	}
+	h := spec.SkillSearchHighlight{Field: field, Snippet: value[start:end]}
+	for _, sp := range spans {
+		if sp.Start >= start && sp.End <= end {
+			h.Spans = append(h.Spans, spec.SkillSearchSpan{Start: sp.Start - start, End: sp.End - start})
+		}
+	}
+	return h
+}
+
+func frontmatterSearchText(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return ""
+	}
+}
+
+// skillSearchTerms returns the distinct lower-case tokens of a query.
+func skillSearchTerms(query string) []string {
+	var terms []string
+	for _, tok := range tokenizeSearchText(query) {
+		if !slices.Contains(terms, tok.text) {
+			terms = append(terms, tok.text)
+		}
+		if len(terms) == maxSkillSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+type searchToken struct {
+	text       string
+	start, end int
+}
+
+// tokenizeSearchText splits s into runs of letters and digits, recording
+// byte offsets into s.
+func tokenizeSearchText(s string) []searchToken {
+	var out []searchToken
+	start := -1
+	for i, r := range s {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case word && start < 0:
+			start = i
+		case !word && start >= 0:
+			out = append(out, searchToken{text: strings.ToLower(s[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		out = append(out, searchToken{text: strings.ToLower(s[start:]), start: start, end: len(s)})
+	}
+	return out
+}
@@ -1,9 +1,25 @@
 package skillstore
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/precondition"
 	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
 )
 
@@ -147,6 +163,70 @@ func TestSkillStore_PatchSkill_EmptyPatchRejected(t *testing.T) {
 	}
 }
 
+func TestSkillStore_PatchSkill_ExpectedModifiedAt(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	putBundle(t, s, skillBundleB1, testBundleSlug, testBundleDisplayName, true)
+	if err := putSkill(t, s, skillBundleB1, skillBundleS1, t.TempDir(), "skill", "desc", "BODY", true); err != nil {
+		t.Fatalf("PutSkill: %v", err)
+	}
+	all, err := s.readAllUser(t.Context(), false)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	read := all.Skills[skillBundleB1][skillBundleS1].ModifiedAt
+
+	patch := func(expected time.Time, name string) error {
+		_, err := s.PatchSkill(t.Context(), &spec.PatchSkillRequest{
+			BundleID:  skillBundleB1,
+			SkillSlug: skillBundleS1,
+			Body:      &spec.PatchSkillRequestBody{DisplayName: &name, ExpectedModifiedAt: &expected},
+		})
+		return err
+	}
+	if err := patch(read, "first"); err != nil {
+		t.Fatalf("patch at current modifiedAt: %v", err)
+	}
+	err = patch(read, "second")
+	if !errors.Is(err, errSkillConflict) || !errors.Is(err, precondition.ErrConflict) {
+		t.Fatalf("stale patch: err = %v, want conflict", err)
+	}
+	current, ok := precondition.Current[spec.Skill](err)
+	if !ok || current.DisplayName != "first" {
+		t.Fatalf("conflict current = %+v, %v; want the first patch", current, ok)
+	}
+}
+
+func TestSkillStore_PutSkill_ExpectedBundleModifiedAt(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	putBundle(t, s, skillBundleB1, testBundleSlug, testBundleDisplayName, true)
+	all, err := s.readAllUser(t.Context(), false)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	stale := all.Bundles[skillBundleB1].ModifiedAt.Add(-time.Second)
+
+	loc := writeSkillPackage(t, t.TempDir(), "some-skill", "desc", "BODY")
+	_, err = s.PutSkill(t.Context(), &spec.PutSkillRequest{
+		BundleID:  skillBundleB1,
+		SkillSlug: "some-skill",
+		Body: &spec.PutSkillRequestBody{
+			SkillType:                spec.SkillTypeFS,
+			Location:                 loc,
+			Name:                     "some-skill",
+			IsEnabled:                true,
+			ExpectedBundleModifiedAt: &stale,
+		},
+	})
+	if !errors.Is(err, errSkillConflict) || !errors.Is(err, precondition.ErrConflict) {
+		t.Fatalf("stale put: err = %v, want conflict", err)
+	}
+	if current, ok := precondition.Current[spec.SkillBundle](err); !ok || current.ID != skillBundleB1 {
+		t.Fatalf("conflict current = %+v, %v; want the bundle", current, ok)
+	}
+}
+
 func TestSkillStore_DeleteSkillBundle_NotEmpty(t *testing.T) {
 	t.Parallel()
 	s := newTestSkillStore(t)
@@ -198,6 +278,623 @@ func TestSkillStore_PutSkillBundle_SoftDeletedCannotBeRecreated(t *testing.T) {
 	}
 }
 
+func TestSkillStore_SetSkillBundleActivationPolicy(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	ctx := t.Context()
+
+	_, err := s.PutSkillBundle(ctx, &spec.PutSkillBundleRequest{
+		BundleID: skillBundleB1,
+		Body: &spec.PutSkillBundleRequestBody{
+			Slug:             testBundleSlug,
+			DisplayName:      testBundleDisplayName,
+			IsEnabled:        true,
+			ActivationPolicy: spec.SkillBundleActivationAlwaysActive,
+		},
+	})
+	if err != nil {
+		t.Fatalf("PutSkillBundle: %v", err)
+	}
+	user, err := readAllUserLocked(t, s, true)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if got := user.Bundles[skillBundleB1].ActivationPolicy; got != spec.SkillBundleActivationAlwaysActive {
+		t.Fatalf("policy after put = %q", got)
+	}
+
+	_, err = s.SetSkillBundleActivationPolicy(ctx, &spec.SetSkillBundleActivationPolicyRequest{
+		BundleID: skillBundleB1,
+		Body:     &spec.SetSkillBundleActivationPolicyRequestBody{ActivationPolicy: spec.SkillBundleActivationNever},
+	})
+	if err != nil {
+		t.Fatalf("SetSkillBundleActivationPolicy: %v", err)
+	}
+	user, err = readAllUserLocked(t, s, true)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if got := user.Bundles[skillBundleB1].ActivationPolicy; got != spec.SkillBundleActivationNever {
+		t.Fatalf("policy after set = %q", got)
+	}
+
+	bundles, _, err := s.builtin.ListBuiltInSkills(ctx)
+	if err != nil {
+		t.Fatalf("ListBuiltInSkills: %v", err)
+	}
+	var builtInID bundleitemutils.BundleID
+	for id := range bundles {
+		builtInID = id
+		break
+	}
+	if builtInID != "" {
+		_, err = s.SetSkillBundleActivationPolicy(ctx, &spec.SetSkillBundleActivationPolicyRequest{
+			BundleID: builtInID,
+			Body: &spec.SetSkillBundleActivationPolicyRequestBody{
+				ActivationPolicy: spec.SkillBundleActivationAlwaysActive,
+			},
+		})
+		if err != nil {
+			t.Fatalf("SetSkillBundleActivationPolicy(builtin): %v", err)
+		}
+		b, err := s.builtin.GetBuiltInSkillBundle(ctx, builtInID)
+		if err != nil {
+			t.Fatalf("GetBuiltInSkillBundle: %v", err)
+		}
+		if b.ActivationPolicy != spec.SkillBundleActivationAlwaysActive {
+			t.Fatalf("built-in policy = %q", b.ActivationPolicy)
+		}
+		if _, err := s.builtin.ResetOverrides(ctx, builtInID); err != nil {
+			t.Fatalf("ResetOverrides: %v", err)
+		}
+		b, err = s.builtin.GetBuiltInSkillBundle(ctx, builtInID)
+		if err != nil {
+			t.Fatalf("GetBuiltInSkillBundle: %v", err)
+		}
+		if b.ActivationPolicy != "" {
+			t.Fatalf("built-in policy after reset = %q", b.ActivationPolicy)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		req    *spec.SetSkillBundleActivationPolicyRequest
+		wantIs error
+	}{
+		{"nil-req", nil, errSkillInvalidRequest},
+		{
+			"empty-policy",
+			&spec.SetSkillBundleActivationPolicyRequest{
+				BundleID: skillBundleB1,
+				Body:     &spec.SetSkillBundleActivationPolicyRequestBody{},
+			},
+			errSkillInvalidRequest,
+		},
+		{
+			"unknown-policy",
+			&spec.SetSkillBundleActivationPolicyRequest{
+				BundleID: skillBundleB1,
+				Body:     &spec.SetSkillBundleActivationPolicyRequestBody{ActivationPolicy: "sometimes"},
+			},
+			errSkillInvalidRequest,
+		},
+		{
+			"missing-bundle",
+			&spec.SetSkillBundleActivationPolicyRequest{
+				BundleID: "nope",
+				Body: &spec.SetSkillBundleActivationPolicyRequestBody{
+					ActivationPolicy: spec.SkillBundleActivationOnDemand,
+				},
+			},
+			errSkillBundleNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.SetSkillBundleActivationPolicy(ctx, tc.req)
+			if !errors.Is(err, tc.wantIs) {
+				t.Fatalf("got %v, want %v", err, tc.wantIs)
+			}
+		})
+	}
+}
+
+func TestSkillStore_RenameSkillBundle(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	ctx := t.Context()
+
+	putBundle(t, s, skillBundleB1, testBundleSlug, testBundleDisplayName, true)
+	putBundle(t, s, "b2", "other-bundle", "Other", true)
+	putBundle(t, s, "b3", "gone-bundle", "Gone", true)
+	if err := putSkill(t, s, skillBundleB1, skillBundleS1, t.TempDir(), "rename-me", "desc", "body", true); err != nil {
+		t.Fatalf("PutSkill: %v", err)
+	}
+	if _, err := s.DeleteSkillBundle(ctx, &spec.DeleteSkillBundleRequest{BundleID: "b3"}); err != nil {
+		t.Fatalf("DeleteSkillBundle: %v", err)
+	}
+	before, err := readAllUserLocked(t, s, true)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+
+	// The slug of a soft-deleted bundle is free again.
+	_, err = s.RenameSkillBundle(ctx, &spec.RenameSkillBundleRequest{
+		BundleID: skillBundleB1,
+		Body:     &spec.RenameSkillBundleRequestBody{Slug: "gone-bundle", DisplayName: " Renamed "},
+	})
+	if err != nil {
+		t.Fatalf("RenameSkillBundle: %v", err)
+	}
+	after, err := readAllUserLocked(t, s, true)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	got := after.Bundles[skillBundleB1]
+	if got.Slug != "gone-bundle" || got.DisplayName != "Renamed" {
+		t.Fatalf("unexpected bundle after rename: %+v", got)
+	}
+	if !got.CreatedAt.Equal(before.Bundles[skillBundleB1].CreatedAt) || !got.IsEnabled {
+		t.Fatalf("rename changed other bundle fields: %+v", got)
+	}
+	if _, ok := after.Skills[skillBundleB1][skillBundleS1]; !ok {
+		t.Fatal("skill lost on rename")
+	}
+	resp, err := s.ListSkills(ctx, &spec.ListSkillsRequest{BundleIDs: []bundleitemutils.BundleID{skillBundleB1}})
+	if err != nil {
+		t.Fatalf("ListSkills: %v", err)
+	}
+	if len(resp.Body.SkillListItems) != 1 || resp.Body.SkillListItems[0].BundleSlug != "gone-bundle" {
+		t.Fatalf("unexpected list items: %+v", resp.Body.SkillListItems)
+	}
+
+	bundles, _, err := s.builtin.ListBuiltInSkills(ctx)
+	if err != nil {
+		t.Fatalf("ListBuiltInSkills: %v", err)
+	}
+	var builtInID bundleitemutils.BundleID
+	for id := range bundles {
+		builtInID = id
+		break
+	}
+
+	tests := []struct {
+		name   string
+		req    *spec.RenameSkillBundleRequest
+		wantIs error
+	}{
+		{"nil-req", nil, errSkillInvalidRequest},
+		{
+			"empty-body",
+			&spec.RenameSkillBundleRequest{BundleID: skillBundleB1, Body: &spec.RenameSkillBundleRequestBody{}},
+			errSkillInvalidRequest,
+		},
+		{
+			"bad-slug",
+			&spec.RenameSkillBundleRequest{
+				BundleID: skillBundleB1,
+				Body:     &spec.RenameSkillBundleRequestBody{Slug: "bad slug"},
+			},
+			errSkillInvalidRequest,
+		},
+		{
+			"slug-taken",
+			&spec.RenameSkillBundleRequest{
+				BundleID: skillBundleB1,
+				Body:     &spec.RenameSkillBundleRequestBody{Slug: "other-bundle"},
+			},
+			errSkillConflict,
+		},
+		{
+			"missing-bundle",
+			&spec.RenameSkillBundleRequest{
+				BundleID: "nope",
+				Body:     &spec.RenameSkillBundleRequestBody{DisplayName: "x"},
+			},
+			errSkillBundleNotFound,
+		},
+		{
+			"soft-deleted",
+			&spec.RenameSkillBundleRequest{
+				BundleID: "b3",
+				Body:     &spec.RenameSkillBundleRequestBody{DisplayName: "x"},
+			},
+			errSkillBundleDeleting,
+		},
+		{
+			"built-in",
+			&spec.RenameSkillBundleRequest{
+				BundleID: builtInID,
+				Body:     &spec.RenameSkillBundleRequestBody{DisplayName: "x"},
+			},
+			errSkillBuiltInReadOnly,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.name == "built-in" && builtInID == "" {
+				t.Skip("no built-in bundles")
+			}
+			_, err := s.RenameSkillBundle(ctx, tc.req)
+			if !errors.Is(err, tc.wantIs) {
+				t.Fatalf("got %v, want %v", err, tc.wantIs)
+			}
+		})
+	}
+}
+
+func TestSkillStore_MoveSkill(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	ctx := t.Context()
+
+	putBundle(t, s, skillBundleB1, testBundleSlug, testBundleDisplayName, true)
+	putBundle(t, s, "b2", "target-bundle", "Target", true)
+	putBundle(t, s, skillBundleBdis, "bundle-disabled", "Disabled Bundle", false)
+	if _, err := s.PutSkillArtifact(ctx, &spec.PutSkillArtifactRequest{
+		BundleID:  skillBundleB1,
+		SkillSlug: "managed",
+		Body: &spec.PutSkillArtifactRequestBody{
+			IsEnabled:    true,
+			Description:  "managed skill",
+			MarkdownBody: "body",
+		},
+	}); err != nil {
+		t.Fatalf("PutSkillArtifact: %v", err)
+	}
+	skillRoot := t.TempDir()
+	for _, bid := range []string{skillBundleB1, "b2"} {
+		if err := putSkill(t, s, bid, skillBundleS1, skillRoot, "move-"+bid, "desc", "body", true); err != nil {
+			t.Fatalf("PutSkill(%s): %v", bid, err)
+		}
+	}
+	if err := s.RecordSkillActivations(ctx, []spec.SkillRef{
+		{BundleID: skillBundleB1, SkillSlug: "managed"},
+	}); err != nil {
+		t.Fatalf("RecordSkillActivations: %v", err)
+	}
+	before, err := readAllUserLocked(t, s, true)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	orig := before.Skills[skillBundleB1]["managed"]
+
+	_, err = s.MoveSkill(ctx, &spec.MoveSkillRequest{
+		BundleID:  skillBundleB1,
+		SkillSlug: "managed",
+		Body:      &spec.MoveSkillRequestBody{TargetBundleID: "b2"},
+	})
+	if err != nil {
+		t.Fatalf("MoveSkill: %v", err)
+	}
+	after, err := readAllUserLocked(t, s, true)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if _, ok := after.Skills[skillBundleB1]["managed"]; ok {
+		t.Fatal("skill still in source bundle")
+	}
+	moved, ok := after.Skills["b2"]["managed"]
+	if !ok {
+		t.Fatal("skill missing from target bundle")
+	}
+	if moved.ID != orig.ID || !moved.CreatedAt.Equal(orig.CreatedAt) || !moved.ModifiedAt.After(orig.ModifiedAt) {
+		t.Fatalf("unexpected moved skill: %+v, was %+v", moved, orig)
+	}
+	if !isManagedSkillPackageLocation(s.baseDir, "b2", moved.Name, moved.Location) {
+		t.Fatalf("package not moved to target bundle: %q", moved.Location)
+	}
+	if _, err := os.Stat(filepath.Join(moved.Location, skillMDFileName)); err != nil {
+		t.Fatalf("moved package unreadable: %v", err)
+	}
+	if _, err := os.Stat(orig.Location); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("old package still present: %v", err)
+	}
+	if u := after.skillUsage("b2", "managed"); u == nil || u.ActivationCount != 1 {
+		t.Fatalf("usage not moved: %+v", u)
+	}
+	if u := after.skillUsage(skillBundleB1, "managed"); u != nil {
+		t.Fatalf("usage left on source: %+v", u)
+	}
+
+	tests := []struct {
+		name   string
+		req    *spec.MoveSkillRequest
+		wantIs error
+	}{
+		{"nil-req", nil, errSkillInvalidRequest},
+		{
+			"same-bundle",
+			&spec.MoveSkillRequest{
+				BundleID: "b2", SkillSlug: "managed",
+				Body: &spec.MoveSkillRequestBody{TargetBundleID: "b2"},
+			},
+			errSkillInvalidRequest,
+		},
+		{
+			"slug-taken",
+			&spec.MoveSkillRequest{
+				BundleID: skillBundleB1, SkillSlug: skillBundleS1,
+				Body: &spec.MoveSkillRequestBody{TargetBundleID: "b2"},
+			},
+			errSkillConflict,
+		},
+		{
+			"target-disabled",
+			&spec.MoveSkillRequest{
+				BundleID: skillBundleB1, SkillSlug: skillBundleS1,
+				Body: &spec.MoveSkillRequestBody{TargetBundleID: skillBundleBdis},
+			},
+			errSkillBundleDisabled,
+		},
+		{
+			"target-missing",
+			&spec.MoveSkillRequest{
+				BundleID: skillBundleB1, SkillSlug: skillBundleS1,
+				Body: &spec.MoveSkillRequestBody{TargetBundleID: "nope"},
+			},
+			errSkillBundleNotFound,
+		},
+		{
+			"skill-missing",
+			&spec.MoveSkillRequest{
+				BundleID: skillBundleB1, SkillSlug: skillBundleMissingSkillSlug,
+				Body: &spec.MoveSkillRequestBody{TargetBundleID: "b2"},
+			},
+			errSkillNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.MoveSkill(ctx, tc.req)
+			if !errors.Is(err, tc.wantIs) {
+				t.Fatalf("got %v, want %v", err, tc.wantIs)
+			}
+		})
+	}
+}
+
+func TestSkillStore_CloneSkill(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	ctx := t.Context()
+
+	putBundle(t, s, skillBundleB1, testBundleSlug, testBundleDisplayName, true)
+	putBundle(t, s, "b2", "target-bundle", "Target", true)
+	art, err := s.PutSkillArtifact(ctx, &spec.PutSkillArtifactRequest{
+		BundleID:  skillBundleB1,
+		SkillSlug: "orig",
+		Body: &spec.PutSkillArtifactRequestBody{
+			IsEnabled:    true,
+			Description:  "managed skill",
+			Tags:         []string{"t1"},
+			MarkdownBody: "body",
+		},
+	})
+	if err != nil {
+		t.Fatalf("PutSkillArtifact: %v", err)
+	}
+	orig := art.Body.Skill
+	if err := os.WriteFile(filepath.Join(orig.Location, "notes.txt"), []byte("resource"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.CloneSkill(ctx, &spec.CloneSkillRequest{
+		BundleID:  skillBundleB1,
+		SkillSlug: "orig",
+		Body:      &spec.CloneSkillRequestBody{NewSkillSlug: "fork", DisplayName: "Fork", CopyPackage: true},
+	})
+	if err != nil {
+		t.Fatalf("CloneSkill(copy): %v", err)
+	}
+	fork := resp.Body.Skill
+	if fork.ID == orig.ID || fork.Slug != "fork" || fork.Name != "fork" || fork.DisplayName != "Fork" {
+		t.Fatalf("unexpected clone: %+v", fork)
+	}
+	if !slices.Equal(fork.Tags, orig.Tags) || fork.Description != orig.Description {
+		t.Fatalf("clone lost definition fields: %+v", fork)
+	}
+	if !isManagedSkillPackageLocation(s.baseDir, skillBundleB1, "fork", fork.Location) {
+		t.Fatalf("clone package not managed: %q", fork.Location)
+	}
+	if raw, err := os.ReadFile(filepath.Join(fork.Location, "notes.txt")); err != nil || string(raw) != "resource" {
+		t.Fatalf("resource not copied: %q %v", raw, err)
+	}
+	raw, err := os.ReadFile(filepath.Join(fork.Location, skillMDFileName))
+	if err != nil || !strings.Contains(string(raw), "name: fork") {
+		t.Fatalf("SKILL.md not renamed: %q %v", raw, err)
+	}
+	if raw, err := os.ReadFile(filepath.Join(orig.Location, skillMDFileName)); err != nil ||
+		!strings.Contains(string(raw), "name: orig") {
+		t.Fatalf("source SKILL.md changed: %q %v", raw, err)
+	}
+
+	resp, err = s.CloneSkill(ctx, &spec.CloneSkillRequest{
+		BundleID:  skillBundleB1,
+		SkillSlug: "orig",
+		Body:      &spec.CloneSkillRequestBody{NewSkillSlug: "moved", TargetBundleID: "b2", CopyPackage: true},
+	})
+	if err != nil {
+		t.Fatalf("CloneSkill(target bundle): %v", err)
+	}
+	if moved := resp.Body.Skill; !isManagedSkillPackageLocation(s.baseDir, "b2", "moved", moved.Location) {
+		t.Fatalf("clone package not managed under the target bundle: %q", moved.Location)
+	}
+	all, err := readAllUserLocked(t, s, true)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if _, ok := all.Skills["b2"]["moved"]; !ok {
+		t.Fatal("clone not stored in target bundle")
+	}
+
+	tests := []struct {
+		name   string
+		req    *spec.CloneSkillRequest
+		wantIs error
+	}{
+		{"nil-req", nil, errSkillInvalidRequest},
+		{
+			"same-slug",
+			&spec.CloneSkillRequest{
+				BundleID: skillBundleB1, SkillSlug: "orig",
+				Body: &spec.CloneSkillRequestBody{NewSkillSlug: "orig"},
+			},
+			errSkillInvalidRequest,
+		},
+		{
+			"shared-package",
+			&spec.CloneSkillRequest{
+				BundleID: skillBundleB1, SkillSlug: "orig",
+				Body: &spec.CloneSkillRequestBody{NewSkillSlug: "shared"},
+			},
+			errSkillInvalidRequest,
+		},
+		{
+			"slug-taken",
+			&spec.CloneSkillRequest{
+				BundleID: skillBundleB1, SkillSlug: "orig",
+				Body: &spec.CloneSkillRequestBody{NewSkillSlug: "fork", CopyPackage: true},
+			},
+			errSkillConflict,
+		},
+		{
+			"skill-missing",
+			&spec.CloneSkillRequest{
+				BundleID: skillBundleB1, SkillSlug: skillBundleMissingSkillSlug,
+				Body: &spec.CloneSkillRequestBody{NewSkillSlug: "x"},
+			},
+			errSkillNotFound,
+		},
+		{
+			"target-missing",
+			&spec.CloneSkillRequest{
+				BundleID: skillBundleB1, SkillSlug: "orig",
+				Body: &spec.CloneSkillRequestBody{NewSkillSlug: "x", TargetBundleID: "nope"},
+			},
+			errSkillBundleNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.CloneSkill(ctx, tc.req)
+			if !errors.Is(err, tc.wantIs) {
+				t.Fatalf("got %v, want %v", err, tc.wantIs)
+			}
+		})
+	}
+}
+
+func TestSkillStore_CreateSkillScaffold(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	ctx := t.Context()
+
+	putBundle(t, s, skillBundleB1, testBundleSlug, testBundleDisplayName, true)
+	resp, err := s.CreateSkillScaffold(ctx, &spec.CreateSkillScaffoldRequest{
+		BundleID:  skillBundleB1,
+		SkillSlug: "release-notes",
+		Body:      &spec.CreateSkillScaffoldRequestBody{Description: "Draft release notes", IsEnabled: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateSkillScaffold(managed): %v", err)
+	}
+	managed := resp.Body.Skill
+	if managed.Name != "release-notes" || managed.DisplayName != "Release Notes" || !managed.IsEnabled {
+		t.Fatalf("unexpected scaffold: %+v", managed)
+	}
+	if !isManagedSkillPackageLocation(s.baseDir, skillBundleB1, "release-notes", managed.Location) {
+		t.Fatalf("scaffold not managed: %q", managed.Location)
+	}
+	raw, err := os.ReadFile(filepath.Join(managed.Location, skillMDFileName))
+	if err != nil || !strings.Contains(string(raw), "name: release-notes") ||
+		!strings.Contains(string(raw), "## Instructions") {
+		t.Fatalf("unexpected SKILL.md: %q %v", raw, err)
+	}
+	for _, d := range skillScaffoldDirs {
+		if fi, err := os.Stat(filepath.Join(managed.Location, d)); err != nil || !fi.IsDir() {
+			t.Fatalf("missing %s directory: %v", d, err)
+		}
+	}
+
+	parent := t.TempDir()
+	resp, err = s.CreateSkillScaffold(ctx, &spec.CreateSkillScaffoldRequest{
+		BundleID:  skillBundleB1,
+		SkillSlug: "custom",
+		Body: &spec.CreateSkillScaffoldRequestBody{
+			Name:        "my-skill",
+			DisplayName: "Mine",
+			Description: "d",
+			Directory:   parent,
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateSkillScaffold(directory): %v", err)
+	}
+	if got := resp.Body.Skill; got.Location != filepath.Join(parent, "my-skill") || got.DisplayName != "Mine" {
+		t.Fatalf("unexpected scaffold: %+v", got)
+	}
+
+	tests := []struct {
+		name   string
+		req    *spec.CreateSkillScaffoldRequest
+		wantIs error
+	}{
+		{"nil-req", nil, errSkillInvalidRequest},
+		{
+			"relative-directory",
+			&spec.CreateSkillScaffoldRequest{
+				BundleID: skillBundleB1, SkillSlug: "rel",
+				Body: &spec.CreateSkillScaffoldRequestBody{Description: "d", Directory: "rel"},
+			},
+			errSkillInvalidRequest,
+		},
+		{
+			"invalid-name",
+			&spec.CreateSkillScaffoldRequest{
+				BundleID: skillBundleB1, SkillSlug: "bad",
+				Body: &spec.CreateSkillScaffoldRequestBody{Name: "Bad Name", Description: "d"},
+			},
+			errSkillInvalidRequest,
+		},
+		{
+			"slug-taken",
+			&spec.CreateSkillScaffoldRequest{
+				BundleID: skillBundleB1, SkillSlug: "custom",
+				Body: &spec.CreateSkillScaffoldRequestBody{Name: "other", Description: "d"},
+			},
+			errSkillConflict,
+		},
+		{
+			"directory-exists",
+			&spec.CreateSkillScaffoldRequest{
+				BundleID: skillBundleB1, SkillSlug: "again",
+				Body: &spec.CreateSkillScaffoldRequestBody{Name: "my-skill", Description: "d", Directory: parent},
+			},
+			errSkillConflict,
+		},
+		{
+			"bundle-missing",
+			&spec.CreateSkillScaffoldRequest{
+				BundleID: "nope", SkillSlug: "x",
+				Body: &spec.CreateSkillScaffoldRequestBody{Description: "d"},
+			},
+			errSkillBundleNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.CreateSkillScaffold(ctx, tc.req)
+			if !errors.Is(err, tc.wantIs) {
+				t.Fatalf("got %v, want %v", err, tc.wantIs)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(parent, "my-skill", skillMDFileName)); err != nil {
+		t.Fatalf("existing package removed by failed scaffold: %v", err)
+	}
+}
+
 func TestSkillStore_withUserWriteSaga_InvalidArgs(t *testing.T) {
 	t.Parallel()
 
@@ -217,3 +914,533 @@ func TestSkillStore_withUserWriteSaga_InvalidArgs(t *testing.T) {
 		t.Fatalf("expected ErrSkillInvalidRequest, got %v", err)
 	}
 }
+
+func TestSkillStore_ReservedBundleIDs(t *testing.T) {
+	t.Parallel()
+
+	t.Run("put-rejects-reserved", func(t *testing.T) {
+		t.Parallel()
+		s := newTestSkillStore(t)
+		builtIns, _, err := s.builtin.ListBuiltInSkills(t.Context())
+		if err != nil {
+			t.Fatalf("ListBuiltInSkills: %v", err)
+		}
+		ids := []bundleitemutils.BundleID{"builtin-mine", "flexigpt-extras"}
+		for id := range builtIns {
+			ids = append(ids, id+"2")
+			break
+		}
+		for _, id := range ids {
+			_, err := s.PutSkillBundle(t.Context(), &spec.PutSkillBundleRequest{
+				BundleID: id,
+				Body: &spec.PutSkillBundleRequestBody{
+					Slug:        testBundleSlug,
+					DisplayName: testBundleDisplayName,
+					IsEnabled:   true,
+				},
+			})
+			if !errors.Is(err, errSkillBundleReserved) || !errors.Is(err, bundleitemutils.ErrReservedName) {
+				t.Fatalf("PutSkillBundle(%q) err=%v want reserved", id, err)
+			}
+		}
+	})
+
+	t.Run("migrate-renames-collisions", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		s, err := NewSkillStore(dir)
+		if err != nil {
+			t.Fatalf("NewSkillStore: %v", err)
+		}
+		putBundle(t, s, "acme-b", testBundleSlug, testBundleDisplayName, true)
+		if _, err := s.PutSkillArtifact(t.Context(), &spec.PutSkillArtifactRequest{
+			BundleID:  "acme-b",
+			SkillSlug: "my-skill",
+			Body: &spec.PutSkillArtifactRequestBody{
+				IsEnabled:    true,
+				Description:  "managed skill",
+				MarkdownBody: "body",
+			},
+		}); err != nil {
+			t.Fatalf("PutSkillArtifact: %v", err)
+		}
+		if err := s.RecordSkillActivations(t.Context(), []spec.SkillRef{
+			{BundleID: "acme-b", SkillSlug: "my-skill"},
+		}); err != nil {
+			t.Fatalf("RecordSkillActivations: %v", err)
+		}
+		s.Close()
+
+		// A newer catalog reserves the "acme-" namespace.
+		s, err = NewSkillStore(dir, WithReservedBundleNamespace(
+			bundleitemutils.ReservedNamespace{Prefixes: []string{"acme-"}},
+		))
+		if err != nil {
+			t.Fatalf("NewSkillStore(reserved): %v", err)
+		}
+		t.Cleanup(s.Close)
+
+		// Existing bundles stay editable until migrated.
+		putBundle(t, s, "acme-b", testBundleSlug, "Renamed display", true)
+
+		renames, err := s.MigrateReservedSkillBundleIDs(t.Context())
+		if err != nil {
+			t.Fatalf("MigrateReservedSkillBundleIDs: %v", err)
+		}
+		if len(renames) != 1 || renames["acme-b"] != "user-acme-b" {
+			t.Fatalf("renames=%v", renames)
+		}
+
+		all, err := readAllUserLocked(t, s, true)
+		if err != nil {
+			t.Fatalf("readAllUser: %v", err)
+		}
+		if _, ok := all.Bundles["acme-b"]; ok {
+			t.Fatalf("old bundle still present")
+		}
+		if b := all.Bundles["user-acme-b"]; b.ID != "user-acme-b" {
+			t.Fatalf("renamed bundle ID=%q", b.ID)
+		}
+		sk, ok := all.Skills["user-acme-b"]["my-skill"]
+		if !ok {
+			t.Fatalf("skill not moved with bundle")
+		}
+		if !isManagedSkillPackageLocation(dir, "user-acme-b", sk.Name, sk.Location) {
+			t.Fatalf("managed location not updated: %q", sk.Location)
+		}
+		if _, err := os.Stat(filepath.Join(sk.Location, skillMDFileName)); err != nil {
+			t.Fatalf("managed package not moved: %v", err)
+		}
+		if _, ok := all.LastActivatedAt["user-acme-b"]["my-skill"]; !ok {
+			t.Fatalf("activation history not moved")
+		}
+		if all.ReservedNamesDigest == "" {
+			t.Fatalf("migration did not record the namespace digest")
+		}
+		if renames, err := s.MigrateReservedSkillBundleIDs(t.Context()); err != nil || len(renames) != 0 {
+			t.Fatalf("second migration renames=%v err=%v", renames, err)
+		}
+	})
+}
+
+func TestSkillStore_ExportImportSkillBundle(t *testing.T) {
+	t.Parallel()
+	src := newTestSkillStore(t)
+	putBundle(t, src, "exp", "exported", "Exported Bundle", true)
+
+	skillRoot := t.TempDir()
+	if err := putSkill(t, src, "exp", "s1", skillRoot, "arch-skill", "desc", "BODY", true); err != nil {
+		t.Fatalf("putSkill: %v", err)
+	}
+	refDir := filepath.Join(skillRoot, "arch-skill", "references")
+	if err := os.MkdirAll(refDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(refDir, "notes.md"), []byte("notes"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := src.ExportSkillBundle(t.Context(), &spec.ExportSkillBundleRequest{BundleID: "exp"})
+	if err != nil {
+		t.Fatalf("ExportSkillBundle: %v", err)
+	}
+	if exported.Body.FileName != "exported.skillbundle.tar.gz" || len(exported.Body.Archive) == 0 {
+		t.Fatalf("unexpected export: %q, %d bytes", exported.Body.FileName, len(exported.Body.Archive))
+	}
+
+	dst := newTestSkillStore(t)
+	imported, err := dst.ImportSkillBundle(t.Context(), &spec.ImportSkillBundleRequest{
+		Body: &spec.ImportSkillBundleRequestBody{Archive: exported.Body.Archive, BundleID: "imp"},
+	})
+	if err != nil {
+		t.Fatalf("ImportSkillBundle: %v", err)
+	}
+	if got := imported.Body.SkillBundle; got.ID != "imp" || got.Slug != "exported" || !got.IsEnabled {
+		t.Fatalf("unexpected bundle: %+v", got)
+	}
+	if len(imported.Body.Skills) != 1 {
+		t.Fatalf("want 1 skill, got %d", len(imported.Body.Skills))
+	}
+	sk := imported.Body.Skills[0]
+	if !isManagedSkillPackageLocation(dst.baseDir, "imp", "arch-skill", sk.Location) {
+		t.Fatalf("location %q is not managed", sk.Location)
+	}
+	if sk.Slug != "s1" || len(sk.Tags) != 1 || sk.Tags[0] != "t1" {
+		t.Fatalf("unexpected skill: %+v", sk)
+	}
+	if b, err := os.ReadFile(filepath.Join(sk.Location, "references", "notes.md")); err != nil ||
+		string(b) != "notes" {
+		t.Fatalf("resource not extracted: %q, %v", b, err)
+	}
+
+	_, err = dst.ImportSkillBundle(t.Context(), &spec.ImportSkillBundleRequest{
+		Body: &spec.ImportSkillBundleRequestBody{Archive: exported.Body.Archive, BundleID: "imp"},
+	})
+	if !errors.Is(err, errSkillConflict) {
+		t.Fatalf("reimport: want errSkillConflict, got %v", err)
+	}
+
+	_, err = dst.ImportSkillBundle(t.Context(), &spec.ImportSkillBundleRequest{
+		Body: &spec.ImportSkillBundleRequestBody{Archive: []byte("not an archive")},
+	})
+	if !errors.Is(err, errSkillInvalidRequest) {
+		t.Fatalf("garbage: want errSkillInvalidRequest, got %v", err)
+	}
+}
+
+func TestSkillStore_GetSkillContent_UserSkill(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	putBundle(t, s, "content", "content", "Content", true)
+
+	root := t.TempDir()
+	if err := putSkill(t, s, "content", "s1", root, "doc-skill", "documented", "HELLO BODY", false); err != nil {
+		t.Fatalf("putSkill: %v", err)
+	}
+	scriptsDir := filepath.Join(root, "doc-skill", "scripts")
+	if err := os.MkdirAll(scriptsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(scriptsDir, "run.sh"), []byte("echo hi"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.GetSkillContent(t.Context(), &spec.GetSkillContentRequest{
+		BundleID:     "content",
+		SkillSlug:    "s1",
+		IncludeFiles: true,
+	})
+	if err != nil {
+		t.Fatalf("GetSkillContent: %v", err)
+	}
+	body := resp.Body
+	if body.IsBuiltIn || body.Name != "doc-skill" || body.Description != "documented" {
+		t.Fatalf("unexpected content: %+v", body)
+	}
+	if !strings.Contains(body.MarkdownBody, "HELLO BODY") {
+		t.Fatalf("markdown body not returned: %q", body.MarkdownBody)
+	}
+	if len(body.Files) != 1 || body.Files[0].Path != "scripts/run.sh" || body.Files[0].Size != 7 {
+		t.Fatalf("unexpected files: %+v", body.Files)
+	}
+
+	_, err = s.GetSkillContent(t.Context(), &spec.GetSkillContentRequest{BundleID: "content", SkillSlug: "nope"})
+	if !errors.Is(err, errSkillNotFound) {
+		t.Fatalf("want errSkillNotFound, got %v", err)
+	}
+}
+
+func TestSkillStore_UpdateSkillContent(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	ctx := t.Context()
+	putBundle(t, s, "content", "content", "Content", true)
+
+	root := t.TempDir()
+	if err := putSkill(t, s, "content", "s1", root, "doc-skill", "documented", "OLD BODY", true); err != nil {
+		t.Fatalf("putSkill: %v", err)
+	}
+	path := filepath.Join(root, "doc-skill", skillMDFileName)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reindexed := 0
+	s.SetReindexHandler(func(_ context.Context, ref spec.SkillRef) error {
+		if ref.BundleID != "content" || ref.SkillSlug != "s1" || ref.SkillID == "" {
+			t.Errorf("reindex handler got %+v", ref)
+		}
+		reindexed++
+		return nil
+	})
+	resp, err := s.UpdateSkillContent(ctx, &spec.UpdateSkillContentRequest{
+		BundleID:  "content",
+		SkillSlug: "s1",
+		Body: &spec.UpdateSkillContentRequestBody{
+			Description:  "rewritten",
+			Frontmatter:  map[string]any{"license": "MIT", "name": "ignored"},
+			MarkdownBody: "# Doc Skill\n\nNEW BODY",
+		},
+	})
+	if err != nil {
+		t.Fatalf("UpdateSkillContent: %v", err)
+	}
+	got := resp.Body.Skill
+	if got.Name != "doc-skill" || got.Description != "rewritten" || got.DisplayName != "Doc Skill" {
+		t.Fatalf("unexpected skill: %+v", got)
+	}
+	if got.RawFrontmatter["license"] != "MIT" || got.Digest != skillDocumentDigest([]byte(resp.Body.Content)) {
+		t.Fatalf("frontmatter or digest not updated: %+v", got)
+	}
+	if got.Presence == nil || got.Presence.Status != spec.SkillPresencePresent {
+		t.Fatalf("presence not refreshed: %+v", got.Presence)
+	}
+	if raw, err := os.ReadFile(path); err != nil || string(raw) != resp.Body.Content ||
+		!strings.Contains(string(raw), "NEW BODY") {
+		t.Fatalf("SKILL.md not written: %q %v", raw, err)
+	}
+	if reindexed != 1 {
+		t.Fatalf("reindexed %d times, want 1", reindexed)
+	}
+	updated := resp.Body.Content
+
+	s.SetReindexHandler(func(context.Context, spec.SkillRef) error {
+		reindexed++
+		if reindexed == 2 {
+			return errors.New("rejected")
+		}
+		return nil
+	})
+	_, err = s.UpdateSkillContent(ctx, &spec.UpdateSkillContentRequest{
+		BundleID:  "content",
+		SkillSlug: "s1",
+		Body:      &spec.UpdateSkillContentRequestBody{Description: "bad", MarkdownBody: "BAD BODY"},
+	})
+	if !errors.Is(err, errSkillInvalidRequest) {
+		t.Fatalf("want errSkillInvalidRequest, got %v", err)
+	}
+	if reindexed != 3 {
+		t.Fatalf("rollback did not reindex: %d", reindexed)
+	}
+	if raw, err := os.ReadFile(path); err != nil || string(raw) != updated {
+		t.Fatalf("SKILL.md not rolled back: %q %v", raw, err)
+	}
+	all, err := readAllUserLocked(t, s, true)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if sk := all.Skills["content"]["s1"]; sk.Description != "rewritten" || sk.Digest != got.Digest {
+		t.Fatalf("record not rolled back: %+v", sk)
+	}
+	if string(before) == updated {
+		t.Fatal("update did not change SKILL.md")
+	}
+
+	tests := []struct {
+		name   string
+		req    *spec.UpdateSkillContentRequest
+		wantIs error
+	}{
+		{"nil-req", nil, errSkillInvalidRequest},
+		{
+			"missing-description",
+			&spec.UpdateSkillContentRequest{
+				BundleID: "content", SkillSlug: "s1",
+				Body: &spec.UpdateSkillContentRequestBody{MarkdownBody: "x"},
+			},
+			errSkillInvalidRequest,
+		},
+		{
+			"skill-missing",
+			&spec.UpdateSkillContentRequest{
+				BundleID: "content", SkillSlug: "nope",
+				Body: &spec.UpdateSkillContentRequestBody{Description: "d"},
+			},
+			errSkillNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.UpdateSkillContent(ctx, tc.req)
+			if !errors.Is(err, tc.wantIs) {
+				t.Fatalf("got %v, want %v", err, tc.wantIs)
+			}
+		})
+	}
+}
+
+func TestSkillStore_InstallRegistrySkill(t *testing.T) {
+	t.Parallel()
+
+	pkgDir := writeSkillPackage(t, t.TempDir(), "reg-skill", "registry desc", "REGISTRY BODY")
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := &skillBundleArchiveWriter{tw: tar.NewWriter(gz)}
+	if err := w.addDir(pkgDir, "reg-skill"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := buf.Bytes()
+	sum := sha256.Sum256(archive)
+
+	index := skillRegistryIndex{Skills: []spec.RegistrySkill{
+		{
+			Name: "reg-skill", Tags: []string{"docs"}, Version: "1.0.0",
+			ArchiveURL: "pkgs/reg-skill.tar.gz", SHA256: hex.EncodeToString(sum[:]),
+		},
+		{Name: "bad-sum", ArchiveURL: "pkgs/reg-skill.tar.gz", SHA256: strings.Repeat("0", 64)},
+	}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/registry/index.json", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(index)
+	})
+	mux.HandleFunc("/registry/pkgs/reg-skill.tar.gz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	registryURL := srv.URL + "/registry"
+
+	s := newTestSkillStore(t)
+	putBundle(t, s, "reg", "registry", "Registry", true)
+
+	if _, err := s.BrowseRegistrySkills(t.Context(), &spec.BrowseRegistrySkillsRequest{}); !errors.Is(
+		err, errSkillRegistryNotConfigured,
+	) {
+		t.Fatalf("expected errSkillRegistryNotConfigured, got %v", err)
+	}
+	browsed, err := s.BrowseRegistrySkills(t.Context(), &spec.BrowseRegistrySkillsRequest{
+		RegistryURL: registryURL, Query: "DOCS",
+	})
+	if err != nil {
+		t.Fatalf("BrowseRegistrySkills: %v", err)
+	}
+	if len(browsed.Body.Skills) != 1 || browsed.Body.Skills[0].Name != "reg-skill" {
+		t.Fatalf("unexpected browse result: %+v", browsed.Body.Skills)
+	}
+
+	_, err = s.InstallRegistrySkill(t.Context(), &spec.InstallRegistrySkillRequest{
+		BundleID: "reg",
+		Body:     &spec.InstallRegistrySkillRequestBody{RegistryURL: registryURL, Name: "bad-sum"},
+	})
+	if !errors.Is(err, errSkillInvalidRequest) || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+
+	installed, err := s.InstallRegistrySkill(t.Context(), &spec.InstallRegistrySkillRequest{
+		BundleID: "reg",
+		Body: &spec.InstallRegistrySkillRequestBody{
+			RegistryURL: registryURL, Name: "reg-skill", Version: "1.0.0", IsEnabled: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("InstallRegistrySkill: %v", err)
+	}
+	sk := installed.Body.Skill
+	if sk.Slug != "reg-skill" || sk.Description != "registry desc" || !slices.Equal(sk.Tags, []string{"docs"}) {
+		t.Fatalf("unexpected installed skill: %+v", sk)
+	}
+	if !isManagedSkillPackageLocation(s.baseDir, "reg", "reg-skill", sk.Location) {
+		t.Fatalf("skill not in a managed location: %s", sk.Location)
+	}
+	raw, err := os.ReadFile(filepath.Join(sk.Location, skillMDFileName))
+	if err != nil || !strings.Contains(string(raw), "REGISTRY BODY") {
+		t.Fatalf("SKILL.md not extracted: %v", err)
+	}
+
+	_, err = s.InstallRegistrySkill(t.Context(), &spec.InstallRegistrySkillRequest{
+		BundleID: "reg",
+		Body:     &spec.InstallRegistrySkillRequestBody{RegistryURL: registryURL, Name: "reg-skill"},
+	})
+	if !errors.Is(err, errSkillConflict) {
+		t.Fatalf("expected conflict on reinstall, got %v", err)
+	}
+}
+
+func TestSkillStore_ExportRestoreSkillStoreState(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+
+	src := newTestSkillStore(t)
+	putBundle(t, src, "st", "state", "State Bundle", true)
+	skillDir := t.TempDir()
+	if err := putSkill(t, src, "st", "s1", skillDir, "state-skill", "desc", "BODY", true); err != nil {
+		t.Fatalf("putSkill: %v", err)
+	}
+	bundles, _, err := src.builtin.ListBuiltInSkills(ctx)
+	if err != nil {
+		t.Fatalf("ListBuiltInSkills: %v", err)
+	}
+	var builtInID bundleitemutils.BundleID
+	for id := range bundles {
+		builtInID = id
+		break
+	}
+	if builtInID != "" {
+		if _, err := src.PatchSkillBundle(ctx, &spec.PatchSkillBundleRequest{
+			BundleID: builtInID, Body: &spec.PatchSkillBundleRequestBody{IsEnabled: false},
+		}); err != nil {
+			t.Fatalf("PatchSkillBundle(builtin): %v", err)
+		}
+	}
+
+	exported, err := src.ExportSkillStoreState(ctx, &spec.ExportSkillStoreStateRequest{})
+	if err != nil {
+		t.Fatalf("ExportSkillStoreState: %v", err)
+	}
+	state := exported.Body.State
+	locations, err := RestoredSkillLocations(state)
+	if err != nil || len(locations) != 1 || !strings.HasPrefix(locations[0], skillDir) {
+		t.Fatalf("RestoredSkillLocations = %v, %v; want one location under %s", locations, err, skillDir)
+	}
+
+	dst := newTestSkillStore(t)
+	putBundle(t, dst, "st", "state", "Old Name", true)
+
+	actions := func(changes []spec.SkillStoreStateChange) map[string]spec.SkillStoreStateAction {
+		out := map[string]spec.SkillStoreStateAction{}
+		for _, c := range changes {
+			out[string(c.Kind)+":"+string(c.BundleID)+"/"+string(c.SkillSlug)] = c.Action
+		}
+		return out
+	}
+
+	dry, err := dst.RestoreSkillStoreState(ctx, &spec.RestoreSkillStoreStateRequest{
+		Body: &spec.RestoreSkillStoreStateRequestBody{State: state, DryRun: true},
+	})
+	if err != nil {
+		t.Fatalf("RestoreSkillStoreState(dry): %v", err)
+	}
+	got := actions(dry.Body.Changes)
+	if got["bundle:st/"] != spec.SkillStoreStateOverwritten || got["skill:st/s1"] != spec.SkillStoreStateCreated {
+		t.Fatalf("unexpected dry-run changes: %v", got)
+	}
+	if builtInID != "" && got["builtInBundleFlag:"+string(builtInID)+"/"] != spec.SkillStoreStateCreated {
+		t.Fatalf("missing built-in flag change: %v", got)
+	}
+	if _, err := dst.GetSkill(ctx, &spec.GetSkillRequest{BundleID: "st", SkillSlug: "s1"}); !errors.Is(
+		err, errSkillNotFound,
+	) {
+		t.Fatalf("dry run wrote skill: %v", err)
+	}
+
+	if _, err := dst.RestoreSkillStoreState(ctx, &spec.RestoreSkillStoreStateRequest{
+		Body: &spec.RestoreSkillStoreStateRequestBody{State: state},
+	}); err != nil {
+		t.Fatalf("RestoreSkillStoreState: %v", err)
+	}
+	if _, err := dst.GetSkill(ctx, &spec.GetSkillRequest{BundleID: "st", SkillSlug: "s1"}); err != nil {
+		t.Fatalf("restored skill: %v", err)
+	}
+	if builtInID != "" {
+		b, err := dst.builtin.GetBuiltInSkillBundle(ctx, builtInID)
+		if err != nil || b.IsEnabled {
+			t.Fatalf("built-in bundle flag not restored: enabled=%v err=%v", b.IsEnabled, err)
+		}
+	}
+
+	again, err := dst.RestoreSkillStoreState(ctx, &spec.RestoreSkillStoreStateRequest{
+		Body: &spec.RestoreSkillStoreStateRequestBody{State: state, DryRun: true},
+	})
+	if err != nil {
+		t.Fatalf("RestoreSkillStoreState(again): %v", err)
+	}
+	for key, action := range actions(again.Body.Changes) {
+		if action != spec.SkillStoreStateSkipped {
+			t.Fatalf("%s: want skipped after restore, got %s", key, action)
+		}
+	}
+
+	state.SchemaVersion = "bogus"
+	if _, err := dst.RestoreSkillStoreState(ctx, &spec.RestoreSkillStoreStateRequest{
+		Body: &spec.RestoreSkillStoreStateRequestBody{State: state},
+	}); !errors.Is(err, errSkillInvalidRequest) {
+		t.Fatalf("want errSkillInvalidRequest, got %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+package skillstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/flexigpt/agentskills-go"
+	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+const maxSkillContentFiles = 1000
+
+// GetSkillContent returns the parsed SKILL.md of a built-in or user skill and,
+// on request, the files of its package. Disabled skills are included so the
+// UI can document them before they are enabled.
+func (s *SkillStore) GetSkillContent(
+	ctx context.Context,
+	req *spec.GetSkillContentRequest,
+) (*spec.GetSkillContentResponse, error) {
+	if req == nil || req.BundleID == "" || req.SkillSlug == "" {
+		return nil, fmt.Errorf("%w: bundleID and skillSlug required", errSkillInvalidRequest)
+	}
+	if err := bundleitemutils.ValidateItemSlug(req.SkillSlug); err != nil {
+		return nil, fmt.Errorf("%w: invalid skillSlug", errSkillInvalidRequest)
+	}
+
+	_, builtIn, err := s.getAnyBundle(ctx, req.BundleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		skill spec.Skill
+		pkg   fs.FS
+	)
+	if builtIn {
+		skill, pkg, err = s.builtin.BuiltInSkillPackageFS(ctx, req.BundleID, req.SkillSlug)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		s.mu.RLock()
+		user, err := s.readAllUser(ctx, false)
+		s.mu.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if skill, ok = user.Skills[req.BundleID][req.SkillSlug]; !ok {
+			return nil, fmt.Errorf("%w: %s", errSkillNotFound, req.SkillSlug)
+		}
+		if skill.Type != spec.SkillTypeFS {
+			return nil, fmt.Errorf("%w: unsupported skillType %q", errSkillInvalidRequest, skill.Type)
+		}
+		pkg = os.DirFS(skill.Location)
+	}
+
+	raw, err := fs.ReadFile(pkg, skillMDFileName)
+	if err != nil {
+		return nil, fmt.Errorf("read %s/%s: %w", req.BundleID, req.SkillSlug, err)
+	}
+	docs, err := parseSkillDocs(req.BundleID, req.SkillSlug, skill.Name, raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s/%s: %w", req.BundleID, req.SkillSlug, err)
+	}
+
+	body := &spec.GetSkillContentResponseBody{
+		GetBuiltInSkillDocsResponseBody: *docs,
+		IsBuiltIn:                       builtIn,
+	}
+	if req.IncludeFiles {
+		body.Files, body.FilesTruncated, err = listSkillPackageFiles(ctx, pkg)
+		if err != nil {
+			return nil, fmt.Errorf("list %s/%s: %w", req.BundleID, req.SkillSlug, err)
+		}
+	}
+	return &spec.GetSkillContentResponse{Body: body}, nil
+}
+
+// ReindexHandler re-indexes one installed skill and fails when the runtime
+// rejects it. Other installed skills are left alone.
+type ReindexHandler func(ctx context.Context, ref spec.SkillRef) error
+
+// SetReindexHandler registers the callback UpdateSkillContent uses to
+// validate an edited SKILL.md, typically a strict runtime resync of the
+// edited skill.
+func (s *SkillStore) SetReindexHandler(handler ReindexHandler) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reindexHandler = handler
+}
+
+// UpdateSkillContent rewrites the SKILL.md of a user filesystem skill and
+// refreshes the parsed fields, digest and presence of its record. The file
+// and the record are written under the store write lock. When the reindex
+// handler rejects the result, the previous file and record are put back.
+// Disabled skills are not indexed, so only the document itself is validated
+// for them.
+func (s *SkillStore) UpdateSkillContent(
+	ctx context.Context,
+	req *spec.UpdateSkillContentRequest,
+) (*spec.UpdateSkillContentResponse, error) {
+	if req == nil || req.Body == nil || req.BundleID == "" || req.SkillSlug == "" {
+		return nil, fmt.Errorf("%w: bundleID, skillSlug and body required", errSkillInvalidRequest)
+	}
+	if err := bundleitemutils.ValidateItemSlug(req.SkillSlug); err != nil {
+		return nil, fmt.Errorf("%w: invalid skillSlug", errSkillInvalidRequest)
+	}
+	if s.builtin != nil {
+		if _, err := s.builtin.GetBuiltInSkillBundle(ctx, req.BundleID); err == nil {
+			return nil, fmt.Errorf("%w: bundleID %q", errSkillBuiltInReadOnly, req.BundleID)
+		}
+	}
+
+	s.mu.RLock()
+	user, err := s.readAllUser(ctx, false)
+	handler := s.reindexHandler
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	prev, ok := user.Skills[req.BundleID][req.SkillSlug]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errSkillNotFound, req.SkillSlug)
+	}
+	if prev.Type != spec.SkillTypeFS {
+		return nil, fmt.Errorf("%w: unsupported skillType %q", errSkillInvalidRequest, prev.Type)
+	}
+
+	raw, err := agentskills.MarshalSkillDocument(agentskillsSpec.SkillDocument{
+		Name:           prev.Name,
+		DisplayName:    req.Body.DisplayName,
+		Description:    req.Body.Description,
+		Insert:         req.Body.Insert,
+		Arguments:      append([]spec.SkillArgument(nil), req.Body.Arguments...),
+		Tags:           append([]string(nil), req.Body.SourceTags...),
+		MarkdownBody:   req.Body.MarkdownBody,
+		RawFrontmatter: cloneAnyMap(req.Body.Frontmatter),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid SKILL.md: %w", errSkillInvalidRequest, err)
+	}
+	document, warnings, err := agentskills.ParseSkillDocument(
+		raw,
+		agentskillsSpec.ParseSkillDocumentOptions{ExpectedName: prev.Name},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: generated SKILL.md is invalid: %w", errSkillInvalidRequest, err)
+	}
+
+	path := filepath.Join(prev.Location, skillMDFileName)
+	var (
+		prevRaw []byte
+		written bool
+	)
+	// restoreFile puts the previous SKILL.md back unless someone replaced the
+	// edited file in the meantime.
+	restoreFile := func() {
+		if !written {
+			return
+		}
+		if cur, err := os.ReadFile(path); err != nil || !bytes.Equal(cur, raw) {
+			logger.Warn("SKILL.md changed after edit; not restoring", "path", path, "err", err)
+			return
+		}
+		var err error
+		if prevRaw == nil {
+			err = os.Remove(path)
+		} else {
+			err = os.WriteFile(path, prevRaw, 0o600)
+		}
+		if err != nil {
+			logger.Error("restore SKILL.md", "path", path, "err", err)
+		}
+	}
+
+	var updated spec.Skill
+	if err := s.withUserWrite(ctx, "updateSkillContent", func(sc *skillStoreSchema) error {
+		if b, ok := sc.Bundles[req.BundleID]; !ok {
+			return fmt.Errorf("%w: %s", errSkillBundleNotFound, req.BundleID)
+		} else if isSoftDeletedSkillBundle(b) {
+			return fmt.Errorf("%w: %s", errSkillBundleDeleting, req.BundleID)
+		}
+		sk, ok := sc.Skills[req.BundleID][req.SkillSlug]
+		if !ok || sk.ID != prev.ID || sk.Location != prev.Location {
+			return fmt.Errorf("%w: skill changed while editing", errSkillConflict)
+		}
+
+		now := time.Now().UTC()
+		sk.DisplayName = document.DisplayName
+		sk.Description = document.Description
+		sk.Insert = document.Insert
+		sk.Arguments = append([]spec.SkillArgument(nil), document.Arguments...)
+		sk.RawFrontmatter = cloneAnyMap(document.RawFrontmatter)
+		sk.RuntimeWarnings = append([]string(nil), warnings...)
+		sk.Digest = skillDocumentDigest(raw)
+		sk.Presence = nextPresence(sk.Presence, nil, now)
+		sk.ModifiedAt = now
+		if err := validateSkill(&sk); err != nil {
+			return err
+		}
+
+		var err error
+		prevRaw, err = os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err := os.WriteFile(path, raw, 0o600); err != nil {
+			return err
+		}
+		written = true
+		sc.Skills[req.BundleID][req.SkillSlug] = sk
+		updated = sk
+		return nil
+	}); err != nil {
+		restoreFile()
+		return nil, err
+	}
+
+	if handler != nil {
+		ref := spec.SkillRef{BundleID: req.BundleID, SkillSlug: req.SkillSlug, SkillID: prev.ID}
+		if err := handler(ctx, ref); err != nil {
+			restoreFile()
+			s.restoreSkillRecord(ctx, req.BundleID, prev, updated.ModifiedAt)
+			if rerr := handler(ctx, ref); rerr != nil {
+				logger.Error("reindex after SKILL.md rollback", "err", rerr)
+			}
+			return nil, fmt.Errorf("%w: runtime rejected SKILL.md: %w", errSkillInvalidRequest, err)
+		}
+	}
+
+	return &spec.UpdateSkillContentResponse{
+		Body: &spec.UpdateSkillContentResponseBody{Skill: cloneSkill(updated), Content: string(raw)},
+	}, nil
+}
+
+// restoreSkillRecord puts prev back unless the skill was modified again after
+// the write stamped modifiedAt.
+func (s *SkillStore) restoreSkillRecord(
+	ctx context.Context,
+	bundleID bundleitemutils.BundleID,
+	prev spec.Skill,
+	modifiedAt time.Time,
+) {
+	if err := s.withUserWrite(ctx, "restoreSkillRecord", func(sc *skillStoreSchema) error {
+		sk, ok := sc.Skills[bundleID][prev.Slug]
+		if !ok || sk.ID != prev.ID || !sk.ModifiedAt.Equal(modifiedAt) {
+			return nil
+		}
+		sc.Skills[bundleID][prev.Slug] = prev
+		return nil
+	}); err != nil {
+		logger.Error("restore skill record", "bundleID", bundleID, "skill", prev.Slug, "err", err)
+	}
+}
+
+// skillDocumentDigest matches the digest the filesystem provider reports for
+// an indexed SKILL.md.
+func skillDocumentDigest(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// listSkillPackageFiles lists regular files other than SKILL.md, in lexical
+// order, up to maxSkillContentFiles.
+func listSkillPackageFiles(ctx context.Context, pkg fs.FS) ([]spec.SkillContentFile, bool, error) {
+	var files []spec.SkillContentFile
+	truncated := false
+	err := fs.WalkDir(pkg, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || p == skillMDFileName {
+			return nil
+		}
+		if len(files) == maxSkillContentFiles {
+			truncated = true
+			return fs.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, spec.SkillContentFile{Path: p, Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return files, truncated, nil
+}
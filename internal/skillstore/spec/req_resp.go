@@ -1,39 +1,109 @@
 package spec
 
 import (
+	"time"
+
 	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
 	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/fsutil"
 )
 
 type PutSkillBundleRequestBody struct {
-	Slug        bundleitemutils.BundleSlug `json:"slug"                  required:"true"`
-	DisplayName string                     `json:"displayName"           required:"true"`
-	IsEnabled   bool                       `json:"isEnabled"             required:"true"`
-	Description string                     `json:"description,omitempty"`
+	Slug             bundleitemutils.BundleSlug  `json:"slug"                       required:"true"`
+	DisplayName      string                      `json:"displayName"                required:"true"`
+	IsEnabled        bool                        `json:"isEnabled"                  required:"true"`
+	Description      string                      `json:"description,omitempty"`
+	ActivationPolicy SkillBundleActivationPolicy `json:"activationPolicy,omitempty"`
+	// ExpectedModifiedAt, if set, makes replacing an existing bundle fail
+	// with a conflict unless the bundle is still at this modifiedAt.
+	ExpectedModifiedAt *time.Time `json:"expectedModifiedAt,omitempty"`
 }
 
 type PutSkillBundleRequest struct {
-	BundleID bundleitemutils.BundleID `path:"bundleID" required:"true"`
-	Body     *PutSkillBundleRequestBody
+	BundleID       bundleitemutils.BundleID `path:"bundleID" required:"true"`
+	IdempotencyKey string                   `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool                     `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body           *PutSkillBundleRequestBody
 }
 
-type PutSkillBundleResponse struct{}
+// PutSkillBundleResponseBody is the bundle a dry run would save.
+type PutSkillBundleResponseBody struct {
+	SkillBundle SkillBundle `json:"skillBundle"`
+}
+
+// PutSkillBundleResponse has a Body only for dry runs.
+type PutSkillBundleResponse struct{ Body *PutSkillBundleResponseBody }
 
 type DeleteSkillBundleRequest struct {
+	BundleID       bundleitemutils.BundleID `path:"bundleID" required:"true"`
+	IdempotencyKey string                   `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool                     `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+}
+
+// DeleteSkillBundleResponseBody is the soft-deleted bundle a dry run would save.
+type DeleteSkillBundleResponseBody struct {
+	SkillBundle SkillBundle `json:"skillBundle"`
+}
+
+// DeleteSkillBundleResponse has a Body only for dry runs.
+type DeleteSkillBundleResponse struct {
+	Body *DeleteSkillBundleResponseBody
+}
+
+// PurgeSkillBundleRequest hard-deletes a soft-deleted bundle without waiting
+// for the retention period to pass.
+type PurgeSkillBundleRequest struct {
 	BundleID bundleitemutils.BundleID `path:"bundleID" required:"true"`
 }
-type DeleteSkillBundleResponse struct{}
+type PurgeSkillBundleResponse struct{}
 
 type PatchSkillBundleRequestBody struct {
-	IsEnabled bool `json:"isEnabled" required:"true"`
+	IsEnabled          bool       `json:"isEnabled"                 required:"true"`
+	ExpectedModifiedAt *time.Time `json:"expectedModifiedAt,omitempty"`
 }
 
 type PatchSkillBundleRequest struct {
+	BundleID       bundleitemutils.BundleID `path:"bundleID" required:"true"`
+	IdempotencyKey string                   `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool                     `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body           *PatchSkillBundleRequestBody
+}
+
+// PatchSkillBundleResponseBody is the bundle a dry run would save.
+type PatchSkillBundleResponseBody struct {
+	SkillBundle SkillBundle `json:"skillBundle"`
+}
+
+// PatchSkillBundleResponse has a Body only for dry runs.
+type PatchSkillBundleResponse struct{ Body *PatchSkillBundleResponseBody }
+
+// SetSkillBundleActivationPolicyRequest sets the activation policy of a user
+// or built-in bundle.
+type SetSkillBundleActivationPolicyRequest struct {
 	BundleID bundleitemutils.BundleID `path:"bundleID" required:"true"`
-	Body     *PatchSkillBundleRequestBody
+	Body     *SetSkillBundleActivationPolicyRequestBody
 }
 
-type PatchSkillBundleResponse struct{}
+type SetSkillBundleActivationPolicyRequestBody struct {
+	ActivationPolicy SkillBundleActivationPolicy `json:"activationPolicy" required:"true"`
+}
+
+type SetSkillBundleActivationPolicyResponse struct{}
+
+// RenameSkillBundleRequestBody changes the slug and/or display name of a user
+// bundle. Empty fields are kept. Skills and selections refer to the bundle by
+// ID and stay valid.
+type RenameSkillBundleRequestBody struct {
+	Slug        bundleitemutils.BundleSlug `json:"slug,omitempty"`
+	DisplayName string                     `json:"displayName,omitempty"`
+}
+
+type RenameSkillBundleRequest struct {
+	BundleID bundleitemutils.BundleID `path:"bundleID" required:"true"`
+	Body     *RenameSkillBundleRequestBody
+}
+
+type RenameSkillBundleResponse struct{}
 
 // SkillBundlePageToken is a stable cursor for bundle listing.
 // Same style as BundlePageToken in tools.
@@ -70,15 +140,27 @@ type PutSkillRequestBody struct {
 	DisplayName string   `json:"displayName,omitempty"`
 	Description string   `json:"description,omitempty"`
 	Tags        []string `json:"tags,omitempty"`
+
+	// ExpectedBundleModifiedAt, if set, makes the create fail with a conflict
+	// unless the bundle is unchanged since it was read.
+	ExpectedBundleModifiedAt *time.Time `json:"expectedBundleModifiedAt,omitempty"`
 }
 
 type PutSkillRequest struct {
-	BundleID  bundleitemutils.BundleID `path:"bundleID"  required:"true"`
-	SkillSlug SkillSlug                `path:"skillSlug" required:"true"`
-	Body      *PutSkillRequestBody
+	BundleID       bundleitemutils.BundleID `path:"bundleID"  required:"true"`
+	SkillSlug      SkillSlug                `path:"skillSlug" required:"true"`
+	IdempotencyKey string                   `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool                     `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body           *PutSkillRequestBody
+}
+
+// PutSkillResponseBody is the skill a dry run would create.
+type PutSkillResponseBody struct {
+	Skill Skill `json:"skill"`
 }
 
-type PutSkillResponse struct{}
+// PutSkillResponse has a Body only for dry runs.
+type PutSkillResponse struct{ Body *PutSkillResponseBody }
 
 type PutSkillArtifactRequestBody struct {
 	// Name is the Agent Skills artifact name. If empty, SkillSlug is used.
@@ -115,11 +197,120 @@ type PutSkillArtifactResponseBody struct {
 }
 type PutSkillArtifactResponse struct{ Body *PutSkillArtifactResponseBody }
 
+// CreateSkillScaffoldRequestBody describes a new filesystem skill package.
+// The package is written to Directory/Name, or to a managed directory when
+// Directory is empty. Name defaults to the skill slug.
+type CreateSkillScaffoldRequestBody struct {
+	Name        string `json:"name,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Description string `json:"description"         required:"true"`
+	// Directory is an absolute parent directory for the package.
+	Directory string `json:"directory,omitempty"`
+	IsEnabled bool   `json:"isEnabled"`
+}
+
+type CreateSkillScaffoldRequest struct {
+	BundleID  bundleitemutils.BundleID `path:"bundleID"  required:"true"`
+	SkillSlug SkillSlug                `path:"skillSlug" required:"true"`
+	Body      *CreateSkillScaffoldRequestBody
+}
+
+type CreateSkillScaffoldResponseBody struct {
+	Skill Skill `json:"skill"`
+}
+type CreateSkillScaffoldResponse struct {
+	Body *CreateSkillScaffoldResponseBody
+}
+
+// DeleteSkillRequest soft-deletes a skill. UndeleteSkill restores it until
+// the retention period passes.
 type DeleteSkillRequest struct {
+	BundleID       bundleitemutils.BundleID `path:"bundleID"  required:"true"`
+	SkillSlug      SkillSlug                `path:"skillSlug" required:"true"`
+	IdempotencyKey string                   `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool                     `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+}
+
+// DeleteSkillResponseBody is the skill a dry run would delete.
+type DeleteSkillResponseBody struct {
+	Skill Skill `json:"skill"`
+}
+
+// DeleteSkillResponse has a Body only for dry runs.
+type DeleteSkillResponse struct{ Body *DeleteSkillResponseBody }
+
+// UndeleteSkillRequest restores a soft-deleted skill.
+type UndeleteSkillRequest struct {
+	BundleID  bundleitemutils.BundleID `path:"bundleID"  required:"true"`
+	SkillSlug SkillSlug                `path:"skillSlug" required:"true"`
+}
+type UndeleteSkillResponse struct{}
+
+// ListSoftDeletedRequest lists the user bundles and skills pending purge.
+type ListSoftDeletedRequest struct{}
+
+// SoftDeletedSkillBundle is a deleted bundle. The cleanup loop hard-deletes
+// it on its first sweep after PurgeAt.
+type SoftDeletedSkillBundle struct {
+	SkillBundle      SkillBundle `json:"skillBundle"`
+	PurgeAt          time.Time   `json:"purgeAt"`
+	RemainingGraceMS int64       `json:"remainingGraceMS"`
+}
+
+// SoftDeletedSkill is a deleted skill. A skill of a deleted bundle is purged
+// with the bundle if that comes first.
+type SoftDeletedSkill struct {
+	BundleID         bundleitemutils.BundleID `json:"bundleID"`
+	Skill            Skill                    `json:"skill"`
+	PurgeAt          time.Time                `json:"purgeAt"`
+	RemainingGraceMS int64                    `json:"remainingGraceMS"`
+}
+
+type ListSoftDeletedResponseBody struct {
+	// Both lists are sorted newest deletion first.
+	SkillBundles []SoftDeletedSkillBundle `json:"skillBundles"`
+	Skills       []SoftDeletedSkill       `json:"skills"`
+}
+
+type ListSoftDeletedResponse struct {
+	Body *ListSoftDeletedResponseBody
+}
+
+type MoveSkillRequestBody struct {
+	TargetBundleID bundleitemutils.BundleID `json:"targetBundleID" required:"true"`
+}
+
+// MoveSkillRequest relocates a user skill to another enabled user bundle. The
+// skill keeps its ID, CreatedAt and usage.
+type MoveSkillRequest struct {
 	BundleID  bundleitemutils.BundleID `path:"bundleID"  required:"true"`
 	SkillSlug SkillSlug                `path:"skillSlug" required:"true"`
+	Body      *MoveSkillRequestBody
+}
+
+type MoveSkillResponse struct{}
+
+// CloneSkillRequestBody copies a user skill under NewSkillSlug, into
+// TargetBundleID or the source bundle when empty. CopyPackage, required for
+// filesystem skills, copies the package to a managed directory and renames
+// its SKILL.md to NewSkillSlug.
+type CloneSkillRequestBody struct {
+	NewSkillSlug   SkillSlug                `json:"newSkillSlug"             required:"true"`
+	TargetBundleID bundleitemutils.BundleID `json:"targetBundleID,omitempty"`
+	DisplayName    string                   `json:"displayName,omitempty"`
+	CopyPackage    bool                     `json:"copyPackage,omitempty"`
 }
-type DeleteSkillResponse struct{}
+
+type CloneSkillRequest struct {
+	BundleID  bundleitemutils.BundleID `path:"bundleID"  required:"true"`
+	SkillSlug SkillSlug                `path:"skillSlug" required:"true"`
+	Body      *CloneSkillRequestBody
+}
+
+type CloneSkillResponseBody struct {
+	Skill Skill `json:"skill"`
+}
+type CloneSkillResponse struct{ Body *CloneSkillResponseBody }
 
 type PatchSkillRequestBody struct {
 	// Built-in skills: only IsEnabled is supported.
@@ -129,15 +320,27 @@ type PatchSkillRequestBody struct {
 	DisplayName *string   `json:"displayName,omitempty"`
 	Description *string   `json:"description,omitempty"`
 	Tags        *[]string `json:"tags,omitempty"` // pointer so caller can send [] to clear
+
+	// ExpectedModifiedAt, if set, makes the patch fail with a conflict unless
+	// the skill is still at this modifiedAt.
+	ExpectedModifiedAt *time.Time `json:"expectedModifiedAt,omitempty"`
 }
 
 type PatchSkillRequest struct {
-	BundleID  bundleitemutils.BundleID `path:"bundleID"  required:"true"`
-	SkillSlug SkillSlug                `path:"skillSlug" required:"true"`
-	Body      *PatchSkillRequestBody
+	BundleID       bundleitemutils.BundleID `path:"bundleID"  required:"true"`
+	SkillSlug      SkillSlug                `path:"skillSlug" required:"true"`
+	IdempotencyKey string                   `query:"idempotencyKey" doc:"Replays the first result to retries." required:"false"`
+	DryRun         bool                     `query:"dryRun" doc:"Validate and return the result without saving." required:"false"`
+	Body           *PatchSkillRequestBody
+}
+
+// PatchSkillResponseBody is the skill a dry run would save.
+type PatchSkillResponseBody struct {
+	Skill Skill `json:"skill"`
 }
 
-type PatchSkillResponse struct{}
+// PatchSkillResponse has a Body only for dry runs.
+type PatchSkillResponse struct{ Body *PatchSkillResponseBody }
 
 type GetSkillRequest struct {
 	BundleID        bundleitemutils.BundleID `path:"bundleID"  required:"true"`
@@ -146,11 +349,58 @@ type GetSkillRequest struct {
 }
 type GetSkillResponse struct{ Body *Skill }
 
+type GetSkillsBatchRequestBody struct {
+	// SkillRefs names the skills to fetch; SkillID is ignored.
+	SkillRefs       []SkillRef `json:"skillRefs"                 required:"true"`
+	IncludeDisabled bool       `json:"includeDisabled,omitempty"`
+}
+
+type GetSkillsBatchRequest struct {
+	Body *GetSkillsBatchRequestBody
+}
+
+// GetSkillsBatchItem holds either the skill or why it could not be fetched.
+type GetSkillsBatchItem struct {
+	SkillRef SkillRef `json:"skillRef"`
+	Skill    *Skill   `json:"skill,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+type GetSkillsBatchResponseBody struct {
+	// Items follow the order of the requested SkillRefs.
+	Items []GetSkillsBatchItem `json:"items"`
+}
+
+type GetSkillsBatchResponse struct {
+	Body *GetSkillsBatchResponseBody
+}
+
 type ListSkillPhase string
 
 const (
 	ListSkillPhaseBuiltIn ListSkillPhase = "builtin"
 	ListSkillPhaseUser    ListSkillPhase = "user"
+	// ListSkillPhaseMerged lists built-in and user skills as one sorted stream.
+	ListSkillPhaseMerged ListSkillPhase = "merged"
+)
+
+// SkillListOrder selects how ListSkills orders items. Unless the listing is
+// merged, built-ins are listed before user skills and the order applies
+// within each group.
+type SkillListOrder string
+
+const (
+	// SkillListOrderDefault lists built-ins by bundle ID and slug, and user
+	// skills and merged listings newest modified first.
+	SkillListOrderDefault        SkillListOrder = ""
+	SkillListOrderModifiedAtDesc SkillListOrder = "modifiedAtDesc"
+	SkillListOrderCreatedAtDesc  SkillListOrder = "createdAtDesc"
+	// SkillListOrderNameAsc sorts case-insensitively by display name, falling
+	// back to the SKILL.md name.
+	SkillListOrderNameAsc  SkillListOrder = "nameAsc"
+	SkillListOrderNameDesc SkillListOrder = "nameDesc"
+	// SkillListOrderBundle sorts by bundle slug, then skill slug.
+	SkillListOrderBundle SkillListOrder = "bundle"
 )
 
 // SkillPageToken for paging skills across bundles.
@@ -163,11 +413,14 @@ type SkillPageToken struct {
 	Types               []SkillType                   `json:"ty,omitempty"`   //nolint:tagliatelle // Page token specific. // optional filter
 	Inserts             []agentskillsSpec.SkillInsert `json:"in,omitempty"`   //nolint:tagliatelle // Page token specific.
 	Tags                []string                      `json:"tags,omitempty"` //nolint:tagliatelle // Page token specific.
+	OrderBy             SkillListOrder                `json:"ob,omitempty"`   //nolint:tagliatelle // Page token specific.
 	Phase               ListSkillPhase                `json:"ph,omitempty"`   //nolint:tagliatelle //nolint:tagliatelle // Page token specific.
 	BuiltInCursor       string                        `json:"bc,omitempty"`   //nolint:tagliatelle // opaque: last (bundleID|skillSlug)
-	DirTok              string                        `json:"dt,omitempty"`   //nolint:tagliatelle // user cursor
+	DirTok              string                        `json:"dt,omitempty"`   //nolint:tagliatelle // user or merged cursor
 }
 
+// ListSkillsRequest lists skills. Merged sorts built-in and user skills
+// together instead of listing all built-ins first.
 type ListSkillsRequest struct {
 	BundleIDs           []bundleitemutils.BundleID    `query:"bundleIDs"`
 	Types               []SkillType                   `query:"types"`
@@ -175,6 +428,8 @@ type ListSkillsRequest struct {
 	Tags                []string                      `query:"tags"`
 	IncludeDisabled     bool                          `query:"includeDisabled"`
 	IncludeMissing      bool                          `query:"includeMissing"`
+	OrderBy             SkillListOrder                `query:"orderBy"`
+	Merged              bool                          `query:"merged"`
 	RecommendedPageSize int                           `query:"recommendedPageSize"`
 	PageToken           string                        `query:"pageToken"`
 }
@@ -187,6 +442,12 @@ type SkillListItem struct {
 	IsBuiltIn bool      `json:"isBuiltIn"`
 
 	SkillDefinition Skill `json:"skillDefinition"`
+
+	// Usage is nil for skills that were never activated.
+	Usage *SkillUsage `json:"usage,omitempty"`
+	// Conflict is set while another enabled skill resolves to the same
+	// runtime type and name.
+	Conflict *SkillConflict `json:"conflict,omitempty"`
 }
 
 type ListSkillsResponseBody struct {
@@ -196,3 +457,430 @@ type ListSkillsResponseBody struct {
 type ListSkillsResponse struct {
 	Body *ListSkillsResponseBody
 }
+
+// CountSkillsRequest takes the ListSkills filters.
+type CountSkillsRequest struct {
+	BundleIDs       []bundleitemutils.BundleID    `query:"bundleIDs"`
+	Types           []SkillType                   `query:"types"`
+	Inserts         []agentskillsSpec.SkillInsert `query:"inserts"`
+	Tags            []string                      `query:"tags"`
+	IncludeDisabled bool                          `query:"includeDisabled"`
+	IncludeMissing  bool                          `query:"includeMissing"`
+}
+
+// CountSkillsResponseBody counts matching skills. A skill in a disabled
+// bundle counts as disabled.
+type CountSkillsResponseBody struct {
+	Count         int `json:"count"`
+	DisabledCount int `json:"disabledCount"`
+}
+
+type CountSkillsResponse struct {
+	Body *CountSkillsResponseBody
+}
+
+// ResetBuiltInOverridesRequest resets the listed built-in bundles, or all
+// built-in bundles when BundleIDs is empty.
+type ResetBuiltInOverridesRequest struct {
+	BundleIDs []bundleitemutils.BundleID `query:"bundleIDs"`
+}
+
+type ResetBuiltInOverridesResponseBody struct {
+	ResetBundleIDs []bundleitemutils.BundleID `json:"resetBundleIDs"`
+}
+
+type ResetBuiltInOverridesResponse struct {
+	Body *ResetBuiltInOverridesResponseBody
+}
+
+type GetBuiltInSkillDocsRequest struct {
+	BundleID  bundleitemutils.BundleID `path:"bundleID"  required:"true"`
+	SkillSlug SkillSlug                `path:"skillSlug" required:"true"`
+}
+
+// GetBuiltInSkillDocsResponseBody is the parsed SKILL.md of a built-in skill.
+// It is read straight from the embedded package and needs no runtime session.
+type GetBuiltInSkillDocsResponseBody struct {
+	BundleID  bundleitemutils.BundleID `json:"bundleID"`
+	SkillSlug SkillSlug                `json:"skillSlug"`
+
+	Name        string                          `json:"name"`
+	DisplayName string                          `json:"displayName,omitempty"`
+	Description string                          `json:"description,omitempty"`
+	Insert      agentskillsSpec.SkillInsert     `json:"insert"`
+	Arguments   []agentskillsSpec.SkillArgument `json:"arguments,omitempty"`
+	SourceTags  []string                        `json:"sourceTags,omitempty"`
+
+	// Frontmatter is the full parsed YAML frontmatter.
+	Frontmatter map[string]any `json:"frontmatter,omitempty"`
+	// MarkdownBody is the SKILL.md body after the frontmatter.
+	MarkdownBody string `json:"markdownBody"`
+	// Content is the raw SKILL.md document.
+	Content  string   `json:"content"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type GetBuiltInSkillDocsResponse struct {
+	Body *GetBuiltInSkillDocsResponseBody
+}
+
+type ExportSkillBundleRequest struct {
+	BundleID bundleitemutils.BundleID `path:"bundleID" required:"true"`
+}
+
+// ExportSkillBundleResponseBody carries a tar.gz with the bundle metadata and
+// the files of every filesystem skill in it.
+type ExportSkillBundleResponseBody struct {
+	FileName string `json:"fileName"`
+	Archive  []byte `json:"archive"`
+}
+
+type ExportSkillBundleResponse struct {
+	Body *ExportSkillBundleResponseBody
+}
+
+type ImportSkillBundleRequestBody struct {
+	Archive []byte `json:"archive" required:"true"`
+
+	// BundleID is the ID of the new bundle. If empty, a new ID is generated.
+	BundleID bundleitemutils.BundleID `json:"bundleID,omitempty"`
+	// Slug overrides the slug recorded in the archive.
+	Slug SkillBundleSlug `json:"slug,omitempty"`
+}
+
+type ImportSkillBundleRequest struct {
+	Body *ImportSkillBundleRequestBody
+}
+
+type ImportSkillBundleResponseBody struct {
+	SkillBundle SkillBundle `json:"skillBundle"`
+	Skills      []Skill     `json:"skills"`
+}
+
+type ImportSkillBundleResponse struct {
+	Body *ImportSkillBundleResponseBody
+}
+
+// TriggerPresenceCheckRequest checks user filesystem skills now, optionally
+// only those in BundleIDs.
+type TriggerPresenceCheckRequest struct {
+	BundleIDs []bundleitemutils.BundleID `query:"bundleIDs"`
+}
+
+type TriggerPresenceCheckResponseBody struct {
+	CheckedAt time.Time `json:"checkedAt"`
+	Checked   int       `json:"checked"`
+	Present   int       `json:"present"`
+	Missing   int       `json:"missing"`
+	Errored   int       `json:"errored"`
+}
+
+type TriggerPresenceCheckResponse struct {
+	Body *TriggerPresenceCheckResponseBody
+}
+
+type GetSkillContentRequest struct {
+	BundleID  bundleitemutils.BundleID `path:"bundleID"  required:"true"`
+	SkillSlug SkillSlug                `path:"skillSlug" required:"true"`
+
+	// IncludeFiles lists the auxiliary files of the skill package.
+	IncludeFiles bool `query:"includeFiles"`
+}
+
+// SkillContentFile is one file of a skill package, relative to its root.
+type SkillContentFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// GetSkillContentResponseBody is the parsed SKILL.md of a built-in or user
+// skill, read from its package regardless of enabled state.
+type GetSkillContentResponseBody struct {
+	GetBuiltInSkillDocsResponseBody
+
+	IsBuiltIn bool `json:"isBuiltIn"`
+
+	Files          []SkillContentFile `json:"files,omitempty"`
+	FilesTruncated bool               `json:"filesTruncated,omitempty"`
+}
+
+type GetSkillContentResponse struct {
+	Body *GetSkillContentResponseBody
+}
+
+// UpdateSkillContentRequestBody replaces the SKILL.md of a user filesystem
+// skill. The frontmatter name is kept. Frontmatter carries any other keys;
+// the fields below replace their keys in it.
+type UpdateSkillContentRequestBody struct {
+	DisplayName string                          `json:"displayName,omitempty"`
+	Description string                          `json:"description"            required:"true"`
+	Insert      agentskillsSpec.SkillInsert     `json:"insert,omitempty"`
+	Arguments   []agentskillsSpec.SkillArgument `json:"arguments,omitempty"`
+	SourceTags  []string                        `json:"sourceTags,omitempty"`
+
+	Frontmatter  map[string]any `json:"frontmatter,omitempty"`
+	MarkdownBody string         `json:"markdownBody"`
+}
+
+type UpdateSkillContentRequest struct {
+	BundleID  bundleitemutils.BundleID `path:"bundleID"  required:"true"`
+	SkillSlug SkillSlug                `path:"skillSlug" required:"true"`
+	Body      *UpdateSkillContentRequestBody
+}
+
+type UpdateSkillContentResponseBody struct {
+	Skill   Skill  `json:"skill"`
+	Content string `json:"content"`
+}
+
+type UpdateSkillContentResponse struct {
+	Body *UpdateSkillContentResponseBody
+}
+
+type SearchSkillsRequest struct {
+	Query           string                     `query:"query"           required:"true"`
+	BundleIDs       []bundleitemutils.BundleID `query:"bundleIDs"`
+	IncludeDisabled bool                       `query:"includeDisabled"`
+	IncludeMissing  bool                       `query:"includeMissing"`
+	Limit           int                        `query:"limit"`
+}
+
+// SkillSearchSpan is a byte range of a matched term within a snippet.
+type SkillSearchSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// SkillSearchHighlight shows where one field matched. Field is a skill field
+// name, "frontmatter.<key>" or "body" for the SKILL.md markdown body.
+type SkillSearchHighlight struct {
+	Field   string            `json:"field"`
+	Snippet string            `json:"snippet"`
+	Spans   []SkillSearchSpan `json:"spans"`
+}
+
+type SkillSearchResult struct {
+	SkillListItem
+
+	Score      float64                `json:"score"`
+	Highlights []SkillSearchHighlight `json:"highlights"`
+}
+
+type SearchSkillsResponseBody struct {
+	Results []SkillSearchResult `json:"results"`
+}
+
+type SearchSkillsResponse struct {
+	Body *SearchSkillsResponseBody
+}
+
+type GetSkillStatsRequest struct {
+	BundleIDs []bundleitemutils.BundleID `query:"bundleIDs"`
+}
+
+type SkillStats struct {
+	BundleID  bundleitemutils.BundleID `json:"bundleID"`
+	SkillSlug SkillSlug                `json:"skillSlug"`
+	IsBuiltIn bool                     `json:"isBuiltIn"`
+	IsEnabled bool                     `json:"isEnabled"`
+	Usage     SkillUsage               `json:"usage"`
+}
+
+type GetSkillStatsResponseBody struct {
+	// Stats covers every skill, unused ones included, most used first.
+	Stats []SkillStats `json:"stats"`
+}
+
+type GetSkillStatsResponse struct {
+	Body *GetSkillStatsResponseBody
+}
+
+// RegistrySkill is one entry of a skill registry index.
+type RegistrySkill struct {
+	Name        string   `json:"name"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Version     string   `json:"version,omitempty"`
+
+	// ArchiveURL points to a tar.gz of the skill package. Relative URLs are
+	// resolved against the registry base URL.
+	ArchiveURL string `json:"archiveURL"`
+	// SHA256 is the hex encoded checksum of the archive.
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+type BrowseRegistrySkillsRequest struct {
+	// RegistryURL overrides the store's configured registry base URL.
+	RegistryURL string `query:"registryURL"`
+	Query       string `query:"query"`
+}
+
+type BrowseRegistrySkillsResponseBody struct {
+	RegistryURL string          `json:"registryURL"`
+	Skills      []RegistrySkill `json:"skills"`
+}
+
+type BrowseRegistrySkillsResponse struct {
+	Body *BrowseRegistrySkillsResponseBody
+}
+
+type InstallRegistrySkillRequestBody struct {
+	// RegistryURL overrides the store's configured registry base URL.
+	RegistryURL string `json:"registryURL,omitempty"`
+	Name        string `json:"name"                  required:"true"`
+	// Version pins an index entry; empty takes the first entry for Name.
+	Version string `json:"version,omitempty"`
+	// SkillSlug defaults to Name.
+	SkillSlug SkillSlug `json:"skillSlug,omitempty"`
+	IsEnabled bool      `json:"isEnabled"             required:"true"`
+}
+
+type InstallRegistrySkillRequest struct {
+	BundleID bundleitemutils.BundleID `path:"bundleID" required:"true"`
+	Body     *InstallRegistrySkillRequestBody
+}
+
+type InstallRegistrySkillResponseBody struct {
+	Skill         Skill         `json:"skill"`
+	RegistrySkill RegistrySkill `json:"registrySkill"`
+}
+
+type InstallRegistrySkillResponse struct {
+	Body *InstallRegistrySkillResponseBody
+}
+
+type BatchPatchSkillsRequestBody struct {
+	// SkillRefs names individual skills; SkillID is ignored.
+	SkillRefs []SkillRef `json:"skillRefs,omitempty"`
+	// BundleIDs selects every skill of each bundle.
+	BundleIDs []bundleitemutils.BundleID `json:"bundleIDs,omitempty"`
+	IsEnabled bool                       `json:"isEnabled" required:"true"`
+}
+
+type BatchPatchSkillsRequest struct {
+	Body *BatchPatchSkillsRequestBody
+}
+
+type BatchPatchSkillsResponseBody struct {
+	// Patched lists the selected skills in bundle and slug order.
+	Patched []SkillRef `json:"patched"`
+}
+
+type BatchPatchSkillsResponse struct {
+	Body *BatchPatchSkillsResponseBody
+}
+
+type ListSkillConflictsRequest struct{}
+
+type ListSkillConflictsResponseBody struct {
+	Conflicts []SkillConflict `json:"conflicts"`
+}
+
+type ListSkillConflictsResponse struct {
+	Body *ListSkillConflictsResponseBody
+}
+
+type ExportSkillStoreStateRequest struct{}
+
+type ExportSkillStoreStateResponseBody struct {
+	State SkillStoreState `json:"state"`
+}
+
+type ExportSkillStoreStateResponse struct {
+	Body *ExportSkillStoreStateResponseBody
+}
+
+type RestoreSkillStoreStateRequestBody struct {
+	State SkillStoreState `json:"state"  required:"true"`
+	// DryRun reports the changes without writing anything.
+	DryRun bool `json:"dryRun"`
+}
+
+// RestoreSkillStoreStateRequest merges a state dump into the store. Entries
+// missing from the dump are kept.
+type RestoreSkillStoreStateRequest struct {
+	Body *RestoreSkillStoreStateRequestBody
+}
+
+type RestoreSkillStoreStateResponseBody struct {
+	DryRun  bool                    `json:"dryRun"`
+	Changes []SkillStoreStateChange `json:"changes"`
+}
+
+type RestoreSkillStoreStateResponse struct {
+	Body *RestoreSkillStoreStateResponseBody
+}
+
+type GetBuiltInSkillsUpdateRequest struct{}
+
+type GetBuiltInSkillsUpdateResponseBody struct {
+	// Update is nil until the built-in skills have been replaced once.
+	Update *BuiltInSkillsUpdate `json:"update,omitempty"`
+}
+
+type GetBuiltInSkillsUpdateResponse struct {
+	Body *GetBuiltInSkillsUpdateResponseBody
+}
+
+type UpdateBuiltInSkillsRequestBody struct {
+	// DryRun computes the diff against the hydrated skills without
+	// replacing them.
+	DryRun bool `json:"dryRun"`
+}
+
+// UpdateBuiltInSkillsRequest brings the hydrated built-in skills in line
+// with the embedded ones.
+type UpdateBuiltInSkillsRequest struct {
+	Body *UpdateBuiltInSkillsRequestBody
+}
+
+type UpdateBuiltInSkillsResponseBody struct {
+	// Applied is false for dry runs and when nothing differed.
+	Applied bool                `json:"applied"`
+	Update  BuiltInSkillsUpdate `json:"update"`
+}
+
+type UpdateBuiltInSkillsResponse struct {
+	Body *UpdateBuiltInSkillsResponseBody
+}
+
+type MarkBuiltInSkillsUpdateSeenRequest struct{}
+
+type MarkBuiltInSkillsUpdateSeenResponse struct{}
+
+// RetrySkillStoreWritableRequest retries writing the user store after it
+// turned read-only.
+type RetrySkillStoreWritableRequest struct{}
+
+type RetrySkillStoreWritableResponseBody struct {
+	fsutil.WriteStatus
+}
+
+type RetrySkillStoreWritableResponse struct {
+	Body *RetrySkillStoreWritableResponseBody
+}
+
+type ListSkillStoreBackupsRequest struct{}
+
+type ListSkillStoreBackupsResponseBody struct {
+	// Backups of the user store file, newest first.
+	Backups []fsutil.BackupInfo `json:"backups"`
+}
+
+type ListSkillStoreBackupsResponse struct {
+	Body *ListSkillStoreBackupsResponseBody
+}
+
+type RestoreSkillStoreFromBackupRequestBody struct {
+	Name string `json:"name" required:"true"`
+}
+
+// RestoreSkillStoreFromBackupRequest replaces the user store with a backup.
+// The replaced content is backed up first.
+type RestoreSkillStoreFromBackupRequest struct {
+	Body *RestoreSkillStoreFromBackupRequestBody
+}
+
+type RestoreSkillStoreFromBackupResponse struct{}
@@ -1,6 +1,7 @@
 package spec
 
 import (
+	"encoding/json"
 	"time"
 
 	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
@@ -12,6 +13,8 @@ const (
 
 	SkillBundlesMetaFileName      = "skills.bundles.json"
 	SkillBuiltInOverlayDBFileName = "skillsbuiltin.overlay.sqlite" // optional: built-in overlay index
+	SkillBuiltInUpdateFileName    = "skillsbuiltin.update.json"    // last built-in skills update
+	SkillBundlesJournalFileName   = "skills.bundles.journal.jsonl" // pending writes to skills.bundles.json
 
 	// BaseSkillBundleID is the default writable bundle for user-created skill artifacts.
 	BaseSkillBundleID          bundleitemutils.BundleID   = "019d3150-6a12-7a6b-a34e-d9032342bc31"
@@ -29,6 +32,16 @@ const (
 	SkillTypeEmbeddedFS SkillType = "embeddedfs" // built-in embedded FS (read-only except enable/disable)
 )
 
+// SkillBundleActivationPolicy says whether new skill sessions pre-activate
+// the skills of a bundle. Empty means on-demand.
+type SkillBundleActivationPolicy string
+
+const (
+	SkillBundleActivationOnDemand     SkillBundleActivationPolicy = "on-demand"     // active only when requested
+	SkillBundleActivationAlwaysActive SkillBundleActivationPolicy = "always-active" // active unless refs are explicit
+	SkillBundleActivationNever        SkillBundleActivationPolicy = "never"         // never pre-activated
+)
+
 // SkillPresenceStatus tracks whether the skill was observed to exist at its location.
 type SkillPresenceStatus string
 
@@ -43,10 +56,10 @@ const (
 type SkillPresence struct {
 	Status SkillPresenceStatus `json:"status"`
 
-	// LastCheckedAt is when we last attempted to validate presence.
+	// LastCheckedAt is when a check last changed the status.
 	LastCheckedAt *time.Time `json:"lastCheckedAt,omitempty"`
 
-	// LastSeenAt is when the location was last confirmed present.
+	// LastSeenAt is when the location was confirmed present as of that change.
 	LastSeenAt *time.Time `json:"lastSeenAt,omitempty"`
 
 	// MissingSince is set when we transition into "missing".
@@ -75,6 +88,85 @@ type SkillRef struct {
 	SkillID   SkillID       `json:"skillID"`
 }
 
+// SkillStoreState is a full dump of user skill state: the skill store
+// document plus the enabled flags set on built-in bundles and skills. Files
+// of skill packages are not included.
+type SkillStoreState struct {
+	SchemaVersion string    `json:"schemaVersion"`
+	ExportedAt    time.Time `json:"exportedAt"`
+
+	// Store is the skills.bundles.json document.
+	Store json.RawMessage `json:"store"`
+
+	BuiltInBundleFlags map[SkillBundleID]bool               `json:"builtInBundleFlags,omitempty"`
+	BuiltInSkillFlags  map[SkillBundleID]map[SkillSlug]bool `json:"builtInSkillFlags,omitempty"`
+}
+
+// SkillStoreStateChangeKind names what a restore change applies to.
+type SkillStoreStateChangeKind string
+
+const (
+	SkillStoreStateChangeBundle            SkillStoreStateChangeKind = "bundle"
+	SkillStoreStateChangeSkill             SkillStoreStateChangeKind = "skill"
+	SkillStoreStateChangeBuiltInBundleFlag SkillStoreStateChangeKind = "builtInBundleFlag"
+	SkillStoreStateChangeBuiltInSkillFlag  SkillStoreStateChangeKind = "builtInSkillFlag"
+)
+
+type SkillStoreStateAction string
+
+const (
+	SkillStoreStateCreated     SkillStoreStateAction = "created"
+	SkillStoreStateOverwritten SkillStoreStateAction = "overwritten"
+	SkillStoreStateSkipped     SkillStoreStateAction = "skipped"
+)
+
+// SkillStoreStateChange is one outcome of a restore. Reason explains skips.
+type SkillStoreStateChange struct {
+	Kind      SkillStoreStateChangeKind `json:"kind"`
+	Action    SkillStoreStateAction     `json:"action"`
+	BundleID  SkillBundleID             `json:"bundleID"`
+	SkillSlug SkillSlug                 `json:"skillSlug,omitempty"`
+	Reason    string                    `json:"reason,omitempty"`
+}
+
+// BuiltInSkillChangeKind says how a built-in skill differs between two
+// versions of the embedded skills.
+type BuiltInSkillChangeKind string
+
+const (
+	BuiltInSkillAdded   BuiltInSkillChangeKind = "added"
+	BuiltInSkillRemoved BuiltInSkillChangeKind = "removed"
+	BuiltInSkillChanged BuiltInSkillChangeKind = "changed"
+)
+
+type BuiltInSkillChange struct {
+	Kind      BuiltInSkillChangeKind `json:"kind"`
+	BundleID  SkillBundleID          `json:"bundleID"`
+	SkillSlug SkillSlug              `json:"skillSlug"`
+	Name      string                 `json:"name"`
+}
+
+// BuiltInSkillsUpdate describes a replacement of the hydrated built-in
+// skills by the ones embedded in the running app.
+type BuiltInSkillsUpdate struct {
+	FromDigest string               `json:"fromDigest,omitempty"`
+	ToDigest   string               `json:"toDigest"`
+	UpdatedAt  time.Time            `json:"updatedAt"`
+	Changes    []BuiltInSkillChange `json:"changes"`
+	// SnapshotDir holds the previously hydrated files; empty when there
+	// were none.
+	SnapshotDir string `json:"snapshotDir,omitempty"`
+	Seen        bool   `json:"seen"`
+}
+
+// SkillConflict groups enabled skills that resolve to the same runtime type
+// and name, as found by the last runtime resync.
+type SkillConflict struct {
+	RuntimeType string     `json:"runtimeType"`
+	RuntimeName string     `json:"runtimeName"`
+	Skills      []SkillRef `json:"skills"`
+}
+
 type SkillSelection struct {
 	SkillRef          SkillRef `json:"skillRef"`
 	PreLoadAsActive   bool     `json:"preLoadAsActive"`
@@ -139,6 +231,8 @@ type Skill struct {
 
 	CreatedAt  time.Time `json:"createdAt"`
 	ModifiedAt time.Time `json:"modifiedAt"`
+
+	SoftDeletedAt *time.Time `json:"softDeletedAt,omitempty"`
 }
 
 type SkillBundle struct {
@@ -149,10 +243,11 @@ type SkillBundle struct {
 	DisplayName string `json:"displayName,omitempty"`
 	Description string `json:"description,omitempty"`
 
-	IsEnabled  bool      `json:"isEnabled"`
-	IsBuiltIn  bool      `json:"isBuiltIn"`
-	CreatedAt  time.Time `json:"createdAt"`
-	ModifiedAt time.Time `json:"modifiedAt"`
+	IsEnabled        bool                        `json:"isEnabled"`
+	ActivationPolicy SkillBundleActivationPolicy `json:"activationPolicy,omitempty"`
+	IsBuiltIn        bool                        `json:"isBuiltIn"`
+	CreatedAt        time.Time                   `json:"createdAt"`
+	ModifiedAt       time.Time                   `json:"modifiedAt"`
 
 	SoftDeletedAt *time.Time `json:"softDeletedAt,omitempty"`
 }
@@ -160,3 +255,10 @@ type SkillBundle struct {
 type AllSkillBundles struct {
 	Bundles map[bundleitemutils.BundleID]SkillBundle `json:"bundles"`
 }
+
+// SkillUsage summarizes how often a skill was activated in sessions.
+type SkillUsage struct {
+	ActivationCount int64      `json:"activationCount"`
+	SessionCount    int64      `json:"sessionCount"`
+	LastActivatedAt *time.Time `json:"lastActivatedAt,omitempty"`
+}
@@ -0,0 +1,371 @@
+package skillstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// ExportSkillStoreState dumps the user store document and the built-in
+// overlay flags.
+func (s *SkillStore) ExportSkillStoreState(
+	ctx context.Context,
+	_ *spec.ExportSkillStoreStateRequest,
+) (*spec.ExportSkillStoreStateResponse, error) {
+	s.mu.RLock()
+	sc, err := s.readAllUser(ctx, false)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	state := spec.SkillStoreState{
+		SchemaVersion: spec.SkillSchemaVersion,
+		ExportedAt:    time.Now().UTC(),
+		Store:         raw,
+	}
+	if s.builtin != nil {
+		state.BuiltInBundleFlags, state.BuiltInSkillFlags, err = s.builtin.OverrideFlags(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &spec.ExportSkillStoreStateResponse{
+		Body: &spec.ExportSkillStoreStateResponseBody{State: state},
+	}, nil
+}
+
+// RestoreSkillStoreState merges a state dump into the store. Bundles and
+// skills in the dump are created or overwritten; entries that are equal,
+// soft-deleted in the dump or clash with built-in or reserved bundle IDs are
+// skipped. With DryRun the same report is produced without writing.
+func (s *SkillStore) RestoreSkillStoreState(
+	ctx context.Context,
+	req *spec.RestoreSkillStoreStateRequest,
+) (*spec.RestoreSkillStoreStateResponse, error) {
+	if req == nil || req.Body == nil {
+		return nil, fmt.Errorf("%w: body required", errSkillInvalidRequest)
+	}
+	state := req.Body.State
+	if state.SchemaVersion != spec.SkillSchemaVersion {
+		return nil, fmt.Errorf("%w: unsupported state schemaVersion %q", errSkillInvalidRequest, state.SchemaVersion)
+	}
+	var in skillStoreSchema
+	if len(state.Store) > 0 {
+		if err := json.Unmarshal(state.Store, &in); err != nil {
+			return nil, fmt.Errorf("%w: invalid store document: %w", errSkillInvalidRequest, err)
+		}
+	}
+	if err := normalizeSkillStoreSchema(&in); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+	}
+
+	flagChanges, bundleFlags, skillFlags, err := s.planBuiltInFlagRestore(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	var userChanges []spec.SkillStoreStateChange
+	if req.Body.DryRun {
+		s.mu.RLock()
+		sc, err := s.readAllUser(ctx, false)
+		s.mu.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+		userChanges = s.restoreUserState(ctx, &sc, in)
+	} else {
+		if err := s.withUserWrite(ctx, "restoreSkillStoreState", func(sc *skillStoreSchema) error {
+			userChanges = s.restoreUserState(ctx, sc, in)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		if err := s.applyBuiltInFlagRestore(ctx, bundleFlags, skillFlags); err != nil {
+			return nil, err
+		}
+		logger.Info("restoreSkillStoreState",
+			"userChanges", len(userChanges), "builtInFlagChanges", len(flagChanges))
+	}
+
+	return &spec.RestoreSkillStoreStateResponse{
+		Body: &spec.RestoreSkillStoreStateResponseBody{
+			DryRun:  req.Body.DryRun,
+			Changes: slices.Concat(userChanges, flagChanges),
+		},
+	}, nil
+}
+
+// RestoredSkillLocations returns the locations of the fs skills a restore of
+// state would register, so callers can check them against trusted
+// directories before writing. Skills of soft-deleted bundles are left out.
+func RestoredSkillLocations(state spec.SkillStoreState) ([]string, error) {
+	if len(state.Store) == 0 {
+		return nil, nil
+	}
+	var in skillStoreSchema
+	if err := json.Unmarshal(state.Store, &in); err != nil {
+		return nil, fmt.Errorf("%w: invalid store document: %w", errSkillInvalidRequest, err)
+	}
+	seen := map[string]struct{}{}
+	for bid, skills := range in.Skills {
+		if b, ok := in.Bundles[bid]; !ok || isSoftDeletedSkillBundle(b) {
+			continue
+		}
+		for _, sk := range skills {
+			if sk.Type == spec.SkillTypeFS && sk.Location != "" {
+				seen[sk.Location] = struct{}{}
+			}
+		}
+	}
+	return slices.Sorted(maps.Keys(seen)), nil
+}
+
+// restoreUserState merges in into sc and reports every bundle and skill of in.
+func (s *SkillStore) restoreUserState(
+	ctx context.Context,
+	sc *skillStoreSchema,
+	in skillStoreSchema,
+) []spec.SkillStoreStateChange {
+	var changes []spec.SkillStoreStateChange
+	for _, bid := range slices.Sorted(maps.Keys(in.Bundles)) {
+		b := in.Bundles[bid]
+		skipReason := ""
+		switch {
+		case isSoftDeletedSkillBundle(b):
+			skipReason = "bundle is soft-deleted in the state"
+		case s.isBuiltInBundleID(ctx, bid):
+			skipReason = "bundle ID belongs to a built-in bundle"
+		}
+		cur, exists := sc.Bundles[bid]
+		if skipReason == "" && !exists {
+			if err := s.checkReservedBundleID(ctx, bid); err != nil {
+				skipReason = err.Error()
+			}
+		}
+
+		inSkills := in.Skills[bid]
+		slugs := slices.Sorted(maps.Keys(inSkills))
+		if skipReason != "" {
+			changes = append(changes, skippedStateChange(spec.SkillStoreStateChangeBundle, bid, "", skipReason))
+			for _, slug := range slugs {
+				changes = append(changes, skippedStateChange(
+					spec.SkillStoreStateChangeSkill, bid, slug, "bundle is skipped",
+				))
+			}
+			continue
+		}
+
+		changes = append(changes, restoreStateChange(
+			spec.SkillStoreStateChangeBundle, bid, "", exists, exists && sameJSON(cur, b),
+		))
+		sc.Bundles[bid] = b
+		sm := sc.Skills[bid]
+		if sm == nil {
+			sm = map[spec.SkillSlug]spec.Skill{}
+			sc.Skills[bid] = sm
+		}
+		for _, slug := range slugs {
+			sk := inSkills[slug]
+			curSkill, skillExists := sm[slug]
+			changes = append(changes, restoreStateChange(
+				spec.SkillStoreStateChangeSkill, bid, slug, skillExists, skillExists && sameJSON(curSkill, sk),
+			))
+			sm[slug] = sk
+		}
+	}
+
+	// Activation history is merged only where the store has none.
+	for bid, m := range in.LastActivatedAt {
+		for slug, at := range m {
+			if _, ok := sc.LastActivatedAt[bid][slug]; ok {
+				continue
+			}
+			if sc.LastActivatedAt == nil {
+				sc.LastActivatedAt = map[bundleitemutils.BundleID]map[spec.SkillSlug]time.Time{}
+			}
+			if sc.LastActivatedAt[bid] == nil {
+				sc.LastActivatedAt[bid] = map[spec.SkillSlug]time.Time{}
+			}
+			sc.LastActivatedAt[bid][slug] = at
+		}
+	}
+	for bid, m := range in.Usage {
+		for slug, u := range m {
+			if _, ok := sc.Usage[bid][slug]; ok {
+				continue
+			}
+			if sc.Usage == nil {
+				sc.Usage = map[bundleitemutils.BundleID]map[spec.SkillSlug]skillUsageRecord{}
+			}
+			if sc.Usage[bid] == nil {
+				sc.Usage[bid] = map[spec.SkillSlug]skillUsageRecord{}
+			}
+			sc.Usage[bid][slug] = u
+		}
+	}
+	return changes
+}
+
+// planBuiltInFlagRestore reports the flag changes of state and returns the
+// flags that differ from the overlay.
+func (s *SkillStore) planBuiltInFlagRestore(
+	ctx context.Context,
+	state spec.SkillStoreState,
+) (
+	changes []spec.SkillStoreStateChange,
+	bundleFlags map[bundleitemutils.BundleID]bool,
+	skillFlags map[bundleitemutils.BundleID]map[spec.SkillSlug]bool,
+	err error,
+) {
+	if len(state.BuiltInBundleFlags) == 0 && len(state.BuiltInSkillFlags) == 0 {
+		return nil, nil, nil, nil
+	}
+	if s.builtin == nil {
+		return nil, nil, nil, fmt.Errorf("%w: built-in skills are not available", errSkillInvalidRequest)
+	}
+	curBundles, curSkills, err := s.builtin.OverrideFlags(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	bundleFlags = map[bundleitemutils.BundleID]bool{}
+	skillFlags = map[bundleitemutils.BundleID]map[spec.SkillSlug]bool{}
+	for _, bid := range slices.Sorted(maps.Keys(state.BuiltInBundleFlags)) {
+		want := state.BuiltInBundleFlags[bid]
+		if _, ok := s.builtin.bundles[bid]; !ok {
+			changes = append(changes, skippedStateChange(
+				spec.SkillStoreStateChangeBuiltInBundleFlag, bid, "", "unknown built-in bundle",
+			))
+			continue
+		}
+		cur, exists := curBundles[bid]
+		changes = append(changes, restoreStateChange(
+			spec.SkillStoreStateChangeBuiltInBundleFlag, bid, "", exists, exists && cur == want,
+		))
+		if !exists || cur != want {
+			bundleFlags[bid] = want
+		}
+	}
+
+	for _, bid := range slices.Sorted(maps.Keys(state.BuiltInSkillFlags)) {
+		for _, slug := range slices.Sorted(maps.Keys(state.BuiltInSkillFlags[bid])) {
+			want := state.BuiltInSkillFlags[bid][slug]
+			if _, ok := s.builtin.skills[bid][slug]; !ok {
+				changes = append(changes, skippedStateChange(
+					spec.SkillStoreStateChangeBuiltInSkillFlag, bid, slug, "unknown built-in skill",
+				))
+				continue
+			}
+			cur, exists := curSkills[bid][slug]
+			changes = append(changes, restoreStateChange(
+				spec.SkillStoreStateChangeBuiltInSkillFlag, bid, slug, exists, exists && cur == want,
+			))
+			if !exists || cur != want {
+				if skillFlags[bid] == nil {
+					skillFlags[bid] = map[spec.SkillSlug]bool{}
+				}
+				skillFlags[bid][slug] = want
+			}
+		}
+	}
+	return changes, bundleFlags, skillFlags, nil
+}
+
+func (s *SkillStore) applyBuiltInFlagRestore(
+	ctx context.Context,
+	bundleFlags map[bundleitemutils.BundleID]bool,
+	skillFlags map[bundleitemutils.BundleID]map[spec.SkillSlug]bool,
+) error {
+	if len(bundleFlags) == 0 && len(skillFlags) == 0 {
+		return nil
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	for _, bid := range slices.Sorted(maps.Keys(bundleFlags)) {
+		if _, err := s.builtin.SetSkillBundleEnabled(ctx, bid, bundleFlags[bid]); err != nil {
+			return err
+		}
+	}
+	var enable, disable []spec.SkillRef
+	for bid, sm := range skillFlags {
+		for slug, enabled := range sm {
+			ref := spec.SkillRef{BundleID: bid, SkillSlug: slug}
+			if enabled {
+				enable = append(enable, ref)
+			} else {
+				disable = append(disable, ref)
+			}
+		}
+	}
+	if len(enable) > 0 {
+		if err := s.builtin.SetSkillsEnabled(ctx, enable, true); err != nil {
+			return err
+		}
+	}
+	if len(disable) > 0 {
+		if err := s.builtin.SetSkillsEnabled(ctx, disable, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SkillStore) isBuiltInBundleID(ctx context.Context, id bundleitemutils.BundleID) bool {
+	if s.builtin == nil {
+		return false
+	}
+	_, err := s.builtin.GetBuiltInSkillBundle(ctx, id)
+	return err == nil
+}
+
+func restoreStateChange(
+	kind spec.SkillStoreStateChangeKind,
+	bid bundleitemutils.BundleID,
+	slug spec.SkillSlug,
+	exists, unchanged bool,
+) spec.SkillStoreStateChange {
+	c := spec.SkillStoreStateChange{Kind: kind, BundleID: bid, SkillSlug: slug}
+	switch {
+	case unchanged:
+		c.Action = spec.SkillStoreStateSkipped
+		c.Reason = "unchanged"
+	case exists:
+		c.Action = spec.SkillStoreStateOverwritten
+	default:
+		c.Action = spec.SkillStoreStateCreated
+	}
+	return c
+}
+
+func skippedStateChange(
+	kind spec.SkillStoreStateChangeKind,
+	bid bundleitemutils.BundleID,
+	slug spec.SkillSlug,
+	reason string,
+) spec.SkillStoreStateChange {
+	return spec.SkillStoreStateChange{
+		Kind:      kind,
+		Action:    spec.SkillStoreStateSkipped,
+		BundleID:  bid,
+		SkillSlug: slug,
+		Reason:    reason,
+	}
+}
+
+func sameJSON(a, b any) bool {
+	ra, errA := json.Marshal(a)
+	rb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ra, rb)
+}
@@ -3,13 +3,14 @@ package skillstore
 import (
 	"context"
 	"fmt"
-	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/flexigpt/mapstore-go"
@@ -17,17 +18,27 @@ import (
 	"github.com/flexigpt/mapstore-go/uuidv7filename"
 
 	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/featureflag"
+	"github.com/flexigpt/flexigpt-app/internal/fsutil"
+	"github.com/flexigpt/flexigpt-app/internal/idempotency"
 	"github.com/flexigpt/flexigpt-app/internal/jsonutil"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
 )
 
+// logger tags records of this package for per-module log levels.
+var logger = logging.Module("skill.store")
+
 const (
 	skillsMaxPageSize     = 256
 	skillsDefaultPageSize = 25
 
+	// Defaults for WithSoftDeleteGrace and WithCleanupInterval.
 	softDeleteGraceSkills = 48 * time.Hour
 	cleanupIntervalSkills = 24 * time.Hour
 
+	presenceCheckIntervalSkills = 15 * time.Minute
+
 	builtInSnapshotMaxAgeSkills = time.Hour
 )
 
@@ -38,6 +49,21 @@ type skillStoreSchema struct {
 
 	Bundles map[bundleitemutils.BundleID]spec.SkillBundle              `json:"bundles"`
 	Skills  map[bundleitemutils.BundleID]map[spec.SkillSlug]spec.Skill `json:"skills"`
+
+	// LastActivatedAt records when a skill was last activated in a session.
+	// It covers built-in skills too, so it is not tied to Skills entries.
+	LastActivatedAt map[bundleitemutils.BundleID]map[spec.SkillSlug]time.Time `json:"lastActivatedAt,omitempty"`
+	// Usage counts activations per skill, keyed like LastActivatedAt.
+	Usage map[bundleitemutils.BundleID]map[spec.SkillSlug]skillUsageRecord `json:"usage,omitempty"`
+
+	// DeletedSkills holds soft-deleted skills until UndeleteSkill restores
+	// them or the cleanup loop hard-deletes them. Keeping them out of Skills
+	// hides them from every read.
+	DeletedSkills map[bundleitemutils.BundleID]map[spec.SkillSlug]spec.Skill `json:"deletedSkills,omitempty"`
+
+	// ReservedNamesDigest is the digest of the reserved bundle namespace the
+	// bundle IDs were last migrated against.
+	ReservedNamesDigest string `json:"reservedNamesDigest,omitempty"`
 }
 
 // SkillStore owns durable Skill management state. It has no session, prompt,
@@ -49,9 +75,42 @@ type SkillStore struct {
 	userStore *mapstore.MapFileStore
 	builtin   *BuiltInSkills
 
-	writeMu               sync.Mutex
-	mu                    sync.RWMutex
+	// Bundle IDs new user bundles may not take, in addition to built-in IDs.
+	reserved bundleitemutils.ReservedNamespace
+	// Consulted for experimental behavior; nil falls back to env and defaults.
+	features featureflag.Gate
+	// Default base URL for registry browse and install; may be empty.
+	registryURL string
+
+	softDeleteGrace time.Duration
+	cleanupInterval time.Duration
+
+	writeMu sync.Mutex
+	mu      sync.RWMutex
+	// Turns the user store read-only when the disk is full or the file is
+	// locked.
+	writeGuard            fsutil.WriteGuard
 	embeddedMaterializeMu sync.Mutex
+	// Copies of the user store file taken before each write; nil disables them.
+	backups *fsutil.FileBackups
+	// Results of keyed mutations, replayed to retries with the same key.
+	replay *idempotency.Cache
+
+	// Last on-disk state of the user store file loaded or written by this
+	// process, nil when unknown; guarded by mu.
+	userFileStat *fsutil.FileState
+	// Records appended to the journal since the store file was last written,
+	// applied on every read; guarded by mu.
+	journal []userJournalRecord
+	// Parsed user store document and its list indexes, or nil until the next
+	// read parses it. Readers holding mu.RLock fill it, hence the atomic; it
+	// is cleared with mu held for writing.
+	userCache             atomic.Pointer[userStoreCache]
+	journalKick           chan struct{}
+	externalChangeHandler ExternalChangeHandler
+	reindexHandler        ReindexHandler
+	// Runtime name conflicts published by the skill runtime; guarded by mu.
+	conflicts []spec.SkillConflict
 
 	cleanOnce sync.Once
 	cleanKick chan struct{}
@@ -64,6 +123,15 @@ type skillStoreOptions struct {
 	// EmbeddedHydrateDir is the store-managed materialization location for
 	// immutable built-in Skill packages.
 	embeddedHydrateDir string
+
+	reserved        *bundleitemutils.ReservedNamespace
+	features        featureflag.Gate
+	registryURL     string
+	softDeleteGrace *time.Duration
+	cleanupInterval *time.Duration
+	fileWatch       bool
+	noPresenceLoop  bool
+	backupPolicy    *fsutil.BackupPolicy
 }
 
 type SkillStoreOption func(*skillStoreOptions) error
@@ -75,6 +143,95 @@ func WithEmbeddedHydrateDir(dir string) SkillStoreOption {
 	}
 }
 
+// WithReservedBundleNamespace replaces the default reserved prefixes for user
+// bundle IDs. Built-in bundle IDs are always reserved.
+func WithReservedBundleNamespace(ns bundleitemutils.ReservedNamespace) SkillStoreOption {
+	return func(options *skillStoreOptions) error {
+		options.reserved = &ns
+		return nil
+	}
+}
+
+// WithFeatureGate sets the gate consulted for experimental store behavior.
+func WithFeatureGate(gate featureflag.Gate) SkillStoreOption {
+	return func(options *skillStoreOptions) error {
+		options.features = gate
+		return nil
+	}
+}
+
+// WithSkillRegistryURL sets the default skill registry base URL. Requests may
+// still name a registry of their own.
+func WithSkillRegistryURL(baseURL string) SkillStoreOption {
+	return func(options *skillStoreOptions) error {
+		baseURL = strings.TrimSpace(baseURL)
+		if baseURL == "" {
+			return nil
+		}
+		if _, err := parseSkillRegistryURL(baseURL); err != nil {
+			return err
+		}
+		options.registryURL = baseURL
+		return nil
+	}
+}
+
+// WithSoftDeleteGrace sets how long a deleted bundle or skill is kept before
+// the cleanup loop hard-deletes it. Zero deletes it on the next sweep.
+func WithSoftDeleteGrace(d time.Duration) SkillStoreOption {
+	return func(options *skillStoreOptions) error {
+		if d < 0 {
+			return fmt.Errorf("%w: negative soft-delete grace", errSkillInvalidRequest)
+		}
+		options.softDeleteGrace = &d
+		return nil
+	}
+}
+
+// WithCleanupInterval sets how often the cleanup loop sweeps soft-deleted
+// bundles.
+func WithCleanupInterval(d time.Duration) SkillStoreOption {
+	return func(options *skillStoreOptions) error {
+		if d <= 0 {
+			return fmt.Errorf("%w: cleanup interval must be positive", errSkillInvalidRequest)
+		}
+		options.cleanupInterval = &d
+		return nil
+	}
+}
+
+// WithFileWatch makes the store reload its user file as soon as it changes
+// on disk, e.g. when synced from another machine, instead of polling for
+// changes. Polling remains the fallback if the file cannot be watched.
+func WithFileWatch(enabled bool) SkillStoreOption {
+	return func(options *skillStoreOptions) error {
+		options.fileWatch = enabled
+		return nil
+	}
+}
+
+// WithPresenceLoop controls the loop that checks every 15 minutes
+// whether the packages of filesystem skills still exist. It is on by default;
+// short-lived processes turn it off and call TriggerPresenceCheck if needed.
+func WithPresenceLoop(enabled bool) SkillStoreOption {
+	return func(options *skillStoreOptions) error {
+		options.noPresenceLoop = !enabled
+		return nil
+	}
+}
+
+// WithBackups copies the user store file into the backups directory of the
+// store before each write, keeping copies within policy.
+func WithBackups(policy fsutil.BackupPolicy) SkillStoreOption {
+	return func(options *skillStoreOptions) error {
+		if policy.MaxCopies < 0 || policy.MaxAge < 0 {
+			return fmt.Errorf("%w: backup policy must not be negative", errSkillInvalidRequest)
+		}
+		options.backupPolicy = &policy
+		return nil
+	}
+}
+
 func NewSkillStore(baseDir string, opts ...SkillStoreOption) (*SkillStore, error) {
 	if strings.TrimSpace(baseDir) == "" {
 		return nil, fmt.Errorf("%w: baseDir is empty", errSkillInvalidRequest)
@@ -90,10 +247,30 @@ func NewSkillStore(baseDir string, opts ...SkillStoreOption) (*SkillStore, error
 		}
 	}
 
-	store := &SkillStore{baseDir: filepath.Clean(baseDir)}
+	store := &SkillStore{
+		baseDir: filepath.Clean(baseDir),
+		replay:  idempotency.NewCache(idempotency.DefaultTTL, idempotency.DefaultMaxEntries),
+	}
+	store.reserved = bundleitemutils.DefaultReservedNamespace()
+	if options.reserved != nil {
+		store.reserved = *options.reserved
+	}
+	store.features = options.features
+	store.registryURL = options.registryURL
+	store.softDeleteGrace = softDeleteGraceSkills
+	if options.softDeleteGrace != nil {
+		store.softDeleteGrace = *options.softDeleteGrace
+	}
+	store.cleanupInterval = cleanupIntervalSkills
+	if options.cleanupInterval != nil {
+		store.cleanupInterval = *options.cleanupInterval
+	}
 	if err := os.MkdirAll(store.baseDir, 0o755); err != nil {
 		return nil, err
 	}
+	if options.backupPolicy != nil {
+		store.backups = fsutil.NewFileBackups(filepath.Join(store.baseDir, "backups"), *options.backupPolicy)
+	}
 
 	ctx := context.Background()
 	builtinSkills, err := NewBuiltInSkills(
@@ -136,21 +313,35 @@ func NewSkillStore(baseDir string, opts ...SkillStoreOption) (*SkillStore, error
 		jsonencdec.JSONEncoderDecoder{},
 		mapstore.WithCreateIfNotExists(true),
 		mapstore.WithFileAutoFlush(true),
-		mapstore.WithFileLogger(slog.Default()),
+		mapstore.WithFileLogger(logger),
 	)
 	if err != nil {
 		_ = store.builtin.Close()
 		return nil, err
 	}
 
-	if err := store.ensureBaseSkillBundleHydrated(); err != nil {
+	if err := store.recoverUserJournal(ctx); err != nil {
+		_ = store.userStore.Close()
+		_ = store.builtin.Close()
+		return nil, err
+	}
+	if err := store.ensureBaseSkillBundleHydrated(ctx); err != nil {
 		_ = store.userStore.Close()
 		_ = store.builtin.Close()
 		return nil, err
 	}
+	store.mu.Lock()
+	store.rememberUserFileStat()
+	store.mu.Unlock()
+
 	store.startCleanupLoop()
+	store.startExternalChangeLoop(options.fileWatch)
+	if !options.noPresenceLoop {
+		store.startPresenceLoop()
+	}
+	store.startJournalCompactLoop()
 
-	slog.Info("skill-store ready", "baseDir", store.baseDir)
+	logger.Info("skill-store ready", "baseDir", store.baseDir)
 	return store, nil
 }
 
@@ -162,6 +353,12 @@ func (s *SkillStore) Close() {
 		s.cleanStop()
 	}
 	s.wg.Wait()
+	if s.userStore != nil {
+		// The store is closing; the journal must reach the file regardless.
+		if err := s.compactUserJournal(context.Background()); err != nil {
+			logger.Error("compact skill store journal on close", "err", err)
+		}
+	}
 	if s.builtin != nil {
 		_ = s.builtin.Close()
 	}
@@ -171,6 +368,16 @@ func (s *SkillStore) Close() {
 }
 
 func (s *SkillStore) PutSkillBundle(
+	ctx context.Context, req *spec.PutSkillBundleRequest,
+) (*spec.PutSkillBundleResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.putSkillBundle(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PutSkillBundle", req.IdempotencyKey, req,
+		func() (*spec.PutSkillBundleResponse, error) { return s.putSkillBundle(ctx, req) })
+}
+
+func (s *SkillStore) putSkillBundle(
 	ctx context.Context,
 	req *spec.PutSkillBundleRequest,
 ) (*spec.PutSkillBundleResponse, error) {
@@ -183,6 +390,9 @@ func (s *SkillStore) PutSkillBundle(
 	if err := bundleitemutils.ValidateBundleSlug(req.Body.Slug); err != nil {
 		return nil, err
 	}
+	if !isValidSkillBundleActivationPolicy(req.Body.ActivationPolicy) {
+		return nil, fmt.Errorf("%w: activationPolicy %q", errSkillInvalidRequest, req.Body.ActivationPolicy)
+	}
 	if err := validateManagedPathSegment(string(req.BundleID), "bundleID"); err != nil {
 		return nil, fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
 	}
@@ -192,7 +402,8 @@ func (s *SkillStore) PutSkillBundle(
 		}
 	}
 
-	if err := s.withUserWrite(
+	var saved spec.SkillBundle
+	if err := s.userWriter(req.DryRun)(
 		ctx,
 		"putSkillBundle",
 		func(snapshot *skillStoreSchema) error {
@@ -202,25 +413,34 @@ func (s *SkillStore) PutSkillBundle(
 				if isSoftDeletedSkillBundle(existing) {
 					return fmt.Errorf("%w: %s", errSkillBundleDeleting, req.BundleID)
 				}
+				if err := checkModifiedAt(req.Body.ExpectedModifiedAt, existing.ModifiedAt, existing); err != nil {
+					return err
+				}
 				if !existing.CreatedAt.IsZero() {
 					createdAt = existing.CreatedAt
 				}
+			} else if req.Body.ExpectedModifiedAt != nil {
+				return fmt.Errorf("%w: %s", errSkillBundleNotFound, req.BundleID)
+			} else if err := s.checkReservedBundleID(ctx, req.BundleID); err != nil {
+				return err
 			}
 
 			bundle := spec.SkillBundle{
-				SchemaVersion: spec.SkillSchemaVersion,
-				ID:            req.BundleID,
-				Slug:          req.Body.Slug,
-				DisplayName:   req.Body.DisplayName,
-				Description:   req.Body.Description,
-				IsEnabled:     req.Body.IsEnabled,
-				IsBuiltIn:     false,
-				CreatedAt:     createdAt,
-				ModifiedAt:    now,
+				SchemaVersion:    spec.SkillSchemaVersion,
+				ID:               req.BundleID,
+				Slug:             req.Body.Slug,
+				DisplayName:      req.Body.DisplayName,
+				Description:      req.Body.Description,
+				IsEnabled:        req.Body.IsEnabled,
+				ActivationPolicy: req.Body.ActivationPolicy,
+				IsBuiltIn:        false,
+				CreatedAt:        createdAt,
+				ModifiedAt:       now,
 			}
 			if err := validateSkillBundle(&bundle); err != nil {
 				return err
 			}
+			saved = bundle
 			snapshot.Bundles[req.BundleID] = bundle
 			if snapshot.Skills[req.BundleID] == nil {
 				snapshot.Skills[req.BundleID] = map[spec.SkillSlug]spec.Skill{}
@@ -230,12 +450,25 @@ func (s *SkillStore) PutSkillBundle(
 	); err != nil {
 		return nil, err
 	}
+	if req.DryRun {
+		return &spec.PutSkillBundleResponse{Body: &spec.PutSkillBundleResponseBody{SkillBundle: saved}}, nil
+	}
 
-	slog.Info("putSkillBundle", "bundleID", req.BundleID)
+	logger.Info("putSkillBundle", "bundleID", req.BundleID)
 	return &spec.PutSkillBundleResponse{}, nil
 }
 
 func (s *SkillStore) PatchSkillBundle(
+	ctx context.Context, req *spec.PatchSkillBundleRequest,
+) (*spec.PatchSkillBundleResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.patchSkillBundle(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PatchSkillBundle", req.IdempotencyKey, req,
+		func() (*spec.PatchSkillBundleResponse, error) { return s.patchSkillBundle(ctx, req) })
+}
+
+func (s *SkillStore) patchSkillBundle(
 	ctx context.Context,
 	req *spec.PatchSkillBundleRequest,
 ) (*spec.PatchSkillBundleResponse, error) {
@@ -247,6 +480,19 @@ func (s *SkillStore) PatchSkillBundle(
 		if _, err := s.builtin.GetBuiltInSkillBundle(ctx, req.BundleID); err == nil {
 			s.writeMu.Lock()
 			defer s.writeMu.Unlock()
+			current, err := s.builtin.GetBuiltInSkillBundle(ctx, req.BundleID)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkModifiedAt(req.Body.ExpectedModifiedAt, current.ModifiedAt, current); err != nil {
+				return nil, err
+			}
+			if req.DryRun {
+				current.IsEnabled = req.Body.IsEnabled
+				current.ModifiedAt = time.Now().UTC()
+				body := &spec.PatchSkillBundleResponseBody{SkillBundle: current}
+				return &spec.PatchSkillBundleResponse{Body: body}, nil
+			}
 			if _, err := s.builtin.SetSkillBundleEnabled(ctx, req.BundleID, req.Body.IsEnabled); err != nil {
 				return nil, err
 			}
@@ -254,7 +500,8 @@ func (s *SkillStore) PatchSkillBundle(
 		}
 	}
 
-	if err := s.withUserWrite(
+	var saved spec.SkillBundle
+	if err := s.userWriter(req.DryRun)(
 		ctx,
 		"patchSkillBundle",
 		func(snapshot *skillStoreSchema) error {
@@ -265,20 +512,184 @@ func (s *SkillStore) PatchSkillBundle(
 			if isSoftDeletedSkillBundle(bundle) {
 				return fmt.Errorf("%w: %s", errSkillBundleDeleting, req.BundleID)
 			}
+			if err := checkModifiedAt(req.Body.ExpectedModifiedAt, bundle.ModifiedAt, bundle); err != nil {
+				return err
+			}
 			bundle.IsEnabled = req.Body.IsEnabled
 			bundle.ModifiedAt = time.Now().UTC()
+			saved = bundle
 			snapshot.Bundles[req.BundleID] = bundle
 			return nil
 		},
 	); err != nil {
 		return nil, err
 	}
+	if req.DryRun {
+		return &spec.PatchSkillBundleResponse{Body: &spec.PatchSkillBundleResponseBody{SkillBundle: saved}}, nil
+	}
 
-	slog.Info("patchSkillBundle", "bundleID", req.BundleID, "enabled", req.Body.IsEnabled)
+	logger.Info("patchSkillBundle", "bundleID", req.BundleID, "enabled", req.Body.IsEnabled)
 	return &spec.PatchSkillBundleResponse{}, nil
 }
 
+// SetSkillBundleActivationPolicy sets how new skill sessions activate the
+// skills of a bundle. Built-in bundles keep the policy in the overlay.
+func (s *SkillStore) SetSkillBundleActivationPolicy(
+	ctx context.Context,
+	req *spec.SetSkillBundleActivationPolicyRequest,
+) (*spec.SetSkillBundleActivationPolicyResponse, error) {
+	if req == nil || req.Body == nil || req.BundleID == "" {
+		return nil, fmt.Errorf("%w: bundleID and body required", errSkillInvalidRequest)
+	}
+	policy := req.Body.ActivationPolicy
+	if policy == "" || !isValidSkillBundleActivationPolicy(policy) {
+		return nil, fmt.Errorf("%w: activationPolicy %q", errSkillInvalidRequest, policy)
+	}
+
+	if s.builtin != nil {
+		if _, err := s.builtin.GetBuiltInSkillBundle(ctx, req.BundleID); err == nil {
+			s.writeMu.Lock()
+			defer s.writeMu.Unlock()
+			if _, err := s.builtin.SetSkillBundleActivationPolicy(ctx, req.BundleID, policy); err != nil {
+				return nil, err
+			}
+			return &spec.SetSkillBundleActivationPolicyResponse{}, nil
+		}
+	}
+
+	if err := s.withUserWrite(
+		ctx,
+		"setSkillBundleActivationPolicy",
+		func(snapshot *skillStoreSchema) error {
+			bundle, ok := snapshot.Bundles[req.BundleID]
+			if !ok {
+				return fmt.Errorf("%w: %s", errSkillBundleNotFound, req.BundleID)
+			}
+			if isSoftDeletedSkillBundle(bundle) {
+				return fmt.Errorf("%w: %s", errSkillBundleDeleting, req.BundleID)
+			}
+			bundle.ActivationPolicy = policy
+			bundle.ModifiedAt = time.Now().UTC()
+			snapshot.Bundles[req.BundleID] = bundle
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	logger.Info("setSkillBundleActivationPolicy", "bundleID", req.BundleID, "policy", policy)
+	return &spec.SetSkillBundleActivationPolicyResponse{}, nil
+}
+
+// RenameSkillBundle changes the slug and/or display name of a user bundle in
+// place. The slug must not be used by another live user or built-in bundle.
+func (s *SkillStore) RenameSkillBundle(
+	ctx context.Context,
+	req *spec.RenameSkillBundleRequest,
+) (*spec.RenameSkillBundleResponse, error) {
+	if req == nil || req.Body == nil || req.BundleID == "" {
+		return nil, fmt.Errorf("%w: bundleID and body required", errSkillInvalidRequest)
+	}
+	if req.Body.Slug == "" && strings.TrimSpace(req.Body.DisplayName) == "" {
+		return nil, fmt.Errorf("%w: slug or displayName required", errSkillInvalidRequest)
+	}
+	if req.Body.Slug != "" {
+		if err := bundleitemutils.ValidateBundleSlug(req.Body.Slug); err != nil {
+			return nil, fmt.Errorf("%w: %w", errSkillInvalidRequest, err)
+		}
+	}
+	var builtInBundles map[bundleitemutils.BundleID]spec.SkillBundle
+	if s.builtin != nil {
+		if _, err := s.builtin.GetBuiltInSkillBundle(ctx, req.BundleID); err == nil {
+			return nil, fmt.Errorf("%w: bundleID %q", errSkillBuiltInReadOnly, req.BundleID)
+		}
+		bundles, _, err := s.builtin.ListBuiltInSkills(ctx)
+		if err != nil {
+			return nil, err
+		}
+		builtInBundles = bundles
+	}
+
+	if err := s.withUserWrite(
+		ctx,
+		"renameSkillBundle",
+		func(snapshot *skillStoreSchema) error {
+			bundle, ok := snapshot.Bundles[req.BundleID]
+			if !ok {
+				return fmt.Errorf("%w: %s", errSkillBundleNotFound, req.BundleID)
+			}
+			if isSoftDeletedSkillBundle(bundle) {
+				return fmt.Errorf("%w: %s", errSkillBundleDeleting, req.BundleID)
+			}
+			if req.Body.Slug != "" && req.Body.Slug != bundle.Slug {
+				for _, b := range builtInBundles {
+					if b.Slug == req.Body.Slug {
+						return fmt.Errorf("%w: slug %q is used by built-in bundle %s",
+							errSkillConflict, req.Body.Slug, b.ID)
+					}
+				}
+				for id, b := range snapshot.Bundles {
+					if id != req.BundleID && !isSoftDeletedSkillBundle(b) && b.Slug == req.Body.Slug {
+						return fmt.Errorf("%w: slug %q is used by bundle %s", errSkillConflict, req.Body.Slug, id)
+					}
+				}
+				bundle.Slug = req.Body.Slug
+			}
+			if name := strings.TrimSpace(req.Body.DisplayName); name != "" {
+				bundle.DisplayName = name
+			}
+			bundle.ModifiedAt = time.Now().UTC()
+			if err := validateSkillBundle(&bundle); err != nil {
+				return err
+			}
+			snapshot.Bundles[req.BundleID] = bundle
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	logger.Info("renameSkillBundle", "bundleID", req.BundleID, "slug", req.Body.Slug)
+	return &spec.RenameSkillBundleResponse{}, nil
+}
+
+// ResetBuiltInOverrides clears the enabled-flag overrides of built-in bundles
+// and their skills.
+func (s *SkillStore) ResetBuiltInOverrides(
+	ctx context.Context,
+	req *spec.ResetBuiltInOverridesRequest,
+) (*spec.ResetBuiltInOverridesResponse, error) {
+	if s.builtin == nil {
+		return nil, errSkillBundleNotFound
+	}
+	var ids []bundleitemutils.BundleID
+	if req != nil {
+		ids = req.BundleIDs
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	reset, err := s.builtin.ResetOverrides(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("resetBuiltInOverrides", "bundles", reset)
+	return &spec.ResetBuiltInOverridesResponse{
+		Body: &spec.ResetBuiltInOverridesResponseBody{ResetBundleIDs: reset},
+	}, nil
+}
+
 func (s *SkillStore) DeleteSkillBundle(
+	ctx context.Context, req *spec.DeleteSkillBundleRequest,
+) (*spec.DeleteSkillBundleResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.deleteSkillBundle(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "DeleteSkillBundle", req.IdempotencyKey, req,
+		func() (*spec.DeleteSkillBundleResponse, error) { return s.deleteSkillBundle(ctx, req) })
+}
+
+func (s *SkillStore) deleteSkillBundle(
 	ctx context.Context,
 	req *spec.DeleteSkillBundleRequest,
 ) (*spec.DeleteSkillBundleResponse, error) {
@@ -291,7 +702,8 @@ func (s *SkillStore) DeleteSkillBundle(
 		}
 	}
 
-	if err := s.withUserWrite(
+	var deleted spec.SkillBundle
+	if err := s.userWriter(req.DryRun)(
 		ctx,
 		"deleteSkillBundle",
 		func(snapshot *skillStoreSchema) error {
@@ -309,18 +721,54 @@ func (s *SkillStore) DeleteSkillBundle(
 			bundle.IsEnabled = false
 			bundle.SoftDeletedAt = &now
 			bundle.ModifiedAt = now
+			deleted = bundle
 			snapshot.Bundles[req.BundleID] = bundle
 			return nil
 		},
 	); err != nil {
 		return nil, err
 	}
+	if req.DryRun {
+		return &spec.DeleteSkillBundleResponse{Body: &spec.DeleteSkillBundleResponseBody{SkillBundle: deleted}}, nil
+	}
 
 	s.kickCleanupLoop()
-	slog.Info("deleteSkillBundle", "bundleID", req.BundleID)
+	logger.Info("deleteSkillBundle", "bundleID", req.BundleID)
 	return &spec.DeleteSkillBundleResponse{}, nil
 }
 
+func (s *SkillStore) PurgeSkillBundle(
+	ctx context.Context,
+	req *spec.PurgeSkillBundleRequest,
+) (*spec.PurgeSkillBundleResponse, error) {
+	if req == nil || req.BundleID == "" {
+		return nil, fmt.Errorf("%w: bundleID required", errSkillInvalidRequest)
+	}
+	var packages []string
+	if err := s.withUserWrite(ctx, "purgeSkillBundle", func(snapshot *skillStoreSchema) error {
+		bundle, ok := snapshot.Bundles[req.BundleID]
+		if !ok {
+			return fmt.Errorf("%w: %s", errSkillBundleNotFound, req.BundleID)
+		}
+		if !isSoftDeletedSkillBundle(bundle) {
+			return fmt.Errorf("%w: bundle %s is not deleted", errSkillInvalidRequest, req.BundleID)
+		}
+		if len(snapshot.Skills[req.BundleID]) > 0 {
+			return fmt.Errorf("%w: %s", errSkillBundleNotEmpty, req.BundleID)
+		}
+		trashed := slices.Collect(maps.Values(snapshot.DeletedSkills[req.BundleID]))
+		packages = snapshot.managedPackagesOf(s.baseDir, req.BundleID, trashed...)
+		snapshot.deleteBundle(req.BundleID)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	removeManagedSkillPackages(packages)
+
+	logger.Info("purgeSkillBundle", "bundleID", req.BundleID)
+	return &spec.PurgeSkillBundleResponse{}, nil
+}
+
 func (s *SkillStore) ListSkillBundles(
 	ctx context.Context,
 	req *spec.ListSkillBundlesRequest,
@@ -374,7 +822,7 @@ func (s *SkillStore) ListSkillBundles(
 		}
 	}
 	s.mu.RLock()
-	user, err := s.readAllUser(false)
+	user, err := s.readAllUser(ctx, false)
 	s.mu.RUnlock()
 	if err != nil {
 		return nil, err
@@ -440,6 +888,16 @@ func (s *SkillStore) ListSkillBundles(
 }
 
 func (s *SkillStore) PutSkill(
+	ctx context.Context, req *spec.PutSkillRequest,
+) (*spec.PutSkillResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.putSkill(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PutSkill", req.IdempotencyKey, req,
+		func() (*spec.PutSkillResponse, error) { return s.putSkill(ctx, req) })
+}
+
+func (s *SkillStore) putSkill(
 	ctx context.Context,
 	req *spec.PutSkillRequest,
 ) (*spec.PutSkillResponse, error) {
@@ -458,7 +916,8 @@ func (s *SkillStore) PutSkill(
 		}
 	}
 
-	if err := s.withUserWrite(ctx, "putSkill", func(snapshot *skillStoreSchema) error {
+	var created spec.Skill
+	if err := s.userWriter(req.DryRun)(ctx, "putSkill", func(snapshot *skillStoreSchema) error {
 		bundle, ok := snapshot.Bundles[req.BundleID]
 		if !ok {
 			return fmt.Errorf("%w: %s", errSkillBundleNotFound, req.BundleID)
@@ -466,12 +925,18 @@ func (s *SkillStore) PutSkill(
 		if isSoftDeletedSkillBundle(bundle) {
 			return fmt.Errorf("%w: %s", errSkillBundleDeleting, req.BundleID)
 		}
+		if err := checkModifiedAt(req.Body.ExpectedBundleModifiedAt, bundle.ModifiedAt, bundle); err != nil {
+			return err
+		}
 		if snapshot.Skills[req.BundleID] == nil {
 			snapshot.Skills[req.BundleID] = map[spec.SkillSlug]spec.Skill{}
 		}
 		if _, exists := snapshot.Skills[req.BundleID][req.SkillSlug]; exists {
 			return fmt.Errorf("%w: duplicate skillSlug in bundle", errSkillConflict)
 		}
+		if _, exists := snapshot.DeletedSkills[req.BundleID][req.SkillSlug]; exists {
+			return fmt.Errorf("%w: %s", errSkillDeleting, req.SkillSlug)
+		}
 		id, err := uuidv7filename.NewUUIDv7String()
 		if err != nil {
 			return err
@@ -496,17 +961,31 @@ func (s *SkillStore) PutSkill(
 		if err := validateSkill(&skill); err != nil {
 			return err
 		}
+		created = skill
 		snapshot.Skills[req.BundleID][req.SkillSlug] = skill
 		return nil
 	}); err != nil {
 		return nil, err
 	}
+	if req.DryRun {
+		return &spec.PutSkillResponse{Body: &spec.PutSkillResponseBody{Skill: created}}, nil
+	}
 
-	slog.Info("putSkill", "bundleID", req.BundleID, "skillSlug", req.SkillSlug)
+	logger.Info("putSkill", "bundleID", req.BundleID, "skillSlug", req.SkillSlug)
 	return &spec.PutSkillResponse{}, nil
 }
 
 func (s *SkillStore) PatchSkill(
+	ctx context.Context, req *spec.PatchSkillRequest,
+) (*spec.PatchSkillResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.patchSkill(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "PatchSkill", req.IdempotencyKey, req,
+		func() (*spec.PatchSkillResponse, error) { return s.patchSkill(ctx, req) })
+}
+
+func (s *SkillStore) patchSkill(
 	ctx context.Context,
 	req *spec.PatchSkillRequest,
 ) (*spec.PatchSkillResponse, error) {
@@ -533,6 +1012,18 @@ func (s *SkillStore) PatchSkill(
 			}
 			s.writeMu.Lock()
 			defer s.writeMu.Unlock()
+			current, err := s.builtin.GetBuiltInSkill(ctx, req.BundleID, req.SkillSlug)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkModifiedAt(req.Body.ExpectedModifiedAt, current.ModifiedAt, current); err != nil {
+				return nil, err
+			}
+			if req.DryRun {
+				current.IsEnabled = *req.Body.IsEnabled
+				current.ModifiedAt = time.Now().UTC()
+				return &spec.PatchSkillResponse{Body: &spec.PatchSkillResponseBody{Skill: current}}, nil
+			}
 			if _, err := s.builtin.SetSkillEnabled(ctx, req.BundleID, req.SkillSlug, *req.Body.IsEnabled); err != nil {
 				return nil, err
 			}
@@ -540,7 +1031,8 @@ func (s *SkillStore) PatchSkill(
 		}
 	}
 
-	if err := s.withUserWrite(ctx, "patchSkill", func(snapshot *skillStoreSchema) error {
+	var saved spec.Skill
+	if err := s.userWriter(req.DryRun)(ctx, "patchSkill", func(snapshot *skillStoreSchema) error {
 		bundle, ok := snapshot.Bundles[req.BundleID]
 		if !ok {
 			return fmt.Errorf("%w: %s", errSkillBundleNotFound, req.BundleID)
@@ -553,6 +1045,9 @@ func (s *SkillStore) PatchSkill(
 		if !ok {
 			return fmt.Errorf("%w: %s", errSkillNotFound, req.SkillSlug)
 		}
+		if err := checkModifiedAt(req.Body.ExpectedModifiedAt, current.ModifiedAt, current); err != nil {
+			return err
+		}
 
 		target := current
 		if req.Body.IsEnabled != nil {
@@ -580,18 +1075,32 @@ func (s *SkillStore) PatchSkill(
 		if err := validateSkill(&target); err != nil {
 			return err
 		}
+		saved = target
 		values[req.SkillSlug] = target
 		snapshot.Skills[req.BundleID] = values
 		return nil
 	}); err != nil {
 		return nil, err
 	}
+	if req.DryRun {
+		return &spec.PatchSkillResponse{Body: &spec.PatchSkillResponseBody{Skill: saved}}, nil
+	}
 
-	slog.Info("patchSkill", "bundleID", req.BundleID, "skillSlug", req.SkillSlug)
+	logger.Info("patchSkill", "bundleID", req.BundleID, "skillSlug", req.SkillSlug)
 	return &spec.PatchSkillResponse{}, nil
 }
 
 func (s *SkillStore) DeleteSkill(
+	ctx context.Context, req *spec.DeleteSkillRequest,
+) (*spec.DeleteSkillResponse, error) {
+	if req == nil || req.IdempotencyKey == "" {
+		return s.deleteSkill(ctx, req)
+	}
+	return idempotency.Do(ctx, s.replay, "DeleteSkill", req.IdempotencyKey, req,
+		func() (*spec.DeleteSkillResponse, error) { return s.deleteSkill(ctx, req) })
+}
+
+func (s *SkillStore) deleteSkill(
 	ctx context.Context,
 	req *spec.DeleteSkillRequest,
 ) (*spec.DeleteSkillResponse, error) {
@@ -608,7 +1117,7 @@ func (s *SkillStore) DeleteSkill(
 	}
 
 	var deleted spec.Skill
-	if err := s.withUserWrite(ctx, "deleteSkill", func(snapshot *skillStoreSchema) error {
+	if err := s.userWriter(req.DryRun)(ctx, "deleteSkill", func(snapshot *skillStoreSchema) error {
 		bundle, ok := snapshot.Bundles[req.BundleID]
 		if !ok {
 			return fmt.Errorf("%w: %s", errSkillBundleNotFound, req.BundleID)
@@ -616,35 +1125,71 @@ func (s *SkillStore) DeleteSkill(
 		if isSoftDeletedSkillBundle(bundle) {
 			return fmt.Errorf("%w: %s", errSkillBundleDeleting, req.BundleID)
 		}
-		values := snapshot.Skills[req.BundleID]
-		skill, ok := values[req.SkillSlug]
+		skill, ok := snapshot.Skills[req.BundleID][req.SkillSlug]
 		if !ok {
 			return fmt.Errorf("%w: %s", errSkillNotFound, req.SkillSlug)
 		}
-		deleted = skill
-		delete(values, req.SkillSlug)
-		snapshot.Skills[req.BundleID] = values
+		// The package and activation history stay until the cleanup loop
+		// hard-deletes the skill, so that UndeleteSkill restores it whole.
+		deleted = snapshot.trashSkill(req.BundleID, skill, time.Now().UTC())
 		return nil
 	}); err != nil {
 		return nil, err
 	}
+	if req.DryRun {
+		return &spec.DeleteSkillResponse{Body: &spec.DeleteSkillResponseBody{Skill: deleted}}, nil
+	}
+
+	s.kickCleanupLoop()
+	logger.Info("deleteSkill", "bundleID", req.BundleID, "skillSlug", req.SkillSlug)
+	return &spec.DeleteSkillResponse{}, nil
+}
+
+// UndeleteSkill restores a soft-deleted skill, as it was when deleted, unless
+// its bundle is being deleted or another skill has taken the slug.
+func (s *SkillStore) UndeleteSkill(
+	ctx context.Context,
+	req *spec.UndeleteSkillRequest,
+) (*spec.UndeleteSkillResponse, error) {
+	if req == nil || req.BundleID == "" || req.SkillSlug == "" {
+		return nil, fmt.Errorf("%w: bundleID and skillSlug required", errSkillInvalidRequest)
+	}
+	if err := bundleitemutils.ValidateItemSlug(req.SkillSlug); err != nil {
+		return nil, err
+	}
 
-	if isManagedSkillPackageLocation(
-		s.baseDir,
-		string(req.BundleID),
-		deleted.Name,
-		deleted.Location,
-	) {
-		if err := os.RemoveAll(deleted.Location); err != nil {
-			slog.Error(
-				"delete managed Skill package failed",
-				"location", deleted.Location,
-				"error", err,
-			)
+	if err := s.withUserWrite(ctx, "undeleteSkill", func(snapshot *skillStoreSchema) error {
+		bundle, ok := snapshot.Bundles[req.BundleID]
+		if !ok {
+			return fmt.Errorf("%w: %s", errSkillBundleNotFound, req.BundleID)
 		}
+		if isSoftDeletedSkillBundle(bundle) {
+			return fmt.Errorf("%w: %s", errSkillBundleDeleting, req.BundleID)
+		}
+		skill, ok := snapshot.DeletedSkills[req.BundleID][req.SkillSlug]
+		if !ok {
+			return fmt.Errorf("%w: %s", errSkillNotFound, req.SkillSlug)
+		}
+		if _, exists := snapshot.Skills[req.BundleID][req.SkillSlug]; exists {
+			return fmt.Errorf("%w: skillSlug %q was reused", errSkillConflict, req.SkillSlug)
+		}
+		delete(snapshot.DeletedSkills[req.BundleID], req.SkillSlug)
+		if len(snapshot.DeletedSkills[req.BundleID]) == 0 {
+			delete(snapshot.DeletedSkills, req.BundleID)
+		}
+		skill.SoftDeletedAt = nil
+		skill.ModifiedAt = time.Now().UTC()
+		if snapshot.Skills[req.BundleID] == nil {
+			snapshot.Skills[req.BundleID] = map[spec.SkillSlug]spec.Skill{}
+		}
+		snapshot.Skills[req.BundleID][req.SkillSlug] = skill
+		return nil
+	}); err != nil {
+		return nil, err
 	}
-	slog.Info("deleteSkill", "bundleID", req.BundleID, "skillSlug", req.SkillSlug)
-	return &spec.DeleteSkillResponse{}, nil
+
+	logger.Info("undeleteSkill", "bundleID", req.BundleID, "skillSlug", req.SkillSlug)
+	return &spec.UndeleteSkillResponse{}, nil
 }
 
 func (s *SkillStore) GetSkill(
@@ -678,7 +1223,7 @@ func (s *SkillStore) GetSkill(
 
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	user, err := s.readAllUser(false)
+	user, err := s.readAllUser(ctx, false)
 	if err != nil {
 		return nil, err
 	}
@@ -1,14 +1,23 @@
 package skillstore
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/featureflag"
+	"github.com/flexigpt/flexigpt-app/internal/fsutil"
+	"github.com/flexigpt/flexigpt-app/internal/idempotency"
 	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
 )
 
@@ -96,22 +105,30 @@ func TestSkillCursor_RoundTripAndErrors(t *testing.T) {
 	t.Parallel()
 
 	now := time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC)
-	cur := buildSkillCursor("b1", "s1", now)
+	order := spec.SkillListOrderModifiedAtDesc
+	cur := buildSkillCursor(order, skillSortKey{Time: now, BundleID: "b1", SkillSlug: "s1"})
 
-	parsed, err := parseSkillCursor(cur)
+	parsed, err := parseSkillCursor(order, cur)
 	if err != nil {
 		t.Fatalf("parseSkillCursor: %v", err)
 	}
-	if !parsed.ModTime.Equal(now) || parsed.BundleID != "b1" || parsed.SkillSlug != "s1" {
+	if !parsed.Time.Equal(now) || parsed.BundleID != "b1" || parsed.SkillSlug != "s1" {
 		t.Fatalf("parsed mismatch: %+v", parsed)
 	}
 
 	bad := []string{"", "a|b", "not-a-time|b|c", "2026-01-01T00:00:00Z|b|c|d"}
 	for _, s := range bad {
-		if _, err := parseSkillCursor(s); err == nil {
+		if _, err := parseSkillCursor(order, s); err == nil {
 			t.Fatalf("expected error for cursor %q", s)
 		}
 	}
+
+	// Name keys may contain the separator.
+	nameCur := buildSkillCursor(spec.SkillListOrderNameAsc, skillSortKey{Text: "a|b", BundleID: "b1", SkillSlug: "s1"})
+	parsed, err = parseSkillCursor(spec.SkillListOrderNameAsc, nameCur)
+	if err != nil || parsed.Text != "a|b" || parsed.BundleID != "b1" || parsed.SkillSlug != "s1" {
+		t.Fatalf("name cursor = %+v, %v", parsed, err)
+	}
 }
 
 func TestSkillStore_PutSkillBundle_Table(t *testing.T) {
@@ -272,7 +289,7 @@ func TestSkillStore_SweepSoftDeleted_HardDeletesAfterGrace(t *testing.T) {
 	// Force softDeletedAt older than grace, then run sweep.
 	s.writeMu.Lock()
 	s.mu.Lock()
-	all, err := s.readAllUser(true)
+	all, err := s.readAllUser(t.Context(), true)
 	if err != nil {
 		s.mu.Unlock()
 		s.writeMu.Unlock()
@@ -282,7 +299,7 @@ func TestSkillStore_SweepSoftDeleted_HardDeletesAfterGrace(t *testing.T) {
 	old := time.Now().UTC().Add(-(softDeleteGraceSkills + time.Hour))
 	b.SoftDeletedAt = &old
 	all.Bundles["b1"] = b
-	if err := s.writeAllUser(all); err != nil {
+	if err := s.writeAllUser(t.Context(), all); err != nil {
 		s.mu.Unlock()
 		s.writeMu.Unlock()
 		t.Fatalf("writeAllUser: %v", err)
@@ -290,7 +307,7 @@ func TestSkillStore_SweepSoftDeleted_HardDeletesAfterGrace(t *testing.T) {
 	s.mu.Unlock()
 	s.writeMu.Unlock()
 
-	s.sweepSoftDeleted()
+	s.sweepSoftDeleted(t.Context())
 
 	resp, err := s.ListSkillBundles(t.Context(), &spec.ListSkillBundlesRequest{
 		BundleIDs:       []bundleitemutils.BundleID{"b1"},
@@ -304,6 +321,343 @@ func TestSkillStore_SweepSoftDeleted_HardDeletesAfterGrace(t *testing.T) {
 	}
 }
 
+func TestSkillStore_SoftDeleteGraceOption(t *testing.T) {
+	t.Parallel()
+	if _, err := NewSkillStore(t.TempDir(), WithCleanupInterval(0)); !errors.Is(err, errSkillInvalidRequest) {
+		t.Fatalf("zero cleanup interval: want errSkillInvalidRequest, got %v", err)
+	}
+
+	s, err := NewSkillStore(t.TempDir(), WithSoftDeleteGrace(0), WithCleanupInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewSkillStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	putBundle(t, s, "b1", testBundleSlug, testBundleDisplayName, true)
+	if _, err := s.DeleteSkillBundle(t.Context(), &spec.DeleteSkillBundleRequest{BundleID: "b1"}); err != nil {
+		t.Fatalf("DeleteSkillBundle: %v", err)
+	}
+
+	s.sweepSoftDeleted(t.Context())
+
+	s.mu.RLock()
+	all, err := s.readAllUser(t.Context(), false)
+	s.mu.RUnlock()
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if _, ok := all.Bundles["b1"]; ok {
+		t.Fatal("expected bundle to be hard-deleted with zero grace")
+	}
+}
+
+func TestSkillStore_DeleteSkill_Undelete(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	ctx := t.Context()
+	putBundle(t, s, "b1", testBundleSlug, testBundleDisplayName, true)
+	resp, err := s.CreateSkillScaffold(ctx, &spec.CreateSkillScaffoldRequest{
+		BundleID:  "b1",
+		SkillSlug: "s1",
+		Body:      &spec.CreateSkillScaffoldRequestBody{Description: "d", IsEnabled: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateSkillScaffold: %v", err)
+	}
+	location := resp.Body.Skill.Location
+	ref := spec.SkillRef{BundleID: "b1", SkillSlug: "s1"}
+	if err := s.RecordSkillActivations(ctx, []spec.SkillRef{ref}); err != nil {
+		t.Fatalf("RecordSkillActivations: %v", err)
+	}
+
+	del := &spec.DeleteSkillRequest{BundleID: "b1", SkillSlug: "s1"}
+	if _, err := s.DeleteSkill(ctx, del); err != nil {
+		t.Fatalf("DeleteSkill: %v", err)
+	}
+	get := &spec.GetSkillRequest{BundleID: "b1", SkillSlug: "s1", IncludeDisabled: true}
+	if _, err := s.GetSkill(ctx, get); !errors.Is(err, errSkillNotFound) {
+		t.Fatalf("GetSkill after delete: want errSkillNotFound, got %v", err)
+	}
+	if _, err := os.Stat(location); err != nil {
+		t.Fatalf("managed package removed on soft delete: %v", err)
+	}
+	if _, err := s.PutSkill(ctx, &spec.PutSkillRequest{
+		BundleID:  "b1",
+		SkillSlug: "s1",
+		Body:      &spec.PutSkillRequestBody{SkillType: spec.SkillTypeFS, Location: location, Name: "s1"},
+	}); !errors.Is(err, errSkillDeleting) {
+		t.Fatalf("PutSkill over deleted slug: want errSkillDeleting, got %v", err)
+	}
+
+	undel := &spec.UndeleteSkillRequest{BundleID: "b1", SkillSlug: "s1"}
+	if _, err := s.UndeleteSkill(ctx, undel); err != nil {
+		t.Fatalf("UndeleteSkill: %v", err)
+	}
+	got, err := s.GetSkill(ctx, get)
+	if err != nil || !got.Body.IsEnabled || got.Body.SoftDeletedAt != nil {
+		t.Fatalf("GetSkill after undelete = %+v, %v", got, err)
+	}
+	if at, _ := s.GetSkillLastActivations(ctx, []spec.SkillRef{ref}); len(at) != 1 {
+		t.Fatalf("activation not restored: %v", at)
+	}
+	if _, err := s.UndeleteSkill(ctx, undel); !errors.Is(err, errSkillNotFound) {
+		t.Fatalf("second UndeleteSkill: want errSkillNotFound, got %v", err)
+	}
+
+	// A bundle holding only deleted skills can be deleted, and purging it
+	// removes their packages.
+	if _, err := s.DeleteSkill(ctx, del); err != nil {
+		t.Fatalf("DeleteSkill: %v", err)
+	}
+	if _, err := s.DeleteSkillBundle(ctx, &spec.DeleteSkillBundleRequest{BundleID: "b1"}); err != nil {
+		t.Fatalf("DeleteSkillBundle: %v", err)
+	}
+	if _, err := s.UndeleteSkill(ctx, undel); !errors.Is(err, errSkillBundleDeleting) {
+		t.Fatalf("UndeleteSkill in deleted bundle: want errSkillBundleDeleting, got %v", err)
+	}
+	if _, err := s.PurgeSkillBundle(ctx, &spec.PurgeSkillBundleRequest{BundleID: "b1"}); err != nil {
+		t.Fatalf("PurgeSkillBundle: %v", err)
+	}
+	if _, err := os.Stat(location); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("managed package after purge: %v", err)
+	}
+}
+
+func TestSkillStore_SweepDeletedSkills(t *testing.T) {
+	t.Parallel()
+	s, err := NewSkillStore(t.TempDir(), WithSoftDeleteGrace(0), WithCleanupInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewSkillStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	ctx := t.Context()
+	putBundle(t, s, "b1", testBundleSlug, testBundleDisplayName, true)
+	resp, err := s.CreateSkillScaffold(ctx, &spec.CreateSkillScaffoldRequest{
+		BundleID:  "b1",
+		SkillSlug: "s1",
+		Body:      &spec.CreateSkillScaffoldRequestBody{Description: "d", IsEnabled: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateSkillScaffold: %v", err)
+	}
+	if _, err := s.DeleteSkill(ctx, &spec.DeleteSkillRequest{BundleID: "b1", SkillSlug: "s1"}); err != nil {
+		t.Fatalf("DeleteSkill: %v", err)
+	}
+
+	s.sweepSoftDeleted(ctx)
+
+	s.mu.RLock()
+	all, err := s.readAllUser(ctx, false)
+	s.mu.RUnlock()
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if len(all.DeletedSkills) != 0 {
+		t.Fatalf("expected deleted skill to be hard-deleted, got %+v", all.DeletedSkills)
+	}
+	if _, err := os.Stat(resp.Body.Skill.Location); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("managed package after sweep: %v", err)
+	}
+	if _, err := s.UndeleteSkill(ctx, &spec.UndeleteSkillRequest{BundleID: "b1", SkillSlug: "s1"}); !errors.Is(
+		err, errSkillNotFound,
+	) {
+		t.Fatalf("UndeleteSkill after sweep: want errSkillNotFound, got %v", err)
+	}
+}
+
+func TestSkillStore_ListSoftDeleted(t *testing.T) {
+	t.Parallel()
+	s, err := NewSkillStore(t.TempDir(), WithSoftDeleteGrace(time.Hour))
+	if err != nil {
+		t.Fatalf("NewSkillStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	ctx := t.Context()
+	putBundle(t, s, "b1", testBundleSlug, testBundleDisplayName, true)
+	putBundle(t, s, "b2", "bundle-two", "Bundle Two", true)
+	root := t.TempDir()
+	for _, slug := range []string{"s1", "s2"} {
+		if err := putSkill(t, s, "b1", slug, root, slug, "desc", "BODY", true); err != nil {
+			t.Fatalf("PutSkill %s: %v", slug, err)
+		}
+		del := &spec.DeleteSkillRequest{BundleID: "b1", SkillSlug: spec.SkillSlug(slug)}
+		if _, err := s.DeleteSkill(ctx, del); err != nil {
+			t.Fatalf("DeleteSkill %s: %v", slug, err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	if _, err := s.DeleteSkillBundle(ctx, &spec.DeleteSkillBundleRequest{BundleID: "b2"}); err != nil {
+		t.Fatalf("DeleteSkillBundle: %v", err)
+	}
+
+	resp, err := s.ListSoftDeleted(ctx, &spec.ListSoftDeletedRequest{})
+	if err != nil {
+		t.Fatalf("ListSoftDeleted: %v", err)
+	}
+	bundles, skills := resp.Body.SkillBundles, resp.Body.Skills
+	if len(bundles) != 1 || bundles[0].SkillBundle.ID != "b2" {
+		t.Fatalf("bundles = %+v, want b2", bundles)
+	}
+	if len(skills) != 2 || skills[0].Skill.Slug != "s2" || skills[1].Skill.Slug != "s1" {
+		t.Fatalf("skills = %+v, want s2 then s1", skills)
+	}
+	for _, sk := range skills {
+		if !sk.PurgeAt.Equal(sk.Skill.SoftDeletedAt.Add(time.Hour)) {
+			t.Fatalf("purgeAt = %v, want an hour after %v", sk.PurgeAt, sk.Skill.SoftDeletedAt)
+		}
+		if sk.RemainingGraceMS <= 0 || sk.RemainingGraceMS > time.Hour.Milliseconds() {
+			t.Fatalf("remainingGraceMS = %d", sk.RemainingGraceMS)
+		}
+	}
+}
+
+func TestSkillStore_PurgeSkillBundle(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	ctx := t.Context()
+	putBundle(t, s, "b1", testBundleSlug, testBundleDisplayName, true)
+
+	if _, err := s.PurgeSkillBundle(ctx, &spec.PurgeSkillBundleRequest{BundleID: "b1"}); !errors.Is(
+		err, errSkillInvalidRequest,
+	) {
+		t.Fatalf("purge of live bundle: want errSkillInvalidRequest, got %v", err)
+	}
+	if _, err := s.PurgeSkillBundle(ctx, &spec.PurgeSkillBundleRequest{BundleID: testNope}); !errors.Is(
+		err, errSkillBundleNotFound,
+	) {
+		t.Fatalf("purge of unknown bundle: want errSkillBundleNotFound, got %v", err)
+	}
+
+	if _, err := s.DeleteSkillBundle(ctx, &spec.DeleteSkillBundleRequest{BundleID: "b1"}); err != nil {
+		t.Fatalf("DeleteSkillBundle: %v", err)
+	}
+	if _, err := s.PurgeSkillBundle(ctx, &spec.PurgeSkillBundleRequest{BundleID: "b1"}); err != nil {
+		t.Fatalf("PurgeSkillBundle: %v", err)
+	}
+
+	s.mu.RLock()
+	all, err := s.readAllUser(t.Context(), false)
+	s.mu.RUnlock()
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if _, ok := all.Bundles["b1"]; ok {
+		t.Fatal("expected purged bundle to be gone")
+	}
+	// The ID is free again.
+	putBundle(t, s, "b1", testBundleSlug, testBundleDisplayName, true)
+}
+
+func TestSkillStore_ExternalModification_ReloadAndConflict(t *testing.T) {
+	// Edits the store file directly, so the bundle must not sit in the journal.
+	s := newTestSkillStore(t, withoutFeature(featureflag.JournaledUserWrites))
+	ctx := t.Context()
+	putBundle(t, s, "ext", "ext", "Before", true)
+
+	editExternally := func(t *testing.T, displayName string, mtime time.Time) {
+		t.Helper()
+		raw, err := os.ReadFile(s.userFilePath())
+		if err != nil {
+			t.Fatalf("read user file: %v", err)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			t.Fatalf("unmarshal user file: %v", err)
+		}
+		bundles, _ := doc[builtInSkillBundlesGroupID].(map[string]any)
+		b, _ := bundles["ext"].(map[string]any)
+		b[testDisplayNameKey] = displayName
+		out, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatalf("marshal user file: %v", err)
+		}
+		if err := os.WriteFile(s.userFilePath(), out, 0o600); err != nil {
+			t.Fatalf("write user file: %v", err)
+		}
+		if err := os.Chtimes(s.userFilePath(), mtime, mtime); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+	displayName := func(t *testing.T) string {
+		t.Helper()
+		sc, err := readAllUserLocked(t, s, false)
+		if err != nil {
+			t.Fatalf("readAllUser: %v", err)
+		}
+		return sc.Bundles["ext"].DisplayName
+	}
+
+	t.Run("reload-runs-handler-once", func(t *testing.T) {
+		var calls atomic.Int32
+		s.SetExternalChangeHandler(func(context.Context) error {
+			calls.Add(1)
+			return nil
+		})
+		t.Cleanup(func() { s.SetExternalChangeHandler(nil) })
+
+		editExternally(t, "After", time.Now().Add(time.Hour))
+		s.reloadIfChangedExternally(ctx)
+		if got := displayName(t); got != "After" {
+			t.Fatalf("expected reloaded displayName, got %q", got)
+		}
+		s.reloadIfChangedExternally(ctx)
+		if got := calls.Load(); got != 1 {
+			t.Fatalf("expected handler to run once, got %d", got)
+		}
+	})
+
+	t.Run("write-applies-on-top-of-external-edit", func(t *testing.T) {
+		editExternally(t, "Edited outside", time.Now().Add(2*time.Hour))
+		_, err := s.PatchSkillBundle(ctx, &spec.PatchSkillBundleRequest{
+			BundleID: "ext",
+			Body:     &spec.PatchSkillBundleRequestBody{IsEnabled: false},
+		})
+		if err != nil {
+			t.Fatalf("PatchSkillBundle: %v", err)
+		}
+		if got := displayName(t); got != "Edited outside" {
+			t.Fatalf("in-app write dropped external edit, got %q", got)
+		}
+	})
+
+	t.Run("edit-during-write-conflicts", func(t *testing.T) {
+		err := s.withUserWrite(ctx, "test", func(sc *skillStoreSchema) error {
+			b := sc.Bundles["ext"]
+			b.DisplayName = "In app"
+			sc.Bundles["ext"] = b
+			editExternally(t, "Concurrent", time.Now().Add(3*time.Hour))
+			return nil
+		})
+		if !errors.Is(err, errSkillConflict) || !errors.Is(err, fsutil.ErrExternalModification) {
+			t.Fatalf("expected conflict, got %v", err)
+		}
+		s.reloadIfChangedExternally(ctx)
+		if got := displayName(t); got != "Concurrent" {
+			t.Fatalf("expected external edit to win, got %q", got)
+		}
+	})
+
+	t.Run("same-size-edit-keeping-mtime-conflicts", func(t *testing.T) {
+		fi, err := os.Stat(s.userFilePath())
+		if err != nil {
+			t.Fatalf("stat user file: %v", err)
+		}
+		err = s.withUserWrite(ctx, "test", func(sc *skillStoreSchema) error {
+			b := sc.Bundles["ext"]
+			b.DisplayName = "In app"
+			sc.Bundles["ext"] = b
+			editExternally(t, "Concurreny", fi.ModTime())
+			return nil
+		})
+		if !errors.Is(err, fsutil.ErrExternalModification) {
+			t.Fatalf("expected external modification, got %v", err)
+		}
+		s.reloadIfChangedExternally(ctx)
+		if got := displayName(t); got != "Concurreny" {
+			t.Fatalf("expected external edit to be reloaded, got %q", got)
+		}
+	})
+}
+
 func TestSkillStore_GetSkill_DisabledChecks(t *testing.T) {
 	t.Parallel()
 	s := newTestSkillStore(t)
@@ -335,6 +689,100 @@ func TestSkillStore_GetSkill_DisabledChecks(t *testing.T) {
 	}
 }
 
+func TestSkillStore_SkillActivations_RecordAndPrune(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	ctx := t.Context()
+
+	putBundle(t, s, "b1", testBundleSlug, testBundleDisplayName, true)
+	if err := putSkill(t, s, "b1", "s1", t.TempDir(), "s1", "mySkill1", "My Skill 1", true); err != nil {
+		t.Fatalf("PutSkill: %v", err)
+	}
+	used := spec.SkillRef{BundleID: "b1", SkillSlug: "s1"}
+	unused := spec.SkillRef{BundleID: "b1", SkillSlug: "s2"}
+
+	if err := s.RecordSkillActivations(ctx, []spec.SkillRef{{BundleID: "b1"}}); !errors.Is(err, errSkillInvalidRequest) {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+
+	before := time.Now().UTC()
+	if err := s.RecordSkillActivations(ctx, []spec.SkillRef{used}); err != nil {
+		t.Fatalf("RecordSkillActivations: %v", err)
+	}
+	got, err := s.GetSkillLastActivations(ctx, []spec.SkillRef{used, unused})
+	if err != nil {
+		t.Fatalf("GetSkillLastActivations: %v", err)
+	}
+	if len(got) != 1 || got[used].Before(before) {
+		t.Fatalf("unexpected activations: %v", got)
+	}
+
+	if _, err := s.DeleteSkill(ctx, &spec.DeleteSkillRequest{BundleID: "b1", SkillSlug: "s1"}); err != nil {
+		t.Fatalf("DeleteSkill: %v", err)
+	}
+	got, err = s.GetSkillLastActivations(ctx, []spec.SkillRef{used})
+	if err != nil {
+		t.Fatalf("GetSkillLastActivations: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("activation not pruned on delete: %v", got)
+	}
+}
+
+func TestSkillStore_SkillUsageStats(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	ctx := t.Context()
+
+	putBundle(t, s, "b1", testBundleSlug, testBundleDisplayName, true)
+	root := t.TempDir()
+	for _, slug := range []string{"s1", "s2"} {
+		if err := putSkill(t, s, "b1", slug, root, slug, "desc", "BODY", true); err != nil {
+			t.Fatalf("PutSkill %s: %v", slug, err)
+		}
+	}
+	used := spec.SkillRef{BundleID: "b1", SkillSlug: "s1"}
+
+	if err := s.RecordSkillActivations(ctx, []spec.SkillRef{used, used}); err != nil {
+		t.Fatalf("RecordSkillActivations: %v", err)
+	}
+	// A batch of two activations in two sessions, recorded before the last one.
+	earlier := time.Now().Add(-time.Hour).UTC()
+	if err := s.RecordSkillUsage(ctx, map[spec.SkillRef]spec.SkillUsage{
+		used: {ActivationCount: 2, SessionCount: 2, LastActivatedAt: &earlier},
+	}); err != nil {
+		t.Fatalf("RecordSkillUsage: %v", err)
+	}
+
+	resp, err := s.GetSkillStats(ctx, &spec.GetSkillStatsRequest{BundleIDs: []bundleitemutils.BundleID{"b1"}})
+	if err != nil {
+		t.Fatalf("GetSkillStats: %v", err)
+	}
+	stats := resp.Body.Stats
+	if len(stats) != 2 || stats[0].SkillSlug != "s1" || stats[1].SkillSlug != "s2" {
+		t.Fatalf("unexpected stats order: %+v", stats)
+	}
+	if u := stats[0].Usage; u.ActivationCount != 3 || u.SessionCount != 2 || u.LastActivatedAt == nil {
+		t.Fatalf("unexpected usage: %+v", u)
+	}
+	if stats[0].Usage.LastActivatedAt.Equal(earlier) {
+		t.Fatal("an older batch moved the last activation back")
+	}
+	if u := stats[1].Usage; u.ActivationCount != 0 || u.LastActivatedAt != nil {
+		t.Fatalf("unused skill has usage: %+v", u)
+	}
+
+	list, err := s.ListSkills(ctx, &spec.ListSkillsRequest{BundleIDs: []bundleitemutils.BundleID{"b1"}})
+	if err != nil {
+		t.Fatalf("ListSkills: %v", err)
+	}
+	for _, item := range list.Body.SkillListItems {
+		if (item.SkillSlug == "s1") != (item.Usage != nil) {
+			t.Fatalf("unexpected usage on %s: %+v", item.SkillSlug, item.Usage)
+		}
+	}
+}
+
 func TestSkillStore_ListSkillBundles_FiltersAndPaging(t *testing.T) {
 	t.Parallel()
 	s := newTestSkillStore(t)
@@ -423,7 +871,7 @@ func TestSkillStore_ListSkills_UserOnlyFiltersAndPaging(t *testing.T) {
 	// Mark s2 missing (so it should be excluded when IncludeMissing=false).
 	s.writeMu.Lock()
 	s.mu.Lock()
-	all, err := s.readAllUser(true)
+	all, err := s.readAllUser(t.Context(), true)
 	if err != nil {
 		s.mu.Unlock()
 		s.writeMu.Unlock()
@@ -432,7 +880,7 @@ func TestSkillStore_ListSkills_UserOnlyFiltersAndPaging(t *testing.T) {
 	sk2 := all.Skills["b1"]["s2"]
 	sk2.Presence = &spec.SkillPresence{Status: spec.SkillPresenceMissing}
 	all.Skills["b1"]["s2"] = sk2
-	if err := s.writeAllUser(all); err != nil {
+	if err := s.writeAllUser(t.Context(), all); err != nil {
 		s.mu.Unlock()
 		s.writeMu.Unlock()
 		t.Fatalf("writeAllUser: %v", err)
@@ -506,77 +954,265 @@ func TestSkillStore_ListSkills_UserOnlyFiltersAndPaging(t *testing.T) {
 	}
 }
 
-func TestSkillStore_ConcurrentPutAndList(t *testing.T) {
+func TestSkillStore_ListSkills_Merged(t *testing.T) {
 	t.Parallel()
 	s := newTestSkillStore(t)
 
 	putBundle(t, s, "b1", testBundleSlug, testBundleDisplayName, true)
-
-	ctx := t.Context()
-	const n = 30
-
-	var wg sync.WaitGroup
-	errCh := make(chan error, n+100)
 	skillBaseDir := t.TempDir()
-
-	// Writer goroutines.
-	for i := range n {
-		wg.Add(1)
-		go func(i int) {
-			defer wg.Done()
-			slug := "sk-" + strconv.Itoa(i)
-			err := putSkill(t, s, "b1", slug, skillBaseDir, slug, "my "+slug, "My "+slug, true)
-			if err != nil {
-				errCh <- err
-			}
-		}(i)
+	for _, slug := range []string{"s1", "s2", "s3"} {
+		time.Sleep(2 * time.Millisecond)
+		if err := putSkill(t, s, "b1", slug, skillBaseDir, slug, "desc", "body", true); err != nil {
+			t.Fatalf("PutSkill: %v", err)
+		}
 	}
 
-	// Concurrent lister.
-	wg.Go(func() {
-		for range 50 {
-			_, err := s.ListSkills(ctx, &spec.ListSkillsRequest{
-				BundleIDs:           []bundleitemutils.BundleID{"b1"},
-				Types:               []spec.SkillType{spec.SkillTypeFS},
-				IncludeDisabled:     true,
-				IncludeMissing:      true,
-				RecommendedPageSize: 25,
-			})
+	listAll := func(merged bool) []spec.SkillListItem {
+		t.Helper()
+		var items []spec.SkillListItem
+		req := &spec.ListSkillsRequest{Merged: merged, IncludeDisabled: true, RecommendedPageSize: 2}
+		for {
+			resp, err := s.ListSkills(t.Context(), req)
 			if err != nil {
-				errCh <- err
-				return
+				t.Fatalf("ListSkills(merged=%v): %v", merged, err)
+			}
+			items = append(items, resp.Body.SkillListItems...)
+			if resp.Body.NextPageToken == nil {
+				return items
 			}
+			req = &spec.ListSkillsRequest{PageToken: *resp.Body.NextPageToken}
 		}
-	})
+	}
 
-	wg.Wait()
-	close(errCh)
+	phased := listAll(false)
+	merged := listAll(true)
+	if len(merged) != len(phased) {
+		t.Fatalf("merged listed %d skills, phased %d", len(merged), len(phased))
+	}
+	if len(phased) <= 3 || !phased[0].IsBuiltIn {
+		t.Skip("no built-in skills to merge")
+	}
 
-	for err := range errCh {
-		t.Fatalf("concurrency error: %v", err)
+	seen := map[string]bool{}
+	for i, it := range merged {
+		key := string(it.BundleID) + "/" + string(it.SkillSlug)
+		if seen[key] {
+			t.Fatalf("skill %s listed twice", key)
+		}
+		seen[key] = true
+		if i > 0 && it.SkillDefinition.ModifiedAt.After(merged[i-1].SkillDefinition.ModifiedAt) {
+			t.Fatalf("item %d (%s) is newer than its predecessor", i, key)
+		}
+	}
+	// The user skills were modified last, so they lead the merged stream.
+	for i, want := range []spec.SkillSlug{"s3", "s2", "s1"} {
+		if merged[i].IsBuiltIn || merged[i].SkillSlug != want {
+			t.Fatalf("merged[%d] = %s/%s, want b1/%s", i, merged[i].BundleID, merged[i].SkillSlug, want)
+		}
 	}
 }
 
-func TestSkillStore_BuiltInReadOnly_Guards(t *testing.T) {
+func TestSkillStore_ListSkills_OrderBy(t *testing.T) {
 	t.Parallel()
 	s := newTestSkillStore(t)
 
-	if s.builtin == nil {
-		t.Skip("builtin store not initialized")
+	putBundle(t, s, "b1", testBundleSlug, testBundleDisplayName, true)
+	skillBaseDir := t.TempDir()
+	for _, sk := range []struct{ slug, name string }{
+		{"s1", "beta"},
+		{"s2", "alpha"},
+		{"s3", "gamma"},
+	} {
+		time.Sleep(2 * time.Millisecond)
+		if err := putSkill(t, s, "b1", sk.slug, skillBaseDir, sk.name, "desc", "body", true); err != nil {
+			t.Fatalf("PutSkill: %v", err)
+		}
 	}
 
-	ctx := t.Context()
-	bundles, skills, err := s.builtin.ListBuiltInSkills(ctx)
-	if err != nil {
-		t.Fatalf("ListBuiltInSkills: %v", err)
-	}
-	if len(bundles) == 0 {
-		t.Skip("no built-in bundles available")
+	listAll := func(order spec.SkillListOrder) []spec.SkillSlug {
+		t.Helper()
+		var slugs []spec.SkillSlug
+		req := &spec.ListSkillsRequest{
+			BundleIDs:           []bundleitemutils.BundleID{"b1"},
+			Types:               []spec.SkillType{spec.SkillTypeFS},
+			OrderBy:             order,
+			RecommendedPageSize: 1,
+		}
+		for {
+			resp, err := s.ListSkills(t.Context(), req)
+			if err != nil {
+				t.Fatalf("ListSkills(%q): %v", order, err)
+			}
+			for _, it := range resp.Body.SkillListItems {
+				slugs = append(slugs, it.SkillSlug)
+			}
+			if resp.Body.NextPageToken == nil {
+				return slugs
+			}
+			req = &spec.ListSkillsRequest{PageToken: *resp.Body.NextPageToken}
+		}
 	}
 
-	// Pick any built-in bundle & skill.
-	var bid bundleitemutils.BundleID
-	for id := range bundles {
+	tests := []struct {
+		order spec.SkillListOrder
+		want  []spec.SkillSlug
+	}{
+		{spec.SkillListOrderDefault, []spec.SkillSlug{"s3", "s2", "s1"}},
+		{spec.SkillListOrderCreatedAtDesc, []spec.SkillSlug{"s3", "s2", "s1"}},
+		{spec.SkillListOrderNameAsc, []spec.SkillSlug{"s2", "s1", "s3"}},
+		{spec.SkillListOrderNameDesc, []spec.SkillSlug{"s3", "s1", "s2"}},
+		{spec.SkillListOrderBundle, []spec.SkillSlug{"s1", "s2", "s3"}},
+	}
+	for _, tc := range tests {
+		if got := listAll(tc.order); !slices.Equal(got, tc.want) {
+			t.Errorf("order %q = %v, want %v", tc.order, got, tc.want)
+		}
+	}
+
+	_, err := s.ListSkills(t.Context(), &spec.ListSkillsRequest{OrderBy: "size"})
+	if !errors.Is(err, errSkillInvalidRequest) {
+		t.Fatalf("expected ErrSkillInvalidRequest for unknown order, got %v", err)
+	}
+}
+
+func TestSkillStore_CountSkills(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+
+	putBundle(t, s, "b1", testBundleSlug, testBundleDisplayName, true)
+	skillBaseDir := t.TempDir()
+	for i, name := range []string{"one", "two", "three"} {
+		slug := "s" + strconv.Itoa(i+1)
+		if err := putSkill(t, s, "b1", slug, skillBaseDir, name, "desc", "body", name != "three"); err != nil {
+			t.Fatalf("PutSkill: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		req  spec.CountSkillsRequest
+		want spec.CountSkillsResponseBody
+	}{
+		{
+			"include-disabled",
+			spec.CountSkillsRequest{BundleIDs: []bundleitemutils.BundleID{"b1"}, IncludeDisabled: true},
+			spec.CountSkillsResponseBody{Count: 3, DisabledCount: 1},
+		},
+		{
+			"enabled-only",
+			spec.CountSkillsRequest{BundleIDs: []bundleitemutils.BundleID{"b1"}},
+			spec.CountSkillsResponseBody{Count: 2},
+		},
+		{
+			"no-match",
+			spec.CountSkillsRequest{BundleIDs: []bundleitemutils.BundleID{"b1"}, Tags: []string{"none"}},
+			spec.CountSkillsResponseBody{},
+		},
+	}
+	for _, tc := range tests {
+		resp, err := s.CountSkills(t.Context(), &tc.req)
+		if err != nil {
+			t.Fatalf("%s: CountSkills: %v", tc.name, err)
+		}
+		if *resp.Body != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, *resp.Body, tc.want)
+		}
+	}
+
+	// Without filters the count covers built-ins too and matches the list.
+	listed := 0
+	req := &spec.ListSkillsRequest{IncludeDisabled: true, IncludeMissing: true}
+	for {
+		resp, err := s.ListSkills(t.Context(), req)
+		if err != nil {
+			t.Fatalf("ListSkills: %v", err)
+		}
+		listed += len(resp.Body.SkillListItems)
+		if resp.Body.NextPageToken == nil {
+			break
+		}
+		req = &spec.ListSkillsRequest{PageToken: *resp.Body.NextPageToken}
+	}
+	all, err := s.CountSkills(t.Context(), &spec.CountSkillsRequest{IncludeDisabled: true, IncludeMissing: true})
+	if err != nil {
+		t.Fatalf("CountSkills: %v", err)
+	}
+	if all.Body.Count != listed {
+		t.Fatalf("count = %d, listed %d", all.Body.Count, listed)
+	}
+}
+
+func TestSkillStore_ConcurrentPutAndList(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+
+	putBundle(t, s, "b1", testBundleSlug, testBundleDisplayName, true)
+
+	ctx := t.Context()
+	const n = 30
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, n+100)
+	skillBaseDir := t.TempDir()
+
+	// Writer goroutines.
+	for i := range n {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			slug := "sk-" + strconv.Itoa(i)
+			err := putSkill(t, s, "b1", slug, skillBaseDir, slug, "my "+slug, "My "+slug, true)
+			if err != nil {
+				errCh <- err
+			}
+		}(i)
+	}
+
+	// Concurrent lister.
+	wg.Go(func() {
+		for range 50 {
+			_, err := s.ListSkills(ctx, &spec.ListSkillsRequest{
+				BundleIDs:           []bundleitemutils.BundleID{"b1"},
+				Types:               []spec.SkillType{spec.SkillTypeFS},
+				IncludeDisabled:     true,
+				IncludeMissing:      true,
+				RecommendedPageSize: 25,
+			})
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	})
+
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		t.Fatalf("concurrency error: %v", err)
+	}
+}
+
+func TestSkillStore_BuiltInReadOnly_Guards(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+
+	if s.builtin == nil {
+		t.Skip("builtin store not initialized")
+	}
+
+	ctx := t.Context()
+	bundles, skills, err := s.builtin.ListBuiltInSkills(ctx)
+	if err != nil {
+		t.Fatalf("ListBuiltInSkills: %v", err)
+	}
+	if len(bundles) == 0 {
+		t.Skip("no built-in bundles available")
+	}
+
+	// Pick any built-in bundle & skill.
+	var bid bundleitemutils.BundleID
+	for id := range bundles {
 		bid = id
 		break
 	}
@@ -820,3 +1456,790 @@ func TestSkillStore_readAllUser_HardeningAndCorruptionDetection(t *testing.T) {
 		}
 	})
 }
+
+func TestSkillStore_TriggerPresenceCheck(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	putBundle(t, s, "pres", "presence", "Presence", true)
+
+	root := t.TempDir()
+	if err := putSkill(t, s, "pres", "keep", root, "keep-skill", "desc", "BODY", true); err != nil {
+		t.Fatalf("putSkill keep: %v", err)
+	}
+	if err := putSkill(t, s, "pres", "gone", root, "gone-skill", "desc", "BODY", true); err != nil {
+		t.Fatalf("putSkill gone: %v", err)
+	}
+	if err := os.RemoveAll(filepath.Join(root, "gone-skill")); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.TriggerPresenceCheck(t.Context(), &spec.TriggerPresenceCheckRequest{
+		BundleIDs: []bundleitemutils.BundleID{"pres"},
+	})
+	if err != nil {
+		t.Fatalf("TriggerPresenceCheck: %v", err)
+	}
+	if resp.Body.Checked != 2 || resp.Body.Present != 1 || resp.Body.Missing != 1 {
+		t.Fatalf("unexpected counts: %+v", resp.Body)
+	}
+
+	gone, err := s.GetSkill(t.Context(), &spec.GetSkillRequest{BundleID: "pres", SkillSlug: "gone"})
+	if err != nil {
+		t.Fatalf("GetSkill: %v", err)
+	}
+	p := gone.Body.Presence
+	if p == nil || p.Status != spec.SkillPresenceMissing || p.MissingSince == nil || p.LastCheckedAt == nil {
+		t.Fatalf("unexpected presence: %+v", p)
+	}
+	missingSince, lastChecked := *p.MissingSince, *p.LastCheckedAt
+
+	list, err := s.ListSkills(t.Context(), &spec.ListSkillsRequest{
+		BundleIDs: []bundleitemutils.BundleID{"pres"},
+	})
+	if err != nil {
+		t.Fatalf("ListSkills: %v", err)
+	}
+	if len(list.Body.SkillListItems) != 1 || list.Body.SkillListItems[0].SkillSlug != "keep" {
+		t.Fatalf("missing skill not filtered: %+v", list.Body.SkillListItems)
+	}
+
+	// MissingSince survives repeated checks and clears once the package is back.
+	// A check that changes nothing does not rewrite the store.
+	resp, err = s.TriggerPresenceCheck(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("TriggerPresenceCheck: %v", err)
+	}
+	if resp.Body.Checked != 2 || resp.Body.Missing != 1 {
+		t.Fatalf("unexpected counts on recheck: %+v", resp.Body)
+	}
+	gone, _ = s.GetSkill(t.Context(), &spec.GetSkillRequest{BundleID: "pres", SkillSlug: "gone"})
+	if !gone.Body.Presence.MissingSince.Equal(missingSince) {
+		t.Fatalf("MissingSince moved: %v -> %v", missingSince, gone.Body.Presence.MissingSince)
+	}
+	if !gone.Body.Presence.LastCheckedAt.Equal(lastChecked) {
+		t.Fatalf("unchanged presence was rewritten: %v -> %v", lastChecked, gone.Body.Presence.LastCheckedAt)
+	}
+	writeSkillPackage(t, root, "gone-skill", "desc", "BODY")
+	if _, err := s.TriggerPresenceCheck(t.Context(), nil); err != nil {
+		t.Fatalf("TriggerPresenceCheck: %v", err)
+	}
+	gone, _ = s.GetSkill(t.Context(), &spec.GetSkillRequest{BundleID: "pres", SkillSlug: "gone"})
+	if p := gone.Body.Presence; p.Status != spec.SkillPresencePresent || p.MissingSince != nil || p.LastSeenAt == nil {
+		t.Fatalf("unexpected presence after restore: %+v", p)
+	}
+}
+
+func TestSkillStore_SearchSkills(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	putBundle(t, s, "srch", "search", "Search", true)
+
+	root := t.TempDir()
+	if err := putSkill(t, s, "srch", "zebra", root, "zebra-notes", "Animal facts", "Mentions quokka once.", true); err != nil {
+		t.Fatalf("putSkill zebra: %v", err)
+	}
+	if err := putSkill(t, s, "srch", "quokka", root, "quokka-helper", "Helps with quokka care", "Body", true); err != nil {
+		t.Fatalf("putSkill quokka: %v", err)
+	}
+
+	resp, err := s.SearchSkills(t.Context(), &spec.SearchSkillsRequest{Query: "Quokka"})
+	if err != nil {
+		t.Fatalf("SearchSkills: %v", err)
+	}
+	results := resp.Body.Results
+	if len(results) != 2 {
+		t.Fatalf("want 2 results, got %d", len(results))
+	}
+	if results[0].SkillSlug != "quokka" || results[1].SkillSlug != "zebra" {
+		t.Fatalf("unexpected ranking: %s, %s", results[0].SkillSlug, results[1].SkillSlug)
+	}
+	var body *spec.SkillSearchHighlight
+	for i := range results[1].Highlights {
+		if results[1].Highlights[i].Field == "body" {
+			body = &results[1].Highlights[i]
+		}
+	}
+	if body == nil || len(body.Spans) != 1 {
+		t.Fatalf("missing body highlight: %+v", results[1].Highlights)
+	}
+	if got := body.Snippet[body.Spans[0].Start:body.Spans[0].End]; got != "quokka" {
+		t.Fatalf("span covers %q", got)
+	}
+
+	// All terms must match.
+	resp, err = s.SearchSkills(t.Context(), &spec.SearchSkillsRequest{Query: "quokka care"})
+	if err != nil {
+		t.Fatalf("SearchSkills: %v", err)
+	}
+	if len(resp.Body.Results) != 1 || resp.Body.Results[0].SkillSlug != "quokka" {
+		t.Fatalf("unexpected results: %+v", resp.Body.Results)
+	}
+
+	if _, err := s.SearchSkills(t.Context(), &spec.SearchSkillsRequest{Query: " -- "}); !errors.Is(
+		err, errSkillInvalidRequest,
+	) {
+		t.Fatalf("empty query: want errSkillInvalidRequest, got %v", err)
+	}
+}
+
+func TestSkillStore_GetSkillsBatch(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+
+	putBundle(t, s, "b1", testBundleSlug, testBundleDisplayName, true)
+	skillBaseDir := t.TempDir()
+	if err := putSkill(t, s, "b1", "s1", skillBaseDir, "one", "desc", "body", true); err != nil {
+		t.Fatalf("PutSkill: %v", err)
+	}
+	if err := putSkill(t, s, "b1", "s2", skillBaseDir, "two", "desc", "body", false); err != nil {
+		t.Fatalf("PutSkill: %v", err)
+	}
+
+	refs := []spec.SkillRef{
+		{BundleID: "b1", SkillSlug: "s2"},
+		{BundleID: "b1", SkillSlug: "s1"},
+		{BundleID: "b1", SkillSlug: "nope"},
+		{BundleID: "missing", SkillSlug: "s1"},
+	}
+	resp, err := s.GetSkillsBatch(t.Context(), &spec.GetSkillsBatchRequest{
+		Body: &spec.GetSkillsBatchRequestBody{SkillRefs: refs},
+	})
+	if err != nil {
+		t.Fatalf("GetSkillsBatch: %v", err)
+	}
+	items := resp.Body.Items
+	if len(items) != len(refs) {
+		t.Fatalf("got %d items, want %d", len(items), len(refs))
+	}
+	for i, it := range items {
+		if it.SkillRef.BundleID != refs[i].BundleID || it.SkillRef.SkillSlug != refs[i].SkillSlug {
+			t.Fatalf("item %d out of order: %+v", i, it.SkillRef)
+		}
+	}
+	if items[0].Skill != nil || items[0].Error == "" {
+		t.Errorf("disabled skill returned without includeDisabled: %+v", items[0])
+	}
+	if items[1].Skill == nil || items[1].Error != "" || items[1].SkillRef.SkillID != items[1].Skill.ID {
+		t.Errorf("s1 = %+v", items[1])
+	}
+	if items[2].Error == "" || items[3].Error == "" {
+		t.Errorf("expected errors for unknown refs: %+v, %+v", items[2], items[3])
+	}
+
+	resp, err = s.GetSkillsBatch(t.Context(), &spec.GetSkillsBatchRequest{
+		Body: &spec.GetSkillsBatchRequestBody{SkillRefs: refs[:1], IncludeDisabled: true},
+	})
+	if err != nil || resp.Body.Items[0].Skill == nil {
+		t.Fatalf("includeDisabled: %+v, %v", resp, err)
+	}
+
+	_, err = s.GetSkillsBatch(t.Context(), &spec.GetSkillsBatchRequest{Body: &spec.GetSkillsBatchRequestBody{}})
+	if !errors.Is(err, errSkillInvalidRequest) {
+		t.Fatalf("expected ErrSkillInvalidRequest, got %v", err)
+	}
+}
+
+func TestSkillStore_BatchPatchSkills(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	ctx := t.Context()
+
+	putBundle(t, s, "b1", "b1", "B1", true)
+	putBundle(t, s, "b2", "b2", "B2", true)
+	root := t.TempDir()
+	for _, sk := range []struct {
+		bid  string
+		slug string
+	}{{"b1", "a"}, {"b1", "b"}, {"b2", "c"}} {
+		if err := putSkill(t, s, sk.bid, sk.slug, root, string(sk.bid)+"-"+sk.slug, "d", "body", true); err != nil {
+			t.Fatalf("putSkill: %v", err)
+		}
+	}
+
+	resp, err := s.BatchPatchSkills(ctx, &spec.BatchPatchSkillsRequest{Body: &spec.BatchPatchSkillsRequestBody{
+		BundleIDs: []bundleitemutils.BundleID{"b1"},
+		SkillRefs: []spec.SkillRef{{BundleID: "b1", SkillSlug: "a"}, {BundleID: "b2", SkillSlug: "c"}},
+		IsEnabled: false,
+	}})
+	if err != nil {
+		t.Fatalf("BatchPatchSkills: %v", err)
+	}
+	var got []string
+	for _, ref := range resp.Body.Patched {
+		got = append(got, string(ref.BundleID)+"/"+string(ref.SkillSlug))
+	}
+	if !slices.Equal(got, []string{"b1/a", "b1/b", "b2/c"}) {
+		t.Fatalf("patched = %v", got)
+	}
+
+	sc, err := s.readAllUser(t.Context(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for bid, sm := range sc.Skills {
+		for slug, sk := range sm {
+			if sk.IsEnabled {
+				t.Fatalf("%s/%s still enabled", bid, slug)
+			}
+		}
+	}
+
+	// A missing skill fails the whole batch without writing.
+	_, err = s.BatchPatchSkills(ctx, &spec.BatchPatchSkillsRequest{Body: &spec.BatchPatchSkillsRequestBody{
+		SkillRefs: []spec.SkillRef{{BundleID: "b1", SkillSlug: "a"}, {BundleID: "b2", SkillSlug: "missing"}},
+		IsEnabled: true,
+	}})
+	if !errors.Is(err, errSkillNotFound) {
+		t.Fatalf("want errSkillNotFound, got %v", err)
+	}
+	sc, err = s.readAllUser(t.Context(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.Skills["b1"]["a"].IsEnabled {
+		t.Fatal("failed batch must not enable b1/a")
+	}
+
+	if _, err := s.BatchPatchSkills(ctx, &spec.BatchPatchSkillsRequest{
+		Body: &spec.BatchPatchSkillsRequestBody{IsEnabled: true},
+	}); !errors.Is(err, errSkillInvalidRequest) {
+		t.Fatalf("empty batch: want errSkillInvalidRequest, got %v", err)
+	}
+
+	bundles, skills, err := s.builtin.ListBuiltInSkills(ctx)
+	if err != nil {
+		t.Fatalf("ListBuiltInSkills: %v", err)
+	}
+	for bid := range bundles {
+		if len(skills[bid]) == 0 {
+			continue
+		}
+		resp, err := s.BatchPatchSkills(ctx, &spec.BatchPatchSkillsRequest{Body: &spec.BatchPatchSkillsRequestBody{
+			BundleIDs: []bundleitemutils.BundleID{bid},
+			IsEnabled: false,
+		}})
+		if err != nil {
+			t.Fatalf("BatchPatchSkills(builtin): %v", err)
+		}
+		if len(resp.Body.Patched) != len(skills[bid]) {
+			t.Fatalf("builtin patched %d of %d", len(resp.Body.Patched), len(skills[bid]))
+		}
+		for slug := range skills[bid] {
+			sk, err := s.builtin.GetBuiltInSkill(ctx, bid, slug)
+			if err != nil || sk.IsEnabled {
+				t.Fatalf("builtin %s/%s: enabled=%v err=%v", bid, slug, sk.IsEnabled, err)
+			}
+		}
+		break
+	}
+}
+
+func TestSkillStore_SkillConflicts(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	ctx := t.Context()
+
+	putBundle(t, s, "b1", "b1", "B1", true)
+	putBundle(t, s, "b2", "b2", "B2", true)
+	root1, root2 := t.TempDir(), t.TempDir()
+	if err := putSkill(t, s, "b1", "dup", root1, "same-name", "d", "body", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := putSkill(t, s, "b2", "dup", root2, "same-name", "d", "body", true); err != nil {
+		t.Fatal(err)
+	}
+
+	conflict := spec.SkillConflict{
+		RuntimeType: string(spec.SkillTypeFS),
+		RuntimeName: "same-name",
+		Skills:      []spec.SkillRef{{BundleID: "b1", SkillSlug: "dup"}, {BundleID: "b2", SkillSlug: "dup"}},
+	}
+	s.SetSkillConflicts([]spec.SkillConflict{conflict})
+
+	listed, err := s.ListSkillConflicts(ctx, &spec.ListSkillConflictsRequest{})
+	if err != nil {
+		t.Fatalf("ListSkillConflicts: %v", err)
+	}
+	if len(listed.Body.Conflicts) != 1 || len(listed.Body.Conflicts[0].Skills) != 2 {
+		t.Fatalf("unexpected conflicts: %+v", listed.Body.Conflicts)
+	}
+
+	items, err := s.ListSkills(ctx, &spec.ListSkillsRequest{
+		BundleIDs: []bundleitemutils.BundleID{"b1", "b2"},
+	})
+	if err != nil {
+		t.Fatalf("ListSkills: %v", err)
+	}
+	if len(items.Body.SkillListItems) != 2 {
+		t.Fatalf("want 2 items, got %d", len(items.Body.SkillListItems))
+	}
+	for _, it := range items.Body.SkillListItems {
+		if it.Conflict == nil || it.Conflict.RuntimeName != "same-name" {
+			t.Fatalf("%s/%s: missing conflict", it.BundleID, it.SkillSlug)
+		}
+	}
+
+	s.SetSkillConflicts(nil)
+	items, err = s.ListSkills(ctx, &spec.ListSkillsRequest{
+		BundleIDs: []bundleitemutils.BundleID{"b1"},
+	})
+	if err != nil {
+		t.Fatalf("ListSkills: %v", err)
+	}
+	if len(items.Body.SkillListItems) != 1 || items.Body.SkillListItems[0].Conflict != nil {
+		t.Fatalf("conflict not cleared: %+v", items.Body.SkillListItems)
+	}
+}
+
+func TestSkillStore_RestoreFromBackup(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	// Journaled writes reach a backup only when compacted, so every write
+	// here rewrites the file.
+	s, err := NewSkillStore(
+		t.TempDir(),
+		WithBackups(fsutil.BackupPolicy{MaxCopies: 5}),
+		withoutFeature(featureflag.JournaledUserWrites),
+	)
+	if err != nil {
+		t.Fatalf("NewSkillStore: %v", err)
+	}
+	t.Cleanup(s.Close)
+	var resyncs atomic.Int32
+	s.SetExternalChangeHandler(func(context.Context) error {
+		resyncs.Add(1)
+		return nil
+	})
+
+	putBundle(t, s, "one", "one", "One", true)
+	putBundle(t, s, "two", "two", "Two", true)
+	listed, err := s.ListSkillStoreBackups(ctx, &spec.ListSkillStoreBackupsRequest{})
+	if err != nil || len(listed.Body.Backups) == 0 {
+		t.Fatalf("ListSkillStoreBackups: %+v, %v", listed, err)
+	}
+	latest := listed.Body.Backups[0].Name
+
+	// Restoring replaces a corrupt file.
+	if err := os.WriteFile(s.userFilePath(), []byte("{corrupt"), 0o600); err != nil {
+		t.Fatalf("corrupt user file: %v", err)
+	}
+	if _, err := s.RestoreSkillStoreFromBackup(ctx, &spec.RestoreSkillStoreFromBackupRequest{
+		Body: &spec.RestoreSkillStoreFromBackupRequestBody{Name: latest},
+	}); err != nil {
+		t.Fatalf("RestoreSkillStoreFromBackup: %v", err)
+	}
+	sc, err := readAllUserLocked(t, s, true)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if _, ok := sc.Bundles["one"]; !ok {
+		t.Fatal("restored store lacks bundle one")
+	}
+	if _, ok := sc.Bundles["two"]; ok {
+		t.Fatal("restored store has bundle two, written after the backup")
+	}
+	if got := resyncs.Load(); got != 1 {
+		t.Fatalf("expected one resync, got %d", got)
+	}
+
+	_, err = s.RestoreSkillStoreFromBackup(ctx, &spec.RestoreSkillStoreFromBackupRequest{
+		Body: &spec.RestoreSkillStoreFromBackupRequestBody{Name: "missing.json"},
+	})
+	if !errors.Is(err, fsutil.ErrBackupNotFound) {
+		t.Fatalf("expected ErrBackupNotFound, got %v", err)
+	}
+}
+
+func TestSkillStore_RetryWritableBacksUp(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	s, err := NewSkillStore(t.TempDir(), WithBackups(fsutil.BackupPolicy{MaxCopies: 5}))
+	if err != nil {
+		t.Fatalf("NewSkillStore: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	putBundle(t, s, "one", "one", "One", true)
+	backups := func() int {
+		t.Helper()
+		listed, err := s.ListSkillStoreBackups(ctx, &spec.ListSkillStoreBackupsRequest{})
+		if err != nil {
+			t.Fatalf("ListSkillStoreBackups: %v", err)
+		}
+		return len(listed.Body.Backups)
+	}
+	before := backups()
+
+	resp, err := s.RetrySkillStoreWritable(ctx, &spec.RetrySkillStoreWritableRequest{})
+	if err != nil {
+		t.Fatalf("RetrySkillStoreWritable: %v", err)
+	}
+	if resp.Body.WriteStatus.ReadOnly {
+		t.Fatalf("write status = %+v", resp.Body.WriteStatus)
+	}
+	if got := backups(); got != before+1 {
+		t.Fatalf("backups = %d, want %d", got, before+1)
+	}
+	sc, err := readAllUserLocked(t, s, true)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if _, ok := sc.Bundles["one"]; !ok {
+		t.Fatal("retried store lacks bundle one")
+	}
+}
+
+func TestSkillStore_JournaledUserWrites(t *testing.T) {
+	dir := t.TempDir()
+	gate := featureflag.GateFunc(func(name featureflag.Name) bool {
+		return name == featureflag.JournaledUserWrites
+	})
+	s, err := NewSkillStore(dir, WithFeatureGate(gate))
+	if err != nil {
+		t.Fatalf("NewSkillStore: %v", err)
+	}
+	mainBefore, err := os.ReadFile(s.userFilePath())
+	if err != nil {
+		t.Fatalf("read user file: %v", err)
+	}
+
+	putBundle(t, s, "jb", "journaled", "Journaled", true)
+	if err := putSkill(t, s, "jb", "js", t.TempDir(), "journaled-skill", "desc", "body", true); err != nil {
+		t.Fatalf("PutSkill: %v", err)
+	}
+	mainAfter, err := os.ReadFile(s.userFilePath())
+	if err != nil {
+		t.Fatalf("read user file: %v", err)
+	}
+	if string(mainAfter) != string(mainBefore) {
+		t.Fatal("journaled writes rewrote the store file")
+	}
+	if _, err := os.Stat(s.userJournalPath()); err != nil {
+		t.Fatalf("journal missing: %v", err)
+	}
+	sc, err := readAllUserLocked(t, s, true)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if sc.Bundles["jb"].DisplayName != "Journaled" || sc.Skills["jb"]["js"].Name != "journaled-skill" {
+		t.Fatalf("journaled writes not visible: %+v", sc.Bundles["jb"])
+	}
+
+	// A torn append from a crash is dropped on recovery.
+	f, err := os.OpenFile(s.userJournalPath(), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	if _, err := f.WriteString(`{"keys":["bundles","jb"],"val`); err != nil {
+		t.Fatalf("write journal: %v", err)
+	}
+	_ = f.Close()
+	// Simulate a crash: the store is not closed, so nothing is compacted.
+	s.cleanStop()
+	s.wg.Wait()
+	t.Cleanup(func() {
+		_ = s.userStore.Close()
+		_ = s.builtin.Close()
+	})
+
+	reopened, err := NewSkillStore(dir)
+	if err != nil {
+		t.Fatalf("NewSkillStore(reopen): %v", err)
+	}
+	t.Cleanup(reopened.Close)
+	if _, err := os.Stat(reopened.userJournalPath()); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("journal not compacted on recovery: %v", err)
+	}
+	raw, err := os.ReadFile(reopened.userFilePath())
+	if err != nil {
+		t.Fatalf("read user file: %v", err)
+	}
+	var doc skillStoreSchema
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("unmarshal user file: %v", err)
+	}
+	if doc.Bundles["jb"].DisplayName != "Journaled" || doc.Skills["jb"]["js"].Name != "journaled-skill" {
+		t.Fatalf("recovered file lacks journaled writes: %+v", doc.Bundles["jb"])
+	}
+}
+
+func TestSkillStore_UnjournaledUserWrites(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t, withoutFeature(featureflag.JournaledUserWrites))
+
+	putBundle(t, s, "rb", "rewritten", "Rewritten", true)
+	if _, err := os.Stat(s.userJournalPath()); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("unjournaled write created a journal: %v", err)
+	}
+	raw, err := os.ReadFile(s.userFilePath())
+	if err != nil {
+		t.Fatalf("read user file: %v", err)
+	}
+	var doc skillStoreSchema
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("unmarshal user file: %v", err)
+	}
+	if doc.Bundles["rb"].DisplayName != "Rewritten" {
+		t.Fatalf("store file lacks the write: %+v", doc.Bundles)
+	}
+}
+
+func TestDiffApplyUserJournal(t *testing.T) {
+	t.Parallel()
+
+	before := map[string]any{
+		"schemaVersion": "v1",
+		"bundles":       map[string]any{"b1": map[string]any{"slug": "one"}, "b2": map[string]any{"slug": "two"}},
+		"skills": map[string]any{
+			"b1": map[string]any{"s1": map[string]any{"name": "a"}, "s2": map[string]any{"name": "b"}},
+		},
+	}
+	after := map[string]any{
+		"schemaVersion": "v1",
+		"bundles":       map[string]any{"b1": map[string]any{"slug": "uno"}},
+		"skills": map[string]any{
+			"b1": map[string]any{"s1": map[string]any{"name": "a"}, "s3": map[string]any{"name": "c"}},
+			"b3": map[string]any{},
+		},
+	}
+	records, err := diffUserStoreMaps(before, after)
+	if err != nil {
+		t.Fatalf("diffUserStoreMaps: %v", err)
+	}
+	var keys []string
+	for _, r := range records {
+		keys = append(keys, strings.Join(r.Keys, "/"))
+	}
+	want := []string{"bundles/b2", "bundles/b1", "skills/b1/s2", "skills/b1/s3", "skills/b3"}
+	if !slices.Equal(keys, want) {
+		t.Fatalf("record keys = %v, want %v", keys, want)
+	}
+
+	// Replaying the journal on a JSON round trip of before yields after.
+	raw, err := json.Marshal(before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyUserJournal(got, records); err != nil {
+		t.Fatalf("applyUserJournal: %v", err)
+	}
+	gotRaw, _ := json.Marshal(got)
+	wantRaw, _ := json.Marshal(after)
+	if string(gotRaw) != string(wantRaw) {
+		t.Fatalf("replayed = %s, want %s", gotRaw, wantRaw)
+	}
+}
+
+func TestSkillStore_ReadAllUserCache(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	putBundle(t, s, "cb", "cached", "Cached", true)
+
+	first, err := readAllUserLocked(t, s, false)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if s.userCache.Load() == nil {
+		t.Fatal("read did not fill the cache")
+	}
+	// Callers own their copy.
+	b := first.Bundles["cb"]
+	b.DisplayName = "Mutated"
+	first.Bundles["cb"] = b
+	delete(first.Skills, "cb")
+	second, err := readAllUserLocked(t, s, false)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if second.Bundles["cb"].DisplayName != "Cached" || second.Skills["cb"] == nil {
+		t.Fatalf("cached document was modified through a copy: %+v", second.Bundles["cb"])
+	}
+
+	// Background readers may refill the cache right after the write, so
+	// compare identities instead of expecting it empty.
+	cached := s.userCache.Load()
+	putBundle(t, s, "cb", "cached", "Renamed", true)
+	if s.userCache.Load() == cached {
+		t.Fatal("write did not invalidate the cache")
+	}
+	third, err := readAllUserLocked(t, s, false)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if third.Bundles["cb"].DisplayName != "Renamed" {
+		t.Fatalf("stale read after write: %+v", third.Bundles["cb"])
+	}
+}
+
+func TestSkillStore_PageUserSkills_MatchesFullSort(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+
+	putBundle(t, s, "b1", testBundleSlug, testBundleDisplayName, true)
+	putBundle(t, s, "b2", "bundle-2", "Bundle 2", true)
+	skillBaseDir := t.TempDir()
+	for i, name := range []string{"delta", "alpha", "echo", "charlie", "bravo", "foxtrot", "golf"} {
+		bid := []string{"b1", "b2"}[i%2]
+		if err := putSkill(t, s, bid, "s"+strconv.Itoa(i), skillBaseDir, name, "desc", "body", i != 3); err != nil {
+			t.Fatalf("PutSkill: %v", err)
+		}
+	}
+	filter := newSkillListFilter(nil, []spec.SkillType{spec.SkillTypeFS}, nil, nil, false, false)
+
+	s.mu.RLock()
+	cache, err := s.readUserCache(t.Context())
+	s.mu.RUnlock()
+	if err != nil {
+		t.Fatalf("readUserCache: %v", err)
+	}
+	for _, order := range []spec.SkillListOrder{
+		spec.SkillListOrderModifiedAtDesc,
+		spec.SkillListOrderCreatedAtDesc,
+		spec.SkillListOrderNameAsc,
+		spec.SkillListOrderNameDesc,
+		spec.SkillListOrderBundle,
+	} {
+		want, _, err := pageSkillItems(order, s.userSkillItems(cache.doc, filter), "", 100)
+		if err != nil {
+			t.Fatalf("pageSkillItems(%q): %v", order, err)
+		}
+		var got []spec.SkillListItem
+		cursor := ""
+		for {
+			page, next, err := s.pageUserSkills(cache, order, filter, cursor, 2)
+			if err != nil {
+				t.Fatalf("pageUserSkills(%q): %v", order, err)
+			}
+			got = append(got, page...)
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		if len(got) != 6 || len(got) != len(want) {
+			t.Fatalf("order %q listed %d skills, want %d of 6", order, len(got), len(want))
+		}
+		for i := range want {
+			if got[i].BundleID != want[i].BundleID || got[i].SkillSlug != want[i].SkillSlug {
+				t.Fatalf("order %q item %d = %s/%s, want %s/%s", order, i,
+					got[i].BundleID, got[i].SkillSlug, want[i].BundleID, want[i].SkillSlug)
+			}
+		}
+	}
+	if len(cache.indexes) != 5 {
+		t.Fatalf("indexes = %d, want one per order", len(cache.indexes))
+	}
+
+	putBundle(t, s, "b2", "bundle-2", "Bundle 2 renamed", true)
+	s.mu.RLock()
+	fresh, err := s.readUserCache(t.Context())
+	s.mu.RUnlock()
+	if err != nil {
+		t.Fatalf("readUserCache: %v", err)
+	}
+	if fresh == cache || len(fresh.indexes) != 0 {
+		t.Fatal("write did not replace the cached indexes")
+	}
+}
+
+func TestSkillStore_CancelledContext(t *testing.T) {
+	t.Parallel()
+	s := newTestSkillStore(t)
+	putBundle(t, s, "cx", "cancelled", "Cancelled", true)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err := s.PutSkillBundle(ctx, &spec.PutSkillBundleRequest{
+		BundleID: "cx2",
+		Body:     &spec.PutSkillBundleRequestBody{Slug: "cancelled-2", DisplayName: "Cancelled 2", IsEnabled: true},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("PutSkillBundle err=%v want context.Canceled", err)
+	}
+	if _, err := s.SearchSkills(ctx, &spec.SearchSkillsRequest{Query: "x"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SearchSkills err=%v want context.Canceled", err)
+	}
+
+	s.writeMu.Lock()
+	s.mu.Lock()
+	err = s.writeAllUser(ctx, skillStoreSchema{})
+	s.mu.Unlock()
+	s.writeMu.Unlock()
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("writeAllUser err=%v want context.Canceled", err)
+	}
+	all, err := readAllUserLocked(t, s, true)
+	if err != nil {
+		t.Fatalf("readAllUser: %v", err)
+	}
+	if _, ok := all.Bundles["cx"]; !ok {
+		t.Fatal("cancelled write reached the store file")
+	}
+	if _, ok := all.Bundles["cx2"]; ok {
+		t.Fatal("cancelled PutSkillBundle was applied")
+	}
+}
+
+func TestSkillStore_PutSkill_IdempotencyKey(t *testing.T) {
+	s := newTestSkillStore(t)
+	putBundle(t, s, "b1", "bundle", "Bundle", true)
+	loc := writeSkillPackage(t, t.TempDir(), "retried", "desc", "body")
+
+	req := func(key, name string) *spec.PutSkillRequest {
+		return &spec.PutSkillRequest{
+			BundleID:       "b1",
+			SkillSlug:      "retried",
+			IdempotencyKey: key,
+			Body: &spec.PutSkillRequestBody{
+				SkillType: spec.SkillTypeFS,
+				Location:  loc,
+				Name:      name,
+				IsEnabled: true,
+			},
+		}
+	}
+	for i := range 2 {
+		if _, err := s.PutSkill(t.Context(), req("k1", "retried")); err != nil {
+			t.Fatalf("PutSkill attempt %d: %v", i, err)
+		}
+	}
+	if _, err := s.PutSkill(t.Context(), req("k1", "other")); !errors.Is(err, idempotency.ErrKeyReused) {
+		t.Fatalf("reused key: err = %v, want ErrKeyReused", err)
+	}
+	if _, err := s.PutSkill(t.Context(), req("", "retried")); !errors.Is(err, errSkillConflict) {
+		t.Fatalf("without key: err = %v, want errSkillConflict", err)
+	}
+}
+
+func TestSkillStore_PutSkill_DryRun(t *testing.T) {
+	s := newTestSkillStore(t)
+	putBundle(t, s, "b1", "bundle", "Bundle", true)
+	loc := writeSkillPackage(t, t.TempDir(), "planned", "desc", "body")
+
+	resp, err := s.PutSkill(t.Context(), &spec.PutSkillRequest{
+		BundleID:  "b1",
+		SkillSlug: "planned",
+		DryRun:    true,
+		Body: &spec.PutSkillRequestBody{
+			SkillType: spec.SkillTypeFS,
+			Location:  loc,
+			Name:      "planned",
+			IsEnabled: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("PutSkill(dry run): %v", err)
+	}
+	if resp.Body == nil || resp.Body.Skill.Slug != "planned" {
+		t.Fatalf("dry run body = %+v, want skill planned", resp.Body)
+	}
+	_, err = s.GetSkill(t.Context(), &spec.GetSkillRequest{BundleID: "b1", SkillSlug: "planned", IncludeDisabled: true})
+	if err == nil {
+		t.Fatalf("dry run skill was saved")
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log/slog"
+	"maps"
+	"os"
 	"slices"
 	"time"
 
@@ -19,11 +20,12 @@ func (s *SkillStore) startCleanupLoop() {
 		s.cleanCtx, s.cleanStop = context.WithCancel(context.Background())
 
 		s.wg.Go(func() {
-			tick := time.NewTicker(cleanupIntervalSkills)
+			tick := time.NewTicker(s.cleanupInterval)
 			defer tick.Stop()
 
 			// Run once at start.
-			s.sweepSoftDeleted()
+			s.compactBuiltInOverlay(s.cleanCtx)
+			s.sweepSoftDeleted(s.cleanCtx)
 
 			for {
 				select {
@@ -32,7 +34,7 @@ func (s *SkillStore) startCleanupLoop() {
 				case <-tick.C:
 				case <-s.cleanKick:
 				}
-				s.sweepSoftDeleted()
+				s.sweepSoftDeleted(s.cleanCtx)
 			}
 		})
 	})
@@ -48,10 +50,29 @@ func (s *SkillStore) kickCleanupLoop() {
 	}
 }
 
-func (s *SkillStore) sweepSoftDeleted() {
+// compactBuiltInOverlay drops built-in overrides that no longer change
+// anything, such as flags left behind by a reset or a removed built-in.
+func (s *SkillStore) compactBuiltInOverlay(ctx context.Context) {
+	if s.builtin == nil {
+		return
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	n, err := s.builtin.CompactOverlay(ctx)
+	if err != nil {
+		logger.Error("compactBuiltInOverlay", "err", err)
+		return
+	}
+	if n > 0 {
+		logger.Info("compacted built-in skill overlay", "deleted", n)
+	}
+}
+
+func (s *SkillStore) sweepSoftDeleted(ctx context.Context) {
 	defer func() {
 		if r := recover(); r != nil {
-			slog.Error("sweepSoftDeleted: panic", "panic", r)
+			logger.Error("sweepSoftDeleted: panic", "panic", r)
 		}
 	}()
 
@@ -59,42 +80,130 @@ func (s *SkillStore) sweepSoftDeleted() {
 	defer s.writeMu.Unlock()
 
 	s.mu.RLock()
-	all, err := s.readAllUser(false)
+	all, err := s.readAllUser(ctx, false)
 	s.mu.RUnlock()
 	if err != nil {
-		slog.Error("sweepSoftDeleted/readAllUser", "err", err)
+		logger.Error("sweepSoftDeleted/readAllUser", "err", err)
 		return
 	}
 
 	now := time.Now().UTC()
 	changed := false
+	var packages []string
+
+	for bid, skills := range all.DeletedSkills {
+		for slug, sk := range skills {
+			if now.Sub(*sk.SoftDeletedAt) < s.softDeleteGrace {
+				continue
+			}
+			all.purgeDeletedSkill(bid, slug)
+			packages = append(packages, all.managedPackagesOf(s.baseDir, bid, sk)...)
+			changed = true
+			logger.Info("hard-deleted skill", "bundleID", bid, "skillSlug", slug)
+		}
+	}
 
 	for bid, b := range all.Bundles {
 		if b.SoftDeletedAt == nil || b.SoftDeletedAt.IsZero() {
 			continue
 		}
-		if now.Sub(*b.SoftDeletedAt) < softDeleteGraceSkills {
+		if now.Sub(*b.SoftDeletedAt) < s.softDeleteGrace {
 			continue
 		}
 
 		// Only hard-delete if still empty.
 		if len(all.Skills[bid]) > 0 {
-			slog.Warn("sweepSoftDeleted: bundle not empty", "bundleID", bid)
+			logger.Warn("sweepSoftDeleted: bundle not empty", "bundleID", bid)
 			continue
 		}
 
-		delete(all.Bundles, bid)
-		delete(all.Skills, bid)
+		trashed := slices.Collect(maps.Values(all.DeletedSkills[bid]))
+		packages = append(packages, all.managedPackagesOf(s.baseDir, bid, trashed...)...)
+		all.deleteBundle(bid)
 		changed = true
-		slog.Info("hard-deleted skill bundle", "bundleID", bid)
+		logger.Info("hard-deleted skill bundle", "bundleID", bid)
 	}
 
 	if changed {
 		s.mu.Lock()
-		err := s.writeAllUser(all)
+		err := s.writeAllUser(ctx, all)
 		s.mu.Unlock()
 		if err != nil {
-			slog.Error("sweepSoftDeleted/writeAllUser", "err", err)
+			logger.Error("sweepSoftDeleted/writeAllUser", "err", err)
+			return
+		}
+		removeManagedSkillPackages(packages)
+	}
+}
+
+// deleteBundle drops a bundle and everything keyed by it.
+func (sc *skillStoreSchema) deleteBundle(bid bundleitemutils.BundleID) {
+	delete(sc.Bundles, bid)
+	delete(sc.Skills, bid)
+	delete(sc.LastActivatedAt, bid)
+	delete(sc.Usage, bid)
+	delete(sc.DeletedSkills, bid)
+}
+
+// trashSkill moves a live skill to DeletedSkills, stamped with now.
+func (sc *skillStoreSchema) trashSkill(bid bundleitemutils.BundleID, sk spec.Skill, now time.Time) spec.Skill {
+	delete(sc.Skills[bid], sk.Slug)
+	sk.SoftDeletedAt = &now
+	sk.ModifiedAt = now
+	if sc.DeletedSkills == nil {
+		sc.DeletedSkills = map[bundleitemutils.BundleID]map[spec.SkillSlug]spec.Skill{}
+	}
+	if sc.DeletedSkills[bid] == nil {
+		sc.DeletedSkills[bid] = map[spec.SkillSlug]spec.Skill{}
+	}
+	sc.DeletedSkills[bid][sk.Slug] = sk
+	return sk
+}
+
+// purgeDeletedSkill hard-deletes a soft-deleted skill. Its activation
+// history goes too, unless a live skill has taken over the slug since.
+func (sc *skillStoreSchema) purgeDeletedSkill(bid bundleitemutils.BundleID, slug spec.SkillSlug) {
+	delete(sc.DeletedSkills[bid], slug)
+	if len(sc.DeletedSkills[bid]) == 0 {
+		delete(sc.DeletedSkills, bid)
+	}
+	if _, live := sc.Skills[bid][slug]; live {
+		return
+	}
+	delete(sc.LastActivatedAt[bid], slug)
+	delete(sc.Usage[bid], slug)
+}
+
+// managedPackagesOf returns the app-managed package directories of the
+// hard-deleted skills of bundle bid that no live skill of sc points at.
+func (sc *skillStoreSchema) managedPackagesOf(
+	baseDir string,
+	bid bundleitemutils.BundleID,
+	skills ...spec.Skill,
+) []string {
+	var dirs []string
+	for _, sk := range skills {
+		if !isManagedSkillPackageLocation(baseDir, string(bid), sk.Name, sk.Location) {
+			continue
+		}
+		inUse := false
+		for _, live := range sc.Skills[bid] {
+			if live.Location == sk.Location {
+				inUse = true
+				break
+			}
+		}
+		if !inUse {
+			dirs = append(dirs, sk.Location)
+		}
+	}
+	return dirs
+}
+
+func removeManagedSkillPackages(dirs []string) {
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Error("delete managed Skill package failed", "location", dir, "error", err)
 		}
 	}
 }
@@ -109,7 +218,7 @@ func (s *SkillStore) getAnyBundle(ctx context.Context, id bundleitemutils.Bundle
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	all, err := s.readAllUser(false)
+	all, err := s.readAllUser(ctx, false)
 	if err != nil {
 		return spec.SkillBundle{}, false, err
 	}
@@ -123,11 +232,11 @@ func (s *SkillStore) getAnyBundle(ctx context.Context, id bundleitemutils.Bundle
 	return b, false, nil
 }
 
-func (s *SkillStore) ensureBaseSkillBundleHydrated() error {
+func (s *SkillStore) ensureBaseSkillBundleHydrated(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	all, err := s.readAllUser(false)
+	all, err := s.readAllUser(ctx, false)
 	if err != nil {
 		return err
 	}
@@ -180,7 +289,7 @@ func (s *SkillStore) ensureBaseSkillBundleHydrated() error {
 			if all.Skills[spec.BaseSkillBundleID] == nil {
 				all.Skills[spec.BaseSkillBundleID] = map[spec.SkillSlug]spec.Skill{}
 			}
-			return s.writeAllUser(all)
+			return s.writeAllUser(ctx, all)
 		}
 		return nil
 	}
@@ -197,34 +306,137 @@ func (s *SkillStore) ensureBaseSkillBundleHydrated() error {
 		ModifiedAt:    now,
 	}
 	all.Skills[spec.BaseSkillBundleID] = map[spec.SkillSlug]spec.Skill{}
-	return s.writeAllUser(all)
+	return s.writeAllUser(ctx, all)
 }
 
-func (s *SkillStore) writeAllUser(sc skillStoreSchema) error {
+func (s *SkillStore) writeAllUser(ctx context.Context, sc skillStoreSchema) error {
+	return s.writeAllUserWith(ctx, sc, s.writeGuard.Write)
+}
+
+// writeAllUserWith writes sc through run, the write or the retry of the write
+// guard.
+func (s *SkillStore) writeAllUserWith(ctx context.Context, sc skillStoreSchema, run func(func() error) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.invalidateUserCache()
 	sc.SchemaVersion = spec.SkillSchemaVersion
 
 	mp, err := jsonencdec.StructWithJSONTagsToMap(sc)
 	if err != nil {
 		return err
 	}
-	return s.userStore.SetAll(mp)
+	err = run(func() error {
+		if err := s.backups.Backup(s.userFilePath()); err != nil {
+			return err
+		}
+		return s.userStore.SetAll(mp)
+	})
+	if err != nil {
+		return err
+	}
+	s.rememberUserFileStat()
+	if len(s.journal) > 0 {
+		s.clearUserJournal()
+	}
+	return nil
 }
 
-func (s *SkillStore) readAllUser(force bool) (skillStoreSchema, error) {
+// readAllUser returns a copy of the user store document the caller may
+// modify. Unforced reads are served from the parsed document cached by the
+// last read; a forced read reloads the file if it changed and parses it
+// again. Caller must hold mu.
+func (s *SkillStore) readAllUser(ctx context.Context, force bool) (skillStoreSchema, error) {
+	if err := ctx.Err(); err != nil {
+		return skillStoreSchema{}, err
+	}
+	if force {
+		s.userCache.Store(nil)
+	} else if cached := s.userCache.Load(); cached != nil {
+		return cached.doc.clone(), nil
+	}
+
 	raw, err := s.userStore.GetAll(force)
 	if err != nil {
 		return skillStoreSchema{}, err
 	}
+	if err := applyUserJournal(raw, s.journal); err != nil {
+		return skillStoreSchema{}, err
+	}
 
 	var sc skillStoreSchema
 	if err := jsonencdec.MapToStructWithJSONTags(raw, &sc); err != nil {
 		return sc, err
 	}
 
+	if err := normalizeSkillStoreSchema(&sc); err != nil {
+		return skillStoreSchema{}, err
+	}
+	s.userCache.Store(&userStoreCache{doc: sc.clone()})
+	return sc, nil
+}
+
+// invalidateUserCache drops the parsed document after the user store or its
+// journal changed. Caller must hold mu for writing.
+func (s *SkillStore) invalidateUserCache() {
+	s.userCache.Store(nil)
+}
+
+// clone deep-copies the document so that a cached copy is never shared with
+// a caller.
+func (sc skillStoreSchema) clone() skillStoreSchema {
+	out := skillStoreSchema{
+		SchemaVersion:       sc.SchemaVersion,
+		ReservedNamesDigest: sc.ReservedNamesDigest,
+		Bundles:             make(map[bundleitemutils.BundleID]spec.SkillBundle, len(sc.Bundles)),
+		Skills:              make(map[bundleitemutils.BundleID]map[spec.SkillSlug]spec.Skill, len(sc.Skills)),
+	}
+	for bid, b := range sc.Bundles {
+		out.Bundles[bid] = cloneBundle(b)
+	}
+	for bid, skills := range sc.Skills {
+		if skills == nil {
+			out.Skills[bid] = nil
+			continue
+		}
+		m := make(map[spec.SkillSlug]spec.Skill, len(skills))
+		for slug, sk := range skills {
+			m[slug] = cloneSkill(sk)
+		}
+		out.Skills[bid] = m
+	}
+	if sc.LastActivatedAt != nil {
+		out.LastActivatedAt = make(map[bundleitemutils.BundleID]map[spec.SkillSlug]time.Time, len(sc.LastActivatedAt))
+		for bid, m := range sc.LastActivatedAt {
+			out.LastActivatedAt[bid] = maps.Clone(m)
+		}
+	}
+	if sc.Usage != nil {
+		out.Usage = make(map[bundleitemutils.BundleID]map[spec.SkillSlug]skillUsageRecord, len(sc.Usage))
+		for bid, m := range sc.Usage {
+			out.Usage[bid] = maps.Clone(m)
+		}
+	}
+	if sc.DeletedSkills != nil {
+		out.DeletedSkills = make(map[bundleitemutils.BundleID]map[spec.SkillSlug]spec.Skill, len(sc.DeletedSkills))
+		for bid, skills := range sc.DeletedSkills {
+			m := make(map[spec.SkillSlug]spec.Skill, len(skills))
+			for slug, sk := range skills {
+				m[slug] = cloneSkill(sk)
+			}
+			out.DeletedSkills[bid] = m
+		}
+	}
+	return out
+}
+
+// normalizeSkillStoreSchema validates a decoded store document and fills
+// defaults (hardening against file corruption).
+func normalizeSkillStoreSchema(sc *skillStoreSchema) error {
 	if sc.SchemaVersion == "" {
 		sc.SchemaVersion = spec.SkillSchemaVersion
 	} else if sc.SchemaVersion != spec.SkillSchemaVersion {
-		return skillStoreSchema{}, fmt.Errorf(
+		return fmt.Errorf(
 			"skill store schemaVersion %q != %q",
 			sc.SchemaVersion,
 			spec.SkillSchemaVersion,
@@ -238,15 +450,14 @@ func (s *SkillStore) readAllUser(force bool) (skillStoreSchema, error) {
 		sc.Skills = map[bundleitemutils.BundleID]map[spec.SkillSlug]spec.Skill{}
 	}
 
-	// Validate + normalize (hardening against file corruption).
 	for bid, b := range sc.Bundles {
 		b.IsBuiltIn = false
 		sc.Bundles[bid] = b
 		if b.ID != bid {
-			return skillStoreSchema{}, fmt.Errorf("bundle key %q != bundle.id %q", bid, b.ID)
+			return fmt.Errorf("bundle key %q != bundle.id %q", bid, b.ID)
 		}
 		if err := validateSkillBundle(&b); err != nil {
-			return skillStoreSchema{}, fmt.Errorf("invalid bundle %q: %w", bid, err)
+			return fmt.Errorf("invalid bundle %q: %w", bid, err)
 		}
 	}
 
@@ -256,31 +467,54 @@ func (s *SkillStore) readAllUser(force bool) (skillStoreSchema, error) {
 			continue
 		}
 		if _, ok := sc.Bundles[bid]; !ok {
-			return skillStoreSchema{}, fmt.Errorf("skills reference missing bundle %q", bid)
+			return fmt.Errorf("skills reference missing bundle %q", bid)
 		}
 		for slug, sk := range sm {
 			sk.IsBuiltIn = false
 			sm[slug] = sk
 			if sk.Slug != slug {
-				return skillStoreSchema{}, fmt.Errorf("skill key %q != skill.slug %q (bundle %q)", slug, sk.Slug, bid)
+				return fmt.Errorf("skill key %q != skill.slug %q (bundle %q)", slug, sk.Slug, bid)
 			}
 			if sk.Insert == "" {
 				sk.Insert = spec.SkillInsertInstructions
 			}
 			if err := validateSkill(&sk); err != nil {
-				return skillStoreSchema{}, fmt.Errorf("invalid skill %q/%q: %w", bid, slug, err)
+				return fmt.Errorf("invalid skill %q/%q: %w", bid, slug, err)
 			}
 			sm[slug] = sk
 		}
 	}
 
-	return sc, nil
+	for bid, sm := range sc.DeletedSkills {
+		if _, ok := sc.Bundles[bid]; !ok {
+			return fmt.Errorf("deleted skills reference missing bundle %q", bid)
+		}
+		for slug, sk := range sm {
+			sk.IsBuiltIn = false
+			if sk.Slug != slug {
+				return fmt.Errorf("deleted skill key %q != skill.slug %q (bundle %q)", slug, sk.Slug, bid)
+			}
+			if !isSoftDeletedSkill(sk) {
+				return fmt.Errorf("deleted skill %q/%q has no softDeletedAt", bid, slug)
+			}
+			if err := validateSkill(&sk); err != nil {
+				return fmt.Errorf("invalid deleted skill %q/%q: %w", bid, slug, err)
+			}
+			sm[slug] = sk
+		}
+	}
+
+	return nil
 }
 
 func isSoftDeletedSkillBundle(b spec.SkillBundle) bool {
 	return b.SoftDeletedAt != nil && !b.SoftDeletedAt.IsZero()
 }
 
+func isSoftDeletedSkill(sk spec.Skill) bool {
+	return sk.SoftDeletedAt != nil && !sk.SoftDeletedAt.IsZero()
+}
+
 func cloneSkill(sk spec.Skill) spec.Skill {
 	c := sk
 	c.Tags = slices.Clone(sk.Tags)
@@ -289,6 +523,7 @@ func cloneSkill(sk spec.Skill) spec.Skill {
 	c.RuntimeWarnings = slices.Clone(sk.RuntimeWarnings)
 	c.RawFrontmatter = cloneAnyMap(sk.RawFrontmatter)
 	c.Presence = clonePresence(sk.Presence)
+	c.SoftDeletedAt = cloneTimePtr(sk.SoftDeletedAt)
 	return c
 }
 
@@ -0,0 +1,74 @@
+package skillstore
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+)
+
+// ListSoftDeleted returns the user bundles and skills pending purge, newest
+// deletion first, with when the cleanup loop may hard-delete each.
+func (s *SkillStore) ListSoftDeleted(
+	ctx context.Context,
+	_ *spec.ListSoftDeletedRequest,
+) (*spec.ListSoftDeletedResponse, error) {
+	s.mu.RLock()
+	all, err := s.readAllUser(ctx, false)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	remaining := func(purgeAt time.Time) int64 { return max(purgeAt.Sub(now), 0).Milliseconds() }
+
+	body := &spec.ListSoftDeletedResponseBody{
+		SkillBundles: []spec.SoftDeletedSkillBundle{},
+		Skills:       []spec.SoftDeletedSkill{},
+	}
+	for _, b := range all.Bundles {
+		if !isSoftDeletedSkillBundle(b) {
+			continue
+		}
+		purgeAt := b.SoftDeletedAt.Add(s.softDeleteGrace)
+		body.SkillBundles = append(body.SkillBundles, spec.SoftDeletedSkillBundle{
+			SkillBundle:      b,
+			PurgeAt:          purgeAt,
+			RemainingGraceMS: remaining(purgeAt),
+		})
+	}
+	for bid, skills := range all.DeletedSkills {
+		for _, sk := range skills {
+			purgeAt := sk.SoftDeletedAt.Add(s.softDeleteGrace)
+			if b := all.Bundles[bid]; isSoftDeletedSkillBundle(b) {
+				if bundlePurgeAt := b.SoftDeletedAt.Add(s.softDeleteGrace); bundlePurgeAt.Before(purgeAt) {
+					purgeAt = bundlePurgeAt
+				}
+			}
+			body.Skills = append(body.Skills, spec.SoftDeletedSkill{
+				BundleID:         bid,
+				Skill:            sk,
+				PurgeAt:          purgeAt,
+				RemainingGraceMS: remaining(purgeAt),
+			})
+		}
+	}
+
+	slices.SortFunc(body.SkillBundles, func(a, b spec.SoftDeletedSkillBundle) int {
+		return cmp.Or(
+			b.SkillBundle.SoftDeletedAt.Compare(*a.SkillBundle.SoftDeletedAt),
+			cmp.Compare(a.SkillBundle.ID, b.SkillBundle.ID),
+		)
+	})
+	slices.SortFunc(body.Skills, func(a, b spec.SoftDeletedSkill) int {
+		return cmp.Or(
+			b.Skill.SoftDeletedAt.Compare(*a.Skill.SoftDeletedAt),
+			cmp.Compare(a.BundleID, b.BundleID),
+			cmp.Compare(a.Skill.Slug, b.Skill.Slug),
+		)
+	})
+	return &spec.ListSoftDeletedResponse{Body: body}, nil
+}
@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/flexigpt/agentskills-go"
 	agentskillsSpec "github.com/flexigpt/agentskills-go/spec"
 
 	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
+	"github.com/flexigpt/flexigpt-app/internal/precondition"
 	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+	"github.com/flexigpt/flexigpt-app/internal/validation"
 )
 
 const (
@@ -27,8 +30,12 @@ var (
 	errSkillBundleDisabled  = errors.New("bundle is disabled")
 	errSkillBundleDeleting  = errors.New("bundle is being deleted")
 	errSkillBundleNotEmpty  = errors.New("bundle still contains skills")
+	errSkillBundleReserved  = errors.New("bundle ID is reserved")
 	errSkillNotFound        = errors.New("skill not found")
 	errSkillDisabled        = errors.New("skill is disabled")
+	errSkillDeleting        = errors.New("skill is being deleted")
+
+	errSkillRegistryNotConfigured = errors.New("skill registry URL is not configured")
 )
 
 // ValidateSkill applies the Skill Store's structural rules to a projected
@@ -56,6 +63,16 @@ func ValidateSkillArtifactMetadata(
 	)
 }
 
+// checkModifiedAt refuses a write whose expectedModifiedAt no longer matches
+// the stored bundle or skill. The error matches errSkillConflict and carries
+// the current value as a precondition.ConflictError.
+func checkModifiedAt[T any](expected *time.Time, modifiedAt time.Time, current T) error {
+	if err := precondition.CheckModifiedAt(expected, modifiedAt, current); err != nil {
+		return fmt.Errorf("%w: %w", errSkillConflict, err)
+	}
+	return nil
+}
+
 func validateSkillBundle(b *spec.SkillBundle) error {
 	if b == nil {
 		return errors.New("bundle is nil")
@@ -93,55 +110,67 @@ func validateSkillBundle(b *spec.SkillBundle) error {
 	if isSoftDeletedSkillBundle(*b) && b.IsEnabled {
 		return errors.New("soft-deleted bundle cannot be enabled")
 	}
+	if !isValidSkillBundleActivationPolicy(b.ActivationPolicy) {
+		return fmt.Errorf("invalid activationPolicy %q", b.ActivationPolicy)
+	}
 	return nil
 }
 
+func isValidSkillBundleActivationPolicy(p spec.SkillBundleActivationPolicy) bool {
+	switch p {
+	case "",
+		spec.SkillBundleActivationOnDemand,
+		spec.SkillBundleActivationAlwaysActive,
+		spec.SkillBundleActivationNever:
+		return true
+	}
+	return false
+}
+
+// validateSkill checks a skill and reports every invalid field as
+// validation.Errors. It defaults an empty insert mode.
 func validateSkill(sk *spec.Skill) error {
 	if sk == nil {
 		return errors.New("skill is nil")
 	}
+	r := &validation.Report{}
 	if sk.SchemaVersion != spec.SkillSchemaVersion {
-		return fmt.Errorf("schemaVersion %q != %q", sk.SchemaVersion, spec.SkillSchemaVersion)
+		r.Addf("schemaVersion", validation.CodeUnsupported,
+			"schemaVersion %q != %q", sk.SchemaVersion, spec.SkillSchemaVersion)
 	}
 	if strings.TrimSpace(string(sk.ID)) == "" {
-		return errors.New("id is empty")
-	}
-	if err := bundleitemutils.ValidateItemSlug(sk.Slug); err != nil {
-		return fmt.Errorf("invalid slug: %w", err)
-	}
-	if strings.TrimSpace(sk.Location) == "" {
-		return errors.New("location is empty")
-	}
-	if strings.TrimSpace(sk.Location) != sk.Location {
-		return errors.New("location has leading/trailing whitespace")
-	}
-	if len(sk.Location) > maxLocationLen {
-		return fmt.Errorf("location too long (>%d)", maxLocationLen)
-	}
-	if strings.TrimSpace(sk.Name) == "" {
-		return errors.New("name is empty")
-	}
-	if strings.TrimSpace(sk.Name) != sk.Name {
-		return errors.New("name has leading/trailing whitespace")
-	}
-	if len(sk.Name) > maxNameLen {
-		return fmt.Errorf("name too long (>%d)", maxNameLen)
+		r.Addf("id", validation.CodeRequired, "id is empty")
+	}
+	r.Checkf("slug", validation.CodeInvalid, "invalid slug", bundleitemutils.ValidateItemSlug(sk.Slug))
+	switch {
+	case strings.TrimSpace(sk.Location) == "":
+		r.Addf("location", validation.CodeRequired, "location is empty")
+	case strings.TrimSpace(sk.Location) != sk.Location:
+		r.Addf("location", validation.CodeInvalid, "location has leading/trailing whitespace")
+	case len(sk.Location) > maxLocationLen:
+		r.Addf("location", validation.CodeTooLong, "location too long (>%d)", maxLocationLen)
+	}
+	switch {
+	case strings.TrimSpace(sk.Name) == "":
+		r.Addf("name", validation.CodeRequired, "name is empty")
+	case strings.TrimSpace(sk.Name) != sk.Name:
+		r.Addf("name", validation.CodeInvalid, "name has leading/trailing whitespace")
+	case len(sk.Name) > maxNameLen:
+		r.Addf("name", validation.CodeTooLong, "name too long (>%d)", maxNameLen)
 	}
 	if len(sk.DisplayName) > maxDisplayNameLen {
-		return fmt.Errorf("displayName too long (>%d)", maxDisplayNameLen)
+		r.Addf("displayName", validation.CodeTooLong, "displayName too long (>%d)", maxDisplayNameLen)
 	}
 	if len(sk.Description) > maxDescriptionLen {
-		return fmt.Errorf("description too long (>%d)", maxDescriptionLen)
-	}
-	if sk.CreatedAt.IsZero() || sk.ModifiedAt.IsZero() {
-		return errors.New("createdAt/modifiedAt is zero")
-	}
-	if sk.ModifiedAt.Before(sk.CreatedAt) {
-		return errors.New("modifiedAt is before createdAt")
+		r.Addf("description", validation.CodeTooLong, "description too long (>%d)", maxDescriptionLen)
 	}
-	if err := bundleitemutils.ValidateTags(sk.Tags); err != nil {
-		return err
+	switch {
+	case sk.CreatedAt.IsZero() || sk.ModifiedAt.IsZero():
+		r.Addf("modifiedAt", validation.CodeRequired, "createdAt/modifiedAt is zero")
+	case sk.ModifiedAt.Before(sk.CreatedAt):
+		r.Addf("modifiedAt", validation.CodeInvalid, "modifiedAt is before createdAt")
 	}
+	r.Check("tags", validation.CodeInvalid, bundleitemutils.ValidateTags(sk.Tags))
 
 	if sk.Insert == "" {
 		sk.Insert = spec.SkillInsertInstructions
@@ -149,60 +178,72 @@ func validateSkill(sk *spec.Skill) error {
 	switch sk.Insert {
 	case spec.SkillInsertInstructions, spec.SkillInsertUserMessage:
 	default:
-		return fmt.Errorf("invalid insert %q", sk.Insert)
-	}
-	if err := validateSkillArguments(sk.Arguments); err != nil {
-		return err
+		r.Addf("insert", validation.CodeUnsupported, "invalid insert %q", sk.Insert)
 	}
+	validateSkillArguments(r, sk.Arguments)
 
 	switch sk.Type {
 	case spec.SkillTypeFS:
 		if sk.IsBuiltIn {
-			return errors.New("built-in skill cannot be type=fs")
+			r.Addf("type", validation.CodeConflict, "built-in skill cannot be type=fs")
 		}
 	case spec.SkillTypeEmbeddedFS:
 		if !sk.IsBuiltIn {
-			return errors.New("non-built-in skill cannot be type=embeddedfs")
+			r.Addf("type", validation.CodeConflict, "non-built-in skill cannot be type=embeddedfs")
 		}
 	default:
-		return fmt.Errorf("invalid type %q", sk.Type)
+		r.Addf("type", validation.CodeUnsupported, "invalid type %q", sk.Type)
 	}
 
 	if sk.Presence != nil {
 		switch sk.Presence.Status {
 		case spec.SkillPresenceUnknown, spec.SkillPresencePresent, spec.SkillPresenceMissing, spec.SkillPresenceError:
 		default:
-			return fmt.Errorf("invalid presence.status %q", sk.Presence.Status)
+			r.Addf("presence.status", validation.CodeUnsupported, "invalid presence.status %q", sk.Presence.Status)
 		}
 	}
 
-	return nil
+	return r.Err()
 }
 
-func validateSkillArguments(args []spec.SkillArgument) error {
+// validateSkillArguments adds an issue for each invalid argument to r.
+func validateSkillArguments(r *validation.Report, args []spec.SkillArgument) {
 	seen := map[string]struct{}{}
 	for i, arg := range args {
+		field := validation.Index("arguments", i) + ".name"
 		name := strings.TrimSpace(arg.Name)
 		if name == "" {
-			return fmt.Errorf("arguments[%d].name is empty", i)
+			r.Addf(field, validation.CodeRequired, "arguments[%d].name is empty", i)
+			continue
 		}
 		if name != arg.Name {
-			return fmt.Errorf("arguments[%d].name has leading/trailing whitespace", i)
+			r.Addf(field, validation.CodeInvalid, "arguments[%d].name has leading/trailing whitespace", i)
+			continue
 		}
-		for j, r := range name {
-			switch {
-			case r == '_':
-			case r >= 'a' && r <= 'z':
-			case r >= 'A' && r <= 'Z':
-			case j > 0 && r >= '0' && r <= '9':
-			default:
-				return fmt.Errorf("arguments[%d].name contains invalid character %q", i, string(r))
-			}
+		if c, ok := invalidSkillArgumentNameRune(name); ok {
+			r.Addf(field, validation.CodeInvalid, "arguments[%d].name contains invalid character %q", i, string(c))
+			continue
 		}
 		if _, exists := seen[name]; exists {
-			return fmt.Errorf("duplicate argument %q", name)
+			r.Addf(field, validation.CodeDuplicate, "duplicate argument %q", name)
+			continue
 		}
 		seen[name] = struct{}{}
 	}
-	return nil
+}
+
+// invalidSkillArgumentNameRune returns the first rune of name that is not
+// allowed in an argument name.
+func invalidSkillArgumentNameRune(name string) (rune, bool) {
+	for j, c := range name {
+		switch {
+		case c == '_':
+		case c >= 'a' && c <= 'z':
+		case c >= 'A' && c <= 'Z':
+		case j > 0 && c >= '0' && c <= '9':
+		default:
+			return c, true
+		}
+	}
+	return 0, false
 }
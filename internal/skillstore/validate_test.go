@@ -1,11 +1,13 @@
 package skillstore
 
 import (
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
+	"github.com/flexigpt/flexigpt-app/internal/validation"
 )
 
 func TestValidateSkillBundle_Table(t *testing.T) {
@@ -168,3 +170,29 @@ func TestValidateSkill_Table(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSkill_ReportsAllFields(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC)
+	sk := spec.Skill{
+		SchemaVersion: spec.SkillSchemaVersion,
+		ID:            "s1",
+		Slug:          "ok-skill",
+		Type:          spec.SkillTypeFS,
+		Location:      testTempLocation,
+		Name:          " padded",
+		Arguments:     []spec.SkillArgument{{Name: "ok"}, {Name: "1bad"}, {Name: "ok"}},
+		CreatedAt:     now,
+		ModifiedAt:    now,
+		Insert:        "sideways",
+	}
+	issues := validation.IssuesOf(validateSkill(&sk))
+	want := []string{"name", "insert", "arguments[1].name", "arguments[2].name"}
+	if got := issues.Fields(); !slices.Equal(got, want) {
+		t.Fatalf("fields = %v, want %v (issues %+v)", got, want, issues)
+	}
+	if issues[3].Code != validation.CodeDuplicate {
+		t.Fatalf("arguments[2] code = %q, want %q", issues[3].Code, validation.CodeDuplicate)
+	}
+}
@@ -2,9 +2,41 @@ package skillstore
 
 import (
 	"context"
+	"errors"
 	"fmt"
+
+	"github.com/flexigpt/mapstore-go/jsonencdec"
+
+	"github.com/flexigpt/flexigpt-app/internal/fsutil"
+	"github.com/flexigpt/flexigpt-app/internal/skillstore/spec"
 )
 
+// userWriter returns withUserWrite, or withUserDryRun for a dry run.
+func (s *SkillStore) userWriter(dryRun bool) func(context.Context, string, func(*skillStoreSchema) error) error {
+	if dryRun {
+		return s.withUserDryRun
+	}
+	return s.withUserWrite
+}
+
+// withUserDryRun runs fn like withUserWrite, with the same checks, on a copy
+// of the user store that is then discarded.
+func (s *SkillStore) withUserDryRun(ctx context.Context, _ string, fn func(sc *skillStoreSchema) error) error {
+	if fn == nil {
+		return fmt.Errorf("%w: nil write function", errSkillInvalidRequest)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	snapshot, err := s.readAllUser(ctx, false)
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return fn(&snapshot)
+}
+
 func (s *SkillStore) withUserWrite(
 	ctx context.Context,
 	_ string,
@@ -22,19 +54,96 @@ func (s *SkillStore) withUserWrite(
 
 	s.writeMu.Lock()
 	defer s.writeMu.Unlock()
+	// The caller may have given up while waiting for another write.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
+	// A journaled write must not read the whole store file, so the file is
+	// only checked by its stat; the external change loop still compares its
+	// content.
+	journaled := s.journalEnabled()
 	s.mu.RLock()
-	snapshot, err := s.readAllUser(false)
+	last := s.userFileStat
 	s.mu.RUnlock()
+	readState := fsutil.ReadFileState
+	if journaled && last != nil {
+		readState = func(path string) (fsutil.FileState, error) { return fsutil.ReadFileStateSince(path, *last) }
+	}
+
+	// Apply the mutation on top of any external modification of the file.
+	before, err := readState(s.userFilePath())
 	if err != nil {
 		return err
 	}
+	s.mu.RLock()
+	s.refreshUserFileCache(before)
+	snapshot, err := s.readAllUser(ctx, before.Exists)
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	var beforeMap map[string]any
+	if journaled {
+		snapshot.SchemaVersion = spec.SkillSchemaVersion
+		if beforeMap, err = jsonencdec.StructWithJSONTagsToMap(snapshot); err != nil {
+			return err
+		}
+	}
 	if err := fn(&snapshot); err != nil {
 		return err
 	}
 
 	s.mu.Lock()
-	err = s.writeAllUser(snapshot)
-	s.mu.Unlock()
-	return err
+	defer s.mu.Unlock()
+	if !journaled {
+		if err := s.checkUserFileUnchanged(before); err != nil {
+			return err
+		}
+		return s.writeAllUser(ctx, snapshot)
+	}
+	now, err := readState(s.userFilePath())
+	if err != nil {
+		return err
+	}
+	if !now.SameContent(before) {
+		return fmt.Errorf("%w: %w: %s; retry", errSkillConflict, fsutil.ErrExternalModification, s.userFilePath())
+	}
+	return s.journalUserWrite(ctx, beforeMap, snapshot)
+}
+
+// WriteStatus reports whether the user store accepts writes.
+func (s *SkillStore) WriteStatus() fsutil.WriteStatus {
+	return s.writeGuard.Status()
+}
+
+// RetrySkillStoreWritable rewrites the current user store and makes it
+// writable again if that succeeds. A store that is still unavailable is
+// reported in the body rather than as an error.
+func (s *SkillStore) RetrySkillStoreWritable(
+	ctx context.Context,
+	_ *spec.RetrySkillStoreWritableRequest,
+) (*spec.RetrySkillStoreWritableResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAllUser(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	err = s.writeAllUserWith(ctx, all, s.writeGuard.Retry)
+	if err != nil && !errors.Is(err, fsutil.ErrStoreReadOnly) {
+		return nil, err
+	}
+	if err == nil {
+		logger.Info("skill store writable again")
+	}
+	return &spec.RetrySkillStoreWritableResponse{
+		Body: &spec.RetrySkillStoreWritableResponseBody{WriteStatus: s.writeGuard.Status()},
+	}, nil
 }
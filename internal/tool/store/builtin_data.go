@@ -8,7 +8,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/fs"
-	"log/slog"
 	"maps"
 	"os"
 	"path"          // POSIX paths for embed.FS
@@ -392,7 +391,7 @@ func (d *BuiltInToolData) populateDataFromFS(ctx context.Context) error {
 		if err := injectLLMToolsGo(ctx, bundleMap, toolMap); err != nil {
 			return err
 		}
-		slog.Info("built-in go tools injected from llmtools-go")
+		logger.Info("built-in go tools injected from llmtools-go")
 	}
 
 	for id, tm := range toolMap {
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log/slog"
 	"slices"
 	"strings"
 	"time"
@@ -60,7 +59,7 @@ func injectLLMToolsGo(
 		}
 
 		if _, exists := tools[meta.BundleID][appTool.ID]; exists {
-			slog.Info(
+			logger.Info(
 				"overriding embedded builtin tool with llmtools-go definition",
 				"bundleID", meta.BundleID,
 				"toolID", appTool.ID,
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime/debug"
@@ -21,10 +20,13 @@ import (
 
 	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
 	"github.com/flexigpt/flexigpt-app/internal/jsonutil"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 	"github.com/flexigpt/flexigpt-app/internal/tool/spec"
 	"github.com/flexigpt/flexigpt-app/internal/tool/storehelper"
 )
 
+var logger = logging.Module("tool.store")
+
 const (
 	fetchBatchTools       = 512            // Directory-store batch size.
 	maxPageSizeTools      = 256            // Hard page limit.
@@ -106,14 +108,14 @@ func NewToolStore(baseDir string, opts ...Option) (*ToolStore, error) {
 		jsonencdec.JSONEncoderDecoder{},
 		mapstore.WithCreateIfNotExists(true),
 		mapstore.WithFileAutoFlush(true),
-		mapstore.WithFileLogger(slog.Default()),
+		mapstore.WithFileLogger(logger),
 	)
 	if err != nil {
 		return nil, err
 	}
 
 	// Directory store.
-	dirOpts := []mapstore.DirOption{mapstore.WithDirLogger(slog.Default())}
+	dirOpts := []mapstore.DirOption{mapstore.WithDirLogger(logger)}
 	ts.toolStore, err = mapstore.NewMapDirectoryStore(
 		ts.baseDir,
 		true,
@@ -127,7 +129,7 @@ func NewToolStore(baseDir string, opts ...Option) (*ToolStore, error) {
 	ts.slugLock = storehelper.NewSlugLocks()
 	ts.startCleanupLoop()
 
-	slog.Info("tool-store ready", "baseDir", ts.baseDir)
+	logger.Info("tool-store ready", "baseDir", ts.baseDir)
 	return ts, nil
 }
 
@@ -202,7 +204,7 @@ func (ts *ToolStore) PutToolBundle(
 	if err := ts.writeAllBundles(all); err != nil {
 		return nil, err
 	}
-	slog.Info("putToolBundle", "bundleID", req.BundleID)
+	logger.Info("putToolBundle", "bundleID", req.BundleID)
 	return &spec.PutToolBundleResponse{}, nil
 }
 
@@ -220,7 +222,7 @@ func (ts *ToolStore) PatchToolBundle(
 			if _, err := ts.builtinData.SetToolBundleEnabled(ctx, req.BundleID, req.Body.IsEnabled); err != nil {
 				return nil, err
 			}
-			slog.Info(
+			logger.Info(
 				"patchToolBundle (builtin)",
 				"id",
 				req.BundleID,
@@ -248,7 +250,7 @@ func (ts *ToolStore) PatchToolBundle(
 	if err := ts.writeAllBundles(all); err != nil {
 		return nil, err
 	}
-	slog.Info("patchToolBundle", "id", req.BundleID, "enabled", req.Body.IsEnabled)
+	logger.Info("patchToolBundle", "id", req.BundleID, "enabled", req.Body.IsEnabled)
 	return &spec.PatchToolBundleResponse{}, nil
 }
 
@@ -305,7 +307,7 @@ func (ts *ToolStore) DeleteToolBundle(
 	}
 
 	ts.kickCleanupLoop()
-	slog.Info("deleteToolBundle", "bundleID", req.BundleID)
+	logger.Info("deleteToolBundle", "bundleID", req.BundleID)
 	return &spec.DeleteToolBundleResponse{}, nil
 }
 
@@ -521,7 +523,7 @@ func (ts *ToolStore) PutTool(
 	); err != nil {
 		return nil, err
 	}
-	slog.Info("putTool", "bundleID", req.BundleID, "slug", req.ToolSlug, "ver", req.Version)
+	logger.Info("putTool", "bundleID", req.BundleID, "slug", req.ToolSlug, "ver", req.Version)
 	return &spec.PutToolResponse{}, nil
 }
 
@@ -552,7 +554,7 @@ func (ts *ToolStore) PatchTool(
 			return nil, err
 		}
 
-		slog.Info("patchTool (builtin)", "bundleID", req.BundleID, "slug", req.ToolSlug,
+		logger.Info("patchTool (builtin)", "bundleID", req.BundleID, "slug", req.ToolSlug,
 			"ver", req.Version, "enabled", req.Body.IsEnabled)
 		return &spec.PatchToolResponse{}, nil
 	}
@@ -584,7 +586,7 @@ func (ts *ToolStore) PatchTool(
 		return nil, err
 	}
 
-	slog.Info("patchTool", "bundleID", req.BundleID, "slug", req.ToolSlug,
+	logger.Info("patchTool", "bundleID", req.BundleID, "slug", req.ToolSlug,
 		"ver", req.Version, "enabled", req.Body.IsEnabled)
 	return &spec.PatchToolResponse{}, nil
 }
@@ -621,7 +623,7 @@ func (ts *ToolStore) DeleteTool(
 	); err != nil {
 		return nil, err
 	}
-	slog.Info("deleteTool", "bundleID", req.BundleID, "slug", req.ToolSlug, "ver", req.Version)
+	logger.Info("deleteTool", "bundleID", req.BundleID, "slug", req.ToolSlug, "ver", req.Version)
 	return &spec.DeleteToolResponse{}, nil
 }
 
@@ -930,7 +932,7 @@ func (ts *ToolStore) startCleanupLoop() {
 			defer tick.Stop()
 			defer func() {
 				if r := recover(); r != nil {
-					slog.Error(
+					logger.Error(
 						"panic in tool-bundle sweep",
 						"err",
 						r,
@@ -962,7 +964,7 @@ func (ts *ToolStore) sweepSoftDeleted() {
 
 	all, err := ts.readAllBundles(false)
 	if err != nil {
-		slog.Error("sweepSoftDeleted/readAllBundles", "err", err)
+		logger.Error("sweepSoftDeleted/readAllBundles", "err", err)
 		return
 	}
 	now := time.Now().UTC()
@@ -981,19 +983,19 @@ func (ts *ToolStore) sweepSoftDeleted() {
 			mapstore.ListingConfig{FilterPartitions: []string{dirInfo.DirName}, PageSize: 1}, "",
 		)
 		if err != nil || len(files) > 0 {
-			slog.Warn("sweepSoftDeleted: bundle not empty", "bundleID", id)
+			logger.Warn("sweepSoftDeleted: bundle not empty", "bundleID", id)
 			continue
 		}
 
 		delete(all.Bundles, id)
 		changed = true
 		_ = os.RemoveAll(filepath.Join(ts.baseDir, dirInfo.DirName))
-		slog.Info("hard-deleted bundle", "bundleID", id)
+		logger.Info("hard-deleted bundle", "bundleID", id)
 	}
 
 	if changed {
 		if err := ts.writeAllBundles(all); err != nil {
-			slog.Error("sweepSoftDeleted/writeAllBundles", "err", err)
+			logger.Error("sweepSoftDeleted/writeAllBundles", "err", err)
 		}
 	}
 }
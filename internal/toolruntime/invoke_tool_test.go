@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -723,6 +724,139 @@ func TestInvokeTool_RequestBodyTemplating_PathQueryHeaderAuth(t *testing.T) {
 	}
 }
 
+func TestToolRunAuditListAndReplay(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if r.Header.Get("X-Key") != "k1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		// The third call returns a different body so replays can be compared.
+		_ = json.NewEncoder(w).Encode(map[string]any{"q": r.URL.Query().Get("q"), "changed": n >= 3})
+	}))
+	defer srv.Close()
+
+	ts, err := store.NewToolStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewToolStore: %v", err)
+	}
+	defer ts.Close()
+	tr := NewToolRuntime(ts, WithRunAuditDir(t.TempDir()))
+
+	const (
+		bundleID = bundleitemutils.BundleID("bundle-audit")
+		toolSlug = bundleitemutils.ItemSlug("echo")
+		version  = bundleitemutils.ItemVersion("v1")
+	)
+	if _, err := ts.PutToolBundle(t.Context(), &toolSpec.PutToolBundleRequest{
+		BundleID: bundleID,
+		Body: &toolSpec.PutToolBundleRequestBody{
+			Slug:        "audit",
+			DisplayName: "Audit",
+			IsEnabled:   true,
+		},
+	}); err != nil {
+		t.Fatalf("PutToolBundle: %v", err)
+	}
+	if _, err := ts.PutTool(t.Context(), &toolSpec.PutToolRequest{
+		BundleID: bundleID,
+		ToolSlug: toolSlug,
+		Version:  version,
+		Body: &toolSpec.PutToolRequestBody{
+			DisplayName:  "Echo",
+			IsEnabled:    true,
+			UserCallable: true,
+			LLMCallable:  true,
+			ArgSchema:    "{}",
+			Type:         toolSpec.ToolTypeHTTP,
+			HTTPImpl: &toolSpec.HTTPToolImpl{
+				Request: toolSpec.HTTPRequest{
+					Method:      "GET",
+					URLTemplate: srv.URL + "/echo",
+					Query:       map[string]string{"q": "${q}"},
+					Headers:     map[string]string{"X-Key": "${SECRET}"},
+				},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("PutTool: %v", err)
+	}
+
+	invoke := func(convID, q string) {
+		t.Helper()
+		if _, err := tr.InvokeTool(t.Context(), &spec.InvokeToolRequest{
+			BundleID: bundleID,
+			ToolSlug: toolSlug,
+			Version:  version,
+			Body: &spec.InvokeToolRequestBody{
+				Args:           spec.JSONRawString(`{"q":"` + q + `"}`),
+				HTTPOptions:    &spec.InvokeHTTPOptions{Secrets: map[string]string{"SECRET": "k1"}},
+				ConversationID: convID,
+			},
+		}); err != nil {
+			t.Fatalf("InvokeTool: %v", err)
+		}
+	}
+	invoke("c1", "a")
+	invoke("c2", "b")
+
+	list, err := tr.ListToolRuns(t.Context(), &spec.ListToolRunsRequest{ConversationID: "c1"})
+	if err != nil {
+		t.Fatalf("ListToolRuns: %v", err)
+	}
+	if len(list.Body.ToolRuns) != 1 {
+		t.Fatalf("got %d runs for c1, want 1", len(list.Body.ToolRuns))
+	}
+	run := list.Body.ToolRuns[0]
+	if run.Args != `{"q":"a"}` || run.IsError || !strings.HasPrefix(run.ResultDigest, "sha256:") {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+
+	all, err := tr.ListToolRuns(t.Context(), &spec.ListToolRunsRequest{})
+	if err != nil {
+		t.Fatalf("ListToolRuns: %v", err)
+	}
+	if len(all.Body.ToolRuns) != 2 || all.Body.ToolRuns[0].ConversationID != "c2" {
+		t.Fatalf("want 2 runs newest first, got %+v", all.Body.ToolRuns)
+	}
+
+	// Secrets are not recorded: replaying without them fails at the server.
+	replay, err := tr.ReplayToolRun(t.Context(), &spec.ReplayToolRunRequest{ID: run.ID})
+	if err != nil {
+		t.Fatalf("ReplayToolRun: %v", err)
+	}
+	if !replay.Body.Result.IsError || replay.Body.SameResult || replay.Body.Run.ReplayOf != run.ID {
+		t.Fatalf("unexpected replay without secrets: %+v", replay.Body.Run)
+	}
+
+	replay, err = tr.ReplayToolRun(t.Context(), &spec.ReplayToolRunRequest{
+		ID:   run.ID,
+		Body: &spec.ReplayToolRunRequestBody{Secrets: map[string]string{"SECRET": "k1"}},
+	})
+	if err != nil {
+		t.Fatalf("ReplayToolRun: %v", err)
+	}
+	if replay.Body.Result.IsError || replay.Body.SameResult {
+		t.Fatalf("replay should succeed with a different result: %+v", replay.Body.Run)
+	}
+
+	errs, err := tr.ListToolRuns(t.Context(), &spec.ListToolRunsRequest{ConversationID: "c1", OnlyErrors: true})
+	if err != nil {
+		t.Fatalf("ListToolRuns: %v", err)
+	}
+	if len(errs.Body.ToolRuns) != 1 || errs.Body.ToolRuns[0].ReplayOf != run.ID {
+		t.Fatalf("want the failed replay only, got %+v", errs.Body.ToolRuns)
+	}
+
+	if _, err := tr.ReplayToolRun(t.Context(), &spec.ReplayToolRunRequest{ID: "missing"}); err == nil {
+		t.Fatal("expected error for unknown run")
+	}
+}
+
 // TestInvokeTool_Go_CustomRegistered covers invoking user-created Go tools
 // by directly inserting Tool records (type=go) into the directory-store.
 // We bypass PutTool because it only accepts custom HTTP tools.
@@ -0,0 +1,274 @@
+package toolruntime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/flexigpt/flexigpt-app/internal/toolruntime/spec"
+)
+
+const (
+	toolRunsFileName = "toolruns.jsonl"
+	// maxToolRunsFileSize is the size after which the log is rotated; one
+	// previous generation is kept.
+	maxToolRunsFileSize  = 8 * 1024 * 1024
+	defaultToolRunsLimit = 100
+	maxToolRunsLimit     = 1000
+)
+
+type Option func(*ToolRuntime)
+
+// WithRunAuditDir records every InvokeTool call in an append-only log under
+// dir, enabling ListToolRuns and ReplayToolRun. An empty dir disables it.
+func WithRunAuditDir(dir string) Option {
+	return func(rt *ToolRuntime) {
+		if strings.TrimSpace(dir) == "" {
+			rt.audit = nil
+			return
+		}
+		rt.audit = &runAudit{path: filepath.Join(filepath.Clean(dir), toolRunsFileName)}
+	}
+}
+
+// ListToolRuns returns recorded tool runs, newest first.
+func (rt *ToolRuntime) ListToolRuns(
+	ctx context.Context,
+	req *spec.ListToolRunsRequest,
+) (*spec.ListToolRunsResponse, error) {
+	if rt.audit == nil {
+		return nil, errors.New("tool run audit is disabled")
+	}
+	if req == nil {
+		req = &spec.ListToolRunsRequest{}
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultToolRunsLimit
+	}
+	limit = min(limit, maxToolRunsLimit)
+
+	runs, err := rt.audit.list(ctx, func(r *spec.ToolRun) bool {
+		return (req.ConversationID == "" || r.ConversationID == req.ConversationID) &&
+			(req.SessionID == "" || r.SessionID == req.SessionID) &&
+			(req.BundleID == "" || r.BundleID == req.BundleID) &&
+			(req.ToolSlug == "" || r.ToolSlug == req.ToolSlug) &&
+			(!req.OnlyErrors || r.IsError)
+	}, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &spec.ListToolRunsResponse{Body: &spec.ListToolRunsResponseBody{ToolRuns: runs}}, nil
+}
+
+// ReplayToolRun invokes a recorded run again with the same tool version,
+// arguments and context. The replay is itself recorded, with ReplayOf set.
+func (rt *ToolRuntime) ReplayToolRun(
+	ctx context.Context,
+	req *spec.ReplayToolRunRequest,
+) (*spec.ReplayToolRunResponse, error) {
+	if rt.audit == nil {
+		return nil, errors.New("tool run audit is disabled")
+	}
+	if req == nil || req.ID == "" {
+		return nil, errors.New("invalid request: id required")
+	}
+	runs, err := rt.audit.list(ctx, func(r *spec.ToolRun) bool { return r.ID == req.ID }, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("tool run not found: %s", req.ID)
+	}
+	run := runs[0]
+
+	body := &spec.InvokeToolRequestBody{
+		Args:           run.Args,
+		ConversationID: run.ConversationID,
+		SessionID:      run.SessionID,
+	}
+	// The tool type is not recorded, so pass the timeout both ways; the runner
+	// only reads the options for its own type.
+	if run.TimeoutMS > 0 || (req.Body != nil && len(req.Body.Secrets) > 0) {
+		body.HTTPOptions = &spec.InvokeHTTPOptions{TimeoutMS: run.TimeoutMS}
+		if req.Body != nil {
+			body.HTTPOptions.Secrets = req.Body.Secrets
+		}
+	}
+	if run.TimeoutMS > 0 {
+		body.GoOptions = &spec.InvokeGoOptions{TimeoutMS: run.TimeoutMS}
+	}
+
+	resp, replay, err := rt.invokeAndRecord(ctx, &spec.InvokeToolRequest{
+		BundleID: run.BundleID,
+		ToolSlug: run.ToolSlug,
+		Version:  run.Version,
+		Body:     body,
+	}, run.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &spec.ReplayToolRunResponse{
+		Body: &spec.ReplayToolRunResponseBody{
+			Run:        *replay,
+			Result:     *resp.Body,
+			SameResult: !run.IsError && !replay.IsError && run.ResultDigest == replay.ResultDigest,
+		},
+	}, nil
+}
+
+// invokeAndRecord runs the tool and, when auditing is enabled, appends the
+// run to the log. Invocation errors are recorded too. Failing to write the log
+// does not fail the call.
+func (rt *ToolRuntime) invokeAndRecord(
+	ctx context.Context,
+	req *spec.InvokeToolRequest,
+	replayOf string,
+) (*spec.InvokeToolResponse, *spec.ToolRun, error) {
+	start := time.Now()
+	resp, err := rt.invokeTool(ctx, req)
+	if rt.audit == nil || req == nil || req.Body == nil {
+		return resp, nil, err
+	}
+
+	run := newToolRun(req, start, replayOf)
+	switch {
+	case err != nil:
+		run.IsError = true
+		run.ErrorMessage = err.Error()
+	case resp != nil && resp.Body != nil:
+		run.IsError = resp.Body.IsError
+		run.ErrorMessage = resp.Body.ErrorMessage
+		run.ResultDigest = toolOutputsDigest(resp.Body)
+	}
+	if aerr := rt.audit.append(run); aerr != nil {
+		logger.Error("record tool run", "tool", run.ToolSlug, "error", aerr)
+	}
+	return resp, run, err
+}
+
+func newToolRun(req *spec.InvokeToolRequest, start time.Time, replayOf string) *spec.ToolRun {
+	id := uuid.NewString()
+	if u, err := uuid.NewV7(); err == nil {
+		id = u.String()
+	}
+	run := &spec.ToolRun{
+		ID:             id,
+		BundleID:       req.BundleID,
+		ToolSlug:       req.ToolSlug,
+		Version:        req.Version,
+		ConversationID: req.Body.ConversationID,
+		SessionID:      req.Body.SessionID,
+		ReplayOf:       replayOf,
+		Args:           req.Body.Args,
+		StartedAt:      start.UTC(),
+		DurationMS:     time.Since(start).Milliseconds(),
+	}
+	if o := req.Body.HTTPOptions; o != nil && o.TimeoutMS > 0 {
+		run.TimeoutMS = o.TimeoutMS
+	}
+	if o := req.Body.GoOptions; o != nil && o.TimeoutMS > 0 {
+		run.TimeoutMS = o.TimeoutMS
+	}
+	return run
+}
+
+func toolOutputsDigest(body *spec.InvokeToolResponseBody) string {
+	raw, err := json.Marshal(body.Outputs)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// runAudit is a JSON lines log of tool runs.
+type runAudit struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (a *runAudit) append(run *spec.ToolRun) error {
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o770); err != nil {
+		return err
+	}
+	if info, err := os.Stat(a.path); err == nil && info.Size()+int64(len(line)) > maxToolRunsFileSize {
+		if err := os.Rename(a.path, a.path+".1"); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o660)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// list returns up to limit runs accepted by keep, newest first. Unreadable
+// lines are skipped.
+func (a *runAudit) list(ctx context.Context, keep func(*spec.ToolRun) bool, limit int) ([]spec.ToolRun, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	out := make([]spec.ToolRun, 0, min(limit, defaultToolRunsLimit))
+	for _, p := range []string{a.path, a.path + ".1"} {
+		data, err := os.ReadFile(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var runs []spec.ToolRun
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		sc.Buffer(make([]byte, 0, 64*1024), maxToolRunsFileSize)
+		for sc.Scan() {
+			var r spec.ToolRun
+			if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+				continue
+			}
+			if keep(&r) {
+				runs = append(runs, r)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		slices.Reverse(runs)
+		for _, r := range runs {
+			out = append(out, r)
+			if len(out) >= limit {
+				return out, nil
+			}
+		}
+	}
+	return out, nil
+}
@@ -1,6 +1,8 @@
 package spec
 
 import (
+	"time"
+
 	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
 	toolSpec "github.com/flexigpt/flexigpt-app/internal/tool/spec"
 	llmtoolsSpec "github.com/flexigpt/llmtools-go/spec"
@@ -34,6 +36,10 @@ type InvokeToolRequestBody struct {
 	// Tool-type-specific options (only one of these is used depending on the tool type).
 	HTTPOptions *InvokeHTTPOptions `json:"httpOptions,omitempty"`
 	GoOptions   *InvokeGoOptions   `json:"goOptions,omitempty"`
+
+	// Optional context recorded with the tool run for ListToolRuns.
+	ConversationID string `json:"conversationID,omitempty"`
+	SessionID      string `json:"sessionID,omitempty"`
 }
 
 type InvokeToolRequest struct {
@@ -67,3 +73,66 @@ type InvokeToolResponseBody struct {
 type InvokeToolResponse struct {
 	Body *InvokeToolResponseBody
 }
+
+// ToolRun is one recorded tool invocation. HTTP secrets and extra headers are
+// not recorded.
+type ToolRun struct {
+	ID       string                      `json:"id"`
+	BundleID bundleitemutils.BundleID    `json:"bundleID"`
+	ToolSlug bundleitemutils.ItemSlug    `json:"toolSlug"`
+	Version  bundleitemutils.ItemVersion `json:"version"`
+
+	ConversationID string `json:"conversationID,omitempty"`
+	SessionID      string `json:"sessionID,omitempty"`
+	// ReplayOf is the ID of the run this one replayed.
+	ReplayOf string `json:"replayOf,omitempty"`
+
+	Args      JSONRawString `json:"args"`
+	TimeoutMS int           `json:"timeoutMS,omitempty"`
+
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMS int64     `json:"durationMS"`
+	// ResultDigest is the sha256 of the JSON encoded outputs.
+	ResultDigest string `json:"resultDigest,omitempty"`
+	IsError      bool   `json:"isError,omitzero"`
+	ErrorMessage string `json:"errorMessage,omitzero"`
+}
+
+type ListToolRunsRequest struct {
+	ConversationID string                   `query:"conversationID"`
+	SessionID      string                   `query:"sessionID"`
+	BundleID       bundleitemutils.BundleID `query:"bundleID"`
+	ToolSlug       bundleitemutils.ItemSlug `query:"toolSlug"`
+	OnlyErrors     bool                     `query:"onlyErrors"`
+	// Limit caps the number of runs returned, newest first. Default 100.
+	Limit int `query:"limit"`
+}
+
+type ListToolRunsResponseBody struct {
+	ToolRuns []ToolRun `json:"toolRuns"`
+}
+
+type ListToolRunsResponse struct {
+	Body *ListToolRunsResponseBody
+}
+
+type ReplayToolRunRequestBody struct {
+	// Secrets are not recorded, so HTTP tools that need them must get them again.
+	Secrets map[string]string `json:"secrets,omitempty"`
+}
+
+type ReplayToolRunRequest struct {
+	ID   string `path:"id" required:"true"`
+	Body *ReplayToolRunRequestBody
+}
+
+type ReplayToolRunResponseBody struct {
+	Run    ToolRun                `json:"run"`
+	Result InvokeToolResponseBody `json:"result"`
+	// SameResult reports whether the replay produced the recorded outputs.
+	SameResult bool `json:"sameResult"`
+}
+
+type ReplayToolRunResponse struct {
+	Body *ReplayToolRunResponseBody
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/flexigpt/flexigpt-app/internal/bundleitemutils"
 	"github.com/flexigpt/flexigpt-app/internal/llmtoolsutil"
+	"github.com/flexigpt/flexigpt-app/internal/logging"
 	toolSpec "github.com/flexigpt/flexigpt-app/internal/tool/spec"
 	"github.com/flexigpt/flexigpt-app/internal/tool/store"
 	"github.com/flexigpt/flexigpt-app/internal/tool/storehelper"
@@ -21,22 +22,38 @@ import (
 	"github.com/flexigpt/flexigpt-app/internal/toolruntime/spec"
 )
 
+var logger = logging.Module("tool.runtime")
+
 // ToolRuntime executes tools (HTTP/Go) using tool definitions retrieved from ToolStore.
 type ToolRuntime struct {
 	store *store.ToolStore
+	audit *runAudit
 }
 
-func NewToolRuntime(s *store.ToolStore) *ToolRuntime {
-	return &ToolRuntime{store: s}
+func NewToolRuntime(s *store.ToolStore, opts ...Option) *ToolRuntime {
+	rt := &ToolRuntime{store: s}
+	for _, o := range opts {
+		o(rt)
+	}
+	return rt
 }
 
 // InvokeTool locates a tool version in the ToolStore and executes it according to its type.
 // - Validates request, slug/version.
 // - Enforces bundle/tool enabled state.
 // - Dispatches to HTTP or Go runner with functional options constructed from the request body.
+// - Records the run when a run audit dir is configured.
 func (rt *ToolRuntime) InvokeTool(
 	ctx context.Context,
 	req *spec.InvokeToolRequest,
+) (*spec.InvokeToolResponse, error) {
+	resp, _, err := rt.invokeAndRecord(ctx, req, "")
+	return resp, err
+}
+
+func (rt *ToolRuntime) invokeTool(
+	ctx context.Context,
+	req *spec.InvokeToolRequest,
 ) (*spec.InvokeToolResponse, error) {
 	if req == nil || req.Body == nil ||
 		req.BundleID == "" || req.ToolSlug == "" || req.Version == "" {
@@ -0,0 +1,71 @@
+package spec
+
+import (
+	"time"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+)
+
+type RecordUsageRequest struct {
+	Body *UsageRecord
+}
+
+type RecordUsageResponse struct {
+	Body *UsageRecord
+}
+
+// UsageFilter narrows the records a summary or listing covers. Zero fields
+// match everything; From is inclusive and To exclusive.
+type UsageFilter struct {
+	From           *time.Time                 `json:"from,omitempty"`
+	To             *time.Time                 `json:"to,omitempty"`
+	ProviderName   inferenceSpec.ProviderName `json:"providerName,omitempty"`
+	ConversationID string                     `json:"conversationID,omitempty"`
+}
+
+type GetUsageSummaryRequestBody struct {
+	GroupBy UsageGroupBy `json:"groupBy" required:"true"`
+	Filter  UsageFilter  `json:"filter"`
+}
+
+type GetUsageSummaryRequest struct {
+	Body *GetUsageSummaryRequestBody
+}
+
+type GetUsageSummaryResponseBody struct {
+	GroupBy UsageGroupBy     `json:"groupBy"`
+	Groups  []UsageAggregate `json:"groups"`
+	Total   UsageAggregate   `json:"total"`
+}
+
+type GetUsageSummaryResponse struct {
+	Body *GetUsageSummaryResponseBody
+}
+
+type ListUsageRecordsRequestBody struct {
+	Filter UsageFilter `json:"filter"`
+	// Records are returned newest first.
+	PageSize  int    `json:"pageSize,omitempty"`
+	PageToken string `json:"pageToken,omitempty"`
+}
+
+type ListUsageRecordsRequest struct {
+	Body *ListUsageRecordsRequestBody
+}
+
+type ListUsageRecordsResponseBody struct {
+	UsageRecords  []UsageRecord `json:"usageRecords"`
+	NextPageToken *string       `json:"nextPageToken,omitempty"`
+}
+
+type ListUsageRecordsResponse struct {
+	Body *ListUsageRecordsResponseBody
+}
+
+// UsageRecordsPageToken resumes a listing after the given record.
+type UsageRecordsPageToken struct {
+	Filter   UsageFilter `json:"f"`           //nolint:tagliatelle // PageToken Specific.
+	PageSize int         `json:"s,omitempty"` //nolint:tagliatelle // PageToken Specific.
+	AfterNS  int64       `json:"t"`           //nolint:tagliatelle // PageToken Specific.
+	AfterID  string      `json:"c"`           //nolint:tagliatelle // PageToken Specific.
+}
@@ -0,0 +1,119 @@
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+)
+
+const (
+	UsageDBFileName = "usage.sqlite"
+
+	MaxPageSize     = 500
+	DefaultPageSize = 100
+)
+
+var (
+	ErrInvalidDir     = errors.New("invalid directory")
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrBudgetExceeded = errors.New("budget exceeded")
+	ErrBudgetUnpriced = errors.New("budget cannot be enforced without model pricing")
+)
+
+// UsageGroupBy selects the aggregation key of a usage summary.
+type UsageGroupBy string
+
+const (
+	UsageGroupByDay          UsageGroupBy = "day"
+	UsageGroupByProvider     UsageGroupBy = "provider"
+	UsageGroupByModel        UsageGroupBy = "model"
+	UsageGroupByConversation UsageGroupBy = "conversation"
+)
+
+// UsageRecord is the token usage of one completion request.
+//
+// CostUSD is computed from the model preset pricing at the time of the
+// request; Priced is false when the preset had no pricing.
+type UsageRecord struct {
+	ID             string                        `json:"id"`
+	CreatedAt      time.Time                     `json:"createdAt"`
+	ProviderName   inferenceSpec.ProviderName    `json:"providerName"`
+	ModelPresetID  modelpresetSpec.ModelPresetID `json:"modelPresetID"`
+	ModelName      inferenceSpec.ModelName       `json:"modelName"`
+	ConversationID string                        `json:"conversationID,omitempty"`
+
+	InputTokensTotal    int64 `json:"inputTokensTotal"`
+	InputTokensCached   int64 `json:"inputTokensCached"`
+	InputTokensUncached int64 `json:"inputTokensUncached"`
+	OutputTokens        int64 `json:"outputTokens"`
+	ReasoningTokens     int64 `json:"reasoningTokens"`
+
+	CostUSD float64 `json:"costUSD"`
+	Priced  bool    `json:"priced"`
+}
+
+// UsageAggregate sums usage records sharing Key. For day grouping Key is the
+// UTC date as YYYY-MM-DD.
+type UsageAggregate struct {
+	Key                  string  `json:"key"`
+	RequestCount         int64   `json:"requestCount"`
+	InputTokensTotal     int64   `json:"inputTokensTotal"`
+	InputTokensCached    int64   `json:"inputTokensCached"`
+	OutputTokens         int64   `json:"outputTokens"`
+	ReasoningTokens      int64   `json:"reasoningTokens"`
+	CostUSD              float64 `json:"costUSD"`
+	UnpricedRequestCount int64   `json:"unpricedRequestCount"`
+}
+
+type BudgetPeriod string
+
+const (
+	BudgetPeriodDay   BudgetPeriod = "day"
+	BudgetPeriodMonth BudgetPeriod = "month"
+)
+
+// BudgetExceededError reports which limit blocked a completion. It matches
+// ErrBudgetExceeded with errors.Is; the request can be retried with the
+// budget override set. UnpricedRequestCount requests of the period had no
+// pricing and are not part of SpentUSD.
+type BudgetExceededError struct {
+	ProviderName         inferenceSpec.ProviderName `json:"providerName"`
+	Period               BudgetPeriod               `json:"period"`
+	LimitUSD             float64                    `json:"limitUSD"`
+	SpentUSD             float64                    `json:"spentUSD"`
+	UnpricedRequestCount int64                      `json:"unpricedRequestCount,omitempty"`
+}
+
+func (e *BudgetExceededError) Error() string {
+	msg := fmt.Sprintf("%s: %s spend on %s is $%.2f of $%.2f",
+		ErrBudgetExceeded, e.Period, e.ProviderName, e.SpentUSD, e.LimitUSD)
+	if e.UnpricedRequestCount > 0 {
+		msg += fmt.Sprintf(" (plus %d unpriced requests)", e.UnpricedRequestCount)
+	}
+	return msg
+}
+
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// BudgetUnpricedError reports that a provider has a budget limit but the
+// requested model preset has no pricing, so its cost would not count against
+// the limit. It matches ErrBudgetUnpriced with errors.Is; set pricing on the
+// preset, or retry with the budget override set.
+type BudgetUnpricedError struct {
+	ProviderName inferenceSpec.ProviderName `json:"providerName"`
+}
+
+func (e *BudgetUnpricedError) Error() string {
+	return fmt.Sprintf("%s: %s has a budget limit but the model preset has no pricing",
+		ErrBudgetUnpriced, e.ProviderName)
+}
+
+func (e *BudgetUnpricedError) Is(target error) bool {
+	return target == ErrBudgetUnpriced
+}
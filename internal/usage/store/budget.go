@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	"github.com/flexigpt/flexigpt-app/internal/usage/spec"
+)
+
+// BudgetLimitsFunc returns the daily and monthly USD limits of a provider.
+// A zero limit is no limit.
+type BudgetLimitsFunc func(ctx context.Context, provider inferenceSpec.ProviderName) (daily, monthly float64, err error)
+
+// BudgetGuard checks recorded spend against the configured limits.
+type BudgetGuard struct {
+	store  *UsageStore
+	limits BudgetLimitsFunc
+	now    func() time.Time
+}
+
+func NewBudgetGuard(s *UsageStore, limits BudgetLimitsFunc) *BudgetGuard {
+	return &BudgetGuard{store: s, limits: limits, now: time.Now}
+}
+
+// CheckBudget returns a *spec.BudgetExceededError when the spend of the
+// current UTC day or month has reached the provider limit. Requests already
+// in flight are not counted, so a burst can overshoot a limit slightly.
+//
+// Spend is only known for priced presets. When a limit is set and pricing of
+// the requested preset is nil or zero, a *spec.BudgetUnpricedError is
+// returned instead of letting the request through uncounted.
+func (g *BudgetGuard) CheckBudget(
+	ctx context.Context,
+	provider inferenceSpec.ProviderName,
+	pricing *modelpresetSpec.ModelPricing,
+) error {
+	if g == nil || g.store == nil || g.limits == nil {
+		return nil
+	}
+	daily, monthly, err := g.limits(ctx, provider)
+	if err != nil {
+		return err
+	}
+	if daily <= 0 && monthly <= 0 {
+		return nil
+	}
+	if pricing == nil || pricing.IsZero() {
+		return &spec.BudgetUnpricedError{ProviderName: provider}
+	}
+	now := g.now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	for _, c := range []struct {
+		period spec.BudgetPeriod
+		limit  float64
+		from   time.Time
+	}{
+		{spec.BudgetPeriodDay, daily, dayStart},
+		{spec.BudgetPeriodMonth, monthly, monthStart},
+	} {
+		if c.limit <= 0 {
+			continue
+		}
+		spent, err := g.spentSince(ctx, provider, c.from)
+		if err != nil {
+			return err
+		}
+		if spent.CostUSD >= c.limit {
+			return &spec.BudgetExceededError{
+				ProviderName:         provider,
+				Period:               c.period,
+				LimitUSD:             c.limit,
+				SpentUSD:             spent.CostUSD,
+				UnpricedRequestCount: spent.UnpricedRequestCount,
+			}
+		}
+	}
+	return nil
+}
+
+func (g *BudgetGuard) spentSince(
+	ctx context.Context,
+	provider inferenceSpec.ProviderName,
+	from time.Time,
+) (spec.UsageAggregate, error) {
+	resp, err := g.store.GetUsageSummary(ctx, &spec.GetUsageSummaryRequest{
+		Body: &spec.GetUsageSummaryRequestBody{
+			GroupBy: spec.UsageGroupByProvider,
+			Filter:  spec.UsageFilter{From: &from, ProviderName: provider},
+		},
+	})
+	if err != nil {
+		return spec.UsageAggregate{}, err
+	}
+	return resp.Body.Total, nil
+}
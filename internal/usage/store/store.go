@@ -0,0 +1,335 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	"github.com/flexigpt/flexigpt-app/internal/usage/spec"
+
+	_ "github.com/glebarez/go-sqlite"
+)
+
+const createSchemaSQL = `
+CREATE TABLE IF NOT EXISTS usage_records (
+	id                    TEXT PRIMARY KEY,
+	created_at_ns         INTEGER NOT NULL,
+	day                   TEXT NOT NULL,
+	provider_name         TEXT NOT NULL,
+	model_preset_id       TEXT NOT NULL,
+	model_name            TEXT NOT NULL,
+	conversation_id       TEXT NOT NULL DEFAULT '',
+	input_tokens_total    INTEGER NOT NULL DEFAULT 0,
+	input_tokens_cached   INTEGER NOT NULL DEFAULT 0,
+	input_tokens_uncached INTEGER NOT NULL DEFAULT 0,
+	output_tokens         INTEGER NOT NULL DEFAULT 0,
+	reasoning_tokens      INTEGER NOT NULL DEFAULT 0,
+	cost_usd              REAL NOT NULL DEFAULT 0,
+	priced                INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS usage_records_created ON usage_records(created_at_ns, id);
+CREATE INDEX IF NOT EXISTS usage_records_conversation ON usage_records(conversation_id);
+`
+
+const recordColumns = `id, created_at_ns, provider_name, model_preset_id, model_name, conversation_id,
+	input_tokens_total, input_tokens_cached, input_tokens_uncached, output_tokens, reasoning_tokens,
+	cost_usd, priced`
+
+// UsageStore persists per-request token usage and cost in SQLite.
+type UsageStore struct {
+	db *sql.DB
+}
+
+func NewUsageStore(ctx context.Context, baseDir string) (*UsageStore, error) {
+	if strings.TrimSpace(baseDir) == "" {
+		return nil, fmt.Errorf("%w: baseDir", spec.ErrInvalidDir)
+	}
+	if err := os.MkdirAll(baseDir, 0o770); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", dataSourceName(filepath.Join(baseDir, spec.UsageDBFileName)))
+	if err != nil {
+		return nil, fmt.Errorf("open usage database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("ping usage database: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, createSchemaSQL); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("initialize usage schema: %w", err)
+	}
+	return &UsageStore{db: db}, nil
+}
+
+func (s *UsageStore) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// ComputeCost prices usage with p. Cached input is billed at the cached
+// price when set. Reasoning tokens are not billed separately as providers
+// report them as part of the output tokens.
+func ComputeCost(u *inferenceSpec.Usage, p *modelpresetSpec.ModelPricing) (cost float64, priced bool) {
+	if u == nil || p == nil || p.IsZero() {
+		return 0, false
+	}
+	uncached := u.InputTokensUncached
+	if uncached == 0 && u.InputTokensTotal > u.InputTokensCached {
+		uncached = u.InputTokensTotal - u.InputTokensCached
+	}
+	cachedPrice := p.CachedInputPerMTok
+	if cachedPrice == 0 {
+		cachedPrice = p.InputPerMTok
+	}
+	cost = (float64(uncached)*p.InputPerMTok +
+		float64(u.InputTokensCached)*cachedPrice +
+		float64(u.OutputTokens)*p.OutputPerMTok) / 1e6
+	return cost, true
+}
+
+// RecordUsage stores a usage record. ID and CreatedAt are filled in when
+// empty.
+func (s *UsageStore) RecordUsage(
+	ctx context.Context,
+	req *spec.RecordUsageRequest,
+) (*spec.RecordUsageResponse, error) {
+	if req == nil || req.Body == nil || req.Body.ProviderName == "" {
+		return nil, fmt.Errorf("%w: providerName required", spec.ErrInvalidRequest)
+	}
+	r := *req.Body
+	if r.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return nil, err
+		}
+		r.ID = id.String()
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	r.CreatedAt = r.CreatedAt.UTC()
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO usage_records (`+recordColumns+`, day) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ID, r.CreatedAt.UnixNano(), string(r.ProviderName), string(r.ModelPresetID), string(r.ModelName),
+		r.ConversationID, r.InputTokensTotal, r.InputTokensCached, r.InputTokensUncached, r.OutputTokens,
+		r.ReasoningTokens, r.CostUSD, r.Priced, r.CreatedAt.Format(time.DateOnly),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert usage record: %w", err)
+	}
+	return &spec.RecordUsageResponse{Body: &r}, nil
+}
+
+// GetUsageSummary aggregates usage per day, provider, model or conversation.
+func (s *UsageStore) GetUsageSummary(
+	ctx context.Context,
+	req *spec.GetUsageSummaryRequest,
+) (*spec.GetUsageSummaryResponse, error) {
+	if req == nil || req.Body == nil {
+		return nil, fmt.Errorf("%w: body required", spec.ErrInvalidRequest)
+	}
+	var keyExpr string
+	switch req.Body.GroupBy {
+	case spec.UsageGroupByDay:
+		keyExpr = "day"
+	case spec.UsageGroupByProvider:
+		keyExpr = "provider_name"
+	case spec.UsageGroupByModel:
+		keyExpr = "provider_name || '/' || model_name"
+	case spec.UsageGroupByConversation:
+		keyExpr = "conversation_id"
+	default:
+		return nil, fmt.Errorf("%w: unknown groupBy %q", spec.ErrInvalidRequest, req.Body.GroupBy)
+	}
+
+	where, args := filterClause(req.Body.Filter)
+	//nolint:gosec // keyExpr is one of the constants above.
+	rows, err := s.db.QueryContext(ctx, `SELECT `+keyExpr+`, COUNT(*),
+		SUM(input_tokens_total), SUM(input_tokens_cached), SUM(output_tokens), SUM(reasoning_tokens),
+		SUM(cost_usd), SUM(CASE WHEN priced = 0 THEN 1 ELSE 0 END)
+		FROM usage_records`+where+` GROUP BY 1 ORDER BY 1`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query usage summary: %w", err)
+	}
+	defer rows.Close()
+
+	body := &spec.GetUsageSummaryResponseBody{GroupBy: req.Body.GroupBy, Groups: []spec.UsageAggregate{}}
+	for rows.Next() {
+		var g spec.UsageAggregate
+		if err := rows.Scan(&g.Key, &g.RequestCount, &g.InputTokensTotal, &g.InputTokensCached,
+			&g.OutputTokens, &g.ReasoningTokens, &g.CostUSD, &g.UnpricedRequestCount); err != nil {
+			return nil, err
+		}
+		body.Groups = append(body.Groups, g)
+
+		body.Total.RequestCount += g.RequestCount
+		body.Total.InputTokensTotal += g.InputTokensTotal
+		body.Total.InputTokensCached += g.InputTokensCached
+		body.Total.OutputTokens += g.OutputTokens
+		body.Total.ReasoningTokens += g.ReasoningTokens
+		body.Total.CostUSD += g.CostUSD
+		body.Total.UnpricedRequestCount += g.UnpricedRequestCount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &spec.GetUsageSummaryResponse{Body: body}, nil
+}
+
+// ListUsageRecords returns usage records newest first.
+func (s *UsageStore) ListUsageRecords(
+	ctx context.Context,
+	req *spec.ListUsageRecordsRequest,
+) (*spec.ListUsageRecordsResponse, error) {
+	tok := spec.UsageRecordsPageToken{}
+	if req != nil && req.Body != nil {
+		if req.Body.PageToken != "" {
+			var err error
+			if tok, err = decodePageToken(req.Body.PageToken); err != nil {
+				return nil, fmt.Errorf("%w: invalid pageToken: %w", spec.ErrInvalidRequest, err)
+			}
+		} else {
+			tok.Filter = req.Body.Filter
+			tok.PageSize = req.Body.PageSize
+		}
+	}
+	if tok.PageSize <= 0 || tok.PageSize > spec.MaxPageSize {
+		tok.PageSize = spec.DefaultPageSize
+	}
+
+	where, args := filterClause(tok.Filter)
+	if tok.AfterID != "" {
+		where += andOrWhere(where) + `(created_at_ns < ? OR (created_at_ns = ? AND id < ?))`
+		args = append(args, tok.AfterNS, tok.AfterNS, tok.AfterID)
+	}
+	args = append(args, tok.PageSize+1)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+recordColumns+` FROM usage_records`+where+
+		` ORDER BY created_at_ns DESC, id DESC LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query usage records: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]spec.UsageRecord, 0, tok.PageSize)
+	for rows.Next() {
+		var (
+			r                         spec.UsageRecord
+			createdNS                 int64
+			provider, preset, modelNm string
+		)
+		if err := rows.Scan(&r.ID, &createdNS, &provider, &preset, &modelNm, &r.ConversationID,
+			&r.InputTokensTotal, &r.InputTokensCached, &r.InputTokensUncached, &r.OutputTokens,
+			&r.ReasoningTokens, &r.CostUSD, &r.Priced); err != nil {
+			return nil, err
+		}
+		r.CreatedAt = time.Unix(0, createdNS).UTC()
+		r.ProviderName = inferenceSpec.ProviderName(provider)
+		r.ModelPresetID = modelpresetSpec.ModelPresetID(preset)
+		r.ModelName = inferenceSpec.ModelName(modelNm)
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	body := &spec.ListUsageRecordsResponseBody{UsageRecords: records}
+	if len(records) > tok.PageSize {
+		body.UsageRecords = records[:tok.PageSize]
+		last := body.UsageRecords[tok.PageSize-1]
+		tok.AfterNS = last.CreatedAt.UnixNano()
+		tok.AfterID = last.ID
+		next, err := encodePageToken(tok)
+		if err != nil {
+			return nil, err
+		}
+		body.NextPageToken = &next
+	}
+	return &spec.ListUsageRecordsResponse{Body: body}, nil
+}
+
+func filterClause(f spec.UsageFilter) (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	if f.From != nil {
+		conds = append(conds, "created_at_ns >= ?")
+		args = append(args, f.From.UnixNano())
+	}
+	if f.To != nil {
+		conds = append(conds, "created_at_ns < ?")
+		args = append(args, f.To.UnixNano())
+	}
+	if f.ProviderName != "" {
+		conds = append(conds, "provider_name = ?")
+		args = append(args, string(f.ProviderName))
+	}
+	if f.ConversationID != "" {
+		conds = append(conds, "conversation_id = ?")
+		args = append(args, f.ConversationID)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func andOrWhere(where string) string {
+	if where == "" {
+		return " WHERE "
+	}
+	return " AND "
+}
+
+func encodePageToken(tok spec.UsageRecordsPageToken) (string, error) {
+	raw, err := json.Marshal(tok)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(raw), nil
+}
+
+func decodePageToken(token string) (spec.UsageRecordsPageToken, error) {
+	raw, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return spec.UsageRecordsPageToken{}, err
+	}
+	var tok spec.UsageRecordsPageToken
+	if err := json.Unmarshal(raw, &tok); err != nil {
+		return spec.UsageRecordsPageToken{}, err
+	}
+	if tok.AfterID == "" {
+		return spec.UsageRecordsPageToken{}, errors.New("missing cursor")
+	}
+	return tok, nil
+}
+
+func dataSourceName(path string) string {
+	normalized := filepath.ToSlash(filepath.Clean(path))
+	if filepath.VolumeName(path) != "" && !strings.HasPrefix(normalized, "/") {
+		normalized = "/" + normalized
+	}
+	value := &url.URL{Scheme: "file", Path: normalized}
+	query := value.Query()
+	query.Set("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "busy_timeout(5000)")
+	value.RawQuery = query.Encode()
+	return value.String()
+}
@@ -0,0 +1,231 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	inferenceSpec "github.com/flexigpt/inference-go/spec"
+
+	modelpresetSpec "github.com/flexigpt/flexigpt-app/internal/modelpreset/spec"
+	"github.com/flexigpt/flexigpt-app/internal/usage/spec"
+)
+
+func TestComputeCost(t *testing.T) {
+	usage := &inferenceSpec.Usage{
+		InputTokensTotal:    1_000_000,
+		InputTokensCached:   400_000,
+		InputTokensUncached: 600_000,
+		OutputTokens:        200_000,
+	}
+	tests := []struct {
+		name       string
+		usage      *inferenceSpec.Usage
+		pricing    *modelpresetSpec.ModelPricing
+		wantCost   float64
+		wantPriced bool
+	}{
+		{name: "no pricing", usage: usage},
+		{name: "zero pricing", usage: usage, pricing: &modelpresetSpec.ModelPricing{}},
+		{
+			name:       "cached price",
+			usage:      usage,
+			pricing:    &modelpresetSpec.ModelPricing{InputPerMTok: 3, CachedInputPerMTok: 0.3, OutputPerMTok: 15},
+			wantCost:   0.6*3 + 0.4*0.3 + 0.2*15,
+			wantPriced: true,
+		},
+		{
+			name:       "cached billed as input",
+			usage:      usage,
+			pricing:    &modelpresetSpec.ModelPricing{InputPerMTok: 2, OutputPerMTok: 8},
+			wantCost:   1*2 + 0.2*8,
+			wantPriced: true,
+		},
+		{
+			name:       "uncached derived from total",
+			usage:      &inferenceSpec.Usage{InputTokensTotal: 500_000, InputTokensCached: 100_000},
+			pricing:    &modelpresetSpec.ModelPricing{InputPerMTok: 1, CachedInputPerMTok: 0.5},
+			wantCost:   0.4 + 0.05,
+			wantPriced: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cost, priced := ComputeCost(tc.usage, tc.pricing)
+			if priced != tc.wantPriced || math.Abs(cost-tc.wantCost) > 1e-9 {
+				t.Fatalf("got (%v, %v), want (%v, %v)", cost, priced, tc.wantCost, tc.wantPriced)
+			}
+		})
+	}
+}
+
+func TestUsageStoreSummaryAndList(t *testing.T) {
+	s, err := NewUsageStore(t.Context(), t.TempDir())
+	if err != nil {
+		t.Fatalf("NewUsageStore: %v", err)
+	}
+	defer s.Close()
+
+	day1 := time.Date(2026, 10, 14, 23, 0, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Hour)
+	records := []spec.UsageRecord{
+		{CreatedAt: day1, ProviderName: "openai", ModelName: "gpt-x", ConversationID: "c1",
+			InputTokensTotal: 100, OutputTokens: 10, CostUSD: 0.5, Priced: true},
+		{CreatedAt: day2, ProviderName: "openai", ModelName: "gpt-x", ConversationID: "c2",
+			InputTokensTotal: 200, OutputTokens: 20, CostUSD: 1, Priced: true},
+		{CreatedAt: day2.Add(time.Minute), ProviderName: "anthropic", ModelName: "claude-x", ConversationID: "c1",
+			InputTokensTotal: 300, OutputTokens: 30},
+	}
+	for i := range records {
+		if _, err := s.RecordUsage(t.Context(), &spec.RecordUsageRequest{Body: &records[i]}); err != nil {
+			t.Fatalf("RecordUsage: %v", err)
+		}
+	}
+
+	summary := func(groupBy spec.UsageGroupBy, f spec.UsageFilter) *spec.GetUsageSummaryResponseBody {
+		t.Helper()
+		resp, err := s.GetUsageSummary(t.Context(), &spec.GetUsageSummaryRequest{
+			Body: &spec.GetUsageSummaryRequestBody{GroupBy: groupBy, Filter: f},
+		})
+		if err != nil {
+			t.Fatalf("GetUsageSummary(%s): %v", groupBy, err)
+		}
+		return resp.Body
+	}
+
+	byDay := summary(spec.UsageGroupByDay, spec.UsageFilter{})
+	if len(byDay.Groups) != 2 || byDay.Groups[0].Key != "2026-10-14" || byDay.Groups[1].RequestCount != 2 {
+		t.Fatalf("unexpected day groups: %+v", byDay.Groups)
+	}
+	if byDay.Total.RequestCount != 3 || byDay.Total.InputTokensTotal != 600 ||
+		byDay.Total.CostUSD != 1.5 || byDay.Total.UnpricedRequestCount != 1 {
+		t.Fatalf("unexpected total: %+v", byDay.Total)
+	}
+
+	byProvider := summary(spec.UsageGroupByProvider, spec.UsageFilter{From: &day2})
+	if len(byProvider.Groups) != 2 || byProvider.Groups[1].Key != "openai" ||
+		byProvider.Groups[1].OutputTokens != 20 {
+		t.Fatalf("unexpected provider groups: %+v", byProvider.Groups)
+	}
+
+	byConvo := summary(spec.UsageGroupByConversation, spec.UsageFilter{ProviderName: "openai"})
+	if len(byConvo.Groups) != 2 || byConvo.Groups[0].Key != "c1" || byConvo.Groups[0].CostUSD != 0.5 {
+		t.Fatalf("unexpected conversation groups: %+v", byConvo.Groups)
+	}
+
+	if _, err := s.GetUsageSummary(t.Context(), &spec.GetUsageSummaryRequest{
+		Body: &spec.GetUsageSummaryRequestBody{GroupBy: "week"},
+	}); err == nil {
+		t.Fatal("expected error for unknown groupBy")
+	}
+
+	var (
+		got   []spec.UsageRecord
+		token string
+	)
+	for {
+		resp, err := s.ListUsageRecords(t.Context(), &spec.ListUsageRecordsRequest{
+			Body: &spec.ListUsageRecordsRequestBody{PageSize: 2, PageToken: token},
+		})
+		if err != nil {
+			t.Fatalf("ListUsageRecords: %v", err)
+		}
+		got = append(got, resp.Body.UsageRecords...)
+		if resp.Body.NextPageToken == nil {
+			break
+		}
+		token = *resp.Body.NextPageToken
+	}
+	if len(got) != 3 || got[0].ProviderName != "anthropic" || !got[2].CreatedAt.Equal(day1) {
+		t.Fatalf("unexpected listing: %+v", got)
+	}
+
+	resp, err := s.ListUsageRecords(t.Context(), &spec.ListUsageRecordsRequest{
+		Body: &spec.ListUsageRecordsRequestBody{Filter: spec.UsageFilter{ConversationID: "c1"}},
+	})
+	if err != nil {
+		t.Fatalf("ListUsageRecords: %v", err)
+	}
+	if len(resp.Body.UsageRecords) != 2 {
+		t.Fatalf("got %d records for c1, want 2", len(resp.Body.UsageRecords))
+	}
+}
+
+func TestBudgetGuard(t *testing.T) {
+	s, err := NewUsageStore(t.Context(), t.TempDir())
+	if err != nil {
+		t.Fatalf("NewUsageStore: %v", err)
+	}
+	defer s.Close()
+
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	for _, r := range []spec.UsageRecord{
+		{CreatedAt: now.AddDate(0, 0, -3), ProviderName: "openai", CostUSD: 4, Priced: true},
+		{CreatedAt: now.Add(-time.Hour), ProviderName: "openai", CostUSD: 1.5, Priced: true},
+		{CreatedAt: now.Add(-time.Hour), ProviderName: "anthropic", CostUSD: 50, Priced: true},
+		{CreatedAt: now.AddDate(0, -1, 0), ProviderName: "openai", CostUSD: 100, Priced: true},
+		{CreatedAt: now.Add(-time.Minute), ProviderName: "openai"},
+	} {
+		if _, err := s.RecordUsage(t.Context(), &spec.RecordUsageRequest{Body: &r}); err != nil {
+			t.Fatalf("RecordUsage: %v", err)
+		}
+	}
+
+	priced := &modelpresetSpec.ModelPricing{InputPerMTok: 1, OutputPerMTok: 2}
+	tests := []struct {
+		name         string
+		daily        float64
+		monthly      float64
+		pricing      *modelpresetSpec.ModelPricing
+		wantPeriod   spec.BudgetPeriod
+		wantSpent    float64
+		wantUnpriced bool
+	}{
+		{name: "no limits"},
+		{name: "no limits unpriced"},
+		{name: "limit unpriced", daily: 2, wantUnpriced: true},
+		{name: "limit zero pricing", daily: 2, pricing: &modelpresetSpec.ModelPricing{}, wantUnpriced: true},
+		{name: "under limits", daily: 2, monthly: 10, pricing: priced},
+		{
+			name: "daily reached", daily: 1.5, monthly: 10, pricing: priced,
+			wantPeriod: spec.BudgetPeriodDay, wantSpent: 1.5,
+		},
+		{
+			name: "monthly reached", daily: 2, monthly: 5, pricing: priced,
+			wantPeriod: spec.BudgetPeriodMonth, wantSpent: 5.5,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewBudgetGuard(s, func(context.Context, inferenceSpec.ProviderName) (float64, float64, error) {
+				return tc.daily, tc.monthly, nil
+			})
+			g.now = func() time.Time { return now }
+
+			err := g.CheckBudget(t.Context(), "openai", tc.pricing)
+			if tc.wantUnpriced {
+				var ue *spec.BudgetUnpricedError
+				if !errors.Is(err, spec.ErrBudgetUnpriced) || !errors.As(err, &ue) || ue.ProviderName != "openai" {
+					t.Fatalf("got %v, want ErrBudgetUnpriced", err)
+				}
+				return
+			}
+			if tc.wantPeriod == "" {
+				if err != nil {
+					t.Fatalf("CheckBudget: %v", err)
+				}
+				return
+			}
+			var be *spec.BudgetExceededError
+			if !errors.Is(err, spec.ErrBudgetExceeded) || !errors.As(err, &be) {
+				t.Fatalf("got %v, want ErrBudgetExceeded", err)
+			}
+			if be.Period != tc.wantPeriod || math.Abs(be.SpentUSD-tc.wantSpent) > 1e-9 ||
+				be.UnpricedRequestCount != 1 {
+				t.Fatalf("unexpected error details: %+v", be)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+// Package validation collects field-level validation failures, so that a
+// Put or Patch handler can report every invalid field of a request at once
+// instead of only the first. Reports may also carry warnings, which describe
+// a questionable but accepted value and never fail a write.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Code classifies an issue independent of its message.
+type Code string
+
+const (
+	CodeRequired    Code = "required"
+	CodeInvalid     Code = "invalid"
+	CodeOutOfRange  Code = "outOfRange"
+	CodeTooLong     Code = "tooLong"
+	CodeUnsupported Code = "unsupported"
+	CodeDuplicate   Code = "duplicate"
+	CodeConflict    Code = "conflict"
+	CodeMismatch    Code = "mismatch"
+)
+
+// Severity tells errors, which reject a value, from warnings.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is one invalid field. Field is the JSON path of the offending value,
+// e.g. "arguments[2].name"; it is empty for issues about the value as a
+// whole.
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Field    string   `json:"field"`
+	Code     Code     `json:"code"`
+	Message  string   `json:"message"`
+
+	err error
+}
+
+// Errors is the issues found in one value. Its message joins the issue
+// messages, and errors.Is matches the errors the issues were built from.
+type Errors []Issue
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, issue := range e {
+		msgs = append(msgs, issue.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e Errors) Unwrap() []error {
+	var errs []error
+	for _, issue := range e {
+		if issue.err != nil {
+			errs = append(errs, issue.err)
+		}
+	}
+	return errs
+}
+
+// Fields returns the field paths of the issues, in order and without
+// duplicates.
+func (e Errors) Fields() []string {
+	var fields []string
+	seen := map[string]struct{}{}
+	for _, issue := range e {
+		if _, ok := seen[issue.Field]; ok {
+			continue
+		}
+		seen[issue.Field] = struct{}{}
+		fields = append(fields, issue.Field)
+	}
+	return fields
+}
+
+// IssuesOf returns the issues carried by err, or nil if err is not or does
+// not wrap Errors.
+func IssuesOf(err error) Errors {
+	var errs Errors
+	if errors.As(err, &errs) {
+		return errs
+	}
+	return nil
+}
+
+// Report accumulates issues. The zero value is ready to use.
+type Report struct {
+	prefix string
+	issues *Errors
+}
+
+// At returns a report that adds issues below field of r. Issues added to
+// it are part of r.
+func (r *Report) At(field string) *Report {
+	if r.issues == nil {
+		r.issues = &Errors{}
+	}
+	return &Report{prefix: r.path(field), issues: r.issues}
+}
+
+// Index returns the path of element i of field, e.g. "tags[3]".
+func Index(field string, i int) string {
+	return field + "[" + strconv.Itoa(i) + "]"
+}
+
+// Addf adds an issue for field with a formatted message.
+func (r *Report) Addf(field string, code Code, format string, args ...any) {
+	r.add(Issue{Field: r.path(field), Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Warnf adds a warning for field. Warnings are listed by Issues but do not
+// make the report fail.
+func (r *Report) Warnf(field string, code Code, format string, args ...any) {
+	r.add(Issue{
+		Severity: SeverityWarning, Field: r.path(field), Code: code, Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Check adds an issue for field if err is not nil. The message is err's,
+// and the report's error still matches err.
+func (r *Report) Check(field string, code Code, err error) {
+	if err == nil {
+		return
+	}
+	r.add(Issue{Field: r.path(field), Code: code, Message: err.Error(), err: err})
+}
+
+// Checkf is Check with the message prefixed, as fmt.Errorf(prefix+": %w")
+// would.
+func (r *Report) Checkf(field string, code Code, prefix string, err error) {
+	if err == nil {
+		return
+	}
+	r.add(Issue{Field: r.path(field), Code: code, Message: prefix + ": " + err.Error(), err: err})
+}
+
+// Include adds issues found by another report below field of r. A non-empty
+// prefix is put in front of their messages, as Checkf does.
+func (r *Report) Include(field, prefix string, issues Errors) {
+	for _, issue := range issues {
+		issue.Field = r.At(field).path(issue.Field)
+		if prefix != "" {
+			issue.Message = prefix + ": " + issue.Message
+		}
+		r.add(issue)
+	}
+}
+
+// OK reports whether no errors were added. Warnings do not count.
+func (r *Report) OK() bool {
+	return r.issues == nil || !slices.ContainsFunc(*r.issues, Issue.isError)
+}
+
+// Err returns the errors added so far as Errors, or nil if there are none.
+// Warnings are left out.
+func (r *Report) Err() error {
+	if r.OK() {
+		return nil
+	}
+	var errs Errors
+	for _, issue := range *r.issues {
+		if issue.isError() {
+			errs = append(errs, issue)
+		}
+	}
+	return errs
+}
+
+// Issues returns the errors and warnings added so far, in order.
+func (r *Report) Issues() Errors {
+	if r.issues == nil {
+		return nil
+	}
+	return slices.Clone(*r.issues)
+}
+
+func (r *Report) add(issue Issue) {
+	if r.issues == nil {
+		r.issues = &Errors{}
+	}
+	if issue.Severity == "" {
+		issue.Severity = SeverityError
+	}
+	*r.issues = append(*r.issues, issue)
+}
+
+func (i Issue) isError() bool {
+	return i.Severity != SeverityWarning
+}
+
+func (r *Report) path(field string) string {
+	switch {
+	case r.prefix == "":
+		return field
+	case field == "":
+		return r.prefix
+	case strings.HasPrefix(field, "["):
+		return r.prefix + field
+	default:
+		return r.prefix + "." + field
+	}
+}
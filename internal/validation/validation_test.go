@@ -0,0 +1,104 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+var errSentinel = errors.New("sentinel")
+
+func TestReport(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		var r Report
+		if !r.OK() || r.Err() != nil {
+			t.Fatalf("empty report: ok=%v err=%v", r.OK(), r.Err())
+		}
+		r.Check("name", CodeInvalid, nil)
+		if r.Err() != nil {
+			t.Fatalf("nil check added an issue: %v", r.Err())
+		}
+	})
+
+	t.Run("collects all issues with paths", func(t *testing.T) {
+		r := &Report{}
+		r.Addf("name", CodeRequired, "name is empty")
+		args := r.At("arguments")
+		args.Addf(Index("", 1)+".name", CodeDuplicate, "duplicate argument %q", "x")
+		args.At(Index("items", 0)).Checkf("", CodeInvalid, "invalid item", errSentinel)
+
+		err := r.Err()
+		issues := IssuesOf(err)
+		want := Errors{
+			{Severity: SeverityError, Field: "name", Code: CodeRequired, Message: "name is empty"},
+			{
+				Severity: SeverityError, Field: "arguments[1].name", Code: CodeDuplicate,
+				Message: `duplicate argument "x"`,
+			},
+			{
+				Severity: SeverityError, Field: "arguments.items[0]", Code: CodeInvalid,
+				Message: "invalid item: sentinel", err: errSentinel,
+			},
+		}
+		if !slices.Equal(issues, want) {
+			t.Fatalf("issues = %+v, want %+v", issues, want)
+		}
+		if got := err.Error(); got != `name is empty; duplicate argument "x"; invalid item: sentinel` {
+			t.Fatalf("message = %q", got)
+		}
+		if !errors.Is(err, errSentinel) {
+			t.Fatalf("errors.Is(%v, sentinel) = false", err)
+		}
+		fields := issues.Fields()
+		if !slices.Equal(fields, []string{"name", "arguments[1].name", "arguments.items[0]"}) {
+			t.Fatalf("fields = %v", fields)
+		}
+	})
+
+	t.Run("warnings do not fail", func(t *testing.T) {
+		r := &Report{}
+		r.At("origin").Warnf("", CodeInvalid, "plain http")
+		if !r.OK() || r.Err() != nil {
+			t.Fatalf("warning failed the report: ok=%v err=%v", r.OK(), r.Err())
+		}
+		r.Addf("name", CodeRequired, "name is empty")
+		issues := r.Issues()
+		if len(issues) != 2 || issues[0].Severity != SeverityWarning || issues[0].Field != "origin" {
+			t.Fatalf("issues = %+v", issues)
+		}
+		errs := IssuesOf(r.Err())
+		if len(errs) != 1 || errs[0].Field != "name" {
+			t.Fatalf("errors = %+v", errs)
+		}
+	})
+
+	t.Run("include", func(t *testing.T) {
+		inner := &Report{}
+		inner.Addf("temperature", CodeRequired, "temperature missing")
+		inner.Warnf("", CodeInvalid, "no tags")
+		r := &Report{}
+		r.Include("models.m1", `model "m1"`, inner.Issues())
+		issues := r.Issues()
+		if len(issues) != 2 || issues[0].Field != "models.m1.temperature" || issues[1].Field != "models.m1" {
+			t.Fatalf("issues = %+v", issues)
+		}
+		if issues[0].Message != `model "m1": temperature missing` || issues[1].Severity != SeverityWarning {
+			t.Fatalf("issues = %+v", issues)
+		}
+	})
+
+	t.Run("found through wrapping", func(t *testing.T) {
+		r := &Report{}
+		r.Addf("a", CodeInvalid, "bad a")
+		r.Addf("a", CodeTooLong, "long a")
+		err := fmt.Errorf("outer: %w", r.Err())
+		issues := IssuesOf(err)
+		if len(issues) != 2 || !slices.Equal(issues.Fields(), []string{"a"}) {
+			t.Fatalf("issues = %+v", issues)
+		}
+		if IssuesOf(errSentinel) != nil {
+			t.Fatalf("IssuesOf(plain error) is not nil")
+		}
+	})
+}